@@ -0,0 +1,241 @@
+package api
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/BullionBear/sequex/internal/execution"
+	"github.com/BullionBear/sequex/internal/fx"
+	"github.com/BullionBear/sequex/internal/pms"
+	"github.com/gin-gonic/gin"
+)
+
+func NewExecutionV2(rg *gin.RouterGroup, history *execution.Store, tenants map[string]*execution.Store, rates *fx.Rates) {
+	h := &executionV2Handler{history: history, tenants: tenants, rates: rates}
+	rg.GET("/orders", h.listOrders)
+	rg.GET("/orders/:id", h.getOrder)
+	rg.GET("/orders/:id/fills", h.listOrderFills)
+	rg.GET("/fills", h.listFills)
+	rg.GET("/fees", h.getFees)
+}
+
+type executionV2Handler struct {
+	history *execution.Store
+	tenants map[string]*execution.Store // History of each tenant, keyed by tenant id
+	rates   *fx.Rates
+}
+
+// historyOf returns the history of the tenant of a request, nil when it has none
+func (h *executionV2Handler) historyOf(c *gin.Context) *execution.Store {
+	if t := tenantOf(c); t != nil {
+		return h.tenants[t.ID]
+	}
+	return h.history
+}
+
+var errNoExecutionHistory = errors.New("execution history is not enabled")
+
+// @Summary Search the order history
+// @Description Orders are built from the execution reports of the OMS and returned oldest first.
+// @Tags executions
+// @Produce json,text/csv
+// @Param from query string false "First creation time included, YYYY-MM-DD or RFC 3339"
+// @Param to query string false "First creation time excluded, YYYY-MM-DD or RFC 3339"
+// @Param symbol query string false "BASE-QUOTE, e.g. BTC-USDT"
+// @Param strategy query string false "Strategy that placed the orders"
+// @Param exchange query string false "e.g. BINANCE"
+// @Param status query string false "e.g. FILLED"
+// @Param limit query int false "Most recent orders returned, every order by default"
+// @Param format query string false "json (default) or csv"
+// @Success 200 {array} execution.Order
+// @Failure 400 {object} ErrorResponse
+// @Failure 503 {object} ErrorResponse "History not enabled"
+// @Router /v2/orders [get]
+func (h *executionV2Handler) listOrders(c *gin.Context) {
+	filter, csv, err := h.filter(c)
+	if err != nil {
+		executionError(c, err)
+		return
+	}
+	orders, err := h.historyOf(c).Orders(filter)
+	if err != nil {
+		executionError(c, err)
+		return
+	}
+	if !csv {
+		c.JSON(http.StatusOK, orders)
+		return
+	}
+	writeCSV(c, "orders", func() error { return execution.WriteOrdersCSV(c.Writer, orders) })
+}
+
+// @Summary Get an order
+// @Tags executions
+// @Produce json
+// @Param id path string true "Order id or client order id"
+// @Success 200 {object} execution.Order
+// @Failure 404 {object} ErrorResponse
+// @Failure 503 {object} ErrorResponse "History not enabled"
+// @Router /v2/orders/{id} [get]
+func (h *executionV2Handler) getOrder(c *gin.Context) {
+	history := h.historyOf(c)
+	if history == nil {
+		executionError(c, errNoExecutionHistory)
+		return
+	}
+	order, err := history.Order(c.Param("id"))
+	if err != nil {
+		executionError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, order)
+}
+
+// @Summary List the fills of an order
+// @Tags executions
+// @Produce json
+// @Param id path string true "Order id or client order id"
+// @Success 200 {array} execution.Fill
+// @Failure 404 {object} ErrorResponse
+// @Failure 503 {object} ErrorResponse "History not enabled"
+// @Router /v2/orders/{id}/fills [get]
+func (h *executionV2Handler) listOrderFills(c *gin.Context) {
+	history := h.historyOf(c)
+	if history == nil {
+		executionError(c, errNoExecutionHistory)
+		return
+	}
+	fills, err := history.OrderFills(c.Param("id"))
+	if err != nil {
+		executionError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, fills)
+}
+
+// @Summary Search the fill history
+// @Description Fills are returned oldest first.
+// @Tags executions
+// @Produce json,text/csv
+// @Param from query string false "First fill time included, YYYY-MM-DD or RFC 3339"
+// @Param to query string false "First fill time excluded, YYYY-MM-DD or RFC 3339"
+// @Param symbol query string false "BASE-QUOTE, e.g. BTC-USDT"
+// @Param strategy query string false "Strategy that placed the orders"
+// @Param exchange query string false "e.g. BINANCE"
+// @Param status query string false "Current status of the orders filled, e.g. FILLED"
+// @Param limit query int false "Most recent fills returned, every fill by default"
+// @Param format query string false "json (default) or csv"
+// @Success 200 {array} execution.Fill
+// @Failure 400 {object} ErrorResponse
+// @Failure 503 {object} ErrorResponse "History not enabled"
+// @Router /v2/fills [get]
+func (h *executionV2Handler) listFills(c *gin.Context) {
+	filter, csv, err := h.filter(c)
+	if err != nil {
+		executionError(c, err)
+		return
+	}
+	fills, err := h.historyOf(c).Fills(filter)
+	if err != nil {
+		executionError(c, err)
+		return
+	}
+	if !csv {
+		c.JSON(http.StatusOK, fills)
+		return
+	}
+	writeCSV(c, "fills", func() error { return execution.WriteFillsCSV(c.Writer, fills) })
+}
+
+// @Summary Report fees per strategy
+// @Description Commissions of the fills per strategy and day (UTC), by asset and converted at the rates as of each fill.
+// @Tags executions
+// @Produce json
+// @Param from query string false "First fill time included, YYYY-MM-DD or RFC 3339"
+// @Param to query string false "First fill time excluded, YYYY-MM-DD or RFC 3339"
+// @Param symbol query string false "BASE-QUOTE, e.g. BTC-USDT"
+// @Param strategy query string false "Strategy that placed the orders"
+// @Param exchange query string false "e.g. BINANCE"
+// @Param currency query string false "Currency of the totals, default USDT"
+// @Success 200 {array} execution.StrategyFees
+// @Failure 400 {object} ErrorResponse
+// @Failure 503 {object} ErrorResponse "History not enabled or no rate to the currency"
+// @Router /v2/fees [get]
+func (h *executionV2Handler) getFees(c *gin.Context) {
+	filter, _, err := h.filter(c)
+	if err != nil {
+		executionError(c, err)
+		return
+	}
+	fills, err := h.historyOf(c).Fills(filter)
+	if err != nil {
+		executionError(c, err)
+		return
+	}
+	currency := strings.ToUpper(c.DefaultQuery("currency", pms.DefaultBaseCurrency))
+	fees, err := execution.FeesByStrategy(fills, currency, h.rates)
+	if err != nil {
+		executionError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, fees)
+}
+
+// filter reads the search parameters and whether CSV was requested
+func (h *executionV2Handler) filter(c *gin.Context) (execution.Filter, bool, error) {
+	var filter execution.Filter
+	if h.historyOf(c) == nil {
+		return filter, false, errNoExecutionHistory
+	}
+	var csv bool
+	switch format := c.DefaultQuery("format", "json"); format {
+	case "json":
+	case "csv":
+		csv = true
+	default:
+		return filter, false, fmt.Errorf("%w: unknown format %q, expected json or csv", execution.ErrInvalid, format)
+	}
+	for name, at := range map[string]*int64{"from": &filter.From, "to": &filter.To} {
+		t, err := parseDate(c.Query(name))
+		if err != nil {
+			return filter, false, fmt.Errorf("%w: %s must be YYYY-MM-DD or RFC 3339", execution.ErrInvalid, name)
+		}
+		if !t.IsZero() {
+			*at = t.UnixMilli()
+		}
+	}
+	if err := queryNumber(c, "limit", &filter.Limit); err != nil {
+		return filter, false, fmt.Errorf("%w: %v", execution.ErrInvalid, err)
+	}
+	filter.Symbol = c.Query("symbol")
+	filter.Strategy = c.Query("strategy")
+	filter.Exchange = c.Query("exchange")
+	filter.Status = c.Query("status")
+	return filter, csv, nil
+}
+
+// writeCSV sends a CSV attachment named after the dataset and the time of the export
+func writeCSV(c *gin.Context, dataset string, write func() error) {
+	c.Header("Content-Disposition", fmt.Sprintf(`attachment; filename="%s-%s.csv"`, dataset, time.Now().UTC().Format("20060102T150405Z")))
+	c.Status(http.StatusOK)
+	c.Writer.Header().Set("Content-Type", "text/csv; charset=utf-8")
+	if err := write(); err != nil {
+		c.Error(err)
+	}
+}
+
+func executionError(c *gin.Context, err error) {
+	status := http.StatusInternalServerError
+	switch {
+	case errors.Is(err, execution.ErrInvalid), errors.Is(err, pms.ErrInvalid):
+		status = http.StatusBadRequest
+	case errors.Is(err, execution.ErrNotFound):
+		status = http.StatusNotFound
+	case errors.Is(err, errNoExecutionHistory), errors.Is(err, fx.ErrNoRate):
+		status = http.StatusServiceUnavailable
+	}
+	c.JSON(status, ErrorResponse{Error: err.Error()})
+}
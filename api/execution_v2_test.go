@@ -0,0 +1,70 @@
+package api
+
+import (
+	"encoding/json"
+	"math"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/BullionBear/sequex/internal/execution"
+)
+
+func TestExecutionHistory(t *testing.T) {
+	engine := newTestRouter()
+
+	w := get(engine, "/api/v2/orders?symbol=BTC-USDT&status=FILLED")
+	var orders []execution.Order
+	if w.Code != http.StatusOK || json.Unmarshal(w.Body.Bytes(), &orders) != nil {
+		t.Fatalf("orders: %d %s", w.Code, w.Body)
+	}
+	if len(orders) != 1 || orders[0].ClientOrderID != "grid-1" || orders[0].Strategy != "grid" {
+		t.Errorf("orders = %+v", orders)
+	}
+	if w := get(engine, "/api/v2/orders?status=CANCELED"); w.Body.String() != "[]" {
+		t.Errorf("canceled orders: %s", w.Body)
+	}
+
+	w = get(engine, "/api/v2/orders/1001")
+	var order execution.Order
+	if w.Code != http.StatusOK || json.Unmarshal(w.Body.Bytes(), &order) != nil || order.Status != "FILLED" {
+		t.Errorf("order: %d %s", w.Code, w.Body)
+	}
+	if w := get(engine, "/api/v2/orders/missing"); w.Code != http.StatusNotFound {
+		t.Errorf("missing order: %d", w.Code)
+	}
+
+	var fills []execution.Fill
+	w = get(engine, "/api/v2/orders/grid-1/fills")
+	if w.Code != http.StatusOK || json.Unmarshal(w.Body.Bytes(), &fills) != nil || len(fills) != 2 {
+		t.Errorf("order fills: %d %s", w.Code, w.Body)
+	}
+	w = get(engine, "/api/v2/fills?from=2026-10-01T02:00:00Z&to=2026-10-02")
+	if w.Code != http.StatusOK || json.Unmarshal(w.Body.Bytes(), &fills) != nil || len(fills) != 1 || fills[0].ExecID != "2" {
+		t.Errorf("fills: %d %s", w.Code, w.Body)
+	}
+
+	w = get(engine, "/api/v2/fills?format=csv&strategy=grid")
+	lines := strings.Split(strings.TrimSpace(w.Body.String()), "\n")
+	if w.Code != http.StatusOK || !strings.HasPrefix(w.Header().Get("Content-Type"), "text/csv") || len(lines) != 3 {
+		t.Errorf("csv fills: %d %q", w.Code, w.Body)
+	}
+
+	var fees []execution.StrategyFees
+	w = get(engine, "/api/v2/fees?strategy=grid")
+	if w.Code != http.StatusOK || json.Unmarshal(w.Body.Bytes(), &fees) != nil || len(fees) != 1 {
+		t.Fatalf("fees: %d %s", w.Code, w.Body)
+	}
+	if f := fees[0].Fees; fees[0].Strategy != "grid" || f.Currency != "USDT" || len(f.Days) != 1 || f.Total.Fills != 2 || math.Abs(f.Total.Total-1.2) > 1e-9 {
+		t.Errorf("fees = %+v", fees)
+	}
+	if w := get(engine, "/api/v2/fees?currency=JPY"); w.Code != http.StatusServiceUnavailable {
+		t.Errorf("fees without a rate: %d", w.Code)
+	}
+
+	for _, path := range []string{"/api/v2/fills?from=yesterday", "/api/v2/orders?status=DONE", "/api/v2/orders?limit=x", "/api/v2/fills?format=xml"} {
+		if w := get(engine, path); w.Code != http.StatusBadRequest {
+			t.Errorf("%s: %d", path, w.Code)
+		}
+	}
+}
@@ -0,0 +1,199 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/BullionBear/sequex/internal/guardian"
+	"github.com/BullionBear/sequex/pkg/eventbus"
+	"github.com/gin-gonic/gin"
+	"github.com/nats-io/nats.go"
+)
+
+// StrategyControl lists, pauses and resumes the strategies of the guardian
+// serving its RPCs under a prefix
+type StrategyControl interface {
+	Strategies(ctx context.Context, prefix string) (*guardian.List, error)
+	Pause(ctx context.Context, prefix string, req *guardian.PauseRequest) (*guardian.Event, error)
+	Resume(ctx context.Context, prefix string, req *guardian.ResumeRequest) (*guardian.Event, error)
+}
+
+// GuardianSubject returns the prefix the guardian of a service serves its
+// RPCs under by default. Services of a tenant serve them under the subject
+// prefix of the tenant.
+func GuardianSubject(service string) string {
+	return guardian.DefaultSubject + "." + service
+}
+
+// NATSStrategyControl sends the guardian RPCs over the event bus
+type NATSStrategyControl struct {
+	bus     *eventbus.Bus
+	timeout time.Duration
+}
+
+// NewNATSStrategyControl creates a guardian client
+func NewNATSStrategyControl(bus *eventbus.Bus, timeout time.Duration) *NATSStrategyControl {
+	return &NATSStrategyControl{bus: bus, timeout: timeout}
+}
+
+func (n *NATSStrategyControl) Strategies(ctx context.Context, prefix string) (*guardian.List, error) {
+	ctx, cancel := context.WithTimeout(ctx, n.timeout)
+	defer cancel()
+	list, err := guardian.ListEndpoint(prefix).Call(ctx, n.bus, &guardian.ListRequest{})
+	return list, unavailable(err, prefix)
+}
+
+func (n *NATSStrategyControl) Pause(ctx context.Context, prefix string, req *guardian.PauseRequest) (*guardian.Event, error) {
+	ctx, cancel := context.WithTimeout(ctx, n.timeout)
+	defer cancel()
+	e, err := guardian.PauseEndpoint(prefix).Call(ctx, n.bus, req)
+	return e, unavailable(err, prefix)
+}
+
+func (n *NATSStrategyControl) Resume(ctx context.Context, prefix string, req *guardian.ResumeRequest) (*guardian.Event, error) {
+	ctx, cancel := context.WithTimeout(ctx, n.timeout)
+	defer cancel()
+	e, err := guardian.ResumeEndpoint(prefix).Call(ctx, n.bus, req)
+	return e, unavailable(err, prefix)
+}
+
+// unavailable reports the services not answering as ErrNodeUnavailable
+func unavailable(err error, subject string) error {
+	if errors.Is(err, nats.ErrNoResponders) || errors.Is(err, nats.ErrTimeout) || errors.Is(err, context.DeadlineExceeded) {
+		return fmt.Errorf("%w: %s", ErrNodeUnavailable, subject)
+	}
+	return err
+}
+
+// StrategyActionRequest is the body of a pause or a resumption
+type StrategyActionRequest struct {
+	By     string `json:"by" binding:"required"`     // Who pauses or resumes the strategy
+	Reason string `json:"reason" binding:"required"` // Recorded in the audit trail
+}
+
+func NewGuardianV2(rg *gin.RouterGroup, control StrategyControl) {
+	if control == nil {
+		return
+	}
+	h := &guardianV2Handler{control: control}
+	rg.GET("/guardians/:service/strategies", h.listStrategies)
+	rg.POST("/guardians/:service/strategies/:strategy/pause", h.pauseStrategy)
+	rg.POST("/guardians/:service/strategies/:strategy/resume", h.resumeStrategy)
+}
+
+type guardianV2Handler struct {
+	control StrategyControl
+}
+
+// prefix returns the prefix of the guardian of the service of the request,
+// under the subject prefix of the tenant
+func (h *guardianV2Handler) prefix(c *gin.Context) (string, bool) {
+	service := c.Param("service")
+	if err := eventbus.ValidateToken(service); err != nil {
+		c.JSON(http.StatusBadRequest, ErrorResponse{Error: err.Error()})
+		return "", false
+	}
+	return tenantOf(c).Subject(GuardianSubject(service)), true
+}
+
+// @Summary List the strategies of a guardian
+// @Description Equity, drawdown and pause of the strategies followed by the guardian of a service, with the latest pauses and resumptions
+// @Tags guardians
+// @Produce json
+// @Param service path string true "Service enforcing the drawdown limits, e.g. fixgateway"
+// @Success 200 {object} guardian.List
+// @Failure 400 {object} ErrorResponse
+// @Failure 503 {object} ErrorResponse "Service did not answer"
+// @Failure 500 {object} ErrorResponse
+// @Router /v2/guardians/{service}/strategies [get]
+func (h *guardianV2Handler) listStrategies(c *gin.Context) {
+	prefix, ok := h.prefix(c)
+	if !ok {
+		return
+	}
+	list, err := h.control.Strategies(c.Request.Context(), prefix)
+	if err != nil {
+		guardianError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, list)
+}
+
+// @Summary Pause a strategy
+// @Description Cancels the working orders of the strategy and rejects its new orders until resumed
+// @Tags guardians
+// @Accept json
+// @Produce json
+// @Param service path string true "Service enforcing the drawdown limits, e.g. fixgateway"
+// @Param strategy path string true "Strategy"
+// @Param request body StrategyActionRequest true "Who pauses the strategy and why"
+// @Success 200 {object} guardian.Event
+// @Failure 400 {object} ErrorResponse
+// @Failure 409 {object} ErrorResponse "Strategy already paused"
+// @Failure 503 {object} ErrorResponse "Service did not answer"
+// @Router /v2/guardians/{service}/strategies/{strategy}/pause [post]
+func (h *guardianV2Handler) pauseStrategy(c *gin.Context) {
+	prefix, ok := h.prefix(c)
+	if !ok {
+		return
+	}
+	var req StrategyActionRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, ErrorResponse{Error: err.Error()})
+		return
+	}
+	e, err := h.control.Pause(c.Request.Context(), prefix, &guardian.PauseRequest{Strategy: c.Param("strategy"), By: req.By, Reason: req.Reason})
+	if err != nil {
+		guardianError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, e)
+}
+
+// @Summary Resume a paused strategy
+// @Description Lets the strategy place orders again, its drawdown starting over from its current equity
+// @Tags guardians
+// @Accept json
+// @Produce json
+// @Param service path string true "Service enforcing the drawdown limits, e.g. fixgateway"
+// @Param strategy path string true "Strategy"
+// @Param request body StrategyActionRequest true "Who resumes the strategy and why"
+// @Success 200 {object} guardian.Event
+// @Failure 400 {object} ErrorResponse
+// @Failure 409 {object} ErrorResponse "Strategy not paused"
+// @Failure 503 {object} ErrorResponse "Service did not answer"
+// @Router /v2/guardians/{service}/strategies/{strategy}/resume [post]
+func (h *guardianV2Handler) resumeStrategy(c *gin.Context) {
+	prefix, ok := h.prefix(c)
+	if !ok {
+		return
+	}
+	var req StrategyActionRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, ErrorResponse{Error: err.Error()})
+		return
+	}
+	e, err := h.control.Resume(c.Request.Context(), prefix, &guardian.ResumeRequest{Strategy: c.Param("strategy"), By: req.By, Reason: req.Reason})
+	if err != nil {
+		guardianError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, e)
+}
+
+// guardianError answers the failure of a guardian RPC. The guardian refusing
+// the request, e.g. resuming a strategy not paused, is a conflict.
+func guardianError(c *gin.Context, err error) {
+	var rpcErr *eventbus.RPCError
+	switch {
+	case errors.Is(err, ErrNodeUnavailable):
+		c.JSON(http.StatusServiceUnavailable, ErrorResponse{Error: err.Error()})
+	case errors.As(err, &rpcErr):
+		c.JSON(http.StatusConflict, ErrorResponse{Error: rpcErr.Message})
+	default:
+		c.JSON(http.StatusInternalServerError, ErrorResponse{Error: err.Error()})
+	}
+}
@@ -0,0 +1,97 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"testing"
+
+	"github.com/BullionBear/sequex/internal/guardian"
+	"github.com/BullionBear/sequex/internal/pms"
+	"github.com/BullionBear/sequex/pkg/eventbus"
+	"github.com/gin-gonic/gin"
+)
+
+// fakeStrategyControl answers the guardian RPCs from the guardians of each
+// prefix, failing them as the event bus would
+type fakeStrategyControl map[string]*guardian.Guardian
+
+func (f fakeStrategyControl) guardian(prefix string) (*guardian.Guardian, error) {
+	g, ok := f[prefix]
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", ErrNodeUnavailable, prefix)
+	}
+	return g, nil
+}
+
+func (f fakeStrategyControl) Strategies(_ context.Context, prefix string) (*guardian.List, error) {
+	g, err := f.guardian(prefix)
+	if err != nil {
+		return nil, err
+	}
+	return &guardian.List{Currency: g.Currency(), Strategies: g.Statuses(), Events: g.History()}, nil
+}
+
+func (f fakeStrategyControl) Pause(_ context.Context, prefix string, req *guardian.PauseRequest) (*guardian.Event, error) {
+	g, err := f.guardian(prefix)
+	if err != nil {
+		return nil, err
+	}
+	e, err := g.Pause(req.Strategy, req.By, req.Reason)
+	if err != nil {
+		return nil, &eventbus.RPCError{Subject: prefix + ".pause", Message: err.Error()}
+	}
+	return &e, nil
+}
+
+func (f fakeStrategyControl) Resume(_ context.Context, prefix string, req *guardian.ResumeRequest) (*guardian.Event, error) {
+	g, err := f.guardian(prefix)
+	if err != nil {
+		return nil, err
+	}
+	e, err := g.Resume(req.Strategy, req.By, req.Reason)
+	if err != nil {
+		return nil, &eventbus.RPCError{Subject: prefix + ".resume", Message: err.Error()}
+	}
+	return &e, nil
+}
+
+func TestGuardianV2(t *testing.T) {
+	g, err := guardian.Open(guardian.Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	gin.SetMode(gin.TestMode)
+	engine := gin.New()
+	NewRouter(engine, Services{
+		Portfolios: pms.NewMemoryStore(),
+		Strategies: fakeStrategyControl{"guardian.fixgateway": g},
+	}, Options{})
+
+	for _, tc := range []struct {
+		method, path, body string
+		status             int
+	}{
+		{http.MethodPost, "/api/v2/guardians/fixgateway/strategies/fix.CLIENT/resume", `{"by":"ops","reason":"fixed"}`, http.StatusConflict},
+		{http.MethodPost, "/api/v2/guardians/fixgateway/strategies/fix.CLIENT/pause", `{"by":"ops"}`, http.StatusBadRequest},
+		{http.MethodPost, "/api/v2/guardians/fixgateway/strategies/fix.CLIENT/pause", `{"by":"ops","reason":"venue incident"}`, http.StatusOK},
+		{http.MethodPost, "/api/v2/guardians/fixgateway/strategies/fix.CLIENT/pause", `{"by":"ops","reason":"again"}`, http.StatusConflict},
+		{http.MethodPost, "/api/v2/guardians/fixgateway/strategies/fix.CLIENT/resume", `{"by":"ops","reason":"incident closed"}`, http.StatusOK},
+		{http.MethodGet, "/api/v2/guardians/oms/strategies", "", http.StatusServiceUnavailable},
+		{http.MethodGet, "/api/v2/guardians/fix*/strategies", "", http.StatusBadRequest},
+	} {
+		if w := as(engine, tc.method, tc.path, tc.body, ""); w.Code != tc.status {
+			t.Errorf("%s %s %s: status %d, want %d: %s", tc.method, tc.path, tc.body, w.Code, tc.status, w.Body)
+		}
+	}
+
+	w := as(engine, http.MethodGet, "/api/v2/guardians/fixgateway/strategies", "", "")
+	var list guardian.List
+	if err := json.Unmarshal(w.Body.Bytes(), &list); err != nil || w.Code != http.StatusOK {
+		t.Fatalf("list: %d %s", w.Code, w.Body)
+	}
+	if len(list.Strategies) != 1 || list.Strategies[0].Paused != nil || len(list.Events) != 2 || list.Events[1].Reason != "incident closed" {
+		t.Errorf("list: %+v", list)
+	}
+}
@@ -1,6 +1,10 @@
 package api
 
 import (
+	"net/http"
+	"strings"
+
+	"github.com/BullionBear/sequex/internal/tenant"
 	"github.com/gin-gonic/gin"
 )
 
@@ -16,3 +20,41 @@ func AllowAllCors(c *gin.Context) {
 	}
 	c.Next()
 }
+
+// HeaderAccessKey carries the API key of a tenant. Keys may also be sent as
+// Authorization: Bearer <key>.
+const HeaderAccessKey = "Access-Key"
+
+// tenantKey is the gin context key of the authenticated tenant
+const tenantKey = "tenant"
+
+// Authenticate returns a middleware resolving the tenant of every request
+// from its API key, rejecting requests without a valid key. Handlers scope
+// their queries to the tenant. A nil registry, a deployment without tenants,
+// lets every request through unscoped.
+func Authenticate(tenants *tenant.Registry) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if tenants == nil {
+			c.Next()
+			return
+		}
+		key := c.GetHeader(HeaderAccessKey)
+		if key == "" {
+			key, _ = strings.CutPrefix(c.GetHeader("Authorization"), "Bearer ")
+		}
+		t, ok := tenants.Authenticate(key)
+		if !ok {
+			c.AbortWithStatusJSON(http.StatusUnauthorized, ErrorResponse{Error: "missing or invalid API key"})
+			return
+		}
+		c.Set(tenantKey, t)
+		c.Next()
+	}
+}
+
+// tenantOf returns the tenant of a request, nil when unscoped
+func tenantOf(c *gin.Context) *tenant.Tenant {
+	v, _ := c.Get(tenantKey)
+	t, _ := v.(*tenant.Tenant)
+	return t
+}
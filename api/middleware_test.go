@@ -0,0 +1,114 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/BullionBear/sequex/internal/execution"
+	"github.com/BullionBear/sequex/internal/pms"
+	"github.com/BullionBear/sequex/internal/tenant"
+	"github.com/BullionBear/sequex/pkg/diagnostics"
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	acmeKey   = "acme-key-0123456789"
+	globexKey = "globex-key-0123456789"
+)
+
+func newTenantRouter(t *testing.T) *gin.Engine {
+	t.Helper()
+	tenants, err := tenant.NewRegistry([]tenant.Tenant{
+		{ID: "acme", Keys: []string{acmeKey}},
+		{ID: "globex", Keys: []string{globexKey}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	gin.SetMode(gin.TestMode)
+	engine := gin.New()
+	NewRouter(engine, Services{
+		Nodes: &fakeNodeControl{reports: map[string]diagnostics.Report{
+			"diag.feed":             {Service: "feed"},
+			"tenant.acme.diag.feed": {Service: "feed", Goroutines: 3},
+		}},
+		Portfolios:       pms.NewMemoryStore(),
+		Rates:            testRates(),
+		Executions:       testExecutions(),
+		Tenants:          tenants,
+		TenantExecutions: map[string]*execution.Store{"acme": testExecutions()},
+	}, Options{})
+	return engine
+}
+
+// as sends a request with the API key of a tenant
+func as(engine *gin.Engine, method, path, body, key string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(method, path, strings.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	if key != "" {
+		req.Header.Set(HeaderAccessKey, key)
+	}
+	w := httptest.NewRecorder()
+	engine.ServeHTTP(w, req)
+	return w
+}
+
+func TestAuthenticate(t *testing.T) {
+	engine := newTenantRouter(t)
+	for _, key := range []string{"", "wrong-key-0123456789"} {
+		if w := as(engine, http.MethodGet, "/api/v2/portfolios", "", key); w.Code != http.StatusUnauthorized {
+			t.Errorf("key %q: status %d", key, w.Code)
+		}
+	}
+	if w := as(engine, http.MethodGet, "/nodes", "", ""); w.Code != http.StatusUnauthorized {
+		t.Errorf("legacy route without a key: status %d", w.Code)
+	}
+	req := httptest.NewRequest(http.MethodGet, "/api/v2/portfolios", nil)
+	req.Header.Set("Authorization", "Bearer "+acmeKey)
+	w := httptest.NewRecorder()
+	engine.ServeHTTP(w, req)
+	if w.Code != http.StatusOK {
+		t.Errorf("bearer key: status %d", w.Code)
+	}
+}
+
+func TestTenantIsolation(t *testing.T) {
+	engine := newTenantRouter(t)
+	for _, key := range []string{acmeKey, globexKey} {
+		if w := as(engine, http.MethodPut, "/api/v2/portfolios/main", `{"name":"Main"}`, key); w.Code != http.StatusCreated {
+			t.Fatalf("create: status %d: %s", w.Code, w.Body)
+		}
+	}
+	if w := as(engine, http.MethodPut, "/api/v2/portfolios/main/positions/BTC-USDT", `{"quantity":1,"avg_price":60000}`, acmeKey); w.Code != http.StatusCreated {
+		t.Fatalf("position: status %d: %s", w.Code, w.Body)
+	}
+
+	var portfolios []pms.Portfolio
+	w := as(engine, http.MethodGet, "/api/v2/portfolios", "", globexKey)
+	if err := json.Unmarshal(w.Body.Bytes(), &portfolios); err != nil || len(portfolios) != 1 || portfolios[0].ID != "main" {
+		t.Errorf("portfolios of globex: %s", w.Body)
+	}
+	if w := as(engine, http.MethodGet, "/api/v2/portfolios/main/positions/BTC-USDT", "", globexKey); w.Code != http.StatusNotFound {
+		t.Errorf("globex reads the position of acme: status %d", w.Code)
+	}
+	if w := as(engine, http.MethodGet, "/api/v2/portfolios/acme_main", "", globexKey); w.Code != http.StatusNotFound {
+		t.Errorf("globex reads the stored portfolio of acme: status %d", w.Code)
+	}
+
+	if w := as(engine, http.MethodGet, "/api/v2/orders/grid-1", "", acmeKey); w.Code != http.StatusOK {
+		t.Errorf("orders of acme: status %d", w.Code)
+	}
+	if w := as(engine, http.MethodGet, "/api/v2/orders/grid-1", "", globexKey); w.Code != http.StatusServiceUnavailable {
+		t.Errorf("globex without history: status %d", w.Code)
+	}
+
+	if w := as(engine, http.MethodGet, "/api/v2/nodes/feed/diagnostics", "", acmeKey); w.Code != http.StatusOK || !strings.Contains(w.Body.String(), `"goroutines":3`) {
+		t.Errorf("node of acme: status %d: %s", w.Code, w.Body)
+	}
+	if w := as(engine, http.MethodGet, "/api/v2/nodes/feed/diagnostics", "", globexKey); w.Code != http.StatusServiceUnavailable {
+		t.Errorf("globex reaches a node outside its prefix: status %d", w.Code)
+	}
+}
@@ -14,20 +14,23 @@ func NewNode(rg *gin.RouterGroup) {
 
 // @Summary List all nodes
 // @Description List all nodes
+// @Tags nodes
 // @Accept json
 // @Produce json
 // @Success 200 {array} string "List of nodes"
-// @Router /nodes [get]
+// @Router /v1/nodes [get]
 func listNodes(c *gin.Context) {
 	c.JSON(http.StatusOK, gin.H{"message": "Hello, World!"})
 }
 
 // @Summary Get a node
 // @Description Get a node
+// @Tags nodes
 // @Accept json
 // @Produce json
+// @Param name path string true "Node name"
 // @Success 200 {object} string "Node"
-// @Router /node/{name} [get]
+// @Router /v1/node/{name} [get]
 func getNode(c *gin.Context) {
 	c.JSON(http.StatusOK, gin.H{"message": "Hello, World!"})
 }
@@ -38,10 +41,12 @@ type RegisterNodeRequest struct {
 
 // @Summary Register a node
 // @Description Register a node
+// @Tags nodes
 // @Accept json
 // @Produce json
+// @Param request body RegisterNodeRequest true "Node to register"
 // @Success 200 {object} string "Node"
-// @Router /node/register [post]
+// @Router /v1/node/register [post]
 func registerNode(c *gin.Context) {
 	var req RegisterNodeRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
@@ -0,0 +1,98 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/BullionBear/sequex/pkg/diagnostics"
+	"github.com/BullionBear/sequex/pkg/eventbus"
+	"github.com/gin-gonic/gin"
+	"github.com/nats-io/nats.go"
+)
+
+// ErrNodeUnavailable is returned when a node does not answer a control RPC
+var ErrNodeUnavailable = errors.New("node unavailable")
+
+// NodeControl sends control RPCs to nodes
+type NodeControl interface {
+	// Diagnostics requests the runtime report a node serves on subject
+	Diagnostics(ctx context.Context, subject string) (diagnostics.Report, error)
+}
+
+// DiagnosticsSubject returns the subject a node serves its runtime report on
+// by default. Nodes of a tenant serve it under the subject prefix of the
+// tenant.
+func DiagnosticsSubject(name string) string {
+	return "diag." + name
+}
+
+// NATSNodeControl sends control RPCs over NATS request/reply
+type NATSNodeControl struct {
+	conn    *nats.Conn
+	timeout time.Duration
+}
+
+// NewNATSNodeControl creates a node control client
+func NewNATSNodeControl(conn *nats.Conn, timeout time.Duration) *NATSNodeControl {
+	return &NATSNodeControl{conn: conn, timeout: timeout}
+}
+
+// Diagnostics requests the runtime report a node serves on subject
+func (n *NATSNodeControl) Diagnostics(ctx context.Context, subject string) (diagnostics.Report, error) {
+	var report diagnostics.Report
+	ctx, cancel := context.WithTimeout(ctx, n.timeout)
+	defer cancel()
+	msg, err := n.conn.RequestWithContext(ctx, subject, nil)
+	if err != nil {
+		if errors.Is(err, nats.ErrNoResponders) || errors.Is(err, context.DeadlineExceeded) {
+			return report, fmt.Errorf("%w: %s", ErrNodeUnavailable, subject)
+		}
+		return report, err
+	}
+	if err := json.Unmarshal(msg.Data, &report); err != nil {
+		return report, fmt.Errorf("invalid diagnostics report on %s: %w", subject, err)
+	}
+	return report, nil
+}
+
+func NewNodeV2(rg *gin.RouterGroup, control NodeControl) {
+	h := &nodeV2Handler{control: control}
+	rg.GET("/nodes/:name/diagnostics", h.getNodeDiagnostics)
+}
+
+type nodeV2Handler struct {
+	control NodeControl
+}
+
+// @Summary Get node diagnostics
+// @Description Runtime diagnostics of a node: goroutines, heap, GC pauses and queue depths
+// @Tags nodes
+// @Produce json
+// @Param name path string true "Node name"
+// @Success 200 {object} diagnostics.Report
+// @Failure 400 {object} ErrorResponse
+// @Failure 503 {object} ErrorResponse "Node did not answer"
+// @Failure 500 {object} ErrorResponse
+// @Router /v2/nodes/{name}/diagnostics [get]
+func (h *nodeV2Handler) getNodeDiagnostics(c *gin.Context) {
+	name := c.Param("name")
+	if err := eventbus.ValidateToken(name); err != nil {
+		c.JSON(http.StatusBadRequest, ErrorResponse{Error: err.Error()})
+		return
+	}
+	// Tenants reach only the nodes serving under their subject prefix
+	report, err := h.control.Diagnostics(c.Request.Context(), tenantOf(c).Subject(DiagnosticsSubject(name)))
+	if err != nil {
+		status := http.StatusInternalServerError
+		if errors.Is(err, ErrNodeUnavailable) {
+			status = http.StatusServiceUnavailable
+		}
+		c.JSON(status, ErrorResponse{Error: err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, report)
+}
@@ -0,0 +1,711 @@
+package api
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/BullionBear/sequex/internal/fx"
+	"github.com/BullionBear/sequex/internal/pms"
+	"github.com/BullionBear/sequex/internal/risk"
+	"github.com/gin-gonic/gin"
+)
+
+// Headers of optimistic concurrency control. Every read returns the version
+// of the resource as its ETag; writes send it back in If-Match.
+const (
+	HeaderETag    = "ETag"
+	HeaderIfMatch = "If-Match"
+)
+
+// PortfolioRequest is the body of a portfolio write
+type PortfolioRequest struct {
+	Name         string `json:"name"`
+	BaseCurrency string `json:"base_currency"` // Currency of valuations and reports, USDT by default
+	CostMethod   string `json:"cost_method"`   // fifo (default), lifo or average
+	Version      int64  `json:"version"`       // Version the update is based on, 0 to create. Ignored when If-Match is set.
+}
+
+// PositionRequest is the body of a position write
+type PositionRequest struct {
+	Quantity float64 `json:"quantity"`
+	AvgPrice float64 `json:"avg_price"`
+	Version  int64   `json:"version"` // Version the update is based on, 0 to create. Ignored when If-Match is set.
+}
+
+func NewPortfolioV2(rg *gin.RouterGroup, store pms.Store, rates *fx.Rates, history *risk.History) {
+	h := &portfolioV2Handler{store: store, rates: rates, history: history}
+	rg.GET("/portfolios", h.listPortfolios)
+	rg.GET("/portfolios/:id", h.getPortfolio)
+	rg.PUT("/portfolios/:id", h.putPortfolio)
+	rg.GET("/portfolios/:id/positions", h.listPositions)
+	rg.GET("/portfolios/:id/positions/:symbol", h.getPosition)
+	rg.PUT("/portfolios/:id/positions/:symbol", h.putPosition)
+	rg.GET("/portfolios/:id/fills", h.listFills)
+	rg.GET("/portfolios/:id/cashflows", h.listCashFlows)
+	rg.GET("/portfolios/:id/export", h.exportPortfolio)
+	rg.POST("/portfolios/:id/import", h.importPortfolio)
+	rg.GET("/portfolios/:id/lots", h.listLots)
+	rg.GET("/portfolios/:id/realized", h.getRealized)
+	rg.GET("/portfolios/:id/fees", h.getFees)
+	rg.GET("/portfolios/:id/valuation", h.getValuation)
+	rg.GET("/portfolios/:id/risk", h.getRisk)
+	rg.GET("/fx/rates", h.listRates)
+}
+
+type portfolioV2Handler struct {
+	store   pms.Store
+	rates   *fx.Rates
+	history *risk.History
+}
+
+// portfolios returns the store as seen by the tenant of a request
+func (h *portfolioV2Handler) portfolios(c *gin.Context) pms.Store {
+	if t := tenantOf(c); t != nil {
+		return pms.Scope(h.store, t.ID)
+	}
+	return h.store
+}
+
+// @Summary List portfolios
+// @Tags portfolios
+// @Produce json
+// @Success 200 {array} pms.Portfolio
+// @Failure 500 {object} ErrorResponse
+// @Router /v2/portfolios [get]
+func (h *portfolioV2Handler) listPortfolios(c *gin.Context) {
+	portfolios, err := h.portfolios(c).Portfolios()
+	if err != nil {
+		storeError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, portfolios)
+}
+
+// @Summary Get a portfolio
+// @Description The version of the portfolio is returned in the body and as ETag.
+// @Description With as_of, the portfolio is rebuilt as it was at that time from the end of day snapshots and the journal,
+// @Description with its positions valued at the marks of the snapshot it was rebuilt from, and returned as a pms.Historical.
+// @Tags portfolios
+// @Produce json
+// @Param id path string true "Portfolio id"
+// @Param as_of query string false "Time the portfolio is rebuilt at, YYYY-MM-DD or RFC 3339"
+// @Success 200 {object} pms.Portfolio
+// @Header 200 {string} ETag "Portfolio version"
+// @Failure 400 {object} ErrorResponse
+// @Failure 404 {object} ErrorResponse "Portfolio missing, or history not kept that far back"
+// @Failure 501 {object} ErrorResponse "Portfolios kept without a journal"
+// @Router /v2/portfolios/{id} [get]
+func (h *portfolioV2Handler) getPortfolio(c *gin.Context) {
+	if asOf := c.Query("as_of"); asOf != "" {
+		h.getHistorical(c, asOf)
+		return
+	}
+	portfolio, err := h.portfolios(c).Portfolio(c.Param("id"))
+	if err != nil {
+		storeError(c, err)
+		return
+	}
+	setETag(c, portfolio.Version)
+	c.JSON(http.StatusOK, portfolio)
+}
+
+// getHistorical answers a portfolio rebuilt as it was at a time
+func (h *portfolioV2Handler) getHistorical(c *gin.Context, asOf string) {
+	at, err := parseDate(asOf)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, ErrorResponse{Error: "as_of must be YYYY-MM-DD or RFC 3339"})
+		return
+	}
+	historian, ok := h.portfolios(c).(pms.Historian)
+	if !ok {
+		storeError(c, pms.ErrNoHistory)
+		return
+	}
+	historical, err := historian.AsOf(c.Param("id"), at)
+	if err != nil {
+		storeError(c, err)
+		return
+	}
+	setETag(c, historical.Portfolio.Version)
+	c.JSON(http.StatusOK, historical)
+}
+
+// @Summary Create or update a portfolio
+// @Description Updates must state the version they are based on, in If-Match or in the body.
+// @Description A stale version is rejected with 409 and the current portfolio.
+// @Tags portfolios
+// @Accept json
+// @Produce json
+// @Param id path string true "Portfolio id"
+// @Param If-Match header string false "ETag of the version the update is based on"
+// @Param portfolio body PortfolioRequest true "Portfolio"
+// @Success 200 {object} pms.Portfolio
+// @Success 201 {object} pms.Portfolio
+// @Header 200,201 {string} ETag "New portfolio version"
+// @Failure 400 {object} ErrorResponse
+// @Failure 404 {object} ErrorResponse
+// @Failure 409 {object} pms.Portfolio "Version conflict"
+// @Router /v2/portfolios/{id} [put]
+func (h *portfolioV2Handler) putPortfolio(c *gin.Context) {
+	var req PortfolioRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, ErrorResponse{Error: err.Error()})
+		return
+	}
+	expected, err := expectedVersion(c, req.Version)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, ErrorResponse{Error: err.Error()})
+		return
+	}
+	portfolio, err := h.portfolios(c).PutPortfolio(pms.Portfolio{
+		ID:           c.Param("id"),
+		Name:         req.Name,
+		BaseCurrency: req.BaseCurrency,
+		CostMethod:   pms.CostMethod(req.CostMethod),
+	}, expected)
+	if errors.Is(err, pms.ErrVersionConflict) {
+		setETag(c, portfolio.Version)
+		c.JSON(http.StatusConflict, portfolio)
+		return
+	}
+	if err != nil {
+		storeError(c, err)
+		return
+	}
+	setETag(c, portfolio.Version)
+	c.JSON(writeStatus(expected), portfolio)
+}
+
+// @Summary List the positions of a portfolio
+// @Tags portfolios
+// @Produce json
+// @Param id path string true "Portfolio id"
+// @Success 200 {array} pms.Position
+// @Failure 404 {object} ErrorResponse
+// @Router /v2/portfolios/{id}/positions [get]
+func (h *portfolioV2Handler) listPositions(c *gin.Context) {
+	positions, err := h.portfolios(c).Positions(c.Param("id"))
+	if err != nil {
+		storeError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, positions)
+}
+
+// @Summary Get a position
+// @Description The version of the position is returned in the body and as ETag
+// @Tags portfolios
+// @Produce json
+// @Param id path string true "Portfolio id"
+// @Param symbol path string true "Symbol, e.g. BTC-USDT"
+// @Success 200 {object} pms.Position
+// @Header 200 {string} ETag "Position version"
+// @Failure 404 {object} ErrorResponse
+// @Router /v2/portfolios/{id}/positions/{symbol} [get]
+func (h *portfolioV2Handler) getPosition(c *gin.Context) {
+	position, err := h.portfolios(c).Position(c.Param("id"), c.Param("symbol"))
+	if err != nil {
+		storeError(c, err)
+		return
+	}
+	setETag(c, position.Version)
+	c.JSON(http.StatusOK, position)
+}
+
+// @Summary Create or update a position
+// @Description Updates must state the version they are based on, in If-Match or in the body.
+// @Description A stale version is rejected with 409 and the current position.
+// @Tags portfolios
+// @Accept json
+// @Produce json
+// @Param id path string true "Portfolio id"
+// @Param symbol path string true "Symbol, e.g. BTC-USDT"
+// @Param If-Match header string false "ETag of the version the update is based on"
+// @Param position body PositionRequest true "Position"
+// @Success 200 {object} pms.Position
+// @Success 201 {object} pms.Position
+// @Header 200,201 {string} ETag "New position version"
+// @Failure 400 {object} ErrorResponse
+// @Failure 404 {object} ErrorResponse
+// @Failure 409 {object} pms.Position "Version conflict"
+// @Router /v2/portfolios/{id}/positions/{symbol} [put]
+func (h *portfolioV2Handler) putPosition(c *gin.Context) {
+	var req PositionRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, ErrorResponse{Error: err.Error()})
+		return
+	}
+	expected, err := expectedVersion(c, req.Version)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, ErrorResponse{Error: err.Error()})
+		return
+	}
+	position, err := h.portfolios(c).PutPosition(pms.Position{
+		PortfolioID: c.Param("id"),
+		Symbol:      c.Param("symbol"),
+		Quantity:    req.Quantity,
+		AvgPrice:    req.AvgPrice,
+	}, expected)
+	if errors.Is(err, pms.ErrVersionConflict) {
+		setETag(c, position.Version)
+		c.JSON(http.StatusConflict, position)
+		return
+	}
+	if err != nil {
+		storeError(c, err)
+		return
+	}
+	setETag(c, position.Version)
+	c.JSON(writeStatus(expected), position)
+}
+
+// @Summary List the fill history of a portfolio
+// @Tags portfolios
+// @Produce json
+// @Param id path string true "Portfolio id"
+// @Success 200 {array} pms.Fill
+// @Failure 404 {object} ErrorResponse
+// @Router /v2/portfolios/{id}/fills [get]
+func (h *portfolioV2Handler) listFills(c *gin.Context) {
+	fills, err := h.portfolios(c).Fills(c.Param("id"))
+	if err != nil {
+		storeError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, fills)
+}
+
+// @Summary List the funding and interest payments of a portfolio
+// @Description Payments booked from the exchange income and interest histories, ordered by time.
+// @Description Amounts are positive when received and negative when paid.
+// @Tags portfolios
+// @Produce json
+// @Param id path string true "Portfolio id"
+// @Success 200 {array} pms.CashFlow
+// @Failure 404 {object} ErrorResponse
+// @Router /v2/portfolios/{id}/cashflows [get]
+func (h *portfolioV2Handler) listCashFlows(c *gin.Context) {
+	flows, err := h.portfolios(c).CashFlows(c.Param("id"))
+	if err != nil {
+		storeError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, flows)
+}
+
+// @Summary Export a portfolio
+// @Description JSON exports carry the portfolio, its positions and its fills. CSV exports carry one dataset.
+// @Tags portfolios
+// @Produce json,text/csv
+// @Param id path string true "Portfolio id"
+// @Param format query string false "json (default) or csv"
+// @Param dataset query string false "positions or fills, required for csv"
+// @Success 200 {object} pms.Bundle
+// @Failure 400 {object} ErrorResponse
+// @Failure 404 {object} ErrorResponse
+// @Router /v2/portfolios/{id}/export [get]
+func (h *portfolioV2Handler) exportPortfolio(c *gin.Context) {
+	format, dataset, err := transferParams(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, ErrorResponse{Error: err.Error()})
+		return
+	}
+	bundle, err := pms.Export(h.portfolios(c), c.Param("id"))
+	if err != nil {
+		storeError(c, err)
+		return
+	}
+	if format == pms.FormatJSON {
+		c.JSON(http.StatusOK, bundle)
+		return
+	}
+	c.Header("Content-Disposition", fmt.Sprintf(`attachment; filename="%s-%s.csv"`, bundle.Portfolio.ID, dataset))
+	c.Status(http.StatusOK)
+	c.Writer.Header().Set("Content-Type", "text/csv; charset=utf-8")
+	if err := pms.WriteCSV(c.Writer, bundle, dataset); err != nil {
+		c.Error(err)
+	}
+}
+
+// @Summary Import positions and fills into a portfolio
+// @Description Every row is validated first and nothing is written unless all rows are valid.
+// @Description Rejected rows are reported with their row number and a 422 status.
+// @Description Imported positions replace existing ones; fills with a recorded id are rejected.
+// @Tags portfolios
+// @Accept json,text/csv
+// @Produce json
+// @Param id path string true "Portfolio id"
+// @Param format query string false "json (default) or csv"
+// @Param dataset query string false "positions or fills, required for csv"
+// @Param dry_run query bool false "Validate without writing"
+// @Param bundle body pms.Bundle true "Export of a portfolio, or CSV rows"
+// @Success 200 {object} pms.ImportResult
+// @Failure 400 {object} ErrorResponse
+// @Failure 404 {object} ErrorResponse
+// @Failure 422 {object} pms.ImportResult "Rejected rows"
+// @Router /v2/portfolios/{id}/import [post]
+func (h *portfolioV2Handler) importPortfolio(c *gin.Context) {
+	format, dataset, err := transferParams(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, ErrorResponse{Error: err.Error()})
+		return
+	}
+	dryRun, err := strconv.ParseBool(c.DefaultQuery("dry_run", "false"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, ErrorResponse{Error: "dry_run must be a boolean"})
+		return
+	}
+	var batch pms.Batch
+	if format == pms.FormatJSON {
+		batch, err = pms.ReadJSON(c.Request.Body)
+	} else {
+		batch, err = pms.ReadCSV(c.Request.Body, dataset)
+	}
+	if err != nil {
+		storeError(c, err)
+		return
+	}
+	result, err := pms.Import(h.portfolios(c), c.Param("id"), batch, dryRun)
+	if err != nil {
+		storeError(c, err)
+		return
+	}
+	if len(result.Errors) > 0 {
+		c.JSON(http.StatusUnprocessableEntity, result)
+		return
+	}
+	c.JSON(http.StatusOK, result)
+}
+
+// @Summary List the open tax lots of a portfolio
+// @Description Lots are built by replaying the fill history; short lots have a negative quantity
+// @Tags portfolios
+// @Produce json
+// @Param id path string true "Portfolio id"
+// @Param method query string false "fifo, lifo or average, default: the cost method of the portfolio"
+// @Success 200 {array} pms.Lot
+// @Failure 400 {object} ErrorResponse
+// @Failure 404 {object} ErrorResponse
+// @Router /v2/portfolios/{id}/lots [get]
+func (h *portfolioV2Handler) listLots(c *gin.Context) {
+	_, ledger, _, err := h.ledger(c)
+	if err != nil {
+		storeError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, ledger.Lots())
+}
+
+// @Summary Report realized PnL
+// @Description Realized gains and losses per period (UTC) for tax purposes, matched under the selected cost method.
+// @Description Amounts are in the base currency of the portfolio, converted at current rates.
+// @Description Fees paid in the quote asset are part of the PnL, those paid in the base asset come out of the quantity traded; fees paid in other assets, such as BNB, are deducted in net_pnl.
+// @Description Funding payments and margin interest are added to net_pnl when paid.
+// @Tags portfolios
+// @Produce json
+// @Param id path string true "Portfolio id"
+// @Param method query string false "fifo, lifo or average, default: the cost method of the portfolio"
+// @Param period query string false "month, quarter or year (default)"
+// @Param from query string false "First day included, YYYY-MM-DD or RFC 3339"
+// @Param to query string false "First day excluded, YYYY-MM-DD or RFC 3339"
+// @Success 200 {object} pms.RealizedReport
+// @Failure 400 {object} ErrorResponse
+// @Failure 404 {object} ErrorResponse
+// @Failure 503 {object} ErrorResponse "No rate to the base currency"
+// @Router /v2/portfolios/{id}/realized [get]
+func (h *portfolioV2Handler) getRealized(c *gin.Context) {
+	period, err := pms.ParsePeriod(c.Query("period"))
+	if err != nil {
+		storeError(c, err)
+		return
+	}
+	from, err := parseDate(c.Query("from"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, ErrorResponse{Error: "from: " + err.Error()})
+		return
+	}
+	to, err := parseDate(c.Query("to"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, ErrorResponse{Error: "to: " + err.Error()})
+		return
+	}
+	portfolio, ledger, method, err := h.ledger(c)
+	if err != nil {
+		storeError(c, err)
+		return
+	}
+	realized, err := pms.ConvertRealized(ledger.Realized(), portfolio.Currency(), h.rates)
+	if err != nil {
+		storeError(c, err)
+		return
+	}
+	charges, err := pms.ConvertCharges(ledger.Charges(), portfolio.Currency(), h.rates)
+	if err != nil {
+		storeError(c, err)
+		return
+	}
+	flows, err := h.portfolios(c).CashFlows(portfolio.ID)
+	if err != nil {
+		storeError(c, err)
+		return
+	}
+	if flows, err = pms.ConvertCashFlows(flows, portfolio.Currency(), h.rates); err != nil {
+		storeError(c, err)
+		return
+	}
+	report := pms.Summarize(method, period, realized, charges, flows, from, to)
+	report.Currency = portfolio.Currency()
+	c.JSON(http.StatusOK, report)
+}
+
+// @Summary Report fees
+// @Description Fees of the fills of a portfolio per day (UTC), by asset and in the base currency of the portfolio at the rates as of each fill.
+// @Tags portfolios
+// @Produce json
+// @Param id path string true "Portfolio id"
+// @Param from query string false "First day included, YYYY-MM-DD or RFC 3339"
+// @Param to query string false "First day excluded, YYYY-MM-DD or RFC 3339"
+// @Success 200 {object} pms.FeeReport
+// @Failure 400 {object} ErrorResponse
+// @Failure 404 {object} ErrorResponse
+// @Failure 503 {object} ErrorResponse "No rate to the base currency"
+// @Router /v2/portfolios/{id}/fees [get]
+func (h *portfolioV2Handler) getFees(c *gin.Context) {
+	from, err := parseDate(c.Query("from"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, ErrorResponse{Error: "from: " + err.Error()})
+		return
+	}
+	to, err := parseDate(c.Query("to"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, ErrorResponse{Error: "to: " + err.Error()})
+		return
+	}
+	portfolio, err := h.portfolios(c).Portfolio(c.Param("id"))
+	if err != nil {
+		storeError(c, err)
+		return
+	}
+	fills, err := h.portfolios(c).Fills(portfolio.ID)
+	if err != nil {
+		storeError(c, err)
+		return
+	}
+	report, err := pms.SummarizeFees(fills, portfolio.Currency(), h.rates, from, to)
+	if err != nil {
+		storeError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, report)
+}
+
+// @Summary Value a portfolio
+// @Description Market value, cost and unrealized PnL of every position in the base currency of the portfolio,
+// @Description converted with the latest traded prices. Positions without a rate are reported and left out of the totals.
+// @Description Option positions are priced with Black-76 at the implied volatility of the option marks and report their greeks,
+// @Description summed by underlying asset with the holdings of the asset netted into delta.
+// @Tags portfolios
+// @Produce json
+// @Param id path string true "Portfolio id"
+// @Success 200 {object} pms.Valuation
+// @Failure 404 {object} ErrorResponse
+// @Router /v2/portfolios/{id}/valuation [get]
+func (h *portfolioV2Handler) getValuation(c *gin.Context) {
+	portfolio, err := h.portfolios(c).Portfolio(c.Param("id"))
+	if err != nil {
+		storeError(c, err)
+		return
+	}
+	positions, err := h.portfolios(c).Positions(portfolio.ID)
+	if err != nil {
+		storeError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, pms.Value(portfolio, positions, h.rates))
+}
+
+// @Summary Simulate the risk of a portfolio
+// @Description Bootstraps recorded returns of the assets held to estimate the loss distribution over the horizon
+// @Description at the current weights: VaR, CVaR and drawdowns, in the base currency of the portfolio.
+// @Description Positions are grouped by base asset; assets without a valuation or return history are excluded and reported.
+// @Tags portfolios
+// @Produce json
+// @Param id path string true "Portfolio id"
+// @Param horizon query int false "Periods simulated, default 24"
+// @Param paths query int false "Simulated paths, default 10000"
+// @Param confidence query number false "VaR confidence level, default 0.99"
+// @Param block_size query int false "Consecutive periods drawn together, default 1"
+// @Param seed query int false "Seed reproducing a previous simulation, random by default"
+// @Success 200 {object} risk.PortfolioReport
+// @Failure 400 {object} ErrorResponse
+// @Failure 404 {object} ErrorResponse
+// @Failure 503 {object} ErrorResponse "No return history"
+// @Router /v2/portfolios/{id}/risk [get]
+func (h *portfolioV2Handler) getRisk(c *gin.Context) {
+	if h.history == nil {
+		c.JSON(http.StatusServiceUnavailable, ErrorResponse{Error: "risk simulation is not configured"})
+		return
+	}
+	var opts risk.Options
+	for _, param := range []struct {
+		name  string
+		value any
+	}{
+		{"horizon", &opts.Horizon},
+		{"paths", &opts.Paths},
+		{"confidence", &opts.Confidence},
+		{"block_size", &opts.BlockSize},
+		{"seed", &opts.Seed},
+	} {
+		if err := queryNumber(c, param.name, param.value); err != nil {
+			c.JSON(http.StatusBadRequest, ErrorResponse{Error: err.Error()})
+			return
+		}
+	}
+	portfolio, err := h.portfolios(c).Portfolio(c.Param("id"))
+	if err != nil {
+		storeError(c, err)
+		return
+	}
+	positions, err := h.portfolios(c).Positions(portfolio.ID)
+	if err != nil {
+		storeError(c, err)
+		return
+	}
+	report, err := risk.SimulatePortfolio(pms.Value(portfolio, positions, h.rates), h.history, opts)
+	if err != nil {
+		storeError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, report)
+}
+
+// @Summary List FX rates
+// @Description Latest prices the conversion service values portfolios with
+// @Tags fx
+// @Produce json
+// @Success 200 {array} fx.Quote
+// @Router /v2/fx/rates [get]
+func (h *portfolioV2Handler) listRates(c *gin.Context) {
+	c.JSON(http.StatusOK, h.rates.Quotes())
+}
+
+// ledger replays the fills of the requested portfolio under the method of
+// the query, or the one of the portfolio
+func (h *portfolioV2Handler) ledger(c *gin.Context) (pms.Portfolio, *pms.Ledger, pms.CostMethod, error) {
+	portfolio, err := h.portfolios(c).Portfolio(c.Param("id"))
+	if err != nil {
+		return portfolio, nil, "", err
+	}
+	method, err := pms.ParseCostMethod(c.DefaultQuery("method", string(portfolio.CostMethod)))
+	if err != nil {
+		return portfolio, nil, "", err
+	}
+	fills, err := h.portfolios(c).Fills(portfolio.ID)
+	if err != nil {
+		return portfolio, nil, "", err
+	}
+	return portfolio, pms.BuildLedger(method, fills), method, nil
+}
+
+// queryNumber parses an optional numeric query parameter into an int,
+// int64 or float64, leaving it unchanged when absent
+func queryNumber(c *gin.Context, name string, value any) error {
+	raw := c.Query(name)
+	if raw == "" {
+		return nil
+	}
+	var err error
+	switch v := value.(type) {
+	case *int:
+		*v, err = strconv.Atoi(raw)
+	case *int64:
+		*v, err = strconv.ParseInt(raw, 10, 64)
+	case *float64:
+		*v, err = strconv.ParseFloat(raw, 64)
+	}
+	if err != nil {
+		return fmt.Errorf("%s must be a number", name)
+	}
+	return nil
+}
+
+// parseDate reads an optional YYYY-MM-DD or RFC 3339 time
+func parseDate(value string) (time.Time, error) {
+	if value == "" {
+		return time.Time{}, nil
+	}
+	if t, err := time.Parse(time.DateOnly, value); err == nil {
+		return t, nil
+	}
+	return time.Parse(time.RFC3339, value)
+}
+
+// transferParams reads the format and dataset of an import or export
+func transferParams(c *gin.Context) (pms.Format, pms.Dataset, error) {
+	format, err := pms.ParseFormat(c.Query("format"))
+	if err != nil {
+		return format, "", err
+	}
+	if format == pms.FormatJSON {
+		return format, "", nil
+	}
+	dataset, err := pms.ParseDataset(c.Query("dataset"))
+	return format, dataset, err
+}
+
+// expectedVersion returns the version a write is based on: the If-Match
+// header when present, the version of the body otherwise
+func expectedVersion(c *gin.Context, bodyVersion int64) (int64, error) {
+	header := strings.TrimSpace(c.GetHeader(HeaderIfMatch))
+	if header == "" {
+		if bodyVersion < 0 {
+			return 0, fmt.Errorf("version cannot be negative")
+		}
+		return bodyVersion, nil
+	}
+	return parseETag(header)
+}
+
+func setETag(c *gin.Context, version int64) {
+	c.Header(HeaderETag, strconv.Quote(strconv.FormatInt(version, 10)))
+}
+
+// parseETag reads a version ETag, accepting weak and unquoted forms
+func parseETag(tag string) (int64, error) {
+	value := strings.Trim(strings.TrimPrefix(tag, "W/"), `"`)
+	version, err := strconv.ParseInt(value, 10, 64)
+	if err != nil || version < 1 {
+		return 0, fmt.Errorf("invalid %s %q", HeaderIfMatch, tag)
+	}
+	return version, nil
+}
+
+// writeStatus is 201 for writes that created the resource
+func writeStatus(expected int64) int {
+	if expected == 0 {
+		return http.StatusCreated
+	}
+	return http.StatusOK
+}
+
+func storeError(c *gin.Context, err error) {
+	status := http.StatusInternalServerError
+	switch {
+	case errors.Is(err, pms.ErrInvalid), errors.Is(err, risk.ErrInvalid):
+		status = http.StatusBadRequest
+	case errors.Is(err, pms.ErrNotFound):
+		status = http.StatusNotFound
+	case errors.Is(err, pms.ErrVersionConflict):
+		status = http.StatusConflict
+	case errors.Is(err, fx.ErrNoRate), errors.Is(err, risk.ErrNoHistory):
+		status = http.StatusServiceUnavailable
+	case errors.Is(err, pms.ErrNoHistory):
+		status = http.StatusNotImplemented
+	}
+	c.JSON(status, ErrorResponse{Error: err.Error()})
+}
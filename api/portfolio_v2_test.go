@@ -0,0 +1,377 @@
+package api
+
+import (
+	"encoding/json"
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/BullionBear/sequex/internal/fx"
+	"github.com/BullionBear/sequex/internal/pms"
+	"github.com/BullionBear/sequex/internal/risk"
+	"github.com/gin-gonic/gin"
+)
+
+func put(engine *gin.Engine, path, body, ifMatch string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(http.MethodPut, path, strings.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	if ifMatch != "" {
+		req.Header.Set(HeaderIfMatch, ifMatch)
+	}
+	w := httptest.NewRecorder()
+	engine.ServeHTTP(w, req)
+	return w
+}
+
+func TestPortfolioOptimisticConcurrency(t *testing.T) {
+	engine := newTestRouter()
+
+	w := put(engine, "/api/v2/portfolios/main", `{"name":"Main"}`, "")
+	if w.Code != http.StatusCreated || w.Header().Get(HeaderETag) != `"1"` {
+		t.Fatalf("create: status %d, etag %q", w.Code, w.Header().Get(HeaderETag))
+	}
+
+	w = get(engine, "/api/v2/portfolios/main")
+	etag := w.Header().Get(HeaderETag)
+	if w.Code != http.StatusOK || etag != `"1"` {
+		t.Fatalf("get: status %d, etag %q", w.Code, etag)
+	}
+
+	// Two clients update from the same read; the second one must not clobber the first
+	if w = put(engine, "/api/v2/portfolios/main", `{"name":"First"}`, etag); w.Code != http.StatusOK {
+		t.Fatalf("first update: status %d", w.Code)
+	}
+	w = put(engine, "/api/v2/portfolios/main", `{"name":"Second"}`, etag)
+	if w.Code != http.StatusConflict || w.Header().Get(HeaderETag) != `"2"` {
+		t.Fatalf("second update: status %d, etag %q", w.Code, w.Header().Get(HeaderETag))
+	}
+	var current pms.Portfolio
+	if err := json.Unmarshal(w.Body.Bytes(), &current); err != nil || current.Name != "First" || current.Version != 2 {
+		t.Errorf("conflict body = %+v, %v", current, err)
+	}
+
+	// Without If-Match the version in the body is used; omitting it means create
+	if w = put(engine, "/api/v2/portfolios/main", `{"name":"Blind"}`, ""); w.Code != http.StatusConflict {
+		t.Errorf("blind overwrite: status %d", w.Code)
+	}
+	if w = put(engine, "/api/v2/portfolios/main", `{"name":"Body","version":2}`, ""); w.Code != http.StatusOK {
+		t.Errorf("body version: status %d", w.Code)
+	}
+	if w = put(engine, "/api/v2/portfolios/main", `{"name":"Bad"}`, "abc"); w.Code != http.StatusBadRequest {
+		t.Errorf("invalid If-Match: status %d", w.Code)
+	}
+	if w = put(engine, "/api/v2/portfolios/missing", `{"name":"Missing"}`, `"4"`); w.Code != http.StatusNotFound {
+		t.Errorf("update of missing portfolio: status %d", w.Code)
+	}
+}
+
+func TestPositionOptimisticConcurrency(t *testing.T) {
+	engine := newTestRouter()
+	if w := put(engine, "/api/v2/portfolios/main", `{"name":"Main"}`, ""); w.Code != http.StatusCreated {
+		t.Fatalf("create portfolio: status %d", w.Code)
+	}
+
+	w := put(engine, "/api/v2/portfolios/main/positions/BTC-USDT", `{"quantity":1,"avg_price":60000}`, "")
+	if w.Code != http.StatusCreated {
+		t.Fatalf("create position: status %d", w.Code)
+	}
+	if w = put(engine, "/api/v2/portfolios/main/positions/BTC-USDT", `{"quantity":2,"avg_price":61000}`, `W/"1"`); w.Code != http.StatusOK {
+		t.Fatalf("update position: status %d", w.Code)
+	}
+	if w = put(engine, "/api/v2/portfolios/main/positions/BTC-USDT", `{"quantity":3}`, `"1"`); w.Code != http.StatusConflict {
+		t.Errorf("stale update: status %d", w.Code)
+	}
+
+	w = get(engine, "/api/v2/portfolios/main/positions")
+	var positions []pms.Position
+	if err := json.Unmarshal(w.Body.Bytes(), &positions); err != nil || len(positions) != 1 || positions[0].Version != 2 {
+		t.Errorf("positions = %+v, %v", positions, err)
+	}
+	if w = get(engine, "/api/v2/portfolios/main/positions/BTC-USDT"); w.Header().Get(HeaderETag) != `"2"` {
+		t.Errorf("position etag = %q", w.Header().Get(HeaderETag))
+	}
+	if w = put(engine, "/api/v2/portfolios/main/positions/BTCUSDT", `{"quantity":1}`, ""); w.Code != http.StatusBadRequest {
+		t.Errorf("invalid symbol: status %d", w.Code)
+	}
+}
+
+func post(engine *gin.Engine, path, contentType, body string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(http.MethodPost, path, strings.NewReader(body))
+	req.Header.Set("Content-Type", contentType)
+	w := httptest.NewRecorder()
+	engine.ServeHTTP(w, req)
+	return w
+}
+
+func TestPortfolioImportExport(t *testing.T) {
+	engine := newTestRouter()
+	if w := put(engine, "/api/v2/portfolios/main", `{"name":"Main"}`, ""); w.Code != http.StatusCreated {
+		t.Fatalf("create portfolio: status %d", w.Code)
+	}
+
+	fills := "id,symbol,side,quantity,price,timestamp\n" +
+		"t1,BTC-USDT,BUY,0.5,60000,1717000000000\n" +
+		"t2,BTC-USDT,SELL,-1,61000,1717000000001\n"
+	w := post(engine, "/api/v2/portfolios/main/import?format=csv&dataset=fills", "text/csv", fills)
+	var result pms.ImportResult
+	if err := json.Unmarshal(w.Body.Bytes(), &result); err != nil {
+		t.Fatal(err)
+	}
+	if w.Code != http.StatusUnprocessableEntity || len(result.Errors) != 1 || result.Errors[0].Row != 3 {
+		t.Fatalf("invalid import: status %d, result %+v", w.Code, result)
+	}
+
+	fills = strings.Replace(fills, "-1", "0.1", 1)
+	if w = post(engine, "/api/v2/portfolios/main/import?format=csv&dataset=fills&dry_run=true", "text/csv", fills); w.Code != http.StatusOK {
+		t.Fatalf("dry run: status %d, body %s", w.Code, w.Body)
+	}
+	if w = get(engine, "/api/v2/portfolios/main/fills"); w.Body.String() != "[]" {
+		t.Fatalf("dry run imported fills: %s", w.Body)
+	}
+	if w = post(engine, "/api/v2/portfolios/main/import?format=csv&dataset=fills", "text/csv", fills); w.Code != http.StatusOK {
+		t.Fatalf("import: status %d, body %s", w.Code, w.Body)
+	}
+
+	w = get(engine, "/api/v2/portfolios/main/export?format=csv&dataset=fills")
+	if w.Code != http.StatusOK || !strings.HasPrefix(w.Header().Get("Content-Type"), "text/csv") {
+		t.Fatalf("csv export: status %d, content type %q", w.Code, w.Header().Get("Content-Type"))
+	}
+	if lines := strings.Count(w.Body.String(), "\n"); lines != 3 {
+		t.Errorf("csv export has %d lines:\n%s", lines, w.Body)
+	}
+
+	w = get(engine, "/api/v2/portfolios/main/export")
+	var bundle pms.Bundle
+	if err := json.Unmarshal(w.Body.Bytes(), &bundle); err != nil || len(bundle.Fills) != 2 || bundle.Portfolio.ID != "main" {
+		t.Errorf("json export = %+v, %v", bundle, err)
+	}
+	if w = get(engine, "/api/v2/portfolios/main/export?format=csv"); w.Code != http.StatusBadRequest {
+		t.Errorf("csv export without dataset: status %d", w.Code)
+	}
+}
+
+func TestRealizedReport(t *testing.T) {
+	engine := newTestRouter()
+	if w := put(engine, "/api/v2/portfolios/main", `{"name":"Main","cost_method":"lifo"}`, ""); w.Code != http.StatusCreated {
+		t.Fatalf("create portfolio: status %d", w.Code)
+	}
+	fills := "id,symbol,side,quantity,price,timestamp\n" +
+		"b1,BTC-USDT,BUY,1,100,2024-01-10T00:00:00Z\n" +
+		"b2,BTC-USDT,BUY,1,200,2024-02-10T00:00:00Z\n" +
+		"s1,BTC-USDT,SELL,1,300,2024-05-10T00:00:00Z\n"
+	if w := post(engine, "/api/v2/portfolios/main/import?format=csv&dataset=fills", "text/csv", fills); w.Code != http.StatusOK {
+		t.Fatalf("import: status %d, body %s", w.Code, w.Body)
+	}
+
+	var report pms.RealizedReport
+	w := get(engine, "/api/v2/portfolios/main/realized?period=quarter")
+	if err := json.Unmarshal(w.Body.Bytes(), &report); err != nil {
+		t.Fatal(err)
+	}
+	if report.Method != pms.CostLIFO || len(report.Periods) != 1 || report.Periods[0].Period != "2024-Q2" || report.Total.PnL != 100 {
+		t.Errorf("lifo report = %+v", report)
+	}
+
+	w = get(engine, "/api/v2/portfolios/main/realized?method=fifo&from=2024-01-01&to=2025-01-01")
+	if err := json.Unmarshal(w.Body.Bytes(), &report); err != nil || report.Total.PnL != 200 {
+		t.Errorf("fifo report = %+v, %v", report, err)
+	}
+	if w = get(engine, "/api/v2/portfolios/main/realized?to=2024-05-01"); !strings.Contains(w.Body.String(), `"periods":[]`) {
+		t.Errorf("report before the sale: %s", w.Body)
+	}
+
+	var lots []pms.Lot
+	w = get(engine, "/api/v2/portfolios/main/lots")
+	if err := json.Unmarshal(w.Body.Bytes(), &lots); err != nil || len(lots) != 1 || lots[0].FillID != "b1" {
+		t.Errorf("lots = %+v, %v", lots, err)
+	}
+	if w = get(engine, "/api/v2/portfolios/main/realized?method=hifo"); w.Code != http.StatusBadRequest {
+		t.Errorf("unknown method: status %d", w.Code)
+	}
+}
+
+func TestPortfolioFees(t *testing.T) {
+	engine := newTestRouter()
+	put(engine, "/api/v2/portfolios/main", `{"name":"Main"}`, "")
+	// Fees are converted at the rates as of their fills, kept for a week
+	now := time.Now().UTC()
+	fills := "id,symbol,side,quantity,price,fee,fee_asset,timestamp\n" +
+		"b1,ETH-USDT,BUY,1,2000,1,USDT," + now.Add(-47*time.Hour).Format(time.RFC3339) + "\n" +
+		"s1,ETH-USDT,SELL,1,2100,0.0001,BTC," + now.Add(-23*time.Hour).Format(time.RFC3339) + "\n"
+	if w := post(engine, "/api/v2/portfolios/main/import?format=csv&dataset=fills", "text/csv", fills); w.Code != http.StatusOK {
+		t.Fatalf("import: status %d, body %s", w.Code, w.Body)
+	}
+
+	// The USDT fee is part of the cost, the BTC fee is worth 6 USDT
+	var report pms.RealizedReport
+	w := get(engine, "/api/v2/portfolios/main/realized")
+	if err := json.Unmarshal(w.Body.Bytes(), &report); err != nil || report.Total.PnL != 99 || report.Total.Fees != 6 || report.Total.NetPnL != 93 {
+		t.Errorf("realized = %+v, %v", report.Total, err)
+	}
+
+	var fees pms.FeeReport
+	w = get(engine, "/api/v2/portfolios/main/fees?from="+now.Add(-30*time.Hour).Format(time.RFC3339))
+	if err := json.Unmarshal(w.Body.Bytes(), &fees); err != nil || len(fees.Days) != 1 || fees.Days[0].ByAsset["BTC"] != 0.0001 || fees.Total.Total != 6 {
+		t.Errorf("fees = %+v, %v", fees, err)
+	}
+	if w = get(engine, "/api/v2/portfolios/main/fees?to=tomorrow"); w.Code != http.StatusBadRequest {
+		t.Errorf("invalid to: status %d", w.Code)
+	}
+
+	put(engine, "/api/v2/portfolios/jp", `{"name":"JP","base_currency":"jpy"}`, "")
+	post(engine, "/api/v2/portfolios/jp/import?format=csv&dataset=fills", "text/csv", fills)
+	if w = get(engine, "/api/v2/portfolios/jp/fees"); w.Code != http.StatusServiceUnavailable {
+		t.Errorf("fees without a rate: status %d", w.Code)
+	}
+}
+
+func TestPortfolioCashFlows(t *testing.T) {
+	store := pms.NewMemoryStore()
+	engine := newTestRouterWith(store)
+	put(engine, "/api/v2/portfolios/main", `{"name":"Main"}`, "")
+	fills := "id,symbol,side,quantity,price,timestamp\n" +
+		"b1,BTC-USDT,BUY,1,100,2024-06-01T00:00:00Z\n" +
+		"s1,BTC-USDT,SELL,1,200,2024-06-05T00:00:00Z\n"
+	if w := post(engine, "/api/v2/portfolios/main/import?format=csv&dataset=fills", "text/csv", fills); w.Code != http.StatusOK {
+		t.Fatalf("import: status %d, body %s", w.Code, w.Body)
+	}
+	day := time.Date(2024, 6, 2, 0, 0, 0, 0, time.UTC).UnixMilli()
+	if _, err := store.AddCashFlows("main", []pms.CashFlow{
+		{ID: "funding-1", Kind: pms.CashFlowFunding, Symbol: "BTC-USDT", Asset: "USDT", Amount: -4, Timestamp: day},
+		{ID: "interest-1", Kind: pms.CashFlowInterest, Asset: "BTC", Amount: -0.00001, Timestamp: day},
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	var flows []pms.CashFlow
+	w := get(engine, "/api/v2/portfolios/main/cashflows")
+	if err := json.Unmarshal(w.Body.Bytes(), &flows); err != nil || len(flows) != 2 {
+		t.Errorf("cash flows = %+v, %v", flows, err)
+	}
+
+	// The BTC interest is worth 0.6 USDT
+	var report pms.RealizedReport
+	w = get(engine, "/api/v2/portfolios/main/realized")
+	if err := json.Unmarshal(w.Body.Bytes(), &report); err != nil || report.Total.PnL != 100 || report.Total.Funding != -4 || math.Abs(report.Total.Interest+0.6) > 1e-9 || math.Abs(report.Total.NetPnL-95.4) > 1e-9 {
+		t.Errorf("realized = %+v, %v", report.Total, err)
+	}
+	if w = get(engine, "/api/v2/portfolios/other/cashflows"); w.Code != http.StatusNotFound {
+		t.Errorf("missing portfolio: status %d", w.Code)
+	}
+}
+
+func TestPortfolioValuation(t *testing.T) {
+	engine := newTestRouter()
+	if w := put(engine, "/api/v2/portfolios/eu", `{"name":"EU","base_currency":"eur"}`, ""); w.Code != http.StatusCreated {
+		t.Fatalf("create portfolio: status %d", w.Code)
+	}
+	for symbol, body := range map[string]string{
+		"BTC-USDT": `{"quantity":1,"avg_price":50000}`,
+		"EUR-USDT": `{"quantity":1000}`,
+		"SOL-USDT": `{"quantity":10,"avg_price":150}`,
+	} {
+		if w := put(engine, "/api/v2/portfolios/eu/positions/"+symbol, body, ""); w.Code != http.StatusCreated {
+			t.Fatalf("create %s: status %d", symbol, w.Code)
+		}
+	}
+
+	var v pms.Valuation
+	w := get(engine, "/api/v2/portfolios/eu/valuation")
+	if err := json.Unmarshal(w.Body.Bytes(), &v); err != nil {
+		t.Fatal(err)
+	}
+	// BTC at 60000 USDT and EUR at 1.25 USDT; SOL has no rate
+	if v.Currency != "EUR" || v.Complete || v.Value != 49000 || v.Cost != 40000 {
+		t.Errorf("valuation = %+v", v)
+	}
+
+	fills := "id,symbol,side,quantity,price,timestamp\n" +
+		"b1,SOL-USDT,BUY,1,100,1717000000000\n" +
+		"s1,SOL-USDT,SELL,1,125,1717000000001\n"
+	if w = post(engine, "/api/v2/portfolios/eu/import?format=csv&dataset=fills", "text/csv", fills); w.Code != http.StatusOK {
+		t.Fatalf("import: status %d, body %s", w.Code, w.Body)
+	}
+	var report pms.RealizedReport
+	w = get(engine, "/api/v2/portfolios/eu/realized")
+	if err := json.Unmarshal(w.Body.Bytes(), &report); err != nil || report.Currency != "EUR" || report.Total.PnL != 20 {
+		t.Errorf("realized = %+v, %v", report, err)
+	}
+
+	var quotes []fx.Quote
+	w = get(engine, "/api/v2/fx/rates")
+	if err := json.Unmarshal(w.Body.Bytes(), &quotes); err != nil || len(quotes) != 2 {
+		t.Errorf("rates = %+v, %v", quotes, err)
+	}
+}
+
+func TestPortfolioRisk(t *testing.T) {
+	engine := newTestRouter()
+	put(engine, "/api/v2/portfolios/main", `{"name":"Main"}`, "")
+	put(engine, "/api/v2/portfolios/main/positions/BTC-USDT", `{"quantity":1,"avg_price":50000}`, "")
+	put(engine, "/api/v2/portfolios/main/positions/EUR-USDT", `{"quantity":1000}`, "")
+
+	var report risk.PortfolioReport
+	w := get(engine, "/api/v2/portfolios/main/risk?horizon=4&paths=500&seed=3")
+	if err := json.Unmarshal(w.Body.Bytes(), &report); w.Code != http.StatusOK || err != nil {
+		t.Fatalf("status %d, body %s", w.Code, w.Body)
+	}
+	// EUR has no return history and is reported rather than simulated
+	if report.Value != 60000 || len(report.Exposures) != 1 || report.Complete || report.Seed != 3 || report.VaR <= 0 {
+		t.Errorf("report = %+v", report)
+	}
+	var again risk.PortfolioReport
+	json.Unmarshal(get(engine, "/api/v2/portfolios/main/risk?horizon=4&paths=500&seed=3").Body.Bytes(), &again)
+	if again.VaR != report.VaR || again.CVaR != report.CVaR {
+		t.Errorf("seeded simulations differ: %v and %v", report.VaR, again.VaR)
+	}
+
+	for path, status := range map[string]int{
+		"/api/v2/portfolios/main/risk?paths=x":        http.StatusBadRequest,
+		"/api/v2/portfolios/main/risk?confidence=1.5": http.StatusBadRequest,
+		"/api/v2/portfolios/missing/risk":             http.StatusNotFound,
+	} {
+		if w := get(engine, path); w.Code != status {
+			t.Errorf("%s: status %d, want %d", path, w.Code, status)
+		}
+	}
+}
+
+func TestPortfolioAsOf(t *testing.T) {
+	store, err := pms.OpenDurable(t.TempDir(), 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer store.Close()
+	engine := newTestRouterWith(store)
+	put(engine, "/api/v2/portfolios/main", `{"name":"Main"}`, "")
+	put(engine, "/api/v2/portfolios/main/positions/BTC-USDT", `{"quantity":1,"avg_price":50000}`, "")
+	if _, err := store.Snapshot("2024-06-01", testRates()); err != nil {
+		t.Fatal(err)
+	}
+	time.Sleep(2 * time.Millisecond)
+	asOf := time.Now().UTC().Format(time.RFC3339Nano)
+	time.Sleep(2 * time.Millisecond)
+	put(engine, "/api/v2/portfolios/main/positions/BTC-USDT", `{"quantity":3,"avg_price":50000,"version":1}`, "")
+
+	var h pms.Historical
+	w := get(engine, "/api/v2/portfolios/main?as_of="+asOf)
+	if err := json.Unmarshal(w.Body.Bytes(), &h); err != nil || w.Code != http.StatusOK {
+		t.Fatalf("as of: status %d, %v", w.Code, err)
+	}
+	// Valued at the 60000 USDT mark of the snapshot
+	if h.Snapshot != "2024-06-01" || len(h.Positions) != 1 || h.Positions[0].Quantity != 1 || h.Valuation == nil || h.Valuation.Value != 60000 {
+		t.Errorf("as of = %+v", h)
+	}
+	if w = get(engine, "/api/v2/portfolios/main?as_of=yesterday"); w.Code != http.StatusBadRequest {
+		t.Errorf("invalid as_of: status %d", w.Code)
+	}
+	if w = get(engine, "/api/v2/portfolios/main?as_of=2000-01-01"); w.Code != http.StatusNotFound {
+		t.Errorf("before the portfolio existed: status %d", w.Code)
+	}
+	if w = get(newTestRouter(), "/api/v2/portfolios/main?as_of=2000-01-01"); w.Code != http.StatusNotImplemented {
+		t.Errorf("memory store: status %d", w.Code)
+	}
+}
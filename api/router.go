@@ -0,0 +1,85 @@
+package api
+
+import (
+	"net/http"
+
+	"github.com/BullionBear/sequex/internal/execution"
+	"github.com/BullionBear/sequex/internal/fx"
+	"github.com/BullionBear/sequex/internal/pms"
+	"github.com/BullionBear/sequex/internal/risk"
+	"github.com/BullionBear/sequex/internal/tenant"
+	"github.com/gin-gonic/gin"
+	"github.com/swaggo/swag"
+)
+
+// API versions. Breaking changes go into a new version group so clients of
+// an existing group keep working until it is retired.
+const (
+	V1 = "v1"
+	V2 = "v2"
+)
+
+// BasePath is the prefix of every versioned route group
+const BasePath = "/api"
+
+// ErrorResponse is the body of failed requests
+type ErrorResponse struct {
+	Error string `json:"error"`
+}
+
+// Services are the backends the routes are served from
+type Services struct {
+	Nodes      NodeControl
+	Strategies StrategyControl // Pauses and resumes strategies through their guardian, nil disables it
+	Portfolios pms.Store
+	Rates      *fx.Rates
+	Risk       *risk.History    // Returns the risk simulations bootstrap, nil disables them
+	Executions *execution.Store // Order and fill history, nil disables it
+	// Tenants authenticate every request by API key and see only their own
+	// portfolios, executions and nodes. Nil serves every request unscoped.
+	Tenants *tenant.Registry
+	// Order and fill history of each tenant, keyed by tenant id. Executions
+	// holds the history of the deployment itself.
+	TenantExecutions map[string]*execution.Store
+}
+
+// Options configures the router
+type Options struct {
+	Deprecations map[string]Deprecation // Deprecated versions, keyed by version
+}
+
+// NewRouter registers every API version under /api/<version> and serves
+// the OpenAPI spec at /swagger/doc.json. Unversioned legacy paths are
+// served by v1.
+func NewRouter(engine *gin.Engine, services Services, opts Options) {
+	auth := Authenticate(services.Tenants)
+	NewNode(versionGroup(engine, V1, opts, auth))
+	v2 := versionGroup(engine, V2, opts, auth)
+	NewNodeV2(v2, services.Nodes)
+	NewGuardianV2(v2, services.Strategies)
+	NewPortfolioV2(v2, services.Portfolios, services.Rates, services.Risk)
+	NewExecutionV2(v2, services.Executions, services.TenantExecutions, services.Rates)
+	engine.GET("/swagger/doc.json", swaggerSpec)
+	engine.NoRoute(legacyRoutes(engine))
+}
+
+// versionGroup creates the route group of a version, announcing its
+// deprecation when configured and authenticating its requests
+func versionGroup(engine *gin.Engine, version string, opts Options, auth gin.HandlerFunc) *gin.RouterGroup {
+	group := engine.Group(BasePath + "/" + version)
+	if d, ok := opts.Deprecations[version]; ok {
+		group.Use(Deprecated(d))
+	}
+	group.Use(auth)
+	return group
+}
+
+// swaggerSpec serves the spec registered by the generated docs package
+func swaggerSpec(c *gin.Context) {
+	doc, err := swag.ReadDoc()
+	if err != nil {
+		c.JSON(http.StatusNotFound, ErrorResponse{Error: err.Error()})
+		return
+	}
+	c.Data(http.StatusOK, "application/json; charset=utf-8", []byte(doc))
+}
@@ -0,0 +1,185 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	_ "github.com/BullionBear/sequex/docs"
+	"github.com/BullionBear/sequex/internal/execution"
+	"github.com/BullionBear/sequex/internal/fx"
+	"github.com/BullionBear/sequex/internal/model/sqx"
+	"github.com/BullionBear/sequex/internal/pms"
+	"github.com/BullionBear/sequex/internal/risk"
+	"github.com/BullionBear/sequex/pkg/diagnostics"
+	"github.com/gin-gonic/gin"
+)
+
+type fakeNodeControl struct {
+	reports map[string]diagnostics.Report
+}
+
+func (f *fakeNodeControl) Diagnostics(ctx context.Context, subject string) (diagnostics.Report, error) {
+	report, ok := f.reports[subject]
+	if !ok {
+		return report, fmt.Errorf("%w: %s", ErrNodeUnavailable, subject)
+	}
+	return report, nil
+}
+
+func newTestRouter() *gin.Engine {
+	return newTestRouterWith(pms.NewMemoryStore())
+}
+
+// newTestRouterWith serves portfolios from store, for tests seeding history
+// the API cannot write
+func newTestRouterWith(portfolios pms.Store) *gin.Engine {
+	gin.SetMode(gin.TestMode)
+	engine := gin.New()
+	NewRouter(engine, Services{
+		Nodes: &fakeNodeControl{reports: map[string]diagnostics.Report{
+			"diag.feed": {Service: "feed", Goroutines: 12},
+		}},
+		Portfolios: portfolios,
+		Rates:      testRates(),
+		Risk:       testHistory(),
+		Executions: testExecutions(),
+	}, Options{Deprecations: map[string]Deprecation{
+		V1: {Since: time.Date(2026, 10, 1, 0, 0, 0, 0, time.UTC), Sunset: time.Date(2027, 4, 1, 0, 0, 0, 0, time.UTC), Successor: "/api/v2"},
+	}})
+	return engine
+}
+
+func testRates() *fx.Rates {
+	rates := fx.NewRates(fx.Options{History: 7 * 24 * time.Hour})
+	rates.Update("BTC", "USDT", 60000, time.Now().Add(-24*time.Hour))
+	rates.Update("BTC", "USDT", 60000, time.Now())
+	rates.SetStatic("EUR", "USDT", 1.25)
+	return rates
+}
+
+func testHistory() *risk.History {
+	history := risk.NewHistory(risk.HistoryOptions{Interval: time.Hour})
+	for i, price := range []float64{60000, 57000, 58000, 61000, 60000} {
+		history.Record("BTC", price, time.Unix(int64(i)*3600, 0))
+	}
+	return history
+}
+
+func testExecutions() *execution.Store {
+	store := execution.NewMemoryStore()
+	for i, status := range []sqx.OrderStatus{sqx.OrderStatusNew, sqx.OrderStatusPartiallyFilled, sqx.OrderStatusFilled} {
+		store.Add(sqx.ExecutionReport{
+			OrderId:         "1001",
+			ClientOrderId:   "grid-1",
+			ExecId:          fmt.Sprint(i),
+			Strategy:        "grid",
+			Exchange:        sqx.ExchangeBinance,
+			Symbol:          sqx.NewSymbol("BTC", "USDT"),
+			Side:            sqx.SideBuy,
+			Status:          status,
+			Quantity:        2,
+			LastPrice:       60000,
+			LastQuantity:    float64(min(i, 1)),
+			Commission:      0.00001 * float64(min(i, 1)),
+			CommissionAsset: "BTC",
+			Timestamp:       time.Date(2026, 10, 1, i, 0, 0, 0, time.UTC).UnixMilli(),
+		})
+	}
+	return store
+}
+
+func get(engine *gin.Engine, path string) *httptest.ResponseRecorder {
+	w := httptest.NewRecorder()
+	engine.ServeHTTP(w, httptest.NewRequest(http.MethodGet, path, nil))
+	return w
+}
+
+func TestVersionedRoutes(t *testing.T) {
+	engine := newTestRouter()
+
+	if w := get(engine, "/api/v1/nodes"); w.Code != http.StatusOK {
+		t.Errorf("v1 nodes: status %d", w.Code)
+	}
+
+	w := get(engine, "/api/v2/nodes/feed/diagnostics")
+	if w.Code != http.StatusOK {
+		t.Fatalf("v2 diagnostics: status %d", w.Code)
+	}
+	var report diagnostics.Report
+	if err := json.Unmarshal(w.Body.Bytes(), &report); err != nil || report.Goroutines != 12 {
+		t.Errorf("report = %+v, %v", report, err)
+	}
+
+	if w := get(engine, "/api/v2/nodes/index/diagnostics"); w.Code != http.StatusServiceUnavailable {
+		t.Errorf("unknown node: status %d", w.Code)
+	}
+	if w := get(engine, "/api/v2/nodes/a*b/diagnostics"); w.Code != http.StatusBadRequest {
+		t.Errorf("invalid node name: status %d", w.Code)
+	}
+}
+
+func TestSwaggerSpec(t *testing.T) {
+	w := get(newTestRouter(), "/swagger/doc.json")
+	if w.Code != http.StatusOK {
+		t.Fatalf("status %d", w.Code)
+	}
+	var spec struct {
+		BasePath string                     `json:"basePath"`
+		Paths    map[string]json.RawMessage `json:"paths"`
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &spec); err != nil {
+		t.Fatal(err)
+	}
+	if spec.BasePath != BasePath {
+		t.Errorf("basePath = %q", spec.BasePath)
+	}
+	for _, path := range []string{"/v1/nodes", "/v2/nodes/{name}/diagnostics", "/v2/orders", "/v2/fills", "/v2/fees", "/v2/portfolios/{id}/fees", "/v2/portfolios/{id}/cashflows"} {
+		if _, ok := spec.Paths[path]; !ok {
+			t.Errorf("spec is missing %s", path)
+		}
+	}
+}
+
+func TestDeprecationHeaders(t *testing.T) {
+	engine := newTestRouter()
+
+	w := get(engine, "/api/v1/nodes")
+	if got := w.Header().Get(HeaderDeprecation); got != "@1790812800" {
+		t.Errorf("Deprecation = %q", got)
+	}
+	if got := w.Header().Get(HeaderSunset); got != "Thu, 01 Apr 2027 00:00:00 GMT" {
+		t.Errorf("Sunset = %q", got)
+	}
+	if got := w.Header().Get(HeaderLink); got != `</api/v2>; rel="successor-version"` {
+		t.Errorf("Link = %q", got)
+	}
+
+	if w := get(engine, "/api/v2/nodes/feed/diagnostics"); w.Header().Get(HeaderDeprecation) != "" {
+		t.Error("v2 responses must not be marked deprecated")
+	}
+}
+
+func TestLegacyRoutes(t *testing.T) {
+	engine := newTestRouter()
+
+	for _, path := range []string{"/nodes", "/v1/nodes", "/node/feed"} {
+		w := get(engine, path)
+		if w.Code != http.StatusOK {
+			t.Errorf("%s: status %d", path, w.Code)
+		}
+		if w.Header().Get(HeaderDeprecation) == "" {
+			t.Errorf("%s: legacy path served without deprecation header", path)
+		}
+	}
+
+	for _, path := range []string{"/unknown", "/api/v3/nodes"} {
+		if w := get(engine, path); w.Code != http.StatusNotFound {
+			t.Errorf("%s: status %d", path, w.Code)
+		}
+	}
+}
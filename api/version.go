@@ -0,0 +1,84 @@
+package api
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Response headers announcing deprecated endpoints (RFC 9745 and RFC 8594)
+const (
+	HeaderDeprecation = "Deprecation"
+	HeaderSunset      = "Sunset"
+	HeaderLink        = "Link"
+)
+
+// Deprecation describes the retirement of an API version
+type Deprecation struct {
+	Since     time.Time // When the version was deprecated
+	Sunset    time.Time // When it stops being served, zero if not scheduled
+	Successor string    // Base path of the replacing version
+}
+
+// Deprecated returns a middleware adding deprecation headers to every response
+func Deprecated(d Deprecation) gin.HandlerFunc {
+	headers := newDeprecationHeaders(d)
+	return func(c *gin.Context) {
+		headers.apply(c.Writer.Header())
+		c.Next()
+	}
+}
+
+type deprecationHeaders struct {
+	deprecation string
+	sunset      string
+	link        string
+}
+
+func newDeprecationHeaders(d Deprecation) deprecationHeaders {
+	h := deprecationHeaders{deprecation: "true"}
+	if !d.Since.IsZero() {
+		h.deprecation = fmt.Sprintf("@%d", d.Since.Unix())
+	}
+	if !d.Sunset.IsZero() {
+		h.sunset = d.Sunset.UTC().Format(http.TimeFormat)
+	}
+	if d.Successor != "" {
+		h.link = fmt.Sprintf(`<%s>; rel="successor-version"`, d.Successor)
+	}
+	return h
+}
+
+func (h deprecationHeaders) apply(header http.Header) {
+	header.Set(HeaderDeprecation, h.deprecation)
+	if h.sunset != "" {
+		header.Set(HeaderSunset, h.sunset)
+	}
+	if h.link != "" {
+		header.Set(HeaderLink, h.link)
+	}
+}
+
+// legacyRoutes keeps clients of unversioned paths working: /nodes and
+// /v1/nodes are served as /api/v1/nodes with deprecation headers pointing to
+// the versioned path. Anything else not found gets a JSON 404.
+func legacyRoutes(engine *gin.Engine) gin.HandlerFunc {
+	headers := newDeprecationHeaders(Deprecation{Successor: BasePath + "/" + V1})
+	return func(c *gin.Context) {
+		path := c.Request.URL.Path
+		// Rewritten requests come back here when the versioned route does not exist either
+		if strings.HasPrefix(path, BasePath+"/") {
+			c.JSON(http.StatusNotFound, ErrorResponse{Error: "route not found"})
+			return
+		}
+		if !strings.HasPrefix(path, "/"+V1+"/") {
+			path = "/" + V1 + path
+		}
+		headers.apply(c.Writer.Header())
+		c.Request.URL.Path = BasePath + path
+		engine.HandleContext(c)
+	}
+}
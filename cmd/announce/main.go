@@ -0,0 +1,151 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"net/http"
+	"os"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/BullionBear/sequex/env"
+	"github.com/BullionBear/sequex/internal/announce"
+	"github.com/BullionBear/sequex/internal/config"
+	"github.com/BullionBear/sequex/internal/sysevent"
+	"github.com/BullionBear/sequex/pkg/diagnostics"
+	"github.com/BullionBear/sequex/pkg/exchange/binance"
+	"github.com/BullionBear/sequex/pkg/logger"
+	"github.com/BullionBear/sequex/pkg/shutdown"
+	"github.com/nats-io/nats.go"
+)
+
+// runAnnounce executes the main announcement collector logic
+func runAnnounce(configFile string) {
+	logger.Log.Info().
+		Str("version", env.Version).
+		Str("buildTime", env.BuildTime).
+		Str("commitHash", env.CommitHash).
+		Msg("Announcement collector started")
+
+	cfg, err := config.LoadAnnounceConfig(configFile)
+	if err != nil {
+		logger.Log.Error().Err(err).Msg("Failed to load config")
+		os.Exit(1)
+	}
+	opts, err := cfg.BinanceOptions()
+	if err != nil {
+		logger.Log.Error().Err(err).Msg("Invalid configuration")
+		os.Exit(1)
+	}
+
+	sources := []announce.Source{announce.BinanceArticles(&http.Client{Timeout: 10 * time.Second}, opts)}
+	if cfg.Status {
+		sources = append(sources, announce.BinanceStatus(binance.NewClient(&binance.Config{BaseURL: binance.MainnetBaseUrl})))
+	}
+	collector := announce.NewCollector(sources...)
+
+	shutdown := shutdown.NewShutdown(logger.Log)
+
+	natsConn, err := nats.Connect(cfg.NATS.URIs)
+	if err != nil {
+		logger.Log.Error().Err(err).Msg("Failed to connect to NATS")
+		os.Exit(1)
+	}
+	defer natsConn.Close()
+
+	_, stopDiagnostics, err := diagnostics.Start(cfg.Diagnostics.Options("announce"), natsConn, logger.Log)
+	if err != nil {
+		logger.Log.Error().Err(err).Msg("Failed to start diagnostics")
+		os.Exit(1)
+	}
+	shutdown.HookShutdownCallback("diagnostics", stopDiagnostics, 10*time.Second)
+	sysEvents, err := cfg.Diagnostics.EventPublisher(natsConn, "announce", logger.Log)
+	if err != nil {
+		logger.Log.Error().Err(err).Msg("Failed to create system event publisher")
+		os.Exit(1)
+	}
+	defer sysEvents.Close()
+	sysEvents.WatchConn(natsConn)
+	js, err := natsConn.JetStream()
+	if err != nil {
+		logger.Log.Error().Err(err).Msg("Failed to create JetStream context")
+		os.Exit(1)
+	}
+	if _, err := js.StreamInfo(cfg.NATS.Stream); err != nil {
+		logger.Log.Error().Err(err).Msg("Failed to get stream info")
+		os.Exit(1)
+	}
+
+	// The message id lets JetStream drop the announcements republished
+	// after a restart within its duplicate window
+	emit := func(a announce.Announcement) error {
+		data, err := json.Marshal(a)
+		if err != nil {
+			return fmt.Errorf("failed to marshal: %w", err)
+		}
+		subject := a.Subject(cfg.NATS.Subject)
+		if _, err := js.Publish(subject, data, nats.MsgId(a.Exchange+"."+a.ID)); err != nil {
+			return fmt.Errorf("failed to publish: %w", err)
+		}
+		logger.Log.Info().
+			Str("subject", subject).
+			Str("title", a.Title).
+			Strs("assets", a.Assets).
+			Strs("symbols", a.Symbols).
+			Msg("Published announcement")
+		if e, ok := sysevent.AnnouncementEvent(a); ok {
+			sysEvents.Publish(e)
+		}
+		return nil
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		collector.Run(ctx, cfg.Interval(), emit, func(err error) {
+			logger.Log.Error().Err(err).Msg("Failed to collect announcements")
+		})
+	}()
+	logger.Log.Info().Dur("interval", cfg.Interval()).Bool("status", cfg.Status).Msg("Collecting announcements")
+	shutdown.HookShutdownCallback("collector", func() {
+		cancel()
+		wg.Wait()
+	}, 10*time.Second)
+
+	shutdown.WaitForShutdown(syscall.SIGINT, syscall.SIGTERM)
+	logger.Log.Info().Msg("Announcement collector command executed successfully!")
+}
+
+func main() {
+	var configFile string
+	flag.StringVar(&configFile, "c", "", "Configuration file path (required)")
+
+	flag.Usage = func() {
+		logger.Log.Info().Msg(`Announce polls the Binance announcements of new listings, delistings and
+maintenance, and optionally the system status, and publishes each new one
+as a structured event to NATS as <subject>.<exchange>.<kind>.
+
+Usage:
+  announce -c <config-file>
+
+Examples:
+  announce -c config/announce.json
+`)
+		flag.PrintDefaults()
+	}
+
+	flag.Parse()
+
+	if configFile == "" {
+		logger.Log.Error().Msg("config file path is required")
+		flag.Usage()
+		os.Exit(1)
+	}
+
+	runAnnounce(configFile)
+}
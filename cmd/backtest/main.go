@@ -0,0 +1,199 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"net/http"
+	"os"
+	"syscall"
+	"time"
+
+	"github.com/BullionBear/sequex/api"
+	"github.com/BullionBear/sequex/env"
+	"github.com/BullionBear/sequex/internal/backtest"
+	"github.com/BullionBear/sequex/internal/config"
+	"github.com/BullionBear/sequex/pkg/diagnostics"
+	"github.com/BullionBear/sequex/pkg/eventbus"
+	"github.com/BullionBear/sequex/pkg/logger"
+	"github.com/BullionBear/sequex/pkg/shutdown"
+	"github.com/gin-gonic/gin"
+	"github.com/nats-io/nats.go"
+)
+
+// runBacktest executes the main backtest job service logic
+func runBacktest(configFile string) {
+	logger.Log.Info().
+		Str("version", env.Version).
+		Str("buildTime", env.BuildTime).
+		Str("commitHash", env.CommitHash).
+		Msg("Backtest started")
+
+	cfg, err := config.LoadBacktestConfig(configFile)
+	if err != nil {
+		logger.Log.Error().Err(err).Msg("Failed to load config")
+		os.Exit(1)
+	}
+
+	shutdown := shutdown.NewShutdown(logger.Log)
+
+	natsConn, err := nats.Connect(cfg.NATS.URIs)
+	if err != nil {
+		logger.Log.Error().Err(err).Msg("Failed to connect to NATS")
+		os.Exit(1)
+	}
+	defer natsConn.Close()
+
+	_, stopDiagnostics, err := diagnostics.Start(cfg.Diagnostics.Options("backtest"), natsConn, logger.Log)
+	if err != nil {
+		logger.Log.Error().Err(err).Msg("Failed to start diagnostics")
+		os.Exit(1)
+	}
+	shutdown.HookShutdownCallback("diagnostics", stopDiagnostics, 10*time.Second)
+	js, err := natsConn.JetStream()
+	if err != nil {
+		logger.Log.Error().Err(err).Msg("Failed to create JetStream context")
+		os.Exit(1)
+	}
+	if _, err := js.StreamInfo(cfg.NATS.Stream); err != nil {
+		logger.Log.Error().Err(err).Msg("Failed to get stream info")
+		os.Exit(1)
+	}
+	busOpts, err := cfg.NATS.BusOptions()
+	if err != nil {
+		logger.Log.Error().Err(err).Msg("Invalid event bus options")
+		os.Exit(1)
+	}
+	if busOpts.Authorizer, err = cfg.NATS.Authorizer(natsConn, "backtest"); err != nil {
+		logger.Log.Error().Err(err).Msg("Invalid command policy")
+		os.Exit(1)
+	}
+	bus, err := eventbus.NewBus(natsConn, busOpts)
+	if err != nil {
+		logger.Log.Error().Err(err).Msg("Failed to create event bus")
+		os.Exit(1)
+	}
+
+	// Every change of status is published, so CI can wait on a job without
+	// polling
+	opts := cfg.Options()
+	opts.OnChange = func(job backtest.Job) {
+		logger.Log.Info().Str("job", job.ID).Str("strategy", job.Spec.Strategy).Str("status", string(job.Status)).Str("error", job.Error).Msg("Job changed")
+		data, err := json.Marshal(job)
+		if err != nil {
+			logger.Log.Error().Err(err).Str("job", job.ID).Msg("Failed to marshal job")
+			return
+		}
+		msg := &nats.Msg{
+			Subject: cfg.NATS.Subject,
+			Data:    data,
+			Header: nats.Header{
+				"Nats-Msg-Id": []string{fmt.Sprintf("BACKTEST-%s-%s", job.ID, job.Status)},
+			},
+		}
+		if err := bus.Encode(msg); err != nil {
+			logger.Log.Error().Err(err).Str("job", job.ID).Msg("Failed to encode job")
+			return
+		}
+		if _, err := js.PublishMsg(msg); err != nil {
+			logger.Log.Error().Err(err).Str("job", job.ID).Msg("Failed to publish job")
+		}
+	}
+	manager, err := backtest.NewManager(opts)
+	if err != nil {
+		logger.Log.Error().Err(err).Msg("Failed to create job manager")
+		os.Exit(1)
+	}
+	shutdown.HookShutdownCallback("jobs", manager.Close, 10*time.Second)
+	logger.Log.Info().Str("dir", cfg.Dir).Int("jobs", len(manager.List())).Strs("strategies", backtest.Strategies()).Msg("Loaded jobs")
+
+	subject := cfg.RPCSubject
+	if subject == "" {
+		subject = backtest.DefaultSubject
+	}
+	subs, err := backtest.Serve(bus, subject, manager)
+	if err != nil {
+		logger.Log.Error().Err(err).Str("subject", subject).Msg("Failed to serve backtest RPCs")
+		os.Exit(1)
+	}
+	shutdown.HookShutdownCallback("unsubscribe "+subject, func() {
+		for _, sub := range subs {
+			if err := sub.Unsubscribe(); err != nil {
+				logger.Log.Error().Err(err).Str("subject", sub.Subject).Msg("Failed to unsubscribe")
+			}
+		}
+	}, 10*time.Second)
+	logger.Log.Info().Str("subject", subject).Msg("Serving backtest RPCs")
+
+	rg := gin.New()
+	rg.Use(gin.Recovery())
+	rg.Use(api.AllowAllCors)
+	backtest.Register(rg, manager)
+	rg.GET("/healthz", func(c *gin.Context) {
+		c.JSON(http.StatusOK, gin.H{"jobs": len(manager.List())})
+	})
+	rg.GET("/version", gin.WrapF(diagnostics.VersionHandler("backtest")))
+
+	server := &http.Server{
+		Addr:    fmt.Sprintf("%s:%d", cfg.Host, cfg.Port),
+		Handler: rg,
+	}
+	go func() {
+		logger.Log.Info().Str("addr", server.Addr).Msg("Backtest API listening")
+		if err := server.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			logger.Log.Error().Err(err).Msg("Backtest API server failed")
+			os.Exit(1)
+		}
+	}()
+
+	shutdown.HookShutdownCallback("http server", func() {
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		if err := server.Shutdown(ctx); err != nil {
+			logger.Log.Error().Err(err).Msg("Failed to shutdown http server")
+		}
+	}, 10*time.Second)
+
+	shutdown.WaitForShutdown(syscall.SIGINT, syscall.SIGTERM)
+	logger.Log.Info().Msg("Backtest command executed successfully!")
+}
+
+func main() {
+	var configFile string
+	flag.StringVar(&configFile, "c", "", "Configuration file path (required)")
+
+	flag.Usage = func() {
+		logger.Log.Info().Msg(`Backtest runs strategies over recorded or synthetic market data as jobs.
+Jobs are submitted, tracked and canceled over REST (POST /jobs) and on the
+backtest.submit, backtest.status and backtest.cancel RPCs, their logs
+streamed from /jobs/<id>/logs?follow=true, and their results, metrics and
+equity curve, fetched from /jobs/<id>/result once succeeded. Sweeps of
+strategies and symbols (POST /sweeps, backtest.sweep) run their backtests
+in parallel over market data loaded once into a shared cache, their
+metrics fetched from /jobs/<id>/outcomes. Walk-forward optimizations (POST
+/walkforwards, backtest.walkforward) search the parameters of a strategy on
+rolling in-sample windows and test them out of sample, their ranked
+parameters and overfitting diagnostics fetched from /jobs/<id>/walkforward.
+Changes of status are published to NATS on the configured subject.
+
+Usage:
+  backtest -c <config-file>
+
+Examples:
+  backtest -c config/backtest.json
+`)
+		flag.PrintDefaults()
+	}
+
+	flag.Parse()
+
+	if configFile == "" {
+		logger.Log.Error().Msg("config file path is required")
+		flag.Usage()
+		os.Exit(1)
+	}
+
+	runBacktest(configFile)
+}
@@ -0,0 +1,362 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"reflect"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/BullionBear/sequex/env"
+	"github.com/BullionBear/sequex/internal/basis"
+	"github.com/BullionBear/sequex/internal/config"
+	"github.com/BullionBear/sequex/internal/model/sqx"
+	"github.com/BullionBear/sequex/internal/sysevent"
+	"github.com/BullionBear/sequex/pkg/diagnostics"
+	"github.com/BullionBear/sequex/pkg/eventbus"
+	"github.com/BullionBear/sequex/pkg/exchange/binanceperp"
+	"github.com/BullionBear/sequex/pkg/featureflag"
+	"github.com/BullionBear/sequex/pkg/logger"
+	"github.com/BullionBear/sequex/pkg/node"
+	"github.com/BullionBear/sequex/pkg/shutdown"
+	"github.com/nats-io/nats.go"
+)
+
+// runBasis executes the main basis node logic
+func runBasis(configFile string) {
+	logger.Log.Info().
+		Str("version", env.Version).
+		Str("buildTime", env.BuildTime).
+		Str("commitHash", env.CommitHash).
+		Msg("Basis started")
+
+	cfg, err := config.LoadBasisConfig(configFile)
+	if err != nil {
+		logger.Log.Error().Err(err).Msg("Failed to load config")
+		os.Exit(1)
+	}
+
+	mon, err := basis.New(cfg.Options())
+	if err != nil {
+		logger.Log.Error().Err(err).Msg("Failed to create basis monitor")
+		os.Exit(1)
+	}
+
+	shutdown := shutdown.NewShutdown(logger.Log)
+
+	natsConn, err := nats.Connect(cfg.NATS.URIs)
+	if err != nil {
+		logger.Log.Error().Err(err).Msg("Failed to connect to NATS")
+		os.Exit(1)
+	}
+	defer natsConn.Close()
+
+	diag, stopDiagnostics, err := diagnostics.Start(cfg.Diagnostics.Options("basis"), natsConn, logger.Log)
+	if err != nil {
+		logger.Log.Error().Err(err).Msg("Failed to start diagnostics")
+		os.Exit(1)
+	}
+	shutdown.HookShutdownCallback("diagnostics", stopDiagnostics, 10*time.Second)
+	sysEvents, err := cfg.Diagnostics.EventPublisher(natsConn, "basis", logger.Log)
+	if err != nil {
+		logger.Log.Error().Err(err).Msg("Failed to create system event publisher")
+		os.Exit(1)
+	}
+	defer sysEvents.Close()
+	sysEvents.WatchConn(natsConn)
+	js, err := natsConn.JetStream()
+	if err != nil {
+		logger.Log.Error().Err(err).Msg("Failed to create JetStream context")
+		os.Exit(1)
+	}
+	if _, err := js.StreamInfo(cfg.NATS.Stream); err != nil {
+		logger.Log.Error().Err(err).Msg("Failed to get stream info")
+		os.Exit(1)
+	}
+	busOpts, err := cfg.NATS.BusOptions()
+	if err != nil {
+		logger.Log.Error().Err(err).Msg("Invalid event bus options")
+		os.Exit(1)
+	}
+	bus, err := eventbus.NewBus(natsConn, busOpts)
+	if err != nil {
+		logger.Log.Error().Err(err).Msg("Failed to create event bus")
+		os.Exit(1)
+	}
+
+	publish := func(signals []basis.Signal) {
+		for _, signal := range signals {
+			logger.Log.Info().Str("action", string(signal.Action)).Str("symbol", signal.Symbol).
+				Float64("basis", signal.Basis).Float64("fundingAPR", signal.FundingAPR).
+				Float64("yield", signal.Yield).Msg("Carry signal")
+
+			data, err := json.Marshal(signal)
+			if err != nil {
+				logger.Log.Error().Err(err).Msg("Failed to marshal signal")
+				continue
+			}
+			subject := fmt.Sprintf("%s.%s", cfg.NATS.Subject, strings.ToLower(strings.ReplaceAll(signal.Symbol, "-", "")))
+			msg := &nats.Msg{Subject: subject, Data: data}
+			if err := bus.Encode(msg); err != nil {
+				logger.Log.Error().Err(err).Str("symbol", signal.Symbol).Msg("Failed to encode signal")
+				continue
+			}
+			if _, err := js.PublishMsg(msg); err != nil {
+				logger.Log.Error().Err(err).Str("symbol", signal.Symbol).Msg("Failed to publish signal")
+			}
+		}
+	}
+
+	n := node.New("basis", bus, logger.Log)
+	if err := n.OnEvent(func(e node.Event) { sysEvents.Publish(sysevent.NodeEvent(e)) }); err != nil {
+		logger.Log.Error().Err(err).Msg("Failed to publish node events")
+		os.Exit(1)
+	}
+	if err := n.SetRecovery(cfg.Recovery.RecoveryOptions()); err != nil {
+		logger.Log.Error().Err(err).Msg("Invalid recovery options")
+		os.Exit(1)
+	}
+	if err := n.SetLimits(cfg.Limits.Limits()); err != nil {
+		logger.Log.Error().Err(err).Msg("Invalid resource limits")
+		os.Exit(1)
+	}
+	flags, err := cfg.FeatureFlags.Set()
+	if err != nil {
+		logger.Log.Error().Err(err).Msg("Invalid feature flags")
+		os.Exit(1)
+	}
+	if cfg.FeatureFlags.Bucket != "" {
+		store, err := featureflag.Watch(js, cfg.FeatureFlags.Bucket, flags, logger.Log)
+		if err != nil {
+			logger.Log.Error().Err(err).Msg("Failed to watch feature flags")
+			os.Exit(1)
+		}
+		shutdown.HookShutdownCallback("feature flags", store.Close, 10*time.Second)
+	}
+	n.SetFlags(flags)
+	for _, subject := range cfg.Spot {
+		err := n.On(subject, func(msg *nats.Msg) error {
+			trades, err := sqx.UnmarshalTrades(msg.Data, msg.Header.Get(sqx.HeaderTradeBatch))
+			if err != nil {
+				return fmt.Errorf("failed to unmarshal trade: %w", err)
+			}
+			for _, trade := range trades {
+				publish(mon.OnTrade(trade))
+			}
+			return nil
+		}, cfg.Dispatch.For(subject))
+		if err == nil {
+			err = n.Document(subject, node.NewPayload("protobuf", (*sqx.Trade)(nil), "Trades, several per message when batched"))
+		}
+		if err != nil {
+			logger.Log.Error().Err(err).Str("subject", subject).Msg("Failed to register spot handler")
+			os.Exit(1)
+		}
+	}
+	for _, subject := range cfg.Marks {
+		err := n.On(subject, func(msg *nats.Msg) error {
+			var mark sqx.MarkPrice
+			if err := sqx.UnmarshalMarkPrice(msg.Data, &mark); err != nil {
+				return fmt.Errorf("failed to unmarshal mark price: %w", err)
+			}
+			publish(mon.OnMark(mark))
+			return nil
+		}, cfg.Dispatch.For(subject))
+		if err == nil {
+			err = n.Document(subject, node.NewPayload("json", (*sqx.MarkPrice)(nil), "Mark prices and funding rates"))
+		}
+		if err != nil {
+			logger.Log.Error().Err(err).Str("subject", subject).Msg("Failed to register mark handler")
+			os.Exit(1)
+		}
+	}
+
+	if err := n.Emits(cfg.NATS.Subject+".*", node.NewPayload("json", (*basis.Signal)(nil), "Carry signals per symbol")); err != nil {
+		logger.Log.Error().Err(err).Msg("Invalid signal subject")
+		os.Exit(1)
+	}
+	n.SetParams(cfg)
+	n.AddSnapshot("estimates", func() (any, error) { return mon.Estimates(), nil })
+	n.AddSnapshot("health", func() (any, error) { return n.Health(), nil })
+	n.AddSnapshot("flags", func() (any, error) { return flags.Snapshot(), nil })
+
+	if err := n.Start(); err != nil {
+		logger.Log.Error().Err(err).Msg("Failed to start node")
+		os.Exit(1)
+	}
+	diag.AddQueueSource(n.QueueDepths)
+	diag.AddRPCSource(bus.RPCStats)
+	diag.AddSlowConsumerSource(bus.SlowConsumerStats)
+	shutdown.HookShutdownCallback("node", n.Stop, 10*time.Second)
+	if subject := cfg.Diagnostics.DescribeSubject("basis"); subject != "" {
+		sub, err := n.ServeDescription(subject)
+		if err != nil {
+			logger.Log.Error().Err(err).Msg("Failed to serve node description")
+			os.Exit(1)
+		}
+		shutdown.HookShutdownCallback("node description", func() { sub.Unsubscribe() }, 10*time.Second)
+	}
+	if subject := cfg.Diagnostics.SnapshotSubject("basis"); subject != "" {
+		sub, err := n.ServeSnapshot(subject)
+		if err != nil {
+			logger.Log.Error().Err(err).Msg("Failed to serve node snapshots")
+			os.Exit(1)
+		}
+		shutdown.HookShutdownCallback("node snapshots", func() { sub.Unsubscribe() }, 10*time.Second)
+	}
+
+	if _, remote, _ := config.ParseRemote(configFile); remote {
+		// The rules are reloaded as the configuration is written, the other
+		// settings on restart
+		current := cfg
+		stop, err := config.WatchConfig(js, configFile, func(data []byte, revision uint64) {
+			next, err := config.ParseBasisConfig(data)
+			if err != nil {
+				logger.Log.Error().Err(err).Uint64("revision", revision).Msg("Ignoring invalid configuration")
+				return
+			}
+			if reflect.DeepEqual(*next, *current) {
+				return
+			}
+			if err := mon.Reconfigure(next.Options()); err != nil {
+				logger.Log.Error().Err(err).Uint64("revision", revision).Msg("Failed to reload the rules")
+				return
+			}
+			if !reflect.DeepEqual(next.Static(), current.Static()) {
+				logger.Log.Warn().Uint64("revision", revision).Msg("Configuration changes other than rules and max_age_ms apply on restart")
+			}
+			logger.Log.Info().Uint64("revision", revision).Int("rules", len(next.Rules)).Msg("Reloaded the rules")
+			current = next
+		}, func(err error) {
+			logger.Log.Warn().Err(err).Msg("Remote configuration")
+		})
+		if err != nil {
+			logger.Log.Error().Err(err).Msg("Failed to watch the configuration")
+			os.Exit(1)
+		}
+		shutdown.HookShutdownCallback("configuration watch", stop, 10*time.Second)
+	}
+
+	if len(cfg.Poll.Symbols) > 0 {
+		stop := pollMarks(cfg.Poll, func(mark sqx.MarkPrice) {
+			publish(mon.OnMark(mark))
+		})
+		shutdown.HookShutdownCallback("mark poller", stop, 10*time.Second)
+	}
+
+	shutdown.WaitForShutdown(syscall.SIGINT, syscall.SIGTERM)
+	logger.Log.Info().Msg("Basis command executed successfully!")
+}
+
+// pollMarks fetches the mark price and funding rate of the configured
+// symbols from Binance USDⓈ-M on every interval until the returned function
+// is called
+func pollMarks(cfg config.MarkPollConfig, onMark func(sqx.MarkPrice)) func() {
+	baseURL := cfg.BaseURL
+	if baseURL == "" {
+		baseURL = binanceperp.MainnetBaseUrl
+	}
+	client := binanceperp.NewClient(&binanceperp.Config{BaseURL: baseURL})
+	symbols := make([]sqx.Symbol, 0, len(cfg.Symbols))
+	for _, s := range cfg.Symbols {
+		symbol, _ := sqx.NewSymbolFromStr(s) // Validated with the configuration
+		symbols = append(symbols, symbol)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		ticker := time.NewTicker(time.Duration(cfg.IntervalMs) * time.Millisecond)
+		defer ticker.Stop()
+		for {
+			for _, symbol := range symbols {
+				resp, err := client.GetMarkPrice(ctx, binanceperp.GetMarkPriceRequest{Symbol: symbol.Base + symbol.Quote})
+				if err != nil || resp.Data == nil || len(*resp.Data) == 0 {
+					logger.Log.Error().Err(err).Str("symbol", symbol.String()).Msg("Failed to get mark price")
+					continue
+				}
+				mark, err := convertMarkPrice(symbol, (*resp.Data)[0])
+				if err != nil {
+					logger.Log.Error().Err(err).Str("symbol", symbol.String()).Msg("Failed to convert mark price")
+					continue
+				}
+				onMark(mark)
+			}
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			}
+		}
+	}()
+	return func() {
+		cancel()
+		<-done
+	}
+}
+
+// convertMarkPrice maps a Binance premium index onto the polled symbol
+func convertMarkPrice(symbol sqx.Symbol, mp binanceperp.MarkPrice) (sqx.MarkPrice, error) {
+	markPrice, err := strconv.ParseFloat(mp.MarkPrice, 64)
+	if err != nil {
+		return sqx.MarkPrice{}, fmt.Errorf("failed to parse mark price %q: %w", mp.MarkPrice, err)
+	}
+	indexPrice, err := strconv.ParseFloat(mp.IndexPrice, 64)
+	if err != nil {
+		return sqx.MarkPrice{}, fmt.Errorf("failed to parse index price %q: %w", mp.IndexPrice, err)
+	}
+	fundingRate, err := strconv.ParseFloat(mp.LastFundingRate, 64)
+	if err != nil {
+		return sqx.MarkPrice{}, fmt.Errorf("failed to parse funding rate %q: %w", mp.LastFundingRate, err)
+	}
+	return sqx.MarkPrice{
+		Exchange:        sqx.ExchangeBinancePerp,
+		InstrumentType:  sqx.InstrumentTypePerp,
+		Symbol:          symbol,
+		MarkPrice:       markPrice,
+		IndexPrice:      indexPrice,
+		FundingRate:     fundingRate,
+		NextFundingTime: mp.NextFundingTime,
+		Timestamp:       mp.Time,
+	}, nil
+}
+
+func main() {
+	var configFile string
+	flag.StringVar(&configFile, "c", "", "Configuration file path, or kv:<key> in the configs bucket (required)")
+
+	flag.Usage = func() {
+		logger.Log.Info().Msg(`Basis joins spot trades with perp mark prices and funding rates, estimates
+the annualized yield of a cash-and-carry position (long spot, short perp) and
+publishes enter and exit signals to NATS as <subject>.<symbol>.
+
+The configuration is read from a file, or from the configs key-value bucket
+at NATS_URL with -c kv:<key>, in which case its rules are reloaded as the
+key is written.
+
+Usage:
+  basis -c <config-file>
+
+Examples:
+  basis -c config/basis.json
+  NATS_URL=nats://localhost:4222 basis -c kv:btc-basis.basis
+`)
+		flag.PrintDefaults()
+	}
+
+	flag.Parse()
+
+	if configFile == "" {
+		logger.Log.Error().Msg("config file path is required")
+		flag.Usage()
+		os.Exit(1)
+	}
+
+	runBasis(configFile)
+}
@@ -0,0 +1,271 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/BullionBear/sequex/api"
+	"github.com/BullionBear/sequex/env"
+	"github.com/BullionBear/sequex/internal/config"
+	"github.com/BullionBear/sequex/internal/correlation"
+	"github.com/BullionBear/sequex/internal/model/sqx"
+	"github.com/BullionBear/sequex/pkg/candles"
+	"github.com/BullionBear/sequex/pkg/columnar"
+	"github.com/BullionBear/sequex/pkg/diagnostics"
+	"github.com/BullionBear/sequex/pkg/eventbus"
+	"github.com/BullionBear/sequex/pkg/exchange/binanceperp"
+	"github.com/BullionBear/sequex/pkg/logger"
+	"github.com/BullionBear/sequex/pkg/shutdown"
+	"github.com/gin-gonic/gin"
+	"github.com/nats-io/nats.go"
+)
+
+// runCorrelation executes the main correlation service logic
+func runCorrelation(configFile string) {
+	logger.Log.Info().
+		Str("version", env.Version).
+		Str("buildTime", env.BuildTime).
+		Str("commitHash", env.CommitHash).
+		Msg("Correlation started")
+
+	cfg, err := config.LoadCorrelationConfig(configFile)
+	if err != nil {
+		logger.Log.Error().Err(err).Msg("Failed to load config")
+		os.Exit(1)
+	}
+
+	tracker := correlation.New(cfg.Options())
+
+	shutdown := shutdown.NewShutdown(logger.Log)
+
+	natsConn, err := nats.Connect(cfg.NATS.URIs)
+	if err != nil {
+		logger.Log.Error().Err(err).Msg("Failed to connect to NATS")
+		os.Exit(1)
+	}
+	defer natsConn.Close()
+
+	_, stopDiagnostics, err := diagnostics.Start(cfg.Diagnostics.Options("correlation"), natsConn, logger.Log)
+	if err != nil {
+		logger.Log.Error().Err(err).Msg("Failed to start diagnostics")
+		os.Exit(1)
+	}
+	shutdown.HookShutdownCallback("diagnostics", stopDiagnostics, 10*time.Second)
+	js, err := natsConn.JetStream()
+	if err != nil {
+		logger.Log.Error().Err(err).Msg("Failed to create JetStream context")
+		os.Exit(1)
+	}
+	if _, err := js.StreamInfo(cfg.NATS.Stream); err != nil {
+		logger.Log.Error().Err(err).Msg("Failed to get stream info")
+		os.Exit(1)
+	}
+	busOpts, err := cfg.NATS.BusOptions()
+	if err != nil {
+		logger.Log.Error().Err(err).Msg("Invalid event bus options")
+		os.Exit(1)
+	}
+	bus, err := eventbus.NewBus(natsConn, busOpts)
+	if err != nil {
+		logger.Log.Error().Err(err).Msg("Failed to create event bus")
+		os.Exit(1)
+	}
+
+	symbols := make([]sqx.Symbol, 0, len(cfg.Binance))
+	for _, s := range cfg.Binance {
+		symbol, _ := sqx.NewSymbolFromStr(s) // Validated with the configuration
+		symbols = append(symbols, symbol)
+	}
+	interval := cfg.KlineInterval()
+
+	if cfg.Backfill {
+		sources := []candles.Source{
+			candles.NewBinancePerpSource(binanceperp.NewClient(&binanceperp.Config{BaseURL: binanceperp.MainnetBaseUrl})),
+		}
+		var sinks []candles.Sink
+		if cfg.KlinesDir != "" {
+			store, err := columnar.NewStore(cfg.KlinesDir)
+			if err != nil {
+				logger.Log.Error().Err(err).Str("dir", cfg.KlinesDir).Msg("Failed to open kline tables")
+				os.Exit(1)
+			}
+			sources, sinks = append([]candles.Source{store}, sources...), []candles.Sink{store}
+		}
+		source := candles.NewService(sources, sinks...)
+		for _, symbol := range symbols {
+			if err := backfill(source, tracker, symbol, interval, cfg.Window); err != nil {
+				logger.Log.Error().Err(err).Str("symbol", symbol.String()).Msg("Failed to backfill klines")
+				continue
+			}
+			logger.Log.Info().Str("symbol", symbol.String()).Msg("Backfilled klines")
+		}
+	}
+
+	wsClient := binanceperp.NewWSClient(nil)
+	for _, symbol := range symbols {
+		unsubscribe, err := wsClient.SubscribeKline(strings.ToLower(symbol.Base+symbol.Quote), interval,
+			(&binanceperp.KlineSubscriptionOptions{}).
+				WithKline(func(kline binanceperp.WSKline) {
+					if !kline.IsClosed {
+						return
+					}
+					price, err := strconv.ParseFloat(kline.Close, 64)
+					if err != nil {
+						logger.Log.Error().Err(err).Str("symbol", symbol.String()).Msg("Failed to parse close price")
+						return
+					}
+					tracker.OnKline(symbol.String(), kline.StartTime, price)
+				}).
+				WithError(func(err error) {
+					logger.Log.Error().Err(err).Str("symbol", symbol.String()).Msg("Kline stream error")
+				}))
+		if err != nil {
+			logger.Log.Error().Err(err).Str("symbol", symbol.String()).Msg("Failed to subscribe to klines")
+			os.Exit(1)
+		}
+		shutdown.HookShutdownCallback("klines "+symbol.String(), unsubscribe, 10*time.Second)
+		logger.Log.Info().Str("symbol", symbol.String()).Str("interval", interval).Msg("Subscribed to Binance klines")
+	}
+	shutdown.HookShutdownCallback("binance websocket", wsClient.Close, 10*time.Second)
+
+	if cfg.RPCSubject != "" {
+		sub, err := correlation.Serve(natsConn, cfg.RPCSubject, tracker)
+		if err != nil {
+			logger.Log.Error().Err(err).Str("subject", cfg.RPCSubject).Msg("Failed to serve correlation requests")
+			os.Exit(1)
+		}
+		shutdown.HookShutdownCallback("correlation rpc", func() {
+			if err := sub.Unsubscribe(); err != nil {
+				logger.Log.Error().Err(err).Str("subject", cfg.RPCSubject).Msg("Failed to unsubscribe")
+			}
+		}, 10*time.Second)
+		logger.Log.Info().Str("subject", cfg.RPCSubject).Msg("Serving correlation requests")
+	}
+
+	if cfg.PublishIntervalMs > 0 {
+		ctx, cancel := context.WithCancel(context.Background())
+		go func() {
+			ticker := time.NewTicker(time.Duration(cfg.PublishIntervalMs) * time.Millisecond)
+			defer ticker.Stop()
+			for {
+				select {
+				case <-ctx.Done():
+					return
+				case <-ticker.C:
+				}
+				m, err := tracker.Matrix(cfg.Binance...)
+				if err != nil {
+					logger.Log.Debug().Err(err).Msg("Matrix not available yet")
+					continue
+				}
+				data, err := json.Marshal(m)
+				if err != nil {
+					logger.Log.Error().Err(err).Msg("Failed to marshal matrix")
+					continue
+				}
+				msg := &nats.Msg{Subject: cfg.NATS.Subject, Data: data}
+				if err := bus.Encode(msg); err != nil {
+					logger.Log.Error().Err(err).Msg("Failed to encode matrix")
+					continue
+				}
+				if _, err := js.PublishMsg(msg); err != nil {
+					logger.Log.Error().Err(err).Msg("Failed to publish matrix")
+				}
+			}
+		}()
+		shutdown.HookShutdownCallback("publications", cancel, 10*time.Second)
+	}
+
+	rg := gin.New()
+	rg.Use(gin.Recovery())
+	rg.Use(api.AllowAllCors)
+	correlation.Register(rg, tracker)
+	rg.GET("/healthz", func(c *gin.Context) {
+		c.JSON(http.StatusOK, gin.H{"symbols": tracker.Symbols()})
+	})
+	rg.GET("/version", gin.WrapF(diagnostics.VersionHandler("correlation")))
+
+	server := &http.Server{
+		Addr:    fmt.Sprintf("%s:%d", cfg.Host, cfg.Port),
+		Handler: rg,
+	}
+	go func() {
+		logger.Log.Info().Str("addr", server.Addr).Msg("Correlation API listening")
+		if err := server.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			logger.Log.Error().Err(err).Msg("Correlation API server failed")
+			os.Exit(1)
+		}
+	}()
+
+	shutdown.HookShutdownCallback("http server", func() {
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		if err := server.Shutdown(ctx); err != nil {
+			logger.Log.Error().Err(err).Msg("Failed to shutdown http server")
+		}
+	}, 10*time.Second)
+
+	shutdown.WaitForShutdown(syscall.SIGINT, syscall.SIGTERM)
+	logger.Log.Info().Msg("Correlation command executed successfully!")
+}
+
+// backfill records the closed klines of the window preceding the stream
+func backfill(source *candles.Service, tracker *correlation.Tracker, symbol sqx.Symbol, interval string, window int) error {
+	if window <= 0 {
+		window = 240
+	}
+	step, err := candles.ParseInterval(interval)
+	if err != nil {
+		return err
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	now := time.Now()
+	series, err := source.GetCandles(ctx, symbol.String(), interval, now.Add(-time.Duration(window+1)*step), now)
+	if err != nil {
+		return err
+	}
+	for _, c := range series {
+		tracker.OnKline(symbol.String(), c.OpenTime, c.Close)
+	}
+	return nil
+}
+
+func main() {
+	var configFile string
+	flag.StringVar(&configFile, "c", "", "Configuration file path (required)")
+
+	flag.Usage = func() {
+		logger.Log.Info().Msg(`Correlation keeps rolling covariance and correlation matrices of the kline
+returns of the configured Binance USDⓈ-M symbols. Matrices are published to
+NATS on the configured subject, answered as requests on the RPC subject, and
+queried over REST at /correlation?symbols=BTC-USDT,ETH-USDT.
+
+Usage:
+  correlation -c <config-file>
+
+Examples:
+  correlation -c config/correlation.json
+`)
+		flag.PrintDefaults()
+	}
+
+	flag.Parse()
+
+	if configFile == "" {
+		logger.Log.Error().Msg("config file path is required")
+		flag.Usage()
+		os.Exit(1)
+	}
+
+	runCorrelation(configFile)
+}
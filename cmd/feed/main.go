@@ -1,8 +1,15 @@
 package main
 
 import (
+	"context"
+	"encoding/json"
 	"flag"
+	"fmt"
 	"os"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
 	"syscall"
 	"time"
 
@@ -10,7 +17,15 @@ import (
 	"github.com/BullionBear/sequex/internal/adapter"
 	_ "github.com/BullionBear/sequex/internal/adapter/init"
 	"github.com/BullionBear/sequex/internal/config"
+	"github.com/BullionBear/sequex/internal/lifecycle"
 	"github.com/BullionBear/sequex/internal/model/sqx"
+	"github.com/BullionBear/sequex/internal/publisher"
+	"github.com/BullionBear/sequex/internal/replay"
+	"github.com/BullionBear/sequex/internal/shard"
+	"github.com/BullionBear/sequex/internal/sysevent"
+	"github.com/BullionBear/sequex/pkg/diagnostics"
+	"github.com/BullionBear/sequex/pkg/exchange/binance"
+	"github.com/BullionBear/sequex/pkg/exchange/transport"
 	"github.com/BullionBear/sequex/pkg/logger"
 	"github.com/BullionBear/sequex/pkg/shutdown"
 	"github.com/nats-io/nats.go"
@@ -32,6 +47,15 @@ func runFeed(configFile string) {
 	}
 
 	printConfiguration(cfg)
+	if cfg.Chaos.Enabled() {
+		if err := transport.EnableChaos(cfg.Chaos); err != nil {
+			logger.Log.Error().Err(err).Msg("Failed to enable chaos")
+			os.Exit(1)
+		}
+		logger.Log.Warn().Float64("rateLimitRate", cfg.Chaos.RateLimitRate).Float64("serverErrorRate", cfg.Chaos.ServerErrorRate).
+			Float64("malformedRate", cfg.Chaos.MalformedRate).Float64("disconnectRate", cfg.Chaos.DisconnectRate).
+			Msg("Chaos enabled, faults are injected into the exchange clients")
+	}
 	sqxExchange := sqx.NewExchange(cfg.Exchange)
 	if sqxExchange == sqx.ExchangeUnknown {
 		logger.Log.Error().Msg("Invalid exchange")
@@ -44,10 +68,13 @@ func runFeed(configFile string) {
 		os.Exit(1)
 	}
 
-	sqxSymbol, err := sqx.NewSymbolFromStr(cfg.Symbol)
-	if err != nil {
-		logger.Log.Error().Err(err).Msg("Failed to create symbol")
-		os.Exit(1)
+	var sqxSymbol sqx.Symbol
+	if cfg.Shard == nil {
+		sqxSymbol, err = sqx.NewSymbolFromStr(cfg.Symbol)
+		if err != nil {
+			logger.Log.Error().Err(err).Msg("Failed to create symbol")
+			os.Exit(1)
+		}
 	}
 
 	sqxDataType := sqx.NewDataType(cfg.Type)
@@ -64,6 +91,20 @@ func runFeed(configFile string) {
 		os.Exit(1)
 	}
 	defer natsConn.Close()
+
+	diag, stopDiagnostics, err := diagnostics.Start(cfg.Diagnostics.Options("feed"), natsConn, logger.Log)
+	if err != nil {
+		logger.Log.Error().Err(err).Msg("Failed to start diagnostics")
+		os.Exit(1)
+	}
+	shutdown.HookShutdownCallback("diagnostics", stopDiagnostics, 10*time.Second)
+	sysEvents, err := cfg.Diagnostics.EventPublisher(natsConn, "feed", logger.Log)
+	if err != nil {
+		logger.Log.Error().Err(err).Msg("Failed to create system event publisher")
+		os.Exit(1)
+	}
+	defer sysEvents.Close()
+	sysEvents.WatchConn(natsConn)
 	js, err := natsConn.JetStream()
 	if err != nil {
 		logger.Log.Error().Err(err).Msg("Failed to create JetStream context")
@@ -75,7 +116,15 @@ func runFeed(configFile string) {
 		os.Exit(1)
 	}
 	logger.Log.Info().Msgf("Stream info: %+v", streamInfo)
-	subject := cfg.NATS.Subject
+	subject := func(*sqx.Trade) string { return cfg.NATS.Subject }
+	if cfg.Shard != nil {
+		subjects, err := replay.NewSubjects(cfg.Shard.SubjectTemplate, nil)
+		if err != nil {
+			logger.Log.Error().Err(err).Msg("Invalid subject template")
+			os.Exit(1)
+		}
+		subject = subjects.Subject
+	}
 	switch sqxDataType {
 	case sqx.DataTypeTrade:
 		adapter, err := adapter.CreateTradeAdapter(sqxExchange)
@@ -83,24 +132,127 @@ func runFeed(configFile string) {
 			logger.Log.Error().Err(err).Msg("Failed to create adapter")
 			os.Exit(1)
 		}
-		unsubscribe, err := adapter.Subscribe(sqxSymbol, sqxInstrumentType, func(trade sqx.Trade) error {
-			data, err := trade.Marshal()
+		// Dedup wraps the publish to JetStream itself, innermost, so a trade
+		// dropped by the queue or lost by a failed batch is not marked seen
+		var dedup *publisher.Dedup
+		if cfg.Dedup.Path != "" {
+			dedup, err = publisher.NewDedup(cfg.Dedup.Path, cfg.Dedup.Window)
+			if err != nil {
+				logger.Log.Error().Err(err).Msg("Failed to open trade dedup window")
+				os.Exit(1)
+			}
+		}
+
+		publishTrade := func(trade sqx.Trade) error {
+			encoder := publisher.GetTradeEncoder()
+			defer publisher.PutTradeEncoder(encoder)
+			msg, err := encoder.Encode(subject(&trade), &trade)
 			if err != nil {
 				logger.Log.Error().Err(err).Msg("Failed to marshal trade")
 				return err
 			}
-			header := nats.Header{
-				"Nats-Msg-Id": []string{trade.IdStr()},
+			_, err = js.PublishMsg(msg)
+			return err
+		}
+		if dedup != nil {
+			publishTrade = dedup.Filter(publishTrade)
+		}
+
+		var batcher *publisher.BatchPublisher
+		if cfg.BatchSize > 1 {
+			var flush publisher.FlushFunc = func(trades []sqx.Trade) error {
+				data, err := sqx.MarshalTradeBatch(trades)
+				if err != nil {
+					return err
+				}
+				_, err = js.PublishMsg(&nats.Msg{
+					Subject: cfg.NATS.Subject,
+					Data:    data,
+					Header: nats.Header{
+						"Nats-Msg-Id":        []string{sqx.TradeBatchIdStr(trades)},
+						sqx.HeaderTradeBatch: []string{strconv.Itoa(len(trades))},
+					},
+				})
+				return err
+			}
+			if dedup != nil {
+				flush = dedup.FilterBatch(flush)
 			}
+			batcher = publisher.NewBatchPublisher(cfg.BatchSize, time.Duration(cfg.BatchIntervalMs)*time.Millisecond, flush,
+				func(err error, trades []sqx.Trade) {
+					logger.Log.Error().Err(err).Int("trades", len(trades)).Msg("Failed to publish trade batch")
+				})
+			publishTrade = batcher.Add
+		}
 
-			_, err = js.PublishMsg(&nats.Msg{
-				Subject: subject,
-				Data:    data,
-				Header:  header,
+		var queue *publisher.Queue
+		stopStats := make(chan struct{})
+		if cfg.Queue.Size > 0 {
+			policy, err := publisher.ParseOverflowPolicy(cfg.Queue.Overflow)
+			if err != nil {
+				logger.Log.Error().Err(err).Msg("Invalid queue overflow policy")
+				os.Exit(1)
+			}
+			queue = publisher.NewQueue(cfg.Queue.Size, policy, cfg.Queue.HighWatermark, publishTrade,
+				func(err error, trades []sqx.Trade) {
+					logger.Log.Error().Err(err).Int("trades", len(trades)).Msg("Failed to publish queued trade")
+				},
+				func(depth int, high bool) {
+					if high {
+						logger.Log.Warn().Int("depth", depth).Int("highWatermark", cfg.Queue.HighWatermark).Msg("Publisher queue above high watermark")
+						return
+					}
+					logger.Log.Info().Int("depth", depth).Msg("Publisher queue drained below watermark")
+				})
+			publishTrade = queue.Push
+			diag.AddQueueSource(func() []diagnostics.QueueDepth {
+				return []diagnostics.QueueDepth{{Name: "publisher", Depth: queue.Stats().Depth}}
 			})
-			return err
-		})
-		shutdown.HookShutdownCallback("unsubscribe", unsubscribe, 10*time.Second)
+			if cfg.Queue.StatsIntervalMs > 0 {
+				go logQueueStats(queue, time.Duration(cfg.Queue.StatsIntervalMs)*time.Millisecond, stopStats)
+			}
+		}
+
+		if dedup != nil {
+			go syncDedup(dedup, cfg.Dedup.SyncInterval(), stopStats)
+		}
+
+		var tracker *lifecycle.Tracker
+		var statusChanges []lifecycle.Change
+		if cfg.Lifecycle != nil {
+			tracker, statusChanges, err = newTracker(sqxExchange, sqxInstrumentType)
+			if err != nil {
+				logger.Log.Error().Err(err).Msg("Failed to read symbol statuses")
+				os.Exit(1)
+			}
+		}
+
+		var unsubscribe func()
+		switch {
+		case cfg.Shard != nil:
+			unsubscribe, err = runShard(cfg, natsConn, js, adapter, sqxExchange, sqxInstrumentType, publishTrade, tracker, statusChanges, sysEvents)
+		case tracker != nil:
+			unsubscribe, err = runSymbol(cfg, js, adapter, sqxSymbol, sqxInstrumentType, publishTrade, tracker, statusChanges, sysEvents)
+		default:
+			unsubscribe, err = adapter.Subscribe(sqxSymbol, sqxInstrumentType, publishTrade)
+		}
+		shutdown.HookShutdownCallback("unsubscribe", func() {
+			unsubscribe()
+			close(stopStats)
+			if queue != nil {
+				queue.Close()
+			}
+			if batcher != nil {
+				if err := batcher.Close(); err != nil {
+					logger.Log.Error().Err(err).Msg("Failed to flush trade batch")
+				}
+			}
+			if dedup != nil {
+				if err := dedup.Close(); err != nil {
+					logger.Log.Error().Err(err).Msg("Failed to close trade dedup window")
+				}
+			}
+		}, 10*time.Second)
 		if err != nil {
 			logger.Log.Error().Err(err).Msg("Failed to subscribe to adapter")
 			os.Exit(1)
@@ -115,19 +267,334 @@ func runFeed(configFile string) {
 	logger.Log.Info().Msg("Feed command executed successfully!")
 }
 
+// runSymbol streams a single symbol while the exchange trades it, parking
+// the stream while the symbol is halted or delisted and resuming it once it
+// trades again. It returns the function stopping the stream.
+func runSymbol(cfg *config.Config, js nats.JetStreamContext, tradeAdapter adapter.TradeAdapter, symbol sqx.Symbol, instrument sqx.InstrumentType, publishTrade publisher.PublishFunc, tracker *lifecycle.Tracker, statusChanges []lifecycle.Change, sysEvents *sysevent.Publisher) (func(), error) {
+	var mu sync.Mutex
+	var stop func() // Nil while parked
+	reconcile := func() error {
+		mu.Lock()
+		defer mu.Unlock()
+		trading := tracker.Trading(symbol.String())
+		switch {
+		case trading && stop == nil:
+			var err error
+			if stop, err = tradeAdapter.Subscribe(symbol, instrument, adapter.TradeCallback(publishTrade)); err != nil {
+				return err
+			}
+		case !trading && stop != nil:
+			stop()
+			stop = nil
+		}
+		return nil
+	}
+	if err := reconcile(); err != nil {
+		return nil, err
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		followLifecycle(ctx, cfg.Lifecycle, js, sysEvents, tracker, statusChanges,
+			func(s string) bool { return s == symbol.String() },
+			func(lifecycle.Change) {
+				if err := reconcile(); err != nil {
+					logger.Log.Error().Err(err).Str("symbol", symbol.String()).Msg("Failed to resume stream")
+				}
+			})
+	}()
+	return func() {
+		cancel()
+		<-done
+		mu.Lock()
+		defer mu.Unlock()
+		if stop != nil {
+			stop()
+			stop = nil
+		}
+	}, nil
+}
+
+// runShard streams the symbols of the shard of this instance, rebalancing
+// them as instances join and leave and, with discovery, as the symbols
+// ranking in the top by volume change. With a tracker the symbols not
+// trading are parked until they trade again. It returns the function
+// leaving the shard.
+func runShard(cfg *config.Config, conn *nats.Conn, js nats.JetStreamContext, tradeAdapter adapter.TradeAdapter, exchange sqx.Exchange, instrument sqx.InstrumentType, publishTrade publisher.PublishFunc, tracker *lifecycle.Tracker, statusChanges []lifecycle.Change, sysEvents *sysevent.Publisher) (func(), error) {
+	member, err := cfg.Shard.MemberName()
+	if err != nil {
+		return nil, err
+	}
+	registry, err := cfg.Shard.Registry(js)
+	if err != nil {
+		return nil, err
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	var discovery *shard.Discovery
+	if cfg.Shard.Discovery != nil {
+		source, err := volumeSource(exchange, instrument)
+		if err != nil {
+			cancel()
+			return nil, err
+		}
+		discovery = shard.NewDiscovery(source, cfg.Shard.Discovery.Options())
+		if _, _, err := discovery.Refresh(ctx); err != nil {
+			cancel()
+			return nil, fmt.Errorf("symbol discovery: %w", err)
+		}
+		logger.Log.Info().Strs("symbols", discovery.Symbols()).Msg("Symbols discovered")
+		go discovery.Run(ctx, cfg.Shard.Discovery.Interval(),
+			func(added, removed []string) {
+				logger.Log.Info().Strs("added", added).Strs("removed", removed).Msg("Discovered symbols changed")
+			},
+			func(err error) {
+				logger.Log.Error().Err(err).Msg("Symbol discovery failed")
+			})
+	}
+
+	var coordinator *shard.Coordinator
+	subscribe := func(symbol string) (func(), error) {
+		sqxSymbol, err := sqx.NewSymbolFromStr(symbol)
+		if err != nil {
+			return nil, err
+		}
+		return tradeAdapter.Subscribe(sqxSymbol, instrument, func(trade sqx.Trade) error {
+			coordinator.OnTrade(symbol, trade.Timestamp)
+			return publishTrade(trade)
+		})
+	}
+	assigner := cfg.Shard.Assigner(member, discovery)
+	if tracker != nil {
+		assigned := assigner
+		assigner = func(members []string) []string { return tracker.Filter(assigned(members)) }
+		covers := func(symbol string) bool { return slices.Contains(cfg.Shard.AllSymbols(), symbol) }
+		if discovery != nil {
+			covers = func(symbol string) bool { return slices.Contains(discovery.Symbols(), symbol) }
+		}
+		// Parked and resumed symbols move at the next rebalance
+		go followLifecycle(ctx, cfg.Lifecycle, js, sysEvents, tracker, statusChanges, covers, func(lifecycle.Change) {})
+	}
+	coordinator = shard.NewCoordinator(member, assigner, registry, subscribe, time.Duration(cfg.Shard.StaleAfterMs)*time.Millisecond)
+	done := make(chan struct{})
+	go logShardHealth(coordinator, cfg.Shard.Heartbeat(), ctx.Done())
+	go func() {
+		defer close(done)
+		coordinator.Run(ctx, cfg.Shard.Heartbeat(),
+			func(added, removed []string) {
+				logger.Log.Info().
+					Str("member", member).
+					Strs("added", added).
+					Strs("removed", removed).
+					Msg("Shard rebalanced")
+				if cfg.Shard.EventsSubject != "" {
+					publishSubscriptionChange(conn, cfg.Shard.EventsSubject, shard.SubscriptionChange{
+						Member:     member,
+						Exchange:   exchange.String(),
+						Instrument: instrument.String(),
+						Added:      added,
+						Removed:    removed,
+						Symbols:    coordinator.Symbols(),
+						Timestamp:  time.Now().UnixMilli(),
+					})
+				}
+			},
+			func(err error) {
+				logger.Log.Error().Err(err).Str("member", member).Msg("Shard sync failed")
+			})
+	}()
+	logger.Log.Info().Str("member", member).Str("mode", cfg.Shard.Mode).Msg("Joined shard")
+	return func() {
+		cancel()
+		<-done
+	}, nil
+}
+
+// volumeSource returns the 24h quote volume of the symbols of the exchange
+func volumeSource(exchange sqx.Exchange, instrument sqx.InstrumentType) (shard.VolumeSource, error) {
+	if exchange != sqx.ExchangeBinance || instrument != sqx.InstrumentTypeSpot {
+		return nil, fmt.Errorf("symbol discovery not supported on %s %s", exchange, instrument)
+	}
+	client := binance.NewClient(&binance.Config{BaseURL: binance.MainnetBaseUrl})
+	return func(ctx context.Context) (map[string]float64, error) {
+		resp, err := client.GetTicker24hr(ctx)
+		if err != nil {
+			return nil, err
+		}
+		volumes := make(map[string]float64, len(*resp.Data))
+		for _, ticker := range *resp.Data {
+			volume, err := strconv.ParseFloat(ticker.QuoteVolume, 64)
+			if err != nil {
+				continue
+			}
+			volumes[ticker.Symbol] = volume
+		}
+		return volumes, nil
+	}, nil
+}
+
+// newTracker reads the trading status of the symbols of the exchange and
+// returns the tracker with the symbols not trading
+func newTracker(exchange sqx.Exchange, instrument sqx.InstrumentType) (*lifecycle.Tracker, []lifecycle.Change, error) {
+	if exchange != sqx.ExchangeBinance || instrument != sqx.InstrumentTypeSpot {
+		return nil, nil, fmt.Errorf("symbol lifecycle not supported on %s %s", exchange, instrument)
+	}
+	client := binance.NewClient(&binance.Config{BaseURL: binance.MainnetBaseUrl})
+	tracker := lifecycle.NewTracker(exchange.String(), instrument.String(), lifecycle.BinanceSpot(client))
+	changes, err := tracker.Refresh(context.Background())
+	if err != nil {
+		return nil, nil, err
+	}
+	return tracker, changes, nil
+}
+
+// followLifecycle follows the trading status of the symbols until ctx is
+// done. The changes of the symbols the feed covers are logged, published to
+// the lifecycle subject and as system events and handed to onChange,
+// starting with those of the first refresh. Every instance of a shard publishes the changes under the
+// same message id so JetStream keeps one.
+func followLifecycle(ctx context.Context, cfg *config.LifecycleConfig, js nats.JetStreamContext, sysEvents *sysevent.Publisher, tracker *lifecycle.Tracker, changes []lifecycle.Change, covers func(string) bool, onChange func(lifecycle.Change)) {
+	handle := func(changes []lifecycle.Change) {
+		for _, change := range changes {
+			if !covers(change.Symbol) {
+				continue
+			}
+			logger.Log.Warn().
+				Str("symbol", change.Symbol).
+				Str("from", change.From).
+				Str("to", change.To).
+				Msg("Symbol status changed")
+			if cfg.Subject != "" {
+				id := strings.Join([]string{change.Exchange, change.Instrument, change.Symbol, change.From, change.To}, ".")
+				data, err := json.Marshal(change)
+				if err == nil {
+					_, err = js.Publish(cfg.Subject, data, nats.MsgId(id))
+				}
+				if err != nil {
+					logger.Log.Error().Err(err).Str("subject", cfg.Subject).Msg("Failed to publish symbol status change")
+				}
+			}
+			if e, ok := sysevent.LifecycleEvent(change); ok {
+				sysEvents.Publish(e)
+			}
+			onChange(change)
+		}
+	}
+	handle(changes)
+	tracker.Run(ctx, cfg.Interval(), handle, func(err error) {
+		logger.Log.Error().Err(err).Msg("Symbol status check failed")
+	})
+}
+
+// publishSubscriptionChange tells downstream consumers which symbols this
+// instance streams
+func publishSubscriptionChange(conn *nats.Conn, subject string, change shard.SubscriptionChange) {
+	data, err := json.Marshal(change)
+	if err == nil {
+		err = conn.Publish(subject, data)
+	}
+	if err != nil {
+		logger.Log.Error().Err(err).Str("subject", subject).Msg("Failed to publish subscription change")
+	}
+}
+
 // printConfiguration prints the parsed configuration
 func printConfiguration(cfg *config.Config) {
+	if cfg.Shard != nil {
+		logger.Log.Info().
+			Str("mode", cfg.Shard.Mode).
+			Strs("symbols", cfg.Shard.Symbols).
+			Bool("discovery", cfg.Shard.Discovery != nil).
+			Str("bucket", cfg.Shard.Bucket).
+			Str("subjectTemplate", cfg.Shard.SubjectTemplate).
+			Msg("Shard Configuration")
+	}
 	logger.Log.Info().
 		Str("exchange", cfg.Exchange).
 		Str("instrument", cfg.Instrument).
 		Str("symbol", cfg.Symbol).
 		Str("dataType", cfg.Type).
+		Int("batchSize", cfg.BatchSize).
 		Str("natsURIs", cfg.NATS.URIs).
 		Str("stream", cfg.NATS.Stream).
 		Str("subject", cfg.NATS.Subject).
 		Msg("Feed Configuration")
 }
 
+// logQueueStats periodically logs publisher queue metrics
+func logQueueStats(queue *publisher.Queue, interval time.Duration, stop <-chan struct{}) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-stop:
+			return
+		case <-ticker.C:
+			stats := queue.Stats()
+			logger.Log.Info().
+				Int("depth", stats.Depth).
+				Int("maxDepth", stats.MaxDepth).
+				Int("capacity", stats.Capacity).
+				Uint64("enqueued", stats.Enqueued).
+				Uint64("published", stats.Published).
+				Uint64("publishErrors", stats.PublishErrors).
+				Uint64("dropped", stats.Dropped).
+				Uint64("blocked", stats.Blocked).
+				Msg("Publisher queue stats")
+		}
+	}
+}
+
+// syncDedup periodically flushes the dedup window to disk and logs the
+// duplicates it dropped
+func syncDedup(dedup *publisher.Dedup, interval time.Duration, stop <-chan struct{}) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	var dropped uint64
+	for {
+		select {
+		case <-stop:
+			return
+		case <-ticker.C:
+			if err := dedup.Sync(); err != nil {
+				logger.Log.Error().Err(err).Msg("Failed to sync trade dedup window")
+			}
+			if stats := dedup.Stats(); stats.Dropped != dropped {
+				logger.Log.Info().
+					Uint64("passed", stats.Passed).
+					Uint64("dropped", stats.Dropped).
+					Msg("Duplicate trades dropped")
+				dropped = stats.Dropped
+			}
+		}
+	}
+}
+
+// logShardHealth periodically logs the symbols of the shard that failed to
+// subscribe or stopped trading
+func logShardHealth(coordinator *shard.Coordinator, interval time.Duration, stop <-chan struct{}) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-stop:
+			return
+		case <-ticker.C:
+			for _, symbol := range coordinator.Status().Symbols {
+				if symbol.Stale || symbol.Error != "" {
+					logger.Log.Warn().
+						Str("symbol", symbol.Symbol).
+						Bool("stale", symbol.Stale).
+						Int64("lastTrade", symbol.LastTrade).
+						Str("error", symbol.Error).
+						Msg("Shard symbol unhealthy")
+				}
+			}
+		}
+	}
+}
+
 func main() {
 	// Define flags
 	var configFile string
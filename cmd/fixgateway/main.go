@@ -0,0 +1,378 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"os"
+	"syscall"
+	"time"
+
+	"github.com/BullionBear/sequex/env"
+	"github.com/BullionBear/sequex/internal/allocation"
+	"github.com/BullionBear/sequex/internal/announce"
+	"github.com/BullionBear/sequex/internal/config"
+	"github.com/BullionBear/sequex/internal/fixgateway"
+	"github.com/BullionBear/sequex/internal/fx"
+	"github.com/BullionBear/sequex/internal/guardian"
+	"github.com/BullionBear/sequex/internal/lifecycle"
+	"github.com/BullionBear/sequex/internal/model/sqx"
+	"github.com/BullionBear/sequex/internal/sysevent"
+	"github.com/BullionBear/sequex/internal/tenant"
+	"github.com/BullionBear/sequex/pkg/diagnostics"
+	"github.com/BullionBear/sequex/pkg/eventbus"
+	"github.com/BullionBear/sequex/pkg/fix"
+	"github.com/BullionBear/sequex/pkg/logger"
+	"github.com/BullionBear/sequex/pkg/shutdown"
+	"github.com/nats-io/nats.go"
+)
+
+// natsPublisher publishes OMS intents to NATS, under the subject prefix of
+// the tenant placing them
+type natsPublisher struct {
+	bus           *eventbus.Bus
+	orderSubject  string
+	cancelSubject string
+}
+
+func (p *natsPublisher) PublishOrder(t *tenant.Tenant, intent sqx.OrderIntent) error {
+	data, err := intent.Marshal()
+	if err != nil {
+		return err
+	}
+	return p.bus.Publish(t.Subject(p.orderSubject), data)
+}
+
+func (p *natsPublisher) PublishCancel(t *tenant.Tenant, intent sqx.CancelIntent) error {
+	data, err := intent.Marshal()
+	if err != nil {
+		return err
+	}
+	return p.bus.Publish(t.Subject(p.cancelSubject), data)
+}
+
+// runFIXGateway executes the main FIX gateway logic
+func runFIXGateway(configFile string) {
+	logger.Log.Info().
+		Str("version", env.Version).
+		Str("buildTime", env.BuildTime).
+		Str("commitHash", env.CommitHash).
+		Msg("FIX gateway started")
+
+	cfg, err := config.LoadFIXConfig(configFile)
+	if err != nil {
+		logger.Log.Error().Err(err).Msg("Failed to load config")
+		os.Exit(1)
+	}
+
+	shutdown := shutdown.NewShutdown(logger.Log)
+
+	natsConn, err := nats.Connect(cfg.NATS.URIs)
+	if err != nil {
+		logger.Log.Error().Err(err).Msg("Failed to connect to NATS")
+		os.Exit(1)
+	}
+	defer natsConn.Close()
+
+	diag, stopDiagnostics, err := diagnostics.Start(cfg.Diagnostics.Options("fixgateway"), natsConn, logger.Log)
+	if err != nil {
+		logger.Log.Error().Err(err).Msg("Failed to start diagnostics")
+		os.Exit(1)
+	}
+	shutdown.HookShutdownCallback("diagnostics", stopDiagnostics, 10*time.Second)
+	sysEvents, err := cfg.Diagnostics.EventPublisher(natsConn, "fixgateway", logger.Log)
+	if err != nil {
+		logger.Log.Error().Err(err).Msg("Failed to create system event publisher")
+		os.Exit(1)
+	}
+	defer sysEvents.Close()
+	sysEvents.WatchConn(natsConn)
+	busOpts, err := cfg.NATS.BusOptions()
+	if err != nil {
+		logger.Log.Error().Err(err).Msg("Invalid event bus options")
+		os.Exit(1)
+	}
+	if busOpts.Authorizer, err = cfg.NATS.Authorizer(natsConn, "fixgateway"); err != nil {
+		logger.Log.Error().Err(err).Msg("Invalid command policy")
+		os.Exit(1)
+	}
+	bus, err := eventbus.NewBus(natsConn, busOpts)
+	if err != nil {
+		logger.Log.Error().Err(err).Msg("Failed to create event bus")
+		os.Exit(1)
+	}
+	laneConn, err := cfg.NATS.ConnectPriorityLane(bus)
+	if err != nil {
+		logger.Log.Error().Err(err).Msg("Failed to connect the priority lane")
+		os.Exit(1)
+	}
+	if laneConn != nil {
+		defer laneConn.Close()
+	}
+
+	gateway := fixgateway.NewGateway(&natsPublisher{
+		bus:           bus,
+		orderSubject:  cfg.OrderSubject,
+		cancelSubject: cfg.CancelSubject,
+	}, sqx.NewExchange(cfg.DefaultExchange))
+
+	tenants, err := config.TenantRegistry(cfg.Tenants)
+	if err != nil {
+		logger.Log.Error().Err(err).Msg("Invalid tenants")
+		os.Exit(1)
+	}
+	sessions := make([]fix.SessionID, 0, len(cfg.Sessions))
+	sessionTenants := make(map[fix.SessionID]*tenant.Tenant)
+	logons := make(map[fix.SessionID]fix.LogonAuth)
+	for _, s := range cfg.Sessions {
+		id := fix.SessionID{SenderCompID: s.SenderCompID, TargetCompID: s.TargetCompID}
+		sessions = append(sessions, id)
+		if t, ok := tenants.Get(s.Tenant); ok {
+			sessionTenants[id] = t
+		}
+		if logons[id], err = s.LogonAuth(); err != nil {
+			logger.Log.Error().Err(err).Str("session", id.String()).Msg("Invalid session logon")
+			os.Exit(1)
+		}
+	}
+	gateway.SetTenants(sessionTenants)
+	acceptorCfg := fix.AcceptorConfig{
+		Addr:     cfg.Addr,
+		Sessions: sessions,
+		Logons:   logons,
+		StoreDir: cfg.StoreDir,
+	}
+	if cfg.TLS != nil {
+		if acceptorCfg.TLS, err = cfg.TLS.Config(); err != nil {
+			logger.Log.Error().Err(err).Msg("Invalid tls")
+			os.Exit(1)
+		}
+	}
+	acceptor, err := fix.NewAcceptor(acceptorCfg, gateway)
+	if err != nil {
+		logger.Log.Error().Err(err).Msg("Failed to create FIX acceptor")
+		os.Exit(1)
+	}
+	gateway.SetSender(acceptor)
+
+	// Each tenant receives the reports published under its subject prefix
+	// only, so its sessions never see the orders of another
+	var subs []*nats.Subscription
+	for _, t := range append([]*tenant.Tenant{nil}, tenants.Tenants()...) {
+		sub, err := bus.Subscribe(t.Subject(cfg.ExecutionSubject), func(msg *nats.Msg) {
+			var report sqx.ExecutionReport
+			if err := sqx.UnmarshalExecutionReport(msg.Data, &report); err != nil {
+				logger.Log.Error().Err(err).Str("subject", msg.Subject).Msg("Failed to unmarshal execution report")
+				return
+			}
+			gateway.OnExecutionReport(t, report)
+		})
+		if err != nil {
+			logger.Log.Error().Err(err).Str("subject", t.Subject(cfg.ExecutionSubject)).Msg("Failed to subscribe to execution reports")
+			os.Exit(1)
+		}
+		subs = append(subs, sub)
+	}
+	diag.AddQueueSource(diagnostics.SubscriptionSource(subs...))
+
+	// Announcements and symbol status changes are replayed from the start of
+	// their streams so the halts in force before the gateway started apply
+	var haltSubs []*nats.Subscription
+	if cfg.HaltSubject != "" || cfg.StatusSubject != "" {
+		js, err := natsConn.JetStream()
+		if err != nil {
+			logger.Log.Error().Err(err).Msg("Failed to create JetStream context")
+			os.Exit(1)
+		}
+		halts := announce.NewHalts(time.Duration(cfg.HaltLeadMs) * time.Millisecond)
+		subscribe := func(subject string, apply func(data []byte) error) {
+			if subject == "" {
+				return
+			}
+			haltSub, err := js.Subscribe(subject, func(msg *nats.Msg) {
+				if err := apply(msg.Data); err != nil {
+					logger.Log.Error().Err(err).Str("subject", msg.Subject).Msg("Failed to unmarshal halt")
+				}
+			}, nats.OrderedConsumer(), nats.DeliverAll())
+			if err != nil {
+				logger.Log.Error().Err(err).Str("subject", subject).Msg("Failed to subscribe to halts")
+				os.Exit(1)
+			}
+			haltSubs = append(haltSubs, haltSub)
+		}
+		subscribe(cfg.HaltSubject, func(data []byte) error {
+			var a announce.Announcement
+			if err := json.Unmarshal(data, &a); err != nil {
+				return err
+			}
+			halts.Apply(a)
+			return nil
+		})
+		subscribe(cfg.StatusSubject, func(data []byte) error {
+			var change lifecycle.Change
+			if err := json.Unmarshal(data, &change); err != nil {
+				return err
+			}
+			halts.ApplyChange(change)
+			return nil
+		})
+		gateway.SetHalts(halts)
+	}
+
+	// Budgets and drawdown limits are enforced before the acceptor starts,
+	// valuing positions and market orders at the last trade prices
+	var allocationSubs []*nats.Subscription
+	var rates *fx.Rates
+	if cfg.Allocations != nil || cfg.Guardian != nil {
+		rates, err = cfg.FX.Rates()
+		if err != nil {
+			logger.Log.Error().Err(err).Msg("Invalid rates")
+			os.Exit(1)
+		}
+		for _, input := range cfg.FX.Inputs {
+			sub, err := natsConn.Subscribe(input, func(msg *nats.Msg) {
+				if err := eventbus.Decode(msg); err != nil {
+					logger.Log.Error().Err(err).Str("subject", msg.Subject).Msg("Failed to decode trade")
+					return
+				}
+				trades, err := sqx.UnmarshalTrades(msg.Data, msg.Header.Get(sqx.HeaderTradeBatch))
+				if err != nil {
+					logger.Log.Error().Err(err).Str("subject", msg.Subject).Msg("Failed to unmarshal trade")
+					return
+				}
+				for _, trade := range trades {
+					rates.OnTrade(trade)
+				}
+			})
+			if err != nil {
+				logger.Log.Error().Err(err).Str("subject", input).Msg("Failed to subscribe to FX input")
+				os.Exit(1)
+			}
+			allocationSubs = append(allocationSubs, sub)
+		}
+	}
+	if cfg.Allocations != nil {
+		book, err := cfg.Allocations.Book(rates)
+		if err != nil {
+			logger.Log.Error().Err(err).Msg("Failed to open allocations")
+			os.Exit(1)
+		}
+		defer book.Close()
+		book.OnChange(func(c allocation.Change) {
+			event := logger.Log.Info().Str("strategy", c.Strategy).Str("by", c.By).Str("reason", c.Reason)
+			if c.Before != nil {
+				event = event.Float64("before", c.Before.Budget)
+			}
+			if c.After != nil {
+				event = event.Str("portfolio", c.After.Portfolio).Float64("budget", c.After.Budget)
+			}
+			event.Msg("Allocation changed")
+		})
+		subject := cfg.Allocations.RPCSubject("fixgateway")
+		subs, err := allocation.Serve(bus, subject, book)
+		if err != nil {
+			logger.Log.Error().Err(err).Str("subject", subject).Msg("Failed to serve allocation RPCs")
+			os.Exit(1)
+		}
+		allocationSubs = append(allocationSubs, subs...)
+		gateway.SetAllocations(book)
+		logger.Log.Info().Int("allocations", len(book.Allocations())).Str("subject", subject).Msg("Enforcing strategy budgets")
+	}
+	if cfg.Guardian != nil {
+		guard, err := cfg.Guardian.Guardian(rates)
+		if err != nil {
+			logger.Log.Error().Err(err).Msg("Failed to open guardian")
+			os.Exit(1)
+		}
+		defer guard.Close()
+		guard.OnEvent(func(e guardian.Event) {
+			logger.Log.Warn().
+				Str("strategy", e.Strategy).
+				Str("action", e.Action).
+				Bool("automatic", e.Automatic).
+				Float64("drawdown", e.Drawdown).
+				Int("canceled", e.Canceled).
+				Str("by", e.By).
+				Str("reason", e.Reason).
+				Msg("Strategy guardian event")
+			sysEvents.Publish(sysevent.GuardianEvent(e))
+		})
+		subject := cfg.Guardian.RPCSubject("fixgateway")
+		subs, err := guardian.Serve(bus, subject, guard)
+		if err != nil {
+			logger.Log.Error().Err(err).Str("subject", subject).Msg("Failed to serve guardian RPCs")
+			os.Exit(1)
+		}
+		allocationSubs = append(allocationSubs, subs...)
+		gateway.SetGuardian(guard)
+		ctx, cancel := context.WithCancel(context.Background())
+		go guard.Run(ctx, cfg.Guardian.Interval())
+		shutdown.HookShutdownCallback("guardian", cancel, time.Second)
+		logger.Log.Info().Str("subject", subject).Msg("Enforcing strategy drawdown limits")
+	}
+
+	if err := acceptor.Start(); err != nil {
+		logger.Log.Error().Err(err).Msg("Failed to start FIX acceptor")
+		os.Exit(1)
+	}
+
+	shutdown.HookShutdownCallback("fix acceptor", func() {
+		for _, sub := range subs {
+			if err := sub.Unsubscribe(); err != nil {
+				logger.Log.Error().Err(err).Msg("Failed to unsubscribe from execution reports")
+			}
+		}
+		for _, sub := range allocationSubs {
+			if err := sub.Unsubscribe(); err != nil {
+				logger.Log.Error().Err(err).Msg("Failed to unsubscribe from allocations and guardian")
+			}
+		}
+		for _, haltSub := range haltSubs {
+			if err := haltSub.Unsubscribe(); err != nil {
+				logger.Log.Error().Err(err).Msg("Failed to unsubscribe from halts")
+			}
+		}
+		acceptor.Stop()
+	}, 10*time.Second)
+
+	shutdown.WaitForShutdown(syscall.SIGINT, syscall.SIGTERM)
+	logger.Log.Info().Msg("FIX gateway command executed successfully!")
+}
+
+func main() {
+	var configFile string
+	flag.StringVar(&configFile, "c", "", "Configuration file path (required)")
+
+	flag.Usage = func() {
+		logger.Log.Info().Msg(`FIX gateway is a FIX 4.4 acceptor translating NewOrderSingle and
+OrderCancelRequest messages into OMS intents and returning ExecutionReports.
+Sessions log on with the Username and Password of their configuration, from
+their allowed addresses; the gateway serves them over TLS, or plaintext on
+loopback only unless explicitly allowed.
+Orders on symbols halted by exchange announcements, e.g. scheduled for
+delisting, or no longer trading are rejected when halt or status subjects
+are configured. Sessions of a tenant trade under its subject prefix, on its
+exchange accounts and within its order limits. Strategies with allocations
+are kept within their budgets, adjusted at runtime with sqx allocations.
+Strategies breaching their drawdown limits are paused, their working orders
+canceled, until resumed with sqx guardian.
+
+Usage:
+  fixgateway -c <config-file>
+
+Examples:
+  fixgateway -c config/fixgateway.json
+`)
+		flag.PrintDefaults()
+	}
+
+	flag.Parse()
+
+	if configFile == "" {
+		logger.Log.Error().Msg("config file path is required")
+		flag.Usage()
+		os.Exit(1)
+	}
+
+	runFIXGateway(configFile)
+}
@@ -0,0 +1,164 @@
+package main
+
+import (
+	"flag"
+	"net"
+	"os"
+	"syscall"
+	"time"
+
+	"github.com/BullionBear/sequex/env"
+	"github.com/BullionBear/sequex/internal/config"
+	"github.com/BullionBear/sequex/internal/grpcgateway"
+	"github.com/BullionBear/sequex/pkg/diagnostics"
+	"github.com/BullionBear/sequex/pkg/eventbus"
+	"github.com/BullionBear/sequex/pkg/logger"
+	"github.com/BullionBear/sequex/pkg/shutdown"
+	"github.com/nats-io/nats.go"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/health"
+	"google.golang.org/grpc/health/grpc_health_v1"
+)
+
+// runGateway executes the main gRPC gateway logic
+func runGateway(configFile string) {
+	logger.Log.Info().
+		Str("version", env.Version).
+		Str("buildTime", env.BuildTime).
+		Str("commitHash", env.CommitHash).
+		Msg("gRPC gateway started")
+
+	cfg, err := config.LoadGRPCGatewayConfig(configFile)
+	if err != nil {
+		logger.Log.Error().Err(err).Msg("Failed to load config")
+		os.Exit(1)
+	}
+
+	shutdown := shutdown.NewShutdown(logger.Log)
+
+	natsConn, err := nats.Connect(cfg.NATS.URIs)
+	if err != nil {
+		logger.Log.Error().Err(err).Msg("Failed to connect to NATS")
+		os.Exit(1)
+	}
+	defer natsConn.Close()
+
+	_, stopDiagnostics, err := diagnostics.Start(cfg.Diagnostics.Options("grpcgateway"), natsConn, logger.Log)
+	if err != nil {
+		logger.Log.Error().Err(err).Msg("Failed to start diagnostics")
+		os.Exit(1)
+	}
+	shutdown.HookShutdownCallback("diagnostics", stopDiagnostics, 10*time.Second)
+
+	busOpts, err := cfg.NATS.BusOptions()
+	if err != nil {
+		logger.Log.Error().Err(err).Msg("Invalid event bus options")
+		os.Exit(1)
+	}
+	if busOpts.Authorizer, err = cfg.NATS.Authorizer(natsConn, "grpcgateway"); err != nil {
+		logger.Log.Error().Err(err).Msg("Invalid command policy")
+		os.Exit(1)
+	}
+	bus, err := eventbus.NewBus(natsConn, busOpts)
+	if err != nil {
+		logger.Log.Error().Err(err).Msg("Failed to create event bus")
+		os.Exit(1)
+	}
+
+	subscribe := func(subject string, handler func(msg *nats.Msg)) (func(), error) {
+		sub, err := bus.Subscribe(subject, handler)
+		if err != nil {
+			return nil, err
+		}
+		return func() {
+			if err := bus.Release(sub).Unsubscribe(); err != nil {
+				logger.Log.Error().Err(err).Str("subject", subject).Msg("Failed to unsubscribe")
+			}
+		}, nil
+	}
+
+	server, err := grpcgateway.NewServer(subscribe, bus.Publish, cfg.GatewayOptions())
+	if err != nil {
+		logger.Log.Error().Err(err).Msg("Invalid gateway options")
+		os.Exit(1)
+	}
+
+	var serverOpts []grpc.ServerOption
+	if cfg.TLS != nil {
+		tlsCfg, err := cfg.TLS.Config()
+		if err != nil {
+			logger.Log.Error().Err(err).Msg("Invalid tls")
+			os.Exit(1)
+		}
+		serverOpts = append(serverOpts, grpc.Creds(credentials.NewTLS(tlsCfg)))
+	}
+	g := grpc.NewServer(serverOpts...)
+	server.Register(g)
+	healthServer := health.NewServer()
+	grpc_health_v1.RegisterHealthServer(g, healthServer)
+
+	lis, err := net.Listen("tcp", cfg.Addr)
+	if err != nil {
+		logger.Log.Error().Err(err).Str("addr", cfg.Addr).Msg("Failed to listen")
+		os.Exit(1)
+	}
+	go func() {
+		logger.Log.Info().Str("addr", lis.Addr().String()).Bool("tls", cfg.TLS != nil).Msg("gRPC gateway listening")
+		if err := g.Serve(lis); err != nil {
+			logger.Log.Error().Err(err).Msg("gRPC gateway server failed")
+			os.Exit(1)
+		}
+	}()
+
+	shutdown.HookShutdownCallback("grpc server", func() {
+		healthServer.Shutdown()
+		stopped := make(chan struct{})
+		go func() {
+			g.GracefulStop()
+			close(stopped)
+		}()
+		// Subscription streams never end by themselves, so they are cut
+		// after a grace period
+		select {
+		case <-stopped:
+		case <-time.After(5 * time.Second):
+			g.Stop()
+		}
+	}, 10*time.Second)
+
+	shutdown.WaitForShutdown(syscall.SIGINT, syscall.SIGTERM)
+	logger.Log.Info().Msg("gRPC gateway command executed successfully!")
+}
+
+func main() {
+	var configFile string
+	flag.StringVar(&configFile, "c", "", "Configuration file path (required)")
+
+	flag.Usage = func() {
+		logger.Log.Info().Msg(`gRPC gateway serves the SequexService: trades, klines and depth streamed
+to external clients by symbol, and events exchanged between external
+strategy bridges and the bus. Clients authenticate with an authorization:
+Bearer <token> metadata entry and are limited to the subjects granted to
+their token. The gateway serves TLS, or plaintext on loopback only unless
+explicitly allowed, and the standard gRPC health service.
+
+Usage:
+  grpcgateway -c <config-file>
+
+Examples:
+  grpcgateway -c config/grpcgateway.json
+`)
+		flag.PrintDefaults()
+	}
+
+	flag.Parse()
+
+	if configFile == "" {
+		logger.Log.Error().Msg("config file path is required")
+		flag.Usage()
+		os.Exit(1)
+	}
+
+	runGateway(configFile)
+}
@@ -0,0 +1,91 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"sort"
+
+	"github.com/BullionBear/sequex/internal/impact"
+	"github.com/BullionBear/sequex/internal/model/protobuf"
+	"github.com/BullionBear/sequex/internal/model/sqx"
+	"github.com/BullionBear/sequex/internal/replay"
+)
+
+func main() {
+	outputFile := flag.String("o", "", "output file of the parameters (default: stdout)")
+	gap := flag.Duration("gap", 0, "longest pause within a run of same side trades (default 1s)")
+	horizon := flag.Duration("horizon", 0, "delay after a run at which its impact is measured (default 10s)")
+	minSpan := flag.Duration("min-span", 0, "shortest recording calibrated per symbol (default 1h)")
+	flag.Usage = func() {
+		fmt.Fprintf(os.Stderr, `Usage: %s [options] <file.raw>...
+
+Calibrates a square-root market impact model per symbol from recorded
+trades and writes its parameters as JSON, for slippage models and order
+routing.
+
+Options:
+`, os.Args[0])
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+	if flag.NArg() == 0 {
+		flag.Usage()
+		os.Exit(1)
+	}
+
+	trades := make(map[string][]sqx.Trade)
+	for _, file := range flag.Args() {
+		_, _, err := replay.ReadTradeFile(file, func(_ int, pb *protobuf.Trade) error {
+			var trade sqx.Trade
+			if err := trade.FromProtobuf(pb); err != nil {
+				return nil // Skipped like undecodable bytes
+			}
+			key := trade.Exchange.String() + "." + trade.InstrumentType.String() + "." + trade.Symbol.String()
+			trades[key] = append(trades[key], trade)
+			return nil
+		})
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: failed to read %s: %v\n", file, err)
+			os.Exit(1)
+		}
+	}
+
+	keys := make([]string, 0, len(trades))
+	for key := range trades {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	opts := impact.Options{Gap: *gap, Horizon: *horizon, MinSpan: *minSpan}
+	params := make([]impact.Params, 0, len(keys))
+	for _, key := range keys {
+		p, err := impact.Calibrate(trades[key], opts)
+		if errors.Is(err, impact.ErrInsufficientData) {
+			fmt.Fprintf(os.Stderr, "Skipping %s: %v\n", key, err)
+			continue
+		}
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: failed to calibrate %s: %v\n", key, err)
+			os.Exit(1)
+		}
+		fmt.Fprintf(os.Stderr, "%s: Y=%.3f delta=%.3f (r2 %.2f) sigma=%.4f daily volume=%.2f from %d runs\n",
+			key, p.Y, p.Delta, p.R2, p.Sigma, p.DailyVolume, p.Samples)
+		params = append(params, p)
+	}
+
+	if *outputFile != "" {
+		if err := impact.Save(*outputFile, params); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: failed to write %s: %v\n", *outputFile, err)
+			os.Exit(1)
+		}
+		return
+	}
+	encoder := json.NewEncoder(os.Stdout)
+	encoder.SetIndent("", "  ")
+	if err := encoder.Encode(params); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+}
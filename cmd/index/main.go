@@ -0,0 +1,202 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/BullionBear/sequex/env"
+	"github.com/BullionBear/sequex/internal/config"
+	"github.com/BullionBear/sequex/internal/index"
+	"github.com/BullionBear/sequex/internal/model/sqx"
+	"github.com/BullionBear/sequex/pkg/diagnostics"
+	"github.com/BullionBear/sequex/pkg/eventbus"
+	"github.com/BullionBear/sequex/pkg/logger"
+	"github.com/BullionBear/sequex/pkg/shutdown"
+	"github.com/nats-io/nats.go"
+)
+
+// runIndex executes the main index calculator logic
+func runIndex(configFile string) {
+	logger.Log.Info().
+		Str("version", env.Version).
+		Str("buildTime", env.BuildTime).
+		Str("commitHash", env.CommitHash).
+		Msg("Index started")
+
+	cfg, err := config.LoadIndexConfig(configFile)
+	if err != nil {
+		logger.Log.Error().Err(err).Msg("Failed to load config")
+		os.Exit(1)
+	}
+
+	baskets, err := buildBaskets(cfg.Baskets)
+	if err != nil {
+		logger.Log.Error().Err(err).Msg("Invalid basket definition")
+		os.Exit(1)
+	}
+
+	calculator, err := index.NewCalculator(baskets)
+	if err != nil {
+		logger.Log.Error().Err(err).Msg("Failed to create index calculator")
+		os.Exit(1)
+	}
+
+	shutdown := shutdown.NewShutdown(logger.Log)
+
+	natsConn, err := nats.Connect(cfg.NATS.URIs)
+	if err != nil {
+		logger.Log.Error().Err(err).Msg("Failed to connect to NATS")
+		os.Exit(1)
+	}
+	defer natsConn.Close()
+
+	diag, stopDiagnostics, err := diagnostics.Start(cfg.Diagnostics.Options("index"), natsConn, logger.Log)
+	if err != nil {
+		logger.Log.Error().Err(err).Msg("Failed to start diagnostics")
+		os.Exit(1)
+	}
+	shutdown.HookShutdownCallback("diagnostics", stopDiagnostics, 10*time.Second)
+	js, err := natsConn.JetStream()
+	if err != nil {
+		logger.Log.Error().Err(err).Msg("Failed to create JetStream context")
+		os.Exit(1)
+	}
+	if _, err := js.StreamInfo(cfg.NATS.Stream); err != nil {
+		logger.Log.Error().Err(err).Msg("Failed to get stream info")
+		os.Exit(1)
+	}
+	busOpts, err := cfg.NATS.BusOptions()
+	if err != nil {
+		logger.Log.Error().Err(err).Msg("Invalid event bus options")
+		os.Exit(1)
+	}
+	bus, err := eventbus.NewBus(natsConn, busOpts)
+	if err != nil {
+		logger.Log.Error().Err(err).Msg("Failed to create event bus")
+		os.Exit(1)
+	}
+
+	minInterval := time.Duration(cfg.MinIntervalMs) * time.Millisecond
+	lastPublished := make(map[string]time.Time)
+
+	handler := func(msg *nats.Msg) {
+		trades, err := sqx.UnmarshalTrades(msg.Data, msg.Header.Get(sqx.HeaderTradeBatch))
+		if err != nil {
+			logger.Log.Error().Err(err).Str("subject", msg.Subject).Msg("Failed to unmarshal trade")
+			return
+		}
+
+		var prices []sqx.IndexPrice
+		for _, trade := range trades {
+			prices = append(prices, calculator.OnTrade(trade)...)
+		}
+		for _, price := range prices {
+			now := time.Now()
+			if now.Sub(lastPublished[price.Name]) < minInterval {
+				continue
+			}
+			lastPublished[price.Name] = now
+
+			data, err := price.Marshal()
+			if err != nil {
+				logger.Log.Error().Err(err).Msg("Failed to marshal index price")
+				continue
+			}
+			msg := &nats.Msg{
+				Subject: fmt.Sprintf("%s.%s", cfg.NATS.Subject, strings.ToLower(price.Name)),
+				Data:    data,
+				Header: nats.Header{
+					"Nats-Msg-Id": []string{price.IdStr()},
+				},
+			}
+			if err := bus.Encode(msg); err != nil {
+				logger.Log.Error().Err(err).Str("index", price.Name).Msg("Failed to encode index price")
+				continue
+			}
+			if _, err := js.PublishMsg(msg); err != nil {
+				logger.Log.Error().Err(err).Str("index", price.Name).Msg("Failed to publish index price")
+			}
+		}
+	}
+
+	for _, input := range cfg.Inputs {
+		// A single subscription callback goroutine keeps lastPublished free of data races
+		sub, err := natsConn.Subscribe(input, handler)
+		if err != nil {
+			logger.Log.Error().Err(err).Str("subject", input).Msg("Failed to subscribe to input")
+			os.Exit(1)
+		}
+		diag.AddQueueSource(diagnostics.SubscriptionSource(sub))
+		shutdown.HookShutdownCallback("unsubscribe "+input, func() {
+			if err := sub.Unsubscribe(); err != nil {
+				logger.Log.Error().Err(err).Str("subject", input).Msg("Failed to unsubscribe")
+			}
+		}, 10*time.Second)
+		logger.Log.Info().Str("subject", input).Msg("Subscribed to input")
+	}
+
+	shutdown.WaitForShutdown(syscall.SIGINT, syscall.SIGTERM)
+	logger.Log.Info().Msg("Index command executed successfully!")
+}
+
+// buildBaskets converts the basket configuration into index baskets
+func buildBaskets(cfgs []config.BasketConfig) ([]index.Basket, error) {
+	baskets := make([]index.Basket, 0, len(cfgs))
+	for _, cfg := range cfgs {
+		basket := index.Basket{
+			Name:      strings.ToUpper(cfg.Name),
+			Method:    index.Method(strings.ToLower(cfg.Method)),
+			Divisor:   cfg.Divisor,
+			BaseValue: cfg.BaseValue,
+		}
+		for _, c := range cfg.Components {
+			symbol, err := sqx.NewSymbolFromStr(c.Symbol)
+			if err != nil {
+				return nil, fmt.Errorf("basket %s: %w", cfg.Name, err)
+			}
+			basket.Components = append(basket.Components, index.Component{
+				Exchange:   sqx.NewExchange(c.Exchange),
+				Instrument: sqx.NewInstrumentType(c.Instrument),
+				Symbol:     symbol,
+				Weight:     c.Weight,
+			})
+		}
+		if err := basket.Validate(); err != nil {
+			return nil, err
+		}
+		baskets = append(baskets, basket)
+	}
+	return baskets, nil
+}
+
+func main() {
+	var configFile string
+	flag.StringVar(&configFile, "c", "", "Configuration file path (required)")
+
+	flag.Usage = func() {
+		logger.Log.Info().Msg(`Index computes synthetic index prices from weighted baskets of instruments
+and publishes them to NATS as index.<name> series.
+
+Usage:
+  index -c <config-file>
+
+Examples:
+  index -c config/index-binance-basis-btcusdt.json
+`)
+		flag.PrintDefaults()
+	}
+
+	flag.Parse()
+
+	if configFile == "" {
+		logger.Log.Error().Msg("config file path is required")
+		flag.Usage()
+		os.Exit(1)
+	}
+
+	runIndex(configFile)
+}
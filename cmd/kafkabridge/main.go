@@ -0,0 +1,248 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"os"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/BullionBear/sequex/env"
+	"github.com/BullionBear/sequex/internal/bridge"
+	"github.com/BullionBear/sequex/internal/config"
+	"github.com/BullionBear/sequex/internal/publisher"
+	"github.com/BullionBear/sequex/pkg/diagnostics"
+	"github.com/BullionBear/sequex/pkg/eventbus"
+	"github.com/BullionBear/sequex/pkg/kafka"
+	"github.com/BullionBear/sequex/pkg/logger"
+	"github.com/BullionBear/sequex/pkg/shutdown"
+	"github.com/nats-io/nats.go"
+)
+
+const (
+	defaultBatchSize = 100
+	fetchWait        = time.Second
+	fetchMaxBytes    = 1 << 20
+	retryBackoff     = time.Second
+)
+
+// runKafkaBridge executes the main Kafka bridge logic
+func runKafkaBridge(configFile string) {
+	logger.Log.Info().
+		Str("version", env.Version).
+		Str("buildTime", env.BuildTime).
+		Str("commitHash", env.CommitHash).
+		Msg("Kafka bridge started")
+
+	cfg, err := config.LoadKafkaBridgeConfig(configFile)
+	if err != nil {
+		logger.Log.Error().Err(err).Msg("Failed to load config")
+		os.Exit(1)
+	}
+	batchSize := cfg.BatchSize
+	if batchSize == 0 {
+		batchSize = defaultBatchSize
+	}
+
+	shutdown := shutdown.NewShutdown(logger.Log)
+
+	natsConn, err := nats.Connect(cfg.NATS.URIs)
+	if err != nil {
+		logger.Log.Error().Err(err).Msg("Failed to connect to NATS")
+		os.Exit(1)
+	}
+	defer natsConn.Close()
+
+	_, stopDiagnostics, err := diagnostics.Start(cfg.Diagnostics.Options("kafkabridge"), natsConn, logger.Log)
+	if err != nil {
+		logger.Log.Error().Err(err).Msg("Failed to start diagnostics")
+		os.Exit(1)
+	}
+	shutdown.HookShutdownCallback("diagnostics", stopDiagnostics, 10*time.Second)
+	js, err := natsConn.JetStream()
+	if err != nil {
+		logger.Log.Error().Err(err).Msg("Failed to create JetStream context")
+		os.Exit(1)
+	}
+
+	client, err := kafka.NewClient(cfg.Brokers, cfg.ClientID, time.Duration(cfg.TimeoutMs)*time.Millisecond)
+	if err != nil {
+		logger.Log.Error().Err(err).Msg("Failed to connect to Kafka")
+		os.Exit(1)
+	}
+	defer client.Close()
+
+	done := make(chan struct{})
+	var wg sync.WaitGroup
+
+	for _, route := range cfg.Forward {
+		encoding, err := bridge.ParseEncoding(route.Encoding)
+		if err != nil {
+			logger.Log.Error().Err(err).Str("topic", route.Topic).Msg("Invalid forward route")
+			os.Exit(1)
+		}
+		sub, err := js.PullSubscribe(route.Subject, route.Durable, nats.BindStream(route.Stream), nats.AckExplicit())
+		if err != nil {
+			logger.Log.Error().Err(err).Str("subject", route.Subject).Msg("Failed to create pull subscription")
+			os.Exit(1)
+		}
+		sink := bridge.NewKafkaSink(client, route.Topic, encoding)
+
+		wg.Add(1)
+		go func(route config.KafkaForwardConfig) {
+			defer wg.Done()
+			forward(sub, sink, route, batchSize, done)
+		}(route)
+		logger.Log.Info().Str("subject", route.Subject).Str("topic", route.Topic).Msg("Forwarding NATS to Kafka")
+	}
+
+	if len(cfg.Reverse) > 0 {
+		checkpoints, err := bridge.NewFileCheckpointStore(cfg.CheckpointFile)
+		if err != nil {
+			logger.Log.Error().Err(err).Msg("Failed to open checkpoint store")
+			os.Exit(1)
+		}
+		publish := func(subject string, data []byte, msgId string) error {
+			_, err := js.PublishMsg(&nats.Msg{
+				Subject: subject,
+				Data:    data,
+				Header:  nats.Header{"Nats-Msg-Id": []string{msgId}},
+			})
+			return err
+		}
+
+		for _, route := range cfg.Reverse {
+			encoding, err := bridge.ParseEncoding(route.Encoding)
+			if err != nil {
+				logger.Log.Error().Err(err).Str("topic", route.Topic).Msg("Invalid reverse route")
+				os.Exit(1)
+			}
+			startAt := kafka.OffsetLatest
+			if route.Start == "earliest" {
+				startAt = kafka.OffsetEarliest
+			}
+			partitions, err := client.Partitions(route.Topic)
+			if err != nil {
+				logger.Log.Error().Err(err).Str("topic", route.Topic).Msg("Failed to get topic partitions")
+				os.Exit(1)
+			}
+			for partition := 0; partition < partitions; partition++ {
+				source := bridge.NewKafkaSource(client, route.Topic, partition, route.Subject, encoding, checkpoints, startAt, publish)
+				wg.Add(1)
+				go func() {
+					defer wg.Done()
+					reverse(source, route.Topic, partition, done)
+				}()
+			}
+			logger.Log.Info().Str("topic", route.Topic).Int("partitions", partitions).Msg("Forwarding Kafka to NATS")
+		}
+	}
+
+	shutdown.HookShutdownCallback("kafka bridge", func() {
+		close(done)
+		wg.Wait()
+	}, 10*time.Second)
+
+	shutdown.WaitForShutdown(syscall.SIGINT, syscall.SIGTERM)
+	logger.Log.Info().Msg("Kafka bridge command executed successfully!")
+}
+
+// forward pulls batches from JetStream and acknowledges them only after Kafka
+// has accepted every record, so a crash in between leads to redelivery
+func forward(sub *nats.Subscription, sink *bridge.KafkaSink, route config.KafkaForwardConfig, batchSize int, done <-chan struct{}) {
+	for {
+		select {
+		case <-done:
+			return
+		default:
+		}
+
+		msgs, err := sub.Fetch(batchSize, nats.MaxWait(fetchWait))
+		if err != nil {
+			if !errors.Is(err, nats.ErrTimeout) {
+				logger.Log.Error().Err(err).Str("subject", route.Subject).Msg("Failed to fetch from JetStream")
+				time.Sleep(retryBackoff)
+			}
+			continue
+		}
+
+		batch := make([]bridge.Message, 0, len(msgs))
+		for _, msg := range msgs {
+			if err := eventbus.Decode(msg); err != nil {
+				// A payload that cannot be decoded never will be, skip it instead of redelivering
+				logger.Log.Error().Err(err).Str("subject", msg.Subject).Msg("Failed to decode message")
+				continue
+			}
+			timestamp := time.Now()
+			if meta, err := msg.Metadata(); err == nil {
+				timestamp = meta.Timestamp
+			}
+			payloads, err := publisher.Unbatch(msg)
+			if err != nil {
+				logger.Log.Error().Err(err).Str("subject", msg.Subject).Msg("Failed to unbatch message")
+				continue
+			}
+			for _, data := range payloads {
+				batch = append(batch, bridge.Message{Subject: msg.Subject, Data: data, Timestamp: timestamp})
+			}
+		}
+
+		if err := sink.Deliver(batch); err != nil {
+			logger.Log.Error().Err(err).Str("topic", route.Topic).Int("messages", len(msgs)).Msg("Failed to deliver batch to Kafka")
+			for _, msg := range msgs {
+				msg.NakWithDelay(retryBackoff)
+			}
+			continue
+		}
+		for _, msg := range msgs {
+			if err := msg.Ack(); err != nil {
+				logger.Log.Error().Err(err).Str("subject", msg.Subject).Msg("Failed to ack message")
+			}
+		}
+	}
+}
+
+// reverse polls a Kafka partition and republishes its records to NATS
+func reverse(source *bridge.KafkaSource, topic string, partition int, done <-chan struct{}) {
+	for {
+		select {
+		case <-done:
+			return
+		default:
+		}
+
+		if _, err := source.Poll(fetchWait, fetchMaxBytes); err != nil {
+			logger.Log.Error().Err(err).Str("topic", topic).Int("partition", partition).Int64("offset", source.Offset()).Msg("Failed to mirror Kafka partition")
+			time.Sleep(retryBackoff)
+		}
+	}
+}
+
+func main() {
+	var configFile string
+	flag.StringVar(&configFile, "c", "", "Configuration file path (required)")
+
+	flag.Usage = func() {
+		logger.Log.Info().Msg(`Kafka bridge mirrors JetStream subjects to Kafka topics partitioned by
+symbol, and optionally mirrors Kafka topics back to NATS.
+
+Usage:
+  kafkabridge -c <config-file>
+
+Examples:
+  kafkabridge -c config/kafkabridge.json
+`)
+		flag.PrintDefaults()
+	}
+
+	flag.Parse()
+
+	if configFile == "" {
+		logger.Log.Error().Msg("config file path is required")
+		flag.Usage()
+		os.Exit(1)
+	}
+
+	runKafkaBridge(configFile)
+}
@@ -0,0 +1,206 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"os"
+	"syscall"
+	"time"
+
+	"github.com/BullionBear/sequex/env"
+	"github.com/BullionBear/sequex/internal/config"
+	"github.com/BullionBear/sequex/internal/lag"
+	"github.com/BullionBear/sequex/internal/sysevent"
+	"github.com/BullionBear/sequex/pkg/diagnostics"
+	"github.com/BullionBear/sequex/pkg/eventbus"
+	"github.com/BullionBear/sequex/pkg/logger"
+	"github.com/BullionBear/sequex/pkg/node"
+	"github.com/BullionBear/sequex/pkg/shutdown"
+	"github.com/nats-io/nats.go"
+)
+
+// runLagMonitor executes the main consumer lag monitor logic
+func runLagMonitor(configFile string) {
+	logger.Log.Info().
+		Str("version", env.Version).
+		Str("buildTime", env.BuildTime).
+		Str("commitHash", env.CommitHash).
+		Msg("Lag monitor started")
+
+	cfg, err := config.LoadLagConfig(configFile)
+	if err != nil {
+		logger.Log.Error().Err(err).Msg("Failed to load config")
+		os.Exit(1)
+	}
+
+	watcher, err := lag.New(cfg.Options())
+	if err != nil {
+		logger.Log.Error().Err(err).Msg("Failed to create lag watcher")
+		os.Exit(1)
+	}
+
+	shutdown := shutdown.NewShutdown(logger.Log)
+
+	natsConn, err := nats.Connect(cfg.NATS.URIs)
+	if err != nil {
+		logger.Log.Error().Err(err).Msg("Failed to connect to NATS")
+		os.Exit(1)
+	}
+	defer natsConn.Close()
+
+	diag, stopDiagnostics, err := diagnostics.Start(cfg.Diagnostics.Options("lagmonitor"), natsConn, logger.Log)
+	if err != nil {
+		logger.Log.Error().Err(err).Msg("Failed to start diagnostics")
+		os.Exit(1)
+	}
+	shutdown.HookShutdownCallback("diagnostics", stopDiagnostics, 10*time.Second)
+	sysEvents, err := cfg.Diagnostics.EventPublisher(natsConn, "lagmonitor", logger.Log)
+	if err != nil {
+		logger.Log.Error().Err(err).Msg("Failed to create system event publisher")
+		os.Exit(1)
+	}
+	defer sysEvents.Close()
+	sysEvents.WatchConn(natsConn)
+	js, err := natsConn.JetStream()
+	if err != nil {
+		logger.Log.Error().Err(err).Msg("Failed to create JetStream context")
+		os.Exit(1)
+	}
+	if _, err := js.StreamInfo(cfg.NATS.Stream); err != nil {
+		logger.Log.Error().Err(err).Msg("Failed to get stream info")
+		os.Exit(1)
+	}
+	busOpts, err := cfg.NATS.BusOptions()
+	if err != nil {
+		logger.Log.Error().Err(err).Msg("Invalid event bus options")
+		os.Exit(1)
+	}
+	bus, err := eventbus.NewBus(natsConn, busOpts)
+	if err != nil {
+		logger.Log.Error().Err(err).Msg("Failed to create event bus")
+		os.Exit(1)
+	}
+
+	publish := func(alerts []lag.Alert) {
+		for _, alert := range alerts {
+			event := logger.Log.Warn()
+			if !alert.Active {
+				event = logger.Log.Info()
+			}
+			event.Str("stream", alert.Stream).Str("consumer", alert.Consumer).Uint64("lag", alert.Lag).
+				Uint64("base", alert.Base).Time("since", time.UnixMilli(alert.Since)).Bool("active", alert.Active).
+				Msg("Consumer lag alert")
+
+			data, err := json.Marshal(alert)
+			if err != nil {
+				logger.Log.Error().Err(err).Msg("Failed to marshal alert")
+				continue
+			}
+			msg := &nats.Msg{Subject: cfg.NATS.Subject, Data: data}
+			if err := bus.Encode(msg); err != nil {
+				logger.Log.Error().Err(err).Msg("Failed to encode alert")
+				continue
+			}
+			if _, err := js.PublishMsg(msg); err != nil {
+				logger.Log.Error().Err(err).Msg("Failed to publish alert")
+			}
+		}
+	}
+
+	n := node.New("lagmonitor", bus, logger.Log)
+	if err := n.OnEvent(func(e node.Event) { sysEvents.Publish(sysevent.NodeEvent(e)) }); err != nil {
+		logger.Log.Error().Err(err).Msg("Failed to publish node events")
+		os.Exit(1)
+	}
+	if err := n.Emits(cfg.NATS.Subject, node.NewPayload("json", (*lag.Alert)(nil), "Consumer lag alerts")); err != nil {
+		logger.Log.Error().Err(err).Msg("Invalid alert subject")
+		os.Exit(1)
+	}
+	n.SetParams(cfg)
+	n.AddSnapshot("consumers", func() (any, error) { return watcher.Gauges(), nil })
+	n.AddSnapshot("alerts", func() (any, error) { return watcher.Active(), nil })
+
+	if err := n.Start(); err != nil {
+		logger.Log.Error().Err(err).Msg("Failed to start node")
+		os.Exit(1)
+	}
+	diag.AddQueueSource(watcher.QueueDepths)
+	shutdown.HookShutdownCallback("node", n.Stop, 10*time.Second)
+	if subject := cfg.Diagnostics.DescribeSubject("lagmonitor"); subject != "" {
+		sub, err := n.ServeDescription(subject)
+		if err != nil {
+			logger.Log.Error().Err(err).Msg("Failed to serve node description")
+			os.Exit(1)
+		}
+		shutdown.HookShutdownCallback("node description", func() { sub.Unsubscribe() }, 10*time.Second)
+	}
+	if subject := cfg.Diagnostics.SnapshotSubject("lagmonitor"); subject != "" {
+		sub, err := n.ServeSnapshot(subject)
+		if err != nil {
+			logger.Log.Error().Err(err).Msg("Failed to serve node snapshots")
+			os.Exit(1)
+		}
+		shutdown.HookShutdownCallback("node snapshots", func() { sub.Unsubscribe() }, 10*time.Second)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		ticker := time.NewTicker(cfg.Interval())
+		defer ticker.Stop()
+		for {
+			pollCtx, cancelPoll := context.WithTimeout(ctx, cfg.Interval())
+			alerts, err := watcher.Poll(pollCtx, js, time.Now())
+			cancelPoll()
+			if err != nil && ctx.Err() == nil {
+				logger.Log.Error().Err(err).Msg("Failed to poll consumers")
+			}
+			publish(alerts)
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			}
+		}
+	}()
+	shutdown.HookShutdownCallback("lag watcher", func() {
+		cancel()
+		<-done
+	}, 10*time.Second)
+
+	shutdown.WaitForShutdown(syscall.SIGINT, syscall.SIGTERM)
+	logger.Log.Info().Msg("Lag monitor command executed successfully!")
+}
+
+func main() {
+	var configFile string
+	flag.StringVar(&configFile, "c", "", "Configuration file path (required)")
+
+	flag.Usage = func() {
+		logger.Log.Info().Msg(`Lagmonitor polls the durable JetStream consumers of the sink and strategy
+nodes and raises an alert when the lag of one, its pending and unacknowledged
+messages, keeps growing for a window, the sign of a stuck or slow node. Lags
+are reported as the queues of the diagnostics reports and alerts are published
+to NATS, sent again inactive once the consumer catches up.
+
+Usage:
+  lagmonitor -c <config-file>
+
+Examples:
+  lagmonitor -c config/lagmonitor.json
+`)
+		flag.PrintDefaults()
+	}
+
+	flag.Parse()
+
+	if configFile == "" {
+		logger.Log.Error().Msg("config file path is required")
+		flag.Usage()
+		os.Exit(1)
+	}
+
+	runLagMonitor(configFile)
+}
@@ -0,0 +1,336 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/BullionBear/sequex/api"
+	"github.com/BullionBear/sequex/env"
+	"github.com/BullionBear/sequex/internal/config"
+	"github.com/BullionBear/sequex/internal/liquidation"
+	"github.com/BullionBear/sequex/internal/model/sqx"
+	"github.com/BullionBear/sequex/internal/sysevent"
+	"github.com/BullionBear/sequex/pkg/diagnostics"
+	"github.com/BullionBear/sequex/pkg/eventbus"
+	"github.com/BullionBear/sequex/pkg/exchange/binanceperp"
+	"github.com/BullionBear/sequex/pkg/featureflag"
+	"github.com/BullionBear/sequex/pkg/logger"
+	"github.com/BullionBear/sequex/pkg/node"
+	"github.com/BullionBear/sequex/pkg/shutdown"
+	"github.com/gin-gonic/gin"
+	"github.com/nats-io/nats.go"
+)
+
+// warmStart restores the liquidations of the window from the snapshot of a
+// running instance, starting empty when none answers
+func warmStart(bus *eventbus.Bus, subject string, aggregator *liquidation.Aggregator) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	snapshot, err := node.FetchSnapshot(ctx, bus, subject, "liquidations")
+	if err != nil {
+		logger.Log.Warn().Err(err).Str("subject", subject).Msg("No snapshot to warm start from, starting empty")
+		return
+	}
+	var events []sqx.Liquidation
+	if _, err := snapshot.Decode("liquidations", &events); err != nil {
+		logger.Log.Warn().Err(err).Str("subject", subject).Msg("Invalid snapshot, starting empty")
+		return
+	}
+	for _, l := range events {
+		aggregator.Add(l)
+	}
+	logger.Log.Info().Int("liquidations", len(events)).Str("node", snapshot.Node).Msg("Warm started from snapshot")
+}
+
+// runLiquidation executes the main liquidation aggregator logic
+func runLiquidation(configFile string) {
+	logger.Log.Info().
+		Str("version", env.Version).
+		Str("buildTime", env.BuildTime).
+		Str("commitHash", env.CommitHash).
+		Msg("Liquidation started")
+
+	cfg, err := config.LoadLiquidationConfig(configFile)
+	if err != nil {
+		logger.Log.Error().Err(err).Msg("Failed to load config")
+		os.Exit(1)
+	}
+
+	aggregator := liquidation.New(cfg.Options())
+
+	shutdown := shutdown.NewShutdown(logger.Log)
+
+	natsConn, err := nats.Connect(cfg.NATS.URIs)
+	if err != nil {
+		logger.Log.Error().Err(err).Msg("Failed to connect to NATS")
+		os.Exit(1)
+	}
+	defer natsConn.Close()
+
+	diag, stopDiagnostics, err := diagnostics.Start(cfg.Diagnostics.Options("liquidation"), natsConn, logger.Log)
+	if err != nil {
+		logger.Log.Error().Err(err).Msg("Failed to start diagnostics")
+		os.Exit(1)
+	}
+	shutdown.HookShutdownCallback("diagnostics", stopDiagnostics, 10*time.Second)
+	sysEvents, err := cfg.Diagnostics.EventPublisher(natsConn, "liquidation", logger.Log)
+	if err != nil {
+		logger.Log.Error().Err(err).Msg("Failed to create system event publisher")
+		os.Exit(1)
+	}
+	defer sysEvents.Close()
+	sysEvents.WatchConn(natsConn)
+	js, err := natsConn.JetStream()
+	if err != nil {
+		logger.Log.Error().Err(err).Msg("Failed to create JetStream context")
+		os.Exit(1)
+	}
+	if _, err := js.StreamInfo(cfg.NATS.Stream); err != nil {
+		logger.Log.Error().Err(err).Msg("Failed to get stream info")
+		os.Exit(1)
+	}
+	busOpts, err := cfg.NATS.BusOptions()
+	if err != nil {
+		logger.Log.Error().Err(err).Msg("Invalid event bus options")
+		os.Exit(1)
+	}
+	bus, err := eventbus.NewBus(natsConn, busOpts)
+	if err != nil {
+		logger.Log.Error().Err(err).Msg("Failed to create event bus")
+		os.Exit(1)
+	}
+
+	n := node.New("liquidation", bus, logger.Log)
+	if err := n.OnEvent(func(e node.Event) { sysEvents.Publish(sysevent.NodeEvent(e)) }); err != nil {
+		logger.Log.Error().Err(err).Msg("Failed to publish node events")
+		os.Exit(1)
+	}
+	if err := n.SetRecovery(cfg.Recovery.RecoveryOptions()); err != nil {
+		logger.Log.Error().Err(err).Msg("Invalid recovery options")
+		os.Exit(1)
+	}
+	if err := n.SetLimits(cfg.Limits.Limits()); err != nil {
+		logger.Log.Error().Err(err).Msg("Invalid resource limits")
+		os.Exit(1)
+	}
+	flags, err := cfg.FeatureFlags.Set()
+	if err != nil {
+		logger.Log.Error().Err(err).Msg("Invalid feature flags")
+		os.Exit(1)
+	}
+	if cfg.FeatureFlags.Bucket != "" {
+		store, err := featureflag.Watch(js, cfg.FeatureFlags.Bucket, flags, logger.Log)
+		if err != nil {
+			logger.Log.Error().Err(err).Msg("Failed to watch feature flags")
+			os.Exit(1)
+		}
+		shutdown.HookShutdownCallback("feature flags", store.Close, 10*time.Second)
+	}
+	n.SetFlags(flags)
+	for _, subject := range cfg.Inputs {
+		err := n.On(subject, func(msg *nats.Msg) error {
+			var l sqx.Liquidation
+			if err := sqx.UnmarshalLiquidation(msg.Data, &l); err != nil {
+				return fmt.Errorf("failed to unmarshal liquidation: %w", err)
+			}
+			aggregator.Add(l)
+			return nil
+		}, cfg.Dispatch.For(subject))
+		if err == nil {
+			err = n.Document(subject, node.NewPayload("json", (*sqx.Liquidation)(nil), "Forced liquidation orders"))
+		}
+		if err != nil {
+			logger.Log.Error().Err(err).Str("subject", subject).Msg("Failed to register liquidation handler")
+			os.Exit(1)
+		}
+	}
+	if cfg.SummaryIntervalMs > 0 {
+		if err := n.Emits(cfg.NATS.Subject+".*", node.NewPayload("json", (*liquidation.Summary)(nil), "Liquidation summaries per symbol")); err != nil {
+			logger.Log.Error().Err(err).Msg("Invalid summary subject")
+			os.Exit(1)
+		}
+	}
+	n.SetParams(cfg)
+	n.AddSnapshot("liquidations", func() (any, error) { return aggregator.Events(), nil })
+	n.AddSnapshot("health", func() (any, error) { return n.Health(), nil })
+	n.AddSnapshot("flags", func() (any, error) { return flags.Snapshot(), nil })
+	if cfg.WarmStartSubject != "" {
+		warmStart(bus, cfg.WarmStartSubject, aggregator)
+	}
+
+	if err := n.Start(); err != nil {
+		logger.Log.Error().Err(err).Msg("Failed to start node")
+		os.Exit(1)
+	}
+	diag.AddQueueSource(n.QueueDepths)
+	diag.AddRPCSource(bus.RPCStats)
+	diag.AddSlowConsumerSource(bus.SlowConsumerStats)
+	shutdown.HookShutdownCallback("node", n.Stop, 10*time.Second)
+	if subject := cfg.Diagnostics.DescribeSubject("liquidation"); subject != "" {
+		sub, err := n.ServeDescription(subject)
+		if err != nil {
+			logger.Log.Error().Err(err).Msg("Failed to serve node description")
+			os.Exit(1)
+		}
+		shutdown.HookShutdownCallback("node description", func() { sub.Unsubscribe() }, 10*time.Second)
+	}
+	if subject := cfg.Diagnostics.SnapshotSubject("liquidation"); subject != "" {
+		sub, err := n.ServeSnapshot(subject)
+		if err != nil {
+			logger.Log.Error().Err(err).Msg("Failed to serve node snapshots")
+			os.Exit(1)
+		}
+		shutdown.HookShutdownCallback("node snapshots", func() { sub.Unsubscribe() }, 10*time.Second)
+	}
+
+	if len(cfg.Binance) > 0 {
+		wsClient := binanceperp.NewWSClient(nil)
+		for _, s := range cfg.Binance {
+			symbol, _ := sqx.NewSymbolFromStr(s) // Validated with the configuration
+			unsubscribe, err := wsClient.SubscribeLiquidation(strings.ToLower(symbol.Base+symbol.Quote),
+				(&binanceperp.LiquidationSubscriptionOptions{}).
+					WithLiquidation(func(event binanceperp.WSLiquidation) {
+						l, err := convertLiquidation(symbol, event.Order)
+						if err != nil {
+							logger.Log.Error().Err(err).Str("symbol", symbol.String()).Msg("Failed to convert liquidation")
+							return
+						}
+						aggregator.Add(l)
+					}).
+					WithError(func(err error) {
+						logger.Log.Error().Err(err).Str("symbol", symbol.String()).Msg("Liquidation stream error")
+					}))
+			if err != nil {
+				logger.Log.Error().Err(err).Str("symbol", symbol.String()).Msg("Failed to subscribe to liquidations")
+				os.Exit(1)
+			}
+			shutdown.HookShutdownCallback("liquidations "+symbol.String(), unsubscribe, 10*time.Second)
+			logger.Log.Info().Str("symbol", symbol.String()).Msg("Subscribed to Binance liquidations")
+		}
+		shutdown.HookShutdownCallback("binance websocket", wsClient.Close, 10*time.Second)
+	}
+
+	if cfg.SummaryIntervalMs > 0 {
+		ctx, cancel := context.WithCancel(context.Background())
+		go func() {
+			ticker := time.NewTicker(time.Duration(cfg.SummaryIntervalMs) * time.Millisecond)
+			defer ticker.Stop()
+			for {
+				select {
+				case <-ctx.Done():
+					return
+				case <-ticker.C:
+				}
+				for _, summary := range aggregator.Summaries() {
+					data, err := json.Marshal(summary)
+					if err != nil {
+						logger.Log.Error().Err(err).Msg("Failed to marshal summary")
+						continue
+					}
+					subject := fmt.Sprintf("%s.%s", cfg.NATS.Subject, strings.ToLower(strings.ReplaceAll(summary.Symbol, "-", "")))
+					msg := &nats.Msg{Subject: subject, Data: data}
+					if err := bus.Encode(msg); err != nil {
+						logger.Log.Error().Err(err).Str("symbol", summary.Symbol).Msg("Failed to encode summary")
+						continue
+					}
+					if _, err := js.PublishMsg(msg); err != nil {
+						logger.Log.Error().Err(err).Str("symbol", summary.Symbol).Msg("Failed to publish summary")
+					}
+				}
+			}
+		}()
+		shutdown.HookShutdownCallback("summaries", cancel, 10*time.Second)
+	}
+
+	rg := gin.New()
+	rg.Use(gin.Recovery())
+	rg.Use(api.AllowAllCors)
+	liquidation.Register(rg, aggregator)
+	rg.GET("/healthz", func(c *gin.Context) {
+		c.JSON(http.StatusOK, gin.H{"symbols": aggregator.Symbols()})
+	})
+	rg.GET("/version", gin.WrapF(diagnostics.VersionHandler("liquidation")))
+
+	server := &http.Server{
+		Addr:    fmt.Sprintf("%s:%d", cfg.Host, cfg.Port),
+		Handler: rg,
+	}
+	go func() {
+		logger.Log.Info().Str("addr", server.Addr).Msg("Liquidation API listening")
+		if err := server.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			logger.Log.Error().Err(err).Msg("Liquidation API server failed")
+			os.Exit(1)
+		}
+	}()
+
+	shutdown.HookShutdownCallback("http server", func() {
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		if err := server.Shutdown(ctx); err != nil {
+			logger.Log.Error().Err(err).Msg("Failed to shutdown http server")
+		}
+	}, 10*time.Second)
+
+	shutdown.WaitForShutdown(syscall.SIGINT, syscall.SIGTERM)
+	logger.Log.Info().Msg("Liquidation command executed successfully!")
+}
+
+// convertLiquidation maps a Binance force order onto the subscribed symbol
+func convertLiquidation(symbol sqx.Symbol, order binanceperp.WSLiquidationOrder) (sqx.Liquidation, error) {
+	price, err := strconv.ParseFloat(order.AveragePrice, 64)
+	if err != nil {
+		return sqx.Liquidation{}, fmt.Errorf("failed to parse average price %q: %w", order.AveragePrice, err)
+	}
+	quantity, err := strconv.ParseFloat(order.FilledAccumulatedQty, 64)
+	if err != nil {
+		return sqx.Liquidation{}, fmt.Errorf("failed to parse filled quantity %q: %w", order.FilledAccumulatedQty, err)
+	}
+	return sqx.Liquidation{
+		Exchange:       sqx.ExchangeBinancePerp,
+		InstrumentType: sqx.InstrumentTypePerp,
+		Symbol:         symbol,
+		Side:           sqx.NewSide(order.Side),
+		Price:          price,
+		Quantity:       quantity,
+		Timestamp:      order.TradeTime,
+	}, nil
+}
+
+func main() {
+	var configFile string
+	flag.StringVar(&configFile, "c", "", "Configuration file path (required)")
+
+	flag.Usage = func() {
+		logger.Log.Info().Msg(`Liquidation aggregates forced liquidations into rolling per-minute statistics
+split by long and short positions, and price heatmaps. Summaries are published
+to NATS as <subject>.<symbol> and the aggregates are queried over REST at
+/liquidations/stats, /liquidations/heatmap and /liquidations/summaries.
+
+Usage:
+  liquidation -c <config-file>
+
+Examples:
+  liquidation -c config/liquidation.json
+`)
+		flag.PrintDefaults()
+	}
+
+	flag.Parse()
+
+	if configFile == "" {
+		logger.Log.Error().Msg("config file path is required")
+		flag.Usage()
+		os.Exit(1)
+	}
+
+	runLiquidation(configFile)
+}
@@ -0,0 +1,166 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"os/signal"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/BullionBear/sequex/internal/lag"
+	"github.com/BullionBear/sequex/internal/loadgen"
+	"github.com/BullionBear/sequex/internal/replay"
+	"github.com/nats-io/nats.go"
+)
+
+var (
+	natsURL   = flag.String("nats", nats.DefaultURL, "NATS server URLs, comma separated")
+	subject   = flag.String("subject", "trade.{exchange}.{instrument}.{symbol}", "Subject template with {exchange}, {instrument}, {symbol}, {base} and {quote}")
+	rate      = flag.Float64("rate", 1000, "Trades published per second over all symbols")
+	symbols   = flag.Int("symbols", 10, "Synthetic symbols the trades are spread over, SYN0USDT, SYN1USDT, ...")
+	duration  = flag.Duration("duration", time.Minute, "Duration of the publishing")
+	workers   = flag.Int("workers", 4, "Concurrent publishers")
+	jetStream = flag.Bool("jetstream", true, "Publish through JetStream and wait for each acknowledgement")
+	seed      = flag.Int64("seed", 1, "Seed of the synthetic markets")
+
+	// Consumer lag
+	streams     = flag.String("streams", "", "Streams whose durable consumers are watched, comma separated, all when empty; requires -jetstream")
+	lagInterval = flag.Duration("lag-interval", time.Second, "Interval between consumer lag polls")
+	drain       = flag.Duration("drain", 30*time.Second, "Wait for the consumers to drain after publishing")
+	noLag       = flag.Bool("no-lag", false, "Do not watch the consumer lags")
+
+	report = flag.String("report", "", "File the report is appended to as a JSON line")
+)
+
+func main() {
+	flag.Usage = func() {
+		fmt.Fprintf(os.Stderr, `Loadgen publishes synthetic trades at a steady rate to size JetStream and the
+nodes. It reports the publish latencies, how far the publishers fell behind
+the rate, and the lag of the durable consumers of the streams until they
+drain. Interrupt it to stop publishing early.
+
+Usage:
+  loadgen [flags]
+
+Examples:
+  loadgen -rate 20000 -symbols 50 -duration 5m -streams TRADE
+  loadgen -jetstream=false -rate 100000 -workers 8 -report loadgen.jsonl
+
+`)
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+
+	subjects, err := replay.NewSubjects(*subject, nil)
+	if err != nil {
+		log.Fatalf("Invalid subject: %v", err)
+	}
+	opts := loadgen.Options{
+		Rate:         *rate,
+		Symbols:      *symbols,
+		Duration:     *duration,
+		Workers:      *workers,
+		Subjects:     subjects,
+		Seed:         *seed,
+		LagInterval:  *lagInterval,
+		DrainTimeout: *drain,
+	}
+	if err := opts.Validate(); err != nil {
+		log.Fatalf("Invalid options: %v", err)
+	}
+
+	conn, err := nats.Connect(*natsURL)
+	if err != nil {
+		log.Fatalf("Failed to connect to NATS: %v", err)
+	}
+	defer conn.Close()
+
+	publish := conn.PublishMsg
+	var lags loadgen.Lags
+	if *jetStream {
+		js, err := conn.JetStream()
+		if err != nil {
+			log.Fatalf("Failed to create JetStream context: %v", err)
+		}
+		publish = func(msg *nats.Msg) error {
+			_, err := js.PublishMsg(msg)
+			return err
+		}
+		if !*noLag {
+			watched := lag.Options{Window: time.Hour}
+			if *streams != "" {
+				watched.Streams = strings.Split(*streams, ",")
+			}
+			watcher, err := lag.New(watched)
+			if err != nil {
+				log.Fatalf("Invalid streams: %v", err)
+			}
+			lags = func(ctx context.Context) ([]lag.Gauge, error) {
+				if _, err := watcher.Poll(ctx, js, time.Now()); err != nil {
+					return nil, err
+				}
+				return watcher.Gauges(), nil
+			}
+		}
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+	fmt.Printf("Publishing %g trades/s over %d symbols to %s for %s\n", *rate, *symbols, *subject, *duration)
+	r, err := loadgen.Run(ctx, opts, publish, lags)
+	if err != nil {
+		log.Fatalf("Load run failed: %v", err)
+	}
+	if err := conn.Flush(); err != nil {
+		log.Printf("Failed to flush: %v", err)
+	}
+
+	printReport(r)
+	if *report != "" {
+		if err := appendReport(*report, r); err != nil {
+			log.Fatalf("Failed to write report: %v", err)
+		}
+	}
+}
+
+func printReport(r loadgen.Report) {
+	fmt.Println(strings.Repeat("=", 50))
+	fmt.Printf("Published:  %d trades in %s, %d errors\n", r.Published, r.Elapsed.Round(time.Millisecond), r.Errors)
+	if r.LastError != "" {
+		fmt.Printf("Last error: %s\n", r.LastError)
+	}
+	fmt.Printf("Throughput: %.0f trades/s of %g targeted\n", r.Throughput, r.Rate)
+	fmt.Printf("Publish:    %s\n", latencies(r.Publish))
+	fmt.Printf("Behind:     %s\n", latencies(r.Behind))
+	if len(r.Consumers) > 0 || r.LagErrors > 0 {
+		fmt.Printf("\nConsumer lag (%d failed polls):\n", r.LagErrors)
+	}
+	for _, c := range r.Consumers {
+		drained := "not drained"
+		if c.Drained {
+			drained = "drained in " + c.Drain.Round(time.Millisecond).String()
+		}
+		fmt.Printf("  %s/%s: max %d, final %d, %s\n", c.Stream, c.Consumer, c.MaxLag, c.FinalLag, drained)
+	}
+}
+
+func latencies(l loadgen.Latencies) string {
+	return fmt.Sprintf("p50 %s, p90 %s, p99 %s, p99.9 %s, max %s", l.P50, l.P90, l.P99, l.P999, l.Max)
+}
+
+func appendReport(path string, r loadgen.Report) error {
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0o644)
+	if err != nil {
+		return err
+	}
+	if err := json.NewEncoder(f).Encode(r); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
@@ -0,0 +1,277 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/BullionBear/sequex/internal/model/sqx"
+)
+
+// stream identifies the trades of one symbol of one market, whose IDs the
+// exchange assigns
+type stream struct {
+	Exchange   sqx.Exchange
+	Instrument sqx.InstrumentType
+	Symbol     sqx.Symbol
+}
+
+func (s stream) String() string {
+	return fmt.Sprintf("%s %s %s", s.Exchange, s.Instrument, s.Symbol)
+}
+
+type tradeKey struct {
+	stream
+	Id int64
+}
+
+// capture holds the trades of a recorded file by key, the first occurrence
+// of duplicates kept
+type capture struct {
+	name   string
+	total  int
+	trades map[tradeKey]*sqx.Trade
+	seen   map[tradeKey]int
+	ranges map[stream][2]int64 // Lowest and highest trade ID per stream
+}
+
+// loadCapture reads the trades of the .raw file name
+func loadCapture(name string) (*capture, error) {
+	file, err := os.Open(name)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open input file %s: %w", name, err)
+	}
+	defer file.Close()
+
+	c := &capture{
+		name:   name,
+		trades: make(map[tradeKey]*sqx.Trade),
+		seen:   make(map[tradeKey]int),
+		ranges: make(map[stream][2]int64),
+	}
+	err = readTrades(file, func(trade *sqx.Trade) {
+		s := stream{trade.Exchange, trade.InstrumentType, trade.Symbol}
+		key := tradeKey{s, trade.Id}
+		c.total++
+		c.seen[key]++
+		if _, ok := c.trades[key]; !ok {
+			c.trades[key] = trade
+		}
+		r, ok := c.ranges[s]
+		if !ok {
+			r = [2]int64{trade.Id, trade.Id}
+		}
+		r[0], r[1] = min(r[0], trade.Id), max(r[1], trade.Id)
+		c.ranges[s] = r
+	})
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", name, err)
+	}
+	return c, nil
+}
+
+// covers reports whether the trade IDs of the file span key, so that its
+// absence is a gap rather than a capture started later or stopped earlier
+func (c *capture) covers(key tradeKey) bool {
+	r, ok := c.ranges[key.stream]
+	return ok && key.Id >= r[0] && key.Id <= r[1]
+}
+
+// tradeDiff lists the differences of two captures
+type tradeDiff struct {
+	missingA   []tradeKey // In b only
+	missingB   []tradeKey // In a only
+	duplicateA []tradeKey
+	duplicateB []tradeKey
+	mismatches []mismatch
+	matched    int
+	outside    int // Missing trades outside the ID range of the other capture
+}
+
+type mismatch struct {
+	key    tradeKey
+	fields []string
+}
+
+// diffCaptures aligns the trades of a and b by key. With overlap, trades
+// outside the trade ID range of the other capture are not missing.
+func diffCaptures(a, b *capture, overlap bool) tradeDiff {
+	var d tradeDiff
+	for key, ta := range a.trades {
+		tb, ok := b.trades[key]
+		switch {
+		case !ok && overlap && !b.covers(key):
+			d.outside++
+		case !ok:
+			d.missingB = append(d.missingB, key)
+		default:
+			if fields := compareTrades(ta, tb); len(fields) > 0 {
+				d.mismatches = append(d.mismatches, mismatch{key, fields})
+			} else {
+				d.matched++
+			}
+		}
+	}
+	for key := range b.trades {
+		if _, ok := a.trades[key]; ok {
+			continue
+		}
+		if overlap && !a.covers(key) {
+			d.outside++
+			continue
+		}
+		d.missingA = append(d.missingA, key)
+	}
+	for key, n := range a.seen {
+		if n > 1 {
+			d.duplicateA = append(d.duplicateA, key)
+		}
+	}
+	for key, n := range b.seen {
+		if n > 1 {
+			d.duplicateB = append(d.duplicateB, key)
+		}
+	}
+
+	for _, keys := range [][]tradeKey{d.missingA, d.missingB, d.duplicateA, d.duplicateB} {
+		sortKeys(keys)
+	}
+	sort.Slice(d.mismatches, func(i, j int) bool { return keyLess(d.mismatches[i].key, d.mismatches[j].key) })
+	return d
+}
+
+// compareTrades returns the differing fields of two trades with the same key
+func compareTrades(a, b *sqx.Trade) []string {
+	var fields []string
+	if a.TakerSide != b.TakerSide {
+		fields = append(fields, fmt.Sprintf("side %s != %s", a.TakerSide, b.TakerSide))
+	}
+	if a.Price != b.Price {
+		fields = append(fields, fmt.Sprintf("price %v != %v", a.Price, b.Price))
+	}
+	if a.Quantity != b.Quantity {
+		fields = append(fields, fmt.Sprintf("quantity %v != %v", a.Quantity, b.Quantity))
+	}
+	if a.Timestamp != b.Timestamp {
+		fields = append(fields, fmt.Sprintf("timestamp %d != %d", a.Timestamp, b.Timestamp))
+	}
+	return fields
+}
+
+func keyLess(a, b tradeKey) bool {
+	if a.stream != b.stream {
+		return a.stream.String() < b.stream.String()
+	}
+	return a.Id < b.Id
+}
+
+func sortKeys(keys []tradeKey) {
+	sort.Slice(keys, func(i, j int) bool { return keyLess(keys[i], keys[j]) })
+}
+
+// runDiff compares two recorded files, returning the exit status: 0 when
+// they hold the same trades, 1 when they differ and 2 on errors
+func runDiff(args []string) int {
+	fs := flag.NewFlagSet("diff", flag.ExitOnError)
+	quiet := fs.Bool("q", false, "print the summary only")
+	limit := fs.Int("max", 20, "trades listed per kind of difference, 0 for all")
+	overlap := fs.Bool("overlap", false, "ignore missing trades outside the trade ID range of the other file, for captures started or stopped at different times")
+	fs.Usage = func() {
+		fmt.Fprintf(os.Stderr, `Usage: marshal diff [options] <a.raw> <b.raw>
+
+Aligns the trades of two recorded files by exchange, instrument, symbol and
+trade ID and reports the trades missing from either file, the trades
+recorded more than once and the trades whose side, price, quantity or
+timestamp differ. Exits with 1 when the files differ.
+
+Options:
+`)
+		fs.PrintDefaults()
+	}
+	fs.Parse(args)
+	if fs.NArg() != 2 {
+		fs.Usage()
+		return 2
+	}
+
+	a, err := loadCapture(fs.Arg(0))
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return 2
+	}
+	b, err := loadCapture(fs.Arg(1))
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return 2
+	}
+	d := diffCaptures(a, b, *overlap)
+	if !*quiet {
+		printDiff(os.Stdout, a, b, d, *limit)
+	}
+	printSummary(os.Stdout, a, b, d)
+
+	if len(d.missingA)+len(d.missingB)+len(d.duplicateA)+len(d.duplicateB)+len(d.mismatches) > 0 {
+		return 1
+	}
+	return 0
+}
+
+// printDiff lists the differences, at most limit of each kind
+func printDiff(out io.Writer, a, b *capture, d tradeDiff, limit int) {
+	section := func(title string, n int, line func(i int) string) {
+		if n == 0 {
+			return
+		}
+		fmt.Fprintf(out, "%s (%d):\n", title, n)
+		shown := n
+		if limit > 0 && shown > limit {
+			shown = limit
+		}
+		for i := 0; i < shown; i++ {
+			fmt.Fprintf(out, "  %s\n", line(i))
+		}
+		if shown < n {
+			fmt.Fprintf(out, "  ... %d more\n", n-shown)
+		}
+		fmt.Fprintln(out)
+	}
+	trade := func(c *capture, key tradeKey) string {
+		t := c.trades[key]
+		return fmt.Sprintf("%s #%d %s %v@%v %d", key.stream, key.Id, t.TakerSide, t.Quantity, t.Price, t.Timestamp)
+	}
+
+	section("Missing in "+a.name, len(d.missingA), func(i int) string { return trade(b, d.missingA[i]) })
+	section("Missing in "+b.name, len(d.missingB), func(i int) string { return trade(a, d.missingB[i]) })
+	section("Duplicates in "+a.name, len(d.duplicateA), func(i int) string {
+		return fmt.Sprintf("%s x%d", trade(a, d.duplicateA[i]), a.seen[d.duplicateA[i]])
+	})
+	section("Duplicates in "+b.name, len(d.duplicateB), func(i int) string {
+		return fmt.Sprintf("%s x%d", trade(b, d.duplicateB[i]), b.seen[d.duplicateB[i]])
+	})
+	section("Mismatches", len(d.mismatches), func(i int) string {
+		m := d.mismatches[i]
+		return fmt.Sprintf("%s #%d: %s", m.key.stream, m.key.Id, strings.Join(m.fields, ", "))
+	})
+}
+
+// printSummary prints the statistics of the comparison
+func printSummary(out io.Writer, a, b *capture, d tradeDiff) {
+	duplicates := func(c *capture) int {
+		n := 0
+		for _, count := range c.seen {
+			n += count - 1
+		}
+		return n
+	}
+	fmt.Fprintf(out, "%s: %d trades, %d unique, %d duplicate records, %d streams\n", a.name, a.total, len(a.trades), duplicates(a), len(a.ranges))
+	fmt.Fprintf(out, "%s: %d trades, %d unique, %d duplicate records, %d streams\n", b.name, b.total, len(b.trades), duplicates(b), len(b.ranges))
+	fmt.Fprintf(out, "Matched: %d, mismatching: %d, missing in %s: %d, missing in %s: %d",
+		d.matched, len(d.mismatches), a.name, len(d.missingA), b.name, len(d.missingB))
+	if d.outside > 0 {
+		fmt.Fprintf(out, ", outside the overlap: %d", d.outside)
+	}
+	fmt.Fprintln(out)
+}
@@ -11,14 +11,38 @@ import (
 
 	"github.com/BullionBear/sequex/internal/model/protobuf"
 	"github.com/BullionBear/sequex/internal/model/sqx"
+	"github.com/BullionBear/sequex/internal/replay"
+	"google.golang.org/protobuf/encoding/protowire"
 	"google.golang.org/protobuf/proto"
 )
 
 func main() {
+	if len(os.Args) > 1 {
+		switch os.Args[1] {
+		case "diff":
+			os.Exit(runDiff(os.Args[2:]))
+		case "manifest":
+			os.Exit(runManifest(os.Args[2:]))
+		case "verify":
+			os.Exit(runVerify(os.Args[2:]))
+		}
+	}
+
 	// Define flags
 	deserializeFlag := flag.Bool("d", false, "deserialize mode - convert .raw protobuf file to JSON format")
 	serializeFlag := flag.Bool("s", false, "serialize mode - convert JSON to protobuf .raw format")
 	outputFile := flag.String("o", "", "output file (default: stdout for -d, required for -s)")
+	writeManifest := flag.Bool("manifest", false, "write the manifest of the output file next to it, with -s")
+	flag.Usage = func() {
+		fmt.Fprintf(os.Stderr, `Usage: marshal -d|-s [-o output] [input]
+       marshal diff [options] <a.raw> <b.raw>
+       marshal manifest <file.raw>...
+       marshal verify <file.raw>...
+
+Options:
+`)
+		flag.PrintDefaults()
+	}
 	flag.Parse()
 
 	// Validate flags - exactly one of -d or -s must be specified
@@ -58,6 +82,12 @@ func main() {
 			fmt.Fprintf(os.Stderr, "Error in serialize mode: %v\n", err)
 			os.Exit(1)
 		}
+		if *writeManifest {
+			if _, err := replay.WriteManifest(*outputFile); err != nil {
+				fmt.Fprintf(os.Stderr, "Error writing manifest: %v\n", err)
+				os.Exit(1)
+			}
+		}
 	}
 }
 
@@ -88,13 +118,31 @@ func deserializeMode(inputFile, outputFile string) error {
 		writer = outFile
 	}
 
+	messageCount := 0
+	err = readTrades(file, func(trade *sqx.Trade) {
+		jsonData, err := json.Marshal(trade)
+		if err == nil {
+			fmt.Fprintf(writer, "%s\n", string(jsonData))
+			messageCount++
+		}
+	})
+	if err != nil {
+		return err
+	}
+
+	fmt.Fprintf(os.Stderr, "Successfully deserialized %d messages\n", messageCount)
+	return nil
+}
+
+// readTrades calls fn with each trade of a .raw protobuf stream, skipping
+// bytes that do not start a valid message
+func readTrades(r io.Reader, fn func(*sqx.Trade)) error {
 	buffer := make([]byte, 1024*1024) // 1MB buffer
 	var accumulated []byte
-	messageCount := 0
 
 	eofReached := false
 	for {
-		n, readErr := file.Read(buffer)
+		n, readErr := r.Read(buffer)
 
 		if n > 0 {
 			accumulated = append(accumulated, buffer[:n]...)
@@ -119,14 +167,10 @@ func deserializeMode(inputFile, outputFile string) error {
 
 			trade := &protobuf.Trade{}
 			if err := proto.Unmarshal(messageData, trade); err == nil {
-				// Convert to SQX format and output as JSON
+				// Convert to SQX format
 				sqxTrade := &sqx.Trade{}
 				if err := sqxTrade.FromProtobuf(trade); err == nil {
-					jsonData, err := json.Marshal(sqxTrade)
-					if err == nil {
-						fmt.Fprintf(writer, "%s\n", string(jsonData))
-						messageCount++
-					}
+					fn(sqxTrade)
 				}
 			}
 
@@ -135,16 +179,13 @@ func deserializeMode(inputFile, outputFile string) error {
 
 		// Check for EOF and exit if no more data
 		if eofReached {
-			break
+			return nil
 		}
 
-		if readErr != nil && readErr != io.EOF {
+		if readErr != nil {
 			return fmt.Errorf("error reading file: %w", readErr)
 		}
 	}
-
-	fmt.Fprintf(os.Stderr, "Successfully deserialized %d messages\n", messageCount)
-	return nil
 }
 
 // serializeMode reads JSON input and writes protobuf .raw file
@@ -291,30 +332,14 @@ func skipVarint(data []byte) (int, bool) {
 	return 0, false
 }
 
-// skipLengthDelimited skips over a length-delimited field
+// skipLengthDelimited skips over a length-delimited field. Lengths past the
+// end of the data, however large, are rejected.
 func skipLengthDelimited(data []byte) (int, bool) {
-	// Decode the length varint
-	length := uint64(0)
-	lengthBytes := 0
-
-	for i := 0; i < len(data) && i < 10; i++ {
-		length |= uint64(data[i]&0x7F) << (7 * i)
-		lengthBytes++
-		if data[i]&0x80 == 0 {
-			break
-		}
-	}
-
-	if lengthBytes == 0 {
-		return 0, false
-	}
-
-	// Skip the length prefix + the data
-	totalLength := lengthBytes + int(length)
-	if len(data) < totalLength {
+	length, lengthBytes := protowire.ConsumeVarint(data)
+	if lengthBytes < 0 || length > uint64(len(data)-lengthBytes) {
 		return 0, false
 	}
-	return totalLength, true
+	return lengthBytes + int(length), true
 }
 
 // hasAllExpectedFields checks if we've seen all the expected fields for a complete Trade message
@@ -1,78 +1,424 @@
 package main
 
 import (
+	"context"
+	"errors"
 	"flag"
 	"fmt"
+	"net/http"
 	"os"
+	"syscall"
+	"time"
 
+	"github.com/BullionBear/sequex/api"
 	_ "github.com/BullionBear/sequex/docs"
 	"github.com/BullionBear/sequex/env"
+	"github.com/BullionBear/sequex/internal/accrual"
+	"github.com/BullionBear/sequex/internal/bundle"
+	"github.com/BullionBear/sequex/internal/config"
+	"github.com/BullionBear/sequex/internal/execution"
+	"github.com/BullionBear/sequex/internal/model/protobuf"
+	"github.com/BullionBear/sequex/internal/model/sqx"
+	"github.com/BullionBear/sequex/internal/replay"
+	"github.com/BullionBear/sequex/pkg/diagnostics"
+	"github.com/BullionBear/sequex/pkg/eventbus"
+	"github.com/BullionBear/sequex/pkg/exchange/binanceperp"
 	"github.com/BullionBear/sequex/pkg/logger"
+	"github.com/BullionBear/sequex/pkg/shutdown"
+	"github.com/gin-gonic/gin"
+	"github.com/nats-io/nats.go"
 )
 
-var log = logger.Log
+// @title Sequex Master API
+// @version 2.0
+// @description Control API of the master and its nodes. Routes are grouped by
+// @description version under /api/v1 and /api/v2; breaking changes only land in a new version.
+// @BasePath /api
 
-func main() {
-	// Parse command line arguments
-	var configFile string
-	flag.StringVar(&configFile, "c", "", "Configuration file path (required)")
-	flag.Parse()
+// runMaster executes the main master logic
+func runMaster(configFile string) {
+	logger.Log.Info().
+		Str("version", env.Version).
+		Str("buildTime", env.BuildTime).
+		Str("commitHash", env.CommitHash).
+		Msg("Master started")
 
-	// Check if the required config file flag is provided
-	if configFile == "" {
-		fmt.Println("Error: config file path is required")
-		fmt.Println("Usage: master -c <config-file>")
-		fmt.Println("Example: master -c config/master/app.yml")
-		flag.PrintDefaults()
+	cfg, err := config.LoadMasterConfig(configFile)
+	if err != nil {
+		logger.Log.Error().Err(err).Msg("Failed to load config")
+		os.Exit(1)
+	}
+
+	shutdown := shutdown.NewShutdown(logger.Log)
+
+	natsConn, err := nats.Connect(cfg.NATS.URIs)
+	if err != nil {
+		logger.Log.Error().Err(err).Msg("Failed to connect to NATS")
+		os.Exit(1)
+	}
+	defer natsConn.Close()
+
+	versionSubs, err := diagnostics.ServeVersion(natsConn, "master")
+	if err != nil {
+		logger.Log.Error().Err(err).Msg("Failed to serve version")
+		os.Exit(1)
+	}
+	shutdown.HookShutdownCallback("version", func() {
+		for _, sub := range versionSubs {
+			sub.Unsubscribe()
+		}
+	}, 10*time.Second)
+
+	apiOpts, err := cfg.APIOptions()
+	if err != nil {
+		logger.Log.Error().Err(err).Msg("Invalid API options")
+		os.Exit(1)
+	}
+
+	rates, err := cfg.FX.Rates()
+	if err != nil {
+		logger.Log.Error().Err(err).Msg("Invalid FX configuration")
+		os.Exit(1)
+	}
+	history := cfg.Risk.History()
+	if history != nil {
+		for _, recording := range cfg.Risk.Recordings {
+			valid, _, err := replay.ReadTradeFile(recording, func(_ int, pb *protobuf.Trade) error {
+				var trade sqx.Trade
+				if trade.FromProtobuf(pb) == nil {
+					history.OnTrade(trade)
+				}
+				return nil
+			})
+			if err != nil {
+				logger.Log.Error().Err(err).Str("recording", recording).Msg("Failed to load risk history")
+				os.Exit(1)
+			}
+			logger.Log.Info().Str("recording", recording).Int("trades", valid).Msg("Loaded risk history")
+		}
+	}
+	for _, input := range cfg.FX.Inputs {
+		sub, err := natsConn.Subscribe(input, func(msg *nats.Msg) {
+			if err := eventbus.Decode(msg); err != nil {
+				logger.Log.Error().Err(err).Str("subject", msg.Subject).Msg("Failed to decode trade")
+				return
+			}
+			trades, err := sqx.UnmarshalTrades(msg.Data, msg.Header.Get(sqx.HeaderTradeBatch))
+			if err != nil {
+				logger.Log.Error().Err(err).Str("subject", msg.Subject).Msg("Failed to unmarshal trade")
+				return
+			}
+			for _, trade := range trades {
+				rates.OnTrade(trade)
+				if history != nil {
+					history.OnTrade(trade)
+				}
+			}
+		})
+		if err != nil {
+			logger.Log.Error().Err(err).Str("subject", input).Msg("Failed to subscribe to FX input")
+			os.Exit(1)
+		}
+		shutdown.HookShutdownCallback("unsubscribe "+input, func() {
+			if err := sub.Unsubscribe(); err != nil {
+				logger.Log.Error().Err(err).Str("subject", input).Msg("Failed to unsubscribe")
+			}
+		}, 10*time.Second)
+		logger.Log.Info().Str("subject", input).Msg("Subscribed to FX input")
+	}
+	for _, input := range cfg.FX.OptionInputs {
+		sub, err := natsConn.Subscribe(input, func(msg *nats.Msg) {
+			if err := eventbus.Decode(msg); err != nil {
+				logger.Log.Error().Err(err).Str("subject", msg.Subject).Msg("Failed to decode option mark")
+				return
+			}
+			var mark sqx.OptionMark
+			if err := sqx.UnmarshalOptionMark(msg.Data, &mark); err != nil {
+				logger.Log.Error().Err(err).Str("subject", msg.Subject).Msg("Failed to unmarshal option mark")
+				return
+			}
+			rates.OnOptionMark(mark)
+		})
+		if err != nil {
+			logger.Log.Error().Err(err).Str("subject", input).Msg("Failed to subscribe to FX option input")
+			os.Exit(1)
+		}
+		shutdown.HookShutdownCallback("unsubscribe "+input, func() {
+			if err := sub.Unsubscribe(); err != nil {
+				logger.Log.Error().Err(err).Str("subject", input).Msg("Failed to unsubscribe")
+			}
+		}, 10*time.Second)
+		logger.Log.Info().Str("subject", input).Msg("Subscribed to FX option input")
+	}
+
+	tenants, err := config.TenantRegistry(cfg.Tenants)
+	if err != nil {
+		logger.Log.Error().Err(err).Msg("Invalid tenants")
+		os.Exit(1)
+	}
+	executions, err := cfg.Executions.Store()
+	if err != nil {
+		logger.Log.Error().Err(err).Msg("Failed to open execution history")
+		os.Exit(1)
+	}
+	if executions != nil {
+		// Closed once the subscriptions feeding it are gone
+		defer executions.Close()
+	}
+	for _, input := range cfg.Executions.Inputs {
+		subscribeExecutions(shutdown, natsConn, input, executions)
+	}
+	// Each tenant has its own history, fed only by the reports under its
+	// subject prefix
+	tenantExecutions := make(map[string]*execution.Store)
+	for _, t := range tenants.Tenants() {
+		store, err := cfg.Executions.TenantStore(t.ID)
+		if err != nil {
+			logger.Log.Error().Err(err).Str("tenant", t.ID).Msg("Failed to open execution history")
+			os.Exit(1)
+		}
+		if store == nil {
+			continue
+		}
+		defer store.Close()
+		tenantExecutions[t.ID] = store
+		for _, input := range cfg.Executions.Inputs {
+			subscribeExecutions(shutdown, natsConn, t.Subject(input), store)
+		}
+	}
+
+	portfolios, durable, err := cfg.Portfolios.Store()
+	if err != nil {
+		logger.Log.Error().Err(err).Msg("Failed to open the portfolio store")
+		os.Exit(1)
+	}
+	if durable != nil {
+		recovery := durable.Recovery()
+		for _, skipped := range recovery.Skipped {
+			logger.Log.Warn().Str("snapshot", skipped).Msg("Skipped unreadable portfolio snapshot")
+		}
+		logger.Log.Info().Str("snapshot", recovery.Snapshot).Uint64("seq", recovery.SnapshotSeq).
+			Int("replayed", recovery.Replayed).Dur("duration", recovery.Duration).Msg("Portfolios recovered")
+		cutoff, _ := cfg.Portfolios.Cutoff()
+		ctx, cancel := context.WithCancel(context.Background())
+		go durable.RunEOD(ctx, cutoff, rates, func(err error) {
+			logger.Log.Error().Err(err).Msg("Failed to snapshot the portfolios")
+		})
+		shutdown.HookShutdownCallback("portfolio store", func() {
+			cancel()
+			if err := durable.Close(); err != nil {
+				logger.Log.Error().Err(err).Msg("Failed to close the portfolio journal")
+			}
+		}, 10*time.Second)
+	}
+	booker, err := cfg.Accruals.Booker(portfolios)
+	if err != nil {
+		logger.Log.Error().Err(err).Msg("Invalid accruals configuration")
+		os.Exit(1)
+	}
+	if booker != nil {
+		ctx, cancel := context.WithCancel(context.Background())
+		shutdown.HookShutdownCallback("accruals", cancel, 10*time.Second)
+		// Funding updates of the user data stream pull the income history
+		// right away; the periodic pulls cover the stream being down
+		trigger := make(chan struct{}, 1)
+		if client := cfg.Accruals.FundingClient(); client != nil {
+			subscription := (&binanceperp.Subscription{}).
+				WithMessage(func(data []byte) {
+					if accrual.IsFundingUpdate(data) {
+						select {
+						case trigger <- struct{}{}:
+						default:
+						}
+					}
+				}).
+				WithError(func(err error) {
+					logger.Log.Warn().Err(err).Msg("Funding user data stream error")
+				})
+			stream := binanceperp.NewBinancePerpUserDataStream(client, nil, subscription)
+			if err := stream.Connect(ctx); err != nil {
+				logger.Log.Warn().Err(err).Msg("Failed to connect the funding user data stream, polling only")
+			} else {
+				shutdown.HookShutdownCallback("funding user data stream", func() {
+					if err := stream.Disconnect(); err != nil {
+						logger.Log.Error().Err(err).Msg("Failed to disconnect the funding user data stream")
+					}
+				}, 10*time.Second)
+			}
+		}
+		go booker.Run(ctx, cfg.Accruals.Interval(), trigger, func(err error) {
+			logger.Log.Error().Err(err).Str("portfolio", cfg.Accruals.Portfolio).Msg("Failed to book accruals")
+		})
+		logger.Log.Info().Str("portfolio", cfg.Accruals.Portfolio).Dur("interval", cfg.Accruals.Interval()).Msg("Booking funding and interest")
+	}
+
+	busOpts, err := cfg.NATS.BusOptions()
+	if err != nil {
+		logger.Log.Error().Err(err).Msg("Invalid event bus options")
+		os.Exit(1)
+	}
+	if busOpts.Authorizer, err = cfg.NATS.Authorizer(natsConn, "master"); err != nil {
+		logger.Log.Error().Err(err).Msg("Invalid command policy")
+		os.Exit(1)
+	}
+	bus, err := eventbus.NewBus(natsConn, busOpts)
+	if err != nil {
+		logger.Log.Error().Err(err).Msg("Failed to create event bus")
 		os.Exit(1)
 	}
 
-	fmt.Println("Starting services with BuildTime:", env.BuildTime)
-	fmt.Println("Starting services with Version:", env.Version)
-	fmt.Println("Starting services with CommitHash:", env.CommitHash)
-	fmt.Printf("Using config file: %s\n", configFile)
-	/*
-	   // Load configuration
-	   cfg, err := config.LoadConfig[config.MasterConfig](configFile)
+	engine := gin.New()
+	engine.Use(gin.Logger(), gin.Recovery(), api.AllowAllCors)
+	api.NewRouter(engine, api.Services{
+		Nodes:            api.NewNATSNodeControl(natsConn, time.Duration(cfg.RequestTimeoutMs)*time.Millisecond),
+		Strategies:       api.NewNATSStrategyControl(bus, time.Duration(cfg.RequestTimeoutMs)*time.Millisecond),
+		Portfolios:       portfolios,
+		Rates:            rates,
+		Risk:             history,
+		Executions:       executions,
+		Tenants:          tenants,
+		TenantExecutions: tenantExecutions,
+	}, apiOpts)
+	engine.GET("/version", gin.WrapF(diagnostics.VersionHandler("master")))
+
+	server := &http.Server{
+		Addr:              fmt.Sprintf("%s:%d", cfg.Host, cfg.Port),
+		Handler:           engine,
+		ReadHeaderTimeout: 5 * time.Second,
+	}
+	go func() {
+		logger.Log.Info().Str("addr", server.Addr).Msg("Master API listening")
+		if err := server.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			logger.Log.Error().Err(err).Msg("Master API stopped")
+			os.Exit(1)
+		}
+	}()
+	shutdown.HookShutdownCallback("master api", func() {
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		if err := server.Shutdown(ctx); err != nil {
+			logger.Log.Error().Err(err).Msg("Failed to shut down master API")
+		}
+	}, 10*time.Second)
+
+	graph, err := cfg.Pipeline.Graph(natsConn, func(name string, err error) {
+		logger.Log.Error().Err(err).Str("node", name).Msg("Pipeline node exited, stopping its dependents")
+	})
+	if err != nil {
+		logger.Log.Error().Err(err).Msg("Invalid pipeline")
+		os.Exit(1)
+	}
+	if graph != nil {
+		ctx, cancel := context.WithCancel(context.Background())
+		// Nodes are stopped one at a time in reverse order, each within its
+		// own stop timeout
+		shutdown.HookShutdownCallback("pipeline", func() {
+			cancel()
+			graph.Stop()
+		}, 0)
+		go func() {
+			logger.Log.Info().Strs("order", graph.Names()).Msg("Starting pipeline")
+			if err := graph.Start(ctx); err != nil {
+				logger.Log.Error().Err(err).Msg("Failed to start pipeline")
+				return
+			}
+			logger.Log.Info().Msg("Pipeline ready")
+		}()
+	}
 
-	   	if err != nil {
-	   		// Use fmt for error before logger is initialized
-	   		fmt.Printf("Failed to load configuration: %v\n", err)
-	   		os.Exit(1)
-	   	}
+	if cfg.Apps.Dir != "" {
+		js, err := natsConn.JetStream()
+		if err != nil {
+			logger.Log.Error().Err(err).Msg("Failed to create JetStream context")
+			os.Exit(1)
+		}
+		bucket := cfg.Apps.Bucket
+		if bucket == "" {
+			bucket = bundle.DefaultBucket
+		}
+		kv, err := config.OpenConfigBucket(js, bucket)
+		if err != nil {
+			logger.Log.Error().Err(err).Msg("Failed to open the configurations of the applications")
+			os.Exit(1)
+		}
+		deployer := bundle.NewDeployer(kv, cfg.Apps.Dir, natsConn, func(app, node string, err error) {
+			logger.Log.Error().Err(err).Str("app", app).Str("node", node).Msg("Application node exited, stopping its dependents")
+		})
+		subject := cfg.Apps.Subject
+		if subject == "" {
+			subject = bundle.DefaultSubject
+		}
+		appSubs, err := bundle.Serve(bus, subject, deployer)
+		if err != nil {
+			logger.Log.Error().Err(err).Msg("Failed to serve the application RPCs")
+			os.Exit(1)
+		}
+		shutdown.HookShutdownCallback("apps", func() {
+			for _, sub := range appSubs {
+				sub.Unsubscribe()
+			}
+			deployer.Close()
+		}, 0)
+		logger.Log.Info().Str("subject", subject).Str("bucket", bucket).Str("dir", cfg.Apps.Dir).Msg("Serving applications")
+	}
 
-	   // Initialize global logger from config
-	   logger, err = config.CreateLogger(cfg.Logger)
+	shutdown.WaitForShutdown(syscall.SIGINT, syscall.SIGTERM)
+	logger.Log.Info().Msg("Master command executed successfully!")
+}
 
-	   	if err != nil {
-	   		fmt.Printf("Failed to initialize logger: %v\n", err)
-	   		os.Exit(1)
-	   	}
+func main() {
+	var configFile string
+	flag.StringVar(&configFile, "c", "", "Configuration file path (required)")
 
-	   // Create shutdown
-	   shutdown := shutdown.NewShutdown(logger)
+	flag.Usage = func() {
+		logger.Log.Info().Msg(`Master serves the control API of the nodes, with the OpenAPI spec at
+/swagger/doc.json.
 
-	   nc, err := config.CreateNATSConnection(cfg.Nats.URL)
+Usage:
+  master -c <config-file>
 
-	   	if err != nil {
-	   		fmt.Printf("Failed to create NATS connection: %v\n", err)
-	   		os.Exit(1)
-	   	}
+Examples:
+  master -c config/master.json
+`)
+		flag.PrintDefaults()
+	}
 
-	   masterRPCClient := master.NewMasterRPCClient(nc)
+	flag.Parse()
 
-	   rg := gin.New()
-	   rg.Use(gin.Logger())
-	   rg.Use(api.AllowAllCors)
-	   v1rg := rg.Group("/v1", gin.Recovery())
-	   api.NewNode(v1rg, masterRPCClient)
+	if configFile == "" {
+		logger.Log.Error().Msg("config file path is required")
+		flag.Usage()
+		os.Exit(1)
+	}
 
-	   	go func() {
-	   		rg.GET("/swagger/*any", ginSwagger.WrapHandler(swaggerFiles.Handler))
-	   		logger.Infof("Server started on %s:%d", cfg.App.Host, cfg.App.Port)
-	   		rg.Run(fmt.Sprintf("%s:%d", cfg.App.Host, cfg.App.Port))
-	   	}()
+	runMaster(configFile)
+}
 
-	   shutdown.WaitForShutdown(syscall.SIGINT, syscall.SIGTERM)
-	*/
+// subscribeExecutions records the execution reports published on subject
+// into store, exiting when the subscription fails
+func subscribeExecutions(shutdown *shutdown.Shutdown, natsConn *nats.Conn, subject string, store *execution.Store) {
+	sub, err := natsConn.Subscribe(subject, func(msg *nats.Msg) {
+		if err := eventbus.Decode(msg); err != nil {
+			logger.Log.Error().Err(err).Str("subject", msg.Subject).Msg("Failed to decode execution report")
+			return
+		}
+		var report sqx.ExecutionReport
+		if err := sqx.UnmarshalExecutionReport(msg.Data, &report); err != nil {
+			logger.Log.Error().Err(err).Str("subject", msg.Subject).Msg("Failed to unmarshal execution report")
+			return
+		}
+		if _, err := store.Add(report); err != nil {
+			logger.Log.Error().Err(err).Str("subject", msg.Subject).Msg("Failed to record execution report")
+		}
+	})
+	if err != nil {
+		logger.Log.Error().Err(err).Str("subject", subject).Msg("Failed to subscribe to execution reports")
+		os.Exit(1)
+	}
+	shutdown.HookShutdownCallback("unsubscribe "+subject, func() {
+		if err := sub.Unsubscribe(); err != nil {
+			logger.Log.Error().Err(err).Str("subject", subject).Msg("Failed to unsubscribe")
+		}
+	}, 10*time.Second)
+	logger.Log.Info().Str("subject", subject).Msg("Subscribed to execution reports")
 }
@@ -0,0 +1,324 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/BullionBear/sequex/env"
+	"github.com/BullionBear/sequex/internal/config"
+	"github.com/BullionBear/sequex/internal/model/sqx"
+	"github.com/BullionBear/sequex/internal/monitor"
+	"github.com/BullionBear/sequex/internal/sysevent"
+	"github.com/BullionBear/sequex/pkg/diagnostics"
+	"github.com/BullionBear/sequex/pkg/eventbus"
+	"github.com/BullionBear/sequex/pkg/featureflag"
+	"github.com/BullionBear/sequex/pkg/logger"
+	"github.com/BullionBear/sequex/pkg/node"
+	"github.com/BullionBear/sequex/pkg/shutdown"
+	"github.com/nats-io/nats.go"
+)
+
+// runMonitor executes the main reference price monitor logic
+func runMonitor(configFile string) {
+	logger.Log.Info().
+		Str("version", env.Version).
+		Str("buildTime", env.BuildTime).
+		Str("commitHash", env.CommitHash).
+		Msg("Monitor started")
+
+	cfg, err := config.LoadMonitorConfig(configFile)
+	if err != nil {
+		logger.Log.Error().Err(err).Msg("Failed to load config")
+		os.Exit(1)
+	}
+
+	mon, err := monitor.New(cfg.Options())
+	if err != nil {
+		logger.Log.Error().Err(err).Msg("Failed to create monitor")
+		os.Exit(1)
+	}
+
+	shutdown := shutdown.NewShutdown(logger.Log)
+
+	natsConn, err := nats.Connect(cfg.NATS.URIs)
+	if err != nil {
+		logger.Log.Error().Err(err).Msg("Failed to connect to NATS")
+		os.Exit(1)
+	}
+	defer natsConn.Close()
+
+	diag, stopDiagnostics, err := diagnostics.Start(cfg.Diagnostics.Options("monitor"), natsConn, logger.Log)
+	if err != nil {
+		logger.Log.Error().Err(err).Msg("Failed to start diagnostics")
+		os.Exit(1)
+	}
+	shutdown.HookShutdownCallback("diagnostics", stopDiagnostics, 10*time.Second)
+	sysEvents, err := cfg.Diagnostics.EventPublisher(natsConn, "monitor", logger.Log)
+	if err != nil {
+		logger.Log.Error().Err(err).Msg("Failed to create system event publisher")
+		os.Exit(1)
+	}
+	defer sysEvents.Close()
+	sysEvents.WatchConn(natsConn)
+	js, err := natsConn.JetStream()
+	if err != nil {
+		logger.Log.Error().Err(err).Msg("Failed to create JetStream context")
+		os.Exit(1)
+	}
+	if _, err := js.StreamInfo(cfg.NATS.Stream); err != nil {
+		logger.Log.Error().Err(err).Msg("Failed to get stream info")
+		os.Exit(1)
+	}
+	busOpts, err := cfg.NATS.BusOptions()
+	if err != nil {
+		logger.Log.Error().Err(err).Msg("Invalid event bus options")
+		os.Exit(1)
+	}
+	bus, err := eventbus.NewBus(natsConn, busOpts)
+	if err != nil {
+		logger.Log.Error().Err(err).Msg("Failed to create event bus")
+		os.Exit(1)
+	}
+	laneConn, err := cfg.NATS.ConnectPriorityLane(bus)
+	if err != nil {
+		logger.Log.Error().Err(err).Msg("Failed to connect the priority lane")
+		os.Exit(1)
+	}
+	if laneConn != nil {
+		defer laneConn.Close()
+	}
+
+	// Handlers may run on several workers, riskMu orders the risk toggles
+	var riskMu sync.Mutex
+	restricted := false
+
+	publish := func(alerts []monitor.Alert) {
+		for _, alert := range alerts {
+			event := logger.Log.Warn()
+			if !alert.Active {
+				event = logger.Log.Info()
+			}
+			event.Str("kind", string(alert.Kind)).Str("name", alert.Name).Str("venue", alert.Venue).Str("feed", alert.Feed).
+				Float64("price", alert.Price).Float64("reference", alert.Reference).
+				Float64("deviation", alert.Deviation).Bool("active", alert.Active).Msg("Monitor alert")
+
+			data, err := json.Marshal(alert)
+			if err != nil {
+				logger.Log.Error().Err(err).Msg("Failed to marshal alert")
+				continue
+			}
+			msg := &nats.Msg{Subject: fmt.Sprintf("%s.%s", cfg.NATS.Subject, alert.Kind), Data: data}
+			if err := bus.Encode(msg); err != nil {
+				logger.Log.Error().Err(err).Msg("Failed to encode alert")
+				continue
+			}
+			if _, err := js.PublishMsg(msg); err != nil {
+				logger.Log.Error().Err(err).Msg("Failed to publish alert")
+			}
+		}
+		if len(alerts) == 0 || cfg.Risk.Subject == "" {
+			return
+		}
+
+		riskMu.Lock()
+		defer riskMu.Unlock()
+		if mon.Restricted() == restricted {
+			return
+		}
+		restricted = !restricted
+		data, err := json.Marshal(monitor.RiskControl{
+			Restricted: restricted,
+			Alerts:     mon.Active(),
+			Timestamp:  time.Now().UnixMilli(),
+		})
+		if err != nil {
+			logger.Log.Error().Err(err).Msg("Failed to marshal risk control")
+			return
+		}
+		if err := bus.Publish(cfg.Risk.Subject, data); err != nil {
+			logger.Log.Error().Err(err).Msg("Failed to publish risk control")
+			return
+		}
+		logger.Log.Warn().Bool("restricted", restricted).Msg("Risk limits toggled")
+	}
+
+	n := node.New("monitor", bus, logger.Log)
+	if err := n.OnEvent(func(e node.Event) { sysEvents.Publish(sysevent.NodeEvent(e)) }); err != nil {
+		logger.Log.Error().Err(err).Msg("Failed to publish node events")
+		os.Exit(1)
+	}
+	if err := n.SetRecovery(cfg.Recovery.RecoveryOptions()); err != nil {
+		logger.Log.Error().Err(err).Msg("Invalid recovery options")
+		os.Exit(1)
+	}
+	if err := n.SetLimits(cfg.Limits.Limits()); err != nil {
+		logger.Log.Error().Err(err).Msg("Invalid resource limits")
+		os.Exit(1)
+	}
+	flags, err := cfg.FeatureFlags.Set()
+	if err != nil {
+		logger.Log.Error().Err(err).Msg("Invalid feature flags")
+		os.Exit(1)
+	}
+	if cfg.FeatureFlags.Bucket != "" {
+		store, err := featureflag.Watch(js, cfg.FeatureFlags.Bucket, flags, logger.Log)
+		if err != nil {
+			logger.Log.Error().Err(err).Msg("Failed to watch feature flags")
+			os.Exit(1)
+		}
+		shutdown.HookShutdownCallback("feature flags", store.Close, 10*time.Second)
+	}
+	n.SetFlags(flags)
+	for _, subject := range cfg.Trades {
+		err := n.On(subject, func(msg *nats.Msg) error {
+			trades, err := sqx.UnmarshalTrades(msg.Data, msg.Header.Get(sqx.HeaderTradeBatch))
+			if err != nil {
+				return fmt.Errorf("failed to unmarshal trade: %w", err)
+			}
+			for _, trade := range trades {
+				publish(mon.OnTrade(trade))
+			}
+			return nil
+		}, cfg.Dispatch.For(subject))
+		if err == nil {
+			err = n.Document(subject, node.NewPayload("protobuf", (*sqx.Trade)(nil), "Trades, several per message when batched"))
+		}
+		if err != nil {
+			logger.Log.Error().Err(err).Str("subject", subject).Msg("Failed to register trade handler")
+			os.Exit(1)
+		}
+	}
+	for _, subject := range cfg.Indexes {
+		err := n.On(subject, func(msg *nats.Msg) error {
+			var price sqx.IndexPrice
+			if err := sqx.UnmarshalIndexPrice(msg.Data, &price); err != nil {
+				return fmt.Errorf("failed to unmarshal index price: %w", err)
+			}
+			publish(mon.OnIndex(price))
+			return nil
+		}, cfg.Dispatch.For(subject))
+		if err == nil {
+			err = n.Document(subject, node.NewPayload("json", (*sqx.IndexPrice)(nil), "Reference index prices"))
+		}
+		if err != nil {
+			logger.Log.Error().Err(err).Str("subject", subject).Msg("Failed to register index handler")
+			os.Exit(1)
+		}
+	}
+	for _, subject := range cfg.Quotes {
+		err := n.On(subject, func(msg *nats.Msg) error {
+			var quote sqx.Quote
+			if err := sqx.UnmarshalQuote(msg.Data, &quote); err != nil {
+				return fmt.Errorf("failed to unmarshal quote: %w", err)
+			}
+			mon.OnQuote(quote)
+			return nil
+		}, cfg.Dispatch.For(subject))
+		if err == nil {
+			err = n.Document(subject, node.NewPayload("json", (*sqx.Quote)(nil), "Venue quotes, checked for rate and spread anomalies"))
+		}
+		if err != nil {
+			logger.Log.Error().Err(err).Str("subject", subject).Msg("Failed to register quote handler")
+			os.Exit(1)
+		}
+	}
+	if bucket := mon.Bucket(); bucket > 0 {
+		done := make(chan struct{})
+		go func() {
+			ticker := time.NewTicker(bucket)
+			defer ticker.Stop()
+			mon.Tick(time.Now())
+			for {
+				select {
+				case <-done:
+					return
+				case now := <-ticker.C:
+					publish(mon.Tick(now))
+				}
+			}
+		}()
+		shutdown.HookShutdownCallback("anomaly checks", func() { close(done) }, 10*time.Second)
+	}
+
+	if err := n.Emits(cfg.NATS.Subject+".*", node.NewPayload("json", (*monitor.Alert)(nil), "Reference price and feed anomaly alerts per kind")); err != nil {
+		logger.Log.Error().Err(err).Msg("Invalid alert subject")
+		os.Exit(1)
+	}
+	if cfg.Risk.Subject != "" {
+		if err := n.Emits(cfg.Risk.Subject, node.NewPayload("json", (*monitor.RiskControl)(nil), "Risk limits toggled on and off by the alerts")); err != nil {
+			logger.Log.Error().Err(err).Msg("Invalid risk subject")
+			os.Exit(1)
+		}
+	}
+	n.SetParams(cfg)
+	n.AddSnapshot("alerts", func() (any, error) { return mon.Active(), nil })
+	n.AddSnapshot("restricted", func() (any, error) { return mon.Restricted(), nil })
+	n.AddSnapshot("health", func() (any, error) { return n.Health(), nil })
+	n.AddSnapshot("flags", func() (any, error) { return flags.Snapshot(), nil })
+
+	if err := n.Start(); err != nil {
+		logger.Log.Error().Err(err).Msg("Failed to start node")
+		os.Exit(1)
+	}
+	diag.AddQueueSource(n.QueueDepths)
+	diag.AddRPCSource(bus.RPCStats)
+	diag.AddSlowConsumerSource(bus.SlowConsumerStats)
+	shutdown.HookShutdownCallback("node", n.Stop, 10*time.Second)
+	if subject := cfg.Diagnostics.DescribeSubject("monitor"); subject != "" {
+		sub, err := n.ServeDescription(subject)
+		if err != nil {
+			logger.Log.Error().Err(err).Msg("Failed to serve node description")
+			os.Exit(1)
+		}
+		shutdown.HookShutdownCallback("node description", func() { sub.Unsubscribe() }, 10*time.Second)
+	}
+	if subject := cfg.Diagnostics.SnapshotSubject("monitor"); subject != "" {
+		sub, err := n.ServeSnapshot(subject)
+		if err != nil {
+			logger.Log.Error().Err(err).Msg("Failed to serve node snapshots")
+			os.Exit(1)
+		}
+		shutdown.HookShutdownCallback("node snapshots", func() { sub.Unsubscribe() }, 10*time.Second)
+	}
+
+	shutdown.WaitForShutdown(syscall.SIGINT, syscall.SIGTERM)
+	logger.Log.Info().Msg("Monitor command executed successfully!")
+}
+
+func main() {
+	var configFile string
+	flag.StringVar(&configFile, "c", "", "Configuration file path (required)")
+
+	flag.Usage = func() {
+		logger.Log.Info().Msg(`Monitor checks that stablecoins trade near their peg and agree across venues,
+and that index prices stay close to a trimmed median of the venues trading
+their underlying. With anomaly checks configured, it also flags the trade
+and quote feeds going silent, bursting or quoting unusually wide spreads
+against their own recent history. State changes are published to NATS as
+<subject>.<kind> alerts and, when a risk subject is configured, risk limits
+are tightened while any alert is active.
+
+Usage:
+  monitor -c <config-file>
+
+Examples:
+  monitor -c config/monitor.json
+`)
+		flag.PrintDefaults()
+	}
+
+	flag.Parse()
+
+	if configFile == "" {
+		logger.Log.Error().Msg("config file path is required")
+		flag.Usage()
+		os.Exit(1)
+	}
+
+	runMonitor(configFile)
+}
@@ -0,0 +1,150 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"syscall"
+	"time"
+
+	"github.com/BullionBear/sequex/env"
+	"github.com/BullionBear/sequex/internal/bridge"
+	"github.com/BullionBear/sequex/internal/config"
+	"github.com/BullionBear/sequex/internal/publisher"
+	"github.com/BullionBear/sequex/pkg/diagnostics"
+	"github.com/BullionBear/sequex/pkg/eventbus"
+	"github.com/BullionBear/sequex/pkg/logger"
+	"github.com/BullionBear/sequex/pkg/shutdown"
+	mqtt "github.com/eclipse/paho.mqtt.golang"
+	"github.com/nats-io/nats.go"
+)
+
+const publishTimeout = 5 * time.Second
+
+// mqttPublisher adapts the paho client to the bridge publisher interface
+type mqttPublisher struct {
+	client mqtt.Client
+}
+
+func (p *mqttPublisher) Publish(topic string, qos byte, retained bool, payload []byte) error {
+	token := p.client.Publish(topic, qos, retained, payload)
+	if qos == 0 {
+		// Fire and forget, do not block the NATS callback
+		return nil
+	}
+	if !token.WaitTimeout(publishTimeout) {
+		return fmt.Errorf("timed out publishing to %s", topic)
+	}
+	return token.Error()
+}
+
+// runMQTTBridge executes the main MQTT bridge logic
+func runMQTTBridge(configFile string) {
+	logger.Log.Info().
+		Str("version", env.Version).
+		Str("buildTime", env.BuildTime).
+		Str("commitHash", env.CommitHash).
+		Msg("MQTT bridge started")
+
+	cfg, err := config.LoadMQTTBridgeConfig(configFile)
+	if err != nil {
+		logger.Log.Error().Err(err).Msg("Failed to load config")
+		os.Exit(1)
+	}
+
+	shutdown := shutdown.NewShutdown(logger.Log)
+
+	opts := mqtt.NewClientOptions().
+		AddBroker(cfg.Broker).
+		SetClientID(cfg.ClientID).
+		SetUsername(cfg.Username).
+		SetPassword(cfg.Password).
+		SetAutoReconnect(true).
+		SetConnectionLostHandler(func(_ mqtt.Client, err error) {
+			logger.Log.Warn().Err(err).Msg("MQTT connection lost")
+		})
+	client := mqtt.NewClient(opts)
+	if token := client.Connect(); token.Wait() && token.Error() != nil {
+		logger.Log.Error().Err(token.Error()).Str("broker", cfg.Broker).Msg("Failed to connect to MQTT broker")
+		os.Exit(1)
+	}
+	defer client.Disconnect(250)
+
+	natsConn, err := nats.Connect(cfg.NATS.URIs)
+	if err != nil {
+		logger.Log.Error().Err(err).Msg("Failed to connect to NATS")
+		os.Exit(1)
+	}
+	defer natsConn.Close()
+
+	diag, stopDiagnostics, err := diagnostics.Start(cfg.Diagnostics.Options("mqttbridge"), natsConn, logger.Log)
+	if err != nil {
+		logger.Log.Error().Err(err).Msg("Failed to start diagnostics")
+		os.Exit(1)
+	}
+	shutdown.HookShutdownCallback("diagnostics", stopDiagnostics, 10*time.Second)
+
+	sink := bridge.NewMQTTSink(&mqttPublisher{client: client}, cfg.TopicPrefix)
+
+	for _, routeCfg := range cfg.Routes {
+		route := bridge.MQTTRoute{Subject: routeCfg.Subject, QoS: routeCfg.QoS, Retain: routeCfg.Retain}
+		sub, err := natsConn.Subscribe(route.Subject, func(msg *nats.Msg) {
+			if err := eventbus.Decode(msg); err != nil {
+				logger.Log.Error().Err(err).Str("subject", msg.Subject).Msg("Failed to decode message")
+				return
+			}
+			payloads, err := publisher.Unbatch(msg)
+			if err != nil {
+				logger.Log.Error().Err(err).Str("subject", msg.Subject).Msg("Failed to unbatch message")
+				return
+			}
+			for _, data := range payloads {
+				if err := sink.Deliver(route, bridge.Message{Subject: msg.Subject, Data: data}); err != nil {
+					logger.Log.Error().Err(err).Str("subject", msg.Subject).Msg("Failed to publish to MQTT")
+				}
+			}
+		})
+		if err != nil {
+			logger.Log.Error().Err(err).Str("subject", route.Subject).Msg("Failed to subscribe")
+			os.Exit(1)
+		}
+		diag.AddQueueSource(diagnostics.SubscriptionSource(sub))
+		shutdown.HookShutdownCallback("unsubscribe "+route.Subject, func() {
+			if err := sub.Unsubscribe(); err != nil {
+				logger.Log.Error().Err(err).Str("subject", route.Subject).Msg("Failed to unsubscribe")
+			}
+		}, 10*time.Second)
+		logger.Log.Info().Str("subject", route.Subject).Uint8("qos", route.QoS).Msg("Bridging subject to MQTT")
+	}
+
+	shutdown.WaitForShutdown(syscall.SIGINT, syscall.SIGTERM)
+	logger.Log.Info().Msg("MQTT bridge command executed successfully!")
+}
+
+func main() {
+	var configFile string
+	flag.StringVar(&configFile, "c", "", "Configuration file path (required)")
+
+	flag.Usage = func() {
+		logger.Log.Info().Msg(`MQTT bridge republishes NATS subjects as JSON on MQTT topics
+(trade.btcusdt -> <prefix>/trade/btcusdt) for lightweight clients.
+
+Usage:
+  mqttbridge -c <config-file>
+
+Examples:
+  mqttbridge -c config/mqttbridge.json
+`)
+		flag.PrintDefaults()
+	}
+
+	flag.Parse()
+
+	if configFile == "" {
+		logger.Log.Error().Msg("config file path is required")
+		flag.Usage()
+		os.Exit(1)
+	}
+
+	runMQTTBridge(configFile)
+}
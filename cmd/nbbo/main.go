@@ -0,0 +1,212 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/BullionBear/sequex/env"
+	"github.com/BullionBear/sequex/internal/config"
+	"github.com/BullionBear/sequex/internal/model/sqx"
+	"github.com/BullionBear/sequex/internal/nbbo"
+	"github.com/BullionBear/sequex/pkg/diagnostics"
+	"github.com/BullionBear/sequex/pkg/eventbus"
+	"github.com/BullionBear/sequex/pkg/logger"
+	"github.com/BullionBear/sequex/pkg/shutdown"
+	"github.com/nats-io/nats.go"
+)
+
+// runNBBO executes the main consolidated quote logic
+func runNBBO(configFile string) {
+	logger.Log.Info().
+		Str("version", env.Version).
+		Str("buildTime", env.BuildTime).
+		Str("commitHash", env.CommitHash).
+		Msg("NBBO started")
+
+	cfg, err := config.LoadNBBOConfig(configFile)
+	if err != nil {
+		logger.Log.Error().Err(err).Msg("Failed to load config")
+		os.Exit(1)
+	}
+
+	opts := nbbo.Options{
+		StaleAfter: time.Duration(cfg.StaleAfterMs) * time.Millisecond,
+		History:    time.Duration(cfg.HistoryMs) * time.Millisecond,
+	}
+	for _, s := range cfg.Symbols {
+		symbol, err := sqx.NewSymbolFromStr(s)
+		if err != nil {
+			logger.Log.Error().Err(err).Str("symbol", s).Msg("Invalid symbol")
+			os.Exit(1)
+		}
+		opts.Symbols = append(opts.Symbols, symbol)
+	}
+	consolidator, err := nbbo.New(opts)
+	if err != nil {
+		logger.Log.Error().Err(err).Msg("Failed to create consolidator")
+		os.Exit(1)
+	}
+
+	shutdown := shutdown.NewShutdown(logger.Log)
+
+	natsConn, err := nats.Connect(cfg.NATS.URIs)
+	if err != nil {
+		logger.Log.Error().Err(err).Msg("Failed to connect to NATS")
+		os.Exit(1)
+	}
+	defer natsConn.Close()
+
+	diag, stopDiagnostics, err := diagnostics.Start(cfg.Diagnostics.Options("nbbo"), natsConn, logger.Log)
+	if err != nil {
+		logger.Log.Error().Err(err).Msg("Failed to start diagnostics")
+		os.Exit(1)
+	}
+	shutdown.HookShutdownCallback("diagnostics", stopDiagnostics, 10*time.Second)
+	js, err := natsConn.JetStream()
+	if err != nil {
+		logger.Log.Error().Err(err).Msg("Failed to create JetStream context")
+		os.Exit(1)
+	}
+	if _, err := js.StreamInfo(cfg.NATS.Stream); err != nil {
+		logger.Log.Error().Err(err).Msg("Failed to get stream info")
+		os.Exit(1)
+	}
+	busOpts, err := cfg.NATS.BusOptions()
+	if err != nil {
+		logger.Log.Error().Err(err).Msg("Invalid event bus options")
+		os.Exit(1)
+	}
+	bus, err := eventbus.NewBus(natsConn, busOpts)
+	if err != nil {
+		logger.Log.Error().Err(err).Msg("Failed to create event bus")
+		os.Exit(1)
+	}
+
+	// Changes are published under a lock so that each symbol stays in
+	// sequence whichever goroutine produced them
+	var publishMu sync.Mutex
+	publish := func(bbo sqx.BBO) {
+		data, err := bbo.Marshal()
+		if err != nil {
+			logger.Log.Error().Err(err).Msg("Failed to marshal BBO")
+			return
+		}
+		msg := &nats.Msg{
+			Subject: fmt.Sprintf("%s.%s", cfg.NATS.Subject, strings.ToLower(bbo.Symbol.Base+bbo.Symbol.Quote)),
+			Data:    data,
+			Header: nats.Header{
+				"Nats-Msg-Id": []string{bbo.IdStr()},
+			},
+		}
+		if err := bus.Encode(msg); err != nil {
+			logger.Log.Error().Err(err).Str("bbo", bbo.IdStr()).Msg("Failed to encode BBO")
+			return
+		}
+		if _, err := js.PublishMsg(msg); err != nil {
+			logger.Log.Error().Err(err).Str("bbo", bbo.IdStr()).Msg("Failed to publish BBO")
+		}
+	}
+
+	handler := func(msg *nats.Msg) {
+		var quote sqx.Quote
+		if err := sqx.UnmarshalQuote(msg.Data, &quote); err != nil {
+			logger.Log.Error().Err(err).Str("subject", msg.Subject).Msg("Failed to unmarshal quote")
+			return
+		}
+		publishMu.Lock()
+		defer publishMu.Unlock()
+		if bbo, ok := consolidator.Update(quote, time.Now()); ok {
+			publish(bbo)
+		}
+	}
+
+	if opts.StaleAfter > 0 {
+		done := make(chan struct{})
+		go func() {
+			staleTicker := time.NewTicker(opts.StaleAfter / 2)
+			defer staleTicker.Stop()
+			for {
+				select {
+				case <-done:
+					return
+				case now := <-staleTicker.C:
+					publishMu.Lock()
+					for _, bbo := range consolidator.Expire(now) {
+						logger.Log.Warn().Str("symbol", bbo.Symbol.String()).Strs("stale", bbo.Stale).Msg("BBO changed on stale venues")
+						publish(bbo)
+					}
+					publishMu.Unlock()
+				}
+			}
+		}()
+		shutdown.HookShutdownCallback("staleness", func() { close(done) }, 10*time.Second)
+	}
+
+	subject := cfg.RPCSubject
+	if subject == "" {
+		subject = nbbo.DefaultSubject
+	}
+	rpcSub, err := nbbo.Serve(bus, subject, consolidator)
+	if err != nil {
+		logger.Log.Error().Err(err).Str("subject", subject).Msg("Failed to serve BBO RPCs")
+		os.Exit(1)
+	}
+	shutdown.HookShutdownCallback("unsubscribe "+subject, func() {
+		if err := rpcSub.Unsubscribe(); err != nil {
+			logger.Log.Error().Err(err).Str("subject", subject).Msg("Failed to unsubscribe")
+		}
+	}, 10*time.Second)
+	logger.Log.Info().Str("subject", nbbo.QuoteEndpoint(subject).Subject).Msg("Serving BBO queries")
+
+	for _, input := range cfg.Inputs {
+		sub, err := bus.Subscribe(input, handler)
+		if err != nil {
+			logger.Log.Error().Err(err).Str("subject", input).Msg("Failed to subscribe to input")
+			os.Exit(1)
+		}
+		diag.AddQueueSource(diagnostics.SubscriptionSource(sub))
+		shutdown.HookShutdownCallback("unsubscribe "+input, func() {
+			if err := sub.Unsubscribe(); err != nil {
+				logger.Log.Error().Err(err).Str("subject", input).Msg("Failed to unsubscribe")
+			}
+		}, 10*time.Second)
+		logger.Log.Info().Str("subject", input).Msg("Subscribed to input")
+	}
+
+	shutdown.WaitForShutdown(syscall.SIGINT, syscall.SIGTERM)
+	logger.Log.Info().Msg("NBBO command executed successfully!")
+}
+
+func main() {
+	var configFile string
+	flag.StringVar(&configFile, "c", "", "Configuration file path (required)")
+
+	flag.Usage = func() {
+		logger.Log.Info().Msg(`NBBO maintains the best bid and offer of symbols across the venues quoting
+them, publishes its changes to NATS as bbo.<symbol> and answers
+point-in-time queries on nbbo.quote.
+
+Usage:
+  nbbo -c <config-file>
+
+Examples:
+  nbbo -c config/nbbo.json
+`)
+		flag.PrintDefaults()
+	}
+
+	flag.Parse()
+
+	if configFile == "" {
+		logger.Log.Error().Msg("config file path is required")
+		flag.Usage()
+		os.Exit(1)
+	}
+
+	runNBBO(configFile)
+}
@@ -0,0 +1,321 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"os"
+	"syscall"
+	"time"
+
+	"github.com/BullionBear/sequex/env"
+	"github.com/BullionBear/sequex/internal/bluegreen"
+	"github.com/BullionBear/sequex/internal/config"
+	"github.com/BullionBear/sequex/internal/dryrun"
+	"github.com/BullionBear/sequex/internal/model/sqx"
+	"github.com/BullionBear/sequex/internal/oms"
+	"github.com/BullionBear/sequex/internal/tenant"
+	"github.com/BullionBear/sequex/pkg/diagnostics"
+	"github.com/BullionBear/sequex/pkg/eventbus"
+	"github.com/BullionBear/sequex/pkg/logger"
+	"github.com/BullionBear/sequex/pkg/shutdown"
+	"github.com/nats-io/nats.go"
+)
+
+// paperInterval is how often orders resting on paper are matched against
+// the prices of the market
+const paperInterval = time.Second
+
+// natsRouter routes intents on to the exchange over NATS, under the subject
+// prefix of the tenant placing them
+type natsRouter struct {
+	bus           *eventbus.Bus
+	orderSubject  string
+	cancelSubject string
+}
+
+func (r *natsRouter) PublishOrder(t *tenant.Tenant, intent sqx.OrderIntent) error {
+	data, err := intent.Marshal()
+	if err != nil {
+		return err
+	}
+	return r.bus.Publish(t.Subject(r.orderSubject), data)
+}
+
+func (r *natsRouter) PublishCancel(t *tenant.Tenant, intent sqx.CancelIntent) error {
+	data, err := intent.Marshal()
+	if err != nil {
+		return err
+	}
+	return r.bus.Publish(t.Subject(r.cancelSubject), data)
+}
+
+// natsReporter publishes the reports and orders of the paper layer to NATS,
+// under the subject prefix of the tenant placing them
+type natsReporter struct {
+	bus              *eventbus.Bus
+	executionSubject string
+	dryRunSubject    string
+}
+
+func (r *natsReporter) PublishReport(t *tenant.Tenant, report sqx.ExecutionReport) error {
+	data, err := report.Marshal()
+	if err != nil {
+		return err
+	}
+	return r.bus.Publish(t.Subject(r.executionSubject), data)
+}
+
+func (r *natsReporter) PublishDryRun(t *tenant.Tenant, order dryrun.Order) error {
+	data, err := json.Marshal(order)
+	if err != nil {
+		return err
+	}
+	return r.bus.Publish(t.Subject(r.dryRunSubject), data)
+}
+
+// runOMS executes the main OMS logic
+func runOMS(configFile string) {
+	logger.Log.Info().
+		Str("version", env.Version).
+		Str("buildTime", env.BuildTime).
+		Str("commitHash", env.CommitHash).
+		Msg("OMS started")
+
+	cfg, err := config.LoadOMSConfig(configFile)
+	if err != nil {
+		logger.Log.Error().Err(err).Msg("Failed to load config")
+		os.Exit(1)
+	}
+
+	shutdown := shutdown.NewShutdown(logger.Log)
+
+	natsConn, err := nats.Connect(cfg.NATS.URIs)
+	if err != nil {
+		logger.Log.Error().Err(err).Msg("Failed to connect to NATS")
+		os.Exit(1)
+	}
+	defer natsConn.Close()
+
+	diag, stopDiagnostics, err := diagnostics.Start(cfg.Diagnostics.Options("oms"), natsConn, logger.Log)
+	if err != nil {
+		logger.Log.Error().Err(err).Msg("Failed to start diagnostics")
+		os.Exit(1)
+	}
+	shutdown.HookShutdownCallback("diagnostics", stopDiagnostics, 10*time.Second)
+	busOpts, err := cfg.NATS.BusOptions()
+	if err != nil {
+		logger.Log.Error().Err(err).Msg("Invalid event bus options")
+		os.Exit(1)
+	}
+	if busOpts.Authorizer, err = cfg.NATS.Authorizer(natsConn, "oms"); err != nil {
+		logger.Log.Error().Err(err).Msg("Invalid command policy")
+		os.Exit(1)
+	}
+	bus, err := eventbus.NewBus(natsConn, busOpts)
+	if err != nil {
+		logger.Log.Error().Err(err).Msg("Failed to create event bus")
+		os.Exit(1)
+	}
+	laneConn, err := cfg.NATS.ConnectPriorityLane(bus)
+	if err != nil {
+		logger.Log.Error().Err(err).Msg("Failed to connect the priority lane")
+		os.Exit(1)
+	}
+	if laneConn != nil {
+		defer laneConn.Close()
+	}
+
+	tenants, err := config.TenantRegistry(cfg.Tenants)
+	if err != nil {
+		logger.Log.Error().Err(err).Msg("Invalid tenants")
+		os.Exit(1)
+	}
+
+	router := &natsRouter{bus: bus, orderSubject: cfg.RouteOrderSubject, cancelSubject: cfg.RouteCancelSubject}
+	reporter := &natsReporter{bus: bus, executionSubject: cfg.ExecutionSubject}
+	flags := dryrun.NewFlags(false, nil)
+	var subs []*nats.Subscription
+	if cfg.DryRun != nil {
+		flags = cfg.DryRun.Flags()
+		flags.OnChange(func(c dryrun.Change) {
+			event := logger.Log.Warn().Str("strategy", c.Strategy).Str("by", c.By)
+			if c.After != nil {
+				event = event.Bool("dryRun", *c.After)
+			}
+			event.Msg("Dry-run flag switched")
+		})
+		subject := cfg.DryRun.RPCSubject("oms")
+		rpcSubs, err := dryrun.Serve(bus, subject, flags)
+		if err != nil {
+			logger.Log.Error().Err(err).Str("subject", subject).Msg("Failed to serve params RPCs")
+			os.Exit(1)
+		}
+		subs = append(subs, rpcSubs...)
+		reporter.dryRunSubject = cfg.DryRun.OrderSubject
+		logger.Log.Info().Bool("global", cfg.DryRun.Enabled).Str("subject", subject).Msg("Keeping dry-run strategies off the exchange")
+	}
+	entry := oms.NewEntry(router, reporter, flags)
+
+	// Orders on paper are filled, and the PnLs of the instances of deployed
+	// strategies valued, at the last trade prices
+	if len(cfg.FX.Inputs) > 0 || cfg.BlueGreen != nil {
+		rates, err := cfg.FX.Rates()
+		if err != nil {
+			logger.Log.Error().Err(err).Msg("Invalid rates")
+			os.Exit(1)
+		}
+		for _, input := range cfg.FX.Inputs {
+			sub, err := natsConn.Subscribe(input, func(msg *nats.Msg) {
+				if err := eventbus.Decode(msg); err != nil {
+					logger.Log.Error().Err(err).Str("subject", msg.Subject).Msg("Failed to decode trade")
+					return
+				}
+				trades, err := sqx.UnmarshalTrades(msg.Data, msg.Header.Get(sqx.HeaderTradeBatch))
+				if err != nil {
+					logger.Log.Error().Err(err).Str("subject", msg.Subject).Msg("Failed to unmarshal trade")
+					return
+				}
+				for _, trade := range trades {
+					rates.OnTrade(trade)
+				}
+			})
+			if err != nil {
+				logger.Log.Error().Err(err).Str("subject", input).Msg("Failed to subscribe to FX input")
+				os.Exit(1)
+			}
+			subs = append(subs, sub)
+		}
+		entry.SetPaper(dryrun.NewPaper(rates))
+
+		if cfg.BlueGreen != nil {
+			deployments, err := cfg.BlueGreen.Open(rates)
+			if err != nil {
+				logger.Log.Error().Err(err).Msg("Failed to open deployments")
+				os.Exit(1)
+			}
+			defer deployments.Close()
+			deployments.OnPromote(func(p bluegreen.Promotion) {
+				event := logger.Log.Warn().Str("strategy", p.Strategy).Str("live", p.Live).Str("by", p.By).Str("reason", p.Reason)
+				if c := p.Comparison; c != nil {
+					event = event.Float64("livePnL", c.Live.PnL).Float64("shadowPnL", c.Shadow.PnL).Float64("matched", c.Matched)
+				}
+				event.Msg("Shadow instance promoted")
+			})
+			subject := cfg.BlueGreen.RPCSubject("oms")
+			rpcSubs, err := bluegreen.Serve(bus, subject, deployments)
+			if err != nil {
+				logger.Log.Error().Err(err).Str("subject", subject).Msg("Failed to serve params RPCs")
+				os.Exit(1)
+			}
+			subs = append(subs, rpcSubs...)
+			entry.SetDeployments(deployments)
+
+			// Reports of the orders of the live instances are followed under
+			// the subject prefix of the tenant that placed them
+			for _, t := range append([]*tenant.Tenant{nil}, tenants.Tenants()...) {
+				sub, err := bus.Subscribe(t.Subject(cfg.ReportSubject), func(msg *nats.Msg) {
+					var report sqx.ExecutionReport
+					if err := sqx.UnmarshalExecutionReport(msg.Data, &report); err != nil {
+						logger.Log.Error().Err(err).Str("subject", msg.Subject).Msg("Failed to unmarshal execution report")
+						return
+					}
+					entry.OnExecutionReport(t, report)
+				})
+				if err != nil {
+					logger.Log.Error().Err(err).Str("subject", t.Subject(cfg.ReportSubject)).Msg("Failed to subscribe to execution reports")
+					os.Exit(1)
+				}
+				subs = append(subs, sub)
+			}
+			logger.Log.Info().Int("deployments", len(deployments.Deployments())).Str("subject", subject).Msg("Running shadow instances on paper")
+		}
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	go entry.RunPaper(ctx, paperInterval)
+	shutdown.HookShutdownCallback("paper", cancel, time.Second)
+
+	// Intents of each tenant enter under its subject prefix and are routed
+	// and reported under it
+	var intentSubs []*nats.Subscription
+	for _, t := range append([]*tenant.Tenant{nil}, tenants.Tenants()...) {
+		orderSub, err := bus.Subscribe(t.Subject(cfg.OrderSubject), func(msg *nats.Msg) {
+			var intent sqx.OrderIntent
+			if err := sqx.UnmarshalOrderIntent(msg.Data, &intent); err != nil {
+				logger.Log.Error().Err(err).Str("subject", msg.Subject).Msg("Failed to unmarshal order intent")
+				return
+			}
+			if err := entry.Submit(t, intent); err != nil {
+				logger.Log.Error().Err(err).Str("strategy", intent.Strategy).Str("clOrdID", intent.ClientOrderId).Msg("Failed to route order intent")
+			}
+		})
+		if err != nil {
+			logger.Log.Error().Err(err).Str("subject", t.Subject(cfg.OrderSubject)).Msg("Failed to subscribe to order intents")
+			os.Exit(1)
+		}
+		cancelSub, err := bus.Subscribe(t.Subject(cfg.CancelSubject), func(msg *nats.Msg) {
+			var intent sqx.CancelIntent
+			if err := sqx.UnmarshalCancelIntent(msg.Data, &intent); err != nil {
+				logger.Log.Error().Err(err).Str("subject", msg.Subject).Msg("Failed to unmarshal cancel intent")
+				return
+			}
+			if err := entry.Cancel(t, intent); err != nil {
+				logger.Log.Error().Err(err).Str("strategy", intent.Strategy).Str("clOrdID", intent.ClientOrderId).Msg("Failed to route cancel intent")
+			}
+		})
+		if err != nil {
+			logger.Log.Error().Err(err).Str("subject", t.Subject(cfg.CancelSubject)).Msg("Failed to subscribe to cancel intents")
+			os.Exit(1)
+		}
+		intentSubs = append(intentSubs, orderSub, cancelSub)
+	}
+	diag.AddQueueSource(diagnostics.SubscriptionSource(intentSubs...))
+	logger.Log.Info().Str("orders", cfg.OrderSubject).Str("route", cfg.RouteOrderSubject).Msg("Routing intents")
+
+	shutdown.HookShutdownCallback("intents", func() {
+		for _, sub := range append(intentSubs, subs...) {
+			if err := sub.Unsubscribe(); err != nil {
+				logger.Log.Error().Err(err).Msg("Failed to unsubscribe")
+			}
+		}
+	}, 10*time.Second)
+
+	shutdown.WaitForShutdown(syscall.SIGINT, syscall.SIGTERM)
+	logger.Log.Info().Msg("OMS command executed successfully!")
+}
+
+func main() {
+	var configFile string
+	flag.StringVar(&configFile, "c", "", "Configuration file path (required)")
+
+	flag.Usage = func() {
+		logger.Log.Info().Msg(`OMS is the entry of the order management system. It receives the order
+and cancel intents strategies and gateways publish, under the subject
+prefix of their tenant, and routes them on to the exchange. Strategies in
+dry run, switched at runtime with sqx dryrun, are kept off the exchange:
+their orders are acknowledged and filled on paper, reported like those of
+the exchange and published as the orders they would have sent. Strategies
+deployed blue/green run as a live and a shadow instance: the orders of the
+live one are routed under the strategy deployed, those of the shadow one
+kept on paper, both compared until the shadow is promoted with sqx
+deployments.
+
+Usage:
+  oms -c <config-file>
+
+Examples:
+  oms -c config/oms.json
+`)
+		flag.PrintDefaults()
+	}
+
+	flag.Parse()
+
+	if configFile == "" {
+		logger.Log.Error().Msg("config file path is required")
+		flag.Usage()
+		os.Exit(1)
+	}
+
+	runOMS(configFile)
+}
@@ -0,0 +1,148 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"net/http"
+	"os"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/BullionBear/sequex/env"
+	"github.com/BullionBear/sequex/internal/config"
+	"github.com/BullionBear/sequex/internal/poller"
+	"github.com/BullionBear/sequex/pkg/diagnostics"
+	"github.com/BullionBear/sequex/pkg/eventbus"
+	"github.com/BullionBear/sequex/pkg/logger"
+	"github.com/BullionBear/sequex/pkg/shutdown"
+	"github.com/nats-io/nats.go"
+)
+
+// runPoller executes the main REST poller logic
+func runPoller(configFile string) {
+	logger.Log.Info().
+		Str("version", env.Version).
+		Str("buildTime", env.BuildTime).
+		Str("commitHash", env.CommitHash).
+		Msg("Poller started")
+
+	cfg, err := config.LoadPollerConfig(configFile)
+	if err != nil {
+		logger.Log.Error().Err(err).Msg("Failed to load config")
+		os.Exit(1)
+	}
+
+	client := &http.Client{}
+	pollers := make([]*poller.Poller, 0, len(cfg.Endpoints))
+	for i := range cfg.Endpoints {
+		p, err := poller.New(cfg.Endpoints[i].Endpoint(cfg.NATS.Subject), client)
+		if err != nil {
+			logger.Log.Error().Err(err).Msg("Failed to create poller")
+			os.Exit(1)
+		}
+		pollers = append(pollers, p)
+	}
+
+	shutdown := shutdown.NewShutdown(logger.Log)
+
+	natsConn, err := nats.Connect(cfg.NATS.URIs)
+	if err != nil {
+		logger.Log.Error().Err(err).Msg("Failed to connect to NATS")
+		os.Exit(1)
+	}
+	defer natsConn.Close()
+
+	_, stopDiagnostics, err := diagnostics.Start(cfg.Diagnostics.Options("poller"), natsConn, logger.Log)
+	if err != nil {
+		logger.Log.Error().Err(err).Msg("Failed to start diagnostics")
+		os.Exit(1)
+	}
+	shutdown.HookShutdownCallback("diagnostics", stopDiagnostics, 10*time.Second)
+	js, err := natsConn.JetStream()
+	if err != nil {
+		logger.Log.Error().Err(err).Msg("Failed to create JetStream context")
+		os.Exit(1)
+	}
+	if _, err := js.StreamInfo(cfg.NATS.Stream); err != nil {
+		logger.Log.Error().Err(err).Msg("Failed to get stream info")
+		os.Exit(1)
+	}
+	busOpts, err := cfg.NATS.BusOptions()
+	if err != nil {
+		logger.Log.Error().Err(err).Msg("Invalid event bus options")
+		os.Exit(1)
+	}
+	bus, err := eventbus.NewBus(natsConn, busOpts)
+	if err != nil {
+		logger.Log.Error().Err(err).Msg("Failed to create event bus")
+		os.Exit(1)
+	}
+
+	emit := func(event poller.Event) error {
+		data, err := json.Marshal(event)
+		if err != nil {
+			return fmt.Errorf("failed to marshal: %w", err)
+		}
+		msg := &nats.Msg{Subject: event.Subject, Data: data}
+		if err := bus.Encode(msg); err != nil {
+			return fmt.Errorf("failed to encode: %w", err)
+		}
+		if _, err := js.PublishMsg(msg); err != nil {
+			return fmt.Errorf("failed to publish: %w", err)
+		}
+		logger.Log.Debug().Str("subject", msg.Subject).Msg("Published polled event")
+		return nil
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	var wg sync.WaitGroup
+	for _, p := range pollers {
+		wg.Add(1)
+		go func(p *poller.Poller) {
+			defer wg.Done()
+			p.Run(ctx, emit, func(err error) {
+				logger.Log.Error().Err(err).Str("endpoint", p.Name()).Msg("Failed to poll endpoint")
+			})
+		}(p)
+		logger.Log.Info().Str("endpoint", p.Name()).Dur("interval", p.Interval()).Msg("Polling endpoint")
+	}
+	shutdown.HookShutdownCallback("pollers", func() {
+		cancel()
+		wg.Wait()
+	}, 10*time.Second)
+
+	shutdown.WaitForShutdown(syscall.SIGINT, syscall.SIGTERM)
+	logger.Log.Info().Msg("Poller command executed successfully!")
+}
+
+func main() {
+	var configFile string
+	flag.StringVar(&configFile, "c", "", "Configuration file path (required)")
+
+	flag.Usage = func() {
+		logger.Log.Info().Msg(`Poller calls the REST endpoints defined in a YAML configuration on their
+intervals, extracts fields from the responses with JSON paths and publishes
+each result as a typed event to NATS as <subject>.<endpoint subject>.
+
+Usage:
+  poller -c <config-file>
+
+Examples:
+  poller -c config/poller.yaml
+`)
+		flag.PrintDefaults()
+	}
+
+	flag.Parse()
+
+	if configFile == "" {
+		logger.Log.Error().Msg("config file path is required")
+		flag.Usage()
+		os.Exit(1)
+	}
+
+	runPoller(configFile)
+}
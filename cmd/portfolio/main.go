@@ -0,0 +1,198 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/BullionBear/sequex/internal/pms"
+)
+
+const usage = `Portfolio exports and imports portfolio positions and fill history through
+the master API.
+
+Usage:
+  portfolio export [flags]
+  portfolio import [flags] <file>
+
+Examples:
+  portfolio export -p main -o main.json
+  portfolio export -p main -f csv -d fills -o fills.csv
+  portfolio import -p main -d positions -dry-run positions.csv
+  portfolio import -p main main.json
+`
+
+func main() {
+	flag.Usage = func() {
+		fmt.Fprint(os.Stderr, usage)
+	}
+	flag.Parse()
+	if flag.NArg() == 0 {
+		flag.Usage()
+		os.Exit(1)
+	}
+
+	var err error
+	switch flag.Arg(0) {
+	case "export":
+		err = runExport(flag.Args()[1:])
+	case "import":
+		err = runImport(flag.Args()[1:])
+	default:
+		fmt.Fprintf(os.Stderr, "Error: unknown command %q\n", flag.Arg(0))
+		flag.Usage()
+		os.Exit(1)
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+}
+
+// options are the flags shared by both commands
+type options struct {
+	master    string
+	portfolio string
+	format    string
+	dataset   string
+}
+
+func (o *options) register(fs *flag.FlagSet) {
+	fs.StringVar(&o.master, "m", "http://localhost:8080", "master API address")
+	fs.StringVar(&o.portfolio, "p", "", "portfolio id (required)")
+	fs.StringVar(&o.format, "f", "", "json or csv (default: from the file extension, else json)")
+	fs.StringVar(&o.dataset, "d", "", "positions or fills, required for csv")
+}
+
+// endpoint builds the URL of a transfer endpoint, inferring the format from file
+func (o *options) endpoint(action, file string, query url.Values) (string, pms.Format, error) {
+	if o.portfolio == "" {
+		return "", "", fmt.Errorf("portfolio id (-p) is required")
+	}
+	format := o.format
+	if format == "" && strings.EqualFold(filepath.Ext(file), ".csv") {
+		format = string(pms.FormatCSV)
+	}
+	f, err := pms.ParseFormat(format)
+	if err != nil {
+		return "", "", err
+	}
+	query.Set("format", string(f))
+	if f == pms.FormatCSV {
+		if _, err := pms.ParseDataset(o.dataset); err != nil {
+			return "", "", err
+		}
+		query.Set("dataset", o.dataset)
+	}
+	return fmt.Sprintf("%s/api/v2/portfolios/%s/%s?%s",
+		strings.TrimRight(o.master, "/"), url.PathEscape(o.portfolio), action, query.Encode()), f, nil
+}
+
+var client = &http.Client{Timeout: time.Minute}
+
+func runExport(args []string) error {
+	fs := flag.NewFlagSet("export", flag.ExitOnError)
+	var opts options
+	opts.register(fs)
+	output := fs.String("o", "", "output file (default: stdout)")
+	fs.Parse(args)
+
+	endpoint, _, err := opts.endpoint("export", *output, url.Values{})
+	if err != nil {
+		return err
+	}
+	resp, err := client.Get(endpoint)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return responseError(resp)
+	}
+
+	var w io.Writer = os.Stdout
+	if *output != "" {
+		file, err := os.Create(*output)
+		if err != nil {
+			return fmt.Errorf("failed to create %s: %w", *output, err)
+		}
+		defer file.Close()
+		w = file
+	}
+	_, err = io.Copy(w, resp.Body)
+	return err
+}
+
+func runImport(args []string) error {
+	fs := flag.NewFlagSet("import", flag.ExitOnError)
+	var opts options
+	opts.register(fs)
+	dryRun := fs.Bool("dry-run", false, "validate without writing")
+	fs.Parse(args)
+	if fs.NArg() != 1 {
+		return fmt.Errorf("exactly one input file is required")
+	}
+	input := fs.Arg(0)
+
+	query := url.Values{}
+	if *dryRun {
+		query.Set("dry_run", "true")
+	}
+	endpoint, format, err := opts.endpoint("import", input, query)
+	if err != nil {
+		return err
+	}
+	file, err := os.Open(input)
+	if err != nil {
+		return fmt.Errorf("failed to open %s: %w", input, err)
+	}
+	defer file.Close()
+
+	contentType := "application/json"
+	if format == pms.FormatCSV {
+		contentType = "text/csv"
+	}
+	resp, err := client.Post(endpoint, contentType, file)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK && resp.StatusCode != http.StatusUnprocessableEntity {
+		return responseError(resp)
+	}
+
+	var result pms.ImportResult
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return fmt.Errorf("invalid response: %w", err)
+	}
+	for _, e := range result.Errors {
+		fmt.Fprintf(os.Stderr, "%s row %d: %s\n", e.Dataset, e.Row, e.Error)
+	}
+	if len(result.Errors) > 0 {
+		return fmt.Errorf("%d rows rejected, nothing imported", len(result.Errors))
+	}
+	verb := "Imported"
+	if result.DryRun {
+		verb = "Validated"
+	}
+	fmt.Printf("%s %d positions and %d fills into %s\n", verb, result.Positions, result.Fills, opts.portfolio)
+	return nil
+}
+
+// responseError reads the error message of a failed request
+func responseError(resp *http.Response) error {
+	var body struct {
+		Error string `json:"error"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&body); err != nil || body.Error == "" {
+		return fmt.Errorf("request failed: %s", resp.Status)
+	}
+	return fmt.Errorf("request failed: %s: %s", resp.Status, body.Error)
+}
@@ -0,0 +1,155 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/BullionBear/sequex/env"
+	"github.com/BullionBear/sequex/internal/config"
+	"github.com/BullionBear/sequex/internal/positioning"
+	"github.com/BullionBear/sequex/pkg/diagnostics"
+	"github.com/BullionBear/sequex/pkg/eventbus"
+	"github.com/BullionBear/sequex/pkg/exchange/binanceperp"
+	"github.com/BullionBear/sequex/pkg/logger"
+	"github.com/BullionBear/sequex/pkg/shutdown"
+	"github.com/nats-io/nats.go"
+)
+
+// runPositioning executes the main positioning collector logic
+func runPositioning(configFile string) {
+	logger.Log.Info().
+		Str("version", env.Version).
+		Str("buildTime", env.BuildTime).
+		Str("commitHash", env.CommitHash).
+		Msg("Positioning started")
+
+	cfg, err := config.LoadPositioningConfig(configFile)
+	if err != nil {
+		logger.Log.Error().Err(err).Msg("Failed to load config")
+		os.Exit(1)
+	}
+	opts, err := cfg.Options()
+	if err != nil {
+		logger.Log.Error().Err(err).Msg("Invalid collector options")
+		os.Exit(1)
+	}
+
+	baseURL := cfg.BaseURL
+	if baseURL == "" {
+		baseURL = binanceperp.MainnetBaseUrl
+	}
+	collector, err := positioning.NewCollector(binanceperp.NewClient(&binanceperp.Config{BaseURL: baseURL}), opts)
+	if err != nil {
+		logger.Log.Error().Err(err).Msg("Failed to create collector")
+		os.Exit(1)
+	}
+
+	shutdown := shutdown.NewShutdown(logger.Log)
+
+	natsConn, err := nats.Connect(cfg.NATS.URIs)
+	if err != nil {
+		logger.Log.Error().Err(err).Msg("Failed to connect to NATS")
+		os.Exit(1)
+	}
+	defer natsConn.Close()
+
+	_, stopDiagnostics, err := diagnostics.Start(cfg.Diagnostics.Options("positioning"), natsConn, logger.Log)
+	if err != nil {
+		logger.Log.Error().Err(err).Msg("Failed to start diagnostics")
+		os.Exit(1)
+	}
+	shutdown.HookShutdownCallback("diagnostics", stopDiagnostics, 10*time.Second)
+	js, err := natsConn.JetStream()
+	if err != nil {
+		logger.Log.Error().Err(err).Msg("Failed to create JetStream context")
+		os.Exit(1)
+	}
+	if _, err := js.StreamInfo(cfg.NATS.Stream); err != nil {
+		logger.Log.Error().Err(err).Msg("Failed to get stream info")
+		os.Exit(1)
+	}
+	busOpts, err := cfg.NATS.BusOptions()
+	if err != nil {
+		logger.Log.Error().Err(err).Msg("Invalid event bus options")
+		os.Exit(1)
+	}
+	bus, err := eventbus.NewBus(natsConn, busOpts)
+	if err != nil {
+		logger.Log.Error().Err(err).Msg("Failed to create event bus")
+		os.Exit(1)
+	}
+
+	emit := func(event positioning.Event) error {
+		data, err := event.Record.Marshal()
+		if err != nil {
+			return fmt.Errorf("failed to marshal: %w", err)
+		}
+		symbol := strings.ToLower(event.Symbol.Base + event.Symbol.Quote)
+		msg := &nats.Msg{
+			Subject: fmt.Sprintf("%s.%s.%s", cfg.NATS.Subject, event.Dataset, symbol),
+			Data:    data,
+			Header: nats.Header{
+				"Nats-Msg-Id": []string{event.Record.IdStr()},
+			},
+		}
+		if err := bus.Encode(msg); err != nil {
+			return fmt.Errorf("failed to encode: %w", err)
+		}
+		if _, err := js.PublishMsg(msg); err != nil {
+			return fmt.Errorf("failed to publish: %w", err)
+		}
+		logger.Log.Debug().Str("subject", msg.Subject).Msg("Published positioning data")
+		return nil
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		collector.Run(ctx, time.Duration(cfg.IntervalMs)*time.Millisecond, emit, func(err error) {
+			logger.Log.Error().Err(err).Msg("Failed to collect positioning data")
+		})
+	}()
+	shutdown.HookShutdownCallback("collector", func() {
+		cancel()
+		<-done
+	}, 10*time.Second)
+
+	logger.Log.Info().Strs("symbols", cfg.Symbols).Str("period", opts.Period).Msg("Collecting positioning data")
+	shutdown.WaitForShutdown(syscall.SIGINT, syscall.SIGTERM)
+	logger.Log.Info().Msg("Positioning command executed successfully!")
+}
+
+func main() {
+	var configFile string
+	flag.StringVar(&configFile, "c", "", "Configuration file path (required)")
+
+	flag.Usage = func() {
+		logger.Log.Info().Msg(`Positioning polls open interest, long/short ratios and taker buy/sell volume
+of Binance USDⓈ-M futures and publishes each new period to NATS as
+<subject>.<dataset>.<symbol>.
+
+Usage:
+  positioning -c <config-file>
+
+Examples:
+  positioning -c config/positioning.json
+`)
+		flag.PrintDefaults()
+	}
+
+	flag.Parse()
+
+	if configFile == "" {
+		logger.Log.Error().Msg("config file path is required")
+		flag.Usage()
+		os.Exit(1)
+	}
+
+	runPositioning(configFile)
+}
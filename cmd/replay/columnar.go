@@ -0,0 +1,96 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/BullionBear/sequex/internal/model/protobuf"
+	"github.com/BullionBear/sequex/internal/model/sqx"
+	"github.com/BullionBear/sequex/pkg/candles"
+	"github.com/BullionBear/sequex/pkg/columnar"
+)
+
+// tradeConverter collects replayed trades per symbol and writes them, and
+// the klines they make, as columnar tables, so that backtests and analytics
+// read them without parsing the recording again
+type tradeConverter struct {
+	store     *columnar.Store
+	intervals []string
+	trades    map[string][]columnar.Trade
+}
+
+// newTradeConverter creates a converter into the store under dir following
+// the columnar flags
+func newTradeConverter(dir string) (*tradeConverter, error) {
+	store, err := columnar.NewStore(dir)
+	if err != nil {
+		return nil, err
+	}
+	c := &tradeConverter{store: store, trades: make(map[string][]columnar.Trade)}
+	for _, interval := range strings.Split(*intervals, ",") {
+		if interval = strings.TrimSpace(interval); interval == "" {
+			continue
+		}
+		if _, err := candles.ParseInterval(interval); err != nil {
+			return nil, err
+		}
+		c.intervals = append(c.intervals, interval)
+	}
+	return c, nil
+}
+
+// Convert collects a trade
+func (c *tradeConverter) Convert(_ int, pb *protobuf.Trade) error {
+	trade := &sqx.Trade{}
+	if err := trade.FromProtobuf(pb); err != nil {
+		return nil // Not a trade of a known exchange, skipped as when displaying
+	}
+	symbol := trade.Symbol.String()
+	c.trades[symbol] = append(c.trades[symbol], columnar.Trade{
+		Time:     trade.Timestamp,
+		ID:       trade.Id,
+		Price:    trade.Price,
+		Quantity: trade.Quantity,
+		Side:     columnar.Side(pb.Side),
+	})
+	return nil
+}
+
+// Close merges the trades into the tables of their symbols, with the klines
+// closed by the last trade of each
+func (c *tradeConverter) Close() error {
+	symbols := make([]string, 0, len(c.trades))
+	for symbol := range c.trades {
+		symbols = append(symbols, symbol)
+	}
+	sort.Strings(symbols)
+	for _, symbol := range symbols {
+		trades := c.trades[symbol]
+		if err := c.store.AppendTrades(symbol, trades); err != nil {
+			return fmt.Errorf("failed to write the trades of %s: %w", symbol, err)
+		}
+		path, _ := c.store.TradesPath(symbol)
+		fmt.Printf("Wrote %d trades of %s to %s\n", len(trades), symbol, path)
+
+		last := trades[0].Time
+		for _, t := range trades {
+			last = max(last, t.Time)
+		}
+		for _, interval := range c.intervals {
+			builder, _ := candles.NewBuilder(interval) // Validated with the flags
+			for _, t := range trades {
+				builder.Add(t.Time, t.Price, t.Quantity)
+			}
+			// The kline of the last trade may have more trades after the
+			// recording ends, and is left out until a later one has them
+			klines := builder.Flush(last + 1)
+			if err := c.store.Record(symbol, interval, klines); err != nil {
+				return fmt.Errorf("failed to write the %s klines of %s: %w", interval, symbol, err)
+			}
+			path, _ := c.store.KlinesPath(symbol, interval)
+			fmt.Printf("Wrote %d %s klines of %s to %s\n", len(klines), interval, symbol, path)
+		}
+	}
+	return nil
+}
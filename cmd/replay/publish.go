@@ -0,0 +1,108 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/signal"
+	"strings"
+	"syscall"
+
+	"github.com/BullionBear/sequex/internal/model/protobuf"
+	"github.com/BullionBear/sequex/internal/model/sqx"
+	"github.com/BullionBear/sequex/internal/publisher"
+	"github.com/BullionBear/sequex/internal/replay"
+	"github.com/nats-io/nats.go"
+)
+
+// tradePublisher streams replayed trades into NATS, paced like the recording
+type tradePublisher struct {
+	conn     *nats.Conn
+	js       nats.JetStreamContext
+	subjects *replay.Subjects
+	pacer    *replay.Pacer
+	encoder  *publisher.TradeEncoder
+	ctx      context.Context
+	stop     context.CancelFunc
+
+	published map[string]int
+}
+
+// newTradePublisher connects to NATS following the publish flags
+func newTradePublisher() (*tradePublisher, error) {
+	var mappings []replay.Remap
+	if *remaps != "" {
+		for _, s := range strings.Split(*remaps, ",") {
+			m, err := replay.ParseRemap(s)
+			if err != nil {
+				return nil, err
+			}
+			mappings = append(mappings, m)
+		}
+	}
+	subjects, err := replay.NewSubjects(*subject, mappings)
+	if err != nil {
+		return nil, err
+	}
+
+	conn, err := nats.Connect(*natsURL, nats.Name("sequex-replay"))
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to NATS: %w", err)
+	}
+	p := &tradePublisher{
+		conn:      conn,
+		subjects:  subjects,
+		pacer:     replay.NewPacer(*speed),
+		encoder:   publisher.NewTradeEncoder(),
+		published: make(map[string]int),
+	}
+	if *jetStream {
+		if p.js, err = conn.JetStream(); err != nil {
+			conn.Close()
+			return nil, fmt.Errorf("failed to create JetStream context: %w", err)
+		}
+	}
+	// Interrupting a replay stops it and still prints the summary
+	p.ctx, p.stop = signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	return p, nil
+}
+
+// Publish waits for the trade to be due, then publishes it
+func (p *tradePublisher) Publish(_ int, pb *protobuf.Trade) error {
+	trade := &sqx.Trade{}
+	if err := trade.FromProtobuf(pb); err != nil {
+		return nil // Not a trade of a known exchange, skipped as when displaying
+	}
+	if err := p.pacer.Wait(p.ctx, trade.Timestamp); err != nil {
+		return err
+	}
+	msg, err := p.encoder.Encode(p.subjects.Subject(trade), trade)
+	if err != nil {
+		return fmt.Errorf("failed to encode trade %d: %w", trade.Id, err)
+	}
+	if !*dedup {
+		msg.Header.Del(nats.MsgIdHdr)
+	}
+	if p.js != nil {
+		_, err = p.js.PublishMsg(msg, nats.Context(p.ctx))
+	} else {
+		err = p.conn.PublishMsg(msg)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to publish trade %d to %s: %w", trade.Id, msg.Subject, err)
+	}
+	p.published[msg.Subject]++
+	return nil
+}
+
+// Close flushes pending messages and prints the published count per subject
+func (p *tradePublisher) Close() {
+	p.stop()
+	if err := p.conn.Flush(); err != nil {
+		fmt.Printf("Failed to flush NATS connection: %v\n", err)
+	}
+	p.conn.Close()
+	for subject, count := range p.published {
+		fmt.Printf("Published %d trades to %s\n", count, subject)
+	}
+}
@@ -0,0 +1,238 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"os"
+	"syscall"
+	"time"
+
+	"github.com/BullionBear/sequex/api"
+	"github.com/BullionBear/sequex/env"
+	"github.com/BullionBear/sequex/internal/config"
+	"github.com/BullionBear/sequex/internal/execution"
+	"github.com/BullionBear/sequex/internal/model/sqx"
+	"github.com/BullionBear/sequex/internal/monitor"
+	"github.com/BullionBear/sequex/internal/report"
+	"github.com/BullionBear/sequex/pkg/diagnostics"
+	"github.com/BullionBear/sequex/pkg/eventbus"
+	"github.com/BullionBear/sequex/pkg/logger"
+	"github.com/BullionBear/sequex/pkg/shutdown"
+	"github.com/nats-io/nats.go"
+)
+
+// runReport executes the main report service logic
+func runReport(configFile string) {
+	logger.Log.Info().
+		Str("version", env.Version).
+		Str("buildTime", env.BuildTime).
+		Str("commitHash", env.CommitHash).
+		Msg("Report started")
+
+	cfg, err := config.LoadReportConfig(configFile)
+	if err != nil {
+		logger.Log.Error().Err(err).Msg("Failed to load config")
+		os.Exit(1)
+	}
+	schedule, _ := cfg.Schedule() // Validated with the configuration
+	senders := cfg.Senders()
+	collector := report.NewCollector()
+
+	shutdown := shutdown.NewShutdown(logger.Log)
+
+	natsConn, err := nats.Connect(cfg.NATS.URIs)
+	if err != nil {
+		logger.Log.Error().Err(err).Msg("Failed to connect to NATS")
+		os.Exit(1)
+	}
+	defer natsConn.Close()
+
+	_, stopDiagnostics, err := diagnostics.Start(cfg.Diagnostics.Options("report"), natsConn, logger.Log)
+	if err != nil {
+		logger.Log.Error().Err(err).Msg("Failed to start diagnostics")
+		os.Exit(1)
+	}
+	shutdown.HookShutdownCallback("diagnostics", stopDiagnostics, 10*time.Second)
+	js, err := natsConn.JetStream()
+	if err != nil {
+		logger.Log.Error().Err(err).Msg("Failed to create JetStream context")
+		os.Exit(1)
+	}
+	if _, err := js.StreamInfo(cfg.NATS.Stream); err != nil {
+		logger.Log.Error().Err(err).Msg("Failed to get stream info")
+		os.Exit(1)
+	}
+	busOpts, err := cfg.NATS.BusOptions()
+	if err != nil {
+		logger.Log.Error().Err(err).Msg("Invalid event bus options")
+		os.Exit(1)
+	}
+	bus, err := eventbus.NewBus(natsConn, busOpts)
+	if err != nil {
+		logger.Log.Error().Err(err).Msg("Failed to create event bus")
+		os.Exit(1)
+	}
+
+	executions, err := cfg.Executions.Store()
+	if err != nil {
+		logger.Log.Error().Err(err).Msg("Failed to open execution history")
+		os.Exit(1)
+	}
+	// Closed once the subscriptions feeding it are gone
+	defer executions.Close()
+	for _, input := range cfg.Executions.Inputs {
+		subscribe(natsConn, shutdown, input, "execution report", func(data []byte) error {
+			var execReport sqx.ExecutionReport
+			if err := sqx.UnmarshalExecutionReport(data, &execReport); err != nil {
+				return err
+			}
+			_, err := executions.Add(execReport)
+			return err
+		})
+	}
+	for _, input := range cfg.Alerts {
+		subscribe(natsConn, shutdown, input, "alert", func(data []byte) error {
+			var alert monitor.Alert
+			if err := json.Unmarshal(data, &alert); err != nil {
+				return err
+			}
+			collector.OnAlert(alert)
+			return nil
+		})
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	shutdown.HookShutdownCallback("scheduler", cancel, 10*time.Second)
+
+	if len(cfg.Nodes) > 0 {
+		interval := time.Minute
+		if cfg.ProbeIntervalMs > 0 {
+			interval = time.Duration(cfg.ProbeIntervalMs) * time.Millisecond
+		}
+		control := api.NewNATSNodeControl(natsConn, min(interval, 5*time.Second))
+		go func() {
+			ticker := time.NewTicker(interval)
+			defer ticker.Stop()
+			for {
+				for _, node := range cfg.Nodes {
+					diag, err := control.Diagnostics(ctx, api.DiagnosticsSubject(node))
+					if ctx.Err() != nil {
+						return
+					}
+					if err != nil {
+						logger.Log.Warn().Err(err).Str("node", node).Msg("Node did not answer the probe")
+					}
+					collector.OnProbe(node, time.Now(), err == nil, diag.Uptime)
+				}
+				select {
+				case <-ctx.Done():
+					return
+				case <-ticker.C:
+				}
+			}
+		}()
+		logger.Log.Info().Strs("nodes", cfg.Nodes).Dur("interval", interval).Msg("Probing nodes")
+	}
+
+	go func() {
+		for {
+			run := schedule.Next(time.Now())
+			logger.Log.Info().Time("at", run).Msg("Next report scheduled")
+			timer := time.NewTimer(time.Until(run))
+			select {
+			case <-ctx.Done():
+				timer.Stop()
+				return
+			case <-timer.C:
+			}
+
+			date, from, to := schedule.Day(run)
+			fills, err := executions.Fills(execution.Filter{To: to.UnixMilli()})
+			if err != nil {
+				logger.Log.Error().Err(err).Str("date", date).Msg("Failed to read fills")
+				continue
+			}
+			daily := collector.Compile(date, from, to, fills)
+			collector.Prune(from)
+
+			if data, err := json.Marshal(daily); err != nil {
+				logger.Log.Error().Err(err).Msg("Failed to marshal report")
+			} else {
+				msg := &nats.Msg{Subject: cfg.NATS.Subject, Data: data}
+				if err := bus.Encode(msg); err != nil {
+					logger.Log.Error().Err(err).Msg("Failed to encode report")
+				} else if _, err := js.PublishMsg(msg); err != nil {
+					logger.Log.Error().Err(err).Msg("Failed to publish report")
+				}
+			}
+			for _, sender := range senders {
+				sendCtx, cancelSend := context.WithTimeout(ctx, 30*time.Second)
+				err := sender.Send(sendCtx, daily)
+				cancelSend()
+				if err != nil {
+					logger.Log.Error().Err(err).Str("sender", sender.Name()).Str("date", date).Msg("Failed to deliver report")
+					continue
+				}
+				logger.Log.Info().Str("sender", sender.Name()).Str("date", date).Msg("Report delivered")
+			}
+		}
+	}()
+
+	shutdown.WaitForShutdown(syscall.SIGINT, syscall.SIGTERM)
+	logger.Log.Info().Msg("Report command executed successfully!")
+}
+
+// subscribe feeds the decoded payloads of subject to handle
+func subscribe(conn *nats.Conn, sd *shutdown.Shutdown, subject, kind string, handle func(data []byte) error) {
+	sub, err := conn.Subscribe(subject, func(msg *nats.Msg) {
+		if err := eventbus.Decode(msg); err != nil {
+			logger.Log.Error().Err(err).Str("subject", msg.Subject).Msgf("Failed to decode %s", kind)
+			return
+		}
+		if err := handle(msg.Data); err != nil {
+			logger.Log.Error().Err(err).Str("subject", msg.Subject).Msgf("Failed to record %s", kind)
+		}
+	})
+	if err != nil {
+		logger.Log.Error().Err(err).Str("subject", subject).Msgf("Failed to subscribe to %s", kind)
+		os.Exit(1)
+	}
+	sd.HookShutdownCallback("unsubscribe "+subject, func() {
+		if err := sub.Unsubscribe(); err != nil {
+			logger.Log.Error().Err(err).Str("subject", subject).Msg("Failed to unsubscribe")
+		}
+	}, 10*time.Second)
+	logger.Log.Info().Str("subject", subject).Msgf("Subscribed to %s", kind)
+}
+
+func main() {
+	var configFile string
+	flag.StringVar(&configFile, "c", "", "Configuration file path (required)")
+
+	flag.Usage = func() {
+		logger.Log.Info().Msg(`Report compiles a daily summary of the trading activity: realized PnL,
+volume and fees per strategy from the execution reports, risk breaches from
+the monitor alerts and the availability of the probed nodes. The report of
+the previous day is sent at the configured time over Slack and/or email as
+Markdown and HTML, and archived as JSON to NATS.
+
+Usage:
+  report -c <config-file>
+
+Examples:
+  report -c config/report.json
+`)
+		flag.PrintDefaults()
+	}
+
+	flag.Parse()
+
+	if configFile == "" {
+		logger.Log.Error().Msg("config file path is required")
+		flag.Usage()
+		os.Exit(1)
+	}
+
+	runReport(configFile)
+}
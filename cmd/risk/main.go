@@ -0,0 +1,143 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/BullionBear/sequex/internal/fx"
+	"github.com/BullionBear/sequex/internal/model/protobuf"
+	"github.com/BullionBear/sequex/internal/model/sqx"
+	"github.com/BullionBear/sequex/internal/pms"
+	"github.com/BullionBear/sequex/internal/replay"
+	"github.com/BullionBear/sequex/internal/risk"
+)
+
+var (
+	recordings = flag.String("recordings", "", "Recorded trade files, comma separated, oldest first (required)")
+	portfolio  = flag.String("portfolio", "", "Portfolio export in JSON, as served by /api/v2/portfolios/{id}/export (required)")
+	quote      = flag.String("quote", "USDT", "Currency returns are measured in")
+	interval   = flag.Duration("interval", time.Hour, "Length of a return period")
+	maxPeriods = flag.Int("max-periods", 720, "Periods of history kept per asset")
+	horizon    = flag.Int("horizon", 24, "Periods simulated")
+	paths      = flag.Int("paths", 10000, "Simulated paths")
+	confidence = flag.Float64("confidence", 0.99, "VaR confidence level")
+	blockSize  = flag.Int("block-size", 1, "Consecutive periods drawn together")
+	seed       = flag.Int64("seed", 0, "Seed reproducing a previous report, random when 0")
+	asJSON     = flag.Bool("json", false, "Print the report as JSON")
+)
+
+func main() {
+	flag.Usage = func() {
+		fmt.Fprintf(os.Stderr, `Risk simulates the loss distribution of a portfolio by bootstrapping the
+returns of recorded trades. Positions are valued at the last recorded prices.
+
+Usage:
+  risk -recordings <files> -portfolio <export.json> [options]
+
+Examples:
+  risk -recordings messages-20250914.raw,messages-20250915.raw -portfolio main.json -horizon 24
+
+`)
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+	if *recordings == "" || *portfolio == "" {
+		flag.Usage()
+		os.Exit(1)
+	}
+
+	history := risk.NewHistory(risk.HistoryOptions{Quote: *quote, Interval: *interval, MaxPeriods: *maxPeriods})
+	rates := fx.NewRates(fx.Options{})
+	for _, recording := range strings.Split(*recordings, ",") {
+		_, _, err := replay.ReadTradeFile(strings.TrimSpace(recording), func(_ int, pb *protobuf.Trade) error {
+			var trade sqx.Trade
+			if trade.FromProtobuf(pb) == nil {
+				history.OnTrade(trade)
+				rates.OnTrade(trade)
+			}
+			return nil
+		})
+		if err != nil {
+			log.Fatalf("Failed to read recording: %v", err)
+		}
+	}
+
+	bundle, err := readBundle(*portfolio)
+	if err != nil {
+		log.Fatalf("Failed to read portfolio: %v", err)
+	}
+	valuation := pms.Value(bundle.Portfolio, bundle.Positions, rates)
+	report, err := risk.SimulatePortfolio(valuation, history, risk.Options{
+		Horizon:    *horizon,
+		Paths:      *paths,
+		Confidence: *confidence,
+		BlockSize:  *blockSize,
+		Seed:       *seed,
+	})
+	if err != nil {
+		log.Fatalf("Failed to simulate: %v", err)
+	}
+
+	if *asJSON {
+		data, err := json.MarshalIndent(report, "", "  ")
+		if err != nil {
+			log.Fatalf("Failed to encode report: %v", err)
+		}
+		fmt.Println(string(data))
+		return
+	}
+	printReport(report)
+}
+
+// readBundle reads a portfolio export
+func readBundle(path string) (pms.Bundle, error) {
+	var bundle pms.Bundle
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return bundle, err
+	}
+	if err := json.Unmarshal(data, &bundle); err != nil {
+		return bundle, fmt.Errorf("failed to parse %s: %w", path, err)
+	}
+	return bundle, nil
+}
+
+// printReport prints the report as a table
+func printReport(r risk.PortfolioReport) {
+	fmt.Printf("Portfolio %s risk over %v (%d paths, seed %d)\n", r.PortfolioID, r.Horizon, r.Paths, r.Seed)
+	fmt.Println(strings.Repeat("=", 60))
+	fmt.Printf("%-12s %16s %10s\n", "Asset", "Exposure", "Weight")
+	for _, e := range r.Exposures {
+		fmt.Printf("%-12s %16.2f %9.2f%%\n", e.Asset, e.Value, weight(e.Value, r.Value))
+	}
+	fmt.Printf("%-12s %16.2f %9.2f%%\n", "cash", r.Cash, weight(r.Cash, r.Value))
+	fmt.Printf("%-12s %16.2f %s\n", "total", r.Value, r.Currency)
+	if !r.Complete {
+		fmt.Printf("Excluded, no valuation or history: %s\n", strings.Join(r.Excluded, ", "))
+	}
+	fmt.Println()
+	fmt.Printf("Bootstrapped periods: %d\n", r.Periods)
+	fmt.Printf("Expected PnL:         %.2f\n", r.ExpectedPnL)
+	fmt.Printf("VaR %.1f%%:            %.2f\n", r.Confidence*100, r.VaR)
+	fmt.Printf("CVaR %.1f%%:           %.2f\n", r.Confidence*100, r.CVaR)
+	fmt.Println()
+	fmt.Printf("%-10s %12s %12s %12s %12s %12s\n", "", "mean", "p50", "p95", "p99", "max")
+	for _, row := range []struct {
+		name string
+		d    risk.Distribution
+	}{{"loss", r.Loss}, {"drawdown", r.Drawdown}} {
+		fmt.Printf("%-10s %12.2f %12.2f %12.2f %12.2f %12.2f\n", row.name, row.d.Mean, row.d.P50, row.d.P95, row.d.P99, row.d.Max)
+	}
+}
+
+func weight(value, total float64) float64 {
+	if total == 0 {
+		return 0
+	}
+	return value / total * 100
+}
@@ -0,0 +1,67 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/BullionBear/sequex/internal/rpcgen"
+)
+
+func main() {
+	out := flag.String("out", "internal/model", "directory the code is generated in, relative to the proto files as protoc-gen-go paths=source_relative")
+	flag.Usage = func() {
+		fmt.Fprintf(os.Stderr, `Usage: rpcgen [-out dir] <proto files>
+
+Generates typed eventbus RPC clients and server interfaces from the service
+definitions of proto files into <dir>/<proto path>_rpc.pb.go, next to the
+messages generated by protoc-gen-go. Files without services are skipped, as
+are streaming rpcs, served over gRPC.
+
+Options:
+`)
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+	if flag.NArg() == 0 {
+		flag.Usage()
+		os.Exit(1)
+	}
+
+	for _, name := range flag.Args() {
+		if err := generate(name, *out); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+	}
+}
+
+// generate writes the RPC code of the services of the proto file name
+func generate(name, out string) error {
+	src, err := os.ReadFile(name)
+	if err != nil {
+		return err
+	}
+	f, err := rpcgen.Parse(filepath.ToSlash(name), src)
+	if err != nil {
+		return err
+	}
+	if len(f.Services) == 0 {
+		return nil
+	}
+	code, err := rpcgen.Generate(f)
+	if err != nil {
+		return err
+	}
+	path := filepath.Join(out, strings.TrimSuffix(name, ".proto")+"_rpc.pb.go")
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return err
+	}
+	if err := os.WriteFile(path, code, 0o644); err != nil {
+		return err
+	}
+	fmt.Printf("Generated %s\n", path)
+	return nil
+}
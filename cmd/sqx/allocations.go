@@ -0,0 +1,153 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"text/tabwriter"
+	"time"
+
+	"github.com/BullionBear/sequex/internal/allocation"
+)
+
+// runAllocations lists and adjusts the strategy budgets of a service at runtime
+func runAllocations(s *session, args []string) error {
+	fs := flag.NewFlagSet("allocations", flag.ExitOnError)
+	server := fs.String("s", s.server(), "NATS server URLs, comma separated, those of the context by default")
+	service := fs.String("service", "fixgateway", "service enforcing the budgets, served on allocation.<service>")
+	subject := fs.String("subject", "", "prefix the allocation RPCs are served under, overriding -service")
+	portfolio := fs.String("portfolio", "", "portfolio the budget is granted from, with set")
+	timeout := fs.Duration("timeout", 5*time.Second, "time to wait for the service to answer")
+	by := fs.String("by", os.Getenv("USER"), "who changes the allocation, with set and rm")
+	reason := fs.String("reason", "", "why the allocation changes, with set and rm")
+	fs.Usage = func() {
+		fmt.Fprintf(os.Stderr, `Usage: sqx allocations [options] <command> [arguments]
+
+Adjusts the notional budgets strategies are granted from portfolios. Every
+change is recorded in the audit trail of the service, with who made it and why.
+
+Commands:
+  ls                        List the allocations and their usage
+  set <strategy> <budget>   Create or update an allocation, -portfolio required
+  rm <strategy>             Remove an allocation, rejecting the orders of the strategy
+  history                   List the latest changes
+
+Options:
+`)
+		fs.PrintDefaults()
+	}
+	fs.Parse(args)
+	if fs.NArg() == 0 {
+		fs.Usage()
+		return fmt.Errorf("a command is required")
+	}
+	// Options are accepted before and after the command
+	cmd := fs.Arg(0)
+	fs.Parse(fs.Args()[1:])
+	prefix := *subject
+	if prefix == "" {
+		prefix = allocation.DefaultSubject + "." + *service
+	}
+	var req any
+	switch cmd {
+	case "ls", "list", "history":
+		req = &allocation.ListRequest{}
+	case "set":
+		if fs.NArg() != 2 {
+			fs.Usage()
+			return fmt.Errorf("a strategy and a budget are required")
+		}
+		budget, err := strconv.ParseFloat(fs.Arg(1), 64)
+		if err != nil {
+			return fmt.Errorf("invalid budget %q", fs.Arg(1))
+		}
+		req = &allocation.SetRequest{Allocation: allocation.Allocation{Strategy: fs.Arg(0), Portfolio: *portfolio, Budget: budget}, By: *by, Reason: *reason}
+	case "rm", "remove":
+		if fs.NArg() != 1 {
+			fs.Usage()
+			return fmt.Errorf("a strategy is required")
+		}
+		req = &allocation.RemoveRequest{Strategy: fs.Arg(0), By: *by, Reason: *reason}
+	default:
+		fs.Usage()
+		return fmt.Errorf("unknown allocations command %q", cmd)
+	}
+	if _, ok := req.(*allocation.ListRequest); !ok {
+		if err := s.authorize("allocations "+cmd, os.Stdin, os.Stderr); err != nil {
+			return err
+		}
+	}
+
+	conn, err := s.connect(*server)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+	bus, err := s.newBus(conn)
+	if err != nil {
+		return err
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), *timeout)
+	defer cancel()
+
+	switch req := req.(type) {
+	case *allocation.SetRequest:
+		c, err := allocation.SetEndpoint(prefix).Call(ctx, bus, req)
+		if err != nil {
+			return err
+		}
+		fmt.Printf("Allocation of %s set to %g from portfolio %s\n", c.Strategy, c.After.Budget, c.After.Portfolio)
+	case *allocation.RemoveRequest:
+		c, err := allocation.RemoveEndpoint(prefix).Call(ctx, bus, req)
+		if err != nil {
+			return err
+		}
+		fmt.Printf("Allocation of %s removed\n", c.Strategy)
+	default:
+		list, err := allocation.ListEndpoint(prefix).Call(ctx, bus, &allocation.ListRequest{})
+		if err != nil {
+			return err
+		}
+		if cmd == "history" {
+			return printAllocationChanges(os.Stdout, list.Changes)
+		}
+		return printAllocations(os.Stdout, list)
+	}
+	return nil
+}
+
+func printAllocations(out io.Writer, list *allocation.List) error {
+	if len(list.Allocations) == 0 {
+		fmt.Fprintln(out, "No strategy is allocated")
+		return nil
+	}
+	fmt.Fprintf(out, "Notionals in %s\n\n", list.Currency)
+	w := tabwriter.NewWriter(out, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "STRATEGY\tPORTFOLIO\tBUDGET\tPOSITIONS\tWORKING\tEXPOSURE\tAVAILABLE\tERROR")
+	for _, u := range list.Allocations {
+		fmt.Fprintf(w, "%s\t%s\t%.2f\t%.2f\t%.2f\t%.2f\t%.2f\t%s\n", u.Strategy, u.Portfolio, u.Budget, u.Positions, u.Working, u.Exposure, u.Available, orDash(u.Error))
+	}
+	return w.Flush()
+}
+
+func printAllocationChanges(out io.Writer, changes []allocation.Change) error {
+	if len(changes) == 0 {
+		fmt.Fprintln(out, "No allocation was changed")
+		return nil
+	}
+	budget := func(a *allocation.Allocation) string {
+		if a == nil {
+			return "-"
+		}
+		return fmt.Sprintf("%g (%s)", a.Budget, a.Portfolio)
+	}
+	w := tabwriter.NewWriter(out, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "STRATEGY\tBEFORE\tAFTER\tBY\tAT\tREASON")
+	for _, c := range changes {
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\t%s\n", c.Strategy, budget(c.Before), budget(c.After), orDash(c.By), c.At.Local().Format(time.DateTime), orDash(c.Reason))
+	}
+	return w.Flush()
+}
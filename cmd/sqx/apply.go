@@ -0,0 +1,94 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/BullionBear/sequex/internal/bundle"
+	"github.com/BullionBear/sequex/internal/config"
+	"github.com/nats-io/nats.go"
+)
+
+// runApply deploys the application of a bundle: its streams are
+// provisioned, its configurations uploaded and its nodes started or
+// updated by the master
+func runApply(s *session, args []string) error {
+	fs := flag.NewFlagSet("apply", flag.ExitOnError)
+	server := fs.String("s", s.server(), "NATS server URLs, comma separated, those of the context by default")
+	subject := fs.String("subject", bundle.DefaultSubject, "prefix the application RPCs of the master are served under")
+	bucket := fs.String("bucket", bundle.DefaultBucket, "key-value bucket the configurations are uploaded to, that of the master")
+	timeout := fs.Duration("timeout", 10*time.Second, "time to wait for JetStream and the master")
+	by := fs.String("by", os.Getenv("USER"), "who applies the bundle")
+	fs.Usage = func() {
+		fmt.Fprintf(os.Stderr, `Usage: sqx apply [options] <bundle>
+
+Deploys the application described by a bundle, usually sqxapp.yml: its
+streams are created or updated, the configurations of its nodes uploaded to
+the key-value bucket, and its nodes started by the master in dependency
+order. An application whose nodes or configurations changed is restarted,
+one applied as is left running. See sqx apps for the state of the nodes.
+
+Options:
+`)
+		fs.PrintDefaults()
+	}
+	fs.Parse(args)
+	if fs.NArg() != 1 {
+		fs.Usage()
+		return fmt.Errorf("a bundle is required")
+	}
+	b, err := bundle.Load(fs.Arg(0))
+	if err != nil {
+		return err
+	}
+
+	conn, err := s.connect(*server)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+	js, err := conn.JetStream(nats.MaxWait(*timeout))
+	if err != nil {
+		return err
+	}
+	streams, err := b.ProvisionStreams(js)
+	for _, r := range streams {
+		fmt.Printf("Stream %s %s\n", r.Name, r.Change)
+	}
+	if err != nil {
+		return err
+	}
+	kv, err := config.OpenConfigBucket(js, *bucket)
+	if err != nil {
+		return err
+	}
+	configs, err := b.UploadConfigs(kv)
+	for _, r := range configs {
+		fmt.Printf("Configuration %s %s\n", r.Name, r.Change)
+	}
+	if err != nil {
+		return err
+	}
+
+	bus, err := s.newBus(conn)
+	if err != nil {
+		return err
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), *timeout)
+	defer cancel()
+	applied, err := bundle.ApplyEndpoint(*subject).Call(ctx, bus, &bundle.ApplyRequest{Bundle: *b, By: *by})
+	if err != nil {
+		return err
+	}
+	switch applied.Change {
+	case bundle.Unchanged:
+		fmt.Printf("Application %s unchanged\n", applied.App)
+	default:
+		fmt.Printf("Application %s %s, starting %s\n", applied.App, applied.Change, strings.Join(applied.Order, ", "))
+	}
+	return nil
+}
@@ -0,0 +1,102 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"text/tabwriter"
+	"time"
+
+	"github.com/BullionBear/sequex/internal/bundle"
+)
+
+// runApps lists the applications run by the master and removes them
+func runApps(s *session, args []string) error {
+	fs := flag.NewFlagSet("apps", flag.ExitOnError)
+	server := fs.String("s", s.server(), "NATS server URLs, comma separated, those of the context by default")
+	subject := fs.String("subject", bundle.DefaultSubject, "prefix the application RPCs of the master are served under")
+	timeout := fs.Duration("timeout", 5*time.Second, "time to wait for the master to answer")
+	by := fs.String("by", os.Getenv("USER"), "who removes the application")
+	fs.Usage = func() {
+		fmt.Fprintf(os.Stderr, `Usage: sqx apps [options] <command> [arguments]
+
+Lists and removes the applications deployed with sqx apply.
+
+Commands:
+  ls          List the applications and the state of their nodes
+  rm <app>    Stop the nodes of an application in reverse order
+
+Options:
+`)
+		fs.PrintDefaults()
+	}
+	fs.Parse(args)
+	if fs.NArg() == 0 {
+		fs.Usage()
+		return fmt.Errorf("a command is required")
+	}
+	// Options are accepted before and after the command
+	cmd := fs.Arg(0)
+	fs.Parse(fs.Args()[1:])
+	switch cmd {
+	case "ls", "list":
+	case "rm", "remove":
+		if fs.NArg() != 1 {
+			fs.Usage()
+			return fmt.Errorf("an application is required")
+		}
+		if err := s.authorize("apps "+cmd, os.Stdin, os.Stderr); err != nil {
+			return err
+		}
+	default:
+		fs.Usage()
+		return fmt.Errorf("unknown apps command %q", cmd)
+	}
+
+	conn, err := s.connect(*server)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+	bus, err := s.newBus(conn)
+	if err != nil {
+		return err
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), *timeout)
+	defer cancel()
+
+	if cmd == "rm" || cmd == "remove" {
+		app, err := bundle.RemoveEndpoint(*subject).Call(ctx, bus, &bundle.RemoveRequest{App: fs.Arg(0), By: *by})
+		if err != nil {
+			return err
+		}
+		fmt.Printf("Stopping the nodes of %s\n", app.Name)
+		return nil
+	}
+	list, err := bundle.ListEndpoint(*subject).Call(ctx, bus, &bundle.ListRequest{})
+	if err != nil {
+		return err
+	}
+	return printApps(os.Stdout, list.Apps)
+}
+
+func printApps(out io.Writer, apps []bundle.App) error {
+	if len(apps) == 0 {
+		fmt.Fprintln(out, "No application is deployed")
+		return nil
+	}
+	w := tabwriter.NewWriter(out, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "APP\tNODE\tSTATE\tAPPLIED\tBY\tERROR")
+	for _, a := range apps {
+		applied := a.Applied.Local().Format(time.DateTime)
+		if a.Error != "" {
+			fmt.Fprintf(w, "%s\t-\t-\t%s\t%s\t%s\n", a.Name, applied, orDash(a.By), a.Error)
+		}
+		for _, n := range a.Nodes {
+			fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\t%s\n", a.Name, n.Name, n.State, applied, orDash(a.By), orDash(n.Error))
+		}
+	}
+	return w.Flush()
+}
@@ -0,0 +1,452 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/BullionBear/sequex/internal/backtest"
+	"github.com/BullionBear/sequex/pkg/eventbus"
+)
+
+// runBacktest submits backtests to the backtest service, follows them, and
+// compares their results against a regression gate
+func runBacktest(s *session, args []string) error {
+	fs := flag.NewFlagSet("backtest", flag.ExitOnError)
+	server := fs.String("s", s.server(), "NATS server URLs, comma separated, those of the context by default")
+	subject := fs.String("subject", backtest.DefaultSubject, "prefix the backtest RPCs are served under")
+	timeout := fs.Duration("timeout", 10*time.Second, "time to wait for the service to answer")
+	wait := fs.Bool("wait", false, "wait for a submitted job or sweep to finish, failing unless it succeeded")
+	output := fs.String("o", "", "file the result is written to, standard output by default")
+	gate := fs.String("gate", "", "worsening allowed per metric, relative in percent, e.g. sharpe=0.1,return=5%,max_drawdown=0.02")
+	trades := fs.Int("trades", 20, "trade changes printed by compare, all when negative")
+	asJSON := fs.Bool("json", false, "print the comparison, the outcomes or the report as JSON")
+	fs.Usage = func() {
+		fmt.Fprint(os.Stderr, `Usage: sqx backtest [options] <command> [arguments]
+
+Backtests run as jobs of the backtest service, which keeps their results.
+Compare diffs the results of two backtests, each a job id or a result file,
+and fails when the head is worse than the base by more than the gate allows,
+to block strategy changes degrading performance in CI, e.g.
+
+  sqx backtest -wait submit spec.json
+  sqx backtest -gate sharpe=0.1,return=5% compare base.json <job>
+
+A sweep runs every variant, a strategy and its parameters, on every market,
+a symbol and its data, with the settings of its base, the backtests in
+parallel over market data loaded once, e.g.
+
+  sqx backtest -wait sweep sweep.json && sqx backtest outcomes <job>
+
+A walk-forward optimization searches the parameters of a strategy, a grid
+or random or bayesian search, on rolling in-sample windows and tests those
+chosen on the out-of-sample windows following them, ranking every
+parameter set and diagnosing overfitting, e.g.
+
+  sqx backtest -wait walkforward wf.json && sqx backtest report <job>
+
+Commands:
+  ls                   List the jobs
+  submit <spec.json>   Submit a backtest, printing its job
+  sweep <sweep.json>   Submit a sweep of backtests, printing its job
+  walkforward <wf.json>
+                       Submit a walk-forward optimization, printing its job
+  status <job>         Print the state of a job
+  cancel <job>         Cancel a job queued or running
+  result <job>         Print the result of a succeeded job as JSON
+  outcomes <job>       Print the metrics of every backtest of a succeeded sweep
+  report <job>         Print the folds, ranked parameters and diagnostics of a
+                       succeeded walk-forward optimization
+  compare <base> <head>
+                       Diff two results and check the gate
+
+Options:
+`)
+		fs.PrintDefaults()
+	}
+	fs.Parse(args)
+	if fs.NArg() == 0 {
+		fs.Usage()
+		return fmt.Errorf("a command is required")
+	}
+	// Options are accepted before and after the command
+	cmd := fs.Arg(0)
+	fs.Parse(fs.Args()[1:])
+	want := map[string]int{"ls": 0, "list": 0, "submit": 1, "sweep": 1, "walkforward": 1, "status": 1, "cancel": 1, "result": 1, "outcomes": 1, "report": 1, "compare": 2}
+	n, ok := want[cmd]
+	if !ok {
+		fs.Usage()
+		return fmt.Errorf("unknown backtest command %q", cmd)
+	}
+	if fs.NArg() != n {
+		fs.Usage()
+		return fmt.Errorf("%s expects %d arguments", cmd, n)
+	}
+	rules, err := backtest.ParseRules(*gate)
+	if err != nil {
+		return err
+	}
+
+	// The bus is only connected to when a job is looked up, so that result
+	// files are compared offline
+	var bus *eventbus.Bus
+	connect := func() (*eventbus.Bus, error) {
+		if bus != nil {
+			return bus, nil
+		}
+		conn, err := s.connect(*server)
+		if err != nil {
+			return nil, err
+		}
+		if bus, err = s.newBus(conn); err != nil {
+			conn.Close()
+			return nil, err
+		}
+		return bus, nil
+	}
+	call := func(f func(ctx context.Context, bus *eventbus.Bus) error) error {
+		bus, err := connect()
+		if err != nil {
+			return err
+		}
+		ctx, cancel := context.WithTimeout(context.Background(), *timeout)
+		defer cancel()
+		return f(ctx, bus)
+	}
+	defer func() {
+		if bus != nil {
+			bus.Conn().Close()
+		}
+	}()
+
+	switch cmd {
+	case "ls", "list":
+		var list *backtest.List
+		if err := call(func(ctx context.Context, bus *eventbus.Bus) (err error) {
+			list, err = backtest.ListEndpoint(*subject).Call(ctx, bus, &backtest.ListRequest{})
+			return err
+		}); err != nil {
+			return err
+		}
+		return printBacktestJobs(os.Stdout, list.Jobs)
+	case "submit", "sweep", "walkforward":
+		data, err := os.ReadFile(fs.Arg(0))
+		if err != nil {
+			return err
+		}
+		var job *backtest.Job
+		switch cmd {
+		case "walkforward":
+			var req backtest.WalkForwardRequest
+			if err := json.Unmarshal(data, &req.WalkForward); err != nil {
+				return fmt.Errorf("failed to parse walk-forward %s: %w", fs.Arg(0), err)
+			}
+			err = call(func(ctx context.Context, bus *eventbus.Bus) (err error) {
+				job, err = backtest.WalkForwardEndpoint(*subject).Call(ctx, bus, &req)
+				return err
+			})
+		case "sweep":
+			var req backtest.SweepRequest
+			if err := json.Unmarshal(data, &req.Sweep); err != nil {
+				return fmt.Errorf("failed to parse sweep %s: %w", fs.Arg(0), err)
+			}
+			err = call(func(ctx context.Context, bus *eventbus.Bus) (err error) {
+				job, err = backtest.SweepEndpoint(*subject).Call(ctx, bus, &req)
+				return err
+			})
+		default:
+			var req backtest.SubmitRequest
+			if err := json.Unmarshal(data, &req.Spec); err != nil {
+				return fmt.Errorf("failed to parse spec %s: %w", fs.Arg(0), err)
+			}
+			err = call(func(ctx context.Context, bus *eventbus.Bus) (err error) {
+				job, err = backtest.SubmitEndpoint(*subject).Call(ctx, bus, &req)
+				return err
+			})
+		}
+		if err != nil {
+			return err
+		}
+		fmt.Printf("Job %s %s\n", job.ID, job.Status)
+		for *wait && !job.Status.Finished() {
+			time.Sleep(time.Second)
+			if err := call(func(ctx context.Context, bus *eventbus.Bus) (err error) {
+				job, err = backtest.StatusEndpoint(*subject).Call(ctx, bus, &backtest.JobRequest{ID: job.ID})
+				return err
+			}); err != nil {
+				return err
+			}
+		}
+		if *wait {
+			return printBacktestJob(os.Stdout, job)
+		}
+		return nil
+	case "status", "cancel":
+		endpoint := backtest.StatusEndpoint(*subject)
+		if cmd == "cancel" {
+			endpoint = backtest.CancelEndpoint(*subject)
+		}
+		var job *backtest.Job
+		if err := call(func(ctx context.Context, bus *eventbus.Bus) (err error) {
+			job, err = endpoint.Call(ctx, bus, &backtest.JobRequest{ID: fs.Arg(0)})
+			return err
+		}); err != nil {
+			return err
+		}
+		return printBacktestJob(os.Stdout, job)
+	case "result":
+		var result *backtest.Result
+		if err := call(func(ctx context.Context, bus *eventbus.Bus) (err error) {
+			result, err = backtest.ResultEndpoint(*subject).Call(ctx, bus, &backtest.JobRequest{ID: fs.Arg(0)})
+			return err
+		}); err != nil {
+			return err
+		}
+		data, err := json.MarshalIndent(result, "", "  ")
+		if err != nil {
+			return err
+		}
+		if *output != "" {
+			return os.WriteFile(*output, append(data, '\n'), 0o644)
+		}
+		fmt.Println(string(data))
+		return nil
+	case "outcomes":
+		var outcomes *backtest.Outcomes
+		if err := call(func(ctx context.Context, bus *eventbus.Bus) (err error) {
+			outcomes, err = backtest.OutcomesEndpoint(*subject).Call(ctx, bus, &backtest.JobRequest{ID: fs.Arg(0)})
+			return err
+		}); err != nil {
+			return err
+		}
+		if *asJSON {
+			data, err := json.MarshalIndent(outcomes.Outcomes, "", "  ")
+			if err != nil {
+				return err
+			}
+			fmt.Println(string(data))
+			return nil
+		}
+		return printBacktestOutcomes(os.Stdout, outcomes.Outcomes)
+	case "report":
+		var report *backtest.WalkForwardReport
+		if err := call(func(ctx context.Context, bus *eventbus.Bus) (err error) {
+			report, err = backtest.ReportEndpoint(*subject).Call(ctx, bus, &backtest.JobRequest{ID: fs.Arg(0)})
+			return err
+		}); err != nil {
+			return err
+		}
+		if *asJSON {
+			data, err := json.MarshalIndent(report, "", "  ")
+			if err != nil {
+				return err
+			}
+			fmt.Println(string(data))
+			return nil
+		}
+		return printWalkForwardReport(os.Stdout, report)
+	}
+
+	// A result is read from a file when one exists at the argument, fetched
+	// from the service otherwise
+	var results [2]*backtest.Result
+	for i, arg := range fs.Args() {
+		data, err := os.ReadFile(arg)
+		switch {
+		case err == nil:
+			results[i] = &backtest.Result{}
+			if err := json.Unmarshal(data, results[i]); err != nil {
+				return fmt.Errorf("failed to parse result %s: %w", arg, err)
+			}
+		case errors.Is(err, os.ErrNotExist):
+			if err := call(func(ctx context.Context, bus *eventbus.Bus) (err error) {
+				results[i], err = backtest.ResultEndpoint(*subject).Call(ctx, bus, &backtest.JobRequest{ID: arg})
+				return err
+			}); err != nil {
+				return fmt.Errorf("result of job %s: %w", arg, err)
+			}
+		default:
+			return err
+		}
+	}
+	report, err := backtest.Gate(results[0], results[1], rules)
+	if err != nil {
+		return err
+	}
+	if *asJSON {
+		data, err := json.MarshalIndent(report, "", "  ")
+		if err != nil {
+			return err
+		}
+		fmt.Println(string(data))
+	} else if err := printBacktestReport(os.Stdout, report, *trades); err != nil {
+		return err
+	}
+	if !report.Passed {
+		return fmt.Errorf("gate failed on %d rules", len(report.Violations))
+	}
+	return nil
+}
+
+func printBacktestJobs(out io.Writer, jobs []backtest.Job) error {
+	if len(jobs) == 0 {
+		fmt.Fprintln(out, "No job was submitted")
+		return nil
+	}
+	w := tabwriter.NewWriter(out, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "JOB\tSTRATEGY\tSYMBOL\tSTATUS\tSUBMITTED\tRETURN\tSHARPE\tERROR")
+	for _, job := range jobs {
+		ret, sharpe := "-", "-"
+		if job.Metrics != nil {
+			ret, sharpe = fmt.Sprintf("%.4f", job.Metrics.Return), fmt.Sprintf("%.2f", job.Metrics.Sharpe)
+		}
+		strategy, symbol := job.Spec.Strategy, job.Spec.Symbol
+		if job.Sweep != nil {
+			strategy, symbol = fmt.Sprintf("sweep of %d", len(job.Sweep.Specs())), "-"
+		}
+		if job.WalkForward != nil {
+			strategy = "walk-forward " + strategy
+		}
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\t%s\t%s\t%s\n", job.ID, strategy, symbol, job.Status,
+			job.Submitted.Local().Format(time.DateTime), ret, sharpe, orDash(job.Error))
+	}
+	return w.Flush()
+}
+
+func printBacktestOutcomes(out io.Writer, outcomes []backtest.Outcome) error {
+	w := tabwriter.NewWriter(out, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "STRATEGY\tPARAMS\tSYMBOL\tPNL\tRETURN\tSHARPE\tMAX DRAWDOWN\tFILLS\tERROR")
+	for _, o := range outcomes {
+		params := formatParams(o.Spec.Params)
+		if o.Metrics == nil {
+			fmt.Fprintf(w, "%s\t%s\t%s\t-\t-\t-\t-\t-\t%s\n", o.Spec.Strategy, params, o.Spec.Symbol, orDash(o.Error))
+			continue
+		}
+		m := o.Metrics
+		fmt.Fprintf(w, "%s\t%s\t%s\t%.2f\t%.4f\t%.2f\t%.4f\t%d\t-\n", o.Spec.Strategy, params, o.Spec.Symbol,
+			m.PnL, m.Return, m.Sharpe, m.MaxDrawdown, m.Fills)
+	}
+	return w.Flush()
+}
+
+func printWalkForwardReport(out io.Writer, report *backtest.WalkForwardReport) error {
+	w := tabwriter.NewWriter(out, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "IN SAMPLE\tOUT OF SAMPLE\tPARAMS\tPNL\tRETURN\tSHARPE\tRANK")
+	for _, f := range report.Folds {
+		window := func(win backtest.Window) string {
+			return win.Start.UTC().Format(time.DateTime) + " - " + win.End.UTC().Format(time.DateTime)
+		}
+		if f.Metrics == nil {
+			fmt.Fprintf(w, "%s\t%s\t-\t-\t-\t-\t-\n", window(f.InSample), window(f.OutOfSample))
+			continue
+		}
+		fmt.Fprintf(w, "%s\t%s\t%s\t%.2f\t%.4f\t%.2f\t%d\n", window(f.InSample), window(f.OutOfSample), formatParams(f.Params),
+			f.Metrics.PnL, f.Metrics.Return, f.Metrics.Sharpe, f.Rank)
+	}
+	if err := w.Flush(); err != nil {
+		return err
+	}
+
+	fmt.Fprintf(out, "\nRanked by %s in sample\n", report.Objective)
+	w = tabwriter.NewWriter(out, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "RANK\tPARAMS\tIN SAMPLE\tOUT OF SAMPLE\tERROR")
+	for _, c := range report.Candidates {
+		if c.Error != "" {
+			fmt.Fprintf(w, "%d\t%s\t-\t-\t%s\n", c.Rank, formatParams(c.Params), c.Error)
+			continue
+		}
+		fmt.Fprintf(w, "%d\t%s\t%.4f\t%.4f\t-\n", c.Rank, formatParams(c.Params), c.InSample, c.OutOfSample)
+	}
+	if err := w.Flush(); err != nil {
+		return err
+	}
+
+	d := report.Diagnostics
+	fmt.Fprintf(out, "\nOut-of-sample pnl %.2f, efficiency %.2f, probability of overfitting %.2f, rank correlation %.2f, %d distinct parameter sets chosen over %d folds\n",
+		report.PnL, d.Efficiency, d.PBO, d.RankCorrelation, d.Distinct, len(report.Folds))
+	return nil
+}
+
+// formatParams returns parameters as sorted name=value pairs, a dash for none
+func formatParams(params map[string]float64) string {
+	pairs := make([]string, 0, len(params))
+	for name, value := range params {
+		pairs = append(pairs, fmt.Sprintf("%s=%g", name, value))
+	}
+	sort.Strings(pairs)
+	return orDash(strings.Join(pairs, ","))
+}
+
+func printBacktestJob(out io.Writer, job *backtest.Job) error {
+	data, err := json.MarshalIndent(job, "", "  ")
+	if err != nil {
+		return err
+	}
+	fmt.Fprintln(out, string(data))
+	if job.Status.Finished() && job.Status != backtest.StatusSucceeded {
+		return fmt.Errorf("job %s %s: %s", job.ID, job.Status, job.Error)
+	}
+	return nil
+}
+
+func printBacktestReport(out io.Writer, report backtest.Report, trades int) error {
+	w := tabwriter.NewWriter(out, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "METRIC\tBASE\tHEAD\tDELTA\tCHANGE")
+	for _, d := range report.Metrics {
+		fmt.Fprintf(w, "%s\t%g\t%g\t%+g\t%+.2f%%\n", d.Metric, d.Base, d.Head, d.Delta, d.Change*100)
+	}
+	if err := w.Flush(); err != nil {
+		return err
+	}
+
+	counts := make(map[string]int)
+	for _, c := range report.Trades {
+		counts[c.Kind]++
+	}
+	fmt.Fprintf(out, "\nTrades: %d identical, %d changed, %d added, %d removed\n", report.Matched, counts["changed"], counts["added"], counts["removed"])
+	if len(report.Trades) > 0 && trades != 0 {
+		w = tabwriter.NewWriter(out, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(w, "\nCHANGE\tTIME\tORDER\tSIDE\tBASE\tHEAD")
+		for i, c := range report.Trades {
+			if trades > 0 && i == trades {
+				fmt.Fprintf(w, "...\t%d more\t\t\t\t\n", len(report.Trades)-trades)
+				break
+			}
+			t := c.Head
+			if t == nil {
+				t = c.Base
+			}
+			fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\t%s\n", c.Kind, time.UnixMilli(t.Time).UTC().Format(time.RFC3339Nano), t.OrderID, t.Side,
+				formatTrade(c.Base), formatTrade(c.Head))
+		}
+		if err := w.Flush(); err != nil {
+			return err
+		}
+	}
+
+	fmt.Fprintln(out)
+	if report.Passed {
+		fmt.Fprintln(out, "Gate passed")
+		return nil
+	}
+	for _, v := range report.Violations {
+		fmt.Fprintf(out, "FAIL %s\n", v)
+	}
+	return nil
+}
+
+// formatTrade returns the quantity and price of a trade, a dash for none
+func formatTrade(t *backtest.Trade) string {
+	if t == nil {
+		return "-"
+	}
+	return fmt.Sprintf("%g @ %g", t.Quantity, t.Price)
+}
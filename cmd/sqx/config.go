@@ -0,0 +1,195 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"sort"
+	"strconv"
+	"text/tabwriter"
+	"time"
+
+	"github.com/BullionBear/sequex/internal/config"
+	"github.com/nats-io/nats.go"
+)
+
+// runConfig manages the configurations kept in the key-value bucket the
+// nodes started with -c kv:<key> read and watch
+func runConfig(s *session, args []string) error {
+	fs := flag.NewFlagSet("config", flag.ExitOnError)
+	server := fs.String("s", s.server(), "NATS server URLs, comma separated, those of the context by default")
+	bucket := fs.String("bucket", config.DefaultConfigBucket, "key-value bucket of the configurations")
+	revision := fs.Uint64("revision", 0, "revision to get, the latest when 0")
+	timeout := fs.Duration("timeout", 5*time.Second, "time to wait for JetStream")
+	fs.Usage = func() {
+		fmt.Fprintf(os.Stderr, `Usage: sqx config [options] <command> [arguments]
+
+Manages the configurations of the nodes kept in a key-value bucket. A node
+started with -c kv:<key> reads its configuration from the bucket and applies
+the settings it reloads at runtime as the key is written.
+
+Commands:
+  ls                  List the configurations with their latest revision
+  get <key>           Print a configuration, -revision for an earlier one
+  put <key> <file>    Write a configuration from a file, - for stdin
+  watch [key]         Print the configurations as they are written, all by default
+
+Options:
+`)
+		fs.PrintDefaults()
+	}
+	fs.Parse(args)
+	if fs.NArg() == 0 {
+		fs.Usage()
+		return fmt.Errorf("a command is required")
+	}
+	// Options are accepted before and after the command
+	cmd := fs.Arg(0)
+	fs.Parse(fs.Args()[1:])
+	switch cmd {
+	case "ls", "list":
+	case "get":
+		if fs.NArg() != 1 {
+			fs.Usage()
+			return fmt.Errorf("a key is required")
+		}
+	case "put":
+		if fs.NArg() != 2 {
+			fs.Usage()
+			return fmt.Errorf("a key and a file are required")
+		}
+	case "watch":
+		if fs.NArg() > 1 {
+			fs.Usage()
+			return fmt.Errorf("watch takes at most one key")
+		}
+	default:
+		fs.Usage()
+		return fmt.Errorf("unknown config command %q", cmd)
+	}
+	var data []byte
+	if cmd == "put" {
+		var err error
+		if fs.Arg(1) == "-" {
+			data, err = io.ReadAll(os.Stdin)
+		} else {
+			data, err = os.ReadFile(fs.Arg(1))
+		}
+		if err != nil {
+			return err
+		}
+		var doc any
+		if err := config.UnmarshalYAML(data, &doc); err != nil {
+			return fmt.Errorf("%s is neither JSON nor YAML: %w", fs.Arg(1), err)
+		}
+		if err := s.authorize("config put", os.Stdin, os.Stderr); err != nil {
+			return err
+		}
+	}
+
+	conn, err := s.connect(*server)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+	js, err := conn.JetStream(nats.MaxWait(*timeout))
+	if err != nil {
+		return err
+	}
+	// Only writes create the bucket
+	var kv nats.KeyValue
+	if cmd == "put" {
+		kv, err = config.OpenConfigBucket(js, *bucket)
+	} else {
+		kv, err = js.KeyValue(*bucket)
+	}
+	if err != nil {
+		return err
+	}
+
+	switch cmd {
+	case "get":
+		var entry nats.KeyValueEntry
+		if *revision > 0 {
+			entry, err = kv.GetRevision(fs.Arg(0), *revision)
+		} else {
+			entry, err = kv.Get(fs.Arg(0))
+		}
+		if err != nil {
+			return err
+		}
+		_, err = os.Stdout.Write(entry.Value())
+		return err
+	case "put":
+		revision, err := kv.Put(fs.Arg(0), data)
+		if err != nil {
+			return err
+		}
+		fmt.Printf("Configuration %s written at revision %d\n", fs.Arg(0), revision)
+		return nil
+	case "watch":
+		key := fs.Arg(0)
+		if key == "" {
+			key = nats.AllKeys
+		}
+		watcher, err := kv.Watch(key)
+		if err != nil {
+			return err
+		}
+		defer watcher.Stop()
+		interrupt := make(chan os.Signal, 1)
+		signal.Notify(interrupt, os.Interrupt)
+		for {
+			select {
+			case entry, ok := <-watcher.Updates():
+				if !ok {
+					return fmt.Errorf("watch of %s ended", key)
+				}
+				if entry != nil {
+					printConfigEntry(os.Stdout, entry)
+				}
+			case <-interrupt:
+				return nil
+			}
+		}
+	default:
+		keys, err := kv.Keys()
+		if errors.Is(err, nats.ErrNoKeysFound) {
+			fmt.Println("No configuration is kept")
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		sort.Strings(keys)
+		w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(w, "KEY\tREVISION\tWRITTEN\tSIZE")
+		for _, key := range keys {
+			entry, err := kv.Get(key)
+			if err != nil {
+				fmt.Fprintf(w, "%s\t-\t-\t%s\n", key, err)
+				continue
+			}
+			fmt.Fprintf(w, "%s\t%d\t%s\t%d\n", key, entry.Revision(), entry.Created().Local().Format(time.DateTime), len(entry.Value()))
+		}
+		return w.Flush()
+	}
+}
+
+// printConfigEntry prints a configuration as written, after a line naming it
+func printConfigEntry(out io.Writer, entry nats.KeyValueEntry) {
+	what := "revision " + strconv.FormatUint(entry.Revision(), 10)
+	if entry.Operation() != nats.KeyValuePut {
+		what += ", deleted"
+	}
+	fmt.Fprintf(out, "# %s %s at %s\n", entry.Key(), what, entry.Created().Local().Format(time.DateTime))
+	if entry.Operation() == nats.KeyValuePut {
+		out.Write(entry.Value())
+		if n := len(entry.Value()); n > 0 && entry.Value()[n-1] != '\n' {
+			fmt.Fprintln(out)
+		}
+	}
+}
@@ -0,0 +1,389 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/BullionBear/sequex/pkg/eventbus"
+	"github.com/nats-io/nats.go"
+)
+
+const (
+	RoleViewer   = "viewer"   // Read-only, destructive commands are refused
+	RoleOperator = "operator" // Every command, the default
+)
+
+// Context is a named deployment sqx operates, e.g. dev, staging or prod
+type Context struct {
+	Server string `json:"server"`          // NATS server URLs, comma separated
+	Creds  string `json:"creds,omitempty"` // NATS user credentials file
+	Env    string `json:"env,omitempty"`   // Destructive commands need a confirmation in prod
+	Role   string `json:"role,omitempty"`  // RoleViewer or RoleOperator, operator when empty
+	// Name commands are signed as, with the private key of the file Key,
+	// for the nodes verifying them, see sqx ctx keygen
+	Operator string `json:"operator,omitempty"`
+	Key      string `json:"key,omitempty"`
+}
+
+// Prod reports whether the context is a production deployment
+func (c Context) Prod() bool {
+	env := strings.ToLower(c.Env)
+	return env == "prod" || env == "production"
+}
+
+// contextFile is the file the contexts are kept in
+type contextFile struct {
+	Current  string             `json:"current,omitempty"`
+	Contexts map[string]Context `json:"contexts"`
+}
+
+// contextPath returns the file of the SQX_CONFIG environment variable,
+// sqx/config.json in the user configuration directory when unset
+func contextPath() (string, error) {
+	if path := os.Getenv("SQX_CONFIG"); path != "" {
+		return path, nil
+	}
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, "sqx", "config.json"), nil
+}
+
+// loadContexts reads the contexts, none when the file does not exist
+func loadContexts() (*contextFile, error) {
+	path, err := contextPath()
+	if err != nil {
+		return nil, err
+	}
+	file := &contextFile{Contexts: make(map[string]Context)}
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return file, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(data, file); err != nil {
+		return nil, fmt.Errorf("failed to parse %s: %w", path, err)
+	}
+	if file.Contexts == nil {
+		file.Contexts = make(map[string]Context)
+	}
+	return file, nil
+}
+
+func (f *contextFile) save() error {
+	path, err := contextPath()
+	if err != nil {
+		return err
+	}
+	data, err := json.MarshalIndent(f, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o700); err != nil {
+		return err
+	}
+	return os.WriteFile(path, append(data, '\n'), 0o600)
+}
+
+// session is the context the command of an invocation runs against
+type session struct {
+	name     string // Empty when no context is defined
+	explicit bool   // Selected with --context rather than being the current one
+	context  Context
+}
+
+// newSession selects the context name, the current one when empty
+func newSession(name string) (*session, error) {
+	file, err := loadContexts()
+	if err != nil {
+		return nil, err
+	}
+	s := &session{name: name, explicit: name != ""}
+	if name == "" {
+		s.name = file.Current
+	}
+	if s.name == "" {
+		return s, nil
+	}
+	c, ok := file.Contexts[s.name]
+	if !ok {
+		return nil, fmt.Errorf("unknown context %q, see sqx ctx list", s.name)
+	}
+	s.context = c
+	return s, nil
+}
+
+// server returns the NATS servers of the context, those of defaultServer
+// without one
+func (s *session) server() string {
+	if s.context.Server != "" {
+		return s.context.Server
+	}
+	return defaultServer()
+}
+
+// connect connects to server with the credentials of the context
+func (s *session) connect(server string) (*nats.Conn, error) {
+	var opts []nats.Option
+	if s.context.Creds != "" {
+		opts = append(opts, nats.UserCredentials(s.context.Creds))
+	}
+	conn, err := nats.Connect(server, opts...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to NATS: %w", err)
+	}
+	return conn, nil
+}
+
+// newBus creates a bus over conn signing the calls of command endpoints as
+// the operator of the context, unsigned without one
+func (s *session) newBus(conn *nats.Conn) (*eventbus.Bus, error) {
+	var opts eventbus.Options
+	if s.context.Key != "" {
+		key, err := readKey(s.context.Key)
+		if err != nil {
+			return nil, err
+		}
+		opts.Signer = &eventbus.Signer{Operator: s.context.Operator, Key: key}
+	}
+	return eventbus.NewBus(conn, opts)
+}
+
+// authorize checks a destructive command may run in the context. Viewer
+// contexts refuse it, and in prod it needs the context to be selected with
+// --context or its name to be typed back on in as a confirmation.
+func (s *session) authorize(command string, in io.Reader, out io.Writer) error {
+	if s.context.Role == RoleViewer {
+		return fmt.Errorf("%s is not allowed in the viewer context %q", command, s.name)
+	}
+	if !s.context.Prod() || s.explicit {
+		return nil
+	}
+	fmt.Fprintf(out, "Context %q is %s, type its name to run %s: ", s.name, s.context.Env, command)
+	line, err := bufio.NewReader(in).ReadString('\n')
+	if err != nil && !errors.Is(err, io.EOF) {
+		return err
+	}
+	if strings.TrimSpace(line) != s.name {
+		return fmt.Errorf("%s not confirmed, pass --context %s to skip the confirmation", command, s.name)
+	}
+	return nil
+}
+
+// splitContext removes the --context flag from args, allowed before or
+// after the command, and returns its value
+func splitContext(args []string) (string, []string, error) {
+	var name string
+	rest := make([]string, 0, len(args))
+	for i := 0; i < len(args); i++ {
+		arg := args[i]
+		if arg == "--" {
+			rest = append(rest, args[i:]...)
+			break
+		}
+		trimmed := strings.TrimLeft(arg, "-")
+		switch {
+		case arg != trimmed && trimmed == "context":
+			if i+1 == len(args) {
+				return "", nil, fmt.Errorf("--context needs a context name")
+			}
+			i++
+			name = args[i]
+		case arg != trimmed && strings.HasPrefix(trimmed, "context="):
+			name = strings.TrimPrefix(trimmed, "context=")
+		default:
+			rest = append(rest, arg)
+		}
+	}
+	return name, rest, nil
+}
+
+// runContext manages the contexts
+func runContext(s *session, args []string) error {
+	usage := func() {
+		fmt.Fprintf(os.Stderr, `Usage: sqx ctx <command> [arguments]
+
+Contexts name the deployments sqx operates, each with its NATS servers,
+credentials, environment and role. The current context is used unless
+another is selected with --context. Nodes with a command policy only serve
+the commands, e.g. guardian pause or apply, signed by an operator the
+policy grants them to: set the operator and key of the context to sign
+them, e.g.
+
+  sqx ctx keygen ~/.config/sqx/alice.key
+  sqx ctx set -operator alice -key ~/.config/sqx/alice.key prod
+
+Commands:
+  list                 List the contexts, the current one marked with *
+  current              Print the current context
+  use <name>           Make name the current context
+  set [options] <name> Create or update a context, -h for the options
+  delete <name>        Delete a context
+  keygen <file>        Write a new operator key to file and print its public
+                       key for the command policy
+`)
+	}
+	if len(args) == 0 {
+		args = []string{"list"}
+	}
+	file, err := loadContexts()
+	if err != nil {
+		return err
+	}
+	nameArg := func(args []string) (string, error) {
+		if len(args) != 1 {
+			usage()
+			return "", fmt.Errorf("a context name is required")
+		}
+		return args[0], nil
+	}
+
+	switch args[0] {
+	case "list", "ls":
+		names := make([]string, 0, len(file.Contexts))
+		for name := range file.Contexts {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(w, "CURRENT\tNAME\tSERVER\tENV\tROLE\tOPERATOR")
+		for _, name := range names {
+			c := file.Contexts[name]
+			current := ""
+			if name == file.Current {
+				current = "*"
+			}
+			role := c.Role
+			if role == "" {
+				role = RoleOperator
+			}
+			fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\t%s\n", current, name, c.Server, c.Env, role, orDash(c.Operator))
+		}
+		return w.Flush()
+	case "current":
+		if file.Current == "" {
+			return fmt.Errorf("no current context, see sqx ctx use")
+		}
+		fmt.Println(file.Current)
+		return nil
+	case "use":
+		name, err := nameArg(args[1:])
+		if err != nil {
+			return err
+		}
+		if _, ok := file.Contexts[name]; !ok {
+			return fmt.Errorf("unknown context %q", name)
+		}
+		file.Current = name
+		if err := file.save(); err != nil {
+			return err
+		}
+		fmt.Printf("Switched to context %q\n", name)
+		return nil
+	case "set":
+		return setContext(file, args[1:])
+	case "keygen":
+		if len(args) != 2 {
+			usage()
+			return fmt.Errorf("a key file is required")
+		}
+		return keygen(args[1], os.Stdout)
+	case "delete", "rm":
+		name, err := nameArg(args[1:])
+		if err != nil {
+			return err
+		}
+		if _, ok := file.Contexts[name]; !ok {
+			return fmt.Errorf("unknown context %q", name)
+		}
+		delete(file.Contexts, name)
+		if file.Current == name {
+			file.Current = ""
+		}
+		return file.save()
+	case "-h", "--help", "help":
+		usage()
+		return nil
+	default:
+		usage()
+		return fmt.Errorf("unknown ctx command %q", args[0])
+	}
+}
+
+// setContext creates or updates a context with the options given, the
+// first context created becoming the current one
+func setContext(file *contextFile, args []string) error {
+	fs := flag.NewFlagSet("ctx set", flag.ExitOnError)
+	server := fs.String("server", "", "NATS server URLs, comma separated")
+	creds := fs.String("creds", "", "NATS user credentials file")
+	env := fs.String("env", "", "environment, e.g. dev, staging or prod")
+	role := fs.String("role", "", "viewer or operator")
+	operator := fs.String("operator", "", "operator name commands are signed as")
+	key := fs.String("key", "", "file of the private key of the operator, see sqx ctx keygen")
+	fs.Usage = func() {
+		fmt.Fprintf(os.Stderr, "Usage: sqx ctx set [options] <name>\n\nOptions:\n")
+		fs.PrintDefaults()
+	}
+	fs.Parse(args)
+	if fs.NArg() != 1 {
+		fs.Usage()
+		return fmt.Errorf("a context name is required")
+	}
+	name := fs.Arg(0)
+
+	c, ok := file.Contexts[name]
+	fs.Visit(func(f *flag.Flag) {
+		switch f.Name {
+		case "server":
+			c.Server = *server
+		case "creds":
+			c.Creds = *creds
+		case "env":
+			c.Env = *env
+		case "role":
+			c.Role = *role
+		case "operator":
+			c.Operator = *operator
+		case "key":
+			c.Key = *key
+		}
+	})
+	if c.Role != "" && c.Role != RoleViewer && c.Role != RoleOperator {
+		return fmt.Errorf("role must be %s or %s", RoleViewer, RoleOperator)
+	}
+	if (c.Operator == "") != (c.Key == "") {
+		return fmt.Errorf("operator and key are set together")
+	}
+	for _, path := range []*string{&c.Creds, &c.Key} {
+		if *path != "" {
+			if abs, err := filepath.Abs(*path); err == nil {
+				*path = abs
+			}
+		}
+	}
+	file.Contexts[name] = c
+	if file.Current == "" {
+		file.Current = name
+	}
+	if err := file.save(); err != nil {
+		return err
+	}
+	if ok {
+		fmt.Printf("Context %q updated\n", name)
+	} else {
+		fmt.Printf("Context %q created\n", name)
+	}
+	return nil
+}
@@ -0,0 +1,137 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"text/tabwriter"
+	"time"
+
+	"github.com/BullionBear/sequex/internal/bluegreen"
+)
+
+// runDeployments compares the live and shadow instances of the strategies
+// of a service and promotes the shadows
+func runDeployments(s *session, args []string) error {
+	fs := flag.NewFlagSet("deployments", flag.ExitOnError)
+	server := fs.String("s", s.server(), "NATS server URLs, comma separated, those of the context by default")
+	service := fs.String("service", "oms", "service routing the instances, served on params.<service>")
+	subject := fs.String("subject", "", "prefix the params RPCs are served under, overriding -service")
+	timeout := fs.Duration("timeout", 5*time.Second, "time to wait for the service to answer")
+	by := fs.String("by", os.Getenv("USER"), "who promotes the instance")
+	reason := fs.String("reason", "", "why the instance is promoted, required")
+	fs.Usage = func() {
+		fmt.Fprintf(os.Stderr, `Usage: sqx deployments [options] <command> [arguments]
+
+Strategies deployed blue/green run as two instances receiving the same
+inputs: the live one trades, the shadow one, running new parameters, trades
+on paper. Their decisions and PnL are compared over a rolling window. A
+promotion makes the shadow live and the live one the shadow, so promoting
+again rolls it back.
+
+Commands:
+  ls                   Compare the live and shadow instances
+  promote <strategy>   Make the shadow instance of a strategy live
+  history              List the latest promotions
+
+Options:
+`)
+		fs.PrintDefaults()
+	}
+	fs.Parse(args)
+	if fs.NArg() == 0 {
+		fs.Usage()
+		return fmt.Errorf("a command is required")
+	}
+	// Options are accepted before and after the command
+	cmd := fs.Arg(0)
+	fs.Parse(fs.Args()[1:])
+	prefix := *subject
+	if prefix == "" {
+		prefix = bluegreen.DefaultSubject + "." + *service
+	}
+	switch cmd {
+	case "ls", "list", "history":
+	case "promote":
+		if fs.NArg() != 1 {
+			fs.Usage()
+			return fmt.Errorf("a strategy is required")
+		}
+		if *reason == "" {
+			return fmt.Errorf("-reason is required to promote an instance")
+		}
+		if err := s.authorize("deployments promote", os.Stdin, os.Stderr); err != nil {
+			return err
+		}
+	default:
+		fs.Usage()
+		return fmt.Errorf("unknown deployments command %q", cmd)
+	}
+
+	conn, err := s.connect(*server)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+	bus, err := s.newBus(conn)
+	if err != nil {
+		return err
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), *timeout)
+	defer cancel()
+
+	if cmd == "promote" {
+		p, err := bluegreen.PromoteEndpoint(prefix).Call(ctx, bus, &bluegreen.PromoteRequest{Strategy: fs.Arg(0), By: *by, Reason: *reason})
+		if err != nil {
+			return err
+		}
+		fmt.Printf("Instance %s of %s promoted\n", p.Live, p.Strategy)
+		return nil
+	}
+	list, err := bluegreen.ListEndpoint(prefix).Call(ctx, bus, &bluegreen.ListRequest{})
+	if err != nil {
+		return err
+	}
+	if cmd == "history" {
+		return printPromotions(os.Stdout, list.Promotions)
+	}
+	return printDeployments(os.Stdout, list)
+}
+
+func printDeployments(out io.Writer, list *bluegreen.List) error {
+	if len(list.Comparisons) == 0 {
+		fmt.Fprintln(out, "No strategy is deployed")
+		return nil
+	}
+	live := make(map[string]string, len(list.Deployments))
+	for _, d := range list.Deployments {
+		live[d.Strategy] = d.Live
+	}
+	fmt.Fprintf(out, "PnLs in %s since %s\n\n", list.Comparisons[0].Currency, list.Comparisons[0].Since.Local().Format(time.DateTime))
+	w := tabwriter.NewWriter(out, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "STRATEGY\tLIVE\tINSTANCE\tORDERS\tFILLS\tNOTIONAL\tPNL\tMATCHED\tPNL DIFF\tERROR")
+	for _, c := range list.Comparisons {
+		fmt.Fprintf(w, "%s\t%s\t%s\t%d\t%d\t%.2f\t%.2f\t%.0f%%\t%+.2f\t%s\n", c.Strategy, live[c.Strategy], c.Live.Strategy, c.Live.Orders, c.Live.Fills, c.Live.Notional, c.Live.PnL, c.Matched*100, c.PnLDiff, orDash(c.Live.Error))
+		fmt.Fprintf(w, "\tshadow\t%s\t%d\t%d\t%.2f\t%.2f\t\t\t%s\n", c.Shadow.Strategy, c.Shadow.Orders, c.Shadow.Fills, c.Shadow.Notional, c.Shadow.PnL, orDash(c.Shadow.Error))
+	}
+	return w.Flush()
+}
+
+func printPromotions(out io.Writer, promotions []bluegreen.Promotion) error {
+	if len(promotions) == 0 {
+		fmt.Fprintln(out, "No instance was promoted")
+		return nil
+	}
+	w := tabwriter.NewWriter(out, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "STRATEGY\tLIVE\tPNL DIFF\tBY\tAT\tREASON")
+	for _, p := range promotions {
+		diff := "-"
+		if p.Comparison != nil {
+			diff = fmt.Sprintf("%+.2f", p.Comparison.PnLDiff)
+		}
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\t%s\n", p.Strategy, p.Live, diff, orDash(p.By), p.At.Local().Format(time.DateTime), orDash(p.Reason))
+	}
+	return w.Flush()
+}
@@ -0,0 +1,165 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"text/tabwriter"
+	"time"
+
+	"github.com/BullionBear/sequex/pkg/eventbus"
+	"github.com/BullionBear/sequex/pkg/node"
+)
+
+// runDescribe prints the subjects and parameters a node describes itself
+// with on describe.<node>
+func runDescribe(s *session, args []string) error {
+	fs := flag.NewFlagSet("describe", flag.ExitOnError)
+	server := fs.String("s", s.server(), "NATS server URLs, comma separated, those of the context by default")
+	timeout := fs.Duration("timeout", 2*time.Second, "time to wait for the node to answer, per attempt")
+	retries := fs.Int("retries", 2, "attempts after the node did not answer")
+	verbose := fs.Bool("v", false, "print the fields of the payloads too")
+	raw := fs.Bool("json", false, "print the description as JSON")
+	fs.Usage = func() {
+		fmt.Fprintf(os.Stderr, `Usage: sqx describe [options] <node>
+
+Prints the subjects a node handles and emits, with their payloads, and the
+parameters it is configured with. Nodes answer when diagnostics.describe is
+enabled in their configuration.
+
+Options:
+`)
+		fs.PrintDefaults()
+	}
+	fs.Parse(args)
+	if fs.NArg() != 1 {
+		fs.Usage()
+		return fmt.Errorf("a node is required")
+	}
+	subject := "describe." + fs.Arg(0)
+
+	conn, err := s.connect(*server)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+	bus, err := eventbus.NewBus(conn, eventbus.Options{RPC: []eventbus.RPCPolicy{
+		{Subject: subject, Timeout: *timeout, Retries: *retries, Backoff: 250 * time.Millisecond},
+	}})
+	if err != nil {
+		return err
+	}
+	d, err := node.DescribeEndpoint(subject).Call(context.Background(), bus, &node.DescribeRequest{})
+	if err != nil {
+		return fmt.Errorf("no description: %w", err)
+	}
+	if *raw {
+		data, err := json.MarshalIndent(d, "", "  ")
+		if err != nil {
+			return err
+		}
+		fmt.Printf("%s\n", data)
+		return nil
+	}
+	printDescription(os.Stdout, *d, *verbose)
+	return nil
+}
+
+// printDescription prints d as tables, the payload fields under their
+// subject when verbose
+func printDescription(out io.Writer, d node.Description, verbose bool) {
+	fmt.Fprintf(out, "Node:   %s\nStatus: %s\n", d.Name, d.Status)
+	subjects := func(title string, infos []node.SubjectInfo) {
+		fmt.Fprintf(out, "\n%s:\n", title)
+		if len(infos) == 0 {
+			fmt.Fprintln(out, "  none")
+			return
+		}
+		w := tabwriter.NewWriter(out, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(w, "  SUBJECT\tQUEUE\tENCODING\tTYPE\tDESCRIPTION")
+		for _, info := range infos {
+			p := info.Payload
+			if p == nil {
+				p = &node.Payload{}
+			}
+			goType := ""
+			if p.Schema != nil {
+				goType = p.Schema.GoType
+			}
+			fmt.Fprintf(w, "  %s\t%s\t%s\t%s\t%s\n", info.Subject, info.Queue, p.Encoding, goType, p.Description)
+			if verbose && p.Schema != nil {
+				for _, f := range schemaFields("", p.Schema) {
+					fmt.Fprintf(w, "    %s\t%s\t\t\t%s\n", f.path, f.kind, f.description)
+				}
+			}
+		}
+		w.Flush()
+	}
+	subjects("Handles", d.On)
+	subjects("Emits", d.Emits)
+
+	fmt.Fprintln(out, "\nParameters:")
+	if d.Params == nil {
+		fmt.Fprintln(out, "  none")
+		return
+	}
+	w := tabwriter.NewWriter(out, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "  FIELD\tTYPE\tDESCRIPTION")
+	for _, f := range schemaFields("", d.Params) {
+		fmt.Fprintf(w, "  %s\t%s\t%s\n", f.path, f.kind, f.description)
+	}
+	w.Flush()
+}
+
+// schemaField is a leaf of a schema, e.g. nats.uris or dispatch[].workers
+type schemaField struct {
+	path        string
+	kind        string
+	description string
+}
+
+// schemaFields flattens the properties of s into leaves sorted by path
+func schemaFields(prefix string, s *node.Schema) []schemaField {
+	var fields []schemaField
+	names := make([]string, 0, len(s.Properties))
+	for name := range s.Properties {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		property := s.Properties[name]
+		path := prefix + name
+		switch {
+		case len(property.Properties) > 0:
+			fields = append(fields, schemaFields(path+".", property)...)
+		case property.Items != nil && len(property.Items.Properties) > 0:
+			fields = append(fields, schemaFields(path+"[].", property.Items)...)
+		case property.AdditionalProperties != nil && len(property.AdditionalProperties.Properties) > 0:
+			fields = append(fields, schemaFields(path+".<key>.", property.AdditionalProperties)...)
+		default:
+			fields = append(fields, schemaField{path: path, kind: schemaKind(property), description: property.Description})
+		}
+	}
+	return fields
+}
+
+// schemaKind names the type of a leaf, e.g. string, []integer or sqx.Side
+func schemaKind(s *node.Schema) string {
+	switch {
+	case s.Items != nil:
+		return "[]" + schemaKind(s.Items)
+	case s.AdditionalProperties != nil:
+		return "map[string]" + schemaKind(s.AdditionalProperties)
+	case s.Type == "" && s.GoType != "":
+		return s.GoType
+	case s.Type == "":
+		return "any"
+	case s.GoType != "":
+		return s.Type + " (" + s.GoType + ")"
+	}
+	return s.Type
+}
@@ -0,0 +1,158 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"maps"
+	"os"
+	"slices"
+	"text/tabwriter"
+	"time"
+
+	"github.com/BullionBear/sequex/internal/dryrun"
+)
+
+// runDryRun lists and switches the dry-run flags of a service at runtime
+func runDryRun(s *session, args []string) error {
+	fs := flag.NewFlagSet("dryrun", flag.ExitOnError)
+	server := fs.String("s", s.server(), "NATS server URLs, comma separated, those of the context by default")
+	service := fs.String("service", "oms", "service routing the orders, served on params.<service>")
+	subject := fs.String("subject", "", "prefix the params RPCs are served under, overriding -service")
+	timeout := fs.Duration("timeout", 5*time.Second, "time to wait for the service to answer")
+	by := fs.String("by", os.Getenv("USER"), "who switches the flag")
+	fs.Usage = func() {
+		fmt.Fprintf(os.Stderr, `Usage: sqx dryrun [options] <command> [arguments]
+
+Strategies in dry run are kept off the exchange: their orders are
+acknowledged on paper, logged and published instead of routed. Strategies
+without their own flag follow the global flag.
+
+Commands:
+  ls                  List the flags
+  on [strategy]       Put a strategy, or every strategy without its own flag, in dry run
+  off [strategy]      Take a strategy, or every strategy without its own flag, out of dry run
+  reset <strategy>    Drop the flag of a strategy, which follows the global flag
+  history             List the latest switches
+
+Options:
+`)
+		fs.PrintDefaults()
+	}
+	fs.Parse(args)
+	if fs.NArg() == 0 {
+		fs.Usage()
+		return fmt.Errorf("a command is required")
+	}
+	// Options are accepted before and after the command
+	cmd := fs.Arg(0)
+	fs.Parse(fs.Args()[1:])
+	prefix := *subject
+	if prefix == "" {
+		prefix = dryrun.DefaultSubject + "." + *service
+	}
+	var req *dryrun.SetRequest
+	switch cmd {
+	case "ls", "list", "history":
+	case "on", "off":
+		if fs.NArg() > 1 {
+			fs.Usage()
+			return fmt.Errorf("at most a strategy is expected")
+		}
+		enabled := cmd == "on"
+		req = &dryrun.SetRequest{Strategy: fs.Arg(0), Enabled: &enabled, By: *by}
+	case "reset":
+		if fs.NArg() != 1 {
+			fs.Usage()
+			return fmt.Errorf("a strategy is required")
+		}
+		req = &dryrun.SetRequest{Strategy: fs.Arg(0), By: *by}
+	default:
+		fs.Usage()
+		return fmt.Errorf("unknown dryrun command %q", cmd)
+	}
+	if req != nil {
+		if err := s.authorize("dryrun "+cmd, os.Stdin, os.Stderr); err != nil {
+			return err
+		}
+	}
+
+	conn, err := s.connect(*server)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+	bus, err := s.newBus(conn)
+	if err != nil {
+		return err
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), *timeout)
+	defer cancel()
+
+	if req != nil {
+		c, err := dryrun.SetEndpoint(prefix).Call(ctx, bus, req)
+		if err != nil {
+			return err
+		}
+		fmt.Printf("Dry run of %s %s\n", flagName(c.Strategy), flagState(c.After))
+		return nil
+	}
+	status, err := dryrun.ListEndpoint(prefix).Call(ctx, bus, &dryrun.ListRequest{})
+	if err != nil {
+		return err
+	}
+	if cmd == "history" {
+		return printDryRunChanges(os.Stdout, status.Changes)
+	}
+	return printDryRun(os.Stdout, status)
+}
+
+// flagName returns the strategy of a flag, every strategy for the global flag
+func flagName(strategy string) string {
+	if strategy == "" {
+		return "every strategy"
+	}
+	return strategy
+}
+
+// flagState returns a flag as on, off or global when dropped
+func flagState(enabled *bool) string {
+	switch {
+	case enabled == nil:
+		return "global"
+	case *enabled:
+		return "on"
+	default:
+		return "off"
+	}
+}
+
+func printDryRun(out io.Writer, status *dryrun.Status) error {
+	fmt.Fprintf(out, "Global dry run %s\n", flagState(&status.Global))
+	if len(status.Strategies) == 0 {
+		fmt.Fprintln(out, "No strategy has its own flag")
+		return nil
+	}
+	fmt.Fprintln(out)
+	w := tabwriter.NewWriter(out, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "STRATEGY\tDRY RUN")
+	for _, strategy := range slices.Sorted(maps.Keys(status.Strategies)) {
+		enabled := status.Strategies[strategy]
+		fmt.Fprintf(w, "%s\t%s\n", strategy, flagState(&enabled))
+	}
+	return w.Flush()
+}
+
+func printDryRunChanges(out io.Writer, changes []dryrun.Change) error {
+	if len(changes) == 0 {
+		fmt.Fprintln(out, "No flag was switched")
+		return nil
+	}
+	w := tabwriter.NewWriter(out, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "STRATEGY\tBEFORE\tAFTER\tBY\tAT")
+	for _, c := range changes {
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\n", flagName(c.Strategy), flagState(c.Before), flagState(c.After), orDash(c.By), c.At.Local().Format(time.DateTime))
+	}
+	return w.Flush()
+}
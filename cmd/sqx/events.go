@@ -0,0 +1,113 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/BullionBear/sequex/internal/model/sqx"
+	"github.com/BullionBear/sequex/internal/sysevent"
+	"github.com/BullionBear/sequex/pkg/eventbus"
+)
+
+// runEvents prints the system events kept by the collector, oldest first
+func runEvents(s *session, args []string) error {
+	fs := flag.NewFlagSet("events", flag.ExitOnError)
+	server := fs.String("s", s.server(), "NATS server URLs, comma separated, those of the context by default")
+	subject := fs.String("subject", sysevent.DefaultSubject, "prefix the system event RPCs are served under")
+	timeout := fs.Duration("timeout", 10*time.Second, "time to wait for the collector to answer")
+	kind := fs.String("kind", "", "kinds printed, comma separated, e.g. risk_breach,exchange_halt, all by default")
+	severity := fs.String("severity", "", "minimum severity printed: info, warning, error or critical")
+	source := fs.String("source", "", "print only the events of this service")
+	code := fs.String("code", "", "print only the events of this code, e.g. quarantined")
+	text := fs.String("text", "", "print only the events whose message contains this text")
+	attrs := fs.String("attr", "", "attributes the events have, comma separated, e.g. strategy:mm,symbol:BTC-USDT")
+	since := fs.Duration("since", 24*time.Hour, "print the events of this last period, all those kept when 0")
+	limit := fs.Int("n", sysevent.DefaultLimit, "print only the last n events")
+	asJSON := fs.Bool("json", false, "print the events as JSON")
+	fs.Usage = func() {
+		fmt.Fprint(os.Stderr, `Usage: sqx events [options]
+
+Prints the system events every service publishes to sys.events.<kind>, as
+kept by the sysevents collector: nodes starting, stopping and quarantined,
+risk breaches, exchange halts, reconnects and dead-lettered messages, e.g.
+
+  sqx events -severity warning -since 1h
+  sqx events -kind risk_breach -attr strategy:mm
+
+Options:
+`)
+		fs.PrintDefaults()
+	}
+	fs.Parse(args)
+	if fs.NArg() != 0 {
+		fs.Usage()
+		return fmt.Errorf("unexpected arguments %v", fs.Args())
+	}
+
+	values := url.Values{
+		"kind":     {*kind},
+		"severity": {*severity},
+		"source":   {*source},
+		"code":     {*code},
+		"text":     {*text},
+		"limit":    {strconv.Itoa(*limit)},
+	}
+	if *since > 0 {
+		values.Set("since", since.String())
+	}
+	if *attrs != "" {
+		values["attr"] = strings.Split(*attrs, ",")
+	}
+	q, err := sysevent.ParseQuery(values, time.Now())
+	if err != nil {
+		return err
+	}
+
+	conn, err := s.connect(*server)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+	bus, err := eventbus.NewBus(conn, eventbus.Options{})
+	if err != nil {
+		return err
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), *timeout)
+	defer cancel()
+	resp, err := sysevent.QueryEndpoint(*subject).Call(ctx, bus, &q)
+	if err != nil {
+		return err
+	}
+
+	events := resp.Events
+	for i, j := 0, len(events)-1; i < j; i, j = i+1, j-1 {
+		events[i], events[j] = events[j], events[i]
+	}
+	if *asJSON {
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		return enc.Encode(events)
+	}
+	return printEvents(os.Stdout, events)
+}
+
+// printEvents prints events as a table
+func printEvents(out io.Writer, events []sqx.SystemEvent) error {
+	w := tabwriter.NewWriter(out, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "TIME\tSEVERITY\tKIND\tSOURCE\tCODE\tMESSAGE")
+	for _, e := range events {
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\t%s\n",
+			time.UnixMilli(e.Timestamp).UTC().Format(time.RFC3339),
+			e.Severity, e.Kind, orDash(e.Source), orDash(e.Code), e.Message)
+	}
+	return w.Flush()
+}
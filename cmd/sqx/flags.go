@@ -0,0 +1,172 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/BullionBear/sequex/pkg/featureflag"
+	"github.com/nats-io/nats.go"
+)
+
+// runFlags lists and overrides the feature flags at runtime
+func runFlags(s *session, args []string) error {
+	fs := flag.NewFlagSet("flags", flag.ExitOnError)
+	server := fs.String("s", s.server(), "NATS server URLs, comma separated, those of the context by default")
+	bucket := fs.String("bucket", featureflag.DefaultBucket, "key-value bucket of the overrides")
+	off := fs.Bool("off", false, "disable the flag, with set")
+	nodes := fs.String("nodes", "", "nodes the flag is on for, comma separated, all of them when empty, with set")
+	symbols := fs.String("symbols", "", "symbols the flag is on for, comma separated, all of them when empty, with set")
+	description := fs.String("desc", "", "description of the flag, with set")
+	by := fs.String("by", os.Getenv("USER"), "who changes the flag, with set and reset")
+	reason := fs.String("reason", "", "why the flag changes, with set and reset")
+	fs.Usage = func() {
+		fmt.Fprintf(os.Stderr, `Usage: sqx flags [options] <command> [arguments]
+
+Overrides the feature flags of the services at runtime. The configured values
+of a node are listed, with its audit trail, by sqx snapshot -part flags <node>.
+
+Commands:
+  ls               List the overrides
+  set <flag>       Override a flag, enabled unless -off
+  reset <flag>     Revert a flag to its configured value
+  history <flag>   List the past overrides of a flag
+
+Options:
+`)
+		fs.PrintDefaults()
+	}
+	fs.Parse(args)
+	if fs.NArg() == 0 {
+		fs.Usage()
+		return fmt.Errorf("a command is required")
+	}
+	// Options are accepted before and after the command
+	cmd := fs.Arg(0)
+	fs.Parse(fs.Args()[1:])
+	name := ""
+	if cmd != "ls" && cmd != "list" {
+		if fs.NArg() != 1 {
+			fs.Usage()
+			return fmt.Errorf("a flag is required")
+		}
+		name = fs.Arg(0)
+	}
+	if cmd == "set" || cmd == "reset" {
+		if err := s.authorize("flags "+cmd, os.Stdin, os.Stderr); err != nil {
+			return err
+		}
+	}
+
+	conn, err := s.connect(*server)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+	js, err := conn.JetStream()
+	if err != nil {
+		return err
+	}
+	kv, err := featureflag.OpenBucket(js, *bucket)
+	if err != nil {
+		return err
+	}
+
+	switch cmd {
+	case "ls", "list":
+		return printOverrides(os.Stdout, kv)
+	case "set":
+		f := featureflag.Flag{Name: name, Description: *description, Enabled: !*off, Nodes: splitList(*nodes), Symbols: splitList(*symbols)}
+		revision, err := featureflag.Put(kv, f, *by, *reason)
+		if err != nil {
+			return err
+		}
+		fmt.Printf("Flag %s overridden at revision %d\n", name, revision)
+		return nil
+	case "reset":
+		revision, err := featureflag.Reset(kv, name, *by, *reason)
+		if err != nil {
+			return err
+		}
+		fmt.Printf("Flag %s reset at revision %d\n", name, revision)
+		return nil
+	case "history":
+		changes, err := featureflag.History(kv, name)
+		if err != nil {
+			return err
+		}
+		if len(changes) == 0 {
+			fmt.Printf("Flag %s was never overridden\n", name)
+			return nil
+		}
+		return printChanges(os.Stdout, changes)
+	default:
+		fs.Usage()
+		return fmt.Errorf("unknown flags command %q", cmd)
+	}
+}
+
+func printOverrides(out io.Writer, kv nats.KeyValue) error {
+	keys, err := kv.Keys()
+	if errors.Is(err, nats.ErrNoKeysFound) {
+		fmt.Fprintln(out, "No flag is overridden")
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	var changes []featureflag.Change
+	for _, key := range keys {
+		history, err := featureflag.History(kv, key)
+		if err != nil {
+			return err
+		}
+		if n := len(history); n > 0 && history[n-1].After != nil {
+			changes = append(changes, history[n-1])
+		}
+	}
+	if len(changes) == 0 {
+		fmt.Fprintln(out, "No flag is overridden")
+		return nil
+	}
+	return printChanges(out, changes)
+}
+
+func printChanges(out io.Writer, changes []featureflag.Change) error {
+	w := tabwriter.NewWriter(out, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "FLAG\tREVISION\tCHANGE\tENABLED\tNODES\tSYMBOLS\tBY\tAT\tREASON")
+	for _, c := range changes {
+		enabled, nodes, symbols := "-", "-", "-"
+		if f := c.After; f != nil {
+			enabled = strconv.FormatBool(f.Enabled)
+			nodes = orAll(f.Nodes)
+			symbols = orAll(f.Symbols)
+		}
+		fmt.Fprintf(w, "%s\t%d\t%s\t%s\t%s\t%s\t%s\t%s\t%s\n", c.Flag, c.Revision, c.Source, enabled, nodes, symbols,
+			orDash(c.By), c.At.Local().Format(time.DateTime), orDash(c.Reason))
+	}
+	return w.Flush()
+}
+
+func orAll(items []string) string {
+	if len(items) == 0 {
+		return "all"
+	}
+	return strings.Join(items, ",")
+}
+
+func splitList(list string) []string {
+	var items []string
+	for _, item := range strings.Split(list, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
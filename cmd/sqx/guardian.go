@@ -0,0 +1,144 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"text/tabwriter"
+	"time"
+
+	"github.com/BullionBear/sequex/internal/guardian"
+)
+
+// runGuardian lists the drawdowns of the strategies of a service and pauses
+// or resumes them
+func runGuardian(s *session, args []string) error {
+	fs := flag.NewFlagSet("guardian", flag.ExitOnError)
+	server := fs.String("s", s.server(), "NATS server URLs, comma separated, those of the context by default")
+	service := fs.String("service", "fixgateway", "service enforcing the drawdown limits, served on guardian.<service>")
+	subject := fs.String("subject", "", "prefix the guardian RPCs are served under, overriding -service")
+	timeout := fs.Duration("timeout", 5*time.Second, "time to wait for the service to answer")
+	by := fs.String("by", os.Getenv("USER"), "who pauses or resumes the strategy")
+	reason := fs.String("reason", "", "why the strategy is paused or resumed, required")
+	fs.Usage = func() {
+		fmt.Fprintf(os.Stderr, `Usage: sqx guardian [options] <command> [arguments]
+
+Strategies breaching their drawdown limits are paused, their working orders
+canceled and their new orders rejected, until resumed by hand. Every pause
+and resumption is recorded in the audit trail of the service, with who made
+it and why.
+
+Commands:
+  ls                   List the strategies and their drawdowns
+  pause <strategy>     Pause a strategy, canceling its working orders
+  resume <strategy>    Resume a paused strategy, its drawdown starting over
+  history              List the latest pauses and resumptions
+
+Options:
+`)
+		fs.PrintDefaults()
+	}
+	fs.Parse(args)
+	if fs.NArg() == 0 {
+		fs.Usage()
+		return fmt.Errorf("a command is required")
+	}
+	// Options are accepted before and after the command
+	cmd := fs.Arg(0)
+	fs.Parse(fs.Args()[1:])
+	prefix := *subject
+	if prefix == "" {
+		prefix = guardian.DefaultSubject + "." + *service
+	}
+	switch cmd {
+	case "ls", "list", "history":
+	case "pause", "resume":
+		if fs.NArg() != 1 {
+			fs.Usage()
+			return fmt.Errorf("a strategy is required")
+		}
+		if *reason == "" {
+			return fmt.Errorf("-reason is required to %s a strategy", cmd)
+		}
+		if err := s.authorize("guardian "+cmd, os.Stdin, os.Stderr); err != nil {
+			return err
+		}
+	default:
+		fs.Usage()
+		return fmt.Errorf("unknown guardian command %q", cmd)
+	}
+
+	conn, err := s.connect(*server)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+	bus, err := s.newBus(conn)
+	if err != nil {
+		return err
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), *timeout)
+	defer cancel()
+
+	switch cmd {
+	case "pause":
+		e, err := guardian.PauseEndpoint(prefix).Call(ctx, bus, &guardian.PauseRequest{Strategy: fs.Arg(0), By: *by, Reason: *reason})
+		if err != nil {
+			return err
+		}
+		fmt.Printf("Strategy %s paused, %d working orders canceled\n", e.Strategy, e.Canceled)
+	case "resume":
+		e, err := guardian.ResumeEndpoint(prefix).Call(ctx, bus, &guardian.ResumeRequest{Strategy: fs.Arg(0), By: *by, Reason: *reason})
+		if err != nil {
+			return err
+		}
+		fmt.Printf("Strategy %s resumed\n", e.Strategy)
+	default:
+		list, err := guardian.ListEndpoint(prefix).Call(ctx, bus, &guardian.ListRequest{})
+		if err != nil {
+			return err
+		}
+		if cmd == "history" {
+			return printGuardianEvents(os.Stdout, list.Events)
+		}
+		return printGuardian(os.Stdout, list)
+	}
+	return nil
+}
+
+func printGuardian(out io.Writer, list *guardian.List) error {
+	if len(list.Strategies) == 0 {
+		fmt.Fprintln(out, "No strategy has traded")
+		return nil
+	}
+	fmt.Fprintf(out, "Equities in %s\n\n", list.Currency)
+	w := tabwriter.NewWriter(out, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "STRATEGY\tEQUITY\tPEAK\tDRAWDOWN\tLIMIT\tWORKING\tPAUSED\tERROR")
+	for _, st := range list.Strategies {
+		paused := "-"
+		if st.Paused != nil {
+			paused = fmt.Sprintf("%s by %s: %s", st.Paused.At.Local().Format(time.DateTime), st.Paused.By, st.Paused.Reason)
+		}
+		limit := "-"
+		if st.MaxDrawdown > 0 {
+			limit = fmt.Sprintf("%.2f", st.MaxDrawdown)
+		}
+		fmt.Fprintf(w, "%s\t%.2f\t%.2f\t%.2f\t%s\t%d\t%s\t%s\n", st.Strategy, st.Equity, st.Peak, st.Drawdown, limit, st.Working, paused, orDash(st.Error))
+	}
+	return w.Flush()
+}
+
+func printGuardianEvents(out io.Writer, events []guardian.Event) error {
+	if len(events) == 0 {
+		fmt.Fprintln(out, "No strategy was paused")
+		return nil
+	}
+	w := tabwriter.NewWriter(out, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "STRATEGY\tACTION\tDRAWDOWN\tCANCELED\tBY\tAT\tREASON")
+	for _, e := range events {
+		fmt.Fprintf(w, "%s\t%s\t%.2f\t%d\t%s\t%s\t%s\n", e.Strategy, e.Action, e.Drawdown, e.Canceled, orDash(e.By), e.At.Local().Format(time.DateTime), orDash(e.Reason))
+	}
+	return w.Flush()
+}
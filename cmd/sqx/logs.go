@@ -0,0 +1,146 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/nats-io/nats.go"
+	"github.com/rs/zerolog"
+)
+
+// catchUpTimeout is how long the stored records are waited for before
+// considering there are none
+const catchUpTimeout = 2 * time.Second
+
+// runLogs prints the records shipped to logs.<node>, those stored by a
+// stream first and, when following, the new ones as they arrive
+func runLogs(s *session, args []string) error {
+	fs := flag.NewFlagSet("logs", flag.ExitOnError)
+	server := fs.String("s", s.server(), "NATS server URLs, comma separated, those of the context by default")
+	since := fs.Duration("since", 15*time.Minute, "print the stored records of this last period")
+	tail := fs.Int("n", 0, "print only the last n stored records, 0 for all")
+	var follow bool
+	fs.BoolVar(&follow, "follow", false, "keep printing new records until interrupted")
+	fs.BoolVar(&follow, "f", false, "shorthand for -follow")
+	raw := fs.Bool("raw", false, "print the records as JSON lines")
+	fs.Usage = func() {
+		fmt.Fprintf(os.Stderr, `Usage: sqx logs [options] <node>
+
+Prints the log records a node ships to logs.<node>, e.g. sqx logs feed, or
+of several nodes with wildcards, e.g. sqx logs '>'. Stored records need a
+stream on logs.>, see infra/nats/stream_logs.json; without one only new
+records can be followed.
+
+Options:
+`)
+		fs.PrintDefaults()
+	}
+	fs.Parse(args)
+	if fs.NArg() != 1 {
+		fs.Usage()
+		return fmt.Errorf("a node is required")
+	}
+	node := fs.Arg(0)
+	subject := "logs." + node
+
+	conn, err := s.connect(*server)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+	ctx, cancel := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer cancel()
+
+	print := recordPrinter(os.Stdout, *raw, strings.ContainsAny(node, "*>"))
+
+	js, err := conn.JetStream()
+	if err != nil {
+		return err
+	}
+	if _, err := js.StreamNameBySubject(subject); err != nil {
+		if !follow {
+			return fmt.Errorf("no stream stores %s, only -follow is available: %w", subject, err)
+		}
+		sub, err := conn.SubscribeSync(subject)
+		if err != nil {
+			return err
+		}
+		defer sub.Unsubscribe()
+		return printNew(ctx, sub, print)
+	}
+
+	sub, err := js.SubscribeSync(subject, nats.OrderedConsumer(), nats.StartTime(time.Now().Add(-*since)))
+	if err != nil {
+		return err
+	}
+	defer sub.Unsubscribe()
+
+	// Stored records, up to the last when the consumer has nothing pending
+	var stored []*nats.Msg
+	for {
+		msg, err := sub.NextMsg(catchUpTimeout)
+		if errors.Is(err, nats.ErrTimeout) {
+			break
+		}
+		if err != nil {
+			return err
+		}
+		stored = append(stored, msg)
+		if *tail > 0 && len(stored) > *tail {
+			stored = stored[1:]
+		}
+		if meta, err := msg.Metadata(); err == nil && meta.NumPending == 0 {
+			break
+		}
+	}
+	for _, msg := range stored {
+		print(msg)
+	}
+	if !follow {
+		return nil
+	}
+	return printNew(ctx, sub, print)
+}
+
+// printNew prints records as they arrive until ctx is done
+func printNew(ctx context.Context, sub *nats.Subscription, print func(*nats.Msg)) error {
+	for {
+		msg, err := sub.NextMsgWithContext(ctx)
+		if ctx.Err() != nil {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		print(msg)
+	}
+}
+
+// recordPrinter returns a function printing records to w, as they were
+// shipped when raw and the way the nodes print them otherwise. With
+// several nodes each record is prefixed with the subject it came from.
+func recordPrinter(w io.Writer, raw, prefix bool) func(*nats.Msg) {
+	// The nodes log timestamps in microseconds
+	zerolog.TimeFieldFormat = zerolog.TimeFormatUnixMicro
+	console := zerolog.ConsoleWriter{Out: w, TimeFormat: "2006-01-02 15:04:05.000000"}
+	return func(msg *nats.Msg) {
+		if prefix {
+			fmt.Fprintf(w, "%s ", strings.TrimPrefix(msg.Subject, "logs."))
+		}
+		if raw {
+			fmt.Fprintf(w, "%s\n", strings.TrimRight(string(msg.Data), "\n"))
+			return
+		}
+		if _, err := console.Write(msg.Data); err != nil {
+			fmt.Fprintf(w, "%s\n", strings.TrimRight(string(msg.Data), "\n"))
+		}
+	}
+}
@@ -0,0 +1,93 @@
+package main
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/nats-io/nats.go"
+)
+
+// command is a sqx subcommand. Destructive commands are authorized by the
+// session before running, see session.authorize.
+type command struct {
+	name        string
+	summary     string
+	run         func(s *session, args []string) error
+	destructive bool
+}
+
+var commands = []command{
+	{"allocations", "List and adjust the strategy budgets at runtime", runAllocations, false},
+	{"apply", "Deploy the application of a bundle", runApply, true},
+	{"apps", "List and remove the applications deployed", runApps, false},
+	{"backtest", "Submit backtests and gate their results against a baseline", runBacktest, false},
+	{"config", "Read, write and watch the configurations of the nodes", runConfig, false},
+	{"ctx", "List, switch and edit the contexts", runContext, false},
+	{"deployments", "Compare shadow strategy instances and promote them", runDeployments, false},
+	{"describe", "Print the subjects and parameters of a node", runDescribe, false},
+	{"dryrun", "Keep strategies off the exchange at runtime", runDryRun, false},
+	{"events", "Print the system events of every service", runEvents, false},
+	{"flags", "List and override the feature flags at runtime", runFlags, false},
+	{"guardian", "List strategy drawdowns, pause and resume strategies", runGuardian, false},
+	{"logs", "Print the log records shipped by a node", runLogs, false},
+	{"new", "Generate the configuration of a new node", runNew, false},
+	{"snapshot", "Print a snapshot of the internal state of a node", runSnapshot, false},
+	{"streams", "Inspect the JetStream streams and their consumers", runStreams, false},
+	{"version", "Print the versions of the running services", runVersion, false},
+	{"withdrawals", "Request, approve and reject withdrawals", runWithdrawals, false},
+}
+
+// defaultServer returns the NATS servers of the NATS_URL environment
+// variable, the local server when unset
+func defaultServer() string {
+	if url := os.Getenv("NATS_URL"); url != "" {
+		return url
+	}
+	return nats.DefaultURL
+}
+
+func usage() {
+	fmt.Fprintf(os.Stderr, `Usage: sqx [--context <name>] <command> [options] [arguments]
+
+Sqx operates the nodes of a running deployment over NATS, the one of the
+current context unless another is selected with --context, see sqx ctx.
+
+Commands:
+`)
+	for _, c := range commands {
+		fmt.Fprintf(os.Stderr, "  %-12s %s\n", c.name, c.summary)
+	}
+	fmt.Fprintf(os.Stderr, "\nRun sqx <command> -h for the options of a command.\n")
+}
+
+func main() {
+	name, args, err := splitContext(os.Args[1:])
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+	if len(args) < 1 || args[0] == "-h" || args[0] == "--help" || args[0] == "help" {
+		usage()
+		os.Exit(1)
+	}
+	for _, c := range commands {
+		if c.name != args[0] {
+			continue
+		}
+		s, err := newSession(name)
+		if err == nil && c.destructive {
+			err = s.authorize(c.name, os.Stdin, os.Stderr)
+		}
+		if err == nil {
+			err = c.run(s, args[1:])
+		}
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+		return
+	}
+	fmt.Fprintf(os.Stderr, "Error: unknown command %q\n\n", args[0])
+	usage()
+	os.Exit(1)
+}
@@ -0,0 +1,131 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/BullionBear/sequex/internal/scaffold"
+)
+
+// runNew generates the configuration of a new node, and the stub of a new
+// strategy
+func runNew(s *session, args []string) error {
+	fs := flag.NewFlagSet("new", flag.ExitOnError)
+	nodeType := fs.String("type", "", "template of the node, required")
+	symbol := fs.String("symbol", "", "symbol traded or streamed, BTCUSDT or BTC-USDT, required")
+	name := fs.String("name", "", "name of the strategy, required by the strategy template, e.g. grid")
+	exchange := fs.String("exchange", "binance", "exchange of the symbol")
+	instrument := fs.String("instrument", "spot", "instrument of the symbol")
+	servers := fs.String("nats", scaffold.DefaultNATSURIs, "NATS server URLs of the node, comma separated")
+	out := fs.String("o", "", "path of the configuration, config/<node>.yml by default, - for stdout")
+	stub := fs.Bool("stub", false, "write the Go stub of the strategy as well")
+	force := fs.Bool("force", false, "overwrite existing files")
+	fs.Usage = func() {
+		fmt.Fprintf(os.Stderr, `Usage: sqx new node [options]
+
+Generates the YAML configuration of a new node, with its subjects and RPC
+endpoints named after the conventions of the other nodes, and the Go stub of
+a new strategy. The configuration is validated as the node loads it before
+it is written.
+
+Templates:
+`)
+		for _, t := range scaffold.Templates() {
+			fmt.Fprintf(os.Stderr, "  %-10s %s\n", t.Type, t.Summary)
+		}
+		fmt.Fprintf(os.Stderr, `
+Examples:
+  sqx new node --type feed --symbol BTCUSDT
+  sqx new node --type strategy --name grid --symbol BTCUSDT --stub
+
+Options:
+`)
+		fs.PrintDefaults()
+	}
+	fs.Parse(args)
+	if fs.NArg() == 0 || fs.Arg(0) != "node" {
+		fs.Usage()
+		return fmt.Errorf("sqx new node is the only command")
+	}
+	// Options are accepted before and after the command
+	fs.Parse(fs.Args()[1:])
+	if *nodeType == "" || *symbol == "" {
+		fs.Usage()
+		return fmt.Errorf("-type and -symbol are required")
+	}
+	sym, err := scaffold.ParseSymbol(*symbol)
+	if err != nil {
+		return err
+	}
+	n, err := scaffold.Generate(scaffold.Params{
+		Type:       *nodeType,
+		Name:       *name,
+		Exchange:   *exchange,
+		Instrument: *instrument,
+		Symbol:     sym,
+		NATSURIs:   *servers,
+	})
+	if err != nil {
+		return err
+	}
+	if *stub && n.Stub == nil {
+		return fmt.Errorf("%s nodes have no stub", n.Type)
+	}
+
+	path := *out
+	if path == "" {
+		path = n.ConfigPath
+	}
+	if path == "-" {
+		_, err := os.Stdout.Write(n.Config)
+		return err
+	}
+	if err := writeNew(path, n.Config, *force); err != nil {
+		return err
+	}
+	fmt.Printf("Configuration of %s written to %s\n", n.Name, path)
+	if *stub {
+		if err := writeNew(n.StubPath, n.Stub, *force); err != nil {
+			return err
+		}
+		fmt.Printf("Strategy stub written to %s\n", n.StubPath)
+	}
+	return printNode(os.Stdout, n)
+}
+
+// writeNew writes a generated file, creating its directory, unless it
+// exists and force is not set
+func writeNew(path string, data []byte, force bool) error {
+	if !force {
+		if _, err := os.Stat(path); err == nil {
+			return fmt.Errorf("%s exists, pass -force to overwrite it", path)
+		}
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return err
+	}
+	return os.WriteFile(path, data, 0o644)
+}
+
+func printNode(out io.Writer, n *scaffold.Node) error {
+	w := tabwriter.NewWriter(out, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "\nROLE\tSUBJECT")
+	roles := make([]string, 0, len(n.Subjects))
+	for role := range n.Subjects {
+		roles = append(roles, role)
+	}
+	sort.Strings(roles)
+	for _, role := range roles {
+		fmt.Fprintf(w, "%s\t%s\n", role, n.Subjects[role])
+	}
+	if len(n.Endpoints) > 0 {
+		fmt.Fprintf(w, "rpc\t%s\n", strings.Join(n.Endpoints, ", "))
+	}
+	return w.Flush()
+}
@@ -0,0 +1,66 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/BullionBear/sequex/pkg/eventbus"
+	"github.com/BullionBear/sequex/pkg/node"
+)
+
+// runSnapshot prints the snapshot of the internal state a node serves on
+// snapshot.<node>
+func runSnapshot(s *session, args []string) error {
+	fs := flag.NewFlagSet("snapshot", flag.ExitOnError)
+	server := fs.String("s", s.server(), "NATS server URLs, comma separated, those of the context by default")
+	timeout := fs.Duration("timeout", 5*time.Second, "time to wait for the node to answer")
+	parts := fs.String("part", "", "parts of the state to capture, comma separated, every part by default")
+	fs.Usage = func() {
+		fmt.Fprintf(os.Stderr, `Usage: sqx snapshot [options] <node>
+
+Prints a snapshot of the internal state of a node as JSON, one key per part.
+Nodes answer when diagnostics.snapshot is enabled in their configuration.
+
+Options:
+`)
+		fs.PrintDefaults()
+	}
+	fs.Parse(args)
+	if fs.NArg() != 1 {
+		fs.Usage()
+		return fmt.Errorf("a node is required")
+	}
+	var names []string
+	for _, part := range strings.Split(*parts, ",") {
+		if part = strings.TrimSpace(part); part != "" {
+			names = append(names, part)
+		}
+	}
+
+	conn, err := s.connect(*server)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+	bus, err := eventbus.NewBus(conn, eventbus.Options{})
+	if err != nil {
+		return err
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), *timeout)
+	defer cancel()
+	snapshot, err := node.FetchSnapshot(ctx, bus, "snapshot."+fs.Arg(0), names...)
+	if err != nil {
+		return fmt.Errorf("no snapshot: %w", err)
+	}
+	data, err := json.MarshalIndent(snapshot, "", "  ")
+	if err != nil {
+		return err
+	}
+	fmt.Printf("%s\n", data)
+	return nil
+}
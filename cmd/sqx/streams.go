@@ -0,0 +1,356 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/nats-io/nats.go"
+)
+
+// runStreams inspects the JetStream streams and their consumers
+func runStreams(s *session, args []string) error {
+	fs := flag.NewFlagSet("streams", flag.ExitOnError)
+	server := fs.String("s", s.server(), "NATS server URLs, comma separated, those of the context by default")
+	timeout := fs.Duration("timeout", 5*time.Second, "time to wait for the JetStream API")
+	subjects := fs.Bool("subjects", false, "list the message count of each subject, with info")
+	minPending := fs.Uint64("min", 1, "pending messages from which a consumer is listed, with lag")
+	fs.Usage = func() {
+		fmt.Fprintf(os.Stderr, `Usage: sqx streams [options] <command> [arguments]
+
+Inspects the JetStream streams and their consumers.
+
+Commands:
+  ls                   List the streams with their sizes and limits
+  info <stream>        Print the configuration and state of a stream
+  consumers <stream>   List the consumers of a stream with their progress
+  lag [stream]         List the consumers behind, most pending first
+
+Options:
+`)
+		fs.PrintDefaults()
+	}
+	fs.Parse(args)
+	if fs.NArg() == 0 {
+		fs.Usage()
+		return fmt.Errorf("a command is required")
+	}
+	// Options are accepted before and after the command
+	cmd := fs.Arg(0)
+	fs.Parse(fs.Args()[1:])
+	rest := fs.Args()
+	streamArg := func(optional bool) (string, error) {
+		switch {
+		case len(rest) == 1:
+			return rest[0], nil
+		case len(rest) == 0 && optional:
+			return "", nil
+		}
+		fs.Usage()
+		return "", fmt.Errorf("a stream is required")
+	}
+
+	conn, err := s.connect(*server)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+	js, err := conn.JetStream()
+	if err != nil {
+		return err
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), *timeout)
+	defer cancel()
+
+	switch cmd {
+	case "ls", "list":
+		streams, err := listStreams(ctx, js)
+		if err != nil {
+			return err
+		}
+		return printStreams(os.Stdout, streams)
+	case "info":
+		name, err := streamArg(false)
+		if err != nil {
+			return err
+		}
+		req := &nats.StreamInfoRequest{}
+		if *subjects {
+			req.SubjectsFilter = ">"
+		}
+		info, err := js.StreamInfo(name, req, nats.Context(ctx))
+		if err != nil {
+			return err
+		}
+		return printStreamInfo(os.Stdout, info)
+	case "consumers":
+		name, err := streamArg(false)
+		if err != nil {
+			return err
+		}
+		consumers, err := listConsumers(ctx, js, name)
+		if err != nil {
+			return err
+		}
+		return printConsumers(os.Stdout, consumers, false)
+	case "lag":
+		name, err := streamArg(true)
+		if err != nil {
+			return err
+		}
+		names := []string{name}
+		if name == "" {
+			streams, err := listStreams(ctx, js)
+			if err != nil {
+				return err
+			}
+			names = names[:0]
+			for _, info := range streams {
+				names = append(names, info.Config.Name)
+			}
+		}
+		var behind []*nats.ConsumerInfo
+		for _, name := range names {
+			consumers, err := listConsumers(ctx, js, name)
+			if err != nil {
+				return err
+			}
+			for _, c := range consumers {
+				if c.NumPending+uint64(c.NumAckPending) >= *minPending {
+					behind = append(behind, c)
+				}
+			}
+		}
+		sort.SliceStable(behind, func(i, j int) bool {
+			return behind[i].NumPending+uint64(behind[i].NumAckPending) > behind[j].NumPending+uint64(behind[j].NumAckPending)
+		})
+		if len(behind) == 0 {
+			fmt.Println("No consumer is behind")
+			return nil
+		}
+		return printConsumers(os.Stdout, behind, true)
+	default:
+		fs.Usage()
+		return fmt.Errorf("unknown streams command %q", cmd)
+	}
+}
+
+// listStreams returns the streams sorted by name
+func listStreams(ctx context.Context, js nats.JetStreamContext) ([]*nats.StreamInfo, error) {
+	var streams []*nats.StreamInfo
+	for info := range js.StreamsInfo(nats.Context(ctx)) {
+		streams = append(streams, info)
+	}
+	if err := ctx.Err(); err != nil {
+		return nil, fmt.Errorf("listing streams: %w", err)
+	}
+	sort.Slice(streams, func(i, j int) bool { return streams[i].Config.Name < streams[j].Config.Name })
+	return streams, nil
+}
+
+// listConsumers returns the consumers of stream sorted by name
+func listConsumers(ctx context.Context, js nats.JetStreamContext, stream string) ([]*nats.ConsumerInfo, error) {
+	if _, err := js.StreamInfo(stream, nats.Context(ctx)); err != nil {
+		return nil, fmt.Errorf("stream %s: %w", stream, err)
+	}
+	var consumers []*nats.ConsumerInfo
+	for info := range js.ConsumersInfo(stream, nats.Context(ctx)) {
+		consumers = append(consumers, info)
+	}
+	if err := ctx.Err(); err != nil {
+		return nil, fmt.Errorf("listing consumers of %s: %w", stream, err)
+	}
+	sort.Slice(consumers, func(i, j int) bool { return consumers[i].Name < consumers[j].Name })
+	return consumers, nil
+}
+
+func printStreams(out io.Writer, streams []*nats.StreamInfo) error {
+	if len(streams) == 0 {
+		fmt.Fprintln(out, "No streams")
+		return nil
+	}
+	w := tabwriter.NewWriter(out, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "STREAM\tSUBJECTS\tSTORAGE\tMESSAGES\tSIZE\tOLDEST\tNEWEST\tCONSUMERS\tMAX AGE\tMAX SIZE")
+	for _, info := range streams {
+		c, st := info.Config, info.State
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\t%s\t%s\t%d\t%s\t%s\n",
+			c.Name, strings.Join(c.Subjects, ","), c.Storage, humanCount(st.Msgs), humanBytes(st.Bytes),
+			ago(st.FirstTime), ago(st.LastTime), st.Consumers, maxAge(c.MaxAge), limitBytes(c.MaxBytes))
+	}
+	return w.Flush()
+}
+
+func printStreamInfo(out io.Writer, info *nats.StreamInfo) error {
+	c, st := info.Config, info.State
+	w := tabwriter.NewWriter(out, 0, 0, 2, ' ', 0)
+	row := func(key string, value any) { fmt.Fprintf(w, "%s:\t%v\n", key, value) }
+	row("Stream", c.Name)
+	if c.Description != "" {
+		row("Description", c.Description)
+	}
+	row("Subjects", strings.Join(c.Subjects, ", "))
+	row("Storage", fmt.Sprintf("%s, %d replicas", c.Storage, c.Replicas))
+	row("Retention", fmt.Sprintf("%s, discard %s", c.Retention, c.Discard))
+	row("Max age", maxAge(c.MaxAge))
+	row("Max size", limitBytes(c.MaxBytes))
+	row("Max messages", limitCount(c.MaxMsgs))
+	row("Duplicate window", c.Duplicates)
+	if len(c.Sources) > 0 {
+		sources := make([]string, len(c.Sources))
+		for i, source := range c.Sources {
+			sources[i] = source.Name
+		}
+		row("Sources", strings.Join(sources, ", "))
+	}
+	fmt.Fprintln(w)
+	row("Messages", humanCount(st.Msgs))
+	row("Size", humanBytes(st.Bytes))
+	row("First", fmt.Sprintf("#%d, %s", st.FirstSeq, ago(st.FirstTime)))
+	row("Last", fmt.Sprintf("#%d, %s", st.LastSeq, ago(st.LastTime)))
+	if st.NumDeleted > 0 {
+		row("Deleted", humanCount(uint64(st.NumDeleted)))
+	}
+	row("Subjects stored", st.NumSubjects)
+	row("Consumers", st.Consumers)
+	if info.Cluster != nil && info.Cluster.Leader != "" {
+		row("Leader", info.Cluster.Leader)
+	}
+	if err := w.Flush(); err != nil {
+		return err
+	}
+
+	if len(st.Subjects) > 0 {
+		subjects := make([]string, 0, len(st.Subjects))
+		for subject := range st.Subjects {
+			subjects = append(subjects, subject)
+		}
+		sort.Strings(subjects)
+		fmt.Fprintln(out)
+		w = tabwriter.NewWriter(out, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(w, "SUBJECT\tMESSAGES")
+		for _, subject := range subjects {
+			fmt.Fprintf(w, "%s\t%s\n", subject, humanCount(st.Subjects[subject]))
+		}
+		return w.Flush()
+	}
+	return nil
+}
+
+// printConsumers prints the progress of consumers, with their stream when
+// they come from several
+func printConsumers(out io.Writer, consumers []*nats.ConsumerInfo, withStream bool) error {
+	if len(consumers) == 0 {
+		fmt.Fprintln(out, "No consumers")
+		return nil
+	}
+	w := tabwriter.NewWriter(out, 0, 0, 2, ' ', 0)
+	header := "CONSUMER\tFILTER\tPENDING\tACK PENDING\tREDELIVERED\tWAITING\tACK FLOOR\tDELIVERED\tLAST ACTIVE"
+	if withStream {
+		header = "STREAM\t" + header
+	}
+	fmt.Fprintln(w, header)
+	for _, c := range consumers {
+		filter := c.Config.FilterSubject
+		if len(c.Config.FilterSubjects) > 0 {
+			filter = strings.Join(c.Config.FilterSubjects, ",")
+		}
+		if filter == "" {
+			filter = "-"
+		}
+		name := c.Name
+		if c.Config.Durable == "" {
+			name += " (ephemeral)"
+		}
+		if withStream {
+			fmt.Fprintf(w, "%s\t", c.Stream)
+		}
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\t%d\t#%d\t#%d\t%s\n",
+			name, filter, humanCount(c.NumPending), humanCount(uint64(c.NumAckPending)), humanCount(uint64(c.NumRedelivered)),
+			c.NumWaiting, c.AckFloor.Stream, c.Delivered.Stream, ago(lastActive(c)))
+	}
+	return w.Flush()
+}
+
+// lastActive returns the last time messages were delivered or acknowledged
+func lastActive(c *nats.ConsumerInfo) time.Time {
+	var last time.Time
+	for _, t := range []*time.Time{c.Delivered.Last, c.AckFloor.Last} {
+		if t != nil && t.After(last) {
+			last = *t
+		}
+	}
+	return last
+}
+
+// ago formats the time elapsed since t, - when unset
+func ago(t time.Time) string {
+	if t.IsZero() || t.Unix() <= 0 {
+		return "-"
+	}
+	d := time.Since(t)
+	switch {
+	case d < time.Second:
+		return "now"
+	case d < time.Minute:
+		return fmt.Sprintf("%ds ago", int(d.Seconds()))
+	case d < time.Hour:
+		return fmt.Sprintf("%dm ago", int(d.Minutes()))
+	case d < 48*time.Hour:
+		return fmt.Sprintf("%dh%02dm ago", int(d.Hours()), int(d.Minutes())%60)
+	default:
+		return fmt.Sprintf("%dd ago", int(d.Hours()/24))
+	}
+}
+
+// humanCount formats a count with thousands separators
+func humanCount(n uint64) string {
+	s := fmt.Sprint(n)
+	for i := len(s) - 3; i > 0; i -= 3 {
+		s = s[:i] + "," + s[i:]
+	}
+	return s
+}
+
+// humanBytes formats a size in binary units
+func humanBytes(n uint64) string {
+	const unit = 1024
+	if n < unit {
+		return fmt.Sprintf("%d B", n)
+	}
+	div, exp := uint64(unit), 0
+	for m := n / unit; m >= unit; m /= unit {
+		div *= unit
+		exp++
+	}
+	return fmt.Sprintf("%.1f %ciB", float64(n)/float64(div), "KMGTPE"[exp])
+}
+
+func maxAge(d time.Duration) string {
+	if d <= 0 {
+		return "unlimited"
+	}
+	if d%(24*time.Hour) == 0 {
+		return fmt.Sprintf("%dd", d/(24*time.Hour))
+	}
+	return d.String()
+}
+
+func limitBytes(n int64) string {
+	if n <= 0 {
+		return "unlimited"
+	}
+	return humanBytes(uint64(n))
+}
+
+func limitCount(n int64) string {
+	if n <= 0 {
+		return "unlimited"
+	}
+	return humanCount(uint64(n))
+}
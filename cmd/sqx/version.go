@@ -0,0 +1,109 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/BullionBear/sequex/pkg/diagnostics"
+	"github.com/nats-io/nats.go"
+)
+
+// runVersion gathers the versions the services answer on req_version
+func runVersion(s *session, args []string) error {
+	fs := flag.NewFlagSet("version", flag.ExitOnError)
+	server := fs.String("s", s.server(), "NATS server URLs, comma separated, those of the context by default")
+	wait := fs.Duration("wait", 2*time.Second, "time to wait for the services to answer")
+	asJSON := fs.Bool("json", false, "print the answers as JSON")
+	fs.Usage = func() {
+		fmt.Fprintf(os.Stderr, `Usage: sqx version [options] [service]
+
+Prints the version, commit and build of every running service instance, or
+of the instances of a service, and of sqx itself.
+
+Options:
+`)
+		fs.PrintDefaults()
+	}
+	fs.Parse(args)
+	if fs.NArg() > 1 {
+		fs.Usage()
+		return fmt.Errorf("at most one service is expected")
+	}
+	subject := diagnostics.VersionSubject
+	if fs.NArg() == 1 {
+		subject += "." + fs.Arg(0)
+	}
+
+	conn, err := s.connect(*server)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+	inbox := nats.NewInbox()
+	sub, err := conn.SubscribeSync(inbox)
+	if err != nil {
+		return err
+	}
+	defer sub.Unsubscribe()
+	if err := conn.PublishRequest(subject, inbox, nil); err != nil {
+		return err
+	}
+
+	versions := []diagnostics.Version{diagnostics.NewVersion("sqx")}
+	deadline := time.Now().Add(*wait)
+	for {
+		msg, err := sub.NextMsg(time.Until(deadline))
+		if errors.Is(err, nats.ErrTimeout) {
+			break
+		}
+		if err != nil {
+			return err
+		}
+		var v diagnostics.Version
+		if err := json.Unmarshal(msg.Data, &v); err != nil {
+			fmt.Fprintf(os.Stderr, "Skipped an invalid answer: %v\n", err)
+			continue
+		}
+		versions = append(versions, v)
+	}
+	sort.SliceStable(versions[1:], func(i, j int) bool {
+		a, b := versions[1+i], versions[1+j]
+		return a.Service < b.Service || (a.Service == b.Service && a.Host < b.Host)
+	})
+
+	if *asJSON {
+		data, err := json.MarshalIndent(versions, "", "  ")
+		if err != nil {
+			return err
+		}
+		fmt.Println(string(data))
+		return nil
+	}
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "SERVICE\tHOST\tVERSION\tCOMMIT\tDIRTY\tBUILT\tGO\tPLATFORM\tTAGS\tFEATURES")
+	for _, v := range versions {
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%t\t%s\t%s\t%s\t%s\t%s\n", v.Service, v.Host, orDash(v.Version), orDash(v.CommitHash),
+			v.Dirty, orDash(v.BuildTime), v.GoVersion, v.Platform, orDash(strings.Join(v.Tags, ",")), orDash(strings.Join(v.Features, ",")))
+	}
+	if err := w.Flush(); err != nil {
+		return err
+	}
+	if len(versions) == 1 {
+		return fmt.Errorf("no service answered on %s", subject)
+	}
+	return nil
+}
+
+func orDash(s string) string {
+	if s == "" {
+		return "-"
+	}
+	return s
+}
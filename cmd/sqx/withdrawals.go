@@ -0,0 +1,247 @@
+package main
+
+import (
+	"context"
+	"crypto/ed25519"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/hex"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/BullionBear/sequex/internal/model/protobuf"
+	"github.com/BullionBear/sequex/internal/withdrawal"
+)
+
+// runWithdrawals lists, requests, approves and rejects withdrawals
+func runWithdrawals(s *session, args []string) error {
+	fs := flag.NewFlagSet("withdrawals", flag.ExitOnError)
+	server := fs.String("s", s.server(), "NATS server URLs, comma separated, those of the context by default")
+	subject := fs.String("subject", withdrawal.DefaultSubject, "prefix the withdrawal RPCs are served under")
+	timeout := fs.Duration("timeout", 30*time.Second, "time to wait for the withdrawal node to answer, sending included")
+	by := fs.String("by", os.Getenv("USER"), "approver signing the request")
+	keyFile := fs.String("key", os.Getenv("SQX_WITHDRAWAL_KEY"), "file of the private key of the approver, $SQX_WITHDRAWAL_KEY by default")
+	asset := fs.String("asset", "", "asset withdrawn")
+	network := fs.String("network", "", "network of the address")
+	address := fs.String("address", "", "whitelisted address withdrawn to")
+	tag := fs.String("tag", "", "memo of the address, for the networks needing one")
+	amount := fs.Float64("amount", 0, "amount withdrawn")
+	reason := fs.String("reason", "", "why the withdrawal is requested or rejected, required")
+	fs.Usage = func() {
+		fmt.Fprintf(os.Stderr, `Usage: sqx withdrawals [options] <command> [arguments]
+
+Withdrawals only go to whitelisted addresses, within daily limits, once
+signed by enough approvers: the requester and at least another one. Every
+request, approval, rejection and result is recorded in the audit log of the
+withdrawal node.
+
+Commands:
+  ls                   List the withdrawals, the whitelist and the limits
+  submit               Request a withdrawal, signed as its first approval
+  approve <id>         Sign a pending withdrawal, sent once approved
+  reject <id>          Reject a pending withdrawal
+  keygen               Write a new private key to -key and print its public key
+
+Options:
+`)
+		fs.PrintDefaults()
+	}
+	fs.Parse(args)
+	if fs.NArg() == 0 {
+		fs.Usage()
+		return fmt.Errorf("a command is required")
+	}
+	// Options are accepted before and after the command
+	cmd := fs.Arg(0)
+	fs.Parse(fs.Args()[1:])
+	switch cmd {
+	case "keygen":
+		return keygen(*keyFile, os.Stdout)
+	case "ls", "list":
+	case "submit":
+		if *asset == "" || *network == "" || *address == "" || *amount <= 0 {
+			return fmt.Errorf("-asset, -network, -address and a positive -amount are required")
+		}
+		if *reason == "" {
+			return fmt.Errorf("-reason is required to submit a withdrawal")
+		}
+	case "approve", "reject":
+		if fs.NArg() != 1 {
+			fs.Usage()
+			return fmt.Errorf("a withdrawal id is required")
+		}
+		if cmd == "reject" && *reason == "" {
+			return fmt.Errorf("-reason is required to reject a withdrawal")
+		}
+	default:
+		fs.Usage()
+		return fmt.Errorf("unknown withdrawals command %q", cmd)
+	}
+	var key ed25519.PrivateKey
+	if cmd != "ls" && cmd != "list" {
+		var err error
+		if key, err = readKey(*keyFile); err != nil {
+			return err
+		}
+	}
+
+	conn, err := s.connect(*server)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+	bus, err := s.newBus(conn)
+	if err != nil {
+		return err
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), *timeout)
+	defer cancel()
+	client := protobuf.NewWithdrawalServiceClient(bus, *subject)
+
+	if cmd == "submit" {
+		w := withdrawal.Withdrawal{ID: newWithdrawalID(), Asset: *asset, Network: *network, Address: *address, Tag: *tag, Amount: *amount, RequestedBy: *by, Reason: *reason}
+		if err := s.authorize("withdrawals submit", os.Stdin, os.Stderr); err != nil {
+			return err
+		}
+		submitted, err := client.Submit(ctx, &protobuf.SubmitWithdrawalRequest{Withdrawal: w.ToProtobuf(), Signature: withdrawal.Sign(key, withdrawal.ActionSubmit, w)})
+		if err != nil {
+			return err
+		}
+		fmt.Printf("Withdrawal %s of %g %s to %s submitted, waiting for approval\n", submitted.Id, submitted.Amount, submitted.Asset, orDash(submitted.Label))
+		return nil
+	}
+	list, err := client.List(ctx, &protobuf.ListWithdrawalsRequest{})
+	if err != nil {
+		return err
+	}
+	if cmd == "ls" || cmd == "list" {
+		return printWithdrawals(os.Stdout, list)
+	}
+
+	// Approvers sign the withdrawal as the node holds it, after reviewing it
+	var w *withdrawal.Withdrawal
+	for _, pb := range list.Withdrawals {
+		if pb.Id == fs.Arg(0) {
+			w = &withdrawal.Withdrawal{}
+			if err := w.FromProtobuf(pb); err != nil {
+				return err
+			}
+		}
+	}
+	if w == nil {
+		return fmt.Errorf("withdrawal %s not found", fs.Arg(0))
+	}
+	fmt.Fprintf(os.Stderr, "Withdrawal %s: %g %s on %s to %s (%s), requested by %s: %s\n",
+		w.ID, w.Amount, w.Asset, w.Network, w.Address, orDash(w.Label), w.RequestedBy, orDash(w.Reason))
+	if err := s.authorize("withdrawals "+cmd, os.Stdin, os.Stderr); err != nil {
+		return err
+	}
+	if cmd == "reject" {
+		r, err := client.Reject(ctx, &protobuf.RejectWithdrawalRequest{Id: w.ID, By: *by, Reason: *reason, Signature: withdrawal.Sign(key, withdrawal.ActionReject, *w)})
+		if err != nil {
+			return err
+		}
+		fmt.Printf("Withdrawal %s rejected\n", r.Id)
+		return nil
+	}
+	a, err := client.Approve(ctx, &protobuf.ApproveWithdrawalRequest{Id: w.ID, By: *by, Signature: withdrawal.Sign(key, withdrawal.ActionApprove, *w)})
+	if err != nil {
+		return err
+	}
+	switch withdrawal.Status(a.Status) {
+	case withdrawal.StatusSent:
+		fmt.Printf("Withdrawal %s approved and sent, exchange id %s\n", a.Id, a.ExchangeId)
+	case withdrawal.StatusFailed:
+		return fmt.Errorf("withdrawal %s approved but not sent: %s", a.Id, a.Error)
+	default:
+		fmt.Printf("Withdrawal %s approved, %d of %d signatures\n", a.Id, len(a.Approvals), list.Approvals)
+	}
+	return nil
+}
+
+// newWithdrawalID returns a random id, used as the client id of the
+// withdrawal on the exchange
+func newWithdrawalID() string {
+	b := make([]byte, 8)
+	rand.Read(b)
+	return "w" + hex.EncodeToString(b)
+}
+
+// keygen writes a new base64 private key to path, refusing to overwrite
+// one, and prints its public key for the configuration of the node
+func keygen(path string, out io.Writer) error {
+	if path == "" {
+		return fmt.Errorf("-key is required")
+	}
+	public, private, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		return err
+	}
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0o600)
+	if err != nil {
+		return fmt.Errorf("failed to create key file: %w", err)
+	}
+	if _, err := f.WriteString(base64.StdEncoding.EncodeToString(private) + "\n"); err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Close(); err != nil {
+		return err
+	}
+	fmt.Fprintf(out, "Private key written to %s\nPublic key: %s\n", path, base64.StdEncoding.EncodeToString(public))
+	return nil
+}
+
+// readKey reads a base64 private key written by keygen
+func readKey(path string) (ed25519.PrivateKey, error) {
+	if path == "" {
+		return nil, fmt.Errorf("-key or $SQX_WITHDRAWAL_KEY is required to sign withdrawals")
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read key file: %w", err)
+	}
+	key, err := base64.StdEncoding.DecodeString(strings.TrimSpace(string(data)))
+	if err != nil || len(key) != ed25519.PrivateKeySize {
+		return nil, fmt.Errorf("%s is not a base64 ed25519 private key", path)
+	}
+	return ed25519.PrivateKey(key), nil
+}
+
+func printWithdrawals(out io.Writer, list *protobuf.WithdrawalList) error {
+	fmt.Fprintf(out, "%d signatures needed from %s\n\n", list.Approvals, strings.Join(list.Approvers, ", "))
+	w := tabwriter.NewWriter(out, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "ASSET\tLIMIT\tUSED TODAY")
+	for _, l := range list.Limits {
+		fmt.Fprintf(w, "%s\t%g\t%g\n", l.Asset, l.Limit, l.Used)
+	}
+	fmt.Fprintln(w)
+	fmt.Fprintln(w, "LABEL\tASSET\tNETWORK\tADDRESS\tTAG")
+	for _, a := range list.Whitelist {
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\n", orDash(a.Label), a.Asset, a.Network, a.Address, orDash(a.Tag))
+	}
+	if err := w.Flush(); err != nil {
+		return err
+	}
+	fmt.Fprintln(out)
+	if len(list.Withdrawals) == 0 {
+		fmt.Fprintln(out, "No withdrawal")
+		return nil
+	}
+	w = tabwriter.NewWriter(out, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "ID\tSTATUS\tAMOUNT\tASSET\tTO\tREQUESTED\tAPPROVED BY\tEXCHANGE ID\tERROR")
+	for _, wd := range list.Withdrawals {
+		names := make([]string, 0, len(wd.Approvals))
+		for _, a := range wd.Approvals {
+			names = append(names, a.By)
+		}
+		fmt.Fprintf(w, "%s\t%s\t%g\t%s\t%s\t%s\t%s\t%s\t%s\n", wd.Id, wd.Status, wd.Amount, wd.Asset, orDash(wd.Label),
+			time.UnixMilli(wd.CreatedAt).Local().Format(time.DateTime), strings.Join(names, ","), orDash(wd.ExchangeId), orDash(wd.Error))
+	}
+	return w.Flush()
+}
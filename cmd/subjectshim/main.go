@@ -0,0 +1,116 @@
+package main
+
+import (
+	"flag"
+	"os"
+	"syscall"
+	"time"
+
+	"github.com/BullionBear/sequex/env"
+	"github.com/BullionBear/sequex/internal/config"
+	"github.com/BullionBear/sequex/pkg/diagnostics"
+	"github.com/BullionBear/sequex/pkg/eventbus"
+	"github.com/BullionBear/sequex/pkg/logger"
+	"github.com/BullionBear/sequex/pkg/shutdown"
+	"github.com/nats-io/nats.go"
+)
+
+// runSubjectShim executes the main subject shim logic
+func runSubjectShim(configFile string) {
+	logger.Log.Info().
+		Str("version", env.Version).
+		Str("buildTime", env.BuildTime).
+		Str("commitHash", env.CommitHash).
+		Msg("Subject shim started")
+
+	cfg, err := config.LoadSubjectShimConfig(configFile)
+	if err != nil {
+		logger.Log.Error().Err(err).Msg("Failed to load config")
+		os.Exit(1)
+	}
+
+	shutdown := shutdown.NewShutdown(logger.Log)
+
+	natsConn, err := nats.Connect(cfg.NATS.URIs)
+	if err != nil {
+		logger.Log.Error().Err(err).Msg("Failed to connect to NATS")
+		os.Exit(1)
+	}
+	defer natsConn.Close()
+
+	diag, stopDiagnostics, err := diagnostics.Start(cfg.Diagnostics.Options("subjectshim"), natsConn, logger.Log)
+	if err != nil {
+		logger.Log.Error().Err(err).Msg("Failed to start diagnostics")
+		os.Exit(1)
+	}
+	shutdown.HookShutdownCallback("diagnostics", stopDiagnostics, 10*time.Second)
+
+	mapper := eventbus.LegacyMapper{
+		Env:               cfg.Env,
+		DefaultExchange:   cfg.DefaultExchange,
+		DefaultInstrument: cfg.DefaultInstrument,
+	}
+
+	handler := func(msg *nats.Msg) {
+		// Never republish namespaced subjects, which would loop on broad patterns
+		if eventbus.IsNamespaced(cfg.Env, msg.Subject) {
+			return
+		}
+		subject, err := mapper.Map(msg.Subject)
+		if err != nil {
+			logger.Log.Warn().Err(err).Str("subject", msg.Subject).Msg("Skipping legacy subject")
+			return
+		}
+		out := &nats.Msg{Subject: subject.String(), Data: msg.Data, Header: msg.Header}
+		if err := natsConn.PublishMsg(out); err != nil {
+			logger.Log.Error().Err(err).Str("subject", out.Subject).Msg("Failed to republish")
+		}
+	}
+
+	for _, pattern := range cfg.Legacy {
+		sub, err := natsConn.Subscribe(pattern, handler)
+		if err != nil {
+			logger.Log.Error().Err(err).Str("subject", pattern).Msg("Failed to subscribe to legacy subject")
+			os.Exit(1)
+		}
+		diag.AddQueueSource(diagnostics.SubscriptionSource(sub))
+		shutdown.HookShutdownCallback("unsubscribe "+pattern, func() {
+			if err := sub.Unsubscribe(); err != nil {
+				logger.Log.Error().Err(err).Str("subject", pattern).Msg("Failed to unsubscribe")
+			}
+		}, 10*time.Second)
+		logger.Log.Info().Str("legacy", pattern).Str("env", cfg.Env).Msg("Republishing legacy subjects")
+	}
+
+	shutdown.WaitForShutdown(syscall.SIGINT, syscall.SIGTERM)
+	logger.Log.Info().Msg("Subject shim command executed successfully!")
+}
+
+func main() {
+	var configFile string
+	flag.StringVar(&configFile, "c", "", "Configuration file path (required)")
+
+	flag.Usage = func() {
+		logger.Log.Info().Msg(`Subject shim republishes legacy subjects (trade.btcusdt,
+trade.binance.spot.btcusdt) to the env.exchange.instrument.symbol.datatype
+namespace while services migrate.
+
+Usage:
+  subjectshim -c <config-file>
+
+Examples:
+  subjectshim -c config/subjectshim.json
+`)
+		flag.PrintDefaults()
+	}
+
+	flag.Parse()
+
+	if configFile == "" {
+		logger.Log.Error().Msg("config file path is required")
+		flag.Usage()
+		os.Exit(1)
+	}
+
+	runSubjectShim(configFile)
+}
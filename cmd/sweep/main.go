@@ -0,0 +1,145 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"os"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/BullionBear/sequex/env"
+	"github.com/BullionBear/sequex/internal/config"
+	"github.com/BullionBear/sequex/internal/sweep"
+	"github.com/BullionBear/sequex/pkg/diagnostics"
+	"github.com/BullionBear/sequex/pkg/eventbus"
+	"github.com/BullionBear/sequex/pkg/logger"
+	"github.com/BullionBear/sequex/pkg/shutdown"
+	"github.com/nats-io/nats.go"
+)
+
+// runSweep executes the main wallet sweeper logic
+func runSweep(configFile string, dryRun bool) {
+	logger.Log.Info().
+		Str("version", env.Version).
+		Str("buildTime", env.BuildTime).
+		Str("commitHash", env.CommitHash).
+		Msg("Wallet sweeper started")
+
+	cfg, err := config.LoadSweepConfig(configFile)
+	if err != nil {
+		logger.Log.Error().Err(err).Msg("Failed to load config")
+		os.Exit(1)
+	}
+	sweeper, err := cfg.Sweeper(dryRun)
+	if err != nil {
+		logger.Log.Error().Err(err).Msg("Invalid configuration")
+		os.Exit(1)
+	}
+
+	shutdown := shutdown.NewShutdown(logger.Log)
+
+	natsConn, err := nats.Connect(cfg.NATS.URIs)
+	if err != nil {
+		logger.Log.Error().Err(err).Msg("Failed to connect to NATS")
+		os.Exit(1)
+	}
+	defer natsConn.Close()
+
+	_, stopDiagnostics, err := diagnostics.Start(cfg.Diagnostics.Options("sweep"), natsConn, logger.Log)
+	if err != nil {
+		logger.Log.Error().Err(err).Msg("Failed to start diagnostics")
+		os.Exit(1)
+	}
+	shutdown.HookShutdownCallback("diagnostics", stopDiagnostics, 10*time.Second)
+	busOpts, err := cfg.NATS.BusOptions()
+	if err != nil {
+		logger.Log.Error().Err(err).Msg("Invalid event bus options")
+		os.Exit(1)
+	}
+	bus, err := eventbus.NewBus(natsConn, busOpts)
+	if err != nil {
+		logger.Log.Error().Err(err).Msg("Failed to create event bus")
+		os.Exit(1)
+	}
+
+	// Transfers are logged and published whether made, planned in dry run or failed
+	onTransfer := func(t sweep.Transfer) {
+		event := logger.Log.Info()
+		if t.Error != "" {
+			event = logger.Log.Error().Str("error", t.Error)
+		}
+		event.
+			Str("rule", t.Rule).
+			Str("direction", t.Direction).
+			Float64("amount", t.Amount).
+			Float64("balance", t.Balance).
+			Float64("funding", t.Funding).
+			Bool("dryRun", t.DryRun).
+			Int64("tranId", t.TranId).
+			Msg("Wallet transfer")
+		data, err := json.Marshal(t)
+		if err != nil {
+			logger.Log.Error().Err(err).Msg("Failed to marshal transfer")
+			return
+		}
+		subject := cfg.NATS.Subject + "." + string(t.Wallet) + "." + strings.ToLower(t.Asset)
+		if err := bus.Publish(subject, data); err != nil {
+			logger.Log.Error().Err(err).Str("subject", subject).Msg("Failed to publish transfer")
+		}
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		sweeper.Run(ctx, cfg.Interval(), onTransfer, func(err error) {
+			logger.Log.Error().Err(err).Msg("Failed to read wallet balances")
+		})
+	}()
+	logger.Log.Info().Dur("interval", cfg.Interval()).Int("rules", len(cfg.Rules)).Bool("dryRun", sweeper.DryRun()).Msg("Sweeping wallets")
+	shutdown.HookShutdownCallback("sweeper", func() {
+		cancel()
+		wg.Wait()
+	}, 10*time.Second)
+
+	shutdown.WaitForShutdown(syscall.SIGINT, syscall.SIGTERM)
+	logger.Log.Info().Msg("Wallet sweeper command executed successfully!")
+}
+
+func main() {
+	var configFile string
+	var dryRun bool
+	flag.StringVar(&configFile, "c", "", "Configuration file path (required)")
+	flag.BoolVar(&dryRun, "dry-run", false, "Log and publish the transfers without making them")
+
+	flag.Usage = func() {
+		logger.Log.Info().Msg(`Sweep keeps the trading wallets of a Binance account topped up from its
+funding wallet with universal transfers. Each rule keeps an asset of the
+spot or USDⓈ-M wallet between a minimum and a maximum: below the minimum the
+balance is raised to the target, above the maximum the excess is swept back
+to the funding wallet. Rules run every interval or at their times of day,
+and every transfer is published to NATS as <subject>.<wallet>.<asset>.
+
+Usage:
+  sweep -c <config-file> [-dry-run]
+
+Examples:
+  sweep -c config/sweep.json -dry-run
+`)
+		flag.PrintDefaults()
+	}
+
+	flag.Parse()
+
+	if configFile == "" {
+		logger.Log.Error().Msg("config file path is required")
+		flag.Usage()
+		os.Exit(1)
+	}
+
+	runSweep(configFile, dryRun)
+}
@@ -0,0 +1,262 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io/fs"
+	"net/http"
+	"os"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/BullionBear/sequex/api"
+	"github.com/BullionBear/sequex/env"
+	"github.com/BullionBear/sequex/internal/config"
+	"github.com/BullionBear/sequex/internal/sysevent"
+	"github.com/BullionBear/sequex/pkg/diagnostics"
+	"github.com/BullionBear/sequex/pkg/eventbus"
+	"github.com/BullionBear/sequex/pkg/logger"
+	"github.com/BullionBear/sequex/pkg/shutdown"
+	"github.com/gin-gonic/gin"
+	"github.com/nats-io/nats.go"
+)
+
+const (
+	batchSize     = 100
+	fetchWait     = 2 * time.Second
+	retryBackoff  = time.Second
+	pruneInterval = time.Hour
+)
+
+// runSysEvents executes the main system event collector logic
+func runSysEvents(configFile string) {
+	logger.Log.Info().
+		Str("version", env.Version).
+		Str("buildTime", env.BuildTime).
+		Str("commitHash", env.CommitHash).
+		Msg("System event collector started")
+
+	cfg, err := config.LoadSysEventsConfig(configFile)
+	if err != nil {
+		logger.Log.Error().Err(err).Msg("Failed to load config")
+		os.Exit(1)
+	}
+
+	store, err := sysevent.OpenStore(cfg.Dir, cfg.Retention())
+	if err != nil {
+		logger.Log.Error().Err(err).Msg("Failed to open event store")
+		os.Exit(1)
+	}
+	logger.Log.Info().Str("dir", cfg.Dir).Int("events", store.Len()).Dur("retention", cfg.Retention()).Msg("Loaded events")
+
+	shutdown := shutdown.NewShutdown(logger.Log)
+
+	natsConn, err := nats.Connect(cfg.NATS.URIs)
+	if err != nil {
+		logger.Log.Error().Err(err).Msg("Failed to connect to NATS")
+		os.Exit(1)
+	}
+	defer natsConn.Close()
+
+	_, stopDiagnostics, err := diagnostics.Start(cfg.Diagnostics.Options("sysevents"), natsConn, logger.Log)
+	if err != nil {
+		logger.Log.Error().Err(err).Msg("Failed to start diagnostics")
+		os.Exit(1)
+	}
+	shutdown.HookShutdownCallback("diagnostics", stopDiagnostics, 10*time.Second)
+	js, err := natsConn.JetStream()
+	if err != nil {
+		logger.Log.Error().Err(err).Msg("Failed to create JetStream context")
+		os.Exit(1)
+	}
+	if _, err := js.StreamInfo(cfg.NATS.Stream); err != nil {
+		logger.Log.Error().Err(err).Msg("Failed to get stream info")
+		os.Exit(1)
+	}
+	busOpts, err := cfg.NATS.BusOptions()
+	if err != nil {
+		logger.Log.Error().Err(err).Msg("Invalid event bus options")
+		os.Exit(1)
+	}
+	bus, err := eventbus.NewBus(natsConn, busOpts)
+	if err != nil {
+		logger.Log.Error().Err(err).Msg("Failed to create event bus")
+		os.Exit(1)
+	}
+
+	// The durable consumer resumes after the last event stored, so none is
+	// missed while the collector is down
+	durable := cfg.Durable
+	if durable == "" {
+		durable = "sysevents"
+	}
+	filter := cfg.NATS.Subject + ".>"
+	sub, err := js.PullSubscribe(filter, durable, nats.BindStream(cfg.NATS.Stream), nats.AckExplicit())
+	if err != nil {
+		logger.Log.Error().Err(err).Str("subject", filter).Msg("Failed to create pull subscription")
+		os.Exit(1)
+	}
+	done := make(chan struct{})
+	var wg sync.WaitGroup
+	wg.Add(2)
+	go func() {
+		defer wg.Done()
+		collect(sub, store, done)
+	}()
+	go func() {
+		defer wg.Done()
+		prune(store, done)
+	}()
+	logger.Log.Info().Str("subject", filter).Str("durable", durable).Msg("Collecting system events")
+	shutdown.HookShutdownCallback("collector", func() {
+		close(done)
+		wg.Wait()
+		if err := store.Close(); err != nil {
+			logger.Log.Error().Err(err).Msg("Failed to close event store")
+		}
+	}, 10*time.Second)
+
+	subject := cfg.RPCSubject
+	if subject == "" {
+		subject = sysevent.DefaultSubject
+	}
+	subs, err := sysevent.Serve(bus, subject, store)
+	if err != nil {
+		logger.Log.Error().Err(err).Str("subject", subject).Msg("Failed to serve system event RPCs")
+		os.Exit(1)
+	}
+	shutdown.HookShutdownCallback("unsubscribe "+subject, func() {
+		for _, sub := range subs {
+			if err := sub.Unsubscribe(); err != nil {
+				logger.Log.Error().Err(err).Str("subject", sub.Subject).Msg("Failed to unsubscribe")
+			}
+		}
+	}, 10*time.Second)
+	logger.Log.Info().Str("subject", subject).Msg("Serving system event RPCs")
+
+	rg := gin.New()
+	rg.Use(gin.Recovery())
+	rg.Use(api.AllowAllCors)
+	sysevent.Register(rg, store)
+	rg.GET("/healthz", func(c *gin.Context) {
+		c.JSON(http.StatusOK, gin.H{"events": store.Len()})
+	})
+	rg.GET("/version", gin.WrapF(diagnostics.VersionHandler("sysevents")))
+
+	server := &http.Server{
+		Addr:    fmt.Sprintf("%s:%d", cfg.Host, cfg.Port),
+		Handler: rg,
+	}
+	go func() {
+		logger.Log.Info().Str("addr", server.Addr).Msg("System event API listening")
+		if err := server.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			logger.Log.Error().Err(err).Msg("System event API server failed")
+			os.Exit(1)
+		}
+	}()
+
+	shutdown.HookShutdownCallback("http server", func() {
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		if err := server.Shutdown(ctx); err != nil {
+			logger.Log.Error().Err(err).Msg("Failed to shutdown http server")
+		}
+	}, 10*time.Second)
+
+	shutdown.WaitForShutdown(syscall.SIGINT, syscall.SIGTERM)
+	logger.Log.Info().Msg("System event collector command executed successfully!")
+}
+
+// collect pulls events from JetStream into the store, acknowledging each
+// once journaled so a crash in between leads to redelivery
+func collect(sub *nats.Subscription, store *sysevent.Store, done <-chan struct{}) {
+	for {
+		select {
+		case <-done:
+			return
+		default:
+		}
+
+		msgs, err := sub.Fetch(batchSize, nats.MaxWait(fetchWait))
+		if err != nil {
+			if !errors.Is(err, nats.ErrTimeout) {
+				logger.Log.Error().Err(err).Msg("Failed to fetch from JetStream")
+				time.Sleep(retryBackoff)
+			}
+			continue
+		}
+		for _, msg := range msgs {
+			added, err := store.HandleMsg(msg)
+			switch {
+			case errors.As(err, new(*fs.PathError)):
+				// The journal failed, have the event redelivered
+				logger.Log.Error().Err(err).Str("subject", msg.Subject).Msg("Failed to journal event")
+				msg.NakWithDelay(retryBackoff)
+				continue
+			case err != nil:
+				// An event that cannot be decoded never will be, skip it
+				logger.Log.Error().Err(err).Str("subject", msg.Subject).Msg("Failed to store event")
+			case added:
+				logger.Log.Debug().Str("subject", msg.Subject).Msg("Stored event")
+			}
+			if err := msg.Ack(); err != nil {
+				logger.Log.Error().Err(err).Str("subject", msg.Subject).Msg("Failed to ack message")
+			}
+		}
+	}
+}
+
+// prune drops the events past the retention every pruneInterval until done
+func prune(store *sysevent.Store, done <-chan struct{}) {
+	ticker := time.NewTicker(pruneInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-done:
+			return
+		case <-ticker.C:
+			dropped, err := store.Prune()
+			if err != nil {
+				logger.Log.Error().Err(err).Msg("Failed to prune events")
+			}
+			if dropped > 0 {
+				logger.Log.Info().Int("dropped", dropped).Int("events", store.Len()).Msg("Pruned events")
+			}
+		}
+	}
+}
+
+func main() {
+	var configFile string
+	flag.StringVar(&configFile, "c", "", "Configuration file path (required)")
+
+	flag.Usage = func() {
+		logger.Log.Info().Msg(`Sysevents collects the system events every service publishes to
+sys.events.<kind>, nodes starting and stopping, risk breaches, exchange
+halts, reconnects and dead-lettered messages, and keeps those of the last
+days so operators have one place to see everything that happened. Events
+are queried over REST (GET /events?kind=risk_breach&since=24h) and on the
+sysevents.query RPC, e.g. with sqx events.
+
+Usage:
+  sysevents -c <config-file>
+
+Examples:
+  sysevents -c config/sysevents.json
+`)
+		flag.PrintDefaults()
+	}
+
+	flag.Parse()
+
+	if configFile == "" {
+		logger.Log.Error().Msg("config file path is required")
+		flag.Usage()
+		os.Exit(1)
+	}
+
+	runSysEvents(configFile)
+}
@@ -0,0 +1,247 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/BullionBear/sequex/env"
+	"github.com/BullionBear/sequex/internal/config"
+	"github.com/BullionBear/sequex/internal/model/sqx"
+	"github.com/BullionBear/sequex/internal/tape"
+	"github.com/BullionBear/sequex/pkg/diagnostics"
+	"github.com/BullionBear/sequex/pkg/eventbus"
+	"github.com/BullionBear/sequex/pkg/logger"
+	"github.com/BullionBear/sequex/pkg/shutdown"
+	"github.com/nats-io/nats.go"
+)
+
+// runTape executes the main consolidated tape logic
+func runTape(configFile string) {
+	logger.Log.Info().
+		Str("version", env.Version).
+		Str("buildTime", env.BuildTime).
+		Str("commitHash", env.CommitHash).
+		Msg("Tape started")
+
+	cfg, err := config.LoadTapeConfig(configFile)
+	if err != nil {
+		logger.Log.Error().Err(err).Msg("Failed to load config")
+		os.Exit(1)
+	}
+
+	opts := tape.Options{
+		Delay:      time.Duration(cfg.DelayMs) * time.Millisecond,
+		SkewWindow: time.Duration(cfg.SkewWindowMs) * time.Millisecond,
+	}
+	for _, s := range cfg.Symbols {
+		symbol, err := sqx.NewSymbolFromStr(s)
+		if err != nil {
+			logger.Log.Error().Err(err).Str("symbol", s).Msg("Invalid symbol")
+			os.Exit(1)
+		}
+		opts.Symbols = append(opts.Symbols, symbol)
+	}
+	consolidator, err := tape.New(opts)
+	if err != nil {
+		logger.Log.Error().Err(err).Msg("Failed to create consolidator")
+		os.Exit(1)
+	}
+
+	shutdown := shutdown.NewShutdown(logger.Log)
+
+	natsConn, err := nats.Connect(cfg.NATS.URIs)
+	if err != nil {
+		logger.Log.Error().Err(err).Msg("Failed to connect to NATS")
+		os.Exit(1)
+	}
+	defer natsConn.Close()
+
+	diag, stopDiagnostics, err := diagnostics.Start(cfg.Diagnostics.Options("tape"), natsConn, logger.Log)
+	if err != nil {
+		logger.Log.Error().Err(err).Msg("Failed to start diagnostics")
+		os.Exit(1)
+	}
+	shutdown.HookShutdownCallback("diagnostics", stopDiagnostics, 10*time.Second)
+	js, err := natsConn.JetStream()
+	if err != nil {
+		logger.Log.Error().Err(err).Msg("Failed to create JetStream context")
+		os.Exit(1)
+	}
+	if _, err := js.StreamInfo(cfg.NATS.Stream); err != nil {
+		logger.Log.Error().Err(err).Msg("Failed to get stream info")
+		os.Exit(1)
+	}
+	busOpts, err := cfg.NATS.BusOptions()
+	if err != nil {
+		logger.Log.Error().Err(err).Msg("Invalid event bus options")
+		os.Exit(1)
+	}
+	bus, err := eventbus.NewBus(natsConn, busOpts)
+	if err != nil {
+		logger.Log.Error().Err(err).Msg("Failed to create event bus")
+		os.Exit(1)
+	}
+
+	subject := func(symbol sqx.Symbol) string {
+		return fmt.Sprintf("%s.%s", cfg.NATS.Subject, strings.ToLower(symbol.Base+symbol.Quote))
+	}
+
+	// Carry the sequences on from the last prints, so consumers see no gap
+	// nor reset across a restart
+	for _, symbol := range opts.Symbols {
+		last, err := lastPrint(js, cfg.NATS.Stream, subject(symbol))
+		if err != nil {
+			logger.Log.Error().Err(err).Str("symbol", symbol.String()).Msg("Failed to read last print")
+			os.Exit(1)
+		}
+		if last == nil {
+			continue
+		}
+		if err := consolidator.Resume(*last); err != nil {
+			logger.Log.Error().Err(err).Str("symbol", symbol.String()).Msg("Failed to resume tape")
+			os.Exit(1)
+		}
+		logger.Log.Info().Str("symbol", symbol.String()).Uint64("seq", last.Seq).Msg("Resumed tape")
+	}
+
+	// Prints are published under a lock so that each tape stays in sequence
+	// whichever goroutine flushes
+	var publishMu sync.Mutex
+	flush := func(now time.Time) {
+		publishMu.Lock()
+		defer publishMu.Unlock()
+		for _, p := range consolidator.Flush(now) {
+			data, err := p.Marshal()
+			if err != nil {
+				logger.Log.Error().Err(err).Msg("Failed to marshal tape print")
+				continue
+			}
+			msg := &nats.Msg{
+				Subject: subject(p.Symbol),
+				Data:    data,
+				Header: nats.Header{
+					"Nats-Msg-Id": []string{p.IdStr()},
+				},
+			}
+			if err := bus.Encode(msg); err != nil {
+				logger.Log.Error().Err(err).Str("print", p.IdStr()).Msg("Failed to encode tape print")
+				continue
+			}
+			if _, err := js.PublishMsg(msg); err != nil {
+				logger.Log.Error().Err(err).Str("print", p.IdStr()).Msg("Failed to publish tape print")
+			}
+		}
+	}
+
+	handler := func(msg *nats.Msg) {
+		trades, err := sqx.UnmarshalTrades(msg.Data, msg.Header.Get(sqx.HeaderTradeBatch))
+		if err != nil {
+			logger.Log.Error().Err(err).Str("subject", msg.Subject).Msg("Failed to unmarshal trade")
+			return
+		}
+		now := time.Now()
+		for _, trade := range trades {
+			consolidator.Add(trade, now)
+		}
+		if opts.Delay == 0 {
+			flush(now)
+		}
+	}
+
+	if opts.Delay > 0 {
+		interval := time.Duration(cfg.FlushIntervalMs) * time.Millisecond
+		if interval == 0 {
+			interval = opts.Delay
+		}
+		done := make(chan struct{})
+		go func() {
+			flushTicker := time.NewTicker(interval)
+			defer flushTicker.Stop()
+			for {
+				select {
+				case <-done:
+					return
+				case now := <-flushTicker.C:
+					flush(now)
+				}
+			}
+		}()
+		shutdown.HookShutdownCallback("flush", func() { close(done) }, 10*time.Second)
+	}
+
+	for _, input := range cfg.Inputs {
+		sub, err := bus.Subscribe(input, handler)
+		if err != nil {
+			logger.Log.Error().Err(err).Str("subject", input).Msg("Failed to subscribe to input")
+			os.Exit(1)
+		}
+		diag.AddQueueSource(diagnostics.SubscriptionSource(sub))
+		shutdown.HookShutdownCallback("unsubscribe "+input, func() {
+			if err := sub.Unsubscribe(); err != nil {
+				logger.Log.Error().Err(err).Str("subject", input).Msg("Failed to unsubscribe")
+			}
+		}, 10*time.Second)
+		logger.Log.Info().Str("subject", input).Msg("Subscribed to input")
+	}
+
+	shutdown.WaitForShutdown(syscall.SIGINT, syscall.SIGTERM)
+	for venue, skew := range consolidator.Skews() {
+		logger.Log.Info().Str("venue", venue).Int64("skewMs", skew).Msg("Venue clock correction")
+	}
+	logger.Log.Info().Msg("Tape command executed successfully!")
+}
+
+// lastPrint reads the last print published on subject, nil when none was
+func lastPrint(js nats.JetStreamContext, stream, subject string) (*sqx.TapePrint, error) {
+	raw, err := js.GetLastMsg(stream, subject)
+	if errors.Is(err, nats.ErrMsgNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	msg := &nats.Msg{Subject: raw.Subject, Header: raw.Header, Data: raw.Data}
+	if err := eventbus.Decode(msg); err != nil {
+		return nil, err
+	}
+	var p sqx.TapePrint
+	if err := sqx.UnmarshalTapePrint(msg.Data, &p); err != nil {
+		return nil, err
+	}
+	return &p, nil
+}
+
+func main() {
+	var configFile string
+	flag.StringVar(&configFile, "c", "", "Configuration file path (required)")
+
+	flag.Usage = func() {
+		logger.Log.Info().Msg(`Tape merges the trades of a symbol on every venue into one consolidated tape,
+corrected for the clock skew of each venue and numbered without gaps, and
+publishes it to NATS as tape.<symbol>.
+
+Usage:
+  tape -c <config-file>
+
+Examples:
+  tape -c config/tape.json
+`)
+		flag.PrintDefaults()
+	}
+
+	flag.Parse()
+
+	if configFile == "" {
+		logger.Log.Error().Msg("config file path is required")
+		flag.Usage()
+		os.Exit(1)
+	}
+
+	runTape(configFile)
+}
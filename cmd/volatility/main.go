@@ -0,0 +1,226 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/BullionBear/sequex/env"
+	"github.com/BullionBear/sequex/internal/config"
+	"github.com/BullionBear/sequex/internal/model/sqx"
+	"github.com/BullionBear/sequex/internal/volatility"
+	"github.com/BullionBear/sequex/pkg/candles"
+	"github.com/BullionBear/sequex/pkg/columnar"
+	"github.com/BullionBear/sequex/pkg/diagnostics"
+	"github.com/BullionBear/sequex/pkg/eventbus"
+	"github.com/BullionBear/sequex/pkg/exchange/binanceperp"
+	"github.com/BullionBear/sequex/pkg/logger"
+	"github.com/BullionBear/sequex/pkg/shutdown"
+	"github.com/nats-io/nats.go"
+)
+
+// runVolatility executes the main volatility service logic
+func runVolatility(configFile string) {
+	logger.Log.Info().
+		Str("version", env.Version).
+		Str("buildTime", env.BuildTime).
+		Str("commitHash", env.CommitHash).
+		Msg("Volatility started")
+
+	cfg, err := config.LoadVolatilityConfig(configFile)
+	if err != nil {
+		logger.Log.Error().Err(err).Msg("Failed to load config")
+		os.Exit(1)
+	}
+
+	tracker, err := volatility.New(cfg.Options())
+	if err != nil {
+		logger.Log.Error().Err(err).Msg("Failed to create volatility tracker")
+		os.Exit(1)
+	}
+
+	shutdown := shutdown.NewShutdown(logger.Log)
+
+	natsConn, err := nats.Connect(cfg.NATS.URIs)
+	if err != nil {
+		logger.Log.Error().Err(err).Msg("Failed to connect to NATS")
+		os.Exit(1)
+	}
+	defer natsConn.Close()
+
+	_, stopDiagnostics, err := diagnostics.Start(cfg.Diagnostics.Options("volatility"), natsConn, logger.Log)
+	if err != nil {
+		logger.Log.Error().Err(err).Msg("Failed to start diagnostics")
+		os.Exit(1)
+	}
+	shutdown.HookShutdownCallback("diagnostics", stopDiagnostics, 10*time.Second)
+	js, err := natsConn.JetStream()
+	if err != nil {
+		logger.Log.Error().Err(err).Msg("Failed to create JetStream context")
+		os.Exit(1)
+	}
+	if _, err := js.StreamInfo(cfg.NATS.Stream); err != nil {
+		logger.Log.Error().Err(err).Msg("Failed to get stream info")
+		os.Exit(1)
+	}
+	busOpts, err := cfg.NATS.BusOptions()
+	if err != nil {
+		logger.Log.Error().Err(err).Msg("Invalid event bus options")
+		os.Exit(1)
+	}
+	bus, err := eventbus.NewBus(natsConn, busOpts)
+	if err != nil {
+		logger.Log.Error().Err(err).Msg("Failed to create event bus")
+		os.Exit(1)
+	}
+
+	symbols := make([]sqx.Symbol, 0, len(cfg.Binance))
+	for _, s := range cfg.Binance {
+		symbol, _ := sqx.NewSymbolFromStr(s) // Validated with the configuration
+		symbols = append(symbols, symbol)
+	}
+	interval := cfg.KlineInterval()
+
+	if cfg.Backfill {
+		sources := []candles.Source{
+			candles.NewBinancePerpSource(binanceperp.NewClient(&binanceperp.Config{BaseURL: binanceperp.MainnetBaseUrl})),
+		}
+		var sinks []candles.Sink
+		if cfg.KlinesDir != "" {
+			store, err := columnar.NewStore(cfg.KlinesDir)
+			if err != nil {
+				logger.Log.Error().Err(err).Str("dir", cfg.KlinesDir).Msg("Failed to open kline tables")
+				os.Exit(1)
+			}
+			sources, sinks = append([]candles.Source{store}, sources...), []candles.Sink{store}
+		}
+		source := candles.NewService(sources, sinks...)
+		for _, symbol := range symbols {
+			if err := backfill(source, tracker, symbol, interval); err != nil {
+				logger.Log.Error().Err(err).Str("symbol", symbol.String()).Msg("Failed to backfill klines")
+				continue
+			}
+			logger.Log.Info().Str("symbol", symbol.String()).Msg("Backfilled klines")
+		}
+	}
+
+	publish := func(symbol sqx.Symbol, snapshot volatility.Snapshot) {
+		data, err := json.Marshal(snapshot)
+		if err != nil {
+			logger.Log.Error().Err(err).Msg("Failed to marshal volatility snapshot")
+			return
+		}
+		msg := &nats.Msg{
+			Subject: fmt.Sprintf("%s.%s", cfg.NATS.Subject, strings.ToLower(symbol.Base+symbol.Quote)),
+			Data:    data,
+			Header: nats.Header{
+				"Nats-Msg-Id": []string{fmt.Sprintf("VOL-%s-%d", symbol, snapshot.Time.UnixMilli())},
+			},
+		}
+		if err := bus.Encode(msg); err != nil {
+			logger.Log.Error().Err(err).Str("symbol", symbol.String()).Msg("Failed to encode volatility snapshot")
+			return
+		}
+		if _, err := js.PublishMsg(msg); err != nil {
+			logger.Log.Error().Err(err).Str("symbol", symbol.String()).Msg("Failed to publish volatility snapshot")
+		}
+	}
+
+	wsClient := binanceperp.NewWSClient(nil)
+	for _, symbol := range symbols {
+		unsubscribe, err := wsClient.SubscribeKline(strings.ToLower(symbol.Base+symbol.Quote), interval,
+			(&binanceperp.KlineSubscriptionOptions{}).
+				WithKline(func(kline binanceperp.WSKline) {
+					if !kline.IsClosed {
+						return
+					}
+					price, err := strconv.ParseFloat(kline.Close, 64)
+					if err != nil {
+						logger.Log.Error().Err(err).Str("symbol", symbol.String()).Msg("Failed to parse close price")
+						return
+					}
+					snapshot, ok := tracker.OnKline(symbol.String(), kline.StartTime, price)
+					if !ok {
+						return
+					}
+					if snapshot.Changed {
+						logger.Log.Info().
+							Str("symbol", symbol.String()).
+							Str("volRegime", string(snapshot.VolRegime)).
+							Str("trendRegime", string(snapshot.TrendRegime)).
+							Float64("volRatio", snapshot.VolRatio).
+							Msg("Regime changed")
+					}
+					publish(symbol, snapshot)
+				}).
+				WithError(func(err error) {
+					logger.Log.Error().Err(err).Str("symbol", symbol.String()).Msg("Kline stream error")
+				}))
+		if err != nil {
+			logger.Log.Error().Err(err).Str("symbol", symbol.String()).Msg("Failed to subscribe to klines")
+			os.Exit(1)
+		}
+		shutdown.HookShutdownCallback("klines "+symbol.String(), unsubscribe, 10*time.Second)
+		logger.Log.Info().Str("symbol", symbol.String()).Str("interval", interval).Msg("Subscribed to Binance klines")
+	}
+	shutdown.HookShutdownCallback("binance websocket", wsClient.Close, 10*time.Second)
+
+	shutdown.WaitForShutdown(syscall.SIGINT, syscall.SIGTERM)
+	logger.Log.Info().Msg("Volatility command executed successfully!")
+}
+
+// backfill records the closed klines of the longest window preceding the
+// stream
+func backfill(source *candles.Service, tracker *volatility.Tracker, symbol sqx.Symbol, interval string) error {
+	step, err := candles.ParseInterval(interval)
+	if err != nil {
+		return err
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+	now := time.Now()
+	series, err := source.GetCandles(ctx, symbol.String(), interval, now.Add(-time.Duration(tracker.Window()+1)*step), now)
+	if err != nil {
+		return err
+	}
+	for _, c := range series {
+		tracker.OnKline(symbol.String(), c.OpenTime, c.Close)
+	}
+	return nil
+}
+
+func main() {
+	var configFile string
+	flag.StringVar(&configFile, "c", "", "Configuration file path (required)")
+
+	flag.Usage = func() {
+		logger.Log.Info().Msg(`Volatility computes the realized volatility of the configured Binance USDⓈ-M
+symbols over rolling windows of kline returns, classifies their regime as
+low, normal or high volatility and trending or ranging, and publishes a
+snapshot to NATS as volatility.<symbol> at every closed kline.
+
+Usage:
+  volatility -c <config-file>
+
+Examples:
+  volatility -c config/volatility.json
+`)
+		flag.PrintDefaults()
+	}
+
+	flag.Parse()
+
+	if configFile == "" {
+		logger.Log.Error().Msg("config file path is required")
+		flag.Usage()
+		os.Exit(1)
+	}
+
+	runVolatility(configFile)
+}
@@ -0,0 +1,159 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"os"
+	"syscall"
+	"time"
+
+	"github.com/BullionBear/sequex/env"
+	"github.com/BullionBear/sequex/internal/config"
+	"github.com/BullionBear/sequex/internal/withdrawal"
+	"github.com/BullionBear/sequex/pkg/diagnostics"
+	"github.com/BullionBear/sequex/pkg/eventbus"
+	"github.com/BullionBear/sequex/pkg/exchange/binance"
+	"github.com/BullionBear/sequex/pkg/logger"
+	"github.com/BullionBear/sequex/pkg/shutdown"
+	"github.com/nats-io/nats.go"
+)
+
+// runWithdrawal executes the main withdrawal node logic
+func runWithdrawal(configFile string) {
+	logger.Log.Info().
+		Str("version", env.Version).
+		Str("buildTime", env.BuildTime).
+		Str("commitHash", env.CommitHash).
+		Msg("Withdrawal node started")
+
+	cfg, err := config.LoadWithdrawalConfig(configFile)
+	if err != nil {
+		logger.Log.Error().Err(err).Msg("Failed to load config")
+		os.Exit(1)
+	}
+	policy, err := cfg.Policy()
+	if err != nil {
+		logger.Log.Error().Err(err).Msg("Invalid configuration")
+		os.Exit(1)
+	}
+	client := binance.NewClient(binance.NewMainnetConfig(os.Getenv(cfg.Binance.KeyEnv), os.Getenv(cfg.Binance.SecretEnv)))
+	engine, err := withdrawal.Open(policy, withdrawal.NewBinanceSender(client), cfg.AuditLog)
+	if err != nil {
+		logger.Log.Error().Err(err).Str("auditLog", cfg.AuditLog).Msg("Failed to open audit log")
+		os.Exit(1)
+	}
+
+	shutdown := shutdown.NewShutdown(logger.Log)
+	shutdown.HookShutdownCallback("audit log", func() {
+		if err := engine.Close(); err != nil {
+			logger.Log.Error().Err(err).Msg("Failed to close audit log")
+		}
+	}, 10*time.Second)
+
+	natsConn, err := nats.Connect(cfg.NATS.URIs)
+	if err != nil {
+		logger.Log.Error().Err(err).Msg("Failed to connect to NATS")
+		os.Exit(1)
+	}
+	defer natsConn.Close()
+
+	_, stopDiagnostics, err := diagnostics.Start(cfg.Diagnostics.Options("withdrawal"), natsConn, logger.Log)
+	if err != nil {
+		logger.Log.Error().Err(err).Msg("Failed to start diagnostics")
+		os.Exit(1)
+	}
+	shutdown.HookShutdownCallback("diagnostics", stopDiagnostics, 10*time.Second)
+	busOpts, err := cfg.NATS.BusOptions()
+	if err != nil {
+		logger.Log.Error().Err(err).Msg("Invalid event bus options")
+		os.Exit(1)
+	}
+	if busOpts.Authorizer, err = cfg.NATS.Authorizer(natsConn, "withdrawal"); err != nil {
+		logger.Log.Error().Err(err).Msg("Invalid command policy")
+		os.Exit(1)
+	}
+	bus, err := eventbus.NewBus(natsConn, busOpts)
+	if err != nil {
+		logger.Log.Error().Err(err).Msg("Failed to create event bus")
+		os.Exit(1)
+	}
+
+	// Entries of the audit log are logged and published on <subject>.audit
+	subject := cfg.RPCSubject()
+	engine.OnEntry(func(e withdrawal.Entry) {
+		logger.Log.Info().
+			Int64("seq", e.Seq).
+			Str("action", e.Action).
+			Str("by", e.By).
+			Str("reason", e.Reason).
+			Str("id", e.Withdrawal.ID).
+			Str("asset", e.Withdrawal.Asset).
+			Float64("amount", e.Withdrawal.Amount).
+			Str("address", e.Withdrawal.Address).
+			Str("status", string(e.Withdrawal.Status)).
+			Str("hash", e.Hash).
+			Msg("Withdrawal audit entry")
+		data, err := json.Marshal(e)
+		if err != nil {
+			logger.Log.Error().Err(err).Msg("Failed to marshal audit entry")
+			return
+		}
+		if err := bus.Publish(subject+".audit", data); err != nil {
+			logger.Log.Error().Err(err).Msg("Failed to publish audit entry")
+		}
+	})
+
+	subs, err := withdrawal.Serve(bus, subject, engine)
+	if err != nil {
+		logger.Log.Error().Err(err).Str("subject", subject).Msg("Failed to serve withdrawal RPCs")
+		os.Exit(1)
+	}
+	shutdown.HookShutdownCallback("withdrawal RPCs", func() {
+		for _, sub := range subs {
+			if err := sub.Unsubscribe(); err != nil {
+				logger.Log.Error().Err(err).Msg("Failed to unsubscribe from withdrawal RPCs")
+			}
+		}
+	}, 10*time.Second)
+	logger.Log.Info().
+		Str("subject", subject).
+		Int("whitelist", len(policy.Whitelist)).
+		Int("approvers", len(policy.Approvers)).
+		Int("approvals", policy.Approvals).
+		Msg("Serving withdrawals")
+
+	shutdown.WaitForShutdown(syscall.SIGINT, syscall.SIGTERM)
+	logger.Log.Info().Msg("Withdrawal node command executed successfully!")
+}
+
+func main() {
+	var configFile string
+	flag.StringVar(&configFile, "c", "", "Configuration file path (required)")
+
+	flag.Usage = func() {
+		logger.Log.Info().Msg(`Withdrawal sends the withdrawals of a Binance account once approved.
+Withdrawals only go to whitelisted addresses, within daily limits per asset,
+and need the ed25519 signatures of two approvers or more: the requester's and
+another's. Requests, approvals, rejections and results are written to a hash
+chained audit log before they take effect, and published to NATS as
+<subject>.audit. Use sqx withdrawals to request and approve withdrawals.
+
+Usage:
+  withdrawal -c <config-file>
+
+Examples:
+  withdrawal -c config/withdrawal.json
+`)
+		flag.PrintDefaults()
+	}
+
+	flag.Parse()
+
+	if configFile == "" {
+		logger.Log.Error().Msg("config file path is required")
+		flag.Usage()
+		os.Exit(1)
+	}
+
+	runWithdrawal(configFile)
+}
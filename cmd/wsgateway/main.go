@@ -0,0 +1,227 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"net/http"
+	"os"
+	"reflect"
+	"syscall"
+	"time"
+
+	"github.com/BullionBear/sequex/api"
+	"github.com/BullionBear/sequex/env"
+	"github.com/BullionBear/sequex/internal/config"
+	"github.com/BullionBear/sequex/internal/gateway"
+	"github.com/BullionBear/sequex/internal/publisher"
+	"github.com/BullionBear/sequex/pkg/diagnostics"
+	"github.com/BullionBear/sequex/pkg/eventbus"
+	"github.com/BullionBear/sequex/pkg/logger"
+	"github.com/BullionBear/sequex/pkg/shutdown"
+	"github.com/gin-gonic/gin"
+	"github.com/nats-io/nats.go"
+)
+
+// runGateway executes the main websocket gateway logic
+func runGateway(configFile string) {
+	logger.Log.Info().
+		Str("version", env.Version).
+		Str("buildTime", env.BuildTime).
+		Str("commitHash", env.CommitHash).
+		Msg("WS gateway started")
+
+	cfg, err := config.LoadGatewayConfig(configFile)
+	if err != nil {
+		logger.Log.Error().Err(err).Msg("Failed to load config")
+		os.Exit(1)
+	}
+
+	shutdown := shutdown.NewShutdown(logger.Log)
+
+	natsConn, err := nats.Connect(cfg.NATS.URIs)
+	if err != nil {
+		logger.Log.Error().Err(err).Msg("Failed to connect to NATS")
+		os.Exit(1)
+	}
+	defer natsConn.Close()
+
+	_, stopDiagnostics, err := diagnostics.Start(cfg.Diagnostics.Options("wsgateway"), natsConn, logger.Log)
+	if err != nil {
+		logger.Log.Error().Err(err).Msg("Failed to start diagnostics")
+		os.Exit(1)
+	}
+	shutdown.HookShutdownCallback("diagnostics", stopDiagnostics, 10*time.Second)
+
+	busOpts, err := cfg.NATS.BusOptions()
+	if err != nil {
+		logger.Log.Error().Err(err).Msg("Invalid event bus options")
+		os.Exit(1)
+	}
+	bus, err := eventbus.NewBus(natsConn, busOpts)
+	if err != nil {
+		logger.Log.Error().Err(err).Msg("Failed to create event bus")
+		os.Exit(1)
+	}
+
+	subscribe := func(subject string, handler func(subject string, data []byte)) (func(), error) {
+		sub, err := bus.Subscribe(subject, func(msg *nats.Msg) {
+			payloads, err := publisher.Unbatch(msg)
+			if err != nil {
+				logger.Log.Error().Err(err).Str("subject", msg.Subject).Msg("Failed to unbatch message")
+				return
+			}
+			for _, data := range payloads {
+				handler(msg.Subject, data)
+			}
+		})
+		if err != nil {
+			return nil, err
+		}
+		return func() {
+			if err := bus.Release(sub).Unsubscribe(); err != nil {
+				logger.Log.Error().Err(err).Str("subject", subject).Msg("Failed to unsubscribe")
+			}
+		}, nil
+	}
+
+	hub := gateway.NewHub(subscribe, gateway.Options{
+		AllowedSubjects:  cfg.AllowedSubjects,
+		Tokens:           cfg.Tokens,
+		RateLimit:        cfg.RateLimit,
+		RateBurst:        cfg.RateBurst,
+		SendBuffer:       cfg.SendBuffer,
+		MaxSubscriptions: cfg.MaxSubscriptions,
+		Accounts:         cfg.GatewayAccounts(),
+		DefaultLimits:    cfg.DefaultLimits,
+	})
+
+	rg := gin.New()
+	rg.Use(gin.Recovery())
+	rg.Use(api.AllowAllCors)
+	rg.GET("/ws", hub.ServeWS)
+	rg.GET("/healthz", func(c *gin.Context) {
+		c.JSON(http.StatusOK, gin.H{"clients": hub.ClientCount()})
+	})
+	rg.GET("/version", gin.WrapF(diagnostics.VersionHandler("wsgateway")))
+	if cfg.AdminToken != "" {
+		gateway.RegisterAdmin(rg.Group("/admin", gateway.AdminAuth(cfg.AdminToken)), hub)
+	}
+
+	if _, remote, _ := config.ParseRemote(configFile); remote {
+		// The limits of the accounts are reloaded as the configuration is
+		// written, the other settings on restart
+		js, err := natsConn.JetStream()
+		if err != nil {
+			logger.Log.Error().Err(err).Msg("Failed to create JetStream context")
+			os.Exit(1)
+		}
+		current := cfg
+		stop, err := config.WatchConfig(js, configFile, func(data []byte, revision uint64) {
+			next, err := config.ParseGatewayConfig(data)
+			if err != nil {
+				logger.Log.Error().Err(err).Uint64("revision", revision).Msg("Ignoring invalid configuration")
+				return
+			}
+			if reflect.DeepEqual(*next, *current) {
+				return
+			}
+			changed := reloadLimits(hub, current, next)
+			if !reflect.DeepEqual(next.Static(), current.Static()) {
+				logger.Log.Warn().Uint64("revision", revision).Msg("Configuration changes other than account limits apply on restart")
+			}
+			logger.Log.Info().Uint64("revision", revision).Int("accounts", changed).Msg("Reloaded the account limits")
+			current = next
+		}, func(err error) {
+			logger.Log.Warn().Err(err).Msg("Remote configuration")
+		})
+		if err != nil {
+			logger.Log.Error().Err(err).Msg("Failed to watch the configuration")
+			os.Exit(1)
+		}
+		shutdown.HookShutdownCallback("configuration watch", stop, 10*time.Second)
+	}
+
+	server := &http.Server{
+		Addr:    fmt.Sprintf("%s:%d", cfg.Host, cfg.Port),
+		Handler: rg,
+	}
+	go func() {
+		logger.Log.Info().Str("addr", server.Addr).Msg("WS gateway listening")
+		if err := server.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			logger.Log.Error().Err(err).Msg("WS gateway server failed")
+			os.Exit(1)
+		}
+	}()
+
+	shutdown.HookShutdownCallback("http server", func() {
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		if err := server.Shutdown(ctx); err != nil {
+			logger.Log.Error().Err(err).Msg("Failed to shutdown http server")
+		}
+		hub.Close()
+	}, 10*time.Second)
+
+	shutdown.WaitForShutdown(syscall.SIGINT, syscall.SIGTERM)
+	logger.Log.Info().Msg("WS gateway command executed successfully!")
+}
+
+// reloadLimits applies the limits of next that changed since current to the
+// accounts of the hub, returning how many changed. Accounts added are served
+// on restart.
+func reloadLimits(hub *gateway.Hub, current, next *config.GatewayConfig) int {
+	previous := map[string]gateway.Limits{gateway.DefaultAccount: current.DefaultLimits}
+	for _, a := range current.Accounts {
+		previous[a.Name] = a.Limits
+	}
+	limits := map[string]gateway.Limits{gateway.DefaultAccount: next.DefaultLimits}
+	for _, a := range next.Accounts {
+		limits[a.Name] = a.Limits
+	}
+	changed := 0
+	for name, l := range limits {
+		if old, ok := previous[name]; ok && old == l {
+			continue
+		}
+		if err := hub.SetLimits(name, l); err != nil {
+			logger.Log.Warn().Err(err).Str("account", name).Msg("Account limits apply on restart")
+			continue
+		}
+		changed++
+	}
+	return changed
+}
+
+func main() {
+	var configFile string
+	flag.StringVar(&configFile, "c", "", "Configuration file path (required)")
+
+	flag.Usage = func() {
+		logger.Log.Info().Msg(`WS gateway bridges internal NATS subjects to external WebSocket clients.
+Clients send {"subscribe": "trade.binance.spot.btcusdt"} to start receiving data.
+The data delivered is metered per account, within the limits of the account,
+and the usage is inspected and limits adjusted on /admin/accounts. Started
+from a kv: configuration, the gateway reloads the account limits as the
+configuration is written.
+
+Usage:
+  wsgateway -c <config-file>
+
+Examples:
+  wsgateway -c config/wsgateway.json
+`)
+		flag.PrintDefaults()
+	}
+
+	flag.Parse()
+
+	if configFile == "" {
+		logger.Log.Error().Msg("config file path is required")
+		flag.Usage()
+		os.Exit(1)
+	}
+
+	runGateway(configFile)
+}
@@ -15,7 +15,7 @@ const docTemplate = `{
     "host": "{{.Host}}",
     "basePath": "{{.BasePath}}",
     "paths": {
-        "/node/register": {
+        "/v1/node/register": {
             "post": {
                 "description": "Register a node",
                 "consumes": [
@@ -24,7 +24,21 @@ const docTemplate = `{
                 "produces": [
                     "application/json"
                 ],
+                "tags": [
+                    "nodes"
+                ],
                 "summary": "Register a node",
+                "parameters": [
+                    {
+                        "description": "Node to register",
+                        "name": "request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/api.RegisterNodeRequest"
+                        }
+                    }
+                ],
                 "responses": {
                     "200": {
                         "description": "Node",
@@ -35,7 +49,7 @@ const docTemplate = `{
                 }
             }
         },
-        "/node/{name}": {
+        "/v1/node/{name}": {
             "get": {
                 "description": "Get a node",
                 "consumes": [
@@ -44,7 +58,19 @@ const docTemplate = `{
                 "produces": [
                     "application/json"
                 ],
+                "tags": [
+                    "nodes"
+                ],
                 "summary": "Get a node",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Node name",
+                        "name": "name",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
                 "responses": {
                     "200": {
                         "description": "Node",
@@ -55,7 +81,7 @@ const docTemplate = `{
                 }
             }
         },
-        "/nodes": {
+        "/v1/nodes": {
             "get": {
                 "description": "List all nodes",
                 "consumes": [
@@ -64,6 +90,9 @@ const docTemplate = `{
                 "produces": [
                     "application/json"
                 ],
+                "tags": [
+                    "nodes"
+                ],
                 "summary": "List all nodes",
                 "responses": {
                     "200": {
@@ -77,18 +106,2131 @@ const docTemplate = `{
                     }
                 }
             }
+        },
+        "/v2/fees": {
+            "get": {
+                "description": "Commissions of the fills per strategy and day (UTC), by asset and converted at the rates as of each fill.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "executions"
+                ],
+                "summary": "Report fees per strategy",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "First fill time included, YYYY-MM-DD or RFC 3339",
+                        "name": "from",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "First fill time excluded, YYYY-MM-DD or RFC 3339",
+                        "name": "to",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "BASE-QUOTE, e.g. BTC-USDT",
+                        "name": "symbol",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Strategy that placed the orders",
+                        "name": "strategy",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "e.g. BINANCE",
+                        "name": "exchange",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Currency of the totals, default USDT",
+                        "name": "currency",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/github_com_BullionBear_sequex_internal_execution.StrategyFees"
+                            }
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/api.ErrorResponse"
+                        }
+                    },
+                    "503": {
+                        "description": "History not enabled or no rate to the currency",
+                        "schema": {
+                            "$ref": "#/definitions/api.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/v2/fills": {
+            "get": {
+                "description": "Fills are returned oldest first.",
+                "produces": [
+                    "application/json",
+                    "text/csv"
+                ],
+                "tags": [
+                    "executions"
+                ],
+                "summary": "Search the fill history",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "First fill time included, YYYY-MM-DD or RFC 3339",
+                        "name": "from",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "First fill time excluded, YYYY-MM-DD or RFC 3339",
+                        "name": "to",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "BASE-QUOTE, e.g. BTC-USDT",
+                        "name": "symbol",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Strategy that placed the orders",
+                        "name": "strategy",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "e.g. BINANCE",
+                        "name": "exchange",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Current status of the orders filled, e.g. FILLED",
+                        "name": "status",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Most recent fills returned, every fill by default",
+                        "name": "limit",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "json (default) or csv",
+                        "name": "format",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/github_com_BullionBear_sequex_internal_execution.Fill"
+                            }
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/api.ErrorResponse"
+                        }
+                    },
+                    "503": {
+                        "description": "History not enabled",
+                        "schema": {
+                            "$ref": "#/definitions/api.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/v2/fx/rates": {
+            "get": {
+                "description": "Latest prices the conversion service values portfolios with",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "fx"
+                ],
+                "summary": "List FX rates",
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/github_com_BullionBear_sequex_internal_fx.Quote"
+                            }
+                        }
+                    }
+                }
+            }
+        },
+        "/v2/nodes/{name}/diagnostics": {
+            "get": {
+                "description": "Runtime diagnostics of a node: goroutines, heap, GC pauses and queue depths",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "nodes"
+                ],
+                "summary": "Get node diagnostics",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Node name",
+                        "name": "name",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_BullionBear_sequex_pkg_diagnostics.Report"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/api.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/api.ErrorResponse"
+                        }
+                    },
+                    "503": {
+                        "description": "Node did not answer",
+                        "schema": {
+                            "$ref": "#/definitions/api.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/v2/orders": {
+            "get": {
+                "description": "Orders are built from the execution reports of the OMS and returned oldest first.",
+                "produces": [
+                    "application/json",
+                    "text/csv"
+                ],
+                "tags": [
+                    "executions"
+                ],
+                "summary": "Search the order history",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "First creation time included, YYYY-MM-DD or RFC 3339",
+                        "name": "from",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "First creation time excluded, YYYY-MM-DD or RFC 3339",
+                        "name": "to",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "BASE-QUOTE, e.g. BTC-USDT",
+                        "name": "symbol",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Strategy that placed the orders",
+                        "name": "strategy",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "e.g. BINANCE",
+                        "name": "exchange",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "e.g. FILLED",
+                        "name": "status",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Most recent orders returned, every order by default",
+                        "name": "limit",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "json (default) or csv",
+                        "name": "format",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/github_com_BullionBear_sequex_internal_execution.Order"
+                            }
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/api.ErrorResponse"
+                        }
+                    },
+                    "503": {
+                        "description": "History not enabled",
+                        "schema": {
+                            "$ref": "#/definitions/api.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/v2/orders/{id}": {
+            "get": {
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "executions"
+                ],
+                "summary": "Get an order",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Order id or client order id",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_BullionBear_sequex_internal_execution.Order"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/api.ErrorResponse"
+                        }
+                    },
+                    "503": {
+                        "description": "History not enabled",
+                        "schema": {
+                            "$ref": "#/definitions/api.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/v2/orders/{id}/fills": {
+            "get": {
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "executions"
+                ],
+                "summary": "List the fills of an order",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Order id or client order id",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/github_com_BullionBear_sequex_internal_execution.Fill"
+                            }
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/api.ErrorResponse"
+                        }
+                    },
+                    "503": {
+                        "description": "History not enabled",
+                        "schema": {
+                            "$ref": "#/definitions/api.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/v2/portfolios": {
+            "get": {
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "portfolios"
+                ],
+                "summary": "List portfolios",
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/github_com_BullionBear_sequex_internal_pms.Portfolio"
+                            }
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/api.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/v2/portfolios/{id}": {
+            "get": {
+                "description": "The version of the portfolio is returned in the body and as ETag",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "portfolios"
+                ],
+                "summary": "Get a portfolio",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Portfolio id",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_BullionBear_sequex_internal_pms.Portfolio"
+                        },
+                        "headers": {
+                            "ETag": {
+                                "type": "string",
+                                "description": "Portfolio version"
+                            }
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/api.ErrorResponse"
+                        }
+                    }
+                }
+            },
+            "put": {
+                "description": "Updates must state the version they are based on, in If-Match or in the body.\nA stale version is rejected with 409 and the current portfolio.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "portfolios"
+                ],
+                "summary": "Create or update a portfolio",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Portfolio id",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "ETag of the version the update is based on",
+                        "name": "If-Match",
+                        "in": "header"
+                    },
+                    {
+                        "description": "Portfolio",
+                        "name": "portfolio",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/api.PortfolioRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_BullionBear_sequex_internal_pms.Portfolio"
+                        },
+                        "headers": {
+                            "ETag": {
+                                "type": "string",
+                                "description": "New portfolio version"
+                            }
+                        }
+                    },
+                    "201": {
+                        "description": "Created",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_BullionBear_sequex_internal_pms.Portfolio"
+                        },
+                        "headers": {
+                            "ETag": {
+                                "type": "string",
+                                "description": "New portfolio version"
+                            }
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/api.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/api.ErrorResponse"
+                        }
+                    },
+                    "409": {
+                        "description": "Version conflict",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_BullionBear_sequex_internal_pms.Portfolio"
+                        }
+                    }
+                }
+            }
+        },
+        "/v2/portfolios/{id}/cashflows": {
+            "get": {
+                "description": "Payments booked from the exchange income and interest histories, ordered by time.\nAmounts are positive when received and negative when paid.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "portfolios"
+                ],
+                "summary": "List the funding and interest payments of a portfolio",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Portfolio id",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/github_com_BullionBear_sequex_internal_pms.CashFlow"
+                            }
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/api.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/v2/portfolios/{id}/export": {
+            "get": {
+                "description": "JSON exports carry the portfolio, its positions and its fills. CSV exports carry one dataset.",
+                "produces": [
+                    "application/json",
+                    "text/csv"
+                ],
+                "tags": [
+                    "portfolios"
+                ],
+                "summary": "Export a portfolio",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Portfolio id",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "json (default) or csv",
+                        "name": "format",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "positions or fills, required for csv",
+                        "name": "dataset",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_BullionBear_sequex_internal_pms.Bundle"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/api.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/api.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/v2/portfolios/{id}/fees": {
+            "get": {
+                "description": "Fees of the fills of a portfolio per day (UTC), by asset and in the base currency of the portfolio at the rates as of each fill.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "portfolios"
+                ],
+                "summary": "Report fees",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Portfolio id",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "First day included, YYYY-MM-DD or RFC 3339",
+                        "name": "from",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "First day excluded, YYYY-MM-DD or RFC 3339",
+                        "name": "to",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_BullionBear_sequex_internal_pms.FeeReport"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/api.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/api.ErrorResponse"
+                        }
+                    },
+                    "503": {
+                        "description": "No rate to the base currency",
+                        "schema": {
+                            "$ref": "#/definitions/api.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/v2/portfolios/{id}/fills": {
+            "get": {
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "portfolios"
+                ],
+                "summary": "List the fill history of a portfolio",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Portfolio id",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/github_com_BullionBear_sequex_internal_pms.Fill"
+                            }
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/api.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/v2/portfolios/{id}/import": {
+            "post": {
+                "description": "Every row is validated first and nothing is written unless all rows are valid.\nRejected rows are reported with their row number and a 422 status.\nImported positions replace existing ones; fills with a recorded id are rejected.",
+                "consumes": [
+                    "application/json",
+                    "text/csv"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "portfolios"
+                ],
+                "summary": "Import positions and fills into a portfolio",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Portfolio id",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "json (default) or csv",
+                        "name": "format",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "positions or fills, required for csv",
+                        "name": "dataset",
+                        "in": "query"
+                    },
+                    {
+                        "type": "boolean",
+                        "description": "Validate without writing",
+                        "name": "dry_run",
+                        "in": "query"
+                    },
+                    {
+                        "description": "Export of a portfolio, or CSV rows",
+                        "name": "bundle",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/github_com_BullionBear_sequex_internal_pms.Bundle"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_BullionBear_sequex_internal_pms.ImportResult"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/api.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/api.ErrorResponse"
+                        }
+                    },
+                    "422": {
+                        "description": "Rejected rows",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_BullionBear_sequex_internal_pms.ImportResult"
+                        }
+                    }
+                }
+            }
+        },
+        "/v2/portfolios/{id}/lots": {
+            "get": {
+                "description": "Lots are built by replaying the fill history; short lots have a negative quantity",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "portfolios"
+                ],
+                "summary": "List the open tax lots of a portfolio",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Portfolio id",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "fifo, lifo or average, default: the cost method of the portfolio",
+                        "name": "method",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/github_com_BullionBear_sequex_internal_pms.Lot"
+                            }
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/api.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/api.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/v2/portfolios/{id}/positions": {
+            "get": {
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "portfolios"
+                ],
+                "summary": "List the positions of a portfolio",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Portfolio id",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/github_com_BullionBear_sequex_internal_pms.Position"
+                            }
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/api.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/v2/portfolios/{id}/positions/{symbol}": {
+            "get": {
+                "description": "The version of the position is returned in the body and as ETag",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "portfolios"
+                ],
+                "summary": "Get a position",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Portfolio id",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Symbol, e.g. BTC-USDT",
+                        "name": "symbol",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_BullionBear_sequex_internal_pms.Position"
+                        },
+                        "headers": {
+                            "ETag": {
+                                "type": "string",
+                                "description": "Position version"
+                            }
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/api.ErrorResponse"
+                        }
+                    }
+                }
+            },
+            "put": {
+                "description": "Updates must state the version they are based on, in If-Match or in the body.\nA stale version is rejected with 409 and the current position.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "portfolios"
+                ],
+                "summary": "Create or update a position",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Portfolio id",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Symbol, e.g. BTC-USDT",
+                        "name": "symbol",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "ETag of the version the update is based on",
+                        "name": "If-Match",
+                        "in": "header"
+                    },
+                    {
+                        "description": "Position",
+                        "name": "position",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/api.PositionRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_BullionBear_sequex_internal_pms.Position"
+                        },
+                        "headers": {
+                            "ETag": {
+                                "type": "string",
+                                "description": "New position version"
+                            }
+                        }
+                    },
+                    "201": {
+                        "description": "Created",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_BullionBear_sequex_internal_pms.Position"
+                        },
+                        "headers": {
+                            "ETag": {
+                                "type": "string",
+                                "description": "New position version"
+                            }
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/api.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/api.ErrorResponse"
+                        }
+                    },
+                    "409": {
+                        "description": "Version conflict",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_BullionBear_sequex_internal_pms.Position"
+                        }
+                    }
+                }
+            }
+        },
+        "/v2/portfolios/{id}/realized": {
+            "get": {
+                "description": "Realized gains and losses per period (UTC) for tax purposes, matched under the selected cost method.\nAmounts are in the base currency of the portfolio, converted at current rates.\nFees paid in the quote asset are part of the PnL, those paid in the base asset come out of the quantity traded; fees paid in other assets, such as BNB, are deducted in net_pnl.\nFunding payments and margin interest are added to net_pnl when paid.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "portfolios"
+                ],
+                "summary": "Report realized PnL",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Portfolio id",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "fifo, lifo or average, default: the cost method of the portfolio",
+                        "name": "method",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "month, quarter or year (default)",
+                        "name": "period",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "First day included, YYYY-MM-DD or RFC 3339",
+                        "name": "from",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "First day excluded, YYYY-MM-DD or RFC 3339",
+                        "name": "to",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_BullionBear_sequex_internal_pms.RealizedReport"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/api.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/api.ErrorResponse"
+                        }
+                    },
+                    "503": {
+                        "description": "No rate to the base currency",
+                        "schema": {
+                            "$ref": "#/definitions/api.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/v2/portfolios/{id}/risk": {
+            "get": {
+                "description": "Bootstraps recorded returns of the assets held to estimate the loss distribution over the horizon\nat the current weights: VaR, CVaR and drawdowns, in the base currency of the portfolio.\nPositions are grouped by base asset; assets without a valuation or return history are excluded and reported.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "portfolios"
+                ],
+                "summary": "Simulate the risk of a portfolio",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Portfolio id",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Periods simulated, default 24",
+                        "name": "horizon",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Simulated paths, default 10000",
+                        "name": "paths",
+                        "in": "query"
+                    },
+                    {
+                        "type": "number",
+                        "description": "VaR confidence level, default 0.99",
+                        "name": "confidence",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Consecutive periods drawn together, default 1",
+                        "name": "block_size",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Seed reproducing a previous simulation, random by default",
+                        "name": "seed",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_BullionBear_sequex_internal_risk.PortfolioReport"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/api.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/api.ErrorResponse"
+                        }
+                    },
+                    "503": {
+                        "description": "No return history",
+                        "schema": {
+                            "$ref": "#/definitions/api.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/v2/portfolios/{id}/valuation": {
+            "get": {
+                "description": "Market value, cost and unrealized PnL of every position in the base currency of the portfolio,\nconverted with the latest traded prices. Positions without a rate are reported and left out of the totals.\nOption positions are priced with Black-76 at the implied volatility of the option marks and report their greeks,\nsummed by underlying asset with the holdings of the asset netted into delta.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "portfolios"
+                ],
+                "summary": "Value a portfolio",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Portfolio id",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/github_com_BullionBear_sequex_internal_pms.Valuation"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/api.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        }
+    },
+    "definitions": {
+        "api.ErrorResponse": {
+            "type": "object",
+            "properties": {
+                "error": {
+                    "type": "string"
+                }
+            }
+        },
+        "api.PortfolioRequest": {
+            "type": "object",
+            "properties": {
+                "base_currency": {
+                    "description": "Currency of valuations and reports, USDT by default",
+                    "type": "string"
+                },
+                "cost_method": {
+                    "description": "fifo (default), lifo or average",
+                    "type": "string"
+                },
+                "name": {
+                    "type": "string"
+                },
+                "version": {
+                    "description": "Version the update is based on, 0 to create. Ignored when If-Match is set.",
+                    "type": "integer"
+                }
+            }
+        },
+        "api.PositionRequest": {
+            "type": "object",
+            "properties": {
+                "avg_price": {
+                    "type": "number"
+                },
+                "quantity": {
+                    "type": "number"
+                },
+                "version": {
+                    "description": "Version the update is based on, 0 to create. Ignored when If-Match is set.",
+                    "type": "integer"
+                }
+            }
+        },
+        "api.RegisterNodeRequest": {
+            "type": "object",
+            "properties": {
+                "name": {
+                    "type": "string"
+                }
+            }
+        },
+        "github_com_BullionBear_sequex_internal_execution.Fill": {
+            "type": "object",
+            "properties": {
+                "client_order_id": {
+                    "type": "string"
+                },
+                "commission": {
+                    "type": "number"
+                },
+                "commission_asset": {
+                    "type": "string"
+                },
+                "exchange": {
+                    "type": "string"
+                },
+                "exec_id": {
+                    "type": "string"
+                },
+                "instrument": {
+                    "type": "string"
+                },
+                "order_id": {
+                    "type": "string"
+                },
+                "price": {
+                    "type": "number"
+                },
+                "quantity": {
+                    "type": "number"
+                },
+                "side": {
+                    "type": "string"
+                },
+                "strategy": {
+                    "type": "string"
+                },
+                "symbol": {
+                    "type": "string"
+                },
+                "timestamp": {
+                    "description": "Unix milliseconds",
+                    "type": "integer"
+                }
+            }
+        },
+        "github_com_BullionBear_sequex_internal_execution.Order": {
+            "type": "object",
+            "properties": {
+                "avg_price": {
+                    "type": "number"
+                },
+                "client_order_id": {
+                    "type": "string"
+                },
+                "commission": {
+                    "description": "Sum over the fills",
+                    "type": "number"
+                },
+                "commission_asset": {
+                    "type": "string"
+                },
+                "created_at": {
+                    "description": "Unix milliseconds of the first report",
+                    "type": "integer"
+                },
+                "cum_quantity": {
+                    "type": "number"
+                },
+                "exchange": {
+                    "type": "string"
+                },
+                "instrument": {
+                    "type": "string"
+                },
+                "order_id": {
+                    "type": "string"
+                },
+                "price": {
+                    "type": "number"
+                },
+                "quantity": {
+                    "type": "number"
+                },
+                "side": {
+                    "type": "string"
+                },
+                "status": {
+                    "type": "string"
+                },
+                "strategy": {
+                    "type": "string"
+                },
+                "symbol": {
+                    "type": "string"
+                },
+                "text": {
+                    "type": "string"
+                },
+                "type": {
+                    "type": "string"
+                },
+                "updated_at": {
+                    "description": "Unix milliseconds of the latest report",
+                    "type": "integer"
+                }
+            }
+        },
+        "github_com_BullionBear_sequex_internal_execution.StrategyFees": {
+            "type": "object",
+            "properties": {
+                "fees": {
+                    "$ref": "#/definitions/github_com_BullionBear_sequex_internal_pms.FeeReport"
+                },
+                "strategy": {
+                    "type": "string"
+                }
+            }
+        },
+        "github_com_BullionBear_sequex_internal_fx.Quote": {
+            "type": "object",
+            "properties": {
+                "base": {
+                    "type": "string"
+                },
+                "price": {
+                    "type": "number"
+                },
+                "quote": {
+                    "type": "string"
+                },
+                "static": {
+                    "description": "Configured rather than observed, never stale",
+                    "type": "boolean"
+                },
+                "updated_at": {
+                    "type": "string"
+                }
+            }
+        },
+        "github_com_BullionBear_sequex_internal_pms.Bundle": {
+            "type": "object",
+            "properties": {
+                "fills": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/github_com_BullionBear_sequex_internal_pms.Fill"
+                    }
+                },
+                "portfolio": {
+                    "$ref": "#/definitions/github_com_BullionBear_sequex_internal_pms.Portfolio"
+                },
+                "positions": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/github_com_BullionBear_sequex_internal_pms.Position"
+                    }
+                }
+            }
+        },
+        "github_com_BullionBear_sequex_internal_pms.CashFlow": {
+            "type": "object",
+            "properties": {
+                "amount": {
+                    "description": "Received when positive, paid when negative",
+                    "type": "number"
+                },
+                "asset": {
+                    "type": "string"
+                },
+                "id": {
+                    "description": "Unique within the portfolio, e.g. the exchange transaction id",
+                    "type": "string"
+                },
+                "kind": {
+                    "$ref": "#/definitions/github_com_BullionBear_sequex_internal_pms.CashFlowKind"
+                },
+                "portfolio_id": {
+                    "type": "string"
+                },
+                "symbol": {
+                    "description": "Position or loan the payment is for, e.g. BTC-USDT",
+                    "type": "string"
+                },
+                "timestamp": {
+                    "description": "Unix milliseconds",
+                    "type": "integer"
+                }
+            }
+        },
+        "github_com_BullionBear_sequex_internal_pms.CashFlowKind": {
+            "type": "string",
+            "enum": [
+                "funding",
+                "interest"
+            ],
+            "x-enum-comments": {
+                "CashFlowFunding": "Perpetual futures funding payment",
+                "CashFlowInterest": "Interest on a margin loan"
+            },
+            "x-enum-descriptions": [
+                "Perpetual futures funding payment",
+                "Interest on a margin loan"
+            ],
+            "x-enum-varnames": [
+                "CashFlowFunding",
+                "CashFlowInterest"
+            ]
+        },
+        "github_com_BullionBear_sequex_internal_pms.CostMethod": {
+            "type": "string",
+            "enum": [
+                "fifo",
+                "lifo",
+                "average"
+            ],
+            "x-enum-comments": {
+                "CostAverage": "One pooled lot per symbol at the average cost",
+                "CostFIFO": "Oldest lots first",
+                "CostLIFO": "Newest lots first"
+            },
+            "x-enum-descriptions": [
+                "Oldest lots first",
+                "Newest lots first",
+                "One pooled lot per symbol at the average cost"
+            ],
+            "x-enum-varnames": [
+                "CostFIFO",
+                "CostLIFO",
+                "CostAverage"
+            ]
+        },
+        "github_com_BullionBear_sequex_internal_pms.DailyFees": {
+            "type": "object",
+            "properties": {
+                "by_asset": {
+                    "description": "Amounts charged, keyed by asset",
+                    "type": "object",
+                    "additionalProperties": {
+                        "type": "number",
+                        "format": "float64"
+                    }
+                },
+                "date": {
+                    "description": "YYYY-MM-DD, or total",
+                    "type": "string"
+                },
+                "fills": {
+                    "type": "integer"
+                },
+                "total": {
+                    "description": "Sum converted to the currency of the report",
+                    "type": "number"
+                }
+            }
+        },
+        "github_com_BullionBear_sequex_internal_pms.Dataset": {
+            "type": "string",
+            "enum": [
+                "positions",
+                "fills"
+            ],
+            "x-enum-varnames": [
+                "DatasetPositions",
+                "DatasetFills"
+            ]
+        },
+        "github_com_BullionBear_sequex_internal_pms.FeeReport": {
+            "type": "object",
+            "properties": {
+                "currency": {
+                    "type": "string"
+                },
+                "days": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/github_com_BullionBear_sequex_internal_pms.DailyFees"
+                    }
+                },
+                "total": {
+                    "$ref": "#/definitions/github_com_BullionBear_sequex_internal_pms.DailyFees"
+                }
+            }
+        },
+        "github_com_BullionBear_sequex_internal_pms.Fill": {
+            "type": "object",
+            "properties": {
+                "fee": {
+                    "type": "number"
+                },
+                "fee_asset": {
+                    "type": "string"
+                },
+                "id": {
+                    "description": "Unique within the portfolio, e.g. the exchange trade id",
+                    "type": "string"
+                },
+                "portfolio_id": {
+                    "type": "string"
+                },
+                "price": {
+                    "type": "number"
+                },
+                "quantity": {
+                    "type": "number"
+                },
+                "side": {
+                    "description": "BUY or SELL",
+                    "type": "string"
+                },
+                "symbol": {
+                    "description": "BASE-QUOTE, e.g. BTC-USDT",
+                    "type": "string"
+                },
+                "timestamp": {
+                    "description": "Unix milliseconds",
+                    "type": "integer"
+                }
+            }
+        },
+        "github_com_BullionBear_sequex_internal_pms.ImportResult": {
+            "type": "object",
+            "properties": {
+                "dry_run": {
+                    "type": "boolean"
+                },
+                "errors": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/github_com_BullionBear_sequex_internal_pms.RowError"
+                    }
+                },
+                "fills": {
+                    "description": "Fills added, or that would be added on a dry run",
+                    "type": "integer"
+                },
+                "positions": {
+                    "description": "Positions written, or that would be written on a dry run",
+                    "type": "integer"
+                }
+            }
+        },
+        "github_com_BullionBear_sequex_internal_pms.Lot": {
+            "type": "object",
+            "properties": {
+                "fill_id": {
+                    "description": "Opening fill, the first one for average cost",
+                    "type": "string"
+                },
+                "opened_at": {
+                    "type": "integer"
+                },
+                "price": {
+                    "description": "Cost per unit including fees, net proceeds per unit for short lots",
+                    "type": "number"
+                },
+                "quantity": {
+                    "type": "number"
+                },
+                "symbol": {
+                    "type": "string"
+                }
+            }
+        },
+        "github_com_BullionBear_sequex_internal_pms.Period": {
+            "type": "string",
+            "enum": [
+                "month",
+                "quarter",
+                "year"
+            ],
+            "x-enum-varnames": [
+                "PeriodMonth",
+                "PeriodQuarter",
+                "PeriodYear"
+            ]
+        },
+        "github_com_BullionBear_sequex_internal_pms.Portfolio": {
+            "type": "object",
+            "properties": {
+                "base_currency": {
+                    "description": "Currency of valuations and reports, DefaultBaseCurrency when empty",
+                    "type": "string"
+                },
+                "cost_method": {
+                    "description": "Default lot matching of realized PnL, FIFO when empty",
+                    "allOf": [
+                        {
+                            "$ref": "#/definitions/github_com_BullionBear_sequex_internal_pms.CostMethod"
+                        }
+                    ]
+                },
+                "id": {
+                    "type": "string"
+                },
+                "name": {
+                    "type": "string"
+                },
+                "updated_at": {
+                    "type": "string"
+                },
+                "version": {
+                    "description": "Incremented by every update, starts at 1",
+                    "type": "integer"
+                }
+            }
+        },
+        "github_com_BullionBear_sequex_internal_pms.Position": {
+            "type": "object",
+            "properties": {
+                "avg_price": {
+                    "type": "number"
+                },
+                "portfolio_id": {
+                    "type": "string"
+                },
+                "quantity": {
+                    "type": "number"
+                },
+                "symbol": {
+                    "description": "BASE-QUOTE, e.g. BTC-USDT, or an option contract, e.g. BTC-USDT-250328-90000-C",
+                    "type": "string"
+                },
+                "updated_at": {
+                    "type": "string"
+                },
+                "version": {
+                    "description": "Incremented by every update, starts at 1",
+                    "type": "integer"
+                }
+            }
+        },
+        "github_com_BullionBear_sequex_internal_pms.PositionValue": {
+            "type": "object",
+            "properties": {
+                "cost": {
+                    "description": "Quantity at the average price, converted at the current rate",
+                    "type": "number"
+                },
+                "error": {
+                    "description": "Why the position could not be valued",
+                    "type": "string"
+                },
+                "greeks": {
+                    "description": "Of an option position, in the base currency",
+                    "allOf": [
+                        {
+                            "$ref": "#/definitions/github_com_BullionBear_sequex_pkg_options.Greeks"
+                        }
+                    ]
+                },
+                "price": {
+                    "description": "Price of one unit of the base asset, or of one option contract",
+                    "type": "number"
+                },
+                "quantity": {
+                    "type": "number"
+                },
+                "symbol": {
+                    "type": "string"
+                },
+                "unrealized_pnl": {
+                    "type": "number"
+                },
+                "value": {
+                    "type": "number"
+                }
+            }
+        },
+        "github_com_BullionBear_sequex_internal_pms.RealizedReport": {
+            "type": "object",
+            "properties": {
+                "currency": {
+                    "description": "Currency of the amounts, set by the caller converting them",
+                    "type": "string"
+                },
+                "method": {
+                    "$ref": "#/definitions/github_com_BullionBear_sequex_internal_pms.CostMethod"
+                },
+                "period": {
+                    "$ref": "#/definitions/github_com_BullionBear_sequex_internal_pms.Period"
+                },
+                "periods": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/github_com_BullionBear_sequex_internal_pms.RealizedSummary"
+                    }
+                },
+                "total": {
+                    "$ref": "#/definitions/github_com_BullionBear_sequex_internal_pms.RealizedSummary"
+                }
+            }
+        },
+        "github_com_BullionBear_sequex_internal_pms.RealizedSummary": {
+            "type": "object",
+            "properties": {
+                "by_symbol": {
+                    "description": "PnL of each symbol",
+                    "type": "object",
+                    "additionalProperties": {
+                        "type": "number",
+                        "format": "float64"
+                    }
+                },
+                "cost_basis": {
+                    "type": "number"
+                },
+                "end": {
+                    "description": "Exclusive",
+                    "type": "string"
+                },
+                "fees": {
+                    "description": "Fees charged in other assets, see Charge",
+                    "type": "number"
+                },
+                "funding": {
+                    "description": "Funding received, negative when paid",
+                    "type": "number"
+                },
+                "gains": {
+                    "description": "Sum of positive realizations",
+                    "type": "number"
+                },
+                "interest": {
+                    "description": "Margin interest received, negative when paid",
+                    "type": "number"
+                },
+                "losses": {
+                    "description": "Sum of negative realizations, negative",
+                    "type": "number"
+                },
+                "net_pnl": {
+                    "description": "PnL less Fees, plus Funding and Interest",
+                    "type": "number"
+                },
+                "period": {
+                    "description": "e.g. 2024, 2024-Q2 or 2024-06",
+                    "type": "string"
+                },
+                "pnl": {
+                    "description": "Net of the fees charged in the quote asset",
+                    "type": "number"
+                },
+                "proceeds": {
+                    "type": "number"
+                },
+                "start": {
+                    "type": "string"
+                }
+            }
+        },
+        "github_com_BullionBear_sequex_internal_pms.RowError": {
+            "type": "object",
+            "properties": {
+                "dataset": {
+                    "$ref": "#/definitions/github_com_BullionBear_sequex_internal_pms.Dataset"
+                },
+                "error": {
+                    "type": "string"
+                },
+                "row": {
+                    "type": "integer"
+                }
+            }
+        },
+        "github_com_BullionBear_sequex_internal_pms.Valuation": {
+            "type": "object",
+            "properties": {
+                "as_of": {
+                    "type": "string"
+                },
+                "complete": {
+                    "description": "Whether every position was valued",
+                    "type": "boolean"
+                },
+                "cost": {
+                    "type": "number"
+                },
+                "currency": {
+                    "type": "string"
+                },
+                "greeks": {
+                    "description": "Greeks of the option positions by underlying asset, in the base\ncurrency, delta netting the holdings of the asset",
+                    "type": "object",
+                    "additionalProperties": {
+                        "$ref": "#/definitions/github_com_BullionBear_sequex_pkg_options.Greeks"
+                    }
+                },
+                "portfolio_id": {
+                    "type": "string"
+                },
+                "positions": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/github_com_BullionBear_sequex_internal_pms.PositionValue"
+                    }
+                },
+                "unrealized_pnl": {
+                    "type": "number"
+                },
+                "value": {
+                    "type": "number"
+                }
+            }
+        },
+        "github_com_BullionBear_sequex_internal_risk.Distribution": {
+            "type": "object",
+            "properties": {
+                "max": {
+                    "type": "number"
+                },
+                "mean": {
+                    "type": "number"
+                },
+                "p50": {
+                    "type": "number"
+                },
+                "p95": {
+                    "type": "number"
+                },
+                "p99": {
+                    "type": "number"
+                }
+            }
+        },
+        "github_com_BullionBear_sequex_internal_risk.Exposure": {
+            "type": "object",
+            "properties": {
+                "asset": {
+                    "type": "string"
+                },
+                "value": {
+                    "type": "number"
+                }
+            }
+        },
+        "github_com_BullionBear_sequex_internal_risk.PortfolioReport": {
+            "type": "object",
+            "properties": {
+                "cash": {
+                    "description": "Value held in the portfolio or history currency, taken as riskless",
+                    "type": "number"
+                },
+                "complete": {
+                    "description": "Whether every position was simulated",
+                    "type": "boolean"
+                },
+                "confidence": {
+                    "type": "number"
+                },
+                "currency": {
+                    "type": "string"
+                },
+                "cvar": {
+                    "description": "Mean loss beyond the VaR",
+                    "type": "number"
+                },
+                "drawdown": {
+                    "description": "Largest fall from a peak within each path",
+                    "allOf": [
+                        {
+                            "$ref": "#/definitions/github_com_BullionBear_sequex_internal_risk.Distribution"
+                        }
+                    ]
+                },
+                "excluded": {
+                    "description": "Assets without a valuation or a return history",
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                },
+                "expected_pnl": {
+                    "type": "number"
+                },
+                "exposures": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/github_com_BullionBear_sequex_internal_risk.Exposure"
+                    }
+                },
+                "horizon": {
+                    "description": "Periods times their interval",
+                    "allOf": [
+                        {
+                            "$ref": "#/definitions/time.Duration"
+                        }
+                    ]
+                },
+                "loss": {
+                    "$ref": "#/definitions/github_com_BullionBear_sequex_internal_risk.Distribution"
+                },
+                "paths": {
+                    "type": "integer"
+                },
+                "periods": {
+                    "description": "Historical periods bootstrapped",
+                    "type": "integer"
+                },
+                "portfolio_id": {
+                    "type": "string"
+                },
+                "seed": {
+                    "description": "Reproduces the simulation",
+                    "type": "integer"
+                },
+                "value": {
+                    "description": "Sum of the exposures",
+                    "type": "number"
+                },
+                "var": {
+                    "description": "Loss not exceeded at the confidence level",
+                    "type": "number"
+                }
+            }
+        },
+        "github_com_BullionBear_sequex_pkg_diagnostics.GCStats": {
+            "type": "object",
+            "properties": {
+                "count": {
+                    "type": "integer"
+                },
+                "last_gc": {
+                    "type": "string"
+                },
+                "pause_total": {
+                    "$ref": "#/definitions/time.Duration"
+                },
+                "recent_pauses": {
+                    "description": "Most recent first",
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/time.Duration"
+                    }
+                }
+            }
+        },
+        "github_com_BullionBear_sequex_pkg_diagnostics.HeapStats": {
+            "type": "object",
+            "properties": {
+                "alloc": {
+                    "type": "integer"
+                },
+                "in_use": {
+                    "type": "integer"
+                },
+                "objects": {
+                    "type": "integer"
+                },
+                "sys": {
+                    "type": "integer"
+                }
+            }
+        },
+        "github_com_BullionBear_sequex_pkg_diagnostics.QueueDepth": {
+            "type": "object",
+            "properties": {
+                "depth": {
+                    "type": "integer"
+                },
+                "name": {
+                    "type": "string"
+                }
+            }
+        },
+        "github_com_BullionBear_sequex_pkg_diagnostics.Report": {
+            "type": "object",
+            "properties": {
+                "cpus": {
+                    "type": "integer"
+                },
+                "gc": {
+                    "$ref": "#/definitions/github_com_BullionBear_sequex_pkg_diagnostics.GCStats"
+                },
+                "goroutines": {
+                    "type": "integer"
+                },
+                "heap": {
+                    "$ref": "#/definitions/github_com_BullionBear_sequex_pkg_diagnostics.HeapStats"
+                },
+                "queues": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/github_com_BullionBear_sequex_pkg_diagnostics.QueueDepth"
+                    }
+                },
+                "service": {
+                    "type": "string"
+                },
+                "uptime": {
+                    "$ref": "#/definitions/time.Duration"
+                }
+            }
+        },
+        "github_com_BullionBear_sequex_pkg_options.Greeks": {
+            "type": "object",
+            "properties": {
+                "delta": {
+                    "type": "number"
+                },
+                "gamma": {
+                    "type": "number"
+                },
+                "price": {
+                    "type": "number"
+                },
+                "rho": {
+                    "type": "number"
+                },
+                "theta": {
+                    "type": "number"
+                },
+                "vega": {
+                    "type": "number"
+                }
+            }
+        },
+        "time.Duration": {
+            "type": "integer",
+            "format": "int64",
+            "enum": [
+                -9223372036854775808,
+                9223372036854775807,
+                1,
+                1000,
+                1000000,
+                1000000000,
+                60000000000,
+                3600000000000
+            ],
+            "x-enum-varnames": [
+                "minDuration",
+                "maxDuration",
+                "Nanosecond",
+                "Microsecond",
+                "Millisecond",
+                "Second",
+                "Minute",
+                "Hour"
+            ]
         }
     }
 }`
 
 // SwaggerInfo holds exported Swagger Info so clients can modify it
 var SwaggerInfo = &swag.Spec{
-	Version:          "",
+	Version:          "2.0",
 	Host:             "",
-	BasePath:         "",
+	BasePath:         "/api",
 	Schemes:          []string{},
-	Title:            "",
-	Description:      "",
+	Title:            "Sequex Master API",
+	Description:      "Control API of the master and its nodes. Routes are grouped by\nversion under /api/v1 and /api/v2; breaking changes only land in a new version.",
 	InfoInstanceName: "swagger",
 	SwaggerTemplate:  docTemplate,
 	LeftDelim:        "{{",
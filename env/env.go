@@ -1,7 +1,77 @@
 package env
 
+import (
+	"runtime"
+	"runtime/debug"
+	"strings"
+)
+
+// Set with -ldflags -X at build time, see the Makefile
 var (
 	Version    string
 	BuildTime  string
 	CommitHash string
+	Dirty      string // "true" when built from a tree with uncommitted changes
+	Features   string // Feature flags compiled in, comma separated
 )
+
+// BuildInfo describes the code a binary was built from
+type BuildInfo struct {
+	Version    string   `json:"version"`
+	CommitHash string   `json:"commit_hash"`
+	BuildTime  string   `json:"build_time"`
+	Dirty      bool     `json:"dirty"` // Built from a tree with uncommitted changes
+	GoVersion  string   `json:"go_version"`
+	Platform   string   `json:"platform"`           // GOOS/GOARCH
+	Tags       []string `json:"tags,omitempty"`     // Build tags
+	Features   []string `json:"features,omitempty"` // Feature flags compiled in
+}
+
+// Info returns the build information of the running binary. The values set
+// by the linker win over those the go command stamps, which are missing for
+// binaries built from files rather than packages.
+func Info() BuildInfo {
+	info := BuildInfo{
+		Version:    Version,
+		CommitHash: CommitHash,
+		BuildTime:  BuildTime,
+		Dirty:      Dirty == "true",
+		GoVersion:  runtime.Version(),
+		Platform:   runtime.GOOS + "/" + runtime.GOARCH,
+		Features:   split(Features),
+	}
+	build, ok := debug.ReadBuildInfo()
+	if !ok {
+		return info
+	}
+	for _, s := range build.Settings {
+		switch s.Key {
+		case "-tags":
+			info.Tags = split(s.Value)
+		case "vcs.revision":
+			if info.CommitHash == "" {
+				info.CommitHash = s.Value
+			}
+		case "vcs.time":
+			if info.BuildTime == "" {
+				info.BuildTime = s.Value
+			}
+		case "vcs.modified":
+			info.Dirty = info.Dirty || s.Value == "true"
+		}
+	}
+	if info.Version == "" && build.Main.Version != "" && build.Main.Version != "(devel)" {
+		info.Version = build.Main.Version
+	}
+	return info
+}
+
+func split(list string) []string {
+	var items []string
+	for _, item := range strings.Split(list, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
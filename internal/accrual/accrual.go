@@ -0,0 +1,267 @@
+// Package accrual books the funding payments of perpetual futures positions
+// and the interest charged on margin loans as cash flows of a portfolio, so
+// the PnL of leveraged positions matches the exchange statements.
+//
+// The income and interest histories of Binance are the record: every payment
+// is booked under its exchange transaction id, and overlapping pulls are
+// skipped by the store. The user data stream only tells when a funding
+// payment happened so it can be pulled right away instead of at the next poll.
+package accrual
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/BullionBear/sequex/internal/pms"
+	"github.com/BullionBear/sequex/pkg/exchange/binance"
+	"github.com/BullionBear/sequex/pkg/exchange/binanceperp"
+)
+
+const (
+	fundingPageSize  = 1000 // Maximum of the income history
+	interestPageSize = 100  // Maximum of the margin interest history
+)
+
+// FundingSource is the subset of the Binance USDⓈ-M client funding is pulled from
+type FundingSource interface {
+	GetIncomeHistory(ctx context.Context, req binanceperp.GetIncomeHistoryRequest) (binanceperp.Response[[]binanceperp.Income], error)
+}
+
+// InterestSource is the subset of the Binance spot client margin interest is pulled from
+type InterestSource interface {
+	GetMarginInterestHistory(ctx context.Context, req binance.GetMarginInterestHistoryRequest) (binance.Response[binance.MarginInterestHistoryResponse], error)
+}
+
+// Options configures a booker
+type Options struct {
+	Portfolio string        // Portfolio the payments are booked to
+	Lookback  time.Duration // History pulled by the first sync, 7 days when 0
+}
+
+// Booker pulls the payments made since the last sync and books them as cash
+// flows. Either source may be nil.
+type Booker struct {
+	store    pms.Store
+	funding  FundingSource
+	interest InterestSource
+	opts     Options
+	now      func() time.Time
+
+	mu           sync.Mutex // Serializes syncs
+	fundingFrom  int64      // Time of the latest funding payment booked, Unix milliseconds
+	interestFrom int64      // Time of the latest interest charge booked, Unix milliseconds
+}
+
+// NewBooker creates a booker
+func NewBooker(store pms.Store, funding FundingSource, interest InterestSource, opts Options) (*Booker, error) {
+	if err := pms.ValidateID(opts.Portfolio); err != nil {
+		return nil, fmt.Errorf("portfolio: %w", err)
+	}
+	if funding == nil && interest == nil {
+		return nil, errors.New("no funding or interest source")
+	}
+	if opts.Lookback < 0 {
+		return nil, errors.New("lookback cannot be negative")
+	}
+	if opts.Lookback == 0 {
+		opts.Lookback = 7 * 24 * time.Hour
+	}
+	return &Booker{store: store, funding: funding, interest: interest, opts: opts, now: time.Now}, nil
+}
+
+// Sync books the payments made since the last sync and returns how many were
+// new. A source that fails is pulled again from the same time by the next sync.
+func (b *Booker) Sync(ctx context.Context) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	now := b.now().UnixMilli()
+	start := now - b.opts.Lookback.Milliseconds()
+	var errs []error
+	booked := 0
+	if b.funding != nil {
+		n, err := b.book(&b.fundingFrom, start, func(from int64) ([]pms.CashFlow, error) {
+			return b.pullFunding(ctx, from, now)
+		})
+		booked += n
+		if err != nil {
+			errs = append(errs, fmt.Errorf("funding: %w", err))
+		}
+	}
+	if b.interest != nil {
+		n, err := b.book(&b.interestFrom, start, func(from int64) ([]pms.CashFlow, error) {
+			return b.pullInterest(ctx, from, now)
+		})
+		booked += n
+		if err != nil {
+			errs = append(errs, fmt.Errorf("interest: %w", err))
+		}
+	}
+	return booked, errors.Join(errs...)
+}
+
+// book pulls the payments made since *from, start on the first sync, and
+// moves *from to the latest once they are stored. The latest payment is
+// pulled again next time, in case others were made in the same millisecond.
+func (b *Booker) book(from *int64, start int64, pull func(from int64) ([]pms.CashFlow, error)) (int, error) {
+	if *from == 0 {
+		*from = start
+	}
+	flows, err := pull(*from)
+	if err != nil || len(flows) == 0 {
+		return 0, err
+	}
+	n, err := b.store.AddCashFlows(b.opts.Portfolio, flows)
+	if err != nil {
+		return 0, err
+	}
+	for _, c := range flows {
+		*from = max(*from, c.Timestamp)
+	}
+	return n, nil
+}
+
+// pullFunding reads the funding payments in [from, to], oldest first
+func (b *Booker) pullFunding(ctx context.Context, from, to int64) ([]pms.CashFlow, error) {
+	var flows []pms.CashFlow
+	for {
+		resp, err := b.funding.GetIncomeHistory(ctx, binanceperp.GetIncomeHistoryRequest{
+			IncomeType: "FUNDING_FEE",
+			StartTime:  from,
+			EndTime:    to,
+			Limit:      fundingPageSize,
+		})
+		if err != nil {
+			return nil, err
+		}
+		if resp.Data == nil {
+			return flows, nil
+		}
+		page := *resp.Data
+		for _, income := range page {
+			c, err := fundingFlow(income)
+			if err != nil {
+				return nil, err
+			}
+			flows = append(flows, c)
+		}
+		// Pages are cut by time: the next starts at the last payment, which
+		// is pulled twice and skipped by the store
+		if len(page) < fundingPageSize || page[len(page)-1].Time <= from {
+			return flows, nil
+		}
+		from = page[len(page)-1].Time
+	}
+}
+
+// pullInterest reads the interest charged in [from, to]
+func (b *Booker) pullInterest(ctx context.Context, from, to int64) ([]pms.CashFlow, error) {
+	var flows []pms.CashFlow
+	for current := 1; ; current++ {
+		resp, err := b.interest.GetMarginInterestHistory(ctx, binance.GetMarginInterestHistoryRequest{
+			StartTime: from,
+			EndTime:   to,
+			Current:   current,
+			Size:      interestPageSize,
+		})
+		if err != nil {
+			return nil, err
+		}
+		if resp.Data == nil {
+			return flows, nil
+		}
+		for _, row := range resp.Data.Rows {
+			c, err := interestFlow(row)
+			if err != nil {
+				return nil, err
+			}
+			flows = append(flows, c)
+		}
+		if len(resp.Data.Rows) < interestPageSize || int64(current*interestPageSize) >= resp.Data.Total {
+			return flows, nil
+		}
+	}
+}
+
+// Run syncs every interval and whenever trigger receives, until ctx is done.
+// Failed syncs are reported to onError, which may be nil.
+func (b *Booker) Run(ctx context.Context, interval time.Duration, trigger <-chan struct{}, onError func(error)) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		if _, err := b.Sync(ctx); err != nil && ctx.Err() == nil && onError != nil {
+			onError(err)
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		case <-trigger:
+		}
+	}
+}
+
+// IsFundingUpdate reports whether a USDⓈ-M user data stream message is the
+// balance update of a funding payment
+func IsFundingUpdate(data []byte) bool {
+	var event binanceperp.WSAccountUpdateEvent
+	if err := json.Unmarshal(data, &event); err != nil {
+		return false
+	}
+	return event.EventType == "ACCOUNT_UPDATE" && event.UpdateData.EventReasonType == "FUNDING_FEE"
+}
+
+// fundingFlow converts a funding income, negative when paid
+func fundingFlow(income binanceperp.Income) (pms.CashFlow, error) {
+	amount, err := strconv.ParseFloat(income.Income, 64)
+	if err != nil {
+		return pms.CashFlow{}, fmt.Errorf("income %d: invalid amount %q", income.TranID, income.Income)
+	}
+	return pms.CashFlow{
+		ID:        "funding-" + strconv.FormatInt(income.TranID, 10),
+		Kind:      pms.CashFlowFunding,
+		Symbol:    pair(income.Symbol, income.Asset),
+		Asset:     income.Asset,
+		Amount:    amount,
+		Timestamp: income.Time,
+	}, nil
+}
+
+// interestFlow converts a margin interest charge, which is always paid
+func interestFlow(row binance.MarginInterest) (pms.CashFlow, error) {
+	interest, err := strconv.ParseFloat(row.Interest, 64)
+	if err != nil {
+		return pms.CashFlow{}, fmt.Errorf("interest %d: invalid amount %q", row.TxId, row.Interest)
+	}
+	return pms.CashFlow{
+		ID:        "interest-" + strconv.FormatInt(row.TxId, 10),
+		Kind:      pms.CashFlowInterest,
+		Symbol:    pair(row.IsolatedSymbol, row.RawAsset),
+		Asset:     row.Asset,
+		Amount:    -interest,
+		Timestamp: row.InterestAccuredTime,
+	}, nil
+}
+
+// pair converts a Binance symbol into the BASE-QUOTE form when asset is one
+// of its sides, and drops it otherwise: the histories do not say where the
+// base ends. Funding of USDⓈ-M contracts is paid in the quote asset and
+// isolated margin loans are taken in either asset of the pair.
+func pair(symbol, asset string) string {
+	if asset == "" {
+		return ""
+	}
+	if base, ok := strings.CutSuffix(symbol, asset); ok && base != "" {
+		return base + "-" + asset
+	}
+	if quote, ok := strings.CutPrefix(symbol, asset); ok && quote != "" {
+		return asset + "-" + quote
+	}
+	return ""
+}
@@ -0,0 +1,188 @@
+package accrual
+
+import (
+	"context"
+	"errors"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/BullionBear/sequex/internal/pms"
+	"github.com/BullionBear/sequex/pkg/exchange/binance"
+	"github.com/BullionBear/sequex/pkg/exchange/binanceperp"
+)
+
+var now = time.Date(2026, 10, 15, 12, 0, 0, 0, time.UTC)
+
+type fakeFunding struct {
+	incomes  []binanceperp.Income
+	requests []binanceperp.GetIncomeHistoryRequest
+	err      error
+}
+
+func (f *fakeFunding) GetIncomeHistory(_ context.Context, req binanceperp.GetIncomeHistoryRequest) (binanceperp.Response[[]binanceperp.Income], error) {
+	f.requests = append(f.requests, req)
+	if f.err != nil {
+		return binanceperp.Response[[]binanceperp.Income]{}, f.err
+	}
+	page := []binanceperp.Income{}
+	for _, income := range f.incomes {
+		if income.Time >= req.StartTime && income.Time <= req.EndTime && len(page) < req.Limit {
+			page = append(page, income)
+		}
+	}
+	return binanceperp.Response[[]binanceperp.Income]{Data: &page}, nil
+}
+
+type fakeInterest struct {
+	rows     []binance.MarginInterest
+	requests []binance.GetMarginInterestHistoryRequest
+}
+
+func (f *fakeInterest) GetMarginInterestHistory(_ context.Context, req binance.GetMarginInterestHistoryRequest) (binance.Response[binance.MarginInterestHistoryResponse], error) {
+	f.requests = append(f.requests, req)
+	var matched []binance.MarginInterest
+	for _, row := range f.rows {
+		if row.InterestAccuredTime >= req.StartTime && row.InterestAccuredTime <= req.EndTime {
+			matched = append(matched, row)
+		}
+	}
+	resp := binance.MarginInterestHistoryResponse{Total: int64(len(matched))}
+	if start := (req.Current - 1) * req.Size; start < len(matched) {
+		resp.Rows = matched[start:min(start+req.Size, len(matched))]
+	}
+	return binance.Response[binance.MarginInterestHistoryResponse]{Data: &resp}, nil
+}
+
+func funding(id int64, at time.Time, amount string) binanceperp.Income {
+	return binanceperp.Income{Symbol: "BTCUSDT", IncomeType: "FUNDING_FEE", Income: amount, Asset: "USDT", Time: at.UnixMilli(), TranID: id}
+}
+
+func newStore(t *testing.T) pms.Store {
+	t.Helper()
+	store := pms.NewMemoryStore()
+	if _, err := store.PutPortfolio(pms.Portfolio{ID: "main", Name: "Main"}, 0); err != nil {
+		t.Fatal(err)
+	}
+	return store
+}
+
+func TestSyncBooksOnce(t *testing.T) {
+	store := newStore(t)
+	perp := &fakeFunding{incomes: []binanceperp.Income{
+		funding(1, now.Add(-10*24*time.Hour), "-9"), // Before the lookback
+		funding(2, now.Add(-16*time.Hour), "-1.5"),
+		funding(3, now.Add(-8*time.Hour), "0.25"),
+	}}
+	margin := &fakeInterest{rows: []binance.MarginInterest{
+		{TxId: 7, InterestAccuredTime: now.Add(-time.Hour).UnixMilli(), Asset: "USDT", RawAsset: "USDT", Interest: "0.02", IsolatedSymbol: "BTCUSDT"},
+		{TxId: 8, InterestAccuredTime: now.Add(-time.Hour).UnixMilli(), Asset: "BNB", RawAsset: "BTC", Interest: "0.0001"},
+	}}
+	b, err := NewBooker(store, perp, margin, Options{Portfolio: "main"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	b.now = func() time.Time { return now }
+
+	if n, err := b.Sync(context.Background()); err != nil || n != 4 {
+		t.Fatalf("first sync: %d, %v", n, err)
+	}
+	if req := perp.requests[0]; req.IncomeType != "FUNDING_FEE" || req.StartTime != now.Add(-7*24*time.Hour).UnixMilli() {
+		t.Errorf("first request = %+v", req)
+	}
+
+	perp.incomes = append(perp.incomes, funding(4, now.Add(30*time.Minute), "-0.75"))
+	b.now = func() time.Time { return now.Add(time.Hour) }
+	if n, err := b.Sync(context.Background()); err != nil || n != 1 {
+		t.Fatalf("second sync: %d, %v", n, err)
+	}
+	if req := perp.requests[1]; req.StartTime != now.Add(-8*time.Hour).UnixMilli() {
+		t.Errorf("second request starts at %d, want the latest payment booked", req.StartTime)
+	}
+
+	flows, err := store.CashFlows("main")
+	if err != nil || len(flows) != 5 {
+		t.Fatalf("cash flows = %+v, %v", flows, err)
+	}
+	if c := flows[0]; c.ID != "funding-2" || c.Kind != pms.CashFlowFunding || c.Symbol != "BTC-USDT" || c.Amount != -1.5 {
+		t.Errorf("funding = %+v", c)
+	}
+	interest := map[string]pms.CashFlow{}
+	for _, c := range flows {
+		if c.Kind == pms.CashFlowInterest {
+			interest[c.ID] = c
+		}
+	}
+	if c := interest["interest-7"]; c.Symbol != "BTC-USDT" || c.Amount != -0.02 {
+		t.Errorf("isolated interest = %+v", c)
+	}
+	if c := interest["interest-8"]; c.Symbol != "" || c.Asset != "BNB" || c.Amount != -0.0001 {
+		t.Errorf("cross interest = %+v", c)
+	}
+}
+
+func TestSyncPages(t *testing.T) {
+	store := newStore(t)
+	perp := &fakeFunding{}
+	for i := 0; i < fundingPageSize+10; i++ {
+		perp.incomes = append(perp.incomes, funding(int64(i), now.Add(-time.Duration(fundingPageSize+10-i)*time.Minute), "-0.01"))
+	}
+	margin := &fakeInterest{}
+	for i := 0; i < interestPageSize+1; i++ {
+		margin.rows = append(margin.rows, binance.MarginInterest{TxId: int64(i), InterestAccuredTime: now.Add(-time.Duration(i) * time.Minute).UnixMilli(), Asset: "USDT", Interest: strconv.Itoa(i)})
+	}
+	b, err := NewBooker(store, perp, margin, Options{Portfolio: "main"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	b.now = func() time.Time { return now }
+
+	if n, err := b.Sync(context.Background()); err != nil || n != fundingPageSize+10+interestPageSize+1 {
+		t.Fatalf("sync: %d, %v", n, err)
+	}
+	if len(perp.requests) != 2 || len(margin.requests) != 2 || margin.requests[1].Current != 2 {
+		t.Errorf("%d funding and %d interest requests", len(perp.requests), len(margin.requests))
+	}
+}
+
+func TestSyncRetriesFailedSource(t *testing.T) {
+	store := newStore(t)
+	perp := &fakeFunding{incomes: []binanceperp.Income{funding(1, now.Add(-time.Hour), "-1")}, err: errors.New("timeout")}
+	b, err := NewBooker(store, perp, nil, Options{Portfolio: "main"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	b.now = func() time.Time { return now }
+
+	if _, err := b.Sync(context.Background()); err == nil {
+		t.Fatal("failure not reported")
+	}
+	perp.err = nil
+	if n, err := b.Sync(context.Background()); err != nil || n != 1 {
+		t.Errorf("retry: %d, %v", n, err)
+	}
+
+	missing, err := NewBooker(store, perp, nil, Options{Portfolio: "other"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := missing.Sync(context.Background()); !errors.Is(err, pms.ErrNotFound) {
+		t.Errorf("sync to missing portfolio: %v", err)
+	}
+	if _, err := NewBooker(store, nil, nil, Options{Portfolio: "main"}); err == nil {
+		t.Error("booker without sources accepted")
+	}
+}
+
+func TestIsFundingUpdate(t *testing.T) {
+	for data, want := range map[string]bool{
+		`{"e":"ACCOUNT_UPDATE","E":1,"T":1,"a":{"m":"FUNDING_FEE","B":[{"a":"USDT","wb":"100","cw":"100","bc":"-0.1"}],"P":[]}}`: true,
+		`{"e":"ACCOUNT_UPDATE","E":1,"T":1,"a":{"m":"ORDER","B":[],"P":[]}}`:                                                     false,
+		`{"e":"ORDER_TRADE_UPDATE","E":1}`: false,
+		`not json`:                         false,
+	} {
+		if got := IsFundingUpdate([]byte(data)); got != want {
+			t.Errorf("IsFundingUpdate(%s) = %v", data, got)
+		}
+	}
+}
@@ -0,0 +1,244 @@
+// Package option normalizes the option market data of exchanges into the sqx
+// option models, so options flow can be captured alongside spot and perps.
+package option
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+
+	"github.com/BullionBear/sequex/internal/model/sqx"
+	"github.com/BullionBear/sequex/pkg/exchange/binanceoptions"
+	"github.com/BullionBear/sequex/pkg/logger"
+)
+
+type TradeCallback func(trade sqx.OptionTrade) error
+
+type MarkCallback func(mark sqx.OptionMark) error
+
+// BinanceAdapter streams the trades and marks of the options listed on an
+// underlying on Binance
+type BinanceAdapter struct {
+	wsClient *binanceoptions.WSClient
+}
+
+func NewBinanceAdapter(config *binanceoptions.WSConfig) *BinanceAdapter {
+	return &BinanceAdapter{wsClient: binanceoptions.NewWSClient(config)}
+}
+
+// SubscribeTrades streams the trades of every option of the underlying
+func (a *BinanceAdapter) SubscribeTrades(underlying sqx.Symbol, callback TradeCallback) (func(), error) {
+	return a.wsClient.SubscribeTrade(underlying.Base, (&binanceoptions.TradeSubscriptionOptions{}).
+		WithError(func(err error) {
+			logger.Log.Error().Err(err).Str("underlying", underlying.String()).Msg("Option trade stream error")
+		}).
+		WithTrade(func(wsTrade binanceoptions.WSTrade) {
+			trade, err := ConvertBinanceTrade(underlying, wsTrade)
+			if err != nil {
+				logger.Log.Error().Err(err).Str("symbol", wsTrade.Symbol).Str("tradeId", wsTrade.TradeId).Msg("Failed to convert option trade")
+				return
+			}
+			if err := callback(trade); err != nil {
+				logger.Log.Error().Err(err).Msgf("Failed to publish option trade: %s", trade.IdStr())
+			}
+		}))
+}
+
+// SubscribeMarks streams the mark prices of every option of the underlying.
+// The stream only carries prices, the greeks are in the ticker of each
+// contract.
+func (a *BinanceAdapter) SubscribeMarks(underlying sqx.Symbol, callback MarkCallback) (func(), error) {
+	return a.wsClient.SubscribeMarkPrice(underlying.Base, (&binanceoptions.MarkPriceSubscriptionOptions{}).
+		WithError(func(err error) {
+			logger.Log.Error().Err(err).Str("underlying", underlying.String()).Msg("Option mark price stream error")
+		}).
+		WithMarkPrice(func(wsMark binanceoptions.WSMarkPrice) {
+			mark, err := ConvertBinanceMarkPrice(underlying, wsMark)
+			if err != nil {
+				logger.Log.Error().Err(err).Str("symbol", wsMark.Symbol).Msg("Failed to convert option mark price")
+				return
+			}
+			if err := callback(mark); err != nil {
+				logger.Log.Error().Err(err).Msgf("Failed to publish option mark: %s", mark.IdStr())
+			}
+		}))
+}
+
+// SubscribeTicker streams the best prices, implied volatilities and greeks
+// of one contract
+func (a *BinanceAdapter) SubscribeTicker(contract sqx.OptionContract, callback MarkCallback) (func(), error) {
+	symbol := BinanceSymbol(contract)
+	return a.wsClient.SubscribeTicker(symbol, (&binanceoptions.TickerSubscriptionOptions{}).
+		WithError(func(err error) {
+			logger.Log.Error().Err(err).Str("symbol", symbol).Msg("Option ticker stream error")
+		}).
+		WithTicker(func(wsTicker binanceoptions.WSTicker) {
+			mark, err := ConvertBinanceTicker(contract, wsTicker)
+			if err != nil {
+				logger.Log.Error().Err(err).Str("symbol", symbol).Msg("Failed to convert option ticker")
+				return
+			}
+			if err := callback(mark); err != nil {
+				logger.Log.Error().Err(err).Msgf("Failed to publish option mark: %s", mark.IdStr())
+			}
+		}))
+}
+
+// Close ends every subscription of the adapter
+func (a *BinanceAdapter) Close() {
+	a.wsClient.Close()
+}
+
+// BinanceSymbol returns the Binance symbol of a contract, e.g.
+// BTC-250328-90000-C, which leaves the quote asset of the underlying out
+func BinanceSymbol(contract sqx.OptionContract) string {
+	name := contract.String()
+	return contract.Underlying.Base + strings.TrimPrefix(name, contract.Underlying.String())
+}
+
+// ParseBinanceSymbol parses the Binance symbol of an option on underlying
+func ParseBinanceSymbol(underlying sqx.Symbol, symbol string) (sqx.OptionContract, error) {
+	rest, ok := strings.CutPrefix(symbol, underlying.Base+"-")
+	if !ok {
+		return sqx.OptionContract{}, fmt.Errorf("%s is not an option on %s", symbol, underlying)
+	}
+	return sqx.NewOptionContractFromStr(underlying.String() + "-" + rest)
+}
+
+// ConvertBinanceContracts normalizes the contracts listed in the exchange
+// information, keyed by Binance symbol
+func ConvertBinanceContracts(info binanceoptions.ExchangeInfo) (map[string]sqx.OptionContract, error) {
+	underlyings := make(map[string]sqx.Symbol, len(info.OptionContracts))
+	for _, c := range info.OptionContracts {
+		underlyings[c.Underlying] = sqx.NewSymbol(c.BaseAsset, c.QuoteAsset)
+	}
+	contracts := make(map[string]sqx.OptionContract, len(info.OptionSymbols))
+	for _, s := range info.OptionSymbols {
+		underlying, ok := underlyings[s.Underlying]
+		if !ok {
+			return nil, fmt.Errorf("unknown underlying %s of %s", s.Underlying, s.Symbol)
+		}
+		strike, err := strconv.ParseFloat(s.StrikePrice, 64)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse strike price %q of %s: %w", s.StrikePrice, s.Symbol, err)
+		}
+		kind := sqx.NewOptionKind(s.Side)
+		if kind == sqx.OptionKindUnknown {
+			return nil, fmt.Errorf("unknown side %s of %s", s.Side, s.Symbol)
+		}
+		contracts[s.Symbol] = sqx.OptionContract{Underlying: underlying, Expiry: s.ExpiryDate, Strike: strike, Kind: kind}
+	}
+	return contracts, nil
+}
+
+// ConvertBinanceTrade normalizes a trade of an option on underlying
+func ConvertBinanceTrade(underlying sqx.Symbol, wsTrade binanceoptions.WSTrade) (sqx.OptionTrade, error) {
+	contract, err := ParseBinanceSymbol(underlying, wsTrade.Symbol)
+	if err != nil {
+		return sqx.OptionTrade{}, err
+	}
+	id, err := strconv.ParseInt(wsTrade.TradeId, 10, 64)
+	if err != nil {
+		return sqx.OptionTrade{}, fmt.Errorf("failed to parse trade id %q: %w", wsTrade.TradeId, err)
+	}
+	price, err := strconv.ParseFloat(wsTrade.Price, 64)
+	if err != nil {
+		return sqx.OptionTrade{}, fmt.Errorf("failed to parse price %q: %w", wsTrade.Price, err)
+	}
+	quantity, err := strconv.ParseFloat(wsTrade.Quantity, 64)
+	if err != nil {
+		return sqx.OptionTrade{}, fmt.Errorf("failed to parse quantity %q: %w", wsTrade.Quantity, err)
+	}
+	takerSide := sqx.SideUnknown
+	switch wsTrade.Direction {
+	case binanceoptions.TradeDirectionBuy:
+		takerSide = sqx.SideBuy
+	case binanceoptions.TradeDirectionSell:
+		takerSide = sqx.SideSell
+	}
+	return sqx.OptionTrade{
+		Id:        id,
+		Exchange:  sqx.ExchangeBinanceOptions,
+		Contract:  contract,
+		TakerSide: takerSide,
+		Price:     price,
+		Quantity:  math.Abs(quantity), // Signed by direction on some messages
+		Timestamp: wsTrade.TradeTime,
+	}, nil
+}
+
+// ConvertBinanceMarkPrice normalizes the mark price of an option on
+// underlying
+func ConvertBinanceMarkPrice(underlying sqx.Symbol, wsMark binanceoptions.WSMarkPrice) (sqx.OptionMark, error) {
+	contract, err := ParseBinanceSymbol(underlying, wsMark.Symbol)
+	if err != nil {
+		return sqx.OptionMark{}, err
+	}
+	markPrice, err := strconv.ParseFloat(wsMark.MarkPrice, 64)
+	if err != nil {
+		return sqx.OptionMark{}, fmt.Errorf("failed to parse mark price %q: %w", wsMark.MarkPrice, err)
+	}
+	return sqx.OptionMark{
+		Exchange:  sqx.ExchangeBinanceOptions,
+		Contract:  contract,
+		MarkPrice: markPrice,
+		Timestamp: wsMark.EventTime,
+	}, nil
+}
+
+// ConvertBinanceTicker normalizes the ticker of contract
+func ConvertBinanceTicker(contract sqx.OptionContract, wsTicker binanceoptions.WSTicker) (sqx.OptionMark, error) {
+	mark := sqx.OptionMark{Exchange: sqx.ExchangeBinanceOptions, Contract: contract, Timestamp: wsTicker.EventTime}
+	err := parseFloats(map[string]parsed{
+		"mark price": {wsTicker.MarkPrice, &mark.MarkPrice},
+		"bid price":  {wsTicker.BestBuyPrice, &mark.BidPrice},
+		"ask price":  {wsTicker.BestSellPrice, &mark.AskPrice},
+		"mark iv":    {wsTicker.MarkIV, &mark.MarkIV},
+		"bid iv":     {wsTicker.BuyIV, &mark.BidIV},
+		"ask iv":     {wsTicker.SellIV, &mark.AskIV},
+		"delta":      {wsTicker.Delta, &mark.Delta},
+		"gamma":      {wsTicker.Gamma, &mark.Gamma},
+		"vega":       {wsTicker.Vega, &mark.Vega},
+		"theta":      {wsTicker.Theta, &mark.Theta},
+	})
+	return mark, err
+}
+
+// ConvertBinanceMark normalizes the mark of contract polled at timestamp,
+// the endpoint not timing its marks
+func ConvertBinanceMark(contract sqx.OptionContract, m binanceoptions.Mark, timestamp int64) (sqx.OptionMark, error) {
+	mark := sqx.OptionMark{Exchange: sqx.ExchangeBinanceOptions, Contract: contract, Timestamp: timestamp}
+	err := parseFloats(map[string]parsed{
+		"mark price": {m.MarkPrice, &mark.MarkPrice},
+		"mark iv":    {m.MarkIV, &mark.MarkIV},
+		"bid iv":     {m.BidIV, &mark.BidIV},
+		"ask iv":     {m.AskIV, &mark.AskIV},
+		"delta":      {m.Delta, &mark.Delta},
+		"gamma":      {m.Gamma, &mark.Gamma},
+		"vega":       {m.Vega, &mark.Vega},
+		"theta":      {m.Theta, &mark.Theta},
+	})
+	return mark, err
+}
+
+// parsed is a decimal string and the field it is parsed into
+type parsed struct {
+	value string
+	into  *float64
+}
+
+// parseFloats parses every named value, leaving the empty ones at 0
+func parseFloats(values map[string]parsed) error {
+	for name, p := range values {
+		if p.value == "" {
+			continue
+		}
+		f, err := strconv.ParseFloat(p.value, 64)
+		if err != nil {
+			return fmt.Errorf("failed to parse %s %q: %w", name, p.value, err)
+		}
+		*p.into = f
+	}
+	return nil
+}
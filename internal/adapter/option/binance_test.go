@@ -0,0 +1,56 @@
+package option
+
+import (
+	"testing"
+	"time"
+
+	"github.com/BullionBear/sequex/internal/model/sqx"
+	"github.com/BullionBear/sequex/pkg/exchange/binanceoptions"
+)
+
+func TestConvertBinance(t *testing.T) {
+	btc := sqx.NewSymbol("BTC", "USDT")
+	expiry := time.Date(2025, 3, 28, 8, 0, 0, 0, time.UTC).UnixMilli()
+	call := sqx.OptionContract{Underlying: btc, Expiry: expiry, Strike: 90000, Kind: sqx.OptionKindCall}
+
+	contracts, err := ConvertBinanceContracts(binanceoptions.ExchangeInfo{
+		OptionContracts: []binanceoptions.OptionContract{{BaseAsset: "BTC", QuoteAsset: "USDT", Underlying: "BTCUSDT"}},
+		OptionSymbols:   []binanceoptions.OptionSymbol{{Symbol: "BTC-250328-90000-C", Side: binanceoptions.OptionSideCall, StrikePrice: "90000.00000000", Underlying: "BTCUSDT", ExpiryDate: expiry}},
+	})
+	if err != nil || contracts["BTC-250328-90000-C"] != call {
+		t.Errorf("ConvertBinanceContracts() = %+v, %v", contracts, err)
+	}
+	if symbol := BinanceSymbol(call); symbol != "BTC-250328-90000-C" {
+		t.Errorf("BinanceSymbol() = %s", symbol)
+	}
+
+	trade, err := ConvertBinanceTrade(btc, binanceoptions.WSTrade{Symbol: "BTC-250328-90000-C", TradeId: "315", Price: "1250", Quantity: "-0.5", TradeTime: 1743062400120, Direction: binanceoptions.TradeDirectionSell})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := sqx.OptionTrade{Id: 315, Exchange: sqx.ExchangeBinanceOptions, Contract: call, TakerSide: sqx.SideSell, Price: 1250, Quantity: 0.5, Timestamp: 1743062400120}
+	if trade != want {
+		t.Errorf("ConvertBinanceTrade() = %+v, want %+v", trade, want)
+	}
+	if _, err := ConvertBinanceTrade(btc, binanceoptions.WSTrade{Symbol: "ETH-250328-2000-C", TradeId: "1", Price: "1", Quantity: "1"}); err == nil {
+		t.Error("ConvertBinanceTrade() of another underlying succeeded")
+	}
+
+	mark, err := ConvertBinanceMarkPrice(btc, binanceoptions.WSMarkPrice{Symbol: "BTC-250328-90000-C", MarkPrice: "2016.5", EventTime: 1743062400123})
+	if err != nil || mark.Contract != call || mark.MarkPrice != 2016.5 || mark.Timestamp != 1743062400123 {
+		t.Errorf("ConvertBinanceMarkPrice() = %+v, %v", mark, err)
+	}
+
+	mark, err = ConvertBinanceTicker(call, binanceoptions.WSTicker{EventTime: 1743062400123, MarkPrice: "2016.5", BestBuyPrice: "2012", BestSellPrice: "2020", MarkIV: "0.526", BuyIV: "0.52", SellIV: "0.53", Delta: "0.49", Gamma: "0.00004", Vega: "72.6", Theta: "-96.1"})
+	if err != nil || mark.BidPrice != 2012 || mark.AskPrice != 2020 || mark.MarkIV != 0.526 || mark.Delta != 0.49 || mark.Theta != -96.1 {
+		t.Errorf("ConvertBinanceTicker() = %+v, %v", mark, err)
+	}
+	if _, err := ConvertBinanceTicker(call, binanceoptions.WSTicker{MarkPrice: "n/a"}); err == nil {
+		t.Error("ConvertBinanceTicker() of an invalid price succeeded")
+	}
+
+	mark, err = ConvertBinanceMark(call, binanceoptions.Mark{MarkPrice: "1343.2", MarkIV: "0.54", Delta: "0.55"}, 1743062400000)
+	if err != nil || mark.MarkPrice != 1343.2 || mark.MarkIV != 0.54 || mark.BidPrice != 0 || mark.Timestamp != 1743062400000 {
+		t.Errorf("ConvertBinanceMark() = %+v, %v", mark, err)
+	}
+}
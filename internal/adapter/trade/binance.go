@@ -33,43 +33,13 @@ func (a *BinanceTradeAdapter) Subscribe(symbol sqx.Symbol, instrumentType sqx.In
 	binanceSymbol := fmt.Sprintf("%s%s", symbol.Base, symbol.Quote)
 	return a.wsClient.SubscribeTrade(binanceSymbol, binance.TradeSubscriptionOptions{
 		OnTrade: func(wsTrade binance.WSTrade) {
-			logger.Log.Info().Msgf("Received trade: %+v", wsTrade)
-			takerSide := sqx.SideBuy
-			if wsTrade.IsBuyerMaker {
-				takerSide = sqx.SideSell
-			}
-			price, err := strconv.ParseFloat(wsTrade.Price, 64)
-			if err != nil {
-				logger.Log.Error().Err(err).Msgf("Failed to parse price: %s", wsTrade.Price)
-				return
-			}
-			quantity, err := strconv.ParseFloat(wsTrade.Quantity, 64)
-			if err != nil {
-				logger.Log.Error().Err(err).Msgf("Failed to parse quantity: %s", wsTrade.Quantity)
-				return
-			}
-			base, err := binance.GetBaseAsset(wsTrade.Symbol)
-			if err != nil {
-				logger.Log.Error().Err(err).Msgf("Failed to get base asset: %s", wsTrade.Symbol)
-				return
-			}
-			quote, err := binance.GetQuoteAsset(wsTrade.Symbol)
+			// Field logging at trace level costs nothing when the level is disabled
+			logger.Log.Trace().Str("symbol", wsTrade.Symbol).Int64("tradeId", wsTrade.TradeId).Msg("Received trade")
+			trade, err := convertTrade(symbol, wsTrade)
 			if err != nil {
-				logger.Log.Error().Err(err).Msgf("Failed to get quote asset: %s", wsTrade.Symbol)
+				logger.Log.Error().Err(err).Int64("tradeId", wsTrade.TradeId).Msg("Failed to convert trade")
 				return
 			}
-
-			trade := sqx.Trade{
-				Id:             wsTrade.TradeId,
-				Symbol:         sqx.NewSymbol(base, quote),
-				Exchange:       sqx.ExchangeBinance,
-				InstrumentType: sqx.InstrumentTypeSpot,
-				TakerSide:      takerSide,
-				Price:          price,
-				Quantity:       quantity,
-				Timestamp:      wsTrade.TradeTime,
-			}
-
 			if err := callback(trade); err != nil {
 				logger.Log.Error().Err(err).Msgf("Failed to publish trade: %s", trade.IdStr())
 				return
@@ -77,3 +47,30 @@ func (a *BinanceTradeAdapter) Subscribe(symbol sqx.Symbol, instrumentType sqx.In
 		},
 	})
 }
+
+// convertTrade maps a raw Binance trade onto the subscribed symbol. The stream
+// only carries that symbol, so no exchange info lookup is needed per trade.
+func convertTrade(symbol sqx.Symbol, wsTrade binance.WSTrade) (sqx.Trade, error) {
+	takerSide := sqx.SideBuy
+	if wsTrade.IsBuyerMaker {
+		takerSide = sqx.SideSell
+	}
+	price, err := strconv.ParseFloat(wsTrade.Price, 64)
+	if err != nil {
+		return sqx.Trade{}, fmt.Errorf("failed to parse price %q: %w", wsTrade.Price, err)
+	}
+	quantity, err := strconv.ParseFloat(wsTrade.Quantity, 64)
+	if err != nil {
+		return sqx.Trade{}, fmt.Errorf("failed to parse quantity %q: %w", wsTrade.Quantity, err)
+	}
+	return sqx.Trade{
+		Id:             wsTrade.TradeId,
+		Symbol:         symbol,
+		Exchange:       sqx.ExchangeBinance,
+		InstrumentType: sqx.InstrumentTypeSpot,
+		TakerSide:      takerSide,
+		Price:          price,
+		Quantity:       quantity,
+		Timestamp:      wsTrade.TradeTime,
+	}, nil
+}
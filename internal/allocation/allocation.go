@@ -0,0 +1,524 @@
+// Package allocation grants each strategy a notional budget from a
+// portfolio and enforces it on order entry: the positions of a strategy and
+// its working orders, as if they all filled, must stay within its budget.
+// Allocations are adjusted at runtime, each change recorded in an audit
+// trail journaled to disk.
+package allocation
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/BullionBear/sequex/internal/model/sqx"
+	"github.com/BullionBear/sequex/internal/pms"
+)
+
+// DefaultCurrency is the currency of budgets when none is configured
+const DefaultCurrency = "USDT"
+
+// auditSize is the number of changes a Book keeps in memory, the journal
+// keeping all of them
+const auditSize = 100
+
+var (
+	ErrNotAllocated = errors.New("not allocated")
+	ErrOverBudget   = errors.New("over budget")
+	ErrInvalid      = errors.New("invalid allocation")
+)
+
+// Allocation is the budget a strategy is granted from a portfolio
+type Allocation struct {
+	Strategy  string  `json:"strategy"`  // As tagged on its orders, e.g. fix.CLIENT
+	Portfolio string  `json:"portfolio"` // Portfolio the budget is granted from
+	Budget    float64 `json:"budget"`    // Notional in the currency of the book, 0 stops new exposure
+}
+
+// Validate validates the allocation
+func (a Allocation) Validate() error {
+	if a.Strategy == "" {
+		return fmt.Errorf("%w: strategy cannot be empty", ErrInvalid)
+	}
+	if err := pms.ValidateID(a.Portfolio); err != nil {
+		return fmt.Errorf("%w: %s: %v", ErrInvalid, a.Strategy, err)
+	}
+	if a.Budget < 0 || math.IsNaN(a.Budget) || math.IsInf(a.Budget, 0) {
+		return fmt.Errorf("%w: %s: budget must be a finite number, not negative", ErrInvalid, a.Strategy)
+	}
+	return nil
+}
+
+// Change is an entry of the audit trail
+type Change struct {
+	Strategy string      `json:"strategy"`
+	Before   *Allocation `json:"before,omitempty"` // Nil when the allocation was created
+	After    *Allocation `json:"after,omitempty"`  // Nil when the allocation was removed
+	By       string      `json:"by"`
+	Reason   string      `json:"reason,omitempty"`
+	At       time.Time   `json:"at"`
+}
+
+// Usage is an allocation and the part of its budget in use
+type Usage struct {
+	Allocation
+	Positions float64 `json:"positions"` // Notional of the positions
+	Working   float64 `json:"working"`   // Notional of the working orders
+	Exposure  float64 `json:"exposure"`  // Worst case of the positions once the working orders of either side fill
+	Available float64 `json:"available"` // Budget less exposure, negative once prices moved the exposure over budget
+	Error     string  `json:"error,omitempty"`
+}
+
+// Options configures a Book
+type Options struct {
+	Currency string             // Of budgets and notionals, DefaultCurrency when empty
+	Capital  map[string]float64 // Capital of each portfolio, which its allocations cannot exceed in total. Portfolios without capital are not bounded.
+	Prices   pms.Converter      // Marks positions and orders without a price, e.g. fx.Rates; fill and order prices are used when nil or without rate
+	Journal  string             // JSON lines file of the changes, replayed on open. In memory only when empty.
+}
+
+// order is a working order of a strategy
+type order struct {
+	strategy string
+	symbol   sqx.Symbol
+	side     sqx.Side
+	price    float64
+	leaves   float64
+}
+
+// position is the net quantity of a strategy in a symbol
+type position struct {
+	quantity float64 // Signed, negative when short
+	price    float64 // Of the last fill
+}
+
+// orderKey identifies an order across strategies, which may reuse client
+// order ids, e.g. those of different tenants
+func orderKey(strategy, clientOrderID string) string {
+	return strategy + "/" + clientOrderID
+}
+
+// Book tracks the allocations, working orders and positions of strategies.
+// It is safe for concurrent use.
+type Book struct {
+	opts Options
+
+	mu          sync.Mutex
+	allocations map[string]Allocation               // By strategy
+	orders      map[string]*order                   // By strategy and client order id, see orderKey
+	positions   map[string]map[sqx.Symbol]*position // By strategy and symbol
+	audit       []Change
+	journal     *os.File
+	onChange    []func(Change)
+}
+
+// Open creates a book of the configured allocations, then replays the
+// changes of the journal, so changes made at runtime take precedence over
+// the configuration across restarts.
+func Open(allocations []Allocation, opts Options) (*Book, error) {
+	if opts.Currency == "" {
+		opts.Currency = DefaultCurrency
+	}
+	b := &Book{
+		opts:        opts,
+		allocations: make(map[string]Allocation, len(allocations)),
+		orders:      make(map[string]*order),
+		positions:   make(map[string]map[sqx.Symbol]*position),
+	}
+	for _, a := range allocations {
+		if err := a.Validate(); err != nil {
+			return nil, err
+		}
+		if _, ok := b.allocations[a.Strategy]; ok {
+			return nil, fmt.Errorf("%w: %s allocated twice", ErrInvalid, a.Strategy)
+		}
+		b.allocations[a.Strategy] = a
+	}
+	for portfolio := range opts.Capital {
+		if err := b.checkCapital(portfolio, "", 0); err != nil {
+			return nil, err
+		}
+	}
+	if opts.Journal == "" {
+		return b, nil
+	}
+	f, err := os.OpenFile(opts.Journal, os.O_CREATE|os.O_RDWR|os.O_APPEND, 0o644)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open journal %s: %w", opts.Journal, err)
+	}
+	size, err := b.replay(f)
+	if err == nil {
+		// Drop a truncated last line so the next change starts on its own line
+		err = f.Truncate(size)
+	}
+	if err != nil {
+		f.Close()
+		return nil, fmt.Errorf("failed to replay journal %s: %w", opts.Journal, err)
+	}
+	b.journal = f
+	return b, nil
+}
+
+// replay applies the journaled changes and returns the size of the lines
+// read. A truncated last line, left by a crash during a write, is skipped.
+func (b *Book) replay(r io.Reader) (int64, error) {
+	reader := bufio.NewReader(r)
+	var size int64
+	for line := 1; ; line++ {
+		data, err := reader.ReadBytes('\n')
+		if err == io.EOF {
+			return size, nil
+		}
+		if err != nil {
+			return size, err
+		}
+		var c Change
+		if err := json.Unmarshal(data, &c); err != nil {
+			return size, fmt.Errorf("line %d: %w", line, err)
+		}
+		b.apply(c)
+		size += int64(len(data))
+	}
+}
+
+// apply applies a change to the allocations and the audit trail
+func (b *Book) apply(c Change) {
+	if c.After != nil {
+		b.allocations[c.Strategy] = *c.After
+	} else {
+		delete(b.allocations, c.Strategy)
+	}
+	b.audit = append(b.audit, c)
+	if len(b.audit) > auditSize {
+		b.audit = b.audit[len(b.audit)-auditSize:]
+	}
+}
+
+// Close closes the journal
+func (b *Book) Close() error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.journal == nil {
+		return nil
+	}
+	err := b.journal.Close()
+	b.journal = nil
+	return err
+}
+
+// Currency returns the currency of budgets and notionals
+func (b *Book) Currency() string {
+	return b.opts.Currency
+}
+
+// OnChange calls fn with every change made at runtime, e.g. to log it
+func (b *Book) OnChange(fn func(Change)) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.onChange = append(b.onChange, fn)
+}
+
+// Set creates or updates the allocation of a strategy. A budget lowered
+// under the exposure of the strategy stops its new orders adding exposure
+// but does not cancel the working ones.
+func (b *Book) Set(a Allocation, by, reason string) (Change, error) {
+	if err := a.Validate(); err != nil {
+		return Change{}, err
+	}
+	b.mu.Lock()
+	var before *Allocation
+	if current, ok := b.allocations[a.Strategy]; ok {
+		before = &current
+	}
+	if err := b.checkCapital(a.Portfolio, a.Strategy, a.Budget); err != nil {
+		b.mu.Unlock()
+		return Change{}, err
+	}
+	return b.commit(Change{Strategy: a.Strategy, Before: before, After: &a, By: by, Reason: reason, At: time.Now().UTC()})
+}
+
+// Remove removes the allocation of a strategy, whose orders are rejected
+// from then on
+func (b *Book) Remove(strategy, by, reason string) (Change, error) {
+	b.mu.Lock()
+	current, ok := b.allocations[strategy]
+	if !ok {
+		b.mu.Unlock()
+		return Change{}, fmt.Errorf("%w: %s", ErrNotAllocated, strategy)
+	}
+	return b.commit(Change{Strategy: strategy, Before: &current, By: by, Reason: reason, At: time.Now().UTC()})
+}
+
+// commit journals and applies a change, then notifies the listeners. It
+// is called with the lock held and releases it.
+func (b *Book) commit(c Change) (Change, error) {
+	if c.By == "" {
+		b.mu.Unlock()
+		return Change{}, fmt.Errorf("%w: by cannot be empty", ErrInvalid)
+	}
+	if b.journal != nil {
+		data, err := json.Marshal(c)
+		if err == nil {
+			_, err = b.journal.Write(append(data, '\n'))
+		}
+		if err != nil {
+			b.mu.Unlock()
+			return Change{}, fmt.Errorf("failed to journal allocation change: %w", err)
+		}
+	}
+	b.apply(c)
+	listeners := b.onChange
+	b.mu.Unlock()
+	for _, fn := range listeners {
+		fn(c)
+	}
+	return c, nil
+}
+
+// checkCapital checks the allocations of a portfolio, with the one of
+// strategy set to budget, stay within its capital
+func (b *Book) checkCapital(portfolio, strategy string, budget float64) error {
+	capital, ok := b.opts.Capital[portfolio]
+	if !ok {
+		return nil
+	}
+	total := budget
+	for _, a := range b.allocations {
+		if a.Portfolio == portfolio && a.Strategy != strategy {
+			total += a.Budget
+		}
+	}
+	if total > capital {
+		return fmt.Errorf("%w: portfolio %s allocations of %g exceed its capital of %g %s", ErrInvalid, portfolio, total, capital, b.opts.Currency)
+	}
+	return nil
+}
+
+// History returns the latest changes, oldest first
+func (b *Book) History() []Change {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return append([]Change(nil), b.audit...)
+}
+
+// Allocations returns every allocation and its usage, ordered by strategy
+func (b *Book) Allocations() []Usage {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	list := make([]Usage, 0, len(b.allocations))
+	for _, a := range b.allocations {
+		list = append(list, b.usage(a))
+	}
+	sort.Slice(list, func(i, j int) bool { return list[i].Strategy < list[j].Strategy })
+	return list
+}
+
+// Usage returns the allocation of a strategy and its usage
+func (b *Book) Usage(strategy string) (Usage, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	a, ok := b.allocations[strategy]
+	if !ok {
+		return Usage{}, fmt.Errorf("%w: %s", ErrNotAllocated, strategy)
+	}
+	return b.usage(a), nil
+}
+
+func (b *Book) usage(a Allocation) Usage {
+	u := Usage{Allocation: a}
+	e, err := b.exposure(a.Strategy, nil)
+	if err != nil {
+		u.Error = err.Error()
+	}
+	u.Positions, u.Working, u.Exposure = e.positions, e.working, e.worst
+	u.Available = a.Budget - e.worst
+	return u
+}
+
+// Check checks an order keeps its strategy within budget and reserves it as
+// working. Orders not adding exposure, e.g. reducing a position, are
+// accepted over budget. Orders that cannot be valued are rejected.
+func (b *Book) Check(intent sqx.OrderIntent) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	a, ok := b.allocations[intent.Strategy]
+	if !ok {
+		return fmt.Errorf("%w: strategy %s", ErrNotAllocated, intent.Strategy)
+	}
+	key := orderKey(intent.Strategy, intent.ClientOrderId)
+	if _, ok := b.orders[key]; ok {
+		return fmt.Errorf("duplicate client order id %s", intent.ClientOrderId)
+	}
+	o := &order{strategy: intent.Strategy, symbol: intent.Symbol, side: intent.Side, price: intent.Price, leaves: intent.Quantity}
+	before, err := b.exposure(intent.Strategy, nil)
+	if err != nil {
+		return err
+	}
+	after, err := b.exposure(intent.Strategy, o)
+	if err != nil {
+		return err
+	}
+	if after.worst > a.Budget && after.worst > before.worst {
+		return fmt.Errorf("%w: strategy %s exposure would be %.2f %s, over its budget of %.2f", ErrOverBudget, intent.Strategy, after.worst, b.opts.Currency, a.Budget)
+	}
+	b.orders[key] = o
+	return nil
+}
+
+// Release releases an order reserved by Check that was not placed
+func (b *Book) Release(intent sqx.OrderIntent) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	delete(b.orders, orderKey(intent.Strategy, intent.ClientOrderId))
+}
+
+// OnExecutionReport updates the working orders and positions of the
+// strategy of an execution report. Working orders placed elsewhere, e.g.
+// before a restart, are tracked from their reports.
+func (b *Book) OnExecutionReport(report sqx.ExecutionReport) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if _, ok := b.allocations[report.Strategy]; !ok && !b.tracks(report.Strategy) {
+		return
+	}
+	key := orderKey(report.Strategy, report.ClientOrderId)
+	o, tracked := b.orders[key]
+	if !tracked && !report.Status.IsFinal() {
+		o = &order{strategy: report.Strategy, symbol: report.Symbol, side: report.Side, price: report.Price}
+		b.orders[key] = o
+	}
+	if o != nil {
+		o.leaves = report.LeavesQuantity()
+		if report.Status.IsFinal() {
+			delete(b.orders, key)
+		}
+	}
+	if !report.IsFill() {
+		return
+	}
+	positions, ok := b.positions[report.Strategy]
+	if !ok {
+		positions = make(map[sqx.Symbol]*position)
+		b.positions[report.Strategy] = positions
+	}
+	p, ok := positions[report.Symbol]
+	if !ok {
+		p = &position{}
+		positions[report.Symbol] = p
+	}
+	if report.Side == sqx.SideSell {
+		p.quantity -= report.LastQuantity
+	} else {
+		p.quantity += report.LastQuantity
+	}
+	p.price = report.LastPrice
+}
+
+// tracks reports whether the book holds orders or positions of a strategy,
+// which are followed after its allocation is removed until they are closed
+func (b *Book) tracks(strategy string) bool {
+	if _, ok := b.positions[strategy]; ok {
+		return true
+	}
+	for _, o := range b.orders {
+		if o.strategy == strategy {
+			return true
+		}
+	}
+	return false
+}
+
+// exposure is the notional of a strategy
+type exposure struct {
+	positions float64
+	working   float64
+	worst     float64
+}
+
+// leg is the position and working orders of a strategy in a symbol
+type leg struct {
+	quantity float64
+	buys     float64
+	sells    float64
+	price    float64 // Reference price when there is no mark
+}
+
+// exposure values the positions and working orders of a strategy, with
+// extra as an additional working order. The worst case of a symbol is its
+// position once every buy, or every sell, fills.
+func (b *Book) exposure(strategy string, extra *order) (exposure, error) {
+	legs := make(map[sqx.Symbol]*leg)
+	get := func(symbol sqx.Symbol) *leg {
+		l, ok := legs[symbol]
+		if !ok {
+			l = &leg{}
+			legs[symbol] = l
+		}
+		return l
+	}
+	for symbol, p := range b.positions[strategy] {
+		l := get(symbol)
+		l.quantity, l.price = p.quantity, p.price
+	}
+	add := func(o *order) {
+		l := get(o.symbol)
+		if o.side == sqx.SideSell {
+			l.sells += o.leaves
+		} else {
+			l.buys += o.leaves
+		}
+		if l.price == 0 {
+			l.price = o.price
+		}
+	}
+	for _, o := range b.orders {
+		if o.strategy == strategy {
+			add(o)
+		}
+	}
+	if extra != nil {
+		add(extra)
+	}
+	var e exposure
+	for symbol, l := range legs {
+		if l.quantity == 0 && l.buys == 0 && l.sells == 0 {
+			continue
+		}
+		mark, err := b.mark(symbol, l.price)
+		if err != nil {
+			return e, err
+		}
+		e.positions += math.Abs(l.quantity) * mark
+		e.working += (l.buys + l.sells) * mark
+		e.worst += math.Max(math.Abs(l.quantity+l.buys), math.Abs(l.quantity-l.sells)) * mark
+	}
+	return e, nil
+}
+
+// mark returns the price of one unit of the base of symbol in the currency
+// of the book, from the rates when available, else from price in the quote
+func (b *Book) mark(symbol sqx.Symbol, price float64) (float64, error) {
+	if b.opts.Prices != nil {
+		if rate, err := b.opts.Prices.Rate(symbol.Base, b.opts.Currency); err == nil {
+			return rate, nil
+		}
+	}
+	if price <= 0 {
+		return 0, fmt.Errorf("no price of %s in %s", symbol, b.opts.Currency)
+	}
+	if symbol.Quote == b.opts.Currency {
+		return price, nil
+	}
+	if b.opts.Prices != nil {
+		if rate, err := b.opts.Prices.Rate(symbol.Quote, b.opts.Currency); err == nil {
+			return price * rate, nil
+		}
+	}
+	return 0, fmt.Errorf("no rate from %s to %s", symbol.Quote, b.opts.Currency)
+}
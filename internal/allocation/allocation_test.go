@@ -0,0 +1,153 @@
+package allocation
+
+import (
+	"errors"
+	"fmt"
+	"path/filepath"
+	"testing"
+
+	"github.com/BullionBear/sequex/internal/model/sqx"
+)
+
+type fakeRates map[string]float64
+
+func (r fakeRates) Rate(from, to string) (float64, error) {
+	if from == to {
+		return 1, nil
+	}
+	if rate, ok := r[from+"-"+to]; ok {
+		return rate, nil
+	}
+	return 0, fmt.Errorf("no rate from %s to %s", from, to)
+}
+
+var btc = sqx.NewSymbol("BTC", "USDT")
+
+func intent(id string, side sqx.Side, quantity, price float64) sqx.OrderIntent {
+	return sqx.OrderIntent{ClientOrderId: id, Strategy: "grid", Symbol: btc, Side: side, Quantity: quantity, Price: price}
+}
+
+func fill(id string, side sqx.Side, quantity float64, status sqx.OrderStatus) sqx.ExecutionReport {
+	return sqx.ExecutionReport{ClientOrderId: id, Strategy: "grid", Symbol: btc, Side: side, Status: status, Quantity: quantity, CumQuantity: quantity, LastQuantity: quantity, LastPrice: 50000}
+}
+
+func TestCheckEnforcesBudget(t *testing.T) {
+	book, err := Open([]Allocation{{Strategy: "grid", Portfolio: "main", Budget: 100000}}, Options{Prices: fakeRates{"BTC-USDT": 50000}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := book.Check(intent("1", sqx.SideBuy, 1.5, 49000)); err != nil {
+		t.Fatalf("within budget: %v", err)
+	}
+	if err := book.Check(intent("2", sqx.SideBuy, 1, 49000)); !errors.Is(err, ErrOverBudget) {
+		t.Errorf("working orders over budget: %v", err)
+	}
+	// Sells offset the buys rather than adding to them
+	if err := book.Check(intent("3", sqx.SideSell, 1.5, 51000)); err != nil {
+		t.Errorf("sell against working buys: %v", err)
+	}
+	if err := book.Check(sqx.OrderIntent{ClientOrderId: "4", Strategy: "arb", Symbol: btc, Quantity: 1}); !errors.Is(err, ErrNotAllocated) {
+		t.Errorf("unallocated strategy: %v", err)
+	}
+
+	book.OnExecutionReport(fill("1", sqx.SideBuy, 1.5, sqx.OrderStatusFilled))
+	book.Release(intent("3", sqx.SideSell, 1.5, 51000))
+	u, err := book.Usage("grid")
+	if err != nil || u.Positions != 75000 || u.Working != 0 || u.Exposure != 75000 || u.Available != 25000 {
+		t.Errorf("usage after the fill: %+v, %v", u, err)
+	}
+	if err := book.Check(intent("5", sqx.SideBuy, 1, 0)); !errors.Is(err, ErrOverBudget) {
+		t.Errorf("market order over budget: %v", err)
+	}
+
+	// Lowering the budget under the exposure still lets the position be reduced
+	if _, err := book.Set(Allocation{Strategy: "grid", Portfolio: "main", Budget: 10000}, "ops", "drawdown"); err != nil {
+		t.Fatal(err)
+	}
+	if err := book.Check(intent("6", sqx.SideSell, 0.5, 0)); err != nil {
+		t.Errorf("reducing order over budget: %v", err)
+	}
+	if err := book.Check(intent("7", sqx.SideSell, 4, 0)); !errors.Is(err, ErrOverBudget) {
+		t.Errorf("order flipping the position over budget: %v", err)
+	}
+
+	// Orders without a price cannot be valued without a rate
+	unpriced, _ := Open([]Allocation{{Strategy: "grid", Portfolio: "main", Budget: 100000}}, Options{})
+	if err := unpriced.Check(intent("8", sqx.SideBuy, 1, 0)); err == nil {
+		t.Error("order without a price accepted")
+	}
+}
+
+func TestOnExecutionReportTracksOrdersPlacedElsewhere(t *testing.T) {
+	book, err := Open([]Allocation{{Strategy: "grid", Portfolio: "main", Budget: 100000}}, Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	book.OnExecutionReport(sqx.ExecutionReport{ClientOrderId: "1", Strategy: "grid", Symbol: btc, Side: sqx.SideBuy, Status: sqx.OrderStatusNew, Quantity: 1, Price: 40000})
+	if u, _ := book.Usage("grid"); u.Working != 40000 {
+		t.Errorf("working order from its report: %+v", u)
+	}
+	partial := fill("1", sqx.SideBuy, 0.25, sqx.OrderStatusPartiallyFilled)
+	partial.Quantity, partial.LastPrice = 1, 40000
+	book.OnExecutionReport(partial)
+	if u, _ := book.Usage("grid"); u.Working != 30000 || u.Positions != 10000 || u.Exposure != 40000 {
+		t.Errorf("usage after a partial fill: %+v", u)
+	}
+	book.OnExecutionReport(sqx.ExecutionReport{ClientOrderId: "1", Strategy: "grid", Symbol: btc, Side: sqx.SideBuy, Status: sqx.OrderStatusCanceled, Quantity: 1, CumQuantity: 0.25})
+	if u, _ := book.Usage("grid"); u.Working != 0 || u.Positions != 10000 {
+		t.Errorf("usage after the cancel: %+v", u)
+	}
+	book.OnExecutionReport(sqx.ExecutionReport{ClientOrderId: "2", Strategy: "arb", Symbol: btc, Status: sqx.OrderStatusNew, Quantity: 1, Price: 40000})
+	if len(book.orders) != 0 {
+		t.Error("orders of unallocated strategies are tracked")
+	}
+}
+
+func TestSetAuditsAndJournals(t *testing.T) {
+	journal := filepath.Join(t.TempDir(), "allocations.jsonl")
+	configured := []Allocation{{Strategy: "grid", Portfolio: "main", Budget: 50000}}
+	opts := Options{Capital: map[string]float64{"main": 100000}, Journal: journal}
+	book, err := Open(configured, opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var logged []Change
+	book.OnChange(func(c Change) { logged = append(logged, c) })
+
+	if _, err := book.Set(Allocation{Strategy: "arb", Portfolio: "main", Budget: 60000}, "ops", ""); !errors.Is(err, ErrInvalid) {
+		t.Errorf("allocations over the capital: %v", err)
+	}
+	if _, err := book.Set(Allocation{Strategy: "arb", Portfolio: "main", Budget: 10000}, "", ""); err == nil {
+		t.Error("change without author accepted")
+	}
+	c, err := book.Set(Allocation{Strategy: "arb", Portfolio: "main", Budget: 40000}, "ops", "new strategy")
+	if err != nil || c.Before != nil || c.After.Budget != 40000 {
+		t.Fatalf("create: %+v, %v", c, err)
+	}
+	if c, err = book.Set(Allocation{Strategy: "grid", Portfolio: "main", Budget: 20000}, "ops", "rebalance"); err != nil || c.Before.Budget != 50000 {
+		t.Fatalf("update: %+v, %v", c, err)
+	}
+	if _, err := book.Remove("arb", "ops", "retired"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := book.Remove("arb", "ops", ""); !errors.Is(err, ErrNotAllocated) {
+		t.Errorf("remove twice: %v", err)
+	}
+	if len(logged) != 3 || len(book.History()) != 3 {
+		t.Errorf("%d changes logged, %d in the audit trail", len(logged), len(book.History()))
+	}
+	book.Close()
+
+	reopened, err := Open(configured, opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer reopened.Close()
+	list := reopened.Allocations()
+	if len(list) != 1 || list[0].Strategy != "grid" || list[0].Budget != 20000 {
+		t.Errorf("allocations after replay: %+v", list)
+	}
+	if history := reopened.History(); len(history) != 3 || history[2].Reason != "retired" {
+		t.Errorf("audit trail after replay: %+v", history)
+	}
+}
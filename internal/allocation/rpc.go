@@ -0,0 +1,85 @@
+package allocation
+
+import (
+	"context"
+
+	"github.com/BullionBear/sequex/pkg/eventbus"
+	"github.com/nats-io/nats.go"
+)
+
+// DefaultSubject is the prefix the allocation RPCs of a service are served
+// under when none is configured
+const DefaultSubject = "allocation"
+
+// SetRequest creates or updates an allocation
+type SetRequest struct {
+	Allocation
+	By     string `json:"by"` // Who changes the allocation, required for the audit trail
+	Reason string `json:"reason,omitempty"`
+}
+
+// RemoveRequest removes the allocation of a strategy
+type RemoveRequest struct {
+	Strategy string `json:"strategy"`
+	By       string `json:"by"`
+	Reason   string `json:"reason,omitempty"`
+}
+
+// ListRequest asks for the allocations and their audit trail
+type ListRequest struct{}
+
+// List is the state of a book
+type List struct {
+	Currency    string   `json:"currency"`
+	Allocations []Usage  `json:"allocations"`
+	Changes     []Change `json:"changes"` // Latest changes, oldest first
+}
+
+// SetEndpoint is the endpoint allocations are set on, prefix.set
+func SetEndpoint(prefix string) eventbus.Endpoint[SetRequest, Change] {
+	return eventbus.NewCommand[SetRequest, Change](prefix+".set", eventbus.JSON)
+}
+
+// RemoveEndpoint is the endpoint allocations are removed on, prefix.remove
+func RemoveEndpoint(prefix string) eventbus.Endpoint[RemoveRequest, Change] {
+	return eventbus.NewCommand[RemoveRequest, Change](prefix+".remove", eventbus.JSON)
+}
+
+// ListEndpoint is the endpoint allocations are listed on, prefix.list
+func ListEndpoint(prefix string) eventbus.Endpoint[ListRequest, List] {
+	return eventbus.NewEndpoint[ListRequest, List](prefix+".list", eventbus.JSON)
+}
+
+// Serve answers the allocation RPCs of book under prefix
+func Serve(bus *eventbus.Bus, prefix string, book *Book) ([]*nats.Subscription, error) {
+	var subs []*nats.Subscription
+	for _, serve := range []func() (*nats.Subscription, error){
+		func() (*nats.Subscription, error) {
+			return SetEndpoint(prefix).Serve(bus, func(ctx context.Context, req *SetRequest) (*Change, error) {
+				c, err := book.Set(req.Allocation, eventbus.Caller(ctx, req.By), req.Reason)
+				return &c, err
+			})
+		},
+		func() (*nats.Subscription, error) {
+			return RemoveEndpoint(prefix).Serve(bus, func(ctx context.Context, req *RemoveRequest) (*Change, error) {
+				c, err := book.Remove(req.Strategy, eventbus.Caller(ctx, req.By), req.Reason)
+				return &c, err
+			})
+		},
+		func() (*nats.Subscription, error) {
+			return ListEndpoint(prefix).Serve(bus, func(_ context.Context, _ *ListRequest) (*List, error) {
+				return &List{Currency: book.Currency(), Allocations: book.Allocations(), Changes: book.History()}, nil
+			})
+		},
+	} {
+		sub, err := serve()
+		if err != nil {
+			for _, sub := range subs {
+				sub.Unsubscribe()
+			}
+			return nil, err
+		}
+		subs = append(subs, sub)
+	}
+	return subs, nil
+}
@@ -0,0 +1,97 @@
+// Package announce collects exchange announcements of new listings,
+// delistings and maintenance and turns them into structured events, so the
+// nodes trading can react, e.g. by halting a symbol scheduled for delisting.
+package announce
+
+import (
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Kind is the kind of an announcement
+type Kind string
+
+const (
+	KindListing     Kind = "listing"
+	KindDelisting   Kind = "delisting"
+	KindMaintenance Kind = "maintenance" // Scheduled maintenance notice
+	KindStatus      Kind = "status"      // The exchange entered or left system maintenance
+	KindOther       Kind = "other"
+)
+
+// Announcement is a structured exchange announcement
+type Announcement struct {
+	ID          string   `json:"id"` // Unique per exchange
+	Exchange    string   `json:"exchange"`
+	Kind        Kind     `json:"kind"`
+	Title       string   `json:"title"`
+	URL         string   `json:"url,omitempty"`
+	Assets      []string `json:"assets,omitempty"`       // Assets named, e.g. BTC
+	Symbols     []string `json:"symbols,omitempty"`      // Pairs named, BASE-QUOTE
+	Maintenance bool     `json:"maintenance,omitempty"`  // Whether the exchange is under maintenance, status announcements only
+	EffectiveAt int64    `json:"effective_at,omitempty"` // When it takes effect, Unix milliseconds, 0 when unknown
+	PublishedAt int64    `json:"published_at"`           // Unix milliseconds
+}
+
+// Subject returns the subject the announcement is published on,
+// <prefix>.<exchange>.<kind>, e.g. announcement.binance.delisting
+func (a Announcement) Subject(prefix string) string {
+	return prefix + "." + strings.ToLower(a.Exchange) + "." + string(a.Kind)
+}
+
+var (
+	pairPattern   = regexp.MustCompile(`\b([A-Z0-9]{2,12})/([A-Z0-9]{2,12})\b`)
+	tickerPattern = regexp.MustCompile(`\(([A-Z0-9]{2,12})\)`)
+	delistPattern = regexp.MustCompile(`(?i)\bdelist\s+(.+?)(?:\s+on\s+\d{4}-\d{2}-\d{2}.*)?$`)
+	assetPattern  = regexp.MustCompile(`^[A-Z0-9]{2,12}$`)
+	datePattern   = regexp.MustCompile(`\b(\d{4}-\d{2}-\d{2})(?:[ T](\d{2}:\d{2}))?`)
+)
+
+// Parse extracts the assets, pairs and effective time named in an
+// announcement title. Pairs are written BASE/QUOTE, assets either in
+// parentheses, "Jupiter (JUP)", or listed after Delist, "Will Delist ABC,
+// DEF and GHI on 2024-04-17". Dates are read as UTC and effectiveAt is 0
+// when the title has none.
+func Parse(title string) (assets, symbols []string, effectiveAt int64) {
+	seenAssets := make(map[string]bool)
+	addAsset := func(asset string) {
+		if asset != "UTC" && !seenAssets[asset] {
+			seenAssets[asset] = true
+			assets = append(assets, asset)
+		}
+	}
+	seenSymbols := make(map[string]bool)
+	for _, m := range pairPattern.FindAllStringSubmatch(title, -1) {
+		symbol := m[1] + "-" + m[2]
+		if !seenSymbols[symbol] {
+			seenSymbols[symbol] = true
+			symbols = append(symbols, symbol)
+		}
+	}
+	for _, m := range tickerPattern.FindAllStringSubmatch(title, -1) {
+		addAsset(m[1])
+	}
+	if m := delistPattern.FindStringSubmatch(title); m != nil {
+		list := strings.NewReplacer(",", " ", "&", " ", " and ", " ").Replace(m[1])
+		for _, token := range strings.Fields(list) {
+			if assetPattern.MatchString(token) {
+				addAsset(token)
+			}
+		}
+	}
+	sort.Strings(assets)
+	sort.Strings(symbols)
+
+	if m := datePattern.FindStringSubmatch(title); m != nil {
+		layout, value := "2006-01-02", m[1]
+		if m[2] != "" {
+			layout, value = "2006-01-02 15:04", m[1]+" "+m[2]
+		}
+		if t, err := time.Parse(layout, value); err == nil {
+			effectiveAt = t.UnixMilli()
+		}
+	}
+	return assets, symbols, effectiveAt
+}
@@ -0,0 +1,145 @@
+package announce
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/BullionBear/sequex/internal/lifecycle"
+	"github.com/BullionBear/sequex/internal/model/sqx"
+)
+
+func TestParse(t *testing.T) {
+	date := func(s string) int64 {
+		tm, _ := time.Parse("2006-01-02 15:04", s)
+		return tm.UnixMilli()
+	}
+	tests := []struct {
+		title       string
+		assets      []string
+		symbols     []string
+		effectiveAt int64
+	}{
+		{"Binance Will Delist BETA, CVX, SNT and VIB on 2024-04-17", []string{"BETA", "CVX", "SNT", "VIB"}, nil, date("2024-04-17 00:00")},
+		{"Binance Will List Jupiter (JUP) with Seed Tag Applied", []string{"JUP"}, nil, 0},
+		{"Binance Will Delist ABC/BTC & DEF/USDT Spot Trading Pairs on 2024-05-10 03:00 (UTC)", nil, []string{"ABC-BTC", "DEF-USDT"}, date("2024-05-10 03:00")},
+		{"Binance Will Perform Scheduled System Maintenance (2024-05-10)", nil, nil, date("2024-05-10 00:00")},
+	}
+	for _, tt := range tests {
+		assets, symbols, effectiveAt := Parse(tt.title)
+		if !reflect.DeepEqual(assets, tt.assets) || !reflect.DeepEqual(symbols, tt.symbols) || effectiveAt != tt.effectiveAt {
+			t.Errorf("Parse(%q) = %v, %v, %d", tt.title, assets, symbols, effectiveAt)
+		}
+	}
+}
+
+func TestBinanceArticles(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != binanceArticlesPath || r.URL.Query().Get("catalogId") != "161" {
+			http.NotFound(w, r)
+			return
+		}
+		fmt.Fprint(w, `{"code":"000000","data":{"catalogs":[{"catalogId":161,"articles":[
+			{"id":7,"code":"abc","title":"Binance Will Delist XYZ on 2024-04-17","releaseDate":1713000000000}]}]}}`)
+	}))
+	defer server.Close()
+
+	source := BinanceArticles(server.Client(), BinanceOptions{BaseURL: server.URL, Catalogs: map[int]Kind{161: KindDelisting}})
+	list, err := source(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(list) != 1 {
+		t.Fatalf("got %d announcements", len(list))
+	}
+	a := list[0]
+	if a.ID != "7" || a.Exchange != "BINANCE" || a.Kind != KindDelisting || a.URL != server.URL+"/en/support/announcement/abc" ||
+		!reflect.DeepEqual(a.Assets, []string{"XYZ"}) || a.PublishedAt != 1713000000000 {
+		t.Errorf("announcement = %+v", a)
+	}
+	if got := a.Subject("announcement"); got != "announcement.binance.delisting" {
+		t.Errorf("subject = %s", got)
+	}
+}
+
+func TestCollector(t *testing.T) {
+	pages := [][]Announcement{
+		{{ID: "2", Exchange: "BINANCE", PublishedAt: 2}, {ID: "1", Exchange: "BINANCE", PublishedAt: 1}},
+		{{ID: "3", Exchange: "BINANCE", PublishedAt: 3}, {ID: "2", Exchange: "BINANCE", PublishedAt: 2}},
+	}
+	poll := 0
+	c := NewCollector(func(context.Context) ([]Announcement, error) {
+		page := pages[poll]
+		poll++
+		return page, nil
+	}, func(context.Context) ([]Announcement, error) {
+		return nil, errors.New("unavailable")
+	})
+
+	ids := func(list []Announcement) (out []string) {
+		for _, a := range list {
+			out = append(out, a.ID)
+		}
+		return out
+	}
+	list, err := c.Poll(context.Background())
+	if err == nil || !reflect.DeepEqual(ids(list), []string{"1", "2"}) {
+		t.Fatalf("first poll = %v, %v", ids(list), err)
+	}
+	list, _ = c.Poll(context.Background())
+	if !reflect.DeepEqual(ids(list), []string{"3"}) {
+		t.Errorf("second poll = %v", ids(list))
+	}
+}
+
+func TestHalts(t *testing.T) {
+	btc := sqx.NewSymbol("BTC", "USDT")
+	xyz := sqx.NewSymbol("XYZ", "USDT")
+	abc := sqx.NewSymbol("ABC", "BTC")
+	effective := time.Date(2024, 4, 17, 3, 0, 0, 0, time.UTC)
+
+	h := NewHalts(24 * time.Hour)
+	h.Apply(Announcement{Exchange: "BINANCE", Kind: KindListing, Assets: []string{"BTC"}})
+	h.Apply(Announcement{Exchange: "BINANCE", Kind: KindDelisting, Assets: []string{"XYZ"}, EffectiveAt: effective.UnixMilli()})
+	h.Apply(Announcement{Exchange: "BINANCE", Kind: KindDelisting, Symbols: []string{"ABC-BTC"}, PublishedAt: effective.UnixMilli()})
+
+	before := effective.Add(-25 * time.Hour)
+	if err := h.Check(sqx.ExchangeBinance, xyz, before); err != nil {
+		t.Errorf("XYZ halted before the lead: %v", err)
+	}
+	if err := h.Check(sqx.ExchangeBinance, xyz, effective.Add(-time.Hour)); !errors.Is(err, ErrHalted) {
+		t.Errorf("XYZ not halted within the lead: %v", err)
+	}
+	if err := h.Check(sqx.ExchangeBybit, xyz, effective); err != nil {
+		t.Errorf("XYZ halted on another exchange: %v", err)
+	}
+	if err := h.Check(sqx.ExchangeBinance, abc, effective); !errors.Is(err, ErrHalted) {
+		t.Errorf("ABC-BTC not halted once announced: %v", err)
+	}
+	if err := h.Check(sqx.ExchangeBinance, btc, effective); err != nil {
+		t.Errorf("BTC halted: %v", err)
+	}
+
+	h.Apply(Announcement{Exchange: "BINANCE", Kind: KindStatus, Maintenance: true})
+	if err := h.Check(sqx.ExchangeBinance, btc, effective); !errors.Is(err, ErrHalted) {
+		t.Errorf("BTC not halted under maintenance: %v", err)
+	}
+	h.Apply(Announcement{Exchange: "BINANCE", Kind: KindStatus})
+	if err := h.Check(sqx.ExchangeBinance, btc, effective); err != nil {
+		t.Errorf("BTC halted after maintenance: %v", err)
+	}
+
+	h.ApplyChange(lifecycle.Change{Exchange: "BINANCE", Symbol: "BTC-USDT", From: "TRADING", To: "BREAK"})
+	if err := h.Check(sqx.ExchangeBinance, btc, effective); !errors.Is(err, ErrHalted) {
+		t.Errorf("BTC not halted on break: %v", err)
+	}
+	h.ApplyChange(lifecycle.Change{Exchange: "BINANCE", Symbol: "BTC-USDT", From: "BREAK", To: "TRADING"})
+	if err := h.Check(sqx.ExchangeBinance, btc, effective); err != nil {
+		t.Errorf("BTC halted once trading again: %v", err)
+	}
+}
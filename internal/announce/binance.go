@@ -0,0 +1,156 @@
+package announce
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/BullionBear/sequex/internal/model/sqx"
+	"github.com/BullionBear/sequex/pkg/exchange/binance"
+)
+
+// Source returns the announcements currently published by an exchange
+type Source func(ctx context.Context) ([]Announcement, error)
+
+const (
+	BinanceAnnouncementURL = "https://www.binance.com"
+	binanceArticlesPath    = "/bapi/composite/v1/public/cms/article/list/query"
+	binanceArticlePath     = "/en/support/announcement/"
+)
+
+// BinanceCatalogs are the announcement catalogs of Binance collected by
+// default, by catalog id
+var BinanceCatalogs = map[int]Kind{
+	48:  KindListing,     // New Cryptocurrency Listing
+	161: KindDelisting,   // Delisting
+	157: KindMaintenance, // Maintenance Updates
+}
+
+// BinanceOptions configures the collection of Binance announcements
+type BinanceOptions struct {
+	BaseURL  string       // BinanceAnnouncementURL when empty
+	Catalogs map[int]Kind // BinanceCatalogs when empty
+	PageSize int          // Latest articles requested per catalog, 20 when 0
+}
+
+type binanceArticles struct {
+	Code    string `json:"code"`
+	Message string `json:"message"`
+	Data    struct {
+		Catalogs []struct {
+			CatalogID int `json:"catalogId"`
+			Articles  []struct {
+				ID          int64  `json:"id"`
+				Code        string `json:"code"`
+				Title       string `json:"title"`
+				ReleaseDate int64  `json:"releaseDate"`
+			} `json:"articles"`
+		} `json:"catalogs"`
+	} `json:"data"`
+}
+
+// BinanceArticles returns a source of the latest articles of the Binance
+// announcement catalogs. A nil client uses http.DefaultClient.
+func BinanceArticles(client *http.Client, opts BinanceOptions) Source {
+	if client == nil {
+		client = http.DefaultClient
+	}
+	if opts.BaseURL == "" {
+		opts.BaseURL = BinanceAnnouncementURL
+	}
+	if len(opts.Catalogs) == 0 {
+		opts.Catalogs = BinanceCatalogs
+	}
+	if opts.PageSize <= 0 {
+		opts.PageSize = 20
+	}
+	baseURL := strings.TrimRight(opts.BaseURL, "/")
+	return func(ctx context.Context) ([]Announcement, error) {
+		var list []Announcement
+		for catalog, kind := range opts.Catalogs {
+			q := url.Values{}
+			q.Set("type", "1")
+			q.Set("catalogId", strconv.Itoa(catalog))
+			q.Set("pageNo", "1")
+			q.Set("pageSize", strconv.Itoa(opts.PageSize))
+			var resp binanceArticles
+			if err := getJSON(ctx, client, baseURL+binanceArticlesPath+"?"+q.Encode(), &resp); err != nil {
+				return nil, fmt.Errorf("catalog %d: %w", catalog, err)
+			}
+			if resp.Code != "000000" {
+				return nil, fmt.Errorf("catalog %d: binance error %s: %s", catalog, resp.Code, resp.Message)
+			}
+			for _, c := range resp.Data.Catalogs {
+				for _, article := range c.Articles {
+					assets, symbols, effectiveAt := Parse(article.Title)
+					list = append(list, Announcement{
+						ID:          strconv.FormatInt(article.ID, 10),
+						Exchange:    sqx.ExchangeBinance.String(),
+						Kind:        kind,
+						Title:       article.Title,
+						URL:         baseURL + binanceArticlePath + article.Code,
+						Assets:      assets,
+						Symbols:     symbols,
+						EffectiveAt: effectiveAt,
+						PublishedAt: article.ReleaseDate,
+					})
+				}
+			}
+		}
+		return list, nil
+	}
+}
+
+// BinanceStatus returns a source of the system status of Binance. It
+// announces the status once and again whenever it changes.
+func BinanceStatus(client *binance.Client) Source {
+	var mu sync.Mutex
+	last, since := -1, int64(0)
+	return func(ctx context.Context) ([]Announcement, error) {
+		resp, err := client.GetSystemStatus(ctx)
+		if err != nil {
+			return nil, err
+		}
+		mu.Lock()
+		defer mu.Unlock()
+		if resp.Data.Status != last {
+			last, since = resp.Data.Status, time.Now().UnixMilli()
+		}
+		return []Announcement{{
+			ID:          fmt.Sprintf("status-%d-%d", last, since),
+			Exchange:    sqx.ExchangeBinance.String(),
+			Kind:        KindStatus,
+			Title:       resp.Data.Msg,
+			Maintenance: last == 1,
+			EffectiveAt: since,
+			PublishedAt: since,
+		}}, nil
+	}
+}
+
+func getJSON(ctx context.Context, client *http.Client, url string, v any) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return err
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return err
+	}
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("http error: %d", resp.StatusCode)
+	}
+	return json.Unmarshal(body, v)
+}
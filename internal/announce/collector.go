@@ -0,0 +1,86 @@
+package announce
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+)
+
+// Collector polls sources and returns each announcement once
+type Collector struct {
+	sources []Source
+
+	mu   sync.Mutex
+	seen []map[string]bool // Per source, the ids of its last poll
+}
+
+// NewCollector creates a collector of the sources
+func NewCollector(sources ...Source) *Collector {
+	return &Collector{sources: sources, seen: make([]map[string]bool, len(sources))}
+}
+
+// Poll polls every source and returns the announcements not returned
+// before, oldest first. Failing sources do not prevent the others from
+// being polled; their errors are returned together. Only the ids of the
+// last poll of a source are remembered, so the memory stays bounded by
+// what the sources publish.
+func (c *Collector) Poll(ctx context.Context) ([]Announcement, error) {
+	var fresh []Announcement
+	var errs []error
+	for i, source := range c.sources {
+		list, err := source(ctx)
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		ids := make(map[string]bool, len(list))
+		c.mu.Lock()
+		for _, a := range list {
+			key := a.Exchange + "/" + a.ID
+			if !c.seen[i][key] && !ids[key] {
+				fresh = append(fresh, a)
+			}
+			ids[key] = true
+		}
+		c.seen[i] = ids
+		c.mu.Unlock()
+	}
+	sort.SliceStable(fresh, func(i, j int) bool { return fresh[i].PublishedAt < fresh[j].PublishedAt })
+	return fresh, errors.Join(errs...)
+}
+
+// forget makes an announcement new again so it is returned by the next poll
+func (c *Collector) forget(a Announcement) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for _, ids := range c.seen {
+		delete(ids, a.Exchange+"/"+a.ID)
+	}
+}
+
+// Run polls every interval until ctx is done. Announcements emit fails on
+// are retried on the next poll.
+func (c *Collector) Run(ctx context.Context, interval time.Duration, emit func(Announcement) error, onError func(error)) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		list, err := c.Poll(ctx)
+		if err != nil && ctx.Err() == nil {
+			onError(fmt.Errorf("announcements: %w", err))
+		}
+		for _, a := range list {
+			if err := emit(a); err != nil {
+				c.forget(a)
+				onError(fmt.Errorf("announcement %s: %w", a.ID, err))
+			}
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
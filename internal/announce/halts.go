@@ -0,0 +1,117 @@
+package announce
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/BullionBear/sequex/internal/lifecycle"
+	"github.com/BullionBear/sequex/internal/model/sqx"
+)
+
+// ErrHalted is returned for orders on a halted symbol
+var ErrHalted = errors.New("trading halted")
+
+// halt is why and from when a symbol is halted
+type halt struct {
+	from   int64 // Unix milliseconds
+	reason string
+}
+
+// Halts tracks where trading should stop from the announcements applied:
+// the assets and pairs of delistings, Lead before they take effect or as
+// soon as announced when no time is given, and whole exchanges while under
+// system maintenance. Listing and maintenance notices are informational.
+// Symbol status changes halt symbols while they are not trading.
+type Halts struct {
+	lead time.Duration
+
+	mu          sync.Mutex
+	assets      map[string]halt   // EXCHANGE/ASSET
+	symbols     map[string]halt   // EXCHANGE/BASE-QUOTE
+	maintenance map[string]bool   // EXCHANGE
+	statuses    map[string]string // EXCHANGE/BASE-QUOTE, symbols not trading
+}
+
+// NewHalts creates halts starting lead before delistings take effect
+func NewHalts(lead time.Duration) *Halts {
+	return &Halts{
+		lead:        lead,
+		assets:      make(map[string]halt),
+		symbols:     make(map[string]halt),
+		maintenance: make(map[string]bool),
+		statuses:    make(map[string]string),
+	}
+}
+
+// Apply updates the halts with an announcement
+func (h *Halts) Apply(a Announcement) {
+	exchange := strings.ToUpper(a.Exchange)
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	switch a.Kind {
+	case KindStatus:
+		h.maintenance[exchange] = a.Maintenance
+	case KindDelisting:
+		from := a.PublishedAt
+		if a.EffectiveAt > 0 {
+			from = a.EffectiveAt - h.lead.Milliseconds()
+		}
+		reason := fmt.Sprintf("delisting announced: %s", a.Title)
+		for _, asset := range a.Assets {
+			h.assets[exchange+"/"+asset] = earliest(h.assets[exchange+"/"+asset], halt{from, reason})
+		}
+		for _, symbol := range a.Symbols {
+			h.symbols[exchange+"/"+symbol] = earliest(h.symbols[exchange+"/"+symbol], halt{from, reason})
+		}
+	}
+}
+
+// ApplyChange halts or resumes a symbol as its trading status changes
+func (h *Halts) ApplyChange(c lifecycle.Change) {
+	key := strings.ToUpper(c.Exchange) + "/" + c.Symbol
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if c.Trading() {
+		delete(h.statuses, key)
+		return
+	}
+	h.statuses[key] = c.To
+}
+
+func earliest(current, next halt) halt {
+	if current.reason != "" && current.from <= next.from {
+		return current
+	}
+	return next
+}
+
+// Check returns an ErrHalted error when trading symbol on exchange is
+// halted at the given time
+func (h *Halts) Check(exchange sqx.Exchange, symbol sqx.Symbol, at time.Time) error {
+	name := exchange.String()
+	ms := at.UnixMilli()
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if h.maintenance[name] {
+		return fmt.Errorf("%w: %s under system maintenance", ErrHalted, name)
+	}
+	if status, ok := h.statuses[name+"/"+symbol.String()]; ok {
+		return fmt.Errorf("%w: %s %s is %s", ErrHalted, name, symbol, status)
+	}
+	halted := func(index map[string]halt, key string) error {
+		if hl, ok := index[name+"/"+key]; ok && ms >= hl.from {
+			return fmt.Errorf("%w: %s %s, %s", ErrHalted, name, symbol, hl.reason)
+		}
+		return nil
+	}
+	if err := halted(h.symbols, symbol.String()); err != nil {
+		return err
+	}
+	if err := halted(h.assets, symbol.Base); err != nil {
+		return err
+	}
+	return halted(h.assets, symbol.Quote)
+}
@@ -0,0 +1,371 @@
+// Package backtest runs strategies over recorded or synthetic market data on
+// the limit order book simulator, and wraps the runs in jobs submitted,
+// tracked and fetched over REST and RPC by research tools and CI.
+package backtest
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/BullionBear/sequex/internal/bench"
+	"github.com/BullionBear/sequex/internal/model/sqx"
+	"github.com/BullionBear/sequex/pkg/lob"
+	"github.com/BullionBear/sequex/pkg/synthdata"
+)
+
+// ErrUnknownStrategy is returned for strategies not registered
+var ErrUnknownStrategy = errors.New("unknown strategy")
+
+// Factory creates a strategy from its parameters, the defaults of those
+// omitted
+type Factory func(params map[string]float64) (bench.Decide, error)
+
+var (
+	strategiesMu sync.RWMutex
+	strategies   = map[string]Factory{
+		"breakout": func(params map[string]float64) (bench.Decide, error) {
+			band, ok := params["band"]
+			if !ok {
+				band = 0.001
+			}
+			if band <= 0 {
+				return nil, fmt.Errorf("band must be positive")
+			}
+			return bench.Breakout(band), nil
+		},
+	}
+)
+
+// RegisterStrategy makes a strategy available to backtests under name
+func RegisterStrategy(name string, factory Factory) {
+	strategiesMu.Lock()
+	defer strategiesMu.Unlock()
+	strategies[name] = factory
+}
+
+// Strategies returns the names of the registered strategies, sorted
+func Strategies() []string {
+	strategiesMu.RLock()
+	defer strategiesMu.RUnlock()
+	names := make([]string, 0, len(strategies))
+	for name := range strategies {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+func strategy(name string) (Factory, bool) {
+	strategiesMu.RLock()
+	defer strategiesMu.RUnlock()
+	factory, ok := strategies[name]
+	return factory, ok
+}
+
+// Data is the market data a backtest runs over
+type Data struct {
+	Start     time.Time         `json:"start"`
+	End       time.Time         `json:"end"`
+	Recording string            `json:"recording,omitempty"` // Events recording in the data directory, as lob.Replay reads
+	Trades    string            `json:"trades,omitempty"`    // Columnar trade table in the data directory, e.g. BTC-USDT/trades.col, replayed without a book
+	Market    *synthdata.Config `json:"market,omitempty"`    // Synthetic market generated when there is no recording, from Start unless it starts earlier
+}
+
+// Spec is a backtest to run
+type Spec struct {
+	Strategy    string             `json:"strategy"`
+	Params      map[string]float64 `json:"params,omitempty"`
+	Symbol      string             `json:"symbol"` // Traded, e.g. BTC-USDT
+	Data        Data               `json:"data"`
+	Capital     float64            `json:"capital"`       // Quote asset at the start, 10000 when 0
+	MakerFeeBps float64            `json:"maker_fee_bps"` // Of the notional of fills resting in the book
+	TakerFeeBps float64            `json:"taker_fee_bps"` // Of the notional of fills crossing the spread
+	LatencyMs   int64              `json:"latency_ms"`    // Before orders reach the book
+	JitterMs    int64              `json:"jitter_ms"`     // Up to which is added at random to the latency of each order
+	FillSplits  int                `json:"fill_splits"`   // Fills of resting orders are split at random into up to as many executions, 1 when 0
+	SampleMs    int64              `json:"sample_ms"`     // Interval of the equity curve, 1 minute when 0
+	Seed        int64              `json:"seed"`          // Of all the randomness of the run, the synthetic market unless it has its own, 1 when 0
+}
+
+// Validate validates the spec
+func (s *Spec) Validate() error {
+	if _, ok := strategy(s.Strategy); !ok {
+		return fmt.Errorf("%w: %q", ErrUnknownStrategy, s.Strategy)
+	}
+	if _, err := sqx.NewSymbolFromStr(s.Symbol); err != nil {
+		return err
+	}
+	if s.Data.Start.IsZero() || !s.Data.End.After(s.Data.Start) {
+		return fmt.Errorf("data range must start before it ends")
+	}
+	sources := 0
+	for _, set := range []bool{s.Data.Recording != "", s.Data.Trades != "", s.Data.Market != nil} {
+		if set {
+			sources++
+		}
+	}
+	if sources > 1 {
+		return fmt.Errorf("data must be one of a recording, a trade table or a synthetic market")
+	}
+	if s.Data.Market != nil && s.Data.Market.Start.After(s.Data.Start) {
+		return fmt.Errorf("synthetic market cannot start after the data")
+	}
+	for _, path := range []string{s.Data.Recording, s.Data.Trades} {
+		if path != "" && !filepath.IsLocal(path) {
+			return fmt.Errorf("data file %q must be a path within the data directory", path)
+		}
+	}
+	if s.Capital < 0 || s.MakerFeeBps < 0 || s.TakerFeeBps < 0 || s.LatencyMs < 0 || s.JitterMs < 0 || s.FillSplits < 0 || s.SampleMs < 0 {
+		return fmt.Errorf("capital, fees, latency, jitter, fill splits and sample cannot be negative")
+	}
+	return nil
+}
+
+// Point is a sample of the equity curve
+type Point struct {
+	Time     int64   `json:"time"`     // Unix milliseconds
+	Price    float64 `json:"price"`    // Of the last trade
+	Position float64 `json:"position"` // Base asset held
+	Equity   float64 `json:"equity"`   // Quote asset and position marked to the last trade
+}
+
+// Metrics summarizes a backtest
+type Metrics struct {
+	Events      int     `json:"events"`
+	Orders      int     `json:"orders"`
+	Rejected    int     `json:"rejected"` // Intents that could not be placed
+	Fills       int     `json:"fills"`
+	Volume      float64 `json:"volume"` // Notional filled
+	Fees        float64 `json:"fees"`
+	Capital     float64 `json:"capital"`
+	Equity      float64 `json:"equity"` // At the end
+	PnL         float64 `json:"pnl"`
+	Return      float64 `json:"return"`       // PnL to capital
+	MaxDrawdown float64 `json:"max_drawdown"` // Largest fall of the equity curve from a peak, as a share of it
+	Sharpe      float64 `json:"sharpe"`       // Of the returns of the equity curve, annualized
+	Position    float64 `json:"position"`     // At the end
+}
+
+// Trade is a fill of an order of the strategy
+type Trade struct {
+	OrderID  string   `json:"order_id"`
+	Side     lob.Side `json:"side"`
+	Time     int64    `json:"time"` // Unix milliseconds
+	Price    float64  `json:"price"`
+	Quantity float64  `json:"quantity"`
+	Fee      float64  `json:"fee"`
+	Maker    bool     `json:"maker"`
+}
+
+// Result is the outcome of a backtest
+type Result struct {
+	Manifest Manifest `json:"manifest"`
+	Metrics  Metrics  `json:"metrics"`
+	Equity   []Point  `json:"equity"`
+	Trades   []Trade  `json:"trades"` // In time order
+}
+
+// Run runs a backtest over the data of cache, loading it unless cached.
+// Progress is reported to logf, which may be nil. The run stops with the
+// error of ctx once it is done. Runs of the same spec over the same data
+// give the same result, the seed of the spec drawing the latency jitter and
+// the fill splits.
+func Run(ctx context.Context, spec Spec, cache *Cache, logf func(format string, args ...any)) (*Result, error) {
+	if err := spec.Validate(); err != nil {
+		return nil, err
+	}
+	if logf == nil {
+		logf = func(string, ...any) {}
+	}
+	factory, _ := strategy(spec.Strategy)
+	decide, err := factory(spec.Params)
+	if err != nil {
+		return nil, fmt.Errorf("strategy %s: %w", spec.Strategy, err)
+	}
+	symbol, _ := sqx.NewSymbolFromStr(spec.Symbol)
+	if spec.Capital == 0 {
+		spec.Capital = 10000
+	}
+	sample := time.Minute.Milliseconds()
+	if spec.SampleMs > 0 {
+		sample = spec.SampleMs
+	}
+	if spec.Seed == 0 {
+		spec.Seed = 1
+	}
+	if spec.Data.Recording == "" && spec.Data.Trades == "" && (spec.Data.Market == nil || spec.Data.Market.Seed == 0) {
+		market := spec.Data.market()
+		market.Seed = spec.Seed
+		spec.Data.Market = &market
+	}
+
+	sim := lob.New(lob.Options{
+		Latency:    time.Duration(spec.LatencyMs) * time.Millisecond,
+		Jitter:     time.Duration(spec.JitterMs) * time.Millisecond,
+		FillSplits: spec.FillSplits,
+		Seed:       spec.Seed,
+	})
+	start, end := spec.Data.Start.UnixMilli(), spec.Data.End.UnixMilli()
+	r := &Result{Metrics: Metrics{Capital: spec.Capital}}
+	m := &r.Metrics
+	cash, position, price := spec.Capital, 0.0, 0.0
+	next := start // Time of the next equity sample
+	sides := make(map[string]lob.Side)
+
+	account := func(fills []lob.Fill) {
+		for _, f := range fills {
+			notional := f.Price * f.Quantity
+			fee := notional * spec.TakerFeeBps / 1e4
+			if f.Maker {
+				fee = notional * spec.MakerFeeBps / 1e4
+			}
+			side := sides[f.OrderID]
+			if side == lob.Buy {
+				cash -= notional
+				position += f.Quantity
+			} else {
+				cash += notional
+				position -= f.Quantity
+			}
+			cash -= fee
+			r.Trades = append(r.Trades, Trade{OrderID: f.OrderID, Side: side, Time: f.Time, Price: f.Price, Quantity: f.Quantity, Fee: fee, Maker: f.Maker})
+			m.Fills++
+			m.Volume += notional
+			m.Fees += fee
+		}
+	}
+	record := func(at int64) {
+		r.Equity = append(r.Equity, Point{Time: at, Price: price, Position: position, Equity: cash + position*price})
+	}
+
+	series, err := cache.Load(spec.Data)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load data: %w", err)
+	}
+	if r.Manifest, err = newManifest(spec, series); err != nil {
+		return nil, err
+	}
+	logf("running %s on %s from %s to %s", spec.Strategy, spec.Symbol, spec.Data.Start.UTC().Format(time.RFC3339), spec.Data.End.UTC().Format(time.RFC3339))
+	var tradeId int64
+	err = series.replay(sim, func(e lob.Event, fills []lob.Fill) error {
+		if e.Time < start {
+			return nil // Builds the book up to the start
+		}
+		m.Events++
+		if m.Events%4096 == 1 { // From the first event, so short runs are canceled too
+			if err := ctx.Err(); err != nil {
+				return err
+			}
+		}
+		for price > 0 && e.Time >= next {
+			record(next)
+			next += sample
+		}
+		account(fills)
+		if e.Type != lob.EventTrade {
+			return nil
+		}
+		price = e.Price
+		tradeId++
+		side := sqx.SideBuy
+		if e.Side == lob.Sell {
+			side = sqx.SideSell
+		}
+		for _, intent := range decide(sqx.Trade{
+			Id:             tradeId,
+			Symbol:         symbol,
+			Exchange:       sqx.ExchangeBinance,
+			InstrumentType: sqx.InstrumentTypeSpot,
+			TakerSide:      side,
+			Price:          e.Price,
+			Quantity:       e.Quantity,
+			Timestamp:      e.Time,
+		}) {
+			orderSide := lob.Buy
+			if intent.Side == sqx.SideSell {
+				orderSide = lob.Sell
+			}
+			if intent.Type != sqx.OrderTypeLimit {
+				m.Rejected++
+				logf("%s: rejected %s, only limit orders are simulated", time.UnixMilli(e.Time).UTC().Format(time.RFC3339Nano), intent.ClientOrderId)
+				continue
+			}
+			sides[intent.ClientOrderId] = orderSide
+			fills, err := sim.Place(e.Time, intent.ClientOrderId, orderSide, intent.Price, intent.Quantity)
+			if err != nil {
+				m.Rejected++
+				logf("%s: rejected %s: %v", time.UnixMilli(e.Time).UTC().Format(time.RFC3339Nano), intent.ClientOrderId, err)
+				continue
+			}
+			m.Orders++
+			account(fills)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	if price > 0 {
+		// The last sample is at the last event, replacing one taken then
+		at := min(sim.Now(), end)
+		if n := len(r.Equity); n > 0 && r.Equity[n-1].Time == at {
+			r.Equity = r.Equity[:n-1]
+		}
+		record(at)
+	}
+
+	m.Position = position
+	m.Equity = cash + position*price
+	m.PnL = m.Equity - m.Capital
+	m.Return = m.PnL / m.Capital
+	m.MaxDrawdown = maxDrawdown(r.Equity)
+	m.Sharpe = sharpe(r.Equity, time.Duration(sample)*time.Millisecond)
+	logf("done: %d events, %d orders, %d fills, pnl %.2f, return %.4f", m.Events, m.Orders, m.Fills, m.PnL, m.Return)
+	return r, nil
+}
+
+// maxDrawdown returns the largest fall of the equity curve from a peak, as a
+// share of the peak
+func maxDrawdown(points []Point) float64 {
+	var peak, drawdown float64
+	for _, p := range points {
+		peak = max(peak, p.Equity)
+		if peak > 0 {
+			drawdown = max(drawdown, (peak-p.Equity)/peak)
+		}
+	}
+	return drawdown
+}
+
+// sharpe returns the Sharpe ratio of the returns between the samples of the
+// equity curve, annualized over a year of continuous trading, 0 when flat
+func sharpe(points []Point, sample time.Duration) float64 {
+	var returns []float64
+	for i := 1; i < len(points); i++ {
+		if points[i-1].Equity > 0 {
+			returns = append(returns, points[i].Equity/points[i-1].Equity-1)
+		}
+	}
+	if len(returns) < 2 {
+		return 0
+	}
+	var mean, variance float64
+	for _, r := range returns {
+		mean += r
+	}
+	mean /= float64(len(returns))
+	for _, r := range returns {
+		variance += (r - mean) * (r - mean)
+	}
+	variance /= float64(len(returns) - 1)
+	if variance == 0 {
+		return 0
+	}
+	return mean / math.Sqrt(variance) * math.Sqrt(float64(365*24*time.Hour)/float64(sample))
+}
@@ -0,0 +1,193 @@
+package backtest
+
+import (
+	"context"
+	"errors"
+	"math"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/BullionBear/sequex/pkg/columnar"
+	"github.com/BullionBear/sequex/pkg/lob"
+	"github.com/BullionBear/sequex/pkg/synthdata"
+)
+
+func testSpec() Spec {
+	start := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	return Spec{
+		Strategy: "breakout",
+		Params:   map[string]float64{"band": 0.0005},
+		Symbol:   "BTC-USDT",
+		Data: Data{
+			Start: start,
+			End:   start.Add(10 * time.Minute),
+			Market: &synthdata.Config{
+				Price:   100,
+				Regimes: []synthdata.Regime{{Volatility: 5, TradeRate: 2}},
+				Depth:   synthdata.Depth{Levels: 3, Interval: time.Second},
+			},
+		},
+		TakerFeeBps: 5,
+		LatencyMs:   10,
+	}
+}
+
+func TestRun(t *testing.T) {
+	spec := testSpec()
+	r, err := Run(context.Background(), spec, NewCache("", 0), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	m := r.Metrics
+	if m.Events == 0 || m.Orders == 0 || m.Fills == 0 {
+		t.Fatalf("nothing traded: %+v", m)
+	}
+	if m.Capital != 10000 || math.Abs(m.Equity-m.Capital-m.PnL) > 1e-9 || math.Abs(m.Return-m.PnL/m.Capital) > 1e-12 {
+		t.Errorf("inconsistent metrics %+v", m)
+	}
+	if m.Fees <= 0 || m.MaxDrawdown < 0 || m.MaxDrawdown > 1 {
+		t.Errorf("fees %g, drawdown %g", m.Fees, m.MaxDrawdown)
+	}
+	// One sample a minute, and the last one at the end
+	if len(r.Equity) < 10 || len(r.Equity) > 12 {
+		t.Fatalf("%d equity samples", len(r.Equity))
+	}
+	for i := 1; i < len(r.Equity); i++ {
+		if r.Equity[i].Time <= r.Equity[i-1].Time {
+			t.Fatalf("samples out of order at %d", i)
+		}
+	}
+	if last := r.Equity[len(r.Equity)-1]; last.Equity != m.Equity || last.Position != m.Position {
+		t.Errorf("last sample %+v, metrics %+v", last, m)
+	}
+
+	again, err := Run(context.Background(), spec, NewCache("", 0), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(r, again) {
+		t.Error("runs of the same spec differ")
+	}
+}
+
+func TestRunRecording(t *testing.T) {
+	spec := testSpec()
+	events, err := synthdata.Generate(synthdata.Config{
+		Start:   spec.Data.Start.Add(-time.Minute),
+		Price:   100,
+		Regimes: spec.Data.Market.Regimes,
+		Depth:   spec.Data.Market.Depth,
+	}, spec.Data.End.Add(time.Minute))
+	if err != nil {
+		t.Fatal(err)
+	}
+	dir := t.TempDir()
+	file, err := os.Create(filepath.Join(dir, "btcusdt.jsonl"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := synthdata.WriteEvents(file, events); err != nil {
+		t.Fatal(err)
+	}
+	file.Close()
+
+	spec.Data.Market, spec.Data.Recording = nil, "btcusdt.jsonl"
+	r, err := Run(context.Background(), spec, NewCache(dir, 0), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if r.Metrics.Fills == 0 {
+		t.Fatalf("nothing traded: %+v", r.Metrics)
+	}
+	if data := r.Manifest.Data; len(data) != 1 || data[0].Path != "btcusdt.jsonl" || data[0].Size == 0 || len(data[0].SHA256) != 64 {
+		t.Errorf("manifest data %+v", data)
+	}
+	if first, last := r.Equity[0].Time, r.Equity[len(r.Equity)-1].Time; first < spec.Data.Start.UnixMilli() || last > spec.Data.End.UnixMilli() {
+		t.Errorf("equity curve from %d to %d outside the data range", first, last)
+	}
+
+	// The trades of the recording as a columnar table replay without a book
+	var trades []columnar.Trade
+	for _, e := range events {
+		if e.Type == lob.EventTrade && e.Time >= spec.Data.Start.UnixMilli() && e.Time <= spec.Data.End.UnixMilli() {
+			trades = append(trades, columnar.Trade{Time: e.Time, ID: int64(len(trades)), Price: e.Price, Quantity: e.Quantity, Side: columnar.Side(e.Side)})
+		}
+	}
+	if err := columnar.WriteTrades(filepath.Join(dir, "BTC-USDT", "trades.col"), trades); err != nil {
+		t.Fatal(err)
+	}
+	spec.Data.Recording, spec.Data.Trades = "", filepath.Join("BTC-USDT", "trades.col")
+	r, err = Run(context.Background(), spec, NewCache(dir, 0), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if r.Metrics.Events != len(trades) || r.Metrics.Fills == 0 {
+		t.Errorf("%d events replayed of %d trades: %+v", r.Metrics.Events, len(trades), r.Metrics)
+	}
+}
+
+func TestRunSeeded(t *testing.T) {
+	spec := testSpec()
+	spec.JitterMs, spec.FillSplits, spec.Seed = 20, 3, 42
+	r, err := Run(context.Background(), spec, NewCache("", 0), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if r.Manifest.Seed != 42 || len(r.Manifest.ConfigHash) != 64 || r.Manifest.Data != nil {
+		t.Errorf("manifest %+v", r.Manifest)
+	}
+	again, err := Run(context.Background(), spec, NewCache("", 0), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(r, again) {
+		t.Error("runs of the same seed differ")
+	}
+
+	spec.Seed = 43
+	other, err := Run(context.Background(), spec, NewCache("", 0), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if reflect.DeepEqual(r.Trades, other.Trades) {
+		t.Error("runs of different seeds agree")
+	}
+	if inputs := Compare(r, other).Inputs; !reflect.DeepEqual(inputs, []string{"seed", "config"}) {
+		t.Errorf("inputs %v differ", inputs)
+	}
+}
+
+func TestRunCanceled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err := Run(ctx, testSpec(), NewCache("", 0), nil); !errors.Is(err, context.Canceled) {
+		t.Fatalf("got %v, want canceled", err)
+	}
+}
+
+func TestSpecValidate(t *testing.T) {
+	for name, change := range map[string]func(*Spec){
+		"strategy": func(s *Spec) { s.Strategy = "unknown" },
+		"symbol":   func(s *Spec) { s.Symbol = "BTCUSDT" },
+		"range":    func(s *Spec) { s.Data.End = s.Data.Start },
+		"both":     func(s *Spec) { s.Data.Recording = "btcusdt.jsonl" },
+		"escape":   func(s *Spec) { s.Data.Market, s.Data.Recording = nil, "../btcusdt.jsonl" },
+		"tables":   func(s *Spec) { s.Data.Market, s.Data.Trades = nil, "/BTC-USDT/trades.col" },
+		"sources":  func(s *Spec) { s.Data.Market, s.Data.Recording, s.Data.Trades = nil, "btcusdt.jsonl", "trades.col" },
+		"negative": func(s *Spec) { s.TakerFeeBps = -1 },
+	} {
+		spec := testSpec()
+		change(&spec)
+		if err := spec.Validate(); err == nil {
+			t.Errorf("%s: invalid spec accepted", name)
+		}
+	}
+	spec := testSpec()
+	spec.Params["band"] = -1
+	if _, err := Run(context.Background(), spec, NewCache("", 0), nil); err == nil {
+		t.Error("invalid strategy parameter accepted")
+	}
+}
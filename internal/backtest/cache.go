@@ -0,0 +1,333 @@
+package backtest
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+
+	"github.com/BullionBear/sequex/pkg/columnar"
+	"github.com/BullionBear/sequex/pkg/lob"
+	"github.com/BullionBear/sequex/pkg/synthdata"
+)
+
+// eventKinds maps the event types onto their column values
+var eventKinds = []lob.EventType{lob.EventSnapshot, lob.EventDepth, lob.EventTrade}
+
+// tradeKind is the column value of trades
+const tradeKind uint8 = 2 // Index of lob.EventTrade
+
+// Series is the events of market data held in columns, a few flat slices
+// rather than an event and its level slices each, so that large ranges stay
+// compact and are replayed by any number of backtests at once. It is
+// read-only once loaded.
+type Series struct {
+	times      []int64
+	kinds      []uint8 // Index in eventKinds
+	prices     []float64
+	quantities []float64
+	sides      []lob.Side
+	// The levels of event i are at levelStart[i] up to levelStart[i+1] in
+	// levelPrice and levelQty, its first bidCount[i] being bids
+	levelStart []int32
+	bidCount   []int32
+	levelPrice []float64
+	levelQty   []float64
+	files      []DataFile // Read to load the events
+}
+
+func newSeries() *Series {
+	return &Series{levelStart: []int32{0}}
+}
+
+func (s *Series) add(e lob.Event) error {
+	kind := -1
+	for i, k := range eventKinds {
+		if e.Type == k {
+			kind = i
+		}
+	}
+	if kind < 0 {
+		return fmt.Errorf("unknown event type %q", e.Type)
+	}
+	s.times = append(s.times, e.Time)
+	s.kinds = append(s.kinds, uint8(kind))
+	s.prices = append(s.prices, e.Price)
+	s.quantities = append(s.quantities, e.Quantity)
+	s.sides = append(s.sides, e.Side)
+	for _, l := range e.Bids {
+		s.levelPrice, s.levelQty = append(s.levelPrice, l.Price), append(s.levelQty, l.Quantity)
+	}
+	for _, l := range e.Asks {
+		s.levelPrice, s.levelQty = append(s.levelPrice, l.Price), append(s.levelQty, l.Quantity)
+	}
+	s.bidCount = append(s.bidCount, int32(len(e.Bids)))
+	s.levelStart = append(s.levelStart, int32(len(s.levelPrice)))
+	return nil
+}
+
+// addTrades appends the rows [i, j) of a trade table, column by column
+func (s *Series) addTrades(t *columnar.Trades, i, j int) {
+	s.times = append(s.times, t.Times[i:j]...)
+	s.prices = append(s.prices, t.Prices[i:j]...)
+	s.quantities = append(s.quantities, t.Quantities[i:j]...)
+	levels := int32(len(s.levelPrice))
+	for k := i; k < j; k++ {
+		s.kinds = append(s.kinds, tradeKind)
+		s.sides = append(s.sides, lob.Side(t.Sides[k]))
+		s.bidCount = append(s.bidCount, 0)
+		s.levelStart = append(s.levelStart, levels)
+	}
+}
+
+// Len returns the number of events
+func (s *Series) Len() int {
+	return len(s.times)
+}
+
+// Bytes returns the memory held by the columns
+func (s *Series) Bytes() int64 {
+	// Time, kind, price, quantity, side, level start and bid count of every
+	// event, price and quantity of every level
+	return int64(len(s.times))*(8+1+8+8+8+4+4) + int64(len(s.levelPrice))*16
+}
+
+// replay applies the events to sim in time order, handing each one to
+// handle with the fills it caused. The level slices of the events are reused
+// from one event to the next.
+func (s *Series) replay(sim *lob.Simulator, handle func(lob.Event, []lob.Fill) error) error {
+	var bids, asks []lob.Level
+	for i := range s.times {
+		e := lob.Event{
+			Type:     eventKinds[s.kinds[i]],
+			Time:     s.times[i],
+			Price:    s.prices[i],
+			Quantity: s.quantities[i],
+			Side:     s.sides[i],
+		}
+		if start, end := s.levelStart[i], s.levelStart[i+1]; end > start {
+			bids, asks = bids[:0], asks[:0]
+			split := start + s.bidCount[i]
+			for k := start; k < end; k++ {
+				l := lob.Level{Price: s.levelPrice[k], Quantity: s.levelQty[k]}
+				if k < split {
+					bids = append(bids, l)
+				} else {
+					asks = append(asks, l)
+				}
+			}
+			e.Bids, e.Asks = bids, asks
+		}
+		fills, err := sim.Apply(e)
+		if err != nil {
+			return fmt.Errorf("event %d: %w", i, err)
+		}
+		if err := handle(e, fills); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// load reads the events of the data, recordings and trade tables from dir
+func (d *Data) load(dir string) (*Series, error) {
+	s := newSeries()
+	for _, path := range []string{d.Trades, d.Recording} {
+		if path == "" {
+			continue
+		}
+		file, err := digest(dir, path)
+		if err != nil {
+			return nil, err
+		}
+		s.files = append(s.files, file)
+	}
+	if d.Trades != "" {
+		t, err := columnar.OpenTrades(filepath.Join(dir, d.Trades))
+		if err != nil {
+			return nil, err
+		}
+		defer t.Close()
+		i, j := t.Range(d.Start.UnixMilli(), d.End.UnixMilli()+1)
+		s.addTrades(t, i, j)
+		return s, nil
+	}
+	if d.Recording != "" {
+		file, err := os.Open(filepath.Join(dir, d.Recording))
+		if err != nil {
+			return nil, err
+		}
+		defer file.Close()
+		scanner := bufio.NewScanner(file)
+		scanner.Buffer(make([]byte, 64*1024), 16*1024*1024) // Snapshots of deep books are long lines
+		line := 0
+		for scanner.Scan() {
+			line++
+			if len(scanner.Bytes()) == 0 {
+				continue
+			}
+			var e lob.Event
+			if err := json.Unmarshal(scanner.Bytes(), &e); err != nil {
+				return nil, fmt.Errorf("%s line %d: %w", d.Recording, line, err)
+			}
+			if e.Time > d.End.UnixMilli() {
+				break
+			}
+			if err := s.add(e); err != nil {
+				return nil, fmt.Errorf("%s line %d: %w", d.Recording, line, err)
+			}
+		}
+		return s, scanner.Err()
+	}
+	g, err := synthdata.New(d.market())
+	if err != nil {
+		return nil, err
+	}
+	return s, g.Until(d.End, s.add)
+}
+
+// market returns the synthetic market of the data, generated from the start
+// of the data unless the market starts earlier, e.g. for the windows of a
+// walk-forward optimization to be ranges of the same market
+func (d *Data) market() synthdata.Config {
+	cfg := synthdata.Config{
+		Price:   100,
+		Regimes: []synthdata.Regime{{Volatility: 0.6, TradeRate: 5}},
+	}
+	if d.Market != nil {
+		cfg = *d.Market
+	}
+	if cfg.Start.IsZero() {
+		cfg.Start = d.Start
+	}
+	return cfg
+}
+
+// key identifies the events of the data: a recording up to the end of the
+// range or a trade table over the range as last modified, or a synthetic
+// market over the range
+func (d *Data) key(dir string) (string, error) {
+	if d.Trades != "" {
+		info, err := os.Stat(filepath.Join(dir, d.Trades))
+		if err != nil {
+			return "", err
+		}
+		return fmt.Sprintf("trades:%s:%d:%d:%d:%d", d.Trades, info.Size(), info.ModTime().UnixNano(), d.Start.UnixMilli(), d.End.UnixMilli()), nil
+	}
+	if d.Recording != "" {
+		info, err := os.Stat(filepath.Join(dir, d.Recording))
+		if err != nil {
+			return "", err
+		}
+		return fmt.Sprintf("recording:%s:%d:%d:%d", d.Recording, info.Size(), info.ModTime().UnixNano(), d.End.UnixMilli()), nil
+	}
+	cfg, err := json.Marshal(d.market())
+	if err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("synthetic:%s:%d", cfg, d.End.UnixMilli()), nil
+}
+
+// CacheStats is the usage of a cache
+type CacheStats struct {
+	Entries int    `json:"entries"`
+	Bytes   int64  `json:"bytes"`
+	Hits    uint64 `json:"hits"`
+	Misses  uint64 `json:"misses"` // Loads of data not cached
+}
+
+// cacheEntry is the series of a key, loaded once however many backtests ask
+// for it at the same time
+type cacheEntry struct {
+	ready  chan struct{} // Closed once loaded
+	series *Series
+	err    error
+	used   uint64 // Sequence of the last use, the least recent being evicted first
+}
+
+// Cache shares the series of market data between backtests, loading each one
+// once and evicting the least recently used beyond a memory budget. Series
+// evicted stay valid for the backtests replaying them. It is safe for
+// concurrent use.
+type Cache struct {
+	dir      string
+	maxBytes int64
+
+	mu      sync.Mutex
+	entries map[string]*cacheEntry
+	seq     uint64
+	stats   CacheStats
+}
+
+// NewCache creates a cache of the recordings of dir and synthetic markets,
+// holding up to maxBytes of series, unbounded when 0
+func NewCache(dir string, maxBytes int64) *Cache {
+	return &Cache{dir: dir, maxBytes: maxBytes, entries: make(map[string]*cacheEntry)}
+}
+
+// Load returns the series of the data, loading it unless cached
+func (c *Cache) Load(d Data) (*Series, error) {
+	key, err := d.key(c.dir)
+	if err != nil {
+		return nil, err
+	}
+	c.mu.Lock()
+	c.seq++
+	e, ok := c.entries[key]
+	if ok {
+		e.used = c.seq
+		c.stats.Hits++
+		c.mu.Unlock()
+		<-e.ready
+		return e.series, e.err
+	}
+	e = &cacheEntry{ready: make(chan struct{}), used: c.seq}
+	c.entries[key] = e
+	c.stats.Misses++
+	c.mu.Unlock()
+
+	series, err := d.load(c.dir)
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	e.series, e.err = series, err
+	close(e.ready)
+	if err != nil {
+		delete(c.entries, key) // Retried by the next backtest
+		return nil, err
+	}
+	c.stats.Bytes += series.Bytes()
+	c.evictLocked(key)
+	return series, nil
+}
+
+// evictLocked drops the least recently used series until the cache fits its
+// budget, keeping the one of key
+func (c *Cache) evictLocked(keep string) {
+	for c.maxBytes > 0 && c.stats.Bytes > c.maxBytes {
+		var oldest string
+		for key, e := range c.entries {
+			if key == keep || e.series == nil {
+				continue // Just loaded, or still loading
+			}
+			if oldest == "" || e.used < c.entries[oldest].used {
+				oldest = key
+			}
+		}
+		if oldest == "" {
+			return
+		}
+		c.stats.Bytes -= c.entries[oldest].series.Bytes()
+		delete(c.entries, oldest)
+	}
+}
+
+// Stats returns the usage of the cache
+func (c *Cache) Stats() CacheStats {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	stats := c.stats
+	stats.Entries = len(c.entries)
+	return stats
+}
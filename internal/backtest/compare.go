@@ -0,0 +1,232 @@
+package backtest
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+)
+
+// metric is a metric of a backtest and whether a higher value is better,
+// compared and gated by name
+type metric struct {
+	name   string
+	value  func(m *Metrics) float64
+	better int // 1 when higher is better, -1 when lower is, 0 when neither
+}
+
+var metrics = []metric{
+	{"pnl", func(m *Metrics) float64 { return m.PnL }, 1},
+	{"return", func(m *Metrics) float64 { return m.Return }, 1},
+	{"sharpe", func(m *Metrics) float64 { return m.Sharpe }, 1},
+	{"max_drawdown", func(m *Metrics) float64 { return m.MaxDrawdown }, -1},
+	{"fees", func(m *Metrics) float64 { return m.Fees }, -1},
+	{"equity", func(m *Metrics) float64 { return m.Equity }, 1},
+	{"fills", func(m *Metrics) float64 { return float64(m.Fills) }, 0},
+	{"orders", func(m *Metrics) float64 { return float64(m.Orders) }, 0},
+	{"rejected", func(m *Metrics) float64 { return float64(m.Rejected) }, -1},
+	{"volume", func(m *Metrics) float64 { return m.Volume }, 0},
+	{"position", func(m *Metrics) float64 { return m.Position }, 0},
+	{"events", func(m *Metrics) float64 { return float64(m.Events) }, 0},
+}
+
+func lookupMetric(name string) (metric, bool) {
+	for _, m := range metrics {
+		if m.name == name {
+			return m, true
+		}
+	}
+	return metric{}, false
+}
+
+// MetricDelta is the change of a metric from a base backtest to a head one
+type MetricDelta struct {
+	Metric string  `json:"metric"`
+	Base   float64 `json:"base"`
+	Head   float64 `json:"head"`
+	Delta  float64 `json:"delta"`  // Head less base
+	Change float64 `json:"change"` // Delta as a share of the magnitude of the base, 0 when the base is 0
+}
+
+// TradeChange is a trade found in only one of two backtests, or in both with
+// a different price, quantity or fee
+type TradeChange struct {
+	Kind string `json:"kind"` // added, removed or changed
+	Base *Trade `json:"base,omitempty"`
+	Head *Trade `json:"head,omitempty"`
+}
+
+// Comparison is the difference between the results of two backtests
+type Comparison struct {
+	Inputs  []string      `json:"inputs,omitempty"` // Of the manifests that differ: seed, config, data or build
+	Metrics []MetricDelta `json:"metrics"`
+	Matched int           `json:"matched"` // Trades identical in both
+	Trades  []TradeChange `json:"trades"`  // In time order
+}
+
+// Compare diffs the result of a head backtest against a base one. Trades
+// are matched by time, side and order id, which deterministic strategies
+// keep stable across runs.
+func Compare(base, head *Result) Comparison {
+	c := Comparison{Inputs: head.Manifest.Diff(&base.Manifest)}
+	for _, m := range metrics {
+		d := MetricDelta{Metric: m.name, Base: m.value(&base.Metrics), Head: m.value(&head.Metrics)}
+		d.Delta = d.Head - d.Base
+		if d.Base != 0 {
+			d.Change = d.Delta / math.Abs(d.Base)
+		}
+		c.Metrics = append(c.Metrics, d)
+	}
+
+	i, j := 0, 0
+	for i < len(base.Trades) || j < len(head.Trades) {
+		switch {
+		case j == len(head.Trades) || (i < len(base.Trades) && tradeBefore(base.Trades[i], head.Trades[j])):
+			c.Trades = append(c.Trades, TradeChange{Kind: "removed", Base: &base.Trades[i]})
+			i++
+		case i == len(base.Trades) || tradeBefore(head.Trades[j], base.Trades[i]):
+			c.Trades = append(c.Trades, TradeChange{Kind: "added", Head: &head.Trades[j]})
+			j++
+		default:
+			b, h := &base.Trades[i], &head.Trades[j]
+			if b.Price == h.Price && b.Quantity == h.Quantity && b.Fee == h.Fee && b.Maker == h.Maker {
+				c.Matched++
+			} else {
+				c.Trades = append(c.Trades, TradeChange{Kind: "changed", Base: b, Head: h})
+			}
+			i, j = i+1, j+1
+		}
+	}
+	return c
+}
+
+// tradeBefore orders trades by time, side and order id
+func tradeBefore(a, b Trade) bool {
+	if a.Time != b.Time {
+		return a.Time < b.Time
+	}
+	if a.Side != b.Side {
+		return a.Side < b.Side
+	}
+	return a.OrderID < b.OrderID
+}
+
+// Rule fails a comparison when a metric of the head is worse than the base
+// by more than a tolerance
+type Rule struct {
+	Metric    string  `json:"metric"`    // e.g. sharpe
+	Tolerance float64 `json:"tolerance"` // Worsening allowed, in the unit of the metric
+	Relative  bool    `json:"relative"`  // The tolerance is a share of the magnitude of the base
+}
+
+func (r Rule) String() string {
+	if r.Relative {
+		return fmt.Sprintf("%s=%g%%", r.Metric, r.Tolerance*100)
+	}
+	return fmt.Sprintf("%s=%g", r.Metric, r.Tolerance)
+}
+
+// Validate validates the rule
+func (r Rule) Validate() error {
+	m, ok := lookupMetric(r.Metric)
+	if !ok {
+		return fmt.Errorf("unknown metric %q", r.Metric)
+	}
+	if m.better == 0 {
+		return fmt.Errorf("metric %s is neither better higher nor lower and cannot be gated", r.Metric)
+	}
+	if r.Tolerance < 0 || math.IsNaN(r.Tolerance) {
+		return fmt.Errorf("tolerance of %s cannot be negative", r.Metric)
+	}
+	return nil
+}
+
+// ParseRules parses comma separated rules of a metric and its tolerance,
+// relative when given in percent, e.g. sharpe=0.1,return=5%,max_drawdown=0.02
+func ParseRules(s string) ([]Rule, error) {
+	var rules []Rule
+	for _, part := range strings.Split(s, ",") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+		name, value, ok := strings.Cut(part, "=")
+		if !ok {
+			return nil, fmt.Errorf("rule %q is not metric=tolerance", part)
+		}
+		r := Rule{Metric: strings.TrimSpace(name)}
+		value = strings.TrimSpace(value)
+		if strings.HasSuffix(value, "%") {
+			r.Relative, value = true, strings.TrimSuffix(value, "%")
+		}
+		tolerance, err := strconv.ParseFloat(value, 64)
+		if err != nil {
+			return nil, fmt.Errorf("rule %q: invalid tolerance: %w", part, err)
+		}
+		r.Tolerance = tolerance
+		if r.Relative {
+			r.Tolerance /= 100
+		}
+		if err := r.Validate(); err != nil {
+			return nil, err
+		}
+		rules = append(rules, r)
+	}
+	return rules, nil
+}
+
+// Violation is a rule a comparison failed
+type Violation struct {
+	Rule      Rule    `json:"rule"`
+	Base      float64 `json:"base"`
+	Head      float64 `json:"head"`
+	Worsening float64 `json:"worsening"` // In the unit of the metric
+	Allowed   float64 `json:"allowed"`   // In the unit of the metric
+}
+
+func (v Violation) String() string {
+	return fmt.Sprintf("%s worsened by %g from %g to %g, more than the %g allowed by %s", v.Rule.Metric, v.Worsening, v.Base, v.Head, v.Allowed, v.Rule)
+}
+
+// Check returns the rules the comparison fails, none when it passes the
+// gate
+func (c Comparison) Check(rules []Rule) ([]Violation, error) {
+	var violations []Violation
+	for _, r := range rules {
+		if err := r.Validate(); err != nil {
+			return nil, err
+		}
+		m, _ := lookupMetric(r.Metric)
+		for _, d := range c.Metrics {
+			if d.Metric != r.Metric {
+				continue
+			}
+			allowed := r.Tolerance
+			if r.Relative {
+				allowed *= math.Abs(d.Base)
+			}
+			if worsening := -d.Delta * float64(m.better); worsening > allowed {
+				violations = append(violations, Violation{Rule: r, Base: d.Base, Head: d.Head, Worsening: worsening, Allowed: allowed})
+			}
+		}
+	}
+	return violations, nil
+}
+
+// Report is a comparison and the outcome of its gate
+type Report struct {
+	Comparison
+	Violations []Violation `json:"violations"`
+	Passed     bool        `json:"passed"`
+}
+
+// Gate compares head against base and checks the comparison against rules
+func Gate(base, head *Result, rules []Rule) (Report, error) {
+	r := Report{Comparison: Compare(base, head)}
+	violations, err := r.Check(rules)
+	if err != nil {
+		return Report{}, err
+	}
+	r.Violations, r.Passed = violations, len(violations) == 0
+	return r, nil
+}
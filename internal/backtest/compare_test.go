@@ -0,0 +1,105 @@
+package backtest
+
+import (
+	"context"
+	"testing"
+
+	"github.com/BullionBear/sequex/pkg/lob"
+)
+
+func TestCompare(t *testing.T) {
+	base := &Result{
+		Metrics: Metrics{PnL: 100, Return: 0.01, Sharpe: 2, MaxDrawdown: 0.05},
+		Trades: []Trade{
+			{OrderID: "a", Side: lob.Buy, Time: 1, Price: 10, Quantity: 1},
+			{OrderID: "b", Side: lob.Sell, Time: 2, Price: 11, Quantity: 1},
+			{OrderID: "c", Side: lob.Buy, Time: 3, Price: 10, Quantity: 1},
+		},
+	}
+	head := &Result{
+		Metrics: Metrics{PnL: 80, Return: 0.008, Sharpe: 1.85, MaxDrawdown: 0.08},
+		Trades: []Trade{
+			{OrderID: "a", Side: lob.Buy, Time: 1, Price: 10, Quantity: 1},
+			{OrderID: "b", Side: lob.Sell, Time: 2, Price: 10.5, Quantity: 1},
+			{OrderID: "d", Side: lob.Sell, Time: 3, Price: 10, Quantity: 1},
+		},
+	}
+	c := Compare(base, head)
+	if c.Matched != 1 || len(c.Trades) != 3 {
+		t.Fatalf("matched %d, changes %+v", c.Matched, c.Trades)
+	}
+	for i, kind := range []string{"changed", "removed", "added"} {
+		if c.Trades[i].Kind != kind {
+			t.Errorf("change %d is %s, want %s", i, c.Trades[i].Kind, kind)
+		}
+	}
+	for _, d := range c.Metrics {
+		if d.Metric == "pnl" && (d.Delta != -20 || d.Change != -0.2) {
+			t.Errorf("pnl delta %+v", d)
+		}
+	}
+
+	for _, tc := range []struct {
+		gate string
+		fail []string
+	}{
+		{"", nil},
+		{"sharpe=0.2,max_drawdown=0.05", nil},
+		{"sharpe=0.1", []string{"sharpe"}},
+		{"pnl=25%,max_drawdown=50%", []string{"max_drawdown"}},
+		{"pnl=10%, return=0.001", []string{"pnl", "return"}},
+	} {
+		rules, err := ParseRules(tc.gate)
+		if err != nil {
+			t.Fatalf("%s: %v", tc.gate, err)
+		}
+		r, err := Gate(base, head, rules)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if r.Passed != (len(tc.fail) == 0) || len(r.Violations) != len(tc.fail) {
+			t.Errorf("%s: violations %v", tc.gate, r.Violations)
+			continue
+		}
+		for i, metric := range tc.fail {
+			if r.Violations[i].Rule.Metric != metric {
+				t.Errorf("%s: violation %d on %s, want %s", tc.gate, i, r.Violations[i].Rule.Metric, metric)
+			}
+		}
+	}
+
+	for _, gate := range []string{"sharpe", "sharpe=x", "unknown=1", "fills=1", "sharpe=-1"} {
+		if _, err := ParseRules(gate); err == nil {
+			t.Errorf("invalid gate %q parsed", gate)
+		}
+	}
+}
+
+func TestCompareRuns(t *testing.T) {
+	spec := testSpec()
+	base, err := Run(context.Background(), spec, NewCache("", 0), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// The same backtest differs in nothing
+	r, err := Gate(base, base, []Rule{{Metric: "sharpe"}, {Metric: "max_drawdown"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !r.Passed || len(r.Trades) != 0 || r.Matched != len(base.Trades) {
+		t.Fatalf("identical runs differ: %+v", r)
+	}
+
+	spec.TakerFeeBps *= 2
+	head, err := Run(context.Background(), spec, NewCache("", 0), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	r, err = Gate(base, head, []Rule{{Metric: "fees"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if r.Passed || len(r.Trades) == 0 {
+		t.Errorf("doubled fees passed the gate with %d trade changes", len(r.Trades))
+	}
+}
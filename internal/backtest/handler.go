@@ -0,0 +1,202 @@
+package backtest
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Register serves the jobs of m:
+//
+//	GET  /strategies                 registered strategies
+//	POST /jobs                       submit a backtest, the Spec as body
+//	POST /sweeps                     submit a sweep of backtests run in parallel, the Sweep as body
+//	POST /walkforwards               submit a walk-forward optimization, the WalkForward as body
+//	GET  /jobs                       every job, latest first
+//	GET  /jobs/:id                   state of a job, with its metrics once succeeded
+//	POST /jobs/:id/cancel            cancel a job queued or running
+//	GET  /jobs/:id/logs?follow=true  log of a job, streamed until it finishes when following
+//	GET  /jobs/:id/result            metrics and equity curve of a succeeded job
+//	GET  /jobs/:id/manifest.json     seed, config hash and data files a succeeded job ran
+//	GET  /jobs/:id/equity.csv        equity curve of a succeeded job as CSV
+//	GET  /jobs/:id/outcomes          metrics of every backtest of a succeeded sweep
+//	GET  /jobs/:id/walkforward       ranked parameters, folds and diagnostics of a succeeded optimization
+//	GET  /compare?base=<id>&head=<id>&gate=sharpe=0.1,return=5%
+//	                                 diff of the results of two jobs, gated by the rules
+func Register(routes gin.IRoutes, m *Manager) {
+	routes.GET("/strategies", func(c *gin.Context) {
+		c.JSON(http.StatusOK, Strategies())
+	})
+	routes.POST("/jobs", func(c *gin.Context) {
+		var spec Spec
+		if err := c.ShouldBindJSON(&spec); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		job, err := m.Submit(spec)
+		if err != nil {
+			c.JSON(status(err), gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusAccepted, job)
+	})
+	routes.POST("/sweeps", func(c *gin.Context) {
+		var sweep Sweep
+		if err := c.ShouldBindJSON(&sweep); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		job, err := m.SubmitSweep(sweep)
+		if err != nil {
+			c.JSON(status(err), gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusAccepted, job)
+	})
+	routes.POST("/walkforwards", func(c *gin.Context) {
+		var w WalkForward
+		if err := c.ShouldBindJSON(&w); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		job, err := m.SubmitWalkForward(w)
+		if err != nil {
+			c.JSON(status(err), gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusAccepted, job)
+	})
+	routes.GET("/jobs", func(c *gin.Context) {
+		c.JSON(http.StatusOK, m.List())
+	})
+	routes.GET("/jobs/:id", func(c *gin.Context) {
+		job, err := m.Get(c.Param("id"))
+		if err != nil {
+			c.JSON(status(err), gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusOK, job)
+	})
+	routes.POST("/jobs/:id/cancel", func(c *gin.Context) {
+		job, err := m.Cancel(c.Param("id"))
+		if err != nil {
+			c.JSON(status(err), gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusOK, job)
+	})
+	routes.GET("/jobs/:id/logs", func(c *gin.Context) {
+		lines, finished, changed, err := m.Logs(c.Param("id"), 0)
+		if err != nil {
+			c.JSON(status(err), gin.H{"error": err.Error()})
+			return
+		}
+		c.Header("Content-Type", "text/plain; charset=utf-8")
+		if c.Query("follow") != "true" {
+			c.String(http.StatusOK, join(lines))
+			return
+		}
+		sent := 0
+		c.Stream(func(w io.Writer) bool {
+			if _, err := w.Write([]byte(join(lines))); err != nil {
+				return false
+			}
+			sent += len(lines)
+			if finished {
+				return false
+			}
+			select {
+			case <-c.Request.Context().Done():
+				return false
+			case <-changed:
+			}
+			lines, finished, changed, err = m.Logs(c.Param("id"), sent)
+			return err == nil
+		})
+	})
+	routes.GET("/jobs/:id/result", func(c *gin.Context) {
+		result, err := m.Result(c.Param("id"))
+		if err != nil {
+			c.JSON(status(err), gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusOK, result)
+	})
+	routes.GET("/jobs/:id/manifest.json", func(c *gin.Context) {
+		path, err := m.Artifact(c.Param("id"), manifestFile)
+		if err != nil {
+			c.JSON(status(err), gin.H{"error": err.Error()})
+			return
+		}
+		c.FileAttachment(path, c.Param("id")+"-manifest.json")
+	})
+	routes.GET("/jobs/:id/equity.csv", func(c *gin.Context) {
+		path, err := m.Artifact(c.Param("id"), equityFile)
+		if err != nil {
+			c.JSON(status(err), gin.H{"error": err.Error()})
+			return
+		}
+		c.FileAttachment(path, c.Param("id")+"-equity.csv")
+	})
+	routes.GET("/jobs/:id/outcomes", func(c *gin.Context) {
+		outcomes, err := m.Outcomes(c.Param("id"))
+		if err != nil {
+			c.JSON(status(err), gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusOK, outcomes)
+	})
+	routes.GET("/jobs/:id/walkforward", func(c *gin.Context) {
+		report, err := m.WalkForwardReport(c.Param("id"))
+		if err != nil {
+			c.JSON(status(err), gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusOK, report)
+	})
+	routes.GET("/compare", func(c *gin.Context) {
+		rules, err := ParseRules(c.Query("gate"))
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		var results [2]*Result
+		for i, id := range []string{c.Query("base"), c.Query("head")} {
+			if results[i], err = m.Result(id); err != nil {
+				c.JSON(status(err), gin.H{"error": err.Error()})
+				return
+			}
+		}
+		report, err := Gate(results[0], results[1], rules)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusOK, report)
+	})
+}
+
+// join returns lines as the text of a log
+func join(lines []string) string {
+	if len(lines) == 0 {
+		return ""
+	}
+	return strings.Join(lines, "\n") + "\n"
+}
+
+// status returns the HTTP status of an error of the manager
+func status(err error) int {
+	switch {
+	case errors.Is(err, ErrJobNotFound):
+		return http.StatusNotFound
+	case errors.Is(err, ErrNotFinished):
+		return http.StatusConflict
+	case errors.Is(err, ErrQueueFull):
+		return http.StatusServiceUnavailable
+	default:
+		return http.StatusBadRequest
+	}
+}
@@ -0,0 +1,560 @@
+package backtest
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+var (
+	// ErrJobNotFound is returned for jobs the manager does not know
+	ErrJobNotFound = errors.New("job not found")
+	// ErrQueueFull is returned when submitting to a full queue
+	ErrQueueFull = errors.New("job queue is full")
+	// ErrNotFinished is returned for the result of a job still queued or
+	// running
+	ErrNotFinished = errors.New("job is not finished")
+)
+
+// Status is the state of a job
+type Status string
+
+const (
+	StatusQueued    Status = "queued"
+	StatusRunning   Status = "running"
+	StatusSucceeded Status = "succeeded"
+	StatusFailed    Status = "failed"
+	StatusCanceled  Status = "canceled"
+)
+
+// Finished reports whether the job reached a final state
+func (s Status) Finished() bool {
+	return s == StatusSucceeded || s == StatusFailed || s == StatusCanceled
+}
+
+// Job is a backtest, a sweep of backtests or a walk-forward optimization,
+// submitted to a manager
+type Job struct {
+	ID          string       `json:"id"`
+	Spec        Spec         `json:"spec"`                   // The base of a sweep or an optimization
+	Sweep       *Sweep       `json:"sweep,omitempty"`        // Nil unless a sweep
+	WalkForward *WalkForward `json:"walk_forward,omitempty"` // Nil unless a walk-forward optimization
+	Status      Status       `json:"status"`
+	Error       string       `json:"error,omitempty"`
+	Submitted   time.Time    `json:"submitted"`
+	Started     time.Time    `json:"started"`
+	Finished    time.Time    `json:"finished"`
+	Metrics     *Metrics     `json:"metrics,omitempty"` // Once a backtest succeeded, the full result being an artifact
+}
+
+// Artifacts of a job in its directory
+const (
+	jobFile         = "job.json"
+	logFile         = "log.txt"
+	resultFile      = "result.json"
+	manifestFile    = "manifest.json"
+	equityFile      = "equity.csv"
+	sweepFile       = "sweep.json"
+	walkForwardFile = "walkforward.json"
+)
+
+// Options configures a manager
+type Options struct {
+	Dir       string           // Jobs and their artifacts are kept in, one directory per job
+	DataDir   string           // Recordings are read from
+	Workers   int              // Jobs run at once, 1 when 0
+	Parallel  int              // Backtests of a sweep or an optimization run at once, GOMAXPROCS when 0
+	CacheSize int64            // Bytes of market data shared by the backtests, unbounded when 0
+	MaxQueued int              // Jobs waiting to run, 100 when 0
+	OnChange  func(job Job)    // Called on every change of status, may be nil
+	Now       func() time.Time // time.Now when nil
+}
+
+// Validate validates the options
+func (o Options) Validate() error {
+	if o.Dir == "" {
+		return fmt.Errorf("dir cannot be empty")
+	}
+	if o.Workers < 0 || o.Parallel < 0 || o.CacheSize < 0 || o.MaxQueued < 0 {
+		return fmt.Errorf("workers, parallel, cache size and max queued cannot be negative")
+	}
+	return nil
+}
+
+// job is a job and its run
+type job struct {
+	Job
+	cancel  context.CancelFunc // Nil unless running
+	log     []string
+	changed chan struct{} // Closed and replaced on every log line and change of status
+}
+
+// Manager queues backtest jobs, runs them on a pool of workers and keeps
+// their artifacts on disk, so results outlive the process. It is safe for
+// concurrent use.
+type Manager struct {
+	opts  Options
+	queue chan *job
+	cache *Cache
+
+	mu   sync.Mutex
+	jobs map[string]*job
+
+	ctx  context.Context
+	stop context.CancelFunc
+	wg   sync.WaitGroup
+}
+
+// NewManager creates a manager and starts its workers. Jobs kept in the
+// directory are loaded; those left unfinished by a previous process are
+// marked failed.
+func NewManager(opts Options) (*Manager, error) {
+	if err := opts.Validate(); err != nil {
+		return nil, err
+	}
+	if opts.Workers == 0 {
+		opts.Workers = 1
+	}
+	if opts.MaxQueued == 0 {
+		opts.MaxQueued = 100
+	}
+	if opts.Now == nil {
+		opts.Now = time.Now
+	}
+	if err := os.MkdirAll(opts.Dir, 0o755); err != nil {
+		return nil, err
+	}
+	m := &Manager{
+		opts:  opts,
+		queue: make(chan *job, opts.MaxQueued),
+		cache: NewCache(opts.DataDir, opts.CacheSize),
+		jobs:  make(map[string]*job),
+	}
+	if err := m.load(); err != nil {
+		return nil, err
+	}
+	m.ctx, m.stop = context.WithCancel(context.Background())
+	for i := 0; i < opts.Workers; i++ {
+		m.wg.Add(1)
+		go m.work()
+	}
+	return m, nil
+}
+
+// load reads the jobs kept in the directory
+func (m *Manager) load() error {
+	entries, err := os.ReadDir(m.opts.Dir)
+	if err != nil {
+		return err
+	}
+	for _, entry := range entries {
+		if !entry.IsDir() {
+			continue
+		}
+		data, err := os.ReadFile(filepath.Join(m.opts.Dir, entry.Name(), jobFile))
+		if errors.Is(err, os.ErrNotExist) {
+			continue
+		}
+		if err != nil {
+			return err
+		}
+		j := &job{changed: make(chan struct{})}
+		if err := json.Unmarshal(data, &j.Job); err != nil {
+			return fmt.Errorf("failed to parse job %s: %w", entry.Name(), err)
+		}
+		if !j.Status.Finished() {
+			j.Status, j.Error, j.Finished = StatusFailed, "interrupted by a restart", m.opts.Now().UTC()
+			if err := m.save(j.Job); err != nil {
+				return err
+			}
+		}
+		if data, err := os.ReadFile(filepath.Join(m.opts.Dir, j.ID, logFile)); err == nil {
+			j.log = strings.Split(strings.TrimSuffix(string(data), "\n"), "\n")
+		}
+		m.jobs[j.ID] = j
+	}
+	return nil
+}
+
+// Submit queues a backtest
+func (m *Manager) Submit(spec Spec) (Job, error) {
+	if err := spec.Validate(); err != nil {
+		return Job{}, err
+	}
+	return m.submit(Job{Spec: spec})
+}
+
+// SubmitSweep queues a sweep of backtests
+func (m *Manager) SubmitSweep(sweep Sweep) (Job, error) {
+	if err := sweep.Validate(); err != nil {
+		return Job{}, err
+	}
+	return m.submit(Job{Spec: sweep.Base, Sweep: &sweep})
+}
+
+// SubmitWalkForward queues a walk-forward optimization
+func (m *Manager) SubmitWalkForward(w WalkForward) (Job, error) {
+	if err := w.Validate(); err != nil {
+		return Job{}, err
+	}
+	return m.submit(Job{Spec: w.Base, WalkForward: &w})
+}
+
+func (m *Manager) submit(submitted Job) (Job, error) {
+	var b [4]byte
+	rand.Read(b[:])
+	now := m.opts.Now().UTC()
+	submitted.ID = now.Format("20060102T150405") + "-" + hex.EncodeToString(b[:])
+	submitted.Status, submitted.Submitted = StatusQueued, now
+	j := &job{Job: submitted, changed: make(chan struct{})}
+	if err := os.MkdirAll(filepath.Join(m.opts.Dir, j.ID), 0o755); err != nil {
+		return Job{}, err
+	}
+	if err := m.save(j.Job); err != nil {
+		return Job{}, err
+	}
+
+	m.mu.Lock()
+	select {
+	case m.queue <- j:
+	default:
+		m.mu.Unlock()
+		os.RemoveAll(filepath.Join(m.opts.Dir, j.ID))
+		return Job{}, ErrQueueFull
+	}
+	m.jobs[j.ID] = j
+	m.mu.Unlock()
+	m.notify(j.Job)
+	return j.Job, nil
+}
+
+// work runs the queued jobs until the manager is closed
+func (m *Manager) work() {
+	defer m.wg.Done()
+	for {
+		select {
+		case <-m.ctx.Done():
+			return
+		case j := <-m.queue:
+			m.run(j)
+		}
+	}
+}
+
+// run runs a job and records its outcome
+func (m *Manager) run(j *job) {
+	m.mu.Lock()
+	if j.Status != StatusQueued {
+		m.mu.Unlock()
+		return // Canceled while queued
+	}
+	ctx, cancel := context.WithCancel(m.ctx)
+	defer cancel()
+	j.cancel = cancel
+	j.Status, j.Started = StatusRunning, m.opts.Now().UTC()
+	m.changeLocked(j)
+	started := j.Job
+	m.mu.Unlock()
+	m.notify(started)
+
+	logf := func(format string, args ...any) {
+		m.logf(j, format, args...)
+	}
+	var result *Result
+	var err error
+	if j.Sweep != nil {
+		err = m.runSweep(ctx, j, logf)
+	} else if j.WalkForward != nil {
+		err = m.runWalkForward(ctx, j, logf)
+	} else if result, err = Run(ctx, j.Spec, m.cache, logf); err == nil {
+		err = m.writeResult(j.ID, result)
+	}
+
+	m.mu.Lock()
+	j.cancel = nil
+	j.Finished = m.opts.Now().UTC()
+	switch {
+	case err == nil:
+		j.Status = StatusSucceeded
+		if result != nil {
+			j.Metrics = &result.Metrics
+		}
+	case m.ctx.Err() != nil:
+		j.Status, j.Error = StatusFailed, "interrupted by a shutdown"
+	case errors.Is(err, context.Canceled):
+		j.Status, j.Error = StatusCanceled, "canceled"
+	default:
+		j.Status, j.Error = StatusFailed, err.Error()
+	}
+	m.changeLocked(j)
+	finished := j.Job
+	m.mu.Unlock()
+	m.notify(finished)
+}
+
+// runSweep runs the backtests of a sweep job and writes their outcomes
+func (m *Manager) runSweep(ctx context.Context, j *job, logf func(format string, args ...any)) error {
+	specs := j.Sweep.Specs()
+	logf("running %d backtests", len(specs))
+	outcomes, err := RunAll(ctx, specs, m.cache, m.opts.Parallel, logf)
+	if err != nil {
+		return err
+	}
+	stats := m.cache.Stats()
+	logf("done: data cache of %d series, %d MiB, %d hits, %d misses", stats.Entries, stats.Bytes>>20, stats.Hits, stats.Misses)
+	data, err := json.Marshal(outcomes)
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(filepath.Join(m.opts.Dir, j.ID, sweepFile), data, 0o644)
+}
+
+// runWalkForward runs a walk-forward optimization job and writes its report
+func (m *Manager) runWalkForward(ctx context.Context, j *job, logf func(format string, args ...any)) error {
+	report, err := RunWalkForward(ctx, *j.WalkForward, m.cache, m.opts.Parallel, logf)
+	if err != nil {
+		return err
+	}
+	d := report.Diagnostics
+	logf("done: out-of-sample pnl %.2f, efficiency %.2f, pbo %.2f, rank correlation %.2f", report.PnL, d.Efficiency, d.PBO, d.RankCorrelation)
+	data, err := json.Marshal(report)
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(filepath.Join(m.opts.Dir, j.ID, walkForwardFile), data, 0o644)
+}
+
+// changeLocked saves a change of status of a job and wakes its followers.
+// The change is announced by the caller once the lock is released.
+func (m *Manager) changeLocked(j *job) {
+	if err := m.save(j.Job); err != nil {
+		m.appendLocked(j, fmt.Sprintf("failed to save job: %v", err))
+	}
+	close(j.changed)
+	j.changed = make(chan struct{})
+}
+
+func (m *Manager) notify(job Job) {
+	if m.opts.OnChange != nil {
+		m.opts.OnChange(job)
+	}
+}
+
+// save writes the job to its directory
+func (m *Manager) save(job Job) error {
+	data, err := json.MarshalIndent(job, "", "  ")
+	if err != nil {
+		return err
+	}
+	path := filepath.Join(m.opts.Dir, job.ID, jobFile)
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0o644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, path)
+}
+
+// writeResult writes the result of a job as JSON, its manifest apart to be
+// diffed against other runs, and its equity curve as CSV for spreadsheets
+// and plotting tools
+func (m *Manager) writeResult(id string, result *Result) error {
+	data, err := json.Marshal(result)
+	if err != nil {
+		return err
+	}
+	if err := os.WriteFile(filepath.Join(m.opts.Dir, id, resultFile), data, 0o644); err != nil {
+		return err
+	}
+	manifest, err := json.MarshalIndent(result.Manifest, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.WriteFile(filepath.Join(m.opts.Dir, id, manifestFile), manifest, 0o644); err != nil {
+		return err
+	}
+	var csv strings.Builder
+	csv.WriteString("time,price,position,equity\n")
+	for _, p := range result.Equity {
+		fmt.Fprintf(&csv, "%d,%g,%g,%g\n", p.Time, p.Price, p.Position, p.Equity)
+	}
+	return os.WriteFile(filepath.Join(m.opts.Dir, id, equityFile), []byte(csv.String()), 0o644)
+}
+
+// logf appends a line to the log of a job
+func (m *Manager) logf(j *job, format string, args ...any) {
+	line := m.opts.Now().UTC().Format(time.RFC3339) + " " + fmt.Sprintf(format, args...)
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.appendLocked(j, line)
+}
+
+func (m *Manager) appendLocked(j *job, line string) {
+	j.log = append(j.log, line)
+	if file, err := os.OpenFile(filepath.Join(m.opts.Dir, j.ID, logFile), os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0o644); err == nil {
+		file.WriteString(line + "\n")
+		file.Close()
+	}
+	close(j.changed)
+	j.changed = make(chan struct{})
+}
+
+// Get returns a job
+func (m *Manager) Get(id string) (Job, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	j, ok := m.jobs[id]
+	if !ok {
+		return Job{}, fmt.Errorf("%w: %s", ErrJobNotFound, id)
+	}
+	return j.Job, nil
+}
+
+// List returns every job, latest submitted first
+func (m *Manager) List() []Job {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	jobs := make([]Job, 0, len(m.jobs))
+	for _, j := range m.jobs {
+		jobs = append(jobs, j.Job)
+	}
+	sort.Slice(jobs, func(i, j int) bool { return jobs[i].ID > jobs[j].ID })
+	return jobs
+}
+
+// Cancel cancels a job queued or running
+func (m *Manager) Cancel(id string) (Job, error) {
+	m.mu.Lock()
+	j, ok := m.jobs[id]
+	if !ok {
+		m.mu.Unlock()
+		return Job{}, fmt.Errorf("%w: %s", ErrJobNotFound, id)
+	}
+	queued := j.Status == StatusQueued
+	switch {
+	case queued:
+		j.Status, j.Error, j.Finished = StatusCanceled, "canceled", m.opts.Now().UTC()
+		m.changeLocked(j)
+	case j.cancel != nil:
+		j.cancel() // The run records the cancellation
+	}
+	job := j.Job
+	m.mu.Unlock()
+	if queued {
+		m.notify(job)
+	}
+	return job, nil
+}
+
+// Logs returns the log lines of a job from the from-th one, whether the job
+// finished, and a channel closed on the next change of the job, to follow
+// the log as it grows
+func (m *Manager) Logs(id string, from int) ([]string, bool, <-chan struct{}, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	j, ok := m.jobs[id]
+	if !ok {
+		return nil, false, nil, fmt.Errorf("%w: %s", ErrJobNotFound, id)
+	}
+	var lines []string
+	if from >= 0 && from < len(j.log) {
+		lines = append(lines, j.log[from:]...)
+	}
+	return lines, j.Status.Finished(), j.changed, nil
+}
+
+// Outcomes returns the outcomes of the backtests of a succeeded sweep job,
+// in the order of its specs
+func (m *Manager) Outcomes(id string) ([]Outcome, error) {
+	path, err := m.Artifact(id, sweepFile)
+	if err != nil {
+		return nil, err
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var outcomes []Outcome
+	if err := json.Unmarshal(data, &outcomes); err != nil {
+		return nil, err
+	}
+	return outcomes, nil
+}
+
+// WalkForwardReport returns the report of a succeeded walk-forward
+// optimization job
+func (m *Manager) WalkForwardReport(id string) (*WalkForwardReport, error) {
+	path, err := m.Artifact(id, walkForwardFile)
+	if err != nil {
+		return nil, err
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var report WalkForwardReport
+	if err := json.Unmarshal(data, &report); err != nil {
+		return nil, err
+	}
+	return &report, nil
+}
+
+// Result returns the result of a succeeded backtest job
+func (m *Manager) Result(id string) (*Result, error) {
+	path, err := m.Artifact(id, resultFile)
+	if err != nil {
+		return nil, err
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var result Result
+	if err := json.Unmarshal(data, &result); err != nil {
+		return nil, err
+	}
+	return &result, nil
+}
+
+// Artifact returns the path of an artifact of a succeeded job: result.json,
+// manifest.json or equity.csv of a backtest, sweep.json of a sweep,
+// walkforward.json of a walk-forward optimization
+func (m *Manager) Artifact(id, name string) (string, error) {
+	job, err := m.Get(id)
+	if err != nil {
+		return "", err
+	}
+	if !job.Status.Finished() {
+		return "", fmt.Errorf("%w: %s is %s", ErrNotFinished, id, job.Status)
+	}
+	if job.Status != StatusSucceeded {
+		return "", fmt.Errorf("job %s %s: %s", id, job.Status, job.Error)
+	}
+	artifacts := []string{resultFile, manifestFile, equityFile}
+	switch {
+	case job.Sweep != nil:
+		artifacts = []string{sweepFile}
+	case job.WalkForward != nil:
+		artifacts = []string{walkForwardFile}
+	}
+	if !slices.Contains(artifacts, name) {
+		return "", fmt.Errorf("job %s has no artifact %q", id, name)
+	}
+	return filepath.Join(m.opts.Dir, id, name), nil
+}
+
+// Close stops the workers, interrupting the running jobs. Jobs still queued
+// are marked failed when the directory is next loaded.
+func (m *Manager) Close() {
+	m.stop()
+	m.wg.Wait()
+}
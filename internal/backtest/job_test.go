@@ -0,0 +1,209 @@
+package backtest
+
+import (
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// wait follows the log of a job until it finishes, returning the lines. A
+// job that never finishes is left to the test timeout.
+func wait(t *testing.T, m *Manager, id string) []string {
+	t.Helper()
+	var log []string
+	for {
+		lines, finished, changed, err := m.Logs(id, len(log))
+		if err != nil {
+			t.Fatal(err)
+		}
+		log = append(log, lines...)
+		if finished {
+			return log
+		}
+		<-changed
+	}
+}
+
+func TestManager(t *testing.T) {
+	dir := t.TempDir()
+	var mu sync.Mutex
+	var changes []Status
+	m, err := NewManager(Options{Dir: dir, OnChange: func(job Job) {
+		mu.Lock()
+		defer mu.Unlock()
+		changes = append(changes, job.Status)
+	}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer m.Close()
+
+	if _, err := m.Submit(Spec{Strategy: "unknown"}); err == nil {
+		t.Error("invalid spec submitted")
+	}
+	job, err := m.Submit(testSpec())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if job.Status != StatusQueued {
+		t.Errorf("submitted job is %s", job.Status)
+	}
+	log := wait(t, m, job.ID)
+	if len(log) < 2 || !strings.Contains(log[len(log)-1], "done") {
+		t.Errorf("log %q", log)
+	}
+
+	job, err = m.Get(job.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if job.Status != StatusSucceeded || job.Metrics == nil || job.Started.IsZero() || job.Finished.Before(job.Started) {
+		t.Fatalf("finished job %+v", job)
+	}
+	result, err := m.Result(job.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.Metrics != *job.Metrics || len(result.Equity) == 0 {
+		t.Errorf("result %+v of job %+v", result.Metrics, job.Metrics)
+	}
+	path, err := m.Artifact(job.ID, "equity.csv")
+	if err != nil {
+		t.Fatal(err)
+	}
+	csv, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if rows := strings.Count(string(csv), "\n"); rows != len(result.Equity)+1 {
+		t.Errorf("%d csv rows for %d samples", rows, len(result.Equity))
+	}
+	if _, err := m.Artifact(job.ID, "manifest.json"); err != nil {
+		t.Error(err)
+	}
+	if _, err := m.Artifact(job.ID, "../job.json"); err == nil {
+		t.Error("served a file that is not an artifact")
+	}
+	if _, err := m.Get("missing"); !errors.Is(err, ErrJobNotFound) {
+		t.Errorf("got %v for a missing job", err)
+	}
+	mu.Lock()
+	if len(changes) != 3 || changes[2] != StatusSucceeded {
+		t.Errorf("changes %v", changes)
+	}
+	mu.Unlock()
+}
+
+func TestManagerCancel(t *testing.T) {
+	m, err := NewManager(Options{Dir: t.TempDir()})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer m.Close()
+
+	// A long backtest keeps the only worker busy, the next one queued
+	spec := testSpec()
+	spec.Data.End = spec.Data.Start.Add(30 * 24 * time.Hour)
+	running, err := m.Submit(spec)
+	if err != nil {
+		t.Fatal(err)
+	}
+	queued, err := m.Submit(spec)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, id := range []string{queued.ID, running.ID} {
+		if _, err := m.Cancel(id); err != nil {
+			t.Fatal(err)
+		}
+		wait(t, m, id)
+		job, _ := m.Get(id)
+		if job.Status != StatusCanceled {
+			t.Errorf("canceled job is %s", job.Status)
+		}
+		if _, err := m.Result(id); err == nil {
+			t.Error("result of a canceled job")
+		}
+	}
+}
+
+func TestManagerSweep(t *testing.T) {
+	m, err := NewManager(Options{Dir: t.TempDir(), Parallel: 3})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer m.Close()
+
+	sweep := testSweep()
+	sweep.Variants = append(sweep.Variants, Variant{Strategy: "unknown"})
+	if _, err := m.SubmitSweep(sweep); err == nil {
+		t.Error("invalid sweep submitted")
+	}
+	sweep = testSweep()
+	job, err := m.SubmitSweep(sweep)
+	if err != nil {
+		t.Fatal(err)
+	}
+	log := wait(t, m, job.ID)
+	if job, _ = m.Get(job.ID); job.Status != StatusSucceeded || job.Sweep == nil || job.Metrics != nil {
+		t.Fatalf("finished sweep %+v: %q", job, log)
+	}
+	outcomes, err := m.Outcomes(job.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if specs := sweep.Specs(); len(outcomes) != len(specs) {
+		t.Fatalf("%d outcomes of %d backtests", len(outcomes), len(specs))
+	}
+	for i, o := range outcomes {
+		if o.Metrics == nil || o.Error != "" {
+			t.Errorf("outcome %d: %+v", i, o)
+		}
+	}
+	if _, err := m.Result(job.ID); err == nil {
+		t.Error("result of a sweep")
+	}
+}
+
+func TestManagerReload(t *testing.T) {
+	dir := t.TempDir()
+	m, err := NewManager(Options{Dir: dir})
+	if err != nil {
+		t.Fatal(err)
+	}
+	done, err := m.Submit(testSpec())
+	if err != nil {
+		t.Fatal(err)
+	}
+	wait(t, m, done.ID)
+	m.Close()
+
+	// A job left running by a crashed process
+	interrupted := Job{ID: "interrupted", Spec: testSpec(), Status: StatusRunning}
+	data, _ := json.Marshal(interrupted)
+	os.MkdirAll(filepath.Join(dir, interrupted.ID), 0o755)
+	os.WriteFile(filepath.Join(dir, interrupted.ID, "job.json"), data, 0o644)
+
+	m, err = NewManager(Options{Dir: dir})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer m.Close()
+	if jobs := m.List(); len(jobs) != 2 {
+		t.Fatalf("%d jobs reloaded", len(jobs))
+	}
+	if _, err := m.Result(done.ID); err != nil {
+		t.Errorf("result lost on restart: %v", err)
+	}
+	if lines, _, _, _ := m.Logs(done.ID, 0); len(lines) == 0 {
+		t.Error("log lost on restart")
+	}
+	if job, _ := m.Get(interrupted.ID); job.Status != StatusFailed {
+		t.Errorf("interrupted job is %s", job.Status)
+	}
+}
@@ -0,0 +1,87 @@
+package backtest
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"io"
+	"os"
+	"path/filepath"
+	"reflect"
+
+	"github.com/BullionBear/sequex/env"
+)
+
+// DataFile is a file of market data a backtest read
+type DataFile struct {
+	Path   string `json:"path"` // In the data directory
+	Size   int64  `json:"size"`
+	SHA256 string `json:"sha256"`
+}
+
+// Manifest records what a backtest ran: the seed of its randomness, a hash
+// of its spec with the defaults applied, the files of its data and the build
+// of the code. Runs with the same manifest give the same result, so results
+// are reproduced from it and a difference between two results is traced
+// to the inputs that differ.
+type Manifest struct {
+	Seed       int64      `json:"seed"`
+	ConfigHash string     `json:"config_hash"`    // SHA-256 of the spec as run
+	Data       []DataFile `json:"data,omitempty"` // None for a synthetic market, which the spec describes
+	Version    string     `json:"version,omitempty"`
+	CommitHash string     `json:"commit_hash,omitempty"`
+	Dirty      bool       `json:"dirty,omitempty"` // Built from a tree with uncommitted changes, which the commit does not reproduce
+}
+
+// newManifest returns the manifest of a run of spec, its defaults applied,
+// over series
+func newManifest(spec Spec, series *Series) (Manifest, error) {
+	config, err := json.Marshal(spec)
+	if err != nil {
+		return Manifest{}, err
+	}
+	sum := sha256.Sum256(config)
+	build := env.Info()
+	return Manifest{
+		Seed:       spec.Seed,
+		ConfigHash: hex.EncodeToString(sum[:]),
+		Data:       series.files,
+		Version:    build.Version,
+		CommitHash: build.CommitHash,
+		Dirty:      build.Dirty,
+	}, nil
+}
+
+// Diff returns the inputs that differ from those of other: seed, config,
+// data or build
+func (m *Manifest) Diff(other *Manifest) []string {
+	var inputs []string
+	if m.Seed != other.Seed {
+		inputs = append(inputs, "seed")
+	}
+	if m.ConfigHash != other.ConfigHash {
+		inputs = append(inputs, "config")
+	}
+	if !reflect.DeepEqual(m.Data, other.Data) {
+		inputs = append(inputs, "data")
+	}
+	if m.Version != other.Version || m.CommitHash != other.CommitHash || m.Dirty != other.Dirty {
+		inputs = append(inputs, "build")
+	}
+	return inputs
+}
+
+// digest returns the size and hash of a file of the data directory
+func digest(dir, path string) (DataFile, error) {
+	file, err := os.Open(filepath.Join(dir, path))
+	if err != nil {
+		return DataFile{}, err
+	}
+	defer file.Close()
+	h := sha256.New()
+	size, err := io.Copy(h, file)
+	if err != nil {
+		return DataFile{}, err
+	}
+	return DataFile{Path: path, Size: size, SHA256: hex.EncodeToString(h.Sum(nil))}, nil
+}
@@ -0,0 +1,166 @@
+package backtest
+
+import (
+	"context"
+
+	"github.com/BullionBear/sequex/pkg/eventbus"
+	"github.com/nats-io/nats.go"
+)
+
+// DefaultSubject is the prefix the backtest RPCs are served under when none
+// is configured
+const DefaultSubject = "backtest"
+
+// SubmitRequest submits a backtest
+type SubmitRequest struct {
+	Spec Spec `json:"spec"`
+}
+
+// SweepRequest submits a sweep of backtests
+type SweepRequest struct {
+	Sweep Sweep `json:"sweep"`
+}
+
+// WalkForwardRequest submits a walk-forward optimization
+type WalkForwardRequest struct {
+	WalkForward WalkForward `json:"walk_forward"`
+}
+
+// JobRequest asks for the state of a job, or cancels it
+type JobRequest struct {
+	ID string `json:"id"`
+}
+
+// ListRequest asks for every job
+type ListRequest struct{}
+
+// List is the jobs of a manager, latest submitted first
+type List struct {
+	Jobs []Job `json:"jobs"`
+}
+
+// Outcomes is the outcomes of the backtests of a sweep, in the order of its
+// specs
+type Outcomes struct {
+	Outcomes []Outcome `json:"outcomes"`
+}
+
+// SubmitEndpoint is the endpoint backtests are submitted on, prefix.submit
+func SubmitEndpoint(prefix string) eventbus.Endpoint[SubmitRequest, Job] {
+	return eventbus.NewCommand[SubmitRequest, Job](prefix+".submit", eventbus.JSON)
+}
+
+// SweepEndpoint is the endpoint sweeps are submitted on, prefix.sweep
+func SweepEndpoint(prefix string) eventbus.Endpoint[SweepRequest, Job] {
+	return eventbus.NewCommand[SweepRequest, Job](prefix+".sweep", eventbus.JSON)
+}
+
+// WalkForwardEndpoint is the endpoint walk-forward optimizations are
+// submitted on, prefix.walkforward
+func WalkForwardEndpoint(prefix string) eventbus.Endpoint[WalkForwardRequest, Job] {
+	return eventbus.NewCommand[WalkForwardRequest, Job](prefix+".walkforward", eventbus.JSON)
+}
+
+// StatusEndpoint is the endpoint jobs are looked up on, prefix.status
+func StatusEndpoint(prefix string) eventbus.Endpoint[JobRequest, Job] {
+	return eventbus.NewEndpoint[JobRequest, Job](prefix+".status", eventbus.JSON)
+}
+
+// CancelEndpoint is the endpoint jobs are canceled on, prefix.cancel
+func CancelEndpoint(prefix string) eventbus.Endpoint[JobRequest, Job] {
+	return eventbus.NewCommand[JobRequest, Job](prefix+".cancel", eventbus.JSON)
+}
+
+// ResultEndpoint is the endpoint the results of succeeded jobs are fetched
+// on, prefix.result
+func ResultEndpoint(prefix string) eventbus.Endpoint[JobRequest, Result] {
+	return eventbus.NewEndpoint[JobRequest, Result](prefix+".result", eventbus.JSON)
+}
+
+// OutcomesEndpoint is the endpoint the outcomes of succeeded sweeps are
+// fetched on, prefix.outcomes
+func OutcomesEndpoint(prefix string) eventbus.Endpoint[JobRequest, Outcomes] {
+	return eventbus.NewEndpoint[JobRequest, Outcomes](prefix+".outcomes", eventbus.JSON)
+}
+
+// ReportEndpoint is the endpoint the reports of succeeded walk-forward
+// optimizations are fetched on, prefix.report
+func ReportEndpoint(prefix string) eventbus.Endpoint[JobRequest, WalkForwardReport] {
+	return eventbus.NewEndpoint[JobRequest, WalkForwardReport](prefix+".report", eventbus.JSON)
+}
+
+// ListEndpoint is the endpoint jobs are listed on, prefix.list
+func ListEndpoint(prefix string) eventbus.Endpoint[ListRequest, List] {
+	return eventbus.NewEndpoint[ListRequest, List](prefix+".list", eventbus.JSON)
+}
+
+// Serve answers the backtest RPCs of m under prefix
+func Serve(bus *eventbus.Bus, prefix string, m *Manager) ([]*nats.Subscription, error) {
+	var subs []*nats.Subscription
+	for _, serve := range []func() (*nats.Subscription, error){
+		func() (*nats.Subscription, error) {
+			return SubmitEndpoint(prefix).Serve(bus, func(_ context.Context, req *SubmitRequest) (*Job, error) {
+				job, err := m.Submit(req.Spec)
+				return &job, err
+			})
+		},
+		func() (*nats.Subscription, error) {
+			return SweepEndpoint(prefix).Serve(bus, func(_ context.Context, req *SweepRequest) (*Job, error) {
+				job, err := m.SubmitSweep(req.Sweep)
+				return &job, err
+			})
+		},
+		func() (*nats.Subscription, error) {
+			return WalkForwardEndpoint(prefix).Serve(bus, func(_ context.Context, req *WalkForwardRequest) (*Job, error) {
+				job, err := m.SubmitWalkForward(req.WalkForward)
+				return &job, err
+			})
+		},
+		func() (*nats.Subscription, error) {
+			return StatusEndpoint(prefix).Serve(bus, func(_ context.Context, req *JobRequest) (*Job, error) {
+				job, err := m.Get(req.ID)
+				return &job, err
+			})
+		},
+		func() (*nats.Subscription, error) {
+			return CancelEndpoint(prefix).Serve(bus, func(_ context.Context, req *JobRequest) (*Job, error) {
+				job, err := m.Cancel(req.ID)
+				return &job, err
+			})
+		},
+		func() (*nats.Subscription, error) {
+			return ResultEndpoint(prefix).Serve(bus, func(_ context.Context, req *JobRequest) (*Result, error) {
+				return m.Result(req.ID)
+			})
+		},
+		func() (*nats.Subscription, error) {
+			return OutcomesEndpoint(prefix).Serve(bus, func(_ context.Context, req *JobRequest) (*Outcomes, error) {
+				outcomes, err := m.Outcomes(req.ID)
+				if err != nil {
+					return nil, err
+				}
+				return &Outcomes{Outcomes: outcomes}, nil
+			})
+		},
+		func() (*nats.Subscription, error) {
+			return ReportEndpoint(prefix).Serve(bus, func(_ context.Context, req *JobRequest) (*WalkForwardReport, error) {
+				return m.WalkForwardReport(req.ID)
+			})
+		},
+		func() (*nats.Subscription, error) {
+			return ListEndpoint(prefix).Serve(bus, func(_ context.Context, _ *ListRequest) (*List, error) {
+				return &List{Jobs: m.List()}, nil
+			})
+		},
+	} {
+		sub, err := serve()
+		if err != nil {
+			for _, sub := range subs {
+				sub.Unsubscribe()
+			}
+			return nil, err
+		}
+		subs = append(subs, sub)
+	}
+	return subs, nil
+}
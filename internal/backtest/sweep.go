@@ -0,0 +1,131 @@
+package backtest
+
+import (
+	"context"
+	"fmt"
+	"runtime"
+	"sync"
+)
+
+// maxSweep bounds the backtests of a sweep
+const maxSweep = 10000
+
+// Variant is a strategy and its parameters
+type Variant struct {
+	Strategy string             `json:"strategy"`
+	Params   map[string]float64 `json:"params,omitempty"`
+}
+
+// Market is a symbol and the data it is backtested over
+type Market struct {
+	Symbol string `json:"symbol"`
+	Data   Data   `json:"data"`
+}
+
+// Sweep is a grid of backtests, every variant run on every market with the
+// settings of the base
+type Sweep struct {
+	Base     Spec      `json:"base"`
+	Variants []Variant `json:"variants"` // The strategy of the base when empty
+	Markets  []Market  `json:"markets"`  // The symbol and data of the base when empty
+}
+
+// Specs returns the backtests of the sweep, the variants of the first
+// market first
+func (s *Sweep) Specs() []Spec {
+	variants, markets := s.Variants, s.Markets
+	if len(variants) == 0 {
+		variants = []Variant{{Strategy: s.Base.Strategy, Params: s.Base.Params}}
+	}
+	if len(markets) == 0 {
+		markets = []Market{{Symbol: s.Base.Symbol, Data: s.Base.Data}}
+	}
+	specs := make([]Spec, 0, len(variants)*len(markets))
+	for _, m := range markets {
+		for _, v := range variants {
+			spec := s.Base
+			spec.Strategy, spec.Params = v.Strategy, v.Params
+			spec.Symbol, spec.Data = m.Symbol, m.Data
+			specs = append(specs, spec)
+		}
+	}
+	return specs
+}
+
+// Validate validates every backtest of the sweep
+func (s *Sweep) Validate() error {
+	if n := max(len(s.Variants), 1) * max(len(s.Markets), 1); n > maxSweep {
+		return fmt.Errorf("sweep of %d backtests exceeds %d", n, maxSweep)
+	}
+	for i, spec := range s.Specs() {
+		if err := spec.Validate(); err != nil {
+			return fmt.Errorf("backtest %d: %w", i, err)
+		}
+	}
+	return nil
+}
+
+// Outcome is the outcome of a backtest of a sweep
+type Outcome struct {
+	Spec     Spec      `json:"spec"`
+	Manifest *Manifest `json:"manifest,omitempty"` // Unless it failed
+	Metrics  *Metrics  `json:"metrics,omitempty"`  // Unless it failed
+	Error    string    `json:"error,omitempty"`
+}
+
+// RunAll runs backtests on workers goroutines, GOMAXPROCS when 0, the
+// backtests over the same data sharing its series in cache. The outcomes are
+// in the order of specs; a backtest failing does not stop the others. It
+// stops with the error of ctx once it is done.
+func RunAll(ctx context.Context, specs []Spec, cache *Cache, workers int, logf func(format string, args ...any)) ([]Outcome, error) {
+	if workers <= 0 {
+		workers = runtime.GOMAXPROCS(0)
+	}
+	if logf == nil {
+		logf = func(string, ...any) {}
+	}
+	outcomes := make([]Outcome, len(specs))
+	next := make(chan int)
+	var wg sync.WaitGroup
+	var mu sync.Mutex // Orders the log lines
+	done := 0
+	for w := 0; w < min(workers, len(specs)); w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range next {
+				o := Outcome{Spec: specs[i]}
+				r, err := Run(ctx, specs[i], cache, nil)
+				if err != nil {
+					o.Error = err.Error()
+				} else {
+					o.Manifest, o.Metrics = &r.Manifest, &r.Metrics
+				}
+				outcomes[i] = o
+
+				mu.Lock()
+				done++
+				if err != nil {
+					logf("%d/%d %s %v on %s failed: %v", done, len(specs), o.Spec.Strategy, o.Spec.Params, o.Spec.Symbol, err)
+				} else {
+					logf("%d/%d %s %v on %s: pnl %.2f, return %.4f, sharpe %.2f", done, len(specs), o.Spec.Strategy, o.Spec.Params, o.Spec.Symbol, r.Metrics.PnL, r.Metrics.Return, r.Metrics.Sharpe)
+				}
+				mu.Unlock()
+			}
+		}()
+	}
+feed:
+	for i := range specs {
+		select {
+		case next <- i:
+		case <-ctx.Done():
+			break feed
+		}
+	}
+	close(next)
+	wg.Wait()
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	return outcomes, nil
+}
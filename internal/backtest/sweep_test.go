@@ -0,0 +1,113 @@
+package backtest
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"sync"
+	"testing"
+	"time"
+)
+
+func testSweep() Sweep {
+	base := testSpec()
+	later := base.Data
+	later.Start, later.End = later.Start.Add(time.Hour), later.End.Add(time.Hour)
+	return Sweep{
+		Base: base,
+		Variants: []Variant{
+			{Strategy: "breakout", Params: map[string]float64{"band": 0.0005}},
+			{Strategy: "breakout", Params: map[string]float64{"band": 0.001}},
+			{Strategy: "breakout", Params: map[string]float64{"band": 0.002}},
+		},
+		Markets: []Market{
+			{Symbol: "BTC-USDT", Data: base.Data},
+			{Symbol: "ETH-USDT", Data: later},
+		},
+	}
+}
+
+func TestRunAll(t *testing.T) {
+	sweep := testSweep()
+	if err := sweep.Validate(); err != nil {
+		t.Fatal(err)
+	}
+	specs := sweep.Specs()
+	if len(specs) != 6 || specs[0].Symbol != "BTC-USDT" || specs[3].Symbol != "ETH-USDT" || specs[4].Params["band"] != 0.001 {
+		t.Fatalf("specs %+v", specs)
+	}
+	specs = append(specs, Spec{Strategy: "breakout", Symbol: "BTC-USDT", Data: Data{Start: specs[0].Data.Start, End: specs[0].Data.End, Recording: "missing.jsonl"}})
+
+	cache := NewCache(t.TempDir(), 0)
+	var mu sync.Mutex
+	var log []string
+	outcomes, err := RunAll(context.Background(), specs, cache, 4, func(format string, args ...any) {
+		mu.Lock()
+		defer mu.Unlock()
+		log = append(log, format)
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(outcomes) != len(specs) || len(log) != len(specs) {
+		t.Fatalf("%d outcomes, %d log lines of %d backtests", len(outcomes), len(log), len(specs))
+	}
+	// Running concurrently over shared series changes nothing
+	for i, spec := range specs[:6] {
+		r, err := Run(context.Background(), spec, NewCache("", 0), nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if outcomes[i].Metrics == nil || !reflect.DeepEqual(*outcomes[i].Metrics, r.Metrics) {
+			t.Errorf("backtest %d: %+v, sequentially %+v", i, outcomes[i].Metrics, r.Metrics)
+		}
+	}
+	if last := outcomes[6]; last.Error == "" || last.Metrics != nil {
+		t.Errorf("backtest of a missing recording: %+v", last)
+	}
+	// Each market is loaded once, the missing recording failing to be stat
+	if stats := cache.Stats(); stats.Entries != 2 || stats.Misses != 2 || stats.Hits != 4 || stats.Bytes == 0 {
+		t.Errorf("cache stats %+v", stats)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err := RunAll(ctx, specs, cache, 2, nil); !errors.Is(err, context.Canceled) {
+		t.Errorf("canceled sweep returned %v", err)
+	}
+
+	sweep.Variants = append(sweep.Variants, Variant{Strategy: "unknown"})
+	if err := sweep.Validate(); !errors.Is(err, ErrUnknownStrategy) {
+		t.Errorf("sweep of an unknown strategy: %v", err)
+	}
+}
+
+func TestCacheEviction(t *testing.T) {
+	sweep := testSweep()
+	first, second := sweep.Markets[0].Data, sweep.Markets[1].Data
+	probe := NewCache("", 0)
+	series, err := probe.Load(first)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if series.Len() == 0 {
+		t.Fatal("no events loaded")
+	}
+
+	// A budget of a bit more than one series keeps only the latest loaded
+	cache := NewCache("", series.Bytes()*3/2)
+	for _, d := range []Data{first, second, first} {
+		if _, err := cache.Load(d); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if stats := cache.Stats(); stats.Entries != 1 || stats.Misses != 3 || stats.Hits != 0 || stats.Bytes != series.Bytes() {
+		t.Errorf("cache stats %+v", stats)
+	}
+	if again, err := cache.Load(first); err != nil || again.Len() != series.Len() {
+		t.Errorf("reloaded %d events of %d: %v", again.Len(), series.Len(), err)
+	}
+	if stats := cache.Stats(); stats.Hits != 1 {
+		t.Errorf("cache stats %+v", stats)
+	}
+}
@@ -0,0 +1,535 @@
+package backtest
+
+import (
+	"context"
+	"fmt"
+	"maps"
+	"math"
+	"math/rand"
+	"runtime"
+	"slices"
+	"sort"
+	"time"
+)
+
+// bayesianBatch is the number of parameter sets a bayesian search proposes
+// at a time, their backtests on every fold run in parallel. It does not
+// depend on the workers, so that a search is reproduced by its seed.
+const bayesianBatch = 4
+
+// Search methods of a walk-forward optimization
+const (
+	SearchGrid     = "grid"
+	SearchRandom   = "random"
+	SearchBayesian = "bayesian"
+)
+
+// Param is the range of a strategy parameter searched over
+type Param struct {
+	Values []float64 `json:"values,omitempty"` // Tried by a grid search
+	Min    float64   `json:"min,omitempty"`    // Bounds of random and bayesian searches
+	Max    float64   `json:"max,omitempty"`
+}
+
+// Search chooses the parameters a walk-forward optimization tries
+type Search struct {
+	Method string           `json:"method,omitempty"` // grid, random or bayesian, grid when empty
+	Params map[string]Param `json:"params"`
+	Trials int              `json:"trials,omitempty"` // Parameter sets tried by random and bayesian searches
+	Seed   int64            `json:"seed,omitempty"`   // Of random and bayesian searches, 1 when 0
+}
+
+// WalkForward optimizes the parameters of a strategy over rolling windows of
+// its data: the parameters best in each in-sample window are tested on the
+// out-of-sample window following it, so the performance reported is that of
+// parameters chosen without seeing the data they trade
+type WalkForward struct {
+	Base          Spec   `json:"base"`                // Strategy, symbol, settings and data range, its params the values of those not searched
+	Search        Search `json:"search"`              // Parameter sets tried, every one on every window
+	InSampleMs    int64  `json:"in_sample_ms"`        // Window the parameters are chosen on
+	OutOfSampleMs int64  `json:"out_of_sample_ms"`    // Window following it the parameters are tested on, and the step between folds
+	Anchored      bool   `json:"anchored,omitempty"`  // In-sample windows all start at the start of the data, growing fold by fold
+	Objective     string `json:"objective,omitempty"` // Metric the parameters are ranked by, sharpe when empty
+}
+
+// Window is a range of the data
+type Window struct {
+	Start time.Time `json:"start"`
+	End   time.Time `json:"end"`
+}
+
+// Windows returns the in-sample and out-of-sample windows of the folds, as
+// many as fit in the data
+func (w *WalkForward) Windows() (in, out []Window) {
+	inSample := time.Duration(w.InSampleMs) * time.Millisecond
+	outOfSample := time.Duration(w.OutOfSampleMs) * time.Millisecond
+	if inSample <= 0 || outOfSample <= 0 {
+		return nil, nil
+	}
+	start := w.Base.Data.Start
+	for from := start; !from.Add(inSample + outOfSample).After(w.Base.Data.End); from = from.Add(outOfSample) {
+		split := from.Add(inSample)
+		if w.Anchored {
+			in = append(in, Window{Start: start, End: split})
+		} else {
+			in = append(in, Window{Start: from, End: split})
+		}
+		out = append(out, Window{Start: split, End: split.Add(outOfSample)})
+	}
+	return in, out
+}
+
+// objective returns the metric the parameters are ranked by
+func (w *WalkForward) objective() (metric, error) {
+	name := w.Objective
+	if name == "" {
+		name = "sharpe"
+	}
+	m, ok := lookupMetric(name)
+	if !ok {
+		return metric{}, fmt.Errorf("unknown objective %q", name)
+	}
+	if m.better == 0 {
+		return metric{}, fmt.Errorf("objective %s is neither better higher nor lower", name)
+	}
+	return m, nil
+}
+
+// trials returns the number of parameter sets the search tries
+func (w *WalkForward) trials() int {
+	if w.Search.Method != "" && w.Search.Method != SearchGrid {
+		return w.Search.Trials
+	}
+	n := 1
+	for _, p := range w.Search.Params {
+		n *= len(p.Values)
+		if n > maxSweep {
+			return n
+		}
+	}
+	return n
+}
+
+// Validate validates the optimization
+func (w *WalkForward) Validate() error {
+	if err := w.Base.Validate(); err != nil {
+		return fmt.Errorf("base: %w", err)
+	}
+	if _, err := w.objective(); err != nil {
+		return err
+	}
+	if w.InSampleMs <= 0 || w.OutOfSampleMs <= 0 {
+		return fmt.Errorf("in-sample and out-of-sample windows must be positive")
+	}
+	in, _ := w.Windows()
+	if len(in) == 0 {
+		return fmt.Errorf("data range is shorter than an in-sample and an out-of-sample window")
+	}
+	if len(w.Search.Params) == 0 {
+		return fmt.Errorf("search params cannot be empty")
+	}
+	switch w.Search.Method {
+	case "", SearchGrid:
+		for name, p := range w.Search.Params {
+			if len(p.Values) == 0 {
+				return fmt.Errorf("param %s: values of a grid search cannot be empty", name)
+			}
+		}
+	case SearchRandom, SearchBayesian:
+		for name, p := range w.Search.Params {
+			if math.IsNaN(p.Min) || math.IsInf(p.Min, 0) || math.IsInf(p.Max, 0) || !(p.Min < p.Max) {
+				return fmt.Errorf("param %s: min must be below max", name)
+			}
+		}
+		if w.Search.Trials <= 0 {
+			return fmt.Errorf("trials must be positive")
+		}
+	default:
+		return fmt.Errorf("unknown search method %q", w.Search.Method)
+	}
+	if n := w.trials() * len(in) * 2; n > maxSweep {
+		return fmt.Errorf("optimization of %d backtests exceeds %d", n, maxSweep)
+	}
+	return nil
+}
+
+// spec returns the backtest of a parameter set over a window. The synthetic
+// market of the base is generated from the start of its data, so that every
+// window is a range of the same market.
+func (w *WalkForward) spec(params map[string]float64, window Window) Spec {
+	spec := w.Base
+	spec.Params = maps.Clone(w.Base.Params)
+	if spec.Params == nil {
+		spec.Params = make(map[string]float64, len(params))
+	}
+	maps.Copy(spec.Params, params)
+	if spec.Data.Recording == "" && spec.Data.Trades == "" {
+		market := w.Base.Data.market()
+		spec.Data.Market = &market
+	}
+	spec.Data.Start, spec.Data.End = window.Start, window.End
+	return spec
+}
+
+// Candidate is a parameter set tried by a walk-forward optimization
+type Candidate struct {
+	Params      map[string]float64 `json:"params"`
+	Rank        int                `json:"rank"`            // By the mean objective in sample, 1 the best
+	InSample    float64            `json:"in_sample"`       // Mean objective over the in-sample windows
+	OutOfSample float64            `json:"out_of_sample"`   // Mean objective over the out-of-sample windows
+	Metrics     []*Metrics         `json:"metrics"`         // Out of sample, by fold
+	Error       string             `json:"error,omitempty"` // Of a backtest that failed, ranking the candidate last
+}
+
+// Fold is a window the parameters are chosen on and the window following it
+// they are tested on
+type Fold struct {
+	InSample    Window             `json:"in_sample"`
+	OutOfSample Window             `json:"out_of_sample"`
+	Params      map[string]float64 `json:"params,omitempty"`  // Best in sample, none when every backtest failed
+	Chosen      *Metrics           `json:"chosen,omitempty"`  // In sample, of the parameters
+	Metrics     *Metrics           `json:"metrics,omitempty"` // Out of sample, of the parameters
+	Rank        int                `json:"rank,omitempty"`    // Of the parameters out of sample among the candidates, 1 the best
+}
+
+// Diagnostics tell a robust optimization from an overfit one
+type Diagnostics struct {
+	Efficiency      float64 `json:"efficiency"`       // Mean objective out of sample of the parameters chosen to their mean in sample, well under 1 when overfit
+	PBO             float64 `json:"pbo"`              // Share of folds whose parameters rank in the worse half out of sample, the probability of backtest overfitting
+	RankCorrelation float64 `json:"rank_correlation"` // Spearman correlation of the candidates in and out of sample, near 0 or below when the ranking does not carry over
+	Distinct        int     `json:"distinct"`         // Parameter sets chosen over the folds, as many as folds for unstable optima
+}
+
+// WalkForwardReport is the outcome of a walk-forward optimization
+type WalkForwardReport struct {
+	Objective   string      `json:"objective"`
+	Folds       []Fold      `json:"folds"`
+	PnL         float64     `json:"pnl"`        // Out of sample over the folds, each traded with its parameters
+	Candidates  []Candidate `json:"candidates"` // Ranked
+	Diagnostics Diagnostics `json:"diagnostics"`
+}
+
+// candidate is a parameter set and its backtests
+type candidate struct {
+	params  map[string]float64
+	in, out []*Metrics // By fold, nil where the backtest failed
+	err     string
+}
+
+// score returns the objective of metrics, higher being better, -Inf for a
+// backtest that failed
+func score(objective metric, m *Metrics) float64 {
+	if m == nil {
+		return math.Inf(-1)
+	}
+	return objective.value(m) * float64(objective.better)
+}
+
+// mean returns the mean score over the folds
+func mean(objective metric, metrics []*Metrics) float64 {
+	var sum float64
+	for _, m := range metrics {
+		sum += score(objective, m)
+	}
+	return sum / float64(len(metrics))
+}
+
+// RunWalkForward runs a walk-forward optimization, every parameter set on
+// every window, the backtests on workers goroutines, GOMAXPROCS when 0. A
+// bayesian search tries its parameter sets in batches, each proposed from
+// the scores in sample of those tried. It stops with the error of ctx once
+// it is done.
+func RunWalkForward(ctx context.Context, w WalkForward, cache *Cache, workers int, logf func(format string, args ...any)) (*WalkForwardReport, error) {
+	if err := w.Validate(); err != nil {
+		return nil, err
+	}
+	if workers <= 0 {
+		workers = runtime.GOMAXPROCS(0)
+	}
+	if logf == nil {
+		logf = func(string, ...any) {}
+	}
+	objective, _ := w.objective()
+	in, out := w.Windows()
+	trials := w.trials()
+	logf("trying %d parameter sets over %d folds", trials, len(in))
+
+	var candidates []*candidate
+	evaluate := func(sets []map[string]float64) error {
+		specs := make([]Spec, 0, len(sets)*len(in)*2)
+		for _, params := range sets {
+			for f := range in {
+				specs = append(specs, w.spec(params, in[f]), w.spec(params, out[f]))
+			}
+		}
+		outcomes, err := RunAll(ctx, specs, cache, workers, nil)
+		if err != nil {
+			return err
+		}
+		for i, params := range sets {
+			c := &candidate{params: params, in: make([]*Metrics, len(in)), out: make([]*Metrics, len(in))}
+			for f := range in {
+				for k, o := range outcomes[(i*len(in)+f)*2 : (i*len(in)+f)*2+2] {
+					if o.Error != "" && c.err == "" {
+						c.err = o.Error
+					}
+					if k == 0 {
+						c.in[f] = o.Metrics
+					} else {
+						c.out[f] = o.Metrics
+					}
+				}
+			}
+			candidates = append(candidates, c)
+		}
+		best := candidates[0]
+		for _, c := range candidates {
+			if mean(objective, c.in) > mean(objective, best.in) {
+				best = c
+			}
+		}
+		logf("%d/%d parameter sets tried, best in sample %v", len(candidates), trials, best.params)
+		return nil
+	}
+
+	s := newSearcher(w.Search)
+	if w.Search.Method == SearchBayesian {
+		for len(candidates) < trials {
+			scored := make([]scoredParams, len(candidates))
+			for i, c := range candidates {
+				scored[i] = scoredParams{params: c.params, score: mean(objective, c.in)}
+			}
+			sets := make([]map[string]float64, min(bayesianBatch, trials-len(candidates)))
+			for i := range sets {
+				sets[i] = s.propose(scored)
+			}
+			if err := evaluate(sets); err != nil {
+				return nil, err
+			}
+		}
+	} else if err := evaluate(s.sets(trials)); err != nil {
+		return nil, err
+	}
+	return report(objective, candidates, in, out), nil
+}
+
+// report ranks the candidates, chooses the parameters of every fold and
+// diagnoses the optimization
+func report(objective metric, candidates []*candidate, in, out []Window) *WalkForwardReport {
+	r := &WalkForwardReport{Objective: objective.name}
+	better := float64(objective.better)
+	order := make([]int, len(candidates))
+	for i := range order {
+		order[i] = i
+	}
+	sort.SliceStable(order, func(i, j int) bool {
+		return mean(objective, candidates[order[i]].in) > mean(objective, candidates[order[j]].in)
+	})
+	var inMeans, outMeans []float64 // Of the candidates that never failed
+	for rank, i := range order {
+		c := candidates[i]
+		ranked := Candidate{Params: c.params, Rank: rank + 1, Metrics: c.out, Error: c.err}
+		if c.err == "" {
+			ranked.InSample, ranked.OutOfSample = mean(objective, c.in)*better, mean(objective, c.out)*better
+			inMeans, outMeans = append(inMeans, ranked.InSample*better), append(outMeans, ranked.OutOfSample*better)
+		}
+		r.Candidates = append(r.Candidates, ranked)
+	}
+
+	var chosenIn, chosenOut float64
+	var folds, worse int
+	distinct := make(map[string]bool)
+	for f := range in {
+		fold := Fold{InSample: in[f], OutOfSample: out[f]}
+		var chosen *candidate
+		for _, i := range order {
+			c := candidates[i]
+			if c.in[f] != nil && c.out[f] != nil && (chosen == nil || score(objective, c.in[f]) > score(objective, chosen.in[f])) {
+				chosen = c
+			}
+		}
+		if chosen != nil {
+			fold.Params, fold.Chosen, fold.Metrics = chosen.params, chosen.in[f], chosen.out[f]
+			// Ranked out of sample among the candidates tested on the fold
+			tested, ahead := 0, 0
+			for _, c := range candidates {
+				if c.out[f] != nil {
+					tested++
+					if score(objective, c.out[f]) > score(objective, chosen.out[f]) {
+						ahead++
+					}
+				}
+			}
+			fold.Rank = ahead + 1
+			if tested > 1 && float64(ahead)/float64(tested-1) > 0.5 {
+				worse++
+			}
+			r.PnL += chosen.out[f].PnL
+			chosenIn += objective.value(chosen.in[f])
+			chosenOut += objective.value(chosen.out[f])
+			distinct[fmt.Sprint(chosen.params)] = true
+			folds++
+		}
+		r.Folds = append(r.Folds, fold)
+	}
+
+	if folds > 0 {
+		r.Diagnostics.PBO = float64(worse) / float64(folds)
+		if chosenIn != 0 {
+			r.Diagnostics.Efficiency = chosenOut / chosenIn
+		}
+	}
+	r.Diagnostics.Distinct = len(distinct)
+	r.Diagnostics.RankCorrelation = spearman(inMeans, outMeans)
+	return r
+}
+
+// spearman returns the rank correlation of x and y, 0 for fewer than 3
+// pairs or a constant series
+func spearman(x, y []float64) float64 {
+	if len(x) < 3 {
+		return 0
+	}
+	rx, ry := ranks(x), ranks(y)
+	var mx, my float64
+	for i := range rx {
+		mx += rx[i]
+		my += ry[i]
+	}
+	mx /= float64(len(rx))
+	my /= float64(len(ry))
+	var cov, vx, vy float64
+	for i := range rx {
+		cov += (rx[i] - mx) * (ry[i] - my)
+		vx += (rx[i] - mx) * (rx[i] - mx)
+		vy += (ry[i] - my) * (ry[i] - my)
+	}
+	if vx == 0 || vy == 0 {
+		return 0
+	}
+	return cov / math.Sqrt(vx*vy)
+}
+
+// ranks returns the ranks of values, ties sharing their mean rank
+func ranks(values []float64) []float64 {
+	order := make([]int, len(values))
+	for i := range order {
+		order[i] = i
+	}
+	sort.Slice(order, func(i, j int) bool { return values[order[i]] < values[order[j]] })
+	r := make([]float64, len(values))
+	for i := 0; i < len(order); {
+		j := i
+		for j+1 < len(order) && values[order[j+1]] == values[order[i]] {
+			j++
+		}
+		for k := i; k <= j; k++ {
+			r[order[k]] = float64(i+j)/2 + 1
+		}
+		i = j + 1
+	}
+	return r
+}
+
+// scoredParams is a parameter set tried and its mean score in sample
+type scoredParams struct {
+	params map[string]float64
+	score  float64
+}
+
+// searcher draws the parameter sets of a search
+type searcher struct {
+	search Search
+	names  []string // Sorted, so the sets drawn only depend on the seed
+	rand   *rand.Rand
+}
+
+func newSearcher(search Search) *searcher {
+	seed := search.Seed
+	if seed == 0 {
+		seed = 1
+	}
+	return &searcher{search: search, names: slices.Sorted(maps.Keys(search.Params)), rand: rand.New(rand.NewSource(seed))}
+}
+
+// sets returns the parameter sets of a grid or random search
+func (s *searcher) sets(n int) []map[string]float64 {
+	sets := make([]map[string]float64, 0, n)
+	if s.search.Method == SearchRandom {
+		for range n {
+			sets = append(sets, s.random())
+		}
+		return sets
+	}
+	// Every combination of the values of a grid, the last name varying first
+	index := make([]int, len(s.names))
+	for range n {
+		set := make(map[string]float64, len(s.names))
+		for i, name := range s.names {
+			set[name] = s.search.Params[name].Values[index[i]]
+		}
+		sets = append(sets, set)
+		for i := len(index) - 1; i >= 0; i-- {
+			if index[i]++; index[i] < len(s.search.Params[s.names[i]].Values) {
+				break
+			}
+			index[i] = 0
+		}
+	}
+	return sets
+}
+
+func (s *searcher) random() map[string]float64 {
+	set := make(map[string]float64, len(s.names))
+	for _, name := range s.names {
+		p := s.search.Params[name]
+		set[name] = p.Min + s.rand.Float64()*(p.Max-p.Min)
+	}
+	return set
+}
+
+// propose returns the next parameter set of a bayesian search, a tree
+// structured Parzen estimator: random until a few sets were scored, then the
+// most likely of draws around the best quarter of the sets to come from them
+// rather than from the rest
+func (s *searcher) propose(scored []scoredParams) map[string]float64 {
+	if len(scored) < max(len(s.names)+1, min(10, s.search.Trials/4)) {
+		return s.random()
+	}
+	sorted := slices.Clone(scored)
+	sort.SliceStable(sorted, func(i, j int) bool { return sorted[i].score > sorted[j].score })
+	good, bad := sorted[:(len(sorted)+3)/4], sorted[(len(sorted)+3)/4:]
+
+	var best map[string]float64
+	bestRatio := math.Inf(-1)
+	for range 24 {
+		anchor := good[s.rand.Intn(len(good))].params
+		set := make(map[string]float64, len(s.names))
+		var ratio float64
+		for _, name := range s.names {
+			p := s.search.Params[name]
+			width := (p.Max - p.Min) / (2 * math.Sqrt(float64(len(good))))
+			v := anchor[name] + s.rand.NormFloat64()*width
+			if v < p.Min || v > p.Max {
+				v = p.Min + s.rand.Float64()*(p.Max-p.Min) // Rather than piling up on the bounds
+			}
+			set[name] = v
+			ratio += math.Log(parzen(v, name, good, p, width)) - math.Log(parzen(v, name, bad, p, width))
+		}
+		if ratio > bestRatio {
+			best, bestRatio = set, ratio
+		}
+	}
+	return best
+}
+
+// parzen returns the density at v of a parameter of sets, a gaussian kernel
+// around each mixed with the uniform prior over its range
+func parzen(v float64, name string, sets []scoredParams, p Param, width float64) float64 {
+	density := 1 / (p.Max - p.Min)
+	for _, s := range sets {
+		z := (v - s.params[name]) / width
+		density += math.Exp(-z*z/2) / (width * math.Sqrt(2*math.Pi))
+	}
+	return density / float64(len(sets)+1)
+}
@@ -0,0 +1,185 @@
+package backtest
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func testWalkForward() WalkForward {
+	base := testSpec()
+	base.Data.End = base.Data.Start.Add(30 * time.Minute)
+	return WalkForward{
+		Base:          base,
+		Search:        Search{Params: map[string]Param{"band": {Values: []float64{0.0002, 0.0005, 0.001}}}},
+		InSampleMs:    (10 * time.Minute).Milliseconds(),
+		OutOfSampleMs: (5 * time.Minute).Milliseconds(),
+	}
+}
+
+func TestWalkForwardWindows(t *testing.T) {
+	w := testWalkForward()
+	in, out := w.Windows()
+	// Folds step by the out-of-sample window while both fit
+	if len(in) != 4 || len(out) != 4 {
+		t.Fatalf("%d in-sample and %d out-of-sample windows", len(in), len(out))
+	}
+	for f := range in {
+		if !in[f].End.Equal(out[f].Start) || in[f].End.Sub(in[f].Start) != 10*time.Minute || out[f].End.Sub(out[f].Start) != 5*time.Minute {
+			t.Errorf("fold %d: %+v then %+v", f, in[f], out[f])
+		}
+	}
+	if last := out[len(out)-1]; last.End.After(w.Base.Data.End) {
+		t.Errorf("last window %+v beyond the data", last)
+	}
+	w.Anchored = true
+	in, _ = w.Windows()
+	if !in[3].Start.Equal(w.Base.Data.Start) || in[3].End.Sub(in[3].Start) != 25*time.Minute {
+		t.Errorf("anchored window %+v", in[3])
+	}
+}
+
+func TestRunWalkForward(t *testing.T) {
+	w := testWalkForward()
+	r, err := RunWalkForward(context.Background(), w, NewCache("", 0), 4, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(r.Folds) != 4 || len(r.Candidates) != 3 || r.Objective != "sharpe" {
+		t.Fatalf("report %+v", r)
+	}
+	for i, c := range r.Candidates {
+		if c.Rank != i+1 || c.Error != "" || len(c.Metrics) != 4 {
+			t.Errorf("candidate %d %+v", i, c)
+		}
+		if i > 0 && c.InSample > r.Candidates[i-1].InSample {
+			t.Errorf("candidate %d ranked below a worse one", i)
+		}
+	}
+	var pnl float64
+	for f, fold := range r.Folds {
+		if fold.Params == nil || fold.Metrics == nil || fold.Rank < 1 || fold.Rank > 3 {
+			t.Fatalf("fold %d %+v", f, fold)
+		}
+		pnl += fold.Metrics.PnL
+	}
+	if pnl != r.PnL {
+		t.Errorf("pnl %g of the folds, %g reported", pnl, r.PnL)
+	}
+	// The out-of-sample windows are ranges of the same synthetic market
+	if metrics := r.Folds[0].Metrics; metrics.Events == 0 || metrics.Events == r.Folds[1].Metrics.Events && metrics.PnL == r.Folds[1].Metrics.PnL {
+		t.Errorf("folds replayed the same data: %+v", metrics)
+	}
+	d := r.Diagnostics
+	if d.PBO < 0 || d.PBO > 1 || d.RankCorrelation < -1 || d.RankCorrelation > 1 || d.Distinct < 1 || d.Distinct > 3 {
+		t.Errorf("diagnostics %+v", d)
+	}
+
+	again, err := RunWalkForward(context.Background(), w, NewCache("", 0), 2, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(r, again) {
+		t.Error("optimizations of the same walk-forward differ")
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err := RunWalkForward(ctx, w, NewCache("", 0), 2, nil); !errors.Is(err, context.Canceled) {
+		t.Errorf("canceled optimization returned %v", err)
+	}
+}
+
+func TestRunWalkForwardSearches(t *testing.T) {
+	for _, method := range []string{SearchRandom, SearchBayesian} {
+		w := testWalkForward()
+		w.Search = Search{Method: method, Params: map[string]Param{"band": {Min: 0.0002, Max: 0.002}}, Trials: 8, Seed: 7}
+		w.Objective = "max_drawdown"
+		r, err := RunWalkForward(context.Background(), w, NewCache("", 0), 4, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(r.Candidates) != 8 {
+			t.Fatalf("%s: %d candidates", method, len(r.Candidates))
+		}
+		seen := make(map[float64]bool)
+		for i, c := range r.Candidates {
+			band := c.Params["band"]
+			if band < 0.0002 || band > 0.002 || seen[band] {
+				t.Errorf("%s: band %g tried", method, band)
+			}
+			seen[band] = true
+			// Lower drawdowns rank first
+			if i > 0 && c.InSample < r.Candidates[i-1].InSample {
+				t.Errorf("%s: candidate %d ranked below a worse one", method, i)
+			}
+		}
+		again, err := RunWalkForward(context.Background(), w, NewCache("", 0), 1, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !reflect.DeepEqual(r.Candidates, again.Candidates) {
+			t.Errorf("%s: searches of the same seed differ", method)
+		}
+	}
+}
+
+func TestWalkForwardValidate(t *testing.T) {
+	for name, change := range map[string]func(*WalkForward){
+		"base":      func(w *WalkForward) { w.Base.Strategy = "unknown" },
+		"objective": func(w *WalkForward) { w.Objective = "fills" },
+		"windows":   func(w *WalkForward) { w.OutOfSampleMs = 0 },
+		"short":     func(w *WalkForward) { w.InSampleMs = (time.Hour).Milliseconds() },
+		"params":    func(w *WalkForward) { w.Search.Params = nil },
+		"values":    func(w *WalkForward) { w.Search.Params["band"] = Param{} },
+		"method":    func(w *WalkForward) { w.Search.Method = "annealing" },
+		"bounds": func(w *WalkForward) {
+			w.Search = Search{Method: SearchRandom, Params: map[string]Param{"band": {Min: 1, Max: 1}}, Trials: 4}
+		},
+		"trials": func(w *WalkForward) {
+			w.Search = Search{Method: SearchBayesian, Params: map[string]Param{"band": {Min: 0, Max: 1}}}
+		},
+		"size": func(w *WalkForward) {
+			w.Search = Search{Method: SearchRandom, Params: map[string]Param{"band": {Min: 0, Max: 1}}, Trials: maxSweep}
+		},
+	} {
+		w := testWalkForward()
+		change(&w)
+		if err := w.Validate(); err == nil {
+			t.Errorf("%s: invalid walk-forward accepted", name)
+		}
+	}
+}
+
+func TestManagerWalkForward(t *testing.T) {
+	m, err := NewManager(Options{Dir: t.TempDir(), Parallel: 4})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer m.Close()
+
+	job, err := m.SubmitWalkForward(testWalkForward())
+	if err != nil {
+		t.Fatal(err)
+	}
+	log := wait(t, m, job.ID)
+	if !strings.Contains(log[len(log)-1], "efficiency") {
+		t.Errorf("log %q", log)
+	}
+	if job, err = m.Get(job.ID); err != nil || job.Status != StatusSucceeded || job.WalkForward == nil {
+		t.Fatalf("job %+v: %v", job, err)
+	}
+	report, err := m.WalkForwardReport(job.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(report.Folds) != 4 || len(report.Candidates) != 3 {
+		t.Errorf("report %+v", report)
+	}
+	if _, err := m.Result(job.ID); err == nil {
+		t.Error("result of an optimization served")
+	}
+}
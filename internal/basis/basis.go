@@ -0,0 +1,249 @@
+// Package basis estimates the yield of a cash-and-carry position, long spot
+// and short the perpetual of the same underlying, from the spot price, the
+// perp mark price and its funding rate, and signals when to enter and exit.
+package basis
+
+import (
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/BullionBear/sequex/internal/model/sqx"
+)
+
+const hoursPerYear = 365 * 24
+
+// Action is what a signal asks the strategy to do
+type Action string
+
+const (
+	ActionEnter Action = "enter" // Buy spot and sell the perp
+	ActionExit  Action = "exit"  // Unwind both legs
+)
+
+// Rule configures the thresholds of one underlying
+type Rule struct {
+	Symbol          string  // Underlying, e.g. BTC-USDT
+	EntryAPR        float64 // Annualized yield at or above which the carry is entered, e.g. 0.15
+	ExitAPR         float64 // Annualized yield at or below which the carry is exited, below EntryAPR
+	MinFundingRate  float64 // Funding rate required to enter, so a wide basis alone is not enough
+	RoundTripFee    float64 // Fees and slippage of entering and exiting both legs, as a fraction of notional
+	HoldingHours    float64 // Expected holding period the basis and fees are amortized over, 168 when 0
+	FundingInterval float64 // Hours between fundings, 8 when 0
+}
+
+// Estimate is the expected carry of an underlying from its latest prices
+type Estimate struct {
+	Symbol          string  `json:"symbol"`
+	SpotPrice       float64 `json:"spot_price"`
+	MarkPrice       float64 `json:"mark_price"`
+	FundingRate     float64 `json:"funding_rate"`
+	Basis           float64 `json:"basis"`            // Relative premium of the perp over spot
+	AnnualizedBasis float64 `json:"annualized_basis"` // Basis captured over the holding period, annualized
+	FundingAPR      float64 `json:"funding_apr"`      // Funding rate annualized
+	FeeAPR          float64 `json:"fee_apr"`          // Round trip fees amortized over the holding period, annualized
+	Yield           float64 `json:"yield"`            // Expected annualized return of the carry, net of fees
+	Open            bool    `json:"open"`             // Whether the carry is currently entered
+	Timestamp       int64   `json:"timestamp"`
+}
+
+// Signal asks the strategy to enter or exit the carry of an underlying
+type Signal struct {
+	Action Action `json:"action"`
+	Estimate
+}
+
+// Options configures a basis monitor
+type Options struct {
+	Rules  []Rule
+	MaxAge time.Duration // Prices older than this are not joined, 0 keeps them forever
+}
+
+type leg struct {
+	spot     float64
+	spotAt   time.Time
+	mark     sqx.MarkPrice
+	markAt   time.Time
+	open     bool
+	estimate *Estimate
+}
+
+// Monitor joins spot and perp prices per underlying. It is safe for
+// concurrent use.
+type Monitor struct {
+	mu    sync.Mutex
+	opts  Options
+	rules map[string]Rule // Keyed by symbol
+	legs  map[string]*leg
+	now   func() time.Time
+}
+
+// New validates the rules and creates a monitor
+func New(opts Options) (*Monitor, error) {
+	rules, err := compile(opts.Rules)
+	if err != nil {
+		return nil, err
+	}
+	m := &Monitor{
+		opts:  opts,
+		rules: rules,
+		legs:  make(map[string]*leg, len(rules)),
+		now:   time.Now,
+	}
+	for symbol := range rules {
+		m.legs[symbol] = &leg{}
+	}
+	return m, nil
+}
+
+// compile validates the rules and keys them by symbol, with their defaults
+func compile(list []Rule) (map[string]Rule, error) {
+	rules := make(map[string]Rule, len(list))
+	for i, rule := range list {
+		symbol, err := sqx.NewSymbolFromStr(rule.Symbol)
+		if err != nil {
+			return nil, fmt.Errorf("rules[%d]: %w", i, err)
+		}
+		if rule.ExitAPR >= rule.EntryAPR {
+			return nil, fmt.Errorf("rules[%d]: exit_apr must be below entry_apr", i)
+		}
+		if rule.RoundTripFee < 0 || rule.HoldingHours < 0 || rule.FundingInterval < 0 {
+			return nil, fmt.Errorf("rules[%d]: fees and durations cannot be negative", i)
+		}
+		if rule.HoldingHours == 0 {
+			rule.HoldingHours = 7 * 24
+		}
+		if rule.FundingInterval == 0 {
+			rule.FundingInterval = 8
+		}
+		rule.Symbol = symbol.String()
+		if _, ok := rules[rule.Symbol]; ok {
+			return nil, fmt.Errorf("rules[%d]: duplicate symbol %s", i, rule.Symbol)
+		}
+		rules[rule.Symbol] = rule
+	}
+	return rules, nil
+}
+
+// Reconfigure replaces the rules and the max age of the monitor at runtime.
+// The prices of the underlyings kept, and whether their carry is entered,
+// carry over; the new thresholds apply from their next prices.
+func (m *Monitor) Reconfigure(opts Options) error {
+	rules, err := compile(opts.Rules)
+	if err != nil {
+		return err
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	legs := make(map[string]*leg, len(rules))
+	for symbol := range rules {
+		if l, ok := m.legs[symbol]; ok {
+			legs[symbol] = l
+		} else {
+			legs[symbol] = &leg{}
+		}
+	}
+	m.opts, m.rules, m.legs = opts, rules, legs
+	return nil
+}
+
+// OnTrade records the price of a spot trade
+func (m *Monitor) OnTrade(trade sqx.Trade) []Signal {
+	if trade.InstrumentType != sqx.InstrumentTypeSpot || trade.Price <= 0 {
+		return nil
+	}
+	symbol := trade.Symbol.String()
+	at := time.UnixMilli(trade.Timestamp)
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	rule, ok := m.rules[symbol]
+	if !ok {
+		return nil
+	}
+	l := m.legs[symbol]
+	if l.spotAt.After(at) {
+		return nil
+	}
+	l.spot, l.spotAt = trade.Price, at
+	return m.evaluateLocked(rule, l)
+}
+
+// OnMark records the mark price and funding rate of a perpetual
+func (m *Monitor) OnMark(mark sqx.MarkPrice) []Signal {
+	if mark.MarkPrice <= 0 {
+		return nil
+	}
+	at := time.UnixMilli(mark.Timestamp)
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	rule, ok := m.rules[mark.Symbol.String()]
+	if !ok {
+		return nil
+	}
+	l := m.legs[rule.Symbol]
+	if l.markAt.After(at) {
+		return nil
+	}
+	l.mark, l.markAt = mark, at
+	return m.evaluateLocked(rule, l)
+}
+
+// evaluateLocked updates the estimate of an underlying once both legs are
+// fresh and returns the signal its thresholds trigger, if any
+func (m *Monitor) evaluateLocked(rule Rule, l *leg) []Signal {
+	if l.spot == 0 || l.mark.MarkPrice == 0 {
+		return nil
+	}
+	if m.opts.MaxAge > 0 {
+		now := m.now()
+		if now.Sub(l.spotAt) > m.opts.MaxAge || now.Sub(l.markAt) > m.opts.MaxAge {
+			return nil
+		}
+	}
+
+	e := Estimate{
+		Symbol:      rule.Symbol,
+		SpotPrice:   l.spot,
+		MarkPrice:   l.mark.MarkPrice,
+		FundingRate: l.mark.FundingRate,
+		Basis:       l.mark.MarkPrice/l.spot - 1,
+		Timestamp:   max(l.spotAt.UnixMilli(), l.markAt.UnixMilli()),
+	}
+	e.AnnualizedBasis = e.Basis * hoursPerYear / rule.HoldingHours
+	e.FundingAPR = e.FundingRate * hoursPerYear / rule.FundingInterval
+	e.FeeAPR = rule.RoundTripFee * hoursPerYear / rule.HoldingHours
+	e.Yield = e.FundingAPR + e.AnnualizedBasis - e.FeeAPR
+
+	var signals []Signal
+	switch {
+	case !l.open && e.Yield >= rule.EntryAPR && e.FundingRate >= rule.MinFundingRate:
+		l.open = true
+		signals = append(signals, Signal{Action: ActionEnter})
+	case l.open && e.Yield <= rule.ExitAPR:
+		l.open = false
+		signals = append(signals, Signal{Action: ActionExit})
+	}
+	e.Open = l.open
+	l.estimate = &e
+	for i := range signals {
+		signals[i].Estimate = e
+	}
+	return signals
+}
+
+// Estimates returns the latest estimate of every underlying with both legs known
+func (m *Monitor) Estimates() []Estimate {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	list := make([]Estimate, 0, len(m.legs))
+	for _, l := range m.legs {
+		if l.estimate != nil {
+			list = append(list, *l.estimate)
+		}
+	}
+	sort.Slice(list, func(i, j int) bool { return list[i].Symbol < list[j].Symbol })
+	return list
+}
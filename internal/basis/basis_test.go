@@ -0,0 +1,134 @@
+package basis
+
+import (
+	"math"
+	"testing"
+	"time"
+
+	"github.com/BullionBear/sequex/internal/model/sqx"
+)
+
+func near(a, b float64) bool {
+	return math.Abs(a-b) < 1e-9*math.Max(1, math.Abs(b))
+}
+
+func spot(price float64, ts int64) sqx.Trade {
+	return sqx.Trade{Exchange: sqx.ExchangeBinance, InstrumentType: sqx.InstrumentTypeSpot, Symbol: sqx.NewSymbol("BTC", "USDT"), Price: price, Timestamp: ts}
+}
+
+func mark(price, funding float64, ts int64) sqx.MarkPrice {
+	return sqx.MarkPrice{Exchange: sqx.ExchangeBinancePerp, InstrumentType: sqx.InstrumentTypePerp, Symbol: sqx.NewSymbol("BTC", "USDT"), MarkPrice: price, FundingRate: funding, Timestamp: ts}
+}
+
+func TestCarrySignals(t *testing.T) {
+	m, err := New(Options{Rules: []Rule{{
+		Symbol:         "btc-usdt",
+		EntryAPR:       0.15,
+		ExitAPR:        0.05,
+		MinFundingRate: 0.0001,
+		RoundTripFee:   0.001,
+		HoldingHours:   365 * 24,
+	}}})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if signals := m.OnTrade(spot(100000, 1)); len(signals) != 0 || len(m.Estimates()) != 0 {
+		t.Fatalf("signals with one leg = %+v", signals)
+	}
+	// 0.0002 per 8h is 21.9% a year, plus a 0.5% premium over a year, minus 0.1% fees
+	signals := m.OnMark(mark(100500, 0.0002, 2))
+	if len(signals) != 1 || signals[0].Action != ActionEnter || !signals[0].Open {
+		t.Fatalf("signals = %+v", signals)
+	}
+	e := signals[0].Estimate
+	if !near(e.Basis, 0.005) || !near(e.FundingAPR, 0.219) || !near(e.FeeAPR, 0.001) || !near(e.Yield, 0.223) {
+		t.Errorf("estimate = %+v", e)
+	}
+
+	// Between the thresholds the carry is held
+	if signals := m.OnMark(mark(100000, 0.0001, 3)); len(signals) != 0 {
+		t.Errorf("signals between thresholds = %+v", signals)
+	}
+	signals = m.OnMark(mark(100000, 0.00003, 4))
+	if len(signals) != 1 || signals[0].Action != ActionExit || signals[0].Open {
+		t.Fatalf("exit signals = %+v", signals)
+	}
+	if estimates := m.Estimates(); len(estimates) != 1 || estimates[0].Open {
+		t.Errorf("estimates = %+v", estimates)
+	}
+
+	// A wide basis without enough funding does not enter
+	if signals := m.OnMark(mark(120000, 0, 5)); len(signals) != 0 {
+		t.Errorf("signals without funding = %+v", signals)
+	}
+}
+
+func TestStaleLegs(t *testing.T) {
+	m, err := New(Options{Rules: []Rule{{Symbol: "BTC-USDT", EntryAPR: 0.1}}, MaxAge: time.Minute})
+	if err != nil {
+		t.Fatal(err)
+	}
+	now := time.Now()
+	m.now = func() time.Time { return now }
+
+	m.OnTrade(spot(100000, now.Add(-2*time.Minute).UnixMilli()))
+	if signals := m.OnMark(mark(101000, 0.001, now.UnixMilli())); len(signals) != 0 {
+		t.Errorf("signals with a stale spot price = %+v", signals)
+	}
+	if signals := m.OnTrade(spot(100000, now.UnixMilli())); len(signals) != 1 {
+		t.Errorf("signals with fresh legs = %+v", signals)
+	}
+	// Perp trades and older marks are ignored
+	perp := spot(90000, now.UnixMilli())
+	perp.InstrumentType = sqx.InstrumentTypePerp
+	if signals := m.OnTrade(perp); signals != nil {
+		t.Errorf("perp trade signals = %+v", signals)
+	}
+	if signals := m.OnMark(mark(1, 0, now.Add(-time.Second).UnixMilli())); signals != nil {
+		t.Errorf("older mark signals = %+v", signals)
+	}
+}
+
+func TestRuleValidation(t *testing.T) {
+	if _, err := New(Options{Rules: []Rule{{Symbol: "BTC-USDT", EntryAPR: 0.1, ExitAPR: 0.1}}}); err == nil {
+		t.Error("exit threshold at the entry threshold accepted")
+	}
+	if _, err := New(Options{Rules: []Rule{{Symbol: "BTCUSDT", EntryAPR: 0.1}}}); err == nil {
+		t.Error("invalid symbol accepted")
+	}
+}
+
+func TestReconfigure(t *testing.T) {
+	m, err := New(Options{Rules: []Rule{{Symbol: "BTC-USDT", EntryAPR: 0.3, ExitAPR: 0.05, HoldingHours: 365 * 24}}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	m.OnTrade(spot(100000, 1))
+	// 22.3% a year is below the entry threshold
+	if signals := m.OnMark(mark(100500, 0.0002, 2)); len(signals) != 0 {
+		t.Fatalf("signals = %+v", signals)
+	}
+
+	if err := m.Reconfigure(Options{Rules: []Rule{{Symbol: "BTC-USDT", EntryAPR: 0.2, ExitAPR: 0.3}}}); err == nil {
+		t.Error("Reconfigure() accepted an exit above the entry")
+	}
+	if err := m.Reconfigure(Options{Rules: []Rule{
+		{Symbol: "BTC-USDT", EntryAPR: 0.15, ExitAPR: 0.05, HoldingHours: 365 * 24},
+		{Symbol: "ETH-USDT", EntryAPR: 0.15, ExitAPR: 0.05},
+	}}); err != nil {
+		t.Fatalf("Reconfigure() error = %v", err)
+	}
+	// The legs carry over, the next price is evaluated against the new threshold
+	signals := m.OnMark(mark(100500, 0.0002, 3))
+	if len(signals) != 1 || signals[0].Action != ActionEnter {
+		t.Fatalf("signals after Reconfigure() = %+v", signals)
+	}
+
+	if err := m.Reconfigure(Options{Rules: []Rule{{Symbol: "ETH-USDT", EntryAPR: 0.15, ExitAPR: 0.05}}}); err != nil {
+		t.Fatal(err)
+	}
+	if signals := m.OnMark(mark(100000, 0, 4)); len(signals) != 0 || len(m.Estimates()) != 0 {
+		t.Errorf("removed underlying still evaluated: %+v, %+v", signals, m.Estimates())
+	}
+}
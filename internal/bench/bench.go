@@ -0,0 +1,281 @@
+// Package bench measures the throughput and latency of a strategy node end
+// to end, in process: trades are injected into a node as the bus delivers
+// them, decoded, handed to a strategy decision and turned into encoded order
+// intents. Results are written as JSON lines so runs can be tracked over
+// time, see bench_test.go.
+package bench
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"math"
+	"runtime"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/BullionBear/sequex/env"
+	"github.com/BullionBear/sequex/internal/model/sqx"
+	"github.com/BullionBear/sequex/pkg/lob"
+	"github.com/BullionBear/sequex/pkg/node"
+	"github.com/BullionBear/sequex/pkg/synthdata"
+	"github.com/nats-io/nats.go"
+	"github.com/rs/zerolog"
+)
+
+// Decide is the decision of a strategy on a trade, the order intents it
+// places. It is called for the trades of a symbol one at a time when the
+// dispatch is keyed by symbol.
+type Decide func(trade sqx.Trade) []sqx.OrderIntent
+
+// Scenario configures a benchmark run
+type Scenario struct {
+	Name     string
+	Symbols  int                  // Distinct symbols the trades are spread over, 4 when 0
+	Dispatch node.DispatchOptions // Of the trade handler
+	Strategy func() Decide        // Creates the strategy of a run, Breakout(0.001) when nil
+	Seed     int64                // Of the trades generated, 1 when 0
+}
+
+// Result is the measurement of a run
+type Result struct {
+	Scenario   string        `json:"scenario"`
+	Events     int           `json:"events"`
+	Intents    int           `json:"intents"`
+	Errors     uint64        `json:"errors"`
+	Workers    int           `json:"workers"`
+	GOMAXPROCS int           `json:"gomaxprocs"`
+	Elapsed    time.Duration `json:"elapsed_ns"`
+	Throughput float64       `json:"events_per_sec"`
+	// Latencies from the injection of a trade to its decision done, intents
+	// encoded. Trades are injected as fast as the node takes them, so the
+	// latencies include the time queued behind the others.
+	P50  time.Duration `json:"p50_ns"`
+	P90  time.Duration `json:"p90_ns"`
+	P99  time.Duration `json:"p99_ns"`
+	P999 time.Duration `json:"p999_ns"`
+	Max  time.Duration `json:"max_ns"`
+
+	Build env.BuildInfo `json:"build"`
+	At    time.Time     `json:"at"`
+}
+
+// Run injects events trades into a node running the strategy of the
+// scenario, and measures it until every trade is handled
+func Run(s Scenario, events int) (Result, error) {
+	if events <= 0 {
+		return Result{}, fmt.Errorf("events must be positive")
+	}
+	if s.Symbols == 0 {
+		s.Symbols = 4
+	}
+	if s.Strategy == nil {
+		s.Strategy = func() Decide { return Breakout(0.001) }
+	}
+	if s.Seed == 0 {
+		s.Seed = 1
+	}
+	msgs, err := generate(events, s.Symbols, s.Seed)
+	if err != nil {
+		return Result{}, err
+	}
+
+	decide := s.Strategy()
+	sent := make([]time.Time, events)
+	latencies := make([]time.Duration, events)
+	intents := make([]int, events)
+	n := node.New("bench", nil, zerolog.Nop())
+	err = n.On("trade.>", func(msg *nats.Msg) error {
+		var trade sqx.Trade
+		if err := sqx.Unmarshal(msg.Data, &trade); err != nil {
+			return err
+		}
+		placed := decide(trade)
+		for i := range placed {
+			if _, err := placed[i].Marshal(); err != nil {
+				return err
+			}
+		}
+		// Trade ids are the positions of the trades, from 1
+		i := trade.Id - 1
+		intents[i] = len(placed)
+		latencies[i] = time.Since(sent[i])
+		return nil
+	}, s.Dispatch)
+	if err != nil {
+		return Result{}, err
+	}
+	if err := n.Start(); err != nil {
+		return Result{}, err
+	}
+
+	start := time.Now()
+	for i, msg := range msgs {
+		sent[i] = time.Now()
+		if err := n.Inject(msg); err != nil {
+			n.Stop()
+			return Result{}, err
+		}
+	}
+	// Stop returns once the queued trades are handled
+	n.Stop()
+	elapsed := time.Since(start)
+
+	r := Result{
+		Scenario:   s.Name,
+		Events:     events,
+		Workers:    s.Dispatch.Workers,
+		GOMAXPROCS: runtime.GOMAXPROCS(0),
+		Elapsed:    elapsed,
+		Throughput: float64(events) / elapsed.Seconds(),
+		Build:      env.Info(),
+		At:         time.Now().UTC(),
+	}
+	for _, stats := range n.Stats() {
+		r.Errors += stats.Errors + stats.Panics
+	}
+	for _, placed := range intents {
+		r.Intents += placed
+	}
+	slices.Sort(latencies)
+	r.P50 = percentile(latencies, 0.5)
+	r.P90 = percentile(latencies, 0.9)
+	r.P99 = percentile(latencies, 0.99)
+	r.P999 = percentile(latencies, 0.999)
+	r.Max = latencies[len(latencies)-1]
+	return r, nil
+}
+
+// percentile returns the nearest rank percentile q of sorted durations
+func percentile(sorted []time.Duration, q float64) time.Duration {
+	rank := int(math.Ceil(q*float64(len(sorted)))) - 1
+	return sorted[max(rank, 0)]
+}
+
+// WriteJSON writes results as JSON lines
+func WriteJSON(w io.Writer, results ...Result) error {
+	encoder := json.NewEncoder(w)
+	for _, r := range results {
+		if err := encoder.Encode(r); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// generate returns events encoded spot trades of synthetic markets, one per
+// symbol, in time order as the feed publishes them
+func generate(events, symbols int, seed int64) ([]*nats.Msg, error) {
+	// Far more volatile than a real market so that the strategy trades within
+	// the seconds of market time a run covers
+	start := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	markets := make([]*synthdata.Generator, symbols)
+	next := make([]lob.Event, symbols)
+	for k := range markets {
+		g, err := synthdata.New(synthdata.Config{
+			Start:   start,
+			Price:   100 * float64(k+1),
+			Regimes: []synthdata.Regime{{Volatility: 20, TradeRate: 50}},
+			Depth:   synthdata.Depth{Interval: time.Hour}, // Only trades are used
+			Seed:    seed + int64(k),
+		})
+		if err != nil {
+			return nil, err
+		}
+		markets[k] = g
+		next[k] = nextTrade(g)
+	}
+	msgs := make([]*nats.Msg, events)
+	for i := range msgs {
+		k := 0
+		for j := range next {
+			if next[j].Time < next[k].Time {
+				k = j
+			}
+		}
+		e := next[k]
+		next[k] = nextTrade(markets[k])
+		side := sqx.SideBuy
+		if e.Side == lob.Sell {
+			side = sqx.SideSell
+		}
+		trade := sqx.Trade{
+			Id:             int64(i + 1),
+			Symbol:         sqx.NewSymbol(fmt.Sprintf("S%d", k), "USDT"),
+			Exchange:       sqx.ExchangeBinance,
+			InstrumentType: sqx.InstrumentTypeSpot,
+			TakerSide:      side,
+			Price:          e.Price,
+			Quantity:       e.Quantity,
+			Timestamp:      e.Time,
+		}
+		data, err := trade.Marshal()
+		if err != nil {
+			return nil, err
+		}
+		subject := "trade.binance.spot." + strings.ToLower(trade.Symbol.Base+trade.Symbol.Quote)
+		msgs[i] = &nats.Msg{Subject: subject, Data: data}
+	}
+	return msgs, nil
+}
+
+func nextTrade(g *synthdata.Generator) lob.Event {
+	for {
+		if e := g.Next(); e.Type == lob.EventTrade {
+			return e
+		}
+	}
+}
+
+// Breakout is a stand-in strategy with the per-trade work of a real one: it
+// tracks an exponential moving average of the price of every symbol, buys
+// when the price breaks above it by band and sells the position when the
+// price breaks below it by band. Trades of different symbols may be decided
+// concurrently.
+func Breakout(band float64) Decide {
+	type state struct {
+		ema  float64
+		long bool
+		seq  int
+	}
+	const alpha = 0.05
+	var states sync.Map // Symbol -> *state
+	return func(trade sqx.Trade) []sqx.OrderIntent {
+		symbol := trade.Symbol.String()
+		v, ok := states.Load(symbol)
+		if !ok {
+			v, _ = states.LoadOrStore(symbol, &state{})
+		}
+		st := v.(*state)
+		if st.ema == 0 {
+			st.ema = trade.Price
+			return nil
+		}
+		st.ema += alpha * (trade.Price - st.ema)
+		var side sqx.Side
+		switch {
+		case !st.long && trade.Price > st.ema*(1+band):
+			side, st.long = sqx.SideBuy, true
+		case st.long && trade.Price < st.ema*(1-band):
+			side, st.long = sqx.SideSell, false
+		default:
+			return nil
+		}
+		st.seq++
+		return []sqx.OrderIntent{{
+			ClientOrderId:  fmt.Sprintf("bench-%s-%d", symbol, st.seq),
+			Strategy:       "breakout",
+			Exchange:       trade.Exchange,
+			InstrumentType: trade.InstrumentType,
+			Symbol:         trade.Symbol,
+			Side:           side,
+			Type:           sqx.OrderTypeLimit,
+			TimeInForce:    sqx.TimeInForceGTC,
+			Price:          trade.Price,
+			Quantity:       0.01,
+			Timestamp:      trade.Timestamp,
+		}}
+	}
+}
@@ -0,0 +1,127 @@
+package bench
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"testing"
+
+	"github.com/BullionBear/sequex/internal/model/sqx"
+	"github.com/BullionBear/sequex/pkg/node"
+)
+
+// Run the benchmarks with -results to append their measurements as JSON
+// lines, e.g.
+//
+//	go test ./internal/bench -run '^$' -bench . -benchtime 200000x -results bench.jsonl
+var results = flag.String("results", "", "file the benchmark results are appended to as JSON lines")
+
+// final holds the last run of every benchmark, the one go test reports,
+// written by TestMain once the benchmarks are done
+var (
+	final = make(map[string]Result)
+	order []string
+)
+
+func TestMain(m *testing.M) {
+	code := m.Run()
+	if *results != "" && len(order) > 0 {
+		if err := appendResults(*results); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			code = 1
+		}
+	}
+	os.Exit(code)
+}
+
+func appendResults(path string) error {
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0o644)
+	if err != nil {
+		return err
+	}
+	for _, name := range order {
+		if err := WriteJSON(f, final[name]); err != nil {
+			f.Close()
+			return err
+		}
+	}
+	return f.Close()
+}
+
+var scenarios = []Scenario{
+	{Name: "inline"},
+	{Name: "keyed-4", Symbols: 16, Dispatch: node.DispatchOptions{Workers: 4, Key: node.SubjectToken(3)}},
+	{Name: "keyed-8", Symbols: 64, Dispatch: node.DispatchOptions{Workers: 8, Key: node.SubjectToken(3)}},
+}
+
+func TestRun(t *testing.T) {
+	for _, s := range scenarios[:2] {
+		r, err := Run(s, 5000)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if r.Events != 5000 || r.Errors != 0 || r.Intents == 0 {
+			t.Errorf("%s: %+v", s.Name, r)
+		}
+		if r.P50 <= 0 || r.P50 > r.P99 || r.P99 > r.Max || r.Throughput <= 0 {
+			t.Errorf("%s: inconsistent latencies %+v", s.Name, r)
+		}
+	}
+	if _, err := Run(Scenario{}, 0); err == nil {
+		t.Error("Run without events should fail")
+	}
+}
+
+func TestBreakout(t *testing.T) {
+	decide := Breakout(0.01)
+	msgs, err := generate(2000, 2, 7)
+	if err != nil {
+		t.Fatal(err)
+	}
+	sides := make(map[string][]string)
+	for _, msg := range msgs {
+		var trade sqx.Trade
+		if err := sqx.Unmarshal(msg.Data, &trade); err != nil {
+			t.Fatal(err)
+		}
+		for _, intent := range decide(trade) {
+			sides[intent.Symbol.String()] = append(sides[intent.Symbol.String()], intent.Side.String())
+		}
+	}
+	// Positions are opened and closed in turn per symbol
+	for symbol, placed := range sides {
+		for i, side := range placed {
+			if want := []string{"BUY", "SELL"}[i%2]; side != want {
+				t.Fatalf("%s: intent %d is a %s, want %s: %v", symbol, i, side, want, placed)
+			}
+		}
+	}
+}
+
+func BenchmarkPipeline(b *testing.B) {
+	for _, s := range scenarios {
+		b.Run(s.Name, func(b *testing.B) {
+			b.ReportAllocs()
+			b.ResetTimer()
+			r, err := Run(s, b.N)
+			if err != nil {
+				b.Fatal(err)
+			}
+			b.StopTimer()
+			// Generating the trades is not part of the pipeline
+			b.ReportMetric(float64(r.Elapsed.Nanoseconds())/float64(b.N), "ns/op")
+			b.ReportMetric(r.Throughput, "events/s")
+			b.ReportMetric(float64(r.P50.Nanoseconds()), "p50-ns")
+			b.ReportMetric(float64(r.P99.Nanoseconds()), "p99-ns")
+			b.ReportMetric(float64(r.Max.Nanoseconds()), "max-ns")
+			record(r)
+		})
+	}
+}
+
+func record(r Result) {
+	if _, ok := final[r.Scenario]; !ok {
+		order = append(order, r.Scenario)
+	}
+	final[r.Scenario] = r
+}
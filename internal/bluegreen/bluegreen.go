@@ -0,0 +1,564 @@
+// Package bluegreen deploys new strategy parameters without risking them.
+// A strategy runs as two instances, blue and green, each a strategy node
+// receiving the same inputs: the live one trades under the name of the
+// strategy while the shadow one, running the new parameters, trades on
+// paper. Their decisions
+// and PnL are compared over a rolling window, and the shadow is promoted
+// by hand once it proves itself, the live instance becoming the shadow so
+// a promotion is rolled back by promoting again. Promotions are journaled
+// so they survive restarts.
+package bluegreen
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/BullionBear/sequex/internal/model/sqx"
+	"github.com/BullionBear/sequex/internal/pms"
+)
+
+// DefaultCurrency is the currency of PnLs when none is configured
+const DefaultCurrency = "USDT"
+
+// DefaultWindow is the rolling window of the comparisons when none is configured
+const DefaultWindow = time.Hour
+
+// DefaultMatch is how far apart orders of the instances may be to be the
+// same decision when none is configured
+const DefaultMatch = time.Second
+
+// auditSize is the number of promotions Deployments keep in memory, the
+// journal keeping all of them
+const auditSize = 100
+
+var (
+	ErrUnknown  = errors.New("unknown deployment")
+	ErrNoReason = errors.New("a reason is required")
+)
+
+// Colors of the instances
+const (
+	Blue  = "blue"
+	Green = "green"
+)
+
+// Deployment is a strategy run as two instances
+type Deployment struct {
+	Strategy string `json:"strategy"` // Orders of the live instance are routed under, e.g. grid
+	Blue     string `json:"blue"`     // Strategy of the blue instance as it tags its orders, e.g. grid-blue
+	Green    string `json:"green"`    // Strategy of the green instance
+	Live     string `json:"live"`     // blue or green, blue when empty
+}
+
+// Validate validates the deployment
+func (d Deployment) Validate() error {
+	if d.Strategy == "" || d.Blue == "" || d.Green == "" {
+		return fmt.Errorf("strategy, blue and green cannot be empty")
+	}
+	if d.Blue == d.Green || d.Blue == d.Strategy || d.Green == d.Strategy {
+		return fmt.Errorf("%s: strategy, blue and green must differ", d.Strategy)
+	}
+	if d.Live != "" && d.Live != Blue && d.Live != Green {
+		return fmt.Errorf("%s: live must be blue or green", d.Strategy)
+	}
+	return nil
+}
+
+// instances returns the live and shadow instances
+func (d Deployment) instances() (live, shadow string) {
+	if d.Live == Green {
+		return d.Green, d.Blue
+	}
+	return d.Blue, d.Green
+}
+
+// Promotion is an entry of the audit trail: a shadow instance made live
+type Promotion struct {
+	Strategy   string      `json:"strategy"`
+	Live       string      `json:"live"` // Color made live
+	By         string      `json:"by"`
+	Reason     string      `json:"reason"`
+	At         time.Time   `json:"at"`
+	Comparison *Comparison `json:"comparison,omitempty"` // Of the instances when promoted
+}
+
+// Stats are the decisions and PnL of an instance within the window
+type Stats struct {
+	Strategy string  `json:"strategy"`
+	Orders   int     `json:"orders"`
+	Buys     float64 `json:"buys"`     // Quantity ordered
+	Sells    float64 `json:"sells"`    // Quantity ordered
+	Notional float64 `json:"notional"` // Of the fills, in the currency
+	Fills    int     `json:"fills"`
+	PnL      float64 `json:"pnl"` // Of the fills, their positions marked to market, in the currency
+	Error    string  `json:"error,omitempty"`
+}
+
+// Comparison is how the instances of a deployment traded within the window
+type Comparison struct {
+	Strategy string    `json:"strategy"`
+	Currency string    `json:"currency"`
+	Since    time.Time `json:"since"`
+	Live     Stats     `json:"live"`
+	Shadow   Stats     `json:"shadow"`
+	Matched  float64   `json:"matched"`  // Share of the orders of either instance with one of the other on the same symbol and side within the match
+	PnLDiff  float64   `json:"pnl_diff"` // Shadow less live
+}
+
+// Options configures Deployments
+type Options struct {
+	Currency string        // Of PnLs, DefaultCurrency when empty
+	Window   time.Duration // Of the comparisons, DefaultWindow when 0
+	Match    time.Duration // How far apart orders of the instances may be to be the same decision, DefaultMatch when 0
+	Prices   pms.Converter // Marks positions and converts cash, e.g. fx.Rates; fill prices are used when nil or without rate
+	Journal  string        // JSON lines file of the promotions, replayed on open so promoted instances stay live. In memory only when empty.
+}
+
+// decision is an order of an instance
+type decision struct {
+	at       time.Time
+	symbol   sqx.Symbol
+	side     sqx.Side
+	quantity float64
+}
+
+// fill is an execution of an instance
+type fill struct {
+	at         time.Time
+	symbol     sqx.Symbol
+	side       sqx.Side
+	quantity   float64
+	price      float64
+	commission float64
+	asset      string // Of the commission
+}
+
+// position is the net quantity of an instance in a symbol
+type position struct {
+	quantity float64 // Signed, negative when short
+	price    float64 // Of the last fill
+}
+
+// instance is what is followed of an instance
+type instance struct {
+	decisions []decision // Within the window, oldest first
+	fills     []fill     // Within the window, oldest first
+}
+
+// Deployments route the orders of the instances of strategies and compare
+// them. They are safe for concurrent use.
+type Deployments struct {
+	opts Options
+
+	mu          sync.Mutex
+	deployments map[string]*Deployment // By strategy
+	instances   map[string]*Deployment // By strategy of the instances
+	followed    map[string]*instance   // By strategy of the instances
+	promotions  []Promotion
+	journal     *os.File
+	onPromote   []func(Promotion)
+}
+
+// Open creates deployments, replaying the promotions of the journal so the
+// instances promoted before a restart stay live
+func Open(deployments []Deployment, opts Options) (*Deployments, error) {
+	if opts.Currency == "" {
+		opts.Currency = DefaultCurrency
+	}
+	if opts.Window <= 0 {
+		opts.Window = DefaultWindow
+	}
+	if opts.Match <= 0 {
+		opts.Match = DefaultMatch
+	}
+	d := &Deployments{
+		opts:        opts,
+		deployments: make(map[string]*Deployment, len(deployments)),
+		instances:   make(map[string]*Deployment, 2*len(deployments)),
+		followed:    make(map[string]*instance, 2*len(deployments)),
+	}
+	for _, deployment := range deployments {
+		if err := deployment.Validate(); err != nil {
+			return nil, err
+		}
+		if _, ok := d.deployments[deployment.Strategy]; ok {
+			return nil, fmt.Errorf("duplicate deployment %s", deployment.Strategy)
+		}
+		dep := deployment
+		if dep.Live == "" {
+			dep.Live = Blue
+		}
+		d.deployments[dep.Strategy] = &dep
+		for _, name := range []string{dep.Blue, dep.Green} {
+			if _, ok := d.instances[name]; ok {
+				return nil, fmt.Errorf("%s: instance %s of another deployment", dep.Strategy, name)
+			}
+			d.instances[name] = &dep
+			d.followed[name] = &instance{}
+		}
+	}
+	for name := range d.instances {
+		if _, ok := d.deployments[name]; ok {
+			return nil, fmt.Errorf("instance %s is a deployment", name)
+		}
+	}
+	if opts.Journal == "" {
+		return d, nil
+	}
+	f, err := os.OpenFile(opts.Journal, os.O_CREATE|os.O_RDWR|os.O_APPEND, 0o644)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open journal %s: %w", opts.Journal, err)
+	}
+	size, err := d.replay(f)
+	if err == nil {
+		// Drop a truncated last line so the next promotion starts on its own line
+		err = f.Truncate(size)
+	}
+	if err != nil {
+		f.Close()
+		return nil, fmt.Errorf("failed to replay journal %s: %w", opts.Journal, err)
+	}
+	d.journal = f
+	return d, nil
+}
+
+// replay applies the journaled promotions and returns the size of the lines
+// read. A truncated last line, left by a crash during a write, is skipped.
+func (d *Deployments) replay(r io.Reader) (int64, error) {
+	reader := bufio.NewReader(r)
+	var size int64
+	for line := 1; ; line++ {
+		data, err := reader.ReadBytes('\n')
+		if err == io.EOF {
+			return size, nil
+		}
+		if err != nil {
+			return size, err
+		}
+		var p Promotion
+		if err := json.Unmarshal(data, &p); err != nil {
+			return size, fmt.Errorf("line %d: %w", line, err)
+		}
+		d.apply(p)
+		size += int64(len(data))
+	}
+}
+
+// apply applies a promotion to its deployment, if still configured, and
+// the audit trail
+func (d *Deployments) apply(p Promotion) {
+	if dep, ok := d.deployments[p.Strategy]; ok {
+		dep.Live = p.Live
+	}
+	d.promotions = append(d.promotions, p)
+	if len(d.promotions) > auditSize {
+		d.promotions = d.promotions[len(d.promotions)-auditSize:]
+	}
+}
+
+// Close closes the journal
+func (d *Deployments) Close() error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if d.journal == nil {
+		return nil
+	}
+	err := d.journal.Close()
+	d.journal = nil
+	return err
+}
+
+// Currency returns the currency of PnLs
+func (d *Deployments) Currency() string {
+	return d.opts.Currency
+}
+
+// OnPromote calls fn with every promotion, e.g. to log it
+func (d *Deployments) OnPromote(fn func(Promotion)) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.onPromote = append(d.onPromote, fn)
+}
+
+// Route returns the strategy the orders of an instance are tagged with and
+// whether the instance is live. Orders of strategies not deployed are
+// routed as they are.
+func (d *Deployments) Route(strategy string) (string, bool) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	dep, ok := d.instances[strategy]
+	if !ok {
+		return strategy, true
+	}
+	if live, _ := dep.instances(); live == strategy {
+		return dep.Strategy, true
+	}
+	return strategy, false
+}
+
+// Strategy returns the strategy an instance is deployed as, the instance
+// itself when not deployed
+func (d *Deployments) Strategy(instance string) string {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if dep, ok := d.instances[instance]; ok {
+		return dep.Strategy
+	}
+	return instance
+}
+
+// OnOrder follows an order an instance placed, live or on paper
+func (d *Deployments) OnOrder(strategy string, intent sqx.OrderIntent) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	in, ok := d.followed[strategy]
+	if !ok {
+		return
+	}
+	now := time.Now()
+	in.decisions = append(in.decisions, decision{at: now, symbol: intent.Symbol, side: intent.Side, quantity: intent.Quantity})
+	d.prune(in, now)
+}
+
+// OnExecutionReport follows a fill of an instance, live or on paper
+func (d *Deployments) OnExecutionReport(strategy string, report sqx.ExecutionReport) {
+	if !report.IsFill() {
+		return
+	}
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	in, ok := d.followed[strategy]
+	if !ok {
+		return
+	}
+	now := time.Now()
+	in.fills = append(in.fills, fill{
+		at:         now,
+		symbol:     report.Symbol,
+		side:       report.Side,
+		quantity:   report.LastQuantity,
+		price:      report.LastPrice,
+		commission: report.Commission,
+		asset:      report.CommissionAsset,
+	})
+	d.prune(in, now)
+}
+
+// prune forgets what an instance did before the window
+func (d *Deployments) prune(in *instance, now time.Time) {
+	since := now.Add(-d.opts.Window)
+	i := sort.Search(len(in.decisions), func(i int) bool { return !in.decisions[i].at.Before(since) })
+	in.decisions = in.decisions[i:]
+	j := sort.Search(len(in.fills), func(j int) bool { return !in.fills[j].at.Before(since) })
+	in.fills = in.fills[j:]
+}
+
+// Compare compares the instances of a deployment within the window
+func (d *Deployments) Compare(strategy string) (Comparison, error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	dep, ok := d.deployments[strategy]
+	if !ok {
+		return Comparison{}, fmt.Errorf("%w: %s", ErrUnknown, strategy)
+	}
+	return d.compare(dep, time.Now()), nil
+}
+
+// Comparisons compares the instances of every deployment, ordered by strategy
+func (d *Deployments) Comparisons() []Comparison {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	now := time.Now()
+	comparisons := make([]Comparison, 0, len(d.deployments))
+	for _, dep := range d.deployments {
+		comparisons = append(comparisons, d.compare(dep, now))
+	}
+	sort.Slice(comparisons, func(i, j int) bool { return comparisons[i].Strategy < comparisons[j].Strategy })
+	return comparisons
+}
+
+func (d *Deployments) compare(dep *Deployment, now time.Time) Comparison {
+	live, shadow := dep.instances()
+	liveIn, shadowIn := d.followed[live], d.followed[shadow]
+	d.prune(liveIn, now)
+	d.prune(shadowIn, now)
+	c := Comparison{
+		Strategy: dep.Strategy,
+		Currency: d.opts.Currency,
+		Since:    now.Add(-d.opts.Window).UTC(),
+		Live:     d.stats(live, liveIn),
+		Shadow:   d.stats(shadow, shadowIn),
+	}
+	c.PnLDiff = c.Shadow.PnL - c.Live.PnL
+	if total := len(liveIn.decisions) + len(shadowIn.decisions); total > 0 {
+		matched := d.matched(liveIn.decisions, shadowIn.decisions) + d.matched(shadowIn.decisions, liveIn.decisions)
+		c.Matched = float64(matched) / float64(total)
+	}
+	return c
+}
+
+// matched returns the decisions of a with one of b on the same symbol and
+// side within the match
+func (d *Deployments) matched(a, b []decision) int {
+	var n int
+	for _, x := range a {
+		for _, y := range b {
+			if x.symbol == y.symbol && x.side == y.side && math.Abs(float64(x.at.Sub(y.at))) <= float64(d.opts.Match) {
+				n++
+				break
+			}
+		}
+	}
+	return n
+}
+
+// stats values the decisions and fills of an instance
+func (d *Deployments) stats(strategy string, in *instance) Stats {
+	s := Stats{Strategy: strategy, Orders: len(in.decisions), Fills: len(in.fills)}
+	for _, o := range in.decisions {
+		if o.side == sqx.SideBuy {
+			s.Buys += o.quantity
+		} else {
+			s.Sells += o.quantity
+		}
+	}
+	cash := make(map[string]float64)
+	positions := make(map[sqx.Symbol]*position)
+	for _, f := range in.fills {
+		sign := 1.0
+		if f.side == sqx.SideSell {
+			sign = -1
+		}
+		cash[f.symbol.Quote] -= sign * f.quantity * f.price
+		if f.commission != 0 {
+			cash[f.asset] -= f.commission
+		}
+		p, ok := positions[f.symbol]
+		if !ok {
+			p = &position{}
+			positions[f.symbol] = p
+		}
+		p.quantity += sign * f.quantity
+		p.price = f.price
+		if rate, err := d.rate(f.symbol.Quote); err == nil {
+			s.Notional += f.quantity * f.price * rate
+		}
+	}
+	for asset, amount := range cash {
+		rate, err := d.rate(asset)
+		if err != nil {
+			s.Error = err.Error()
+			continue
+		}
+		s.PnL += amount * rate
+	}
+	for symbol, p := range positions {
+		if p.quantity == 0 {
+			continue
+		}
+		mark, err := d.mark(symbol, p.price)
+		if err != nil {
+			s.Error = err.Error()
+			continue
+		}
+		s.PnL += p.quantity * mark
+	}
+	return s
+}
+
+func (d *Deployments) rate(asset string) (float64, error) {
+	if asset == d.opts.Currency {
+		return 1, nil
+	}
+	if d.opts.Prices == nil {
+		return 0, fmt.Errorf("no rate from %s to %s", asset, d.opts.Currency)
+	}
+	return d.opts.Prices.Rate(asset, d.opts.Currency)
+}
+
+// mark returns the price of one unit of the base of symbol in the currency,
+// from the rates when available, else from the last fill price
+func (d *Deployments) mark(symbol sqx.Symbol, price float64) (float64, error) {
+	if rate, err := d.rate(symbol.Base); err == nil {
+		return rate, nil
+	}
+	rate, err := d.rate(symbol.Quote)
+	if err != nil {
+		return 0, fmt.Errorf("no price of %s in %s", symbol, d.opts.Currency)
+	}
+	return price * rate, nil
+}
+
+// Promote makes the shadow instance of a deployment live, the live one
+// becoming the shadow. Working orders stay where they were placed: those of
+// the former live instance on the exchange until it cancels them.
+func (d *Deployments) Promote(strategy, by, reason string) (Promotion, error) {
+	if by == "" || reason == "" {
+		return Promotion{}, ErrNoReason
+	}
+	d.mu.Lock()
+	dep, ok := d.deployments[strategy]
+	if !ok {
+		d.mu.Unlock()
+		return Promotion{}, fmt.Errorf("%w: %s", ErrUnknown, strategy)
+	}
+	now := time.Now()
+	comparison := d.compare(dep, now)
+	p := Promotion{Strategy: strategy, Live: Green, By: by, Reason: reason, At: now.UTC(), Comparison: &comparison}
+	if dep.Live == Green {
+		p.Live = Blue
+	}
+	if err := d.write(p); err != nil {
+		d.mu.Unlock()
+		return Promotion{}, err
+	}
+	d.apply(p)
+	listeners := d.onPromote
+	d.mu.Unlock()
+	for _, fn := range listeners {
+		fn(p)
+	}
+	return p, nil
+}
+
+// write journals a promotion
+func (d *Deployments) write(p Promotion) error {
+	if d.journal == nil {
+		return nil
+	}
+	data, err := json.Marshal(p)
+	if err == nil {
+		_, err = d.journal.Write(append(data, '\n'))
+	}
+	if err != nil {
+		return fmt.Errorf("failed to journal promotion: %w", err)
+	}
+	return nil
+}
+
+// Deployments returns the deployments, ordered by strategy
+func (d *Deployments) Deployments() []Deployment {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	deployments := make([]Deployment, 0, len(d.deployments))
+	for _, dep := range d.deployments {
+		deployments = append(deployments, *dep)
+	}
+	sort.Slice(deployments, func(i, j int) bool { return deployments[i].Strategy < deployments[j].Strategy })
+	return deployments
+}
+
+// History returns the latest promotions, oldest first
+func (d *Deployments) History() []Promotion {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return append([]Promotion(nil), d.promotions...)
+}
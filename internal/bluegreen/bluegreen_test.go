@@ -0,0 +1,136 @@
+package bluegreen
+
+import (
+	"errors"
+	"fmt"
+	"path/filepath"
+	"testing"
+
+	"github.com/BullionBear/sequex/internal/model/sqx"
+)
+
+// fakeRates prices assets in USDT
+type fakeRates map[string]float64
+
+func (r fakeRates) Rate(from, to string) (float64, error) {
+	if from == to {
+		return 1, nil
+	}
+	if rate, ok := r[from+"-"+to]; ok {
+		return rate, nil
+	}
+	return 0, fmt.Errorf("no rate from %s to %s", from, to)
+}
+
+var btc = sqx.NewSymbol("BTC", "USDT")
+
+func deployment() Deployment {
+	return Deployment{Strategy: "grid", Blue: "grid-blue", Green: "grid-green"}
+}
+
+func filled(side sqx.Side, quantity, price float64) sqx.ExecutionReport {
+	return sqx.ExecutionReport{Symbol: btc, Side: side, Status: sqx.OrderStatusFilled, Quantity: quantity, LastQuantity: quantity, LastPrice: price}
+}
+
+func TestRouteAndCompare(t *testing.T) {
+	rates := fakeRates{"BTC-USDT": 50000}
+	d, err := Open([]Deployment{deployment()}, Options{Prices: rates})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strategy, live := d.Route("grid-blue"); strategy != "grid" || !live {
+		t.Errorf("blue routed as %s, live %v", strategy, live)
+	}
+	if strategy, live := d.Route("grid-green"); strategy != "grid-green" || live {
+		t.Errorf("green routed as %s, live %v", strategy, live)
+	}
+	if strategy, live := d.Route("other"); strategy != "other" || !live {
+		t.Errorf("strategy not deployed routed as %s, live %v", strategy, live)
+	}
+	if d.Strategy("grid-green") != "grid" || d.Strategy("other") != "other" {
+		t.Error("strategies of the instances")
+	}
+
+	// Both buy, only the shadow sells the rally
+	buy := sqx.OrderIntent{Symbol: btc, Side: sqx.SideBuy, Quantity: 1}
+	d.OnOrder("grid-blue", buy)
+	d.OnOrder("grid-green", buy)
+	d.OnOrder("grid-green", sqx.OrderIntent{Symbol: btc, Side: sqx.SideSell, Quantity: 1})
+	d.OnExecutionReport("grid-blue", filled(sqx.SideBuy, 1, 50000))
+	d.OnExecutionReport("grid-green", filled(sqx.SideBuy, 1, 50000))
+	d.OnExecutionReport("grid-green", filled(sqx.SideSell, 1, 51000))
+	d.OnExecutionReport("grid-green", sqx.ExecutionReport{Symbol: btc, Status: sqx.OrderStatusNew, Quantity: 1})
+	rates["BTC-USDT"] = 49000
+
+	c, err := d.Compare("grid")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if c.Live.Strategy != "grid-blue" || c.Live.Orders != 1 || c.Live.Fills != 1 || c.Live.PnL != -1000 {
+		t.Errorf("live %+v", c.Live)
+	}
+	if c.Shadow.Orders != 2 || c.Shadow.Buys != 1 || c.Shadow.Sells != 1 || c.Shadow.Fills != 2 || c.Shadow.PnL != 1000 || c.Shadow.Notional != 101000 {
+		t.Errorf("shadow %+v", c.Shadow)
+	}
+	if c.PnLDiff != 2000 || c.Matched != 2.0/3 {
+		t.Errorf("comparison %+v", c)
+	}
+	if _, err := d.Compare("other"); !errors.Is(err, ErrUnknown) {
+		t.Errorf("comparison of an unknown deployment: %v", err)
+	}
+}
+
+func TestPromoteSurvivesRestart(t *testing.T) {
+	journal := filepath.Join(t.TempDir(), "promotions.jsonl")
+	d, err := Open([]Deployment{deployment()}, Options{Journal: journal})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var promoted []Promotion
+	d.OnPromote(func(p Promotion) { promoted = append(promoted, p) })
+	if _, err := d.Promote("grid", "ops", ""); !errors.Is(err, ErrNoReason) {
+		t.Errorf("promoted without a reason: %v", err)
+	}
+	p, err := d.Promote("grid", "ops", "tighter bands")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if p.Live != Green || p.Comparison == nil || len(promoted) != 1 {
+		t.Errorf("promotion %+v", p)
+	}
+	if strategy, live := d.Route("grid-green"); strategy != "grid" || !live {
+		t.Errorf("promoted green routed as %s, live %v", strategy, live)
+	}
+	if _, live := d.Route("grid-blue"); live {
+		t.Error("blue still live")
+	}
+	d.Close()
+
+	d, err = Open([]Deployment{deployment()}, Options{Journal: journal})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer d.Close()
+	if _, live := d.Route("grid-green"); !live || len(d.History()) != 1 {
+		t.Error("promotion lost on restart")
+	}
+	// Promoting again rolls back
+	if p, err := d.Promote("grid", "ops", "rollback"); err != nil || p.Live != Blue {
+		t.Errorf("rollback %+v: %v", p, err)
+	}
+}
+
+func TestOpenRejectsInvalidDeployments(t *testing.T) {
+	for name, deployments := range map[string][]Deployment{
+		"empty":     {{Strategy: "grid", Blue: "grid-blue"}},
+		"same":      {{Strategy: "grid", Blue: "grid-blue", Green: "grid-blue"}},
+		"live":      {{Strategy: "grid", Blue: "grid-blue", Green: "grid-green", Live: "red"}},
+		"duplicate": {deployment(), deployment()},
+		"shared":    {deployment(), {Strategy: "mm", Blue: "grid-blue", Green: "mm-green"}},
+		"nested":    {deployment(), {Strategy: "grid-blue", Blue: "a", Green: "b"}},
+	} {
+		if _, err := Open(deployments, Options{}); err == nil {
+			t.Errorf("%s: invalid deployments opened", name)
+		}
+	}
+}
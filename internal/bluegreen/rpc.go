@@ -0,0 +1,69 @@
+package bluegreen
+
+import (
+	"context"
+
+	"github.com/BullionBear/sequex/pkg/eventbus"
+	"github.com/nats-io/nats.go"
+)
+
+// DefaultSubject is the prefix the params RPCs of a service, promoting its
+// shadow instances, are served under when none is configured
+const DefaultSubject = "params"
+
+// PromoteRequest makes the shadow instance of a deployment live
+type PromoteRequest struct {
+	Strategy string `json:"strategy"`
+	By       string `json:"by"`     // Who promotes the instance, required for the audit trail
+	Reason   string `json:"reason"` // Required
+}
+
+// ListRequest asks for the deployments, their comparisons and the audit trail
+type ListRequest struct{}
+
+// List is the state of deployments
+type List struct {
+	Deployments []Deployment `json:"deployments"`
+	Comparisons []Comparison `json:"comparisons"`
+	Promotions  []Promotion  `json:"promotions"` // Latest promotions, oldest first
+}
+
+// PromoteEndpoint is the endpoint shadow instances are promoted on,
+// prefix.deployments.promote
+func PromoteEndpoint(prefix string) eventbus.Endpoint[PromoteRequest, Promotion] {
+	return eventbus.NewCommand[PromoteRequest, Promotion](prefix+".deployments.promote", eventbus.JSON)
+}
+
+// ListEndpoint is the endpoint deployments are listed on,
+// prefix.deployments.list
+func ListEndpoint(prefix string) eventbus.Endpoint[ListRequest, List] {
+	return eventbus.NewEndpoint[ListRequest, List](prefix+".deployments.list", eventbus.JSON)
+}
+
+// Serve answers the deployment RPCs of d under prefix
+func Serve(bus *eventbus.Bus, prefix string, d *Deployments) ([]*nats.Subscription, error) {
+	var subs []*nats.Subscription
+	for _, serve := range []func() (*nats.Subscription, error){
+		func() (*nats.Subscription, error) {
+			return PromoteEndpoint(prefix).Serve(bus, func(ctx context.Context, req *PromoteRequest) (*Promotion, error) {
+				p, err := d.Promote(req.Strategy, eventbus.Caller(ctx, req.By), req.Reason)
+				return &p, err
+			})
+		},
+		func() (*nats.Subscription, error) {
+			return ListEndpoint(prefix).Serve(bus, func(_ context.Context, _ *ListRequest) (*List, error) {
+				return &List{Deployments: d.Deployments(), Comparisons: d.Comparisons(), Promotions: d.History()}, nil
+			})
+		},
+	} {
+		sub, err := serve()
+		if err != nil {
+			for _, sub := range subs {
+				sub.Unsubscribe()
+			}
+			return nil, err
+		}
+		subs = append(subs, sub)
+	}
+	return subs, nil
+}
@@ -0,0 +1,218 @@
+package bridge
+
+import (
+	"encoding/json"
+	"fmt"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/BullionBear/sequex/internal/model/sqx"
+	"github.com/BullionBear/sequex/pkg/kafka"
+)
+
+type fakeKafka struct {
+	partitions int
+	produced   map[int][]kafka.Record
+	failAfter  int
+	calls      int
+}
+
+func (f *fakeKafka) Partitions(topic string) (int, error) {
+	return f.partitions, nil
+}
+
+func (f *fakeKafka) Produce(topic string, partition int, records []kafka.Record) (int64, error) {
+	f.calls++
+	if f.failAfter > 0 && f.calls > f.failAfter {
+		return 0, kafka.Error(7)
+	}
+	base := int64(len(f.produced[partition]))
+	for i := range records {
+		records[i].Offset = base + int64(i)
+	}
+	f.produced[partition] = append(f.produced[partition], records...)
+	return base, nil
+}
+
+func (f *fakeKafka) Fetch(topic string, partition int, offset int64, maxWait time.Duration, maxBytes int) ([]kafka.Record, int64, error) {
+	records := f.produced[partition]
+	if offset >= int64(len(records)) {
+		return nil, int64(len(records)), nil
+	}
+	return records[offset:], int64(len(records)), nil
+}
+
+func (f *fakeKafka) ListOffset(topic string, partition int, timestamp int64) (int64, error) {
+	if timestamp == kafka.OffsetEarliest {
+		return 0, nil
+	}
+	return int64(len(f.produced[partition])), nil
+}
+
+func tradePayload(t *testing.T, id int64, base string) []byte {
+	trade := sqx.Trade{
+		Id:             id,
+		Symbol:         sqx.NewSymbol(base, "USDT"),
+		Exchange:       sqx.ExchangeBinance,
+		InstrumentType: sqx.InstrumentTypeSpot,
+		TakerSide:      sqx.SideBuy,
+		Price:          100,
+		Quantity:       1,
+		Timestamp:      id,
+	}
+	data, err := trade.Marshal()
+	if err != nil {
+		t.Fatalf("failed to marshal trade: %v", err)
+	}
+	return data
+}
+
+func TestKafkaSink_PartitionsBySymbol(t *testing.T) {
+	producer := &fakeKafka{partitions: 4, produced: make(map[int][]kafka.Record)}
+	sink := NewKafkaSink(producer, "sequex.trade", EncodingJSON)
+
+	var msgs []Message
+	for i := int64(1); i <= 3; i++ {
+		msgs = append(msgs,
+			Message{Subject: "trade.binance.spot.btcusdt", Data: tradePayload(t, i, "BTC")},
+			Message{Subject: "trade.binance.spot.ethusdt", Data: tradePayload(t, i, "ETH")},
+		)
+	}
+	if err := sink.Deliver(msgs); err != nil {
+		t.Fatalf("Deliver() returned error: %v", err)
+	}
+
+	btc := producer.produced[kafka.Partition([]byte("btcusdt"), 4)]
+	var ids []int64
+	for _, r := range btc {
+		if string(r.Key) != "btcusdt" {
+			continue
+		}
+		var trade sqx.Trade
+		if err := json.Unmarshal(r.Value, &trade); err != nil {
+			t.Fatalf("record value is not trade JSON: %v", err)
+		}
+		ids = append(ids, trade.Id)
+	}
+	if fmt.Sprint(ids) != "[1 2 3]" {
+		t.Errorf("btcusdt trades = %v, expected in-order [1 2 3] on a single partition", ids)
+	}
+}
+
+func TestKafkaSink_ErrorOnFailedProduce(t *testing.T) {
+	producer := &fakeKafka{partitions: 1, produced: make(map[int][]kafka.Record), failAfter: 1}
+	sink := NewKafkaSink(producer, "sequex.trade", EncodingProtobuf)
+	msgs := []Message{{Subject: "trade.binance.spot.btcusdt", Data: tradePayload(t, 1, "BTC")}}
+
+	if err := sink.Deliver(msgs); err != nil {
+		t.Fatalf("first Deliver() returned error: %v", err)
+	}
+	if err := sink.Deliver(msgs); err == nil {
+		t.Fatal("expected error so the batch is not acknowledged")
+	}
+}
+
+func TestKafkaSource_ResumesFromCheckpoint(t *testing.T) {
+	fetcher := &fakeKafka{partitions: 1, produced: make(map[int][]kafka.Record)}
+	sink := NewKafkaSink(fetcher, "sequex.trade", EncodingJSON)
+	var msgs []Message
+	for i := int64(1); i <= 3; i++ {
+		msgs = append(msgs, Message{Subject: "trade.binance.spot.btcusdt", Data: tradePayload(t, i, "BTC")})
+	}
+	if err := sink.Deliver(msgs); err != nil {
+		t.Fatalf("Deliver() returned error: %v", err)
+	}
+
+	path := filepath.Join(t.TempDir(), "offsets.json")
+	store, err := NewFileCheckpointStore(path)
+	if err != nil {
+		t.Fatalf("NewFileCheckpointStore() returned error: %v", err)
+	}
+
+	// JSON records are converted back to protobuf, and NATS fails on the second record
+	calls := 0
+	publish := func(subject string, data []byte, msgId string) error {
+		var trade sqx.Trade
+		if err := sqx.Unmarshal(data, &trade); err != nil {
+			return err
+		}
+		calls++
+		if calls == 2 {
+			return fmt.Errorf("nats unavailable")
+		}
+		return nil
+	}
+
+	source := NewKafkaSource(fetcher, "sequex.trade", 0, "", EncodingJSON, store, kafka.OffsetEarliest, publish)
+	if _, err := source.Poll(0, 0); err == nil {
+		t.Fatal("expected publish failure")
+	}
+	if source.Offset() != 1 {
+		t.Errorf("offset = %d, expected 1", source.Offset())
+	}
+
+	// A fresh source resumes after the checkpoint, not from the start
+	reopened, err := NewFileCheckpointStore(path)
+	if err != nil {
+		t.Fatalf("NewFileCheckpointStore() returned error: %v", err)
+	}
+	var published []string
+	source = NewKafkaSource(fetcher, "sequex.trade", 0, "", EncodingJSON, reopened, kafka.OffsetEarliest, func(subject string, data []byte, msgId string) error {
+		if subject != "trade.binance.spot.btcusdt" {
+			t.Errorf("subject = %s, expected subject from record header", subject)
+		}
+		published = append(published, msgId)
+		return nil
+	})
+	n, err := source.Poll(0, 0)
+	if err != nil {
+		t.Fatalf("Poll() returned error: %v", err)
+	}
+	if n != 2 || fmt.Sprint(published) != "[kafka.sequex.trade.0.1 kafka.sequex.trade.0.2]" {
+		t.Errorf("published %v", published)
+	}
+	if offset, _, _ := reopened.Load("sequex.trade", 0); offset != 3 {
+		t.Errorf("checkpoint = %d, expected 3", offset)
+	}
+}
+
+type fakeMQTT struct {
+	topics   []string
+	payloads [][]byte
+	qos      []byte
+}
+
+func (f *fakeMQTT) Publish(topic string, qos byte, retained bool, payload []byte) error {
+	f.topics = append(f.topics, topic)
+	f.payloads = append(f.payloads, payload)
+	f.qos = append(f.qos, qos)
+	return nil
+}
+
+func TestMQTTSink_Deliver(t *testing.T) {
+	publisher := &fakeMQTT{}
+	sink := NewMQTTSink(publisher, "/sequex/")
+	route := MQTTRoute{Subject: "trade.>", QoS: 1}
+
+	if err := sink.Deliver(route, Message{Subject: "trade.btcusdt", Data: tradePayload(t, 7, "BTC")}); err != nil {
+		t.Fatalf("Deliver() returned error: %v", err)
+	}
+	if err := sink.Deliver(route, Message{Subject: "index.btc_basis", Data: []byte(`{"name":"BTC_BASIS"}`)}); err != nil {
+		t.Fatalf("Deliver() returned error: %v", err)
+	}
+	if err := sink.Deliver(route, Message{Subject: "index.raw", Data: []byte{0x01, 0x02}}); err == nil {
+		t.Error("expected error for non-JSON payload")
+	}
+
+	if fmt.Sprint(publisher.topics) != "[sequex/trade/btcusdt sequex/index/btc_basis]" {
+		t.Errorf("topics = %v", publisher.topics)
+	}
+	var trade sqx.Trade
+	if err := json.Unmarshal(publisher.payloads[0], &trade); err != nil || trade.Id != 7 {
+		t.Errorf("trade payload = %s, err = %v", publisher.payloads[0], err)
+	}
+	if publisher.qos[0] != 1 {
+		t.Errorf("qos = %d, expected 1", publisher.qos[0])
+	}
+}
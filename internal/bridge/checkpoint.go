@@ -0,0 +1,70 @@
+package bridge
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+)
+
+// CheckpointStore persists the next offset to consume per topic partition
+type CheckpointStore interface {
+	Load(topic string, partition int) (int64, bool, error)
+	Save(topic string, partition int, offset int64) error
+}
+
+// FileCheckpointStore keeps offsets in a JSON file rewritten atomically on every save
+type FileCheckpointStore struct {
+	mu      sync.Mutex
+	path    string
+	offsets map[string]int64
+}
+
+// NewFileCheckpointStore opens a checkpoint file, creating it on first save
+func NewFileCheckpointStore(path string) (*FileCheckpointStore, error) {
+	s := &FileCheckpointStore{path: path, offsets: make(map[string]int64)}
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return s, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read checkpoint file %s: %w", path, err)
+	}
+	if err := json.Unmarshal(data, &s.offsets); err != nil {
+		return nil, fmt.Errorf("failed to parse checkpoint file %s: %w", path, err)
+	}
+	return s, nil
+}
+
+func checkpointKey(topic string, partition int) string {
+	return fmt.Sprintf("%s/%d", topic, partition)
+}
+
+// Load returns the checkpointed offset of a topic partition
+func (s *FileCheckpointStore) Load(topic string, partition int) (int64, bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	offset, ok := s.offsets[checkpointKey(topic, partition)]
+	return offset, ok, nil
+}
+
+// Save records the next offset to consume of a topic partition
+func (s *FileCheckpointStore) Save(topic string, partition int, offset int64) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.offsets[checkpointKey(topic, partition)] = offset
+
+	data, err := json.MarshalIndent(s.offsets, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(s.path), 0o755); err != nil {
+		return err
+	}
+	tmp := s.path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0o644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, s.path)
+}
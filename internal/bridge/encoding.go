@@ -0,0 +1,67 @@
+package bridge
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/BullionBear/sequex/internal/model/sqx"
+)
+
+// Encoding is the payload encoding used on the external system
+type Encoding string
+
+const (
+	EncodingProtobuf Encoding = "protobuf" // payloads are forwarded as-is
+	EncodingJSON     Encoding = "json"     // trade payloads are converted to JSON
+)
+
+// ParseEncoding parses an encoding name, defaulting to protobuf
+func ParseEncoding(s string) (Encoding, error) {
+	switch Encoding(strings.ToLower(s)) {
+	case "", EncodingProtobuf:
+		return EncodingProtobuf, nil
+	case EncodingJSON:
+		return EncodingJSON, nil
+	default:
+		return "", fmt.Errorf("unknown encoding: %s", s)
+	}
+}
+
+// isTradeSubject reports whether a subject carries protobuf encoded trades
+func isTradeSubject(subject string) bool {
+	return strings.HasPrefix(subject, "trade.")
+}
+
+// Export converts a NATS payload into the external encoding
+func Export(subject string, data []byte, encoding Encoding) ([]byte, error) {
+	if encoding != EncodingJSON || !isTradeSubject(subject) {
+		return data, nil
+	}
+	var trade sqx.Trade
+	if err := sqx.Unmarshal(data, &trade); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal trade: %w", err)
+	}
+	return json.Marshal(trade)
+}
+
+// Import converts an external payload back into the NATS encoding
+func Import(subject string, data []byte, encoding Encoding) ([]byte, error) {
+	if encoding != EncodingJSON || !isTradeSubject(subject) {
+		return data, nil
+	}
+	var trade sqx.Trade
+	if err := json.Unmarshal(data, &trade); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal trade JSON: %w", err)
+	}
+	return trade.Marshal()
+}
+
+// SymbolKey returns the symbol token of a subject such as
+// trade.binance.spot.btcusdt, used as the partitioning key
+func SymbolKey(subject string) string {
+	if i := strings.LastIndexByte(subject, '.'); i >= 0 {
+		return subject[i+1:]
+	}
+	return subject
+}
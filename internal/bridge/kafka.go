@@ -0,0 +1,177 @@
+package bridge
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/BullionBear/sequex/pkg/kafka"
+)
+
+// Message is a NATS message travelling through a bridge
+type Message struct {
+	Subject   string
+	Data      []byte
+	Timestamp time.Time
+}
+
+// Header names attached to Kafka records mirrored from NATS
+const (
+	HeaderSubject = "nats-subject"
+)
+
+// Producer writes records to Kafka partitions
+type Producer interface {
+	Partitions(topic string) (int, error)
+	Produce(topic string, partition int, records []kafka.Record) (int64, error)
+}
+
+// KafkaSink mirrors NATS messages of one route to a Kafka topic, keyed and
+// partitioned by symbol so per-symbol ordering is preserved
+type KafkaSink struct {
+	producer Producer
+	topic    string
+	encoding Encoding
+}
+
+// NewKafkaSink creates a sink writing to the given topic
+func NewKafkaSink(producer Producer, topic string, encoding Encoding) *KafkaSink {
+	return &KafkaSink{producer: producer, topic: topic, encoding: encoding}
+}
+
+// Deliver writes a batch of messages and returns only once every record has
+// been acknowledged by Kafka. Callers acknowledge the NATS messages after a
+// nil return, giving at-least-once delivery; on error the whole batch is
+// redelivered.
+func (s *KafkaSink) Deliver(msgs []Message) error {
+	partitions, err := s.producer.Partitions(s.topic)
+	if err != nil {
+		return fmt.Errorf("failed to get partitions of %s: %w", s.topic, err)
+	}
+
+	batches := make(map[int][]kafka.Record)
+	order := make([]int, 0)
+	for _, msg := range msgs {
+		value, err := Export(msg.Subject, msg.Data, s.encoding)
+		if err != nil {
+			return fmt.Errorf("failed to encode message on %s: %w", msg.Subject, err)
+		}
+		key := []byte(SymbolKey(msg.Subject))
+		partition := kafka.Partition(key, partitions)
+		if _, ok := batches[partition]; !ok {
+			order = append(order, partition)
+		}
+		batches[partition] = append(batches[partition], kafka.Record{
+			Key:       key,
+			Value:     value,
+			Headers:   []kafka.Header{{Key: HeaderSubject, Value: []byte(msg.Subject)}},
+			Timestamp: msg.Timestamp,
+		})
+	}
+
+	for _, partition := range order {
+		if _, err := s.producer.Produce(s.topic, partition, batches[partition]); err != nil {
+			return fmt.Errorf("failed to produce to %s/%d: %w", s.topic, partition, err)
+		}
+	}
+	return nil
+}
+
+// Fetcher reads records from Kafka partitions
+type Fetcher interface {
+	Partitions(topic string) (int, error)
+	Fetch(topic string, partition int, offset int64, maxWait time.Duration, maxBytes int) ([]kafka.Record, int64, error)
+	ListOffset(topic string, partition int, timestamp int64) (int64, error)
+}
+
+// PublishFunc publishes a message to NATS. msgId is stable across
+// redeliveries so JetStream can deduplicate replays after a restart.
+type PublishFunc func(subject string, data []byte, msgId string) error
+
+// KafkaSource mirrors one Kafka topic partition back into NATS
+type KafkaSource struct {
+	fetcher     Fetcher
+	topic       string
+	partition   int
+	subject     string
+	encoding    Encoding
+	checkpoints CheckpointStore
+	publish     PublishFunc
+	startAt     int64
+	offset      int64
+	loaded      bool
+}
+
+// NewKafkaSource creates a source for a topic partition. startAt is used when
+// no checkpoint exists and is either kafka.OffsetEarliest or kafka.OffsetLatest.
+func NewKafkaSource(fetcher Fetcher, topic string, partition int, subject string, encoding Encoding,
+	checkpoints CheckpointStore, startAt int64, publish PublishFunc) *KafkaSource {
+	return &KafkaSource{
+		fetcher:     fetcher,
+		topic:       topic,
+		partition:   partition,
+		subject:     subject,
+		encoding:    encoding,
+		checkpoints: checkpoints,
+		publish:     publish,
+		startAt:     startAt,
+	}
+}
+
+// Offset returns the next offset to be consumed
+func (s *KafkaSource) Offset() int64 {
+	return s.offset
+}
+
+// Poll fetches one batch of records, publishes them and checkpoints the next
+// offset. It returns the number of records published.
+func (s *KafkaSource) Poll(maxWait time.Duration, maxBytes int) (published int, err error) {
+	if !s.loaded {
+		offset, ok, err := s.checkpoints.Load(s.topic, s.partition)
+		if err != nil {
+			return 0, fmt.Errorf("failed to load checkpoint: %w", err)
+		}
+		if !ok {
+			if offset, err = s.fetcher.ListOffset(s.topic, s.partition, s.startAt); err != nil {
+				return 0, fmt.Errorf("failed to resolve start offset: %w", err)
+			}
+		}
+		s.offset = offset
+		s.loaded = true
+	}
+
+	records, _, err := s.fetcher.Fetch(s.topic, s.partition, s.offset, maxWait, maxBytes)
+	if err != nil {
+		return 0, err
+	}
+
+	defer func() {
+		// Checkpoint whatever was published, even when the batch stopped early
+		if published > 0 {
+			if saveErr := s.checkpoints.Save(s.topic, s.partition, s.offset); saveErr != nil && err == nil {
+				err = fmt.Errorf("failed to save checkpoint: %w", saveErr)
+			}
+		}
+	}()
+	for _, record := range records {
+		subject := s.subject
+		for _, h := range record.Headers {
+			if h.Key == HeaderSubject && subject == "" {
+				subject = string(h.Value)
+			}
+		}
+		if subject == "" {
+			return published, fmt.Errorf("record %s/%d@%d has no target subject", s.topic, s.partition, record.Offset)
+		}
+		data, err := Import(subject, record.Value, s.encoding)
+		if err != nil {
+			return published, fmt.Errorf("failed to decode record %s/%d@%d: %w", s.topic, s.partition, record.Offset, err)
+		}
+		msgId := fmt.Sprintf("kafka.%s.%d.%d", s.topic, s.partition, record.Offset)
+		if err := s.publish(subject, data, msgId); err != nil {
+			return published, fmt.Errorf("failed to publish record %s/%d@%d: %w", s.topic, s.partition, record.Offset, err)
+		}
+		s.offset = record.Offset + 1
+		published++
+	}
+	return published, nil
+}
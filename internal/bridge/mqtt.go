@@ -0,0 +1,52 @@
+package bridge
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
+// MQTTPublisher publishes a payload to an MQTT topic
+type MQTTPublisher interface {
+	Publish(topic string, qos byte, retained bool, payload []byte) error
+}
+
+// MQTTRoute maps NATS subjects to MQTT topics with a delivery QoS
+type MQTTRoute struct {
+	Subject string // NATS subject, wildcards allowed
+	QoS     byte   // 0, 1 or 2
+	Retain  bool   // Keep the last payload on the broker for late subscribers
+}
+
+// MQTTSink republishes NATS messages as JSON on MQTT topics, e.g.
+// trade.binance.spot.btcusdt becomes <prefix>/trade/binance/spot/btcusdt
+type MQTTSink struct {
+	publisher MQTTPublisher
+	prefix    string
+}
+
+// NewMQTTSink creates a sink publishing under the given topic prefix
+func NewMQTTSink(publisher MQTTPublisher, prefix string) *MQTTSink {
+	return &MQTTSink{publisher: publisher, prefix: strings.Trim(prefix, "/")}
+}
+
+// Topic maps a NATS subject to its MQTT topic
+func (s *MQTTSink) Topic(subject string) string {
+	topic := strings.ReplaceAll(subject, ".", "/")
+	if s.prefix == "" {
+		return topic
+	}
+	return s.prefix + "/" + topic
+}
+
+// Deliver converts a message to JSON and publishes it according to the route
+func (s *MQTTSink) Deliver(route MQTTRoute, msg Message) error {
+	payload, err := Export(msg.Subject, msg.Data, EncodingJSON)
+	if err != nil {
+		return err
+	}
+	if !json.Valid(payload) {
+		return fmt.Errorf("payload on %s is not valid JSON", msg.Subject)
+	}
+	return s.publisher.Publish(s.Topic(msg.Subject), route.QoS, route.Retain, payload)
+}
@@ -0,0 +1,87 @@
+package bundle
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"reflect"
+
+	"github.com/nats-io/nats.go"
+)
+
+// Change is what applying a bundle did to a stream or a configuration
+type Change string
+
+const (
+	Created   Change = "created"
+	Updated   Change = "updated"
+	Unchanged Change = "unchanged"
+)
+
+// Result is the change applied to a stream or a configuration
+type Result struct {
+	Name   string `json:"name"`
+	Change Change `json:"change"`
+}
+
+// ProvisionStreams creates the streams of the bundle that are missing and
+// updates the others to their configuration
+func (b *Bundle) ProvisionStreams(js nats.JetStreamContext) ([]Result, error) {
+	results := make([]Result, 0, len(b.Streams))
+	for _, s := range b.Streams {
+		cfg := s.StreamConfig
+		info, err := js.StreamInfo(cfg.Name)
+		if errors.Is(err, nats.ErrStreamNotFound) {
+			if _, err := js.AddStream(&cfg); err != nil {
+				return results, fmt.Errorf("failed to create stream %s: %w", cfg.Name, err)
+			}
+			results = append(results, Result{Name: cfg.Name, Change: Created})
+			continue
+		}
+		if err != nil {
+			return results, fmt.Errorf("failed to look up stream %s: %w", cfg.Name, err)
+		}
+		updated, err := js.UpdateStream(&cfg)
+		if err != nil {
+			return results, fmt.Errorf("failed to update stream %s: %w", cfg.Name, err)
+		}
+		// The server fills in the defaults, so the configurations it
+		// returns are compared rather than the one of the bundle
+		change := Updated
+		if reflect.DeepEqual(info.Config, updated.Config) {
+			change = Unchanged
+		}
+		results = append(results, Result{Name: cfg.Name, Change: change})
+	}
+	return results, nil
+}
+
+// UploadConfigs puts the configuration of every node in the bucket unless
+// it is already there, setting the revision the master runs the node with
+func (b *Bundle) UploadConfigs(kv nats.KeyValue) ([]Result, error) {
+	var results []Result
+	for i := range b.Nodes {
+		n := &b.Nodes[i]
+		if n.Config == "" {
+			continue
+		}
+		key := ConfigKey(b.Name, n.Name)
+		change := Created
+		current, err := kv.Get(key)
+		switch {
+		case err == nil && bytes.Equal(current.Value(), n.data):
+			n.Revision = current.Revision()
+			results = append(results, Result{Name: key, Change: Unchanged})
+			continue
+		case err == nil:
+			change = Updated
+		case !errors.Is(err, nats.ErrKeyNotFound):
+			return results, fmt.Errorf("failed to read %s: %w", key, err)
+		}
+		if n.Revision, err = kv.Put(key, n.data); err != nil {
+			return results, fmt.Errorf("failed to upload %s: %w", key, err)
+		}
+		results = append(results, Result{Name: key, Change: change})
+	}
+	return results, nil
+}
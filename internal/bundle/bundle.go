@@ -0,0 +1,147 @@
+// Package bundle describes a complete application as a deployable unit: the
+// streams it needs, its nodes with their configurations and the order they
+// start in. Applying a bundle provisions its streams, uploads its
+// configurations to a NATS key-value bucket and has the master start or
+// update its nodes.
+package bundle
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/BullionBear/sequex/internal/config"
+	"github.com/BullionBear/sequex/pkg/eventbus"
+	"github.com/nats-io/nats.go"
+)
+
+// DefaultBucket is the key-value bucket the configurations of the nodes are
+// uploaded to, one key per node named <app>.<node>
+const DefaultBucket = config.DefaultConfigBucket
+
+// Bundle is an application, usually written in a sqxapp.yml file
+type Bundle struct {
+	Name    string   `json:"name"` // Of the application, a subject token
+	Streams []Stream `json:"streams"`
+	Nodes   []Node   `json:"nodes"` // Started in dependency order
+}
+
+// Stream is a stream the application needs, created when missing and
+// updated otherwise
+type Stream struct {
+	// JSON configuration as written for nats stream add --config, relative
+	// to the bundle, e.g. infra/nats/stream_trade.json; the stream is
+	// configured inline when empty
+	File string `json:"file,omitempty"`
+	nats.StreamConfig
+}
+
+// Node is a node of the application. The master runs it with -c and the
+// path its configuration was written to appended to its arguments.
+type Node struct {
+	config.PipelineNodeConfig
+	Config   string `json:"config"`             // Configuration file, relative to the bundle; the node is run without -c when empty
+	Revision uint64 `json:"revision,omitempty"` // Of the configuration in the bucket, set once uploaded
+	data     []byte
+}
+
+// ConfigKey is the key of the configuration of a node in the bucket
+func ConfigKey(app, node string) string {
+	return app + "." + node
+}
+
+// Load reads a bundle with the files it refers to, resolved relative to the
+// bundle, and validates it
+func Load(path string) (*Bundle, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read bundle %s: %w", path, err)
+	}
+	var b Bundle
+	if err := config.UnmarshalYAML(data, &b); err != nil {
+		return nil, fmt.Errorf("failed to parse bundle %s: %w", path, err)
+	}
+	dir := filepath.Dir(path)
+	resolve := func(file string) string {
+		if filepath.IsAbs(file) {
+			return file
+		}
+		return filepath.Join(dir, file)
+	}
+	for i := range b.Streams {
+		s := &b.Streams[i]
+		if s.File == "" {
+			continue
+		}
+		data, err := os.ReadFile(resolve(s.File))
+		if err != nil {
+			return nil, fmt.Errorf("failed to read stream %s: %w", s.File, err)
+		}
+		if err := json.Unmarshal(data, &s.StreamConfig); err != nil {
+			return nil, fmt.Errorf("failed to parse stream %s: %w", s.File, err)
+		}
+	}
+	for i := range b.Nodes {
+		n := &b.Nodes[i]
+		if n.Config == "" {
+			continue
+		}
+		if n.data, err = os.ReadFile(resolve(n.Config)); err != nil {
+			return nil, fmt.Errorf("failed to read the configuration of node %s: %w", n.Name, err)
+		}
+	}
+	if err := b.Validate(); err != nil {
+		return nil, fmt.Errorf("invalid bundle %s: %w", path, err)
+	}
+	return &b, nil
+}
+
+// Validate validates the bundle, including the dependency graph of its nodes
+func (b *Bundle) Validate() error {
+	if err := eventbus.ValidateToken(b.Name); err != nil {
+		return fmt.Errorf("invalid name: %w", err)
+	}
+	if len(b.Nodes) == 0 {
+		return fmt.Errorf("a bundle needs at least one node")
+	}
+	streams := make(map[string]bool, len(b.Streams))
+	for i, s := range b.Streams {
+		if s.Name == "" || strings.ContainsAny(s.Name, " .*>") {
+			return fmt.Errorf("streams[%d]: invalid name %q", i, s.Name)
+		}
+		if streams[s.Name] {
+			return fmt.Errorf("stream %s is declared twice", s.Name)
+		}
+		streams[s.Name] = true
+		if len(s.Subjects) == 0 {
+			return fmt.Errorf("stream %s has no subjects", s.Name)
+		}
+		for _, subject := range s.Subjects {
+			if err := eventbus.ValidatePattern(subject); err != nil {
+				return fmt.Errorf("stream %s: %w", s.Name, err)
+			}
+		}
+	}
+	pipeline := b.pipeline()
+	if err := pipeline.Validate(); err != nil {
+		return err
+	}
+	for _, n := range b.Nodes {
+		if n.Config != "" && n.Revision == 0 && n.data == nil {
+			return fmt.Errorf("node %s: configuration %s is neither loaded nor uploaded", n.Name, n.Config)
+		}
+	}
+	return nil
+}
+
+// pipeline returns the nodes as run by the master, without their
+// configurations
+func (b *Bundle) pipeline() config.PipelineConfig {
+	nodes := make([]config.PipelineNodeConfig, len(b.Nodes))
+	for i, n := range b.Nodes {
+		nodes[i] = n.PipelineNodeConfig
+	}
+	return config.PipelineConfig{Nodes: nodes}
+}
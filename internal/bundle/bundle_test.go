@@ -0,0 +1,172 @@
+package bundle
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/BullionBear/sequex/internal/config"
+	"github.com/BullionBear/sequex/internal/pipeline"
+)
+
+func writeFile(t *testing.T, path, data string) {
+	t.Helper()
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, []byte(data), 0o644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestLoad(t *testing.T) {
+	dir := t.TempDir()
+	writeFile(t, filepath.Join(dir, "streams/trade.json"), `{"name": "TRADE", "subjects": ["trade.>"], "storage": "memory", "max_age": 21600000000000}`)
+	writeFile(t, filepath.Join(dir, "config/feed.yml"), "exchange: binance\n")
+	writeFile(t, filepath.Join(dir, "sqxapp.yml"), `
+name: btc
+streams:
+  - file: streams/trade.json
+  - name: SIGNAL
+    subjects: [signal.>]
+    storage: file
+nodes:
+  - name: basis
+    command: ./bin/basis-linux-amd64
+    config: config/feed.yml
+    depends_on: [feed]
+    ready_subject: diag.basis
+  - name: feed
+    command: ./bin/feed-linux-amd64
+    config: config/feed.yml
+`)
+
+	b, err := Load(filepath.Join(dir, "sqxapp.yml"))
+	if err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+	if b.Name != "btc" || len(b.Streams) != 2 || len(b.Nodes) != 2 {
+		t.Fatalf("Load() = %+v", b)
+	}
+	if s := b.Streams[0]; s.Name != "TRADE" || s.MaxAge != 6*time.Hour || s.Subjects[0] != "trade.>" {
+		t.Errorf("stream read from its file = %+v", s.StreamConfig)
+	}
+	if s := b.Streams[1]; s.Name != "SIGNAL" || s.Storage.String() != "File" {
+		t.Errorf("inline stream = %+v", s.StreamConfig)
+	}
+	if n := b.Nodes[0]; n.DependsOn[0] != "feed" || n.ReadySubject != "diag.basis" || string(n.data) != "exchange: binance\n" {
+		t.Errorf("node = %+v", n)
+	}
+
+	for _, tc := range []struct {
+		name, bundle, err string
+	}{
+		{"missing config", "name: btc\nnodes:\n  - name: feed\n    command: feed\n    config: missing.yml\n", "failed to read the configuration of node feed"},
+		{"invalid name", "name: btc.usdt\nnodes:\n  - name: feed\n    command: feed\n", "invalid name"},
+		{"no nodes", "name: btc\n", "at least one node"},
+		{"stream without subjects", "name: btc\nstreams:\n  - name: TRADE\nnodes:\n  - name: feed\n    command: feed\n", "stream TRADE has no subjects"},
+		{"cycle", "name: btc\nnodes:\n  - name: a\n    command: a\n    depends_on: [b]\n  - name: b\n    command: b\n    depends_on: [a]\n", "dependency cycle"},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			path := filepath.Join(dir, "invalid.yml")
+			writeFile(t, path, tc.bundle)
+			if _, err := Load(path); err == nil || !strings.Contains(err.Error(), tc.err) {
+				t.Errorf("Load() error = %v, want %q", err, tc.err)
+			}
+		})
+	}
+}
+
+// waitStates waits for the nodes of app to reach state
+func waitStates(t *testing.T, d *Deployer, app string, state pipeline.State) App {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		for _, a := range d.Apps() {
+			if a.Name != app {
+				continue
+			}
+			done := true
+			for _, n := range a.Nodes {
+				done = done && n.State == state
+			}
+			if done {
+				return a
+			}
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("nodes of %s not %s: %+v", app, state, d.Apps())
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func TestDeployer(t *testing.T) {
+	dir := t.TempDir()
+	configs := map[uint64]string{1: `{"v": 1}`, 2: `{"v": 2}`}
+	d := &Deployer{
+		dir: dir,
+		fetch: func(key string, revision uint64) ([]byte, error) {
+			if key != "btc.feed" {
+				t.Errorf("fetched %s", key)
+			}
+			return []byte(configs[revision]), nil
+		},
+		apps:    make(map[string]*deployment),
+		removed: make(map[string]*deployment),
+	}
+	defer d.Close()
+	node := func(name string, deps ...string) Node {
+		return Node{PipelineNodeConfig: config.PipelineNodeConfig{Name: name, Command: "/bin/sh", Args: []string{"-c", "exec sleep 30"}, DependsOn: deps}}
+	}
+	feed := node("feed")
+	feed.Config, feed.Revision = "config/feed.json", 1
+	b := Bundle{Name: "btc", Nodes: []Node{node("basis", "feed"), feed}}
+
+	applied, err := d.Apply(b, "alice")
+	if err != nil {
+		t.Fatalf("Apply() error = %v", err)
+	}
+	if applied.Change != Created || strings.Join(applied.Order, ",") != "feed,basis" {
+		t.Errorf("Apply() = %+v", applied)
+	}
+	waitStates(t, d, "btc", pipeline.StateReady)
+	data, err := os.ReadFile(filepath.Join(dir, "btc", "feed.json"))
+	if err != nil || string(data) != configs[1] {
+		t.Errorf("configuration written = %q, %v", data, err)
+	}
+
+	if applied, err := d.Apply(b, "alice"); err != nil || applied.Change != Unchanged {
+		t.Errorf("Apply() of the same bundle = %+v, %v", applied, err)
+	}
+
+	b.Nodes[1].Revision = 2
+	if applied, err := d.Apply(b, "bob"); err != nil || applied.Change != Updated {
+		t.Errorf("Apply() of a new configuration = %+v, %v", applied, err)
+	}
+	app := waitStates(t, d, "btc", pipeline.StateReady)
+	if app.By != "bob" {
+		t.Errorf("applied by %s", app.By)
+	}
+	data, _ = os.ReadFile(filepath.Join(dir, "btc", "feed.json"))
+	if string(data) != configs[2] {
+		t.Errorf("configuration written = %q", data)
+	}
+
+	b.Nodes[1].Revision = 0
+	if _, err := d.Apply(b, "bob"); err == nil {
+		t.Error("Apply() of a configuration not uploaded succeeded")
+	}
+
+	if _, err := d.Remove("btc"); err != nil {
+		t.Fatalf("Remove() error = %v", err)
+	}
+	if apps := d.Apps(); len(apps) != 0 {
+		t.Errorf("Apps() after Remove() = %+v", apps)
+	}
+	if _, err := d.Remove("btc"); err == nil {
+		t.Error("Remove() of a removed application succeeded")
+	}
+}
@@ -0,0 +1,256 @@
+package bundle
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/BullionBear/sequex/internal/config"
+	"github.com/BullionBear/sequex/internal/pipeline"
+	"github.com/nats-io/nats.go"
+)
+
+// App is an application run by the master
+type App struct {
+	Name    string               `json:"name"`
+	By      string               `json:"by"`
+	Applied time.Time            `json:"applied"`
+	Error   string               `json:"error,omitempty"` // Why its nodes failed to start
+	Nodes   []pipeline.NodeState `json:"nodes"`           // In start order
+}
+
+// Applied is the outcome of applying a bundle to the master
+type Applied struct {
+	App    string   `json:"app"`
+	Change Change   `json:"change"`
+	Order  []string `json:"order"` // Nodes in start order
+}
+
+// Deployer runs the applications applied to the master, each as a pipeline
+// graph of its own. Applying a bundle whose nodes changed restarts the
+// application as a whole, its nodes stopped in reverse order before the new
+// ones start in dependency order.
+type Deployer struct {
+	dir    string
+	conn   *nats.Conn
+	fetch  func(key string, revision uint64) ([]byte, error)
+	onExit func(app, node string, err error)
+
+	mu      sync.Mutex
+	apps    map[string]*deployment
+	removed map[string]*deployment // Stopping, until they stopped
+}
+
+// deployment is an application as last applied
+type deployment struct {
+	bundle  Bundle
+	digest  string
+	by      string
+	applied time.Time
+	graph   *pipeline.Graph
+	cancel  context.CancelFunc
+	started chan struct{} // Closed once the nodes started or failed to
+	err     error
+	once    sync.Once
+}
+
+// NewDeployer creates a deployer writing the configurations read from kv
+// under dir, one directory per application, and probing the readiness of
+// the nodes over conn. onExit is called when a node exits on its own.
+func NewDeployer(kv nats.KeyValue, dir string, conn *nats.Conn, onExit func(app, node string, err error)) *Deployer {
+	return &Deployer{
+		dir:  dir,
+		conn: conn,
+		fetch: func(key string, revision uint64) ([]byte, error) {
+			entry, err := kv.GetRevision(key, revision)
+			if err != nil {
+				return nil, err
+			}
+			return entry.Value(), nil
+		},
+		onExit:  onExit,
+		apps:    make(map[string]*deployment),
+		removed: make(map[string]*deployment),
+	}
+}
+
+// Apply starts the nodes of a bundle whose configurations were uploaded,
+// unless they run as applied already. Nodes start in the background, their
+// progress is listed by Apps.
+func (d *Deployer) Apply(b Bundle, by string) (Applied, error) {
+	applied := Applied{App: b.Name}
+	if err := b.Validate(); err != nil {
+		return applied, err
+	}
+	data, err := json.Marshal(b.Nodes)
+	if err != nil {
+		return applied, err
+	}
+	digest := string(data)
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	old := d.apps[b.Name]
+	applied.Change = Created
+	if old == nil {
+		// The nodes of an application removed and applied again are
+		// stopped before they restart
+		old = d.removed[b.Name]
+	} else {
+		applied.Change = Updated
+		if old.digest == digest && old.healthy() {
+			applied.Change, applied.Order = Unchanged, old.graph.Names()
+			return applied, nil
+		}
+	}
+
+	nodes, err := d.materialize(b)
+	if err != nil {
+		return applied, err
+	}
+	pipelineConfig := config.PipelineConfig{Nodes: nodes}
+	graph, err := pipelineConfig.Graph(d.conn, func(node string, err error) {
+		if d.onExit != nil {
+			d.onExit(b.Name, node, err)
+		}
+	})
+	if err != nil {
+		return applied, err
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	dep := &deployment{bundle: b, digest: digest, by: by, applied: time.Now().UTC(), graph: graph, cancel: cancel, started: make(chan struct{})}
+	d.apps[b.Name] = dep
+	go func() {
+		defer close(dep.started)
+		if old != nil {
+			old.stop()
+		}
+		if err := graph.Start(ctx); err != nil {
+			d.mu.Lock()
+			dep.err = err
+			d.mu.Unlock()
+		}
+	}()
+	applied.Order = graph.Names()
+	return applied, nil
+}
+
+// materialize writes the configurations of the nodes of b and returns the
+// nodes as run, with -c and the path of their configuration appended to
+// their arguments
+func (d *Deployer) materialize(b Bundle) ([]config.PipelineNodeConfig, error) {
+	dir := filepath.Join(d.dir, b.Name)
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, err
+	}
+	nodes := make([]config.PipelineNodeConfig, len(b.Nodes))
+	for i, n := range b.Nodes {
+		nodes[i] = n.PipelineNodeConfig
+		if n.Config == "" {
+			continue
+		}
+		key := ConfigKey(b.Name, n.Name)
+		data, err := d.fetch(key, n.Revision)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read %s at revision %d: %w", key, n.Revision, err)
+		}
+		ext := filepath.Ext(n.Config)
+		if ext == "" {
+			ext = ".json"
+		}
+		path := filepath.Join(dir, n.Name+ext)
+		if err := os.WriteFile(path, data, 0o644); err != nil {
+			return nil, err
+		}
+		nodes[i].Args = append(append([]string(nil), n.Args...), "-c", path)
+	}
+	return nodes, nil
+}
+
+// healthy reports whether every node of the deployment is starting or ready
+func (dep *deployment) healthy() bool {
+	if dep.err != nil {
+		return false
+	}
+	for _, state := range dep.graph.States() {
+		switch state.State {
+		case pipeline.StatePending, pipeline.StateStarting, pipeline.StateReady:
+		default:
+			return false
+		}
+	}
+	return true
+}
+
+// stop stops the nodes of the deployment once they are done starting,
+// returning once they stopped
+func (dep *deployment) stop() {
+	dep.once.Do(func() {
+		dep.cancel()
+		<-dep.started
+		dep.graph.Stop()
+	})
+}
+
+// Remove stops the nodes of an application in the background and forgets it
+func (d *Deployer) Remove(app string) (App, error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	dep, ok := d.apps[app]
+	if !ok {
+		return App{Name: app}, fmt.Errorf("no application %s", app)
+	}
+	delete(d.apps, app)
+	d.removed[app] = dep
+	go func() {
+		dep.stop()
+		d.mu.Lock()
+		defer d.mu.Unlock()
+		if d.removed[app] == dep {
+			delete(d.removed, app)
+		}
+	}()
+	return dep.app(), nil
+}
+
+// Apps lists the applications, sorted by name
+func (d *Deployer) Apps() []App {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	apps := make([]App, 0, len(d.apps))
+	for _, dep := range d.apps {
+		apps = append(apps, dep.app())
+	}
+	sort.Slice(apps, func(i, j int) bool { return apps[i].Name < apps[j].Name })
+	return apps
+}
+
+func (dep *deployment) app() App {
+	a := App{Name: dep.bundle.Name, By: dep.by, Applied: dep.applied, Nodes: dep.graph.States()}
+	if dep.err != nil {
+		a.Error = dep.err.Error()
+	}
+	return a
+}
+
+// Close stops the nodes of every application, the last applied first
+func (d *Deployer) Close() {
+	d.mu.Lock()
+	deps := make([]*deployment, 0, len(d.apps)+len(d.removed))
+	for _, apps := range []map[string]*deployment{d.apps, d.removed} {
+		for _, dep := range apps {
+			deps = append(deps, dep)
+		}
+	}
+	d.apps, d.removed = make(map[string]*deployment), make(map[string]*deployment)
+	d.mu.Unlock()
+	sort.Slice(deps, func(i, j int) bool { return deps[i].applied.After(deps[j].applied) })
+	for _, dep := range deps {
+		dep.stop()
+	}
+}
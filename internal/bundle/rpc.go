@@ -0,0 +1,81 @@
+package bundle
+
+import (
+	"context"
+
+	"github.com/BullionBear/sequex/pkg/eventbus"
+	"github.com/nats-io/nats.go"
+)
+
+// DefaultSubject is the prefix the application RPCs of the master are
+// served under when none is configured
+const DefaultSubject = "app.master"
+
+// ApplyRequest applies a bundle whose configurations were uploaded
+type ApplyRequest struct {
+	Bundle Bundle `json:"bundle"`
+	By     string `json:"by"`
+}
+
+// RemoveRequest stops the nodes of an application
+type RemoveRequest struct {
+	App string `json:"app"`
+	By  string `json:"by"`
+}
+
+// ListRequest asks for the applications run by the master
+type ListRequest struct{}
+
+// List is the applications run by the master
+type List struct {
+	Apps []App `json:"apps"`
+}
+
+// ApplyEndpoint is the endpoint bundles are applied on, prefix.apply
+func ApplyEndpoint(prefix string) eventbus.Endpoint[ApplyRequest, Applied] {
+	return eventbus.NewCommand[ApplyRequest, Applied](prefix+".apply", eventbus.JSON)
+}
+
+// RemoveEndpoint is the endpoint applications are removed on, prefix.remove
+func RemoveEndpoint(prefix string) eventbus.Endpoint[RemoveRequest, App] {
+	return eventbus.NewCommand[RemoveRequest, App](prefix+".remove", eventbus.JSON)
+}
+
+// ListEndpoint is the endpoint applications are listed on, prefix.list
+func ListEndpoint(prefix string) eventbus.Endpoint[ListRequest, List] {
+	return eventbus.NewEndpoint[ListRequest, List](prefix+".list", eventbus.JSON)
+}
+
+// Serve answers the application RPCs of d under prefix
+func Serve(bus *eventbus.Bus, prefix string, d *Deployer) ([]*nats.Subscription, error) {
+	var subs []*nats.Subscription
+	for _, serve := range []func() (*nats.Subscription, error){
+		func() (*nats.Subscription, error) {
+			return ApplyEndpoint(prefix).Serve(bus, func(ctx context.Context, req *ApplyRequest) (*Applied, error) {
+				applied, err := d.Apply(req.Bundle, eventbus.Caller(ctx, req.By))
+				return &applied, err
+			})
+		},
+		func() (*nats.Subscription, error) {
+			return RemoveEndpoint(prefix).Serve(bus, func(_ context.Context, req *RemoveRequest) (*App, error) {
+				app, err := d.Remove(req.App)
+				return &app, err
+			})
+		},
+		func() (*nats.Subscription, error) {
+			return ListEndpoint(prefix).Serve(bus, func(_ context.Context, _ *ListRequest) (*List, error) {
+				return &List{Apps: d.Apps()}, nil
+			})
+		},
+	} {
+		sub, err := serve()
+		if err != nil {
+			for _, sub := range subs {
+				sub.Unsubscribe()
+			}
+			return nil, err
+		}
+		subs = append(subs, sub)
+	}
+	return subs, nil
+}
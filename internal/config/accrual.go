@@ -0,0 +1,112 @@
+package config
+
+import (
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/BullionBear/sequex/internal/accrual"
+	"github.com/BullionBear/sequex/internal/pms"
+	"github.com/BullionBear/sequex/pkg/exchange/binance"
+	"github.com/BullionBear/sequex/pkg/exchange/binanceperp"
+)
+
+// ExchangeKeyConfig names the environment variables of the API credentials
+// of an exchange account, so they stay out of configuration files
+type ExchangeKeyConfig struct {
+	KeyEnv    string `json:"key_env"`
+	SecretEnv string `json:"secret_env"`
+}
+
+// Validate checks the environment variables are set
+func (c *ExchangeKeyConfig) Validate(name string) error {
+	for _, env := range []string{c.KeyEnv, c.SecretEnv} {
+		if env == "" {
+			return fmt.Errorf("%s.key_env and %s.secret_env are required", name, name)
+		}
+		if os.Getenv(env) == "" {
+			return fmt.Errorf("invalid %s: environment variable %s is not set", name, env)
+		}
+	}
+	return nil
+}
+
+// AccrualConfig configures the booking of funding payments and margin
+// interest into the cash flows of a portfolio
+type AccrualConfig struct {
+	Portfolio     string             `json:"portfolio"`      // Portfolio the payments are booked to, empty disables accruals
+	IntervalMs    int64              `json:"interval_ms"`    // Interval between pulls of the histories, 15 minutes when 0
+	LookbackMs    int64              `json:"lookback_ms"`    // History pulled at startup, 7 days when 0
+	BinancePerp   *ExchangeKeyConfig `json:"binance_perp"`   // USDⓈ-M account funding is pulled from, none when omitted
+	BinanceMargin *ExchangeKeyConfig `json:"binance_margin"` // Margin account interest is pulled from, none when omitted
+}
+
+// Validate validates the accrual configuration
+func (c *AccrualConfig) Validate() error {
+	if c.Portfolio == "" {
+		return nil
+	}
+	if err := pms.ValidateID(c.Portfolio); err != nil {
+		return fmt.Errorf("invalid accruals.portfolio: %w", err)
+	}
+	if c.IntervalMs < 0 {
+		return fmt.Errorf("accruals.interval_ms cannot be negative")
+	}
+	if c.LookbackMs < 0 {
+		return fmt.Errorf("accruals.lookback_ms cannot be negative")
+	}
+	if c.BinancePerp == nil && c.BinanceMargin == nil {
+		return fmt.Errorf("accruals.binance_perp or accruals.binance_margin must be configured")
+	}
+	if c.BinancePerp != nil {
+		if err := c.BinancePerp.Validate("accruals.binance_perp"); err != nil {
+			return err
+		}
+	}
+	if c.BinanceMargin != nil {
+		if err := c.BinanceMargin.Validate("accruals.binance_margin"); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Interval returns the interval between pulls of the histories
+func (c *AccrualConfig) Interval() time.Duration {
+	if c.IntervalMs == 0 {
+		return 15 * time.Minute
+	}
+	return time.Duration(c.IntervalMs) * time.Millisecond
+}
+
+// FundingClient creates the USDⓈ-M client funding is pulled with, nil when
+// funding is not booked
+func (c *AccrualConfig) FundingClient() *binanceperp.Client {
+	if c.Portfolio == "" || c.BinancePerp == nil {
+		return nil
+	}
+	return binanceperp.NewClient(&binanceperp.Config{
+		APIKey:    os.Getenv(c.BinancePerp.KeyEnv),
+		APISecret: os.Getenv(c.BinancePerp.SecretEnv),
+		BaseURL:   binanceperp.MainnetBaseUrl,
+	})
+}
+
+// Booker creates the booker of the portfolio in store, nil when accruals are disabled
+func (c *AccrualConfig) Booker(store pms.Store) (*accrual.Booker, error) {
+	if c.Portfolio == "" {
+		return nil, nil
+	}
+	var funding accrual.FundingSource
+	if client := c.FundingClient(); client != nil {
+		funding = client
+	}
+	var interest accrual.InterestSource
+	if c.BinanceMargin != nil {
+		interest = binance.NewClient(binance.NewMainnetConfig(os.Getenv(c.BinanceMargin.KeyEnv), os.Getenv(c.BinanceMargin.SecretEnv)))
+	}
+	return accrual.NewBooker(store, funding, interest, accrual.Options{
+		Portfolio: c.Portfolio,
+		Lookback:  time.Duration(c.LookbackMs) * time.Millisecond,
+	})
+}
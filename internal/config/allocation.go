@@ -0,0 +1,60 @@
+package config
+
+import (
+	"fmt"
+
+	"github.com/BullionBear/sequex/internal/allocation"
+	"github.com/BullionBear/sequex/internal/pms"
+	"github.com/BullionBear/sequex/pkg/eventbus"
+)
+
+// AllocationConfig grants strategies notional budgets from portfolios,
+// enforced on their orders
+type AllocationConfig struct {
+	Currency    string                  `json:"currency"`    // Of budgets, USDT when empty
+	Capital     map[string]float64      `json:"capital"`     // Capital of each portfolio, bounding the total of its allocations
+	Allocations []allocation.Allocation `json:"allocations"` // Initial budgets, changes made at runtime take precedence
+	Journal     string                  `json:"journal"`     // JSON lines audit trail of the changes, in memory only when empty
+	Subject     string                  `json:"subject"`     // Prefix the allocation RPCs are served under, allocation.<service> when empty
+}
+
+// Validate validates the allocation configuration
+func (c *AllocationConfig) Validate() error {
+	// Allocations are checked against the capital without replaying the journal
+	if _, err := allocation.Open(c.Allocations, allocation.Options{Currency: c.Currency, Capital: c.Capital}); err != nil {
+		return fmt.Errorf("invalid allocations: %w", err)
+	}
+	for portfolio, capital := range c.Capital {
+		if err := pms.ValidateID(portfolio); err != nil {
+			return fmt.Errorf("invalid allocations.capital: %w", err)
+		}
+		if capital < 0 {
+			return fmt.Errorf("allocations.capital of %s cannot be negative", portfolio)
+		}
+	}
+	if c.Subject != "" {
+		if err := eventbus.ValidatePattern(c.Subject); err != nil {
+			return fmt.Errorf("invalid allocations.subject: %w", err)
+		}
+	}
+	return nil
+}
+
+// RPCSubject returns the prefix the allocation RPCs of a service are served under
+func (c *AllocationConfig) RPCSubject(service string) string {
+	if c.Subject == "" {
+		return allocation.DefaultSubject + "." + service
+	}
+	return c.Subject
+}
+
+// Book opens the allocations, replaying the journal, with positions and
+// market orders valued at prices
+func (c *AllocationConfig) Book(prices pms.Converter) (*allocation.Book, error) {
+	return allocation.Open(c.Allocations, allocation.Options{
+		Currency: c.Currency,
+		Capital:  c.Capital,
+		Prices:   prices,
+		Journal:  c.Journal,
+	})
+}
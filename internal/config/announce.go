@@ -0,0 +1,92 @@
+package config
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/BullionBear/sequex/internal/announce"
+)
+
+// AnnounceConfig represents the configuration of the announcement collector
+type AnnounceConfig struct {
+	BaseURL     string            `json:"base_url"`    // Binance announcement site, https://www.binance.com when omitted
+	Catalogs    map[string]string `json:"catalogs"`    // Kind of the articles of each catalog id, the listing, delisting and maintenance catalogs when omitted
+	PageSize    int               `json:"page_size"`   // Latest articles polled per catalog, 20 when omitted
+	Status      bool              `json:"status"`      // Announce when the exchange enters or leaves system maintenance
+	IntervalMs  int64             `json:"interval_ms"` // Interval between polls, 1 minute when omitted
+	Diagnostics DiagnosticsConfig `json:"diagnostics"` // Runtime diagnostics endpoints
+	NATS        NATSConfig        `json:"nats"`        // Stream and subject prefix the announcements are published to
+}
+
+// LoadAnnounceConfig loads the announcement collector configuration from a JSON file
+func LoadAnnounceConfig(filePath string) (*AnnounceConfig, error) {
+	if filePath == "" {
+		return nil, fmt.Errorf("config file path cannot be empty")
+	}
+
+	data, err := ReadConfig(filePath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read config file %s: %w", filePath, err)
+	}
+
+	var config AnnounceConfig
+	if err := json.Unmarshal(data, &config); err != nil {
+		return nil, fmt.Errorf("failed to parse config file %s: %w", filePath, err)
+	}
+
+	if err := config.Validate(); err != nil {
+		return nil, fmt.Errorf("invalid configuration in %s: %w", filePath, err)
+	}
+
+	return &config, nil
+}
+
+// Validate validates the announcement collector configuration
+func (c *AnnounceConfig) Validate() error {
+	if _, err := c.BinanceOptions(); err != nil {
+		return err
+	}
+
+	if c.PageSize < 0 || c.IntervalMs < 0 {
+		return fmt.Errorf("page_size and interval_ms cannot be negative")
+	}
+
+	if err := c.Diagnostics.Validate(); err != nil {
+		return err
+	}
+
+	return c.NATS.Validate()
+}
+
+// BinanceOptions converts the configuration into the options of the
+// Binance announcement source
+func (c *AnnounceConfig) BinanceOptions() (announce.BinanceOptions, error) {
+	opts := announce.BinanceOptions{BaseURL: c.BaseURL, PageSize: c.PageSize}
+	if len(c.Catalogs) == 0 {
+		return opts, nil
+	}
+	opts.Catalogs = make(map[int]announce.Kind, len(c.Catalogs))
+	for id, kind := range c.Catalogs {
+		catalog, err := strconv.Atoi(id)
+		if err != nil || catalog <= 0 {
+			return opts, fmt.Errorf("catalogs: invalid catalog id %q", id)
+		}
+		switch k := announce.Kind(kind); k {
+		case announce.KindListing, announce.KindDelisting, announce.KindMaintenance, announce.KindOther:
+			opts.Catalogs[catalog] = k
+		default:
+			return opts, fmt.Errorf("catalogs: catalog %s must be listing, delisting, maintenance or other, got %q", id, kind)
+		}
+	}
+	return opts, nil
+}
+
+// Interval returns the interval between polls
+func (c *AnnounceConfig) Interval() time.Duration {
+	if c.IntervalMs == 0 {
+		return time.Minute
+	}
+	return time.Duration(c.IntervalMs) * time.Millisecond
+}
@@ -0,0 +1,79 @@
+package config
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/BullionBear/sequex/internal/backtest"
+)
+
+// BacktestConfig represents the configuration of the backtest job service
+type BacktestConfig struct {
+	Host        string            `json:"host"`
+	Port        int               `json:"port"`        // REST API
+	Dir         string            `json:"dir"`         // Jobs and their artifacts are kept in
+	DataDir     string            `json:"data_dir"`    // Recordings backtests can replay
+	Workers     int               `json:"workers"`     // Jobs run at once, 1 when omitted
+	Parallel    int               `json:"parallel"`    // Backtests of a sweep run at once, one per CPU when omitted
+	CacheMB     int64             `json:"cache_mb"`    // Market data shared by the backtests, unbounded when omitted
+	MaxQueued   int               `json:"max_queued"`  // Jobs waiting to run, 100 when omitted
+	RPCSubject  string            `json:"rpc_subject"` // Prefix the job RPCs are served under, backtest when omitted
+	Diagnostics DiagnosticsConfig `json:"diagnostics"` // Runtime diagnostics endpoints
+	NATS        NATSConfig        `json:"nats"`        // Stream and subject the changes of status of jobs are published to
+}
+
+// LoadBacktestConfig loads the backtest job service configuration from a JSON file
+func LoadBacktestConfig(filePath string) (*BacktestConfig, error) {
+	if filePath == "" {
+		return nil, fmt.Errorf("config file path cannot be empty")
+	}
+
+	data, err := ReadConfig(filePath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read config file %s: %w", filePath, err)
+	}
+
+	var config BacktestConfig
+	if err := json.Unmarshal(data, &config); err != nil {
+		return nil, fmt.Errorf("failed to parse config file %s: %w", filePath, err)
+	}
+
+	if err := config.Validate(); err != nil {
+		return nil, fmt.Errorf("invalid configuration in %s: %w", filePath, err)
+	}
+
+	return &config, nil
+}
+
+// Validate validates the backtest job service configuration
+func (c *BacktestConfig) Validate() error {
+	if c.Port <= 0 || c.Port > 65535 {
+		return fmt.Errorf("port must be between 1 and 65535, got %d", c.Port)
+	}
+
+	if c.Dir == "" {
+		return fmt.Errorf("dir cannot be empty")
+	}
+
+	if c.Workers < 0 || c.Parallel < 0 || c.CacheMB < 0 || c.MaxQueued < 0 {
+		return fmt.Errorf("workers, parallel, cache_mb and max_queued cannot be negative")
+	}
+
+	if err := c.Diagnostics.Validate(); err != nil {
+		return err
+	}
+
+	return c.NATS.Validate()
+}
+
+// Options converts the configuration into job manager options
+func (c *BacktestConfig) Options() backtest.Options {
+	return backtest.Options{
+		Dir:       c.Dir,
+		DataDir:   c.DataDir,
+		Workers:   c.Workers,
+		Parallel:  c.Parallel,
+		CacheSize: c.CacheMB << 20,
+		MaxQueued: c.MaxQueued,
+	}
+}
@@ -0,0 +1,167 @@
+package config
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/BullionBear/sequex/internal/basis"
+	"github.com/BullionBear/sequex/internal/model/sqx"
+	"github.com/BullionBear/sequex/pkg/eventbus"
+)
+
+// CarryRuleConfig configures the cash-and-carry thresholds of one underlying
+type CarryRuleConfig struct {
+	Symbol               string  `json:"symbol"`                 // Underlying, e.g. BTC-USDT
+	EntryAPR             float64 `json:"entry_apr"`              // Annualized yield that enters the carry, e.g. 0.15
+	ExitAPR              float64 `json:"exit_apr"`               // Annualized yield that exits the carry, below entry_apr
+	MinFundingRate       float64 `json:"min_funding_rate"`       // Funding rate per interval required to enter
+	RoundTripFee         float64 `json:"round_trip_fee"`         // Fees of entering and exiting both legs, as a fraction of notional
+	HoldingHours         float64 `json:"holding_hours"`          // Holding period basis and fees are amortized over, 168 when omitted
+	FundingIntervalHours float64 `json:"funding_interval_hours"` // Hours between fundings, 8 when omitted
+}
+
+// MarkPollConfig polls Binance USDⓈ-M mark prices and funding rates
+type MarkPollConfig struct {
+	Symbols    []string `json:"symbols"`     // e.g. BTC-USDT, empty disables polling
+	IntervalMs int64    `json:"interval_ms"` // Polling interval
+	BaseURL    string   `json:"base_url"`    // Defaults to the mainnet REST endpoint
+}
+
+// BasisConfig represents the configuration of the spot-perp basis node
+type BasisConfig struct {
+	Spot         []string           `json:"spot"`       // Spot trade subjects
+	Marks        []string           `json:"marks"`      // Perp mark price subjects
+	Poll         MarkPollConfig     `json:"poll"`       // Mark prices fetched by the node itself
+	MaxAgeMs     int64              `json:"max_age_ms"` // Legs older than this are not joined, 0 keeps them forever
+	Rules        []CarryRuleConfig  `json:"rules"`
+	Dispatch     DispatchConfigs    `json:"dispatch,omitempty"` // Per-subject handler concurrency
+	Recovery     RecoveryConfig     `json:"recovery"`           // Quarantine of subjects that keep crashing the handlers
+	Limits       LimitsConfig       `json:"limits"`             // Resource guards throttling the intake
+	FeatureFlags FeatureFlagsConfig `json:"feature_flags"`      // Gated behaviors being rolled out
+	Diagnostics  DiagnosticsConfig  `json:"diagnostics"`        // Runtime diagnostics endpoints
+	NATS         NATSConfig         `json:"nats"`               // Stream and subject the signals are published to
+}
+
+// LoadBasisConfig loads the basis node configuration from a JSON or YAML file
+func LoadBasisConfig(filePath string) (*BasisConfig, error) {
+	if filePath == "" {
+		return nil, fmt.Errorf("config file path cannot be empty")
+	}
+
+	data, err := ReadConfig(filePath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read config file %s: %w", filePath, err)
+	}
+
+	config, err := ParseBasisConfig(data)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", filePath, err)
+	}
+
+	return config, nil
+}
+
+// ParseBasisConfig decodes and validates a basis node configuration, as
+// loaded or reloaded from its bucket
+func ParseBasisConfig(data []byte) (*BasisConfig, error) {
+	var config BasisConfig
+	if err := UnmarshalYAML(data, &config); err != nil {
+		return nil, fmt.Errorf("failed to parse configuration: %w", err)
+	}
+
+	if err := config.Validate(); err != nil {
+		return nil, fmt.Errorf("invalid configuration: %w", err)
+	}
+
+	return &config, nil
+}
+
+// Static returns the configuration without the settings reloaded at
+// runtime, the rules and max_age_ms, so that changing anything else can be
+// told apart as requiring a restart
+func (c BasisConfig) Static() BasisConfig {
+	c.Rules, c.MaxAgeMs = nil, 0
+	return c
+}
+
+// Validate validates the basis node configuration
+func (c *BasisConfig) Validate() error {
+	if len(c.Spot) == 0 {
+		return fmt.Errorf("spot cannot be empty")
+	}
+
+	if len(c.Marks) == 0 && len(c.Poll.Symbols) == 0 {
+		return fmt.Errorf("marks and poll.symbols cannot both be empty")
+	}
+
+	if len(c.Rules) == 0 {
+		return fmt.Errorf("rules cannot be empty")
+	}
+
+	for i, subject := range c.Spot {
+		if err := eventbus.ValidatePattern(subject); err != nil {
+			return fmt.Errorf("invalid spot[%d]: %w", i, err)
+		}
+	}
+	for i, subject := range c.Marks {
+		if err := eventbus.ValidatePattern(subject); err != nil {
+			return fmt.Errorf("invalid marks[%d]: %w", i, err)
+		}
+	}
+
+	for i, symbol := range c.Poll.Symbols {
+		if _, err := sqx.NewSymbolFromStr(symbol); err != nil {
+			return fmt.Errorf("invalid poll.symbols[%d]: %w", i, err)
+		}
+	}
+	if len(c.Poll.Symbols) > 0 && c.Poll.IntervalMs <= 0 {
+		return fmt.Errorf("poll.interval_ms must be positive")
+	}
+
+	if c.MaxAgeMs < 0 {
+		return fmt.Errorf("max_age_ms cannot be negative")
+	}
+
+	if _, err := basis.New(c.Options()); err != nil {
+		return err
+	}
+
+	if err := c.Dispatch.Validate(); err != nil {
+		return err
+	}
+
+	if err := c.Recovery.Validate(); err != nil {
+		return err
+	}
+
+	if err := c.Limits.Validate(); err != nil {
+		return err
+	}
+
+	if err := c.FeatureFlags.Validate(); err != nil {
+		return err
+	}
+
+	if err := c.Diagnostics.Validate(); err != nil {
+		return err
+	}
+
+	return c.NATS.Validate()
+}
+
+// Options converts the rules into basis monitor options
+func (c *BasisConfig) Options() basis.Options {
+	opts := basis.Options{MaxAge: time.Duration(c.MaxAgeMs) * time.Millisecond}
+	for _, r := range c.Rules {
+		opts.Rules = append(opts.Rules, basis.Rule{
+			Symbol:          r.Symbol,
+			EntryAPR:        r.EntryAPR,
+			ExitAPR:         r.ExitAPR,
+			MinFundingRate:  r.MinFundingRate,
+			RoundTripFee:    r.RoundTripFee,
+			HoldingHours:    r.HoldingHours,
+			FundingInterval: r.FundingIntervalHours,
+		})
+	}
+	return opts
+}
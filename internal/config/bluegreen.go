@@ -0,0 +1,64 @@
+package config
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/BullionBear/sequex/internal/bluegreen"
+	"github.com/BullionBear/sequex/internal/pms"
+	"github.com/BullionBear/sequex/pkg/eventbus"
+)
+
+// BlueGreenConfig runs strategies as a live and a shadow instance, the
+// shadow trading on paper until promoted
+type BlueGreenConfig struct {
+	Deployments []bluegreen.Deployment `json:"deployments"`
+	Currency    string                 `json:"currency"`  // Of PnLs, USDT when empty
+	WindowMs    int64                  `json:"window_ms"` // Of the comparisons, 1 hour when 0
+	MatchMs     int64                  `json:"match_ms"`  // How far apart orders of the instances may be to be the same decision, 1 second when 0
+	Journal     string                 `json:"journal"`   // JSON lines audit trail of the promotions, keeping promoted instances live across restarts. In memory only when empty.
+	Subject     string                 `json:"subject"`   // Prefix the params RPCs promoting the instances are served under, params.<service> when empty
+}
+
+// Validate validates the blue/green configuration
+func (c *BlueGreenConfig) Validate() error {
+	if len(c.Deployments) == 0 {
+		return fmt.Errorf("blue_green.deployments cannot be empty")
+	}
+	// Deployments are checked without replaying the journal
+	if _, err := bluegreen.Open(c.Deployments, bluegreen.Options{}); err != nil {
+		return fmt.Errorf("invalid blue_green.deployments: %w", err)
+	}
+	if c.WindowMs < 0 {
+		return fmt.Errorf("blue_green.window_ms cannot be negative")
+	}
+	if c.MatchMs < 0 {
+		return fmt.Errorf("blue_green.match_ms cannot be negative")
+	}
+	if c.Subject != "" {
+		if err := eventbus.ValidatePattern(c.Subject); err != nil {
+			return fmt.Errorf("invalid blue_green.subject: %w", err)
+		}
+	}
+	return nil
+}
+
+// RPCSubject returns the prefix the params RPCs of a service are served under
+func (c *BlueGreenConfig) RPCSubject(service string) string {
+	if c.Subject == "" {
+		return bluegreen.DefaultSubject + "." + service
+	}
+	return c.Subject
+}
+
+// Open opens the deployments, replaying the journal, with positions valued
+// at prices
+func (c *BlueGreenConfig) Open(prices pms.Converter) (*bluegreen.Deployments, error) {
+	return bluegreen.Open(c.Deployments, bluegreen.Options{
+		Currency: c.Currency,
+		Window:   time.Duration(c.WindowMs) * time.Millisecond,
+		Match:    time.Duration(c.MatchMs) * time.Millisecond,
+		Prices:   prices,
+		Journal:  c.Journal,
+	})
+}
@@ -0,0 +1,116 @@
+package config
+
+import (
+	"crypto/ed25519"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/BullionBear/sequex/pkg/eventbus"
+	"github.com/BullionBear/sequex/pkg/logger"
+	"github.com/nats-io/nats.go"
+)
+
+// OperatorConfig is an operator who may sign commands
+type OperatorConfig struct {
+	Name      string   `json:"name"`
+	PublicKey string   `json:"public_key"` // Base64 ed25519 public key, see sqx ctx keygen
+	Roles     []string `json:"roles"`
+}
+
+// GrantConfig allows calling endpoints on nodes, see eventbus.Grant
+type GrantConfig struct {
+	Endpoints []string `json:"endpoints"` // Subject patterns, e.g. guardian.*.pause
+	Nodes     []string `json:"nodes"`     // Names or shell patterns, * for every node
+}
+
+// CommandsConfig represents the policy of the signed commands: who may call
+// which command endpoints on which nodes. It is shared by the nodes, each
+// referencing it from nats.commands.
+type CommandsConfig struct {
+	Operators []OperatorConfig         `json:"operators"`
+	Roles     map[string][]GrantConfig `json:"roles"`      // Grants by role name
+	MaxTTLMs  int64                    `json:"max_ttl_ms"` // Longest validity of a command accepted, 5 minutes when 0
+}
+
+// LoadCommandsConfig loads the command policy from a JSON file, or from its
+// bucket on conn, see ReadConfigConn
+func LoadCommandsConfig(conn *nats.Conn, filePath string) (*CommandsConfig, error) {
+	if filePath == "" {
+		return nil, fmt.Errorf("config file path cannot be empty")
+	}
+
+	data, err := ReadConfigConn(conn, filePath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read config file %s: %w", filePath, err)
+	}
+
+	var config CommandsConfig
+	if err := json.Unmarshal(data, &config); err != nil {
+		return nil, fmt.Errorf("failed to parse config file %s: %w", filePath, err)
+	}
+
+	if err := config.Validate(); err != nil {
+		return nil, fmt.Errorf("invalid configuration in %s: %w", filePath, err)
+	}
+
+	return &config, nil
+}
+
+// Validate validates the command policy
+func (c *CommandsConfig) Validate() error {
+	_, err := c.Policy()
+	return err
+}
+
+// Policy converts the configuration into an event bus command policy
+func (c *CommandsConfig) Policy() (eventbus.CommandPolicy, error) {
+	if c.MaxTTLMs < 0 {
+		return eventbus.CommandPolicy{}, fmt.Errorf("max_ttl_ms cannot be negative")
+	}
+	policy := eventbus.CommandPolicy{
+		Roles:  make(map[string][]eventbus.Grant, len(c.Roles)),
+		MaxTTL: time.Duration(c.MaxTTLMs) * time.Millisecond,
+	}
+	for i, o := range c.Operators {
+		key, err := base64.StdEncoding.DecodeString(o.PublicKey)
+		if err != nil || len(key) != ed25519.PublicKeySize {
+			return policy, fmt.Errorf("invalid operators[%d].public_key: expected a base64 ed25519 public key", i)
+		}
+		policy.Operators = append(policy.Operators, eventbus.Operator{Name: o.Name, Key: ed25519.PublicKey(key), Roles: o.Roles})
+	}
+	for role, grants := range c.Roles {
+		for _, g := range grants {
+			policy.Roles[role] = append(policy.Roles[role], eventbus.Grant{Endpoints: g.Endpoints, Nodes: g.Nodes})
+		}
+	}
+	return policy, policy.Validate()
+}
+
+// Authorizer returns the authorizer of the commands served by node, nil
+// when nats.commands is omitted, which serves them to anyone. A kv: policy
+// is read on conn.
+func (n *NATSConfig) Authorizer(conn *nats.Conn, node string) (*eventbus.Authorizer, error) {
+	if n.Commands == "" {
+		return nil, nil
+	}
+	c, err := LoadCommandsConfig(conn, n.Commands)
+	if err != nil {
+		return nil, fmt.Errorf("nats.commands: %w", err)
+	}
+	policy, err := c.Policy()
+	if err != nil {
+		return nil, err
+	}
+	return eventbus.NewAuthorizer(node, policy)
+}
+
+// logCommand logs the commands served by the buses, refused or not
+func logCommand(event eventbus.CommandEvent) {
+	log := logger.Log.Info()
+	if event.Err != nil {
+		log = logger.Log.Warn().Err(event.Err)
+	}
+	log.Str("node", event.Node).Str("subject", event.Subject).Str("operator", event.Operator).Bool("replayed", event.Replayed).Msg("Command")
+}
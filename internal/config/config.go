@@ -4,39 +4,146 @@ import (
 	"encoding/json"
 	"fmt"
 	"net/url"
-	"os"
 	"strings"
+	"time"
+
+	"github.com/BullionBear/sequex/pkg/eventbus"
+	"github.com/BullionBear/sequex/pkg/exchange/transport"
+	"github.com/BullionBear/sequex/pkg/logger"
+	"github.com/nats-io/nats.go"
+	"gopkg.in/yaml.v3"
 )
 
 // NATSConfig represents NATS connection configuration
 type NATSConfig struct {
-	URIs    string `json:"uris"`
-	Stream  string `json:"stream"`
-	Subject string `json:"subject"`
+	URIs        string              `json:"uris"`
+	Stream      string              `json:"stream"`
+	Subject     string              `json:"subject"`
+	Compression []CompressionConfig `json:"compression,omitempty"` // Per-subject payload compression
+	RPC         []RPCPolicyConfig   `json:"rpc,omitempty"`         // Per-endpoint timeout, retry and circuit breaker policy
+	// Dedicated connection for order entry and risk subjects, so they never
+	// queue behind market data
+	PriorityLane     bool     `json:"priority_lane,omitempty"`
+	PrioritySubjects []string `json:"priority_subjects,omitempty"` // Subject patterns of the lane, oms.> and risk.> of the deployment and its tenants when omitted
+	// Subscriptions switched to JetStream when the server drops messages
+	// for them, for subjects where loss is unacceptable
+	Lossless []LosslessConfig `json:"lossless,omitempty"`
+	// Policy file of the signed commands, see CommandsConfig. Command
+	// endpoints are served to anyone with NATS access when omitted.
+	Commands string `json:"commands,omitempty"`
+}
+
+// LosslessConfig switches the slow subscriptions of matching subjects to the
+// stream storing them, see eventbus.LosslessRule
+type LosslessConfig struct {
+	Subject    string `json:"subject"`     // Subject pattern, wildcards allowed
+	Stream     string `json:"stream"`      // Stream storing the subjects
+	LookbackMs int64  `json:"lookback_ms"` // Replayed before the last message handled, 1s when omitted
+}
+
+// CompressionConfig enables payload compression on matching subjects
+type CompressionConfig struct {
+	Subject string `json:"subject"`  // Subject pattern, wildcards allowed
+	Codec   string `json:"codec"`    // snappy or zstd
+	MinSize int    `json:"min_size"` // Payloads below this size are left uncompressed
+}
+
+// RPCPolicyConfig bounds the RPC calls to matching subjects, see
+// eventbus.RPCPolicy
+type RPCPolicyConfig struct {
+	Subject       string `json:"subject"`        // Subject pattern, wildcards allowed
+	TimeoutMs     int64  `json:"timeout_ms"`     // Of each attempt, 0 for the caller deadline
+	Retries       int    `json:"retries"`        // Attempts after a timeout or no responders
+	BackoffMs     int64  `json:"backoff_ms"`     // Wait before the first retry, doubled before each next one
+	MaxBackoffMs  int64  `json:"max_backoff_ms"` // Cap of the backoff, 0 for none
+	BreakAfter    int    `json:"break_after"`    // Consecutive failures opening the circuit, 0 disables it
+	BreakForMs    int64  `json:"break_for_ms"`   // Calls fail fast while the circuit is open
+	MaxConcurrent int    `json:"max_concurrent"` // Calls in flight, 0 for no limit
+}
+
+// Policy converts the configuration into an event bus RPC policy
+func (r *RPCPolicyConfig) Policy() eventbus.RPCPolicy {
+	ms := func(v int64) time.Duration { return time.Duration(v) * time.Millisecond }
+	return eventbus.RPCPolicy{
+		Subject:       r.Subject,
+		Timeout:       ms(r.TimeoutMs),
+		Retries:       r.Retries,
+		Backoff:       ms(r.BackoffMs),
+		MaxBackoff:    ms(r.MaxBackoffMs),
+		BreakAfter:    r.BreakAfter,
+		BreakFor:      ms(r.BreakForMs),
+		MaxConcurrent: r.MaxConcurrent,
+	}
 }
 
 // Config represents the main configuration structure
 type Config struct {
-	Exchange   string     `json:"exchange"`
-	Instrument string     `json:"instrument"`
-	Symbol     string     `json:"symbol"`
-	Type       string     `json:"type"`
-	NATS       NATSConfig `json:"nats"`
+	Exchange        string            `json:"exchange"`
+	Instrument      string            `json:"instrument"`
+	Symbol          string            `json:"symbol"`          // Symbol streamed, unless sharded
+	Shard           *ShardConfig      `json:"shard,omitempty"` // Symbols split across instances
+	Type            string            `json:"type"`
+	BatchSize       int               `json:"batch_size,omitempty"`        // Trades per published batch, 0 or 1 publishes every trade
+	BatchIntervalMs int64             `json:"batch_interval_ms,omitempty"` // Maximum time a trade waits in a partial batch
+	Queue           QueueConfig       `json:"queue"`                       // Buffer between the adapter and the publisher
+	Dedup           DedupConfig       `json:"dedup"`                       // Persistent trade deduplication
+	Lifecycle       *LifecycleConfig  `json:"lifecycle,omitempty"`         // Parks the symbols the exchange stops trading
+	Chaos           transport.Chaos   `json:"chaos"`                       // Faults injected into the exchange clients, staging builds only
+	Diagnostics     DiagnosticsConfig `json:"diagnostics"`                 // Runtime diagnostics endpoints
+	NATS            NATSConfig        `json:"nats"`
+}
+
+// QueueConfig configures the bounded queue between adapter callbacks and the publisher
+type QueueConfig struct {
+	Size            int    `json:"size"`              // Queue capacity, 0 publishes from the adapter callback
+	Overflow        string `json:"overflow"`          // block, drop_oldest or drop_newest
+	HighWatermark   int    `json:"high_watermark"`    // Depth that triggers an alert, 0 disables it
+	StatsIntervalMs int64  `json:"stats_interval_ms"` // Interval of queue metrics logging, 0 disables it
+}
+
+// DedupConfig configures the persistent deduplication of trades by exchange
+// trade id, around the publish to JetStream behind the queue and batches
+type DedupConfig struct {
+	Path           string `json:"path"`             // File the ids are kept in, empty disables deduplication
+	Window         int    `json:"window"`           // Runs of consecutive trade ids remembered per instrument, 100000 when omitted
+	SyncIntervalMs int64  `json:"sync_interval_ms"` // Interval the ids are flushed to disk at, 1000 when omitted
 }
 
-// LoadConfig loads configuration from a JSON file
+// LifecycleConfig follows the trading status of the symbols streamed in the
+// exchange metadata, parking their streams while they are not trading
+type LifecycleConfig struct {
+	IntervalMs int64  `json:"interval_ms"` // Interval between status checks, 60000 when omitted
+	Subject    string `json:"subject"`     // Subject of status change events, none when empty
+}
+
+// UnmarshalYAML decodes a YAML or JSON configuration into v. It decodes
+// through JSON so that YAML configurations share the json tags of the JSON
+// ones.
+func UnmarshalYAML(data []byte, v any) error {
+	var doc any
+	if err := yaml.Unmarshal(data, &doc); err != nil {
+		return err
+	}
+	data, err := json.Marshal(doc)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(data, v)
+}
+
+// LoadConfig loads configuration from a JSON or YAML file
 func LoadConfig(filePath string) (*Config, error) {
 	if filePath == "" {
 		return nil, fmt.Errorf("config file path cannot be empty")
 	}
 
-	data, err := os.ReadFile(filePath)
+	data, err := ReadConfig(filePath)
 	if err != nil {
 		return nil, fmt.Errorf("failed to read config file %s: %w", filePath, err)
 	}
 
 	var config Config
-	if err := json.Unmarshal(data, &config); err != nil {
+	if err := UnmarshalYAML(data, &config); err != nil {
 		return nil, fmt.Errorf("failed to parse config file %s: %w", filePath, err)
 	}
 
@@ -58,7 +165,14 @@ func (c *Config) Validate() error {
 		return fmt.Errorf("instrument cannot be empty")
 	}
 
-	if c.Symbol == "" {
+	if c.Shard != nil {
+		if err := c.Shard.Validate(); err != nil {
+			return err
+		}
+		if c.BatchSize > 1 {
+			return fmt.Errorf("batch_size cannot be used with shard, batches are published on one subject")
+		}
+	} else if c.Symbol == "" {
 		return fmt.Errorf("symbol cannot be empty")
 	}
 
@@ -66,10 +180,102 @@ func (c *Config) Validate() error {
 		return fmt.Errorf("type cannot be empty")
 	}
 
+	if c.BatchSize < 0 {
+		return fmt.Errorf("batch_size cannot be negative")
+	}
+
+	if c.BatchSize > 1 && c.BatchIntervalMs <= 0 {
+		return fmt.Errorf("batch_interval_ms must be positive when batching")
+	}
+
+	if err := c.Queue.Validate(); err != nil {
+		return err
+	}
+
+	if err := c.Dedup.Validate(); err != nil {
+		return err
+	}
+
+	if c.Lifecycle != nil {
+		if err := c.Lifecycle.Validate(); err != nil {
+			return err
+		}
+	}
+
+	if err := c.Chaos.Validate(); err != nil {
+		return err
+	}
+
+	if err := c.Diagnostics.Validate(); err != nil {
+		return err
+	}
+
 	// Validate NATS configuration
 	return c.NATS.Validate()
 }
 
+// Validate validates the queue configuration
+func (q *QueueConfig) Validate() error {
+	if q.Size < 0 {
+		return fmt.Errorf("queue.size cannot be negative")
+	}
+	switch q.Overflow {
+	case "", "block", "drop_oldest", "drop_newest":
+	default:
+		return fmt.Errorf("queue.overflow must be block, drop_oldest or drop_newest")
+	}
+	if q.HighWatermark < 0 || q.HighWatermark > q.Size {
+		return fmt.Errorf("queue.high_watermark must be between 0 and queue.size")
+	}
+	if q.StatsIntervalMs < 0 {
+		return fmt.Errorf("queue.stats_interval_ms cannot be negative")
+	}
+	return nil
+}
+
+// Validate validates the dedup configuration
+func (d *DedupConfig) Validate() error {
+	if d.Window < 0 {
+		return fmt.Errorf("dedup.window cannot be negative")
+	}
+	if d.SyncIntervalMs < 0 {
+		return fmt.Errorf("dedup.sync_interval_ms cannot be negative")
+	}
+	return nil
+}
+
+// SyncInterval returns the interval the ids are flushed to disk at
+func (d *DedupConfig) SyncInterval() time.Duration {
+	if d.SyncIntervalMs == 0 {
+		return time.Second
+	}
+	return time.Duration(d.SyncIntervalMs) * time.Millisecond
+}
+
+// Validate validates the lifecycle configuration
+func (l *LifecycleConfig) Validate() error {
+	if l.IntervalMs < 0 {
+		return fmt.Errorf("lifecycle.interval_ms cannot be negative")
+	}
+	if l.Subject != "" {
+		if err := eventbus.ValidatePattern(l.Subject); err != nil {
+			return fmt.Errorf("invalid lifecycle.subject: %w", err)
+		}
+		if strings.ContainsAny(l.Subject, "*>") {
+			return fmt.Errorf("lifecycle.subject cannot contain wildcards")
+		}
+	}
+	return nil
+}
+
+// Interval returns the interval between status checks
+func (l *LifecycleConfig) Interval() time.Duration {
+	if l.IntervalMs == 0 {
+		return time.Minute
+	}
+	return time.Duration(l.IntervalMs) * time.Millisecond
+}
+
 // Validate validates the NATS configuration
 func (n *NATSConfig) Validate() error {
 	if n.URIs == "" {
@@ -84,7 +290,15 @@ func (n *NATSConfig) Validate() error {
 		return fmt.Errorf("nats.subject cannot be empty")
 	}
 
-	// Validate that URIs are valid NATS URLs
+	return n.ValidateURIs()
+}
+
+// ValidateURIs validates that URIs are valid NATS URLs
+func (n *NATSConfig) ValidateURIs() error {
+	if n.URIs == "" {
+		return fmt.Errorf("nats.uris cannot be empty")
+	}
+
 	uris := strings.Split(n.URIs, ",")
 	for i, uri := range uris {
 		uri = strings.TrimSpace(uri)
@@ -109,6 +323,69 @@ func (n *NATSConfig) Validate() error {
 	return nil
 }
 
+// BusOptions converts the NATS configuration into event bus options
+func (n *NATSConfig) BusOptions() (eventbus.Options, error) {
+	var opts eventbus.Options
+	for i, c := range n.Compression {
+		codec, err := eventbus.ParseCodec(c.Codec)
+		if err != nil {
+			return eventbus.Options{}, fmt.Errorf("nats.compression[%d]: %w", i, err)
+		}
+		opts.Compression = append(opts.Compression, eventbus.CompressionRule{
+			Subject: c.Subject,
+			Codec:   codec,
+			MinSize: c.MinSize,
+		})
+	}
+	for i, pattern := range n.PrioritySubjects {
+		if err := eventbus.ValidatePattern(pattern); err != nil {
+			return eventbus.Options{}, fmt.Errorf("nats.priority_subjects[%d]: %w", i, err)
+		}
+	}
+	opts.Priority = n.PrioritySubjects
+	for i, c := range n.Lossless {
+		rule := eventbus.LosslessRule{Subject: c.Subject, Stream: c.Stream, Lookback: time.Duration(c.LookbackMs) * time.Millisecond}
+		if err := rule.Validate(); err != nil {
+			return eventbus.Options{}, fmt.Errorf("nats.lossless[%d]: %w", i, err)
+		}
+		opts.Lossless = append(opts.Lossless, rule)
+	}
+	opts.OnSlowConsumer = logSlowConsumer
+	opts.OnCommand = logCommand
+	for i := range n.RPC {
+		policy := n.RPC[i].Policy()
+		if err := policy.Validate(); err != nil {
+			return eventbus.Options{}, fmt.Errorf("nats.rpc[%d]: %w", i, err)
+		}
+		opts.RPC = append(opts.RPC, policy)
+	}
+	return opts, nil
+}
+
+// logSlowConsumer logs the slow consumer reports of the buses
+func logSlowConsumer(event eventbus.SlowConsumerEvent) {
+	log := logger.Log.Warn()
+	if event.Err != nil {
+		log = logger.Log.Error().Err(event.Err)
+	}
+	log.Str("subject", event.Subject).Str("queue", event.Queue).Int("dropped", event.Dropped).Int("pending", event.Pending).
+		Bool("switched", event.Switched).Msg("Slow consumer, messages dropped")
+}
+
+// ConnectPriorityLane opens the priority lane connection of bus when
+// enabled, returning nil otherwise
+func (n *NATSConfig) ConnectPriorityLane(bus *eventbus.Bus) (*nats.Conn, error) {
+	if !n.PriorityLane {
+		return nil, nil
+	}
+	conn, err := nats.Connect(n.URIs, nats.Name("priority-lane"))
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect the priority lane: %w", err)
+	}
+	bus.AttachPriorityLane(conn)
+	return conn, nil
+}
+
 // GetNATSURIs returns the NATS URIs
 func (n *NATSConfig) GetNATSURIs() []string {
 	return strings.Split(n.URIs, ",")
@@ -1,9 +1,14 @@
 package config
 
 import (
+	"crypto/ed25519"
+	"encoding/base64"
 	"os"
 	"strings"
 	"testing"
+
+	"github.com/BullionBear/sequex/internal/bluegreen"
+	"github.com/BullionBear/sequex/internal/tenant"
 )
 
 func TestLoadConfig(t *testing.T) {
@@ -352,6 +357,23 @@ func TestConfig_Validate(t *testing.T) {
 			expectError: true,
 			errorMsg:    "nats.uris cannot be empty",
 		},
+		{
+			name: "wildcard lifecycle subject",
+			config: &Config{
+				Exchange:   "binance",
+				Instrument: "spot",
+				Symbol:     "BTC-USDT",
+				Type:       "trade",
+				Lifecycle:  &LifecycleConfig{Subject: "feed.lifecycle.>"},
+				NATS: NATSConfig{
+					URIs:    "nats://localhost:4222",
+					Stream:  "TRADE",
+					Subject: "test",
+				},
+			},
+			expectError: true,
+			errorMsg:    "lifecycle.subject cannot contain wildcards",
+		},
 	}
 
 	for _, tt := range tests {
@@ -484,3 +506,425 @@ func TestNATSConfig_Validate(t *testing.T) {
 		})
 	}
 }
+
+func TestShardConfig_Validate(t *testing.T) {
+	base := func(shard ShardConfig) ShardConfig {
+		shard.SubjectTemplate = "trade.{exchange}.{instrument}.{symbol}"
+		return shard
+	}
+	tests := []struct {
+		name     string
+		shard    ShardConfig
+		errorMsg string
+	}{
+		{name: "hash", shard: base(ShardConfig{Mode: "hash", Symbols: []string{"BTC-USDT", "ETH-USDT"}, Index: 1, Count: 2})},
+		{name: "list", shard: base(ShardConfig{Mode: "list", Member: "a", Lists: map[string][]string{"a": {"BTC-USDT"}, "b": {"ETH-USDT"}}})},
+		{name: "dynamic", shard: base(ShardConfig{Mode: "dynamic", Symbols: []string{"BTC-USDT"}, Bucket: "FEED_SHARDS"})},
+		{name: "index out of range", shard: base(ShardConfig{Mode: "hash", Symbols: []string{"BTC-USDT"}, Index: 2, Count: 2}), errorMsg: "shard.index"},
+		{name: "no list for member", shard: base(ShardConfig{Mode: "list", Member: "c", Lists: map[string][]string{"a": {"BTC-USDT"}}}), errorMsg: "no list for member"},
+		{name: "symbol in two lists", shard: base(ShardConfig{Mode: "list", Member: "a", Lists: map[string][]string{"a": {"BTC-USDT"}, "b": {"BTC-USDT"}}}), errorMsg: "listed twice"},
+		{name: "dynamic without bucket", shard: base(ShardConfig{Mode: "dynamic", Symbols: []string{"BTC-USDT"}}), errorMsg: "shard.bucket"},
+		{name: "invalid symbol", shard: base(ShardConfig{Mode: "hash", Symbols: []string{"BTCUSDT"}, Count: 1}), errorMsg: "invalid shard symbol"},
+		{name: "unknown placeholder", shard: ShardConfig{Mode: "hash", Symbols: []string{"BTC-USDT"}, Count: 1, SubjectTemplate: "trade.{pair}"}, errorMsg: "subject_template"},
+		{name: "discovery", shard: base(ShardConfig{Mode: "hash", Count: 1, Discovery: &DiscoveryConfig{Quote: "USDT", Top: 10}})},
+		{name: "discovery and symbols", shard: base(ShardConfig{Mode: "hash", Count: 1, Symbols: []string{"BTC-USDT"}, Discovery: &DiscoveryConfig{Quote: "USDT", Top: 10}}), errorMsg: "cannot both be set"},
+		{name: "discovery in list mode", shard: base(ShardConfig{Mode: "list", Member: "a", Lists: map[string][]string{"a": {"BTC-USDT"}}, Discovery: &DiscoveryConfig{Quote: "USDT", Top: 10}}), errorMsg: "not supported in list mode"},
+		{name: "discovery without top", shard: base(ShardConfig{Mode: "hash", Count: 1, Discovery: &DiscoveryConfig{Quote: "USDT"}}), errorMsg: "shard.discovery.top"},
+		{name: "wildcard events subject", shard: base(ShardConfig{Mode: "hash", Symbols: []string{"BTC-USDT"}, Count: 1, EventsSubject: "feed.>"}), errorMsg: "wildcards"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.shard.Validate()
+			if tt.errorMsg == "" {
+				if err != nil {
+					t.Errorf("unexpected error: %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.errorMsg) {
+				t.Errorf("expected error containing %q, got %v", tt.errorMsg, err)
+			}
+		})
+	}
+
+	if _, err := LoadConfig("../../config/trade-binance-spot-top.json"); err != nil {
+		t.Fatal(err)
+	}
+	config, err := LoadConfig("../../config/trade-binance-spot-shard.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	config.BatchSize = 10
+	config.BatchIntervalMs = 100
+	if err := config.Validate(); err == nil {
+		t.Error("batching accepted with shard")
+	}
+}
+
+func TestPipelineConfig_Validate(t *testing.T) {
+	node := func(name string, deps ...string) PipelineNodeConfig {
+		return PipelineNodeConfig{Name: name, Command: "./bin/" + name, DependsOn: deps}
+	}
+	tests := []struct {
+		name     string
+		nodes    []PipelineNodeConfig
+		errorMsg string
+	}{
+		{name: "empty"},
+		{name: "chain", nodes: []PipelineNodeConfig{node("marketmaker", "orderbook"), node("orderbook", "feed"), node("feed")}},
+		{name: "cycle", nodes: []PipelineNodeConfig{node("a", "b"), node("b", "a")}, errorMsg: "dependency cycle"},
+		{name: "unknown dependency", nodes: []PipelineNodeConfig{node("a", "feed")}, errorMsg: "unknown node feed"},
+		{name: "duplicate", nodes: []PipelineNodeConfig{node("feed"), node("feed")}, errorMsg: "declared twice"},
+		{name: "invalid name", nodes: []PipelineNodeConfig{node("feed.a")}, errorMsg: "pipeline.nodes[0].name"},
+		{name: "no command", nodes: []PipelineNodeConfig{{Name: "feed"}}, errorMsg: "command cannot be empty"},
+		{name: "wildcard ready subject", nodes: []PipelineNodeConfig{{Name: "feed", Command: "./bin/feed", ReadySubject: "diag.*"}}, errorMsg: "wildcards"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := (&PipelineConfig{Nodes: tt.nodes}).Validate()
+			if tt.errorMsg == "" {
+				if err != nil {
+					t.Errorf("unexpected error: %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.errorMsg) {
+				t.Errorf("expected error containing %q, got %v", tt.errorMsg, err)
+			}
+		})
+	}
+}
+
+func TestTenantRegistry(t *testing.T) {
+	t.Setenv("ACME_API_KEY", "acme-key-0123456789")
+	tests := []struct {
+		name     string
+		tenants  []TenantConfig
+		errorMsg string
+	}{
+		{name: "none"},
+		{name: "keys from the environment", tenants: []TenantConfig{{ID: "acme", KeyEnvs: []string{"ACME_API_KEY"}, Accounts: []string{"BINANCE"}}}},
+		{name: "unset key", tenants: []TenantConfig{{ID: "acme", KeyEnvs: []string{"GLOBEX_API_KEY"}}}, errorMsg: "GLOBEX_API_KEY is not set"},
+		{name: "invalid tenant", tenants: []TenantConfig{{ID: "acme", Limits: tenant.Limits{MaxOrderQuantity: -1}}}, errorMsg: "invalid tenants"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			registry, err := TenantRegistry(tt.tenants)
+			if tt.errorMsg == "" {
+				if err != nil {
+					t.Errorf("unexpected error: %v", err)
+				}
+				if len(registry.Tenants()) != len(tt.tenants) {
+					t.Errorf("registry %+v", registry)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.errorMsg) {
+				t.Errorf("expected error containing %q, got %v", tt.errorMsg, err)
+			}
+		})
+	}
+
+	t.Setenv("FIX_PASSWORD", "secret")
+	fix := &FIXConfig{
+		Addr:             "127.0.0.1:9878",
+		Sessions:         []FIXSessionConfig{{SenderCompID: "SEQUEX", TargetCompID: "CLIENT", Tenant: "globex", Username: "client", PasswordEnv: "FIX_PASSWORD"}},
+		OrderSubject:     "fix.orders",
+		CancelSubject:    "fix.cancels",
+		ExecutionSubject: "fix.executions",
+		Tenants:          []TenantConfig{{ID: "acme"}},
+		NATS:             NATSConfig{URIs: "nats://localhost:4222"},
+	}
+	if err := fix.Validate(); err == nil || !strings.Contains(err.Error(), "unknown tenant") {
+		t.Errorf("expected an unknown tenant error, got %v", err)
+	}
+}
+
+func TestFIXConfig_Validate(t *testing.T) {
+	t.Setenv("FIX_PASSWORD", "secret")
+	session := FIXSessionConfig{SenderCompID: "SEQUEX", TargetCompID: "CLIENT", Username: "client", PasswordEnv: "FIX_PASSWORD", AllowedAddrs: []string{"10.0.0.5", "192.168.0.0/24"}}
+	tests := []struct {
+		name      string
+		addr      string
+		tls       *TLSConfig
+		plaintext bool
+		session   FIXSessionConfig
+		errorMsg  string
+	}{
+		{name: "loopback", addr: "127.0.0.1:9878", session: session},
+		{name: "localhost", addr: "localhost:9878", session: session},
+		{name: "tls", addr: "0.0.0.0:9878", tls: &TLSConfig{CertFile: "fix.crt", KeyFile: "fix.key"}, session: session},
+		{name: "plaintext allowed", addr: ":9878", plaintext: true, session: session},
+		{name: "plaintext", addr: "0.0.0.0:9878", session: session, errorMsg: "not loopback"},
+		{name: "tls without key", addr: "0.0.0.0:9878", tls: &TLSConfig{CertFile: "fix.crt"}, session: session, errorMsg: "key_file"},
+		{name: "no credentials", addr: "127.0.0.1:9878", session: FIXSessionConfig{SenderCompID: "SEQUEX", TargetCompID: "CLIENT"}, errorMsg: "username and password_env"},
+		{name: "password unset", addr: "127.0.0.1:9878", session: FIXSessionConfig{SenderCompID: "SEQUEX", TargetCompID: "CLIENT", Username: "client", PasswordEnv: "FIX_UNSET"}, errorMsg: "FIX_UNSET is not set"},
+		{name: "invalid address", addr: "127.0.0.1:9878", session: FIXSessionConfig{SenderCompID: "SEQUEX", TargetCompID: "CLIENT", Username: "client", PasswordEnv: "FIX_PASSWORD", AllowedAddrs: []string{"10.0.0"}}, errorMsg: "invalid allowed address"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := (&FIXConfig{
+				Addr:             tt.addr,
+				TLS:              tt.tls,
+				Plaintext:        tt.plaintext,
+				Sessions:         []FIXSessionConfig{tt.session},
+				OrderSubject:     "fix.orders",
+				CancelSubject:    "fix.cancels",
+				ExecutionSubject: "fix.executions",
+				NATS:             NATSConfig{URIs: "nats://localhost:4222"},
+			}).Validate()
+			if tt.errorMsg == "" {
+				if err != nil {
+					t.Errorf("unexpected error: %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.errorMsg) {
+				t.Errorf("expected error containing %q, got %v", tt.errorMsg, err)
+			}
+		})
+	}
+
+	auth, err := session.LogonAuth()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if auth.Password != "secret" || len(auth.Allowed) != 2 || auth.Allowed[0].Bits() != 32 {
+		t.Errorf("logon %+v", auth)
+	}
+}
+
+func TestOMSConfig_Validate(t *testing.T) {
+	tests := []struct {
+		name      string
+		route     string
+		dryRun    *DryRunConfig
+		blueGreen *BlueGreenConfig
+		reports   string
+		errorMsg  string
+	}{
+		{name: "valid", route: "oms.route.order"},
+		{name: "dry run", route: "oms.route.order", dryRun: &DryRunConfig{Strategies: map[string]bool{"grid": true}, OrderSubject: "oms.dryrun"}},
+		{name: "dry run unpublished", route: "oms.route.order", dryRun: &DryRunConfig{Enabled: true}, errorMsg: "dry_run.order_subject"},
+		{name: "dry run invalid subject", route: "oms.route.order", dryRun: &DryRunConfig{OrderSubject: "oms.dryrun", Subject: "params.>.x"}, errorMsg: "dry_run.subject"},
+		{name: "no route", errorMsg: "route_order_subject cannot be empty"},
+		{name: "route pattern", route: "oms.route.>", errorMsg: "invalid route_order_subject"},
+		{name: "route loops", route: "oms.intent.order", errorMsg: "must differ"},
+		{name: "blue green", route: "oms.route.order", blueGreen: &BlueGreenConfig{Deployments: []bluegreen.Deployment{{Strategy: "grid", Blue: "grid-blue", Green: "grid-green"}}}, reports: "oms.execution.>"},
+		{name: "blue green unreported", route: "oms.route.order", blueGreen: &BlueGreenConfig{Deployments: []bluegreen.Deployment{{Strategy: "grid", Blue: "grid-blue", Green: "grid-green"}}}, errorMsg: "report_subject cannot be empty"},
+		{name: "blue green live", route: "oms.route.order", blueGreen: &BlueGreenConfig{Deployments: []bluegreen.Deployment{{Strategy: "grid", Blue: "grid-blue", Green: "grid-blue", Live: "red"}}}, reports: "oms.execution.>", errorMsg: "invalid blue_green.deployments"},
+		{name: "blue green window", route: "oms.route.order", blueGreen: &BlueGreenConfig{Deployments: []bluegreen.Deployment{{Strategy: "grid", Blue: "grid-blue", Green: "grid-green"}}, WindowMs: -1}, reports: "oms.execution.>", errorMsg: "blue_green.window_ms"},
+		{name: "reports invalid", route: "oms.route.order", reports: "oms.>.x", errorMsg: "invalid report_subject"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := (&OMSConfig{
+				OrderSubject:       "oms.intent.order",
+				CancelSubject:      "oms.intent.cancel",
+				RouteOrderSubject:  tt.route,
+				RouteCancelSubject: "oms.route.cancel",
+				ExecutionSubject:   "oms.execution.paper",
+				DryRun:             tt.dryRun,
+				BlueGreen:          tt.blueGreen,
+				ReportSubject:      tt.reports,
+				NATS:               NATSConfig{URIs: "nats://localhost:4222"},
+			}).Validate()
+			if tt.errorMsg == "" {
+				if err != nil {
+					t.Errorf("unexpected error: %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.errorMsg) {
+				t.Errorf("expected error containing %q, got %v", tt.errorMsg, err)
+			}
+		})
+	}
+}
+
+func TestGRPCGatewayConfig_Validate(t *testing.T) {
+	t.Setenv("GRPC_TOKEN", "secret")
+	client := GRPCClientConfig{Name: "research", TokenEnv: "GRPC_TOKEN", Subscribe: []string{"trade.>"}, Publish: []string{"strategy.research.>"}}
+	tests := []struct {
+		name      string
+		addr      string
+		tls       *TLSConfig
+		plaintext bool
+		client    GRPCClientConfig
+		errorMsg  string
+	}{
+		{name: "loopback", addr: "127.0.0.1:9090", client: client},
+		{name: "tls", addr: "0.0.0.0:9090", tls: &TLSConfig{CertFile: "grpc.crt", KeyFile: "grpc.key"}, client: client},
+		{name: "plaintext allowed", addr: ":9090", plaintext: true, client: client},
+		{name: "plaintext", addr: "0.0.0.0:9090", client: client, errorMsg: "not loopback"},
+		{name: "no token", addr: "127.0.0.1:9090", client: GRPCClientConfig{Name: "research"}, errorMsg: "name and token_env"},
+		{name: "token unset", addr: "127.0.0.1:9090", client: GRPCClientConfig{Name: "research", TokenEnv: "GRPC_UNSET"}, errorMsg: "GRPC_UNSET is not set"},
+		{name: "invalid grant", addr: "127.0.0.1:9090", client: GRPCClientConfig{Name: "research", TokenEnv: "GRPC_TOKEN", Subscribe: []string{"trade.>.x"}}, errorMsg: "clients[0]"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := (&GRPCGatewayConfig{
+				Addr:      tt.addr,
+				TLS:       tt.tls,
+				Plaintext: tt.plaintext,
+				Clients:   []GRPCClientConfig{tt.client},
+				NATS:      NATSConfig{URIs: "nats://localhost:4222"},
+			}).Validate()
+			if tt.errorMsg == "" {
+				if err != nil {
+					t.Errorf("unexpected error: %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.errorMsg) {
+				t.Errorf("expected error containing %q, got %v", tt.errorMsg, err)
+			}
+		})
+	}
+
+	opts := (&GRPCGatewayConfig{Clients: []GRPCClientConfig{client}, DepthSubject: "book.{symbol}"}).GatewayOptions()
+	if opts.Clients[0].Token != "secret" || opts.Subjects.Depth != "book.{symbol}" || opts.Subjects.Trades == "" {
+		t.Errorf("options %+v", opts)
+	}
+}
+
+func TestSweepConfig_Validate(t *testing.T) {
+	t.Setenv("SWEEP_KEY", "key")
+	t.Setenv("SWEEP_SECRET", "secret")
+	rule := SweepRuleConfig{Asset: "USDT", Wallet: "spot", Min: 500, Target: 1000, Max: 2000}
+	tests := []struct {
+		name     string
+		rules    []SweepRuleConfig
+		subject  string
+		errorMsg string
+	}{
+		{name: "valid", rules: []SweepRuleConfig{rule}, subject: "sweep"},
+		{name: "scheduled", rules: []SweepRuleConfig{{Asset: "USDT", Wallet: "usdm", Target: 1000, Schedule: []string{"00:05"}, Timezone: "Asia/Taipei"}}, subject: "sweep"},
+		{name: "no rules", subject: "sweep", errorMsg: "rules cannot be empty"},
+		{name: "unordered bounds", rules: []SweepRuleConfig{{Asset: "USDT", Wallet: "spot", Min: 1000, Target: 500}}, subject: "sweep", errorMsg: "min <= target"},
+		{name: "invalid schedule", rules: []SweepRuleConfig{{Asset: "USDT", Wallet: "spot", Target: 1, Schedule: []string{"25:00"}}}, subject: "sweep", errorMsg: "schedule"},
+		{name: "wildcard subject", rules: []SweepRuleConfig{rule}, subject: "sweep.>", errorMsg: "nats.subject"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := (&SweepConfig{
+				Binance: ExchangeKeyConfig{KeyEnv: "SWEEP_KEY", SecretEnv: "SWEEP_SECRET"},
+				Rules:   tt.rules,
+				NATS:    NATSConfig{URIs: "nats://localhost:4222", Subject: tt.subject},
+			}).Validate()
+			if tt.errorMsg == "" {
+				if err != nil {
+					t.Errorf("unexpected error: %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.errorMsg) {
+				t.Errorf("expected error containing %q, got %v", tt.errorMsg, err)
+			}
+		})
+	}
+}
+
+func TestWithdrawalConfig_Validate(t *testing.T) {
+	t.Setenv("WITHDRAWAL_KEY", "key")
+	t.Setenv("WITHDRAWAL_SECRET", "secret")
+	key := base64.StdEncoding.EncodeToString(make([]byte, ed25519.PublicKeySize))
+	alice, bob := ApproverConfig{Name: "alice", PublicKey: key}, ApproverConfig{Name: "bob", PublicKey: key}
+	tests := []struct {
+		name      string
+		approvers []ApproverConfig
+		approvals int
+		auditLog  string
+		errorMsg  string
+	}{
+		{name: "valid", approvers: []ApproverConfig{alice, bob}, auditLog: "audit.jsonl"},
+		{name: "single approver", approvers: []ApproverConfig{alice}, auditLog: "audit.jsonl", errorMsg: "2 approvals needed from 1 approvers"},
+		{name: "single approval", approvers: []ApproverConfig{alice, bob}, approvals: 1, auditLog: "audit.jsonl", errorMsg: "approvals must be at least 2"},
+		{name: "invalid key", approvers: []ApproverConfig{alice, {Name: "bob", PublicKey: "bob"}}, auditLog: "audit.jsonl", errorMsg: "approvers[1].public_key"},
+		{name: "no audit log", approvers: []ApproverConfig{alice, bob}, errorMsg: "audit_log cannot be empty"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := (&WithdrawalConfig{
+				Binance:   ExchangeKeyConfig{KeyEnv: "WITHDRAWAL_KEY", SecretEnv: "WITHDRAWAL_SECRET"},
+				Approvers: tt.approvers,
+				Approvals: tt.approvals,
+				AuditLog:  tt.auditLog,
+				NATS:      NATSConfig{URIs: "nats://localhost:4222"},
+			}).Validate()
+			if tt.errorMsg == "" {
+				if err != nil {
+					t.Errorf("unexpected error: %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.errorMsg) {
+				t.Errorf("expected error containing %q, got %v", tt.errorMsg, err)
+			}
+		})
+	}
+}
+
+func TestParseRemote(t *testing.T) {
+	tests := []struct {
+		path     string
+		remote   bool
+		source   RemoteSource
+		errorMsg string
+	}{
+		{path: "config/basis.json"},
+		{path: "kv:btc-basis.basis", remote: true, source: RemoteSource{Bucket: DefaultConfigBucket, Key: "btc-basis.basis"}},
+		{path: "kv:staging/btc-basis.basis", remote: true, source: RemoteSource{Bucket: "staging", Key: "btc-basis.basis"}},
+		{path: "kv:", remote: true, errorMsg: "invalid key"},
+		{path: "kv:btc.*", remote: true, errorMsg: "invalid key"},
+		{path: "kv:a.b/basis", remote: true, errorMsg: "invalid bucket"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.path, func(t *testing.T) {
+			source, remote, err := ParseRemote(tt.path)
+			if remote != tt.remote {
+				t.Errorf("remote = %v, want %v", remote, tt.remote)
+			}
+			if tt.errorMsg != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.errorMsg) {
+					t.Errorf("expected error containing %q, got %v", tt.errorMsg, err)
+				}
+				return
+			}
+			if err != nil || source != tt.source {
+				t.Errorf("ParseRemote() = %+v, %v, want %+v", source, err, tt.source)
+			}
+		})
+	}
+}
+
+func TestCommandsConfig_Validate(t *testing.T) {
+	public, _, _ := ed25519.GenerateKey(nil)
+	key := base64.StdEncoding.EncodeToString(public)
+	grants := map[string][]GrantConfig{"risk": {{Endpoints: []string{"guardian.*.pause"}, Nodes: []string{"fixgateway"}}}}
+	tests := []struct {
+		name     string
+		config   CommandsConfig
+		errorMsg string
+	}{
+		{name: "valid", config: CommandsConfig{Operators: []OperatorConfig{{Name: "alice", PublicKey: key, Roles: []string{"risk"}}}, Roles: grants}},
+		{name: "invalid key", config: CommandsConfig{Operators: []OperatorConfig{{Name: "alice", PublicKey: "abc", Roles: []string{"risk"}}}, Roles: grants}, errorMsg: "public_key"},
+		{name: "unknown role", config: CommandsConfig{Operators: []OperatorConfig{{Name: "alice", PublicKey: key, Roles: []string{"admin"}}}, Roles: grants}, errorMsg: "unknown role"},
+		{name: "grant without nodes", config: CommandsConfig{Roles: map[string][]GrantConfig{"risk": {{Endpoints: []string{"guardian.>"}}}}}, errorMsg: "endpoints and nodes"},
+		{name: "negative ttl", config: CommandsConfig{MaxTTLMs: -1}, errorMsg: "max_ttl_ms"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.config.Validate()
+			if tt.errorMsg == "" {
+				if err != nil {
+					t.Errorf("unexpected error: %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.errorMsg) {
+				t.Errorf("expected error containing %q, got %v", tt.errorMsg, err)
+			}
+		})
+	}
+}
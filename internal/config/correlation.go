@@ -0,0 +1,119 @@
+package config
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/BullionBear/sequex/internal/correlation"
+	"github.com/BullionBear/sequex/internal/model/sqx"
+)
+
+// klineIntervals maps the Binance kline intervals onto their duration
+var klineIntervals = map[string]time.Duration{
+	"1m":  time.Minute,
+	"3m":  3 * time.Minute,
+	"5m":  5 * time.Minute,
+	"15m": 15 * time.Minute,
+	"30m": 30 * time.Minute,
+	"1h":  time.Hour,
+	"2h":  2 * time.Hour,
+	"4h":  4 * time.Hour,
+	"6h":  6 * time.Hour,
+	"8h":  8 * time.Hour,
+	"12h": 12 * time.Hour,
+	"1d":  24 * time.Hour,
+}
+
+// CorrelationConfig represents the configuration of the correlation service
+type CorrelationConfig struct {
+	Host              string            `json:"host"`
+	Port              int               `json:"port"`                // REST query endpoint
+	Binance           []string          `json:"binance"`             // Symbols whose Binance USDⓈ-M klines are streamed, e.g. BTC-USDT
+	Interval          string            `json:"interval"`            // Kline interval, 1m when omitted
+	Window            int               `json:"window"`              // Returns the matrices are computed over, 240 when omitted
+	MinPeriods        int               `json:"min_periods"`         // Returns every symbol must share before a matrix is computed, 30 when omitted
+	Backfill          bool              `json:"backfill"`            // Fill the window from the REST API on start
+	KlinesDir         string            `json:"klines_dir"`          // Columnar kline tables read before the REST API when backfilling, and keeping what it served
+	RPCSubject        string            `json:"rpc_subject"`         // Subject matrices are requested on, empty disables it
+	PublishIntervalMs int64             `json:"publish_interval_ms"` // Interval between matrix publications, 0 disables them
+	Diagnostics       DiagnosticsConfig `json:"diagnostics"`         // Runtime diagnostics endpoints
+	NATS              NATSConfig        `json:"nats"`                // Stream and subject the matrices are published to
+}
+
+// LoadCorrelationConfig loads the correlation service configuration from a JSON file
+func LoadCorrelationConfig(filePath string) (*CorrelationConfig, error) {
+	if filePath == "" {
+		return nil, fmt.Errorf("config file path cannot be empty")
+	}
+
+	data, err := ReadConfig(filePath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read config file %s: %w", filePath, err)
+	}
+
+	var config CorrelationConfig
+	if err := json.Unmarshal(data, &config); err != nil {
+		return nil, fmt.Errorf("failed to parse config file %s: %w", filePath, err)
+	}
+
+	if err := config.Validate(); err != nil {
+		return nil, fmt.Errorf("invalid configuration in %s: %w", filePath, err)
+	}
+
+	return &config, nil
+}
+
+// Validate validates the correlation service configuration
+func (c *CorrelationConfig) Validate() error {
+	if c.Port <= 0 || c.Port > 65535 {
+		return fmt.Errorf("port must be between 1 and 65535, got %d", c.Port)
+	}
+
+	if len(c.Binance) < 2 {
+		return fmt.Errorf("binance must list at least 2 symbols")
+	}
+
+	for i, symbol := range c.Binance {
+		if _, err := sqx.NewSymbolFromStr(symbol); err != nil {
+			return fmt.Errorf("invalid binance[%d]: %w", i, err)
+		}
+	}
+
+	if c.Interval != "" {
+		if _, ok := klineIntervals[c.Interval]; !ok {
+			return fmt.Errorf("unsupported interval %q", c.Interval)
+		}
+	}
+
+	if c.Window < 0 || c.MinPeriods < 0 || c.PublishIntervalMs < 0 {
+		return fmt.Errorf("window, min_periods and publish_interval_ms cannot be negative")
+	}
+
+	if c.Window > 0 && c.MinPeriods > c.Window {
+		return fmt.Errorf("min_periods cannot exceed window")
+	}
+
+	if err := c.Diagnostics.Validate(); err != nil {
+		return err
+	}
+
+	return c.NATS.Validate()
+}
+
+// KlineInterval returns the Binance kline interval
+func (c *CorrelationConfig) KlineInterval() string {
+	if c.Interval == "" {
+		return "1m"
+	}
+	return c.Interval
+}
+
+// Options converts the configuration into tracker options
+func (c *CorrelationConfig) Options() correlation.Options {
+	return correlation.Options{
+		Interval:   klineIntervals[c.KlineInterval()],
+		Window:     c.Window,
+		MinPeriods: c.MinPeriods,
+	}
+}
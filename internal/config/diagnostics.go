@@ -0,0 +1,130 @@
+package config
+
+import (
+	"fmt"
+	"net"
+	"strings"
+
+	"github.com/BullionBear/sequex/internal/sysevent"
+	"github.com/BullionBear/sequex/pkg/diagnostics"
+	"github.com/BullionBear/sequex/pkg/eventbus"
+	"github.com/nats-io/nats.go"
+	"github.com/rs/zerolog"
+)
+
+// DiagnosticsConfig enables the runtime diagnostics endpoints of a service
+type DiagnosticsConfig struct {
+	PprofAddr  string `json:"pprof_addr"`  // Listen address of the pprof endpoints, empty disables them
+	RPC        bool   `json:"rpc"`         // Answer diagnostics requests over NATS
+	RPCSubject string `json:"rpc_subject"` // Defaults to diag.<service>
+	Logs       bool   `json:"logs"`        // Ship log records to NATS for centralized viewing
+	LogLevel   string `json:"log_level"`   // Lowest level shipped, warn when omitted
+	LogSubject string `json:"log_subject"` // Defaults to logs.<service>
+	Describe   bool   `json:"describe"`    // Answer node description requests on describe.<service>
+	Snapshot   bool   `json:"snapshot"`    // Answer state snapshot requests on snapshot.<service>
+	// Publish system events, e.g. nodes starting or strategies paused, to
+	// <events_prefix>.<kind>, which the stream of the service must store
+	Events       bool   `json:"events"`
+	EventsPrefix string `json:"events_prefix"` // Defaults to sys.events
+}
+
+// Validate validates the diagnostics configuration
+func (d *DiagnosticsConfig) Validate() error {
+	if d.PprofAddr != "" {
+		if _, _, err := net.SplitHostPort(d.PprofAddr); err != nil {
+			return fmt.Errorf("invalid diagnostics.pprof_addr: %w", err)
+		}
+	}
+	if d.RPCSubject != "" {
+		if err := eventbus.ValidatePattern(d.RPCSubject); err != nil {
+			return fmt.Errorf("invalid diagnostics.rpc_subject: %w", err)
+		}
+	}
+	if d.LogSubject != "" {
+		if err := eventbus.ValidatePattern(d.LogSubject); err != nil {
+			return fmt.Errorf("invalid diagnostics.log_subject: %w", err)
+		}
+		if strings.ContainsAny(d.LogSubject, "*>") {
+			return fmt.Errorf("diagnostics.log_subject cannot contain wildcards")
+		}
+	}
+	if d.EventsPrefix != "" {
+		if err := eventbus.ValidatePattern(d.EventsPrefix); err != nil {
+			return fmt.Errorf("invalid diagnostics.events_prefix: %w", err)
+		}
+		if strings.ContainsAny(d.EventsPrefix, "*>") {
+			return fmt.Errorf("diagnostics.events_prefix cannot contain wildcards")
+		}
+	}
+	if _, err := d.logLevel(); err != nil {
+		return err
+	}
+	return nil
+}
+
+// logLevel returns the lowest level shipped
+func (d *DiagnosticsConfig) logLevel() (zerolog.Level, error) {
+	if d.LogLevel == "" {
+		return zerolog.WarnLevel, nil
+	}
+	level, err := zerolog.ParseLevel(d.LogLevel)
+	if err != nil || level == zerolog.NoLevel || level == zerolog.Disabled {
+		return level, fmt.Errorf("diagnostics.log_level must be trace, debug, info, warn, error, fatal or panic, got %q", d.LogLevel)
+	}
+	return level, nil
+}
+
+// DescribeSubject returns the subject the description of a node is served
+// on, empty when disabled
+func (d *DiagnosticsConfig) DescribeSubject(service string) string {
+	if !d.Describe {
+		return ""
+	}
+	return "describe." + service
+}
+
+// SnapshotSubject returns the subject the state snapshots of a node are
+// served on, empty when disabled
+func (d *DiagnosticsConfig) SnapshotSubject(service string) string {
+	if !d.Snapshot {
+		return ""
+	}
+	return "snapshot." + service
+}
+
+// EventPublisher returns the publisher of the system events of a service,
+// nil when disabled, which drops them. It is closed after the shutdown
+// callbacks, e.g. deferred, so the events they raise are still published.
+func (d *DiagnosticsConfig) EventPublisher(conn *nats.Conn, service string, log zerolog.Logger) (*sysevent.Publisher, error) {
+	if !d.Events {
+		return nil, nil
+	}
+	js, err := conn.JetStream()
+	if err != nil {
+		return nil, fmt.Errorf("failed to create JetStream context: %w", err)
+	}
+	prefix := d.EventsPrefix
+	if prefix == "" {
+		prefix = sysevent.DefaultPrefix
+	}
+	return sysevent.NewPublisher(js, prefix, service, log), nil
+}
+
+// Options converts the configuration into diagnostics options of a service
+func (d *DiagnosticsConfig) Options(service string) diagnostics.Options {
+	opts := diagnostics.Options{Service: service, PprofAddr: d.PprofAddr}
+	if d.RPC {
+		opts.RPCSubject = d.RPCSubject
+		if opts.RPCSubject == "" {
+			opts.RPCSubject = "diag." + service
+		}
+	}
+	if d.Logs {
+		opts.LogSubject = d.LogSubject
+		if opts.LogSubject == "" {
+			opts.LogSubject = "logs." + service
+		}
+		opts.LogLevel, _ = d.logLevel()
+	}
+	return opts
+}
@@ -0,0 +1,48 @@
+package config
+
+import (
+	"fmt"
+
+	"github.com/BullionBear/sequex/internal/dryrun"
+	"github.com/BullionBear/sequex/pkg/eventbus"
+)
+
+// DryRunConfig keeps strategies off the exchange, their orders acknowledged
+// on paper, logged and published instead
+type DryRunConfig struct {
+	Enabled      bool            `json:"enabled"`       // Global flag, of the strategies without their own
+	Strategies   map[string]bool `json:"strategies"`    // Flags by strategy, switches made at runtime take precedence until a restart
+	OrderSubject string          `json:"order_subject"` // Subject the orders kept off the exchange are published to, under the prefix of their tenant
+	Subject      string          `json:"subject"`       // Prefix the params RPCs switching the flags are served under, params.<service> when empty
+}
+
+// Validate validates the dry-run configuration
+func (c *DryRunConfig) Validate() error {
+	if c.OrderSubject == "" {
+		return fmt.Errorf("dry_run.order_subject cannot be empty")
+	}
+	for strategy := range c.Strategies {
+		if strategy == "" {
+			return fmt.Errorf("dry_run.strategies cannot have an empty strategy")
+		}
+	}
+	if c.Subject != "" {
+		if err := eventbus.ValidatePattern(c.Subject); err != nil {
+			return fmt.Errorf("invalid dry_run.subject: %w", err)
+		}
+	}
+	return nil
+}
+
+// RPCSubject returns the prefix the params RPCs of a service are served under
+func (c *DryRunConfig) RPCSubject(service string) string {
+	if c.Subject == "" {
+		return dryrun.DefaultSubject + "." + service
+	}
+	return c.Subject
+}
+
+// Flags returns the flags of the configuration
+func (c *DryRunConfig) Flags() *dryrun.Flags {
+	return dryrun.NewFlags(c.Enabled, c.Strategies)
+}
@@ -0,0 +1,53 @@
+package config
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+
+	"github.com/BullionBear/sequex/internal/execution"
+	"github.com/BullionBear/sequex/pkg/eventbus"
+)
+
+// ExecutionHistoryConfig configures the order and fill history built from
+// the execution reports of the OMS
+type ExecutionHistoryConfig struct {
+	Inputs  []string `json:"inputs"`  // Execution report subjects, the history is disabled when empty
+	Journal string   `json:"journal"` // JSON lines file the reports are appended to, in memory only when empty
+}
+
+// Validate validates the execution history configuration
+func (c *ExecutionHistoryConfig) Validate() error {
+	for i, input := range c.Inputs {
+		if err := eventbus.ValidatePattern(input); err != nil {
+			return fmt.Errorf("invalid executions.inputs[%d]: %w", i, err)
+		}
+	}
+	return nil
+}
+
+// Store opens the history, replaying the journal. It is nil when the
+// history is disabled.
+func (c *ExecutionHistoryConfig) Store() (*execution.Store, error) {
+	if len(c.Inputs) == 0 {
+		return nil, nil
+	}
+	if c.Journal == "" {
+		return execution.NewMemoryStore(), nil
+	}
+	return execution.Open(c.Journal)
+}
+
+// TenantStore opens the history of a tenant, fed by the inputs under its
+// subject prefix. Its journal sits next to the one of the deployment, named
+// after the tenant, e.g. executions.acme.jsonl.
+func (c *ExecutionHistoryConfig) TenantStore(id string) (*execution.Store, error) {
+	if len(c.Inputs) == 0 {
+		return nil, nil
+	}
+	if c.Journal == "" {
+		return execution.NewMemoryStore(), nil
+	}
+	ext := filepath.Ext(c.Journal)
+	return execution.Open(strings.TrimSuffix(c.Journal, ext) + "." + id + ext)
+}
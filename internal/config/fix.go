@@ -0,0 +1,175 @@
+package config
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/netip"
+	"os"
+
+	"github.com/BullionBear/sequex/pkg/eventbus"
+	"github.com/BullionBear/sequex/pkg/fix"
+)
+
+// FIXSessionConfig represents a FIX session allowed to log on to the gateway
+type FIXSessionConfig struct {
+	SenderCompID string   `json:"sender_comp_id"`          // Our comp id
+	TargetCompID string   `json:"target_comp_id"`          // Counterparty comp id
+	Tenant       string   `json:"tenant,omitempty"`        // Tenant trading through the session, the deployment itself when omitted
+	Username     string   `json:"username"`                // Username(553) of the Logon
+	PasswordEnv  string   `json:"password_env"`            // Environment variable holding the Password(554) of the Logon
+	AllowedAddrs []string `json:"allowed_addrs,omitempty"` // Addresses or CIDR ranges the session logs on from, any when omitted
+}
+
+// LogonAuth returns who may log on to the session
+func (s FIXSessionConfig) LogonAuth() (fix.LogonAuth, error) {
+	auth := fix.LogonAuth{Username: s.Username, Password: os.Getenv(s.PasswordEnv)}
+	for _, addr := range s.AllowedAddrs {
+		prefix, err := netip.ParsePrefix(addr)
+		if err != nil {
+			ip, ipErr := netip.ParseAddr(addr)
+			if ipErr != nil {
+				return auth, fmt.Errorf("invalid allowed address %q", addr)
+			}
+			prefix = netip.PrefixFrom(ip, ip.BitLen())
+		}
+		auth.Allowed = append(auth.Allowed, prefix.Masked())
+	}
+	return auth, nil
+}
+
+// FIXConfig represents the configuration of the FIX order entry gateway
+type FIXConfig struct {
+	Addr             string             `json:"addr"`                // Loopback unless tls is set or plaintext allowed
+	TLS              *TLSConfig         `json:"tls,omitempty"`       // Serves FIX over TLS, plaintext when omitted
+	Plaintext        bool               `json:"plaintext,omitempty"` // Allows plaintext on an addr other than loopback, e.g. behind a TLS terminating proxy
+	StoreDir         string             `json:"store_dir"`           // Sequence number persistence directory
+	DefaultExchange  string             `json:"default_exchange"`
+	Sessions         []FIXSessionConfig `json:"sessions"`
+	OrderSubject     string             `json:"order_subject"`
+	CancelSubject    string             `json:"cancel_subject"`
+	ExecutionSubject string             `json:"execution_subject"`
+	HaltSubject      string             `json:"halt_subject,omitempty"`   // Announcements halting orders, e.g. announcement.>, none when omitted
+	HaltLeadMs       int64              `json:"halt_lead_ms,omitempty"`   // How long before a delisting its symbols are halted
+	StatusSubject    string             `json:"status_subject,omitempty"` // Symbol status changes halting orders while not trading, none when omitted
+	Tenants          []TenantConfig     `json:"tenants,omitempty"`        // Tenants of the sessions, their API keys are not needed
+	Allocations      *AllocationConfig  `json:"allocations,omitempty"`    // Budgets of the strategies of the sessions, fix.<target comp id>, none enforced when omitted
+	Guardian         *GuardianConfig    `json:"guardian,omitempty"`       // Drawdown limits of the strategies of the sessions, none enforced when omitted
+	FX               FXConfig           `json:"fx"`                       // Prices positions are valued at by allocations and the guardian
+	Diagnostics      DiagnosticsConfig  `json:"diagnostics"`              // Runtime diagnostics endpoints
+	NATS             NATSConfig         `json:"nats"`
+}
+
+// LoadFIXConfig loads the FIX gateway configuration from a JSON file
+func LoadFIXConfig(filePath string) (*FIXConfig, error) {
+	if filePath == "" {
+		return nil, fmt.Errorf("config file path cannot be empty")
+	}
+
+	data, err := ReadConfig(filePath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read config file %s: %w", filePath, err)
+	}
+
+	var config FIXConfig
+	if err := json.Unmarshal(data, &config); err != nil {
+		return nil, fmt.Errorf("failed to parse config file %s: %w", filePath, err)
+	}
+
+	if err := config.Validate(); err != nil {
+		return nil, fmt.Errorf("invalid configuration in %s: %w", filePath, err)
+	}
+
+	return &config, nil
+}
+
+// Validate validates the FIX gateway configuration
+func (c *FIXConfig) Validate() error {
+	if c.Addr == "" {
+		return fmt.Errorf("addr cannot be empty")
+	}
+
+	if c.TLS != nil {
+		if c.TLS.CertFile == "" || c.TLS.KeyFile == "" {
+			return fmt.Errorf("tls: cert_file and key_file cannot be empty")
+		}
+	} else if !c.Plaintext && !loopback(c.Addr) {
+		return fmt.Errorf("addr %s is not loopback: set tls, or plaintext to serve it unencrypted", c.Addr)
+	}
+
+	if len(c.Sessions) == 0 {
+		return fmt.Errorf("sessions cannot be empty")
+	}
+
+	tenants, err := TenantRegistry(c.Tenants)
+	if err != nil {
+		return err
+	}
+
+	for i, session := range c.Sessions {
+		if session.SenderCompID == "" || session.TargetCompID == "" {
+			return fmt.Errorf("sessions[%d]: sender_comp_id and target_comp_id cannot be empty", i)
+		}
+		if _, ok := tenants.Get(session.Tenant); session.Tenant != "" && !ok {
+			return fmt.Errorf("sessions[%d]: unknown tenant %q", i, session.Tenant)
+		}
+		if session.Username == "" || session.PasswordEnv == "" {
+			return fmt.Errorf("sessions[%d]: username and password_env cannot be empty", i)
+		}
+		if os.Getenv(session.PasswordEnv) == "" {
+			return fmt.Errorf("sessions[%d]: environment variable %s is not set", i, session.PasswordEnv)
+		}
+		if _, err := session.LogonAuth(); err != nil {
+			return fmt.Errorf("sessions[%d]: %w", i, err)
+		}
+	}
+
+	if c.OrderSubject == "" {
+		return fmt.Errorf("order_subject cannot be empty")
+	}
+
+	if c.CancelSubject == "" {
+		return fmt.Errorf("cancel_subject cannot be empty")
+	}
+
+	if c.ExecutionSubject == "" {
+		return fmt.Errorf("execution_subject cannot be empty")
+	}
+
+	if c.HaltSubject != "" {
+		if err := eventbus.ValidatePattern(c.HaltSubject); err != nil {
+			return fmt.Errorf("invalid halt_subject: %w", err)
+		}
+	}
+
+	if c.StatusSubject != "" {
+		if err := eventbus.ValidatePattern(c.StatusSubject); err != nil {
+			return fmt.Errorf("invalid status_subject: %w", err)
+		}
+	}
+
+	if c.HaltLeadMs < 0 {
+		return fmt.Errorf("halt_lead_ms cannot be negative")
+	}
+
+	if c.Allocations != nil {
+		if err := c.Allocations.Validate(); err != nil {
+			return err
+		}
+	}
+
+	if c.Guardian != nil {
+		if err := c.Guardian.Validate(); err != nil {
+			return err
+		}
+	}
+
+	if err := c.FX.Validate(); err != nil {
+		return err
+	}
+
+	if err := c.Diagnostics.Validate(); err != nil {
+		return err
+	}
+
+	return c.NATS.ValidateURIs()
+}
@@ -0,0 +1,74 @@
+package config
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/BullionBear/sequex/internal/fx"
+	"github.com/BullionBear/sequex/internal/model/sqx"
+	"github.com/BullionBear/sequex/pkg/eventbus"
+)
+
+// StaticRateConfig is a conversion rate no market data feed provides, e.g. EUR-USD
+type StaticRateConfig struct {
+	Pair string  `json:"pair"` // BASE-QUOTE
+	Rate float64 `json:"rate"` // Price of one unit of BASE in QUOTE
+}
+
+// FXConfig configures the conversion service portfolios are valued with
+type FXConfig struct {
+	Inputs       []string           `json:"inputs"`                  // Trade subjects whose last prices are used as rates
+	OptionInputs []string           `json:"option_inputs,omitempty"` // Option mark subjects whose implied volatilities value option positions
+	MaxAgeMs     int64              `json:"max_age_ms"`              // Rates older than this are ignored, 0 keeps them forever
+	HistoryMs    int64              `json:"history_ms,omitempty"`    // How long hourly rates are kept to convert fees at the time of their fills, none when 0
+	Pivots       []string           `json:"pivots"`                  // Currencies rates are triangulated through, defaults to USDT, USD, USDC and BTC
+	Static       []StaticRateConfig `json:"static"`
+}
+
+// Validate validates the conversion service configuration
+func (c *FXConfig) Validate() error {
+	for i, input := range c.Inputs {
+		if err := eventbus.ValidatePattern(input); err != nil {
+			return fmt.Errorf("invalid fx.inputs[%d]: %w", i, err)
+		}
+	}
+	for i, input := range c.OptionInputs {
+		if err := eventbus.ValidatePattern(input); err != nil {
+			return fmt.Errorf("invalid fx.option_inputs[%d]: %w", i, err)
+		}
+	}
+	if c.MaxAgeMs < 0 {
+		return fmt.Errorf("fx.max_age_ms cannot be negative")
+	}
+	if c.HistoryMs < 0 {
+		return fmt.Errorf("fx.history_ms cannot be negative")
+	}
+	for i, rate := range c.Static {
+		if _, err := sqx.NewSymbolFromStr(rate.Pair); err != nil {
+			return fmt.Errorf("invalid fx.static[%d].pair: %w", i, err)
+		}
+		if rate.Rate <= 0 {
+			return fmt.Errorf("fx.static[%d].rate must be positive", i)
+		}
+	}
+	return nil
+}
+
+// Rates creates the conversion service with the static rates set
+func (c *FXConfig) Rates() (*fx.Rates, error) {
+	rates := fx.NewRates(fx.Options{
+		MaxAge:  time.Duration(c.MaxAgeMs) * time.Millisecond,
+		Pivots:  c.Pivots,
+		History: time.Duration(c.HistoryMs) * time.Millisecond,
+	})
+	for _, static := range c.Static {
+		pair, err := sqx.NewSymbolFromStr(static.Pair)
+		if err != nil {
+			return nil, err
+		}
+		if err := rates.SetStatic(pair.Base, pair.Quote, static.Rate); err != nil {
+			return nil, err
+		}
+	}
+	return rates, nil
+}
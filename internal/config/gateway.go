@@ -0,0 +1,138 @@
+package config
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/BullionBear/sequex/internal/gateway"
+)
+
+// GatewayConfig represents the configuration of the websocket gateway
+type GatewayConfig struct {
+	Host             string            `json:"host"`
+	Port             int               `json:"port"`
+	AllowedSubjects  []string          `json:"allowed_subjects"`   // Subject patterns clients may subscribe to
+	Tokens           []string          `json:"tokens"`             // Accepted auth tokens of the default account, auth is disabled without tokens and accounts
+	RateLimit        float64           `json:"rate_limit"`         // Data messages per second per client
+	RateBurst        int               `json:"rate_burst"`         // Burst size of the per-client rate limiter
+	SendBuffer       int               `json:"send_buffer"`        // Outbound queue size per client
+	MaxSubscriptions int               `json:"max_subscriptions"`  // Maximum subjects per client
+	Accounts         []AccountConfig   `json:"accounts,omitempty"` // Metered accounts, authenticated by their tokens
+	DefaultLimits    gateway.Limits    `json:"default_limits"`     // Limits shared by the clients without an account token
+	AdminToken       string            `json:"admin_token"`        // Bearer token of the /admin API, empty disables it
+	Diagnostics      DiagnosticsConfig `json:"diagnostics"`        // Runtime diagnostics endpoints
+	NATS             NATSConfig        `json:"nats"`
+}
+
+// AccountConfig is a customer or team of the gateway, see gateway.Account
+type AccountConfig struct {
+	Name   string         `json:"name"`
+	Token  string         `json:"token"`
+	Limits gateway.Limits `json:"limits"`
+}
+
+// LoadGatewayConfig loads the websocket gateway configuration from a JSON file
+func LoadGatewayConfig(filePath string) (*GatewayConfig, error) {
+	if filePath == "" {
+		return nil, fmt.Errorf("config file path cannot be empty")
+	}
+
+	data, err := ReadConfig(filePath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read config file %s: %w", filePath, err)
+	}
+
+	config, err := ParseGatewayConfig(data)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", filePath, err)
+	}
+
+	return config, nil
+}
+
+// ParseGatewayConfig decodes and validates a websocket gateway
+// configuration, as loaded or reloaded from its bucket
+func ParseGatewayConfig(data []byte) (*GatewayConfig, error) {
+	var config GatewayConfig
+	if err := json.Unmarshal(data, &config); err != nil {
+		return nil, fmt.Errorf("failed to parse configuration: %w", err)
+	}
+
+	if err := config.Validate(); err != nil {
+		return nil, fmt.Errorf("invalid configuration: %w", err)
+	}
+
+	return &config, nil
+}
+
+// Static returns the configuration without the settings reloaded at
+// runtime, the limits of the accounts and default_limits, so that changing
+// anything else can be told apart as requiring a restart
+func (c GatewayConfig) Static() GatewayConfig {
+	c.DefaultLimits = gateway.Limits{}
+	c.Accounts = append([]AccountConfig(nil), c.Accounts...)
+	for i := range c.Accounts {
+		c.Accounts[i].Limits = gateway.Limits{}
+	}
+	return c
+}
+
+// Validate validates the websocket gateway configuration
+func (c *GatewayConfig) Validate() error {
+	if c.Port <= 0 || c.Port > 65535 {
+		return fmt.Errorf("port must be between 1 and 65535, got %d", c.Port)
+	}
+
+	if c.RateLimit < 0 {
+		return fmt.Errorf("rate_limit cannot be negative")
+	}
+
+	if c.SendBuffer < 0 {
+		return fmt.Errorf("send_buffer cannot be negative")
+	}
+
+	if c.MaxSubscriptions < 0 {
+		return fmt.Errorf("max_subscriptions cannot be negative")
+	}
+
+	names := map[string]bool{gateway.DefaultAccount: true}
+	tokens := make(map[string]bool, len(c.Tokens))
+	for _, token := range c.Tokens {
+		tokens[token] = true
+	}
+	for i, a := range c.Accounts {
+		if a.Name == "" || a.Token == "" {
+			return fmt.Errorf("accounts[%d]: name and token cannot be empty", i)
+		}
+		if names[a.Name] {
+			return fmt.Errorf("accounts[%d]: duplicate account %s", i, a.Name)
+		}
+		if tokens[a.Token] {
+			return fmt.Errorf("accounts[%d]: token already used", i)
+		}
+		if err := a.Limits.Validate(); err != nil {
+			return fmt.Errorf("accounts[%d]: %w", i, err)
+		}
+		names[a.Name], tokens[a.Token] = true, true
+	}
+
+	if err := c.DefaultLimits.Validate(); err != nil {
+		return fmt.Errorf("default_limits: %w", err)
+	}
+
+	// The gateway only subscribes to core NATS subjects, so stream and subject are not required
+	if err := c.Diagnostics.Validate(); err != nil {
+		return err
+	}
+
+	return c.NATS.ValidateURIs()
+}
+
+// GatewayAccounts converts the configured accounts
+func (c *GatewayConfig) GatewayAccounts() []gateway.Account {
+	accounts := make([]gateway.Account, len(c.Accounts))
+	for i, a := range c.Accounts {
+		accounts[i] = gateway.Account{Name: a.Name, Token: a.Token, Limits: a.Limits}
+	}
+	return accounts
+}
@@ -0,0 +1,138 @@
+package config
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+
+	"github.com/BullionBear/sequex/internal/grpcgateway"
+	"github.com/BullionBear/sequex/pkg/eventbus"
+)
+
+// GRPCClientConfig represents a client of the gRPC gateway
+type GRPCClientConfig struct {
+	Name      string   `json:"name"`
+	TokenEnv  string   `json:"token_env"`         // Environment variable holding the bearer token
+	Subscribe []string `json:"subscribe"`         // Subject patterns the client may receive, market data included
+	Publish   []string `json:"publish,omitempty"` // Subject patterns the client may publish events to, none when omitted
+}
+
+// GRPCGatewayConfig represents the configuration of the gRPC gateway
+type GRPCGatewayConfig struct {
+	Addr         string             `json:"addr"`                // Loopback unless tls is set or plaintext allowed
+	TLS          *TLSConfig         `json:"tls,omitempty"`       // Serves gRPC over TLS, plaintext when omitted
+	Plaintext    bool               `json:"plaintext,omitempty"` // Allows plaintext on an addr other than loopback, e.g. behind a TLS terminating proxy
+	Clients      []GRPCClientConfig `json:"clients"`
+	TradeSubject string             `json:"trade_subject,omitempty"` // Subject template of trades, see grpcgateway.Subjects
+	KlineSubject string             `json:"kline_subject,omitempty"` // Subject template of klines
+	DepthSubject string             `json:"depth_subject,omitempty"` // Subject template of depth
+	SendBuffer   int                `json:"send_buffer"`             // Messages a stream may fall behind by before it is ended
+	MaxStreams   int                `json:"max_streams"`             // Streams open at once per client, unlimited when 0
+	Diagnostics  DiagnosticsConfig  `json:"diagnostics"`             // Runtime diagnostics endpoints
+	NATS         NATSConfig         `json:"nats"`
+}
+
+// LoadGRPCGatewayConfig loads the gRPC gateway configuration from a JSON file
+func LoadGRPCGatewayConfig(filePath string) (*GRPCGatewayConfig, error) {
+	if filePath == "" {
+		return nil, fmt.Errorf("config file path cannot be empty")
+	}
+
+	data, err := ReadConfig(filePath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read config file %s: %w", filePath, err)
+	}
+
+	var config GRPCGatewayConfig
+	if err := json.Unmarshal(data, &config); err != nil {
+		return nil, fmt.Errorf("failed to parse config file %s: %w", filePath, err)
+	}
+
+	if err := config.Validate(); err != nil {
+		return nil, fmt.Errorf("invalid configuration in %s: %w", filePath, err)
+	}
+
+	return &config, nil
+}
+
+// Validate validates the gRPC gateway configuration
+func (c *GRPCGatewayConfig) Validate() error {
+	if c.Addr == "" {
+		return fmt.Errorf("addr cannot be empty")
+	}
+
+	if c.TLS != nil {
+		if c.TLS.CertFile == "" || c.TLS.KeyFile == "" {
+			return fmt.Errorf("tls: cert_file and key_file cannot be empty")
+		}
+	} else if !c.Plaintext && !loopback(c.Addr) {
+		return fmt.Errorf("addr %s is not loopback: set tls, or plaintext to serve it unencrypted", c.Addr)
+	}
+
+	if len(c.Clients) == 0 {
+		return fmt.Errorf("clients cannot be empty")
+	}
+
+	names := make(map[string]bool, len(c.Clients))
+	for i, client := range c.Clients {
+		if client.Name == "" || client.TokenEnv == "" {
+			return fmt.Errorf("clients[%d]: name and token_env cannot be empty", i)
+		}
+		if names[client.Name] {
+			return fmt.Errorf("clients[%d]: duplicate client %s", i, client.Name)
+		}
+		names[client.Name] = true
+		if os.Getenv(client.TokenEnv) == "" {
+			return fmt.Errorf("clients[%d]: environment variable %s is not set", i, client.TokenEnv)
+		}
+		for _, pattern := range append(append([]string(nil), client.Subscribe...), client.Publish...) {
+			if err := eventbus.ValidatePattern(pattern); err != nil {
+				return fmt.Errorf("clients[%d]: %w", i, err)
+			}
+		}
+	}
+
+	if c.SendBuffer < 0 {
+		return fmt.Errorf("send_buffer cannot be negative")
+	}
+
+	if c.MaxStreams < 0 {
+		return fmt.Errorf("max_streams cannot be negative")
+	}
+
+	if err := c.Diagnostics.Validate(); err != nil {
+		return err
+	}
+
+	return c.NATS.ValidateURIs()
+}
+
+// GatewayOptions converts the configuration to the options of the server,
+// reading the tokens of the clients from their environment variables
+func (c *GRPCGatewayConfig) GatewayOptions() grpcgateway.Options {
+	subjects := grpcgateway.DefaultSubjects
+	if c.TradeSubject != "" {
+		subjects.Trades = c.TradeSubject
+	}
+	if c.KlineSubject != "" {
+		subjects.Klines = c.KlineSubject
+	}
+	if c.DepthSubject != "" {
+		subjects.Depth = c.DepthSubject
+	}
+	clients := make([]grpcgateway.Client, len(c.Clients))
+	for i, client := range c.Clients {
+		clients[i] = grpcgateway.Client{
+			Name:      client.Name,
+			Token:     os.Getenv(client.TokenEnv),
+			Subscribe: client.Subscribe,
+			Publish:   client.Publish,
+		}
+	}
+	return grpcgateway.Options{
+		Clients:    clients,
+		Subjects:   subjects,
+		SendBuffer: c.SendBuffer,
+		MaxStreams: c.MaxStreams,
+	}
+}
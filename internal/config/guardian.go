@@ -0,0 +1,102 @@
+package config
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/BullionBear/sequex/internal/guardian"
+	"github.com/BullionBear/sequex/internal/pms"
+	"github.com/BullionBear/sequex/pkg/eventbus"
+)
+
+// DefaultGuardianIntervalMs is how often the guardian marks positions to
+// market when no interval is configured
+const DefaultGuardianIntervalMs = 1000
+
+// DrawdownConfig bounds the drawdown of a strategy
+type DrawdownConfig struct {
+	MaxDrawdown float64 `json:"max_drawdown"` // From the peak equity within the window, in the currency. 0 never pauses.
+	WindowMs    int64   `json:"window_ms"`    // Of the peak equity, 24 hours when 0
+}
+
+// Thresholds returns the thresholds of the configuration
+func (c DrawdownConfig) Thresholds() guardian.Thresholds {
+	return guardian.Thresholds{
+		MaxDrawdown: c.MaxDrawdown,
+		Window:      time.Duration(c.WindowMs) * time.Millisecond,
+	}
+}
+
+func (c DrawdownConfig) validate(field string) error {
+	if c.MaxDrawdown < 0 {
+		return fmt.Errorf("%s.max_drawdown cannot be negative", field)
+	}
+	if c.WindowMs < 0 {
+		return fmt.Errorf("%s.window_ms cannot be negative", field)
+	}
+	return nil
+}
+
+// GuardianConfig pauses strategies whose drawdown breaches their limits,
+// until resumed by hand
+type GuardianConfig struct {
+	Currency   string                    `json:"currency"`    // Of equities and limits, USDT when empty
+	Default    DrawdownConfig            `json:"default"`     // Limits of the strategies without their own
+	Strategies map[string]DrawdownConfig `json:"strategies"`  // Limits by strategy
+	IntervalMs int64                     `json:"interval_ms"` // How often positions are marked to market, DefaultGuardianIntervalMs when 0
+	Journal    string                    `json:"journal"`     // JSON lines audit trail of the pauses, keeping strategies paused across restarts. In memory only when empty.
+	Subject    string                    `json:"subject"`     // Prefix the guardian RPCs are served under, guardian.<service> when empty
+}
+
+// Validate validates the guardian configuration
+func (c *GuardianConfig) Validate() error {
+	if err := c.Default.validate("guardian.default"); err != nil {
+		return err
+	}
+	for strategy, limits := range c.Strategies {
+		if err := limits.validate("guardian.strategies." + strategy); err != nil {
+			return err
+		}
+	}
+	if c.IntervalMs < 0 {
+		return fmt.Errorf("guardian.interval_ms cannot be negative")
+	}
+	if c.Subject != "" {
+		if err := eventbus.ValidatePattern(c.Subject); err != nil {
+			return fmt.Errorf("invalid guardian.subject: %w", err)
+		}
+	}
+	return nil
+}
+
+// Interval returns how often positions are marked to market
+func (c *GuardianConfig) Interval() time.Duration {
+	if c.IntervalMs == 0 {
+		return DefaultGuardianIntervalMs * time.Millisecond
+	}
+	return time.Duration(c.IntervalMs) * time.Millisecond
+}
+
+// RPCSubject returns the prefix the guardian RPCs of a service are served under
+func (c *GuardianConfig) RPCSubject(service string) string {
+	if c.Subject == "" {
+		return guardian.DefaultSubject + "." + service
+	}
+	return c.Subject
+}
+
+// Guardian opens the guardian, replaying the journal, with positions valued
+// at prices
+func (c *GuardianConfig) Guardian(prices pms.Converter) (*guardian.Guardian, error) {
+	strategies := make(map[string]guardian.Thresholds, len(c.Strategies))
+	for strategy, limits := range c.Strategies {
+		strategies[strategy] = limits.Thresholds()
+	}
+	return guardian.Open(guardian.Options{
+		Currency:   c.Currency,
+		Default:    c.Default.Thresholds(),
+		Strategies: strategies,
+		Prices:     prices,
+		Journal:    c.Journal,
+	})
+}
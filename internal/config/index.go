@@ -0,0 +1,85 @@
+package config
+
+import (
+	"encoding/json"
+	"fmt"
+)
+
+// ComponentConfig represents a single instrument of an index basket
+type ComponentConfig struct {
+	Exchange   string  `json:"exchange"`
+	Instrument string  `json:"instrument"`
+	Symbol     string  `json:"symbol"`
+	Weight     float64 `json:"weight"`
+}
+
+// BasketConfig represents the definition of a synthetic index
+type BasketConfig struct {
+	Name       string            `json:"name"`
+	Method     string            `json:"method"`
+	Divisor    float64           `json:"divisor"`
+	BaseValue  float64           `json:"base_value"`
+	Components []ComponentConfig `json:"components"`
+}
+
+// IndexConfig represents the configuration of the index calculator
+type IndexConfig struct {
+	Inputs        []string          `json:"inputs"`          // Trade subjects the components are read from
+	MinIntervalMs int64             `json:"min_interval_ms"` // Minimum interval between two publications of the same index
+	Baskets       []BasketConfig    `json:"baskets"`
+	Diagnostics   DiagnosticsConfig `json:"diagnostics"` // Runtime diagnostics endpoints
+	NATS          NATSConfig        `json:"nats"`        // Stream and subject the index prices are published to
+}
+
+// LoadIndexConfig loads the index calculator configuration from a JSON file
+func LoadIndexConfig(filePath string) (*IndexConfig, error) {
+	if filePath == "" {
+		return nil, fmt.Errorf("config file path cannot be empty")
+	}
+
+	data, err := ReadConfig(filePath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read config file %s: %w", filePath, err)
+	}
+
+	var config IndexConfig
+	if err := json.Unmarshal(data, &config); err != nil {
+		return nil, fmt.Errorf("failed to parse config file %s: %w", filePath, err)
+	}
+
+	if err := config.Validate(); err != nil {
+		return nil, fmt.Errorf("invalid configuration in %s: %w", filePath, err)
+	}
+
+	return &config, nil
+}
+
+// Validate validates the index calculator configuration
+func (c *IndexConfig) Validate() error {
+	if len(c.Inputs) == 0 {
+		return fmt.Errorf("inputs cannot be empty")
+	}
+
+	if len(c.Baskets) == 0 {
+		return fmt.Errorf("baskets cannot be empty")
+	}
+
+	if c.MinIntervalMs < 0 {
+		return fmt.Errorf("min_interval_ms cannot be negative")
+	}
+
+	for i, basket := range c.Baskets {
+		if basket.Name == "" {
+			return fmt.Errorf("baskets[%d].name cannot be empty", i)
+		}
+		if len(basket.Components) == 0 {
+			return fmt.Errorf("baskets[%d].components cannot be empty", i)
+		}
+	}
+
+	if err := c.Diagnostics.Validate(); err != nil {
+		return err
+	}
+
+	return c.NATS.Validate()
+}
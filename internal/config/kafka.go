@@ -0,0 +1,99 @@
+package config
+
+import (
+	"encoding/json"
+	"fmt"
+)
+
+// KafkaForwardConfig mirrors a JetStream subject to a Kafka topic
+type KafkaForwardConfig struct {
+	Stream   string `json:"stream"`   // JetStream stream holding the subject
+	Subject  string `json:"subject"`  // Subject filter, wildcards allowed
+	Durable  string `json:"durable"`  // Durable consumer name, tracks delivery progress
+	Topic    string `json:"topic"`    // Target Kafka topic
+	Encoding string `json:"encoding"` // protobuf or json
+}
+
+// KafkaReverseConfig mirrors a Kafka topic back to NATS
+type KafkaReverseConfig struct {
+	Topic    string `json:"topic"`
+	Subject  string `json:"subject"`  // Target subject, empty uses the subject recorded on the record
+	Encoding string `json:"encoding"` // protobuf or json
+	Start    string `json:"start"`    // earliest or latest, used when no checkpoint exists
+}
+
+// KafkaBridgeConfig represents the configuration of the Kafka bridge
+type KafkaBridgeConfig struct {
+	Brokers        []string             `json:"brokers"`
+	ClientID       string               `json:"client_id"`
+	TimeoutMs      int64                `json:"timeout_ms"`
+	BatchSize      int                  `json:"batch_size"`      // Messages fetched from JetStream per produce request
+	CheckpointFile string               `json:"checkpoint_file"` // Offsets of reverse routes
+	Forward        []KafkaForwardConfig `json:"forward"`
+	Reverse        []KafkaReverseConfig `json:"reverse"`
+	Diagnostics    DiagnosticsConfig    `json:"diagnostics"` // Runtime diagnostics endpoints
+	NATS           NATSConfig           `json:"nats"`
+}
+
+// LoadKafkaBridgeConfig loads the Kafka bridge configuration from a JSON file
+func LoadKafkaBridgeConfig(filePath string) (*KafkaBridgeConfig, error) {
+	if filePath == "" {
+		return nil, fmt.Errorf("config file path cannot be empty")
+	}
+
+	data, err := ReadConfig(filePath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read config file %s: %w", filePath, err)
+	}
+
+	var config KafkaBridgeConfig
+	if err := json.Unmarshal(data, &config); err != nil {
+		return nil, fmt.Errorf("failed to parse config file %s: %w", filePath, err)
+	}
+
+	if err := config.Validate(); err != nil {
+		return nil, fmt.Errorf("invalid configuration in %s: %w", filePath, err)
+	}
+
+	return &config, nil
+}
+
+// Validate validates the Kafka bridge configuration
+func (c *KafkaBridgeConfig) Validate() error {
+	if len(c.Brokers) == 0 {
+		return fmt.Errorf("brokers cannot be empty")
+	}
+
+	if len(c.Forward) == 0 && len(c.Reverse) == 0 {
+		return fmt.Errorf("at least one forward or reverse route is required")
+	}
+
+	if c.BatchSize < 0 {
+		return fmt.Errorf("batch_size cannot be negative")
+	}
+
+	for i, route := range c.Forward {
+		if route.Stream == "" || route.Subject == "" || route.Durable == "" || route.Topic == "" {
+			return fmt.Errorf("forward[%d] requires stream, subject, durable and topic", i)
+		}
+	}
+
+	for i, route := range c.Reverse {
+		if route.Topic == "" {
+			return fmt.Errorf("reverse[%d].topic cannot be empty", i)
+		}
+		if route.Start != "" && route.Start != "earliest" && route.Start != "latest" {
+			return fmt.Errorf("reverse[%d].start must be earliest or latest, got %s", i, route.Start)
+		}
+	}
+
+	if len(c.Reverse) > 0 && c.CheckpointFile == "" {
+		return fmt.Errorf("checkpoint_file is required for reverse routes")
+	}
+
+	if err := c.Diagnostics.Validate(); err != nil {
+		return err
+	}
+
+	return c.NATS.ValidateURIs()
+}
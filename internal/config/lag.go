@@ -0,0 +1,96 @@
+package config
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/BullionBear/sequex/internal/lag"
+	"github.com/BullionBear/sequex/pkg/eventbus"
+)
+
+// LagConfig represents the configuration of the consumer lag monitor
+type LagConfig struct {
+	IntervalMs  int64             `json:"interval_ms"` // Interval between polls of the consumers, 30s when omitted
+	WindowMs    int64             `json:"window_ms"`   // Growth of the lag of a consumer raising an alert
+	MinLag      uint64            `json:"min_lag"`     // Lags below are never alerted on
+	Streams     []string          `json:"streams"`     // Streams whose durable consumers are watched, all when empty
+	Diagnostics DiagnosticsConfig `json:"diagnostics"` // Runtime diagnostics endpoints
+	NATS        NATSConfig        `json:"nats"`        // Stream and subject the alerts are published to
+}
+
+// LoadLagConfig loads the consumer lag monitor configuration from a JSON file
+func LoadLagConfig(filePath string) (*LagConfig, error) {
+	if filePath == "" {
+		return nil, fmt.Errorf("config file path cannot be empty")
+	}
+
+	data, err := ReadConfig(filePath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read config file %s: %w", filePath, err)
+	}
+
+	var config LagConfig
+	if err := json.Unmarshal(data, &config); err != nil {
+		return nil, fmt.Errorf("failed to parse config file %s: %w", filePath, err)
+	}
+
+	if err := config.Validate(); err != nil {
+		return nil, fmt.Errorf("invalid configuration in %s: %w", filePath, err)
+	}
+
+	return &config, nil
+}
+
+// Validate validates the consumer lag monitor configuration
+func (c *LagConfig) Validate() error {
+	if c.IntervalMs < 0 {
+		return fmt.Errorf("interval_ms cannot be negative")
+	}
+
+	if c.WindowMs <= 0 {
+		return fmt.Errorf("window_ms must be positive")
+	}
+
+	if c.WindowMs < c.Interval().Milliseconds() {
+		return fmt.Errorf("window_ms cannot be shorter than interval_ms")
+	}
+
+	if err := c.Options().Validate(); err != nil {
+		return err
+	}
+
+	if err := c.Diagnostics.Validate(); err != nil {
+		return err
+	}
+
+	if err := c.NATS.Validate(); err != nil {
+		return err
+	}
+
+	if err := eventbus.ValidatePattern(c.NATS.Subject); err != nil {
+		return fmt.Errorf("invalid nats.subject: %w", err)
+	}
+	if strings.ContainsAny(c.NATS.Subject, "*>") {
+		return fmt.Errorf("nats.subject cannot contain wildcards")
+	}
+	return nil
+}
+
+// Interval returns the interval between polls of the consumers
+func (c *LagConfig) Interval() time.Duration {
+	if c.IntervalMs == 0 {
+		return 30 * time.Second
+	}
+	return time.Duration(c.IntervalMs) * time.Millisecond
+}
+
+// Options converts the configuration into watcher options
+func (c *LagConfig) Options() lag.Options {
+	return lag.Options{
+		Streams: c.Streams,
+		Window:  time.Duration(c.WindowMs) * time.Millisecond,
+		MinLag:  c.MinLag,
+	}
+}
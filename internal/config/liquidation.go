@@ -0,0 +1,123 @@
+package config
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/BullionBear/sequex/internal/liquidation"
+	"github.com/BullionBear/sequex/internal/model/sqx"
+	"github.com/BullionBear/sequex/pkg/eventbus"
+)
+
+// LiquidationConfig represents the configuration of the liquidation aggregator
+type LiquidationConfig struct {
+	Host              string             `json:"host"`
+	Port              int                `json:"port"`                // REST query endpoint
+	Inputs            []string           `json:"inputs"`              // Liquidation subjects
+	Binance           []string           `json:"binance"`             // Symbols whose Binance USDⓈ-M liquidations are streamed directly, e.g. BTC-USDT
+	WindowMs          int64              `json:"window_ms"`           // Rolling window, 1h when omitted
+	StepRatio         float64            `json:"step_ratio"`          // Default heatmap bucket width relative to the last price, 0.001 when omitted
+	SummaryIntervalMs int64              `json:"summary_interval_ms"` // Interval between summary publications, 0 disables them
+	WarmStartSubject  string             `json:"warm_start_subject"`  // Snapshot subject of a running instance the window is restored from at start
+	Dispatch          DispatchConfigs    `json:"dispatch,omitempty"`  // Per-subject handler concurrency
+	Recovery          RecoveryConfig     `json:"recovery"`            // Quarantine of subjects that keep crashing the handlers
+	Limits            LimitsConfig       `json:"limits"`              // Resource guards throttling the intake
+	FeatureFlags      FeatureFlagsConfig `json:"feature_flags"`       // Gated behaviors being rolled out
+	Diagnostics       DiagnosticsConfig  `json:"diagnostics"`         // Runtime diagnostics endpoints
+	NATS              NATSConfig         `json:"nats"`                // Stream and subject the summaries are published to
+}
+
+// LoadLiquidationConfig loads the liquidation aggregator configuration from a JSON file
+func LoadLiquidationConfig(filePath string) (*LiquidationConfig, error) {
+	if filePath == "" {
+		return nil, fmt.Errorf("config file path cannot be empty")
+	}
+
+	data, err := ReadConfig(filePath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read config file %s: %w", filePath, err)
+	}
+
+	var config LiquidationConfig
+	if err := json.Unmarshal(data, &config); err != nil {
+		return nil, fmt.Errorf("failed to parse config file %s: %w", filePath, err)
+	}
+
+	if err := config.Validate(); err != nil {
+		return nil, fmt.Errorf("invalid configuration in %s: %w", filePath, err)
+	}
+
+	return &config, nil
+}
+
+// Validate validates the liquidation aggregator configuration
+func (c *LiquidationConfig) Validate() error {
+	if c.Port <= 0 || c.Port > 65535 {
+		return fmt.Errorf("port must be between 1 and 65535, got %d", c.Port)
+	}
+
+	if len(c.Inputs) == 0 && len(c.Binance) == 0 {
+		return fmt.Errorf("inputs and binance cannot both be empty")
+	}
+
+	for i, subject := range c.Inputs {
+		if err := eventbus.ValidatePattern(subject); err != nil {
+			return fmt.Errorf("invalid inputs[%d]: %w", i, err)
+		}
+	}
+
+	for i, symbol := range c.Binance {
+		if _, err := sqx.NewSymbolFromStr(symbol); err != nil {
+			return fmt.Errorf("invalid binance[%d]: %w", i, err)
+		}
+	}
+
+	if c.WindowMs < 0 || c.SummaryIntervalMs < 0 {
+		return fmt.Errorf("window_ms and summary_interval_ms cannot be negative")
+	}
+
+	if c.StepRatio < 0 {
+		return fmt.Errorf("step_ratio cannot be negative")
+	}
+
+	if c.WarmStartSubject != "" {
+		if err := eventbus.ValidatePattern(c.WarmStartSubject); err != nil {
+			return fmt.Errorf("invalid warm_start_subject: %w", err)
+		}
+		if strings.ContainsAny(c.WarmStartSubject, "*>") {
+			return fmt.Errorf("warm_start_subject cannot contain wildcards")
+		}
+	}
+
+	if err := c.Dispatch.Validate(); err != nil {
+		return err
+	}
+
+	if err := c.Recovery.Validate(); err != nil {
+		return err
+	}
+
+	if err := c.Limits.Validate(); err != nil {
+		return err
+	}
+
+	if err := c.FeatureFlags.Validate(); err != nil {
+		return err
+	}
+
+	if err := c.Diagnostics.Validate(); err != nil {
+		return err
+	}
+
+	return c.NATS.Validate()
+}
+
+// Options converts the configuration into aggregator options
+func (c *LiquidationConfig) Options() liquidation.Options {
+	return liquidation.Options{
+		Window:    time.Duration(c.WindowMs) * time.Millisecond,
+		StepRatio: c.StepRatio,
+	}
+}
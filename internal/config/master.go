@@ -0,0 +1,133 @@
+package config
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/BullionBear/sequex/api"
+)
+
+// APIDeprecationConfig announces the retirement of an API version
+type APIDeprecationConfig struct {
+	Version   string `json:"version"`   // e.g. v1
+	Since     string `json:"since"`     // Deprecation date, YYYY-MM-DD
+	Sunset    string `json:"sunset"`    // Removal date, YYYY-MM-DD, empty if not scheduled
+	Successor string `json:"successor"` // Version replacing it, e.g. v2
+}
+
+// MasterConfig represents the configuration of the master control API
+type MasterConfig struct {
+	Host             string                 `json:"host"`
+	Port             int                    `json:"port"`
+	RequestTimeoutMs int64                  `json:"request_timeout_ms"` // Timeout of RPCs sent to nodes
+	Deprecations     []APIDeprecationConfig `json:"deprecations"`       // Deprecated API versions
+	FX               FXConfig               `json:"fx"`                 // Conversion rates of portfolio valuations
+	Risk             PortfolioRiskConfig    `json:"risk"`               // Return history of portfolio risk simulations
+	Portfolios       PortfolioStoreConfig   `json:"portfolios"`         // Journal and end of day snapshots of the portfolios
+	Executions       ExecutionHistoryConfig `json:"executions"`         // Order and fill history
+	Accruals         AccrualConfig          `json:"accruals"`           // Funding and margin interest booked to a portfolio
+	Pipeline         PipelineConfig         `json:"pipeline"`           // Nodes run by the master in dependency order
+	Apps             AppsConfig             `json:"apps"`               // Applications deployed with sqx apply
+	Tenants          []TenantConfig         `json:"tenants"`            // Teams sharing the deployment, every request is unscoped when empty
+	NATS             NATSConfig             `json:"nats"`
+}
+
+// LoadMasterConfig loads the master configuration from a JSON file
+func LoadMasterConfig(filePath string) (*MasterConfig, error) {
+	if filePath == "" {
+		return nil, fmt.Errorf("config file path cannot be empty")
+	}
+
+	data, err := ReadConfig(filePath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read config file %s: %w", filePath, err)
+	}
+
+	var config MasterConfig
+	if err := json.Unmarshal(data, &config); err != nil {
+		return nil, fmt.Errorf("failed to parse config file %s: %w", filePath, err)
+	}
+
+	if err := config.Validate(); err != nil {
+		return nil, fmt.Errorf("invalid configuration in %s: %w", filePath, err)
+	}
+
+	return &config, nil
+}
+
+// Validate validates the master configuration
+func (c *MasterConfig) Validate() error {
+	if c.Port <= 0 || c.Port > 65535 {
+		return fmt.Errorf("port must be between 1 and 65535")
+	}
+
+	if c.RequestTimeoutMs <= 0 {
+		return fmt.Errorf("request_timeout_ms must be positive")
+	}
+
+	if _, err := c.APIOptions(); err != nil {
+		return err
+	}
+
+	if err := c.FX.Validate(); err != nil {
+		return err
+	}
+
+	if err := c.Risk.Validate(); err != nil {
+		return err
+	}
+
+	if err := c.Portfolios.Validate(); err != nil {
+		return err
+	}
+
+	if err := c.Executions.Validate(); err != nil {
+		return err
+	}
+
+	if err := c.Accruals.Validate(); err != nil {
+		return err
+	}
+
+	if err := c.Pipeline.Validate(); err != nil {
+		return err
+	}
+
+	if err := c.Apps.Validate(); err != nil {
+		return err
+	}
+
+	if _, err := TenantRegistry(c.Tenants); err != nil {
+		return err
+	}
+
+	return c.NATS.ValidateURIs()
+}
+
+// APIOptions converts the configuration into router options
+func (c *MasterConfig) APIOptions() (api.Options, error) {
+	opts := api.Options{Deprecations: make(map[string]api.Deprecation, len(c.Deprecations))}
+	for i, d := range c.Deprecations {
+		if d.Version != api.V1 && d.Version != api.V2 {
+			return opts, fmt.Errorf("deprecations[%d]: unknown API version %q", i, d.Version)
+		}
+		var deprecation api.Deprecation
+		var err error
+		if d.Since != "" {
+			if deprecation.Since, err = time.Parse(time.DateOnly, d.Since); err != nil {
+				return opts, fmt.Errorf("deprecations[%d].since: %w", i, err)
+			}
+		}
+		if d.Sunset != "" {
+			if deprecation.Sunset, err = time.Parse(time.DateOnly, d.Sunset); err != nil {
+				return opts, fmt.Errorf("deprecations[%d].sunset: %w", i, err)
+			}
+		}
+		if d.Successor != "" {
+			deprecation.Successor = api.BasePath + "/" + d.Successor
+		}
+		opts.Deprecations[d.Version] = deprecation
+	}
+	return opts, nil
+}
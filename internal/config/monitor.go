@@ -0,0 +1,197 @@
+package config
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/BullionBear/sequex/internal/monitor"
+	"github.com/BullionBear/sequex/pkg/eventbus"
+)
+
+// StablecoinConfig checks that a stablecoin trades near its peg on every venue
+type StablecoinConfig struct {
+	Symbol       string  `json:"symbol"`        // e.g. USDC-USDT
+	Peg          float64 `json:"peg"`           // Expected price, 1 when omitted
+	MaxDeviation float64 `json:"max_deviation"` // Tolerated relative distance to the peg, e.g. 0.005
+	MaxSpread    float64 `json:"max_spread"`    // Tolerated relative spread between venues, 0 disables the check
+}
+
+// ReferenceConfig checks that an index stays close to the venues trading its underlying
+type ReferenceConfig struct {
+	Index        string  `json:"index"`         // Index name as published by the index calculator
+	Symbol       string  `json:"symbol"`        // Underlying, e.g. BTC-USDT
+	MaxDeviation float64 `json:"max_deviation"` // Tolerated relative distance to the trimmed median
+	Trim         float64 `json:"trim"`          // Fraction of venues dropped at each end, in [0, 0.5]
+	MinVenues    int     `json:"min_venues"`    // Fresh venues required before comparing, 1 when omitted
+}
+
+// AnomalyConfig configures the statistical checks of the feed message rates
+// and spreads
+type AnomalyConfig struct {
+	BucketMs   int64   `json:"bucket_ms"`    // Interval messages are counted over, 1000 when omitted
+	HalfLifeMs int64   `json:"half_life_ms"` // Half-life of the baselines, 10 minutes when omitted
+	Threshold  float64 `json:"threshold"`    // Z-score raising an alert, 4 when omitted
+	MinBuckets int     `json:"min_buckets"`  // Buckets a baseline is learnt over before alerting, 60 when omitted
+}
+
+// Options converts the configuration into anomaly options
+func (c *AnomalyConfig) Options() *monitor.AnomalyOptions {
+	if c == nil {
+		return nil
+	}
+	return &monitor.AnomalyOptions{
+		Bucket:     time.Duration(c.BucketMs) * time.Millisecond,
+		HalfLife:   time.Duration(c.HalfLifeMs) * time.Millisecond,
+		Threshold:  c.Threshold,
+		MinBuckets: c.MinBuckets,
+	}
+}
+
+// RiskConfig configures the risk control messages sent while alerts are active
+type RiskConfig struct {
+	Subject string `json:"subject"` // Subject risk limits are toggled on, empty disables it
+}
+
+// MonitorConfig represents the configuration of the reference price monitor
+type MonitorConfig struct {
+	Trades       []string           `json:"trades"`     // Trade subjects venue prices are read from
+	Indexes      []string           `json:"indexes"`    // Index price subjects to check
+	Quotes       []string           `json:"quotes"`     // Quote subjects whose rates and spreads are checked for anomalies
+	MaxAgeMs     int64              `json:"max_age_ms"` // Venue prices older than this are ignored, 0 keeps them forever
+	Stablecoins  []StablecoinConfig `json:"stablecoins"`
+	References   []ReferenceConfig  `json:"references"`
+	Anomaly      *AnomalyConfig     `json:"anomaly,omitempty"` // Statistical checks of the feeds, disabled when omitted
+	Risk         RiskConfig         `json:"risk"`
+	Dispatch     DispatchConfigs    `json:"dispatch,omitempty"` // Per-subject handler concurrency
+	Recovery     RecoveryConfig     `json:"recovery"`           // Quarantine of subjects that keep crashing the handlers
+	Limits       LimitsConfig       `json:"limits"`             // Resource guards throttling the intake
+	FeatureFlags FeatureFlagsConfig `json:"feature_flags"`      // Gated behaviors being rolled out
+	Diagnostics  DiagnosticsConfig  `json:"diagnostics"`        // Runtime diagnostics endpoints
+	NATS         NATSConfig         `json:"nats"`               // Stream and subject the alerts are published to
+}
+
+// LoadMonitorConfig loads the monitor configuration from a JSON file
+func LoadMonitorConfig(filePath string) (*MonitorConfig, error) {
+	if filePath == "" {
+		return nil, fmt.Errorf("config file path cannot be empty")
+	}
+
+	data, err := ReadConfig(filePath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read config file %s: %w", filePath, err)
+	}
+
+	var config MonitorConfig
+	if err := json.Unmarshal(data, &config); err != nil {
+		return nil, fmt.Errorf("failed to parse config file %s: %w", filePath, err)
+	}
+
+	if err := config.Validate(); err != nil {
+		return nil, fmt.Errorf("invalid configuration in %s: %w", filePath, err)
+	}
+
+	return &config, nil
+}
+
+// Validate validates the monitor configuration
+func (c *MonitorConfig) Validate() error {
+	if len(c.Trades) == 0 {
+		return fmt.Errorf("trades cannot be empty")
+	}
+
+	if len(c.Stablecoins) == 0 && len(c.References) == 0 && c.Anomaly == nil {
+		return fmt.Errorf("stablecoins, references and anomaly cannot all be empty")
+	}
+
+	if len(c.Quotes) > 0 && c.Anomaly == nil {
+		return fmt.Errorf("quotes are only read for the anomaly checks")
+	}
+
+	if len(c.References) > 0 && len(c.Indexes) == 0 {
+		return fmt.Errorf("indexes cannot be empty when references are checked")
+	}
+
+	for i, subject := range c.Trades {
+		if err := eventbus.ValidatePattern(subject); err != nil {
+			return fmt.Errorf("invalid trades[%d]: %w", i, err)
+		}
+	}
+	for i, subject := range c.Indexes {
+		if err := eventbus.ValidatePattern(subject); err != nil {
+			return fmt.Errorf("invalid indexes[%d]: %w", i, err)
+		}
+	}
+	for i, subject := range c.Quotes {
+		if err := eventbus.ValidatePattern(subject); err != nil {
+			return fmt.Errorf("invalid quotes[%d]: %w", i, err)
+		}
+	}
+
+	if c.Anomaly != nil && (c.Anomaly.BucketMs < 0 || c.Anomaly.HalfLifeMs < 0) {
+		return fmt.Errorf("anomaly.bucket_ms and anomaly.half_life_ms cannot be negative")
+	}
+
+	if c.MaxAgeMs < 0 {
+		return fmt.Errorf("max_age_ms cannot be negative")
+	}
+
+	if _, err := monitor.New(c.Options()); err != nil {
+		return err
+	}
+
+	if c.Risk.Subject != "" {
+		if err := eventbus.ValidatePattern(c.Risk.Subject); err != nil {
+			return fmt.Errorf("invalid risk.subject: %w", err)
+		}
+		if strings.ContainsAny(c.Risk.Subject, "*>") {
+			return fmt.Errorf("risk.subject cannot contain wildcards")
+		}
+	}
+
+	if err := c.Dispatch.Validate(); err != nil {
+		return err
+	}
+
+	if err := c.Recovery.Validate(); err != nil {
+		return err
+	}
+
+	if err := c.Limits.Validate(); err != nil {
+		return err
+	}
+
+	if err := c.FeatureFlags.Validate(); err != nil {
+		return err
+	}
+
+	if err := c.Diagnostics.Validate(); err != nil {
+		return err
+	}
+
+	return c.NATS.Validate()
+}
+
+// Options converts the rules into monitor options
+func (c *MonitorConfig) Options() monitor.Options {
+	opts := monitor.Options{MaxAge: time.Duration(c.MaxAgeMs) * time.Millisecond, Anomaly: c.Anomaly.Options()}
+	for _, s := range c.Stablecoins {
+		opts.Stablecoins = append(opts.Stablecoins, monitor.StablecoinRule{
+			Symbol:       s.Symbol,
+			Peg:          s.Peg,
+			MaxDeviation: s.MaxDeviation,
+			MaxSpread:    s.MaxSpread,
+		})
+	}
+	for _, r := range c.References {
+		opts.References = append(opts.References, monitor.ReferenceRule{
+			Index:        r.Index,
+			Symbol:       r.Symbol,
+			MaxDeviation: r.MaxDeviation,
+			Trim:         r.Trim,
+			MinVenues:    r.MinVenues,
+		})
+	}
+	return opts
+}
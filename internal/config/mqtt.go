@@ -0,0 +1,74 @@
+package config
+
+import (
+	"encoding/json"
+	"fmt"
+)
+
+// MQTTRouteConfig maps a NATS subject to MQTT topics
+type MQTTRouteConfig struct {
+	Subject string `json:"subject"` // NATS subject, wildcards allowed
+	QoS     byte   `json:"qos"`     // 0, 1 or 2
+	Retain  bool   `json:"retain"`  // Keep the last payload for late subscribers
+}
+
+// MQTTBridgeConfig represents the configuration of the MQTT bridge
+type MQTTBridgeConfig struct {
+	Broker      string            `json:"broker"` // e.g. tcp://localhost:1883
+	ClientID    string            `json:"client_id"`
+	Username    string            `json:"username"`
+	Password    string            `json:"password"`
+	TopicPrefix string            `json:"topic_prefix"` // Prepended to every MQTT topic
+	Routes      []MQTTRouteConfig `json:"routes"`
+	Diagnostics DiagnosticsConfig `json:"diagnostics"` // Runtime diagnostics endpoints
+	NATS        NATSConfig        `json:"nats"`
+}
+
+// LoadMQTTBridgeConfig loads the MQTT bridge configuration from a JSON file
+func LoadMQTTBridgeConfig(filePath string) (*MQTTBridgeConfig, error) {
+	if filePath == "" {
+		return nil, fmt.Errorf("config file path cannot be empty")
+	}
+
+	data, err := ReadConfig(filePath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read config file %s: %w", filePath, err)
+	}
+
+	var config MQTTBridgeConfig
+	if err := json.Unmarshal(data, &config); err != nil {
+		return nil, fmt.Errorf("failed to parse config file %s: %w", filePath, err)
+	}
+
+	if err := config.Validate(); err != nil {
+		return nil, fmt.Errorf("invalid configuration in %s: %w", filePath, err)
+	}
+
+	return &config, nil
+}
+
+// Validate validates the MQTT bridge configuration
+func (c *MQTTBridgeConfig) Validate() error {
+	if c.Broker == "" {
+		return fmt.Errorf("broker cannot be empty")
+	}
+
+	if len(c.Routes) == 0 {
+		return fmt.Errorf("routes cannot be empty")
+	}
+
+	for i, route := range c.Routes {
+		if route.Subject == "" {
+			return fmt.Errorf("routes[%d].subject cannot be empty", i)
+		}
+		if route.QoS > 2 {
+			return fmt.Errorf("routes[%d].qos must be 0, 1 or 2, got %d", i, route.QoS)
+		}
+	}
+
+	if err := c.Diagnostics.Validate(); err != nil {
+		return err
+	}
+
+	return c.NATS.ValidateURIs()
+}
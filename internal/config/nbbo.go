@@ -0,0 +1,65 @@
+package config
+
+import (
+	"encoding/json"
+	"fmt"
+)
+
+// NBBOConfig represents the configuration of the consolidated quote service
+type NBBOConfig struct {
+	Inputs       []string          `json:"inputs"`         // Quote subjects of every venue consolidated
+	Symbols      []string          `json:"symbols"`        // Symbols a BBO is maintained for, e.g. BTC-USDT
+	StaleAfterMs int64             `json:"stale_after_ms"` // Venues not quoting for this long are left out of the BBO, never when 0
+	HistoryMs    int64             `json:"history_ms"`     // How long past BBOs are kept for point-in-time queries, only the latest when 0
+	RPCSubject   string            `json:"rpc_subject"`    // Prefix BBOs are queried under, nbbo when omitted
+	Diagnostics  DiagnosticsConfig `json:"diagnostics"`    // Runtime diagnostics endpoints
+	NATS         NATSConfig        `json:"nats"`           // Stream and subject the BBO changes are published to
+}
+
+// LoadNBBOConfig loads the consolidated quote configuration from a JSON file
+func LoadNBBOConfig(filePath string) (*NBBOConfig, error) {
+	if filePath == "" {
+		return nil, fmt.Errorf("config file path cannot be empty")
+	}
+
+	data, err := ReadConfig(filePath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read config file %s: %w", filePath, err)
+	}
+
+	var config NBBOConfig
+	if err := json.Unmarshal(data, &config); err != nil {
+		return nil, fmt.Errorf("failed to parse config file %s: %w", filePath, err)
+	}
+
+	if err := config.Validate(); err != nil {
+		return nil, fmt.Errorf("invalid configuration in %s: %w", filePath, err)
+	}
+
+	return &config, nil
+}
+
+// Validate validates the consolidated quote configuration
+func (c *NBBOConfig) Validate() error {
+	if len(c.Inputs) == 0 {
+		return fmt.Errorf("inputs cannot be empty")
+	}
+
+	if len(c.Symbols) == 0 {
+		return fmt.Errorf("symbols cannot be empty")
+	}
+
+	if c.StaleAfterMs < 0 {
+		return fmt.Errorf("stale_after_ms cannot be negative")
+	}
+
+	if c.HistoryMs < 0 {
+		return fmt.Errorf("history_ms cannot be negative")
+	}
+
+	if err := c.Diagnostics.Validate(); err != nil {
+		return err
+	}
+
+	return c.NATS.Validate()
+}
@@ -0,0 +1,159 @@
+package config
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/BullionBear/sequex/pkg/eventbus"
+	"github.com/BullionBear/sequex/pkg/featureflag"
+	"github.com/BullionBear/sequex/pkg/node"
+)
+
+// DispatchConfig configures the concurrency of a node handler
+type DispatchConfig struct {
+	Subject   string `json:"subject"`    // Subject pattern the handler is registered on
+	Workers   int    `json:"workers"`    // 0 handles messages on the subscription goroutine
+	QueueSize int    `json:"queue_size"` // Messages buffered per worker
+	OrderBy   string `json:"order_by"`   // Ordering key: none, subject or symbol
+}
+
+// Validate validates the dispatch configuration
+func (d *DispatchConfig) Validate() error {
+	if err := eventbus.ValidatePattern(d.Subject); err != nil {
+		return err
+	}
+	if d.Workers < 0 {
+		return fmt.Errorf("%s: workers cannot be negative", d.Subject)
+	}
+	if d.QueueSize < 0 {
+		return fmt.Errorf("%s: queue_size cannot be negative", d.Subject)
+	}
+	switch d.OrderBy {
+	case "", "none", "subject", "symbol":
+	default:
+		return fmt.Errorf("%s: order_by must be none, subject or symbol", d.Subject)
+	}
+	return nil
+}
+
+// DispatchOptions converts the configuration into node dispatch options
+func (d *DispatchConfig) DispatchOptions() node.DispatchOptions {
+	opts := node.DispatchOptions{Workers: d.Workers, QueueSize: d.QueueSize}
+	switch d.OrderBy {
+	case "subject":
+		opts.Key = node.SubjectKey
+	case "symbol":
+		// Namespaced subjects are env.exchange.instrument.symbol.datatype
+		opts.Key = node.SubjectToken(3)
+	}
+	return opts
+}
+
+// DispatchConfigs is the per-subject dispatch configuration of a node
+type DispatchConfigs []DispatchConfig
+
+// Validate validates every entry and rejects duplicated subjects
+func (d DispatchConfigs) Validate() error {
+	seen := make(map[string]bool, len(d))
+	for i := range d {
+		if err := d[i].Validate(); err != nil {
+			return fmt.Errorf("dispatch[%d]: %w", i, err)
+		}
+		if seen[d[i].Subject] {
+			return fmt.Errorf("dispatch[%d]: duplicate subject %s", i, d[i].Subject)
+		}
+		seen[d[i].Subject] = true
+	}
+	return nil
+}
+
+// For returns the dispatch options of the handler registered on subject,
+// defaulting to inline handling
+func (d DispatchConfigs) For(subject string) node.DispatchOptions {
+	for i := range d {
+		if d[i].Subject == subject {
+			return d[i].DispatchOptions()
+		}
+	}
+	return node.DispatchOptions{}
+}
+
+// RecoveryConfig configures the quarantine of subjects whose messages keep
+// crashing a node handler
+type RecoveryConfig struct {
+	QuarantineAfter int   `json:"quarantine_after"` // Panics within the window that quarantine a subject, 0 disables it
+	WindowMs        int64 `json:"window_ms"`        // Sliding window panics are counted over
+	DurationMs      int64 `json:"duration_ms"`      // Quarantine length, 0 until released manually
+}
+
+// Validate validates the recovery configuration
+func (r *RecoveryConfig) Validate() error {
+	if r.QuarantineAfter < 0 {
+		return fmt.Errorf("recovery.quarantine_after cannot be negative")
+	}
+	if r.WindowMs < 0 || r.DurationMs < 0 {
+		return fmt.Errorf("recovery durations cannot be negative")
+	}
+	return nil
+}
+
+// RecoveryOptions converts the configuration into node recovery options
+func (r *RecoveryConfig) RecoveryOptions() node.RecoveryOptions {
+	return node.RecoveryOptions{
+		QuarantineAfter: r.QuarantineAfter,
+		Window:          time.Duration(r.WindowMs) * time.Millisecond,
+		Duration:        time.Duration(r.DurationMs) * time.Millisecond,
+	}
+}
+
+// LimitsConfig bounds the resources of a node, which throttles its intake
+// and reports a degraded status past them
+type LimitsConfig struct {
+	MaxGoroutines   int     `json:"max_goroutines"`    // 0 for no limit
+	MaxMemoryMB     int64   `json:"max_memory_mb"`     // Heap in use, 0 for no limit
+	MaxRate         float64 `json:"max_rate"`          // Messages per second over all subjects, 0 for no limit
+	RateBurst       int     `json:"rate_burst"`        // Messages handed over at once under max_rate, 1 when omitted
+	CheckIntervalMs int64   `json:"check_interval_ms"` // Of the goroutine and memory samples, 1s when omitted
+}
+
+// Validate validates the limits configuration
+func (l *LimitsConfig) Validate() error {
+	if l.MaxGoroutines < 0 || l.MaxMemoryMB < 0 || l.MaxRate < 0 || l.RateBurst < 0 {
+		return fmt.Errorf("limits cannot be negative")
+	}
+	if l.CheckIntervalMs < 0 {
+		return fmt.Errorf("limits.check_interval_ms cannot be negative")
+	}
+	return nil
+}
+
+// Limits converts the configuration into node limits
+func (l *LimitsConfig) Limits() node.Limits {
+	return node.Limits{
+		MaxGoroutines: l.MaxGoroutines,
+		MaxMemory:     uint64(l.MaxMemoryMB) << 20,
+		MaxRate:       l.MaxRate,
+		RateBurst:     l.RateBurst,
+		CheckInterval: time.Duration(l.CheckIntervalMs) * time.Millisecond,
+	}
+}
+
+// FeatureFlagsConfig configures the feature flags a node consults, see
+// pkg/featureflag
+type FeatureFlagsConfig struct {
+	Bucket string             `json:"bucket"` // Key-value bucket of the runtime overrides, none when omitted
+	Flags  []featureflag.Flag `json:"flags"`  // Configured values
+}
+
+// Validate validates the feature flags configuration
+func (f *FeatureFlagsConfig) Validate() error {
+	if _, err := featureflag.NewSet(f.Flags); err != nil {
+		return fmt.Errorf("feature_flags: %w", err)
+	}
+	return nil
+}
+
+// Set creates the set of the configured flags
+func (f *FeatureFlagsConfig) Set() (*featureflag.Set, error) {
+	return featureflag.NewSet(f.Flags)
+}
@@ -0,0 +1,109 @@
+package config
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/BullionBear/sequex/pkg/eventbus"
+)
+
+// OMSConfig represents the configuration of the entry of the OMS
+type OMSConfig struct {
+	OrderSubject       string            `json:"order_subject"`        // Order intents of strategies and gateways, e.g. oms.intent.order
+	CancelSubject      string            `json:"cancel_subject"`       // Cancel intents of strategies and gateways
+	RouteOrderSubject  string            `json:"route_order_subject"`  // Order intents routed on to the exchange, e.g. oms.route.order
+	RouteCancelSubject string            `json:"route_cancel_subject"` // Cancel intents routed on to the exchange
+	ExecutionSubject   string            `json:"execution_subject"`    // Execution reports of the orders on paper, e.g. oms.execution.paper
+	Tenants            []TenantConfig    `json:"tenants,omitempty"`    // Tenants whose intents enter under their subject prefix, their API keys are not needed
+	DryRun             *DryRunConfig     `json:"dry_run,omitempty"`    // Strategies kept off the exchange, none when omitted
+	BlueGreen          *BlueGreenConfig  `json:"blue_green,omitempty"` // Strategies run as a live and a shadow instance, none when omitted
+	ReportSubject      string            `json:"report_subject"`       // Execution reports of the exchange followed for the live instances, e.g. oms.execution.>, required with blue_green
+	FX                 FXConfig          `json:"fx"`                   // Prices orders on paper are filled at and PnLs of the instances valued at
+	Diagnostics        DiagnosticsConfig `json:"diagnostics"`          // Runtime diagnostics endpoints
+	NATS               NATSConfig        `json:"nats"`
+}
+
+// LoadOMSConfig loads the OMS configuration from a JSON file
+func LoadOMSConfig(filePath string) (*OMSConfig, error) {
+	if filePath == "" {
+		return nil, fmt.Errorf("config file path cannot be empty")
+	}
+
+	data, err := ReadConfig(filePath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read config file %s: %w", filePath, err)
+	}
+
+	var config OMSConfig
+	if err := json.Unmarshal(data, &config); err != nil {
+		return nil, fmt.Errorf("failed to parse config file %s: %w", filePath, err)
+	}
+
+	if err := config.Validate(); err != nil {
+		return nil, fmt.Errorf("invalid configuration in %s: %w", filePath, err)
+	}
+
+	return &config, nil
+}
+
+// Validate validates the OMS configuration
+func (c *OMSConfig) Validate() error {
+	for _, subject := range []struct {
+		name, value string
+	}{
+		{"order_subject", c.OrderSubject},
+		{"cancel_subject", c.CancelSubject},
+		{"route_order_subject", c.RouteOrderSubject},
+		{"route_cancel_subject", c.RouteCancelSubject},
+		{"execution_subject", c.ExecutionSubject},
+	} {
+		if subject.value == "" {
+			return fmt.Errorf("%s cannot be empty", subject.name)
+		}
+		for _, token := range strings.Split(subject.value, ".") {
+			if err := eventbus.ValidateToken(token); err != nil {
+				return fmt.Errorf("invalid %s: %w", subject.name, err)
+			}
+		}
+	}
+
+	// Intents routed on the subjects they came in on would enter again
+	if c.RouteOrderSubject == c.OrderSubject || c.RouteCancelSubject == c.CancelSubject {
+		return fmt.Errorf("route subjects must differ from the intent subjects")
+	}
+
+	if _, err := TenantRegistry(c.Tenants); err != nil {
+		return err
+	}
+
+	if c.DryRun != nil {
+		if err := c.DryRun.Validate(); err != nil {
+			return err
+		}
+	}
+
+	if c.BlueGreen != nil {
+		if err := c.BlueGreen.Validate(); err != nil {
+			return err
+		}
+		if c.ReportSubject == "" {
+			return fmt.Errorf("report_subject cannot be empty with blue_green")
+		}
+	}
+	if c.ReportSubject != "" {
+		if err := eventbus.ValidatePattern(c.ReportSubject); err != nil {
+			return fmt.Errorf("invalid report_subject: %w", err)
+		}
+	}
+
+	if err := c.FX.Validate(); err != nil {
+		return err
+	}
+
+	if err := c.Diagnostics.Validate(); err != nil {
+		return err
+	}
+
+	return c.NATS.ValidateURIs()
+}
@@ -0,0 +1,112 @@
+package config
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/BullionBear/sequex/internal/pipeline"
+	"github.com/BullionBear/sequex/pkg/eventbus"
+	"github.com/nats-io/nats.go"
+)
+
+// PipelineNodeConfig declares a node the master runs as a child process
+type PipelineNodeConfig struct {
+	Name           string   `json:"name"`
+	Command        string   `json:"command"`          // Executable, e.g. ./bin/feed-linux-amd64
+	Args           []string `json:"args"`             // e.g. ["-c", "config/trade-binance-spot-btcusdt.json"]
+	Env            []string `json:"env"`              // KEY=VALUE pairs added to the environment of the master
+	DependsOn      []string `json:"depends_on"`       // Nodes started and ready before this one
+	ReadySubject   string   `json:"ready_subject"`    // Subject the node answers once ready, e.g. diag.feed; empty is ready once started
+	ReadyTimeoutMs int64    `json:"ready_timeout_ms"` // Time the node has to become ready, 30000 when omitted
+	StopTimeoutMs  int64    `json:"stop_timeout_ms"`  // Time the node has to exit before it is killed, 10000 when omitted
+}
+
+// PipelineConfig is the graph of nodes started by the master in dependency
+// order and stopped in reverse order
+type PipelineConfig struct {
+	Nodes []PipelineNodeConfig `json:"nodes"` // Empty runs no node
+}
+
+// Validate validates the pipeline configuration, including its dependency graph
+func (p *PipelineConfig) Validate() error {
+	for i, n := range p.Nodes {
+		if err := eventbus.ValidateToken(n.Name); err != nil {
+			return fmt.Errorf("invalid pipeline.nodes[%d].name: %w", i, err)
+		}
+		if n.Command == "" {
+			return fmt.Errorf("pipeline node %s: command cannot be empty", n.Name)
+		}
+		for _, kv := range n.Env {
+			if !strings.Contains(kv, "=") {
+				return fmt.Errorf("pipeline node %s: env %q is not KEY=VALUE", n.Name, kv)
+			}
+		}
+		if n.ReadySubject != "" {
+			if err := eventbus.ValidatePattern(n.ReadySubject); err != nil {
+				return fmt.Errorf("pipeline node %s: invalid ready_subject: %w", n.Name, err)
+			}
+			if strings.ContainsAny(n.ReadySubject, "*>") {
+				return fmt.Errorf("pipeline node %s: ready_subject cannot contain wildcards", n.Name)
+			}
+		}
+		if n.ReadyTimeoutMs < 0 || n.StopTimeoutMs < 0 {
+			return fmt.Errorf("pipeline node %s: timeouts cannot be negative", n.Name)
+		}
+	}
+	_, err := pipeline.Order(p.specs(nil))
+	if err != nil {
+		return fmt.Errorf("invalid pipeline: %w", err)
+	}
+	return nil
+}
+
+func (p *PipelineConfig) specs(conn *nats.Conn) []pipeline.Spec {
+	specs := make([]pipeline.Spec, len(p.Nodes))
+	for i, n := range p.Nodes {
+		specs[i] = pipeline.Spec{
+			Name:         n.Name,
+			DependsOn:    n.DependsOn,
+			Start:        pipeline.Command(n.Command, n.Args, n.Env),
+			ReadyTimeout: time.Duration(n.ReadyTimeoutMs) * time.Millisecond,
+			StopTimeout:  time.Duration(n.StopTimeoutMs) * time.Millisecond,
+		}
+		if n.ReadySubject != "" && conn != nil {
+			specs[i].Ready = pipeline.RequestProbe(conn, n.ReadySubject)
+		}
+	}
+	return specs
+}
+
+// Graph creates the graph of the nodes, nil when none is configured.
+// Readiness is probed over conn.
+func (p *PipelineConfig) Graph(conn *nats.Conn, onExit func(name string, err error)) (*pipeline.Graph, error) {
+	if len(p.Nodes) == 0 {
+		return nil, nil
+	}
+	return pipeline.New(p.specs(conn), onExit)
+}
+
+// AppsConfig lets the master run the applications applied with sqx apply,
+// each as a graph of its own next to the pipeline
+type AppsConfig struct {
+	Dir     string `json:"dir"`     // Where the configurations of their nodes are written, applications are refused when empty
+	Bucket  string `json:"bucket"`  // Key-value bucket the configurations are uploaded to, configs when omitted
+	Subject string `json:"subject"` // Prefix of the application RPCs, app.master when omitted
+}
+
+// Validate validates the applications configuration
+func (a *AppsConfig) Validate() error {
+	if a.Subject != "" {
+		if err := eventbus.ValidatePattern(a.Subject); err != nil {
+			return fmt.Errorf("invalid apps.subject: %w", err)
+		}
+		if strings.ContainsAny(a.Subject, "*>") {
+			return fmt.Errorf("apps.subject cannot contain wildcards")
+		}
+	}
+	if a.Bucket != "" && strings.ContainsAny(a.Bucket, " .*>") {
+		return fmt.Errorf("invalid apps.bucket %q", a.Bucket)
+	}
+	return nil
+}
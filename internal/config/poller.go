@@ -0,0 +1,151 @@
+package config
+
+import (
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/BullionBear/sequex/internal/poller"
+)
+
+// PollerConfig represents the configuration of the REST poller
+type PollerConfig struct {
+	Endpoints   []EndpointConfig  `json:"endpoints"`
+	Diagnostics DiagnosticsConfig `json:"diagnostics"` // Runtime diagnostics endpoints
+	NATS        NATSConfig        `json:"nats"`        // Stream and subject prefix the events are published to
+}
+
+// EndpointConfig describes a polled REST endpoint. Path, params, headers,
+// body and subject are templates rendered with the variables of each target.
+type EndpointConfig struct {
+	Name       string              `json:"name"`
+	EventType  string              `json:"event_type"` // Type of the published events, the name when omitted
+	BaseURL    string              `json:"base_url"`
+	Method     string              `json:"method"` // GET or POST, GET when omitted
+	Path       string              `json:"path"`
+	Params     map[string]string   `json:"params"`
+	Headers    map[string]string   `json:"headers"`
+	Body       string              `json:"body"`
+	Targets    []map[string]string `json:"targets"` // e.g. [{symbol: BTCUSDT}], one request without variables when omitted
+	Items      string              `json:"items"`   // JSON path of the items to emit one event each
+	Fields     []FieldConfig       `json:"fields"`
+	Subject    string              `json:"subject"` // Appended to the NATS subject, the name when omitted
+	Auth       AuthConfig          `json:"auth"`
+	IntervalMs int64               `json:"interval_ms"`
+	TimeoutMs  int64               `json:"timeout_ms"` // Per request, the interval when omitted
+}
+
+// FieldConfig extracts one value of the event
+type FieldConfig struct {
+	Name     string `json:"name"`
+	Path     string `json:"path"` // JSON path, e.g. $.data[0].price
+	Type     string `json:"type"` // number, int, string, bool or raw, raw when omitted
+	Optional bool   `json:"optional"`
+}
+
+// AuthConfig configures request authentication. Credentials are read from
+// environment variables so they stay out of configuration files.
+type AuthConfig struct {
+	Kind           string `json:"kind"`    // bearer or hmac, none when omitted
+	KeyEnv         string `json:"key_env"` // Environment variable of the API key
+	SecretEnv      string `json:"secret_env"`
+	KeyHeader      string `json:"key_header"`      // Header the API key is sent in, e.g. X-MBX-APIKEY
+	SignatureParam string `json:"signature_param"` // signature when omitted
+	TimestampParam string `json:"timestamp_param"` // timestamp when omitted
+}
+
+// LoadPollerConfig loads the poller configuration from a YAML file. JSON
+// files load as well since JSON is valid YAML.
+func LoadPollerConfig(filePath string) (*PollerConfig, error) {
+	if filePath == "" {
+		return nil, fmt.Errorf("config file path cannot be empty")
+	}
+
+	data, err := ReadConfig(filePath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read config file %s: %w", filePath, err)
+	}
+
+	var config PollerConfig
+	if err := UnmarshalYAML(data, &config); err != nil {
+		return nil, fmt.Errorf("failed to parse config file %s: %w", filePath, err)
+	}
+
+	if err := config.Validate(); err != nil {
+		return nil, fmt.Errorf("invalid configuration in %s: %w", filePath, err)
+	}
+
+	return &config, nil
+}
+
+// Validate validates the poller configuration
+func (c *PollerConfig) Validate() error {
+	if len(c.Endpoints) == 0 {
+		return fmt.Errorf("endpoints cannot be empty")
+	}
+
+	names := make(map[string]bool)
+	for i := range c.Endpoints {
+		name := c.Endpoints[i].Name
+		if names[name] {
+			return fmt.Errorf("duplicate endpoint %q", name)
+		}
+		names[name] = true
+		for _, env := range []string{c.Endpoints[i].Auth.KeyEnv, c.Endpoints[i].Auth.SecretEnv} {
+			if env != "" && os.Getenv(env) == "" {
+				return fmt.Errorf("invalid endpoints[%d]: environment variable %s is not set", i, env)
+			}
+		}
+		if _, err := poller.New(c.Endpoints[i].Endpoint(c.NATS.Subject), nil); err != nil {
+			return fmt.Errorf("invalid endpoints[%d]: %w", i, err)
+		}
+	}
+
+	if err := c.Diagnostics.Validate(); err != nil {
+		return err
+	}
+
+	return c.NATS.Validate()
+}
+
+// Endpoint converts the configuration into a poller endpoint publishing
+// under the given subject prefix
+func (c *EndpointConfig) Endpoint(prefix string) poller.Endpoint {
+	subject := c.Subject
+	if subject == "" {
+		subject = c.Name
+	}
+	fields := make([]poller.Field, len(c.Fields))
+	for i, f := range c.Fields {
+		fields[i] = poller.Field{Name: f.Name, Path: f.Path, Type: poller.FieldType(f.Type), Optional: f.Optional}
+	}
+	auth := poller.Auth{
+		Kind:           poller.AuthKind(c.Auth.Kind),
+		KeyHeader:      c.Auth.KeyHeader,
+		SignatureParam: c.Auth.SignatureParam,
+		TimestampParam: c.Auth.TimestampParam,
+	}
+	if c.Auth.KeyEnv != "" {
+		auth.APIKey = os.Getenv(c.Auth.KeyEnv)
+	}
+	if c.Auth.SecretEnv != "" {
+		auth.Secret = os.Getenv(c.Auth.SecretEnv)
+	}
+	return poller.Endpoint{
+		Name:      c.Name,
+		EventType: c.EventType,
+		BaseURL:   c.BaseURL,
+		Method:    c.Method,
+		Path:      c.Path,
+		Params:    c.Params,
+		Headers:   c.Headers,
+		Body:      c.Body,
+		Targets:   c.Targets,
+		Items:     c.Items,
+		Fields:    fields,
+		Subject:   prefix + "." + subject,
+		Auth:      auth,
+		Interval:  time.Duration(c.IntervalMs) * time.Millisecond,
+		Timeout:   time.Duration(c.TimeoutMs) * time.Millisecond,
+	}
+}
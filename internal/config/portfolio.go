@@ -0,0 +1,51 @@
+package config
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/BullionBear/sequex/internal/pms"
+)
+
+// PortfolioStoreConfig configures where the portfolios are kept
+type PortfolioStoreConfig struct {
+	Dir       string `json:"dir"`        // Directory of the journal and end of day snapshots, in memory only when empty
+	EODCutoff string `json:"eod_cutoff"` // Time of day of the end of day snapshot, HH:MM in UTC, 00:00 when empty
+	Keep      int    `json:"keep"`       // End of day snapshots kept, 30 when 0
+}
+
+// Validate validates the portfolio store configuration
+func (c *PortfolioStoreConfig) Validate() error {
+	if c.Keep < 0 {
+		return fmt.Errorf("portfolios.keep cannot be negative")
+	}
+	if _, err := c.Cutoff(); err != nil {
+		return err
+	}
+	return nil
+}
+
+// Cutoff returns the time of day of the end of day snapshot
+func (c *PortfolioStoreConfig) Cutoff() (time.Duration, error) {
+	if c.EODCutoff == "" {
+		return 0, nil
+	}
+	t, err := time.Parse("15:04", c.EODCutoff)
+	if err != nil {
+		return 0, fmt.Errorf("invalid portfolios.eod_cutoff %q, expected HH:MM", c.EODCutoff)
+	}
+	return time.Duration(t.Hour())*time.Hour + time.Duration(t.Minute())*time.Minute, nil
+}
+
+// Store opens the portfolio store, loading the latest snapshot and replaying
+// the journal after it. The durable store is nil when kept in memory only.
+func (c *PortfolioStoreConfig) Store() (pms.Store, *pms.DurableStore, error) {
+	if c.Dir == "" {
+		return pms.NewMemoryStore(), nil, nil
+	}
+	durable, err := pms.OpenDurable(c.Dir, c.Keep)
+	if err != nil {
+		return nil, nil, err
+	}
+	return durable, durable, nil
+}
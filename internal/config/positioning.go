@@ -0,0 +1,86 @@
+package config
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/BullionBear/sequex/internal/model/sqx"
+	"github.com/BullionBear/sequex/internal/positioning"
+)
+
+// PositioningConfig represents the configuration of the positioning collector
+type PositioningConfig struct {
+	Symbols     []string          `json:"symbols"`     // e.g. BTC-USDT
+	Datasets    []string          `json:"datasets"`    // open_interest, top_positions, top_accounts, global_account or taker_volume, every dataset when omitted
+	Period      string            `json:"period"`      // Sampling period, 5m when omitted
+	IntervalMs  int64             `json:"interval_ms"` // Polling interval
+	BaseURL     string            `json:"base_url"`    // Defaults to the Binance USDⓈ-M mainnet REST endpoint
+	Diagnostics DiagnosticsConfig `json:"diagnostics"` // Runtime diagnostics endpoints
+	NATS        NATSConfig        `json:"nats"`        // Stream and subject prefix the statistics are published to
+}
+
+// LoadPositioningConfig loads the positioning collector configuration from a JSON file
+func LoadPositioningConfig(filePath string) (*PositioningConfig, error) {
+	if filePath == "" {
+		return nil, fmt.Errorf("config file path cannot be empty")
+	}
+
+	data, err := ReadConfig(filePath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read config file %s: %w", filePath, err)
+	}
+
+	var config PositioningConfig
+	if err := json.Unmarshal(data, &config); err != nil {
+		return nil, fmt.Errorf("failed to parse config file %s: %w", filePath, err)
+	}
+
+	if err := config.Validate(); err != nil {
+		return nil, fmt.Errorf("invalid configuration in %s: %w", filePath, err)
+	}
+
+	return &config, nil
+}
+
+// Validate validates the positioning collector configuration
+func (c *PositioningConfig) Validate() error {
+	if c.IntervalMs <= 0 {
+		return fmt.Errorf("interval_ms must be positive")
+	}
+
+	if _, err := c.Options(); err != nil {
+		return err
+	}
+
+	if err := c.Diagnostics.Validate(); err != nil {
+		return err
+	}
+
+	return c.NATS.Validate()
+}
+
+// Options converts the configuration into collector options
+func (c *PositioningConfig) Options() (positioning.Options, error) {
+	opts := positioning.Options{Period: c.Period}
+	if len(c.Symbols) == 0 {
+		return opts, fmt.Errorf("symbols cannot be empty")
+	}
+	for i, s := range c.Symbols {
+		symbol, err := sqx.NewSymbolFromStr(s)
+		if err != nil {
+			return opts, fmt.Errorf("invalid symbols[%d]: %w", i, err)
+		}
+		opts.Symbols = append(opts.Symbols, symbol)
+	}
+	for i, d := range c.Datasets {
+		dataset, err := positioning.ParseDataset(d)
+		if err != nil {
+			return opts, fmt.Errorf("invalid datasets[%d]: %w", i, err)
+		}
+		opts.Datasets = append(opts.Datasets, dataset)
+	}
+	if _, err := positioning.NewCollector(nil, opts); err != nil {
+		return opts, err
+	}
+	return opts, nil
+}
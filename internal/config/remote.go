@@ -0,0 +1,153 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/BullionBear/sequex/pkg/eventbus"
+	"github.com/nats-io/nats.go"
+)
+
+// DefaultConfigBucket is the key-value bucket remote configurations are
+// kept in, one key per node
+const DefaultConfigBucket = "configs"
+
+// RemoteScheme prefixes the configuration paths resolved from a key-value
+// bucket rather than a file: kv:<key> in the default bucket, or
+// kv:<bucket>/<key>. The bucket is read at the servers of the NATS_URL
+// environment variable, the local server when unset.
+const RemoteScheme = "kv:"
+
+// configHistory is the number of revisions the bucket keeps per
+// configuration, so that a node can be rolled back to an earlier one
+const configHistory = 16
+
+// RemoteSource is a configuration kept in a key-value bucket
+type RemoteSource struct {
+	Bucket string
+	Key    string
+}
+
+func (r RemoteSource) String() string {
+	return RemoteScheme + r.Bucket + "/" + r.Key
+}
+
+// ParseRemote parses a kv: configuration path, reporting false for files
+func ParseRemote(path string) (RemoteSource, bool, error) {
+	rest, ok := strings.CutPrefix(path, RemoteScheme)
+	if !ok {
+		return RemoteSource{}, false, nil
+	}
+	src := RemoteSource{Bucket: DefaultConfigBucket, Key: rest}
+	if bucket, key, ok := strings.Cut(rest, "/"); ok {
+		src.Bucket, src.Key = bucket, key
+	}
+	if src.Bucket == "" || strings.ContainsAny(src.Bucket, " .*>") {
+		return src, true, fmt.Errorf("invalid bucket in %s", path)
+	}
+	if err := eventbus.ValidatePattern(src.Key); err != nil || strings.ContainsAny(src.Key, "*>") {
+		return src, true, fmt.Errorf("invalid key in %s", path)
+	}
+	return src, true, nil
+}
+
+// OpenConfigBucket opens the bucket of the remote configurations, creating
+// it when missing
+func OpenConfigBucket(js nats.JetStreamContext, bucket string) (nats.KeyValue, error) {
+	if bucket == "" {
+		return nil, fmt.Errorf("bucket cannot be empty")
+	}
+	kv, err := js.KeyValue(bucket)
+	if errors.Is(err, nats.ErrBucketNotFound) {
+		kv, err = js.CreateKeyValue(&nats.KeyValueConfig{Bucket: bucket, History: configHistory, Description: "Node configurations"})
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to open bucket %s: %w", bucket, err)
+	}
+	return kv, nil
+}
+
+// ReadConfig reads a configuration from a file, or from its bucket for kv:
+// paths. The bucket is reached on a connection to NATS_URL, opened for the
+// read since the configuration of a node holds the servers it connects to;
+// configurations read once connected are read with ReadConfigConn.
+func ReadConfig(path string) ([]byte, error) {
+	return ReadConfigConn(nil, path)
+}
+
+// ReadConfigConn reads a configuration as ReadConfig does, reaching the
+// bucket of kv: paths on conn, the connection of the node, when not nil
+func ReadConfigConn(conn *nats.Conn, path string) ([]byte, error) {
+	src, remote, err := ParseRemote(path)
+	if err != nil || !remote {
+		if err != nil {
+			return nil, err
+		}
+		return os.ReadFile(path)
+	}
+	if conn == nil {
+		servers := os.Getenv("NATS_URL")
+		if servers == "" {
+			servers = nats.DefaultURL
+		}
+		conn, err = nats.Connect(servers, nats.Name("config reader"))
+		if err != nil {
+			return nil, fmt.Errorf("failed to connect to %s: %w", servers, err)
+		}
+		defer conn.Close()
+	}
+	js, err := conn.JetStream()
+	if err != nil {
+		return nil, err
+	}
+	kv, err := js.KeyValue(src.Bucket)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open bucket %s: %w", src.Bucket, err)
+	}
+	entry, err := kv.Get(src.Key)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get %s: %w", src.Key, err)
+	}
+	return entry.Value(), nil
+}
+
+// WatchConfig calls onChange with the configuration of a kv: path as
+// written, starting with the current one, until stop is called. Deletions
+// are reported to onError, the node keeping its configuration. Files are
+// not watched.
+func WatchConfig(js nats.JetStreamContext, path string, onChange func(data []byte, revision uint64), onError func(error)) (stop func(), err error) {
+	src, remote, err := ParseRemote(path)
+	if err != nil {
+		return nil, err
+	}
+	if !remote {
+		return nil, fmt.Errorf("%s is not a remote configuration", path)
+	}
+	kv, err := js.KeyValue(src.Bucket)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open bucket %s: %w", src.Bucket, err)
+	}
+	watcher, err := kv.Watch(src.Key)
+	if err != nil {
+		return nil, fmt.Errorf("failed to watch %s: %w", src, err)
+	}
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for entry := range watcher.Updates() {
+			switch {
+			case entry == nil: // End of the current value
+			case entry.Operation() != nats.KeyValuePut:
+				onError(fmt.Errorf("%s was deleted, keeping the configuration", src))
+			default:
+				onChange(entry.Value(), entry.Revision())
+			}
+		}
+	}()
+	return func() {
+		watcher.Stop()
+		<-done
+	}, nil
+}
@@ -0,0 +1,137 @@
+package config
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+
+	"github.com/BullionBear/sequex/internal/report"
+	"github.com/BullionBear/sequex/pkg/eventbus"
+)
+
+// SlackConfig configures the delivery of the reports to a Slack incoming webhook
+type SlackConfig struct {
+	WebhookURLEnv string `json:"webhook_url_env"` // Environment variable of the webhook URL, empty disables Slack
+}
+
+// SMTPConfig configures the delivery of the reports by email. The password
+// is read from an environment variable so it stays out of configuration files.
+type SMTPConfig struct {
+	Addr        string   `json:"addr"` // host:port, empty disables email
+	Username    string   `json:"username"`
+	PasswordEnv string   `json:"password_env"`
+	From        string   `json:"from"`
+	To          []string `json:"to"`
+}
+
+// ReportConfig represents the configuration of the daily report service
+type ReportConfig struct {
+	Executions      ExecutionHistoryConfig `json:"executions"`        // Execution reports the fills are read from
+	Alerts          []string               `json:"alerts"`            // Monitor alert subjects recorded as risk breaches
+	Nodes           []string               `json:"nodes"`             // Nodes whose diagnostics are probed for availability
+	ProbeIntervalMs int64                  `json:"probe_interval_ms"` // Interval between probes, 60000 when omitted
+	Time            string                 `json:"time"`              // HH:MM the report of the previous day is sent at
+	Timezone        string                 `json:"timezone"`          // IANA time zone days are cut in, UTC when omitted
+	Slack           SlackConfig            `json:"slack"`
+	SMTP            SMTPConfig             `json:"smtp"`
+	Diagnostics     DiagnosticsConfig      `json:"diagnostics"` // Runtime diagnostics endpoints
+	NATS            NATSConfig             `json:"nats"`        // Stream and subject the reports are archived to
+}
+
+// LoadReportConfig loads the report service configuration from a JSON file
+func LoadReportConfig(filePath string) (*ReportConfig, error) {
+	if filePath == "" {
+		return nil, fmt.Errorf("config file path cannot be empty")
+	}
+
+	data, err := ReadConfig(filePath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read config file %s: %w", filePath, err)
+	}
+
+	var config ReportConfig
+	if err := json.Unmarshal(data, &config); err != nil {
+		return nil, fmt.Errorf("failed to parse config file %s: %w", filePath, err)
+	}
+
+	if err := config.Validate(); err != nil {
+		return nil, fmt.Errorf("invalid configuration in %s: %w", filePath, err)
+	}
+
+	return &config, nil
+}
+
+// Validate validates the report service configuration
+func (c *ReportConfig) Validate() error {
+	if len(c.Executions.Inputs) == 0 {
+		return fmt.Errorf("executions.inputs cannot be empty")
+	}
+
+	if err := c.Executions.Validate(); err != nil {
+		return err
+	}
+
+	for i, subject := range c.Alerts {
+		if err := eventbus.ValidatePattern(subject); err != nil {
+			return fmt.Errorf("invalid alerts[%d]: %w", i, err)
+		}
+	}
+
+	for i, node := range c.Nodes {
+		if err := eventbus.ValidateToken(node); err != nil {
+			return fmt.Errorf("invalid nodes[%d]: %w", i, err)
+		}
+	}
+
+	if c.ProbeIntervalMs < 0 {
+		return fmt.Errorf("probe_interval_ms cannot be negative")
+	}
+
+	if _, err := c.Schedule(); err != nil {
+		return err
+	}
+
+	if c.Slack.WebhookURLEnv == "" && c.SMTP.Addr == "" {
+		return fmt.Errorf("slack or smtp must be configured")
+	}
+
+	if env := c.Slack.WebhookURLEnv; env != "" && os.Getenv(env) == "" {
+		return fmt.Errorf("invalid slack: environment variable %s is not set", env)
+	}
+
+	if c.SMTP.Addr != "" {
+		if c.SMTP.From == "" || len(c.SMTP.To) == 0 {
+			return fmt.Errorf("invalid smtp: from and to are required")
+		}
+		if env := c.SMTP.PasswordEnv; env != "" && os.Getenv(env) == "" {
+			return fmt.Errorf("invalid smtp: environment variable %s is not set", env)
+		}
+	}
+
+	if err := c.Diagnostics.Validate(); err != nil {
+		return err
+	}
+
+	return c.NATS.Validate()
+}
+
+// Schedule returns the time of day the reports are sent at
+func (c *ReportConfig) Schedule() (report.Schedule, error) {
+	return report.ParseSchedule(c.Time, c.Timezone)
+}
+
+// Senders creates the configured report deliveries
+func (c *ReportConfig) Senders() []report.Sender {
+	var senders []report.Sender
+	if c.Slack.WebhookURLEnv != "" {
+		senders = append(senders, &report.Slack{WebhookURL: os.Getenv(c.Slack.WebhookURLEnv)})
+	}
+	if c.SMTP.Addr != "" {
+		s := &report.SMTP{Addr: c.SMTP.Addr, Username: c.SMTP.Username, From: c.SMTP.From, To: c.SMTP.To}
+		if c.SMTP.PasswordEnv != "" {
+			s.Password = os.Getenv(c.SMTP.PasswordEnv)
+		}
+		senders = append(senders, s)
+	}
+	return senders
+}
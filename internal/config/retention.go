@@ -0,0 +1,104 @@
+package config
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/BullionBear/sequex/internal/retention"
+)
+
+// RetentionConfig represents the configuration of the retention manager
+type RetentionConfig struct {
+	IntervalMs  int64                   `json:"interval_ms"` // Interval between enforcement runs, 1h when omitted
+	Policies    []RetentionPolicyConfig `json:"policies"`
+	Diagnostics DiagnosticsConfig       `json:"diagnostics"` // Runtime diagnostics endpoints
+	NATS        NATSConfig              `json:"nats"`        // Server the streams are managed on
+}
+
+// RetentionPolicyConfig bounds the age of the messages of a stream on a
+// subject, see retention.Policy
+type RetentionPolicyConfig struct {
+	Stream      string            `json:"stream"`
+	Subject     string            `json:"subject"`       // Subject filter, wildcards allowed
+	MaxAgeHours float64           `json:"max_age_hours"` // Messages older are deleted, 0 keeps them forever
+	Compact     *CompactionConfig `json:"compact,omitempty"`
+}
+
+// CompactionConfig aggregates the trades a policy expires into klines
+// before they are deleted
+type CompactionConfig struct {
+	Interval string `json:"interval"` // Kline interval, e.g. 1m
+	Stream   string `json:"stream"`   // Stream the klines are published to
+	Prefix   string `json:"prefix"`   // Klines go to <prefix>.<exchange>.<instrument>.<SYMBOL>.<interval>
+}
+
+// LoadRetentionConfig loads the retention manager configuration from a JSON file
+func LoadRetentionConfig(filePath string) (*RetentionConfig, error) {
+	if filePath == "" {
+		return nil, fmt.Errorf("config file path cannot be empty")
+	}
+
+	data, err := ReadConfig(filePath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read config file %s: %w", filePath, err)
+	}
+
+	var config RetentionConfig
+	if err := json.Unmarshal(data, &config); err != nil {
+		return nil, fmt.Errorf("failed to parse config file %s: %w", filePath, err)
+	}
+
+	if err := config.Validate(); err != nil {
+		return nil, fmt.Errorf("invalid configuration in %s: %w", filePath, err)
+	}
+
+	return &config, nil
+}
+
+// Validate validates the retention manager configuration
+func (c *RetentionConfig) Validate() error {
+	if c.IntervalMs < 0 {
+		return fmt.Errorf("interval_ms cannot be negative")
+	}
+
+	if len(c.Policies) == 0 {
+		return fmt.Errorf("policies cannot be empty")
+	}
+
+	for i, p := range c.RetentionPolicies() {
+		if err := p.Validate(); err != nil {
+			return fmt.Errorf("invalid policies[%d]: %w", i, err)
+		}
+	}
+
+	if err := c.Diagnostics.Validate(); err != nil {
+		return err
+	}
+
+	return c.NATS.ValidateURIs()
+}
+
+// Interval returns the interval between enforcement runs
+func (c *RetentionConfig) Interval() time.Duration {
+	if c.IntervalMs == 0 {
+		return time.Hour
+	}
+	return time.Duration(c.IntervalMs) * time.Millisecond
+}
+
+// RetentionPolicies converts the configuration into retention policies
+func (c *RetentionConfig) RetentionPolicies() []retention.Policy {
+	policies := make([]retention.Policy, len(c.Policies))
+	for i, p := range c.Policies {
+		policies[i] = retention.Policy{
+			Stream:  p.Stream,
+			Subject: p.Subject,
+			MaxAge:  time.Duration(p.MaxAgeHours * float64(time.Hour)),
+		}
+		if p.Compact != nil {
+			policies[i].Compact = &retention.Compaction{Interval: p.Compact.Interval, Stream: p.Compact.Stream, Prefix: p.Compact.Prefix}
+		}
+	}
+	return policies
+}
@@ -0,0 +1,47 @@
+package config
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/BullionBear/sequex/internal/risk"
+)
+
+// PortfolioRiskConfig configures the return history portfolio risk is simulated from.
+// Prices are taken from the trades of the FX inputs.
+type PortfolioRiskConfig struct {
+	Enabled    bool     `json:"enabled"`
+	Quote      string   `json:"quote"`       // Currency returns are measured in, USDT when empty
+	IntervalMs int64    `json:"interval_ms"` // Length of a return period, 1 hour when 0
+	MaxPeriods int      `json:"max_periods"` // Periods kept per asset, 720 when 0
+	Recordings []string `json:"recordings"`  // Recorded trade files loaded at startup, oldest first
+}
+
+// Validate validates the risk configuration
+func (c *PortfolioRiskConfig) Validate() error {
+	if c.IntervalMs < 0 {
+		return fmt.Errorf("risk.interval_ms cannot be negative")
+	}
+	if c.MaxPeriods < 0 {
+		return fmt.Errorf("risk.max_periods cannot be negative")
+	}
+	for i, r := range c.Recordings {
+		if strings.TrimSpace(r) == "" {
+			return fmt.Errorf("risk.recordings[%d] cannot be empty", i)
+		}
+	}
+	return nil
+}
+
+// History creates the empty price history, nil when risk is disabled
+func (c *PortfolioRiskConfig) History() *risk.History {
+	if !c.Enabled {
+		return nil
+	}
+	return risk.NewHistory(risk.HistoryOptions{
+		Quote:      c.Quote,
+		Interval:   time.Duration(c.IntervalMs) * time.Millisecond,
+		MaxPeriods: c.MaxPeriods,
+	})
+}
@@ -0,0 +1,214 @@
+package config
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/BullionBear/sequex/internal/model/sqx"
+	"github.com/BullionBear/sequex/internal/replay"
+	"github.com/BullionBear/sequex/internal/shard"
+	"github.com/BullionBear/sequex/pkg/eventbus"
+	"github.com/nats-io/nats.go"
+)
+
+// ShardConfig splits the symbols of a feed across instances sharing the
+// configuration. In hash mode the instance streams the symbols whose hash
+// falls in range index of count; in list mode the symbols of the list named
+// after it; in dynamic mode the symbols rendezvous hashing assigns it among
+// the live members of the registry, rebalanced as instances come and go.
+// In hash and dynamic modes the symbols split can be discovered by volume
+// rather than listed.
+type ShardConfig struct {
+	Symbols         []string            `json:"symbols"`          // Symbols split across the instances, hash and dynamic modes
+	Discovery       *DiscoveryConfig    `json:"discovery"`        // Ranks the symbols split instead of listing them, hash and dynamic modes
+	Mode            string              `json:"mode"`             // hash, list or dynamic
+	Index           int                 `json:"index"`            // Hash range of this instance, from 0
+	Count           int                 `json:"count"`            // Number of hash ranges
+	Lists           map[string][]string `json:"lists"`            // Symbols of each named shard, list mode
+	Member          string              `json:"member"`           // Name of this instance, the host name when omitted; list mode reads its list
+	Bucket          string              `json:"bucket"`           // Key-value bucket of the registry, required in dynamic mode, health reporting only otherwise
+	HeartbeatMs     int64               `json:"heartbeat_ms"`     // Interval between heartbeats and rebalances, 5000 when omitted
+	StaleAfterMs    int64               `json:"stale_after_ms"`   // Symbols without a trade for this long are reported stale, 0 disables it
+	SubjectTemplate string              `json:"subject_template"` // Subject of each trade, with {exchange}, {instrument}, {symbol}, {base} and {quote}
+	EventsSubject   string              `json:"events_subject"`   // Subject of subscription change events, none when empty
+}
+
+// DiscoveryConfig keeps the symbols ranking in the top by 24h quote volume
+// on the exchange, refreshed periodically
+type DiscoveryConfig struct {
+	Quote          string   `json:"quote"`            // Quote asset of the symbols ranked, e.g. USDT
+	Top            int      `json:"top"`              // Rank a symbol must reach to be added
+	Hysteresis     int      `json:"hysteresis"`       // Ranks beyond top a symbol may fall before it is removed
+	MinQuoteVolume float64  `json:"min_quote_volume"` // 24h quote volume below which a symbol is never kept
+	Exclude        []string `json:"exclude"`          // Symbols never kept, e.g. USDC-USDT
+	IntervalMs     int64    `json:"interval_ms"`      // Interval between rankings, 300000 when omitted
+}
+
+// Validate validates the discovery configuration
+func (d *DiscoveryConfig) Validate() error {
+	if d.Quote == "" || strings.ToUpper(d.Quote) != d.Quote {
+		return fmt.Errorf("shard.discovery.quote must be an upper case asset, e.g. USDT")
+	}
+	if d.Top <= 0 {
+		return fmt.Errorf("shard.discovery.top must be positive")
+	}
+	if d.Hysteresis < 0 || d.MinQuoteVolume < 0 || d.IntervalMs < 0 {
+		return fmt.Errorf("shard.discovery.hysteresis, min_quote_volume and interval_ms cannot be negative")
+	}
+	for _, symbol := range d.Exclude {
+		if _, err := sqx.NewSymbolFromStr(symbol); err != nil {
+			return fmt.Errorf("invalid shard.discovery.exclude symbol %q: %w", symbol, err)
+		}
+	}
+	return nil
+}
+
+// Interval returns the interval between rankings
+func (d *DiscoveryConfig) Interval() time.Duration {
+	if d.IntervalMs == 0 {
+		return 5 * time.Minute
+	}
+	return time.Duration(d.IntervalMs) * time.Millisecond
+}
+
+// Options returns the ranking options
+func (d *DiscoveryConfig) Options() shard.DiscoveryOptions {
+	return shard.DiscoveryOptions{
+		Quote:      d.Quote,
+		Top:        d.Top,
+		Hysteresis: d.Hysteresis,
+		MinVolume:  d.MinQuoteVolume,
+		Exclude:    d.Exclude,
+	}
+}
+
+// Validate validates the shard configuration
+func (s *ShardConfig) Validate() error {
+	var symbols []string
+	switch s.Mode {
+	case "hash":
+		if s.Count <= 0 || s.Index < 0 || s.Index >= s.Count {
+			return fmt.Errorf("shard.index must be between 0 and shard.count - 1")
+		}
+		symbols = s.Symbols
+	case "list":
+		if s.Discovery != nil {
+			return fmt.Errorf("shard.discovery is not supported in list mode")
+		}
+		member, err := s.MemberName()
+		if err != nil {
+			return err
+		}
+		if _, ok := s.Lists[member]; !ok {
+			return fmt.Errorf("shard.lists has no list for member %q", member)
+		}
+		for _, list := range s.Lists {
+			symbols = append(symbols, list...)
+		}
+	case "dynamic":
+		if s.Bucket == "" {
+			return fmt.Errorf("shard.bucket is required in dynamic mode")
+		}
+		symbols = s.Symbols
+	default:
+		return fmt.Errorf("shard.mode must be hash, list or dynamic")
+	}
+	if s.Discovery != nil {
+		if len(symbols) > 0 {
+			return fmt.Errorf("shard.symbols and shard.discovery cannot both be set")
+		}
+		if err := s.Discovery.Validate(); err != nil {
+			return err
+		}
+	} else if len(symbols) == 0 {
+		return fmt.Errorf("shard has no symbols")
+	}
+	seen := make(map[string]bool)
+	for _, symbol := range symbols {
+		if _, err := sqx.NewSymbolFromStr(symbol); err != nil {
+			return fmt.Errorf("invalid shard symbol %q: %w", symbol, err)
+		}
+		if seen[symbol] {
+			return fmt.Errorf("shard symbol %s is listed twice", symbol)
+		}
+		seen[symbol] = true
+	}
+	if s.HeartbeatMs < 0 || s.StaleAfterMs < 0 {
+		return fmt.Errorf("shard.heartbeat_ms and shard.stale_after_ms cannot be negative")
+	}
+	if _, err := replay.NewSubjects(s.SubjectTemplate, nil); err != nil {
+		return fmt.Errorf("invalid shard.subject_template: %w", err)
+	}
+	if s.EventsSubject != "" {
+		if err := eventbus.ValidatePattern(s.EventsSubject); err != nil {
+			return fmt.Errorf("invalid shard.events_subject: %w", err)
+		}
+		if strings.ContainsAny(s.EventsSubject, "*>") {
+			return fmt.Errorf("shard.events_subject cannot contain wildcards")
+		}
+	}
+	return nil
+}
+
+// MemberName returns the name of this instance
+func (s *ShardConfig) MemberName() (string, error) {
+	if s.Member != "" {
+		return s.Member, nil
+	}
+	return os.Hostname()
+}
+
+// AllSymbols returns the symbols split across the instances, empty with
+// discovery
+func (s *ShardConfig) AllSymbols() []string {
+	if s.Mode != "list" {
+		return s.Symbols
+	}
+	var symbols []string
+	for _, list := range s.Lists {
+		symbols = append(symbols, list...)
+	}
+	return symbols
+}
+
+// Heartbeat returns the interval between heartbeats and rebalances
+func (s *ShardConfig) Heartbeat() time.Duration {
+	if s.HeartbeatMs == 0 {
+		return 5 * time.Second
+	}
+	return time.Duration(s.HeartbeatMs) * time.Millisecond
+}
+
+// Assigner returns the assignment of the symbols of member. With discovery
+// the symbols split are those discovery currently keeps.
+func (s *ShardConfig) Assigner(member string, discovery *shard.Discovery) shard.Assigner {
+	if discovery != nil {
+		if s.Mode == "hash" {
+			return func([]string) []string { return shard.HashRange(discovery.Symbols(), s.Index, s.Count) }
+		}
+		return func(members []string) []string { return shard.Rendezvous(discovery.Symbols(), member, members) }
+	}
+	switch s.Mode {
+	case "hash":
+		return shard.Static(shard.HashRange(s.Symbols, s.Index, s.Count))
+	case "list":
+		return shard.Static(s.Lists[member])
+	default:
+		return shard.Dynamic(s.Symbols, member)
+	}
+}
+
+// Registry opens the registry of the instances, nil when no bucket is
+// configured. Members missing three heartbeats are dropped.
+func (s *ShardConfig) Registry(js nats.JetStreamContext) (shard.Registry, error) {
+	if s.Bucket == "" {
+		return nil, nil
+	}
+	registry, err := shard.NewNATSRegistry(js, s.Bucket, 3*s.Heartbeat())
+	if err != nil {
+		return nil, err
+	}
+	return registry, nil
+}
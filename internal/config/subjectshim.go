@@ -0,0 +1,64 @@
+package config
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/BullionBear/sequex/pkg/eventbus"
+)
+
+// SubjectShimConfig represents the configuration of the legacy subject shim
+type SubjectShimConfig struct {
+	Env               string            `json:"env"`                // Target environment namespace, e.g. prod
+	DefaultExchange   string            `json:"default_exchange"`   // Venue of short legacy subjects like trade.btcusdt
+	DefaultInstrument string            `json:"default_instrument"` // Instrument of short legacy subjects
+	Legacy            []string          `json:"legacy"`             // Legacy subject patterns to republish
+	Diagnostics       DiagnosticsConfig `json:"diagnostics"`        // Runtime diagnostics endpoints
+	NATS              NATSConfig        `json:"nats"`
+}
+
+// LoadSubjectShimConfig loads the subject shim configuration from a JSON file
+func LoadSubjectShimConfig(filePath string) (*SubjectShimConfig, error) {
+	if filePath == "" {
+		return nil, fmt.Errorf("config file path cannot be empty")
+	}
+
+	data, err := ReadConfig(filePath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read config file %s: %w", filePath, err)
+	}
+
+	var config SubjectShimConfig
+	if err := json.Unmarshal(data, &config); err != nil {
+		return nil, fmt.Errorf("failed to parse config file %s: %w", filePath, err)
+	}
+
+	if err := config.Validate(); err != nil {
+		return nil, fmt.Errorf("invalid configuration in %s: %w", filePath, err)
+	}
+
+	return &config, nil
+}
+
+// Validate validates the subject shim configuration
+func (c *SubjectShimConfig) Validate() error {
+	if err := eventbus.ValidateToken(c.Env); err != nil {
+		return fmt.Errorf("invalid env: %w", err)
+	}
+
+	if len(c.Legacy) == 0 {
+		return fmt.Errorf("legacy cannot be empty")
+	}
+
+	for i, pattern := range c.Legacy {
+		if err := eventbus.ValidatePattern(pattern); err != nil {
+			return fmt.Errorf("legacy[%d]: %w", i, err)
+		}
+	}
+
+	if err := c.Diagnostics.Validate(); err != nil {
+		return err
+	}
+
+	return c.NATS.ValidateURIs()
+}
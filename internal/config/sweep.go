@@ -0,0 +1,144 @@
+package config
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/BullionBear/sequex/internal/sweep"
+	"github.com/BullionBear/sequex/pkg/calendar"
+	"github.com/BullionBear/sequex/pkg/eventbus"
+	"github.com/BullionBear/sequex/pkg/exchange/binance"
+	"github.com/BullionBear/sequex/pkg/exchange/binanceperp"
+)
+
+// SweepRuleConfig keeps the balance of an asset in a trading wallet within bounds
+type SweepRuleConfig struct {
+	Asset    string   `json:"asset"`
+	Wallet   string   `json:"wallet"`   // spot or usdm
+	Min      float64  `json:"min"`      // Topped up to target from the funding wallet below
+	Target   float64  `json:"target"`   // Balance transfers aim at
+	Max      float64  `json:"max"`      // Excess swept back to the funding wallet above, never when 0
+	Schedule []string `json:"schedule"` // HH:MM times of day the rule runs at, every sweep when empty
+	Timezone string   `json:"timezone"` // IANA time zone of the schedule, UTC when empty
+}
+
+// Rule converts the configuration into a sweep rule
+func (c SweepRuleConfig) Rule() (sweep.Rule, error) {
+	r := sweep.Rule{Asset: c.Asset, Wallet: sweep.Wallet(c.Wallet), Min: c.Min, Target: c.Target, Max: c.Max}
+	for _, clock := range c.Schedule {
+		at, err := calendar.ParseSettlement(clock, c.Timezone)
+		if err != nil {
+			return r, fmt.Errorf("schedule: %w", err)
+		}
+		r.Schedule = append(r.Schedule, at)
+	}
+	return r, r.Validate()
+}
+
+// SweepConfig represents the configuration of the wallet sweeper, keeping
+// the trading wallets of a Binance account topped up from its funding wallet
+type SweepConfig struct {
+	Binance     ExchangeKeyConfig `json:"binance"`     // Account the balances are transferred within, allowed universal transfers
+	Rules       []SweepRuleConfig `json:"rules"`       // One per asset and wallet
+	IntervalMs  int64             `json:"interval_ms"` // Interval between sweeps, 1 minute when 0
+	DryRun      bool              `json:"dry_run"`     // Log and publish the transfers without making them
+	Diagnostics DiagnosticsConfig `json:"diagnostics"` // Runtime diagnostics endpoints
+	NATS        NATSConfig        `json:"nats"`        // Transfers are published on <subject>.<wallet>.<asset>, no stream needed
+}
+
+// LoadSweepConfig loads the wallet sweeper configuration from a JSON file
+func LoadSweepConfig(filePath string) (*SweepConfig, error) {
+	if filePath == "" {
+		return nil, fmt.Errorf("config file path cannot be empty")
+	}
+
+	data, err := ReadConfig(filePath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read config file %s: %w", filePath, err)
+	}
+
+	var config SweepConfig
+	if err := json.Unmarshal(data, &config); err != nil {
+		return nil, fmt.Errorf("failed to parse config file %s: %w", filePath, err)
+	}
+
+	if err := config.Validate(); err != nil {
+		return nil, fmt.Errorf("invalid configuration in %s: %w", filePath, err)
+	}
+
+	return &config, nil
+}
+
+// Validate validates the wallet sweeper configuration
+func (c *SweepConfig) Validate() error {
+	if err := c.Binance.Validate("binance"); err != nil {
+		return err
+	}
+
+	if _, err := c.SweepRules(); err != nil {
+		return err
+	}
+
+	if c.IntervalMs < 0 {
+		return fmt.Errorf("interval_ms cannot be negative")
+	}
+
+	if err := c.Diagnostics.Validate(); err != nil {
+		return err
+	}
+
+	if c.NATS.Subject == "" {
+		return fmt.Errorf("nats.subject cannot be empty")
+	}
+	if err := eventbus.ValidatePattern(c.NATS.Subject); err != nil || strings.ContainsAny(c.NATS.Subject, "*>") {
+		return fmt.Errorf("invalid nats.subject %q", c.NATS.Subject)
+	}
+
+	return c.NATS.ValidateURIs()
+}
+
+// SweepRules converts the rules of the configuration
+func (c *SweepConfig) SweepRules() ([]sweep.Rule, error) {
+	if len(c.Rules) == 0 {
+		return nil, fmt.Errorf("rules cannot be empty")
+	}
+	rules := make([]sweep.Rule, 0, len(c.Rules))
+	for i, rc := range c.Rules {
+		r, err := rc.Rule()
+		if err != nil {
+			return nil, fmt.Errorf("invalid rules[%d]: %w", i, err)
+		}
+		rules = append(rules, r)
+	}
+	return rules, nil
+}
+
+// Interval returns the interval between sweeps
+func (c *SweepConfig) Interval() time.Duration {
+	if c.IntervalMs == 0 {
+		return time.Minute
+	}
+	return time.Duration(c.IntervalMs) * time.Millisecond
+}
+
+// Sweeper creates the sweeper of the account, dry run when dryRun or the
+// configuration says so
+func (c *SweepConfig) Sweeper(dryRun bool) (*sweep.Sweeper, error) {
+	rules, err := c.SweepRules()
+	if err != nil {
+		return nil, err
+	}
+	key, secret := os.Getenv(c.Binance.KeyEnv), os.Getenv(c.Binance.SecretEnv)
+	client := binance.NewClient(binance.NewMainnetConfig(key, secret))
+	var futures sweep.FuturesClient
+	for _, r := range rules {
+		if r.Wallet == sweep.WalletUSDM {
+			futures = binanceperp.NewClient(&binanceperp.Config{APIKey: key, APISecret: secret, BaseURL: binanceperp.MainnetBaseUrl})
+			break
+		}
+	}
+	return sweep.NewSweeper(client, futures, rules, sweep.Options{DryRun: dryRun || c.DryRun})
+}
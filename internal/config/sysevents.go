@@ -0,0 +1,79 @@
+package config
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/BullionBear/sequex/internal/sysevent"
+)
+
+// SysEventsConfig represents the configuration of the system event
+// collector
+type SysEventsConfig struct {
+	Host          string            `json:"host"`
+	Port          int               `json:"port"`           // REST API
+	Dir           string            `json:"dir"`            // Journal of the events, in memory only when omitted
+	RetentionDays int               `json:"retention_days"` // Days of events kept, 7 when omitted
+	Durable       string            `json:"durable"`        // Consumer of the stream, sysevents when omitted
+	RPCSubject    string            `json:"rpc_subject"`    // Prefix the query RPCs are served under, sysevents when omitted
+	Diagnostics   DiagnosticsConfig `json:"diagnostics"`    // Runtime diagnostics endpoints
+	NATS          NATSConfig        `json:"nats"`           // Stream storing the events and their subject prefix, e.g. sys.events
+}
+
+// LoadSysEventsConfig loads the system event collector configuration from a JSON file
+func LoadSysEventsConfig(filePath string) (*SysEventsConfig, error) {
+	if filePath == "" {
+		return nil, fmt.Errorf("config file path cannot be empty")
+	}
+
+	data, err := ReadConfig(filePath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read config file %s: %w", filePath, err)
+	}
+
+	var config SysEventsConfig
+	if err := json.Unmarshal(data, &config); err != nil {
+		return nil, fmt.Errorf("failed to parse config file %s: %w", filePath, err)
+	}
+
+	if err := config.Validate(); err != nil {
+		return nil, fmt.Errorf("invalid configuration in %s: %w", filePath, err)
+	}
+
+	return &config, nil
+}
+
+// Validate validates the system event collector configuration
+func (c *SysEventsConfig) Validate() error {
+	if c.Port <= 0 || c.Port > 65535 {
+		return fmt.Errorf("port must be between 1 and 65535, got %d", c.Port)
+	}
+
+	if c.RetentionDays < 0 {
+		return fmt.Errorf("retention_days cannot be negative")
+	}
+
+	if strings.ContainsAny(c.Durable, ".*> ") {
+		return fmt.Errorf("invalid durable %q", c.Durable)
+	}
+
+	if strings.ContainsAny(c.NATS.Subject, "*>") {
+		return fmt.Errorf("nats.subject is the prefix of the events and cannot contain wildcards")
+	}
+
+	if err := c.Diagnostics.Validate(); err != nil {
+		return err
+	}
+
+	return c.NATS.Validate()
+}
+
+// Retention returns how long events are kept
+func (c *SysEventsConfig) Retention() time.Duration {
+	if c.RetentionDays == 0 {
+		return sysevent.DefaultRetention
+	}
+	return time.Duration(c.RetentionDays) * 24 * time.Hour
+}
@@ -0,0 +1,69 @@
+package config
+
+import (
+	"encoding/json"
+	"fmt"
+)
+
+// TapeConfig represents the configuration of the consolidated tape
+type TapeConfig struct {
+	Inputs          []string          `json:"inputs"`            // Trade subjects of every venue consolidated
+	Symbols         []string          `json:"symbols"`           // Symbols a tape is published for, e.g. BTC-USDT
+	DelayMs         int64             `json:"delay_ms"`          // Trades are held this long to be ordered across venues, printed on arrival when 0
+	SkewWindowMs    int64             `json:"skew_window_ms"`    // Window the clock offset of a venue is estimated over, 1 minute when 0
+	FlushIntervalMs int64             `json:"flush_interval_ms"` // Interval held trades are checked at, the delay when 0
+	Diagnostics     DiagnosticsConfig `json:"diagnostics"`       // Runtime diagnostics endpoints
+	NATS            NATSConfig        `json:"nats"`              // Stream and subject the tapes are published to
+}
+
+// LoadTapeConfig loads the consolidated tape configuration from a JSON file
+func LoadTapeConfig(filePath string) (*TapeConfig, error) {
+	if filePath == "" {
+		return nil, fmt.Errorf("config file path cannot be empty")
+	}
+
+	data, err := ReadConfig(filePath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read config file %s: %w", filePath, err)
+	}
+
+	var config TapeConfig
+	if err := json.Unmarshal(data, &config); err != nil {
+		return nil, fmt.Errorf("failed to parse config file %s: %w", filePath, err)
+	}
+
+	if err := config.Validate(); err != nil {
+		return nil, fmt.Errorf("invalid configuration in %s: %w", filePath, err)
+	}
+
+	return &config, nil
+}
+
+// Validate validates the consolidated tape configuration
+func (c *TapeConfig) Validate() error {
+	if len(c.Inputs) == 0 {
+		return fmt.Errorf("inputs cannot be empty")
+	}
+
+	if len(c.Symbols) == 0 {
+		return fmt.Errorf("symbols cannot be empty")
+	}
+
+	if c.DelayMs < 0 {
+		return fmt.Errorf("delay_ms cannot be negative")
+	}
+
+	if c.SkewWindowMs < 0 {
+		return fmt.Errorf("skew_window_ms cannot be negative")
+	}
+
+	if c.FlushIntervalMs < 0 {
+		return fmt.Errorf("flush_interval_ms cannot be negative")
+	}
+
+	if err := c.Diagnostics.Validate(); err != nil {
+		return err
+	}
+
+	return c.NATS.Validate()
+}
@@ -0,0 +1,58 @@
+package config
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/BullionBear/sequex/internal/tenant"
+)
+
+// TenantConfig represents a team sharing the deployment
+type TenantConfig struct {
+	ID            string        `json:"id"`             // Lower case letters and digits, e.g. acme
+	Name          string        `json:"name"`           // Displayed
+	SubjectPrefix string        `json:"subject_prefix"` // Of every subject of the tenant, tenant.<id> when empty
+	KeyEnvs       []string      `json:"key_envs"`       // Environment variables of the API keys, so they stay out of configuration files
+	Accounts      []string      `json:"accounts"`       // Exchanges the tenant trades on, e.g. BINANCE
+	Limits        tenant.Limits `json:"limits"`         // Bounds of each order of the tenant
+}
+
+// Tenant converts the configuration, reading the API keys from the environment
+func (c *TenantConfig) Tenant() (tenant.Tenant, error) {
+	t := tenant.Tenant{
+		ID:            c.ID,
+		Name:          c.Name,
+		SubjectPrefix: c.SubjectPrefix,
+		Accounts:      c.Accounts,
+		Limits:        c.Limits,
+	}
+	for _, env := range c.KeyEnvs {
+		key := os.Getenv(env)
+		if key == "" {
+			return t, fmt.Errorf("invalid tenants %s: environment variable %s is not set", c.ID, env)
+		}
+		t.Keys = append(t.Keys, key)
+	}
+	return t, nil
+}
+
+// TenantRegistry validates the tenants and indexes them. It is nil when no
+// tenant is configured, so services run unscoped.
+func TenantRegistry(configs []TenantConfig) (*tenant.Registry, error) {
+	if len(configs) == 0 {
+		return nil, nil
+	}
+	tenants := make([]tenant.Tenant, 0, len(configs))
+	for _, c := range configs {
+		t, err := c.Tenant()
+		if err != nil {
+			return nil, err
+		}
+		tenants = append(tenants, t)
+	}
+	registry, err := tenant.NewRegistry(tenants)
+	if err != nil {
+		return nil, fmt.Errorf("invalid tenants: %w", err)
+	}
+	return registry, nil
+}
@@ -0,0 +1,94 @@
+package config
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/BullionBear/sequex/internal/model/sqx"
+	"github.com/BullionBear/sequex/internal/volatility"
+)
+
+// VolatilityConfig represents the configuration of the volatility service
+type VolatilityConfig struct {
+	Binance        []string          `json:"binance"`         // Symbols whose Binance USDⓈ-M klines are streamed, e.g. BTC-USDT
+	Interval       string            `json:"interval"`        // Kline interval, 1m when omitted
+	Windows        []int             `json:"windows"`         // Returns each volatility is computed over, 30, 240 and 1440 when omitted
+	LowRatio       float64           `json:"low_ratio"`       // Short to long volatility ratio below which the regime is low, 0.75 when omitted
+	HighRatio      float64           `json:"high_ratio"`      // Short to long volatility ratio above which the regime is high, 1.5 when omitted
+	TrendThreshold float64           `json:"trend_threshold"` // Efficiency ratio from which prices trend, 0.3 when omitted
+	Backfill       bool              `json:"backfill"`        // Fill the windows from the REST API on start
+	KlinesDir      string            `json:"klines_dir"`      // Columnar kline tables read before the REST API when backfilling, and keeping what it served
+	Diagnostics    DiagnosticsConfig `json:"diagnostics"`     // Runtime diagnostics endpoints
+	NATS           NATSConfig        `json:"nats"`            // Stream and subject the snapshots are published to
+}
+
+// LoadVolatilityConfig loads the volatility service configuration from a JSON file
+func LoadVolatilityConfig(filePath string) (*VolatilityConfig, error) {
+	if filePath == "" {
+		return nil, fmt.Errorf("config file path cannot be empty")
+	}
+
+	data, err := ReadConfig(filePath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read config file %s: %w", filePath, err)
+	}
+
+	var config VolatilityConfig
+	if err := json.Unmarshal(data, &config); err != nil {
+		return nil, fmt.Errorf("failed to parse config file %s: %w", filePath, err)
+	}
+
+	if err := config.Validate(); err != nil {
+		return nil, fmt.Errorf("invalid configuration in %s: %w", filePath, err)
+	}
+
+	return &config, nil
+}
+
+// Validate validates the volatility service configuration
+func (c *VolatilityConfig) Validate() error {
+	if len(c.Binance) == 0 {
+		return fmt.Errorf("binance cannot be empty")
+	}
+
+	for i, symbol := range c.Binance {
+		if _, err := sqx.NewSymbolFromStr(symbol); err != nil {
+			return fmt.Errorf("invalid binance[%d]: %w", i, err)
+		}
+	}
+
+	if c.Interval != "" {
+		if _, ok := klineIntervals[c.Interval]; !ok {
+			return fmt.Errorf("unsupported interval %q", c.Interval)
+		}
+	}
+
+	if err := c.Options().Validate(); err != nil {
+		return err
+	}
+
+	if err := c.Diagnostics.Validate(); err != nil {
+		return err
+	}
+
+	return c.NATS.Validate()
+}
+
+// KlineInterval returns the Binance kline interval
+func (c *VolatilityConfig) KlineInterval() string {
+	if c.Interval == "" {
+		return "1m"
+	}
+	return c.Interval
+}
+
+// Options converts the configuration into tracker options
+func (c *VolatilityConfig) Options() volatility.Options {
+	return volatility.Options{
+		Interval:       klineIntervals[c.KlineInterval()],
+		Windows:        c.Windows,
+		LowRatio:       c.LowRatio,
+		HighRatio:      c.HighRatio,
+		TrendThreshold: c.TrendThreshold,
+	}
+}
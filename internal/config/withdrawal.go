@@ -0,0 +1,119 @@
+package config
+
+import (
+	"crypto/ed25519"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/BullionBear/sequex/internal/withdrawal"
+	"github.com/BullionBear/sequex/pkg/eventbus"
+)
+
+// ApproverConfig is an approver of withdrawals
+type ApproverConfig struct {
+	Name      string `json:"name"`
+	PublicKey string `json:"public_key"` // Base64 ed25519 public key, see sqx withdrawals keygen
+}
+
+// WithdrawalConfig represents the configuration of the withdrawal node,
+// sending the withdrawals of a Binance account once approved
+type WithdrawalConfig struct {
+	Binance     ExchangeKeyConfig    `json:"binance"`      // Account withdrawn from, allowed withdrawals
+	Whitelist   []withdrawal.Address `json:"whitelist"`    // Only destinations withdrawals can be sent to
+	DailyLimits map[string]float64   `json:"daily_limits"` // By asset, per UTC day. Assets without a limit cannot be withdrawn.
+	Approvers   []ApproverConfig     `json:"approvers"`    // Who may request and approve withdrawals
+	Approvals   int                  `json:"approvals"`    // Signatures a withdrawal needs, the requester's included, withdrawal.MinApprovals when 0
+	TTLMs       int64                `json:"ttl_ms"`       // Pending withdrawals expire after, 24 hours when 0
+	AuditLog    string               `json:"audit_log"`    // Hash chained JSON lines audit log, required
+	Diagnostics DiagnosticsConfig    `json:"diagnostics"`  // Runtime diagnostics endpoints
+	NATS        NATSConfig           `json:"nats"`         // RPCs are served under the subject, withdrawal when empty, no stream needed
+}
+
+// LoadWithdrawalConfig loads the withdrawal node configuration from a JSON file
+func LoadWithdrawalConfig(filePath string) (*WithdrawalConfig, error) {
+	if filePath == "" {
+		return nil, fmt.Errorf("config file path cannot be empty")
+	}
+
+	data, err := ReadConfig(filePath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read config file %s: %w", filePath, err)
+	}
+
+	var config WithdrawalConfig
+	if err := json.Unmarshal(data, &config); err != nil {
+		return nil, fmt.Errorf("failed to parse config file %s: %w", filePath, err)
+	}
+
+	if err := config.Validate(); err != nil {
+		return nil, fmt.Errorf("invalid configuration in %s: %w", filePath, err)
+	}
+
+	return &config, nil
+}
+
+// Validate validates the withdrawal node configuration
+func (c *WithdrawalConfig) Validate() error {
+	if err := c.Binance.Validate("binance"); err != nil {
+		return err
+	}
+
+	policy, err := c.Policy()
+	if err != nil {
+		return err
+	}
+	if err := policy.Validate(); err != nil {
+		return fmt.Errorf("invalid policy: %w", err)
+	}
+
+	if c.AuditLog == "" {
+		return fmt.Errorf("audit_log cannot be empty")
+	}
+
+	if err := c.Diagnostics.Validate(); err != nil {
+		return err
+	}
+
+	if c.NATS.Subject != "" {
+		if err := eventbus.ValidatePattern(c.NATS.Subject); err != nil || strings.ContainsAny(c.NATS.Subject, "*>") {
+			return fmt.Errorf("invalid nats.subject %q", c.NATS.Subject)
+		}
+	}
+
+	return c.NATS.ValidateURIs()
+}
+
+// Policy returns the policy withdrawals are checked against
+func (c *WithdrawalConfig) Policy() (withdrawal.Policy, error) {
+	if c.TTLMs < 0 {
+		return withdrawal.Policy{}, fmt.Errorf("ttl_ms cannot be negative")
+	}
+	policy := withdrawal.Policy{
+		Whitelist:   c.Whitelist,
+		DailyLimits: c.DailyLimits,
+		Approvals:   c.Approvals,
+		TTL:         time.Duration(c.TTLMs) * time.Millisecond,
+	}
+	if policy.Approvals == 0 {
+		policy.Approvals = withdrawal.MinApprovals
+	}
+	for i, a := range c.Approvers {
+		key, err := base64.StdEncoding.DecodeString(a.PublicKey)
+		if err != nil || len(key) != ed25519.PublicKeySize {
+			return policy, fmt.Errorf("invalid approvers[%d].public_key: expected a base64 ed25519 public key", i)
+		}
+		policy.Approvers = append(policy.Approvers, withdrawal.Approver{Name: a.Name, Key: ed25519.PublicKey(key)})
+	}
+	return policy, nil
+}
+
+// RPCSubject returns the prefix the withdrawal RPCs are served under
+func (c *WithdrawalConfig) RPCSubject() string {
+	if c.NATS.Subject == "" {
+		return withdrawal.DefaultSubject
+	}
+	return c.NATS.Subject
+}
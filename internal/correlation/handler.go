@@ -0,0 +1,91 @@
+package correlation
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/nats-io/nats.go"
+)
+
+// Register serves the matrices of t:
+//
+//	GET /correlation?symbols=BTC-USDT,ETH-USDT   (every symbol when omitted)
+func Register(routes gin.IRoutes, t *Tracker) {
+	routes.GET("/correlation", func(c *gin.Context) {
+		var symbols []string
+		if s := c.Query("symbols"); s != "" {
+			symbols = strings.Split(s, ",")
+		}
+		m, err := t.Matrix(symbols...)
+		switch {
+		case errors.Is(err, ErrUnknownSymbol):
+			c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		case errors.Is(err, ErrNotEnoughData):
+			c.JSON(http.StatusServiceUnavailable, gin.H{"error": err.Error()})
+		case err != nil:
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		default:
+			c.JSON(http.StatusOK, m)
+		}
+	})
+}
+
+// Request asks for the matrices of Symbols, or of every symbol when empty
+type Request struct {
+	Symbols []string `json:"symbols,omitempty"`
+}
+
+type response struct {
+	Matrix *Matrix `json:"matrix,omitempty"`
+	Error  string  `json:"error,omitempty"`
+}
+
+// Serve answers Requests on subject with the matrices of t
+func Serve(conn *nats.Conn, subject string, t *Tracker) (*nats.Subscription, error) {
+	return conn.Subscribe(subject, func(msg *nats.Msg) {
+		var req Request
+		var resp response
+		if len(msg.Data) > 0 {
+			if err := json.Unmarshal(msg.Data, &req); err != nil {
+				resp.Error = fmt.Sprintf("invalid request: %v", err)
+			}
+		}
+		if resp.Error == "" {
+			if m, err := t.Matrix(req.Symbols...); err != nil {
+				resp.Error = err.Error()
+			} else {
+				resp.Matrix = &m
+			}
+		}
+		data, err := json.Marshal(resp)
+		if err != nil {
+			data = []byte(fmt.Sprintf(`{"error":%q}`, err.Error()))
+		}
+		msg.Respond(data)
+	})
+}
+
+// Query requests the matrices of symbols from the service answering on subject
+func Query(conn *nats.Conn, subject string, symbols []string, timeout time.Duration) (Matrix, error) {
+	data, err := json.Marshal(Request{Symbols: symbols})
+	if err != nil {
+		return Matrix{}, err
+	}
+	msg, err := conn.Request(subject, data, timeout)
+	if err != nil {
+		return Matrix{}, fmt.Errorf("failed to request correlation: %w", err)
+	}
+	var resp response
+	if err := json.Unmarshal(msg.Data, &resp); err != nil {
+		return Matrix{}, fmt.Errorf("failed to unmarshal correlation: %w", err)
+	}
+	if resp.Error != "" || resp.Matrix == nil {
+		return Matrix{}, fmt.Errorf("correlation service: %s", resp.Error)
+	}
+	return *resp.Matrix, nil
+}
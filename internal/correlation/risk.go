@@ -0,0 +1,44 @@
+package correlation
+
+import (
+	"fmt"
+	"math"
+)
+
+// Risk is the volatility of the value of a portfolio over one interval
+type Risk struct {
+	Volatility    float64 `json:"volatility"`    // Standard deviation of the value, in the exposures' currency
+	Undiversified float64 `json:"undiversified"` // Sum of the standalone volatilities, as if every pair were perfectly correlated
+	Ratio         float64 `json:"ratio"`         // Undiversified over Volatility, 1 without any diversification benefit
+}
+
+// Risk computes the volatility of exposures keyed by symbol, signed values
+// with short positions negative. Symbols missing from the matrix are an error.
+func (m Matrix) Risk(exposures map[string]float64) (Risk, error) {
+	weights := make([]float64, len(m.Symbols))
+	index := make(map[string]int, len(m.Symbols))
+	for i, symbol := range m.Symbols {
+		index[symbol] = i
+	}
+	for symbol, value := range exposures {
+		i, ok := index[symbol]
+		if !ok {
+			return Risk{}, fmt.Errorf("%w: %s", ErrUnknownSymbol, symbol)
+		}
+		weights[i] += value
+	}
+
+	var risk Risk
+	var variance float64
+	for i := range weights {
+		risk.Undiversified += math.Abs(weights[i]) * m.Volatility[i]
+		for j := range weights {
+			variance += weights[i] * weights[j] * m.Covariance[i][j]
+		}
+	}
+	risk.Volatility = math.Sqrt(max(variance, 0))
+	if risk.Volatility > 0 {
+		risk.Ratio = risk.Undiversified / risk.Volatility
+	}
+	return risk, nil
+}
@@ -0,0 +1,199 @@
+// Package correlation keeps rolling covariance and correlation matrices of
+// the log returns of closed klines, so portfolio risk limits can account for
+// how much positions diversify each other.
+package correlation
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"sort"
+	"sync"
+	"time"
+)
+
+var (
+	// ErrUnknownSymbol is returned for symbols no kline was received for
+	ErrUnknownSymbol = errors.New("unknown symbol")
+	// ErrNotEnoughData is returned until the symbols share MinPeriods returns
+	ErrNotEnoughData = errors.New("not enough data")
+)
+
+// Options configures a tracker
+type Options struct {
+	Interval   time.Duration // Kline interval, 1m when 0
+	Window     int           // Returns the matrices are computed over, 240 when 0
+	MinPeriods int           // Returns every symbol must share before a matrix is computed, 30 when 0
+}
+
+// Matrix is the covariance and correlation of the per-interval log returns
+// of Symbols. Rows and columns follow the order of Symbols.
+type Matrix struct {
+	Symbols     []string    `json:"symbols"`
+	IntervalMs  int64       `json:"interval_ms"`
+	Periods     int         `json:"periods"` // Returns the matrices were computed over
+	From        time.Time   `json:"from"`    // Open time of the first return's kline
+	To          time.Time   `json:"to"`      // Open time of the last return's kline
+	Volatility  []float64   `json:"volatility"`
+	Covariance  [][]float64 `json:"covariance"`
+	Correlation [][]float64 `json:"correlation"`
+}
+
+type point struct {
+	openTime int64 // Milliseconds
+	value    float64
+}
+
+type series struct {
+	last    point   // Last close
+	returns []point // Log return of each kline following the previous one, oldest first
+}
+
+// Tracker keeps the returns of the window per symbol. It is safe for
+// concurrent use.
+type Tracker struct {
+	opts Options
+
+	mu     sync.Mutex
+	series map[string]*series
+}
+
+// New creates a tracker
+func New(opts Options) *Tracker {
+	if opts.Interval <= 0 {
+		opts.Interval = time.Minute
+	}
+	if opts.Window <= 0 {
+		opts.Window = 240
+	}
+	if opts.MinPeriods <= 0 {
+		opts.MinPeriods = 30
+	}
+	opts.MinPeriods = max(2, min(opts.MinPeriods, opts.Window))
+	return &Tracker{opts: opts, series: make(map[string]*series)}
+}
+
+// OnKline records the close of a closed kline. Klines older than the last
+// one of the symbol are ignored; a return is only recorded when the kline
+// directly follows the previous one.
+func (t *Tracker) OnKline(symbol string, openTime int64, close float64) {
+	if close <= 0 || math.IsNaN(close) || math.IsInf(close, 0) {
+		return
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	s, ok := t.series[symbol]
+	if !ok {
+		s = &series{}
+		t.series[symbol] = s
+	}
+	if s.last.openTime != 0 && openTime <= s.last.openTime {
+		return
+	}
+	if s.last.openTime != 0 && openTime-s.last.openTime == t.opts.Interval.Milliseconds() {
+		s.returns = append(s.returns, point{openTime: openTime, value: math.Log(close / s.last.value)})
+		if len(s.returns) > t.opts.Window {
+			s.returns = append(s.returns[:0], s.returns[len(s.returns)-t.opts.Window:]...)
+		}
+	}
+	s.last = point{openTime: openTime, value: close}
+}
+
+// Symbols returns the symbols klines were received for, sorted
+func (t *Tracker) Symbols() []string {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	symbols := make([]string, 0, len(t.series))
+	for symbol := range t.series {
+		symbols = append(symbols, symbol)
+	}
+	sort.Strings(symbols)
+	return symbols
+}
+
+// Matrix computes the matrices of symbols, or of every symbol when empty,
+// over the latest returns they share
+func (t *Tracker) Matrix(symbols ...string) (Matrix, error) {
+	if len(symbols) == 0 {
+		symbols = t.Symbols()
+		if len(symbols) == 0 {
+			return Matrix{}, ErrNotEnoughData
+		}
+	}
+
+	t.mu.Lock()
+	counts := make(map[int64]int)
+	returns := make([]map[int64]float64, len(symbols))
+	for i, symbol := range symbols {
+		s, ok := t.series[symbol]
+		if !ok {
+			t.mu.Unlock()
+			return Matrix{}, fmt.Errorf("%w: %s", ErrUnknownSymbol, symbol)
+		}
+		returns[i] = make(map[int64]float64, len(s.returns))
+		for _, r := range s.returns {
+			returns[i][r.openTime] = r.value
+			counts[r.openTime]++
+		}
+	}
+	t.mu.Unlock()
+
+	var times []int64
+	for openTime, count := range counts {
+		if count == len(symbols) {
+			times = append(times, openTime)
+		}
+	}
+	if len(times) < t.opts.MinPeriods {
+		return Matrix{}, fmt.Errorf("%w: %d common returns, %d required", ErrNotEnoughData, len(times), t.opts.MinPeriods)
+	}
+	sort.Slice(times, func(i, j int) bool { return times[i] < times[j] })
+	if len(times) > t.opts.Window {
+		times = times[len(times)-t.opts.Window:]
+	}
+
+	n := len(symbols)
+	means := make([]float64, n)
+	for i := range symbols {
+		for _, openTime := range times {
+			means[i] += returns[i][openTime]
+		}
+		means[i] /= float64(len(times))
+	}
+	m := Matrix{
+		Symbols:     append([]string(nil), symbols...),
+		IntervalMs:  t.opts.Interval.Milliseconds(),
+		Periods:     len(times),
+		From:        time.UnixMilli(times[0]),
+		To:          time.UnixMilli(times[len(times)-1]),
+		Volatility:  make([]float64, n),
+		Covariance:  make([][]float64, n),
+		Correlation: make([][]float64, n),
+	}
+	for i := range symbols {
+		m.Covariance[i] = make([]float64, n)
+		m.Correlation[i] = make([]float64, n)
+	}
+	for i := range symbols {
+		for j := i; j < n; j++ {
+			var sum float64
+			for _, openTime := range times {
+				sum += (returns[i][openTime] - means[i]) * (returns[j][openTime] - means[j])
+			}
+			cov := sum / float64(len(times)-1)
+			m.Covariance[i][j], m.Covariance[j][i] = cov, cov
+		}
+		m.Volatility[i] = math.Sqrt(m.Covariance[i][i])
+	}
+	for i := range symbols {
+		m.Correlation[i][i] = 1
+		for j := i + 1; j < n; j++ {
+			// Left at 0 when either price did not move over the window
+			if m.Volatility[i] > 0 && m.Volatility[j] > 0 {
+				c := m.Covariance[i][j] / (m.Volatility[i] * m.Volatility[j])
+				m.Correlation[i][j], m.Correlation[j][i] = c, c
+			}
+		}
+	}
+	return m, nil
+}
@@ -0,0 +1,121 @@
+package correlation
+
+import (
+	"encoding/json"
+	"errors"
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+const minute = int64(60000)
+
+// feed records closes one minute apart starting at 1 minute past the epoch
+func feed(t *Tracker, symbol string, closes ...float64) {
+	for i, c := range closes {
+		t.OnKline(symbol, int64(i+1)*minute, c)
+	}
+}
+
+func TestMatrix(t *testing.T) {
+	tr := New(Options{MinPeriods: 3})
+	up := []float64{100, 101, 99, 102, 103, 101}
+	down := make([]float64, len(up))
+	for i, c := range up {
+		down[i] = 10000 / c
+	}
+	feed(tr, "BTC-USDT", up...)
+	feed(tr, "ETH-USDT", up...)
+	feed(tr, "XRP-USDT", down...)
+
+	m, err := tr.Matrix()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if m.Periods != 5 || len(m.Symbols) != 3 || m.Symbols[0] != "BTC-USDT" {
+		t.Fatalf("matrix = %+v", m)
+	}
+	if math.Abs(m.Correlation[0][1]-1) > 1e-9 || math.Abs(m.Correlation[0][2]+1) > 1e-9 {
+		t.Errorf("correlation = %v", m.Correlation)
+	}
+	if math.Abs(m.Covariance[0][0]-m.Volatility[0]*m.Volatility[0]) > 1e-12 {
+		t.Errorf("covariance = %v, volatility = %v", m.Covariance, m.Volatility)
+	}
+
+	// A long hedged by a perfectly anti-correlated short of the same size
+	risk, err := m.Risk(map[string]float64{"BTC-USDT": 1000, "XRP-USDT": 1000})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if risk.Volatility > 1e-6*risk.Undiversified || risk.Undiversified <= 0 {
+		t.Errorf("hedged risk = %+v", risk)
+	}
+	risk, _ = m.Risk(map[string]float64{"BTC-USDT": 1000, "ETH-USDT": 1000})
+	if math.Abs(risk.Ratio-1) > 1e-9 {
+		t.Errorf("undiversified risk = %+v", risk)
+	}
+	if _, err := m.Risk(map[string]float64{"SOL-USDT": 1}); !errors.Is(err, ErrUnknownSymbol) {
+		t.Errorf("unknown exposure: %v", err)
+	}
+}
+
+func TestMatrixAlignment(t *testing.T) {
+	tr := New(Options{MinPeriods: 2, Window: 5})
+	feed(tr, "BTC-USDT", 100, 101, 102, 103, 104, 105)
+	// A gap between the second and third kline leaves no return for the third
+	tr.OnKline("ETH-USDT", 1*minute, 10)
+	tr.OnKline("ETH-USDT", 2*minute, 11)
+	tr.OnKline("ETH-USDT", 4*minute, 12)
+	tr.OnKline("ETH-USDT", 5*minute, 13)
+	tr.OnKline("ETH-USDT", 3*minute, 99) // Out of order, ignored
+
+	if _, err := tr.Matrix("BTC-USDT", "SOL-USDT"); !errors.Is(err, ErrUnknownSymbol) {
+		t.Errorf("unknown symbol: %v", err)
+	}
+	m, err := tr.Matrix("BTC-USDT", "ETH-USDT")
+	if err != nil {
+		t.Fatal(err)
+	}
+	// BTC has returns for minutes 2 to 6, ETH for minutes 2 and 5
+	if m.Periods != 2 || m.From.UnixMilli() != 2*minute || m.To.UnixMilli() != 5*minute {
+		t.Errorf("matrix = %+v", m)
+	}
+
+	if _, err := New(Options{}).Matrix(); !errors.Is(err, ErrNotEnoughData) {
+		t.Errorf("empty tracker: %v", err)
+	}
+}
+
+func TestHandler(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	tr := New(Options{MinPeriods: 2})
+	feed(tr, "BTC-USDT", 100, 101, 99)
+	feed(tr, "ETH-USDT", 10, 11, 10)
+	rg := gin.New()
+	Register(rg, tr)
+
+	get := func(path string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		rg.ServeHTTP(w, httptest.NewRequest(http.MethodGet, path, nil))
+		return w
+	}
+
+	w := get("/correlation?symbols=ETH-USDT,BTC-USDT")
+	var m Matrix
+	if w.Code != http.StatusOK || json.Unmarshal(w.Body.Bytes(), &m) != nil {
+		t.Fatalf("correlation: %d %s", w.Code, w.Body)
+	}
+	if m.Symbols[0] != "ETH-USDT" || m.Correlation[0][1] <= 0 {
+		t.Errorf("matrix = %+v", m)
+	}
+	if w := get("/correlation?symbols=SOL-USDT"); w.Code != http.StatusNotFound {
+		t.Errorf("unknown symbol: %d", w.Code)
+	}
+	feed(tr, "SOL-USDT", 20)
+	if w := get("/correlation"); w.Code != http.StatusServiceUnavailable {
+		t.Errorf("not enough data: %d", w.Code)
+	}
+}
@@ -0,0 +1,160 @@
+// Package dryrun keeps strategies off the exchange. Their orders are
+// acknowledged, and filled at the prices of the market, by a paper layer in
+// place of the exchange, and logged and published as the orders they would
+// have sent, while a global flag and a flag by strategy are switched at
+// runtime.
+package dryrun
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/BullionBear/sequex/internal/model/sqx"
+)
+
+// Order is an order a strategy in dry run would have sent, or a cancel of
+// one, and the execution report the paper layer acknowledged it with.
+// Fills on paper carry neither.
+type Order struct {
+	Order  *sqx.OrderIntent    `json:"order,omitempty"`
+	Cancel *sqx.CancelIntent   `json:"cancel,omitempty"`
+	Report sqx.ExecutionReport `json:"report"`
+}
+
+// Change is a flag switched at runtime
+type Change struct {
+	Strategy string    `json:"strategy,omitempty"` // None for the global flag
+	Before   *bool     `json:"before,omitempty"`   // Nil when the strategy followed the global flag
+	After    *bool     `json:"after,omitempty"`    // Nil when the strategy follows the global flag
+	By       string    `json:"by"`
+	At       time.Time `json:"at"`
+}
+
+// Status is the state of the flags
+type Status struct {
+	Global     bool            `json:"global"`
+	Strategies map[string]bool `json:"strategies"` // Overrides of the global flag
+	Changes    []Change        `json:"changes"`    // Latest changes, oldest first
+}
+
+// auditSize is the number of changes Flags keep
+const auditSize = 100
+
+// Flags tell the strategies in dry run. They are safe for concurrent use.
+type Flags struct {
+	mu         sync.Mutex
+	global     bool
+	strategies map[string]bool
+	changes    []Change
+	onChange   []func(Change)
+}
+
+// NewFlags creates the flags, global for the strategies without their own
+func NewFlags(global bool, strategies map[string]bool) *Flags {
+	f := &Flags{global: global, strategies: make(map[string]bool, len(strategies))}
+	for strategy, enabled := range strategies {
+		f.strategies[strategy] = enabled
+	}
+	return f
+}
+
+// OnChange calls fn with every change made at runtime, e.g. to log it
+func (f *Flags) OnChange(fn func(Change)) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.onChange = append(f.onChange, fn)
+}
+
+// Enabled reports whether a strategy is in dry run
+func (f *Flags) Enabled(strategy string) bool {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if enabled, ok := f.strategies[strategy]; ok {
+		return enabled
+	}
+	return f.global
+}
+
+// Set switches the flag of a strategy, the global flag when strategy is
+// empty. A nil enabled drops the flag of the strategy, which follows the
+// global flag again.
+func (f *Flags) Set(strategy string, enabled *bool, by string) (Change, error) {
+	if by == "" {
+		return Change{}, fmt.Errorf("who switches the flag is required")
+	}
+	if strategy == "" && enabled == nil {
+		return Change{}, fmt.Errorf("the global flag cannot be dropped")
+	}
+	f.mu.Lock()
+	c := Change{Strategy: strategy, By: by, At: time.Now()}
+	if strategy == "" {
+		before := f.global
+		c.Before, c.After = &before, enabled
+		f.global = *enabled
+	} else {
+		if before, ok := f.strategies[strategy]; ok {
+			c.Before = &before
+		}
+		c.After = enabled
+		if enabled == nil {
+			delete(f.strategies, strategy)
+		} else {
+			f.strategies[strategy] = *enabled
+		}
+	}
+	f.changes = append(f.changes, c)
+	if len(f.changes) > auditSize {
+		f.changes = f.changes[len(f.changes)-auditSize:]
+	}
+	onChange := f.onChange
+	f.mu.Unlock()
+	for _, fn := range onChange {
+		fn(c)
+	}
+	return c, nil
+}
+
+// Status returns the flags and their latest changes
+func (f *Flags) Status() Status {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	s := Status{Global: f.global, Strategies: make(map[string]bool, len(f.strategies)), Changes: append([]Change(nil), f.changes...)}
+	for strategy, enabled := range f.strategies {
+		s.Strategies[strategy] = enabled
+	}
+	return s
+}
+
+// Ack returns the execution report the paper layer acknowledges an order
+// with: accepted and working, never reaching the exchange
+func Ack(intent sqx.OrderIntent) sqx.ExecutionReport {
+	return sqx.ExecutionReport{
+		OrderId:        "DRY-" + intent.ClientOrderId,
+		ClientOrderId:  intent.ClientOrderId,
+		ExecId:         execID(intent.ClientOrderId),
+		Strategy:       intent.Strategy,
+		Exchange:       intent.Exchange,
+		InstrumentType: intent.InstrumentType,
+		Symbol:         intent.Symbol,
+		Side:           intent.Side,
+		Type:           intent.Type,
+		Status:         sqx.OrderStatusNew,
+		Price:          intent.Price,
+		Quantity:       intent.Quantity,
+		Text:           "dry run",
+		Timestamp:      time.Now().UnixMilli(),
+	}
+}
+
+// Cancel returns the execution report the paper layer cancels a working
+// order with, from its last report
+func Cancel(working sqx.ExecutionReport, intent sqx.CancelIntent) sqx.ExecutionReport {
+	report := working
+	report.ClientOrderId = intent.ClientOrderId
+	report.ExecId = execID(intent.ClientOrderId)
+	report.Status = sqx.OrderStatusCanceled
+	report.LastPrice, report.LastQuantity = 0, 0
+	report.Timestamp = time.Now().UnixMilli()
+	return report
+}
@@ -0,0 +1,111 @@
+package dryrun
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/BullionBear/sequex/internal/model/sqx"
+)
+
+func TestFlags(t *testing.T) {
+	f := NewFlags(false, map[string]bool{"fix.A": true})
+	var changes []Change
+	f.OnChange(func(c Change) { changes = append(changes, c) })
+	if !f.Enabled("fix.A") || f.Enabled("fix.B") {
+		t.Fatal("flags of the configuration not followed")
+	}
+
+	on, off := true, false
+	if _, err := f.Set("", &on, ""); err == nil {
+		t.Error("flag switched by no one")
+	}
+	if _, err := f.Set("", nil, "ops"); err == nil {
+		t.Error("global flag dropped")
+	}
+	if _, err := f.Set("", &on, "ops"); err != nil {
+		t.Fatal(err)
+	}
+	if !f.Enabled("fix.B") {
+		t.Error("global flag not followed")
+	}
+	if _, err := f.Set("fix.B", &off, "ops"); err != nil {
+		t.Fatal(err)
+	}
+	if f.Enabled("fix.B") {
+		t.Error("flag of the strategy not followed")
+	}
+	c, err := f.Set("fix.B", nil, "ops")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if c.Before == nil || *c.Before || c.After != nil || !f.Enabled("fix.B") {
+		t.Errorf("dropped flag %+v", c)
+	}
+
+	status := f.Status()
+	if !status.Global || len(status.Strategies) != 1 || !status.Strategies["fix.A"] || len(status.Changes) != 3 || len(changes) != 3 {
+		t.Errorf("status %+v", status)
+	}
+}
+
+func TestPaper(t *testing.T) {
+	intent := sqx.OrderIntent{ClientOrderId: "1", Strategy: "fix.A", Symbol: sqx.NewSymbol("BTC", "USDT"), Side: sqx.SideBuy, Type: sqx.OrderTypeLimit, Quantity: 2, Price: 50000}
+	ack := Ack(intent)
+	if ack.Status != sqx.OrderStatusNew || ack.LeavesQuantity() != 2 || ack.IsFill() || ack.Strategy != "fix.A" {
+		t.Fatalf("ack %+v", ack)
+	}
+	canceled := Cancel(ack, sqx.CancelIntent{ClientOrderId: "2", OrigClientOrderId: "1"})
+	if canceled.Status != sqx.OrderStatusCanceled || canceled.ClientOrderId != "2" || canceled.OrderId != ack.OrderId || canceled.ExecId == ack.ExecId {
+		t.Errorf("cancel %+v", canceled)
+	}
+}
+
+// fakeRates prices assets in USDT
+type fakeRates map[string]float64
+
+func (r fakeRates) Rate(from, to string) (float64, error) {
+	if rate, ok := r[from+"-"+to]; ok {
+		return rate, nil
+	}
+	return 0, fmt.Errorf("no rate from %s to %s", from, to)
+}
+
+func TestPaperFills(t *testing.T) {
+	rates := fakeRates{"BTC-USDT": 50000}
+	p := NewPaper(rates)
+	btc := sqx.NewSymbol("BTC", "USDT")
+
+	reports := p.Submit("1", sqx.OrderIntent{ClientOrderId: "1", Symbol: btc, Side: sqx.SideBuy, Type: sqx.OrderTypeMarket, Quantity: 1})
+	if len(reports) != 2 || reports[1].Status != sqx.OrderStatusFilled || reports[1].LastPrice != 50000 || p.Working("1") {
+		t.Fatalf("market order %+v", reports)
+	}
+	reports = p.Submit("2", sqx.OrderIntent{ClientOrderId: "2", Symbol: btc, Side: sqx.SideBuy, Type: sqx.OrderTypeLimit, TimeInForce: sqx.TimeInForceIOC, Quantity: 1, Price: 49000})
+	if len(reports) != 2 || reports[1].Status != sqx.OrderStatusExpired {
+		t.Fatalf("IOC order %+v", reports)
+	}
+	reports = p.Submit("3", sqx.OrderIntent{ClientOrderId: "3", Symbol: btc, Side: sqx.SideBuy, Type: sqx.OrderTypeLimit, Quantity: 1, Price: 49000})
+	if len(reports) != 1 || !p.Working("3") {
+		t.Fatalf("resting order %+v", reports)
+	}
+	p.Submit("4", sqx.OrderIntent{ClientOrderId: "4", Symbol: btc, Side: sqx.SideSell, Type: sqx.OrderTypeStopMarket, Quantity: 1, StopPrice: 48000})
+	if fills := p.Match(); len(fills) != 0 {
+		t.Errorf("filled out of the market %+v", fills)
+	}
+
+	rates["BTC-USDT"] = 48500
+	fills := p.Match()
+	if len(fills) != 1 || fills[0].Key != "3" || fills[0].Report.LastPrice != 49000 || fills[0].Report.CumQuantity != 1 {
+		t.Fatalf("fills %+v", fills)
+	}
+	if _, ok := p.Cancel("3", sqx.CancelIntent{ClientOrderId: "5", OrigClientOrderId: "3"}); ok {
+		t.Error("filled order canceled")
+	}
+	if report, ok := p.Cancel("4", sqx.CancelIntent{ClientOrderId: "6", OrigClientOrderId: "4"}); !ok || report.Status != sqx.OrderStatusCanceled || p.Working("4") {
+		t.Errorf("cancel %+v", report)
+	}
+
+	// Without prices orders are acknowledged only
+	if reports := NewPaper(nil).Submit("1", sqx.OrderIntent{ClientOrderId: "1", Symbol: btc, Side: sqx.SideBuy, Type: sqx.OrderTypeMarket, Quantity: 1}); len(reports) != 1 {
+		t.Errorf("market order without prices %+v", reports)
+	}
+}
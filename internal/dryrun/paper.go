@@ -0,0 +1,142 @@
+package dryrun
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/BullionBear/sequex/internal/model/sqx"
+	"github.com/BullionBear/sequex/internal/pms"
+)
+
+// Fill is an execution report of the paper layer for the order of a key
+type Fill struct {
+	Key    string
+	Report sqx.ExecutionReport
+}
+
+// paperOrder is an order working on paper
+type paperOrder struct {
+	intent sqx.OrderIntent
+	report sqx.ExecutionReport // Last
+}
+
+// Paper is the paper layer. It acknowledges the orders kept off the
+// exchange and, given prices, fills them whole once marketable at the mark
+// of their symbol: market orders at once, limit orders once the mark
+// reaches their price and stop orders once it reaches their stop. Without
+// prices orders are acknowledged only. It is safe for concurrent use.
+type Paper struct {
+	prices pms.Converter
+	mu     sync.Mutex
+	orders map[string]*paperOrder // Working, by key
+}
+
+// NewPaper creates a paper layer marking symbols at prices, e.g. fx.Rates,
+// acknowledging orders only when nil
+func NewPaper(prices pms.Converter) *Paper {
+	return &Paper{prices: prices, orders: make(map[string]*paperOrder)}
+}
+
+// mark returns the price of the base of symbol in its quote
+func (p *Paper) mark(symbol sqx.Symbol) (float64, bool) {
+	if p.prices == nil {
+		return 0, false
+	}
+	mark, err := p.prices.Rate(symbol.Base, symbol.Quote)
+	return mark, err == nil && mark > 0
+}
+
+// marketable returns the price an order fills at, and whether it fills
+func (p *Paper) marketable(intent sqx.OrderIntent) (float64, bool) {
+	mark, ok := p.mark(intent.Symbol)
+	if !ok {
+		return 0, false
+	}
+	buy := intent.Side == sqx.SideBuy
+	switch intent.Type {
+	case sqx.OrderTypeMarket:
+		return mark, true
+	case sqx.OrderTypeLimit:
+		return mark, buy && mark <= intent.Price || !buy && mark >= intent.Price
+	case sqx.OrderTypeStopMarket:
+		return mark, buy && mark >= intent.StopPrice || !buy && mark <= intent.StopPrice
+	}
+	return 0, false
+}
+
+// Submit acknowledges an order under key, then fills it when marketable.
+// An IOC or FOK order that is not expires.
+func (p *Paper) Submit(key string, intent sqx.OrderIntent) []sqx.ExecutionReport {
+	ack := Ack(intent)
+	reports := []sqx.ExecutionReport{ack}
+	if price, ok := p.marketable(intent); ok {
+		return append(reports, fill(ack, price))
+	}
+	if intent.TimeInForce == sqx.TimeInForceIOC || intent.TimeInForce == sqx.TimeInForceFOK {
+		expired := ack
+		expired.ExecId = execID(intent.ClientOrderId)
+		expired.Status = sqx.OrderStatusExpired
+		return append(reports, expired)
+	}
+	p.mu.Lock()
+	p.orders[key] = &paperOrder{intent: intent, report: ack}
+	p.mu.Unlock()
+	return reports
+}
+
+// Cancel cancels the working order of key, reporting false when none works
+// on paper
+func (p *Paper) Cancel(key string, intent sqx.CancelIntent) (sqx.ExecutionReport, bool) {
+	p.mu.Lock()
+	o, ok := p.orders[key]
+	delete(p.orders, key)
+	p.mu.Unlock()
+	if !ok {
+		return sqx.ExecutionReport{}, false
+	}
+	return Cancel(o.report, intent), true
+}
+
+// Working reports whether the order of key works on paper
+func (p *Paper) Working(key string) bool {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	_, ok := p.orders[key]
+	return ok
+}
+
+// Match fills the working orders marketable at the current marks, resting
+// limit orders at their price
+func (p *Paper) Match() []Fill {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	var fills []Fill
+	for key, o := range p.orders {
+		price, ok := p.marketable(o.intent)
+		if !ok {
+			continue
+		}
+		if o.intent.Type == sqx.OrderTypeLimit {
+			price = o.intent.Price
+		}
+		fills = append(fills, Fill{Key: key, Report: fill(o.report, price)})
+		delete(p.orders, key)
+	}
+	return fills
+}
+
+// fill returns the report filling a working order whole at price
+func fill(working sqx.ExecutionReport, price float64) sqx.ExecutionReport {
+	report := working
+	report.ExecId = execID(working.ClientOrderId)
+	report.Status = sqx.OrderStatusFilled
+	report.LastQuantity, report.LastPrice = working.Quantity, price
+	report.CumQuantity, report.AvgPrice = working.Quantity, price
+	report.Timestamp = time.Now().UnixMilli()
+	return report
+}
+
+func execID(clientOrderID string) string {
+	return fmt.Sprintf("DRY-%s-%d", clientOrderID, time.Now().UnixNano())
+}
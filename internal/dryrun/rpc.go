@@ -0,0 +1,61 @@
+package dryrun
+
+import (
+	"context"
+
+	"github.com/BullionBear/sequex/pkg/eventbus"
+	"github.com/nats-io/nats.go"
+)
+
+// DefaultSubject is the prefix the params RPCs of a service, switching its
+// dry-run flags, are served under when none is configured
+const DefaultSubject = "params"
+
+// SetRequest switches a flag
+type SetRequest struct {
+	Strategy string `json:"strategy,omitempty"` // The global flag when empty
+	Enabled  *bool  `json:"enabled"`            // Null drops the flag of the strategy, which follows the global flag
+	By       string `json:"by"`                 // Who switches the flag, required
+}
+
+// ListRequest asks for the flags and their latest changes
+type ListRequest struct{}
+
+// SetEndpoint is the endpoint flags are switched on, prefix.dryrun.set
+func SetEndpoint(prefix string) eventbus.Endpoint[SetRequest, Change] {
+	return eventbus.NewCommand[SetRequest, Change](prefix+".dryrun.set", eventbus.JSON)
+}
+
+// ListEndpoint is the endpoint flags are listed on, prefix.dryrun.list
+func ListEndpoint(prefix string) eventbus.Endpoint[ListRequest, Status] {
+	return eventbus.NewEndpoint[ListRequest, Status](prefix+".dryrun.list", eventbus.JSON)
+}
+
+// Serve answers the dry-run RPCs of flags under prefix
+func Serve(bus *eventbus.Bus, prefix string, flags *Flags) ([]*nats.Subscription, error) {
+	var subs []*nats.Subscription
+	for _, serve := range []func() (*nats.Subscription, error){
+		func() (*nats.Subscription, error) {
+			return SetEndpoint(prefix).Serve(bus, func(ctx context.Context, req *SetRequest) (*Change, error) {
+				c, err := flags.Set(req.Strategy, req.Enabled, eventbus.Caller(ctx, req.By))
+				return &c, err
+			})
+		},
+		func() (*nats.Subscription, error) {
+			return ListEndpoint(prefix).Serve(bus, func(_ context.Context, _ *ListRequest) (*Status, error) {
+				s := flags.Status()
+				return &s, nil
+			})
+		},
+	} {
+		sub, err := serve()
+		if err != nil {
+			for _, sub := range subs {
+				sub.Unsubscribe()
+			}
+			return nil, err
+		}
+		subs = append(subs, sub)
+	}
+	return subs, nil
+}
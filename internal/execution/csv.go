@@ -0,0 +1,45 @@
+package execution
+
+import (
+	"encoding/csv"
+	"io"
+	"strconv"
+)
+
+var (
+	orderColumns = []string{"order_id", "client_order_id", "strategy", "exchange", "instrument", "symbol", "side", "type", "status",
+		"price", "quantity", "cum_quantity", "avg_price", "commission", "commission_asset", "text", "created_at", "updated_at"}
+	fillColumns = []string{"exec_id", "order_id", "client_order_id", "strategy", "exchange", "instrument", "symbol", "side",
+		"price", "quantity", "commission", "commission_asset", "timestamp"}
+)
+
+// WriteOrdersCSV encodes orders with a header line
+func WriteOrdersCSV(w io.Writer, orders []Order) error {
+	cw := csv.NewWriter(w)
+	cw.Write(orderColumns)
+	for _, o := range orders {
+		cw.Write([]string{o.OrderID, o.ClientOrderID, o.Strategy, o.Exchange, o.Instrument, o.Symbol, o.Side, o.Type, o.Status,
+			formatFloat(o.Price), formatFloat(o.Quantity), formatFloat(o.CumQuantity), formatFloat(o.AvgPrice),
+			formatFloat(o.Commission), o.CommissionAsset, o.Text,
+			strconv.FormatInt(o.CreatedAt, 10), strconv.FormatInt(o.UpdatedAt, 10)})
+	}
+	cw.Flush()
+	return cw.Error()
+}
+
+// WriteFillsCSV encodes fills with a header line
+func WriteFillsCSV(w io.Writer, fills []Fill) error {
+	cw := csv.NewWriter(w)
+	cw.Write(fillColumns)
+	for _, f := range fills {
+		cw.Write([]string{f.ExecID, f.OrderID, f.ClientOrderID, f.Strategy, f.Exchange, f.Instrument, f.Symbol, f.Side,
+			formatFloat(f.Price), formatFloat(f.Quantity), formatFloat(f.Commission), f.CommissionAsset,
+			strconv.FormatInt(f.Timestamp, 10)})
+	}
+	cw.Flush()
+	return cw.Error()
+}
+
+func formatFloat(v float64) string {
+	return strconv.FormatFloat(v, 'f', -1, 64)
+}
@@ -0,0 +1,47 @@
+package execution
+
+import (
+	"sort"
+	"time"
+
+	"github.com/BullionBear/sequex/internal/pms"
+)
+
+// StrategyFees is the fee report of the fills of one strategy
+type StrategyFees struct {
+	Strategy string        `json:"strategy"`
+	Fees     pms.FeeReport `json:"fees"`
+}
+
+// PortfolioFill converts the fill for the portfolio ledger
+func (f Fill) PortfolioFill() pms.Fill {
+	return pms.Fill{
+		ID:        f.OrderID + "/" + f.ExecID,
+		Symbol:    f.Symbol,
+		Side:      f.Side,
+		Quantity:  f.Quantity,
+		Price:     f.Price,
+		Fee:       f.Commission,
+		FeeAsset:  f.CommissionAsset,
+		Timestamp: f.Timestamp,
+	}
+}
+
+// FeesByStrategy totals the commissions of the fills per strategy and UTC
+// day, converted to currency at the rate as of each fill
+func FeesByStrategy(fills []Fill, currency string, rates pms.HistoricalConverter) ([]StrategyFees, error) {
+	byStrategy := make(map[string][]pms.Fill)
+	for _, f := range fills {
+		byStrategy[f.Strategy] = append(byStrategy[f.Strategy], f.PortfolioFill())
+	}
+	list := make([]StrategyFees, 0, len(byStrategy))
+	for strategy, fills := range byStrategy {
+		report, err := pms.SummarizeFees(fills, currency, rates, time.Time{}, time.Time{})
+		if err != nil {
+			return nil, err
+		}
+		list = append(list, StrategyFees{Strategy: strategy, Fees: report})
+	}
+	sort.Slice(list, func(i, j int) bool { return list[i].Strategy < list[j].Strategy })
+	return list, nil
+}
@@ -0,0 +1,387 @@
+// Package execution keeps the order and fill history built from the OMS
+// execution reports. The reports can be journaled to an append-only JSON
+// lines file, which is replayed on open so the history survives restarts.
+package execution
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/BullionBear/sequex/internal/model/sqx"
+)
+
+var (
+	// ErrNotFound is returned for unknown orders
+	ErrNotFound = errors.New("not found")
+	// ErrInvalid is returned for invalid reports and filters
+	ErrInvalid = errors.New("invalid")
+)
+
+// Order is the latest state of an order
+type Order struct {
+	OrderID         string  `json:"order_id"`
+	ClientOrderID   string  `json:"client_order_id"`
+	Strategy        string  `json:"strategy"`
+	Exchange        string  `json:"exchange"`
+	Instrument      string  `json:"instrument"`
+	Symbol          string  `json:"symbol"`
+	Side            string  `json:"side"`
+	Type            string  `json:"type"`
+	Status          string  `json:"status"`
+	Price           float64 `json:"price"`
+	Quantity        float64 `json:"quantity"`
+	CumQuantity     float64 `json:"cum_quantity"`
+	AvgPrice        float64 `json:"avg_price"`
+	Commission      float64 `json:"commission"` // Sum over the fills
+	CommissionAsset string  `json:"commission_asset"`
+	Text            string  `json:"text"`
+	CreatedAt       int64   `json:"created_at"` // Unix milliseconds of the first report
+	UpdatedAt       int64   `json:"updated_at"` // Unix milliseconds of the latest report
+}
+
+// Fill is one execution of an order
+type Fill struct {
+	ExecID          string  `json:"exec_id"`
+	OrderID         string  `json:"order_id"`
+	ClientOrderID   string  `json:"client_order_id"`
+	Strategy        string  `json:"strategy"`
+	Exchange        string  `json:"exchange"`
+	Instrument      string  `json:"instrument"`
+	Symbol          string  `json:"symbol"`
+	Side            string  `json:"side"`
+	Price           float64 `json:"price"`
+	Quantity        float64 `json:"quantity"`
+	Commission      float64 `json:"commission"`
+	CommissionAsset string  `json:"commission_asset"`
+	Timestamp       int64   `json:"timestamp"` // Unix milliseconds
+}
+
+// Filter selects orders and fills. Zero fields match everything.
+type Filter struct {
+	From     int64  // First millisecond included, of the order creation or the fill
+	To       int64  // First millisecond excluded
+	Symbol   string // BASE-QUOTE
+	Strategy string
+	Exchange string
+	Status   string // Order status, e.g. FILLED; fills match the status of their order
+	Limit    int    // Most recent matches returned, every match when 0
+}
+
+// normalize checks the fields of a filter and returns it with canonical
+// symbol, exchange and status names
+func (f Filter) normalize() (Filter, error) {
+	if f.From < 0 || f.To < 0 || f.Limit < 0 {
+		return f, fmt.Errorf("%w: from, to and limit cannot be negative", ErrInvalid)
+	}
+	if f.To > 0 && f.To <= f.From {
+		return f, fmt.Errorf("%w: to must be after from", ErrInvalid)
+	}
+	if f.Symbol != "" {
+		symbol, err := sqx.NewSymbolFromStr(strings.ToUpper(f.Symbol))
+		if err != nil {
+			return f, fmt.Errorf("%w: %v", ErrInvalid, err)
+		}
+		f.Symbol = symbol.String()
+	}
+	if f.Exchange != "" {
+		exchange := sqx.NewExchange(f.Exchange)
+		if exchange == sqx.ExchangeUnknown {
+			return f, fmt.Errorf("%w: unknown exchange %q", ErrInvalid, f.Exchange)
+		}
+		f.Exchange = exchange.String()
+	}
+	if f.Status != "" {
+		status := sqx.NewOrderStatus(f.Status)
+		if status == sqx.OrderStatusUnknown {
+			return f, fmt.Errorf("%w: unknown status %q", ErrInvalid, f.Status)
+		}
+		f.Status = status.String()
+	}
+	return f, nil
+}
+
+func (f Filter) match(at int64, symbol, strategy, exchange string) bool {
+	return at >= f.From && (f.To == 0 || at < f.To) &&
+		(f.Symbol == "" || f.Symbol == symbol) &&
+		(f.Strategy == "" || f.Strategy == strategy) &&
+		(f.Exchange == "" || f.Exchange == exchange)
+}
+
+// Store is the order and fill history. It is safe for concurrent use.
+type Store struct {
+	mu      sync.RWMutex
+	orders  map[string]*Order // Keyed by orderKey
+	fills   []Fill            // Ordered by timestamp
+	execs   map[string]bool   // Fills recorded, keyed by orderKey and exec id
+	journal *os.File
+}
+
+// NewMemoryStore creates a history kept in memory only
+func NewMemoryStore() *Store {
+	return &Store{orders: make(map[string]*Order), execs: make(map[string]bool)}
+}
+
+// Open creates a history journaled to path, replaying the reports it holds
+func Open(path string) (*Store, error) {
+	s := NewMemoryStore()
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_RDWR|os.O_APPEND, 0o644)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open journal %s: %w", path, err)
+	}
+	size, err := s.replay(f)
+	if err == nil {
+		// Drop a truncated last line so the next report starts on its own line
+		err = f.Truncate(size)
+	}
+	if err != nil {
+		f.Close()
+		return nil, fmt.Errorf("failed to replay journal %s: %w", path, err)
+	}
+	s.journal = f
+	return s, nil
+}
+
+// replay applies the journaled reports and returns the size of the lines
+// read. A truncated last line, left by a crash during a write, is skipped.
+func (s *Store) replay(r io.Reader) (int64, error) {
+	reader := bufio.NewReader(r)
+	var size int64
+	for line := 1; ; line++ {
+		data, err := reader.ReadBytes('\n')
+		if err == io.EOF {
+			return size, nil
+		}
+		if err != nil {
+			return size, err
+		}
+		var report sqx.ExecutionReport
+		if err := sqx.UnmarshalExecutionReport(data, &report); err != nil {
+			return size, fmt.Errorf("line %d: %w", line, err)
+		}
+		s.apply(report)
+		size += int64(len(data))
+	}
+}
+
+// Close closes the journal
+func (s *Store) Close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.journal == nil {
+		return nil
+	}
+	err := s.journal.Close()
+	s.journal = nil
+	return err
+}
+
+// Add records a report, returning false for fills already recorded
+func (s *Store) Add(report sqx.ExecutionReport) (bool, error) {
+	if orderKey(report) == "" {
+		return false, fmt.Errorf("%w: report has neither order id nor client order id", ErrInvalid)
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if key := execKey(report); key != "" && s.execs[key] {
+		return false, nil
+	}
+	if s.journal != nil {
+		data, err := report.Marshal()
+		if err != nil {
+			return false, err
+		}
+		if _, err := s.journal.Write(append(data, '\n')); err != nil {
+			return false, fmt.Errorf("failed to journal report: %w", err)
+		}
+	}
+	s.apply(report)
+	return true, nil
+}
+
+func (s *Store) apply(report sqx.ExecutionReport) {
+	key := orderKey(report)
+	if exec := execKey(report); exec != "" {
+		if s.execs[exec] {
+			return
+		}
+		s.execs[exec] = true
+	}
+
+	o, ok := s.orders[key]
+	if !ok {
+		o = &Order{
+			OrderID:       report.OrderId,
+			ClientOrderID: report.ClientOrderId,
+			CreatedAt:     report.Timestamp,
+		}
+		s.orders[key] = o
+	}
+	if report.Timestamp < o.CreatedAt {
+		o.CreatedAt = report.Timestamp
+	}
+	// Reports arriving out of order only add their fill
+	if report.Timestamp >= o.UpdatedAt {
+		o.Strategy = report.Strategy
+		o.Exchange = report.Exchange.String()
+		o.Instrument = report.InstrumentType.String()
+		o.Symbol = report.Symbol.String()
+		o.Side = report.Side.String()
+		o.Type = report.Type.String()
+		o.Status = report.Status.String()
+		o.Price = report.Price
+		o.Quantity = report.Quantity
+		o.CumQuantity = report.CumQuantity
+		o.AvgPrice = report.AvgPrice
+		o.Text = report.Text
+		o.UpdatedAt = report.Timestamp
+		if o.OrderID == "" {
+			o.OrderID = report.OrderId
+		}
+	}
+	if !report.IsFill() {
+		return
+	}
+	o.Commission += report.Commission
+	if report.CommissionAsset != "" {
+		o.CommissionAsset = report.CommissionAsset
+	}
+
+	fill := Fill{
+		ExecID:          report.ExecId,
+		OrderID:         report.OrderId,
+		ClientOrderID:   report.ClientOrderId,
+		Strategy:        report.Strategy,
+		Exchange:        report.Exchange.String(),
+		Instrument:      report.InstrumentType.String(),
+		Symbol:          report.Symbol.String(),
+		Side:            report.Side.String(),
+		Price:           report.LastPrice,
+		Quantity:        report.LastQuantity,
+		Commission:      report.Commission,
+		CommissionAsset: report.CommissionAsset,
+		Timestamp:       report.Timestamp,
+	}
+	i := sort.Search(len(s.fills), func(i int) bool { return s.fills[i].Timestamp > fill.Timestamp })
+	s.fills = append(s.fills, Fill{})
+	copy(s.fills[i+1:], s.fills[i:])
+	s.fills[i] = fill
+}
+
+// Order returns an order by order id or client order id
+func (s *Store) Order(id string) (Order, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	if o, ok := s.orders[id]; ok {
+		return *o, nil
+	}
+	for _, o := range s.orders {
+		if o.OrderID == id || o.ClientOrderID == id {
+			return *o, nil
+		}
+	}
+	return Order{}, fmt.Errorf("order %s: %w", id, ErrNotFound)
+}
+
+// Orders returns the orders matching f, oldest first
+func (s *Store) Orders(f Filter) ([]Order, error) {
+	f, err := f.normalize()
+	if err != nil {
+		return nil, err
+	}
+	s.mu.RLock()
+	list := make([]Order, 0)
+	for _, o := range s.orders {
+		if f.match(o.CreatedAt, o.Symbol, o.Strategy, o.Exchange) && (f.Status == "" || o.Status == f.Status) {
+			list = append(list, *o)
+		}
+	}
+	s.mu.RUnlock()
+	sort.Slice(list, func(i, j int) bool {
+		if list[i].CreatedAt != list[j].CreatedAt {
+			return list[i].CreatedAt < list[j].CreatedAt
+		}
+		return list[i].ClientOrderID < list[j].ClientOrderID
+	})
+	return limit(list, f.Limit), nil
+}
+
+// Fills returns the fills matching f, oldest first
+func (s *Store) Fills(f Filter) ([]Fill, error) {
+	f, err := f.normalize()
+	if err != nil {
+		return nil, err
+	}
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	list := make([]Fill, 0)
+	for _, fill := range s.fills {
+		if !f.match(fill.Timestamp, fill.Symbol, fill.Strategy, fill.Exchange) {
+			continue
+		}
+		if f.Status != "" {
+			o := s.orders[fillOrderKey(fill)]
+			if o == nil || o.Status != f.Status {
+				continue
+			}
+		}
+		list = append(list, fill)
+	}
+	return limit(list, f.Limit), nil
+}
+
+// OrderFills returns the fills of an order by order id or client order id, oldest first
+func (s *Store) OrderFills(id string) ([]Fill, error) {
+	o, err := s.Order(id)
+	if err != nil {
+		return nil, err
+	}
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	list := make([]Fill, 0)
+	for _, fill := range s.fills {
+		if fillOrderKey(fill) == orderKeyOf(o.OrderID, o.ClientOrderID) {
+			list = append(list, fill)
+		}
+	}
+	return list, nil
+}
+
+func limit[T any](list []T, n int) []T {
+	if n > 0 && len(list) > n {
+		return list[len(list)-n:]
+	}
+	return list
+}
+
+// orderKey identifies the order of a report. The client order id is set
+// from the first acknowledgement while the exchange order id may be missing
+// from rejects, so it is preferred.
+func orderKey(report sqx.ExecutionReport) string {
+	return orderKeyOf(report.OrderId, report.ClientOrderId)
+}
+
+func fillOrderKey(fill Fill) string {
+	return orderKeyOf(fill.OrderID, fill.ClientOrderID)
+}
+
+func orderKeyOf(orderID, clientOrderID string) string {
+	if clientOrderID != "" {
+		return clientOrderID
+	}
+	return orderID
+}
+
+// execKey identifies the fill of a report, empty for reports without a fill
+// or exec id, which cannot be deduplicated
+func execKey(report sqx.ExecutionReport) string {
+	if !report.IsFill() || report.ExecId == "" {
+		return ""
+	}
+	return orderKey(report) + "/" + report.ExecId
+}
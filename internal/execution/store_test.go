@@ -0,0 +1,146 @@
+package execution
+
+import (
+	"bytes"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/BullionBear/sequex/internal/model/sqx"
+)
+
+func report(clientOrderID, execID string, status sqx.OrderStatus, lastQty float64, ts int64) sqx.ExecutionReport {
+	return sqx.ExecutionReport{
+		OrderId:         "x-" + clientOrderID,
+		ClientOrderId:   clientOrderID,
+		ExecId:          execID,
+		Strategy:        "grid",
+		Exchange:        sqx.ExchangeBinance,
+		InstrumentType:  sqx.InstrumentTypeSpot,
+		Symbol:          sqx.NewSymbol("BTC", "USDT"),
+		Side:            sqx.SideBuy,
+		Type:            sqx.OrderTypeLimit,
+		Status:          status,
+		Price:           60000,
+		Quantity:        2,
+		LastPrice:       60000,
+		LastQuantity:    lastQty,
+		CumQuantity:     lastQty,
+		Commission:      lastQty * 0.001,
+		CommissionAsset: "BTC",
+		Timestamp:       ts,
+	}
+}
+
+func TestStore(t *testing.T) {
+	s := NewMemoryStore()
+	reports := []sqx.ExecutionReport{
+		report("a", "", sqx.OrderStatusNew, 0, 1000),
+		report("a", "1", sqx.OrderStatusPartiallyFilled, 1, 2000),
+		report("a", "2", sqx.OrderStatusFilled, 1, 3000),
+		report("b", "", sqx.OrderStatusCanceled, 0, 2500),
+	}
+	for _, r := range reports {
+		if ok, err := s.Add(r); !ok || err != nil {
+			t.Fatalf("add %+v: %v, %v", r, ok, err)
+		}
+	}
+	if ok, err := s.Add(reports[1]); ok || err != nil {
+		t.Errorf("duplicate fill: %v, %v", ok, err)
+	}
+	if _, err := s.Add(sqx.ExecutionReport{}); !errors.Is(err, ErrInvalid) {
+		t.Errorf("report without ids: %v", err)
+	}
+
+	o, err := s.Order("x-a")
+	if err != nil || o.Status != "FILLED" || o.Commission != 0.002 || o.CreatedAt != 1000 || o.UpdatedAt != 3000 {
+		t.Errorf("order = %+v, %v", o, err)
+	}
+	if _, err := s.Order("c"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("unknown order: %v", err)
+	}
+
+	orders, _ := s.Orders(Filter{})
+	if len(orders) != 2 || orders[0].ClientOrderID != "a" {
+		t.Errorf("orders = %+v", orders)
+	}
+	orders, _ = s.Orders(Filter{Status: "canceled"})
+	if len(orders) != 1 || orders[0].ClientOrderID != "b" {
+		t.Errorf("canceled orders = %+v", orders)
+	}
+	orders, _ = s.Orders(Filter{From: 2000, Symbol: "btc-usdt"})
+	if len(orders) != 1 || orders[0].ClientOrderID != "b" {
+		t.Errorf("orders from 2000 = %+v", orders)
+	}
+
+	fills, _ := s.Fills(Filter{Strategy: "grid", Exchange: "binance"})
+	if len(fills) != 2 || fills[0].ExecID != "1" || fills[1].Quantity != 1 {
+		t.Errorf("fills = %+v", fills)
+	}
+	fills, _ = s.Fills(Filter{Limit: 1})
+	if len(fills) != 1 || fills[0].ExecID != "2" {
+		t.Errorf("latest fill = %+v", fills)
+	}
+	if fills, _ = s.Fills(Filter{Status: "CANCELED"}); len(fills) != 0 {
+		t.Errorf("fills of canceled orders = %+v", fills)
+	}
+	if fills, _ = s.OrderFills("a"); len(fills) != 2 {
+		t.Errorf("order fills = %+v", fills)
+	}
+
+	for _, f := range []Filter{{Status: "DONE"}, {Exchange: "FTX"}, {Symbol: "BTCUSDT"}, {From: 10, To: 5}, {Limit: -1}} {
+		if _, err := s.Orders(f); !errors.Is(err, ErrInvalid) {
+			t.Errorf("filter %+v: %v", f, err)
+		}
+	}
+}
+
+func TestJournal(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "executions.jsonl")
+	s, err := Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	s.Add(report("a", "", sqx.OrderStatusNew, 0, 1000))
+	s.Add(report("a", "1", sqx.OrderStatusFilled, 2, 2000))
+	if err := s.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	// A crash in the middle of a write leaves a truncated line
+	f, _ := os.OpenFile(path, os.O_APPEND|os.O_WRONLY, 0)
+	f.WriteString(`{"order_id":"x-b","client_`)
+	f.Close()
+
+	s, err = Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ok, _ := s.Add(report("a", "1", sqx.OrderStatusFilled, 2, 2000)); ok {
+		t.Error("replayed fill recorded twice")
+	}
+	s.Add(report("b", "", sqx.OrderStatusNew, 0, 3000))
+	s.Close()
+
+	s, err = Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+	orders, _ := s.Orders(Filter{})
+	fills, _ := s.Fills(Filter{})
+	if len(orders) != 2 || orders[0].Status != "FILLED" || len(fills) != 1 {
+		t.Errorf("replayed orders = %+v, fills = %+v", orders, fills)
+	}
+
+	var buf bytes.Buffer
+	if err := WriteFillsCSV(&buf, fills); err != nil {
+		t.Fatal(err)
+	}
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	if len(lines) != 2 || lines[1] != "1,x-a,a,grid,BINANCE,SPOT,BTC-USDT,BUY,60000,2,0.002,BTC,2000" {
+		t.Errorf("csv = %q", buf.String())
+	}
+}
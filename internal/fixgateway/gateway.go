@@ -0,0 +1,261 @@
+package fixgateway
+
+import (
+	"sync"
+	"time"
+
+	"github.com/BullionBear/sequex/internal/allocation"
+	"github.com/BullionBear/sequex/internal/announce"
+	"github.com/BullionBear/sequex/internal/guardian"
+	"github.com/BullionBear/sequex/internal/model/sqx"
+	"github.com/BullionBear/sequex/internal/tenant"
+	"github.com/BullionBear/sequex/pkg/fix"
+	"github.com/BullionBear/sequex/pkg/logger"
+)
+
+// Publisher forwards intents to the OMS, under the subject prefix of the
+// tenant placing them. The tenant is nil for sessions of the deployment itself.
+type Publisher interface {
+	PublishOrder(t *tenant.Tenant, intent sqx.OrderIntent) error
+	PublishCancel(t *tenant.Tenant, intent sqx.CancelIntent) error
+}
+
+// Sender delivers FIX messages to a logged on session
+type Sender interface {
+	Send(id fix.SessionID, msg *fix.Message) error
+}
+
+// Gateway translates FIX order entry into OMS intents and routes
+// execution reports back to the session that placed the order
+type Gateway struct {
+	publisher       Publisher
+	sender          Sender
+	defaultExchange sqx.Exchange
+	halts           *announce.Halts
+	allocations     *allocation.Book
+	guardian        *guardian.Guardian
+	tenants         map[fix.SessionID]*tenant.Tenant // Tenant of each session, none for the sessions of the deployment
+	mu              sync.Mutex
+	orders          map[string]fix.SessionID // order key -> owning session
+}
+
+// NewGateway creates a FIX gateway. The sender is usually the fix.Acceptor and
+// may be set after construction with SetSender since the acceptor needs the gateway.
+func NewGateway(publisher Publisher, defaultExchange sqx.Exchange) *Gateway {
+	return &Gateway{
+		publisher:       publisher,
+		defaultExchange: defaultExchange,
+		orders:          make(map[string]fix.SessionID),
+	}
+}
+
+func (g *Gateway) SetSender(sender Sender) {
+	g.sender = sender
+}
+
+// SetHalts rejects orders on the symbols halted by exchange announcements,
+// e.g. those scheduled for delisting
+func (g *Gateway) SetHalts(halts *announce.Halts) {
+	g.halts = halts
+}
+
+// SetAllocations rejects orders taking the strategy of their session over
+// its budget. The book follows the working orders and positions of the
+// strategies from the execution reports.
+func (g *Gateway) SetAllocations(book *allocation.Book) {
+	g.allocations = book
+}
+
+// SetGuardian rejects the orders of the strategies the guardian paused and
+// cancels their working orders once they are paused. The guardian follows
+// the equity of the strategies from the execution reports.
+func (g *Gateway) SetGuardian(guardian *guardian.Guardian) {
+	g.guardian = guardian
+	guardian.OnPause(g.cancelAll)
+}
+
+// SetTenants assigns sessions to tenants. Their orders are checked against
+// the accounts and limits of the tenant and routed under its subject prefix.
+func (g *Gateway) SetTenants(tenants map[fix.SessionID]*tenant.Tenant) {
+	g.tenants = tenants
+}
+
+// orderKey identifies an order across tenants, which may reuse client
+// order ids
+func orderKey(t *tenant.Tenant, clOrdID string) string {
+	if t == nil {
+		return clOrdID
+	}
+	return t.ID + "/" + clOrdID
+}
+
+// strategyFor tags intents with the counterparty so fills can be attributed
+func strategyFor(id fix.SessionID) string {
+	return "fix." + id.TargetCompID
+}
+
+func (g *Gateway) OnLogon(id fix.SessionID) {
+	logger.Log.Info().Str("session", id.String()).Msg("FIX counterparty logged on")
+}
+
+func (g *Gateway) OnLogout(id fix.SessionID) {
+	logger.Log.Info().Str("session", id.String()).Msg("FIX counterparty logged out")
+}
+
+// FromApp handles application messages from a counterparty
+func (g *Gateway) FromApp(id fix.SessionID, msg *fix.Message) {
+	switch msg.MsgType() {
+	case fix.MsgTypeNewOrderSingle:
+		g.onNewOrderSingle(id, msg)
+	case fix.MsgTypeOrderCancelRequest:
+		g.onOrderCancelRequest(id, msg)
+	default:
+		logger.Log.Warn().Str("session", id.String()).Str("msgType", msg.MsgType()).Msg("Unsupported FIX message type")
+		reject := fix.NewMessage(fix.MsgTypeBusinessMessageReject).
+			Set(fix.TagRefMsgType, msg.MsgType()).
+			Set(fix.TagBusinessRejectReason, "3"). // Unsupported message type
+			Set(fix.TagText, "unsupported message type")
+		g.send(id, reject)
+	}
+}
+
+func (g *Gateway) onNewOrderSingle(id fix.SessionID, msg *fix.Message) {
+	t := g.tenants[id]
+	intent, err := toOrderIntent(msg, strategyFor(id), g.defaultExchange)
+	if err != nil {
+		g.send(id, rejectReport(msg, err.Error()))
+		return
+	}
+	if g.halts != nil {
+		if err := g.halts.Check(intent.Exchange, intent.Symbol, time.Now()); err != nil {
+			logger.Log.Warn().Err(err).Str("clOrdID", intent.ClientOrderId).Msg("Rejected order on halted symbol")
+			g.send(id, rejectReport(msg, err.Error()))
+			return
+		}
+	}
+	if err := t.CheckOrder(intent); err != nil {
+		logger.Log.Warn().Err(err).Str("tenant", t.ID).Str("clOrdID", intent.ClientOrderId).Msg("Rejected order outside the tenant accounts or limits")
+		g.send(id, rejectReport(msg, err.Error()))
+		return
+	}
+
+	if g.guardian != nil {
+		if err := g.guardian.Check(intent); err != nil {
+			logger.Log.Warn().Err(err).Str("strategy", intent.Strategy).Str("clOrdID", intent.ClientOrderId).Msg("Rejected order of a paused strategy")
+			g.send(id, rejectReport(msg, err.Error()))
+			return
+		}
+	}
+
+	key := orderKey(t, intent.ClientOrderId)
+	g.mu.Lock()
+	if _, exists := g.orders[key]; exists {
+		g.mu.Unlock()
+		g.send(id, rejectReport(msg, "duplicate ClOrdID"))
+		return
+	}
+	g.orders[key] = id
+	g.mu.Unlock()
+
+	if g.allocations != nil {
+		if err := g.allocations.Check(intent); err != nil {
+			logger.Log.Warn().Err(err).Str("strategy", intent.Strategy).Str("clOrdID", intent.ClientOrderId).Msg("Rejected order over the strategy budget")
+			g.mu.Lock()
+			delete(g.orders, key)
+			g.mu.Unlock()
+			g.send(id, rejectReport(msg, err.Error()))
+			return
+		}
+	}
+
+	if err := g.publisher.PublishOrder(t, intent); err != nil {
+		logger.Log.Error().Err(err).Str("clOrdID", intent.ClientOrderId).Msg("Failed to publish order intent")
+		if g.allocations != nil {
+			g.allocations.Release(intent)
+		}
+		g.mu.Lock()
+		delete(g.orders, key)
+		g.mu.Unlock()
+		g.send(id, rejectReport(msg, "order routing unavailable"))
+	}
+}
+
+func (g *Gateway) onOrderCancelRequest(id fix.SessionID, msg *fix.Message) {
+	t := g.tenants[id]
+	intent, err := toCancelIntent(msg, strategyFor(id), g.defaultExchange)
+	if err != nil {
+		g.send(id, cancelReject(msg, err.Error()))
+		return
+	}
+
+	g.mu.Lock()
+	owner, ok := g.orders[orderKey(t, intent.OrigClientOrderId)]
+	if ok && owner == id {
+		g.orders[orderKey(t, intent.ClientOrderId)] = id
+	}
+	g.mu.Unlock()
+	if !ok || owner != id {
+		g.send(id, cancelReject(msg, "unknown order"))
+		return
+	}
+
+	if err := g.publisher.PublishCancel(t, intent); err != nil {
+		logger.Log.Error().Err(err).Str("clOrdID", intent.ClientOrderId).Msg("Failed to publish cancel intent")
+		g.send(id, cancelReject(msg, "order routing unavailable"))
+	}
+}
+
+// OnExecutionReport routes an OMS execution report, received under the
+// subject prefix of tenant t, to the owning session. Reports reach only the
+// sessions of the tenant they were published for.
+func (g *Gateway) OnExecutionReport(t *tenant.Tenant, report sqx.ExecutionReport) {
+	if g.allocations != nil {
+		g.allocations.OnExecutionReport(report)
+	}
+	if g.guardian != nil {
+		g.guardian.OnExecutionReport(report)
+	}
+	key := orderKey(t, report.ClientOrderId)
+	g.mu.Lock()
+	id, ok := g.orders[key]
+	if ok && report.Status.IsFinal() {
+		delete(g.orders, key)
+	}
+	g.mu.Unlock()
+	if !ok {
+		return
+	}
+	g.send(id, toExecutionReport(&report))
+}
+
+// cancelAll cancels the working orders of a strategy paused by the
+// guardian. The cancels are routed like those of its session, which is
+// sent their execution reports.
+func (g *Gateway) cancelAll(e guardian.Event, cancels []sqx.CancelIntent) {
+	var t *tenant.Tenant
+	for id, tt := range g.tenants {
+		if strategyFor(id) == e.Strategy {
+			t = tt
+			break
+		}
+	}
+	for _, intent := range cancels {
+		g.mu.Lock()
+		if owner, ok := g.orders[orderKey(t, intent.OrigClientOrderId)]; ok {
+			g.orders[orderKey(t, intent.ClientOrderId)] = owner
+		}
+		g.mu.Unlock()
+		if err := g.publisher.PublishCancel(t, intent); err != nil {
+			logger.Log.Error().Err(err).Str("strategy", e.Strategy).Str("origClOrdID", intent.OrigClientOrderId).Msg("Failed to cancel order of paused strategy")
+		}
+	}
+}
+
+func (g *Gateway) send(id fix.SessionID, msg *fix.Message) {
+	if g.sender == nil {
+		return
+	}
+	if err := g.sender.Send(id, msg); err != nil {
+		logger.Log.Error().Err(err).Str("session", id.String()).Str("msgType", msg.MsgType()).Msg("Failed to send FIX message")
+	}
+}
@@ -0,0 +1,329 @@
+package fixgateway
+
+import (
+	"errors"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/BullionBear/sequex/internal/allocation"
+	"github.com/BullionBear/sequex/internal/announce"
+	"github.com/BullionBear/sequex/internal/guardian"
+	"github.com/BullionBear/sequex/internal/lifecycle"
+	"github.com/BullionBear/sequex/internal/model/sqx"
+	"github.com/BullionBear/sequex/internal/tenant"
+	"github.com/BullionBear/sequex/pkg/fix"
+)
+
+// fakePublisher records the intents forwarded to the OMS
+type fakePublisher struct {
+	err     error // Returned by every publish when set
+	orders  []sqx.OrderIntent
+	cancels []sqx.CancelIntent
+	tenants []*tenant.Tenant // Of each order and cancel, in order
+}
+
+func (p *fakePublisher) PublishOrder(t *tenant.Tenant, intent sqx.OrderIntent) error {
+	if p.err != nil {
+		return p.err
+	}
+	p.orders = append(p.orders, intent)
+	p.tenants = append(p.tenants, t)
+	return nil
+}
+
+func (p *fakePublisher) PublishCancel(t *tenant.Tenant, intent sqx.CancelIntent) error {
+	if p.err != nil {
+		return p.err
+	}
+	p.cancels = append(p.cancels, intent)
+	p.tenants = append(p.tenants, t)
+	return nil
+}
+
+// sent is a message delivered to a session
+type sent struct {
+	id  fix.SessionID
+	msg *fix.Message
+}
+
+// fakeAcceptor records the messages the gateway sends to sessions
+type fakeAcceptor struct {
+	mu   sync.Mutex
+	sent []sent
+}
+
+func (a *fakeAcceptor) Send(id fix.SessionID, msg *fix.Message) error {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.sent = append(a.sent, sent{id, msg})
+	return nil
+}
+
+// last returns the last message sent, failing the test when none was
+func (a *fakeAcceptor) last(t *testing.T) sent {
+	t.Helper()
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if len(a.sent) == 0 {
+		t.Fatal("no message sent")
+	}
+	return a.sent[len(a.sent)-1]
+}
+
+var (
+	alice = fix.SessionID{SenderCompID: "SEQUEX", TargetCompID: "ALICE"}
+	bob   = fix.SessionID{SenderCompID: "SEQUEX", TargetCompID: "BOB"}
+)
+
+func newTestGateway() (*Gateway, *fakePublisher, *fakeAcceptor) {
+	publisher := &fakePublisher{}
+	acceptor := &fakeAcceptor{}
+	g := NewGateway(publisher, sqx.ExchangeBinance)
+	g.SetSender(acceptor)
+	return g, publisher, acceptor
+}
+
+func newOrder(clOrdID string) *fix.Message {
+	return fix.NewMessage(fix.MsgTypeNewOrderSingle).
+		Set(fix.TagClOrdID, clOrdID).
+		Set(fix.TagSymbol, "BTC-USDT").
+		Set(fix.TagSide, "1").
+		Set(fix.TagOrdType, "2").
+		SetFloat(fix.TagOrderQty, 0.5).
+		SetFloat(fix.TagPrice, 50000)
+}
+
+func cancelRequest(clOrdID, origClOrdID string) *fix.Message {
+	return fix.NewMessage(fix.MsgTypeOrderCancelRequest).
+		Set(fix.TagClOrdID, clOrdID).
+		Set(fix.TagOrigClOrdID, origClOrdID).
+		Set(fix.TagSymbol, "BTC-USDT").
+		Set(fix.TagSide, "1")
+}
+
+// expectReject checks msg rejects an order with a reason containing text
+func expectReject(t *testing.T, msg *fix.Message, text string) {
+	t.Helper()
+	if msg.MsgType() != fix.MsgTypeExecutionReport {
+		t.Fatalf("msgType = %s, want an execution report", msg.MsgType())
+	}
+	if status, _ := msg.Get(fix.TagOrdStatus); status != "8" {
+		t.Errorf("OrdStatus = %s, want rejected", status)
+	}
+	if reason, _ := msg.Get(fix.TagText); !strings.Contains(reason, text) {
+		t.Errorf("Text = %q, want it to contain %q", reason, text)
+	}
+}
+
+func TestNewOrderSingle(t *testing.T) {
+	g, publisher, acceptor := newTestGateway()
+	g.FromApp(alice, newOrder("1"))
+	if len(publisher.orders) != 1 {
+		t.Fatalf("orders published = %d, want 1", len(publisher.orders))
+	}
+	intent := publisher.orders[0]
+	if intent.ClientOrderId != "1" || intent.Strategy != "fix.ALICE" || intent.Exchange != sqx.ExchangeBinance ||
+		intent.Side != sqx.SideBuy || intent.Type != sqx.OrderTypeLimit || intent.Quantity != 0.5 || intent.Price != 50000 {
+		t.Errorf("intent = %+v", intent)
+	}
+	if len(acceptor.sent) != 0 {
+		t.Errorf("sent %d messages to an accepted order, want none before the OMS reports", len(acceptor.sent))
+	}
+}
+
+func TestNewOrderSingleRejects(t *testing.T) {
+	halts := announce.NewHalts(0)
+	halts.ApplyChange(lifecycle.Change{Exchange: "BINANCE", Symbol: "ETH-USDT", From: "TRADING", To: "BREAK"})
+	acme := &tenant.Tenant{ID: "acme", Accounts: []string{"BINANCE"}, Limits: tenant.Limits{MaxOrderQuantity: 1}}
+	carol := fix.SessionID{SenderCompID: "SEQUEX", TargetCompID: "CAROL"}
+	dave := fix.SessionID{SenderCompID: "SEQUEX", TargetCompID: "DAVE"}
+
+	tests := []struct {
+		name    string
+		session fix.SessionID
+		msg     *fix.Message
+		publish error
+		reason  string
+	}{
+		{"missing ClOrdID", alice, newOrder(""), nil, "missing ClOrdID"},
+		{"missing Symbol", alice, fix.NewMessage(fix.MsgTypeNewOrderSingle).Set(fix.TagClOrdID, "1"), nil, "missing Symbol"},
+		{"invalid Symbol", alice, newOrder("1").Set(fix.TagSymbol, "BTCUSDT"), nil, "BTCUSDT"},
+		{"unknown SecurityExchange", alice, newOrder("1").Set(fix.TagSecurityExchange, "NASDAQ"), nil, "unknown SecurityExchange"},
+		{"unsupported SecurityType", alice, newOrder("1").Set(fix.TagSecurityType, "CS"), nil, "unsupported SecurityType"},
+		{"unsupported Side", alice, newOrder("1").Set(fix.TagSide, "5"), nil, "unsupported Side"},
+		{"unsupported OrdType", alice, newOrder("1").Set(fix.TagOrdType, "P"), nil, "unsupported OrdType"},
+		{"unsupported TimeInForce", alice, newOrder("1").Set(fix.TagTimeInForce, "6"), nil, "unsupported TimeInForce"},
+		{"invalid OrderQty", alice, newOrder("1").SetFloat(fix.TagOrderQty, 0), nil, "invalid OrderQty"},
+		{"invalid Price", alice, newOrder("1").SetFloat(fix.TagPrice, -1), nil, "invalid Price"},
+		{"invalid StopPx", alice, newOrder("1").Set(fix.TagOrdType, "3"), nil, "invalid StopPx"},
+		{"halted symbol", alice, newOrder("1").Set(fix.TagSymbol, "ETH-USDT"), nil, "ETH-USDT is BREAK"},
+		{"tenant account", bob, newOrder("1").Set(fix.TagSecurityExchange, "BYBIT"), nil, "has no BYBIT account"},
+		{"tenant limit", bob, newOrder("1").SetFloat(fix.TagOrderQty, 2), nil, "above the limit"},
+		{"paused strategy", carol, newOrder("1"), nil, guardian.ErrPaused.Error()},
+		{"over budget", dave, newOrder("1"), nil, allocation.ErrOverBudget.Error()},
+		{"routing unavailable", alice, newOrder("1"), errors.New("nats: connection closed"), "order routing unavailable"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			g, publisher, acceptor := newTestGateway()
+			publisher.err = tt.publish
+			g.SetHalts(halts)
+			g.SetTenants(map[fix.SessionID]*tenant.Tenant{bob: acme})
+			guard, err := guardian.Open(guardian.Options{})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if _, err := guard.Pause("fix.CAROL", "ops", "drawdown review"); err != nil {
+				t.Fatal(err)
+			}
+			g.SetGuardian(guard)
+			book, err := allocation.Open([]allocation.Allocation{
+				{Strategy: "fix.ALICE", Portfolio: "main", Budget: 1e6},
+				{Strategy: "fix.BOB", Portfolio: "main", Budget: 1e6},
+				{Strategy: "fix.DAVE", Portfolio: "main", Budget: 1000},
+			}, allocation.Options{})
+			if err != nil {
+				t.Fatal(err)
+			}
+			g.SetAllocations(book)
+
+			g.FromApp(tt.session, tt.msg)
+			if len(publisher.orders) != 0 {
+				t.Errorf("published %+v, want no order", publisher.orders)
+			}
+			reply := acceptor.last(t)
+			if reply.id != tt.session {
+				t.Errorf("reject sent to %s, want %s", reply.id, tt.session)
+			}
+			expectReject(t, reply.msg, tt.reason)
+
+			// Rejected orders leave their ClOrdID free for a valid order
+			publisher.err = nil
+			g.FromApp(alice, newOrder("1"))
+			if len(publisher.orders) != 1 {
+				t.Errorf("orders published after the reject = %d, want 1", len(publisher.orders))
+			}
+		})
+	}
+}
+
+func TestDuplicateClOrdID(t *testing.T) {
+	g, publisher, acceptor := newTestGateway()
+	g.FromApp(alice, newOrder("1"))
+	g.FromApp(alice, newOrder("1"))
+	if len(publisher.orders) != 1 {
+		t.Fatalf("orders published = %d, want 1", len(publisher.orders))
+	}
+	expectReject(t, acceptor.last(t).msg, "duplicate ClOrdID")
+
+	// Client order ids are scoped to the tenant, not to the deployment
+	g.SetTenants(map[fix.SessionID]*tenant.Tenant{bob: {ID: "acme", Accounts: []string{"BINANCE"}}})
+	g.FromApp(bob, newOrder("1"))
+	if len(publisher.orders) != 2 {
+		t.Errorf("orders published = %d, want the order of the tenant as well", len(publisher.orders))
+	}
+
+	// A final report frees the id
+	g.OnExecutionReport(nil, sqx.ExecutionReport{ClientOrderId: "1", Status: sqx.OrderStatusFilled, Timestamp: time.Now().UnixMilli()})
+	g.FromApp(alice, newOrder("1"))
+	if len(publisher.orders) != 3 {
+		t.Errorf("orders published = %d, want the id reused once the order is done", len(publisher.orders))
+	}
+}
+
+func TestCancelOfAnotherSession(t *testing.T) {
+	g, publisher, acceptor := newTestGateway()
+	g.FromApp(alice, newOrder("1"))
+
+	for _, tt := range []struct {
+		name        string
+		session     fix.SessionID
+		origClOrdID string
+	}{
+		{"order of another session", bob, "1"},
+		{"unknown order", alice, "2"},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			g.FromApp(tt.session, cancelRequest("c-"+tt.name, tt.origClOrdID))
+			if len(publisher.cancels) != 0 {
+				t.Fatalf("published %+v, want no cancel", publisher.cancels)
+			}
+			reply := acceptor.last(t)
+			if reply.id != tt.session || reply.msg.MsgType() != fix.MsgTypeOrderCancelReject {
+				t.Fatalf("sent %s to %s, want a cancel reject to %s", reply.msg.MsgType(), reply.id, tt.session)
+			}
+			if reason, _ := reply.msg.Get(fix.TagText); reason != "unknown order" {
+				t.Errorf("Text = %q", reason)
+			}
+		})
+	}
+
+	g.FromApp(alice, cancelRequest("c1", "1"))
+	if len(publisher.cancels) != 1 || publisher.cancels[0].OrigClientOrderId != "1" || publisher.cancels[0].Strategy != "fix.ALICE" {
+		t.Errorf("cancels = %+v, want the cancel of the owner", publisher.cancels)
+	}
+}
+
+func TestExecutionReportRouting(t *testing.T) {
+	g, publisher, acceptor := newTestGateway()
+	acme := &tenant.Tenant{ID: "acme", Accounts: []string{"BINANCE"}}
+	beta := &tenant.Tenant{ID: "beta", Accounts: []string{"BINANCE"}}
+	g.SetTenants(map[fix.SessionID]*tenant.Tenant{alice: acme, bob: beta})
+
+	// Both tenants use the same client order id
+	g.FromApp(alice, newOrder("1"))
+	g.FromApp(bob, newOrder("1"))
+	if len(publisher.orders) != 2 || publisher.tenants[0] != acme || publisher.tenants[1] != beta {
+		t.Fatalf("orders published under %v, want acme then beta", publisher.tenants)
+	}
+
+	report := sqx.ExecutionReport{ClientOrderId: "1", Exchange: sqx.ExchangeBinance, Symbol: sqx.NewSymbol("BTC", "USDT"),
+		Side: sqx.SideBuy, Status: sqx.OrderStatusNew, Quantity: 0.5, Price: 50000, Timestamp: time.Now().UnixMilli()}
+	for _, tt := range []struct {
+		tenant *tenant.Tenant
+		want   fix.SessionID
+	}{
+		{acme, alice},
+		{beta, bob},
+	} {
+		before := len(acceptor.sent)
+		g.OnExecutionReport(tt.tenant, report)
+		if len(acceptor.sent) != before+1 {
+			t.Fatalf("tenant %s: sent %d reports, want 1", tt.tenant.ID, len(acceptor.sent)-before)
+		}
+		if got := acceptor.last(t); got.id != tt.want || got.msg.MsgType() != fix.MsgTypeExecutionReport {
+			t.Errorf("tenant %s: sent %s to %s, want an execution report to %s", tt.tenant.ID, got.msg.MsgType(), got.id, tt.want)
+		}
+	}
+
+	// Reports of other tenants or of orders no session placed reach no session
+	before := len(acceptor.sent)
+	g.OnExecutionReport(nil, report)
+	g.OnExecutionReport(&tenant.Tenant{ID: "gamma"}, report)
+	g.OnExecutionReport(acme, sqx.ExecutionReport{ClientOrderId: "2", Status: sqx.OrderStatusNew})
+	if len(acceptor.sent) != before {
+		t.Errorf("sent %d reports of orders the sessions do not own", len(acceptor.sent)-before)
+	}
+
+	// The final report is the last one routed
+	report.Status = sqx.OrderStatusCanceled
+	g.OnExecutionReport(acme, report)
+	g.OnExecutionReport(acme, report)
+	if len(acceptor.sent) != before+1 {
+		t.Errorf("sent %d reports after the order was done, want only the final one", len(acceptor.sent)-before)
+	}
+}
+
+func TestUnsupportedMessage(t *testing.T) {
+	g, _, acceptor := newTestGateway()
+	g.FromApp(alice, fix.NewMessage("AE"))
+	reply := acceptor.last(t)
+	if reply.msg.MsgType() != fix.MsgTypeBusinessMessageReject {
+		t.Fatalf("msgType = %s, want a business message reject", reply.msg.MsgType())
+	}
+	if ref, _ := reply.msg.Get(fix.TagRefMsgType); ref != "AE" {
+		t.Errorf("RefMsgType = %s", ref)
+	}
+}
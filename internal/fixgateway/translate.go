@@ -0,0 +1,252 @@
+package fixgateway
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/BullionBear/sequex/internal/model/sqx"
+	"github.com/BullionBear/sequex/pkg/fix"
+)
+
+// FIX enumerations used by the gateway
+var (
+	fixSides = map[string]sqx.Side{"1": sqx.SideBuy, "2": sqx.SideSell}
+	fixTypes = map[string]sqx.OrderType{
+		"1": sqx.OrderTypeMarket,
+		"2": sqx.OrderTypeLimit,
+		"3": sqx.OrderTypeStopMarket,
+	}
+	fixTimeInForces = map[string]sqx.TimeInForce{
+		"1": sqx.TimeInForceGTC,
+		"3": sqx.TimeInForceIOC,
+		"4": sqx.TimeInForceFOK,
+	}
+	fixSecurityTypes = map[string]sqx.InstrumentType{
+		"SPOT": sqx.InstrumentTypeSpot,
+		"PERP": sqx.InstrumentTypePerp,
+		"FUT":  sqx.InstrumentTypeFutures,
+		"OPT":  sqx.InstrumentTypeOption,
+	}
+	fixOrdStatus = map[sqx.OrderStatus]string{
+		sqx.OrderStatusNew:             "0",
+		sqx.OrderStatusPartiallyFilled: "1",
+		sqx.OrderStatusFilled:          "2",
+		sqx.OrderStatusCanceled:        "4",
+		sqx.OrderStatusRejected:        "8",
+		sqx.OrderStatusExpired:         "C",
+	}
+)
+
+// execType maps an execution report onto the FIX 4.4 ExecType
+func execType(report *sqx.ExecutionReport) string {
+	switch {
+	case report.IsFill():
+		return "F" // Trade
+	case report.Status == sqx.OrderStatusCanceled:
+		return "4"
+	case report.Status == sqx.OrderStatusRejected:
+		return "8"
+	case report.Status == sqx.OrderStatusExpired:
+		return "C"
+	default:
+		return "0"
+	}
+}
+
+func fromFIXSide(side sqx.Side) string {
+	if side == sqx.SideSell {
+		return "2"
+	}
+	return "1"
+}
+
+// parseInstrument reads Symbol (BTC-USDT), SecurityExchange and SecurityType
+func parseInstrument(msg *fix.Message, defaultExchange sqx.Exchange) (sqx.Exchange, sqx.InstrumentType, sqx.Symbol, error) {
+	symbolStr, ok := msg.Get(fix.TagSymbol)
+	if !ok {
+		return 0, 0, sqx.Symbol{}, fmt.Errorf("missing Symbol")
+	}
+	symbol, err := sqx.NewSymbolFromStr(symbolStr)
+	if err != nil {
+		return 0, 0, sqx.Symbol{}, err
+	}
+
+	exchange := defaultExchange
+	if v, ok := msg.Get(fix.TagSecurityExchange); ok {
+		exchange = sqx.NewExchange(v)
+	}
+	if exchange == sqx.ExchangeUnknown {
+		return 0, 0, sqx.Symbol{}, fmt.Errorf("unknown SecurityExchange")
+	}
+
+	instrument := sqx.InstrumentTypeSpot
+	if v, ok := msg.Get(fix.TagSecurityType); ok {
+		if instrument, ok = fixSecurityTypes[strings.ToUpper(v)]; !ok {
+			return 0, 0, sqx.Symbol{}, fmt.Errorf("unsupported SecurityType: %s", v)
+		}
+	}
+	return exchange, instrument, symbol, nil
+}
+
+// toOrderIntent translates a NewOrderSingle into an OMS order intent
+func toOrderIntent(msg *fix.Message, strategy string, defaultExchange sqx.Exchange) (sqx.OrderIntent, error) {
+	clOrdID, ok := msg.Get(fix.TagClOrdID)
+	if !ok || clOrdID == "" {
+		return sqx.OrderIntent{}, fmt.Errorf("missing ClOrdID")
+	}
+	exchange, instrument, symbol, err := parseInstrument(msg, defaultExchange)
+	if err != nil {
+		return sqx.OrderIntent{}, err
+	}
+
+	sideStr, _ := msg.Get(fix.TagSide)
+	side, ok := fixSides[sideStr]
+	if !ok {
+		return sqx.OrderIntent{}, fmt.Errorf("unsupported Side: %s", sideStr)
+	}
+	typeStr, _ := msg.Get(fix.TagOrdType)
+	orderType, ok := fixTypes[typeStr]
+	if !ok {
+		return sqx.OrderIntent{}, fmt.Errorf("unsupported OrdType: %s", typeStr)
+	}
+	timeInForce := sqx.TimeInForceGTC
+	if v, ok := msg.Get(fix.TagTimeInForce); ok {
+		if timeInForce, ok = fixTimeInForces[v]; !ok {
+			return sqx.OrderIntent{}, fmt.Errorf("unsupported TimeInForce: %s", v)
+		}
+	}
+
+	quantity, err := msg.GetFloat(fix.TagOrderQty)
+	if err != nil || quantity <= 0 {
+		return sqx.OrderIntent{}, fmt.Errorf("invalid OrderQty")
+	}
+
+	intent := sqx.OrderIntent{
+		ClientOrderId:  clOrdID,
+		Strategy:       strategy,
+		Exchange:       exchange,
+		InstrumentType: instrument,
+		Symbol:         symbol,
+		Side:           side,
+		Type:           orderType,
+		TimeInForce:    timeInForce,
+		Quantity:       quantity,
+		Timestamp:      time.Now().UnixMilli(),
+	}
+	if orderType == sqx.OrderTypeLimit {
+		if intent.Price, err = msg.GetFloat(fix.TagPrice); err != nil || intent.Price <= 0 {
+			return sqx.OrderIntent{}, fmt.Errorf("invalid Price for limit order")
+		}
+	}
+	if orderType == sqx.OrderTypeStopMarket {
+		if intent.StopPrice, err = msg.GetFloat(fix.TagStopPx); err != nil || intent.StopPrice <= 0 {
+			return sqx.OrderIntent{}, fmt.Errorf("invalid StopPx for stop order")
+		}
+	}
+	return intent, nil
+}
+
+// toCancelIntent translates an OrderCancelRequest into an OMS cancel intent
+func toCancelIntent(msg *fix.Message, strategy string, defaultExchange sqx.Exchange) (sqx.CancelIntent, error) {
+	clOrdID, ok := msg.Get(fix.TagClOrdID)
+	if !ok || clOrdID == "" {
+		return sqx.CancelIntent{}, fmt.Errorf("missing ClOrdID")
+	}
+	origClOrdID, ok := msg.Get(fix.TagOrigClOrdID)
+	if !ok || origClOrdID == "" {
+		return sqx.CancelIntent{}, fmt.Errorf("missing OrigClOrdID")
+	}
+	exchange, instrument, symbol, err := parseInstrument(msg, defaultExchange)
+	if err != nil {
+		return sqx.CancelIntent{}, err
+	}
+	return sqx.CancelIntent{
+		ClientOrderId:     clOrdID,
+		OrigClientOrderId: origClOrdID,
+		Strategy:          strategy,
+		Exchange:          exchange,
+		InstrumentType:    instrument,
+		Symbol:            symbol,
+		Timestamp:         time.Now().UnixMilli(),
+	}, nil
+}
+
+// toExecutionReport translates an OMS execution report into a FIX ExecutionReport
+func toExecutionReport(report *sqx.ExecutionReport) *fix.Message {
+	ordStatus, ok := fixOrdStatus[report.Status]
+	if !ok {
+		ordStatus = "0"
+	}
+	execID := report.ExecId
+	if execID == "" {
+		execID = fmt.Sprintf("%s-%d", report.ClientOrderId, report.Timestamp)
+	}
+	orderID := report.OrderId
+	if orderID == "" {
+		orderID = "NONE"
+	}
+
+	msg := fix.NewMessage(fix.MsgTypeExecutionReport).
+		Set(fix.TagOrderID, orderID).
+		Set(fix.TagClOrdID, report.ClientOrderId).
+		Set(fix.TagExecID, execID).
+		Set(fix.TagExecType, execType(report)).
+		Set(fix.TagOrdStatus, ordStatus).
+		Set(fix.TagSymbol, report.Symbol.String()).
+		Set(fix.TagSecurityExchange, report.Exchange.String()).
+		Set(fix.TagSide, fromFIXSide(report.Side)).
+		SetFloat(fix.TagOrderQty, report.Quantity).
+		SetFloat(fix.TagLastQty, report.LastQuantity).
+		SetFloat(fix.TagLastPx, report.LastPrice).
+		SetFloat(fix.TagLeavesQty, report.LeavesQuantity()).
+		SetFloat(fix.TagCumQty, report.CumQuantity).
+		SetFloat(fix.TagAvgPx, report.AvgPrice).
+		SetTime(fix.TagTransactTime, time.UnixMilli(report.Timestamp))
+	if report.Price > 0 {
+		msg.SetFloat(fix.TagPrice, report.Price)
+	}
+	if report.Commission != 0 {
+		msg.SetFloat(fix.TagCommission, report.Commission)
+		msg.Set(fix.TagCommCurrency, report.CommissionAsset)
+	}
+	if report.Text != "" {
+		msg.Set(fix.TagText, report.Text)
+	}
+	return msg
+}
+
+// rejectReport builds an execution report rejecting a NewOrderSingle
+func rejectReport(msg *fix.Message, reason string) *fix.Message {
+	clOrdID, _ := msg.Get(fix.TagClOrdID)
+	symbol, _ := msg.Get(fix.TagSymbol)
+	side, _ := msg.Get(fix.TagSide)
+	qty, _ := msg.Get(fix.TagOrderQty)
+	return fix.NewMessage(fix.MsgTypeExecutionReport).
+		Set(fix.TagOrderID, "NONE").
+		Set(fix.TagClOrdID, clOrdID).
+		Set(fix.TagExecID, "REJ-"+clOrdID+"-"+strconv.FormatInt(time.Now().UnixNano(), 10)).
+		Set(fix.TagExecType, "8").
+		Set(fix.TagOrdStatus, "8").
+		Set(fix.TagSymbol, symbol).
+		Set(fix.TagSide, side).
+		Set(fix.TagOrderQty, qty).
+		SetInt(fix.TagLeavesQty, 0).
+		SetInt(fix.TagCumQty, 0).
+		SetInt(fix.TagAvgPx, 0).
+		Set(fix.TagText, reason)
+}
+
+// cancelReject builds an OrderCancelReject for an invalid OrderCancelRequest
+func cancelReject(msg *fix.Message, reason string) *fix.Message {
+	clOrdID, _ := msg.Get(fix.TagClOrdID)
+	origClOrdID, _ := msg.Get(fix.TagOrigClOrdID)
+	return fix.NewMessage(fix.MsgTypeOrderCancelReject).
+		Set(fix.TagOrderID, "NONE").
+		Set(fix.TagClOrdID, clOrdID).
+		Set(fix.TagOrigClOrdID, origClOrdID).
+		Set(fix.TagOrdStatus, "8").
+		Set(fix.TagCxlRejResponseTo, "1"). // Order cancel request
+		Set(fix.TagText, reason)
+}
@@ -0,0 +1,278 @@
+// Package fx converts amounts between currencies and crypto assets using
+// the last traded prices of the market data feeds, complemented by static
+// rates for pairs no feed covers, such as fiat deposits.
+package fx
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/BullionBear/sequex/internal/model/sqx"
+)
+
+// ErrNoRate is returned when no fresh rate connects two currencies
+var ErrNoRate = errors.New("no rate")
+
+// ErrNoVol is returned when no fresh implied volatility of an option is known
+var ErrNoVol = errors.New("no implied volatility")
+
+// DefaultPivots are the currencies conversions are triangulated through
+// when no direct rate exists
+var DefaultPivots = []string{"USDT", "USD", "USDC", "BTC"}
+
+// Quote is the last known price of one unit of Base in Quote
+type Quote struct {
+	Base      string    `json:"base"`
+	Quote     string    `json:"quote"`
+	Price     float64   `json:"price"`
+	UpdatedAt time.Time `json:"updated_at"`
+	Static    bool      `json:"static"` // Configured rather than observed, never stale
+}
+
+// historyResolution is the interval of the observed rates kept, the first
+// one of each interval standing for it. Rates of the past are as fresh as
+// Options.MaxAge plus the resolution.
+const historyResolution = time.Hour
+
+// Options configures a rate service
+type Options struct {
+	MaxAge  time.Duration // Observed rates older than this are ignored, 0 keeps them forever
+	Pivots  []string      // Intermediate currencies, DefaultPivots when empty
+	History time.Duration // How long observed rates are kept to convert at past times, none when 0
+}
+
+// Rates is a conversion service safe for concurrent use
+type Rates struct {
+	opts    Options
+	mu      sync.RWMutex
+	quotes  map[string]Quote   // Keyed by BASE-QUOTE
+	history map[string][]Quote // Observed quotes in time order, the first of each historyResolution, keyed by BASE-QUOTE
+	vols    map[string]vol     // Keyed by option contract name
+	now     func() time.Time
+}
+
+// NewRates creates an empty rate service
+func NewRates(opts Options) *Rates {
+	pivots := opts.Pivots
+	if len(pivots) == 0 {
+		pivots = DefaultPivots
+	}
+	opts.Pivots = make([]string, len(pivots))
+	for i, pivot := range pivots {
+		opts.Pivots[i] = strings.ToUpper(pivot)
+	}
+	return &Rates{opts: opts, quotes: make(map[string]Quote), history: make(map[string][]Quote), vols: make(map[string]vol), now: time.Now}
+}
+
+// vol is the last known implied volatility of an option contract
+type vol struct {
+	value float64
+	at    time.Time
+}
+
+func pairKey(base, quote string) string {
+	return base + "-" + quote
+}
+
+// Update records the price of one unit of base in quote
+func (r *Rates) Update(base, quote string, price float64, at time.Time) {
+	if price <= 0 {
+		return
+	}
+	base, quote = strings.ToUpper(base), strings.ToUpper(quote)
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	key := pairKey(base, quote)
+	if current, ok := r.quotes[key]; ok && (current.Static || current.UpdatedAt.After(at)) {
+		return
+	}
+	q := Quote{Base: base, Quote: quote, Price: price, UpdatedAt: at}
+	r.quotes[key] = q
+	if r.opts.History > 0 {
+		r.record(key, q)
+	}
+}
+
+// record keeps an observed quote in the history of its pair unless one of
+// its interval is, dropping those older than Options.History
+func (r *Rates) record(key string, q Quote) {
+	history := r.history[key]
+	if n := len(history); n > 0 && q.UpdatedAt.Truncate(historyResolution).Equal(history[n-1].UpdatedAt.Truncate(historyResolution)) {
+		return
+	}
+	history = append(history, q)
+	cutoff := r.now().Add(-r.opts.History)
+	i := sort.Search(len(history), func(i int) bool { return !history[i].UpdatedAt.Before(cutoff) })
+	r.history[key] = history[i:]
+}
+
+// SetStatic sets a rate that is never replaced by observed prices
+func (r *Rates) SetStatic(base, quote string, price float64) error {
+	if price <= 0 {
+		return fmt.Errorf("rate of %s-%s must be positive", base, quote)
+	}
+	base, quote = strings.ToUpper(base), strings.ToUpper(quote)
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.quotes[pairKey(base, quote)] = Quote{Base: base, Quote: quote, Price: price, UpdatedAt: r.now(), Static: true}
+	return nil
+}
+
+// OnTrade records the price of a trade. Trades of every instrument type
+// update the same pair, so the freshest one wins.
+func (r *Rates) OnTrade(trade sqx.Trade) {
+	r.Update(trade.Symbol.Base, trade.Symbol.Quote, trade.Price, time.UnixMilli(trade.Timestamp))
+}
+
+// OnOptionMark records the implied volatility of an option mark. Marks
+// without one, like those of the Binance mark price stream, are ignored.
+func (r *Rates) OnOptionMark(mark sqx.OptionMark) {
+	if mark.MarkIV <= 0 {
+		return
+	}
+	at := time.UnixMilli(mark.Timestamp)
+	key := mark.Contract.String()
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if current, ok := r.vols[key]; ok && current.at.After(at) {
+		return
+	}
+	r.vols[key] = vol{value: mark.MarkIV, at: at}
+}
+
+// Vol returns the implied volatility of an option contract by name, e.g.
+// BTC-USDT-250328-90000-C, as fresh as the rates
+func (r *Rates) Vol(contract string) (float64, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	v, ok := r.vols[contract]
+	if !ok || r.opts.MaxAge > 0 && r.now().Sub(v.at) > r.opts.MaxAge {
+		return 0, fmt.Errorf("%w of %s", ErrNoVol, contract)
+	}
+	return v.value, nil
+}
+
+// direct returns the rate of from in to from a quote of either direction
+func (r *Rates) direct(from, to string, now time.Time) (float64, bool) {
+	if q, ok := r.quotes[pairKey(from, to)]; ok && r.fresh(q, now) {
+		return q.Price, true
+	}
+	if q, ok := r.quotes[pairKey(to, from)]; ok && r.fresh(q, now) {
+		return 1 / q.Price, true
+	}
+	return 0, false
+}
+
+// directAt returns the rate of from in to as of at from a quote of either
+// direction, static or the last observed at or before at
+func (r *Rates) directAt(from, to string, at time.Time) (float64, bool) {
+	if q, ok := r.quoteAt(pairKey(from, to), at); ok {
+		return q.Price, true
+	}
+	if q, ok := r.quoteAt(pairKey(to, from), at); ok {
+		return 1 / q.Price, true
+	}
+	return 0, false
+}
+
+func (r *Rates) quoteAt(key string, at time.Time) (Quote, bool) {
+	if q, ok := r.quotes[key]; ok && q.Static {
+		return q, true
+	}
+	history := r.history[key]
+	i := sort.Search(len(history), func(i int) bool { return history[i].UpdatedAt.After(at) })
+	if i == 0 {
+		return Quote{}, false
+	}
+	q := history[i-1]
+	return q, r.opts.MaxAge <= 0 || at.Sub(q.UpdatedAt) <= r.opts.MaxAge+historyResolution
+}
+
+func (r *Rates) fresh(q Quote, now time.Time) bool {
+	return q.Static || r.opts.MaxAge <= 0 || now.Sub(q.UpdatedAt) <= r.opts.MaxAge
+}
+
+// Rate returns the price of one unit of from in to, triangulating through
+// at most two pivot currencies
+func (r *Rates) Rate(from, to string) (float64, error) {
+	now := r.now()
+	return r.triangulate(from, to, func(from, to string) (float64, bool) {
+		return r.direct(from, to, now)
+	})
+}
+
+// RateAt returns the price of one unit of from in to as of at, from the
+// rates observed within Options.History and the static ones
+func (r *Rates) RateAt(from, to string, at time.Time) (float64, error) {
+	return r.triangulate(from, to, func(from, to string) (float64, bool) {
+		return r.directAt(from, to, at)
+	})
+}
+
+// triangulate converts from into to with the direct rates of direct,
+// through at most two pivot currencies
+func (r *Rates) triangulate(from, to string, direct func(from, to string) (float64, bool)) (float64, error) {
+	from, to = strings.ToUpper(from), strings.ToUpper(to)
+	if from == to {
+		return 1, nil
+	}
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	if rate, ok := direct(from, to); ok {
+		return rate, nil
+	}
+	for _, pivot := range r.opts.Pivots {
+		if pivot == from || pivot == to {
+			continue
+		}
+		a, ok := direct(from, pivot)
+		if !ok {
+			continue
+		}
+		if b, ok := direct(pivot, to); ok {
+			return a * b, nil
+		}
+		// e.g. EUR -> USD -> USDT -> ETH
+		for _, second := range r.opts.Pivots {
+			if second == pivot || second == from || second == to {
+				continue
+			}
+			b, ok := direct(pivot, second)
+			if !ok {
+				continue
+			}
+			if c, ok := direct(second, to); ok {
+				return a * b * c, nil
+			}
+		}
+	}
+	return 0, fmt.Errorf("%w: %s to %s", ErrNoRate, from, to)
+}
+
+// Convert converts an amount of from into to
+func (r *Rates) Convert(amount float64, from, to string) (float64, error) {
+	rate, err := r.Rate(from, to)
+	if err != nil {
+		return 0, err
+	}
+	return amount * rate, nil
+}
+
+// Quotes returns every known quote ordered by pair
+func (r *Rates) Quotes() []Quote {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	list := make([]Quote, 0, len(r.quotes))
+	for _, q := range r.quotes {
+		list = append(list, q)
+	}
+	sort.Slice(list, func(i, j int) bool {
+		return pairKey(list[i].Base, list[i].Quote) < pairKey(list[j].Base, list[j].Quote)
+	})
+	return list
+}
@@ -0,0 +1,148 @@
+package fx
+
+import (
+	"errors"
+	"math"
+	"testing"
+	"time"
+
+	"github.com/BullionBear/sequex/internal/model/sqx"
+)
+
+func near(a, b float64) bool {
+	return math.Abs(a-b) < 1e-9*math.Max(1, math.Abs(b))
+}
+
+func TestRateTriangulation(t *testing.T) {
+	r := NewRates(Options{})
+	now := time.Now()
+	r.OnTrade(sqx.Trade{Symbol: sqx.NewSymbol("BTC", "USDT"), Price: 60000, Timestamp: now.UnixMilli()})
+	r.Update("ETH", "BTC", 0.05, now)
+	r.Update("USDT", "USD", 1, now)
+	if err := r.SetStatic("EUR", "USD", 1.25); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		from, to string
+		want     float64
+	}{
+		{"BTC", "BTC", 1},
+		{"btc", "usdt", 60000},
+		{"USDT", "BTC", 1.0 / 60000},
+		{"ETH", "USDT", 3000},        // Through BTC
+		{"EUR", "USDT", 1.25},        // Through USD
+		{"EUR", "BTC", 1.25 / 60000}, // Through USD and USDT
+	}
+	for _, tt := range tests {
+		got, err := r.Rate(tt.from, tt.to)
+		if err != nil || !near(got, tt.want) {
+			t.Errorf("Rate(%s, %s) = %v, %v, want %v", tt.from, tt.to, got, err, tt.want)
+		}
+	}
+	if _, err := r.Rate("SOL", "USDT"); !errors.Is(err, ErrNoRate) {
+		t.Errorf("unknown asset: %v", err)
+	}
+}
+
+func TestRateStaleness(t *testing.T) {
+	r := NewRates(Options{MaxAge: time.Minute})
+	now := time.Now()
+	r.now = func() time.Time { return now }
+	r.Update("BTC", "USDT", 60000, now.Add(-2*time.Minute))
+	if err := r.SetStatic("EUR", "USDT", 1.1); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := r.Rate("BTC", "USDT"); !errors.Is(err, ErrNoRate) {
+		t.Errorf("stale rate used: %v", err)
+	}
+	if rate, err := r.Rate("EUR", "USDT"); err != nil || rate != 1.1 {
+		t.Errorf("static rate = %v, %v", rate, err)
+	}
+	// Older observations never replace newer ones, and never replace static rates
+	r.Update("BTC", "USDT", 61000, now)
+	r.Update("BTC", "USDT", 59000, now.Add(-time.Second))
+	r.Update("EUR", "USDT", 2, now)
+	if rate, _ := r.Rate("BTC", "USDT"); rate != 61000 {
+		t.Errorf("btc rate = %v", rate)
+	}
+	if rate, _ := r.Rate("EUR", "USDT"); rate != 1.1 {
+		t.Errorf("eur rate = %v", rate)
+	}
+}
+
+func TestRateAt(t *testing.T) {
+	r := NewRates(Options{MaxAge: time.Minute, History: 24 * time.Hour})
+	start := time.Date(2024, 6, 1, 0, 0, 0, 0, time.UTC)
+	now := start.Add(30 * time.Hour)
+	r.now = func() time.Time { return now }
+	for h, price := range []float64{50000, 51000, 52000} {
+		r.Update("BTC", "USDT", price, now.Add(time.Duration(h-2)*time.Hour))
+		r.Update("BTC", "USDT", price+500, now.Add(time.Duration(h-2)*time.Hour+time.Minute)) // Same hour
+	}
+	r.Update("ETH", "BTC", 0.05, now.Add(-2*time.Hour))
+	if err := r.SetStatic("EUR", "USDT", 1.1); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		from, to string
+		at       time.Time
+		want     float64
+	}{
+		{"BTC", "USDT", now.Add(-2 * time.Hour), 50000},
+		{"BTC", "USDT", now.Add(-90 * time.Minute), 50000}, // First rate of the hour
+		{"BTC", "USDT", now.Add(-time.Hour), 51000},
+		{"ETH", "USDT", now.Add(-time.Hour), 0.05 * 51000},
+		{"EUR", "USDT", start, 1.1}, // Static rates hold at any time
+		{"BTC", "BTC", start, 1},
+	}
+	for _, tt := range tests {
+		got, err := r.RateAt(tt.from, tt.to, tt.at)
+		if err != nil || !near(got, tt.want) {
+			t.Errorf("RateAt(%s, %s, %s) = %v, %v, want %v", tt.from, tt.to, tt.at, got, err, tt.want)
+		}
+	}
+	for name, at := range map[string]time.Time{
+		"before": now.Add(-3 * time.Hour),
+		"stale":  now,
+	} {
+		if _, err := r.RateAt("ETH", "BTC", at); !errors.Is(err, ErrNoRate) {
+			t.Errorf("%s: %v", name, err)
+		}
+	}
+	if rate, err := r.Rate("BTC", "USDT"); err != nil || rate != 52500 {
+		t.Errorf("current rate = %v, %v", rate, err)
+	}
+
+	// Rates older than the history are dropped as new ones are observed
+	now = now.Add(23 * time.Hour)
+	r.Update("BTC", "USDT", 53000, now)
+	if _, err := r.RateAt("BTC", "USDT", now.Add(-24*time.Hour-30*time.Minute)); !errors.Is(err, ErrNoRate) {
+		t.Errorf("rate kept past the history: %v", err)
+	}
+	if rate, err := r.RateAt("BTC", "USDT", now.Add(-22*time.Hour-30*time.Minute)); err != nil || rate != 52000 {
+		t.Errorf("rate within the history = %v, %v", rate, err)
+	}
+}
+
+func TestVol(t *testing.T) {
+	r := NewRates(Options{MaxAge: time.Minute})
+	now := time.Now()
+	contract, err := sqx.NewOptionContractFromStr("BTC-USDT-250328-90000-C")
+	if err != nil {
+		t.Fatal(err)
+	}
+	r.OnOptionMark(sqx.OptionMark{Contract: contract, MarkIV: 0.55, Timestamp: now.UnixMilli()})
+	r.OnOptionMark(sqx.OptionMark{Contract: contract, MarkIV: 0.6, Timestamp: now.Add(-time.Second).UnixMilli()})
+	r.OnOptionMark(sqx.OptionMark{Contract: contract, MarkPrice: 1200, Timestamp: now.UnixMilli()})
+	if vol, err := r.Vol("BTC-USDT-250328-90000-C"); err != nil || vol != 0.55 {
+		t.Errorf("Vol = %v, %v, want the latest mark", vol, err)
+	}
+
+	r.now = func() time.Time { return now.Add(2 * time.Minute) }
+	if _, err := r.Vol("BTC-USDT-250328-90000-C"); !errors.Is(err, ErrNoVol) {
+		t.Errorf("stale vol: %v", err)
+	}
+}
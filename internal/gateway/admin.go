@@ -0,0 +1,66 @@
+package gateway
+
+import (
+	"crypto/subtle"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// AdminAuth rejects the requests without token as Authorization bearer
+func AdminAuth(token string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		got := strings.TrimPrefix(c.GetHeader("Authorization"), "Bearer ")
+		if subtle.ConstantTimeCompare([]byte(got), []byte(token)) != 1 {
+			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+			return
+		}
+		c.Next()
+	}
+}
+
+// RegisterAdmin registers the endpoints inspecting the usage of the
+// accounts and adjusting their limits:
+//
+//	GET  /accounts              usage of every account
+//	GET  /accounts/:name        usage of an account
+//	PUT  /accounts/:name/limits replace the limits of an account
+//	POST /accounts/:name/reset  clear the usage of an account
+func RegisterAdmin(routes gin.IRoutes, h *Hub) {
+	routes.GET("/accounts", func(c *gin.Context) {
+		c.JSON(http.StatusOK, h.Usage())
+	})
+	routes.GET("/accounts/:name", func(c *gin.Context) {
+		usage, ok := h.AccountUsage(c.Param("name"))
+		if !ok {
+			c.JSON(http.StatusNotFound, gin.H{"error": "unknown account " + c.Param("name")})
+			return
+		}
+		c.JSON(http.StatusOK, usage)
+	})
+	routes.PUT("/accounts/:name/limits", func(c *gin.Context) {
+		var limits Limits
+		if err := c.ShouldBindJSON(&limits); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		if _, ok := h.AccountUsage(c.Param("name")); !ok {
+			c.JSON(http.StatusNotFound, gin.H{"error": "unknown account " + c.Param("name")})
+			return
+		}
+		if err := h.SetLimits(c.Param("name"), limits); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		usage, _ := h.AccountUsage(c.Param("name"))
+		c.JSON(http.StatusOK, usage)
+	})
+	routes.POST("/accounts/:name/reset", func(c *gin.Context) {
+		if err := h.ResetUsage(c.Param("name")); err != nil {
+			c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+			return
+		}
+		c.Status(http.StatusNoContent)
+	})
+}
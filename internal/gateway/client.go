@@ -0,0 +1,212 @@
+package gateway
+
+import (
+	"encoding/json"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/BullionBear/sequex/pkg/logger"
+	"github.com/gorilla/websocket"
+)
+
+const (
+	writeWait      = 10 * time.Second
+	pongWait       = 60 * time.Second
+	pingPeriod     = (pongWait * 9) / 10
+	maxMessageSize = 4096
+)
+
+// Client is a single external websocket connection
+type Client struct {
+	id        string
+	conn      *websocket.Conn
+	hub       *Hub
+	send      chan []byte
+	limiter   *tokenBucket
+	meter     *meter
+	notified  atomic.Int64 // Start of the last quota period the client was told it exceeded
+	mu        sync.Mutex
+	subjects  map[string]struct{}
+	dropped   atomic.Int64
+	closeOnce sync.Once
+	done      chan struct{}
+}
+
+func newClient(id string, conn *websocket.Conn, hub *Hub, m *meter) *Client {
+	return &Client{
+		id:       id,
+		conn:     conn,
+		hub:      hub,
+		send:     make(chan []byte, hub.opts.SendBuffer),
+		limiter:  newTokenBucket(hub.opts.RateLimit, hub.opts.RateBurst),
+		meter:    m,
+		subjects: make(map[string]struct{}),
+		done:     make(chan struct{}),
+	}
+}
+
+// Id returns the client identifier
+func (c *Client) Id() string {
+	return c.id
+}
+
+// Dropped returns the number of data messages dropped by rate limiting or a full queue
+func (c *Client) Dropped() int64 {
+	return c.dropped.Load()
+}
+
+func (c *Client) addSubject(subject string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.subjects[subject] = struct{}{}
+}
+
+func (c *Client) removeSubject(subject string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	delete(c.subjects, subject)
+}
+
+func (c *Client) subscriptionCount() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return len(c.subjects)
+}
+
+func (c *Client) subjectList() []string {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	subjects := make([]string, 0, len(c.subjects))
+	for subject := range c.subjects {
+		subjects = append(subjects, subject)
+	}
+	return subjects
+}
+
+// sendData queues a data message, dropping it if the client or its account
+// is rate limited, its account is over quota or the client is too slow.
+// Only the messages queued are metered.
+func (c *Client) sendData(subject string, message []byte) {
+	if !c.limiter.Allow() {
+		c.dropped.Add(1)
+		return
+	}
+	switch c.meter.admit(len(message)) {
+	case throttled:
+		c.dropped.Add(1)
+		return
+	case overQuota:
+		c.dropped.Add(1)
+		// Told once per quota period
+		period := c.meter.periodStart().Unix()
+		if c.notified.Swap(period) != period {
+			c.sendEvent(ServerEvent{Event: EventQuotaExceeded, Error: "daily quota exceeded"})
+		}
+		return
+	}
+	if c.enqueue(message) {
+		c.meter.record(subject, len(message))
+	}
+}
+
+// sendEvent queues a control event, which is never rate limited
+func (c *Client) sendEvent(event ServerEvent) {
+	data, err := json.Marshal(event)
+	if err != nil {
+		logger.Log.Error().Err(err).Str("client", c.id).Msg("Failed to marshal event")
+		return
+	}
+	c.enqueue(data)
+}
+
+// enqueue queues a message, reporting whether it was queued
+func (c *Client) enqueue(message []byte) bool {
+	select {
+	case <-c.done:
+	case c.send <- message:
+		return true
+	default:
+		c.dropped.Add(1)
+	}
+	return false
+}
+
+func (c *Client) readLoop() {
+	defer c.close()
+
+	c.conn.SetReadLimit(maxMessageSize)
+	c.conn.SetReadDeadline(time.Now().Add(pongWait))
+	c.conn.SetPongHandler(func(string) error {
+		return c.conn.SetReadDeadline(time.Now().Add(pongWait))
+	})
+
+	for {
+		_, data, err := c.conn.ReadMessage()
+		if err != nil {
+			if websocket.IsUnexpectedCloseError(err, websocket.CloseGoingAway, websocket.CloseNormalClosure) {
+				logger.Log.Warn().Err(err).Str("client", c.id).Msg("Unexpected websocket close")
+			}
+			return
+		}
+
+		var msg ClientMessage
+		if err := json.Unmarshal(data, &msg); err != nil {
+			c.sendEvent(ServerEvent{Event: EventError, Error: "invalid message: " + err.Error()})
+			continue
+		}
+
+		switch {
+		case msg.Subscribe != "":
+			if err := c.hub.Subscribe(c, msg.Subscribe); err != nil {
+				c.sendEvent(ServerEvent{Event: EventError, Subject: msg.Subscribe, Error: err.Error()})
+				continue
+			}
+			c.sendEvent(ServerEvent{Event: EventSubscribed, Subject: msg.Subscribe})
+		case msg.Unsubscribe != "":
+			if err := c.hub.Unsubscribe(c, msg.Unsubscribe); err != nil {
+				c.sendEvent(ServerEvent{Event: EventError, Subject: msg.Unsubscribe, Error: err.Error()})
+				continue
+			}
+			c.sendEvent(ServerEvent{Event: EventUnsubscribed, Subject: msg.Unsubscribe})
+		default:
+			c.sendEvent(ServerEvent{Event: EventError, Error: "expected subscribe or unsubscribe"})
+		}
+	}
+}
+
+func (c *Client) writeLoop() {
+	ticker := time.NewTicker(pingPeriod)
+	defer func() {
+		ticker.Stop()
+		c.close()
+	}()
+
+	for {
+		select {
+		case <-c.done:
+			return
+		case message := <-c.send:
+			c.conn.SetWriteDeadline(time.Now().Add(writeWait))
+			if err := c.conn.WriteMessage(websocket.TextMessage, message); err != nil {
+				return
+			}
+		case <-ticker.C:
+			c.conn.SetWriteDeadline(time.Now().Add(writeWait))
+			if err := c.conn.WriteMessage(websocket.PingMessage, nil); err != nil {
+				return
+			}
+		}
+	}
+}
+
+// close detaches the client from the hub and closes the connection
+func (c *Client) close() {
+	c.closeOnce.Do(func() {
+		close(c.done)
+		c.hub.remove(c)
+		c.meter.disconnect()
+		c.conn.Close()
+		logger.Log.Info().Str("client", c.id).Int64("dropped", c.dropped.Load()).Msg("Client disconnected")
+	})
+}
@@ -0,0 +1,261 @@
+package gateway
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/BullionBear/sequex/pkg/logger"
+	"github.com/gin-gonic/gin"
+	"github.com/gorilla/websocket"
+)
+
+// SubscribeFunc subscribes to an internal subject and returns an unsubscribe function.
+// It decouples the hub from the NATS connection.
+type SubscribeFunc func(subject string, handler func(subject string, data []byte)) (func(), error)
+
+// Options configures the websocket gateway hub
+type Options struct {
+	AllowedSubjects  []string  // Subject patterns clients may subscribe to, empty allows all
+	Tokens           []string  // Accepted auth tokens of the default account, auth is disabled without tokens and accounts
+	RateLimit        float64   // Data messages per second per client, 0 disables limiting
+	RateBurst        int       // Token bucket burst size
+	SendBuffer       int       // Outbound queue size per client
+	MaxSubscriptions int       // Maximum subjects per client, 0 means unlimited
+	Accounts         []Account // Metered accounts, authenticated by their tokens
+	DefaultLimits    Limits    // Limits of the default account, shared by clients without an account token
+}
+
+// bridge is a shared internal subscription fanned out to many clients
+type bridge struct {
+	unsubscribe func()
+	clients     map[*Client]struct{}
+}
+
+// Hub bridges internal subjects to external websocket clients
+type Hub struct {
+	mu        sync.Mutex
+	opts      Options
+	subscribe SubscribeFunc
+	clients   map[*Client]struct{}
+	bridges   map[string]*bridge
+	tokens    map[string]struct{}
+	meters    map[string]*meter // By account name
+	accounts  map[string]*meter // By account token
+	upgrader  websocket.Upgrader
+	nextId    int64
+}
+
+// NewHub creates a new websocket gateway hub
+func NewHub(subscribe SubscribeFunc, opts Options) *Hub {
+	if opts.SendBuffer <= 0 {
+		opts.SendBuffer = 256
+	}
+	tokens := make(map[string]struct{}, len(opts.Tokens))
+	for _, token := range opts.Tokens {
+		tokens[token] = struct{}{}
+	}
+	meters := map[string]*meter{DefaultAccount: newMeter(DefaultAccount, opts.DefaultLimits, time.Now)}
+	accounts := make(map[string]*meter, len(opts.Accounts))
+	for _, a := range opts.Accounts {
+		m := newMeter(a.Name, a.Limits, time.Now)
+		meters[a.Name] = m
+		accounts[a.Token] = m
+	}
+	return &Hub{
+		opts:      opts,
+		subscribe: subscribe,
+		clients:   make(map[*Client]struct{}),
+		bridges:   make(map[string]*bridge),
+		tokens:    tokens,
+		meters:    meters,
+		accounts:  accounts,
+		upgrader: websocket.Upgrader{
+			ReadBufferSize:  1024,
+			WriteBufferSize: 1024,
+			CheckOrigin:     func(r *http.Request) bool { return true },
+		},
+	}
+}
+
+// ServeWS upgrades the request to a websocket connection and serves the client
+func (h *Hub) ServeWS(c *gin.Context) {
+	m, ok := h.authorize(c.Request)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+	if !m.connect() {
+		c.JSON(http.StatusTooManyRequests, gin.H{"error": "connection limit reached"})
+		return
+	}
+
+	conn, err := h.upgrader.Upgrade(c.Writer, c.Request, nil)
+	if err != nil {
+		m.disconnect()
+		logger.Log.Error().Err(err).Msg("Failed to upgrade websocket connection")
+		return
+	}
+
+	h.mu.Lock()
+	h.nextId++
+	client := newClient(fmt.Sprintf("client-%d", h.nextId), conn, h, m)
+	h.clients[client] = struct{}{}
+	h.mu.Unlock()
+
+	logger.Log.Info().Str("client", client.id).Str("account", m.name).Str("remote", c.Request.RemoteAddr).Msg("Client connected")
+	go client.writeLoop()
+	go client.readLoop()
+}
+
+// authorize checks the token passed as ?token= or as an Authorization bearer
+// header and returns the meter of its account. Account tokens meter their
+// account, other tokens the default account.
+func (h *Hub) authorize(r *http.Request) (*meter, bool) {
+	token := r.URL.Query().Get("token")
+	if token == "" {
+		token = strings.TrimPrefix(r.Header.Get("Authorization"), "Bearer ")
+	}
+	if m, ok := h.accounts[token]; ok && token != "" {
+		return m, true
+	}
+	if len(h.tokens) == 0 && len(h.accounts) == 0 {
+		return h.meters[DefaultAccount], true
+	}
+	_, ok := h.tokens[token]
+	return h.meters[DefaultAccount], ok
+}
+
+// allowed reports whether clients may subscribe to the subject
+func (h *Hub) allowed(subject string) bool {
+	if len(h.opts.AllowedSubjects) == 0 {
+		return true
+	}
+	for _, pattern := range h.opts.AllowedSubjects {
+		if matchSubject(pattern, subject) {
+			return true
+		}
+	}
+	return false
+}
+
+// Subscribe adds a client to the subject, creating the internal subscription if needed
+func (h *Hub) Subscribe(client *Client, subject string) error {
+	if subject == "" || strings.ContainsAny(subject, "*> ") {
+		return fmt.Errorf("invalid subject: %q", subject)
+	}
+	if !h.allowed(subject) {
+		return fmt.Errorf("subject not allowed: %s", subject)
+	}
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if h.opts.MaxSubscriptions > 0 && client.subscriptionCount() >= h.opts.MaxSubscriptions {
+		return fmt.Errorf("subscription limit of %d reached", h.opts.MaxSubscriptions)
+	}
+
+	b, ok := h.bridges[subject]
+	if !ok {
+		unsubscribe, err := h.subscribe(subject, h.dispatch)
+		if err != nil {
+			return fmt.Errorf("failed to subscribe to %s: %w", subject, err)
+		}
+		b = &bridge{unsubscribe: unsubscribe, clients: make(map[*Client]struct{})}
+		h.bridges[subject] = b
+	}
+	b.clients[client] = struct{}{}
+	client.addSubject(subject)
+	return nil
+}
+
+// Unsubscribe removes a client from the subject, dropping the internal subscription when unused
+func (h *Hub) Unsubscribe(client *Client, subject string) error {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	return h.unsubscribeLocked(client, subject)
+}
+
+func (h *Hub) unsubscribeLocked(client *Client, subject string) error {
+	b, ok := h.bridges[subject]
+	if !ok {
+		return fmt.Errorf("not subscribed to %s", subject)
+	}
+	if _, ok := b.clients[client]; !ok {
+		return fmt.Errorf("not subscribed to %s", subject)
+	}
+	delete(b.clients, client)
+	client.removeSubject(subject)
+	if len(b.clients) == 0 {
+		b.unsubscribe()
+		delete(h.bridges, subject)
+	}
+	return nil
+}
+
+// remove detaches a disconnected client from the hub
+func (h *Hub) remove(client *Client) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if _, ok := h.clients[client]; !ok {
+		return
+	}
+	for _, subject := range client.subjectList() {
+		_ = h.unsubscribeLocked(client, subject)
+	}
+	delete(h.clients, client)
+}
+
+// dispatch fans an internal message out to every subscribed client
+func (h *Hub) dispatch(subject string, data []byte) {
+	payload, err := decodePayload(subject, data)
+	if err != nil {
+		logger.Log.Error().Err(err).Str("subject", subject).Msg("Failed to decode payload")
+		return
+	}
+	message, err := json.Marshal(DataMessage{Subject: subject, Data: payload})
+	if err != nil {
+		logger.Log.Error().Err(err).Str("subject", subject).Msg("Failed to marshal data message")
+		return
+	}
+
+	h.mu.Lock()
+	b, ok := h.bridges[subject]
+	if !ok {
+		h.mu.Unlock()
+		return
+	}
+	clients := make([]*Client, 0, len(b.clients))
+	for client := range b.clients {
+		clients = append(clients, client)
+	}
+	h.mu.Unlock()
+
+	for _, client := range clients {
+		client.sendData(subject, message)
+	}
+}
+
+// ClientCount returns the number of connected clients
+func (h *Hub) ClientCount() int {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	return len(h.clients)
+}
+
+// Close disconnects all clients and drops every internal subscription
+func (h *Hub) Close() {
+	h.mu.Lock()
+	clients := make([]*Client, 0, len(h.clients))
+	for client := range h.clients {
+		clients = append(clients, client)
+	}
+	h.mu.Unlock()
+
+	for _, client := range clients {
+		client.close()
+	}
+}
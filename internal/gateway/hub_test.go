@@ -0,0 +1,361 @@
+package gateway
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/BullionBear/sequex/internal/model/sqx"
+	"github.com/gin-gonic/gin"
+	"github.com/gorilla/websocket"
+)
+
+// fakeBus records subscriptions and lets tests publish to them
+type fakeBus struct {
+	mu       sync.Mutex
+	handlers map[string]func(subject string, data []byte)
+}
+
+func newFakeBus() *fakeBus {
+	return &fakeBus{handlers: make(map[string]func(subject string, data []byte))}
+}
+
+func (b *fakeBus) subscribe(subject string, handler func(subject string, data []byte)) (func(), error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.handlers[subject] = handler
+	return func() {
+		b.mu.Lock()
+		defer b.mu.Unlock()
+		delete(b.handlers, subject)
+	}, nil
+}
+
+func (b *fakeBus) publish(subject string, data []byte) {
+	b.mu.Lock()
+	handler := b.handlers[subject]
+	b.mu.Unlock()
+	if handler != nil {
+		handler(subject, data)
+	}
+}
+
+func (b *fakeBus) subscribed(subject string) bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	_, ok := b.handlers[subject]
+	return ok
+}
+
+func newTestServer(t *testing.T, bus *fakeBus, opts Options) (*Hub, string) {
+	gin.SetMode(gin.TestMode)
+	hub := NewHub(bus.subscribe, opts)
+	rg := gin.New()
+	rg.GET("/ws", hub.ServeWS)
+	server := httptest.NewServer(rg)
+	t.Cleanup(func() {
+		hub.Close()
+		server.Close()
+	})
+	return hub, "ws" + strings.TrimPrefix(server.URL, "http") + "/ws"
+}
+
+func readJSON(t *testing.T, conn *websocket.Conn, v interface{}) {
+	t.Helper()
+	conn.SetReadDeadline(time.Now().Add(2 * time.Second))
+	if err := conn.ReadJSON(v); err != nil {
+		t.Fatalf("failed to read message: %v", err)
+	}
+}
+
+func TestHub_SubscribeAndReceiveTrade(t *testing.T) {
+	bus := newFakeBus()
+	_, url := newTestServer(t, bus, Options{AllowedSubjects: []string{"trade.>"}})
+
+	conn, _, err := websocket.DefaultDialer.Dial(url, nil)
+	if err != nil {
+		t.Fatalf("failed to dial: %v", err)
+	}
+	defer conn.Close()
+
+	subject := "trade.binance.spot.btcusdt"
+	if err := conn.WriteJSON(ClientMessage{Subscribe: subject}); err != nil {
+		t.Fatalf("failed to subscribe: %v", err)
+	}
+	var event ServerEvent
+	readJSON(t, conn, &event)
+	if event.Event != EventSubscribed || event.Subject != subject {
+		t.Fatalf("expected subscribed event, got %+v", event)
+	}
+	if !bus.subscribed(subject) {
+		t.Fatal("expected internal subscription to be created")
+	}
+
+	trade := sqx.Trade{
+		Id:             42,
+		Symbol:         sqx.NewSymbol("BTC", "USDT"),
+		Exchange:       sqx.ExchangeBinance,
+		InstrumentType: sqx.InstrumentTypeSpot,
+		TakerSide:      sqx.SideBuy,
+		Price:          60000,
+		Quantity:       0.5,
+		Timestamp:      1700000000000,
+	}
+	data, err := trade.Marshal()
+	if err != nil {
+		t.Fatalf("failed to marshal trade: %v", err)
+	}
+	bus.publish(subject, data)
+
+	var msg DataMessage
+	readJSON(t, conn, &msg)
+	if msg.Subject != subject {
+		t.Errorf("Subject = %s, expected %s", msg.Subject, subject)
+	}
+	var received sqx.Trade
+	if err := json.Unmarshal(msg.Data, &received); err != nil {
+		t.Fatalf("failed to decode trade: %v", err)
+	}
+	if received != trade {
+		t.Errorf("received %+v, expected %+v", received, trade)
+	}
+
+	if err := conn.WriteJSON(ClientMessage{Unsubscribe: subject}); err != nil {
+		t.Fatalf("failed to unsubscribe: %v", err)
+	}
+	readJSON(t, conn, &event)
+	if event.Event != EventUnsubscribed {
+		t.Fatalf("expected unsubscribed event, got %+v", event)
+	}
+	if bus.subscribed(subject) {
+		t.Error("expected internal subscription to be dropped after last client left")
+	}
+}
+
+func TestHub_RejectsDisallowedSubject(t *testing.T) {
+	bus := newFakeBus()
+	_, url := newTestServer(t, bus, Options{AllowedSubjects: []string{"trade.>"}})
+
+	conn, _, err := websocket.DefaultDialer.Dial(url, nil)
+	if err != nil {
+		t.Fatalf("failed to dial: %v", err)
+	}
+	defer conn.Close()
+
+	for _, subject := range []string{"order.binance", "trade.>", ""} {
+		if err := conn.WriteJSON(map[string]string{"subscribe": subject}); err != nil {
+			t.Fatalf("failed to write: %v", err)
+		}
+		var event ServerEvent
+		readJSON(t, conn, &event)
+		if event.Event != EventError {
+			t.Errorf("subject %q: expected error event, got %+v", subject, event)
+		}
+	}
+}
+
+func TestHub_Auth(t *testing.T) {
+	bus := newFakeBus()
+	_, url := newTestServer(t, bus, Options{Tokens: []string{"secret"}})
+
+	_, resp, err := websocket.DefaultDialer.Dial(url, nil)
+	if err == nil {
+		t.Fatal("expected dial without token to fail")
+	}
+	if resp == nil || resp.StatusCode != http.StatusUnauthorized {
+		t.Fatalf("expected 401, got %v", resp)
+	}
+
+	conn, _, err := websocket.DefaultDialer.Dial(url+"?token=secret", nil)
+	if err != nil {
+		t.Fatalf("failed to dial with query token: %v", err)
+	}
+	conn.Close()
+
+	header := http.Header{"Authorization": []string{"Bearer secret"}}
+	conn, _, err = websocket.DefaultDialer.Dial(url, header)
+	if err != nil {
+		t.Fatalf("failed to dial with bearer token: %v", err)
+	}
+	conn.Close()
+}
+
+func TestHub_RateLimit(t *testing.T) {
+	bus := newFakeBus()
+	hub, url := newTestServer(t, bus, Options{RateLimit: 0.001, RateBurst: 2})
+
+	conn, _, err := websocket.DefaultDialer.Dial(url, nil)
+	if err != nil {
+		t.Fatalf("failed to dial: %v", err)
+	}
+	defer conn.Close()
+
+	subject := "index.btc_basis"
+	conn.WriteJSON(ClientMessage{Subscribe: subject})
+	var event ServerEvent
+	readJSON(t, conn, &event)
+
+	for i := 0; i < 5; i++ {
+		bus.publish(subject, []byte(`{"name":"BTC_BASIS","value":1}`))
+	}
+	for i := 0; i < 2; i++ {
+		var msg DataMessage
+		readJSON(t, conn, &msg)
+	}
+
+	hub.mu.Lock()
+	var dropped int64
+	for client := range hub.clients {
+		dropped = client.Dropped()
+	}
+	hub.mu.Unlock()
+	if dropped != 3 {
+		t.Errorf("expected 3 dropped messages, got %d", dropped)
+	}
+}
+
+func TestHub_Metering(t *testing.T) {
+	bus := newFakeBus()
+	hub, url := newTestServer(t, bus, Options{
+		Accounts: []Account{{Name: "research", Token: "research-token", Limits: Limits{MaxConnections: 1, DailyMessages: 2}}},
+	})
+
+	if _, resp, err := websocket.DefaultDialer.Dial(url, nil); err == nil || resp.StatusCode != http.StatusUnauthorized {
+		t.Fatalf("expected 401 without an account token, got %v", err)
+	}
+	conn, _, err := websocket.DefaultDialer.Dial(url+"?token=research-token", nil)
+	if err != nil {
+		t.Fatalf("failed to dial: %v", err)
+	}
+	defer conn.Close()
+	if _, resp, err := websocket.DefaultDialer.Dial(url+"?token=research-token", nil); err == nil || resp.StatusCode != http.StatusTooManyRequests {
+		t.Fatalf("expected 429 beyond max connections, got %v", err)
+	}
+
+	subject := "index.btc_basis"
+	conn.WriteJSON(ClientMessage{Subscribe: subject})
+	var event ServerEvent
+	readJSON(t, conn, &event)
+
+	payload := []byte(`{"name":"BTC_BASIS","value":1}`)
+	for i := 0; i < 4; i++ {
+		bus.publish(subject, payload)
+	}
+	for i := 0; i < 2; i++ {
+		var msg DataMessage
+		readJSON(t, conn, &msg)
+	}
+	// Told once that the quota is exceeded
+	readJSON(t, conn, &event)
+	if event.Event != EventQuotaExceeded {
+		t.Fatalf("expected quota exceeded event, got %+v", event)
+	}
+
+	usage, ok := hub.AccountUsage("research")
+	if !ok {
+		t.Fatal("no usage of the account")
+	}
+	if usage.Messages != 2 || usage.PeriodMessages != 2 || usage.OverQuota != 2 || usage.Connections != 1 || usage.Subjects[subject].Messages != 2 {
+		t.Errorf("usage = %+v", usage)
+	}
+	if usage.Bytes == 0 || usage.Subjects[subject].Bytes != usage.Bytes {
+		t.Errorf("bytes = %d, subject bytes = %d", usage.Bytes, usage.Subjects[subject].Bytes)
+	}
+
+	// Raising the quota lets data through again
+	if err := hub.SetLimits("research", Limits{MaxConnections: 1, DailyMessages: 3}); err != nil {
+		t.Fatal(err)
+	}
+	bus.publish(subject, payload)
+	var msg DataMessage
+	readJSON(t, conn, &msg)
+	if err := hub.SetLimits("unknown", Limits{}); err == nil {
+		t.Error("limits set on an unknown account")
+	}
+}
+
+func TestAdminAPI(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	hub := NewHub(newFakeBus().subscribe, Options{Accounts: []Account{{Name: "research", Token: "t"}}})
+	rg := gin.New()
+	RegisterAdmin(rg.Group("/admin", AdminAuth("admin")), hub)
+
+	do := func(method, path, token, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, path, strings.NewReader(body))
+		if token != "" {
+			req.Header.Set("Authorization", "Bearer "+token)
+		}
+		w := httptest.NewRecorder()
+		rg.ServeHTTP(w, req)
+		return w
+	}
+
+	if w := do("GET", "/admin/accounts", "", ""); w.Code != http.StatusUnauthorized {
+		t.Errorf("without token: %d", w.Code)
+	}
+	w := do("GET", "/admin/accounts", "admin", "")
+	var usage []Usage
+	if err := json.Unmarshal(w.Body.Bytes(), &usage); err != nil || len(usage) != 2 || usage[0].Account != DefaultAccount || usage[1].Account != "research" {
+		t.Errorf("accounts = %s", w.Body)
+	}
+	w = do("PUT", "/admin/accounts/research/limits", "admin", `{"rate_limit":50,"daily_bytes":1000}`)
+	if w.Code != http.StatusOK {
+		t.Fatalf("set limits: %d %s", w.Code, w.Body)
+	}
+	if u, _ := hub.AccountUsage("research"); u.Limits.RateLimit != 50 || u.Limits.DailyBytes != 1000 {
+		t.Errorf("limits = %+v", u.Limits)
+	}
+	if w := do("PUT", "/admin/accounts/research/limits", "admin", `{"rate_limit":-1}`); w.Code != http.StatusBadRequest {
+		t.Errorf("negative limits: %d", w.Code)
+	}
+	if w := do("GET", "/admin/accounts/unknown", "admin", ""); w.Code != http.StatusNotFound {
+		t.Errorf("unknown account: %d", w.Code)
+	}
+	if w := do("POST", "/admin/accounts/research/reset", "admin", ""); w.Code != http.StatusNoContent {
+		t.Errorf("reset: %d", w.Code)
+	}
+}
+
+func TestMeterQuotaPeriod(t *testing.T) {
+	now := time.Date(2025, 9, 15, 23, 59, 0, 0, time.UTC)
+	m := newMeter("research", Limits{DailyBytes: 100}, func() time.Time { return now })
+	if m.admit(60) != admitted {
+		t.Fatal("first message not admitted")
+	}
+	m.record("index.btc", 60)
+	if m.admit(60) != overQuota {
+		t.Fatal("message beyond the daily bytes admitted")
+	}
+	now = now.Add(2 * time.Minute)
+	if m.admit(60) != admitted {
+		t.Error("quota not reset the next UTC day")
+	}
+	if u := m.snapshot(); u.PeriodBytes != 0 || u.Bytes != 60 || !u.PeriodStart.Equal(time.Date(2025, 9, 16, 0, 0, 0, 0, time.UTC)) {
+		t.Errorf("usage = %+v", u)
+	}
+}
+
+func TestMatchSubject(t *testing.T) {
+	tests := []struct {
+		pattern  string
+		subject  string
+		expected bool
+	}{
+		{"trade.>", "trade.binance.spot.btcusdt", true},
+		{"trade.>", "trade", false},
+		{"trade.*.spot.*", "trade.binance.spot.btcusdt", true},
+		{"trade.*.spot.*", "trade.binance.perp.btcusdt", false},
+		{"trade.binance", "trade.binance.spot", false},
+		{"index.btc", "index.btc", true},
+	}
+
+	for _, tt := range tests {
+		if result := matchSubject(tt.pattern, tt.subject); result != tt.expected {
+			t.Errorf("matchSubject(%s, %s) = %v, expected %v", tt.pattern, tt.subject, result, tt.expected)
+		}
+	}
+}
@@ -0,0 +1,72 @@
+package gateway
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/BullionBear/sequex/internal/model/sqx"
+)
+
+// ClientMessage is a control message sent by a websocket client,
+// e.g. {"subscribe": "trade.btcusdt"} or {"unsubscribe": "trade.btcusdt"}
+type ClientMessage struct {
+	Subscribe   string `json:"subscribe,omitempty"`
+	Unsubscribe string `json:"unsubscribe,omitempty"`
+}
+
+// Event types sent back to clients
+const (
+	EventSubscribed    = "subscribed"
+	EventUnsubscribed  = "unsubscribed"
+	EventError         = "error"
+	EventQuotaExceeded = "quota_exceeded"
+)
+
+// ServerEvent is a control response sent to a websocket client
+type ServerEvent struct {
+	Event   string `json:"event"`
+	Subject string `json:"subject,omitempty"`
+	Error   string `json:"error,omitempty"`
+}
+
+// DataMessage carries a market data payload to a websocket client
+type DataMessage struct {
+	Subject string          `json:"subject"`
+	Data    json.RawMessage `json:"data"`
+}
+
+// decodePayload converts a NATS payload into JSON for external clients.
+// Trade subjects carry protobuf and are normalized through sqx.Trade,
+// other subjects are expected to already be JSON encoded.
+func decodePayload(subject string, data []byte) (json.RawMessage, error) {
+	if strings.HasPrefix(subject, "trade.") {
+		var trade sqx.Trade
+		if err := sqx.Unmarshal(data, &trade); err != nil {
+			return nil, fmt.Errorf("failed to unmarshal trade: %w", err)
+		}
+		return json.Marshal(trade)
+	}
+	if !json.Valid(data) {
+		return nil, fmt.Errorf("payload on %s is not valid JSON", subject)
+	}
+	return json.RawMessage(data), nil
+}
+
+// matchSubject reports whether a NATS subject matches a pattern with * and > wildcards
+func matchSubject(pattern, subject string) bool {
+	patternTokens := strings.Split(pattern, ".")
+	subjectTokens := strings.Split(subject, ".")
+	for i, token := range patternTokens {
+		if token == ">" {
+			return len(subjectTokens) > i
+		}
+		if i >= len(subjectTokens) {
+			return false
+		}
+		if token != "*" && token != subjectTokens[i] {
+			return false
+		}
+	}
+	return len(patternTokens) == len(subjectTokens)
+}
@@ -0,0 +1,235 @@
+package gateway
+
+import (
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+)
+
+// DefaultAccount meters the clients connecting without an account token
+const DefaultAccount = "default"
+
+// quotaPeriod is the period quotas are counted over, reset at 00:00 UTC
+const quotaPeriod = 24 * time.Hour
+
+// Limits bounds the usage of an account over all its connections. Zero
+// values mean no limit.
+type Limits struct {
+	RateLimit      float64 `json:"rate_limit"`      // Data messages per second
+	RateBurst      int     `json:"rate_burst"`      // Burst size of the rate limiter
+	MaxConnections int     `json:"max_connections"` // Concurrent websocket connections
+	DailyMessages  int64   `json:"daily_messages"`  // Data messages per UTC day
+	DailyBytes     int64   `json:"daily_bytes"`     // Data bytes per UTC day
+}
+
+// Validate validates the limits
+func (l Limits) Validate() error {
+	if l.RateLimit < 0 || l.RateBurst < 0 || l.MaxConnections < 0 || l.DailyMessages < 0 || l.DailyBytes < 0 {
+		return fmt.Errorf("limits cannot be negative")
+	}
+	return nil
+}
+
+// Account is a customer or team of the gateway, identified by its token
+type Account struct {
+	Name   string
+	Token  string
+	Limits Limits
+}
+
+// SubjectUsage is the data delivered to an account on a subject
+type SubjectUsage struct {
+	Messages uint64 `json:"messages"`
+	Bytes    uint64 `json:"bytes"`
+}
+
+// Usage is the metered usage of an account since the gateway started, and
+// over the current quota period
+type Usage struct {
+	Account        string                  `json:"account"`
+	Limits         Limits                  `json:"limits"`
+	Connections    int                     `json:"connections"`
+	Messages       uint64                  `json:"messages"`
+	Bytes          uint64                  `json:"bytes"`
+	Throttled      uint64                  `json:"throttled"`    // Messages dropped by the account rate limit
+	OverQuota      uint64                  `json:"over_quota"`   // Messages dropped by the daily quotas
+	PeriodStart    time.Time               `json:"period_start"` // Start of the quota period
+	PeriodMessages int64                   `json:"period_messages"`
+	PeriodBytes    int64                   `json:"period_bytes"`
+	Subjects       map[string]SubjectUsage `json:"subjects"`
+}
+
+// verdict is the decision of a meter on a data message
+type verdict int
+
+const (
+	admitted verdict = iota
+	throttled
+	overQuota
+)
+
+// meter accounts the data delivered to the connections of an account
+type meter struct {
+	name   string
+	mu     sync.Mutex
+	now    func() time.Time
+	usage  Usage
+	bucket *tokenBucket
+}
+
+func newMeter(name string, limits Limits, now func() time.Time) *meter {
+	m := &meter{name: name, now: now, usage: Usage{Account: name, Subjects: make(map[string]SubjectUsage)}}
+	m.setLimits(limits)
+	m.roll(now())
+	return m
+}
+
+// setLimits replaces the limits, restarting the rate limiter
+func (m *meter) setLimits(limits Limits) {
+	m.usage.Limits = limits
+	m.bucket = newTokenBucket(limits.RateLimit, limits.RateBurst)
+}
+
+// roll starts a new quota period once the current one is over
+func (m *meter) roll(now time.Time) {
+	if start := now.UTC().Truncate(quotaPeriod); start.After(m.usage.PeriodStart) {
+		m.usage.PeriodStart = start
+		m.usage.PeriodMessages = 0
+		m.usage.PeriodBytes = 0
+	}
+}
+
+// admit decides whether a data message of size bytes may be delivered
+func (m *meter) admit(size int) verdict {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.roll(m.now())
+	l := m.usage.Limits
+	if (l.DailyMessages > 0 && m.usage.PeriodMessages >= l.DailyMessages) ||
+		(l.DailyBytes > 0 && m.usage.PeriodBytes+int64(size) > l.DailyBytes) {
+		m.usage.OverQuota++
+		return overQuota
+	}
+	if !m.bucket.Allow() {
+		m.usage.Throttled++
+		return throttled
+	}
+	return admitted
+}
+
+// record accounts a data message delivered on subject
+func (m *meter) record(subject string, size int) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.usage.Messages++
+	m.usage.Bytes += uint64(size)
+	m.usage.PeriodMessages++
+	m.usage.PeriodBytes += int64(size)
+	s := m.usage.Subjects[subject]
+	s.Messages++
+	s.Bytes += uint64(size)
+	m.usage.Subjects[subject] = s
+}
+
+// periodStart returns the start of the current quota period
+func (m *meter) periodStart() time.Time {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.usage.PeriodStart
+}
+
+// connect counts a new connection, refused beyond the connection limit
+func (m *meter) connect() bool {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if l := m.usage.Limits.MaxConnections; l > 0 && m.usage.Connections >= l {
+		return false
+	}
+	m.usage.Connections++
+	return true
+}
+
+func (m *meter) disconnect() {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.usage.Connections--
+}
+
+// snapshot returns a copy of the usage
+func (m *meter) snapshot() Usage {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.roll(m.now())
+	u := m.usage
+	u.Subjects = make(map[string]SubjectUsage, len(m.usage.Subjects))
+	for subject, s := range m.usage.Subjects {
+		u.Subjects[subject] = s
+	}
+	return u
+}
+
+// reset clears the usage, the connections aside
+func (m *meter) reset() {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.usage = Usage{Account: m.name, Limits: m.usage.Limits, Connections: m.usage.Connections, Subjects: make(map[string]SubjectUsage)}
+	m.roll(m.now())
+}
+
+// Usage returns the usage of every account, sorted by name
+func (h *Hub) Usage() []Usage {
+	h.mu.Lock()
+	meters := make([]*meter, 0, len(h.meters))
+	for _, m := range h.meters {
+		meters = append(meters, m)
+	}
+	h.mu.Unlock()
+	usage := make([]Usage, len(meters))
+	for i, m := range meters {
+		usage[i] = m.snapshot()
+	}
+	sort.Slice(usage, func(i, j int) bool { return usage[i].Account < usage[j].Account })
+	return usage
+}
+
+// AccountUsage returns the usage of an account
+func (h *Hub) AccountUsage(name string) (Usage, bool) {
+	h.mu.Lock()
+	m, ok := h.meters[name]
+	h.mu.Unlock()
+	if !ok {
+		return Usage{}, false
+	}
+	return m.snapshot(), true
+}
+
+// SetLimits adjusts the limits of an account, taking effect on the next
+// message. The change lasts until the gateway restarts.
+func (h *Hub) SetLimits(name string, limits Limits) error {
+	if err := limits.Validate(); err != nil {
+		return err
+	}
+	h.mu.Lock()
+	m, ok := h.meters[name]
+	h.mu.Unlock()
+	if !ok {
+		return fmt.Errorf("unknown account %s", name)
+	}
+	m.mu.Lock()
+	m.setLimits(limits)
+	m.mu.Unlock()
+	return nil
+}
+
+// ResetUsage clears the usage of an account, its quota period included
+func (h *Hub) ResetUsage(name string) error {
+	h.mu.Lock()
+	m, ok := h.meters[name]
+	h.mu.Unlock()
+	if !ok {
+		return fmt.Errorf("unknown account %s", name)
+	}
+	m.reset()
+	return nil
+}
@@ -0,0 +1,53 @@
+package gateway
+
+import (
+	"sync"
+	"time"
+)
+
+// tokenBucket is a simple token bucket refilled continuously at rate tokens per second
+type tokenBucket struct {
+	mu       sync.Mutex
+	rate     float64
+	burst    float64
+	tokens   float64
+	lastFill time.Time
+}
+
+func newTokenBucket(rate float64, burst int) *tokenBucket {
+	if burst <= 0 {
+		burst = int(rate)
+	}
+	if burst <= 0 {
+		burst = 1
+	}
+	return &tokenBucket{
+		rate:     rate,
+		burst:    float64(burst),
+		tokens:   float64(burst),
+		lastFill: time.Now(),
+	}
+}
+
+// Allow consumes one token if available. A bucket with a non-positive rate is unlimited.
+func (b *tokenBucket) Allow() bool {
+	if b == nil || b.rate <= 0 {
+		return true
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	now := time.Now()
+	b.tokens += now.Sub(b.lastFill).Seconds() * b.rate
+	if b.tokens > b.burst {
+		b.tokens = b.burst
+	}
+	b.lastFill = now
+
+	if b.tokens < 1 {
+		return false
+	}
+	b.tokens--
+	return true
+}
@@ -0,0 +1,52 @@
+package grpcgateway
+
+import (
+	"context"
+	"sync"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// queue buffers the messages of a stream between the NATS handlers, which
+// must never block, and the sender, which waits on the HTTP/2 flow control
+// of the client. A client falling further behind than the buffer has its
+// stream ended: dropping messages silently would leave gaps in trades and
+// corrupt the books built from depth diffs.
+type queue[T any] struct {
+	ch       chan T
+	overflow chan struct{}
+	once     sync.Once
+}
+
+func newQueue[T any](size int) *queue[T] {
+	return &queue[T]{ch: make(chan T, size), overflow: make(chan struct{})}
+}
+
+// push queues a message, flagging the overflow when the buffer is full
+func (q *queue[T]) push(v T) {
+	select {
+	case q.ch <- v:
+	default:
+		q.once.Do(func() { close(q.overflow) })
+	}
+}
+
+// drain sends the queued messages until the stream ends, the queue
+// overflows or an error is received on errs
+func (q *queue[T]) drain(ctx context.Context, errs <-chan error, send func(T) error) error {
+	for {
+		select {
+		case <-ctx.Done():
+			return status.FromContextError(ctx.Err()).Err()
+		case err := <-errs:
+			return err
+		case <-q.overflow:
+			return status.Errorf(codes.ResourceExhausted, "slow consumer: more than %d messages behind", cap(q.ch))
+		case v := <-q.ch:
+			if err := send(v); err != nil {
+				return err
+			}
+		}
+	}
+}
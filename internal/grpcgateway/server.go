@@ -0,0 +1,366 @@
+// Package grpcgateway serves the SequexService: market data streamed to
+// external clients by symbol, and the events of external strategy bridges
+// exchanged with the bus, over gRPC.
+package grpcgateway
+
+import (
+	"context"
+	"crypto/subtle"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"sync"
+
+	"github.com/BullionBear/sequex/internal/model/protobuf"
+	"github.com/BullionBear/sequex/internal/model/sqx"
+	"github.com/BullionBear/sequex/pkg/candles"
+	"github.com/BullionBear/sequex/pkg/eventbus"
+	"github.com/BullionBear/sequex/pkg/lob"
+	"github.com/BullionBear/sequex/pkg/logger"
+	"github.com/nats-io/nats.go"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+// SubscribeFunc subscribes to a subject or pattern and returns an
+// unsubscribe function. It decouples the server from the NATS connection.
+type SubscribeFunc func(subject string, handler func(msg *nats.Msg)) (func(), error)
+
+// PublishFunc publishes an event sent by a strategy bridge
+type PublishFunc func(subject string, data []byte) error
+
+// Client is an external client of the service, authenticated by its token
+type Client struct {
+	Name      string
+	Token     string
+	Subscribe []string // Subject patterns the client may receive, market data included
+	Publish   []string // Subject patterns the client may publish events to
+}
+
+// Options configures the server
+type Options struct {
+	Clients    []Client
+	Subjects   Subjects // DefaultSubjects when empty
+	SendBuffer int      // Messages a stream may fall behind by before it is ended, 256 when 0
+	MaxStreams int      // Streams open at once per client, unlimited when 0
+}
+
+// Server implements the SequexService
+type Server struct {
+	protobuf.UnimplementedSequexServiceServer
+
+	subscribe SubscribeFunc
+	publish   PublishFunc
+	subjects  *subjects
+	clients   []Client
+	buffer    int
+	max       int
+
+	mu      sync.Mutex
+	streams map[string]int // Open streams by client name
+}
+
+// NewServer creates the server of the SequexService
+func NewServer(subscribe SubscribeFunc, publish PublishFunc, opts Options) (*Server, error) {
+	if opts.Subjects == (Subjects{}) {
+		opts.Subjects = DefaultSubjects
+	}
+	subjects, err := newSubjects(opts.Subjects)
+	if err != nil {
+		return nil, err
+	}
+	names := make(map[string]bool, len(opts.Clients))
+	for _, c := range opts.Clients {
+		if c.Name == "" || c.Token == "" {
+			return nil, fmt.Errorf("clients need a name and a token")
+		}
+		if names[c.Name] {
+			return nil, fmt.Errorf("duplicate client %s", c.Name)
+		}
+		names[c.Name] = true
+		for _, pattern := range append(append([]string(nil), c.Subscribe...), c.Publish...) {
+			if err := validSubject(pattern); err != nil {
+				return nil, fmt.Errorf("client %s: %w", c.Name, err)
+			}
+		}
+	}
+	if opts.SendBuffer <= 0 {
+		opts.SendBuffer = 256
+	}
+	return &Server{
+		subscribe: subscribe,
+		publish:   publish,
+		subjects:  subjects,
+		clients:   opts.Clients,
+		buffer:    opts.SendBuffer,
+		max:       opts.MaxStreams,
+		streams:   make(map[string]int),
+	}, nil
+}
+
+// Register registers the service on a gRPC server
+func (s *Server) Register(g *grpc.Server) {
+	protobuf.RegisterSequexServiceServer(g, s)
+}
+
+// open authenticates the client of a stream from its authorization
+// metadata and counts the stream against its limit. The returned function
+// releases the stream.
+func (s *Server) open(ctx context.Context) (*Client, func(), error) {
+	token := ""
+	if md, ok := metadata.FromIncomingContext(ctx); ok {
+		if values := md.Get("authorization"); len(values) > 0 {
+			token = strings.TrimPrefix(values[0], "Bearer ")
+		}
+	}
+	var client *Client
+	for i := range s.clients {
+		if subtle.ConstantTimeCompare([]byte(s.clients[i].Token), []byte(token)) == 1 {
+			client = &s.clients[i]
+		}
+	}
+	if token == "" || client == nil {
+		return nil, nil, status.Error(codes.Unauthenticated, "invalid or missing bearer token")
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.max > 0 && s.streams[client.Name] >= s.max {
+		return nil, nil, status.Errorf(codes.ResourceExhausted, "limit of %d streams reached", s.max)
+	}
+	s.streams[client.Name]++
+	return client, func() {
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		if s.streams[client.Name]--; s.streams[client.Name] == 0 {
+			delete(s.streams, client.Name)
+		}
+	}, nil
+}
+
+// SubscribeTrades streams the trades of a symbol
+func (s *Server) SubscribeTrades(req *protobuf.MarketRequest, stream grpc.ServerStreamingServer[protobuf.Trade]) error {
+	return serveMarket(s, stream, func() (string, error) { return s.subjects.trades(req) }, func(msg *nats.Msg) ([]*protobuf.Trade, error) {
+		trades, err := sqx.UnmarshalTrades(msg.Data, msg.Header.Get(sqx.HeaderTradeBatch))
+		if err != nil {
+			return nil, err
+		}
+		out := make([]*protobuf.Trade, len(trades))
+		for i := range trades {
+			out[i] = trades[i].ToProtobuf()
+		}
+		return out, nil
+	})
+}
+
+// SubscribeKlines streams the klines of a symbol as they close
+func (s *Server) SubscribeKlines(req *protobuf.KlineRequest, stream grpc.ServerStreamingServer[protobuf.Kline]) error {
+	return serveMarket(s, stream, func() (string, error) { return s.subjects.klines(req) }, func(msg *nats.Msg) ([]*protobuf.Kline, error) {
+		var c candles.Candle
+		if err := json.Unmarshal(msg.Data, &c); err != nil {
+			return nil, err
+		}
+		return []*protobuf.Kline{{
+			Interval:    req.Interval,
+			OpenTime:    c.OpenTime,
+			Open:        c.Open,
+			High:        c.High,
+			Low:         c.Low,
+			Close:       c.Close,
+			Volume:      c.Volume,
+			QuoteVolume: c.QuoteVolume,
+			Trades:      c.Trades,
+		}}, nil
+	})
+}
+
+// SubscribeDepth streams the book snapshots and diffs of a symbol
+func (s *Server) SubscribeDepth(req *protobuf.MarketRequest, stream grpc.ServerStreamingServer[protobuf.DepthUpdate]) error {
+	return serveMarket(s, stream, func() (string, error) { return s.subjects.depth(req) }, func(msg *nats.Msg) ([]*protobuf.DepthUpdate, error) {
+		var e lob.Event
+		if err := json.Unmarshal(msg.Data, &e); err != nil {
+			return nil, err
+		}
+		if e.Type != lob.EventSnapshot && e.Type != lob.EventDepth {
+			return nil, nil
+		}
+		return []*protobuf.DepthUpdate{{
+			Snapshot: e.Type == lob.EventSnapshot,
+			Time:     e.Time,
+			Bids:     levels(e.Bids),
+			Asks:     levels(e.Asks),
+		}}, nil
+	})
+}
+
+func levels(in []lob.Level) []*protobuf.PriceLevel {
+	out := make([]*protobuf.PriceLevel, len(in))
+	for i, l := range in {
+		out[i] = &protobuf.PriceLevel{Price: l.Price, Quantity: l.Quantity}
+	}
+	return out
+}
+
+// serveMarket streams the messages of a market data subject, decoded, until
+// the client goes away or falls behind
+func serveMarket[T any](s *Server, stream grpc.ServerStreamingServer[T], subjectOf func() (string, error), decode func(*nats.Msg) ([]*T, error)) error {
+	client, release, err := s.open(stream.Context())
+	if err != nil {
+		return err
+	}
+	defer release()
+	subject, err := subjectOf()
+	if err != nil {
+		return status.Error(codes.InvalidArgument, err.Error())
+	}
+	if !covered(client.Subscribe, subject) {
+		return status.Errorf(codes.PermissionDenied, "%s may not receive %s", client.Name, subject)
+	}
+
+	q := newQueue[*T](s.buffer)
+	unsubscribe, err := s.subscribe(subject, func(msg *nats.Msg) {
+		values, err := decode(msg)
+		if err != nil {
+			logger.Log.Error().Err(err).Str("subject", msg.Subject).Msg("Failed to decode market data")
+			return
+		}
+		for _, v := range values {
+			q.push(v)
+		}
+	})
+	if err != nil {
+		return status.Errorf(codes.Unavailable, "failed to subscribe to %s: %v", subject, err)
+	}
+	defer unsubscribe()
+	logger.Log.Info().Str("client", client.Name).Str("subject", subject).Msg("Streaming market data")
+	return q.drain(stream.Context(), nil, stream.Send)
+}
+
+// StreamEvents bridges the events of a strategy: the client subscribes to
+// subjects and publishes events, each within its grants
+func (s *Server) StreamEvents(stream grpc.BidiStreamingServer[protobuf.EventRequest, protobuf.Event]) error {
+	client, release, err := s.open(stream.Context())
+	if err != nil {
+		return err
+	}
+	defer release()
+
+	q := newQueue[*protobuf.Event](s.buffer)
+	subs := make(map[string]func())
+	var mu sync.Mutex // Guards subs against the unsubscription of the stream ending
+	defer func() {
+		mu.Lock()
+		defer mu.Unlock()
+		for _, unsubscribe := range subs {
+			unsubscribe()
+		}
+		subs = nil
+	}()
+
+	received := make(chan error, 1)
+	go func() {
+		for {
+			req, err := stream.Recv()
+			if err == nil {
+				mu.Lock()
+				err = s.handle(client, req, subs, q)
+				mu.Unlock()
+			}
+			if err != nil {
+				received <- err
+				return
+			}
+		}
+	}()
+	logger.Log.Info().Str("client", client.Name).Msg("Streaming events")
+	return q.drain(stream.Context(), received, stream.Send)
+}
+
+// handle applies a request of an event stream
+func (s *Server) handle(client *Client, req *protobuf.EventRequest, subs map[string]func(), q *queue[*protobuf.Event]) error {
+	if subs == nil {
+		return status.Error(codes.Canceled, "stream ended")
+	}
+	switch action := req.Action.(type) {
+	case *protobuf.EventRequest_Subscribe:
+		subject := action.Subscribe
+		if err := validSubject(subject); err != nil {
+			return status.Error(codes.InvalidArgument, err.Error())
+		}
+		if !covered(client.Subscribe, subject) {
+			return status.Errorf(codes.PermissionDenied, "%s may not receive %s", client.Name, subject)
+		}
+		if _, ok := subs[subject]; ok {
+			return nil
+		}
+		unsubscribe, err := s.subscribe(subject, func(msg *nats.Msg) {
+			q.push(&protobuf.Event{Subject: msg.Subject, Data: msg.Data})
+		})
+		if err != nil {
+			return status.Errorf(codes.Unavailable, "failed to subscribe to %s: %v", subject, err)
+		}
+		subs[subject] = unsubscribe
+	case *protobuf.EventRequest_Unsubscribe:
+		if unsubscribe, ok := subs[action.Unsubscribe]; ok {
+			unsubscribe()
+			delete(subs, action.Unsubscribe)
+		}
+	case *protobuf.EventRequest_Publish:
+		subject := action.Publish.GetSubject()
+		if err := validSubject(subject); err != nil || strings.ContainsAny(subject, "*>") {
+			return status.Errorf(codes.InvalidArgument, "invalid subject %q", subject)
+		}
+		if !covered(client.Publish, subject) {
+			return status.Errorf(codes.PermissionDenied, "%s may not publish to %s", client.Name, subject)
+		}
+		if err := s.publish(subject, action.Publish.Data); err != nil {
+			return status.Errorf(codes.Unavailable, "failed to publish to %s: %v", subject, err)
+		}
+	default:
+		return status.Error(codes.InvalidArgument, "expected subscribe, unsubscribe or publish")
+	}
+	return nil
+}
+
+// validSubject checks a subject or pattern received from a client
+func validSubject(subject string) error {
+	if subject == "" || strings.ContainsAny(subject, " \t\r\n") {
+		return fmt.Errorf("invalid subject %q", subject)
+	}
+	for i, token := range strings.Split(subject, ".") {
+		if token == "" || (token == eventbus.WildcardTail && i != strings.Count(subject, ".")) {
+			return fmt.Errorf("invalid subject %q", subject)
+		}
+	}
+	return nil
+}
+
+// covered reports whether every subject matching pattern is matched by one
+// of the grants
+func covered(grants []string, pattern string) bool {
+	for _, grant := range grants {
+		if covers(grant, pattern) {
+			return true
+		}
+	}
+	return false
+}
+
+func covers(grant, pattern string) bool {
+	grantTokens := strings.Split(grant, ".")
+	patternTokens := strings.Split(pattern, ".")
+	for i, token := range grantTokens {
+		if token == eventbus.WildcardTail {
+			return len(patternTokens) > i
+		}
+		if i >= len(patternTokens) || patternTokens[i] == eventbus.WildcardTail {
+			return false
+		}
+		if token != eventbus.WildcardToken && (token != patternTokens[i]) {
+			return false
+		}
+	}
+	return len(grantTokens) == len(patternTokens)
+}
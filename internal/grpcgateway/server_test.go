@@ -0,0 +1,367 @@
+package grpcgateway
+
+import (
+	"context"
+	"net"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/BullionBear/sequex/internal/model/protobuf"
+	"github.com/BullionBear/sequex/internal/model/sqx"
+	"github.com/BullionBear/sequex/pkg/eventbus"
+	"github.com/nats-io/nats.go"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+)
+
+// fakeBus records subscriptions and publications and lets tests publish
+// to the subscriptions
+type fakeBus struct {
+	mu         sync.Mutex
+	handlers   map[string]func(msg *nats.Msg)
+	published  []string
+	subscribed chan string
+}
+
+func newFakeBus() *fakeBus {
+	return &fakeBus{handlers: make(map[string]func(msg *nats.Msg)), subscribed: make(chan string, 16)}
+}
+
+func (b *fakeBus) subscribe(subject string, handler func(msg *nats.Msg)) (func(), error) {
+	b.mu.Lock()
+	b.handlers[subject] = handler
+	b.mu.Unlock()
+	b.subscribed <- subject
+	return func() {
+		b.mu.Lock()
+		defer b.mu.Unlock()
+		delete(b.handlers, subject)
+	}, nil
+}
+
+func (b *fakeBus) publish(subject string, data []byte) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.published = append(b.published, subject+":"+string(data))
+	return nil
+}
+
+// deliver hands a message to every subscription matching its subject
+func (b *fakeBus) deliver(msg *nats.Msg) {
+	b.mu.Lock()
+	var handlers []func(msg *nats.Msg)
+	for pattern, handler := range b.handlers {
+		if eventbus.Match(pattern, msg.Subject) {
+			handlers = append(handlers, handler)
+		}
+	}
+	b.mu.Unlock()
+	for _, handler := range handlers {
+		handler(msg)
+	}
+}
+
+func (b *fakeBus) waitSubscribed(t *testing.T, subject string) {
+	t.Helper()
+	select {
+	case got := <-b.subscribed:
+		if got != subject {
+			t.Fatalf("subscribed to %s, expected %s", got, subject)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatalf("not subscribed to %s", subject)
+	}
+}
+
+var testClients = []Client{
+	{Name: "quant", Token: "secret", Subscribe: []string{"trade.>", "strategy.quant.>"}, Publish: []string{"strategy.quant.signal.*"}},
+	{Name: "viewer", Token: "viewer", Subscribe: []string{"kline.>"}},
+}
+
+func newTestClient(t *testing.T, bus *fakeBus, opts Options) protobuf.SequexServiceClient {
+	t.Helper()
+	server, err := NewServer(bus.subscribe, bus.publish, opts)
+	if err != nil {
+		t.Fatalf("NewServer() returned error: %v", err)
+	}
+	listener := bufconn.Listen(1 << 20)
+	g := grpc.NewServer()
+	server.Register(g)
+	go g.Serve(listener)
+	conn, err := grpc.NewClient("passthrough:///bufnet",
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) { return listener.DialContext(ctx) }),
+		grpc.WithTransportCredentials(insecure.NewCredentials()))
+	if err != nil {
+		t.Fatalf("failed to dial: %v", err)
+	}
+	t.Cleanup(func() {
+		conn.Close()
+		g.Stop()
+	})
+	return protobuf.NewSequexServiceClient(conn)
+}
+
+func withToken(t *testing.T, token string) context.Context {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	t.Cleanup(cancel)
+	return metadata.AppendToOutgoingContext(ctx, "authorization", "Bearer "+token)
+}
+
+var btcusdt = &protobuf.MarketRequest{
+	Exchange:   protobuf.Exchange_EXCHANGE_BINANCE,
+	Instrument: protobuf.Instrument_INSTRUMENT_SPOT,
+	Symbol:     &protobuf.Symbol{Base: "BTC", Quote: "USDT"},
+}
+
+func TestServer_SubscribeTrades(t *testing.T) {
+	bus := newFakeBus()
+	client := newTestClient(t, bus, Options{Clients: testClients})
+
+	stream, err := client.SubscribeTrades(withToken(t, "secret"), btcusdt)
+	if err != nil {
+		t.Fatalf("SubscribeTrades() returned error: %v", err)
+	}
+	bus.waitSubscribed(t, "trade.binance.spot.btcusdt")
+
+	trades := []sqx.Trade{
+		{Id: 1, Exchange: sqx.ExchangeBinance, InstrumentType: sqx.InstrumentTypeSpot, Symbol: sqx.NewSymbol("BTC", "USDT"), TakerSide: sqx.SideBuy, Price: 42000, Quantity: 0.5, Timestamp: 1700000000000},
+		{Id: 2, Exchange: sqx.ExchangeBinance, InstrumentType: sqx.InstrumentTypeSpot, Symbol: sqx.NewSymbol("BTC", "USDT"), TakerSide: sqx.SideSell, Price: 42001, Quantity: 0.1, Timestamp: 1700000000001},
+	}
+	single, err := trades[0].Marshal()
+	if err != nil {
+		t.Fatalf("Marshal() returned error: %v", err)
+	}
+	batch, err := sqx.MarshalTradeBatch(trades[1:])
+	if err != nil {
+		t.Fatalf("MarshalTradeBatch() returned error: %v", err)
+	}
+	bus.deliver(&nats.Msg{Subject: "trade.binance.spot.btcusdt", Data: single})
+	bus.deliver(&nats.Msg{Subject: "trade.binance.spot.btcusdt", Data: batch, Header: nats.Header{sqx.HeaderTradeBatch: {"1"}}})
+
+	for _, expected := range trades {
+		trade, err := stream.Recv()
+		if err != nil {
+			t.Fatalf("Recv() returned error: %v", err)
+		}
+		if trade.Id != expected.Id || trade.Price != expected.Price || trade.Symbol.GetBase() != "BTC" {
+			t.Errorf("received trade %v, expected %+v", trade, expected)
+		}
+	}
+}
+
+func TestServer_Refused(t *testing.T) {
+	bus := newFakeBus()
+	client := newTestClient(t, bus, Options{Clients: testClients, MaxStreams: 1})
+
+	tests := []struct {
+		name  string
+		token string
+		req   *protobuf.MarketRequest
+		code  codes.Code
+	}{
+		{name: "no token", token: "", req: btcusdt, code: codes.Unauthenticated},
+		{name: "wrong token", token: "guess", req: btcusdt, code: codes.Unauthenticated},
+		{name: "not granted", token: "viewer", req: btcusdt, code: codes.PermissionDenied},
+		{name: "no exchange", token: "secret", req: &protobuf.MarketRequest{Instrument: btcusdt.Instrument, Symbol: btcusdt.Symbol}, code: codes.InvalidArgument},
+		{name: "wildcard symbol", token: "secret", req: &protobuf.MarketRequest{Exchange: btcusdt.Exchange, Instrument: btcusdt.Instrument, Symbol: &protobuf.Symbol{Base: "*", Quote: "USDT"}}, code: codes.InvalidArgument},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			stream, err := client.SubscribeDepth(withToken(t, tt.token), tt.req)
+			if err == nil {
+				_, err = stream.Recv()
+			}
+			if status.Code(err) != tt.code {
+				t.Errorf("expected %s, got %v", tt.code, err)
+			}
+		})
+	}
+
+	t.Run("stream limit", func(t *testing.T) {
+		if _, err := client.SubscribeTrades(withToken(t, "secret"), btcusdt); err != nil {
+			t.Fatalf("SubscribeTrades() returned error: %v", err)
+		}
+		bus.waitSubscribed(t, "trade.binance.spot.btcusdt")
+		stream, err := client.SubscribeTrades(withToken(t, "secret"), btcusdt)
+		if err == nil {
+			_, err = stream.Recv()
+		}
+		if status.Code(err) != codes.ResourceExhausted {
+			t.Errorf("expected %s, got %v", codes.ResourceExhausted, err)
+		}
+	})
+}
+
+func TestServer_SubscribeKlinesAndDepth(t *testing.T) {
+	bus := newFakeBus()
+	clients := []Client{{Name: "all", Token: "secret", Subscribe: []string{">"}}}
+	client := newTestClient(t, bus, Options{Clients: clients})
+
+	klines, err := client.SubscribeKlines(withToken(t, "secret"), &protobuf.KlineRequest{Market: btcusdt, Interval: "1m"})
+	if err != nil {
+		t.Fatalf("SubscribeKlines() returned error: %v", err)
+	}
+	bus.waitSubscribed(t, "kline.binance.spot.BTC-USDT.1m")
+	bus.deliver(&nats.Msg{Subject: "kline.binance.spot.BTC-USDT.1m", Data: []byte(`{"open_time":1700000040000,"open":1,"high":3,"low":0.5,"close":2,"volume":10,"trades":4}`)})
+	kline, err := klines.Recv()
+	if err != nil {
+		t.Fatalf("Recv() returned error: %v", err)
+	}
+	if kline.Interval != "1m" || kline.OpenTime != 1700000040000 || kline.High != 3 || kline.Trades != 4 {
+		t.Errorf("unexpected kline %v", kline)
+	}
+
+	depth, err := client.SubscribeDepth(withToken(t, "secret"), btcusdt)
+	if err != nil {
+		t.Fatalf("SubscribeDepth() returned error: %v", err)
+	}
+	bus.waitSubscribed(t, "depth.binance.spot.btcusdt")
+	for _, data := range []string{
+		`{"type":"snapshot","time":1,"bids":[[42000.1,1.5]],"asks":[[42000.2,0.8]]}`,
+		`{"type":"trade","time":2,"price":42000.1,"quantity":0.4,"side":"SELL"}`,
+		`{"type":"depth","time":3,"bids":[[42000.1,0]]}`,
+	} {
+		bus.deliver(&nats.Msg{Subject: "depth.binance.spot.btcusdt", Data: []byte(data)})
+	}
+	snapshot, err := depth.Recv()
+	if err != nil {
+		t.Fatalf("Recv() returned error: %v", err)
+	}
+	if !snapshot.Snapshot || len(snapshot.Bids) != 1 || snapshot.Asks[0].GetPrice() != 42000.2 {
+		t.Errorf("unexpected snapshot %v", snapshot)
+	}
+	diff, err := depth.Recv()
+	if err != nil {
+		t.Fatalf("Recv() returned error: %v", err)
+	}
+	if diff.Snapshot || diff.Time != 3 || diff.Bids[0].GetQuantity() != 0 {
+		t.Errorf("unexpected diff %v, trades must be skipped", diff)
+	}
+
+	monthly, err := client.SubscribeKlines(withToken(t, "secret"), &protobuf.KlineRequest{Market: btcusdt, Interval: "1M"})
+	if err == nil {
+		_, err = monthly.Recv()
+	}
+	if status.Code(err) != codes.InvalidArgument {
+		t.Errorf("expected %s for an unsupported interval, got %v", codes.InvalidArgument, err)
+	}
+}
+
+func TestServer_StreamEvents(t *testing.T) {
+	bus := newFakeBus()
+	client := newTestClient(t, bus, Options{Clients: testClients})
+
+	stream, err := client.StreamEvents(withToken(t, "secret"))
+	if err != nil {
+		t.Fatalf("StreamEvents() returned error: %v", err)
+	}
+	if err := stream.Send(&protobuf.EventRequest{Action: &protobuf.EventRequest_Subscribe{Subscribe: "strategy.quant.params.*"}}); err != nil {
+		t.Fatalf("Send() returned error: %v", err)
+	}
+	bus.waitSubscribed(t, "strategy.quant.params.*")
+	bus.deliver(&nats.Msg{Subject: "strategy.quant.params.mm", Data: []byte(`{"spread":2}`)})
+	event, err := stream.Recv()
+	if err != nil {
+		t.Fatalf("Recv() returned error: %v", err)
+	}
+	if event.Subject != "strategy.quant.params.mm" || string(event.Data) != `{"spread":2}` {
+		t.Errorf("unexpected event %v", event)
+	}
+
+	publish := &protobuf.EventRequest{Action: &protobuf.EventRequest_Publish{Publish: &protobuf.Event{Subject: "strategy.quant.signal.btcusdt", Data: []byte("buy")}}}
+	if err := stream.Send(publish); err != nil {
+		t.Fatalf("Send() returned error: %v", err)
+	}
+
+	// A publish outside the grants ends the stream, after the allowed one
+	denied := &protobuf.EventRequest{Action: &protobuf.EventRequest_Publish{Publish: &protobuf.Event{Subject: "oms.intent.order", Data: []byte("{}")}}}
+	if err := stream.Send(denied); err != nil {
+		t.Fatalf("Send() returned error: %v", err)
+	}
+	if _, err := stream.Recv(); status.Code(err) != codes.PermissionDenied {
+		t.Fatalf("expected %s, got %v", codes.PermissionDenied, err)
+	}
+	bus.mu.Lock()
+	defer bus.mu.Unlock()
+	if strings.Join(bus.published, ",") != "strategy.quant.signal.btcusdt:buy" {
+		t.Errorf("published %v", bus.published)
+	}
+	if len(bus.handlers) != 0 {
+		t.Errorf("subscriptions left after the stream ended: %v", bus.handlers)
+	}
+}
+
+func TestServer_StreamEventsRefusesUngrantedSubscriptions(t *testing.T) {
+	tests := []struct {
+		name    string
+		subject string
+		code    codes.Code
+	}{
+		{name: "wider than the grant", subject: "strategy.>", code: codes.PermissionDenied},
+		{name: "wildcard over the grant", subject: "*.quant.params", code: codes.PermissionDenied},
+		{name: "other subject", subject: "oms.execution.>", code: codes.PermissionDenied},
+		{name: "tail not last", subject: "strategy.>.quant", code: codes.InvalidArgument},
+		{name: "empty token", subject: "strategy..quant", code: codes.InvalidArgument},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			client := newTestClient(t, newFakeBus(), Options{Clients: testClients})
+			stream, err := client.StreamEvents(withToken(t, "secret"))
+			if err != nil {
+				t.Fatalf("StreamEvents() returned error: %v", err)
+			}
+			if err := stream.Send(&protobuf.EventRequest{Action: &protobuf.EventRequest_Subscribe{Subscribe: tt.subject}}); err != nil {
+				t.Fatalf("Send() returned error: %v", err)
+			}
+			if _, err := stream.Recv(); status.Code(err) != tt.code {
+				t.Errorf("expected %s, got %v", tt.code, err)
+			}
+		})
+	}
+}
+
+func TestQueue_EndsSlowConsumers(t *testing.T) {
+	q := newQueue[int](2)
+	for i := 0; i < 3; i++ {
+		q.push(i)
+	}
+	var sent []int
+	err := q.drain(context.Background(), nil, func(v int) error {
+		sent = append(sent, v)
+		return nil
+	})
+	if status.Code(err) != codes.ResourceExhausted {
+		t.Fatalf("expected %s, got %v", codes.ResourceExhausted, err)
+	}
+	if len(sent) > 2 {
+		t.Errorf("sent %v, more than were queued", sent)
+	}
+}
+
+func TestNewServer_RejectsInvalidOptions(t *testing.T) {
+	tests := []struct {
+		name     string
+		opts     Options
+		errorMsg string
+	}{
+		{name: "client without token", opts: Options{Clients: []Client{{Name: "quant"}}}, errorMsg: "name and a token"},
+		{name: "duplicate client", opts: Options{Clients: []Client{{Name: "quant", Token: "a"}, {Name: "quant", Token: "b"}}}, errorMsg: "duplicate"},
+		{name: "invalid grant", opts: Options{Clients: []Client{{Name: "quant", Token: "a", Subscribe: []string{"trade.>.x"}}}}, errorMsg: "invalid subject"},
+		{name: "unknown placeholder", opts: Options{Subjects: Subjects{Trades: "trade.{venue}", Klines: "k", Depth: "d"}}, errorMsg: "{venue}"},
+		{name: "interval outside klines", opts: Options{Subjects: Subjects{Trades: "trade.{interval}", Klines: "k", Depth: "d"}}, errorMsg: "{interval}"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := NewServer(nil, nil, tt.opts)
+			if err == nil || !strings.Contains(err.Error(), tt.errorMsg) {
+				t.Errorf("expected error containing %q, got %v", tt.errorMsg, err)
+			}
+		})
+	}
+}
@@ -0,0 +1,104 @@
+package grpcgateway
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/BullionBear/sequex/internal/model/protobuf"
+	"github.com/BullionBear/sequex/internal/model/sqx"
+	"github.com/BullionBear/sequex/pkg/candles"
+)
+
+// Subjects are the templates of the market data subjects, with the
+// {exchange}, {instrument}, {symbol}, {base} and {quote} placeholders, lower
+// cased, {pair}, the BASE-QUOTE symbol, and for klines {interval}
+type Subjects struct {
+	Trades string
+	Klines string
+	Depth  string
+}
+
+// DefaultSubjects are the subjects the feeds and the retention compaction
+// publish to
+var DefaultSubjects = Subjects{
+	Trades: "trade.{exchange}.{instrument}.{symbol}",
+	Klines: "kline.{exchange}.{instrument}.{pair}.{interval}",
+	Depth:  "depth.{exchange}.{instrument}.{symbol}",
+}
+
+// subjects resolves the subjects of market data requests
+type subjects struct {
+	templates Subjects
+}
+
+func newSubjects(templates Subjects) (*subjects, error) {
+	for name, template := range map[string]string{"trades": templates.Trades, "klines": templates.Klines, "depth": templates.Depth} {
+		if template == "" {
+			return nil, fmt.Errorf("%s subject template cannot be empty", name)
+		}
+		rest := template
+		for {
+			open := strings.IndexByte(rest, '{')
+			if open < 0 {
+				break
+			}
+			end := strings.IndexByte(rest[open:], '}')
+			if end < 0 {
+				return nil, fmt.Errorf("subject template %q: unterminated {", template)
+			}
+			switch placeholder := rest[open+1 : open+end]; placeholder {
+			case "exchange", "instrument", "symbol", "base", "quote", "pair":
+			case "interval":
+				if name != "klines" {
+					return nil, fmt.Errorf("subject template %q: {interval} is only known to klines", template)
+				}
+			default:
+				return nil, fmt.Errorf("subject template %q: unknown placeholder {%s}", template, placeholder)
+			}
+			rest = rest[open+end+1:]
+		}
+	}
+	return &subjects{templates: templates}, nil
+}
+
+func (s *subjects) trades(req *protobuf.MarketRequest) (string, error) {
+	return expand(s.templates.Trades, req, "")
+}
+
+func (s *subjects) klines(req *protobuf.KlineRequest) (string, error) {
+	if _, err := candles.ParseInterval(req.GetInterval()); err != nil {
+		return "", err
+	}
+	return expand(s.templates.Klines, req.GetMarket(), req.GetInterval())
+}
+
+func (s *subjects) depth(req *protobuf.MarketRequest) (string, error) {
+	return expand(s.templates.Depth, req, "")
+}
+
+// expand fills a template with the market of a request
+func expand(template string, req *protobuf.MarketRequest, interval string) (string, error) {
+	exchange := sqx.NewExchangeFromProtobuf(req.GetExchange())
+	if exchange == sqx.ExchangeUnknown {
+		return "", fmt.Errorf("exchange is required")
+	}
+	instrument := sqx.NewInstrumentTypeFromProtobuf(req.GetInstrument())
+	if instrument == sqx.InstrumentTypeUnknown {
+		return "", fmt.Errorf("instrument is required")
+	}
+	symbol := sqx.NewSymbol(req.GetSymbol().GetBase(), req.GetSymbol().GetQuote())
+	for _, asset := range []string{symbol.Base, symbol.Quote} {
+		if asset == "" || strings.ContainsAny(asset, ".*> \t\r\n-") {
+			return "", fmt.Errorf("invalid symbol %s", symbol)
+		}
+	}
+	return strings.NewReplacer(
+		"{exchange}", strings.ToLower(exchange.String()),
+		"{instrument}", strings.ToLower(instrument.String()),
+		"{symbol}", strings.ToLower(symbol.Base+symbol.Quote),
+		"{base}", strings.ToLower(symbol.Base),
+		"{quote}", strings.ToLower(symbol.Quote),
+		"{pair}", symbol.String(),
+		"{interval}", interval,
+	).Replace(template), nil
+}
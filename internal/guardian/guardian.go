@@ -0,0 +1,562 @@
+// Package guardian pauses strategies losing too much. It follows the
+// equity of each strategy, its cash flows and positions marked to market,
+// and pauses a strategy once its drawdown from the peak equity within a
+// rolling window breaches its threshold: the working orders of the strategy
+// are canceled and its new orders rejected until it is resumed by hand,
+// with a recorded reason.
+package guardian
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/BullionBear/sequex/internal/model/sqx"
+	"github.com/BullionBear/sequex/internal/pms"
+)
+
+// DefaultCurrency is the currency of equities and thresholds when none is configured
+const DefaultCurrency = "USDT"
+
+// DefaultWindow is the rolling window of the peak equity when none is configured
+const DefaultWindow = 24 * time.Hour
+
+// auditSize is the number of events a Guardian keeps in memory, the journal
+// keeping all of them
+const auditSize = 100
+
+var (
+	ErrPaused    = errors.New("strategy paused")
+	ErrNotPaused = errors.New("strategy not paused")
+	ErrNoReason  = errors.New("a reason is required")
+)
+
+// Actions of events
+const (
+	ActionPause  = "pause"
+	ActionResume = "resume"
+)
+
+// Thresholds bound the drawdown of a strategy
+type Thresholds struct {
+	MaxDrawdown float64       // From the peak equity within the window, in the currency. 0 never pauses.
+	Window      time.Duration // Of the peak equity, DefaultWindow when 0
+}
+
+// Event is an entry of the audit trail: a strategy paused or resumed
+type Event struct {
+	Strategy  string    `json:"strategy"`
+	Action    string    `json:"action"`
+	Automatic bool      `json:"automatic"`          // Paused on a breach rather than by hand
+	Drawdown  float64   `json:"drawdown,omitempty"` // When the event happened
+	By        string    `json:"by"`
+	Reason    string    `json:"reason"`
+	At        time.Time `json:"at"`
+	Canceled  int       `json:"canceled,omitempty"` // Working orders canceled by a pause
+}
+
+// Status is the state of a strategy
+type Status struct {
+	Strategy    string    `json:"strategy"`
+	Equity      float64   `json:"equity"`       // Cash flows and positions marked to market since the start of the service
+	Peak        float64   `json:"peak"`         // Within the window
+	Drawdown    float64   `json:"drawdown"`     // Peak less equity
+	MaxDrawdown float64   `json:"max_drawdown"` // Threshold, 0 when none
+	Working     int       `json:"working"`      // Working orders
+	Paused      *Event    `json:"paused,omitempty"`
+	UpdatedAt   time.Time `json:"updated_at"`
+	Error       string    `json:"error,omitempty"` // Why the equity could not be valued or the pause journaled
+}
+
+// Options configures a Guardian
+type Options struct {
+	Currency   string                // Of equities and thresholds, DefaultCurrency when empty
+	Default    Thresholds            // Of the strategies without their own
+	Strategies map[string]Thresholds // By strategy
+	Prices     pms.Converter         // Marks positions and converts cash, e.g. fx.Rates; fill prices are used when nil or without rate
+	Journal    string                // JSON lines file of the events, replayed on open so paused strategies stay paused. In memory only when empty.
+}
+
+// sample is the equity of a strategy at a point in time
+type sample struct {
+	at     time.Time
+	equity float64
+}
+
+// position is the net quantity of a strategy in a symbol
+type position struct {
+	quantity float64 // Signed, negative when short
+	price    float64 // Of the last fill
+}
+
+// strategy is what the guardian follows of a strategy
+type strategy struct {
+	cash      map[string]float64 // By asset
+	positions map[sqx.Symbol]*position
+	orders    map[string]sqx.ExecutionReport // Last report of the working orders, by client order id
+	samples   []sample                       // Equities within the window, oldest first
+	paused    *Event
+	err       error
+}
+
+// Guardian follows the equity of strategies and pauses those breaching
+// their thresholds. It is safe for concurrent use.
+type Guardian struct {
+	opts Options
+
+	mu         sync.Mutex
+	strategies map[string]*strategy
+	events     []Event
+	journal    *os.File
+	onPause    []func(Event, []sqx.CancelIntent)
+	onEvent    []func(Event)
+}
+
+// Open creates a guardian, replaying the events of the journal so the
+// strategies paused before a restart stay paused
+func Open(opts Options) (*Guardian, error) {
+	if opts.Currency == "" {
+		opts.Currency = DefaultCurrency
+	}
+	g := &Guardian{opts: opts, strategies: make(map[string]*strategy)}
+	if opts.Journal == "" {
+		return g, nil
+	}
+	f, err := os.OpenFile(opts.Journal, os.O_CREATE|os.O_RDWR|os.O_APPEND, 0o644)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open journal %s: %w", opts.Journal, err)
+	}
+	size, err := g.replay(f)
+	if err == nil {
+		// Drop a truncated last line so the next event starts on its own line
+		err = f.Truncate(size)
+	}
+	if err != nil {
+		f.Close()
+		return nil, fmt.Errorf("failed to replay journal %s: %w", opts.Journal, err)
+	}
+	g.journal = f
+	return g, nil
+}
+
+// replay applies the journaled events and returns the size of the lines
+// read. A truncated last line, left by a crash during a write, is skipped.
+func (g *Guardian) replay(r io.Reader) (int64, error) {
+	reader := bufio.NewReader(r)
+	var size int64
+	for line := 1; ; line++ {
+		data, err := reader.ReadBytes('\n')
+		if err == io.EOF {
+			return size, nil
+		}
+		if err != nil {
+			return size, err
+		}
+		var e Event
+		if err := json.Unmarshal(data, &e); err != nil {
+			return size, fmt.Errorf("line %d: %w", line, err)
+		}
+		g.apply(e)
+		size += int64(len(data))
+	}
+}
+
+// apply applies an event to the state of its strategy and the audit trail
+func (g *Guardian) apply(e Event) {
+	s := g.strategy(e.Strategy)
+	if e.Action == ActionPause {
+		s.paused = &e
+	} else {
+		s.paused = nil
+		// The drawdown starts over from the equity at resumption
+		s.samples = nil
+	}
+	g.events = append(g.events, e)
+	if len(g.events) > auditSize {
+		g.events = g.events[len(g.events)-auditSize:]
+	}
+}
+
+// Close closes the journal
+func (g *Guardian) Close() error {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	if g.journal == nil {
+		return nil
+	}
+	err := g.journal.Close()
+	g.journal = nil
+	return err
+}
+
+// Currency returns the currency of equities and thresholds
+func (g *Guardian) Currency() string {
+	return g.opts.Currency
+}
+
+// OnPause calls fn when a strategy is paused with the cancels of its
+// working orders, which fn sends
+func (g *Guardian) OnPause(fn func(Event, []sqx.CancelIntent)) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	g.onPause = append(g.onPause, fn)
+}
+
+// OnEvent calls fn with every event, e.g. to log it
+func (g *Guardian) OnEvent(fn func(Event)) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	g.onEvent = append(g.onEvent, fn)
+}
+
+func (g *Guardian) strategy(name string) *strategy {
+	s, ok := g.strategies[name]
+	if !ok {
+		s = &strategy{
+			cash:      make(map[string]float64),
+			positions: make(map[sqx.Symbol]*position),
+			orders:    make(map[string]sqx.ExecutionReport),
+		}
+		g.strategies[name] = s
+	}
+	return s
+}
+
+func (g *Guardian) thresholds(name string) Thresholds {
+	t, ok := g.opts.Strategies[name]
+	if !ok {
+		t = g.opts.Default
+	}
+	if t.Window <= 0 {
+		t.Window = DefaultWindow
+	}
+	return t
+}
+
+// Check rejects the orders of paused strategies
+func (g *Guardian) Check(intent sqx.OrderIntent) error {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	if s, ok := g.strategies[intent.Strategy]; ok && s.paused != nil {
+		return fmt.Errorf("%w: %s since %s: %s", ErrPaused, intent.Strategy, s.paused.At.Format(time.RFC3339), s.paused.Reason)
+	}
+	return nil
+}
+
+// OnExecutionReport follows the working orders, cash and positions of the
+// strategy of a report, pausing it when a fill breaches its threshold
+func (g *Guardian) OnExecutionReport(report sqx.ExecutionReport) {
+	g.mu.Lock()
+	s := g.strategy(report.Strategy)
+	if report.Status.IsFinal() {
+		delete(s.orders, report.ClientOrderId)
+	} else {
+		s.orders[report.ClientOrderId] = report
+	}
+	if !report.IsFill() {
+		g.mu.Unlock()
+		return
+	}
+	p, ok := s.positions[report.Symbol]
+	if !ok {
+		p = &position{}
+		s.positions[report.Symbol] = p
+	}
+	notional := report.LastQuantity * report.LastPrice
+	if report.Side == sqx.SideSell {
+		p.quantity -= report.LastQuantity
+		s.cash[report.Symbol.Quote] += notional
+	} else {
+		p.quantity += report.LastQuantity
+		s.cash[report.Symbol.Quote] -= notional
+	}
+	p.price = report.LastPrice
+	if report.CommissionAsset != "" {
+		s.cash[report.CommissionAsset] -= report.Commission
+	}
+	pause := g.evaluate(report.Strategy, s, time.Now())
+	g.mu.Unlock()
+	g.notify(pause)
+}
+
+// Evaluate marks the positions of every strategy to market and pauses
+// those breaching their thresholds. It returns the pauses.
+func (g *Guardian) Evaluate(now time.Time) []Event {
+	g.mu.Lock()
+	var pauses []*pause
+	for name, s := range g.strategies {
+		if p := g.evaluate(name, s, now); p != nil {
+			pauses = append(pauses, p)
+		}
+	}
+	g.mu.Unlock()
+	events := make([]Event, 0, len(pauses))
+	for _, p := range pauses {
+		g.notify(p)
+		events = append(events, p.event)
+	}
+	return events
+}
+
+// Run evaluates the strategies every interval until ctx is done, so
+// positions losing value pause their strategy between fills
+func (g *Guardian) Run(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case now := <-ticker.C:
+			g.Evaluate(now)
+		}
+	}
+}
+
+// pause is an automatic pause to notify once the lock is released
+type pause struct {
+	event   Event
+	cancels []sqx.CancelIntent
+}
+
+// evaluate samples the equity of a strategy and pauses it on a breach
+func (g *Guardian) evaluate(name string, s *strategy, now time.Time) *pause {
+	equity, err := g.equity(s)
+	s.err = err
+	if err != nil {
+		return nil
+	}
+	t := g.thresholds(name)
+	s.samples = append(s.samples, sample{at: now, equity: equity})
+	// Samples older than the window are dropped, the latest always kept
+	cut := 0
+	for cut < len(s.samples)-1 && now.Sub(s.samples[cut].at) > t.Window {
+		cut++
+	}
+	s.samples = s.samples[cut:]
+	if s.paused != nil || t.MaxDrawdown <= 0 {
+		return nil
+	}
+	drawdown := peak(s.samples) - equity
+	if drawdown < t.MaxDrawdown {
+		return nil
+	}
+	e := Event{
+		Strategy:  name,
+		Action:    ActionPause,
+		Automatic: true,
+		Drawdown:  drawdown,
+		By:        "guardian",
+		Reason:    fmt.Sprintf("drawdown of %.2f %s over %s breached the limit of %.2f", drawdown, g.opts.Currency, t.Window, t.MaxDrawdown),
+		At:        now.UTC(),
+	}
+	p := &pause{cancels: cancels(name, s, now)}
+	e.Canceled = len(p.cancels)
+	p.event = e
+	// The strategy is paused even when the event cannot be journaled, the
+	// failure showing in its status
+	s.err = g.commit(e)
+	return p
+}
+
+// notify calls the listeners of a pause
+func (g *Guardian) notify(p *pause) {
+	if p == nil {
+		return
+	}
+	g.mu.Lock()
+	onPause, onEvent := g.onPause, g.onEvent
+	g.mu.Unlock()
+	for _, fn := range onEvent {
+		fn(p.event)
+	}
+	for _, fn := range onPause {
+		fn(p.event, p.cancels)
+	}
+}
+
+// commit journals and applies an event. The event applies even when it
+// cannot be journaled: a strategy must be paused whether or not the disk
+// is full.
+func (g *Guardian) commit(e Event) error {
+	err := g.write(e)
+	g.apply(e)
+	return err
+}
+
+// write journals an event
+func (g *Guardian) write(e Event) error {
+	if g.journal == nil {
+		return nil
+	}
+	data, err := json.Marshal(e)
+	if err == nil {
+		_, err = g.journal.Write(append(data, '\n'))
+	}
+	if err != nil {
+		return fmt.Errorf("failed to journal guardian event: %w", err)
+	}
+	return nil
+}
+
+// cancels returns the cancels of the working orders of a strategy
+func cancels(name string, s *strategy, now time.Time) []sqx.CancelIntent {
+	list := make([]sqx.CancelIntent, 0, len(s.orders))
+	for id, o := range s.orders {
+		list = append(list, sqx.CancelIntent{
+			ClientOrderId:     "guardian-" + id,
+			OrigClientOrderId: id,
+			Strategy:          name,
+			Exchange:          o.Exchange,
+			InstrumentType:    o.InstrumentType,
+			Symbol:            o.Symbol,
+			Timestamp:         now.UnixMilli(),
+		})
+	}
+	sort.Slice(list, func(i, j int) bool { return list[i].OrigClientOrderId < list[j].OrigClientOrderId })
+	return list
+}
+
+func peak(samples []sample) float64 {
+	max := samples[0].equity
+	for _, s := range samples[1:] {
+		if s.equity > max {
+			max = s.equity
+		}
+	}
+	return max
+}
+
+// equity values the cash and positions of a strategy in the currency
+func (g *Guardian) equity(s *strategy) (float64, error) {
+	var equity float64
+	for asset, amount := range s.cash {
+		if amount == 0 {
+			continue
+		}
+		rate, err := g.rate(asset)
+		if err != nil {
+			return 0, err
+		}
+		equity += amount * rate
+	}
+	for symbol, p := range s.positions {
+		if p.quantity == 0 {
+			continue
+		}
+		mark, err := g.mark(symbol, p.price)
+		if err != nil {
+			return 0, err
+		}
+		equity += p.quantity * mark
+	}
+	return equity, nil
+}
+
+func (g *Guardian) rate(asset string) (float64, error) {
+	if asset == g.opts.Currency {
+		return 1, nil
+	}
+	if g.opts.Prices == nil {
+		return 0, fmt.Errorf("no rate from %s to %s", asset, g.opts.Currency)
+	}
+	return g.opts.Prices.Rate(asset, g.opts.Currency)
+}
+
+// mark returns the price of one unit of the base of symbol in the currency,
+// from the rates when available, else from the last fill price
+func (g *Guardian) mark(symbol sqx.Symbol, price float64) (float64, error) {
+	if rate, err := g.rate(symbol.Base); err == nil {
+		return rate, nil
+	}
+	rate, err := g.rate(symbol.Quote)
+	if err != nil {
+		return 0, fmt.Errorf("no price of %s in %s", symbol, g.opts.Currency)
+	}
+	return price * rate, nil
+}
+
+// Pause pauses a strategy by hand, canceling its working orders
+func (g *Guardian) Pause(name, by, reason string) (Event, error) {
+	if by == "" || reason == "" {
+		return Event{}, ErrNoReason
+	}
+	g.mu.Lock()
+	s := g.strategy(name)
+	if s.paused != nil {
+		g.mu.Unlock()
+		return Event{}, fmt.Errorf("%w: %s", ErrPaused, name)
+	}
+	now := time.Now()
+	p := &pause{cancels: cancels(name, s, now)}
+	p.event = Event{Strategy: name, Action: ActionPause, By: by, Reason: reason, At: now.UTC(), Canceled: len(p.cancels)}
+	if len(s.samples) > 0 {
+		p.event.Drawdown = peak(s.samples) - s.samples[len(s.samples)-1].equity
+	}
+	err := g.commit(p.event)
+	g.mu.Unlock()
+	g.notify(p)
+	return p.event, err
+}
+
+// Resume lets a paused strategy place orders again. Its drawdown starts
+// over from its current equity.
+func (g *Guardian) Resume(name, by, reason string) (Event, error) {
+	if by == "" || reason == "" {
+		return Event{}, ErrNoReason
+	}
+	g.mu.Lock()
+	s, ok := g.strategies[name]
+	if !ok || s.paused == nil {
+		g.mu.Unlock()
+		return Event{}, fmt.Errorf("%w: %s", ErrNotPaused, name)
+	}
+	e := Event{Strategy: name, Action: ActionResume, By: by, Reason: reason, At: time.Now().UTC()}
+	// Unlike a pause, a resumption is not urgent and needs its audit trail
+	if err := g.write(e); err != nil {
+		g.mu.Unlock()
+		return Event{}, err
+	}
+	g.apply(e)
+	listeners := g.onEvent
+	g.mu.Unlock()
+	for _, fn := range listeners {
+		fn(e)
+	}
+	return e, nil
+}
+
+// History returns the latest events, oldest first
+func (g *Guardian) History() []Event {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	return append([]Event(nil), g.events...)
+}
+
+// Statuses returns the state of every strategy, ordered by name
+func (g *Guardian) Statuses() []Status {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	list := make([]Status, 0, len(g.strategies))
+	for name, s := range g.strategies {
+		st := Status{Strategy: name, MaxDrawdown: g.thresholds(name).MaxDrawdown, Working: len(s.orders), Paused: s.paused}
+		if n := len(s.samples); n > 0 {
+			st.Equity, st.UpdatedAt = s.samples[n-1].equity, s.samples[n-1].at
+			st.Peak = peak(s.samples)
+			st.Drawdown = st.Peak - st.Equity
+		}
+		if s.err != nil {
+			st.Error = s.err.Error()
+		}
+		list = append(list, st)
+	}
+	sort.Slice(list, func(i, j int) bool { return list[i].Strategy < list[j].Strategy })
+	return list
+}
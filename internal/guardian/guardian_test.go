@@ -0,0 +1,145 @@
+package guardian
+
+import (
+	"errors"
+	"fmt"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/BullionBear/sequex/internal/model/sqx"
+)
+
+// fakeRates prices assets in USDT, mutable to move the marks
+type fakeRates map[string]float64
+
+func (r fakeRates) Rate(from, to string) (float64, error) {
+	if from == to {
+		return 1, nil
+	}
+	if rate, ok := r[from+"-"+to]; ok {
+		return rate, nil
+	}
+	return 0, fmt.Errorf("no rate from %s to %s", from, to)
+}
+
+var btc = sqx.NewSymbol("BTC", "USDT")
+
+func report(id string, side sqx.Side, quantity, price float64, status sqx.OrderStatus) sqx.ExecutionReport {
+	return sqx.ExecutionReport{ClientOrderId: id, Strategy: "grid", Exchange: sqx.ExchangeBinance, InstrumentType: sqx.InstrumentTypeSpot, Symbol: btc, Side: side, Status: status, Quantity: quantity, LastQuantity: quantity, LastPrice: price}
+}
+
+func TestPausesOnDrawdown(t *testing.T) {
+	rates := fakeRates{"BTC-USDT": 50000}
+	g, err := Open(Options{Default: Thresholds{MaxDrawdown: 1000}, Prices: rates})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var paused []Event
+	var canceled []sqx.CancelIntent
+	g.OnPause(func(e Event, cancels []sqx.CancelIntent) {
+		paused = append(paused, e)
+		canceled = append(canceled, cancels...)
+	})
+
+	g.OnExecutionReport(report("1", sqx.SideBuy, 1, 50000, sqx.OrderStatusFilled))
+	working := report("2", sqx.SideSell, 1, 52000, sqx.OrderStatusNew)
+	working.LastQuantity = 0
+	g.OnExecutionReport(working)
+	now := time.Now()
+	rates["BTC-USDT"] = 51000
+	g.Evaluate(now.Add(time.Second))
+	rates["BTC-USDT"] = 50100
+	if events := g.Evaluate(now.Add(2 * time.Second)); len(events) != 0 {
+		t.Fatalf("paused within the limit: %+v", events)
+	}
+	if err := g.Check(sqx.OrderIntent{Strategy: "grid"}); err != nil {
+		t.Errorf("active strategy rejected: %v", err)
+	}
+
+	// 1000 below the peak of 1000 reached at 51000
+	rates["BTC-USDT"] = 50000
+	events := g.Evaluate(now.Add(3 * time.Second))
+	if len(events) != 1 || !events[0].Automatic || events[0].Drawdown != 1000 || events[0].Canceled != 1 {
+		t.Fatalf("pause on breach: %+v", events)
+	}
+	if len(paused) != 1 || len(canceled) != 1 || canceled[0].OrigClientOrderId != "2" || canceled[0].Symbol != btc || canceled[0].Exchange != sqx.ExchangeBinance {
+		t.Errorf("working orders not canceled: %+v", canceled)
+	}
+	if err := g.Check(sqx.OrderIntent{Strategy: "grid"}); !errors.Is(err, ErrPaused) {
+		t.Errorf("paused strategy accepted: %v", err)
+	}
+	if err := g.Check(sqx.OrderIntent{Strategy: "arb"}); err != nil {
+		t.Errorf("other strategy rejected: %v", err)
+	}
+	// A paused strategy is paused once
+	rates["BTC-USDT"] = 40000
+	if events := g.Evaluate(now.Add(4 * time.Second)); len(events) != 0 {
+		t.Errorf("paused twice: %+v", events)
+	}
+}
+
+func TestWindowForgetsOldPeaks(t *testing.T) {
+	rates := fakeRates{"BTC-USDT": 50000}
+	g, err := Open(Options{Default: Thresholds{MaxDrawdown: 1000, Window: time.Minute}, Prices: rates})
+	if err != nil {
+		t.Fatal(err)
+	}
+	g.OnExecutionReport(report("1", sqx.SideBuy, 1, 50000, sqx.OrderStatusFilled))
+	now := time.Now()
+	rates["BTC-USDT"] = 52000
+	g.Evaluate(now)
+	rates["BTC-USDT"] = 51500
+	g.Evaluate(now.Add(30 * time.Second))
+	// The peak of 2000 left the window, the drawdown is from 1500
+	rates["BTC-USDT"] = 51000
+	if events := g.Evaluate(now.Add(90 * time.Second)); len(events) != 0 {
+		t.Errorf("paused on a peak out of the window: %+v", events)
+	}
+	st := g.Statuses()
+	if len(st) != 1 || st[0].Peak != 1500 || st[0].Drawdown != 500 {
+		t.Errorf("status: %+v", st)
+	}
+}
+
+func TestResumeRequiresReasonAndSurvivesRestart(t *testing.T) {
+	journal := filepath.Join(t.TempDir(), "guardian.jsonl")
+	g, err := Open(Options{Journal: journal})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := g.Pause("grid", "ops", ""); !errors.Is(err, ErrNoReason) {
+		t.Errorf("pause without reason: %v", err)
+	}
+	if _, err := g.Pause("grid", "ops", "venue incident"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := g.Resume("arb", "ops", "never paused"); !errors.Is(err, ErrNotPaused) {
+		t.Errorf("resume of an active strategy: %v", err)
+	}
+	g.Close()
+
+	// Strategies paused before a restart stay paused
+	g, err = Open(Options{Journal: journal})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer g.Close()
+	if err := g.Check(sqx.OrderIntent{Strategy: "grid"}); !errors.Is(err, ErrPaused) {
+		t.Fatalf("pause lost on restart: %v", err)
+	}
+	if _, err := g.Resume("grid", "ops", ""); !errors.Is(err, ErrNoReason) {
+		t.Errorf("resume without reason: %v", err)
+	}
+	e, err := g.Resume("grid", "ops", "incident closed")
+	if err != nil || e.Action != ActionResume || e.By != "ops" {
+		t.Fatalf("resume: %+v, %v", e, err)
+	}
+	if err := g.Check(sqx.OrderIntent{Strategy: "grid"}); err != nil {
+		t.Errorf("resumed strategy rejected: %v", err)
+	}
+	history := g.History()
+	if len(history) != 2 || history[0].Reason != "venue incident" || history[1].Reason != "incident closed" {
+		t.Errorf("history: %+v", history)
+	}
+}
@@ -0,0 +1,85 @@
+package guardian
+
+import (
+	"context"
+
+	"github.com/BullionBear/sequex/pkg/eventbus"
+	"github.com/nats-io/nats.go"
+)
+
+// DefaultSubject is the prefix the guardian RPCs of a service are served
+// under when none is configured
+const DefaultSubject = "guardian"
+
+// PauseRequest pauses a strategy by hand
+type PauseRequest struct {
+	Strategy string `json:"strategy"`
+	By       string `json:"by"`     // Who pauses the strategy, required for the audit trail
+	Reason   string `json:"reason"` // Required
+}
+
+// ResumeRequest resumes a paused strategy
+type ResumeRequest struct {
+	Strategy string `json:"strategy"`
+	By       string `json:"by"`     // Who resumes the strategy, required for the audit trail
+	Reason   string `json:"reason"` // Required
+}
+
+// ListRequest asks for the state of the strategies and the audit trail
+type ListRequest struct{}
+
+// List is the state of a guardian
+type List struct {
+	Currency   string   `json:"currency"`
+	Strategies []Status `json:"strategies"`
+	Events     []Event  `json:"events"` // Latest pauses and resumptions, oldest first
+}
+
+// PauseEndpoint is the endpoint strategies are paused on, prefix.pause
+func PauseEndpoint(prefix string) eventbus.Endpoint[PauseRequest, Event] {
+	return eventbus.NewCommand[PauseRequest, Event](prefix+".pause", eventbus.JSON)
+}
+
+// ResumeEndpoint is the endpoint strategies are resumed on, prefix.resume
+func ResumeEndpoint(prefix string) eventbus.Endpoint[ResumeRequest, Event] {
+	return eventbus.NewCommand[ResumeRequest, Event](prefix+".resume", eventbus.JSON)
+}
+
+// ListEndpoint is the endpoint strategies are listed on, prefix.list
+func ListEndpoint(prefix string) eventbus.Endpoint[ListRequest, List] {
+	return eventbus.NewEndpoint[ListRequest, List](prefix+".list", eventbus.JSON)
+}
+
+// Serve answers the guardian RPCs of g under prefix
+func Serve(bus *eventbus.Bus, prefix string, g *Guardian) ([]*nats.Subscription, error) {
+	var subs []*nats.Subscription
+	for _, serve := range []func() (*nats.Subscription, error){
+		func() (*nats.Subscription, error) {
+			return PauseEndpoint(prefix).Serve(bus, func(ctx context.Context, req *PauseRequest) (*Event, error) {
+				e, err := g.Pause(req.Strategy, eventbus.Caller(ctx, req.By), req.Reason)
+				return &e, err
+			})
+		},
+		func() (*nats.Subscription, error) {
+			return ResumeEndpoint(prefix).Serve(bus, func(ctx context.Context, req *ResumeRequest) (*Event, error) {
+				e, err := g.Resume(req.Strategy, eventbus.Caller(ctx, req.By), req.Reason)
+				return &e, err
+			})
+		},
+		func() (*nats.Subscription, error) {
+			return ListEndpoint(prefix).Serve(bus, func(_ context.Context, _ *ListRequest) (*List, error) {
+				return &List{Currency: g.Currency(), Strategies: g.Statuses(), Events: g.History()}, nil
+			})
+		},
+	} {
+		sub, err := serve()
+		if err != nil {
+			for _, sub := range subs {
+				sub.Unsubscribe()
+			}
+			return nil, err
+		}
+		subs = append(subs, sub)
+	}
+	return subs, nil
+}
@@ -0,0 +1,200 @@
+package impact
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"sort"
+	"time"
+
+	"github.com/BullionBear/sequex/internal/model/sqx"
+)
+
+// ErrInsufficientData is returned when the trades cannot support a fit
+var ErrInsufficientData = errors.New("insufficient data")
+
+const (
+	dayMs         = int64(24 * time.Hour / time.Millisecond)
+	returnStepMs  = int64(time.Minute / time.Millisecond) // Sampling of the returns volatility is measured on
+	minBinSamples = 3                                     // Runs averaged per size bin of the free fit
+)
+
+// Options configures a calibration
+type Options struct {
+	Gap     time.Duration // Longest pause within a run of same side aggressive trades, 1s when 0
+	Horizon time.Duration // Delay after a run at which its impact is measured, 10s when 0
+	MinSpan time.Duration // Shortest recording accepted, 1h when 0
+}
+
+func (o *Options) defaults() {
+	if o.Gap <= 0 {
+		o.Gap = time.Second
+	}
+	if o.Horizon <= 0 {
+		o.Horizon = 10 * time.Second
+	}
+	if o.MinSpan <= 0 {
+		o.MinSpan = time.Hour
+	}
+}
+
+// sample is the size and normalized impact of a run of aggressive trades
+type sample struct {
+	x float64 // Quantity over daily volume
+	y float64 // Signed log price move over daily volatility
+}
+
+// Calibrate fits the impact model to the trades of one symbol. Runs of
+// consecutive aggressive trades on one side stand for the metaorders the
+// model describes; the impact of a run is the move from the last price
+// before it to the last price Horizon after it.
+func Calibrate(trades []sqx.Trade, opts Options) (Params, error) {
+	opts.defaults()
+	if len(trades) < 2 {
+		return Params{}, fmt.Errorf("%w: %d trades", ErrInsufficientData, len(trades))
+	}
+	trades = append([]sqx.Trade(nil), trades...)
+	sort.SliceStable(trades, func(i, j int) bool { return trades[i].Timestamp < trades[j].Timestamp })
+
+	first, last := trades[0], trades[len(trades)-1]
+	p := Params{
+		Exchange:   first.Exchange.String(),
+		Instrument: first.InstrumentType.String(),
+		Symbol:     first.Symbol.String(),
+		From:       first.Timestamp,
+		To:         last.Timestamp,
+	}
+	span := last.Timestamp - first.Timestamp
+	if span < opts.MinSpan.Milliseconds() {
+		return p, fmt.Errorf("%w: trades span %s, less than %s", ErrInsufficientData, time.Duration(span)*time.Millisecond, opts.MinSpan)
+	}
+	days := float64(span) / float64(dayMs)
+
+	var volume float64
+	for _, t := range trades {
+		volume += t.Quantity
+	}
+	p.DailyVolume = volume / days
+	p.Sigma = dailyVolatility(trades, days)
+	if p.Sigma <= 0 || p.DailyVolume <= 0 {
+		return p, fmt.Errorf("%w: no price or volume variation", ErrInsufficientData)
+	}
+
+	samples := runs(trades, opts, p.DailyVolume, p.Sigma)
+	p.Samples = len(samples)
+	if len(samples) == 0 {
+		return p, fmt.Errorf("%w: no complete aggressive run", ErrInsufficientData)
+	}
+	// Least squares of y = Y·√x
+	var xy, xx float64
+	for _, s := range samples {
+		xy += s.y * math.Sqrt(s.x)
+		xx += s.x
+	}
+	p.Y = xy / xx
+	p.Delta, p.YFree, p.R2 = fitPower(samples)
+	return p, nil
+}
+
+// dailyVolatility measures the volatility of one minute log returns,
+// scaled to a day
+func dailyVolatility(trades []sqx.Trade, days float64) float64 {
+	var variance float64
+	prev, bucket := trades[0].Price, trades[0].Timestamp/returnStepMs
+	closing := prev
+	for _, t := range trades[1:] {
+		if b := t.Timestamp / returnStepMs; b != bucket {
+			r := math.Log(closing / prev)
+			variance += r * r
+			prev, bucket = closing, b
+		}
+		closing = t.Price
+	}
+	r := math.Log(closing / prev)
+	variance += r * r
+	return math.Sqrt(variance / days)
+}
+
+// runs splits the trades into runs of aggressive trades on one side and
+// measures the impact of each run whose horizon the trades cover
+func runs(trades []sqx.Trade, opts Options, dailyVolume, sigma float64) []sample {
+	gap, horizon := opts.Gap.Milliseconds(), opts.Horizon.Milliseconds()
+	end := trades[len(trades)-1].Timestamp
+	var samples []sample
+	for start := 1; start < len(trades); {
+		side := trades[start].TakerSide
+		quantity := trades[start].Quantity
+		i := start + 1
+		for i < len(trades) && trades[i].TakerSide == side && trades[i].Timestamp-trades[i-1].Timestamp <= gap {
+			quantity += trades[i].Quantity
+			i++
+		}
+		runEnd := trades[i-1].Timestamp + horizon
+		if runEnd > end {
+			break
+		}
+		after := sort.Search(len(trades), func(j int) bool { return trades[j].Timestamp > runEnd }) - 1
+		before := trades[start-1].Price
+		sign := 1.0
+		if side == sqx.SideSell {
+			sign = -1
+		}
+		if side == sqx.SideBuy || side == sqx.SideSell {
+			samples = append(samples, sample{
+				x: quantity / dailyVolume,
+				y: sign * math.Log(trades[after].Price/before) / sigma,
+			})
+		}
+		start = i
+	}
+	return samples
+}
+
+// fitPower fits y = Y·x^δ by least squares on log scales, over the mean
+// impact of runs binned by size as single runs are too noisy and often
+// move against themselves. It returns zeros without two bins of positive
+// mean impact.
+func fitPower(samples []sample) (delta, y, r2 float64) {
+	type bin struct{ x, y, n float64 }
+	bins := make(map[int]*bin)
+	for _, s := range samples {
+		k := int(math.Floor(math.Log2(s.x)))
+		if bins[k] == nil {
+			bins[k] = &bin{}
+		}
+		bins[k].x += s.x
+		bins[k].y += s.y
+		bins[k].n++
+	}
+	var lx, ly []float64
+	for _, b := range bins {
+		if b.n >= minBinSamples && b.y > 0 {
+			lx = append(lx, math.Log(b.x/b.n))
+			ly = append(ly, math.Log(b.y/b.n))
+		}
+	}
+	if len(lx) < 2 {
+		return 0, 0, 0
+	}
+	n := float64(len(lx))
+	var mx, my float64
+	for i := range lx {
+		mx += lx[i] / n
+		my += ly[i] / n
+	}
+	var sxy, sxx, syy float64
+	for i := range lx {
+		sxy += (lx[i] - mx) * (ly[i] - my)
+		sxx += (lx[i] - mx) * (lx[i] - mx)
+		syy += (ly[i] - my) * (ly[i] - my)
+	}
+	if sxx == 0 {
+		return 0, 0, 0
+	}
+	delta = sxy / sxx
+	y = math.Exp(my - delta*mx)
+	if syy > 0 {
+		r2 = sxy * sxy / (sxx * syy)
+	}
+	return delta, y, r2
+}
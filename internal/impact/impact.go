@@ -0,0 +1,74 @@
+// Package impact calibrates a square-root market impact model from recorded
+// trades. Trading a quantity Q moves the price by Y·σ·(Q/V)^δ, σ being the
+// daily volatility and V the daily volume of the symbol, and δ being 1/2
+// under the square-root law.
+package impact
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"os"
+)
+
+// Params is a calibrated impact model of a symbol
+type Params struct {
+	Exchange    string  `json:"exchange"`
+	Instrument  string  `json:"instrument"`
+	Symbol      string  `json:"symbol"`       // BASE-QUOTE
+	Y           float64 `json:"y"`            // Prefactor with δ fixed at 1/2
+	Delta       float64 `json:"delta"`        // Exponent fitted freely, 0 when it could not be fitted
+	YFree       float64 `json:"y_free"`       // Prefactor going with Delta
+	R2          float64 `json:"r2"`           // Fit of the free exponent, on log scales
+	Sigma       float64 `json:"sigma"`        // Daily volatility of log returns
+	DailyVolume float64 `json:"daily_volume"` // Base quantity traded per day
+	Samples     int     `json:"samples"`      // Aggressive runs measured
+	From        int64   `json:"from"`         // First trade, Unix milliseconds
+	To          int64   `json:"to"`           // Last trade, Unix milliseconds
+}
+
+// Key identifies the symbol, e.g. BINANCE.SPOT.BTC-USDT
+func (p Params) Key() string {
+	return p.Exchange + "." + p.Instrument + "." + p.Symbol
+}
+
+// Impact returns the expected relative price move of trading quantity
+// aggressively, e.g. 0.001 for 10 bps, under the square-root law
+func (p Params) Impact(quantity float64) float64 {
+	if p.DailyVolume <= 0 || quantity <= 0 {
+		return 0
+	}
+	return p.Y * p.Sigma * math.Sqrt(quantity/p.DailyVolume)
+}
+
+// Slippage returns the expected cost in quote currency of trading quantity
+// at price, for slippage models and order routing
+func (p Params) Slippage(price, quantity float64) float64 {
+	return price * quantity * p.Impact(quantity)
+}
+
+// Save writes calibrated parameters as a JSON array
+func Save(path string, params []Params) error {
+	data, err := json.MarshalIndent(params, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, append(data, '\n'), 0o644)
+}
+
+// Load reads parameters written by Save, keyed by Params.Key
+func Load(path string) (map[string]Params, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read impact parameters %s: %w", path, err)
+	}
+	var list []Params
+	if err := json.Unmarshal(data, &list); err != nil {
+		return nil, fmt.Errorf("failed to parse impact parameters %s: %w", path, err)
+	}
+	params := make(map[string]Params, len(list))
+	for _, p := range list {
+		params[p.Key()] = p
+	}
+	return params, nil
+}
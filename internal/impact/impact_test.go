@@ -0,0 +1,76 @@
+package impact
+
+import (
+	"errors"
+	"math"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/BullionBear/sequex/internal/model/sqx"
+)
+
+// squareRootTrades simulates runs of three trades every 30s, alternating
+// buys and sells, each moving the log price by k·√Q once Q was traded, so
+// sells bring the price back to where it started
+func squareRootTrades(k float64, runs int) []sqx.Trade {
+	const p0 = 50000.0
+	symbol := sqx.NewSymbol("BTC", "USDT")
+	trade := func(at int64, side sqx.Side, price, quantity float64) sqx.Trade {
+		return sqx.Trade{Symbol: symbol, Exchange: sqx.ExchangeBinance, InstrumentType: sqx.InstrumentTypeSpot, TakerSide: side, Price: price, Quantity: quantity, Timestamp: at}
+	}
+	trades := []sqx.Trade{trade(0, sqx.SideSell, p0, 0.001)}
+	sizes := []float64{1, 3, 9, 27}
+	for i := 0; i < runs; i++ {
+		at := int64(i+1) * 30_000
+		q := sizes[(i/2)%len(sizes)]
+		side, sign, start := sqx.SideBuy, 1.0, p0
+		if i%2 == 1 {
+			side, sign, start = sqx.SideSell, -1, p0*math.Exp(k*math.Sqrt(q))
+		}
+		for j := 1; j <= 3; j++ {
+			price := start * math.Exp(sign*k*math.Sqrt(q*float64(j)/3))
+			trades = append(trades, trade(at+int64(j)*100, side, price, q/3))
+		}
+	}
+	return trades
+}
+
+func TestCalibrate(t *testing.T) {
+	const k = 0.0002
+	p, err := Calibrate(squareRootTrades(k, 240), Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if p.Key() != "BINANCE.SPOT.BTC-USDT" || p.Samples != 239 {
+		t.Errorf("params = %+v", p)
+	}
+	if got := p.Impact(9); math.Abs(got-k*3)/(k*3) > 0.05 {
+		t.Errorf("impact of 9 = %v, want %v", got, k*3)
+	}
+	if math.Abs(p.Delta-0.5) > 0.05 || p.R2 < 0.99 {
+		t.Errorf("delta = %v, r2 = %v", p.Delta, p.R2)
+	}
+	if s := p.Slippage(50000, 9); math.Abs(s-50000*9*p.Impact(9)) > 1e-9 {
+		t.Errorf("slippage = %v", s)
+	}
+
+	path := filepath.Join(t.TempDir(), "impact.json")
+	if err := Save(path, []Params{p}); err != nil {
+		t.Fatal(err)
+	}
+	loaded, err := Load(path)
+	if err != nil || loaded[p.Key()] != p {
+		t.Errorf("loaded %+v, %v", loaded, err)
+	}
+}
+
+func TestCalibrateInsufficientData(t *testing.T) {
+	// 20 runs span 10 minutes, less than the default hour
+	if _, err := Calibrate(squareRootTrades(0.0002, 20), Options{}); !errors.Is(err, ErrInsufficientData) {
+		t.Errorf("err = %v", err)
+	}
+	if _, err := Calibrate(squareRootTrades(0.0002, 20), Options{MinSpan: time.Minute}); err != nil {
+		t.Errorf("err = %v", err)
+	}
+}
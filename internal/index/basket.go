@@ -0,0 +1,107 @@
+package index
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/BullionBear/sequex/internal/model/sqx"
+)
+
+// Method defines how component prices are combined into an index value
+type Method string
+
+const (
+	// MethodPrice computes sum(weight * price) / divisor, e.g. a perp-spot basis
+	// with weights +1 and -1
+	MethodPrice Method = "price"
+	// MethodReturn computes base * sum(weight * price / basePrice), where the base
+	// prices are captured on the first complete observation, e.g. an equal-weight index
+	MethodReturn Method = "return"
+)
+
+// Component is one instrument of a basket
+type Component struct {
+	Exchange   sqx.Exchange
+	Instrument sqx.InstrumentType
+	Symbol     sqx.Symbol
+	Weight     float64
+}
+
+// Id returns the key under which the component price is tracked
+func (c Component) Id() string {
+	return ComponentId(c.Exchange, c.Instrument, c.Symbol)
+}
+
+// ComponentId builds the component key for an instrument
+func ComponentId(exchange sqx.Exchange, instrument sqx.InstrumentType, symbol sqx.Symbol) string {
+	return fmt.Sprintf("%s.%s.%s", exchange.String(), instrument.String(), symbol.String())
+}
+
+// Basket is the definition of a synthetic index
+type Basket struct {
+	Name       string
+	Method     Method
+	Divisor    float64 // Only used by MethodPrice, defaults to 1
+	BaseValue  float64 // Only used by MethodReturn, defaults to 100
+	Components []Component
+}
+
+// Validate validates the basket definition
+func (b *Basket) Validate() error {
+	if b.Name == "" {
+		return fmt.Errorf("basket name cannot be empty")
+	}
+	if b.Method != MethodPrice && b.Method != MethodReturn {
+		return fmt.Errorf("basket %s: invalid method %q, expected %q or %q", b.Name, b.Method, MethodPrice, MethodReturn)
+	}
+	if len(b.Components) == 0 {
+		return fmt.Errorf("basket %s: components cannot be empty", b.Name)
+	}
+	seen := make(map[string]bool)
+	for _, c := range b.Components {
+		if c.Exchange == sqx.ExchangeUnknown {
+			return fmt.Errorf("basket %s: component %s has unknown exchange", b.Name, c.Symbol)
+		}
+		if c.Instrument == sqx.InstrumentTypeUnknown {
+			return fmt.Errorf("basket %s: component %s has unknown instrument", b.Name, c.Symbol)
+		}
+		if c.Weight == 0 {
+			return fmt.Errorf("basket %s: component %s has zero weight", b.Name, c.Id())
+		}
+		if seen[c.Id()] {
+			return fmt.Errorf("basket %s: duplicate component %s", b.Name, c.Id())
+		}
+		seen[c.Id()] = true
+	}
+	return nil
+}
+
+// NewEqualWeightBasket creates a return-based basket giving each symbol the same weight
+func NewEqualWeightBasket(name string, exchange sqx.Exchange, instrument sqx.InstrumentType, symbols []sqx.Symbol) Basket {
+	components := make([]Component, 0, len(symbols))
+	for _, symbol := range symbols {
+		components = append(components, Component{
+			Exchange:   exchange,
+			Instrument: instrument,
+			Symbol:     symbol,
+			Weight:     1 / float64(len(symbols)),
+		})
+	}
+	return Basket{
+		Name:       strings.ToUpper(name),
+		Method:     MethodReturn,
+		Components: components,
+	}
+}
+
+// NewBasisBasket creates a price-based basket computing perp minus spot for one symbol
+func NewBasisBasket(name string, spotExchange, perpExchange sqx.Exchange, symbol sqx.Symbol) Basket {
+	return Basket{
+		Name:   strings.ToUpper(name),
+		Method: MethodPrice,
+		Components: []Component{
+			{Exchange: perpExchange, Instrument: sqx.InstrumentTypePerp, Symbol: symbol, Weight: 1},
+			{Exchange: spotExchange, Instrument: sqx.InstrumentTypeSpot, Symbol: symbol, Weight: -1},
+		},
+	}
+}
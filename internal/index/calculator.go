@@ -0,0 +1,120 @@
+package index
+
+import (
+	"fmt"
+	"sync"
+
+	"github.com/BullionBear/sequex/internal/model/sqx"
+)
+
+// Calculator keeps the last price of every basket component and recomputes
+// the index values whenever a component price changes
+type Calculator struct {
+	mu         sync.Mutex
+	baskets    []Basket
+	prices     map[string]float64
+	basePrices map[string]map[string]float64 // basket name -> component id -> base price
+	byId       map[string][]int              // component id -> basket indices
+}
+
+// NewCalculator creates a calculator for the given baskets
+func NewCalculator(baskets []Basket) (*Calculator, error) {
+	c := &Calculator{
+		baskets:    baskets,
+		prices:     make(map[string]float64),
+		basePrices: make(map[string]map[string]float64),
+		byId:       make(map[string][]int),
+	}
+	names := make(map[string]bool)
+	for i := range baskets {
+		if err := baskets[i].Validate(); err != nil {
+			return nil, err
+		}
+		if names[baskets[i].Name] {
+			return nil, fmt.Errorf("duplicate basket name: %s", baskets[i].Name)
+		}
+		names[baskets[i].Name] = true
+		for _, component := range baskets[i].Components {
+			c.byId[component.Id()] = append(c.byId[component.Id()], i)
+		}
+	}
+	return c, nil
+}
+
+// OnTrade updates the component price from a trade and returns the index
+// prices of every affected basket that has a complete set of prices
+func (c *Calculator) OnTrade(trade sqx.Trade) []sqx.IndexPrice {
+	id := ComponentId(trade.Exchange, trade.InstrumentType, trade.Symbol)
+	return c.Update(id, trade.Price, trade.Timestamp)
+}
+
+// Update sets the price of a component and returns the recomputed index prices
+func (c *Calculator) Update(id string, price float64, timestamp int64) []sqx.IndexPrice {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	indices, ok := c.byId[id]
+	if !ok || price <= 0 {
+		return nil
+	}
+	c.prices[id] = price
+
+	results := make([]sqx.IndexPrice, 0, len(indices))
+	for _, i := range indices {
+		if value, ok := c.compute(&c.baskets[i]); ok {
+			results = append(results, sqx.IndexPrice{
+				Name:       c.baskets[i].Name,
+				Value:      value,
+				Components: c.snapshot(&c.baskets[i]),
+				Timestamp:  timestamp,
+			})
+		}
+	}
+	return results
+}
+
+// compute returns the index value of a basket, or false if a component price is missing
+func (c *Calculator) compute(b *Basket) (float64, bool) {
+	for _, component := range b.Components {
+		if _, ok := c.prices[component.Id()]; !ok {
+			return 0, false
+		}
+	}
+
+	switch b.Method {
+	case MethodReturn:
+		base, ok := c.basePrices[b.Name]
+		if !ok {
+			base = c.snapshot(b)
+			c.basePrices[b.Name] = base
+		}
+		baseValue := b.BaseValue
+		if baseValue == 0 {
+			baseValue = 100
+		}
+		sum := 0.0
+		for _, component := range b.Components {
+			sum += component.Weight * c.prices[component.Id()] / base[component.Id()]
+		}
+		return baseValue * sum, true
+	default:
+		divisor := b.Divisor
+		if divisor == 0 {
+			divisor = 1
+		}
+		sum := 0.0
+		for _, component := range b.Components {
+			sum += component.Weight * c.prices[component.Id()]
+		}
+		return sum / divisor, true
+	}
+}
+
+// snapshot copies the current prices of the basket components
+func (c *Calculator) snapshot(b *Basket) map[string]float64 {
+	prices := make(map[string]float64, len(b.Components))
+	for _, component := range b.Components {
+		prices[component.Id()] = c.prices[component.Id()]
+	}
+	return prices
+}
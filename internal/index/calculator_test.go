@@ -0,0 +1,107 @@
+package index
+
+import (
+	"math"
+	"testing"
+
+	"github.com/BullionBear/sequex/internal/model/sqx"
+)
+
+func newTrade(exchange sqx.Exchange, instrument sqx.InstrumentType, base, quote string, price float64, ts int64) sqx.Trade {
+	return sqx.Trade{
+		Exchange:       exchange,
+		InstrumentType: instrument,
+		Symbol:         sqx.NewSymbol(base, quote),
+		Price:          price,
+		Timestamp:      ts,
+	}
+}
+
+func TestCalculator_Basis(t *testing.T) {
+	basket := NewBasisBasket("btc_basis", sqx.ExchangeBinance, sqx.ExchangeBinancePerp, sqx.NewSymbol("BTC", "USDT"))
+	calculator, err := NewCalculator([]Basket{basket})
+	if err != nil {
+		t.Fatalf("NewCalculator() returned error: %v", err)
+	}
+
+	// Only one leg known, no index yet
+	if prices := calculator.OnTrade(newTrade(sqx.ExchangeBinance, sqx.InstrumentTypeSpot, "BTC", "USDT", 60000, 1)); len(prices) != 0 {
+		t.Fatalf("expected no index price with a missing component, got %v", prices)
+	}
+
+	prices := calculator.OnTrade(newTrade(sqx.ExchangeBinancePerp, sqx.InstrumentTypePerp, "BTC", "USDT", 60030, 2))
+	if len(prices) != 1 {
+		t.Fatalf("expected 1 index price, got %d", len(prices))
+	}
+	if prices[0].Name != "BTC_BASIS" {
+		t.Errorf("Name = %s, expected BTC_BASIS", prices[0].Name)
+	}
+	if math.Abs(prices[0].Value-30) > 1e-9 {
+		t.Errorf("Value = %v, expected 30", prices[0].Value)
+	}
+	if prices[0].Timestamp != 2 {
+		t.Errorf("Timestamp = %d, expected 2", prices[0].Timestamp)
+	}
+	if len(prices[0].Components) != 2 {
+		t.Errorf("expected 2 components, got %d", len(prices[0].Components))
+	}
+}
+
+func TestCalculator_EqualWeight(t *testing.T) {
+	symbols := []sqx.Symbol{sqx.NewSymbol("BTC", "USDT"), sqx.NewSymbol("ETH", "USDT")}
+	basket := NewEqualWeightBasket("top2", sqx.ExchangeBinance, sqx.InstrumentTypeSpot, symbols)
+	calculator, err := NewCalculator([]Basket{basket})
+	if err != nil {
+		t.Fatalf("NewCalculator() returned error: %v", err)
+	}
+
+	calculator.OnTrade(newTrade(sqx.ExchangeBinance, sqx.InstrumentTypeSpot, "BTC", "USDT", 60000, 1))
+	prices := calculator.OnTrade(newTrade(sqx.ExchangeBinance, sqx.InstrumentTypeSpot, "ETH", "USDT", 3000, 2))
+	if len(prices) != 1 || math.Abs(prices[0].Value-100) > 1e-9 {
+		t.Fatalf("expected base value 100 on first complete observation, got %v", prices)
+	}
+
+	// BTC +10%, ETH unchanged -> index +5%
+	prices = calculator.OnTrade(newTrade(sqx.ExchangeBinance, sqx.InstrumentTypeSpot, "BTC", "USDT", 66000, 3))
+	if len(prices) != 1 || math.Abs(prices[0].Value-105) > 1e-9 {
+		t.Fatalf("expected index value 105, got %v", prices)
+	}
+}
+
+func TestCalculator_IgnoresUnrelatedTrades(t *testing.T) {
+	basket := NewBasisBasket("btc_basis", sqx.ExchangeBinance, sqx.ExchangeBinancePerp, sqx.NewSymbol("BTC", "USDT"))
+	calculator, err := NewCalculator([]Basket{basket})
+	if err != nil {
+		t.Fatalf("NewCalculator() returned error: %v", err)
+	}
+	if prices := calculator.OnTrade(newTrade(sqx.ExchangeBinance, sqx.InstrumentTypeSpot, "ETH", "USDT", 3000, 1)); prices != nil {
+		t.Errorf("expected nil for unrelated trade, got %v", prices)
+	}
+}
+
+func TestNewCalculator_InvalidBaskets(t *testing.T) {
+	valid := NewBasisBasket("basis", sqx.ExchangeBinance, sqx.ExchangeBinancePerp, sqx.NewSymbol("BTC", "USDT"))
+	tests := []struct {
+		name    string
+		baskets []Basket
+	}{
+		{"empty name", []Basket{{Method: MethodPrice, Components: valid.Components}}},
+		{"invalid method", []Basket{{Name: "X", Method: "median", Components: valid.Components}}},
+		{"no components", []Basket{{Name: "X", Method: MethodPrice}}},
+		{"zero weight", []Basket{{Name: "X", Method: MethodPrice, Components: []Component{
+			{Exchange: sqx.ExchangeBinance, Instrument: sqx.InstrumentTypeSpot, Symbol: sqx.NewSymbol("BTC", "USDT")},
+		}}}},
+		{"duplicate component", []Basket{{Name: "X", Method: MethodPrice, Components: []Component{
+			valid.Components[0], valid.Components[0],
+		}}}},
+		{"duplicate basket name", []Basket{valid, valid}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := NewCalculator(tt.baskets); err == nil {
+				t.Error("NewCalculator() expected error, got nil")
+			}
+		})
+	}
+}
@@ -0,0 +1,215 @@
+// Package lag watches the durable JetStream consumers of the nodes and
+// raises an alert when the pending count of one keeps growing for a window,
+// the sign of a stuck or slow node.
+package lag
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/BullionBear/sequex/pkg/diagnostics"
+	"github.com/nats-io/nats.go"
+)
+
+// Gauge is the lag of a durable consumer at the last poll
+type Gauge struct {
+	Stream      string        `json:"stream"`
+	Consumer    string        `json:"consumer"`
+	Pending     uint64        `json:"pending"`     // Messages not delivered yet
+	AckPending  int           `json:"ack_pending"` // Messages delivered and not acknowledged yet
+	Redelivered int           `json:"redelivered"`
+	Lag         uint64        `json:"lag"`         // Pending and ack pending messages
+	GrowingFor  time.Duration `json:"growing_for"` // Since the lag last decreased
+	Alerting    bool          `json:"alerting"`
+}
+
+// Alert is raised when the lag of a consumer grows for the window and sent
+// again, inactive, once it decreases
+type Alert struct {
+	Stream    string `json:"stream"`
+	Consumer  string `json:"consumer"`
+	Lag       uint64 `json:"lag"`
+	Base      uint64 `json:"base"`  // Lag when it started growing
+	Since     int64  `json:"since"` // Time the lag started growing, Unix milliseconds
+	Window    int64  `json:"window"`
+	Active    bool   `json:"active"`
+	Timestamp int64  `json:"timestamp"`
+}
+
+// Options configures a watcher
+type Options struct {
+	Streams []string      // Streams watched, all when empty
+	Window  time.Duration // Growth of the lag raising an alert
+	MinLag  uint64        // Lags below are never alerted on
+}
+
+// Validate validates the options
+func (o Options) Validate() error {
+	if o.Window <= 0 {
+		return fmt.Errorf("window must be positive")
+	}
+	for i, stream := range o.Streams {
+		if stream == "" {
+			return fmt.Errorf("streams[%d] cannot be empty", i)
+		}
+	}
+	return nil
+}
+
+type key struct {
+	stream, consumer string
+}
+
+// state follows the lag of a consumer since it last decreased
+type state struct {
+	gauge Gauge
+	base  uint64
+	since time.Time
+}
+
+// Watcher tracks the lag of durable consumers across polls. It is safe for
+// concurrent use.
+type Watcher struct {
+	opts   Options
+	mu     sync.Mutex
+	states map[key]*state
+}
+
+// New creates a watcher
+func New(opts Options) (*Watcher, error) {
+	if err := opts.Validate(); err != nil {
+		return nil, err
+	}
+	return &Watcher{opts: opts, states: make(map[key]*state)}, nil
+}
+
+// Observe updates the lags with the consumers listed at now and returns the
+// alerts changing state. Consumers missing from infos are forgotten, their
+// active alerts resolved.
+func (w *Watcher) Observe(infos []*nats.ConsumerInfo, now time.Time) []Alert {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	var alerts []Alert
+	seen := make(map[key]bool, len(infos))
+	for _, info := range infos {
+		k := key{info.Stream, info.Name}
+		seen[k] = true
+		lag := info.NumPending + uint64(info.NumAckPending)
+		s, ok := w.states[k]
+		if !ok || lag < s.gauge.Lag {
+			if ok && s.gauge.Alerting {
+				alerts = append(alerts, w.alert(s, lag, false, now))
+			}
+			s = &state{base: lag, since: now}
+			w.states[k] = s
+		}
+		s.gauge = Gauge{
+			Stream:      info.Stream,
+			Consumer:    info.Name,
+			Pending:     info.NumPending,
+			AckPending:  info.NumAckPending,
+			Redelivered: info.NumRedelivered,
+			Lag:         lag,
+			GrowingFor:  now.Sub(s.since),
+			Alerting:    s.gauge.Alerting,
+		}
+		if !s.gauge.Alerting && lag > s.base && lag >= w.opts.MinLag && s.gauge.GrowingFor >= w.opts.Window {
+			s.gauge.Alerting = true
+			alerts = append(alerts, w.alert(s, lag, true, now))
+		}
+	}
+	for k, s := range w.states {
+		if seen[k] {
+			continue
+		}
+		if s.gauge.Alerting {
+			alerts = append(alerts, w.alert(s, 0, false, now))
+		}
+		delete(w.states, k)
+	}
+	sort.Slice(alerts, func(i, j int) bool {
+		return alerts[i].Stream < alerts[j].Stream || (alerts[i].Stream == alerts[j].Stream && alerts[i].Consumer < alerts[j].Consumer)
+	})
+	return alerts
+}
+
+func (w *Watcher) alert(s *state, lag uint64, active bool, now time.Time) Alert {
+	return Alert{
+		Stream:    s.gauge.Stream,
+		Consumer:  s.gauge.Consumer,
+		Lag:       lag,
+		Base:      s.base,
+		Since:     s.since.UnixMilli(),
+		Window:    w.opts.Window.Milliseconds(),
+		Active:    active,
+		Timestamp: now.UnixMilli(),
+	}
+}
+
+// Gauges returns the lag of every consumer, sorted by stream and consumer
+func (w *Watcher) Gauges() []Gauge {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	gauges := make([]Gauge, 0, len(w.states))
+	for _, s := range w.states {
+		gauges = append(gauges, s.gauge)
+	}
+	sort.Slice(gauges, func(i, j int) bool {
+		return gauges[i].Stream < gauges[j].Stream || (gauges[i].Stream == gauges[j].Stream && gauges[i].Consumer < gauges[j].Consumer)
+	})
+	return gauges
+}
+
+// Active returns the consumers alerting
+func (w *Watcher) Active() []Gauge {
+	var active []Gauge
+	for _, g := range w.Gauges() {
+		if g.Alerting {
+			active = append(active, g)
+		}
+	}
+	return active
+}
+
+// QueueDepths reports the lags as the queues of the diagnostics reports,
+// named <stream>/<consumer>
+func (w *Watcher) QueueDepths() []diagnostics.QueueDepth {
+	gauges := w.Gauges()
+	depths := make([]diagnostics.QueueDepth, len(gauges))
+	for i, g := range gauges {
+		depths[i] = diagnostics.QueueDepth{Name: g.Stream + "/" + g.Consumer, Depth: int(g.Lag)}
+	}
+	return depths
+}
+
+// Poll lists the durable consumers of the watched streams and observes them
+func (w *Watcher) Poll(ctx context.Context, js nats.JetStreamContext, now time.Time) ([]Alert, error) {
+	streams := w.opts.Streams
+	if len(streams) == 0 {
+		for name := range js.StreamNames(nats.Context(ctx)) {
+			streams = append(streams, name)
+		}
+		if err := ctx.Err(); err != nil {
+			return nil, fmt.Errorf("listing streams: %w", err)
+		}
+	}
+	var infos []*nats.ConsumerInfo
+	for _, stream := range streams {
+		if _, err := js.StreamInfo(stream, nats.Context(ctx)); err != nil {
+			return nil, fmt.Errorf("stream %s: %w", stream, err)
+		}
+		for info := range js.ConsumersInfo(stream, nats.Context(ctx)) {
+			// Ephemeral consumers come and go with the tools reading a stream
+			if info.Config.Durable != "" {
+				infos = append(infos, info)
+			}
+		}
+		if err := ctx.Err(); err != nil {
+			return nil, fmt.Errorf("listing consumers of %s: %w", stream, err)
+		}
+	}
+	return w.Observe(infos, now), nil
+}
@@ -0,0 +1,84 @@
+package lag
+
+import (
+	"testing"
+	"time"
+
+	"github.com/nats-io/nats.go"
+)
+
+func consumer(stream, name string, pending uint64, ackPending int) *nats.ConsumerInfo {
+	return &nats.ConsumerInfo{Stream: stream, Name: name, NumPending: pending, NumAckPending: ackPending}
+}
+
+func TestWatcher(t *testing.T) {
+	if _, err := New(Options{}); err == nil {
+		t.Error("zero window validated")
+	}
+	w, err := New(Options{Window: 5 * time.Minute, MinLag: 10})
+	if err != nil {
+		t.Fatal(err)
+	}
+	start := time.Unix(1_700_000_000, 0)
+	at := func(minutes int) time.Time { return start.Add(time.Duration(minutes) * time.Minute) }
+
+	// The sink keeps up, its lag goes up and down; the strategy is stuck
+	polls := [][]*nats.ConsumerInfo{
+		{consumer("TRADE", "sink", 5, 0), consumer("TRADE", "strategy", 0, 0)},
+		{consumer("TRADE", "sink", 8, 2), consumer("TRADE", "strategy", 20, 100)},
+		{consumer("TRADE", "sink", 1, 0), consumer("TRADE", "strategy", 300, 100)},
+		{consumer("TRADE", "sink", 9, 3), consumer("TRADE", "strategy", 300, 100)},
+		{consumer("TRADE", "sink", 0, 0), consumer("TRADE", "strategy", 700, 100)},
+	}
+	for i, infos := range polls[:4] {
+		if alerts := w.Observe(infos, at(i)); len(alerts) != 0 {
+			t.Fatalf("poll %d alerts = %+v", i, alerts)
+		}
+	}
+	alerts := w.Observe(polls[4], at(8))
+	if len(alerts) != 1 || alerts[0].Consumer != "strategy" || !alerts[0].Active || alerts[0].Lag != 800 ||
+		alerts[0].Base != 0 || alerts[0].Since != start.UnixMilli() {
+		t.Fatalf("alerts = %+v", alerts)
+	}
+	// Still growing: no new transition
+	if alerts := w.Observe([]*nats.ConsumerInfo{consumer("TRADE", "strategy", 900, 100)}, at(10)); len(alerts) != 0 {
+		t.Errorf("repeated alerts = %+v", alerts)
+	}
+	gauges := w.Gauges()
+	if len(gauges) != 1 || gauges[0].Lag != 1000 || gauges[0].GrowingFor != 10*time.Minute || !gauges[0].Alerting {
+		t.Errorf("gauges = %+v", gauges)
+	}
+	if depths := w.QueueDepths(); len(depths) != 1 || depths[0].Name != "TRADE/strategy" || depths[0].Depth != 1000 {
+		t.Errorf("queue depths = %+v", depths)
+	}
+
+	// Catching up resolves the alert and restarts the window
+	alerts = w.Observe([]*nats.ConsumerInfo{consumer("TRADE", "strategy", 500, 100)}, at(12))
+	if len(alerts) != 1 || alerts[0].Active || alerts[0].Lag != 600 || len(w.Active()) != 0 {
+		t.Fatalf("recovery alerts = %+v", alerts)
+	}
+	if alerts := w.Observe([]*nats.ConsumerInfo{consumer("TRADE", "strategy", 700, 100)}, at(16)); len(alerts) != 0 {
+		t.Errorf("alerts within the window = %+v", alerts)
+	}
+	if alerts := w.Observe([]*nats.ConsumerInfo{consumer("TRADE", "strategy", 701, 100)}, at(17)); len(alerts) != 1 || !alerts[0].Active {
+		t.Fatalf("alerts = %+v", alerts)
+	}
+	// A deleted consumer is forgotten
+	alerts = w.Observe(nil, at(18))
+	if len(alerts) != 1 || alerts[0].Active || len(w.Gauges()) != 0 {
+		t.Errorf("deleted consumer alerts = %+v", alerts)
+	}
+}
+
+func TestWatcher_MinLag(t *testing.T) {
+	w, err := New(Options{Window: time.Minute, MinLag: 100})
+	if err != nil {
+		t.Fatal(err)
+	}
+	now := time.Unix(1_700_000_000, 0)
+	for i := range 5 {
+		if alerts := w.Observe([]*nats.ConsumerInfo{consumer("LOGS", "archiver", uint64(i*10), 0)}, now.Add(time.Duration(i)*time.Minute)); len(alerts) != 0 {
+			t.Fatalf("alerts below the minimum lag = %+v", alerts)
+		}
+	}
+}
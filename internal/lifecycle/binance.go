@@ -0,0 +1,22 @@
+package lifecycle
+
+import (
+	"context"
+
+	"github.com/BullionBear/sequex/pkg/exchange/binance"
+)
+
+// BinanceSpot returns a source of the statuses of the Binance spot symbols
+func BinanceSpot(client *binance.Client) StatusSource {
+	return func(ctx context.Context) (map[string]string, error) {
+		resp, err := client.GetExchangeInfo(ctx, binance.ExchangeInfoRequest{})
+		if err != nil {
+			return nil, err
+		}
+		statuses := make(map[string]string, len(resp.Data.Symbols))
+		for _, s := range resp.Data.Symbols {
+			statuses[s.BaseAsset+"-"+s.QuoteAsset] = s.Status
+		}
+		return statuses, nil
+	}
+}
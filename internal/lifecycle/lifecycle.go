@@ -0,0 +1,146 @@
+// Package lifecycle tracks the trading status of symbols from exchange
+// metadata, so feeds can park the streams of symbols going offline and
+// resume them, and order entry can refuse orders on them, rather than a
+// delisted symbol being noticed only as a silent stream.
+package lifecycle
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+)
+
+const (
+	StatusTrading  = "TRADING"
+	StatusDelisted = "DELISTED" // No longer listed by the exchange
+)
+
+// StatusSource returns the status of every symbol of a venue, keyed by
+// symbol, BASE-QUOTE, e.g. TRADING, BREAK or HALT
+type StatusSource func(ctx context.Context) (map[string]string, error)
+
+// Change is published when the status of a symbol changes
+type Change struct {
+	Exchange   string `json:"exchange"`
+	Instrument string `json:"instrument"`
+	Symbol     string `json:"symbol"` // BASE-QUOTE
+	From       string `json:"from"`   // Empty when the symbol was not known before, e.g. newly listed
+	To         string `json:"to"`
+	Timestamp  int64  `json:"timestamp"`
+}
+
+// Trading reports whether the symbol can be traded after the change
+func (c Change) Trading() bool {
+	return c.To == StatusTrading
+}
+
+// Tracker keeps the last status of the symbols of a venue
+type Tracker struct {
+	exchange   string
+	instrument string
+	source     StatusSource
+
+	mu       sync.Mutex
+	statuses map[string]string // Nil until the first refresh
+}
+
+// NewTracker creates a tracker of the symbols of source. Every symbol is
+// considered trading until the first refresh.
+func NewTracker(exchange, instrument string, source StatusSource) *Tracker {
+	return &Tracker{exchange: exchange, instrument: instrument, source: source}
+}
+
+// Status returns the status of symbol, StatusDelisted when the exchange
+// does not list it
+func (t *Tracker) Status(symbol string) string {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.statuses == nil {
+		return StatusTrading
+	}
+	if status, ok := t.statuses[symbol]; ok {
+		return status
+	}
+	return StatusDelisted
+}
+
+// Trading reports whether symbol can be traded
+func (t *Tracker) Trading(symbol string) bool {
+	return t.Status(symbol) == StatusTrading
+}
+
+// Filter returns the symbols that can be traded
+func (t *Tracker) Filter(symbols []string) []string {
+	trading := make([]string, 0, len(symbols))
+	for _, symbol := range symbols {
+		if t.Trading(symbol) {
+			trading = append(trading, symbol)
+		}
+	}
+	return trading
+}
+
+// Refresh reads the statuses again and returns the changes, sorted by
+// symbol. The first refresh returns the symbols not trading, so their state
+// is known downstream after a restart, and later refreshes the symbols
+// newly listed. Symbols dropped by the exchange change to StatusDelisted.
+func (t *Tracker) Refresh(ctx context.Context) ([]Change, error) {
+	statuses, err := t.source(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if len(statuses) == 0 {
+		// Rather than every symbol being delisted at once
+		return nil, fmt.Errorf("no symbols listed")
+	}
+	now := time.Now().UnixMilli()
+	change := func(symbol, from, to string) Change {
+		return Change{Exchange: t.exchange, Instrument: t.instrument, Symbol: symbol, From: from, To: to, Timestamp: now}
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	var changes []Change
+	for symbol, status := range statuses {
+		previous, ok := t.statuses[symbol]
+		switch {
+		case !ok && (t.statuses != nil || status != StatusTrading):
+			changes = append(changes, change(symbol, "", status))
+		case ok && previous != status:
+			changes = append(changes, change(symbol, previous, status))
+		}
+	}
+	for symbol, previous := range t.statuses {
+		if _, ok := statuses[symbol]; !ok {
+			changes = append(changes, change(symbol, previous, StatusDelisted))
+		}
+	}
+	sort.Slice(changes, func(i, j int) bool { return changes[i].Symbol < changes[j].Symbol })
+	t.statuses = statuses
+	return changes, nil
+}
+
+// Run refreshes the statuses every interval until ctx is done. Changes go
+// to onChange and errors to onError; the statuses are kept when a refresh
+// fails.
+func (t *Tracker) Run(ctx context.Context, interval time.Duration, onChange func([]Change), onError func(error)) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+		changes, err := t.Refresh(ctx)
+		if err != nil {
+			onError(fmt.Errorf("symbol status: %w", err))
+			continue
+		}
+		if len(changes) > 0 {
+			onChange(changes)
+		}
+	}
+}
@@ -0,0 +1,56 @@
+package lifecycle
+
+import (
+	"context"
+	"reflect"
+	"testing"
+)
+
+func TestTracker(t *testing.T) {
+	var statuses map[string]string
+	tracker := NewTracker("BINANCE", "SPOT", func(context.Context) (map[string]string, error) {
+		return statuses, nil
+	})
+	if !tracker.Trading("BTC-USDT") {
+		t.Error("symbols should trade before the first refresh")
+	}
+
+	transitions := func(changes []Change) (out []string) {
+		for _, c := range changes {
+			out = append(out, c.Symbol+":"+c.From+">"+c.To)
+		}
+		return out
+	}
+	steps := []struct {
+		statuses map[string]string
+		want     []string
+	}{
+		// Only the symbols not trading are announced at first
+		{map[string]string{"BTC-USDT": "TRADING", "XYZ-USDT": "BREAK"}, []string{"XYZ-USDT:>BREAK"}},
+		{map[string]string{"BTC-USDT": "HALT", "XYZ-USDT": "BREAK", "NEW-USDT": "TRADING"}, []string{"BTC-USDT:TRADING>HALT", "NEW-USDT:>TRADING"}},
+		{map[string]string{"BTC-USDT": "TRADING", "NEW-USDT": "TRADING"}, []string{"BTC-USDT:HALT>TRADING", "XYZ-USDT:BREAK>DELISTED"}},
+	}
+	for i, step := range steps {
+		statuses = step.statuses
+		changes, err := tracker.Refresh(context.Background())
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := transitions(changes); !reflect.DeepEqual(got, step.want) {
+			t.Errorf("step %d: changes = %v, want %v", i, got, step.want)
+		}
+	}
+
+	if got := tracker.Filter([]string{"BTC-USDT", "XYZ-USDT", "NEW-USDT"}); !reflect.DeepEqual(got, []string{"BTC-USDT", "NEW-USDT"}) {
+		t.Errorf("trading = %v", got)
+	}
+	if tracker.Status("XYZ-USDT") != StatusDelisted {
+		t.Errorf("status = %s", tracker.Status("XYZ-USDT"))
+	}
+
+	// An empty listing is an error rather than every symbol delisted
+	statuses = map[string]string{}
+	if _, err := tracker.Refresh(context.Background()); err == nil || !tracker.Trading("BTC-USDT") {
+		t.Errorf("empty listing: err = %v", err)
+	}
+}
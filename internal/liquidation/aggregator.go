@@ -0,0 +1,292 @@
+// Package liquidation aggregates forced liquidations into rolling per-minute
+// statistics and price heatmaps, showing where leveraged positions were
+// wiped out and on which side.
+package liquidation
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/BullionBear/sequex/internal/model/sqx"
+)
+
+// Options configures an aggregator
+type Options struct {
+	Window    time.Duration // Liquidations older than this are dropped, 1h when 0
+	StepRatio float64       // Default heatmap bucket width relative to the last price, 0.001 when 0
+}
+
+// Notional splits liquidated value by the side of the positions
+type Notional struct {
+	Count int     `json:"count"`
+	Long  float64 `json:"long"`  // Value of long positions liquidated, by sell orders
+	Short float64 `json:"short"` // Value of short positions liquidated, by buy orders
+}
+
+// Total returns the value liquidated on both sides
+func (n Notional) Total() float64 {
+	return n.Long + n.Short
+}
+
+func (n *Notional) add(l sqx.Liquidation) {
+	n.Count++
+	if l.Long() {
+		n.Long += l.Notional()
+	} else {
+		n.Short += l.Notional()
+	}
+}
+
+// Minute is the liquidated value of one minute
+type Minute struct {
+	Start time.Time `json:"start"`
+	Notional
+}
+
+// Stats is the liquidated value of a symbol, or of every symbol, over the window
+type Stats struct {
+	Symbol  string    `json:"symbol,omitempty"` // Empty when aggregated over every symbol
+	From    time.Time `json:"from"`
+	To      time.Time `json:"to"`
+	Minutes []Minute  `json:"minutes"` // Minutes with liquidations, oldest first
+	Notional
+}
+
+// Bucket is the liquidated value within [Low, High)
+type Bucket struct {
+	Low  float64 `json:"low"`
+	High float64 `json:"high"`
+	Notional
+}
+
+// Heatmap is the liquidated value of a symbol per price bucket over the window
+type Heatmap struct {
+	Symbol  string    `json:"symbol"`
+	Step    float64   `json:"step"`
+	From    time.Time `json:"from"`
+	To      time.Time `json:"to"`
+	Buckets []Bucket  `json:"buckets"` // Buckets with liquidations, lowest price first
+}
+
+// Aggregator keeps the liquidations of the window. It is safe for concurrent use.
+type Aggregator struct {
+	opts Options
+
+	mu     sync.Mutex
+	events map[string][]sqx.Liquidation // Keyed by symbol, in arrival order
+	last   map[string]float64           // Last liquidation price keyed by symbol
+	now    func() time.Time
+}
+
+// New creates an empty aggregator
+func New(opts Options) *Aggregator {
+	if opts.Window <= 0 {
+		opts.Window = time.Hour
+	}
+	if opts.StepRatio <= 0 {
+		opts.StepRatio = 0.001
+	}
+	return &Aggregator{
+		opts:   opts,
+		events: make(map[string][]sqx.Liquidation),
+		last:   make(map[string]float64),
+		now:    time.Now,
+	}
+}
+
+// Add records a liquidation
+func (a *Aggregator) Add(l sqx.Liquidation) {
+	if l.Price <= 0 || l.Quantity <= 0 {
+		return
+	}
+	symbol := l.Symbol.String()
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.events[symbol] = append(a.prune(a.events[symbol]), l)
+	a.last[symbol] = l.Price
+}
+
+// prune drops the liquidations that left the window. Events arrive roughly in
+// time order, so only the head is inspected.
+func (a *Aggregator) prune(events []sqx.Liquidation) []sqx.Liquidation {
+	cutoff := a.now().Add(-a.opts.Window).UnixMilli()
+	i := 0
+	for i < len(events) && events[i].Timestamp < cutoff {
+		i++
+	}
+	return events[i:]
+}
+
+// Symbols lists the symbols with liquidations in the window
+func (a *Aggregator) Symbols() []string {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	symbols := make([]string, 0, len(a.events))
+	for symbol, events := range a.events {
+		if events = a.prune(events); len(events) > 0 {
+			symbols = append(symbols, symbol)
+		}
+		a.events[symbol] = events
+	}
+	sort.Strings(symbols)
+	return symbols
+}
+
+// Events returns the liquidations of every symbol within the window, oldest
+// first, e.g. to warm-start another aggregator with Add
+func (a *Aggregator) Events() []sqx.Liquidation {
+	a.mu.Lock()
+	events := a.window("")
+	a.mu.Unlock()
+	sort.SliceStable(events, func(i, j int) bool { return events[i].Timestamp < events[j].Timestamp })
+	return events
+}
+
+// window returns the liquidations of a symbol within the window, or of every
+// symbol when symbol is empty
+func (a *Aggregator) window(symbol string) []sqx.Liquidation {
+	cutoff := a.now().Add(-a.opts.Window).UnixMilli()
+	var events []sqx.Liquidation
+	for s, list := range a.events {
+		if symbol != "" && s != symbol {
+			continue
+		}
+		for _, l := range list {
+			if l.Timestamp >= cutoff {
+				events = append(events, l)
+			}
+		}
+	}
+	return events
+}
+
+// normalize turns BTC-USDT or btc-usdt into the key liquidations are stored under
+func normalize(symbol string) (string, error) {
+	if symbol == "" {
+		return "", nil
+	}
+	s, err := sqx.NewSymbolFromStr(symbol)
+	if err != nil {
+		return "", err
+	}
+	return s.String(), nil
+}
+
+// Stats returns the liquidated value per minute over the window. An empty
+// symbol aggregates every symbol.
+func (a *Aggregator) Stats(symbol string) (Stats, error) {
+	symbol, err := normalize(symbol)
+	if err != nil {
+		return Stats{}, err
+	}
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	now := a.now()
+	stats := Stats{Symbol: symbol, From: now.Add(-a.opts.Window), To: now, Minutes: []Minute{}}
+	byMinute := make(map[int64]*Minute)
+	for _, l := range a.window(symbol) {
+		stats.add(l)
+		start := time.UnixMilli(l.Timestamp).Truncate(time.Minute)
+		minute, ok := byMinute[start.UnixMilli()]
+		if !ok {
+			minute = &Minute{Start: start}
+			byMinute[start.UnixMilli()] = minute
+		}
+		minute.add(l)
+	}
+	for _, minute := range byMinute {
+		stats.Minutes = append(stats.Minutes, *minute)
+	}
+	sort.Slice(stats.Minutes, func(i, j int) bool { return stats.Minutes[i].Start.Before(stats.Minutes[j].Start) })
+	return stats, nil
+}
+
+// Heatmap buckets the liquidated value of a symbol by price over the window.
+// A step of 0 derives the bucket width from the last price.
+func (a *Aggregator) Heatmap(symbol string, step float64) (Heatmap, error) {
+	symbol, err := normalize(symbol)
+	if err != nil {
+		return Heatmap{}, err
+	}
+	if symbol == "" {
+		return Heatmap{}, fmt.Errorf("symbol is required, heatmaps of different symbols have different price scales")
+	}
+	if step < 0 {
+		return Heatmap{}, fmt.Errorf("step cannot be negative")
+	}
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	if step == 0 {
+		step = NiceStep(a.last[symbol] * a.opts.StepRatio)
+	}
+	now := a.now()
+	heatmap := Heatmap{Symbol: symbol, Step: step, From: now.Add(-a.opts.Window), To: now, Buckets: []Bucket{}}
+	if step == 0 {
+		return heatmap, nil // No liquidation seen yet
+	}
+	byBucket := make(map[int64]*Bucket)
+	for _, l := range a.window(symbol) {
+		index := int64(math.Floor(l.Price / step))
+		bucket, ok := byBucket[index]
+		if !ok {
+			bucket = &Bucket{Low: float64(index) * step, High: float64(index+1) * step}
+			byBucket[index] = bucket
+		}
+		bucket.add(l)
+	}
+	for _, bucket := range byBucket {
+		heatmap.Buckets = append(heatmap.Buckets, *bucket)
+	}
+	sort.Slice(heatmap.Buckets, func(i, j int) bool { return heatmap.Buckets[i].Low < heatmap.Buckets[j].Low })
+	return heatmap, nil
+}
+
+// Summary is the rolling liquidated value of a symbol, published periodically
+type Summary struct {
+	Symbol     string    `json:"symbol"`
+	Window     string    `json:"window"` // e.g. 1h0m0s
+	Total      Notional  `json:"total"`
+	LastMinute Notional  `json:"last_minute"` // Liquidated during the last minute
+	Timestamp  time.Time `json:"timestamp"`
+}
+
+// Summaries returns the summary of every symbol with liquidations in the window
+func (a *Aggregator) Summaries() []Summary {
+	symbols := a.Symbols()
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	now := a.now()
+	lastMinute := now.Add(-time.Minute).UnixMilli()
+	summaries := make([]Summary, 0, len(symbols))
+	for _, symbol := range symbols {
+		summary := Summary{Symbol: symbol, Window: a.opts.Window.String(), Timestamp: now}
+		for _, l := range a.window(symbol) {
+			summary.Total.add(l)
+			if l.Timestamp >= lastMinute {
+				summary.LastMinute.add(l)
+			}
+		}
+		summaries = append(summaries, summary)
+	}
+	return summaries
+}
+
+// NiceStep rounds a bucket width to 1, 2 or 5 times a power of ten, so bucket
+// edges are round prices
+func NiceStep(step float64) float64 {
+	if step <= 0 || math.IsInf(step, 0) || math.IsNaN(step) {
+		return 0
+	}
+	magnitude := math.Pow(10, math.Floor(math.Log10(step)))
+	for _, m := range []float64{1, 2, 5} {
+		if step <= m*magnitude {
+			return m * magnitude
+		}
+	}
+	return 10 * magnitude
+}
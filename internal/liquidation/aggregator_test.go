@@ -0,0 +1,94 @@
+package liquidation
+
+import (
+	"testing"
+	"time"
+
+	"github.com/BullionBear/sequex/internal/model/sqx"
+)
+
+func liquidation(side sqx.Side, price, quantity float64, at time.Time) sqx.Liquidation {
+	return sqx.Liquidation{
+		Exchange:       sqx.ExchangeBinancePerp,
+		InstrumentType: sqx.InstrumentTypePerp,
+		Symbol:         sqx.NewSymbol("BTC", "USDT"),
+		Side:           side,
+		Price:          price,
+		Quantity:       quantity,
+		Timestamp:      at.UnixMilli(),
+	}
+}
+
+func TestStats(t *testing.T) {
+	a := New(Options{Window: 10 * time.Minute})
+	now := time.Date(2024, 6, 1, 12, 0, 30, 0, time.UTC)
+	a.now = func() time.Time { return now }
+
+	a.Add(liquidation(sqx.SideSell, 60000, 1, now.Add(-20*time.Minute))) // Outside the window
+	a.Add(liquidation(sqx.SideSell, 60000, 1, now.Add(-2*time.Minute)))
+	a.Add(liquidation(sqx.SideBuy, 61000, 2, now.Add(-2*time.Minute)))
+	a.Add(liquidation(sqx.SideSell, 59000, 0.5, now))
+	eth := liquidation(sqx.SideBuy, 3000, 10, now)
+	eth.Symbol = sqx.NewSymbol("ETH", "USDT")
+	a.Add(eth)
+
+	stats, err := a.Stats("btc-usdt")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if stats.Count != 3 || stats.Long != 89500 || stats.Short != 122000 {
+		t.Errorf("stats = %+v", stats.Notional)
+	}
+	if len(stats.Minutes) != 2 || stats.Minutes[0].Count != 2 || !stats.Minutes[1].Start.Equal(now.Truncate(time.Minute)) {
+		t.Errorf("minutes = %+v", stats.Minutes)
+	}
+
+	all, _ := a.Stats("")
+	if all.Count != 4 || all.Short != 152000 {
+		t.Errorf("all symbols = %+v", all.Notional)
+	}
+
+	summaries := a.Summaries()
+	if len(summaries) != 2 || summaries[0].Symbol != "BTC-USDT" || summaries[0].LastMinute.Long != 29500 || summaries[0].Total.Count != 3 {
+		t.Errorf("summaries = %+v", summaries)
+	}
+}
+
+func TestHeatmap(t *testing.T) {
+	a := New(Options{})
+	now := time.Now()
+	a.Add(liquidation(sqx.SideSell, 60010, 1, now))
+	a.Add(liquidation(sqx.SideSell, 60090, 1, now))
+	a.Add(liquidation(sqx.SideBuy, 60150, 1, now))
+
+	heatmap, err := a.Heatmap("BTC-USDT", 100)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(heatmap.Buckets) != 2 || heatmap.Buckets[0].Low != 60000 || heatmap.Buckets[0].Long != 120100 || heatmap.Buckets[1].Short != 60150 {
+		t.Errorf("buckets = %+v", heatmap.Buckets)
+	}
+
+	// 0.1% of 60150 is rounded to 100
+	if heatmap, _ := a.Heatmap("BTC-USDT", 0); heatmap.Step != 100 {
+		t.Errorf("default step = %v", heatmap.Step)
+	}
+	if _, err := a.Heatmap("", 100); err == nil {
+		t.Error("heatmap across symbols accepted")
+	}
+}
+
+func TestNiceStep(t *testing.T) {
+	tests := []struct{ in, want float64 }{
+		{60.15, 100},
+		{3, 5},
+		{0.0015, 0.002},
+		{1, 1},
+		{0, 0},
+	}
+	for _, tt := range tests {
+		if got := NiceStep(tt.in); got != tt.want {
+			t.Errorf("NiceStep(%v) = %v, want %v", tt.in, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,43 @@
+package liquidation
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Register serves the aggregates of a under /liquidations:
+//
+//	GET /liquidations/summaries
+//	GET /liquidations/stats?symbol=BTC-USDT   (every symbol when omitted)
+//	GET /liquidations/heatmap?symbol=BTC-USDT&step=100
+func Register(routes gin.IRoutes, a *Aggregator) {
+	routes.GET("/liquidations/summaries", func(c *gin.Context) {
+		c.JSON(http.StatusOK, a.Summaries())
+	})
+	routes.GET("/liquidations/stats", func(c *gin.Context) {
+		stats, err := a.Stats(c.Query("symbol"))
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusOK, stats)
+	})
+	routes.GET("/liquidations/heatmap", func(c *gin.Context) {
+		var step float64
+		if s := c.Query("step"); s != "" {
+			var err error
+			if step, err = strconv.ParseFloat(s, 64); err != nil {
+				c.JSON(http.StatusBadRequest, gin.H{"error": "invalid step: " + err.Error()})
+				return
+			}
+		}
+		heatmap, err := a.Heatmap(c.Query("symbol"), step)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusOK, heatmap)
+	})
+}
@@ -0,0 +1,48 @@
+package liquidation
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/BullionBear/sequex/internal/model/sqx"
+	"github.com/gin-gonic/gin"
+)
+
+func TestHandlers(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	a := New(Options{})
+	a.Add(liquidation(sqx.SideSell, 60050, 2, time.Now()))
+	rg := gin.New()
+	Register(rg, a)
+
+	get := func(path string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		rg.ServeHTTP(w, httptest.NewRequest(http.MethodGet, path, nil))
+		return w
+	}
+
+	w := get("/liquidations/heatmap?symbol=BTC-USDT&step=100")
+	var heatmap Heatmap
+	if w.Code != http.StatusOK || json.Unmarshal(w.Body.Bytes(), &heatmap) != nil {
+		t.Fatalf("heatmap: %d %s", w.Code, w.Body)
+	}
+	if len(heatmap.Buckets) != 1 || heatmap.Buckets[0].Low != 60000 || heatmap.Buckets[0].Long != 120100 {
+		t.Errorf("heatmap = %+v", heatmap)
+	}
+
+	w = get("/liquidations/stats")
+	var stats Stats
+	if w.Code != http.StatusOK || json.Unmarshal(w.Body.Bytes(), &stats) != nil || stats.Count != 1 {
+		t.Errorf("stats: %d %s", w.Code, w.Body)
+	}
+
+	if w := get("/liquidations/heatmap?symbol=BTC-USDT&step=x"); w.Code != http.StatusBadRequest {
+		t.Errorf("invalid step: %d", w.Code)
+	}
+	if w := get("/liquidations/stats?symbol=BTCUSDT"); w.Code != http.StatusBadRequest {
+		t.Errorf("invalid symbol: %d", w.Code)
+	}
+}
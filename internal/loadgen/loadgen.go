@@ -0,0 +1,407 @@
+// Package loadgen publishes synthetic trades at a steady rate to size the
+// NATS and JetStream deployment of the pipeline. It measures the latency of
+// every publish, how far the publishers fall behind the schedule, and the
+// lag of the downstream durable consumers while the load runs and until they
+// drain.
+package loadgen
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"slices"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/BullionBear/sequex/env"
+	"github.com/BullionBear/sequex/internal/lag"
+	"github.com/BullionBear/sequex/internal/model/sqx"
+	"github.com/BullionBear/sequex/internal/publisher"
+	"github.com/BullionBear/sequex/internal/replay"
+	"github.com/BullionBear/sequex/pkg/lob"
+	"github.com/BullionBear/sequex/pkg/synthdata"
+	"github.com/nats-io/nats.go"
+)
+
+// Options configures a load run
+type Options struct {
+	Rate         float64          // Trades per second over all symbols
+	Symbols      int              // Symbols the trades are spread over
+	Duration     time.Duration    // Of the publishing
+	Workers      int              // Publishing concurrently, 1 when 0
+	Subjects     *replay.Subjects // Subject of each trade
+	Seed         int64            // Of the synthetic markets, 1 when 0
+	LagInterval  time.Duration    // Between consumer lag polls, 1s when 0
+	DrainTimeout time.Duration    // Wait for the consumers to drain after publishing, 0 to not wait
+}
+
+// Validate validates the options
+func (o Options) Validate() error {
+	if o.Rate <= 0 {
+		return fmt.Errorf("rate must be positive")
+	}
+	if o.Symbols <= 0 {
+		return fmt.Errorf("symbols must be positive")
+	}
+	if o.Duration <= 0 {
+		return fmt.Errorf("duration must be positive")
+	}
+	if o.Workers < 0 || o.LagInterval < 0 || o.DrainTimeout < 0 {
+		return fmt.Errorf("workers, lag interval and drain timeout cannot be negative")
+	}
+	if o.Subjects == nil {
+		return fmt.Errorf("subjects are required")
+	}
+	return nil
+}
+
+// Publish sends a message and returns once it is published, acknowledged by
+// JetStream when publishing to a stream. It is called concurrently by the
+// workers.
+type Publish func(msg *nats.Msg) error
+
+// Lags returns the current lag of the downstream consumers
+type Lags func(ctx context.Context) ([]lag.Gauge, error)
+
+// Latencies summarizes durations
+type Latencies struct {
+	P50  time.Duration `json:"p50_ns"`
+	P90  time.Duration `json:"p90_ns"`
+	P99  time.Duration `json:"p99_ns"`
+	P999 time.Duration `json:"p999_ns"`
+	Max  time.Duration `json:"max_ns"`
+}
+
+// ConsumerLag is the lag of a downstream consumer over a run
+type ConsumerLag struct {
+	Stream   string `json:"stream"`
+	Consumer string `json:"consumer"`
+	MaxLag   uint64 `json:"max_lag"`
+	FinalLag uint64 `json:"final_lag"` // At the last poll
+	// Time from the end of the publishing until the lag was back to 0, when
+	// Drained
+	Drain   time.Duration `json:"drain_ns"`
+	Drained bool          `json:"drained"`
+}
+
+// Report is the measurement of a run
+type Report struct {
+	Rate       float64       `json:"rate"` // Targeted
+	Symbols    int           `json:"symbols"`
+	Workers    int           `json:"workers"`
+	Published  uint64        `json:"published"`
+	Errors     uint64        `json:"errors"`
+	Elapsed    time.Duration `json:"elapsed_ns"`
+	Throughput float64       `json:"throughput"` // Achieved, trades per second
+	// Latencies of the publishes, from the call to its return
+	Publish Latencies `json:"publish"`
+	// Delays of the publishes behind their schedule, growing when the
+	// publishers cannot sustain the rate
+	Behind    Latencies     `json:"behind"`
+	Consumers []ConsumerLag `json:"consumers,omitempty"`
+	LagErrors int           `json:"lag_errors,omitempty"` // Failed polls
+	LastError string        `json:"last_error,omitempty"`
+
+	Build env.BuildInfo `json:"build"`
+	At    time.Time     `json:"at"`
+}
+
+type job struct {
+	trade sqx.Trade
+	due   time.Time
+}
+
+// worker holds the measurements of a publishing goroutine
+type worker struct {
+	latencies []time.Duration
+	behind    []time.Duration
+	errors    uint64
+	lastErr   error
+}
+
+// Run publishes the trades until the duration elapses or ctx is done, then
+// waits for the consumers to drain. lags may be nil when no consumer is
+// watched.
+func Run(ctx context.Context, opts Options, publish Publish, lags Lags) (Report, error) {
+	if err := opts.Validate(); err != nil {
+		return Report{}, err
+	}
+	if opts.Workers == 0 {
+		opts.Workers = 1
+	}
+	if opts.Seed == 0 {
+		opts.Seed = 1
+	}
+	if opts.LagInterval == 0 {
+		opts.LagInterval = time.Second
+	}
+	source, err := newSource(opts.Symbols, opts.Seed)
+	if err != nil {
+		return Report{}, err
+	}
+
+	var watch *watcher
+	if lags != nil {
+		watch = newWatcher(lags)
+		go watch.run(ctx, opts.LagInterval)
+	}
+
+	jobs := make(chan job, opts.Workers)
+	workers := make([]worker, opts.Workers)
+	var wg sync.WaitGroup
+	for i := range workers {
+		wg.Add(1)
+		go func(w *worker) {
+			defer wg.Done()
+			encoder := publisher.NewTradeEncoder()
+			for j := range jobs {
+				msg, err := encoder.Encode(opts.Subjects.Subject(&j.trade), &j.trade)
+				sent := time.Now()
+				if err == nil {
+					err = publish(msg)
+				}
+				w.latencies = append(w.latencies, time.Since(sent))
+				w.behind = append(w.behind, max(sent.Sub(j.due), 0))
+				if err != nil {
+					w.errors++
+					w.lastErr = err
+				}
+			}
+		}(&workers[i])
+	}
+
+	// Trades are due at regular intervals from the start; a late publisher
+	// catches up by publishing the trades due without waiting
+	start := time.Now()
+	interval := time.Duration(float64(time.Second) / opts.Rate)
+	timer := time.NewTimer(0)
+	defer timer.Stop()
+schedule:
+	for i := 0; ; i++ {
+		due := start.Add(time.Duration(i) * interval)
+		if due.Sub(start) >= opts.Duration {
+			break
+		}
+		if wait := time.Until(due); wait > 0 {
+			timer.Reset(wait)
+			select {
+			case <-ctx.Done():
+				break schedule
+			case <-timer.C:
+			}
+		} else if ctx.Err() != nil {
+			break
+		}
+		select {
+		case <-ctx.Done():
+			break schedule
+		case jobs <- job{trade: source.next(due), due: due}:
+		}
+	}
+	close(jobs)
+	wg.Wait()
+	elapsed := time.Since(start)
+
+	r := Report{
+		Rate:    opts.Rate,
+		Symbols: opts.Symbols,
+		Workers: opts.Workers,
+		Elapsed: elapsed,
+		Build:   env.Info(),
+		At:      time.Now().UTC(),
+	}
+	var latencies, behind []time.Duration
+	for _, w := range workers {
+		latencies = append(latencies, w.latencies...)
+		behind = append(behind, w.behind...)
+		r.Errors += w.errors
+		if w.lastErr != nil {
+			r.LastError = w.lastErr.Error()
+		}
+	}
+	r.Published = uint64(len(latencies)) - r.Errors
+	r.Throughput = float64(r.Published) / elapsed.Seconds()
+	r.Publish = summarize(latencies)
+	r.Behind = summarize(behind)
+
+	if watch != nil {
+		r.Consumers, r.LagErrors = watch.drain(ctx, time.Now(), opts.DrainTimeout, opts.LagInterval)
+	}
+	return r, nil
+}
+
+// summarize returns the nearest rank percentiles of durations
+func summarize(durations []time.Duration) Latencies {
+	if len(durations) == 0 {
+		return Latencies{}
+	}
+	slices.Sort(durations)
+	percentile := func(q float64) time.Duration {
+		rank := int(math.Ceil(q*float64(len(durations)))) - 1
+		return durations[max(rank, 0)]
+	}
+	return Latencies{
+		P50:  percentile(0.5),
+		P90:  percentile(0.9),
+		P99:  percentile(0.99),
+		P999: percentile(0.999),
+		Max:  durations[len(durations)-1],
+	}
+}
+
+// source generates the trades of synthetic markets, one per symbol, taking
+// turns. Prices and quantities are synthetic, timestamps are the time a
+// trade is due so the consumers see live trades.
+type source struct {
+	markets []*synthdata.Generator
+	symbols []sqx.Symbol
+	id      int64
+	turn    int
+}
+
+func newSource(symbols int, seed int64) (*source, error) {
+	s := &source{
+		// Ids from the start time do not collide with the ids of earlier
+		// runs within the JetStream duplicate window
+		id: time.Now().UnixMicro(),
+	}
+	for k := 0; k < symbols; k++ {
+		g, err := synthdata.New(synthdata.Config{
+			Start:   time.Now(),
+			Price:   100 * float64(k+1),
+			Regimes: []synthdata.Regime{{Volatility: 0.8, TradeRate: 10}},
+			Depth:   synthdata.Depth{Interval: time.Hour}, // Only trades are used
+			Seed:    seed + int64(k),
+		})
+		if err != nil {
+			return nil, err
+		}
+		s.markets = append(s.markets, g)
+		s.symbols = append(s.symbols, sqx.NewSymbol(fmt.Sprintf("SYN%d", k), "USDT"))
+	}
+	return s, nil
+}
+
+func (s *source) next(due time.Time) sqx.Trade {
+	k := s.turn
+	s.turn = (s.turn + 1) % len(s.markets)
+	s.id++
+	var e lob.Event
+	for e = s.markets[k].Next(); e.Type != lob.EventTrade; e = s.markets[k].Next() {
+	}
+	side := sqx.SideBuy
+	if e.Side == lob.Sell {
+		side = sqx.SideSell
+	}
+	return sqx.Trade{
+		Id:             s.id,
+		Symbol:         s.symbols[k],
+		Exchange:       sqx.ExchangeBinance,
+		InstrumentType: sqx.InstrumentTypeSpot,
+		TakerSide:      side,
+		Price:          e.Price,
+		Quantity:       e.Quantity,
+		Timestamp:      due.UnixMilli(),
+	}
+}
+
+// watcher polls the consumer lags while the load runs
+type watcher struct {
+	lags Lags
+	stop chan struct{}
+	done chan struct{}
+
+	mu        sync.Mutex
+	consumers map[string]*ConsumerLag
+	errors    int
+}
+
+func newWatcher(lags Lags) *watcher {
+	return &watcher{
+		lags:      lags,
+		stop:      make(chan struct{}),
+		done:      make(chan struct{}),
+		consumers: make(map[string]*ConsumerLag),
+	}
+}
+
+func (w *watcher) run(ctx context.Context, interval time.Duration) {
+	defer close(w.done)
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		w.poll(ctx, interval)
+		select {
+		case <-ctx.Done():
+			return
+		case <-w.stop:
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// poll observes the lags, and reports whether every consumer is drained
+func (w *watcher) poll(ctx context.Context, timeout time.Duration) bool {
+	pollCtx, cancel := context.WithTimeout(ctx, timeout)
+	gauges, err := w.lags(pollCtx)
+	cancel()
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if err != nil {
+		w.errors++
+		return false
+	}
+	drained := true
+	for _, g := range gauges {
+		key := g.Stream + "/" + g.Consumer
+		c, ok := w.consumers[key]
+		if !ok {
+			c = &ConsumerLag{Stream: g.Stream, Consumer: g.Consumer}
+			w.consumers[key] = c
+		}
+		c.MaxLag = max(c.MaxLag, g.Lag)
+		c.FinalLag = g.Lag
+		drained = drained && g.Lag == 0
+	}
+	return drained
+}
+
+// drain stops the polling of the run and polls until every consumer is back
+// to no lag or timeout elapses since end, the end of the publishing
+func (w *watcher) drain(ctx context.Context, end time.Time, timeout, interval time.Duration) ([]ConsumerLag, int) {
+	close(w.stop)
+	<-w.done
+	for {
+		drained := w.poll(ctx, interval)
+		w.mu.Lock()
+		for _, c := range w.consumers {
+			if c.FinalLag == 0 && !c.Drained {
+				c.Drained, c.Drain = true, time.Since(end)
+			}
+		}
+		w.mu.Unlock()
+		if drained || time.Since(end) >= timeout || ctx.Err() != nil {
+			break
+		}
+		select {
+		case <-ctx.Done():
+		case <-time.After(interval):
+		}
+	}
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	consumers := make([]ConsumerLag, 0, len(w.consumers))
+	for _, c := range w.consumers {
+		consumers = append(consumers, *c)
+	}
+	sort.Slice(consumers, func(i, j int) bool {
+		if consumers[i].Stream != consumers[j].Stream {
+			return consumers[i].Stream < consumers[j].Stream
+		}
+		return consumers[i].Consumer < consumers[j].Consumer
+	})
+	return consumers, w.errors
+}
@@ -0,0 +1,152 @@
+package loadgen
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/BullionBear/sequex/internal/lag"
+	"github.com/BullionBear/sequex/internal/model/sqx"
+	"github.com/BullionBear/sequex/internal/replay"
+	"github.com/nats-io/nats.go"
+)
+
+func options(t *testing.T) Options {
+	subjects, err := replay.NewSubjects("trade.{exchange}.{instrument}.{symbol}", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return Options{
+		Rate:         2000,
+		Symbols:      3,
+		Duration:     200 * time.Millisecond,
+		Workers:      2,
+		Subjects:     subjects,
+		LagInterval:  10 * time.Millisecond,
+		DrainTimeout: time.Second,
+	}
+}
+
+func TestRun(t *testing.T) {
+	var mu sync.Mutex
+	subjects := make(map[string]int)
+	ids := make(map[int64]bool)
+	// A consumer reading 1000 trades per second falls behind the 2000
+	// published, then catches up
+	var published, consumed atomic.Int64
+	stop := make(chan struct{})
+	go func() {
+		ticker := time.NewTicker(time.Millisecond)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-stop:
+				return
+			case <-ticker.C:
+				if consumed.Load() < published.Load() {
+					consumed.Add(1)
+				}
+			}
+		}
+	}()
+	defer close(stop)
+
+	publish := func(msg *nats.Msg) error {
+		var trade sqx.Trade
+		if err := sqx.Unmarshal(msg.Data, &trade); err != nil {
+			return err
+		}
+		mu.Lock()
+		defer mu.Unlock()
+		if ids[trade.Id] {
+			t.Errorf("trade %d published twice", trade.Id)
+		}
+		ids[trade.Id] = true
+		subjects[msg.Subject]++
+		if msg.Header.Get("Nats-Msg-Id") != trade.IdStr() {
+			t.Errorf("deduplication id %q", msg.Header.Get("Nats-Msg-Id"))
+		}
+		published.Add(1)
+		return nil
+	}
+	lags := func(context.Context) ([]lag.Gauge, error) {
+		return []lag.Gauge{
+			{Stream: "TRADE", Consumer: "slow", Lag: uint64(published.Load() - consumed.Load())},
+			{Stream: "TRADE", Consumer: "fast"},
+		}, nil
+	}
+
+	r, err := Run(context.Background(), options(t), publish, lags)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if r.Published != 400 || r.Errors != 0 || len(ids) != 400 {
+		t.Fatalf("published %d trades, %d errors: %+v", len(ids), r.Errors, r)
+	}
+	if len(subjects) != 3 || subjects["trade.binance.spot.syn0usdt"] == 0 {
+		t.Errorf("subjects %v", subjects)
+	}
+	if r.Publish.P50 > r.Publish.P99 || r.Publish.P99 > r.Publish.Max || r.Throughput < 1000 {
+		t.Errorf("inconsistent report %+v", r)
+	}
+	if len(r.Consumers) != 2 {
+		t.Fatalf("consumers %+v", r.Consumers)
+	}
+	fast, slow := r.Consumers[0], r.Consumers[1]
+	if fast.Consumer != "fast" || fast.MaxLag != 0 || !fast.Drained || fast.Drain > 50*time.Millisecond {
+		t.Errorf("fast consumer %+v", fast)
+	}
+	if slow.MaxLag < 50 || slow.FinalLag != 0 || !slow.Drained || slow.Drain < 10*time.Millisecond {
+		t.Errorf("slow consumer %+v", slow)
+	}
+}
+
+func TestRun_Errors(t *testing.T) {
+	opts := options(t)
+	opts.Rate = 100
+	opts.DrainTimeout = 50 * time.Millisecond
+	failed := errors.New("no responders")
+	r, err := Run(context.Background(), opts, func(*nats.Msg) error { return failed }, func(context.Context) ([]lag.Gauge, error) {
+		return nil, failed
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if r.Published != 0 || r.Errors != 20 || r.LastError != failed.Error() || r.LagErrors == 0 {
+		t.Errorf("report %+v", r)
+	}
+}
+
+func TestRun_Canceled(t *testing.T) {
+	opts := options(t)
+	opts.Duration = time.Hour
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	r, err := Run(ctx, opts, func(*nats.Msg) error { return nil }, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if r.Published == 0 || r.Elapsed > time.Second {
+		t.Errorf("report %+v", r)
+	}
+}
+
+func TestValidate(t *testing.T) {
+	for name, mutate := range map[string]func(*Options){
+		"no rate":     func(o *Options) { o.Rate = 0 },
+		"no symbol":   func(o *Options) { o.Symbols = 0 },
+		"no duration": func(o *Options) { o.Duration = 0 },
+		"no subjects": func(o *Options) { o.Subjects = nil },
+		"workers":     func(o *Options) { o.Workers = -1 },
+		"drain":       func(o *Options) { o.DrainTimeout = -time.Second },
+	} {
+		opts := options(t)
+		mutate(&opts)
+		if err := opts.Validate(); err == nil {
+			t.Errorf("%s: expected an error", name)
+		}
+	}
+}
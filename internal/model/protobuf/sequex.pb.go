@@ -0,0 +1,647 @@
+// Code generated by protoc-gen-go. DO NOT EDIT.
+// versions:
+// 	protoc-gen-go v1.36.9
+// 	protoc        v5.28.3
+// source: protobuf/sequex.proto
+
+package protobuf
+
+import (
+	protoreflect "google.golang.org/protobuf/reflect/protoreflect"
+	protoimpl "google.golang.org/protobuf/runtime/protoimpl"
+	reflect "reflect"
+	sync "sync"
+	unsafe "unsafe"
+)
+
+const (
+	// Verify that this generated code is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(20 - protoimpl.MinVersion)
+	// Verify that runtime/protoimpl is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(protoimpl.MaxVersion - 20)
+)
+
+// MarketRequest selects the market data of one symbol
+type MarketRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Exchange      Exchange               `protobuf:"varint,1,opt,name=exchange,proto3,enum=app.Exchange" json:"exchange,omitempty"`
+	Instrument    Instrument             `protobuf:"varint,2,opt,name=instrument,proto3,enum=app.Instrument" json:"instrument,omitempty"`
+	Symbol        *Symbol                `protobuf:"bytes,3,opt,name=symbol,proto3" json:"symbol,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *MarketRequest) Reset() {
+	*x = MarketRequest{}
+	mi := &file_protobuf_sequex_proto_msgTypes[0]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *MarketRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*MarketRequest) ProtoMessage() {}
+
+func (x *MarketRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_protobuf_sequex_proto_msgTypes[0]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use MarketRequest.ProtoReflect.Descriptor instead.
+func (*MarketRequest) Descriptor() ([]byte, []int) {
+	return file_protobuf_sequex_proto_rawDescGZIP(), []int{0}
+}
+
+func (x *MarketRequest) GetExchange() Exchange {
+	if x != nil {
+		return x.Exchange
+	}
+	return Exchange_EXCHANGE_UNSPECIFIED
+}
+
+func (x *MarketRequest) GetInstrument() Instrument {
+	if x != nil {
+		return x.Instrument
+	}
+	return Instrument_INSTRUMENT_UNSPECIFIED
+}
+
+func (x *MarketRequest) GetSymbol() *Symbol {
+	if x != nil {
+		return x.Symbol
+	}
+	return nil
+}
+
+type KlineRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Market        *MarketRequest         `protobuf:"bytes,1,opt,name=market,proto3" json:"market,omitempty"`
+	Interval      string                 `protobuf:"bytes,2,opt,name=interval,proto3" json:"interval,omitempty"` // e.g. 1m or 4h
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *KlineRequest) Reset() {
+	*x = KlineRequest{}
+	mi := &file_protobuf_sequex_proto_msgTypes[1]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *KlineRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*KlineRequest) ProtoMessage() {}
+
+func (x *KlineRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_protobuf_sequex_proto_msgTypes[1]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use KlineRequest.ProtoReflect.Descriptor instead.
+func (*KlineRequest) Descriptor() ([]byte, []int) {
+	return file_protobuf_sequex_proto_rawDescGZIP(), []int{1}
+}
+
+func (x *KlineRequest) GetMarket() *MarketRequest {
+	if x != nil {
+		return x.Market
+	}
+	return nil
+}
+
+func (x *KlineRequest) GetInterval() string {
+	if x != nil {
+		return x.Interval
+	}
+	return ""
+}
+
+// Kline is a closed candle
+type Kline struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Interval      string                 `protobuf:"bytes,1,opt,name=interval,proto3" json:"interval,omitempty"`
+	OpenTime      int64                  `protobuf:"varint,2,opt,name=open_time,json=openTime,proto3" json:"open_time,omitempty"` // Unix milliseconds
+	Open          float64                `protobuf:"fixed64,3,opt,name=open,proto3" json:"open,omitempty"`
+	High          float64                `protobuf:"fixed64,4,opt,name=high,proto3" json:"high,omitempty"`
+	Low           float64                `protobuf:"fixed64,5,opt,name=low,proto3" json:"low,omitempty"`
+	Close         float64                `protobuf:"fixed64,6,opt,name=close,proto3" json:"close,omitempty"`
+	Volume        float64                `protobuf:"fixed64,7,opt,name=volume,proto3" json:"volume,omitempty"`
+	QuoteVolume   float64                `protobuf:"fixed64,8,opt,name=quote_volume,json=quoteVolume,proto3" json:"quote_volume,omitempty"`
+	Trades        int64                  `protobuf:"varint,9,opt,name=trades,proto3" json:"trades,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *Kline) Reset() {
+	*x = Kline{}
+	mi := &file_protobuf_sequex_proto_msgTypes[2]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *Kline) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Kline) ProtoMessage() {}
+
+func (x *Kline) ProtoReflect() protoreflect.Message {
+	mi := &file_protobuf_sequex_proto_msgTypes[2]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Kline.ProtoReflect.Descriptor instead.
+func (*Kline) Descriptor() ([]byte, []int) {
+	return file_protobuf_sequex_proto_rawDescGZIP(), []int{2}
+}
+
+func (x *Kline) GetInterval() string {
+	if x != nil {
+		return x.Interval
+	}
+	return ""
+}
+
+func (x *Kline) GetOpenTime() int64 {
+	if x != nil {
+		return x.OpenTime
+	}
+	return 0
+}
+
+func (x *Kline) GetOpen() float64 {
+	if x != nil {
+		return x.Open
+	}
+	return 0
+}
+
+func (x *Kline) GetHigh() float64 {
+	if x != nil {
+		return x.High
+	}
+	return 0
+}
+
+func (x *Kline) GetLow() float64 {
+	if x != nil {
+		return x.Low
+	}
+	return 0
+}
+
+func (x *Kline) GetClose() float64 {
+	if x != nil {
+		return x.Close
+	}
+	return 0
+}
+
+func (x *Kline) GetVolume() float64 {
+	if x != nil {
+		return x.Volume
+	}
+	return 0
+}
+
+func (x *Kline) GetQuoteVolume() float64 {
+	if x != nil {
+		return x.QuoteVolume
+	}
+	return 0
+}
+
+func (x *Kline) GetTrades() int64 {
+	if x != nil {
+		return x.Trades
+	}
+	return 0
+}
+
+type PriceLevel struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Price         float64                `protobuf:"fixed64,1,opt,name=price,proto3" json:"price,omitempty"`
+	Quantity      float64                `protobuf:"fixed64,2,opt,name=quantity,proto3" json:"quantity,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *PriceLevel) Reset() {
+	*x = PriceLevel{}
+	mi := &file_protobuf_sequex_proto_msgTypes[3]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *PriceLevel) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*PriceLevel) ProtoMessage() {}
+
+func (x *PriceLevel) ProtoReflect() protoreflect.Message {
+	mi := &file_protobuf_sequex_proto_msgTypes[3]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use PriceLevel.ProtoReflect.Descriptor instead.
+func (*PriceLevel) Descriptor() ([]byte, []int) {
+	return file_protobuf_sequex_proto_rawDescGZIP(), []int{3}
+}
+
+func (x *PriceLevel) GetPrice() float64 {
+	if x != nil {
+		return x.Price
+	}
+	return 0
+}
+
+func (x *PriceLevel) GetQuantity() float64 {
+	if x != nil {
+		return x.Quantity
+	}
+	return 0
+}
+
+// DepthUpdate is a full book when snapshot is set, otherwise the absolute
+// quantities of the levels changed, zero removing a level
+type DepthUpdate struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Snapshot      bool                   `protobuf:"varint,1,opt,name=snapshot,proto3" json:"snapshot,omitempty"`
+	Time          int64                  `protobuf:"varint,2,opt,name=time,proto3" json:"time,omitempty"` // Unix milliseconds
+	Bids          []*PriceLevel          `protobuf:"bytes,3,rep,name=bids,proto3" json:"bids,omitempty"`
+	Asks          []*PriceLevel          `protobuf:"bytes,4,rep,name=asks,proto3" json:"asks,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *DepthUpdate) Reset() {
+	*x = DepthUpdate{}
+	mi := &file_protobuf_sequex_proto_msgTypes[4]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *DepthUpdate) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*DepthUpdate) ProtoMessage() {}
+
+func (x *DepthUpdate) ProtoReflect() protoreflect.Message {
+	mi := &file_protobuf_sequex_proto_msgTypes[4]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use DepthUpdate.ProtoReflect.Descriptor instead.
+func (*DepthUpdate) Descriptor() ([]byte, []int) {
+	return file_protobuf_sequex_proto_rawDescGZIP(), []int{4}
+}
+
+func (x *DepthUpdate) GetSnapshot() bool {
+	if x != nil {
+		return x.Snapshot
+	}
+	return false
+}
+
+func (x *DepthUpdate) GetTime() int64 {
+	if x != nil {
+		return x.Time
+	}
+	return 0
+}
+
+func (x *DepthUpdate) GetBids() []*PriceLevel {
+	if x != nil {
+		return x.Bids
+	}
+	return nil
+}
+
+func (x *DepthUpdate) GetAsks() []*PriceLevel {
+	if x != nil {
+		return x.Asks
+	}
+	return nil
+}
+
+type Event struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Subject       string                 `protobuf:"bytes,1,opt,name=subject,proto3" json:"subject,omitempty"`
+	Data          []byte                 `protobuf:"bytes,2,opt,name=data,proto3" json:"data,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *Event) Reset() {
+	*x = Event{}
+	mi := &file_protobuf_sequex_proto_msgTypes[5]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *Event) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Event) ProtoMessage() {}
+
+func (x *Event) ProtoReflect() protoreflect.Message {
+	mi := &file_protobuf_sequex_proto_msgTypes[5]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Event.ProtoReflect.Descriptor instead.
+func (*Event) Descriptor() ([]byte, []int) {
+	return file_protobuf_sequex_proto_rawDescGZIP(), []int{5}
+}
+
+func (x *Event) GetSubject() string {
+	if x != nil {
+		return x.Subject
+	}
+	return ""
+}
+
+func (x *Event) GetData() []byte {
+	if x != nil {
+		return x.Data
+	}
+	return nil
+}
+
+type EventRequest struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	// Types that are valid to be assigned to Action:
+	//
+	//	*EventRequest_Subscribe
+	//	*EventRequest_Unsubscribe
+	//	*EventRequest_Publish
+	Action        isEventRequest_Action `protobuf_oneof:"action"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *EventRequest) Reset() {
+	*x = EventRequest{}
+	mi := &file_protobuf_sequex_proto_msgTypes[6]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *EventRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*EventRequest) ProtoMessage() {}
+
+func (x *EventRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_protobuf_sequex_proto_msgTypes[6]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use EventRequest.ProtoReflect.Descriptor instead.
+func (*EventRequest) Descriptor() ([]byte, []int) {
+	return file_protobuf_sequex_proto_rawDescGZIP(), []int{6}
+}
+
+func (x *EventRequest) GetAction() isEventRequest_Action {
+	if x != nil {
+		return x.Action
+	}
+	return nil
+}
+
+func (x *EventRequest) GetSubscribe() string {
+	if x != nil {
+		if x, ok := x.Action.(*EventRequest_Subscribe); ok {
+			return x.Subscribe
+		}
+	}
+	return ""
+}
+
+func (x *EventRequest) GetUnsubscribe() string {
+	if x != nil {
+		if x, ok := x.Action.(*EventRequest_Unsubscribe); ok {
+			return x.Unsubscribe
+		}
+	}
+	return ""
+}
+
+func (x *EventRequest) GetPublish() *Event {
+	if x != nil {
+		if x, ok := x.Action.(*EventRequest_Publish); ok {
+			return x.Publish
+		}
+	}
+	return nil
+}
+
+type isEventRequest_Action interface {
+	isEventRequest_Action()
+}
+
+type EventRequest_Subscribe struct {
+	Subscribe string `protobuf:"bytes,1,opt,name=subscribe,proto3,oneof"` // Subject or pattern whose events are received
+}
+
+type EventRequest_Unsubscribe struct {
+	Unsubscribe string `protobuf:"bytes,2,opt,name=unsubscribe,proto3,oneof"`
+}
+
+type EventRequest_Publish struct {
+	Publish *Event `protobuf:"bytes,3,opt,name=publish,proto3,oneof"`
+}
+
+func (*EventRequest_Subscribe) isEventRequest_Action() {}
+
+func (*EventRequest_Unsubscribe) isEventRequest_Action() {}
+
+func (*EventRequest_Publish) isEventRequest_Action() {}
+
+var File_protobuf_sequex_proto protoreflect.FileDescriptor
+
+const file_protobuf_sequex_proto_rawDesc = "" +
+	"\n" +
+	"\x15protobuf/sequex.proto\x12\x03app\x1a\x15protobuf/shared.proto\x1a\x14protobuf/trade.proto\"\x90\x01\n" +
+	"\rMarketRequest\x12)\n" +
+	"\bexchange\x18\x01 \x01(\x0e2\r.app.ExchangeR\bexchange\x12/\n" +
+	"\n" +
+	"instrument\x18\x02 \x01(\x0e2\x0f.app.InstrumentR\n" +
+	"instrument\x12#\n" +
+	"\x06symbol\x18\x03 \x01(\v2\v.app.SymbolR\x06symbol\"V\n" +
+	"\fKlineRequest\x12*\n" +
+	"\x06market\x18\x01 \x01(\v2\x12.app.MarketRequestR\x06market\x12\x1a\n" +
+	"\binterval\x18\x02 \x01(\tR\binterval\"\xe3\x01\n" +
+	"\x05Kline\x12\x1a\n" +
+	"\binterval\x18\x01 \x01(\tR\binterval\x12\x1b\n" +
+	"\topen_time\x18\x02 \x01(\x03R\bopenTime\x12\x12\n" +
+	"\x04open\x18\x03 \x01(\x01R\x04open\x12\x12\n" +
+	"\x04high\x18\x04 \x01(\x01R\x04high\x12\x10\n" +
+	"\x03low\x18\x05 \x01(\x01R\x03low\x12\x14\n" +
+	"\x05close\x18\x06 \x01(\x01R\x05close\x12\x16\n" +
+	"\x06volume\x18\a \x01(\x01R\x06volume\x12!\n" +
+	"\fquote_volume\x18\b \x01(\x01R\vquoteVolume\x12\x16\n" +
+	"\x06trades\x18\t \x01(\x03R\x06trades\">\n" +
+	"\n" +
+	"PriceLevel\x12\x14\n" +
+	"\x05price\x18\x01 \x01(\x01R\x05price\x12\x1a\n" +
+	"\bquantity\x18\x02 \x01(\x01R\bquantity\"\x87\x01\n" +
+	"\vDepthUpdate\x12\x1a\n" +
+	"\bsnapshot\x18\x01 \x01(\bR\bsnapshot\x12\x12\n" +
+	"\x04time\x18\x02 \x01(\x03R\x04time\x12#\n" +
+	"\x04bids\x18\x03 \x03(\v2\x0f.app.PriceLevelR\x04bids\x12#\n" +
+	"\x04asks\x18\x04 \x03(\v2\x0f.app.PriceLevelR\x04asks\"5\n" +
+	"\x05Event\x12\x18\n" +
+	"\asubject\x18\x01 \x01(\tR\asubject\x12\x12\n" +
+	"\x04data\x18\x02 \x01(\fR\x04data\"\x84\x01\n" +
+	"\fEventRequest\x12\x1e\n" +
+	"\tsubscribe\x18\x01 \x01(\tH\x00R\tsubscribe\x12\"\n" +
+	"\vunsubscribe\x18\x02 \x01(\tH\x00R\vunsubscribe\x12&\n" +
+	"\apublish\x18\x03 \x01(\v2\n" +
+	".app.EventH\x00R\apublishB\b\n" +
+	"\x06action2\xe5\x01\n" +
+	"\rSequexService\x123\n" +
+	"\x0fSubscribeTrades\x12\x12.app.MarketRequest\x1a\n" +
+	".app.Trade0\x01\x122\n" +
+	"\x0fSubscribeKlines\x12\x11.app.KlineRequest\x1a\n" +
+	".app.Kline0\x01\x128\n" +
+	"\x0eSubscribeDepth\x12\x12.app.MarketRequest\x1a\x10.app.DepthUpdate0\x01\x121\n" +
+	"\fStreamEvents\x12\x11.app.EventRequest\x1a\n" +
+	".app.Event(\x010\x01B7Z5github.com/BullionBear/sequex/internal/model/protobufb\x06proto3"
+
+var (
+	file_protobuf_sequex_proto_rawDescOnce sync.Once
+	file_protobuf_sequex_proto_rawDescData []byte
+)
+
+func file_protobuf_sequex_proto_rawDescGZIP() []byte {
+	file_protobuf_sequex_proto_rawDescOnce.Do(func() {
+		file_protobuf_sequex_proto_rawDescData = protoimpl.X.CompressGZIP(unsafe.Slice(unsafe.StringData(file_protobuf_sequex_proto_rawDesc), len(file_protobuf_sequex_proto_rawDesc)))
+	})
+	return file_protobuf_sequex_proto_rawDescData
+}
+
+var file_protobuf_sequex_proto_msgTypes = make([]protoimpl.MessageInfo, 7)
+var file_protobuf_sequex_proto_goTypes = []any{
+	(*MarketRequest)(nil), // 0: app.MarketRequest
+	(*KlineRequest)(nil),  // 1: app.KlineRequest
+	(*Kline)(nil),         // 2: app.Kline
+	(*PriceLevel)(nil),    // 3: app.PriceLevel
+	(*DepthUpdate)(nil),   // 4: app.DepthUpdate
+	(*Event)(nil),         // 5: app.Event
+	(*EventRequest)(nil),  // 6: app.EventRequest
+	(Exchange)(0),         // 7: app.Exchange
+	(Instrument)(0),       // 8: app.Instrument
+	(*Symbol)(nil),        // 9: app.Symbol
+	(*Trade)(nil),         // 10: app.Trade
+}
+var file_protobuf_sequex_proto_depIdxs = []int32{
+	7,  // 0: app.MarketRequest.exchange:type_name -> app.Exchange
+	8,  // 1: app.MarketRequest.instrument:type_name -> app.Instrument
+	9,  // 2: app.MarketRequest.symbol:type_name -> app.Symbol
+	0,  // 3: app.KlineRequest.market:type_name -> app.MarketRequest
+	3,  // 4: app.DepthUpdate.bids:type_name -> app.PriceLevel
+	3,  // 5: app.DepthUpdate.asks:type_name -> app.PriceLevel
+	5,  // 6: app.EventRequest.publish:type_name -> app.Event
+	0,  // 7: app.SequexService.SubscribeTrades:input_type -> app.MarketRequest
+	1,  // 8: app.SequexService.SubscribeKlines:input_type -> app.KlineRequest
+	0,  // 9: app.SequexService.SubscribeDepth:input_type -> app.MarketRequest
+	6,  // 10: app.SequexService.StreamEvents:input_type -> app.EventRequest
+	10, // 11: app.SequexService.SubscribeTrades:output_type -> app.Trade
+	2,  // 12: app.SequexService.SubscribeKlines:output_type -> app.Kline
+	4,  // 13: app.SequexService.SubscribeDepth:output_type -> app.DepthUpdate
+	5,  // 14: app.SequexService.StreamEvents:output_type -> app.Event
+	11, // [11:15] is the sub-list for method output_type
+	7,  // [7:11] is the sub-list for method input_type
+	7,  // [7:7] is the sub-list for extension type_name
+	7,  // [7:7] is the sub-list for extension extendee
+	0,  // [0:7] is the sub-list for field type_name
+}
+
+func init() { file_protobuf_sequex_proto_init() }
+func file_protobuf_sequex_proto_init() {
+	if File_protobuf_sequex_proto != nil {
+		return
+	}
+	file_protobuf_shared_proto_init()
+	file_protobuf_trade_proto_init()
+	file_protobuf_sequex_proto_msgTypes[6].OneofWrappers = []any{
+		(*EventRequest_Subscribe)(nil),
+		(*EventRequest_Unsubscribe)(nil),
+		(*EventRequest_Publish)(nil),
+	}
+	type x struct{}
+	out := protoimpl.TypeBuilder{
+		File: protoimpl.DescBuilder{
+			GoPackagePath: reflect.TypeOf(x{}).PkgPath(),
+			RawDescriptor: unsafe.Slice(unsafe.StringData(file_protobuf_sequex_proto_rawDesc), len(file_protobuf_sequex_proto_rawDesc)),
+			NumEnums:      0,
+			NumMessages:   7,
+			NumExtensions: 0,
+			NumServices:   1,
+		},
+		GoTypes:           file_protobuf_sequex_proto_goTypes,
+		DependencyIndexes: file_protobuf_sequex_proto_depIdxs,
+		MessageInfos:      file_protobuf_sequex_proto_msgTypes,
+	}.Build()
+	File_protobuf_sequex_proto = out.File
+	file_protobuf_sequex_proto_goTypes = nil
+	file_protobuf_sequex_proto_depIdxs = nil
+}
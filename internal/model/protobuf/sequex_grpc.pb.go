@@ -0,0 +1,254 @@
+// Code generated by protoc-gen-go-grpc. DO NOT EDIT.
+// versions:
+// - protoc-gen-go-grpc v1.5.1
+// - protoc             v5.28.3
+// source: protobuf/sequex.proto
+
+package protobuf
+
+import (
+	context "context"
+	grpc "google.golang.org/grpc"
+	codes "google.golang.org/grpc/codes"
+	status "google.golang.org/grpc/status"
+)
+
+// This is a compile-time assertion to ensure that this generated file
+// is compatible with the grpc package it is being compiled against.
+// Requires gRPC-Go v1.64.0 or later.
+const _ = grpc.SupportPackageIsVersion9
+
+const (
+	SequexService_SubscribeTrades_FullMethodName = "/app.SequexService/SubscribeTrades"
+	SequexService_SubscribeKlines_FullMethodName = "/app.SequexService/SubscribeKlines"
+	SequexService_SubscribeDepth_FullMethodName  = "/app.SequexService/SubscribeDepth"
+	SequexService_StreamEvents_FullMethodName    = "/app.SequexService/StreamEvents"
+)
+
+// SequexServiceClient is the client API for SequexService service.
+//
+// For semantics around ctx use and closing/ending streaming RPCs, please refer to https://pkg.go.dev/google.golang.org/grpc/?tab=doc#ClientConn.NewStream.
+//
+// SequexService streams market data to external clients and bridges the
+// events of external strategies onto the bus. Every call carries an
+// authorization: Bearer <token> metadata entry. A stream falling behind
+// by more than its send buffer is ended with RESOURCE_EXHAUSTED rather
+// than silently skipping messages, so clients resubscribe.
+type SequexServiceClient interface {
+	SubscribeTrades(ctx context.Context, in *MarketRequest, opts ...grpc.CallOption) (grpc.ServerStreamingClient[Trade], error)
+	SubscribeKlines(ctx context.Context, in *KlineRequest, opts ...grpc.CallOption) (grpc.ServerStreamingClient[Kline], error)
+	SubscribeDepth(ctx context.Context, in *MarketRequest, opts ...grpc.CallOption) (grpc.ServerStreamingClient[DepthUpdate], error)
+	// StreamEvents receives the events of the subjects subscribed to and
+	// publishes those sent, both within the grants of the token
+	StreamEvents(ctx context.Context, opts ...grpc.CallOption) (grpc.BidiStreamingClient[EventRequest, Event], error)
+}
+
+type sequexServiceClient struct {
+	cc grpc.ClientConnInterface
+}
+
+func NewSequexServiceClient(cc grpc.ClientConnInterface) SequexServiceClient {
+	return &sequexServiceClient{cc}
+}
+
+func (c *sequexServiceClient) SubscribeTrades(ctx context.Context, in *MarketRequest, opts ...grpc.CallOption) (grpc.ServerStreamingClient[Trade], error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	stream, err := c.cc.NewStream(ctx, &SequexService_ServiceDesc.Streams[0], SequexService_SubscribeTrades_FullMethodName, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &grpc.GenericClientStream[MarketRequest, Trade]{ClientStream: stream}
+	if err := x.ClientStream.SendMsg(in); err != nil {
+		return nil, err
+	}
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	return x, nil
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type SequexService_SubscribeTradesClient = grpc.ServerStreamingClient[Trade]
+
+func (c *sequexServiceClient) SubscribeKlines(ctx context.Context, in *KlineRequest, opts ...grpc.CallOption) (grpc.ServerStreamingClient[Kline], error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	stream, err := c.cc.NewStream(ctx, &SequexService_ServiceDesc.Streams[1], SequexService_SubscribeKlines_FullMethodName, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &grpc.GenericClientStream[KlineRequest, Kline]{ClientStream: stream}
+	if err := x.ClientStream.SendMsg(in); err != nil {
+		return nil, err
+	}
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	return x, nil
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type SequexService_SubscribeKlinesClient = grpc.ServerStreamingClient[Kline]
+
+func (c *sequexServiceClient) SubscribeDepth(ctx context.Context, in *MarketRequest, opts ...grpc.CallOption) (grpc.ServerStreamingClient[DepthUpdate], error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	stream, err := c.cc.NewStream(ctx, &SequexService_ServiceDesc.Streams[2], SequexService_SubscribeDepth_FullMethodName, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &grpc.GenericClientStream[MarketRequest, DepthUpdate]{ClientStream: stream}
+	if err := x.ClientStream.SendMsg(in); err != nil {
+		return nil, err
+	}
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	return x, nil
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type SequexService_SubscribeDepthClient = grpc.ServerStreamingClient[DepthUpdate]
+
+func (c *sequexServiceClient) StreamEvents(ctx context.Context, opts ...grpc.CallOption) (grpc.BidiStreamingClient[EventRequest, Event], error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	stream, err := c.cc.NewStream(ctx, &SequexService_ServiceDesc.Streams[3], SequexService_StreamEvents_FullMethodName, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &grpc.GenericClientStream[EventRequest, Event]{ClientStream: stream}
+	return x, nil
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type SequexService_StreamEventsClient = grpc.BidiStreamingClient[EventRequest, Event]
+
+// SequexServiceServer is the server API for SequexService service.
+// All implementations must embed UnimplementedSequexServiceServer
+// for forward compatibility.
+//
+// SequexService streams market data to external clients and bridges the
+// events of external strategies onto the bus. Every call carries an
+// authorization: Bearer <token> metadata entry. A stream falling behind
+// by more than its send buffer is ended with RESOURCE_EXHAUSTED rather
+// than silently skipping messages, so clients resubscribe.
+type SequexServiceServer interface {
+	SubscribeTrades(*MarketRequest, grpc.ServerStreamingServer[Trade]) error
+	SubscribeKlines(*KlineRequest, grpc.ServerStreamingServer[Kline]) error
+	SubscribeDepth(*MarketRequest, grpc.ServerStreamingServer[DepthUpdate]) error
+	// StreamEvents receives the events of the subjects subscribed to and
+	// publishes those sent, both within the grants of the token
+	StreamEvents(grpc.BidiStreamingServer[EventRequest, Event]) error
+	mustEmbedUnimplementedSequexServiceServer()
+}
+
+// UnimplementedSequexServiceServer must be embedded to have
+// forward compatible implementations.
+//
+// NOTE: this should be embedded by value instead of pointer to avoid a nil
+// pointer dereference when methods are called.
+type UnimplementedSequexServiceServer struct{}
+
+func (UnimplementedSequexServiceServer) SubscribeTrades(*MarketRequest, grpc.ServerStreamingServer[Trade]) error {
+	return status.Errorf(codes.Unimplemented, "method SubscribeTrades not implemented")
+}
+func (UnimplementedSequexServiceServer) SubscribeKlines(*KlineRequest, grpc.ServerStreamingServer[Kline]) error {
+	return status.Errorf(codes.Unimplemented, "method SubscribeKlines not implemented")
+}
+func (UnimplementedSequexServiceServer) SubscribeDepth(*MarketRequest, grpc.ServerStreamingServer[DepthUpdate]) error {
+	return status.Errorf(codes.Unimplemented, "method SubscribeDepth not implemented")
+}
+func (UnimplementedSequexServiceServer) StreamEvents(grpc.BidiStreamingServer[EventRequest, Event]) error {
+	return status.Errorf(codes.Unimplemented, "method StreamEvents not implemented")
+}
+func (UnimplementedSequexServiceServer) mustEmbedUnimplementedSequexServiceServer() {}
+func (UnimplementedSequexServiceServer) testEmbeddedByValue()                       {}
+
+// UnsafeSequexServiceServer may be embedded to opt out of forward compatibility for this service.
+// Use of this interface is not recommended, as added methods to SequexServiceServer will
+// result in compilation errors.
+type UnsafeSequexServiceServer interface {
+	mustEmbedUnimplementedSequexServiceServer()
+}
+
+func RegisterSequexServiceServer(s grpc.ServiceRegistrar, srv SequexServiceServer) {
+	// If the following call pancis, it indicates UnimplementedSequexServiceServer was
+	// embedded by pointer and is nil.  This will cause panics if an
+	// unimplemented method is ever invoked, so we test this at initialization
+	// time to prevent it from happening at runtime later due to I/O.
+	if t, ok := srv.(interface{ testEmbeddedByValue() }); ok {
+		t.testEmbeddedByValue()
+	}
+	s.RegisterService(&SequexService_ServiceDesc, srv)
+}
+
+func _SequexService_SubscribeTrades_Handler(srv interface{}, stream grpc.ServerStream) error {
+	m := new(MarketRequest)
+	if err := stream.RecvMsg(m); err != nil {
+		return err
+	}
+	return srv.(SequexServiceServer).SubscribeTrades(m, &grpc.GenericServerStream[MarketRequest, Trade]{ServerStream: stream})
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type SequexService_SubscribeTradesServer = grpc.ServerStreamingServer[Trade]
+
+func _SequexService_SubscribeKlines_Handler(srv interface{}, stream grpc.ServerStream) error {
+	m := new(KlineRequest)
+	if err := stream.RecvMsg(m); err != nil {
+		return err
+	}
+	return srv.(SequexServiceServer).SubscribeKlines(m, &grpc.GenericServerStream[KlineRequest, Kline]{ServerStream: stream})
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type SequexService_SubscribeKlinesServer = grpc.ServerStreamingServer[Kline]
+
+func _SequexService_SubscribeDepth_Handler(srv interface{}, stream grpc.ServerStream) error {
+	m := new(MarketRequest)
+	if err := stream.RecvMsg(m); err != nil {
+		return err
+	}
+	return srv.(SequexServiceServer).SubscribeDepth(m, &grpc.GenericServerStream[MarketRequest, DepthUpdate]{ServerStream: stream})
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type SequexService_SubscribeDepthServer = grpc.ServerStreamingServer[DepthUpdate]
+
+func _SequexService_StreamEvents_Handler(srv interface{}, stream grpc.ServerStream) error {
+	return srv.(SequexServiceServer).StreamEvents(&grpc.GenericServerStream[EventRequest, Event]{ServerStream: stream})
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type SequexService_StreamEventsServer = grpc.BidiStreamingServer[EventRequest, Event]
+
+// SequexService_ServiceDesc is the grpc.ServiceDesc for SequexService service.
+// It's only intended for direct use with grpc.RegisterService,
+// and not to be introspected or modified (even as a copy)
+var SequexService_ServiceDesc = grpc.ServiceDesc{
+	ServiceName: "app.SequexService",
+	HandlerType: (*SequexServiceServer)(nil),
+	Methods:     []grpc.MethodDesc{},
+	Streams: []grpc.StreamDesc{
+		{
+			StreamName:    "SubscribeTrades",
+			Handler:       _SequexService_SubscribeTrades_Handler,
+			ServerStreams: true,
+		},
+		{
+			StreamName:    "SubscribeKlines",
+			Handler:       _SequexService_SubscribeKlines_Handler,
+			ServerStreams: true,
+		},
+		{
+			StreamName:    "SubscribeDepth",
+			Handler:       _SequexService_SubscribeDepth_Handler,
+			ServerStreams: true,
+		},
+		{
+			StreamName:    "StreamEvents",
+			Handler:       _SequexService_StreamEvents_Handler,
+			ServerStreams: true,
+			ClientStreams: true,
+		},
+	},
+	Metadata: "protobuf/sequex.proto",
+}
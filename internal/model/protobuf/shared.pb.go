@@ -85,10 +85,11 @@ func (Instrument) EnumDescriptor() ([]byte, []int) {
 type Exchange int32
 
 const (
-	Exchange_EXCHANGE_UNSPECIFIED  Exchange = 0
-	Exchange_EXCHANGE_BINANCE      Exchange = 1
-	Exchange_EXCHANGE_BINANCE_PERP Exchange = 2
-	Exchange_EXCHANGE_BYBIT        Exchange = 3
+	Exchange_EXCHANGE_UNSPECIFIED     Exchange = 0
+	Exchange_EXCHANGE_BINANCE         Exchange = 1
+	Exchange_EXCHANGE_BINANCE_PERP    Exchange = 2
+	Exchange_EXCHANGE_BYBIT           Exchange = 3
+	Exchange_EXCHANGE_BINANCE_OPTIONS Exchange = 4
 )
 
 // Enum value maps for Exchange.
@@ -98,12 +99,14 @@ var (
 		1: "EXCHANGE_BINANCE",
 		2: "EXCHANGE_BINANCE_PERP",
 		3: "EXCHANGE_BYBIT",
+		4: "EXCHANGE_BINANCE_OPTIONS",
 	}
 	Exchange_value = map[string]int32{
-		"EXCHANGE_UNSPECIFIED":  0,
-		"EXCHANGE_BINANCE":      1,
-		"EXCHANGE_BINANCE_PERP": 2,
-		"EXCHANGE_BYBIT":        3,
+		"EXCHANGE_UNSPECIFIED":     0,
+		"EXCHANGE_BINANCE":         1,
+		"EXCHANGE_BINANCE_PERP":    2,
+		"EXCHANGE_BYBIT":           3,
+		"EXCHANGE_BINANCE_OPTIONS": 4,
 	}
 )
 
@@ -355,12 +358,13 @@ const file_protobuf_shared_proto_rawDesc = "" +
 	"\x0fINSTRUMENT_PERP\x10\x03\x12\x16\n" +
 	"\x12INSTRUMENT_INVERSE\x10\x04\x12\x16\n" +
 	"\x12INSTRUMENT_FUTURES\x10\x05\x12\x15\n" +
-	"\x11INSTRUMENT_OPTION\x10\x06*i\n" +
+	"\x11INSTRUMENT_OPTION\x10\x06*\x87\x01\n" +
 	"\bExchange\x12\x18\n" +
 	"\x14EXCHANGE_UNSPECIFIED\x10\x00\x12\x14\n" +
 	"\x10EXCHANGE_BINANCE\x10\x01\x12\x19\n" +
 	"\x15EXCHANGE_BINANCE_PERP\x10\x02\x12\x12\n" +
-	"\x0eEXCHANGE_BYBIT\x10\x03*9\n" +
+	"\x0eEXCHANGE_BYBIT\x10\x03\x12\x1c\n" +
+	"\x18EXCHANGE_BINANCE_OPTIONS\x10\x04*9\n" +
 	"\x04Side\x12\x14\n" +
 	"\x10SIDE_UNSPECIFIED\x10\x00\x12\f\n" +
 	"\bSIDE_BUY\x10\x01\x12\r\n" +
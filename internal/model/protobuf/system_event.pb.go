@@ -0,0 +1,332 @@
+// Code generated by protoc-gen-go. DO NOT EDIT.
+// versions:
+// 	protoc-gen-go v1.36.9
+// 	protoc        v5.28.3
+// source: protobuf/system_event.proto
+
+package protobuf
+
+import (
+	protoreflect "google.golang.org/protobuf/reflect/protoreflect"
+	protoimpl "google.golang.org/protobuf/runtime/protoimpl"
+	reflect "reflect"
+	sync "sync"
+	unsafe "unsafe"
+)
+
+const (
+	// Verify that this generated code is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(20 - protoimpl.MinVersion)
+	// Verify that runtime/protoimpl is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(protoimpl.MaxVersion - 20)
+)
+
+// SystemEventKind is the taxonomy of system events, each kind published
+// under its own subject sys.events.<kind>
+type SystemEventKind int32
+
+const (
+	SystemEventKind_SYSTEM_EVENT_KIND_UNSPECIFIED    SystemEventKind = 0
+	SystemEventKind_SYSTEM_EVENT_KIND_NODE_LIFECYCLE SystemEventKind = 1 // A node started, stopped, degraded or recovered
+	SystemEventKind_SYSTEM_EVENT_KIND_RISK_BREACH    SystemEventKind = 2 // A limit breached, e.g. a strategy paused on drawdown
+	SystemEventKind_SYSTEM_EVENT_KIND_EXCHANGE_HALT  SystemEventKind = 3 // Trading halted or resumed on an exchange or symbol
+	SystemEventKind_SYSTEM_EVENT_KIND_RECONNECT      SystemEventKind = 4 // A connection to an exchange or NATS dropped and came back
+	SystemEventKind_SYSTEM_EVENT_KIND_DLQ_ENTRY      SystemEventKind = 5 // A message dropped after its handler failed
+	SystemEventKind_SYSTEM_EVENT_KIND_ERROR          SystemEventKind = 6 // Any other error worth an operator's attention
+)
+
+// Enum value maps for SystemEventKind.
+var (
+	SystemEventKind_name = map[int32]string{
+		0: "SYSTEM_EVENT_KIND_UNSPECIFIED",
+		1: "SYSTEM_EVENT_KIND_NODE_LIFECYCLE",
+		2: "SYSTEM_EVENT_KIND_RISK_BREACH",
+		3: "SYSTEM_EVENT_KIND_EXCHANGE_HALT",
+		4: "SYSTEM_EVENT_KIND_RECONNECT",
+		5: "SYSTEM_EVENT_KIND_DLQ_ENTRY",
+		6: "SYSTEM_EVENT_KIND_ERROR",
+	}
+	SystemEventKind_value = map[string]int32{
+		"SYSTEM_EVENT_KIND_UNSPECIFIED":    0,
+		"SYSTEM_EVENT_KIND_NODE_LIFECYCLE": 1,
+		"SYSTEM_EVENT_KIND_RISK_BREACH":    2,
+		"SYSTEM_EVENT_KIND_EXCHANGE_HALT":  3,
+		"SYSTEM_EVENT_KIND_RECONNECT":      4,
+		"SYSTEM_EVENT_KIND_DLQ_ENTRY":      5,
+		"SYSTEM_EVENT_KIND_ERROR":          6,
+	}
+)
+
+func (x SystemEventKind) Enum() *SystemEventKind {
+	p := new(SystemEventKind)
+	*p = x
+	return p
+}
+
+func (x SystemEventKind) String() string {
+	return protoimpl.X.EnumStringOf(x.Descriptor(), protoreflect.EnumNumber(x))
+}
+
+func (SystemEventKind) Descriptor() protoreflect.EnumDescriptor {
+	return file_protobuf_system_event_proto_enumTypes[0].Descriptor()
+}
+
+func (SystemEventKind) Type() protoreflect.EnumType {
+	return &file_protobuf_system_event_proto_enumTypes[0]
+}
+
+func (x SystemEventKind) Number() protoreflect.EnumNumber {
+	return protoreflect.EnumNumber(x)
+}
+
+// Deprecated: Use SystemEventKind.Descriptor instead.
+func (SystemEventKind) EnumDescriptor() ([]byte, []int) {
+	return file_protobuf_system_event_proto_rawDescGZIP(), []int{0}
+}
+
+type Severity int32
+
+const (
+	Severity_SEVERITY_UNSPECIFIED Severity = 0
+	Severity_SEVERITY_INFO        Severity = 1
+	Severity_SEVERITY_WARNING     Severity = 2
+	Severity_SEVERITY_ERROR       Severity = 3
+	Severity_SEVERITY_CRITICAL    Severity = 4
+)
+
+// Enum value maps for Severity.
+var (
+	Severity_name = map[int32]string{
+		0: "SEVERITY_UNSPECIFIED",
+		1: "SEVERITY_INFO",
+		2: "SEVERITY_WARNING",
+		3: "SEVERITY_ERROR",
+		4: "SEVERITY_CRITICAL",
+	}
+	Severity_value = map[string]int32{
+		"SEVERITY_UNSPECIFIED": 0,
+		"SEVERITY_INFO":        1,
+		"SEVERITY_WARNING":     2,
+		"SEVERITY_ERROR":       3,
+		"SEVERITY_CRITICAL":    4,
+	}
+)
+
+func (x Severity) Enum() *Severity {
+	p := new(Severity)
+	*p = x
+	return p
+}
+
+func (x Severity) String() string {
+	return protoimpl.X.EnumStringOf(x.Descriptor(), protoreflect.EnumNumber(x))
+}
+
+func (Severity) Descriptor() protoreflect.EnumDescriptor {
+	return file_protobuf_system_event_proto_enumTypes[1].Descriptor()
+}
+
+func (Severity) Type() protoreflect.EnumType {
+	return &file_protobuf_system_event_proto_enumTypes[1]
+}
+
+func (x Severity) Number() protoreflect.EnumNumber {
+	return protoreflect.EnumNumber(x)
+}
+
+// Deprecated: Use Severity.Descriptor instead.
+func (Severity) EnumDescriptor() ([]byte, []int) {
+	return file_protobuf_system_event_proto_rawDescGZIP(), []int{1}
+}
+
+// SystemEvent is something that happened to the system, published by the
+// service it happened to
+type SystemEvent struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Id            string                 `protobuf:"bytes,1,opt,name=id,proto3" json:"id,omitempty"` // Unique, the message id deduplicating republished events
+	Kind          SystemEventKind        `protobuf:"varint,2,opt,name=kind,proto3,enum=app.SystemEventKind" json:"kind,omitempty"`
+	Severity      Severity               `protobuf:"varint,3,opt,name=severity,proto3,enum=app.Severity" json:"severity,omitempty"`
+	Source        string                 `protobuf:"bytes,4,opt,name=source,proto3" json:"source,omitempty"` // Service or node publishing the event
+	Code          string                 `protobuf:"bytes,5,opt,name=code,proto3" json:"code,omitempty"`     // What happened within the kind, e.g. started or paused
+	Message       string                 `protobuf:"bytes,6,opt,name=message,proto3" json:"message,omitempty"`
+	Timestamp     int64                  `protobuf:"varint,7,opt,name=timestamp,proto3" json:"timestamp,omitempty"`                                                                            // Unix milliseconds
+	Attributes    map[string]string      `protobuf:"bytes,8,rep,name=attributes,proto3" json:"attributes,omitempty" protobuf_key:"bytes,1,opt,name=key" protobuf_val:"bytes,2,opt,name=value"` // E.g. the strategy, symbol or subject concerned
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *SystemEvent) Reset() {
+	*x = SystemEvent{}
+	mi := &file_protobuf_system_event_proto_msgTypes[0]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *SystemEvent) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*SystemEvent) ProtoMessage() {}
+
+func (x *SystemEvent) ProtoReflect() protoreflect.Message {
+	mi := &file_protobuf_system_event_proto_msgTypes[0]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use SystemEvent.ProtoReflect.Descriptor instead.
+func (*SystemEvent) Descriptor() ([]byte, []int) {
+	return file_protobuf_system_event_proto_rawDescGZIP(), []int{0}
+}
+
+func (x *SystemEvent) GetId() string {
+	if x != nil {
+		return x.Id
+	}
+	return ""
+}
+
+func (x *SystemEvent) GetKind() SystemEventKind {
+	if x != nil {
+		return x.Kind
+	}
+	return SystemEventKind_SYSTEM_EVENT_KIND_UNSPECIFIED
+}
+
+func (x *SystemEvent) GetSeverity() Severity {
+	if x != nil {
+		return x.Severity
+	}
+	return Severity_SEVERITY_UNSPECIFIED
+}
+
+func (x *SystemEvent) GetSource() string {
+	if x != nil {
+		return x.Source
+	}
+	return ""
+}
+
+func (x *SystemEvent) GetCode() string {
+	if x != nil {
+		return x.Code
+	}
+	return ""
+}
+
+func (x *SystemEvent) GetMessage() string {
+	if x != nil {
+		return x.Message
+	}
+	return ""
+}
+
+func (x *SystemEvent) GetTimestamp() int64 {
+	if x != nil {
+		return x.Timestamp
+	}
+	return 0
+}
+
+func (x *SystemEvent) GetAttributes() map[string]string {
+	if x != nil {
+		return x.Attributes
+	}
+	return nil
+}
+
+var File_protobuf_system_event_proto protoreflect.FileDescriptor
+
+const file_protobuf_system_event_proto_rawDesc = "" +
+	"\n" +
+	"\x1bprotobuf/system_event.proto\x12\x03app\"\xd7\x02\n" +
+	"\vSystemEvent\x12\x0e\n" +
+	"\x02id\x18\x01 \x01(\tR\x02id\x12(\n" +
+	"\x04kind\x18\x02 \x01(\x0e2\x14.app.SystemEventKindR\x04kind\x12)\n" +
+	"\bseverity\x18\x03 \x01(\x0e2\r.app.SeverityR\bseverity\x12\x16\n" +
+	"\x06source\x18\x04 \x01(\tR\x06source\x12\x12\n" +
+	"\x04code\x18\x05 \x01(\tR\x04code\x12\x18\n" +
+	"\amessage\x18\x06 \x01(\tR\amessage\x12\x1c\n" +
+	"\ttimestamp\x18\a \x01(\x03R\ttimestamp\x12@\n" +
+	"\n" +
+	"attributes\x18\b \x03(\v2 .app.SystemEvent.AttributesEntryR\n" +
+	"attributes\x1a=\n" +
+	"\x0fAttributesEntry\x12\x10\n" +
+	"\x03key\x18\x01 \x01(\tR\x03key\x12\x14\n" +
+	"\x05value\x18\x02 \x01(\tR\x05value:\x028\x01*\x81\x02\n" +
+	"\x0fSystemEventKind\x12!\n" +
+	"\x1dSYSTEM_EVENT_KIND_UNSPECIFIED\x10\x00\x12$\n" +
+	" SYSTEM_EVENT_KIND_NODE_LIFECYCLE\x10\x01\x12!\n" +
+	"\x1dSYSTEM_EVENT_KIND_RISK_BREACH\x10\x02\x12#\n" +
+	"\x1fSYSTEM_EVENT_KIND_EXCHANGE_HALT\x10\x03\x12\x1f\n" +
+	"\x1bSYSTEM_EVENT_KIND_RECONNECT\x10\x04\x12\x1f\n" +
+	"\x1bSYSTEM_EVENT_KIND_DLQ_ENTRY\x10\x05\x12\x1b\n" +
+	"\x17SYSTEM_EVENT_KIND_ERROR\x10\x06*x\n" +
+	"\bSeverity\x12\x18\n" +
+	"\x14SEVERITY_UNSPECIFIED\x10\x00\x12\x11\n" +
+	"\rSEVERITY_INFO\x10\x01\x12\x14\n" +
+	"\x10SEVERITY_WARNING\x10\x02\x12\x12\n" +
+	"\x0eSEVERITY_ERROR\x10\x03\x12\x15\n" +
+	"\x11SEVERITY_CRITICAL\x10\x04B7Z5github.com/BullionBear/sequex/internal/model/protobufb\x06proto3"
+
+var (
+	file_protobuf_system_event_proto_rawDescOnce sync.Once
+	file_protobuf_system_event_proto_rawDescData []byte
+)
+
+func file_protobuf_system_event_proto_rawDescGZIP() []byte {
+	file_protobuf_system_event_proto_rawDescOnce.Do(func() {
+		file_protobuf_system_event_proto_rawDescData = protoimpl.X.CompressGZIP(unsafe.Slice(unsafe.StringData(file_protobuf_system_event_proto_rawDesc), len(file_protobuf_system_event_proto_rawDesc)))
+	})
+	return file_protobuf_system_event_proto_rawDescData
+}
+
+var file_protobuf_system_event_proto_enumTypes = make([]protoimpl.EnumInfo, 2)
+var file_protobuf_system_event_proto_msgTypes = make([]protoimpl.MessageInfo, 2)
+var file_protobuf_system_event_proto_goTypes = []any{
+	(SystemEventKind)(0), // 0: app.SystemEventKind
+	(Severity)(0),        // 1: app.Severity
+	(*SystemEvent)(nil),  // 2: app.SystemEvent
+	nil,                  // 3: app.SystemEvent.AttributesEntry
+}
+var file_protobuf_system_event_proto_depIdxs = []int32{
+	0, // 0: app.SystemEvent.kind:type_name -> app.SystemEventKind
+	1, // 1: app.SystemEvent.severity:type_name -> app.Severity
+	3, // 2: app.SystemEvent.attributes:type_name -> app.SystemEvent.AttributesEntry
+	3, // [3:3] is the sub-list for method output_type
+	3, // [3:3] is the sub-list for method input_type
+	3, // [3:3] is the sub-list for extension type_name
+	3, // [3:3] is the sub-list for extension extendee
+	0, // [0:3] is the sub-list for field type_name
+}
+
+func init() { file_protobuf_system_event_proto_init() }
+func file_protobuf_system_event_proto_init() {
+	if File_protobuf_system_event_proto != nil {
+		return
+	}
+	type x struct{}
+	out := protoimpl.TypeBuilder{
+		File: protoimpl.DescBuilder{
+			GoPackagePath: reflect.TypeOf(x{}).PkgPath(),
+			RawDescriptor: unsafe.Slice(unsafe.StringData(file_protobuf_system_event_proto_rawDesc), len(file_protobuf_system_event_proto_rawDesc)),
+			NumEnums:      2,
+			NumMessages:   2,
+			NumExtensions: 0,
+			NumServices:   0,
+		},
+		GoTypes:           file_protobuf_system_event_proto_goTypes,
+		DependencyIndexes: file_protobuf_system_event_proto_depIdxs,
+		EnumInfos:         file_protobuf_system_event_proto_enumTypes,
+		MessageInfos:      file_protobuf_system_event_proto_msgTypes,
+	}.Build()
+	File_protobuf_system_event_proto = out.File
+	file_protobuf_system_event_proto_goTypes = nil
+	file_protobuf_system_event_proto_depIdxs = nil
+}
@@ -121,6 +121,52 @@ func (x *Trade) GetTimestamp() int64 {
 	return 0
 }
 
+// TradeBatch carries several trades of one subject in a single message,
+// flagged by the Sqx-Trade-Batch header
+type TradeBatch struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Trades        []*Trade               `protobuf:"bytes,1,rep,name=trades,proto3" json:"trades,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *TradeBatch) Reset() {
+	*x = TradeBatch{}
+	mi := &file_protobuf_trade_proto_msgTypes[1]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *TradeBatch) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*TradeBatch) ProtoMessage() {}
+
+func (x *TradeBatch) ProtoReflect() protoreflect.Message {
+	mi := &file_protobuf_trade_proto_msgTypes[1]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use TradeBatch.ProtoReflect.Descriptor instead.
+func (*TradeBatch) Descriptor() ([]byte, []int) {
+	return file_protobuf_trade_proto_rawDescGZIP(), []int{1}
+}
+
+func (x *TradeBatch) GetTrades() []*Trade {
+	if x != nil {
+		return x.Trades
+	}
+	return nil
+}
+
 var File_protobuf_trade_proto protoreflect.FileDescriptor
 
 const file_protobuf_trade_proto_rawDesc = "" +
@@ -136,7 +182,11 @@ const file_protobuf_trade_proto_rawDesc = "" +
 	"\x04side\x18\x05 \x01(\x0e2\t.app.SideR\x04side\x12\x14\n" +
 	"\x05price\x18\a \x01(\x01R\x05price\x12\x1a\n" +
 	"\bquantity\x18\b \x01(\x01R\bquantity\x12\x1c\n" +
-	"\ttimestamp\x18\t \x01(\x03R\ttimestampB7Z5github.com/BullionBear/sequex/internal/model/protobufb\x06proto3"
+	"\ttimestamp\x18\t \x01(\x03R\ttimestamp\"0\n" +
+	"\n" +
+	"TradeBatch\x12\"\n" +
+	"\x06trades\x18\x01 \x03(\v2\n" +
+	".app.TradeR\x06tradesB7Z5github.com/BullionBear/sequex/internal/model/protobufb\x06proto3"
 
 var (
 	file_protobuf_trade_proto_rawDescOnce sync.Once
@@ -150,24 +200,26 @@ func file_protobuf_trade_proto_rawDescGZIP() []byte {
 	return file_protobuf_trade_proto_rawDescData
 }
 
-var file_protobuf_trade_proto_msgTypes = make([]protoimpl.MessageInfo, 1)
+var file_protobuf_trade_proto_msgTypes = make([]protoimpl.MessageInfo, 2)
 var file_protobuf_trade_proto_goTypes = []any{
-	(*Trade)(nil),   // 0: app.Trade
-	(Exchange)(0),   // 1: app.Exchange
-	(Instrument)(0), // 2: app.Instrument
-	(*Symbol)(nil),  // 3: app.Symbol
-	(Side)(0),       // 4: app.Side
+	(*Trade)(nil),      // 0: app.Trade
+	(*TradeBatch)(nil), // 1: app.TradeBatch
+	(Exchange)(0),      // 2: app.Exchange
+	(Instrument)(0),    // 3: app.Instrument
+	(*Symbol)(nil),     // 4: app.Symbol
+	(Side)(0),          // 5: app.Side
 }
 var file_protobuf_trade_proto_depIdxs = []int32{
-	1, // 0: app.Trade.exchange:type_name -> app.Exchange
-	2, // 1: app.Trade.instrument:type_name -> app.Instrument
-	3, // 2: app.Trade.symbol:type_name -> app.Symbol
-	4, // 3: app.Trade.side:type_name -> app.Side
-	4, // [4:4] is the sub-list for method output_type
-	4, // [4:4] is the sub-list for method input_type
-	4, // [4:4] is the sub-list for extension type_name
-	4, // [4:4] is the sub-list for extension extendee
-	0, // [0:4] is the sub-list for field type_name
+	2, // 0: app.Trade.exchange:type_name -> app.Exchange
+	3, // 1: app.Trade.instrument:type_name -> app.Instrument
+	4, // 2: app.Trade.symbol:type_name -> app.Symbol
+	5, // 3: app.Trade.side:type_name -> app.Side
+	0, // 4: app.TradeBatch.trades:type_name -> app.Trade
+	5, // [5:5] is the sub-list for method output_type
+	5, // [5:5] is the sub-list for method input_type
+	5, // [5:5] is the sub-list for extension type_name
+	5, // [5:5] is the sub-list for extension extendee
+	0, // [0:5] is the sub-list for field type_name
 }
 
 func init() { file_protobuf_trade_proto_init() }
@@ -182,7 +234,7 @@ func file_protobuf_trade_proto_init() {
 			GoPackagePath: reflect.TypeOf(x{}).PkgPath(),
 			RawDescriptor: unsafe.Slice(unsafe.StringData(file_protobuf_trade_proto_rawDesc), len(file_protobuf_trade_proto_rawDesc)),
 			NumEnums:      0,
-			NumMessages:   1,
+			NumMessages:   2,
 			NumExtensions: 0,
 			NumServices:   0,
 		},
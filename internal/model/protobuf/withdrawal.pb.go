@@ -0,0 +1,799 @@
+// Code generated by protoc-gen-go. DO NOT EDIT.
+// versions:
+// 	protoc-gen-go v1.36.9
+// 	protoc        v5.28.3
+// source: protobuf/withdrawal.proto
+
+package protobuf
+
+import (
+	protoreflect "google.golang.org/protobuf/reflect/protoreflect"
+	protoimpl "google.golang.org/protobuf/runtime/protoimpl"
+	reflect "reflect"
+	sync "sync"
+	unsafe "unsafe"
+)
+
+const (
+	// Verify that this generated code is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(20 - protoimpl.MinVersion)
+	// Verify that runtime/protoimpl is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(protoimpl.MaxVersion - 20)
+)
+
+// Withdrawal is a request to send an asset to an address
+type Withdrawal struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Id            string                 `protobuf:"bytes,1,opt,name=id,proto3" json:"id,omitempty"` // Chosen by the requester, signed with the request
+	Asset         string                 `protobuf:"bytes,2,opt,name=asset,proto3" json:"asset,omitempty"`
+	Network       string                 `protobuf:"bytes,3,opt,name=network,proto3" json:"network,omitempty"`
+	Address       string                 `protobuf:"bytes,4,opt,name=address,proto3" json:"address,omitempty"`
+	Tag           string                 `protobuf:"bytes,5,opt,name=tag,proto3" json:"tag,omitempty"`
+	Amount        float64                `protobuf:"fixed64,6,opt,name=amount,proto3" json:"amount,omitempty"`
+	Label         string                 `protobuf:"bytes,7,opt,name=label,proto3" json:"label,omitempty"` // Of the whitelisted address
+	Status        string                 `protobuf:"bytes,8,opt,name=status,proto3" json:"status,omitempty"`
+	RequestedBy   string                 `protobuf:"bytes,9,opt,name=requested_by,json=requestedBy,proto3" json:"requested_by,omitempty"`
+	Reason        string                 `protobuf:"bytes,10,opt,name=reason,proto3" json:"reason,omitempty"`
+	Approvals     []*WithdrawalApproval  `protobuf:"bytes,11,rep,name=approvals,proto3" json:"approvals,omitempty"` // Requester first
+	ExchangeId    string                 `protobuf:"bytes,12,opt,name=exchange_id,json=exchangeId,proto3" json:"exchange_id,omitempty"`
+	Error         string                 `protobuf:"bytes,13,opt,name=error,proto3" json:"error,omitempty"`
+	CreatedAt     int64                  `protobuf:"varint,14,opt,name=created_at,json=createdAt,proto3" json:"created_at,omitempty"` // Unix milliseconds
+	UpdatedAt     int64                  `protobuf:"varint,15,opt,name=updated_at,json=updatedAt,proto3" json:"updated_at,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *Withdrawal) Reset() {
+	*x = Withdrawal{}
+	mi := &file_protobuf_withdrawal_proto_msgTypes[0]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *Withdrawal) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Withdrawal) ProtoMessage() {}
+
+func (x *Withdrawal) ProtoReflect() protoreflect.Message {
+	mi := &file_protobuf_withdrawal_proto_msgTypes[0]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Withdrawal.ProtoReflect.Descriptor instead.
+func (*Withdrawal) Descriptor() ([]byte, []int) {
+	return file_protobuf_withdrawal_proto_rawDescGZIP(), []int{0}
+}
+
+func (x *Withdrawal) GetId() string {
+	if x != nil {
+		return x.Id
+	}
+	return ""
+}
+
+func (x *Withdrawal) GetAsset() string {
+	if x != nil {
+		return x.Asset
+	}
+	return ""
+}
+
+func (x *Withdrawal) GetNetwork() string {
+	if x != nil {
+		return x.Network
+	}
+	return ""
+}
+
+func (x *Withdrawal) GetAddress() string {
+	if x != nil {
+		return x.Address
+	}
+	return ""
+}
+
+func (x *Withdrawal) GetTag() string {
+	if x != nil {
+		return x.Tag
+	}
+	return ""
+}
+
+func (x *Withdrawal) GetAmount() float64 {
+	if x != nil {
+		return x.Amount
+	}
+	return 0
+}
+
+func (x *Withdrawal) GetLabel() string {
+	if x != nil {
+		return x.Label
+	}
+	return ""
+}
+
+func (x *Withdrawal) GetStatus() string {
+	if x != nil {
+		return x.Status
+	}
+	return ""
+}
+
+func (x *Withdrawal) GetRequestedBy() string {
+	if x != nil {
+		return x.RequestedBy
+	}
+	return ""
+}
+
+func (x *Withdrawal) GetReason() string {
+	if x != nil {
+		return x.Reason
+	}
+	return ""
+}
+
+func (x *Withdrawal) GetApprovals() []*WithdrawalApproval {
+	if x != nil {
+		return x.Approvals
+	}
+	return nil
+}
+
+func (x *Withdrawal) GetExchangeId() string {
+	if x != nil {
+		return x.ExchangeId
+	}
+	return ""
+}
+
+func (x *Withdrawal) GetError() string {
+	if x != nil {
+		return x.Error
+	}
+	return ""
+}
+
+func (x *Withdrawal) GetCreatedAt() int64 {
+	if x != nil {
+		return x.CreatedAt
+	}
+	return 0
+}
+
+func (x *Withdrawal) GetUpdatedAt() int64 {
+	if x != nil {
+		return x.UpdatedAt
+	}
+	return 0
+}
+
+// WithdrawalApproval is the signature of an approver
+type WithdrawalApproval struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	By            string                 `protobuf:"bytes,1,opt,name=by,proto3" json:"by,omitempty"`
+	At            int64                  `protobuf:"varint,2,opt,name=at,proto3" json:"at,omitempty"` // Unix milliseconds
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *WithdrawalApproval) Reset() {
+	*x = WithdrawalApproval{}
+	mi := &file_protobuf_withdrawal_proto_msgTypes[1]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *WithdrawalApproval) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*WithdrawalApproval) ProtoMessage() {}
+
+func (x *WithdrawalApproval) ProtoReflect() protoreflect.Message {
+	mi := &file_protobuf_withdrawal_proto_msgTypes[1]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use WithdrawalApproval.ProtoReflect.Descriptor instead.
+func (*WithdrawalApproval) Descriptor() ([]byte, []int) {
+	return file_protobuf_withdrawal_proto_rawDescGZIP(), []int{1}
+}
+
+func (x *WithdrawalApproval) GetBy() string {
+	if x != nil {
+		return x.By
+	}
+	return ""
+}
+
+func (x *WithdrawalApproval) GetAt() int64 {
+	if x != nil {
+		return x.At
+	}
+	return 0
+}
+
+type SubmitWithdrawalRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Withdrawal    *Withdrawal            `protobuf:"bytes,1,opt,name=withdrawal,proto3" json:"withdrawal,omitempty"`
+	Signature     []byte                 `protobuf:"bytes,2,opt,name=signature,proto3" json:"signature,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *SubmitWithdrawalRequest) Reset() {
+	*x = SubmitWithdrawalRequest{}
+	mi := &file_protobuf_withdrawal_proto_msgTypes[2]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *SubmitWithdrawalRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*SubmitWithdrawalRequest) ProtoMessage() {}
+
+func (x *SubmitWithdrawalRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_protobuf_withdrawal_proto_msgTypes[2]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use SubmitWithdrawalRequest.ProtoReflect.Descriptor instead.
+func (*SubmitWithdrawalRequest) Descriptor() ([]byte, []int) {
+	return file_protobuf_withdrawal_proto_rawDescGZIP(), []int{2}
+}
+
+func (x *SubmitWithdrawalRequest) GetWithdrawal() *Withdrawal {
+	if x != nil {
+		return x.Withdrawal
+	}
+	return nil
+}
+
+func (x *SubmitWithdrawalRequest) GetSignature() []byte {
+	if x != nil {
+		return x.Signature
+	}
+	return nil
+}
+
+type ApproveWithdrawalRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Id            string                 `protobuf:"bytes,1,opt,name=id,proto3" json:"id,omitempty"`
+	By            string                 `protobuf:"bytes,2,opt,name=by,proto3" json:"by,omitempty"`
+	Signature     []byte                 `protobuf:"bytes,3,opt,name=signature,proto3" json:"signature,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ApproveWithdrawalRequest) Reset() {
+	*x = ApproveWithdrawalRequest{}
+	mi := &file_protobuf_withdrawal_proto_msgTypes[3]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ApproveWithdrawalRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ApproveWithdrawalRequest) ProtoMessage() {}
+
+func (x *ApproveWithdrawalRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_protobuf_withdrawal_proto_msgTypes[3]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ApproveWithdrawalRequest.ProtoReflect.Descriptor instead.
+func (*ApproveWithdrawalRequest) Descriptor() ([]byte, []int) {
+	return file_protobuf_withdrawal_proto_rawDescGZIP(), []int{3}
+}
+
+func (x *ApproveWithdrawalRequest) GetId() string {
+	if x != nil {
+		return x.Id
+	}
+	return ""
+}
+
+func (x *ApproveWithdrawalRequest) GetBy() string {
+	if x != nil {
+		return x.By
+	}
+	return ""
+}
+
+func (x *ApproveWithdrawalRequest) GetSignature() []byte {
+	if x != nil {
+		return x.Signature
+	}
+	return nil
+}
+
+type RejectWithdrawalRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Id            string                 `protobuf:"bytes,1,opt,name=id,proto3" json:"id,omitempty"`
+	By            string                 `protobuf:"bytes,2,opt,name=by,proto3" json:"by,omitempty"`
+	Reason        string                 `protobuf:"bytes,3,opt,name=reason,proto3" json:"reason,omitempty"`
+	Signature     []byte                 `protobuf:"bytes,4,opt,name=signature,proto3" json:"signature,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *RejectWithdrawalRequest) Reset() {
+	*x = RejectWithdrawalRequest{}
+	mi := &file_protobuf_withdrawal_proto_msgTypes[4]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *RejectWithdrawalRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*RejectWithdrawalRequest) ProtoMessage() {}
+
+func (x *RejectWithdrawalRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_protobuf_withdrawal_proto_msgTypes[4]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use RejectWithdrawalRequest.ProtoReflect.Descriptor instead.
+func (*RejectWithdrawalRequest) Descriptor() ([]byte, []int) {
+	return file_protobuf_withdrawal_proto_rawDescGZIP(), []int{4}
+}
+
+func (x *RejectWithdrawalRequest) GetId() string {
+	if x != nil {
+		return x.Id
+	}
+	return ""
+}
+
+func (x *RejectWithdrawalRequest) GetBy() string {
+	if x != nil {
+		return x.By
+	}
+	return ""
+}
+
+func (x *RejectWithdrawalRequest) GetReason() string {
+	if x != nil {
+		return x.Reason
+	}
+	return ""
+}
+
+func (x *RejectWithdrawalRequest) GetSignature() []byte {
+	if x != nil {
+		return x.Signature
+	}
+	return nil
+}
+
+type ListWithdrawalsRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ListWithdrawalsRequest) Reset() {
+	*x = ListWithdrawalsRequest{}
+	mi := &file_protobuf_withdrawal_proto_msgTypes[5]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ListWithdrawalsRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ListWithdrawalsRequest) ProtoMessage() {}
+
+func (x *ListWithdrawalsRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_protobuf_withdrawal_proto_msgTypes[5]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ListWithdrawalsRequest.ProtoReflect.Descriptor instead.
+func (*ListWithdrawalsRequest) Descriptor() ([]byte, []int) {
+	return file_protobuf_withdrawal_proto_rawDescGZIP(), []int{5}
+}
+
+// WithdrawalAddress is a whitelisted destination
+type WithdrawalAddress struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Label         string                 `protobuf:"bytes,1,opt,name=label,proto3" json:"label,omitempty"`
+	Asset         string                 `protobuf:"bytes,2,opt,name=asset,proto3" json:"asset,omitempty"`
+	Network       string                 `protobuf:"bytes,3,opt,name=network,proto3" json:"network,omitempty"`
+	Address       string                 `protobuf:"bytes,4,opt,name=address,proto3" json:"address,omitempty"`
+	Tag           string                 `protobuf:"bytes,5,opt,name=tag,proto3" json:"tag,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *WithdrawalAddress) Reset() {
+	*x = WithdrawalAddress{}
+	mi := &file_protobuf_withdrawal_proto_msgTypes[6]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *WithdrawalAddress) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*WithdrawalAddress) ProtoMessage() {}
+
+func (x *WithdrawalAddress) ProtoReflect() protoreflect.Message {
+	mi := &file_protobuf_withdrawal_proto_msgTypes[6]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use WithdrawalAddress.ProtoReflect.Descriptor instead.
+func (*WithdrawalAddress) Descriptor() ([]byte, []int) {
+	return file_protobuf_withdrawal_proto_rawDescGZIP(), []int{6}
+}
+
+func (x *WithdrawalAddress) GetLabel() string {
+	if x != nil {
+		return x.Label
+	}
+	return ""
+}
+
+func (x *WithdrawalAddress) GetAsset() string {
+	if x != nil {
+		return x.Asset
+	}
+	return ""
+}
+
+func (x *WithdrawalAddress) GetNetwork() string {
+	if x != nil {
+		return x.Network
+	}
+	return ""
+}
+
+func (x *WithdrawalAddress) GetAddress() string {
+	if x != nil {
+		return x.Address
+	}
+	return ""
+}
+
+func (x *WithdrawalAddress) GetTag() string {
+	if x != nil {
+		return x.Tag
+	}
+	return ""
+}
+
+// WithdrawalLimit is the daily limit of an asset and how much of it is used
+type WithdrawalLimit struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Asset         string                 `protobuf:"bytes,1,opt,name=asset,proto3" json:"asset,omitempty"`
+	Limit         float64                `protobuf:"fixed64,2,opt,name=limit,proto3" json:"limit,omitempty"`
+	Used          float64                `protobuf:"fixed64,3,opt,name=used,proto3" json:"used,omitempty"` // Sent today and pending
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *WithdrawalLimit) Reset() {
+	*x = WithdrawalLimit{}
+	mi := &file_protobuf_withdrawal_proto_msgTypes[7]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *WithdrawalLimit) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*WithdrawalLimit) ProtoMessage() {}
+
+func (x *WithdrawalLimit) ProtoReflect() protoreflect.Message {
+	mi := &file_protobuf_withdrawal_proto_msgTypes[7]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use WithdrawalLimit.ProtoReflect.Descriptor instead.
+func (*WithdrawalLimit) Descriptor() ([]byte, []int) {
+	return file_protobuf_withdrawal_proto_rawDescGZIP(), []int{7}
+}
+
+func (x *WithdrawalLimit) GetAsset() string {
+	if x != nil {
+		return x.Asset
+	}
+	return ""
+}
+
+func (x *WithdrawalLimit) GetLimit() float64 {
+	if x != nil {
+		return x.Limit
+	}
+	return 0
+}
+
+func (x *WithdrawalLimit) GetUsed() float64 {
+	if x != nil {
+		return x.Used
+	}
+	return 0
+}
+
+// WithdrawalList is the state of the withdrawal node
+type WithdrawalList struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Withdrawals   []*Withdrawal          `protobuf:"bytes,1,rep,name=withdrawals,proto3" json:"withdrawals,omitempty"` // Newest first
+	Whitelist     []*WithdrawalAddress   `protobuf:"bytes,2,rep,name=whitelist,proto3" json:"whitelist,omitempty"`
+	Limits        []*WithdrawalLimit     `protobuf:"bytes,3,rep,name=limits,proto3" json:"limits,omitempty"`
+	Approvers     []string               `protobuf:"bytes,4,rep,name=approvers,proto3" json:"approvers,omitempty"`
+	Approvals     int32                  `protobuf:"varint,5,opt,name=approvals,proto3" json:"approvals,omitempty"` // Signatures needed, the requester's included
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *WithdrawalList) Reset() {
+	*x = WithdrawalList{}
+	mi := &file_protobuf_withdrawal_proto_msgTypes[8]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *WithdrawalList) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*WithdrawalList) ProtoMessage() {}
+
+func (x *WithdrawalList) ProtoReflect() protoreflect.Message {
+	mi := &file_protobuf_withdrawal_proto_msgTypes[8]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use WithdrawalList.ProtoReflect.Descriptor instead.
+func (*WithdrawalList) Descriptor() ([]byte, []int) {
+	return file_protobuf_withdrawal_proto_rawDescGZIP(), []int{8}
+}
+
+func (x *WithdrawalList) GetWithdrawals() []*Withdrawal {
+	if x != nil {
+		return x.Withdrawals
+	}
+	return nil
+}
+
+func (x *WithdrawalList) GetWhitelist() []*WithdrawalAddress {
+	if x != nil {
+		return x.Whitelist
+	}
+	return nil
+}
+
+func (x *WithdrawalList) GetLimits() []*WithdrawalLimit {
+	if x != nil {
+		return x.Limits
+	}
+	return nil
+}
+
+func (x *WithdrawalList) GetApprovers() []string {
+	if x != nil {
+		return x.Approvers
+	}
+	return nil
+}
+
+func (x *WithdrawalList) GetApprovals() int32 {
+	if x != nil {
+		return x.Approvals
+	}
+	return 0
+}
+
+var File_protobuf_withdrawal_proto protoreflect.FileDescriptor
+
+const file_protobuf_withdrawal_proto_rawDesc = "" +
+	"\n" +
+	"\x19protobuf/withdrawal.proto\x12\x03app\x1a\x12protobuf/rpc.proto\"\xa5\x03\n" +
+	"\n" +
+	"Withdrawal\x12\x0e\n" +
+	"\x02id\x18\x01 \x01(\tR\x02id\x12\x14\n" +
+	"\x05asset\x18\x02 \x01(\tR\x05asset\x12\x18\n" +
+	"\anetwork\x18\x03 \x01(\tR\anetwork\x12\x18\n" +
+	"\aaddress\x18\x04 \x01(\tR\aaddress\x12\x10\n" +
+	"\x03tag\x18\x05 \x01(\tR\x03tag\x12\x16\n" +
+	"\x06amount\x18\x06 \x01(\x01R\x06amount\x12\x14\n" +
+	"\x05label\x18\a \x01(\tR\x05label\x12\x16\n" +
+	"\x06status\x18\b \x01(\tR\x06status\x12!\n" +
+	"\frequested_by\x18\t \x01(\tR\vrequestedBy\x12\x16\n" +
+	"\x06reason\x18\n" +
+	" \x01(\tR\x06reason\x125\n" +
+	"\tapprovals\x18\v \x03(\v2\x17.app.WithdrawalApprovalR\tapprovals\x12\x1f\n" +
+	"\vexchange_id\x18\f \x01(\tR\n" +
+	"exchangeId\x12\x14\n" +
+	"\x05error\x18\r \x01(\tR\x05error\x12\x1d\n" +
+	"\n" +
+	"created_at\x18\x0e \x01(\x03R\tcreatedAt\x12\x1d\n" +
+	"\n" +
+	"updated_at\x18\x0f \x01(\x03R\tupdatedAt\"4\n" +
+	"\x12WithdrawalApproval\x12\x0e\n" +
+	"\x02by\x18\x01 \x01(\tR\x02by\x12\x0e\n" +
+	"\x02at\x18\x02 \x01(\x03R\x02at\"h\n" +
+	"\x17SubmitWithdrawalRequest\x12/\n" +
+	"\n" +
+	"withdrawal\x18\x01 \x01(\v2\x0f.app.WithdrawalR\n" +
+	"withdrawal\x12\x1c\n" +
+	"\tsignature\x18\x02 \x01(\fR\tsignature\"X\n" +
+	"\x18ApproveWithdrawalRequest\x12\x0e\n" +
+	"\x02id\x18\x01 \x01(\tR\x02id\x12\x0e\n" +
+	"\x02by\x18\x02 \x01(\tR\x02by\x12\x1c\n" +
+	"\tsignature\x18\x03 \x01(\fR\tsignature\"o\n" +
+	"\x17RejectWithdrawalRequest\x12\x0e\n" +
+	"\x02id\x18\x01 \x01(\tR\x02id\x12\x0e\n" +
+	"\x02by\x18\x02 \x01(\tR\x02by\x12\x16\n" +
+	"\x06reason\x18\x03 \x01(\tR\x06reason\x12\x1c\n" +
+	"\tsignature\x18\x04 \x01(\fR\tsignature\"\x18\n" +
+	"\x16ListWithdrawalsRequest\"\x85\x01\n" +
+	"\x11WithdrawalAddress\x12\x14\n" +
+	"\x05label\x18\x01 \x01(\tR\x05label\x12\x14\n" +
+	"\x05asset\x18\x02 \x01(\tR\x05asset\x12\x18\n" +
+	"\anetwork\x18\x03 \x01(\tR\anetwork\x12\x18\n" +
+	"\aaddress\x18\x04 \x01(\tR\aaddress\x12\x10\n" +
+	"\x03tag\x18\x05 \x01(\tR\x03tag\"Q\n" +
+	"\x0fWithdrawalLimit\x12\x14\n" +
+	"\x05asset\x18\x01 \x01(\tR\x05asset\x12\x14\n" +
+	"\x05limit\x18\x02 \x01(\x01R\x05limit\x12\x12\n" +
+	"\x04used\x18\x03 \x01(\x01R\x04used\"\xe3\x01\n" +
+	"\x0eWithdrawalList\x121\n" +
+	"\vwithdrawals\x18\x01 \x03(\v2\x0f.app.WithdrawalR\vwithdrawals\x124\n" +
+	"\twhitelist\x18\x02 \x03(\v2\x16.app.WithdrawalAddressR\twhitelist\x12,\n" +
+	"\x06limits\x18\x03 \x03(\v2\x14.app.WithdrawalLimitR\x06limits\x12\x1c\n" +
+	"\tapprovers\x18\x04 \x03(\tR\tapprovers\x12\x1c\n" +
+	"\tapprovals\x18\x05 \x01(\x05R\tapprovals2\x8c\x02\n" +
+	"\x11WithdrawalService\x12=\n" +
+	"\x06Submit\x12\x1c.app.SubmitWithdrawalRequest\x1a\x0f.app.Withdrawal\"\x04\x80\xb5\x18\x01\x12?\n" +
+	"\aApprove\x12\x1d.app.ApproveWithdrawalRequest\x1a\x0f.app.Withdrawal\"\x04\x80\xb5\x18\x01\x12=\n" +
+	"\x06Reject\x12\x1c.app.RejectWithdrawalRequest\x1a\x0f.app.Withdrawal\"\x04\x80\xb5\x18\x01\x128\n" +
+	"\x04List\x12\x1b.app.ListWithdrawalsRequest\x1a\x13.app.WithdrawalListB7Z5github.com/BullionBear/sequex/internal/model/protobufb\x06proto3"
+
+var (
+	file_protobuf_withdrawal_proto_rawDescOnce sync.Once
+	file_protobuf_withdrawal_proto_rawDescData []byte
+)
+
+func file_protobuf_withdrawal_proto_rawDescGZIP() []byte {
+	file_protobuf_withdrawal_proto_rawDescOnce.Do(func() {
+		file_protobuf_withdrawal_proto_rawDescData = protoimpl.X.CompressGZIP(unsafe.Slice(unsafe.StringData(file_protobuf_withdrawal_proto_rawDesc), len(file_protobuf_withdrawal_proto_rawDesc)))
+	})
+	return file_protobuf_withdrawal_proto_rawDescData
+}
+
+var file_protobuf_withdrawal_proto_msgTypes = make([]protoimpl.MessageInfo, 9)
+var file_protobuf_withdrawal_proto_goTypes = []any{
+	(*Withdrawal)(nil),               // 0: app.Withdrawal
+	(*WithdrawalApproval)(nil),       // 1: app.WithdrawalApproval
+	(*SubmitWithdrawalRequest)(nil),  // 2: app.SubmitWithdrawalRequest
+	(*ApproveWithdrawalRequest)(nil), // 3: app.ApproveWithdrawalRequest
+	(*RejectWithdrawalRequest)(nil),  // 4: app.RejectWithdrawalRequest
+	(*ListWithdrawalsRequest)(nil),   // 5: app.ListWithdrawalsRequest
+	(*WithdrawalAddress)(nil),        // 6: app.WithdrawalAddress
+	(*WithdrawalLimit)(nil),          // 7: app.WithdrawalLimit
+	(*WithdrawalList)(nil),           // 8: app.WithdrawalList
+}
+var file_protobuf_withdrawal_proto_depIdxs = []int32{
+	1, // 0: app.Withdrawal.approvals:type_name -> app.WithdrawalApproval
+	0, // 1: app.SubmitWithdrawalRequest.withdrawal:type_name -> app.Withdrawal
+	0, // 2: app.WithdrawalList.withdrawals:type_name -> app.Withdrawal
+	6, // 3: app.WithdrawalList.whitelist:type_name -> app.WithdrawalAddress
+	7, // 4: app.WithdrawalList.limits:type_name -> app.WithdrawalLimit
+	2, // 5: app.WithdrawalService.Submit:input_type -> app.SubmitWithdrawalRequest
+	3, // 6: app.WithdrawalService.Approve:input_type -> app.ApproveWithdrawalRequest
+	4, // 7: app.WithdrawalService.Reject:input_type -> app.RejectWithdrawalRequest
+	5, // 8: app.WithdrawalService.List:input_type -> app.ListWithdrawalsRequest
+	0, // 9: app.WithdrawalService.Submit:output_type -> app.Withdrawal
+	0, // 10: app.WithdrawalService.Approve:output_type -> app.Withdrawal
+	0, // 11: app.WithdrawalService.Reject:output_type -> app.Withdrawal
+	8, // 12: app.WithdrawalService.List:output_type -> app.WithdrawalList
+	9, // [9:13] is the sub-list for method output_type
+	5, // [5:9] is the sub-list for method input_type
+	5, // [5:5] is the sub-list for extension type_name
+	5, // [5:5] is the sub-list for extension extendee
+	0, // [0:5] is the sub-list for field type_name
+}
+
+func init() { file_protobuf_withdrawal_proto_init() }
+func file_protobuf_withdrawal_proto_init() {
+	if File_protobuf_withdrawal_proto != nil {
+		return
+	}
+	file_protobuf_rpc_proto_init()
+	type x struct{}
+	out := protoimpl.TypeBuilder{
+		File: protoimpl.DescBuilder{
+			GoPackagePath: reflect.TypeOf(x{}).PkgPath(),
+			RawDescriptor: unsafe.Slice(unsafe.StringData(file_protobuf_withdrawal_proto_rawDesc), len(file_protobuf_withdrawal_proto_rawDesc)),
+			NumEnums:      0,
+			NumMessages:   9,
+			NumExtensions: 0,
+			NumServices:   1,
+		},
+		GoTypes:           file_protobuf_withdrawal_proto_goTypes,
+		DependencyIndexes: file_protobuf_withdrawal_proto_depIdxs,
+		MessageInfos:      file_protobuf_withdrawal_proto_msgTypes,
+	}.Build()
+	File_protobuf_withdrawal_proto = out.File
+	file_protobuf_withdrawal_proto_goTypes = nil
+	file_protobuf_withdrawal_proto_depIdxs = nil
+}
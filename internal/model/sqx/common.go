@@ -14,14 +14,17 @@ const (
 	ExchangeBinance
 	ExchangeBinancePerp
 	ExchangeBybit
+	ExchangeBinanceOptions
 )
 
 func (e Exchange) ToProtobuf() protobuf.Exchange {
 	return protobuf.Exchange(e)
 }
 
+var exchangeNames = []string{"UNKNOWN", "BINANCE", "BINANCE_PERP", "BYBIT", "BINANCE_OPTIONS"}
+
 func (e Exchange) String() string {
-	return []string{"UNKNOWN", "BINANCE", "BINANCE_PERP", "BYBIT"}[e]
+	return enumName(exchangeNames, int(e))
 }
 
 func NewExchange(exchange string) Exchange {
@@ -32,11 +35,17 @@ func NewExchange(exchange string) Exchange {
 		return ExchangeBinancePerp
 	case "BYBIT":
 		return ExchangeBybit
+	case "BINANCE_OPTIONS":
+		return ExchangeBinanceOptions
 	}
 	return ExchangeUnknown
 }
 
+// NewExchangeFromProtobuf converts an exchange, unknown when out of range
 func NewExchangeFromProtobuf(pbExchange protobuf.Exchange) Exchange {
+	if !inEnum(exchangeNames, int(pbExchange)) {
+		return ExchangeUnknown
+	}
 	return Exchange(pbExchange)
 }
 
@@ -56,7 +65,12 @@ func (i InstrumentType) ToProtobuf() protobuf.Instrument {
 	return protobuf.Instrument(i)
 }
 
+// NewInstrumentTypeFromProtobuf converts an instrument type, unknown when
+// out of range
 func NewInstrumentTypeFromProtobuf(pbInstrument protobuf.Instrument) InstrumentType {
+	if !inEnum(instrumentTypeNames, int(pbInstrument)) {
+		return InstrumentTypeUnknown
+	}
 	return InstrumentType(pbInstrument)
 }
 
@@ -78,8 +92,10 @@ func NewInstrumentType(instrumentType string) InstrumentType {
 	return InstrumentTypeUnknown
 }
 
+var instrumentTypeNames = []string{"UNKNOWN", "SPOT", "MARGIN", "PERP", "INVERSE", "FUTURES", "OPTION"}
+
 func (i InstrumentType) String() string {
-	return []string{"UNKNOWN", "SPOT", "MARGIN", "PERP", "INVERSE", "FUTURES", "OPTION"}[i]
+	return enumName(instrumentTypeNames, int(i))
 }
 
 type Symbol struct {
@@ -101,9 +117,11 @@ func NewSymbol(base, quote string) Symbol {
 	}
 }
 
+// NewSymbolFromStr parses a BASE-QUOTE symbol such as BTC-USDT, rejecting
+// missing assets and extra parts rather than dropping them
 func NewSymbolFromStr(symbol string) (Symbol, error) {
 	parts := strings.Split(symbol, "-")
-	if len(parts) < 2 {
+	if len(parts) != 2 || parts[0] == "" || parts[1] == "" {
 		return Symbol{}, fmt.Errorf("invalid symbol: %s", symbol)
 	}
 	return Symbol{
@@ -138,12 +156,18 @@ func (s Side) ToProtobuf() protobuf.Side {
 	return protobuf.Side(s)
 }
 
+// NewSideFromProtobuf converts a side, unknown when out of range
 func NewSideFromProtobuf(pbSide protobuf.Side) Side {
+	if !inEnum(sideNames, int(pbSide)) {
+		return SideUnknown
+	}
 	return Side(pbSide)
 }
 
+var sideNames = []string{"UNKNOWN", "BUY", "SELL"}
+
 func (s Side) String() string {
-	return []string{"UNKNOWN", "BUY", "SELL"}[s]
+	return enumName(sideNames, int(s))
 }
 
 type TimeInForce int
@@ -172,7 +196,7 @@ func NewTimeInForce(timeInForce string) TimeInForce {
 }
 
 func (t TimeInForce) String() string {
-	return []string{"UNKNOWN", "GTC", "IOC", "FOK"}[t]
+	return enumName([]string{"UNKNOWN", "GTC", "IOC", "FOK"}, int(t))
 }
 
 type DataType int
@@ -182,6 +206,7 @@ const (
 	DataTypeTrade
 	DataTypeDepth
 	DataTypeOrder
+	DataTypeIndex
 )
 
 func NewDataType(dataType string) DataType {
@@ -192,10 +217,25 @@ func NewDataType(dataType string) DataType {
 		return DataTypeDepth
 	case "ORDER":
 		return DataTypeOrder
+	case "INDEX":
+		return DataTypeIndex
 	}
 	return DataTypeUnknown
 }
 
 func (d DataType) String() string {
-	return []string{"UNKNOWN", "TRADE", "DEPTH", "ORDER"}[d]
+	return enumName([]string{"UNKNOWN", "TRADE", "DEPTH", "ORDER", "INDEX"}, int(d))
+}
+
+func inEnum(names []string, i int) bool {
+	return i >= 0 && i < len(names)
+}
+
+// enumName returns the name of value i, UNKNOWN when out of range, such as
+// values decoded from a newer or corrupted message
+func enumName(names []string, i int) string {
+	if !inEnum(names, i) {
+		return names[0]
+	}
+	return names[i]
 }
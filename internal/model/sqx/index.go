@@ -0,0 +1,28 @@
+package sqx
+
+import (
+	"encoding/json"
+	"fmt"
+)
+
+// IndexPrice is a synthetic price computed from a weighted basket of instruments
+type IndexPrice struct {
+	Name       string             `json:"name"`
+	Value      float64            `json:"value"`
+	Components map[string]float64 `json:"components"` // Last price of each component keyed by component id
+	Timestamp  int64              `json:"timestamp"`
+}
+
+// Marshal encodes the index price as JSON. Index prices are consumed by dashboards
+// and strategies directly, so they are not part of the protobuf schema.
+func (p *IndexPrice) Marshal() ([]byte, error) {
+	return json.Marshal(p)
+}
+
+func UnmarshalIndexPrice(data []byte, price *IndexPrice) error {
+	return json.Unmarshal(data, price)
+}
+
+func (p *IndexPrice) IdStr() string {
+	return fmt.Sprintf("INDEX-%s-%d", p.Name, p.Timestamp)
+}
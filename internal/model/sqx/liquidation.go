@@ -0,0 +1,42 @@
+package sqx
+
+import (
+	"encoding/json"
+	"fmt"
+)
+
+// Liquidation is an order placed by an exchange to close a position that
+// ran out of margin. A sell liquidates a long, a buy liquidates a short.
+type Liquidation struct {
+	Exchange       Exchange       `json:"exchange"`
+	InstrumentType InstrumentType `json:"instrument"`
+	Symbol         Symbol         `json:"symbol"`
+	Side           Side           `json:"side"`
+	Price          float64        `json:"price"` // Average fill price
+	Quantity       float64        `json:"quantity"`
+	Timestamp      int64          `json:"timestamp"`
+}
+
+// Notional returns the quote value of the liquidation
+func (l *Liquidation) Notional() float64 {
+	return l.Price * l.Quantity
+}
+
+// Long reports whether the liquidated position was long
+func (l *Liquidation) Long() bool {
+	return l.Side == SideSell
+}
+
+// Marshal encodes the liquidation as JSON. Liquidations are not part of the
+// protobuf schema.
+func (l *Liquidation) Marshal() ([]byte, error) {
+	return json.Marshal(l)
+}
+
+func UnmarshalLiquidation(data []byte, liquidation *Liquidation) error {
+	return json.Unmarshal(data, liquidation)
+}
+
+func (l *Liquidation) IdStr() string {
+	return fmt.Sprintf("LIQ-%s-%s-%d", l.Exchange, l.Symbol, l.Timestamp)
+}
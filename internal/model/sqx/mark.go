@@ -0,0 +1,32 @@
+package sqx
+
+import (
+	"encoding/json"
+	"fmt"
+)
+
+// MarkPrice is the mark price and funding of a perpetual contract
+type MarkPrice struct {
+	Exchange        Exchange       `json:"exchange"`
+	InstrumentType  InstrumentType `json:"instrument"`
+	Symbol          Symbol         `json:"symbol"`
+	MarkPrice       float64        `json:"mark_price"`
+	IndexPrice      float64        `json:"index_price"`
+	FundingRate     float64        `json:"funding_rate"`      // Rate paid by longs to shorts at the next funding, e.g. 0.0001
+	NextFundingTime int64          `json:"next_funding_time"` // Unix milliseconds
+	Timestamp       int64          `json:"timestamp"`
+}
+
+// Marshal encodes the mark price as JSON. Like index prices, mark prices are
+// not part of the protobuf schema.
+func (p *MarkPrice) Marshal() ([]byte, error) {
+	return json.Marshal(p)
+}
+
+func UnmarshalMarkPrice(data []byte, price *MarkPrice) error {
+	return json.Unmarshal(data, price)
+}
+
+func (p *MarkPrice) IdStr() string {
+	return fmt.Sprintf("MARK-%s-%s-%d", p.Exchange, p.Symbol, p.Timestamp)
+}
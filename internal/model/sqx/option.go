@@ -0,0 +1,151 @@
+package sqx
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+type OptionKind int
+
+const (
+	OptionKindUnknown OptionKind = iota
+	OptionKindCall
+	OptionKindPut
+)
+
+var optionKindNames = []string{"UNKNOWN", "CALL", "PUT"}
+
+func (k OptionKind) String() string {
+	return enumName(optionKindNames, int(k))
+}
+
+func NewOptionKind(kind string) OptionKind {
+	switch strings.ToUpper(kind) {
+	case "CALL", "C":
+		return OptionKindCall
+	case "PUT", "P":
+		return OptionKindPut
+	}
+	return OptionKindUnknown
+}
+
+// optionExpiryLayout is the expiry date of contract names, e.g. 250328
+const optionExpiryLayout = "060102"
+
+// OptionContract identifies an option on an underlying symbol. Its name is
+// the underlying, expiry date, strike and kind, e.g. BTC-USDT-250328-90000-C.
+type OptionContract struct {
+	Underlying Symbol     `json:"underlying"`
+	Expiry     int64      `json:"expiry"` // Unix milliseconds
+	Strike     float64    `json:"strike"`
+	Kind       OptionKind `json:"kind"`
+}
+
+// NewOptionContractFromStr parses a contract name such as
+// BTC-USDT-250328-90000-C, expiring at 08:00 UTC of its date like the
+// contracts of Binance and Deribit
+func NewOptionContractFromStr(contract string) (OptionContract, error) {
+	parts := strings.Split(contract, "-")
+	if len(parts) != 5 {
+		return OptionContract{}, fmt.Errorf("invalid option contract: %s", contract)
+	}
+	underlying, err := NewSymbolFromStr(parts[0] + "-" + parts[1])
+	if err != nil {
+		return OptionContract{}, fmt.Errorf("invalid option contract: %s", contract)
+	}
+	date, err := time.Parse(optionExpiryLayout, parts[2])
+	if err != nil {
+		return OptionContract{}, fmt.Errorf("invalid expiry in %s", contract)
+	}
+	strike, err := strconv.ParseFloat(parts[3], 64)
+	if err != nil || strike <= 0 {
+		return OptionContract{}, fmt.Errorf("invalid strike in %s", contract)
+	}
+	kind := NewOptionKind(parts[4])
+	if kind == OptionKindUnknown {
+		return OptionContract{}, fmt.Errorf("invalid kind in %s", contract)
+	}
+	return OptionContract{
+		Underlying: underlying,
+		Expiry:     date.Add(8 * time.Hour).UnixMilli(),
+		Strike:     strike,
+		Kind:       kind,
+	}, nil
+}
+
+func (c OptionContract) String() string {
+	return fmt.Sprintf("%s-%s-%s-%s", c.Underlying, time.UnixMilli(c.Expiry).UTC().Format(optionExpiryLayout),
+		strconv.FormatFloat(c.Strike, 'f', -1, 64), c.Kind.String()[:1])
+}
+
+// YearsToExpiry returns the time left until expiry at now in years, the unit
+// of the pricing models, 0 once expired
+func (c OptionContract) YearsToExpiry(now time.Time) float64 {
+	left := time.UnixMilli(c.Expiry).Sub(now)
+	if left <= 0 {
+		return 0
+	}
+	return left.Hours() / (365 * 24)
+}
+
+// OptionTrade is a trade of an option contract, priced in the quote asset of
+// the underlying per unit of it
+type OptionTrade struct {
+	Id        int64          `json:"id"`
+	Exchange  Exchange       `json:"exchange"`
+	Contract  OptionContract `json:"contract"`
+	TakerSide Side           `json:"taker_side"`
+	Price     float64        `json:"price"`
+	Quantity  float64        `json:"quantity"` // Contracts
+	Timestamp int64          `json:"timestamp"`
+}
+
+// Marshal encodes the option trade as JSON. Options are not part of the
+// protobuf schema.
+func (t *OptionTrade) Marshal() ([]byte, error) {
+	return json.Marshal(t)
+}
+
+func UnmarshalOptionTrade(data []byte, trade *OptionTrade) error {
+	return json.Unmarshal(data, trade)
+}
+
+func (t *OptionTrade) IdStr() string {
+	return fmt.Sprintf("%s-OPTION-%s-%d", t.Exchange, t.Contract, t.Id)
+}
+
+// OptionMark is the mark price of an option contract with the implied
+// volatilities and greeks the exchange computed for it. Fields a source does
+// not carry are 0, the mark price stream of Binance only carrying the price.
+type OptionMark struct {
+	Exchange  Exchange       `json:"exchange"`
+	Contract  OptionContract `json:"contract"`
+	MarkPrice float64        `json:"mark_price"`
+	BidPrice  float64        `json:"bid_price"`
+	AskPrice  float64        `json:"ask_price"`
+	MarkIV    float64        `json:"mark_iv"` // Annualized, e.g. 0.55
+	BidIV     float64        `json:"bid_iv"`
+	AskIV     float64        `json:"ask_iv"`
+	Delta     float64        `json:"delta"`
+	Gamma     float64        `json:"gamma"`
+	Vega      float64        `json:"vega"`
+	Theta     float64        `json:"theta"` // Per day
+	Timestamp int64          `json:"timestamp"`
+}
+
+// Marshal encodes the option mark as JSON. Options are not part of the
+// protobuf schema.
+func (m *OptionMark) Marshal() ([]byte, error) {
+	return json.Marshal(m)
+}
+
+func UnmarshalOptionMark(data []byte, mark *OptionMark) error {
+	return json.Unmarshal(data, mark)
+}
+
+func (m *OptionMark) IdStr() string {
+	return fmt.Sprintf("MARK-%s-%s-%d", m.Exchange, m.Contract, m.Timestamp)
+}
@@ -0,0 +1,37 @@
+package sqx
+
+import (
+	"testing"
+	"time"
+)
+
+func TestNewOptionContractFromStr(t *testing.T) {
+	contract, err := NewOptionContractFromStr("btc-usdt-250328-90000-c")
+	if err != nil {
+		t.Fatal(err)
+	}
+	expiry := time.Date(2025, 3, 28, 8, 0, 0, 0, time.UTC)
+	if contract.Underlying != NewSymbol("BTC", "USDT") || contract.Expiry != expiry.UnixMilli() || contract.Strike != 90000 || contract.Kind != OptionKindCall {
+		t.Errorf("parsed %+v", contract)
+	}
+	if s := contract.String(); s != "BTC-USDT-250328-90000-C" {
+		t.Errorf("String() = %s", s)
+	}
+	if years := contract.YearsToExpiry(expiry.Add(-365 * 24 * time.Hour)); years != 1 {
+		t.Errorf("YearsToExpiry() = %v, want 1", years)
+	}
+	if years := contract.YearsToExpiry(expiry.Add(time.Hour)); years != 0 {
+		t.Errorf("YearsToExpiry() after expiry = %v", years)
+	}
+
+	put, err := NewOptionContractFromStr("ETH-USDT-250627-2450.5-P")
+	if err != nil || put.Kind != OptionKindPut || put.Strike != 2450.5 || put.String() != "ETH-USDT-250627-2450.5-P" {
+		t.Errorf("parsed %+v, %v", put, err)
+	}
+
+	for _, s := range []string{"BTC-250328-90000-C", "BTC-USDT-20250328-90000-C", "BTC-USDT-250328-0-C", "BTC-USDT-250328-90000-X", "BTC-USDT-250328-90000-C-1"} {
+		if _, err := NewOptionContractFromStr(s); err == nil {
+			t.Errorf("%s parsed", s)
+		}
+	}
+}
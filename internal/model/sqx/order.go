@@ -0,0 +1,165 @@
+package sqx
+
+import (
+	"encoding/json"
+	"strings"
+
+	"github.com/BullionBear/sequex/internal/model/protobuf"
+)
+
+type OrderType int
+
+const (
+	OrderTypeUnknown OrderType = iota
+	OrderTypeLimit
+	OrderTypeMarket
+	OrderTypeStopMarket
+)
+
+func (o OrderType) ToProtobuf() protobuf.OrderType {
+	return protobuf.OrderType(o)
+}
+
+func NewOrderType(orderType string) OrderType {
+	switch strings.ToUpper(orderType) {
+	case "LIMIT":
+		return OrderTypeLimit
+	case "MARKET":
+		return OrderTypeMarket
+	case "STOP_MARKET":
+		return OrderTypeStopMarket
+	}
+	return OrderTypeUnknown
+}
+
+func (o OrderType) String() string {
+	return enumName([]string{"UNKNOWN", "LIMIT", "MARKET", "STOP_MARKET"}, int(o))
+}
+
+type OrderStatus int
+
+const (
+	OrderStatusUnknown OrderStatus = iota
+	OrderStatusNew
+	OrderStatusPartiallyFilled
+	OrderStatusFilled
+	OrderStatusCanceled
+	OrderStatusRejected
+	OrderStatusExpired
+)
+
+func NewOrderStatus(status string) OrderStatus {
+	switch strings.ToUpper(status) {
+	case "NEW":
+		return OrderStatusNew
+	case "PARTIALLY_FILLED":
+		return OrderStatusPartiallyFilled
+	case "FILLED":
+		return OrderStatusFilled
+	case "CANCELED":
+		return OrderStatusCanceled
+	case "REJECTED":
+		return OrderStatusRejected
+	case "EXPIRED":
+		return OrderStatusExpired
+	}
+	return OrderStatusUnknown
+}
+
+func (o OrderStatus) String() string {
+	return enumName([]string{"UNKNOWN", "NEW", "PARTIALLY_FILLED", "FILLED", "CANCELED", "REJECTED", "EXPIRED"}, int(o))
+}
+
+// IsFinal reports whether no further execution can happen on the order
+func (o OrderStatus) IsFinal() bool {
+	return o == OrderStatusFilled || o == OrderStatusCanceled || o == OrderStatusRejected || o == OrderStatusExpired
+}
+
+// OrderIntent is a request to the OMS to place a new order
+type OrderIntent struct {
+	ClientOrderId  string         `json:"client_order_id"`
+	Strategy       string         `json:"strategy"`
+	Exchange       Exchange       `json:"exchange"`
+	InstrumentType InstrumentType `json:"instrument"`
+	Symbol         Symbol         `json:"symbol"`
+	Side           Side           `json:"side"`
+	Type           OrderType      `json:"type"`
+	TimeInForce    TimeInForce    `json:"time_in_force"`
+	Price          float64        `json:"price"`
+	Quantity       float64        `json:"quantity"`
+	StopPrice      float64        `json:"stop_price"`
+	Timestamp      int64          `json:"timestamp"`
+}
+
+func (o *OrderIntent) Marshal() ([]byte, error) {
+	return json.Marshal(o)
+}
+
+func UnmarshalOrderIntent(data []byte, intent *OrderIntent) error {
+	return json.Unmarshal(data, intent)
+}
+
+// CancelIntent is a request to the OMS to cancel a working order
+type CancelIntent struct {
+	ClientOrderId     string         `json:"client_order_id"`
+	OrigClientOrderId string         `json:"orig_client_order_id"`
+	Strategy          string         `json:"strategy"`
+	Exchange          Exchange       `json:"exchange"`
+	InstrumentType    InstrumentType `json:"instrument"`
+	Symbol            Symbol         `json:"symbol"`
+	Timestamp         int64          `json:"timestamp"`
+}
+
+func (c *CancelIntent) Marshal() ([]byte, error) {
+	return json.Marshal(c)
+}
+
+func UnmarshalCancelIntent(data []byte, intent *CancelIntent) error {
+	return json.Unmarshal(data, intent)
+}
+
+// ExecutionReport is an order state change published by the OMS,
+// including acknowledgements, fills, cancels and rejects
+type ExecutionReport struct {
+	OrderId         string         `json:"order_id"`
+	ClientOrderId   string         `json:"client_order_id"`
+	ExecId          string         `json:"exec_id"`
+	Strategy        string         `json:"strategy"`
+	Exchange        Exchange       `json:"exchange"`
+	InstrumentType  InstrumentType `json:"instrument"`
+	Symbol          Symbol         `json:"symbol"`
+	Side            Side           `json:"side"`
+	Type            OrderType      `json:"type"`
+	Status          OrderStatus    `json:"status"`
+	Price           float64        `json:"price"`
+	Quantity        float64        `json:"quantity"`
+	LastPrice       float64        `json:"last_price"`
+	LastQuantity    float64        `json:"last_quantity"`
+	CumQuantity     float64        `json:"cum_quantity"`
+	AvgPrice        float64        `json:"avg_price"`
+	Commission      float64        `json:"commission"`
+	CommissionAsset string         `json:"commission_asset"`
+	Text            string         `json:"text"`
+	Timestamp       int64          `json:"timestamp"`
+}
+
+// IsFill reports whether the report carries an execution
+func (e *ExecutionReport) IsFill() bool {
+	return e.LastQuantity > 0
+}
+
+// LeavesQuantity returns the quantity still open on the order
+func (e *ExecutionReport) LeavesQuantity() float64 {
+	if e.Status.IsFinal() {
+		return 0
+	}
+	return e.Quantity - e.CumQuantity
+}
+
+func (e *ExecutionReport) Marshal() ([]byte, error) {
+	return json.Marshal(e)
+}
+
+func UnmarshalExecutionReport(data []byte, report *ExecutionReport) error {
+	return json.Unmarshal(data, report)
+}
@@ -0,0 +1,90 @@
+package sqx
+
+import (
+	"encoding/json"
+	"fmt"
+)
+
+// OpenInterest is the total size of the open positions of a contract
+type OpenInterest struct {
+	Exchange       Exchange       `json:"exchange"`
+	InstrumentType InstrumentType `json:"instrument"`
+	Symbol         Symbol         `json:"symbol"`
+	Quantity       float64        `json:"quantity"` // Open interest in contracts
+	Notional       float64        `json:"notional"` // Open interest value in the quote asset
+	Period         string         `json:"period"`   // Sampling period, e.g. 5m
+	Timestamp      int64          `json:"timestamp"`
+}
+
+// LongShortRatioKind is the population a long/short ratio is measured over
+type LongShortRatioKind string
+
+const (
+	LongShortTopPositions  LongShortRatioKind = "top_positions"  // Position sizes of the top traders
+	LongShortTopAccounts   LongShortRatioKind = "top_accounts"   // Accounts of the top traders
+	LongShortGlobalAccount LongShortRatioKind = "global_account" // Every account with a position
+)
+
+// LongShortRatio is the balance between long and short positioning
+type LongShortRatio struct {
+	Exchange       Exchange           `json:"exchange"`
+	InstrumentType InstrumentType     `json:"instrument"`
+	Symbol         Symbol             `json:"symbol"`
+	Kind           LongShortRatioKind `json:"kind"`
+	Ratio          float64            `json:"ratio"` // Long / short
+	Long           float64            `json:"long"`  // Long share, between 0 and 1
+	Short          float64            `json:"short"` // Short share, between 0 and 1
+	Period         string             `json:"period"`
+	Timestamp      int64              `json:"timestamp"`
+}
+
+// TakerVolume is the volume bought and sold by takers over a period
+type TakerVolume struct {
+	Exchange       Exchange       `json:"exchange"`
+	InstrumentType InstrumentType `json:"instrument"`
+	Symbol         Symbol         `json:"symbol"`
+	BuyVolume      float64        `json:"buy_volume"`
+	SellVolume     float64        `json:"sell_volume"`
+	Ratio          float64        `json:"ratio"` // Buy / sell
+	Period         string         `json:"period"`
+	Timestamp      int64          `json:"timestamp"`
+}
+
+// Positioning data is consumed by strategies and dashboards directly, so
+// like index prices it is encoded as JSON rather than protobuf.
+
+func (o *OpenInterest) Marshal() ([]byte, error) {
+	return json.Marshal(o)
+}
+
+func UnmarshalOpenInterest(data []byte, o *OpenInterest) error {
+	return json.Unmarshal(data, o)
+}
+
+func (o *OpenInterest) IdStr() string {
+	return fmt.Sprintf("OI-%s-%s-%s-%d", o.Exchange, o.Symbol, o.Period, o.Timestamp)
+}
+
+func (r *LongShortRatio) Marshal() ([]byte, error) {
+	return json.Marshal(r)
+}
+
+func UnmarshalLongShortRatio(data []byte, r *LongShortRatio) error {
+	return json.Unmarshal(data, r)
+}
+
+func (r *LongShortRatio) IdStr() string {
+	return fmt.Sprintf("LSR-%s-%s-%s-%s-%d", r.Exchange, r.Symbol, r.Kind, r.Period, r.Timestamp)
+}
+
+func (v *TakerVolume) Marshal() ([]byte, error) {
+	return json.Marshal(v)
+}
+
+func UnmarshalTakerVolume(data []byte, v *TakerVolume) error {
+	return json.Unmarshal(data, v)
+}
+
+func (v *TakerVolume) IdStr() string {
+	return fmt.Sprintf("TV-%s-%s-%s-%d", v.Exchange, v.Symbol, v.Period, v.Timestamp)
+}
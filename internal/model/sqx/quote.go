@@ -0,0 +1,81 @@
+package sqx
+
+import (
+	"encoding/json"
+	"fmt"
+)
+
+// Quote is the best bid and ask of a symbol on a venue. A side without
+// orders has a zero price.
+type Quote struct {
+	Exchange       Exchange       `json:"exchange"`
+	InstrumentType InstrumentType `json:"instrument"`
+	Symbol         Symbol         `json:"symbol"`
+	BidPrice       float64        `json:"bid_price"`
+	BidQuantity    float64        `json:"bid_quantity"`
+	AskPrice       float64        `json:"ask_price"`
+	AskQuantity    float64        `json:"ask_quantity"`
+	Timestamp      int64          `json:"timestamp"`
+}
+
+// Venue returns the venue of the quote, e.g. BINANCE.SPOT
+func (q *Quote) Venue() string {
+	return q.Exchange.String() + "." + q.InstrumentType.String()
+}
+
+// Marshal encodes the quote as JSON. Quotes are not part of the protobuf
+// schema.
+func (q *Quote) Marshal() ([]byte, error) {
+	return json.Marshal(q)
+}
+
+func UnmarshalQuote(data []byte, quote *Quote) error {
+	return json.Unmarshal(data, quote)
+}
+
+func (q *Quote) IdStr() string {
+	return fmt.Sprintf("QUOTE-%s-%s-%s-%d", q.Exchange, q.InstrumentType, q.Symbol, q.Timestamp)
+}
+
+// BBO is the consolidated best bid and offer of a symbol across venues, in
+// the manner of an NBBO. The quantity of a side sums the venues quoting its
+// best price.
+type BBO struct {
+	Seq         uint64   `json:"seq"` // Position among the changes of the symbol since the service started, from 1
+	Symbol      Symbol   `json:"symbol"`
+	BidPrice    float64  `json:"bid_price"`
+	BidQuantity float64  `json:"bid_quantity"`
+	BidVenues   []string `json:"bid_venues"` // Venues quoting the best bid, e.g. BINANCE.SPOT
+	AskPrice    float64  `json:"ask_price"`
+	AskQuantity float64  `json:"ask_quantity"`
+	AskVenues   []string `json:"ask_venues"` // Venues quoting the best ask
+	Stale       []string `json:"stale"`      // Venues left out for not quoting recently
+	Timestamp   int64    `json:"timestamp"`  // Time of the change, Unix milliseconds
+}
+
+// Mid returns the mid price, 0 unless both sides are quoted
+func (b *BBO) Mid() float64 {
+	if b.BidPrice == 0 || b.AskPrice == 0 {
+		return 0
+	}
+	return (b.BidPrice + b.AskPrice) / 2
+}
+
+// Crossed reports whether the best bid of a venue reaches the best ask of
+// another, which a router can arbitrage
+func (b *BBO) Crossed() bool {
+	return b.BidPrice != 0 && b.AskPrice != 0 && b.BidPrice >= b.AskPrice
+}
+
+// Marshal encodes the BBO as JSON. BBOs are not part of the protobuf schema.
+func (b *BBO) Marshal() ([]byte, error) {
+	return json.Marshal(b)
+}
+
+func UnmarshalBBO(data []byte, bbo *BBO) error {
+	return json.Unmarshal(data, bbo)
+}
+
+func (b *BBO) IdStr() string {
+	return fmt.Sprintf("BBO-%s-%d-%d", b.Symbol, b.Timestamp, b.Seq)
+}
@@ -0,0 +1,184 @@
+package sqx
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/BullionBear/sequex/internal/model/protobuf"
+	"google.golang.org/protobuf/proto"
+)
+
+// SystemEventKind is the taxonomy of system events
+type SystemEventKind int
+
+const (
+	SystemEventKindUnknown SystemEventKind = iota
+	SystemEventKindNodeLifecycle
+	SystemEventKindRiskBreach
+	SystemEventKindExchangeHalt
+	SystemEventKindReconnect
+	SystemEventKindDLQEntry
+	SystemEventKindError
+)
+
+var systemEventKindNames = []string{"UNKNOWN", "NODE_LIFECYCLE", "RISK_BREACH", "EXCHANGE_HALT", "RECONNECT", "DLQ_ENTRY", "ERROR"}
+
+// SystemEventKinds are the known kinds, in their protobuf order
+var SystemEventKinds = []SystemEventKind{
+	SystemEventKindNodeLifecycle,
+	SystemEventKindRiskBreach,
+	SystemEventKindExchangeHalt,
+	SystemEventKindReconnect,
+	SystemEventKindDLQEntry,
+	SystemEventKindError,
+}
+
+func (k SystemEventKind) ToProtobuf() protobuf.SystemEventKind {
+	return protobuf.SystemEventKind(k)
+}
+
+// NewSystemEventKindFromProtobuf converts a kind, unknown when out of range
+func NewSystemEventKindFromProtobuf(pbKind protobuf.SystemEventKind) SystemEventKind {
+	if !inEnum(systemEventKindNames, int(pbKind)) {
+		return SystemEventKindUnknown
+	}
+	return SystemEventKind(pbKind)
+}
+
+// NewSystemEventKind parses a kind by name, in either case
+func NewSystemEventKind(kind string) SystemEventKind {
+	for i, name := range systemEventKindNames {
+		if i > 0 && strings.EqualFold(kind, name) {
+			return SystemEventKind(i)
+		}
+	}
+	return SystemEventKindUnknown
+}
+
+func (k SystemEventKind) String() string {
+	return enumName(systemEventKindNames, int(k))
+}
+
+// Token returns the last token of the subject of the kind, e.g. risk_breach
+func (k SystemEventKind) Token() string {
+	return strings.ToLower(k.String())
+}
+
+func (k SystemEventKind) MarshalText() ([]byte, error) {
+	return []byte(k.String()), nil
+}
+
+func (k *SystemEventKind) UnmarshalText(text []byte) error {
+	if *k = NewSystemEventKind(string(text)); *k == SystemEventKindUnknown {
+		return fmt.Errorf("unknown system event kind: %s", text)
+	}
+	return nil
+}
+
+// Severity is how much a system event matters, in increasing order
+type Severity int
+
+const (
+	SeverityUnknown Severity = iota
+	SeverityInfo
+	SeverityWarning
+	SeverityError
+	SeverityCritical
+)
+
+var severityNames = []string{"UNKNOWN", "INFO", "WARNING", "ERROR", "CRITICAL"}
+
+func (s Severity) ToProtobuf() protobuf.Severity {
+	return protobuf.Severity(s)
+}
+
+// NewSeverityFromProtobuf converts a severity, unknown when out of range
+func NewSeverityFromProtobuf(pbSeverity protobuf.Severity) Severity {
+	if !inEnum(severityNames, int(pbSeverity)) {
+		return SeverityUnknown
+	}
+	return Severity(pbSeverity)
+}
+
+// NewSeverity parses a severity by name, in either case
+func NewSeverity(severity string) Severity {
+	for i, name := range severityNames {
+		if i > 0 && strings.EqualFold(severity, name) {
+			return Severity(i)
+		}
+	}
+	return SeverityUnknown
+}
+
+func (s Severity) String() string {
+	return enumName(severityNames, int(s))
+}
+
+func (s Severity) MarshalText() ([]byte, error) {
+	return []byte(s.String()), nil
+}
+
+func (s *Severity) UnmarshalText(text []byte) error {
+	if *s = NewSeverity(string(text)); *s == SeverityUnknown {
+		return fmt.Errorf("unknown severity: %s", text)
+	}
+	return nil
+}
+
+// SystemEvent is something that happened to the system: a node starting or
+// stopping, a risk limit breached, an exchange halting trading, a
+// connection dropped, a message dead-lettered. Every service publishes its
+// own to sys.events.<kind>.
+type SystemEvent struct {
+	ID         string            `json:"id"` // Unique, the message id of the event
+	Kind       SystemEventKind   `json:"kind"`
+	Severity   Severity          `json:"severity"`
+	Source     string            `json:"source"` // Service or node publishing the event
+	Code       string            `json:"code"`   // What happened within the kind, e.g. started or paused
+	Message    string            `json:"message"`
+	Timestamp  int64             `json:"timestamp"` // Unix milliseconds
+	Attributes map[string]string `json:"attributes,omitempty"`
+}
+
+func (e *SystemEvent) ToProtobuf() *protobuf.SystemEvent {
+	return &protobuf.SystemEvent{
+		Id:         e.ID,
+		Kind:       e.Kind.ToProtobuf(),
+		Severity:   e.Severity.ToProtobuf(),
+		Source:     e.Source,
+		Code:       e.Code,
+		Message:    e.Message,
+		Timestamp:  e.Timestamp,
+		Attributes: e.Attributes,
+	}
+}
+
+func (e *SystemEvent) FromProtobuf(event *protobuf.SystemEvent) error {
+	e.Kind = NewSystemEventKindFromProtobuf(event.Kind)
+	if e.Kind == SystemEventKindUnknown {
+		return fmt.Errorf("unknown system event kind: %s", event.Kind.String())
+	}
+	e.Severity = NewSeverityFromProtobuf(event.Severity)
+	if e.Severity == SeverityUnknown {
+		return fmt.Errorf("unknown severity: %s", event.Severity.String())
+	}
+	e.ID = event.Id
+	e.Source = event.Source
+	e.Code = event.Code
+	e.Message = event.Message
+	e.Timestamp = event.Timestamp
+	e.Attributes = event.Attributes
+	return nil
+}
+
+func (e *SystemEvent) Marshal() ([]byte, error) {
+	return proto.Marshal(e.ToProtobuf())
+}
+
+func UnmarshalSystemEvent(data []byte, event *SystemEvent) error {
+	var pb protobuf.SystemEvent
+	if err := proto.Unmarshal(data, &pb); err != nil {
+		return err
+	}
+	return event.FromProtobuf(&pb)
+}
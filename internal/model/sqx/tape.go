@@ -0,0 +1,42 @@
+package sqx
+
+import (
+	"encoding/json"
+	"fmt"
+)
+
+// TapePrint is a trade of the consolidated tape of a symbol, which merges
+// the trades of every venue listing it. Prints of a symbol are numbered
+// without gaps and timed on a clock that never goes backwards.
+type TapePrint struct {
+	Seq            uint64         `json:"seq"` // Position on the tape of the symbol, from 1
+	Symbol         Symbol         `json:"symbol"`
+	Exchange       Exchange       `json:"exchange"` // Venue the trade happened on
+	InstrumentType InstrumentType `json:"instrument"`
+	TradeId        int64          `json:"trade_id"` // Id of the trade on its venue
+	TakerSide      Side           `json:"taker_side"`
+	Price          float64        `json:"price"`
+	Quantity       float64        `json:"quantity"`
+	VenueTime      int64          `json:"venue_time"` // Timestamp of the venue, Unix milliseconds
+	Skew           int64          `json:"skew"`       // Correction applied to the venue clock, milliseconds
+	Timestamp      int64          `json:"timestamp"`  // Time on the tape, Unix milliseconds
+}
+
+// Venue returns the venue of the print, e.g. BINANCE.SPOT
+func (p *TapePrint) Venue() string {
+	return p.Exchange.String() + "." + p.InstrumentType.String()
+}
+
+// Marshal encodes the print as JSON. Tape prints are not part of the
+// protobuf schema.
+func (p *TapePrint) Marshal() ([]byte, error) {
+	return json.Marshal(p)
+}
+
+func UnmarshalTapePrint(data []byte, print *TapePrint) error {
+	return json.Unmarshal(data, print)
+}
+
+func (p *TapePrint) IdStr() string {
+	return fmt.Sprintf("TAPE-%s-%d", p.Symbol, p.Seq)
+}
@@ -2,6 +2,7 @@ package sqx
 
 import (
 	"fmt"
+	"strconv"
 
 	"github.com/BullionBear/sequex/internal/model/protobuf"
 	"google.golang.org/protobuf/proto"
@@ -33,6 +34,9 @@ func (t *Trade) ToProtobuf() *protobuf.Trade {
 }
 
 func (t *Trade) FromProtobuf(trade *protobuf.Trade) error {
+	if trade.Symbol == nil {
+		return fmt.Errorf("missing symbol")
+	}
 	t.Id = trade.Id
 	t.Symbol = NewSymbol(trade.Symbol.Base, trade.Symbol.Quote)
 	t.Exchange = NewExchangeFromProtobuf(trade.Exchange)
@@ -57,6 +61,24 @@ func (t *Trade) Marshal() ([]byte, error) {
 	return proto.Marshal(t.ToProtobuf())
 }
 
+// MarshalAppend encodes the trade into pb and appends the wire bytes to buf.
+// Reusing pb and buf across calls keeps the publish path allocation free.
+func (t *Trade) MarshalAppend(buf []byte, pb *protobuf.Trade) ([]byte, error) {
+	if pb.Symbol == nil {
+		pb.Symbol = &protobuf.Symbol{}
+	}
+	pb.Id = t.Id
+	pb.Symbol.Base = t.Symbol.Base
+	pb.Symbol.Quote = t.Symbol.Quote
+	pb.Exchange = t.Exchange.ToProtobuf()
+	pb.Instrument = t.InstrumentType.ToProtobuf()
+	pb.Side = t.TakerSide.ToProtobuf()
+	pb.Price = t.Price
+	pb.Quantity = t.Quantity
+	pb.Timestamp = t.Timestamp
+	return proto.MarshalOptions{}.MarshalAppend(buf, pb)
+}
+
 func Unmarshal(data []byte, trade *Trade) error {
 	pbTrade := &protobuf.Trade{}
 	err := proto.Unmarshal(data, pbTrade)
@@ -74,3 +96,16 @@ func (t *Trade) IdStr() string {
 
 	return fmt.Sprintf("%s-%s-%s-%d", t.Exchange.String(), t.InstrumentType.String(), t.Symbol.String(), t.Id)
 }
+
+// AppendIdStr appends the IdStr of the trade to buf
+func (t *Trade) AppendIdStr(buf []byte) []byte {
+	buf = append(buf, t.Exchange.String()...)
+	buf = append(buf, '-')
+	buf = append(buf, t.InstrumentType.String()...)
+	buf = append(buf, '-')
+	buf = append(buf, t.Symbol.Base...)
+	buf = append(buf, '-')
+	buf = append(buf, t.Symbol.Quote...)
+	buf = append(buf, '-')
+	return strconv.AppendInt(buf, t.Id, 10)
+}
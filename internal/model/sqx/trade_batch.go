@@ -0,0 +1,67 @@
+package sqx
+
+import (
+	"fmt"
+	"strconv"
+
+	"github.com/BullionBear/sequex/internal/model/protobuf"
+	"google.golang.org/protobuf/proto"
+)
+
+// HeaderTradeBatch marks a payload as a TradeBatch; its value is the number
+// of trades. Payloads without the header carry a single Trade.
+const HeaderTradeBatch = "Sqx-Trade-Batch"
+
+// MarshalTradeBatch encodes trades as a single TradeBatch message
+func MarshalTradeBatch(trades []Trade) ([]byte, error) {
+	batch := &protobuf.TradeBatch{Trades: make([]*protobuf.Trade, 0, len(trades))}
+	for i := range trades {
+		batch.Trades = append(batch.Trades, trades[i].ToProtobuf())
+	}
+	return proto.Marshal(batch)
+}
+
+// UnmarshalTradeBatch decodes a TradeBatch message
+func UnmarshalTradeBatch(data []byte) ([]Trade, error) {
+	batch := &protobuf.TradeBatch{}
+	if err := proto.Unmarshal(data, batch); err != nil {
+		return nil, err
+	}
+	trades := make([]Trade, len(batch.Trades))
+	for i, pbTrade := range batch.Trades {
+		if err := trades[i].FromProtobuf(pbTrade); err != nil {
+			return nil, fmt.Errorf("trade %d of batch: %w", i, err)
+		}
+	}
+	return trades, nil
+}
+
+// UnmarshalTrades decodes a trade payload whether or not it is batched.
+// batchHeader is the value of the HeaderTradeBatch header, empty for a
+// single trade.
+func UnmarshalTrades(data []byte, batchHeader string) ([]Trade, error) {
+	if batchHeader == "" {
+		var trade Trade
+		if err := Unmarshal(data, &trade); err != nil {
+			return nil, err
+		}
+		return []Trade{trade}, nil
+	}
+	trades, err := UnmarshalTradeBatch(data)
+	if err != nil {
+		return nil, err
+	}
+	if n, err := strconv.Atoi(batchHeader); err == nil && n != len(trades) {
+		return nil, fmt.Errorf("trade batch header announces %d trades, payload has %d", n, len(trades))
+	}
+	return trades, nil
+}
+
+// TradeBatchIdStr returns a deduplication id spanning the first and last trade of a batch
+func TradeBatchIdStr(trades []Trade) string {
+	if len(trades) == 0 {
+		return ""
+	}
+	first, last := trades[0], trades[len(trades)-1]
+	return fmt.Sprintf("%s-%d", first.IdStr(), last.Id)
+}
@@ -0,0 +1,148 @@
+package sqx
+
+import (
+	"encoding/json"
+	"math"
+	"strconv"
+	"strings"
+	"testing"
+	"testing/quick"
+
+	"github.com/BullionBear/sequex/internal/model/protobuf"
+	"google.golang.org/protobuf/proto"
+)
+
+func FuzzNewSymbolFromStr(f *testing.F) {
+	for _, s := range []string{"BTC-USDT", "eth-usdt", "BTC-", "-USDT", "BTC-USDT-PERP", "BTCUSDT", "", "--", "1000PEPE-USDT"} {
+		f.Add(s)
+	}
+	f.Fuzz(func(t *testing.T, s string) {
+		symbol, err := NewSymbolFromStr(s)
+		if err != nil {
+			return
+		}
+		if symbol.Base == "" || symbol.Quote == "" || strings.Contains(symbol.Base, "-") || strings.Contains(symbol.Quote, "-") {
+			t.Fatalf("%q parsed as %+v", s, symbol)
+		}
+		if symbol.String() != strings.ToUpper(s) {
+			t.Fatalf("%q parsed as %s, dropping a part", s, symbol)
+		}
+		again, err := NewSymbolFromStr(symbol.String())
+		if err != nil || again != symbol {
+			t.Fatalf("%s parsed back as %+v: %v", symbol, again, err)
+		}
+	})
+}
+
+func FuzzUnmarshalTrade(f *testing.F) {
+	valid := Trade{Id: 1, Symbol: NewSymbol("BTC", "USDT"), Exchange: ExchangeBinance, InstrumentType: InstrumentTypeSpot,
+		TakerSide: SideBuy, Price: 60000, Quantity: 0.5, Timestamp: 1757894400000}
+	data, err := valid.Marshal()
+	if err != nil {
+		f.Fatal(err)
+	}
+	f.Add(data)
+	f.Add(data[:len(data)/2])
+	noSymbol, _ := proto.Marshal(&protobuf.Trade{Id: 1, Exchange: 1, Instrument: 1, Side: 1})
+	f.Add(noSymbol)
+	unknownEnums, _ := proto.Marshal(&protobuf.Trade{Id: 1, Symbol: &protobuf.Symbol{Base: "BTC", Quote: "USDT"}, Exchange: 9, Instrument: 9, Side: 9})
+	f.Add(unknownEnums)
+	f.Fuzz(func(t *testing.T, data []byte) {
+		var trade Trade
+		if err := Unmarshal(data, &trade); err != nil {
+			return
+		}
+		trade.IdStr()
+		encoded, err := trade.Marshal()
+		if err != nil {
+			t.Fatal(err)
+		}
+		var again Trade
+		if err := Unmarshal(encoded, &again); err != nil {
+			t.Fatalf("%+v does not decode back: %v", trade, err)
+		}
+		if !sameTrade(trade, again) {
+			t.Fatalf("%+v decoded back as %+v", trade, again)
+		}
+	})
+}
+
+// sameTrade compares trades, NaN prices and quantities being equal
+func sameTrade(a, b Trade) bool {
+	same := func(x, y float64) bool { return x == y || math.IsNaN(x) && math.IsNaN(y) }
+	return a.Id == b.Id && a.Symbol == b.Symbol && a.Exchange == b.Exchange && a.InstrumentType == b.InstrumentType &&
+		a.TakerSide == b.TakerSide && same(a.Price, b.Price) && same(a.Quantity, b.Quantity) && a.Timestamp == b.Timestamp
+}
+
+func TestTradeRoundTrip(t *testing.T) {
+	property := func(id int64, base, quote string, exchange, instrument, side uint8, price, quantity float64, timestamp int64) bool {
+		trade := Trade{
+			Id:             id,
+			Symbol:         NewSymbol(base, quote),
+			Exchange:       Exchange(1 + int(exchange)%3),
+			InstrumentType: InstrumentType(1 + int(instrument)%6),
+			TakerSide:      Side(1 + int(side)%2),
+			Price:          price,
+			Quantity:       quantity,
+			Timestamp:      timestamp,
+		}
+
+		var fromProtobuf Trade
+		if err := fromProtobuf.FromProtobuf(trade.ToProtobuf()); err != nil || !sameTrade(trade, fromProtobuf) {
+			t.Logf("protobuf: %+v decoded as %+v: %v", trade, fromProtobuf, err)
+			return false
+		}
+		var pb protobuf.Trade
+		buf, err := trade.MarshalAppend(nil, &pb)
+		if err != nil {
+			return false
+		}
+		var appended Trade
+		if err := Unmarshal(buf, &appended); err != nil || !sameTrade(trade, appended) {
+			t.Logf("appended: %+v decoded as %+v: %v", trade, appended, err)
+			return false
+		}
+
+		data, err := json.Marshal(trade)
+		if err != nil {
+			// Only NaN and infinite values have no JSON encoding
+			return math.IsNaN(price) || math.IsNaN(quantity) || math.IsInf(price, 0) || math.IsInf(quantity, 0)
+		}
+		var fromJSON Trade
+		if err := json.Unmarshal(data, &fromJSON); err != nil || !sameTrade(trade, fromJSON) {
+			t.Logf("json: %+v decoded as %+v: %v", trade, fromJSON, err)
+			return false
+		}
+		return true
+	}
+	if err := quick.Check(property, &quick.Config{MaxCount: 2000}); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestTradeBatchRoundTrip(t *testing.T) {
+	property := func(ids []int64, price float64) bool {
+		trades := make([]Trade, len(ids))
+		for i, id := range ids {
+			trades[i] = Trade{Id: id, Symbol: NewSymbol("ETH", "USDT"), Exchange: ExchangeBybit, InstrumentType: InstrumentTypePerp,
+				TakerSide: SideSell, Price: price, Quantity: 1, Timestamp: id}
+		}
+		data, err := MarshalTradeBatch(trades)
+		if err != nil {
+			return false
+		}
+		decoded, err := UnmarshalTrades(data, strconv.Itoa(len(trades)))
+		if err != nil || len(decoded) != len(trades) {
+			return false
+		}
+		for i := range trades {
+			if !sameTrade(trades[i], decoded[i]) {
+				return false
+			}
+		}
+		return true
+	}
+	if err := quick.Check(property, nil); err != nil {
+		t.Error(err)
+	}
+}
@@ -0,0 +1,235 @@
+package monitor
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"time"
+
+	"github.com/BullionBear/sequex/internal/model/sqx"
+)
+
+const (
+	KindSilence    Kind = "silence"     // A feed went quiet for longer than its usual rate explains
+	KindBurst      Kind = "burst"       // A feed sends far more messages than usual
+	KindWideSpread Kind = "wide_spread" // The quoted spread of a venue widened far beyond usual
+)
+
+// Feeds whose health is watched
+const (
+	FeedTrade = "trade"
+	FeedQuote = "quote"
+)
+
+// AnomalyOptions configures the statistical checks of the feeds. Each feed
+// of a venue and symbol has an exponentially weighted baseline of its
+// message rate and quoted spread, against which every bucket is scored.
+type AnomalyOptions struct {
+	Bucket     time.Duration // Interval messages are counted over, 1s when 0
+	HalfLife   time.Duration // Half-life of the baselines, 10m when 0
+	Threshold  float64       // Z-score raising an alert, 4 when 0
+	MinBuckets int           // Buckets a baseline is learnt over before alerting, 60 when 0
+}
+
+// Validate validates the options
+func (o AnomalyOptions) Validate() error {
+	if o.Bucket < 0 || o.HalfLife < 0 || o.Threshold < 0 || o.MinBuckets < 0 {
+		return fmt.Errorf("anomaly options cannot be negative")
+	}
+	if o.HalfLife > 0 && o.HalfLife < o.Bucket {
+		return fmt.Errorf("half-life %s cannot be shorter than a bucket %s", o.HalfLife, o.Bucket)
+	}
+	return nil
+}
+
+func (o AnomalyOptions) withDefaults() AnomalyOptions {
+	if o.Bucket == 0 {
+		o.Bucket = time.Second
+	}
+	if o.HalfLife == 0 {
+		o.HalfLife = 10 * time.Minute
+	}
+	if o.Threshold == 0 {
+		o.Threshold = 4
+	}
+	if o.MinBuckets == 0 {
+		o.MinBuckets = 60
+	}
+	return o
+}
+
+// ewma is an exponentially weighted mean and variance
+type ewma struct {
+	mean, variance float64
+	n              int // Samples added
+}
+
+func (e *ewma) add(x, alpha float64) {
+	if e.n == 0 {
+		e.mean = x
+	} else {
+		d := x - e.mean
+		e.mean += alpha * d
+		e.variance = (1 - alpha) * (e.variance + alpha*d*d)
+	}
+	e.n++
+}
+
+// feedKey identifies a feed of a symbol on a venue
+type feedKey struct {
+	feed, venue, symbol string
+}
+
+// feedHealth is the state of a feed
+type feedHealth struct {
+	count     float64 // Messages in the current bucket
+	rate      ewma    // Messages per bucket
+	silent    int     // Empty buckets in a row
+	spreadSum float64 // Relative spreads quoted in the current bucket
+	spreadN   int
+	spread    ewma // Mean relative spread per bucket
+}
+
+// anomalies is the state of the statistical checks
+type anomalies struct {
+	opts  AnomalyOptions
+	alpha float64   // Weight of a bucket in the baselines
+	start time.Time // Of the current bucket
+	feeds map[feedKey]*feedHealth
+}
+
+func newAnomalies(opts AnomalyOptions) *anomalies {
+	opts = opts.withDefaults()
+	return &anomalies{
+		opts:  opts,
+		alpha: 1 - math.Pow(0.5, float64(opts.Bucket)/float64(opts.HalfLife)),
+		feeds: make(map[feedKey]*feedHealth),
+	}
+}
+
+func (a *anomalies) feed(k feedKey) *feedHealth {
+	f, ok := a.feeds[k]
+	if !ok {
+		f = &feedHealth{}
+		a.feeds[k] = f
+	}
+	return f
+}
+
+// OnQuote counts the quote in the rate of its feed and records its spread.
+// Quotes are only used for the anomaly checks, scored by Tick.
+func (m *Monitor) OnQuote(quote sqx.Quote) {
+	if m.anomalies == nil {
+		return
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	f := m.anomalies.feed(feedKey{FeedQuote, quote.Venue(), quote.Symbol.String()})
+	f.count++
+	if quote.BidPrice > 0 && quote.AskPrice >= quote.BidPrice {
+		f.spreadSum += (quote.AskPrice - quote.BidPrice) / ((quote.AskPrice + quote.BidPrice) / 2)
+		f.spreadN++
+	}
+}
+
+// Bucket returns the interval Tick is meant to be called at, 0 when the
+// anomaly checks are disabled
+func (m *Monitor) Bucket() time.Duration {
+	if m.anomalies == nil {
+		return 0
+	}
+	return m.anomalies.opts.Bucket
+}
+
+// Tick closes the buckets elapsed by now and scores them against the
+// baselines, returning the alerts whose state changed. It is meant to be
+// called about once a bucket; the first call starts the first bucket.
+func (m *Monitor) Tick(now time.Time) []Alert {
+	if m.anomalies == nil {
+		return nil
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	a := m.anomalies
+	if a.start.IsZero() {
+		a.start = now
+		return nil
+	}
+	var changed []Alert
+	for !now.Before(a.start.Add(a.opts.Bucket)) {
+		a.start = a.start.Add(a.opts.Bucket)
+		for k, f := range a.feeds {
+			changed = m.closeLocked(changed, k, f, a.start)
+		}
+	}
+	sort.Slice(changed, func(i, j int) bool { return changed[i].key() < changed[j].key() })
+	return changed
+}
+
+// closeLocked scores the bucket of a feed ending at end and adds it to the
+// baselines
+func (m *Monitor) closeLocked(changed []Alert, k feedKey, f *feedHealth, end time.Time) []Alert {
+	a := m.anomalies
+	alert := func(kind Kind, observed, expected, z float64, active bool) Alert {
+		return Alert{
+			Kind:      kind,
+			Name:      k.symbol,
+			Venue:     k.venue,
+			Feed:      k.feed,
+			Price:     observed,
+			Reference: expected,
+			Deviation: z,
+			Threshold: a.opts.Threshold,
+			Active:    active,
+			Timestamp: end.UnixMilli(),
+		}
+	}
+	warm := f.rate.n >= a.opts.MinBuckets
+
+	// A silence is scored over the empty buckets in a row: with messages
+	// arriving at random, missing mean*k of them is as unlikely as a z-score
+	// of sqrt(mean*k). The baseline is left as it is once alerting, so that a
+	// long outage is not learnt as the new normal.
+	if f.count == 0 {
+		f.silent++
+		silence := false
+		if warm {
+			expected := f.rate.mean * float64(f.silent)
+			z := -math.Sqrt(expected)
+			silence = -z > a.opts.Threshold
+			changed = m.setLocked(changed, alert(KindSilence, 0, expected, z, silence))
+		}
+		if !silence {
+			f.rate.add(0, a.alpha)
+		}
+	} else {
+		if f.silent > 0 && warm {
+			changed = m.setLocked(changed, alert(KindSilence, f.count, f.rate.mean, 0, false))
+		}
+		f.silent = 0
+		if warm {
+			// Arrivals are at least as dispersed as at random, and a single
+			// message never counts as a burst
+			std := math.Max(math.Max(math.Sqrt(f.rate.variance), math.Sqrt(f.rate.mean)), 1)
+			z := (f.count - f.rate.mean) / std
+			changed = m.setLocked(changed, alert(KindBurst, f.count, f.rate.mean, z, z > a.opts.Threshold))
+		}
+		f.rate.add(f.count, a.alpha)
+	}
+	f.count = 0
+
+	if f.spreadN > 0 {
+		spread := f.spreadSum / float64(f.spreadN)
+		if f.spread.n >= a.opts.MinBuckets {
+			// Spreads stable to the tick would otherwise alert on any change
+			std := math.Max(math.Sqrt(f.spread.variance), 0.1*f.spread.mean)
+			if std > 0 {
+				z := (spread - f.spread.mean) / std
+				changed = m.setLocked(changed, alert(KindWideSpread, spread, f.spread.mean, z, z > a.opts.Threshold))
+			}
+		}
+		f.spread.add(spread, a.alpha)
+		f.spreadSum, f.spreadN = 0, 0
+	}
+	return changed
+}
@@ -0,0 +1,72 @@
+package monitor
+
+import (
+	"testing"
+	"time"
+
+	"github.com/BullionBear/sequex/internal/model/sqx"
+)
+
+func TestAnomalies(t *testing.T) {
+	m, err := New(Options{Anomaly: &AnomalyOptions{Bucket: time.Second, HalfLife: time.Minute, MinBuckets: 10}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	btc := sqx.NewSymbol("BTC", "USDT")
+	now := time.UnixMilli(1_000_000)
+	m.Tick(now)
+	// bucket sends trades and quotes of the given spread, in basis points,
+	// then closes the bucket
+	bucket := func(trades int, spread float64) []Alert {
+		for i := 0; i < trades; i++ {
+			m.OnTrade(trade(sqx.ExchangeBinance, "BTC-USDT", 60000, now.UnixMilli()))
+		}
+		m.OnQuote(sqx.Quote{Exchange: sqx.ExchangeBinance, InstrumentType: sqx.InstrumentTypeSpot, Symbol: btc, BidPrice: 100, AskPrice: 100 * (1 + spread/10000)})
+		now = now.Add(time.Second)
+		return m.Tick(now)
+	}
+
+	for i := 0; i < 20; i++ {
+		if alerts := bucket(10, 1); len(alerts) != 0 {
+			t.Fatalf("alerts while learning = %+v", alerts)
+		}
+	}
+
+	// Missing 10 trades could be chance, missing 20 hardly
+	if alerts := bucket(0, 1); len(alerts) != 0 {
+		t.Errorf("alerts after a quiet bucket = %+v", alerts)
+	}
+	alerts := bucket(0, 1)
+	if len(alerts) != 1 || alerts[0].Kind != KindSilence || alerts[0].Feed != FeedTrade || alerts[0].Venue != "BINANCE.SPOT" || !alerts[0].Active {
+		t.Fatalf("alerts after two quiet buckets = %+v", alerts)
+	}
+	if !m.Restricted() {
+		t.Error("Restricted() = false while a feed is silent")
+	}
+	alerts = bucket(10, 1)
+	if len(alerts) != 1 || alerts[0].Kind != KindSilence || alerts[0].Active {
+		t.Fatalf("alerts once the feed resumed = %+v", alerts)
+	}
+
+	alerts = bucket(40, 3)
+	if len(alerts) != 2 || alerts[0].Kind != KindBurst || !alerts[0].Active || alerts[1].Kind != KindWideSpread || alerts[1].Feed != FeedQuote || !alerts[1].Active {
+		t.Fatalf("alerts on a burst = %+v", alerts)
+	}
+	if alerts := bucket(10, 1); len(alerts) != 2 || alerts[0].Active || alerts[1].Active {
+		t.Errorf("alerts once back to normal = %+v", alerts)
+	}
+}
+
+func TestAnomalies_Disabled(t *testing.T) {
+	m, err := New(Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	m.OnQuote(sqx.Quote{})
+	if m.Bucket() != 0 || m.Tick(time.Now()) != nil {
+		t.Error("anomaly checks ran while disabled")
+	}
+	if _, err := New(Options{Anomaly: &AnomalyOptions{Bucket: time.Minute, HalfLife: time.Second}}); err == nil {
+		t.Error("New() with a half-life shorter than a bucket succeeded")
+	}
+}
@@ -0,0 +1,319 @@
+// Package monitor checks the sanity of reference prices: stablecoins must
+// trade near their peg and agree across venues, and index prices must stay
+// close to a robust cross-venue consensus of their underlying. It also scores
+// the message rates and spreads of the feeds against their own history, so a
+// feed going quiet or erratic is flagged before its prices go stale.
+package monitor
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/BullionBear/sequex/internal/model/sqx"
+)
+
+// Kind is the check an alert was raised by
+type Kind string
+
+const (
+	KindDepeg     Kind = "depeg"     // A stablecoin trades away from its peg on a venue
+	KindSpread    Kind = "spread"    // Venues disagree on the price of a stablecoin
+	KindReference Kind = "reference" // An index diverges from the cross-venue median
+)
+
+// Alert is raised when a check fails and sent again, inactive, when it
+// passes again
+type Alert struct {
+	Kind      Kind    `json:"kind"`
+	Name      string  `json:"name"`             // Stablecoin symbol, index name, or symbol of anomaly alerts
+	Symbol    string  `json:"symbol,omitempty"` // Underlying of reference alerts
+	Venue     string  `json:"venue,omitempty"`  // EXCHANGE.INSTRUMENT of depeg and feed alerts
+	Feed      string  `json:"feed,omitempty"`   // Feed of anomaly alerts, trade or quote
+	Price     float64 `json:"price"`            // Observed price, max price of spread alerts, or observed value of anomaly alerts
+	Reference float64 `json:"reference"`        // Peg, min price of spread alerts, median, or baseline of anomaly alerts
+	Deviation float64 `json:"deviation"`        // Relative deviation from the reference, or z-score of anomaly alerts
+	Threshold float64 `json:"threshold"`
+	Active    bool    `json:"active"`
+	Timestamp int64   `json:"timestamp"`
+}
+
+func (a Alert) key() string {
+	return fmt.Sprintf("%s/%s/%s/%s/%s", a.Kind, a.Name, a.Symbol, a.Venue, a.Feed)
+}
+
+// StablecoinRule checks a stablecoin pair such as USDC-USDT
+type StablecoinRule struct {
+	Symbol       string
+	Peg          float64 // Expected price, 1 when 0
+	MaxDeviation float64 // Tolerated relative distance to the peg on any venue
+	MaxSpread    float64 // Tolerated relative spread between venues, 0 disables the check
+}
+
+// ReferenceRule checks an index against the venues trading its underlying
+type ReferenceRule struct {
+	Index        string  // Index name
+	Symbol       string  // Underlying traded on the venues, e.g. BTC-USDT
+	MaxDeviation float64 // Tolerated relative distance to the consensus of the venues
+	Trim         float64 // Fraction of venues dropped at each end of the consensus, in [0, 0.5]
+	MinVenues    int     // Venues required for a consensus, 1 when 0
+}
+
+// Options configures a monitor
+type Options struct {
+	Stablecoins []StablecoinRule
+	References  []ReferenceRule
+	MaxAge      time.Duration   // Venue prices older than this are ignored, 0 keeps them forever
+	Anomaly     *AnomalyOptions // Statistical checks of the feeds, disabled when nil
+}
+
+type venuePrice struct {
+	price float64
+	at    time.Time
+}
+
+// Monitor evaluates the rules as prices arrive. It is safe for concurrent use.
+type Monitor struct {
+	opts       Options
+	stablecoin map[string]StablecoinRule  // Keyed by symbol
+	references map[string][]ReferenceRule // Keyed by index name
+	watched    map[string]bool            // Symbols whose venue prices are kept
+
+	mu        sync.Mutex
+	prices    map[string]map[string]venuePrice // Keyed by symbol, then venue
+	active    map[string]Alert
+	anomalies *anomalies // Nil when disabled
+	now       func() time.Time
+}
+
+// New validates the rules and creates a monitor
+func New(opts Options) (*Monitor, error) {
+	m := &Monitor{
+		opts:       opts,
+		stablecoin: make(map[string]StablecoinRule),
+		references: make(map[string][]ReferenceRule),
+		watched:    make(map[string]bool),
+		prices:     make(map[string]map[string]venuePrice),
+		active:     make(map[string]Alert),
+		now:        time.Now,
+	}
+	for i, rule := range opts.Stablecoins {
+		symbol, err := sqx.NewSymbolFromStr(rule.Symbol)
+		if err != nil {
+			return nil, fmt.Errorf("stablecoins[%d]: %w", i, err)
+		}
+		if rule.Peg == 0 {
+			rule.Peg = 1
+		}
+		if rule.Peg < 0 || rule.MaxDeviation <= 0 || rule.MaxSpread < 0 {
+			return nil, fmt.Errorf("stablecoins[%d]: peg and max_deviation must be positive", i)
+		}
+		rule.Symbol = symbol.String()
+		m.stablecoin[rule.Symbol] = rule
+		m.watched[rule.Symbol] = true
+	}
+	for i, rule := range opts.References {
+		symbol, err := sqx.NewSymbolFromStr(rule.Symbol)
+		if err != nil {
+			return nil, fmt.Errorf("references[%d]: %w", i, err)
+		}
+		if rule.Index == "" {
+			return nil, fmt.Errorf("references[%d]: index cannot be empty", i)
+		}
+		if rule.MaxDeviation <= 0 {
+			return nil, fmt.Errorf("references[%d]: max_deviation must be positive", i)
+		}
+		if rule.Trim < 0 || rule.Trim > 0.5 {
+			return nil, fmt.Errorf("references[%d]: trim must be in [0, 0.5]", i)
+		}
+		if rule.MinVenues <= 0 {
+			rule.MinVenues = 1
+		}
+		rule.Symbol = symbol.String()
+		rule.Index = strings.ToUpper(rule.Index)
+		m.references[rule.Index] = append(m.references[rule.Index], rule)
+		m.watched[rule.Symbol] = true
+	}
+	if opts.Anomaly != nil {
+		if err := opts.Anomaly.Validate(); err != nil {
+			return nil, err
+		}
+		m.anomalies = newAnomalies(*opts.Anomaly)
+	}
+	return m, nil
+}
+
+// venueOf identifies the market a trade happened on
+func venueOf(trade sqx.Trade) string {
+	return trade.Exchange.String() + "." + trade.InstrumentType.String()
+}
+
+// OnTrade records the venue price of a trade and returns the alerts whose
+// state changed
+func (m *Monitor) OnTrade(trade sqx.Trade) []Alert {
+	symbol := trade.Symbol.String()
+	venue := venueOf(trade)
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.anomalies != nil {
+		m.anomalies.feed(feedKey{FeedTrade, venue, symbol}).count++
+	}
+	if !m.watched[symbol] || trade.Price <= 0 {
+		return nil
+	}
+	at := time.UnixMilli(trade.Timestamp)
+
+	venues, ok := m.prices[symbol]
+	if !ok {
+		venues = make(map[string]venuePrice)
+		m.prices[symbol] = venues
+	}
+	if current, ok := venues[venue]; ok && current.at.After(at) {
+		return nil
+	}
+	venues[venue] = venuePrice{price: trade.Price, at: at}
+
+	rule, ok := m.stablecoin[symbol]
+	if !ok {
+		return nil
+	}
+	var changed []Alert
+	deviation := math.Abs(trade.Price/rule.Peg - 1)
+	changed = m.setLocked(changed, Alert{
+		Kind:      KindDepeg,
+		Name:      symbol,
+		Venue:     venue,
+		Price:     trade.Price,
+		Reference: rule.Peg,
+		Deviation: deviation,
+		Threshold: rule.MaxDeviation,
+		Active:    deviation > rule.MaxDeviation,
+		Timestamp: trade.Timestamp,
+	})
+	if rule.MaxSpread > 0 {
+		prices := m.freshLocked(symbol)
+		if len(prices) >= 2 {
+			low, high := prices[0], prices[len(prices)-1]
+			spread := high/low - 1
+			changed = m.setLocked(changed, Alert{
+				Kind:      KindSpread,
+				Name:      symbol,
+				Price:     high,
+				Reference: low,
+				Deviation: spread,
+				Threshold: rule.MaxSpread,
+				Active:    spread > rule.MaxSpread,
+				Timestamp: trade.Timestamp,
+			})
+		}
+	}
+	return changed
+}
+
+// OnIndex compares an index price with the venues of its underlying and
+// returns the alerts whose state changed
+func (m *Monitor) OnIndex(price sqx.IndexPrice) []Alert {
+	rules := m.references[strings.ToUpper(price.Name)]
+	if len(rules) == 0 || price.Value <= 0 {
+		return nil
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	var changed []Alert
+	for _, rule := range rules {
+		prices := m.freshLocked(rule.Symbol)
+		if len(prices) < rule.MinVenues {
+			continue // No consensus to compare with, keep the current state
+		}
+		median := TrimmedMedian(prices, rule.Trim)
+		deviation := math.Abs(price.Value/median - 1)
+		changed = m.setLocked(changed, Alert{
+			Kind:      KindReference,
+			Name:      rule.Index,
+			Symbol:    rule.Symbol,
+			Price:     price.Value,
+			Reference: median,
+			Deviation: deviation,
+			Threshold: rule.MaxDeviation,
+			Active:    deviation > rule.MaxDeviation,
+			Timestamp: price.Timestamp,
+		})
+	}
+	return changed
+}
+
+// freshLocked returns the sorted venue prices of a symbol that are not stale
+func (m *Monitor) freshLocked(symbol string) []float64 {
+	now := m.now()
+	prices := make([]float64, 0, len(m.prices[symbol]))
+	for _, p := range m.prices[symbol] {
+		if m.opts.MaxAge > 0 && now.Sub(p.at) > m.opts.MaxAge {
+			continue
+		}
+		prices = append(prices, p.price)
+	}
+	sort.Float64s(prices)
+	return prices
+}
+
+// setLocked records the state of a check, appending the alert to changed
+// when it was raised or cleared
+func (m *Monitor) setLocked(changed []Alert, alert Alert) []Alert {
+	key := alert.key()
+	_, wasActive := m.active[key]
+	if alert.Active {
+		m.active[key] = alert
+	} else {
+		delete(m.active, key)
+	}
+	if alert.Active != wasActive {
+		changed = append(changed, alert)
+	}
+	return changed
+}
+
+// Active returns the alerts currently raised
+func (m *Monitor) Active() []Alert {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	list := make([]Alert, 0, len(m.active))
+	for _, alert := range m.active {
+		list = append(list, alert)
+	}
+	sort.Slice(list, func(i, j int) bool { return list[i].key() < list[j].key() })
+	return list
+}
+
+// RiskControl asks the risk limits to be tightened while reference prices
+// cannot be trusted, and relaxed again once every alert has cleared
+type RiskControl struct {
+	Restricted bool    `json:"restricted"`
+	Alerts     []Alert `json:"alerts"` // Active alerts
+	Timestamp  int64   `json:"timestamp"`
+}
+
+// Restricted reports whether any alert is active
+func (m *Monitor) Restricted() bool {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return len(m.active) > 0
+}
+
+// TrimmedMedian drops the trim fraction of the sorted prices at each end and
+// averages the rest, so a venue printing off-market prices cannot move the
+// reference. A trim of 0.5 keeps the one or two middle prices: the median.
+func TrimmedMedian(sorted []float64, trim float64) float64 {
+	if len(sorted) == 0 {
+		return 0
+	}
+	cut := min(int(float64(len(sorted))*trim), (len(sorted)-1)/2)
+	kept := sorted[cut : len(sorted)-cut]
+	var sum float64
+	for _, p := range kept {
+		sum += p
+	}
+	return sum / float64(len(kept))
+}
@@ -0,0 +1,99 @@
+package monitor
+
+import (
+	"testing"
+	"time"
+
+	"github.com/BullionBear/sequex/internal/model/sqx"
+)
+
+func trade(exchange sqx.Exchange, symbol string, price float64, ts int64) sqx.Trade {
+	s, _ := sqx.NewSymbolFromStr(symbol)
+	return sqx.Trade{Exchange: exchange, InstrumentType: sqx.InstrumentTypeSpot, Symbol: s, Price: price, Timestamp: ts}
+}
+
+func TestStablecoinDepegAndSpread(t *testing.T) {
+	m, err := New(Options{Stablecoins: []StablecoinRule{{Symbol: "USDC-USDT", MaxDeviation: 0.005, MaxSpread: 0.003}}})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if alerts := m.OnTrade(trade(sqx.ExchangeBinance, "USDC-USDT", 1.0002, 1)); len(alerts) != 0 {
+		t.Fatalf("alerts at peg = %+v", alerts)
+	}
+	// Bybit depegs: the venue deviates and the venues disagree
+	alerts := m.OnTrade(trade(sqx.ExchangeBybit, "USDC-USDT", 0.99, 2))
+	if len(alerts) != 2 || alerts[0].Kind != KindDepeg || alerts[0].Venue != "BYBIT.SPOT" || alerts[1].Kind != KindSpread || !alerts[1].Active {
+		t.Fatalf("alerts = %+v", alerts)
+	}
+	// Still depegged: no new transition
+	if alerts := m.OnTrade(trade(sqx.ExchangeBybit, "USDC-USDT", 0.991, 3)); len(alerts) != 0 {
+		t.Errorf("repeated alerts = %+v", alerts)
+	}
+	if len(m.Active()) != 2 {
+		t.Errorf("active = %+v", m.Active())
+	}
+	alerts = m.OnTrade(trade(sqx.ExchangeBybit, "USDC-USDT", 1.0001, 4))
+	if len(alerts) != 2 || alerts[0].Active || alerts[1].Active || len(m.Active()) != 0 {
+		t.Errorf("recovery alerts = %+v, active %+v", alerts, m.Active())
+	}
+	// Trades of unwatched symbols are ignored
+	if alerts := m.OnTrade(trade(sqx.ExchangeBinance, "BTC-USDT", 60000, 5)); alerts != nil {
+		t.Errorf("unwatched alerts = %+v", alerts)
+	}
+}
+
+func TestIndexReference(t *testing.T) {
+	m, err := New(Options{
+		References: []ReferenceRule{{Index: "btc_ref", Symbol: "BTC-USDT", MaxDeviation: 0.01, Trim: 0.25, MinVenues: 3}},
+		MaxAge:     time.Minute,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	now := time.Now()
+	m.now = func() time.Time { return now }
+	ts := now.UnixMilli()
+
+	m.OnTrade(trade(sqx.ExchangeBinance, "BTC-USDT", 60000, ts))
+	m.OnTrade(trade(sqx.ExchangeBybit, "BTC-USDT", 60100, ts))
+	if alerts := m.OnIndex(sqx.IndexPrice{Name: "BTC_REF", Value: 70000, Timestamp: ts}); len(alerts) != 0 {
+		t.Fatalf("alerts without enough venues = %+v", alerts)
+	}
+
+	perp := trade(sqx.ExchangeBinancePerp, "BTC-USDT", 30000, ts) // Off-market venue, trimmed away
+	perp.InstrumentType = sqx.InstrumentTypePerp
+	m.OnTrade(perp)
+	m.OnTrade(trade(sqx.ExchangeUnknown, "BTC-USDT", 60050, ts))
+	if alerts := m.OnIndex(sqx.IndexPrice{Name: "BTC_REF", Value: 60200, Timestamp: ts}); len(alerts) != 0 {
+		t.Fatalf("alerts within tolerance = %+v", alerts)
+	}
+	alerts := m.OnIndex(sqx.IndexPrice{Name: "BTC_REF", Value: 62000, Timestamp: ts})
+	if len(alerts) != 1 || !alerts[0].Active || alerts[0].Reference != 60025 || alerts[0].Symbol != "BTC-USDT" {
+		t.Fatalf("alerts = %+v", alerts)
+	}
+
+	// Stale venues drop out of the consensus
+	now = now.Add(2 * time.Minute)
+	if alerts := m.OnIndex(sqx.IndexPrice{Name: "BTC_REF", Value: 60000}); len(alerts) != 0 || len(m.Active()) != 1 {
+		t.Errorf("alerts with stale venues = %+v", alerts)
+	}
+}
+
+func TestTrimmedMedian(t *testing.T) {
+	tests := []struct {
+		prices []float64
+		trim   float64
+		want   float64
+	}{
+		{[]float64{1, 2, 3, 10}, 0, 4},
+		{[]float64{1, 2, 3, 10}, 0.25, 2.5},
+		{[]float64{1, 2, 3, 4, 100}, 0.5, 3},
+		{[]float64{5}, 0.5, 5},
+	}
+	for _, tt := range tests {
+		if got := TrimmedMedian(tt.prices, tt.trim); got != tt.want {
+			t.Errorf("TrimmedMedian(%v, %v) = %v, want %v", tt.prices, tt.trim, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,198 @@
+// Package nbbo maintains the best bid and offer of symbols across the venues
+// quoting them, in the manner of an NBBO, and keeps its recent history for
+// point-in-time queries, such as the arrival price of an order.
+package nbbo
+
+import (
+	"fmt"
+	"slices"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/BullionBear/sequex/internal/model/sqx"
+)
+
+// Options configures a consolidator
+type Options struct {
+	Symbols    []sqx.Symbol  // Consolidated symbols, the quotes of others are dropped
+	StaleAfter time.Duration // Venues not quoting for this long are left out of the BBO, never when 0
+	History    time.Duration // How long past BBOs are kept for point-in-time queries, only the latest when 0
+}
+
+// Validate validates the options
+func (o Options) Validate() error {
+	if len(o.Symbols) == 0 {
+		return fmt.Errorf("symbols cannot be empty")
+	}
+	seen := make(map[sqx.Symbol]bool)
+	for _, symbol := range o.Symbols {
+		if seen[symbol] {
+			return fmt.Errorf("duplicate symbol %s", symbol)
+		}
+		seen[symbol] = true
+	}
+	if o.StaleAfter < 0 || o.History < 0 {
+		return fmt.Errorf("stale after and history cannot be negative")
+	}
+	return nil
+}
+
+// venueQuote is the last quote of a venue and when it was received
+type venueQuote struct {
+	quote    sqx.Quote
+	received time.Time
+}
+
+// book is the state of a symbol
+type book struct {
+	quotes  map[string]venueQuote
+	history []sqx.BBO // Oldest first, the last being current
+}
+
+// Consolidator maintains the BBO of symbols. It is safe for concurrent use.
+type Consolidator struct {
+	opts  Options
+	mu    sync.Mutex
+	books map[sqx.Symbol]*book
+}
+
+// New creates a consolidator
+func New(opts Options) (*Consolidator, error) {
+	if err := opts.Validate(); err != nil {
+		return nil, err
+	}
+	c := &Consolidator{opts: opts, books: make(map[sqx.Symbol]*book)}
+	for _, symbol := range opts.Symbols {
+		c.books[symbol] = &book{quotes: make(map[string]venueQuote)}
+	}
+	return c, nil
+}
+
+// Update applies the quote of a venue received at now, returning the BBO
+// when it changed. Quotes of symbols not consolidated, and quotes older than
+// the last one of their venue, are dropped.
+func (c *Consolidator) Update(quote sqx.Quote, now time.Time) (sqx.BBO, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	b, ok := c.books[quote.Symbol]
+	if !ok {
+		return sqx.BBO{}, false
+	}
+	venue := quote.Venue()
+	if last, ok := b.quotes[venue]; ok && quote.Timestamp < last.quote.Timestamp {
+		return sqx.BBO{}, false
+	}
+	b.quotes[venue] = venueQuote{quote: quote, received: now}
+	return c.refresh(quote.Symbol, b, now)
+}
+
+// Expire leaves out the venues gone stale by now, returning the BBOs that
+// changed
+func (c *Consolidator) Expire(now time.Time) []sqx.BBO {
+	if c.opts.StaleAfter == 0 {
+		return nil
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	var changed []sqx.BBO
+	for _, symbol := range c.opts.Symbols {
+		if bbo, ok := c.refresh(symbol, c.books[symbol], now); ok {
+			changed = append(changed, bbo)
+		}
+	}
+	return changed
+}
+
+// refresh recomputes the BBO of symbol at now, recording it when it changed
+func (c *Consolidator) refresh(symbol sqx.Symbol, b *book, now time.Time) (sqx.BBO, bool) {
+	if len(b.quotes) == 0 {
+		return sqx.BBO{}, false
+	}
+	bbo := sqx.BBO{Symbol: symbol, Timestamp: now.UnixMilli()}
+	venues := make([]string, 0, len(b.quotes))
+	for venue := range b.quotes {
+		venues = append(venues, venue)
+	}
+	sort.Strings(venues)
+	for _, venue := range venues {
+		vq := b.quotes[venue]
+		if c.opts.StaleAfter > 0 && now.Sub(vq.received) >= c.opts.StaleAfter {
+			bbo.Stale = append(bbo.Stale, venue)
+			continue
+		}
+		q := vq.quote
+		switch {
+		case q.BidPrice == 0:
+		case q.BidPrice > bbo.BidPrice:
+			bbo.BidPrice, bbo.BidQuantity, bbo.BidVenues = q.BidPrice, q.BidQuantity, []string{venue}
+		case q.BidPrice == bbo.BidPrice:
+			bbo.BidQuantity += q.BidQuantity
+			bbo.BidVenues = append(bbo.BidVenues, venue)
+		}
+		switch {
+		case q.AskPrice == 0:
+		case bbo.AskPrice == 0 || q.AskPrice < bbo.AskPrice:
+			bbo.AskPrice, bbo.AskQuantity, bbo.AskVenues = q.AskPrice, q.AskQuantity, []string{venue}
+		case q.AskPrice == bbo.AskPrice:
+			bbo.AskQuantity += q.AskQuantity
+			bbo.AskVenues = append(bbo.AskVenues, venue)
+		}
+	}
+
+	if n := len(b.history); n > 0 {
+		current := b.history[n-1]
+		if same(current, bbo) {
+			return sqx.BBO{}, false
+		}
+		bbo.Seq = current.Seq + 1
+	} else {
+		bbo.Seq = 1
+	}
+	b.history = append(b.history, bbo)
+	c.prune(b, now)
+	return bbo, true
+}
+
+// prune drops the BBOs replaced before the history window, keeping the one
+// in force at its start
+func (c *Consolidator) prune(b *book, now time.Time) {
+	start := now.Add(-c.opts.History).UnixMilli()
+	i := sort.Search(len(b.history), func(i int) bool { return b.history[i].Timestamp > start })
+	if i > 1 {
+		b.history = append(b.history[:0], b.history[i-1:]...)
+	}
+}
+
+// same reports whether two BBOs quote the same prices, quantities and venues
+func same(a, b sqx.BBO) bool {
+	return a.BidPrice == b.BidPrice && a.BidQuantity == b.BidQuantity && a.AskPrice == b.AskPrice && a.AskQuantity == b.AskQuantity &&
+		slices.Equal(a.BidVenues, b.BidVenues) && slices.Equal(a.AskVenues, b.AskVenues) && slices.Equal(a.Stale, b.Stale)
+}
+
+// Latest returns the current BBO of a symbol, false before its first quote
+func (c *Consolidator) Latest(symbol sqx.Symbol) (sqx.BBO, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	b, ok := c.books[symbol]
+	if !ok || len(b.history) == 0 {
+		return sqx.BBO{}, false
+	}
+	return b.history[len(b.history)-1], true
+}
+
+// At returns the BBO of a symbol in force at timestamp, in Unix
+// milliseconds, false when it precedes the history kept
+func (c *Consolidator) At(symbol sqx.Symbol, timestamp int64) (sqx.BBO, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	b, ok := c.books[symbol]
+	if !ok {
+		return sqx.BBO{}, false
+	}
+	i := sort.Search(len(b.history), func(i int) bool { return b.history[i].Timestamp > timestamp })
+	if i == 0 {
+		return sqx.BBO{}, false
+	}
+	return b.history[i-1], true
+}
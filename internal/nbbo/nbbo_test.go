@@ -0,0 +1,95 @@
+package nbbo
+
+import (
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/BullionBear/sequex/internal/model/sqx"
+)
+
+var btc = sqx.NewSymbol("BTC", "USDT")
+
+func quote(exchange sqx.Exchange, bid, ask float64, timestamp int64) sqx.Quote {
+	return sqx.Quote{Exchange: exchange, InstrumentType: sqx.InstrumentTypeSpot, Symbol: btc, BidPrice: bid, BidQuantity: 1, AskPrice: ask, AskQuantity: 2, Timestamp: timestamp}
+}
+
+func TestConsolidator_Update(t *testing.T) {
+	c, err := New(Options{Symbols: []sqx.Symbol{btc}, StaleAfter: time.Second, History: time.Minute})
+	if err != nil {
+		t.Fatal(err)
+	}
+	start := time.UnixMilli(1_000_000)
+
+	if _, ok := c.Update(sqx.Quote{Symbol: sqx.NewSymbol("ETH", "USDT")}, start); ok {
+		t.Error("Update() of another symbol changed the BBO")
+	}
+	bbo, ok := c.Update(quote(sqx.ExchangeBinance, 100, 101, 1), start)
+	if !ok || bbo.Seq != 1 || bbo.BidPrice != 100 || bbo.AskPrice != 101 {
+		t.Fatalf("Update() = %+v, %v", bbo, ok)
+	}
+	// Bybit joins the bid and improves the ask
+	bbo, ok = c.Update(quote(sqx.ExchangeBybit, 100, 100.5, 1), start.Add(100*time.Millisecond))
+	if !ok || bbo.Seq != 2 || bbo.BidQuantity != 2 || !slices.Equal(bbo.BidVenues, []string{"BINANCE.SPOT", "BYBIT.SPOT"}) ||
+		bbo.AskPrice != 100.5 || !slices.Equal(bbo.AskVenues, []string{"BYBIT.SPOT"}) || bbo.Mid() != 100.25 {
+		t.Errorf("Update() = %+v, %v", bbo, ok)
+	}
+	if _, ok := c.Update(quote(sqx.ExchangeBybit, 100, 100.5, 2), start.Add(200*time.Millisecond)); ok {
+		t.Error("Update() of an identical quote changed the BBO")
+	}
+	if _, ok := c.Update(quote(sqx.ExchangeBybit, 102, 103, 1), start.Add(300*time.Millisecond)); ok {
+		t.Error("Update() of an out of order quote changed the BBO")
+	}
+
+	// Binance stops quoting
+	if changed := c.Expire(start.Add(500 * time.Millisecond)); len(changed) != 0 {
+		t.Errorf("Expire() before staleness = %+v", changed)
+	}
+	changed := c.Expire(start.Add(1100 * time.Millisecond))
+	if len(changed) != 1 || changed[0].BidQuantity != 1 || !slices.Equal(changed[0].Stale, []string{"BINANCE.SPOT"}) {
+		t.Errorf("Expire() = %+v", changed)
+	}
+
+	// Point in time
+	if bbo, ok := c.At(btc, 1_000_150); !ok || bbo.Seq != 2 {
+		t.Errorf("At() = %+v, %v", bbo, ok)
+	}
+	if _, ok := c.At(btc, 999_999); ok {
+		t.Error("At() before the first quote succeeded")
+	}
+	if bbo, ok := c.Latest(btc); !ok || bbo.Seq != 3 {
+		t.Errorf("Latest() = %+v, %v", bbo, ok)
+	}
+}
+
+func TestConsolidator_History(t *testing.T) {
+	c, err := New(Options{Symbols: []sqx.Symbol{btc}, History: time.Second})
+	if err != nil {
+		t.Fatal(err)
+	}
+	start := time.UnixMilli(1_000_000)
+	c.Update(quote(sqx.ExchangeBinance, 100, 101, 1), start)
+	c.Update(quote(sqx.ExchangeBinance, 99, 101, 2), start.Add(500*time.Millisecond))
+	c.Update(quote(sqx.ExchangeBinance, 98, 101, 3), start.Add(3*time.Second))
+
+	// The BBO in force when the window starts is kept, the one before it is not
+	if bbo, ok := c.At(btc, 1_002_500); !ok || bbo.BidPrice != 99 {
+		t.Errorf("At() in the window = %+v, %v", bbo, ok)
+	}
+	if _, ok := c.At(btc, 1_000_100); ok {
+		t.Error("At() before the window succeeded")
+	}
+}
+
+func TestBBO_Crossed(t *testing.T) {
+	c, err := New(Options{Symbols: []sqx.Symbol{btc}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	now := time.UnixMilli(1_000_000)
+	c.Update(quote(sqx.ExchangeBinance, 100, 101, 1), now)
+	bbo, _ := c.Update(quote(sqx.ExchangeBybit, 101.5, 102, 1), now)
+	if !bbo.Crossed() {
+		t.Errorf("Crossed() of %+v = false", bbo)
+	}
+}
@@ -0,0 +1,47 @@
+package nbbo
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/BullionBear/sequex/internal/model/sqx"
+	"github.com/BullionBear/sequex/pkg/eventbus"
+	"github.com/nats-io/nats.go"
+)
+
+// DefaultSubject is the prefix the BBO RPCs are served under when none is
+// configured
+const DefaultSubject = "nbbo"
+
+// QuoteRequest asks for the BBO of a symbol, e.g. by the order router
+// before splitting an order or by cost analysis for its arrival price
+type QuoteRequest struct {
+	Symbol    string `json:"symbol"`    // e.g. BTC-USDT
+	Timestamp int64  `json:"timestamp"` // Point in time, Unix milliseconds, the latest BBO when 0
+}
+
+// QuoteEndpoint is the endpoint BBOs are queried on, prefix.quote
+func QuoteEndpoint(prefix string) eventbus.Endpoint[QuoteRequest, sqx.BBO] {
+	return eventbus.NewEndpoint[QuoteRequest, sqx.BBO](prefix+".quote", eventbus.JSON)
+}
+
+// Serve answers the BBO RPCs of c under prefix
+func Serve(bus *eventbus.Bus, prefix string, c *Consolidator) (*nats.Subscription, error) {
+	return QuoteEndpoint(prefix).Serve(bus, func(_ context.Context, req *QuoteRequest) (*sqx.BBO, error) {
+		symbol, err := sqx.NewSymbolFromStr(req.Symbol)
+		if err != nil {
+			return nil, err
+		}
+		var bbo sqx.BBO
+		var ok bool
+		if req.Timestamp == 0 {
+			bbo, ok = c.Latest(symbol)
+		} else {
+			bbo, ok = c.At(symbol, req.Timestamp)
+		}
+		if !ok {
+			return nil, fmt.Errorf("no BBO of %s at %d", symbol, req.Timestamp)
+		}
+		return &bbo, nil
+	})
+}
@@ -0,0 +1,227 @@
+// Package oms is where order intents enter the order management system.
+// Strategies and gateways publish their intents on the intent subjects and
+// the entry routes them on to the exchange, except those of the strategies
+// in dry run: their orders are acknowledged, and filled at the prices of
+// the market, by the paper layer, logged and published as the orders they
+// would have sent, and reported like those of the exchange. Strategies
+// deployed blue/green run as a live and a shadow instance: the orders of the
+// live one are routed under the name of the strategy deployed, those of the
+// shadow one kept on paper like the orders of a strategy in dry run.
+package oms
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/BullionBear/sequex/internal/bluegreen"
+	"github.com/BullionBear/sequex/internal/dryrun"
+	"github.com/BullionBear/sequex/internal/model/sqx"
+	"github.com/BullionBear/sequex/internal/tenant"
+	"github.com/BullionBear/sequex/pkg/logger"
+)
+
+// Router routes intents on to the exchange, under the subject prefix of the
+// tenant placing them. The tenant is nil for the deployment itself.
+type Router interface {
+	PublishOrder(t *tenant.Tenant, intent sqx.OrderIntent) error
+	PublishCancel(t *tenant.Tenant, intent sqx.CancelIntent) error
+}
+
+// Reporter publishes the execution reports of the paper layer, and the
+// orders it kept off the exchange, under the subject prefix of the tenant
+// placing them
+type Reporter interface {
+	PublishReport(t *tenant.Tenant, report sqx.ExecutionReport) error
+	PublishDryRun(t *tenant.Tenant, order dryrun.Order) error
+}
+
+// paperOrder is an order working on paper
+type paperOrder struct {
+	tenant   *tenant.Tenant
+	instance string // Strategy that placed the order
+}
+
+// Entry passes the intents entering the OMS. It is safe for concurrent use.
+type Entry struct {
+	router      Router
+	reporter    Reporter
+	dryRun      *dryrun.Flags
+	paper       *dryrun.Paper
+	deployments *bluegreen.Deployments
+	mu          sync.Mutex
+	orders      map[string]paperOrder // Working on paper, by order key
+	live        map[string]string     // Instance of the orders of deployed strategies on the exchange, by order key
+}
+
+// NewEntry creates the entry of the OMS, keeping the strategies flagged in
+// dry run off the exchange. Orders on paper are only acknowledged unless
+// another paper layer is set with SetPaper.
+func NewEntry(router Router, reporter Reporter, flags *dryrun.Flags) *Entry {
+	return &Entry{
+		router:   router,
+		reporter: reporter,
+		dryRun:   flags,
+		paper:    dryrun.NewPaper(nil),
+		orders:   make(map[string]paperOrder),
+		live:     make(map[string]string),
+	}
+}
+
+// SetPaper replaces the paper layer, e.g. with one filling orders at the
+// prices of the market. Orders resting on paper are filled by RunPaper.
+func (e *Entry) SetPaper(paper *dryrun.Paper) {
+	e.paper = paper
+}
+
+// SetDeployments runs strategies as a live and a shadow instance, each a
+// strategy node tagging its orders with its own name. Orders of the live
+// instance are routed under the strategy deployed, so the instances follow
+// its reports as well as theirs, while those of the shadow instance are
+// kept on paper. Both are followed to compare their decisions and PnL
+// until the shadow is promoted.
+func (e *Entry) SetDeployments(deployments *bluegreen.Deployments) {
+	e.deployments = deployments
+}
+
+// orderKey identifies an order across tenants and strategies, which may
+// reuse client order ids
+func orderKey(t *tenant.Tenant, strategy, clientOrderID string) string {
+	key := strategy + "/" + clientOrderID
+	if t == nil {
+		return key
+	}
+	return t.ID + "/" + key
+}
+
+// Submit routes an order on to the exchange, or to the paper layer when
+// its strategy is in dry run or it is placed by a shadow instance
+func (e *Entry) Submit(t *tenant.Tenant, intent sqx.OrderIntent) error {
+	instance := intent.Strategy
+	shadow := false
+	if e.deployments != nil {
+		var live bool
+		intent.Strategy, live = e.deployments.Route(instance)
+		shadow = !live
+	}
+	if !shadow && !e.dryRun.Enabled(intent.Strategy) {
+		if err := e.router.PublishOrder(t, intent); err != nil {
+			return err
+		}
+		if e.deployments != nil && intent.Strategy != instance {
+			e.mu.Lock()
+			e.live[orderKey(t, intent.Strategy, intent.ClientOrderId)] = instance
+			e.mu.Unlock()
+			e.deployments.OnOrder(instance, intent)
+		}
+		return nil
+	}
+	if e.deployments != nil {
+		e.deployments.OnOrder(instance, intent)
+	}
+	logger.Log.Info().
+		Str("strategy", intent.Strategy).
+		Str("clOrdID", intent.ClientOrderId).
+		Str("symbol", intent.Symbol.String()).
+		Str("side", intent.Side.String()).
+		Float64("quantity", intent.Quantity).
+		Float64("price", intent.Price).
+		Msg("Dry-run order submitted on paper")
+	key := orderKey(t, instance, intent.ClientOrderId)
+	e.mu.Lock()
+	e.orders[key] = paperOrder{tenant: t, instance: instance}
+	e.mu.Unlock()
+	for i, report := range e.paper.Submit(key, intent) {
+		order := dryrun.Order{Report: report}
+		if i == 0 {
+			order.Order = &intent
+		}
+		e.report(key, t, instance, order)
+	}
+	return nil
+}
+
+// Cancel cancels an order on paper, even once its strategy is out of dry
+// run, and routes the cancels of the other orders on to the exchange: a
+// strategy switched to dry run can still cancel what it placed there, and
+// an instance no longer live its orders placed under the strategy deployed.
+func (e *Entry) Cancel(t *tenant.Tenant, intent sqx.CancelIntent) error {
+	instance := intent.Strategy
+	key := orderKey(t, instance, intent.OrigClientOrderId)
+	report, ok := e.paper.Cancel(key, intent)
+	if !ok {
+		if e.deployments != nil {
+			intent.Strategy = e.deployments.Strategy(instance)
+		}
+		return e.router.PublishCancel(t, intent)
+	}
+	logger.Log.Info().
+		Str("strategy", intent.Strategy).
+		Str("clOrdID", intent.ClientOrderId).
+		Str("origClOrdID", intent.OrigClientOrderId).
+		Msg("Dry-run order canceled on paper")
+	e.report(key, t, instance, dryrun.Order{Cancel: &intent, Report: report})
+	return nil
+}
+
+// OnExecutionReport follows a report of the exchange, received under the
+// subject prefix of tenant t, for the instance of a deployed strategy that
+// placed the order
+func (e *Entry) OnExecutionReport(t *tenant.Tenant, report sqx.ExecutionReport) {
+	if e.deployments == nil {
+		return
+	}
+	key := orderKey(t, report.Strategy, report.ClientOrderId)
+	e.mu.Lock()
+	instance, ok := e.live[key]
+	if ok && report.Status.IsFinal() {
+		delete(e.live, key)
+	}
+	e.mu.Unlock()
+	if ok {
+		e.deployments.OnExecutionReport(instance, report)
+	}
+}
+
+// RunPaper fills the orders resting on paper once marketable, checking
+// every interval until ctx is done
+func (e *Entry) RunPaper(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			for _, f := range e.paper.Match() {
+				e.mu.Lock()
+				o, ok := e.orders[f.Key]
+				e.mu.Unlock()
+				if !ok {
+					continue
+				}
+				logger.Log.Info().Str("strategy", f.Report.Strategy).Str("clOrdID", f.Report.ClientOrderId).Float64("price", f.Report.LastPrice).Msg("Dry-run order filled on paper")
+				e.report(f.Key, o.tenant, o.instance, dryrun.Order{Report: f.Report})
+			}
+		}
+	}
+}
+
+// report publishes an order on paper of an instance and its execution
+// report, forgetting the order once final
+func (e *Entry) report(key string, t *tenant.Tenant, instance string, order dryrun.Order) {
+	if order.Report.Status.IsFinal() {
+		e.mu.Lock()
+		delete(e.orders, key)
+		e.mu.Unlock()
+	}
+	if e.deployments != nil {
+		e.deployments.OnExecutionReport(instance, order.Report)
+	}
+	if err := e.reporter.PublishDryRun(t, order); err != nil {
+		logger.Log.Error().Err(err).Str("clOrdID", order.Report.ClientOrderId).Msg("Failed to publish dry-run order")
+	}
+	if err := e.reporter.PublishReport(t, order.Report); err != nil {
+		logger.Log.Error().Err(err).Str("clOrdID", order.Report.ClientOrderId).Msg("Failed to publish execution report")
+	}
+}
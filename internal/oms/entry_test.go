@@ -0,0 +1,297 @@
+package oms
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/BullionBear/sequex/internal/bluegreen"
+	"github.com/BullionBear/sequex/internal/dryrun"
+	"github.com/BullionBear/sequex/internal/model/sqx"
+	"github.com/BullionBear/sequex/internal/tenant"
+)
+
+// fakeRouter records the intents routed on to the exchange
+type fakeRouter struct {
+	orders  []sqx.OrderIntent
+	cancels []sqx.CancelIntent
+}
+
+func (r *fakeRouter) PublishOrder(_ *tenant.Tenant, intent sqx.OrderIntent) error {
+	r.orders = append(r.orders, intent)
+	return nil
+}
+
+func (r *fakeRouter) PublishCancel(_ *tenant.Tenant, intent sqx.CancelIntent) error {
+	r.cancels = append(r.cancels, intent)
+	return nil
+}
+
+// fakeReporter records what the paper layer publishes
+type fakeReporter struct {
+	reports []sqx.ExecutionReport
+	tenants []*tenant.Tenant // Of each report
+	dryRuns []dryrun.Order
+}
+
+func (r *fakeReporter) PublishReport(t *tenant.Tenant, report sqx.ExecutionReport) error {
+	r.reports = append(r.reports, report)
+	r.tenants = append(r.tenants, t)
+	return nil
+}
+
+func (r *fakeReporter) PublishDryRun(_ *tenant.Tenant, order dryrun.Order) error {
+	r.dryRuns = append(r.dryRuns, order)
+	return nil
+}
+
+type fakePrices map[string]float64
+
+func (p fakePrices) Rate(from, to string) (float64, error) {
+	return p[from+"-"+to], nil
+}
+
+var btc = sqx.NewSymbol("BTC", "USDT")
+
+func order(strategy, id string, price float64) sqx.OrderIntent {
+	return sqx.OrderIntent{ClientOrderId: id, Strategy: strategy, Exchange: sqx.ExchangeBinance, Symbol: btc,
+		Side: sqx.SideBuy, Type: sqx.OrderTypeLimit, TimeInForce: sqx.TimeInForceGTC, Quantity: 1, Price: price}
+}
+
+func cancel(strategy, id, origID string) sqx.CancelIntent {
+	return sqx.CancelIntent{ClientOrderId: id, OrigClientOrderId: origID, Strategy: strategy, Exchange: sqx.ExchangeBinance, Symbol: btc}
+}
+
+func TestEntryRoutesLiveStrategies(t *testing.T) {
+	router, reporter := &fakeRouter{}, &fakeReporter{}
+	e := NewEntry(router, reporter, dryrun.NewFlags(false, map[string]bool{"grid": true}))
+	if err := e.Submit(nil, order("arb", "1", 50000)); err != nil {
+		t.Fatal(err)
+	}
+	if err := e.Cancel(nil, cancel("arb", "2", "1")); err != nil {
+		t.Fatal(err)
+	}
+	if len(router.orders) != 1 || len(router.cancels) != 1 {
+		t.Errorf("routed %d orders and %d cancels, want 1 of each", len(router.orders), len(router.cancels))
+	}
+	if len(reporter.reports) != 0 {
+		t.Errorf("reported %+v on paper, want the exchange to report", reporter.reports)
+	}
+}
+
+func TestEntryKeepsDryRunOffTheExchange(t *testing.T) {
+	acme := &tenant.Tenant{ID: "acme"}
+	for _, tt := range []struct {
+		name  string
+		flags *dryrun.Flags
+	}{
+		{"global", dryrun.NewFlags(true, nil)},
+		{"strategy", dryrun.NewFlags(false, map[string]bool{"grid": true})},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			router, reporter := &fakeRouter{}, &fakeReporter{}
+			e := NewEntry(router, reporter, tt.flags)
+			if err := e.Submit(acme, order("grid", "1", 49000)); err != nil {
+				t.Fatal(err)
+			}
+			if err := e.Cancel(acme, cancel("grid", "2", "1")); err != nil {
+				t.Fatal(err)
+			}
+			if len(router.orders) != 0 || len(router.cancels) != 0 {
+				t.Fatalf("routed %+v and %+v to the exchange", router.orders, router.cancels)
+			}
+			if len(reporter.reports) != 2 || reporter.reports[0].Status != sqx.OrderStatusNew || reporter.reports[1].Status != sqx.OrderStatusCanceled {
+				t.Fatalf("reports = %+v, want an acknowledgement then a cancel", reporter.reports)
+			}
+			if reporter.reports[1].ClientOrderId != "2" || reporter.tenants[1] != acme {
+				t.Errorf("cancel reported as %s for %v", reporter.reports[1].ClientOrderId, reporter.tenants[1])
+			}
+			if len(reporter.dryRuns) != 2 || reporter.dryRuns[0].Order == nil || reporter.dryRuns[1].Cancel == nil {
+				t.Errorf("dry-run orders = %+v, want the order then its cancel", reporter.dryRuns)
+			}
+		})
+	}
+}
+
+func TestEntryCancelsOnPaperOnceLive(t *testing.T) {
+	router, reporter := &fakeRouter{}, &fakeReporter{}
+	flags := dryrun.NewFlags(false, map[string]bool{"grid": true})
+	e := NewEntry(router, reporter, flags)
+	if err := e.Submit(nil, order("grid", "1", 49000)); err != nil {
+		t.Fatal(err)
+	}
+	off := false
+	if _, err := flags.Set("grid", &off, "ops"); err != nil {
+		t.Fatal(err)
+	}
+
+	// The order on paper is canceled there, the one placed since on the exchange
+	if err := e.Submit(nil, order("grid", "2", 49000)); err != nil {
+		t.Fatal(err)
+	}
+	if err := e.Cancel(nil, cancel("grid", "3", "1")); err != nil {
+		t.Fatal(err)
+	}
+	if err := e.Cancel(nil, cancel("grid", "4", "2")); err != nil {
+		t.Fatal(err)
+	}
+	if len(router.orders) != 1 || router.orders[0].ClientOrderId != "2" {
+		t.Errorf("routed orders %+v, want only the one placed out of dry run", router.orders)
+	}
+	if len(router.cancels) != 1 || router.cancels[0].OrigClientOrderId != "2" {
+		t.Errorf("routed cancels %+v, want only the one of the order on the exchange", router.cancels)
+	}
+	if last := reporter.reports[len(reporter.reports)-1]; last.ClientOrderId != "3" || last.Status != sqx.OrderStatusCanceled {
+		t.Errorf("last report on paper = %+v, want the cancel of the order on paper", last)
+	}
+}
+
+func TestEntryFillsOnPaper(t *testing.T) {
+	router, reporter := &fakeRouter{}, &fakeReporter{}
+	prices := fakePrices{"BTC-USDT": 50000}
+	e := NewEntry(router, reporter, dryrun.NewFlags(true, nil))
+	e.SetPaper(dryrun.NewPaper(prices))
+	if err := e.Submit(nil, order("grid", "1", 49000)); err != nil {
+		t.Fatal(err)
+	}
+	prices["BTC-USDT"] = 48900
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		e.RunPaper(ctx, time.Millisecond)
+		close(done)
+	}()
+	deadline := time.Now().Add(time.Second)
+	for {
+		e.mu.Lock()
+		working := len(e.orders)
+		e.mu.Unlock()
+		if working == 0 || time.Now().After(deadline) {
+			break
+		}
+		time.Sleep(time.Millisecond)
+	}
+	cancel()
+	<-done
+
+	if len(router.orders) != 0 {
+		t.Fatalf("routed %+v to the exchange", router.orders)
+	}
+	if len(reporter.reports) != 2 || reporter.reports[1].Status != sqx.OrderStatusFilled || reporter.reports[1].LastPrice != 49000 {
+		t.Errorf("reports = %+v, want the acknowledgement then a fill at the limit", reporter.reports)
+	}
+}
+
+// deployed creates an entry running grid as the blue and green instances,
+// blue live, with orders on paper filled at prices
+func deployed(t *testing.T, router *fakeRouter, reporter *fakeReporter, prices fakePrices) (*Entry, *bluegreen.Deployments) {
+	t.Helper()
+	d, err := bluegreen.Open([]bluegreen.Deployment{{Strategy: "grid", Blue: "grid-blue", Green: "grid-green"}}, bluegreen.Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	e := NewEntry(router, reporter, dryrun.NewFlags(false, nil))
+	e.SetPaper(dryrun.NewPaper(prices))
+	e.SetDeployments(d)
+	return e, d
+}
+
+func filled(strategy, id string) sqx.ExecutionReport {
+	return sqx.ExecutionReport{ClientOrderId: id, Strategy: strategy, Exchange: sqx.ExchangeBinance, Symbol: btc, Side: sqx.SideBuy,
+		Status: sqx.OrderStatusFilled, Quantity: 1, LastQuantity: 1, LastPrice: 50000}
+}
+
+func TestEntryShadowsDeployedStrategies(t *testing.T) {
+	router, reporter := &fakeRouter{}, &fakeReporter{}
+	e, d := deployed(t, router, reporter, fakePrices{"BTC-USDT": 50000})
+	for _, instance := range []string{"grid-blue", "grid-green"} {
+		if err := e.Submit(nil, order(instance, "1", 51000)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if len(router.orders) != 1 || router.orders[0].Strategy != "grid" {
+		t.Fatalf("routed %+v, want the order of the live instance under the strategy deployed", router.orders)
+	}
+	if len(reporter.reports) != 2 || reporter.reports[1].Status != sqx.OrderStatusFilled {
+		t.Fatalf("reports on paper = %+v, want the shadow order acknowledged then filled", reporter.reports)
+	}
+	for _, report := range reporter.reports {
+		if report.Strategy != "grid-green" {
+			t.Errorf("report on paper of %s, want the shadow instance", report.Strategy)
+		}
+	}
+
+	// The exchange reports the live order under the strategy deployed, and
+	// nothing of the other strategies is followed
+	e.OnExecutionReport(nil, filled("grid", "1"))
+	e.OnExecutionReport(nil, filled("arb", "1"))
+	c, err := d.Compare("grid")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if c.Live.Strategy != "grid-blue" || c.Live.Orders != 1 || c.Live.Fills != 1 {
+		t.Errorf("live = %+v, want one order and its fill on the exchange", c.Live)
+	}
+	if c.Shadow.Strategy != "grid-green" || c.Shadow.Orders != 1 || c.Shadow.Fills != 1 {
+		t.Errorf("shadow = %+v, want one order and its fill on paper", c.Shadow)
+	}
+}
+
+func TestEntryPromotesAndRollsBack(t *testing.T) {
+	router, reporter := &fakeRouter{}, &fakeReporter{}
+	e, d := deployed(t, router, reporter, fakePrices{"BTC-USDT": 50000})
+	for _, instance := range []string{"grid-blue", "grid-green"} {
+		if err := e.Submit(nil, order(instance, "1", 49000)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if _, err := d.Promote("grid", "ops", "tighter bands"); err != nil {
+		t.Fatal(err)
+	}
+
+	// The promoted instance trades, the former live one on paper
+	for _, instance := range []string{"grid-green", "grid-blue"} {
+		if err := e.Submit(nil, order(instance, "2", 49000)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if len(router.orders) != 2 || router.orders[1].ClientOrderId != "2" || router.orders[1].Strategy != "grid" {
+		t.Fatalf("routed %+v, want the order of the promoted instance under the strategy deployed", router.orders)
+	}
+	if reporter.reports[len(reporter.reports)-1].Strategy != "grid-blue" {
+		t.Errorf("last report on paper = %+v, want the order of the former live instance", reporter.reports[len(reporter.reports)-1])
+	}
+
+	// Orders on the exchange are still canceled, and their reports followed,
+	// for the instance that placed them
+	if err := e.Cancel(nil, cancel("grid-blue", "3", "1")); err != nil {
+		t.Fatal(err)
+	}
+	if err := e.Cancel(nil, cancel("grid-green", "3", "1")); err != nil {
+		t.Fatal(err)
+	}
+	if len(router.cancels) != 1 || router.cancels[0].OrigClientOrderId != "1" || router.cancels[0].Strategy != "grid" {
+		t.Errorf("routed cancels %+v, want only the one of the order on the exchange", router.cancels)
+	}
+	e.OnExecutionReport(nil, filled("grid", "1"))
+	if c, err := d.Compare("grid"); err != nil || c.Shadow.Strategy != "grid-blue" || c.Shadow.Fills != 1 || c.Live.Fills != 0 {
+		t.Errorf("comparison = %+v, %v, want the fill of the order placed live followed for grid-blue", c, err)
+	}
+
+	// Promoting again rolls back
+	if _, err := d.Promote("grid", "ops", "rollback"); err != nil {
+		t.Fatal(err)
+	}
+	for _, instance := range []string{"grid-blue", "grid-green"} {
+		if err := e.Submit(nil, order(instance, "4", 49000)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if len(router.orders) != 3 || router.orders[2].ClientOrderId != "4" {
+		t.Errorf("routed %+v, want the order of the blue instance live again", router.orders)
+	}
+	if len(d.History()) != 2 {
+		t.Errorf("history = %+v, want the promotion and its rollback", d.History())
+	}
+}
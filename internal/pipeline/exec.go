@@ -0,0 +1,79 @@
+package pipeline
+
+import (
+	"context"
+	"errors"
+	"os"
+	"os/exec"
+	"syscall"
+	"time"
+
+	"github.com/nats-io/nats.go"
+)
+
+// execProcess is a node running as a child process
+type execProcess struct {
+	cmd  *exec.Cmd
+	done chan struct{}
+	err  error
+}
+
+// Command starts a node as a child process sharing the output of the
+// master, with env added to its environment. The process gets its own
+// process group so a Ctrl-C in the terminal reaches the master only, which
+// then stops the nodes in order.
+func Command(path string, args, env []string) StartFunc {
+	return func() (Process, error) {
+		cmd := exec.Command(path, args...)
+		cmd.Env = append(os.Environ(), env...)
+		cmd.Stdout, cmd.Stderr = os.Stdout, os.Stderr
+		cmd.SysProcAttr = &syscall.SysProcAttr{Setpgid: true}
+		if err := cmd.Start(); err != nil {
+			return nil, err
+		}
+		p := &execProcess{cmd: cmd, done: make(chan struct{})}
+		go func() {
+			p.err = cmd.Wait()
+			close(p.done)
+		}()
+		return p, nil
+	}
+}
+
+// Stop sends SIGTERM, then SIGKILL when the process outlives timeout
+func (p *execProcess) Stop(timeout time.Duration) error {
+	if err := p.cmd.Process.Signal(syscall.SIGTERM); err != nil && !errors.Is(err, os.ErrProcessDone) {
+		return err
+	}
+	select {
+	case <-p.done:
+		return nil
+	case <-time.After(timeout):
+	}
+	if err := p.cmd.Process.Kill(); err != nil && !errors.Is(err, os.ErrProcessDone) {
+		return err
+	}
+	<-p.done
+	return errors.New("killed after the stop timeout")
+}
+
+// Done implements Process
+func (p *execProcess) Done() <-chan struct{} {
+	return p.done
+}
+
+// Err implements Process
+func (p *execProcess) Err() error {
+	return p.err
+}
+
+// RequestProbe reports a node ready once it answers a request on subject,
+// such as the diag.<service> subject of its diagnostics RPC
+func RequestProbe(conn *nats.Conn, subject string) ProbeFunc {
+	return func(ctx context.Context) error {
+		ctx, cancel := context.WithTimeout(ctx, time.Second)
+		defer cancel()
+		_, err := conn.RequestWithContext(ctx, subject, nil)
+		return err
+	}
+}
@@ -0,0 +1,310 @@
+// Package pipeline starts a graph of nodes in dependency order, waiting for
+// each node to be ready before starting the nodes depending on it, and stops
+// them in reverse order. A node exiting on its own takes its dependents down
+// with it.
+package pipeline
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Process is a running node
+type Process interface {
+	// Stop asks the node to exit and kills it after timeout
+	Stop(timeout time.Duration) error
+	// Done is closed once the node exited
+	Done() <-chan struct{}
+	// Err returns why the node exited, once Done is closed
+	Err() error
+}
+
+// StartFunc starts a node
+type StartFunc func() (Process, error)
+
+// ProbeFunc checks once whether a node is ready to serve
+type ProbeFunc func(ctx context.Context) error
+
+// Spec declares a node of the graph
+type Spec struct {
+	Name         string
+	DependsOn    []string
+	Start        StartFunc
+	Ready        ProbeFunc     // Nil treats the node as ready once started
+	ReadyTimeout time.Duration // Time the node has to become ready, 30s when 0
+	StopTimeout  time.Duration // Time the node has to exit before it is killed, 10s when 0
+}
+
+// State is the lifecycle stage of a node
+type State string
+
+const (
+	StatePending  State = "pending"
+	StateStarting State = "starting"
+	StateReady    State = "ready"
+	StateStopped  State = "stopped"
+	StateExited   State = "exited" // Exited on its own
+	StateFailed   State = "failed" // Failed to start or become ready
+)
+
+// NodeState is the state of a node of the graph
+type NodeState struct {
+	Name  string `json:"name"`
+	State State  `json:"state"`
+	Error string `json:"error,omitempty"`
+}
+
+// Order sorts the specs so every node comes after its dependencies, nodes
+// of the same depth by name. It rejects duplicated names, unknown
+// dependencies and cycles.
+func Order(specs []Spec) ([]Spec, error) {
+	byName := make(map[string]Spec, len(specs))
+	for _, spec := range specs {
+		if spec.Name == "" {
+			return nil, fmt.Errorf("node name cannot be empty")
+		}
+		if _, ok := byName[spec.Name]; ok {
+			return nil, fmt.Errorf("node %s is declared twice", spec.Name)
+		}
+		byName[spec.Name] = spec
+	}
+	pending := make(map[string]int, len(specs)) // Dependencies not yet ordered
+	dependents := make(map[string][]string)
+	for _, spec := range specs {
+		for _, dep := range spec.DependsOn {
+			if _, ok := byName[dep]; !ok {
+				return nil, fmt.Errorf("node %s depends on unknown node %s", spec.Name, dep)
+			}
+			pending[spec.Name]++
+			dependents[dep] = append(dependents[dep], spec.Name)
+		}
+	}
+	var ready []string
+	for _, spec := range specs {
+		if pending[spec.Name] == 0 {
+			ready = append(ready, spec.Name)
+		}
+	}
+	ordered := make([]Spec, 0, len(specs))
+	for len(ready) > 0 {
+		sort.Strings(ready)
+		var next []string
+		for _, name := range ready {
+			ordered = append(ordered, byName[name])
+			for _, dependent := range dependents[name] {
+				if pending[dependent]--; pending[dependent] == 0 {
+					next = append(next, dependent)
+				}
+			}
+		}
+		ready = next
+	}
+	if len(ordered) < len(specs) {
+		var cycle []string
+		for _, spec := range specs {
+			if pending[spec.Name] > 0 {
+				cycle = append(cycle, spec.Name)
+			}
+		}
+		sort.Strings(cycle)
+		return nil, fmt.Errorf("dependency cycle between nodes %s", strings.Join(cycle, ", "))
+	}
+	return ordered, nil
+}
+
+// node is a spec and what became of it
+type node struct {
+	spec    Spec
+	process Process
+	state   State
+	err     error
+}
+
+// Graph runs the nodes of a pipeline
+type Graph struct {
+	nodes         []*node // In start order
+	probeInterval time.Duration
+	onExit        func(name string, err error)
+
+	mu       sync.Mutex
+	stopping bool
+}
+
+// New creates a graph of the specs. onExit is called when a node exits on
+// its own, before its dependents are stopped.
+func New(specs []Spec, onExit func(name string, err error)) (*Graph, error) {
+	ordered, err := Order(specs)
+	if err != nil {
+		return nil, err
+	}
+	g := &Graph{probeInterval: 250 * time.Millisecond, onExit: onExit}
+	for _, spec := range ordered {
+		if spec.Start == nil {
+			return nil, fmt.Errorf("node %s has no start function", spec.Name)
+		}
+		if spec.ReadyTimeout <= 0 {
+			spec.ReadyTimeout = 30 * time.Second
+		}
+		if spec.StopTimeout <= 0 {
+			spec.StopTimeout = 10 * time.Second
+		}
+		g.nodes = append(g.nodes, &node{spec: spec, state: StatePending})
+	}
+	return g, nil
+}
+
+// Names returns the nodes in start order
+func (g *Graph) Names() []string {
+	names := make([]string, len(g.nodes))
+	for i, n := range g.nodes {
+		names[i] = n.spec.Name
+	}
+	return names
+}
+
+// Start starts the nodes one at a time in dependency order, each once the
+// previous one is ready. When a node fails to start or become ready, the
+// nodes already started are stopped in reverse order and the error returned.
+func (g *Graph) Start(ctx context.Context) error {
+	for _, n := range g.nodes {
+		if err := g.start(ctx, n); err != nil {
+			g.set(n, StateFailed, err)
+			g.Stop()
+			return fmt.Errorf("node %s: %w", n.spec.Name, err)
+		}
+	}
+	return nil
+}
+
+func (g *Graph) start(ctx context.Context, n *node) error {
+	g.mu.Lock()
+	if g.stopping {
+		g.mu.Unlock()
+		return errors.New("pipeline is stopping")
+	}
+	n.state = StateStarting
+	g.mu.Unlock()
+
+	process, err := n.spec.Start()
+	if err != nil {
+		return err
+	}
+	g.mu.Lock()
+	n.process = process
+	g.mu.Unlock()
+	if err := g.waitReady(ctx, n); err != nil {
+		return err
+	}
+	g.set(n, StateReady, nil)
+	go g.watch(n)
+	return nil
+}
+
+// waitReady probes the node until it is ready, exits or runs out of time
+func (g *Graph) waitReady(ctx context.Context, n *node) error {
+	if n.spec.Ready == nil {
+		return nil
+	}
+	ctx, cancel := context.WithTimeout(ctx, n.spec.ReadyTimeout)
+	defer cancel()
+	ticker := time.NewTicker(g.probeInterval)
+	defer ticker.Stop()
+	for {
+		err := n.spec.Ready(ctx)
+		if err == nil {
+			return nil
+		}
+		select {
+		case <-n.process.Done():
+			return fmt.Errorf("exited before ready: %w", n.process.Err())
+		case <-ctx.Done():
+			return fmt.Errorf("not ready within %s: %w", n.spec.ReadyTimeout, err)
+		case <-ticker.C:
+		}
+	}
+}
+
+// watch stops the dependents of a node that exits on its own
+func (g *Graph) watch(n *node) {
+	<-n.process.Done()
+	g.mu.Lock()
+	if g.stopping || n.state != StateReady {
+		g.mu.Unlock()
+		return
+	}
+	n.state, n.err = StateExited, n.process.Err()
+	g.mu.Unlock()
+	if g.onExit != nil {
+		g.onExit(n.spec.Name, n.process.Err())
+	}
+	g.stopNodes(g.dependents(n.spec.Name))
+}
+
+// dependents returns the nodes depending on name, directly or not, in start order
+func (g *Graph) dependents(name string) []*node {
+	down := map[string]bool{name: true}
+	var nodes []*node
+	for _, n := range g.nodes {
+		for _, dep := range n.spec.DependsOn {
+			if down[dep] {
+				down[n.spec.Name] = true
+				nodes = append(nodes, n)
+				break
+			}
+		}
+	}
+	return nodes
+}
+
+// Stop stops every running node in reverse start order
+func (g *Graph) Stop() {
+	g.mu.Lock()
+	g.stopping = true
+	g.mu.Unlock()
+	g.stopNodes(g.nodes)
+}
+
+// stopNodes stops the running nodes among nodes, last first
+func (g *Graph) stopNodes(nodes []*node) {
+	for i := len(nodes) - 1; i >= 0; i-- {
+		n := nodes[i]
+		g.mu.Lock()
+		process, state := n.process, n.state
+		if process == nil || state == StateStopped || state == StateExited {
+			g.mu.Unlock()
+			continue
+		}
+		if state != StateFailed {
+			n.state = StateStopped
+		}
+		g.mu.Unlock()
+		if err := process.Stop(n.spec.StopTimeout); err != nil {
+			g.set(n, StateStopped, err)
+		}
+	}
+}
+
+func (g *Graph) set(n *node, state State, err error) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	n.state, n.err = state, err
+}
+
+// States returns the state of every node in start order
+func (g *Graph) States() []NodeState {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	states := make([]NodeState, len(g.nodes))
+	for i, n := range g.nodes {
+		states[i] = NodeState{Name: n.spec.Name, State: n.state}
+		if n.err != nil {
+			states[i].Error = n.err.Error()
+		}
+	}
+	return states
+}
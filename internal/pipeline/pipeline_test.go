@@ -0,0 +1,216 @@
+package pipeline
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// fakeProcess records the order nodes start and stop in
+type fakeProcess struct {
+	name string
+	log  *eventLog
+	done chan struct{}
+	once sync.Once
+	err  error
+}
+
+type eventLog struct {
+	mu     sync.Mutex
+	events []string
+}
+
+func (l *eventLog) add(event string) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.events = append(l.events, event)
+}
+
+func (l *eventLog) String() string {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return strings.Join(l.events, " ")
+}
+
+func (p *fakeProcess) exit(err error) {
+	p.once.Do(func() {
+		p.err = err
+		close(p.done)
+	})
+}
+
+func (p *fakeProcess) Stop(time.Duration) error {
+	p.log.add("stop:" + p.name)
+	p.exit(nil)
+	return nil
+}
+
+func (p *fakeProcess) Done() <-chan struct{} { return p.done }
+func (p *fakeProcess) Err() error            { return p.err }
+
+type fakeNodes struct {
+	log       eventLog
+	mu        sync.Mutex
+	processes map[string]*fakeProcess
+}
+
+func (f *fakeNodes) spec(name string, deps ...string) Spec {
+	return Spec{Name: name, DependsOn: deps, Start: func() (Process, error) {
+		f.log.add("start:" + name)
+		p := &fakeProcess{name: name, log: &f.log, done: make(chan struct{})}
+		f.mu.Lock()
+		f.processes[name] = p
+		f.mu.Unlock()
+		return p, nil
+	}}
+}
+
+func (f *fakeNodes) process(name string) *fakeProcess {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.processes[name]
+}
+
+func TestOrder(t *testing.T) {
+	f := &fakeNodes{processes: map[string]*fakeProcess{}}
+	ordered, err := Order([]Spec{
+		f.spec("marketmaker", "orderbook", "oms"),
+		f.spec("orderbook", "feed"),
+		f.spec("oms"),
+		f.spec("feed"),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var names []string
+	for _, spec := range ordered {
+		names = append(names, spec.Name)
+	}
+	if got := strings.Join(names, " "); got != "feed oms orderbook marketmaker" {
+		t.Errorf("order = %s", got)
+	}
+
+	for _, specs := range [][]Spec{
+		{f.spec("a", "b"), f.spec("b", "c"), f.spec("c", "a"), f.spec("d")},
+		{f.spec("a", "missing")},
+		{f.spec("a"), f.spec("a")},
+	} {
+		if _, err := Order(specs); err == nil {
+			t.Errorf("invalid graph %v accepted", specs)
+		}
+	}
+}
+
+func TestGraphStartsAndStopsInOrder(t *testing.T) {
+	f := &fakeNodes{processes: map[string]*fakeProcess{}}
+	probes := 0
+	book := f.spec("orderbook", "feed")
+	book.Ready = func(context.Context) error {
+		if probes++; probes < 3 {
+			return errors.New("no responders")
+		}
+		return nil
+	}
+	g, err := New([]Spec{f.spec("marketmaker", "orderbook"), book, f.spec("feed")}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	g.probeInterval = time.Millisecond
+
+	if err := g.Start(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if probes != 3 {
+		t.Errorf("orderbook probed %d times", probes)
+	}
+	g.Stop()
+	want := "start:feed start:orderbook start:marketmaker stop:marketmaker stop:orderbook stop:feed"
+	if got := f.log.String(); got != want {
+		t.Errorf("events = %s, want %s", got, want)
+	}
+	for _, state := range g.States() {
+		if state.State != StateStopped {
+			t.Errorf("%s is %s", state.Name, state.State)
+		}
+	}
+}
+
+func TestGraphFailsWhenNotReady(t *testing.T) {
+	f := &fakeNodes{processes: map[string]*fakeProcess{}}
+	book := f.spec("orderbook", "feed")
+	book.Ready = func(context.Context) error { return errors.New("no responders") }
+	book.ReadyTimeout = 20 * time.Millisecond
+	g, err := New([]Spec{f.spec("marketmaker", "orderbook"), book, f.spec("feed")}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	g.probeInterval = time.Millisecond
+
+	if err := g.Start(context.Background()); err == nil || !strings.Contains(err.Error(), "orderbook") {
+		t.Fatalf("start error = %v", err)
+	}
+	// The market maker never started; the others were stopped in reverse order
+	if got := f.log.String(); got != "start:feed start:orderbook stop:orderbook stop:feed" {
+		t.Errorf("events = %s", got)
+	}
+	if states := g.States(); states[1].State != StateFailed || states[2].State != StatePending {
+		t.Errorf("states = %+v", states)
+	}
+}
+
+func TestGraphStopsDependentsOfExitedNode(t *testing.T) {
+	f := &fakeNodes{processes: map[string]*fakeProcess{}}
+	exited := make(chan string, 1)
+	g, err := New([]Spec{
+		f.spec("marketmaker", "orderbook"),
+		f.spec("orderbook", "feed"),
+		f.spec("feed"),
+		f.spec("report"),
+	}, func(name string, err error) { exited <- name })
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := g.Start(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	f.process("feed").exit(errors.New("exit status 1"))
+	if name := <-exited; name != "feed" {
+		t.Errorf("exited = %s", name)
+	}
+	deadline := time.Now().Add(time.Second)
+	for !strings.HasSuffix(f.log.String(), "stop:orderbook") && time.Now().Before(deadline) {
+		time.Sleep(time.Millisecond)
+	}
+	if got := f.log.String(); !strings.HasSuffix(got, "stop:marketmaker stop:orderbook") {
+		t.Errorf("events = %s", got)
+	}
+	for _, state := range g.States() {
+		if state.Name == "report" && state.State != StateReady {
+			t.Errorf("unrelated node is %s", state.State)
+		}
+	}
+	g.Stop()
+}
+
+func TestCommand(t *testing.T) {
+	p, err := Command("sleep", []string{"30"}, nil)()
+	if err != nil {
+		t.Skip("sleep unavailable:", err)
+	}
+	start := time.Now()
+	if err := p.Stop(5 * time.Second); err != nil {
+		t.Fatal(err)
+	}
+	if time.Since(start) > 2*time.Second {
+		t.Error("SIGTERM did not stop the process")
+	}
+	select {
+	case <-p.Done():
+	default:
+		t.Error("process not done after stop")
+	}
+}
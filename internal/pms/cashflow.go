@@ -0,0 +1,60 @@
+package pms
+
+import (
+	"fmt"
+	"strings"
+)
+
+// CashFlowKind is what a cash flow pays for
+type CashFlowKind string
+
+const (
+	CashFlowFunding  CashFlowKind = "funding"  // Perpetual futures funding payment
+	CashFlowInterest CashFlowKind = "interest" // Interest on a margin loan
+)
+
+// CashFlow is a payment booked by the exchange outside of trades. Like
+// fills, cash flows are append-only history.
+type CashFlow struct {
+	ID          string       `json:"id"` // Unique within the portfolio, e.g. the exchange transaction id
+	PortfolioID string       `json:"portfolio_id"`
+	Kind        CashFlowKind `json:"kind"`
+	Symbol      string       `json:"symbol,omitempty"` // Position or loan the payment is for, e.g. BTC-USDT
+	Asset       string       `json:"asset"`
+	Amount      float64      `json:"amount"`    // Received when positive, paid when negative
+	Timestamp   int64        `json:"timestamp"` // Unix milliseconds
+}
+
+// Validate checks the fields of a cash flow
+func (c CashFlow) Validate() error {
+	if err := ValidateID(c.PortfolioID); err != nil {
+		return err
+	}
+	if c.ID == "" {
+		return fmt.Errorf("%w: cash flow id cannot be empty", ErrInvalid)
+	}
+	if c.Kind != CashFlowFunding && c.Kind != CashFlowInterest {
+		return fmt.Errorf("%w: kind must be funding or interest, got %q", ErrInvalid, c.Kind)
+	}
+	if c.Symbol != "" {
+		if _, err := NormalizeSymbol(c.Symbol); err != nil {
+			return fmt.Errorf("%w: %v", ErrInvalid, err)
+		}
+	}
+	if c.Asset == "" {
+		return fmt.Errorf("%w: asset cannot be empty", ErrInvalid)
+	}
+	if c.Timestamp <= 0 {
+		return fmt.Errorf("%w: timestamp must be positive", ErrInvalid)
+	}
+	return nil
+}
+
+// normalize returns the cash flow with canonical symbol and asset
+func (c CashFlow) normalize() CashFlow {
+	if c.Symbol != "" {
+		c.Symbol, _ = NormalizeSymbol(c.Symbol)
+	}
+	c.Asset = strings.ToUpper(c.Asset)
+	return c
+}
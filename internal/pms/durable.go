@@ -0,0 +1,537 @@
+package pms
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// DefaultSnapshotsKept is the number of end of day snapshots a durable store
+// keeps unless set
+const DefaultSnapshotsKept = 30
+
+const (
+	snapshotPrefix = "snapshot-"
+	journalPrefix  = "journal-"
+)
+
+// Snapshot is the state of every portfolio at a point of the journal
+type Snapshot struct {
+	Seq        uint64              `json:"seq"`  // Last journal entry included
+	Date       string              `json:"date"` // Day closed, YYYY-MM-DD in UTC
+	TakenAt    time.Time           `json:"taken_at"`
+	Portfolios []PortfolioSnapshot `json:"portfolios"`
+}
+
+// PortfolioSnapshot is the state of a portfolio
+type PortfolioSnapshot struct {
+	Portfolio Portfolio  `json:"portfolio"`
+	Positions []Position `json:"positions"`
+	Fills     []Fill     `json:"fills"`
+	CashFlows []CashFlow `json:"cash_flows"`
+	// Quantity held of every asset, the positions summed by base asset
+	Balances map[string]float64 `json:"balances"`
+	// Net asset value when the snapshot was taken, nil when taken without
+	// rates
+	NAV *Valuation `json:"nav,omitempty"`
+}
+
+// Recovery describes how a durable store rebuilt its state on open
+type Recovery struct {
+	Snapshot    string        `json:"snapshot"` // File loaded, empty when starting from the journal alone
+	SnapshotSeq uint64        `json:"snapshot_seq"`
+	Replayed    int           `json:"replayed"` // Journal entries applied over the snapshot
+	Skipped     []string      `json:"skipped,omitempty"`
+	Duration    time.Duration `json:"duration"`
+}
+
+// entry is a journaled write. Portfolios and positions are journaled as
+// written, so replaying them restores their versions and update times.
+type entry struct {
+	Seq         uint64     `json:"seq"`
+	At          time.Time  `json:"at"` // When written, zero in journals written before it was recorded
+	Portfolio   *Portfolio `json:"portfolio,omitempty"`
+	Position    *Position  `json:"position,omitempty"`
+	PortfolioID string     `json:"portfolio_id,omitempty"`
+	Fills       []Fill     `json:"fills,omitempty"`
+	CashFlows   []CashFlow `json:"cash_flows,omitempty"`
+}
+
+// DurableStore is a MemoryStore whose writes are journaled to JSON lines
+// files in a directory, along with snapshots of the whole state. On open it
+// loads the latest snapshot and replays the journal written after it, so
+// recovery takes the time of one snapshot and one day of writes however long
+// the history is. Taking a snapshot starts a new journal segment; segments
+// older than the snapshots kept are deleted.
+type DurableStore struct {
+	*MemoryStore
+	dir  string
+	keep int
+
+	mu       sync.Mutex // Serializes the writes with their journal entries
+	seq      uint64     // Of the last entry
+	journal  *os.File
+	recovery Recovery
+
+	replaying func() // Called by AsOf once the lock is released, for tests
+}
+
+// OpenDurable opens the store kept in dir, creating it if needed. keep is
+// the number of snapshots kept, DefaultSnapshotsKept when 0.
+func OpenDurable(dir string, keep int) (*DurableStore, error) {
+	if keep < 0 {
+		return nil, fmt.Errorf("snapshots kept cannot be negative")
+	}
+	if keep == 0 {
+		keep = DefaultSnapshotsKept
+	}
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, err
+	}
+	start := time.Now()
+	d := &DurableStore{MemoryStore: NewMemoryStore(), dir: dir, keep: keep}
+
+	// The latest readable snapshot; an unreadable one, e.g. written by a
+	// full disk, falls back to the previous day and a longer journal tail
+	snapshots, err := d.Snapshots()
+	if err != nil {
+		return nil, err
+	}
+	for i := len(snapshots) - 1; i >= 0; i-- {
+		snapshot, err := ReadSnapshot(snapshots[i])
+		if err != nil {
+			d.recovery.Skipped = append(d.recovery.Skipped, fmt.Sprintf("%s: %v", filepath.Base(snapshots[i]), err))
+			continue
+		}
+		d.MemoryStore.restore(snapshot)
+		d.seq = snapshot.Seq
+		d.recovery.Snapshot, d.recovery.SnapshotSeq = filepath.Base(snapshots[i]), snapshot.Seq
+		break
+	}
+
+	segments, err := d.segments()
+	if err != nil {
+		return nil, err
+	}
+	for i, segment := range segments {
+		last := i == len(segments)-1
+		if err := d.replay(segment.path, last); err != nil {
+			return nil, fmt.Errorf("failed to replay %s: %w", filepath.Base(segment.path), err)
+		}
+	}
+	if len(segments) > 0 && segments[len(segments)-1].first > d.seq {
+		// The last segment holds no entry, append to it
+		d.journal, err = os.OpenFile(segments[len(segments)-1].path, os.O_WRONLY|os.O_APPEND, 0o644)
+	} else {
+		d.journal, err = d.createSegment()
+	}
+	if err != nil {
+		return nil, err
+	}
+	d.recovery.Duration = time.Since(start)
+	return d, nil
+}
+
+// Recovery returns how the state was rebuilt on open
+func (d *DurableStore) Recovery() Recovery {
+	return d.recovery
+}
+
+// Close closes the journal
+func (d *DurableStore) Close() error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if d.journal == nil {
+		return nil
+	}
+	err := d.journal.Close()
+	d.journal = nil
+	return err
+}
+
+// PutPortfolio creates or updates a portfolio and journals it
+func (d *DurableStore) PutPortfolio(p Portfolio, expected int64) (Portfolio, error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	written, err := d.MemoryStore.PutPortfolio(p, expected)
+	if err != nil {
+		return written, err
+	}
+	return written, d.append(entry{Portfolio: &written})
+}
+
+// PutPosition creates or updates a position and journals it
+func (d *DurableStore) PutPosition(p Position, expected int64) (Position, error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	written, err := d.MemoryStore.PutPosition(p, expected)
+	if err != nil {
+		return written, err
+	}
+	return written, d.append(entry{Position: &written})
+}
+
+// AddFills appends fills to the history of a portfolio and journals them
+func (d *DurableStore) AddFills(portfolioID string, fills []Fill) error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if err := d.MemoryStore.AddFills(portfolioID, fills); err != nil {
+		return err
+	}
+	return d.append(entry{PortfolioID: portfolioID, Fills: fills})
+}
+
+// AddCashFlows appends the payments not recorded yet and journals them
+func (d *DurableStore) AddCashFlows(portfolioID string, flows []CashFlow) (int, error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	n, err := d.MemoryStore.AddCashFlows(portfolioID, flows)
+	if err != nil || n == 0 {
+		return n, err
+	}
+	// Replaying skips the payments recorded before as the write did
+	return n, d.append(entry{PortfolioID: portfolioID, CashFlows: flows})
+}
+
+// append journals a write applied in memory. A write that fails to be
+// journaled is reported but stays in memory until the next restart.
+func (d *DurableStore) append(e entry) error {
+	if d.journal == nil {
+		return fmt.Errorf("store is closed")
+	}
+	e.Seq, e.At = d.seq+1, time.Now().UTC()
+	data, err := json.Marshal(e)
+	if err != nil {
+		return err
+	}
+	if _, err := d.journal.Write(append(data, '\n')); err != nil {
+		return fmt.Errorf("failed to journal write: %w", err)
+	}
+	d.seq = e.Seq
+	return nil
+}
+
+// apply replays a journaled write
+func (s *MemoryStore) apply(e entry) error {
+	switch {
+	case e.Portfolio != nil:
+		s.mu.Lock()
+		s.portfolios[e.Portfolio.ID] = *e.Portfolio
+		s.mu.Unlock()
+	case e.Position != nil:
+		s.mu.Lock()
+		if s.positions[e.Position.PortfolioID] == nil {
+			s.positions[e.Position.PortfolioID] = make(map[string]Position)
+		}
+		s.positions[e.Position.PortfolioID][e.Position.Symbol] = *e.Position
+		s.mu.Unlock()
+	case len(e.Fills) > 0:
+		return s.AddFills(e.PortfolioID, e.Fills)
+	case len(e.CashFlows) > 0:
+		_, err := s.AddCashFlows(e.PortfolioID, e.CashFlows)
+		return err
+	}
+	return nil
+}
+
+// replay applies the entries of a journal segment written after the state
+// loaded. A truncated last line of the last segment, left by a crash during
+// a write, is dropped.
+func (d *DurableStore) replay(path string, last bool) error {
+	f, err := os.OpenFile(path, os.O_RDWR, 0o644)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	reader := bufio.NewReader(f)
+	var size int64
+	for line := 1; ; line++ {
+		data, err := reader.ReadBytes('\n')
+		if err == io.EOF {
+			if len(data) > 0 && last {
+				return f.Truncate(size)
+			}
+			if len(data) > 0 {
+				return fmt.Errorf("line %d is truncated", line)
+			}
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		size += int64(len(data))
+		var e entry
+		if err := json.Unmarshal(data, &e); err != nil {
+			return fmt.Errorf("line %d: %w", line, err)
+		}
+		if e.Seq <= d.seq {
+			continue // In the snapshot
+		}
+		if e.Seq != d.seq+1 {
+			return fmt.Errorf("line %d: entry %d follows %d, the journal has a gap", line, e.Seq, d.seq)
+		}
+		if err := d.MemoryStore.apply(e); err != nil {
+			return fmt.Errorf("line %d: %w", line, err)
+		}
+		d.seq = e.Seq
+		d.recovery.Replayed++
+	}
+}
+
+// restore replaces the state with a snapshot
+func (s *MemoryStore) restore(snapshot Snapshot) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, p := range snapshot.Portfolios {
+		id := p.Portfolio.ID
+		s.portfolios[id] = p.Portfolio
+		s.positions[id] = make(map[string]Position, len(p.Positions))
+		for _, position := range p.Positions {
+			s.positions[id][position.Symbol] = position
+		}
+		s.fills[id] = p.Fills
+		s.cashFlows[id] = p.CashFlows
+	}
+}
+
+// Snapshot writes the state of every portfolio, valued with rates unless
+// nil, as the snapshot of date, a YYYY-MM-DD day. Writes wait for it. The
+// journal continues in a new segment and the snapshots and segments beyond
+// those kept are deleted.
+func (d *DurableStore) Snapshot(date string, rates Converter) (Snapshot, error) {
+	if _, err := time.Parse(time.DateOnly, date); err != nil {
+		return Snapshot{}, fmt.Errorf("%w: date %q is not YYYY-MM-DD", ErrInvalid, date)
+	}
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if d.journal == nil {
+		return Snapshot{}, fmt.Errorf("store is closed")
+	}
+	snapshot := Snapshot{Seq: d.seq, Date: date, TakenAt: time.Now().UTC()}
+	portfolios, err := d.MemoryStore.Portfolios()
+	if err != nil {
+		return snapshot, err
+	}
+	for _, p := range portfolios {
+		ps := PortfolioSnapshot{Portfolio: p}
+		if ps.Positions, err = d.MemoryStore.Positions(p.ID); err != nil {
+			return snapshot, err
+		}
+		if ps.Fills, err = d.MemoryStore.Fills(p.ID); err != nil {
+			return snapshot, err
+		}
+		if ps.CashFlows, err = d.MemoryStore.CashFlows(p.ID); err != nil {
+			return snapshot, err
+		}
+		ps.Balances = balances(ps.Positions)
+		if rates != nil {
+			nav := Value(p, ps.Positions, rates)
+			ps.NAV = &nav
+		}
+		snapshot.Portfolios = append(snapshot.Portfolios, ps)
+	}
+
+	path := filepath.Join(d.dir, fmt.Sprintf("%s%020d-%s.json", snapshotPrefix, snapshot.Seq, date))
+	if err := writeFileAtomic(path, snapshot); err != nil {
+		return snapshot, fmt.Errorf("failed to write snapshot: %w", err)
+	}
+	if err := d.rotate(); err != nil {
+		return snapshot, err
+	}
+	return snapshot, d.prune()
+}
+
+// balances sums the quantities of positions by base asset
+func balances(positions []Position) map[string]float64 {
+	balances := make(map[string]float64)
+	for _, position := range positions {
+		base, _, _ := strings.Cut(position.Symbol, "-")
+		balances[base] += position.Quantity
+	}
+	return balances
+}
+
+// rotate continues the journal in a new segment after a snapshot, unless
+// the current one is still empty
+func (d *DurableStore) rotate() error {
+	info, err := d.journal.Stat()
+	if err != nil {
+		return err
+	}
+	if info.Size() == 0 {
+		return nil
+	}
+	journal, err := d.createSegment()
+	if err != nil {
+		return err
+	}
+	d.journal.Close()
+	d.journal = journal
+	return nil
+}
+
+func (d *DurableStore) createSegment() (*os.File, error) {
+	path := filepath.Join(d.dir, fmt.Sprintf("%s%020d.jsonl", journalPrefix, d.seq+1))
+	return os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o644)
+}
+
+// prune deletes the snapshots beyond those kept and the journal segments
+// holding only entries of the oldest snapshot kept
+func (d *DurableStore) prune() error {
+	snapshots, err := d.Snapshots()
+	if err != nil || len(snapshots) == 0 {
+		return err
+	}
+	if len(snapshots) > d.keep {
+		for _, path := range snapshots[:len(snapshots)-d.keep] {
+			if err := os.Remove(path); err != nil {
+				return err
+			}
+		}
+		snapshots = snapshots[len(snapshots)-d.keep:]
+	}
+	oldest, ok := snapshotSeq(filepath.Base(snapshots[0]))
+	if !ok {
+		return nil
+	}
+	segments, err := d.segments()
+	if err != nil {
+		return err
+	}
+	for i := 0; i+1 < len(segments) && segments[i+1].first <= oldest+1; i++ {
+		if err := os.Remove(segments[i].path); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Snapshots returns the paths of the snapshots kept, oldest first
+func (d *DurableStore) Snapshots() ([]string, error) {
+	entries, err := os.ReadDir(d.dir)
+	if err != nil {
+		return nil, err
+	}
+	var paths []string
+	for _, e := range entries {
+		if _, ok := snapshotSeq(e.Name()); ok && !e.IsDir() {
+			paths = append(paths, filepath.Join(d.dir, e.Name()))
+		}
+	}
+	// Zero padded sequences sort in order
+	sort.Strings(paths)
+	return paths, nil
+}
+
+// ReadSnapshot reads a snapshot file
+func ReadSnapshot(path string) (Snapshot, error) {
+	var snapshot Snapshot
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return snapshot, err
+	}
+	if err := json.Unmarshal(data, &snapshot); err != nil {
+		return snapshot, err
+	}
+	return snapshot, nil
+}
+
+func snapshotSeq(name string) (uint64, bool) {
+	rest, ok := strings.CutPrefix(name, snapshotPrefix)
+	if !ok || !strings.HasSuffix(name, ".json") {
+		return 0, false
+	}
+	seq, _, _ := strings.Cut(rest, "-")
+	n, err := strconv.ParseUint(seq, 10, 64)
+	return n, err == nil
+}
+
+type segment struct {
+	path  string
+	first uint64 // Sequence of its first entry
+}
+
+// segments returns the journal segments in order
+func (d *DurableStore) segments() ([]segment, error) {
+	entries, err := os.ReadDir(d.dir)
+	if err != nil {
+		return nil, err
+	}
+	var segments []segment
+	for _, e := range entries {
+		rest, ok := strings.CutPrefix(e.Name(), journalPrefix)
+		if !ok || e.IsDir() {
+			continue
+		}
+		first, err := strconv.ParseUint(strings.TrimSuffix(rest, ".jsonl"), 10, 64)
+		if err != nil {
+			continue
+		}
+		segments = append(segments, segment{path: filepath.Join(d.dir, e.Name()), first: first})
+	}
+	sort.Slice(segments, func(i, j int) bool { return segments[i].first < segments[j].first })
+	return segments, nil
+}
+
+// writeFileAtomic writes v as JSON to path through a synced temporary file,
+// so a crash leaves either the previous file or the complete new one
+func writeFileAtomic(path string, v any) error {
+	tmp := path + ".tmp"
+	f, err := os.Create(tmp)
+	if err != nil {
+		return err
+	}
+	err = json.NewEncoder(f).Encode(v)
+	if err == nil {
+		err = f.Sync()
+	}
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil {
+		err = os.Rename(tmp, path)
+	}
+	if err != nil {
+		os.Remove(tmp)
+	}
+	return err
+}
+
+// RunEOD takes the end of day snapshot every day at cutoff, a time of day in
+// UTC, until ctx is done. The snapshot is dated with the day it closes, so a
+// midnight cutoff dates it with the previous day.
+func (d *DurableStore) RunEOD(ctx context.Context, cutoff time.Duration, rates Converter, onError func(error)) {
+	for {
+		due := nextCutoff(time.Now(), cutoff)
+		timer := time.NewTimer(time.Until(due))
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return
+		case <-timer.C:
+		}
+		date := due.Add(-time.Nanosecond).Format(time.DateOnly)
+		if _, err := d.Snapshot(date, rates); err != nil {
+			onError(fmt.Errorf("end of day snapshot of %s: %w", date, err))
+		}
+	}
+}
+
+// nextCutoff returns the first time of day cutoff in UTC after now
+func nextCutoff(now time.Time, cutoff time.Duration) time.Time {
+	now = now.UTC()
+	due := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.UTC).Add(cutoff)
+	if !due.After(now) {
+		due = due.AddDate(0, 0, 1)
+	}
+	return due
+}
@@ -0,0 +1,219 @@
+package pms
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// state reads everything a store holds, encoded as it is persisted
+func state(t *testing.T, s Store) string {
+	t.Helper()
+	portfolios, err := s.Portfolios()
+	if err != nil {
+		t.Fatal(err)
+	}
+	all := map[string]any{"portfolios": portfolios}
+	for _, p := range portfolios {
+		positions, _ := s.Positions(p.ID)
+		fills, _ := s.Fills(p.ID)
+		flows, _ := s.CashFlows(p.ID)
+		all[p.ID] = []any{positions, fills, flows}
+	}
+	data, err := json.Marshal(all)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(data)
+}
+
+func write(t *testing.T, s Store, day int) {
+	t.Helper()
+	if day == 0 {
+		if _, err := s.PutPortfolio(Portfolio{ID: "main", Name: "Main"}, 0); err != nil {
+			t.Fatal(err)
+		}
+	}
+	position, err := s.Position("main", "BTC-USDT")
+	if err != nil {
+		position = Position{PortfolioID: "main", Symbol: "BTC-USDT"}
+	}
+	position.Quantity += 0.5
+	position.AvgPrice = 40000
+	if _, err := s.PutPosition(position, position.Version); err != nil {
+		t.Fatal(err)
+	}
+	ts := int64(1700000000000 + day*86400000)
+	if err := s.AddFills("main", []Fill{{ID: "f" + string(rune('a'+day)), Symbol: "btc-usdt", Side: "buy", Quantity: 0.5, Price: 40000, Timestamp: ts}}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := s.AddCashFlows("main", []CashFlow{{ID: "c" + string(rune('a'+day)), Kind: CashFlowFunding, Asset: "usdt", Amount: -1, Timestamp: ts}}); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestDurableStoreReplaysJournal(t *testing.T) {
+	dir := t.TempDir()
+	s, err := OpenDurable(dir, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	write(t, s, 0)
+	write(t, s, 1)
+	// Duplicate payments are not journaled again
+	if n, err := s.AddCashFlows("main", []CashFlow{{ID: "ca", Kind: CashFlowFunding, Asset: "USDT", Amount: -1, Timestamp: 1}}); err != nil || n != 0 {
+		t.Fatalf("duplicate cash flow: %d, %v", n, err)
+	}
+	want := state(t, s)
+	s.Close()
+
+	reopened, err := OpenDurable(dir, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer reopened.Close()
+	if got := state(t, reopened); got != want {
+		t.Errorf("recovered\n%+v\nwant\n%+v", got, want)
+	}
+	if r := reopened.Recovery(); r.Snapshot != "" || r.Replayed != 7 {
+		t.Errorf("recovery %+v", r)
+	}
+	// Versions continue from the recovered ones
+	if p, err := reopened.PutPortfolio(Portfolio{ID: "main", Name: "Renamed"}, 1); err != nil || p.Version != 2 {
+		t.Errorf("update after recovery: %+v, %v", p, err)
+	}
+}
+
+func TestDurableStoreSnapshots(t *testing.T) {
+	dir := t.TempDir()
+	s, err := OpenDurable(dir, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	rates := fakeRates{"BTC-USDT": 50000}
+	for day := 0; day < 3; day++ {
+		write(t, s, day)
+		date := time.Date(2025, 1, 1+day, 0, 0, 0, 0, time.UTC).Format(time.DateOnly)
+		snapshot, err := s.Snapshot(date, rates)
+		if err != nil {
+			t.Fatal(err)
+		}
+		main := snapshot.Portfolios[0]
+		if len(snapshot.Portfolios) != 1 || len(main.Fills) != day+1 || len(main.CashFlows) != day+1 {
+			t.Fatalf("snapshot %+v", snapshot)
+		}
+		if held := 0.5 * float64(day+1); main.Balances["BTC"] != held || main.NAV == nil || main.NAV.Value != held*50000 {
+			t.Errorf("day %d: balances %v, nav %+v", day, main.Balances, main.NAV)
+		}
+	}
+	write(t, s, 3)
+	want := state(t, s)
+	s.Close()
+
+	snapshots, _ := s.Snapshots()
+	if len(snapshots) != 2 || !strings.HasSuffix(snapshots[1], "-2025-01-03.json") {
+		t.Errorf("snapshots kept %v", snapshots)
+	}
+	segments, _ := s.segments()
+	if len(segments) != 2 {
+		t.Errorf("%d journal segments kept, want the ones after each snapshot kept", len(segments))
+	}
+
+	reopened, err := OpenDurable(dir, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := state(t, reopened); got != want {
+		t.Errorf("recovered\n%+v\nwant\n%+v", got, want)
+	}
+	if r := reopened.Recovery(); r.SnapshotSeq != 10 || r.Replayed != 3 {
+		t.Errorf("recovery %+v", r)
+	}
+	reopened.Close()
+
+	// An unreadable latest snapshot falls back to the one before and the
+	// journal after it
+	if err := os.WriteFile(snapshots[1], []byte("{"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	fallback, err := OpenDurable(dir, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer fallback.Close()
+	if got := state(t, fallback); got != want {
+		t.Errorf("recovered from the previous snapshot\n%+v\nwant\n%+v", got, want)
+	}
+	if r := fallback.Recovery(); r.SnapshotSeq != 7 || r.Replayed != 6 || len(r.Skipped) != 1 {
+		t.Errorf("recovery %+v", r)
+	}
+}
+
+func TestDurableStoreTruncatedJournal(t *testing.T) {
+	dir := t.TempDir()
+	s, err := OpenDurable(dir, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	write(t, s, 0)
+	want := state(t, s)
+	s.Close()
+
+	segments, _ := s.segments()
+	f, err := os.OpenFile(segments[0].path, os.O_WRONLY|os.O_APPEND, 0o644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	f.WriteString(`{"seq":5,"portfolio":{"id":"ma`)
+	f.Close()
+
+	reopened, err := OpenDurable(dir, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := state(t, reopened); got != want {
+		t.Errorf("recovered %+v", got)
+	}
+	// The next entry starts on its own line
+	write(t, reopened, 1)
+	reopened.Close()
+	if _, err := OpenDurable(dir, 0); err != nil {
+		t.Fatal(err)
+	}
+
+	// A gap is an error rather than a silently missing write
+	gap := filepath.Join(dir, journalPrefix+"00000000000000000100.jsonl")
+	if err := os.WriteFile(gap, []byte(`{"seq":100,"portfolio_id":"main"}`+"\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := OpenDurable(dir, 0); err == nil || !strings.Contains(err.Error(), "gap") {
+		t.Errorf("expected a gap error, got %v", err)
+	}
+}
+
+func TestNextCutoff(t *testing.T) {
+	at := func(s string) time.Time {
+		ts, err := time.Parse(time.RFC3339, s)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return ts
+	}
+	for _, c := range []struct {
+		now    string
+		cutoff time.Duration
+		want   string
+	}{
+		{"2025-01-01T10:00:00Z", 0, "2025-01-02T00:00:00Z"},
+		{"2025-01-01T00:00:00Z", 0, "2025-01-02T00:00:00Z"},
+		{"2025-01-01T10:00:00Z", 16 * time.Hour, "2025-01-01T16:00:00Z"},
+		{"2025-01-01T20:00:00+08:00", 16 * time.Hour, "2025-01-01T16:00:00Z"},
+	} {
+		if got := nextCutoff(at(c.now), c.cutoff); !got.Equal(at(c.want)) {
+			t.Errorf("nextCutoff(%s, %s) = %s, want %s", c.now, c.cutoff, got, c.want)
+		}
+	}
+}
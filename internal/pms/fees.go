@@ -0,0 +1,78 @@
+package pms
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/BullionBear/sequex/internal/model/sqx"
+)
+
+// DailyFees totals the fees of the fills of one UTC day
+type DailyFees struct {
+	Date    string             `json:"date"` // YYYY-MM-DD, or total
+	Fills   int                `json:"fills"`
+	ByAsset map[string]float64 `json:"by_asset"` // Amounts charged, keyed by asset
+	Total   float64            `json:"total"`    // Sum converted to the currency of the report
+}
+
+// FeeReport totals fees per day in one currency
+type FeeReport struct {
+	Currency string      `json:"currency"`
+	Days     []DailyFees `json:"days"`
+	Total    DailyFees   `json:"total"`
+}
+
+// SummarizeFees groups the fees of the fills within [from, to) by UTC day
+// and converts them to currency at the rate as of each fill, fees in the
+// base asset at the price of the fill. Fees without an asset are in the
+// quote asset of the symbol. Zero bounds are open.
+func SummarizeFees(fills []Fill, currency string, rates HistoricalConverter, from, to time.Time) (FeeReport, error) {
+	report := FeeReport{Currency: currency, Days: []DailyFees{}, Total: DailyFees{Date: "total", ByAsset: make(map[string]float64)}}
+	byDay := make(map[string]*DailyFees)
+	for _, f := range fills {
+		at := time.UnixMilli(f.Timestamp).UTC()
+		if (!from.IsZero() && at.Before(from)) || (!to.IsZero() && !at.Before(to)) {
+			continue
+		}
+		date := at.Format(time.DateOnly)
+		day, ok := byDay[date]
+		if !ok {
+			day = &DailyFees{Date: date, ByAsset: make(map[string]float64)}
+			byDay[date] = day
+		}
+		day.Fills++
+		report.Total.Fills++
+		if f.Fee == 0 {
+			continue
+		}
+		symbol, err := sqx.NewSymbolFromStr(f.Symbol)
+		if err != nil {
+			return report, fmt.Errorf("%w: fill %s: %v", ErrInvalid, f.ID, err)
+		}
+		asset := strings.ToUpper(f.FeeAsset)
+		if asset == "" {
+			asset = symbol.Quote
+		}
+		var rate float64
+		if asset == symbol.Base && f.Price > 0 {
+			rate, err = rates.RateAt(symbol.Quote, currency, at)
+			rate *= f.Price
+		} else {
+			rate, err = rates.RateAt(asset, currency, at)
+		}
+		if err != nil {
+			return report, fmt.Errorf("%s fee of %s at %s: %w", asset, f.Symbol, at.Format(time.RFC3339), err)
+		}
+		day.ByAsset[asset] += f.Fee
+		day.Total += f.Fee * rate
+		report.Total.ByAsset[asset] += f.Fee
+		report.Total.Total += f.Fee * rate
+	}
+	for _, day := range byDay {
+		report.Days = append(report.Days, *day)
+	}
+	sort.Slice(report.Days, func(i, j int) bool { return report.Days[i].Date < report.Days[j].Date })
+	return report, nil
+}
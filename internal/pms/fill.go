@@ -0,0 +1,57 @@
+package pms
+
+import (
+	"fmt"
+
+	"github.com/BullionBear/sequex/internal/model/sqx"
+)
+
+// Fill is one execution that changed a position. Fills are append-only
+// history, so they have no version.
+type Fill struct {
+	ID          string  `json:"id"` // Unique within the portfolio, e.g. the exchange trade id
+	PortfolioID string  `json:"portfolio_id"`
+	Symbol      string  `json:"symbol"` // BASE-QUOTE, e.g. BTC-USDT
+	Side        string  `json:"side"`   // BUY or SELL
+	Quantity    float64 `json:"quantity"`
+	Price       float64 `json:"price"`
+	Fee         float64 `json:"fee"`
+	FeeAsset    string  `json:"fee_asset"`
+	Timestamp   int64   `json:"timestamp"` // Unix milliseconds
+}
+
+// Validate checks the fields of a fill
+func (f Fill) Validate() error {
+	if err := ValidateID(f.PortfolioID); err != nil {
+		return err
+	}
+	if f.ID == "" {
+		return fmt.Errorf("%w: fill id cannot be empty", ErrInvalid)
+	}
+	if _, err := NormalizeSymbol(f.Symbol); err != nil {
+		return fmt.Errorf("%w: %v", ErrInvalid, err)
+	}
+	if sqx.NewSide(f.Side) == sqx.SideUnknown {
+		return fmt.Errorf("%w: side must be BUY or SELL, got %q", ErrInvalid, f.Side)
+	}
+	if f.Quantity <= 0 {
+		return fmt.Errorf("%w: quantity must be positive", ErrInvalid)
+	}
+	if f.Price <= 0 {
+		return fmt.Errorf("%w: price must be positive", ErrInvalid)
+	}
+	if f.Fee < 0 {
+		return fmt.Errorf("%w: fee cannot be negative", ErrInvalid)
+	}
+	if f.Timestamp <= 0 {
+		return fmt.Errorf("%w: timestamp must be positive", ErrInvalid)
+	}
+	return nil
+}
+
+// normalize returns the fill with canonical symbol and side
+func (f Fill) normalize() Fill {
+	f.Symbol, _ = NormalizeSymbol(f.Symbol)
+	f.Side = sqx.NewSide(f.Side).String()
+	return f
+}
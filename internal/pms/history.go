@@ -0,0 +1,264 @@
+package pms
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// ErrNoHistory is returned by stores that keep no history
+var ErrNoHistory = errors.New("no history kept")
+
+// Historian rebuilds portfolios as they were at a past time
+type Historian interface {
+	AsOf(portfolioID string, at time.Time) (Historical, error)
+}
+
+// Historical is a portfolio rebuilt as it was at a past time
+type Historical struct {
+	AsOf      time.Time          `json:"as_of"`
+	Portfolio Portfolio          `json:"portfolio"`
+	Positions []Position         `json:"positions"`
+	Balances  map[string]float64 `json:"balances"` // Positions summed by base asset
+	// Valuation of the positions at the marks of the end of day snapshot
+	// rebuilt from, nil when rebuilt from the journal alone or the snapshot
+	// was taken without rates
+	Valuation *Valuation `json:"valuation,omitempty"`
+	Snapshot  string     `json:"snapshot,omitempty"` // Date of the snapshot rebuilt from
+	Replayed  int        `json:"replayed"`           // Journal entries applied over the snapshot
+}
+
+// time returns when an entry was written. Entries journaled before their
+// time was recorded fall back on the time of what they write.
+func (e entry) time() time.Time {
+	if !e.At.IsZero() {
+		return e.At
+	}
+	switch {
+	case e.Portfolio != nil:
+		return e.Portfolio.UpdatedAt
+	case e.Position != nil:
+		return e.Position.UpdatedAt
+	}
+	var latest int64
+	for _, f := range e.Fills {
+		latest = max(latest, f.Timestamp)
+	}
+	for _, f := range e.CashFlows {
+		latest = max(latest, f.Timestamp)
+	}
+	return time.UnixMilli(latest)
+}
+
+// AsOf rebuilds a portfolio as it was at a time, from the latest snapshot
+// taken by then and the journal written after it. Only the history of the
+// snapshots kept can be rebuilt. The files are opened under the lock, so a
+// snapshot pruning them meanwhile leaves them readable, and replayed without
+// it up to the last entry written then, so writes do not wait for it.
+func (d *DurableStore) AsOf(portfolioID string, at time.Time) (Historical, error) {
+	h := Historical{AsOf: at.UTC()}
+	files, err := d.openHistory()
+	if err != nil {
+		return h, err
+	}
+	defer files.close()
+	if d.replaying != nil {
+		d.replaying()
+	}
+
+	state := NewMemoryStore()
+	var seq uint64
+	var base *Snapshot
+	for i := len(files.snapshots) - 1; i >= 0; i-- {
+		var snapshot Snapshot
+		err := json.NewDecoder(files.snapshots[i]).Decode(&snapshot)
+		if err != nil || snapshot.TakenAt.After(at) {
+			continue // Unreadable snapshots are skipped as on open
+		}
+		state.restore(snapshot)
+		seq, base, h.Snapshot = snapshot.Seq, &snapshot, snapshot.Date
+		break
+	}
+	if base == nil && (len(files.segments) == 0 || files.segments[0].first != 1) {
+		return h, fmt.Errorf("%w: history before the oldest snapshot kept", ErrNotFound)
+	}
+
+	for i, segment := range files.segments {
+		replayed, done, err := replayUntil(state, files.journal[i], &seq, files.seq, at)
+		h.Replayed += replayed
+		if err != nil {
+			return h, fmt.Errorf("failed to replay %s: %w", filepath.Base(segment.path), err)
+		}
+		if done {
+			break
+		}
+	}
+
+	if h.Portfolio, err = state.Portfolio(portfolioID); err != nil {
+		return h, fmt.Errorf("%w: portfolio %s at %s", ErrNotFound, portfolioID, h.AsOf.Format(time.RFC3339))
+	}
+	if h.Positions, err = state.Positions(portfolioID); err != nil {
+		return h, err
+	}
+	h.Balances = balances(h.Positions)
+	if base != nil {
+		if rates := snapshotMarks(*base); len(rates) > 0 {
+			v := Value(h.Portfolio, h.Positions, rates)
+			v.AsOf = h.AsOf
+			h.Valuation = &v
+		}
+	}
+	return h, nil
+}
+
+// history are the files a portfolio is rebuilt from
+type history struct {
+	seq       uint64     // Of the last entry written when opened
+	snapshots []*os.File // Oldest first
+	segments  []segment
+	journal   []*os.File // Of each segment
+}
+
+// openHistory opens the snapshots and journal segments kept
+func (d *DurableStore) openHistory() (*history, error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if d.journal == nil {
+		return nil, fmt.Errorf("store is closed")
+	}
+	h := &history{seq: d.seq}
+	snapshots, err := d.Snapshots()
+	if err != nil {
+		return nil, err
+	}
+	for _, path := range snapshots {
+		f, err := os.Open(path)
+		if err != nil {
+			h.close()
+			return nil, err
+		}
+		h.snapshots = append(h.snapshots, f)
+	}
+	if h.segments, err = d.segments(); err != nil {
+		h.close()
+		return nil, err
+	}
+	for _, segment := range h.segments {
+		f, err := os.Open(segment.path)
+		if err != nil {
+			h.close()
+			return nil, err
+		}
+		h.journal = append(h.journal, f)
+	}
+	return h, nil
+}
+
+func (h *history) close() {
+	for _, f := range h.snapshots {
+		f.Close()
+	}
+	for _, f := range h.journal {
+		f.Close()
+	}
+}
+
+// replayUntil applies to state the entries of a journal segment following
+// seq, up to last and written by at, reporting whether an entry past either
+// ended the replay
+func replayUntil(state *MemoryStore, r io.Reader, seq *uint64, last uint64, at time.Time) (int, bool, error) {
+	reader := bufio.NewReader(r)
+	replayed := 0
+	for line := 1; ; line++ {
+		if *seq >= last {
+			// Entries written since are not read, nor a line being written
+			return replayed, true, nil
+		}
+		data, err := reader.ReadBytes('\n')
+		if err == io.EOF {
+			return replayed, false, nil
+		}
+		if err != nil {
+			return replayed, false, err
+		}
+		var e entry
+		if err := json.Unmarshal(data, &e); err != nil {
+			return replayed, false, fmt.Errorf("line %d: %w", line, err)
+		}
+		if e.Seq <= *seq {
+			continue // In the snapshot
+		}
+		if e.Seq != *seq+1 {
+			return replayed, false, fmt.Errorf("line %d: entry %d follows %d, the journal has a gap", line, e.Seq, *seq)
+		}
+		// Entries are journaled in the order they were written
+		if e.time().After(at) {
+			return replayed, true, nil
+		}
+		if err := state.apply(e); err != nil {
+			return replayed, false, fmt.Errorf("line %d: %w", line, err)
+		}
+		*seq = e.Seq
+		replayed++
+	}
+}
+
+// marks are the prices of the assets valued in a snapshot, keyed by
+// FROM-TO
+type marks map[string]float64
+
+// snapshotMarks returns the prices the positions of a snapshot were valued at
+func snapshotMarks(s Snapshot) marks {
+	m := make(marks)
+	for _, p := range s.Portfolios {
+		if p.NAV == nil {
+			continue
+		}
+		for _, pv := range p.NAV.Positions {
+			if pv.Error != "" || pv.Price <= 0 {
+				continue
+			}
+			base, _, _ := strings.Cut(strings.ToUpper(pv.Symbol), "-")
+			m[base+"-"+p.NAV.Currency] = pv.Price
+		}
+	}
+	return m
+}
+
+// Rate converts directly, through the inverse of a mark or through one
+// intermediate currency
+func (m marks) Rate(from, to string) (float64, error) {
+	from, to = strings.ToUpper(from), strings.ToUpper(to)
+	if from == to {
+		return 1, nil
+	}
+	direct := func(from, to string) (float64, bool) {
+		if rate, ok := m[from+"-"+to]; ok {
+			return rate, true
+		}
+		if rate, ok := m[to+"-"+from]; ok {
+			return 1 / rate, true
+		}
+		return 0, false
+	}
+	if rate, ok := direct(from, to); ok {
+		return rate, nil
+	}
+	for pair := range m {
+		_, via, _ := strings.Cut(pair, "-")
+		first, ok := direct(from, via)
+		if !ok {
+			continue
+		}
+		if second, ok := direct(via, to); ok {
+			return first * second, nil
+		}
+	}
+	return 0, fmt.Errorf("no mark from %s to %s in the snapshot", from, to)
+}
@@ -0,0 +1,95 @@
+package pms
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestDurableStoreAsOf(t *testing.T) {
+	s, err := OpenDurable(t.TempDir(), 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+	before := time.Now()
+	time.Sleep(2 * time.Millisecond)
+	write(t, s, 0)
+	if _, err := s.Snapshot("2025-01-01", fakeRates{"BTC-USDT": 50000}); err != nil {
+		t.Fatal(err)
+	}
+	closed := time.Now()
+	time.Sleep(2 * time.Millisecond)
+	write(t, s, 1)
+	afterDay1 := time.Now()
+	time.Sleep(2 * time.Millisecond)
+	write(t, s, 2)
+
+	if _, err := s.AsOf("main", before); !errors.Is(err, ErrNotFound) {
+		t.Errorf("rebuilt a portfolio before it existed: %v", err)
+	}
+	h, err := s.AsOf("main", closed)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if h.Snapshot != "2025-01-01" || h.Replayed != 0 || h.Balances["BTC"] != 0.5 {
+		t.Errorf("as of the snapshot: %+v", h)
+	}
+	h, err = s.AsOf("main", afterDay1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Valued at the marks of the snapshot
+	if h.Replayed != 3 || len(h.Positions) != 1 || h.Positions[0].Quantity != 1 || h.Valuation == nil || h.Valuation.Value != 50000 {
+		t.Errorf("as of day 1: %+v", h)
+	}
+	if current, _ := s.Position("main", "BTC-USDT"); current.Quantity != 1.5 {
+		t.Errorf("rebuilding changed the store: %+v", current)
+	}
+
+	scoped := Scope(s, "acme").(Historian)
+	if _, err := scoped.AsOf("main", afterDay1); !errors.Is(err, ErrNotFound) {
+		t.Errorf("tenant reached a portfolio of another: %v", err)
+	}
+	if _, err := Scope(NewMemoryStore(), "acme").(Historian).AsOf("main", afterDay1); !errors.Is(err, ErrNoHistory) {
+		t.Errorf("memory store rebuilt history: %v", err)
+	}
+}
+
+func TestDurableStoreAsOfDoesNotBlockWrites(t *testing.T) {
+	s, err := OpenDurable(t.TempDir(), 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+	write(t, s, 0)
+	write(t, s, 1)
+
+	// Writes while the journal is replayed, which would deadlock under the
+	// lock, are left out of the history rebuilt
+	written := make(chan error, 1)
+	s.replaying = func() {
+		go func() {
+			_, err := s.AddCashFlows("main", []CashFlow{{ID: "late", Kind: CashFlowFunding, Asset: "usdt", Amount: -1, Timestamp: 1700000000000}})
+			written <- err
+		}()
+		select {
+		case err := <-written:
+			if err != nil {
+				t.Error(err)
+			}
+		case <-time.After(5 * time.Second):
+			t.Fatal("write waited for AsOf")
+		}
+	}
+	h, err := s.AsOf("main", time.Now().Add(time.Hour))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if h.Replayed != 7 || h.Balances["BTC"] != 1 {
+		t.Errorf("rebuilt %+v", h)
+	}
+	if flows, _ := s.CashFlows("main"); len(flows) != 3 {
+		t.Errorf("cash flows after the write: %+v", flows)
+	}
+}
@@ -0,0 +1,230 @@
+package pms
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+
+	"github.com/BullionBear/sequex/internal/model/sqx"
+)
+
+// CostMethod selects which lots a closing fill is matched against
+type CostMethod string
+
+const (
+	CostFIFO    CostMethod = "fifo"    // Oldest lots first
+	CostLIFO    CostMethod = "lifo"    // Newest lots first
+	CostAverage CostMethod = "average" // One pooled lot per symbol at the average cost
+)
+
+// ParseCostMethod parses a cost method name, defaulting to FIFO
+func ParseCostMethod(s string) (CostMethod, error) {
+	switch CostMethod(strings.ToLower(s)) {
+	case "", CostFIFO:
+		return CostFIFO, nil
+	case CostLIFO:
+		return CostLIFO, nil
+	case CostAverage, "avg":
+		return CostAverage, nil
+	}
+	return "", fmt.Errorf("%w: unknown cost method %q, expected fifo, lifo or average", ErrInvalid, s)
+}
+
+// quantityEpsilon absorbs float rounding when lots are closed exactly
+const quantityEpsilon = 1e-12
+
+// Lot is an open quantity of a symbol acquired by one fill. Short lots,
+// opened by selling more than is held, have a negative quantity.
+type Lot struct {
+	Symbol   string  `json:"symbol"`
+	FillID   string  `json:"fill_id"` // Opening fill, the first one for average cost
+	Quantity float64 `json:"quantity"`
+	Price    float64 `json:"price"` // Cost per unit including fees, net proceeds per unit for short lots
+	OpenedAt int64   `json:"opened_at"`
+}
+
+// Realization is the gain or loss of closing (part of) a lot
+type Realization struct {
+	Symbol      string  `json:"symbol"`
+	OpenFillID  string  `json:"open_fill_id"`
+	CloseFillID string  `json:"close_fill_id"`
+	Quantity    float64 `json:"quantity"` // Closed quantity, always positive
+	Proceeds    float64 `json:"proceeds"`
+	CostBasis   float64 `json:"cost_basis"`
+	PnL         float64 `json:"pnl"`
+	OpenedAt    int64   `json:"opened_at"`
+	ClosedAt    int64   `json:"closed_at"`
+}
+
+// Charge is a fee paid in another asset than those of the symbol, such as
+// BNB. It cannot count towards cost and proceeds without a rate, so it
+// is kept apart and deducted from PnL once converted.
+type Charge struct {
+	Symbol    string  `json:"symbol"`
+	FillID    string  `json:"fill_id"`
+	Asset     string  `json:"asset"`
+	Amount    float64 `json:"amount"`
+	ChargedAt int64   `json:"charged_at"`
+}
+
+// Ledger tracks the tax lots of a portfolio by replaying its fills in time
+// order. Fees count towards cost and proceeds when charged in the quote
+// asset or without an asset, and are paid out of the quantity traded when
+// charged in the base asset; fees in other assets are recorded as charges.
+type Ledger struct {
+	method   CostMethod
+	lots     map[string][]Lot // Keyed by symbol, in opening order
+	realized []Realization
+	charges  []Charge
+}
+
+// NewLedger creates an empty ledger
+func NewLedger(method CostMethod) *Ledger {
+	return &Ledger{method: method, lots: make(map[string][]Lot)}
+}
+
+// BuildLedger replays a fill history
+func BuildLedger(method CostMethod, fills []Fill) *Ledger {
+	sorted := append([]Fill{}, fills...)
+	sort.SliceStable(sorted, func(i, j int) bool { return sorted[i].Timestamp < sorted[j].Timestamp })
+	l := NewLedger(method)
+	for _, f := range sorted {
+		l.Apply(f)
+	}
+	return l
+}
+
+// Apply books a fill: it closes opposite lots according to the cost method
+// and opens a lot with the remainder
+func (l *Ledger) Apply(f Fill) {
+	f = f.normalize()
+	sign := 1.0
+	if f.Side == sqx.SideSell.String() {
+		sign = -1
+	}
+	fee := quoteFee(f)
+	quantity, price := f.Quantity, f.Price
+	switch {
+	case fee != 0 || f.Fee == 0:
+	case baseFee(f):
+		// A buy receives the quantity less the fee, a sell gives up the
+		// quantity plus the fee, for the same cost or proceeds
+		quantity = f.Quantity - sign*f.Fee
+		if quantity <= quantityEpsilon {
+			return
+		}
+		price = f.Price * f.Quantity / quantity
+	default:
+		l.charges = append(l.charges, Charge{
+			Symbol:    f.Symbol,
+			FillID:    f.ID,
+			Asset:     strings.ToUpper(f.FeeAsset),
+			Amount:    f.Fee,
+			ChargedAt: f.Timestamp,
+		})
+	}
+	remaining := quantity
+
+	lots := l.lots[f.Symbol]
+	for remaining > quantityEpsilon && len(lots) > 0 {
+		i := 0
+		if l.method == CostLIFO {
+			i = len(lots) - 1
+		}
+		lot := &lots[i]
+		if lot.Quantity*sign > 0 {
+			break // Same direction, nothing to close
+		}
+		closed := math.Min(remaining, math.Abs(lot.Quantity))
+		closeFee := fee * closed / quantity
+		r := Realization{
+			Symbol:      f.Symbol,
+			OpenFillID:  lot.FillID,
+			CloseFillID: f.ID,
+			Quantity:    closed,
+			OpenedAt:    lot.OpenedAt,
+			ClosedAt:    f.Timestamp,
+		}
+		if sign < 0 {
+			// Selling a long lot
+			r.Proceeds = price*closed - closeFee
+			r.CostBasis = lot.Price * closed
+		} else {
+			// Buying back a short lot
+			r.Proceeds = lot.Price * closed
+			r.CostBasis = price*closed + closeFee
+		}
+		r.PnL = r.Proceeds - r.CostBasis
+		l.realized = append(l.realized, r)
+
+		remaining -= closed
+		lot.Quantity += sign * closed
+		if math.Abs(lot.Quantity) <= quantityEpsilon {
+			lots = append(lots[:i], lots[i+1:]...)
+		}
+	}
+
+	if remaining > quantityEpsilon {
+		openFee := fee * remaining / quantity
+		lot := Lot{
+			Symbol:   f.Symbol,
+			FillID:   f.ID,
+			Quantity: sign * remaining,
+			Price:    (price*remaining + sign*openFee) / remaining,
+			OpenedAt: f.Timestamp,
+		}
+		if l.method == CostAverage && len(lots) > 0 {
+			pooled := &lots[0]
+			total := pooled.Quantity + lot.Quantity
+			pooled.Price = (pooled.Price*pooled.Quantity + lot.Price*lot.Quantity) / total
+			pooled.Quantity = total
+		} else {
+			lots = append(lots, lot)
+		}
+	}
+	l.lots[f.Symbol] = lots
+}
+
+// quoteFee returns the fee of a fill in the quote asset
+func quoteFee(f Fill) float64 {
+	if f.FeeAsset == "" {
+		return f.Fee
+	}
+	symbol, err := sqx.NewSymbolFromStr(f.Symbol)
+	if err != nil || !strings.EqualFold(f.FeeAsset, symbol.Quote) {
+		return 0
+	}
+	return f.Fee
+}
+
+// baseFee reports whether the fee of a fill is charged in the base asset
+func baseFee(f Fill) bool {
+	symbol, err := sqx.NewSymbolFromStr(f.Symbol)
+	return err == nil && f.FeeAsset != "" && strings.EqualFold(f.FeeAsset, symbol.Base)
+}
+
+// Lots returns the open lots ordered by symbol and opening time
+func (l *Ledger) Lots() []Lot {
+	symbols := make([]string, 0, len(l.lots))
+	for symbol := range l.lots {
+		symbols = append(symbols, symbol)
+	}
+	sort.Strings(symbols)
+	list := []Lot{}
+	for _, symbol := range symbols {
+		list = append(list, l.lots[symbol]...)
+	}
+	return list
+}
+
+// Realized returns the realized gains in the order they were booked
+func (l *Ledger) Realized() []Realization {
+	return append([]Realization{}, l.realized...)
+}
+
+// Charges returns the fees kept out of cost and proceeds in the order they
+// were booked
+func (l *Ledger) Charges() []Charge {
+	return append([]Charge{}, l.charges...)
+}
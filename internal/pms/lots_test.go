@@ -0,0 +1,182 @@
+package pms
+
+import (
+	"math"
+	"testing"
+	"time"
+)
+
+func ms(year int, month time.Month, day int) int64 {
+	return time.Date(year, month, day, 12, 0, 0, 0, time.UTC).UnixMilli()
+}
+
+func TestLedgerCostMethods(t *testing.T) {
+	fills := []Fill{
+		{ID: "b2", Symbol: "BTC-USDT", Side: "BUY", Quantity: 1, Price: 200, Timestamp: 2},
+		{ID: "b1", Symbol: "BTC-USDT", Side: "BUY", Quantity: 1, Price: 100, Timestamp: 1},
+		{ID: "s1", Symbol: "BTC-USDT", Side: "SELL", Quantity: 1, Price: 300, Timestamp: 3},
+	}
+	tests := []struct {
+		method   CostMethod
+		pnl      float64
+		lotPrice float64
+	}{
+		{CostFIFO, 200, 200},
+		{CostLIFO, 100, 100},
+		{CostAverage, 150, 150},
+	}
+	for _, tt := range tests {
+		l := BuildLedger(tt.method, fills)
+		realized := l.Realized()
+		if len(realized) != 1 || realized[0].PnL != tt.pnl {
+			t.Errorf("%s: realized = %+v, want pnl %v", tt.method, realized, tt.pnl)
+		}
+		lots := l.Lots()
+		if len(lots) != 1 || lots[0].Quantity != 1 || lots[0].Price != tt.lotPrice {
+			t.Errorf("%s: lots = %+v, want one lot at %v", tt.method, lots, tt.lotPrice)
+		}
+	}
+}
+
+func TestLedgerPartialClosesAndFees(t *testing.T) {
+	l := NewLedger(CostFIFO)
+	l.Apply(Fill{ID: "b1", Symbol: "ETH-USDT", Side: "BUY", Quantity: 2, Price: 100, Fee: 2, FeeAsset: "USDT", Timestamp: 1})
+	l.Apply(Fill{ID: "b2", Symbol: "ETH-USDT", Side: "BUY", Quantity: 1, Price: 130, Fee: 0.001, FeeAsset: "BNB", Timestamp: 2})
+	// Closes b1 and half of b2
+	l.Apply(Fill{ID: "s1", Symbol: "ETH-USDT", Side: "SELL", Quantity: 2.5, Price: 150, Fee: 2.5, FeeAsset: "USDT", Timestamp: 3})
+
+	realized := l.Realized()
+	if len(realized) != 2 {
+		t.Fatalf("realized = %+v", realized)
+	}
+	// b1: proceeds 2*150 - 2 fee, cost 2*100 + 2 fee; the BNB fee of b2 is kept as a charge
+	if r := realized[0]; r.OpenFillID != "b1" || r.Proceeds != 298 || r.CostBasis != 202 || r.PnL != 96 {
+		t.Errorf("b1 = %+v", r)
+	}
+	if r := realized[1]; r.OpenFillID != "b2" || r.Quantity != 0.5 || r.PnL != 0.5*150-0.5-0.5*130 {
+		t.Errorf("b2 = %+v", r)
+	}
+	if lots := l.Lots(); len(lots) != 1 || lots[0].FillID != "b2" || lots[0].Quantity != 0.5 {
+		t.Errorf("lots = %+v", lots)
+	}
+	if charges := l.Charges(); len(charges) != 1 || charges[0].FillID != "b2" || charges[0].Asset != "BNB" || charges[0].Amount != 0.001 {
+		t.Errorf("charges = %+v", charges)
+	}
+}
+
+func TestLedgerBaseAssetFees(t *testing.T) {
+	l := NewLedger(CostFIFO)
+	// Receives 0.99 ETH for 200 USDT
+	l.Apply(Fill{ID: "b1", Symbol: "ETH-USDT", Side: "BUY", Quantity: 1, Price: 200, Fee: 0.01, FeeAsset: "eth", Timestamp: 1})
+	if lots := l.Lots(); len(lots) != 1 || lots[0].Quantity != 0.99 || math.Abs(lots[0].Price*0.99-200) > 1e-9 {
+		t.Fatalf("lots = %+v, want 0.99 ETH costing 200", lots)
+	}
+	// Gives up 0.5 ETH for 0.495 sold
+	l.Apply(Fill{ID: "s1", Symbol: "ETH-USDT", Side: "SELL", Quantity: 0.495, Price: 300, Fee: 0.005, FeeAsset: "ETH", Timestamp: 2})
+
+	realized := l.Realized()
+	if len(realized) != 1 || realized[0].Quantity != 0.5 || math.Abs(realized[0].Proceeds-148.5) > 1e-9 || math.Abs(realized[0].CostBasis-0.5*200/0.99) > 1e-9 {
+		t.Errorf("realized = %+v, want 0.5 ETH closed for 148.5", realized)
+	}
+	if lots := l.Lots(); len(lots) != 1 || math.Abs(lots[0].Quantity-0.49) > 1e-9 {
+		t.Errorf("lots = %+v, want 0.49 ETH left", lots)
+	}
+	if charges := l.Charges(); len(charges) != 0 {
+		t.Errorf("charges = %+v, want the base fees paid out of the quantities", charges)
+	}
+}
+
+func TestLedgerShortLots(t *testing.T) {
+	l := NewLedger(CostFIFO)
+	l.Apply(Fill{ID: "b1", Symbol: "BTC-USDT", Side: "BUY", Quantity: 1, Price: 100, Timestamp: 1})
+	// Sells through the long lot into a short one
+	l.Apply(Fill{ID: "s1", Symbol: "BTC-USDT", Side: "SELL", Quantity: 3, Price: 120, Timestamp: 2})
+	l.Apply(Fill{ID: "b2", Symbol: "BTC-USDT", Side: "BUY", Quantity: 2, Price: 90, Timestamp: 3})
+
+	realized := l.Realized()
+	if len(realized) != 2 || realized[0].PnL != 20 || realized[1].PnL != 60 {
+		t.Errorf("realized = %+v", realized)
+	}
+	if lots := l.Lots(); len(lots) != 0 {
+		t.Errorf("lots = %+v", lots)
+	}
+}
+
+func TestSummarize(t *testing.T) {
+	realized := []Realization{
+		{Symbol: "BTC-USDT", PnL: 100, Proceeds: 300, CostBasis: 200, ClosedAt: ms(2024, time.February, 1)},
+		{Symbol: "ETH-USDT", PnL: -40, Proceeds: 60, CostBasis: 100, ClosedAt: ms(2024, time.March, 31)},
+		{Symbol: "BTC-USDT", PnL: 10, Proceeds: 20, CostBasis: 10, ClosedAt: ms(2024, time.July, 4)},
+		{Symbol: "BTC-USDT", PnL: 999, ClosedAt: ms(2025, time.January, 2)},
+	}
+	charges := []Charge{
+		{Symbol: "BTC-USDT", Asset: "USDT", Amount: 5, ChargedAt: ms(2024, time.January, 15)},
+		{Symbol: "BTC-USDT", Asset: "USDT", Amount: 2, ChargedAt: ms(2024, time.May, 1)}, // Quarter without realizations
+	}
+	flows := []CashFlow{
+		{Kind: CashFlowFunding, Symbol: "BTC-USDT", Asset: "USDT", Amount: -3, Timestamp: ms(2024, time.February, 8)},
+		{Kind: CashFlowFunding, Symbol: "BTC-USDT", Asset: "USDT", Amount: 1, Timestamp: ms(2024, time.February, 9)},
+		{Kind: CashFlowInterest, Symbol: "ETH-USDT", Asset: "USDT", Amount: -0.5, Timestamp: ms(2024, time.July, 1)},
+		{Kind: CashFlowInterest, Asset: "USDT", Amount: -100, Timestamp: ms(2025, time.January, 1)}, // Excluded
+	}
+	report := Summarize(CostFIFO, PeriodQuarter, realized, charges, flows, time.Time{}, time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC))
+
+	if len(report.Periods) != 3 {
+		t.Fatalf("periods = %+v", report.Periods)
+	}
+	q1 := report.Periods[0]
+	if q1.Period != "2024-Q1" || q1.PnL != 60 || q1.Gains != 100 || q1.Losses != -40 || q1.BySymbol["ETH-USDT"] != -40 || q1.Fees != 5 || q1.Funding != -2 || q1.NetPnL != 53 {
+		t.Errorf("q1 = %+v", q1)
+	}
+	if q2 := report.Periods[1]; q2.Period != "2024-Q2" || q2.PnL != 0 || q2.NetPnL != -2 {
+		t.Errorf("q2 = %+v", q2)
+	}
+	if q3 := report.Periods[2]; q3.Period != "2024-Q3" || !q3.End.Equal(time.Date(2024, 10, 1, 0, 0, 0, 0, time.UTC)) || q3.Interest != -0.5 || q3.NetPnL != 9.5 {
+		t.Errorf("q3 = %+v", q3)
+	}
+	if math.Abs(report.Total.PnL-70) > 1e-9 || math.Abs(report.Total.NetPnL-60.5) > 1e-9 || report.Total.BySymbol["BTC-USDT"] != 110 {
+		t.Errorf("total = %+v", report.Total)
+	}
+}
+
+// fakeHistory holds rates by UTC day
+type fakeHistory map[string]fakeRates
+
+func (f fakeHistory) RateAt(from, to string, at time.Time) (float64, error) {
+	return f[at.UTC().Format(time.DateOnly)].Rate(from, to)
+}
+
+func TestSummarizeFees(t *testing.T) {
+	fills := []Fill{
+		{ID: "1", Symbol: "BTC-USDT", Fee: 1.5, FeeAsset: "USDT", Timestamp: ms(2024, time.June, 1)},
+		{ID: "2", Symbol: "BTC-USDT", Fee: 0.01, FeeAsset: "bnb", Timestamp: ms(2024, time.June, 1)},
+		{ID: "3", Symbol: "ETH-BTC", Fee: 0.0001, Timestamp: ms(2024, time.June, 2)}, // In the quote asset
+		{ID: "4", Symbol: "ETH-USDT", Timestamp: ms(2024, time.June, 2)},
+		{ID: "6", Symbol: "ETH-USDT", Price: 3000, Fee: 0.001, FeeAsset: "ETH", Timestamp: ms(2024, time.June, 2)}, // At the price of the fill
+		{ID: "5", Symbol: "ETH-USDT", Fee: 9, FeeAsset: "USDT", Timestamp: ms(2024, time.June, 3)},
+	}
+	// Each fee is converted at the rates of its day
+	rates := fakeHistory{
+		"2024-06-01": {"BNB-USDT": 600, "BTC-USDT": 50000},
+		"2024-06-02": {"BNB-USDT": 700, "BTC-USDT": 60000},
+	}
+	report, err := SummarizeFees(fills, "USDT", rates, time.Time{}, time.Date(2024, 6, 3, 0, 0, 0, 0, time.UTC))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(report.Days) != 2 {
+		t.Fatalf("days = %+v", report.Days)
+	}
+	if d := report.Days[0]; d.Date != "2024-06-01" || d.Fills != 2 || d.ByAsset["BNB"] != 0.01 || math.Abs(d.Total-7.5) > 1e-9 {
+		t.Errorf("june 1 = %+v", d)
+	}
+	if d := report.Days[1]; d.Fills != 3 || d.ByAsset["BTC"] != 0.0001 || d.ByAsset["ETH"] != 0.001 || math.Abs(d.Total-9) > 1e-9 {
+		t.Errorf("june 2 = %+v", d)
+	}
+	if total := report.Total; total.Fills != 5 || math.Abs(total.Total-16.5) > 1e-9 {
+		t.Errorf("total = %+v", total)
+	}
+	if _, err := SummarizeFees(fills, "EUR", rates, time.Time{}, time.Time{}); err == nil {
+		t.Error("missing rate not reported")
+	}
+}
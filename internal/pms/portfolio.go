@@ -0,0 +1,120 @@
+// Package pms manages portfolios and their positions. Every resource carries
+// a version that is incremented on each update, and writes must state the
+// version they were based on so concurrent updates cannot overwrite each
+// other silently.
+package pms
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/BullionBear/sequex/internal/model/sqx"
+	"github.com/BullionBear/sequex/pkg/eventbus"
+)
+
+var (
+	// ErrNotFound is returned when a portfolio or position does not exist
+	ErrNotFound = errors.New("not found")
+	// ErrVersionConflict is returned when a write is based on a stale version
+	ErrVersionConflict = errors.New("version conflict")
+	// ErrExists is returned when adding a fill whose id is already recorded
+	ErrExists = errors.New("already exists")
+	// ErrInvalid is returned when a write fails validation
+	ErrInvalid = errors.New("invalid")
+)
+
+// DefaultBaseCurrency is the currency portfolios are valued in unless set
+const DefaultBaseCurrency = "USDT"
+
+// AnyVersion skips the version check of a write. It is meant for internal
+// writers that own the resource, never for API clients.
+const AnyVersion int64 = -1
+
+// Portfolio is a named set of positions
+type Portfolio struct {
+	ID           string     `json:"id"`
+	Name         string     `json:"name"`
+	BaseCurrency string     `json:"base_currency"` // Currency of valuations and reports, DefaultBaseCurrency when empty
+	CostMethod   CostMethod `json:"cost_method"`   // Default lot matching of realized PnL, FIFO when empty
+	Version      int64      `json:"version"`       // Incremented by every update, starts at 1
+	UpdatedAt    time.Time  `json:"updated_at"`
+}
+
+// Validate checks the fields set by clients
+func (p Portfolio) Validate() error {
+	if err := ValidateID(p.ID); err != nil {
+		return err
+	}
+	if p.Name == "" {
+		return fmt.Errorf("%w: portfolio name cannot be empty", ErrInvalid)
+	}
+	if _, err := ParseCostMethod(string(p.CostMethod)); err != nil {
+		return err
+	}
+	for _, r := range p.BaseCurrency {
+		if (r < 'A' || r > 'Z') && (r < 'a' || r > 'z') && (r < '0' || r > '9') {
+			return fmt.Errorf("%w: base_currency %q must be alphanumeric", ErrInvalid, p.BaseCurrency)
+		}
+	}
+	return nil
+}
+
+// Currency returns the base currency of the portfolio
+func (p Portfolio) Currency() string {
+	if p.BaseCurrency == "" {
+		return DefaultBaseCurrency
+	}
+	return strings.ToUpper(p.BaseCurrency)
+}
+
+// Position is the holding of one symbol in a portfolio
+type Position struct {
+	PortfolioID string    `json:"portfolio_id"`
+	Symbol      string    `json:"symbol"` // BASE-QUOTE, e.g. BTC-USDT, or an option contract, e.g. BTC-USDT-250328-90000-C
+	Quantity    float64   `json:"quantity"`
+	AvgPrice    float64   `json:"avg_price"`
+	Version     int64     `json:"version"` // Incremented by every update, starts at 1
+	UpdatedAt   time.Time `json:"updated_at"`
+}
+
+// Validate checks the fields set by clients
+func (p Position) Validate() error {
+	if err := ValidateID(p.PortfolioID); err != nil {
+		return err
+	}
+	if _, err := NormalizeSymbol(p.Symbol); err != nil {
+		return fmt.Errorf("%w: %v", ErrInvalid, err)
+	}
+	if p.AvgPrice < 0 {
+		return fmt.Errorf("%w: avg_price cannot be negative", ErrInvalid)
+	}
+	return nil
+}
+
+// ValidateID checks a portfolio id, which follows the subject token rules so
+// it can be used in event subjects
+func ValidateID(id string) error {
+	if err := eventbus.ValidateToken(id); err != nil {
+		return fmt.Errorf("%w: portfolio id: %v", ErrInvalid, err)
+	}
+	return nil
+}
+
+// NormalizeSymbol returns the canonical BASE-QUOTE form of a symbol, or the
+// canonical name of an option contract such as BTC-USDT-250328-90000-C
+func NormalizeSymbol(symbol string) (string, error) {
+	if contract, err := sqx.NewOptionContractFromStr(symbol); err == nil {
+		return contract.String(), nil
+	}
+	s, err := sqx.NewSymbolFromStr(symbol)
+	if err != nil {
+		return "", err
+	}
+	return s.String(), nil
+}
+
+func conflict(resource string, expected, current int64) error {
+	return fmt.Errorf("%w: %s is at version %d, not %d", ErrVersionConflict, resource, current, expected)
+}
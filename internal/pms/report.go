@@ -0,0 +1,153 @@
+package pms
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Period is the length of the intervals realized gains are summarized over
+type Period string
+
+const (
+	PeriodMonth   Period = "month"
+	PeriodQuarter Period = "quarter"
+	PeriodYear    Period = "year"
+)
+
+// ParsePeriod parses a period name, defaulting to a year
+func ParsePeriod(s string) (Period, error) {
+	switch Period(strings.ToLower(s)) {
+	case "", PeriodYear:
+		return PeriodYear, nil
+	case PeriodQuarter:
+		return PeriodQuarter, nil
+	case PeriodMonth:
+		return PeriodMonth, nil
+	}
+	return "", fmt.Errorf("%w: unknown period %q, expected month, quarter or year", ErrInvalid, s)
+}
+
+// bounds returns the label and interval of the period containing t
+func (p Period) bounds(t time.Time) (string, time.Time, time.Time) {
+	year := t.Year()
+	switch p {
+	case PeriodMonth:
+		start := time.Date(year, t.Month(), 1, 0, 0, 0, 0, t.Location())
+		return start.Format("2006-01"), start, start.AddDate(0, 1, 0)
+	case PeriodQuarter:
+		quarter := (int(t.Month()) - 1) / 3
+		start := time.Date(year, time.Month(quarter*3+1), 1, 0, 0, 0, 0, t.Location())
+		return fmt.Sprintf("%d-Q%d", year, quarter+1), start, start.AddDate(0, 3, 0)
+	default:
+		start := time.Date(year, 1, 1, 0, 0, 0, 0, t.Location())
+		return fmt.Sprintf("%d", year), start, start.AddDate(1, 0, 0)
+	}
+}
+
+// RealizedSummary totals the realized gains of one period
+type RealizedSummary struct {
+	Period    string             `json:"period"` // e.g. 2024, 2024-Q2 or 2024-06
+	Start     time.Time          `json:"start"`
+	End       time.Time          `json:"end"` // Exclusive
+	Proceeds  float64            `json:"proceeds"`
+	CostBasis float64            `json:"cost_basis"`
+	Gains     float64            `json:"gains"`     // Sum of positive realizations
+	Losses    float64            `json:"losses"`    // Sum of negative realizations, negative
+	PnL       float64            `json:"pnl"`       // Net of the fees charged in the quote asset
+	Fees      float64            `json:"fees"`      // Fees charged in other assets, see Charge
+	Funding   float64            `json:"funding"`   // Funding received, negative when paid
+	Interest  float64            `json:"interest"`  // Margin interest received, negative when paid
+	NetPnL    float64            `json:"net_pnl"`   // PnL less Fees, plus Funding and Interest
+	BySymbol  map[string]float64 `json:"by_symbol"` // PnL of each symbol
+}
+
+// RealizedReport summarizes realized gains per period for tax purposes
+type RealizedReport struct {
+	Method   CostMethod        `json:"method"`
+	Period   Period            `json:"period"`
+	Currency string            `json:"currency"` // Currency of the amounts, set by the caller converting them
+	Periods  []RealizedSummary `json:"periods"`
+	Total    RealizedSummary   `json:"total"`
+}
+
+// Summarize groups realizations closed, and charges and cash flows paid
+// within [from, to) by period in UTC. Charges and cash flows must be in the
+// currency of the realizations. Zero bounds are open.
+func Summarize(method CostMethod, period Period, realized []Realization, charges []Charge, flows []CashFlow, from, to time.Time) RealizedReport {
+	report := RealizedReport{Method: method, Period: period, Periods: []RealizedSummary{}}
+	report.Total.BySymbol = make(map[string]float64)
+	byPeriod := make(map[string]*RealizedSummary)
+	summary := func(at int64) *RealizedSummary {
+		t := time.UnixMilli(at).UTC()
+		if (!from.IsZero() && t.Before(from)) || (!to.IsZero() && !t.Before(to)) {
+			return nil
+		}
+		label, start, end := period.bounds(t)
+		s, ok := byPeriod[label]
+		if !ok {
+			s = &RealizedSummary{Period: label, Start: start, End: end, BySymbol: make(map[string]float64)}
+			byPeriod[label] = s
+		}
+		return s
+	}
+
+	for _, r := range realized {
+		if s := summary(r.ClosedAt); s != nil {
+			s.add(r)
+			report.Total.add(r)
+		}
+	}
+	for _, c := range charges {
+		if s := summary(c.ChargedAt); s != nil {
+			s.charge(c)
+			report.Total.charge(c)
+		}
+	}
+	for _, c := range flows {
+		if s := summary(c.Timestamp); s != nil {
+			s.flow(c)
+			report.Total.flow(c)
+		}
+	}
+
+	for _, summary := range byPeriod {
+		report.Periods = append(report.Periods, *summary)
+	}
+	sort.Slice(report.Periods, func(i, j int) bool { return report.Periods[i].Start.Before(report.Periods[j].Start) })
+	if n := len(report.Periods); n > 0 {
+		report.Total.Period = "total"
+		report.Total.Start = report.Periods[0].Start
+		report.Total.End = report.Periods[n-1].End
+	}
+	return report
+}
+
+func (s *RealizedSummary) add(r Realization) {
+	s.Proceeds += r.Proceeds
+	s.CostBasis += r.CostBasis
+	s.PnL += r.PnL
+	s.NetPnL += r.PnL
+	if r.PnL >= 0 {
+		s.Gains += r.PnL
+	} else {
+		s.Losses += r.PnL
+	}
+	s.BySymbol[r.Symbol] += r.PnL
+}
+
+func (s *RealizedSummary) charge(c Charge) {
+	s.Fees += c.Amount
+	s.NetPnL -= c.Amount
+}
+
+func (s *RealizedSummary) flow(c CashFlow) {
+	switch c.Kind {
+	case CashFlowFunding:
+		s.Funding += c.Amount
+	case CashFlowInterest:
+		s.Interest += c.Amount
+	}
+	s.NetPnL += c.Amount
+}
@@ -0,0 +1,162 @@
+package pms
+
+import (
+	"strings"
+	"time"
+)
+
+// scoped is a Store holding only the portfolios of one tenant
+type scoped struct {
+	store  Store
+	prefix string
+}
+
+// Scope returns the view of store of a tenant. Its portfolios are stored as
+// <tenant>_<id>, so tenants may use the same ids, and it neither lists nor
+// reaches the portfolios of other tenants. Resources are returned with the
+// ids of the tenant.
+func Scope(store Store, tenant string) Store {
+	return &scoped{store: store, prefix: tenant + "_"}
+}
+
+// id returns the stored id of a portfolio of the tenant, validating the id
+// first so an empty id cannot name the prefix alone
+func (s *scoped) id(id string) (string, error) {
+	if err := ValidateID(id); err != nil {
+		return "", err
+	}
+	return s.prefix + id, nil
+}
+
+func (s *scoped) strip(id string) string {
+	return strings.TrimPrefix(id, s.prefix)
+}
+
+func (s *scoped) Portfolios() ([]Portfolio, error) {
+	all, err := s.store.Portfolios()
+	if err != nil {
+		return nil, err
+	}
+	list := make([]Portfolio, 0, len(all))
+	for _, p := range all {
+		if strings.HasPrefix(p.ID, s.prefix) {
+			p.ID = s.strip(p.ID)
+			list = append(list, p)
+		}
+	}
+	return list, nil
+}
+
+func (s *scoped) Portfolio(id string) (Portfolio, error) {
+	stored, err := s.id(id)
+	if err != nil {
+		return Portfolio{}, err
+	}
+	p, err := s.store.Portfolio(stored)
+	p.ID = s.strip(p.ID)
+	return p, err
+}
+
+func (s *scoped) PutPortfolio(p Portfolio, expected int64) (Portfolio, error) {
+	stored, err := s.id(p.ID)
+	if err != nil {
+		return p, err
+	}
+	p.ID = stored
+	p, err = s.store.PutPortfolio(p, expected)
+	p.ID = s.strip(p.ID)
+	return p, err
+}
+
+func (s *scoped) Positions(portfolioID string) ([]Position, error) {
+	stored, err := s.id(portfolioID)
+	if err != nil {
+		return nil, err
+	}
+	positions, err := s.store.Positions(stored)
+	for i := range positions {
+		positions[i].PortfolioID = portfolioID
+	}
+	return positions, err
+}
+
+func (s *scoped) Position(portfolioID, symbol string) (Position, error) {
+	stored, err := s.id(portfolioID)
+	if err != nil {
+		return Position{}, err
+	}
+	p, err := s.store.Position(stored, symbol)
+	p.PortfolioID = s.strip(p.PortfolioID)
+	return p, err
+}
+
+func (s *scoped) PutPosition(p Position, expected int64) (Position, error) {
+	stored, err := s.id(p.PortfolioID)
+	if err != nil {
+		return p, err
+	}
+	p.PortfolioID = stored
+	p, err = s.store.PutPosition(p, expected)
+	p.PortfolioID = s.strip(p.PortfolioID)
+	return p, err
+}
+
+func (s *scoped) Fills(portfolioID string) ([]Fill, error) {
+	stored, err := s.id(portfolioID)
+	if err != nil {
+		return nil, err
+	}
+	fills, err := s.store.Fills(stored)
+	for i := range fills {
+		fills[i].PortfolioID = portfolioID
+	}
+	return fills, err
+}
+
+func (s *scoped) AddFills(portfolioID string, fills []Fill) error {
+	stored, err := s.id(portfolioID)
+	if err != nil {
+		return err
+	}
+	return s.store.AddFills(stored, fills)
+}
+
+func (s *scoped) CashFlows(portfolioID string) ([]CashFlow, error) {
+	stored, err := s.id(portfolioID)
+	if err != nil {
+		return nil, err
+	}
+	flows, err := s.store.CashFlows(stored)
+	for i := range flows {
+		flows[i].PortfolioID = portfolioID
+	}
+	return flows, err
+}
+
+func (s *scoped) AddCashFlows(portfolioID string, flows []CashFlow) (int, error) {
+	stored, err := s.id(portfolioID)
+	if err != nil {
+		return 0, err
+	}
+	return s.store.AddCashFlows(stored, flows)
+}
+
+func (s *scoped) AsOf(portfolioID string, at time.Time) (Historical, error) {
+	historian, ok := s.store.(Historian)
+	if !ok {
+		return Historical{}, ErrNoHistory
+	}
+	stored, err := s.id(portfolioID)
+	if err != nil {
+		return Historical{}, err
+	}
+	h, err := historian.AsOf(stored, at)
+	h.Portfolio.ID = s.strip(h.Portfolio.ID)
+	for i := range h.Positions {
+		h.Positions[i].PortfolioID = portfolioID
+	}
+	if h.Valuation != nil {
+		h.Valuation.PortfolioID = portfolioID
+	}
+	return h, err
+}
@@ -0,0 +1,50 @@
+package pms
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestScopeIsolatesTenants(t *testing.T) {
+	s := NewMemoryStore()
+	acme, globex := Scope(s, "acme"), Scope(s, "globex")
+
+	for _, store := range []Store{acme, globex} {
+		if p, err := store.PutPortfolio(Portfolio{ID: "main", Name: "Main"}, 0); err != nil || p.ID != "main" {
+			t.Fatalf("create: %+v, %v", p, err)
+		}
+	}
+	if _, err := acme.PutPosition(Position{PortfolioID: "main", Symbol: "BTC-USDT", Quantity: 1}, 0); err != nil {
+		t.Fatal(err)
+	}
+	if err := acme.AddFills("main", []Fill{{ID: "f1", Symbol: "BTC-USDT", Side: "buy", Quantity: 1, Price: 40000, Timestamp: 1}}); err != nil {
+		t.Fatal(err)
+	}
+
+	portfolios, err := acme.Portfolios()
+	if err != nil || len(portfolios) != 1 || portfolios[0].ID != "main" {
+		t.Errorf("portfolios of acme: %+v, %v", portfolios, err)
+	}
+	if positions, _ := acme.Positions("main"); len(positions) != 1 || positions[0].PortfolioID != "main" {
+		t.Errorf("positions of acme: %+v", positions)
+	}
+	if fills, _ := acme.Fills("main"); len(fills) != 1 || fills[0].PortfolioID != "main" {
+		t.Errorf("fills of acme: %+v", fills)
+	}
+	if positions, _ := globex.Positions("main"); len(positions) != 0 {
+		t.Errorf("globex sees the positions of acme: %+v", positions)
+	}
+	if fills, _ := globex.Fills("main"); len(fills) != 0 {
+		t.Errorf("globex sees the fills of acme: %+v", fills)
+	}
+	// The stored id of another tenant is not an id of this one
+	if _, err := globex.Portfolio("acme_main"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("reached another tenant: %v", err)
+	}
+	if _, err := acme.Portfolio(""); err == nil {
+		t.Error("empty id accepted")
+	}
+	if all, _ := s.Portfolios(); len(all) != 2 {
+		t.Errorf("stored portfolios %+v", all)
+	}
+}
@@ -0,0 +1,253 @@
+package pms
+
+import (
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+)
+
+// Store persists portfolios and positions. Writes take the version the
+// caller last read: 0 creates the resource and fails if it exists, any other
+// value must match the current version, and AnyVersion skips the check.
+// Successful writes return the resource with its new version.
+type Store interface {
+	Portfolios() ([]Portfolio, error)
+	Portfolio(id string) (Portfolio, error)
+	PutPortfolio(p Portfolio, expected int64) (Portfolio, error)
+
+	Positions(portfolioID string) ([]Position, error)
+	Position(portfolioID, symbol string) (Position, error)
+	PutPosition(p Position, expected int64) (Position, error)
+
+	// Fills returns the fill history of a portfolio ordered by time
+	Fills(portfolioID string) ([]Fill, error)
+	// AddFills appends fills to the history of a portfolio. Nothing is
+	// added if the id of any fill already exists.
+	AddFills(portfolioID string, fills []Fill) error
+
+	// CashFlows returns the funding and interest payments of a portfolio
+	// ordered by time
+	CashFlows(portfolioID string) ([]CashFlow, error)
+	// AddCashFlows appends payments to the history of a portfolio and
+	// returns how many were added. Payments whose id already exists are
+	// skipped, so overlapping pulls of an exchange history book them once.
+	AddCashFlows(portfolioID string, flows []CashFlow) (int, error)
+}
+
+// MemoryStore is a Store kept in memory
+type MemoryStore struct {
+	mu         sync.RWMutex
+	portfolios map[string]Portfolio
+	positions  map[string]map[string]Position // Keyed by portfolio id, then symbol
+	fills      map[string][]Fill
+	cashFlows  map[string][]CashFlow
+	now        func() time.Time
+}
+
+// NewMemoryStore creates an empty store
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{
+		portfolios: make(map[string]Portfolio),
+		positions:  make(map[string]map[string]Position),
+		fills:      make(map[string][]Fill),
+		cashFlows:  make(map[string][]CashFlow),
+		now:        time.Now,
+	}
+}
+
+// Portfolios returns every portfolio ordered by id
+func (s *MemoryStore) Portfolios() ([]Portfolio, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	list := make([]Portfolio, 0, len(s.portfolios))
+	for _, p := range s.portfolios {
+		list = append(list, p)
+	}
+	sort.Slice(list, func(i, j int) bool { return list[i].ID < list[j].ID })
+	return list, nil
+}
+
+// Portfolio returns one portfolio
+func (s *MemoryStore) Portfolio(id string) (Portfolio, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	p, ok := s.portfolios[id]
+	if !ok {
+		return p, fmt.Errorf("portfolio %s: %w", id, ErrNotFound)
+	}
+	return p, nil
+}
+
+// PutPortfolio creates or updates a portfolio
+func (s *MemoryStore) PutPortfolio(p Portfolio, expected int64) (Portfolio, error) {
+	if err := p.Validate(); err != nil {
+		return p, err
+	}
+	p.CostMethod, _ = ParseCostMethod(string(p.CostMethod))
+	p.BaseCurrency = p.Currency()
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	current, ok := s.portfolios[p.ID]
+	if err := checkVersion("portfolio "+p.ID, ok, current.Version, expected); err != nil {
+		return current, err
+	}
+	p.Version = current.Version + 1
+	p.UpdatedAt = s.now()
+	s.portfolios[p.ID] = p
+	return p, nil
+}
+
+// Positions returns the positions of a portfolio ordered by symbol
+func (s *MemoryStore) Positions(portfolioID string) ([]Position, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	if _, ok := s.portfolios[portfolioID]; !ok {
+		return nil, fmt.Errorf("portfolio %s: %w", portfolioID, ErrNotFound)
+	}
+	list := make([]Position, 0, len(s.positions[portfolioID]))
+	for _, p := range s.positions[portfolioID] {
+		list = append(list, p)
+	}
+	sort.Slice(list, func(i, j int) bool { return list[i].Symbol < list[j].Symbol })
+	return list, nil
+}
+
+// Position returns one position of a portfolio
+func (s *MemoryStore) Position(portfolioID, symbol string) (Position, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	if _, ok := s.portfolios[portfolioID]; !ok {
+		return Position{}, fmt.Errorf("portfolio %s: %w", portfolioID, ErrNotFound)
+	}
+	if normalized, err := NormalizeSymbol(symbol); err == nil {
+		symbol = normalized
+	}
+	p, ok := s.positions[portfolioID][symbol]
+	if !ok {
+		return p, fmt.Errorf("position %s/%s: %w", portfolioID, symbol, ErrNotFound)
+	}
+	return p, nil
+}
+
+// PutPosition creates or updates a position of an existing portfolio
+func (s *MemoryStore) PutPosition(p Position, expected int64) (Position, error) {
+	if err := p.Validate(); err != nil {
+		return p, err
+	}
+	p.Symbol, _ = NormalizeSymbol(p.Symbol)
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, ok := s.portfolios[p.PortfolioID]; !ok {
+		return p, fmt.Errorf("portfolio %s: %w", p.PortfolioID, ErrNotFound)
+	}
+	current, ok := s.positions[p.PortfolioID][p.Symbol]
+	if err := checkVersion("position "+p.PortfolioID+"/"+p.Symbol, ok, current.Version, expected); err != nil {
+		return current, err
+	}
+	p.Version = current.Version + 1
+	p.UpdatedAt = s.now()
+	if s.positions[p.PortfolioID] == nil {
+		s.positions[p.PortfolioID] = make(map[string]Position)
+	}
+	s.positions[p.PortfolioID][p.Symbol] = p
+	return p, nil
+}
+
+// Fills returns the fill history of a portfolio ordered by time
+func (s *MemoryStore) Fills(portfolioID string) ([]Fill, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	if _, ok := s.portfolios[portfolioID]; !ok {
+		return nil, fmt.Errorf("portfolio %s: %w", portfolioID, ErrNotFound)
+	}
+	return append([]Fill{}, s.fills[portfolioID]...), nil
+}
+
+// AddFills appends fills to the history of a portfolio
+func (s *MemoryStore) AddFills(portfolioID string, fills []Fill) error {
+	added := make([]Fill, len(fills))
+	for i, f := range fills {
+		f.PortfolioID = portfolioID
+		if err := f.Validate(); err != nil {
+			return fmt.Errorf("fill %s: %w", f.ID, err)
+		}
+		added[i] = f.normalize()
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, ok := s.portfolios[portfolioID]; !ok {
+		return fmt.Errorf("portfolio %s: %w", portfolioID, ErrNotFound)
+	}
+	ids := make(map[string]struct{}, len(s.fills[portfolioID])+len(fills))
+	for _, f := range s.fills[portfolioID] {
+		ids[f.ID] = struct{}{}
+	}
+	for _, f := range added {
+		if _, ok := ids[f.ID]; ok {
+			return fmt.Errorf("fill %s: %w", f.ID, ErrExists)
+		}
+		ids[f.ID] = struct{}{}
+	}
+	history := append(s.fills[portfolioID], added...)
+	sort.SliceStable(history, func(i, j int) bool { return history[i].Timestamp < history[j].Timestamp })
+	s.fills[portfolioID] = history
+	return nil
+}
+
+// CashFlows returns the funding and interest payments of a portfolio ordered by time
+func (s *MemoryStore) CashFlows(portfolioID string) ([]CashFlow, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	if _, ok := s.portfolios[portfolioID]; !ok {
+		return nil, fmt.Errorf("portfolio %s: %w", portfolioID, ErrNotFound)
+	}
+	return append([]CashFlow{}, s.cashFlows[portfolioID]...), nil
+}
+
+// AddCashFlows appends the payments not recorded yet to the history of a portfolio
+func (s *MemoryStore) AddCashFlows(portfolioID string, flows []CashFlow) (int, error) {
+	added := make([]CashFlow, len(flows))
+	for i, c := range flows {
+		c.PortfolioID = portfolioID
+		if err := c.Validate(); err != nil {
+			return 0, fmt.Errorf("cash flow %s: %w", c.ID, err)
+		}
+		added[i] = c.normalize()
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, ok := s.portfolios[portfolioID]; !ok {
+		return 0, fmt.Errorf("portfolio %s: %w", portfolioID, ErrNotFound)
+	}
+	history := s.cashFlows[portfolioID]
+	ids := make(map[string]struct{}, len(history)+len(added))
+	for _, c := range history {
+		ids[c.ID] = struct{}{}
+	}
+	n := 0
+	for _, c := range added {
+		if _, ok := ids[c.ID]; ok {
+			continue
+		}
+		ids[c.ID] = struct{}{}
+		history = append(history, c)
+		n++
+	}
+	sort.SliceStable(history, func(i, j int) bool { return history[i].Timestamp < history[j].Timestamp })
+	s.cashFlows[portfolioID] = history
+	return n, nil
+}
+
+// checkVersion applies the write rules of Store to a resource
+func checkVersion(resource string, exists bool, current, expected int64) error {
+	switch {
+	case expected == AnyVersion:
+		return nil
+	case !exists && expected != 0:
+		return fmt.Errorf("%s: %w", resource, ErrNotFound)
+	case exists && expected != current:
+		return conflict(resource, expected, current)
+	}
+	return nil
+}
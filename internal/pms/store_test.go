@@ -0,0 +1,121 @@
+package pms
+
+import (
+	"errors"
+	"sync"
+	"testing"
+)
+
+func TestPutPortfolioVersions(t *testing.T) {
+	s := NewMemoryStore()
+
+	p, err := s.PutPortfolio(Portfolio{ID: "main", Name: "Main"}, 0)
+	if err != nil || p.Version != 1 {
+		t.Fatalf("create: %+v, %v", p, err)
+	}
+	if _, err := s.PutPortfolio(Portfolio{ID: "main", Name: "Again"}, 0); !errors.Is(err, ErrVersionConflict) {
+		t.Errorf("create over existing: %v", err)
+	}
+	if p, err = s.PutPortfolio(Portfolio{ID: "main", Name: "Renamed"}, 1); err != nil || p.Version != 2 {
+		t.Fatalf("update: %+v, %v", p, err)
+	}
+	current, err := s.PutPortfolio(Portfolio{ID: "main", Name: "Stale"}, 1)
+	if !errors.Is(err, ErrVersionConflict) || current.Version != 2 || current.Name != "Renamed" {
+		t.Errorf("stale update: %+v, %v", current, err)
+	}
+	if _, err := s.PutPortfolio(Portfolio{ID: "other", Name: "Other"}, 3); !errors.Is(err, ErrNotFound) {
+		t.Errorf("update of missing portfolio: %v", err)
+	}
+	if p, err = s.PutPortfolio(Portfolio{ID: "main", Name: "Forced"}, AnyVersion); err != nil || p.Version != 3 {
+		t.Errorf("forced update: %+v, %v", p, err)
+	}
+}
+
+func TestPutPositionVersions(t *testing.T) {
+	s := NewMemoryStore()
+	if _, err := s.PutPosition(Position{PortfolioID: "main", Symbol: "BTC-USDT"}, 0); !errors.Is(err, ErrNotFound) {
+		t.Errorf("position of missing portfolio: %v", err)
+	}
+	if _, err := s.PutPortfolio(Portfolio{ID: "main", Name: "Main"}, 0); err != nil {
+		t.Fatal(err)
+	}
+
+	p, err := s.PutPosition(Position{PortfolioID: "main", Symbol: "btc-usdt", Quantity: 1, AvgPrice: 60000}, 0)
+	if err != nil || p.Version != 1 || p.Symbol != "BTC-USDT" {
+		t.Fatalf("create: %+v, %v", p, err)
+	}
+	if p, err = s.Position("main", "btc-usdt"); err != nil || p.Quantity != 1 {
+		t.Errorf("get: %+v, %v", p, err)
+	}
+	if _, err := s.PutPosition(Position{PortfolioID: "main", Symbol: "BTC-USDT", Quantity: 2}, 2); !errors.Is(err, ErrVersionConflict) {
+		t.Errorf("stale update: %v", err)
+	}
+	if _, err := s.PutPosition(Position{PortfolioID: "main", Symbol: "BTCUSDT"}, 0); err == nil {
+		t.Error("invalid symbol accepted")
+	}
+}
+
+func TestConcurrentUpdatesConflict(t *testing.T) {
+	s := NewMemoryStore()
+	if _, err := s.PutPortfolio(Portfolio{ID: "main", Name: "Main"}, 0); err != nil {
+		t.Fatal(err)
+	}
+
+	const writers = 8
+	var wg sync.WaitGroup
+	errs := make(chan error, writers)
+	for i := 0; i < writers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_, err := s.PutPortfolio(Portfolio{ID: "main", Name: "Writer"}, 1)
+			errs <- err
+		}()
+	}
+	wg.Wait()
+	close(errs)
+
+	var ok int
+	for err := range errs {
+		if err == nil {
+			ok++
+		} else if !errors.Is(err, ErrVersionConflict) {
+			t.Errorf("unexpected error: %v", err)
+		}
+	}
+	if ok != 1 {
+		t.Errorf("%d writers succeeded from the same version, want 1", ok)
+	}
+}
+
+func TestAddCashFlowsSkipsBooked(t *testing.T) {
+	s := NewMemoryStore()
+	if _, err := s.AddCashFlows("main", []CashFlow{{ID: "1", Kind: CashFlowFunding, Asset: "USDT", Timestamp: 1}}); !errors.Is(err, ErrNotFound) {
+		t.Errorf("cash flows of missing portfolio: %v", err)
+	}
+	if _, err := s.PutPortfolio(Portfolio{ID: "main", Name: "Main"}, 0); err != nil {
+		t.Fatal(err)
+	}
+
+	n, err := s.AddCashFlows("main", []CashFlow{
+		{ID: "2", Kind: CashFlowFunding, Symbol: "btc-usdt", Asset: "usdt", Amount: -1.5, Timestamp: 2000},
+		{ID: "1", Kind: CashFlowInterest, Asset: "USDT", Amount: -0.1, Timestamp: 1000},
+	})
+	if err != nil || n != 2 {
+		t.Fatalf("add: %d, %v", n, err)
+	}
+	n, err = s.AddCashFlows("main", []CashFlow{
+		{ID: "2", Kind: CashFlowFunding, Symbol: "BTC-USDT", Asset: "USDT", Amount: -1.5, Timestamp: 2000},
+		{ID: "3", Kind: CashFlowFunding, Symbol: "BTC-USDT", Asset: "USDT", Amount: 0.7, Timestamp: 3000},
+	})
+	if err != nil || n != 1 {
+		t.Fatalf("overlapping add: %d, %v", n, err)
+	}
+	flows, err := s.CashFlows("main")
+	if err != nil || len(flows) != 3 || flows[0].ID != "1" || flows[1].Symbol != "BTC-USDT" || flows[1].Asset != "USDT" {
+		t.Errorf("cash flows = %+v, %v", flows, err)
+	}
+	if _, err := s.AddCashFlows("main", []CashFlow{{ID: "4", Kind: "rebate", Asset: "USDT", Timestamp: 1}}); !errors.Is(err, ErrInvalid) {
+		t.Errorf("unknown kind: %v", err)
+	}
+}
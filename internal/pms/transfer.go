@@ -0,0 +1,380 @@
+package pms
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Format is the encoding of an import or export
+type Format string
+
+const (
+	FormatJSON Format = "json"
+	FormatCSV  Format = "csv"
+)
+
+// ParseFormat parses a format name, defaulting to JSON
+func ParseFormat(s string) (Format, error) {
+	switch Format(strings.ToLower(s)) {
+	case "", FormatJSON:
+		return FormatJSON, nil
+	case FormatCSV:
+		return FormatCSV, nil
+	}
+	return "", fmt.Errorf("unknown format %q, expected json or csv", s)
+}
+
+// Dataset selects the records of a CSV import or export. JSON transfers
+// always carry every dataset in one Bundle.
+type Dataset string
+
+const (
+	DatasetPositions Dataset = "positions"
+	DatasetFills     Dataset = "fills"
+)
+
+// ParseDataset parses a dataset name
+func ParseDataset(s string) (Dataset, error) {
+	switch Dataset(strings.ToLower(s)) {
+	case DatasetPositions:
+		return DatasetPositions, nil
+	case DatasetFills:
+		return DatasetFills, nil
+	}
+	return "", fmt.Errorf("unknown dataset %q, expected positions or fills", s)
+}
+
+// Bundle is the JSON export of a portfolio
+type Bundle struct {
+	Portfolio Portfolio  `json:"portfolio"`
+	Positions []Position `json:"positions"`
+	Fills     []Fill     `json:"fills"`
+}
+
+// Export reads the positions and fill history of a portfolio
+func Export(store Store, portfolioID string) (Bundle, error) {
+	var b Bundle
+	var err error
+	if b.Portfolio, err = store.Portfolio(portfolioID); err != nil {
+		return b, err
+	}
+	if b.Positions, err = store.Positions(portfolioID); err != nil {
+		return b, err
+	}
+	if b.Fills, err = store.Fills(portfolioID); err != nil {
+		return b, err
+	}
+	return b, nil
+}
+
+// RowError reports why one record of an import was rejected. Row is the
+// 1-based record number; in CSV it counts the header line, so it matches
+// the line number shown by spreadsheets.
+type RowError struct {
+	Dataset Dataset `json:"dataset"`
+	Row     int     `json:"row"`
+	Error   string  `json:"error"`
+}
+
+// ImportResult summarizes an import
+type ImportResult struct {
+	DryRun    bool       `json:"dry_run"`
+	Positions int        `json:"positions"` // Positions written, or that would be written on a dry run
+	Fills     int        `json:"fills"`     // Fills added, or that would be added on a dry run
+	Errors    []RowError `json:"errors"`
+}
+
+// Batch is the decoded content of an import
+type Batch struct {
+	Positions    []Position
+	Fills        []Fill
+	PositionRows []int      // Row of each position, record number when nil
+	FillRows     []int      // Row of each fill, record number when nil
+	Errors       []RowError // Records that could not be decoded
+}
+
+func (b *Batch) positionRow(i int) int {
+	if b.PositionRows != nil {
+		return b.PositionRows[i]
+	}
+	return i + 1
+}
+
+func (b *Batch) fillRow(i int) int {
+	if b.FillRows != nil {
+		return b.FillRows[i]
+	}
+	return i + 1
+}
+
+// Import validates every record of a batch and writes them only if none is
+// rejected, so a failed import never leaves a portfolio half migrated.
+// Imported positions replace existing ones of the same symbol; fills whose
+// id is already recorded are rejected. A dry run validates without writing.
+func Import(store Store, portfolioID string, batch Batch, dryRun bool) (ImportResult, error) {
+	result := ImportResult{DryRun: dryRun, Errors: append([]RowError{}, batch.Errors...)}
+	if _, err := store.Portfolio(portfolioID); err != nil {
+		return result, err
+	}
+	existing, err := store.Fills(portfolioID)
+	if err != nil {
+		return result, err
+	}
+
+	reject := func(dataset Dataset, row int, err error) {
+		result.Errors = append(result.Errors, RowError{Dataset: dataset, Row: row, Error: err.Error()})
+	}
+
+	positions := make([]Position, 0, len(batch.Positions))
+	symbols := make(map[string]int, len(batch.Positions))
+	for i, p := range batch.Positions {
+		row := batch.positionRow(i)
+		p.PortfolioID = portfolioID
+		if err := p.Validate(); err != nil {
+			reject(DatasetPositions, row, err)
+			continue
+		}
+		p.Symbol, _ = NormalizeSymbol(p.Symbol)
+		if first, ok := symbols[p.Symbol]; ok {
+			reject(DatasetPositions, row, fmt.Errorf("duplicate symbol %s, first seen in row %d", p.Symbol, first))
+			continue
+		}
+		symbols[p.Symbol] = row
+		positions = append(positions, p)
+	}
+
+	recorded := make(map[string]struct{}, len(existing))
+	for _, f := range existing {
+		recorded[f.ID] = struct{}{}
+	}
+	fills := make([]Fill, 0, len(batch.Fills))
+	ids := make(map[string]int, len(batch.Fills))
+	for i, f := range batch.Fills {
+		row := batch.fillRow(i)
+		f.PortfolioID = portfolioID
+		if err := f.Validate(); err != nil {
+			reject(DatasetFills, row, err)
+			continue
+		}
+		if _, ok := recorded[f.ID]; ok {
+			reject(DatasetFills, row, fmt.Errorf("fill %s: %w", f.ID, ErrExists))
+			continue
+		}
+		if first, ok := ids[f.ID]; ok {
+			reject(DatasetFills, row, fmt.Errorf("duplicate fill id %s, first seen in row %d", f.ID, first))
+			continue
+		}
+		ids[f.ID] = row
+		fills = append(fills, f)
+	}
+
+	if len(result.Errors) > 0 {
+		sort.SliceStable(result.Errors, func(i, j int) bool {
+			a, b := result.Errors[i], result.Errors[j]
+			return a.Dataset > b.Dataset || a.Dataset == b.Dataset && a.Row < b.Row
+		})
+		return result, nil
+	}
+	result.Positions = len(positions)
+	result.Fills = len(fills)
+	if dryRun {
+		return result, nil
+	}
+
+	if err := store.AddFills(portfolioID, fills); err != nil {
+		return result, err
+	}
+	for _, p := range positions {
+		if _, err := store.PutPosition(p, AnyVersion); err != nil {
+			return result, fmt.Errorf("position %s: %w", p.Symbol, err)
+		}
+	}
+	return result, nil
+}
+
+// ReadJSON decodes a Bundle. The portfolio of the bundle is ignored, so an
+// export can be imported into another portfolio.
+func ReadJSON(r io.Reader) (Batch, error) {
+	var bundle Bundle
+	if err := json.NewDecoder(r).Decode(&bundle); err != nil {
+		return Batch{}, fmt.Errorf("%w: %v", ErrInvalid, err)
+	}
+	return Batch{Positions: bundle.Positions, Fills: bundle.Fills}, nil
+}
+
+// WriteJSON encodes a Bundle
+func WriteJSON(w io.Writer, bundle Bundle) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(bundle)
+}
+
+var (
+	positionColumns = []string{"symbol", "quantity", "avg_price", "version", "updated_at"}
+	fillColumns     = []string{"id", "symbol", "side", "quantity", "price", "fee", "fee_asset", "timestamp"}
+)
+
+// WriteCSV encodes one dataset of a bundle with a header line
+func WriteCSV(w io.Writer, bundle Bundle, dataset Dataset) error {
+	cw := csv.NewWriter(w)
+	f := func(v float64) string { return strconv.FormatFloat(v, 'f', -1, 64) }
+	switch dataset {
+	case DatasetPositions:
+		cw.Write(positionColumns)
+		for _, p := range bundle.Positions {
+			cw.Write([]string{p.Symbol, f(p.Quantity), f(p.AvgPrice), strconv.FormatInt(p.Version, 10), p.UpdatedAt.UTC().Format(time.RFC3339)})
+		}
+	case DatasetFills:
+		cw.Write(fillColumns)
+		for _, fill := range bundle.Fills {
+			cw.Write([]string{fill.ID, fill.Symbol, fill.Side, f(fill.Quantity), f(fill.Price), f(fill.Fee), fill.FeeAsset, strconv.FormatInt(fill.Timestamp, 10)})
+		}
+	default:
+		return fmt.Errorf("unknown dataset %q", dataset)
+	}
+	cw.Flush()
+	return cw.Error()
+}
+
+// ReadCSV decodes one dataset. Columns are matched by the header line, in
+// any order and case; unknown columns such as version are ignored. Fill
+// timestamps are Unix milliseconds or RFC 3339.
+func ReadCSV(r io.Reader, dataset Dataset) (Batch, error) {
+	var batch Batch
+	cr := csv.NewReader(r)
+	cr.FieldsPerRecord = -1
+	cr.TrimLeadingSpace = true
+	header, err := cr.Read()
+	if err != nil {
+		return batch, fmt.Errorf("%w: failed to read header: %v", ErrInvalid, err)
+	}
+	cols := make(map[string]int, len(header))
+	for i, name := range header {
+		cols[strings.ToLower(strings.TrimSpace(name))] = i
+	}
+	var required []string
+	switch dataset {
+	case DatasetPositions:
+		required = []string{"symbol", "quantity"}
+	case DatasetFills:
+		required = []string{"id", "symbol", "side", "quantity", "price", "timestamp"}
+	default:
+		return batch, fmt.Errorf("unknown dataset %q", dataset)
+	}
+	for _, name := range required {
+		if _, ok := cols[name]; !ok {
+			return batch, fmt.Errorf("%w: missing column %s", ErrInvalid, name)
+		}
+	}
+
+	for row := 2; ; row++ {
+		fields, err := cr.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			var parseErr *csv.ParseError
+			if !errors.As(err, &parseErr) {
+				return batch, err
+			}
+			batch.Errors = append(batch.Errors, RowError{Dataset: dataset, Row: row, Error: parseErr.Err.Error()})
+			continue
+		}
+		rec := csvRecord{cols: cols, fields: fields}
+		switch dataset {
+		case DatasetPositions:
+			p, err := rec.position()
+			if err != nil {
+				batch.Errors = append(batch.Errors, RowError{Dataset: dataset, Row: row, Error: err.Error()})
+				continue
+			}
+			batch.Positions = append(batch.Positions, p)
+			batch.PositionRows = append(batch.PositionRows, row)
+		case DatasetFills:
+			f, err := rec.fill()
+			if err != nil {
+				batch.Errors = append(batch.Errors, RowError{Dataset: dataset, Row: row, Error: err.Error()})
+				continue
+			}
+			batch.Fills = append(batch.Fills, f)
+			batch.FillRows = append(batch.FillRows, row)
+		}
+	}
+	return batch, nil
+}
+
+// csvRecord reads the fields of a record by column name
+type csvRecord struct {
+	cols   map[string]int
+	fields []string
+}
+
+func (r csvRecord) get(name string) string {
+	i, ok := r.cols[name]
+	if !ok || i >= len(r.fields) {
+		return ""
+	}
+	return strings.TrimSpace(r.fields[i])
+}
+
+// float parses a numeric column, empty meaning 0
+func (r csvRecord) float(name string) (float64, error) {
+	value := r.get(name)
+	if value == "" {
+		return 0, nil
+	}
+	v, err := strconv.ParseFloat(value, 64)
+	if err != nil {
+		return 0, fmt.Errorf("%s: invalid number %q", name, value)
+	}
+	return v, nil
+}
+
+func (r csvRecord) position() (Position, error) {
+	p := Position{Symbol: r.get("symbol")}
+	var err error
+	if p.Quantity, err = r.float("quantity"); err != nil {
+		return p, err
+	}
+	if p.AvgPrice, err = r.float("avg_price"); err != nil {
+		return p, err
+	}
+	return p, nil
+}
+
+func (r csvRecord) fill() (Fill, error) {
+	f := Fill{ID: r.get("id"), Symbol: r.get("symbol"), Side: r.get("side"), FeeAsset: r.get("fee_asset")}
+	var err error
+	if f.Quantity, err = r.float("quantity"); err != nil {
+		return f, err
+	}
+	if f.Price, err = r.float("price"); err != nil {
+		return f, err
+	}
+	if f.Fee, err = r.float("fee"); err != nil {
+		return f, err
+	}
+	if f.Timestamp, err = parseTimestamp(r.get("timestamp")); err != nil {
+		return f, err
+	}
+	return f, nil
+}
+
+// parseTimestamp reads Unix milliseconds or an RFC 3339 time
+func parseTimestamp(value string) (int64, error) {
+	if ms, err := strconv.ParseInt(value, 10, 64); err == nil {
+		return ms, nil
+	}
+	t, err := time.Parse(time.RFC3339, value)
+	if err != nil {
+		return 0, fmt.Errorf("timestamp: expected Unix milliseconds or RFC 3339, got %q", value)
+	}
+	return t.UnixMilli(), nil
+}
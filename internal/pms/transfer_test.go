@@ -0,0 +1,119 @@
+package pms
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func newTestStore(t *testing.T) *MemoryStore {
+	t.Helper()
+	s := NewMemoryStore()
+	if _, err := s.PutPortfolio(Portfolio{ID: "main", Name: "Main"}, 0); err != nil {
+		t.Fatal(err)
+	}
+	return s
+}
+
+func TestImportCSVReportsRowErrors(t *testing.T) {
+	s := newTestStore(t)
+	csv := "ID,Symbol,Side,Quantity,Price,Fee,Fee_Asset,Timestamp\n" +
+		"t1,BTC-USDT,buy,0.5,60000,0.1,USDT,1717000000000\n" +
+		"t2,BTC-USDT,HOLD,0.5,60000,0,,1717000000001\n" +
+		"t3,ETH-USDT,SELL,abc,3000,0,,1717000000002\n" +
+		"t1,BTC-USDT,SELL,0.1,61000,0,,2024-06-01T00:00:00Z\n"
+
+	batch, err := ReadCSV(strings.NewReader(csv), DatasetFills)
+	if err != nil {
+		t.Fatal(err)
+	}
+	result, err := Import(s, "main", batch, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(result.Errors) != 3 {
+		t.Fatalf("errors = %+v", result.Errors)
+	}
+	for i, row := range []int{3, 4, 5} {
+		if result.Errors[i].Row != row || result.Errors[i].Dataset != DatasetFills {
+			t.Errorf("error %d = %+v, want row %d", i, result.Errors[i], row)
+		}
+	}
+	// A rejected row aborts the whole import
+	if fills, _ := s.Fills("main"); len(fills) != 0 || result.Fills != 0 {
+		t.Errorf("imported %d fills despite errors", len(fills))
+	}
+}
+
+func TestImportDryRun(t *testing.T) {
+	s := newTestStore(t)
+	batch := Batch{Positions: []Position{{Symbol: "btc-usdt", Quantity: 1, AvgPrice: 60000}}}
+
+	result, err := Import(s, "main", batch, true)
+	if err != nil || len(result.Errors) != 0 || result.Positions != 1 || !result.DryRun {
+		t.Fatalf("dry run = %+v, %v", result, err)
+	}
+	if positions, _ := s.Positions("main"); len(positions) != 0 {
+		t.Fatal("dry run wrote positions")
+	}
+
+	if _, err := Import(s, "main", batch, false); err != nil {
+		t.Fatal(err)
+	}
+	if p, err := s.Position("main", "BTC-USDT"); err != nil || p.Quantity != 1 {
+		t.Errorf("position = %+v, %v", p, err)
+	}
+}
+
+func TestExportRoundTrip(t *testing.T) {
+	s := newTestStore(t)
+	if _, err := s.PutPosition(Position{PortfolioID: "main", Symbol: "BTC-USDT", Quantity: 0.4, AvgPrice: 60000}, 0); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.AddFills("main", []Fill{
+		{ID: "t1", Symbol: "BTC-USDT", Side: "BUY", Quantity: 0.5, Price: 60000, Timestamp: 1},
+		{ID: "t2", Symbol: "BTC-USDT", Side: "SELL", Quantity: 0.1, Price: 61000, Fee: 0.2, FeeAsset: "USDT", Timestamp: 2},
+	}); err != nil {
+		t.Fatal(err)
+	}
+	bundle, err := Export(s, "main")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	target := NewMemoryStore()
+	if _, err := target.PutPortfolio(Portfolio{ID: "copy", Name: "Copy"}, 0); err != nil {
+		t.Fatal(err)
+	}
+	for _, dataset := range []Dataset{DatasetPositions, DatasetFills} {
+		var buf bytes.Buffer
+		if err := WriteCSV(&buf, bundle, dataset); err != nil {
+			t.Fatal(err)
+		}
+		batch, err := ReadCSV(&buf, dataset)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if result, err := Import(target, "copy", batch, false); err != nil || len(result.Errors) != 0 {
+			t.Fatalf("%s import = %+v, %v", dataset, result, err)
+		}
+	}
+
+	var buf bytes.Buffer
+	if err := WriteJSON(&buf, bundle); err != nil {
+		t.Fatal(err)
+	}
+	batch, err := ReadJSON(&buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Fills are already recorded, so importing the JSON export again is rejected
+	if result, err := Import(target, "copy", batch, true); err != nil || len(result.Errors) != 2 {
+		t.Errorf("reimport = %+v, %v", result, err)
+	}
+
+	copied, _ := Export(target, "copy")
+	if len(copied.Positions) != 1 || copied.Positions[0].Quantity != 0.4 || len(copied.Fills) != 2 || copied.Fills[1].Fee != 0.2 {
+		t.Errorf("copied = %+v", copied)
+	}
+}
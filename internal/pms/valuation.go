@@ -0,0 +1,254 @@
+package pms
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/BullionBear/sequex/internal/model/sqx"
+	"github.com/BullionBear/sequex/pkg/options"
+)
+
+// Converter provides the price of one unit of a currency in another
+type Converter interface {
+	Rate(from, to string) (float64, error)
+}
+
+// HistoricalConverter provides the price of one unit of a currency in
+// another as of a past time, like fx.Rates
+type HistoricalConverter interface {
+	RateAt(from, to string, at time.Time) (float64, error)
+}
+
+// Vols provides the implied volatility of option contracts by name. A
+// Converter that is also Vols, like fx.Rates, lets Value price options.
+type Vols interface {
+	Vol(contract string) (float64, error)
+}
+
+// PositionValue is the valuation of one position in the base currency
+type PositionValue struct {
+	Symbol        string          `json:"symbol"`
+	Quantity      float64         `json:"quantity"`
+	Price         float64         `json:"price"` // Price of one unit of the base asset, or of one option contract
+	Value         float64         `json:"value"`
+	Cost          float64         `json:"cost"` // Quantity at the average price, converted at the current rate
+	UnrealizedPnL float64         `json:"unrealized_pnl"`
+	Greeks        *options.Greeks `json:"greeks,omitempty"` // Of an option position, in the base currency
+	Error         string          `json:"error,omitempty"`  // Why the position could not be valued
+}
+
+// Valuation expresses a portfolio in its base currency. Positions that
+// cannot be converted are listed with an error and left out of the totals.
+type Valuation struct {
+	PortfolioID   string          `json:"portfolio_id"`
+	Currency      string          `json:"currency"`
+	Value         float64         `json:"value"`
+	Cost          float64         `json:"cost"`
+	UnrealizedPnL float64         `json:"unrealized_pnl"`
+	Complete      bool            `json:"complete"` // Whether every position was valued
+	Positions     []PositionValue `json:"positions"`
+	// Greeks of the option positions by underlying asset, in the base
+	// currency, delta netting the holdings of the asset
+	Greeks map[string]options.Greeks `json:"greeks,omitempty"`
+	AsOf   time.Time                 `json:"as_of"`
+}
+
+// Value values the positions of a portfolio in its base currency. Each
+// position is worth its quantity of the base asset, whatever market it was
+// traded on, so a BTC perp position and BTC spot holdings count alike and
+// cash such as EUR deposits is held as a EUR-<quote> position.
+//
+// Option positions, whose symbol is a contract name such as
+// BTC-USDT-250328-90000-C, are priced with Black-76 on the price of their
+// underlying and the implied volatility of rates, which must be Vols.
+func Value(portfolio Portfolio, positions []Position, rates Converter) Valuation {
+	currency := portfolio.Currency()
+	v := Valuation{
+		PortfolioID: portfolio.ID,
+		Currency:    currency,
+		Complete:    true,
+		Positions:   make([]PositionValue, 0, len(positions)),
+		AsOf:        time.Now(),
+	}
+	vols, _ := rates.(Vols)
+	books := make(map[string]*optionBook)
+	holdings := make(map[string]float64)
+	for _, p := range positions {
+		pv := PositionValue{Symbol: p.Symbol, Quantity: p.Quantity}
+		var err error
+		if contract, cerr := sqx.NewOptionContractFromStr(p.Symbol); cerr == nil {
+			err = valueOption(&pv, p, contract, currency, rates, vols, v.AsOf, books)
+		} else if err = valuePosition(&pv, p, currency, rates); err == nil {
+			symbol, _ := sqx.NewSymbolFromStr(p.Symbol)
+			holdings[symbol.Base] += p.Quantity
+		}
+		if err != nil {
+			pv.Error = err.Error()
+			v.Complete = false
+		} else {
+			v.Value += pv.Value
+			v.Cost += pv.Cost
+			v.UnrealizedPnL += pv.UnrealizedPnL
+		}
+		v.Positions = append(v.Positions, pv)
+	}
+	if len(books) > 0 {
+		v.Greeks = make(map[string]options.Greeks, len(books))
+	}
+	for _, book := range books {
+		// Its positions were each valued when added, so the book values
+		total, _ := options.ValuePortfolio(book.positions)
+		v.Greeks[book.asset] = v.Greeks[book.asset].Add(inCurrency(total.Greeks, book.rate))
+	}
+	for asset, greeks := range v.Greeks {
+		greeks.Delta += holdings[asset]
+		v.Greeks[asset] = greeks
+	}
+	return v
+}
+
+// optionBook holds the option positions on one underlying symbol
+type optionBook struct {
+	asset     string // Base asset of the underlying
+	positions []options.Position
+	rate      float64 // Of the quote of the underlying in the base currency
+}
+
+// valueOption values an option position, adding it to the book of its
+// underlying. The price of the underlying is taken as its forward.
+func valueOption(pv *PositionValue, p Position, contract sqx.OptionContract, currency string, rates Converter, vols Vols, now time.Time, books map[string]*optionBook) error {
+	if vols == nil {
+		return fmt.Errorf("no implied volatility to value options with")
+	}
+	vol, err := vols.Vol(contract.String())
+	if err != nil {
+		return err
+	}
+	forward, err := rates.Rate(contract.Underlying.Base, contract.Underlying.Quote)
+	if err != nil {
+		return err
+	}
+	quoteRate, err := rates.Rate(contract.Underlying.Quote, currency)
+	if err != nil {
+		return err
+	}
+	position := options.Position{
+		Name: contract.String(),
+		Contract: options.Contract{
+			Type:    options.NewOptionType(contract.Kind.String()),
+			Forward: forward,
+			Strike:  contract.Strike,
+			Expiry:  contract.YearsToExpiry(now),
+		},
+		Size:       p.Quantity,
+		EntryPrice: p.AvgPrice,
+		Vol:        vol,
+	}
+	value, err := position.Value()
+	if err != nil {
+		return err
+	}
+	price, err := options.Price(position.Contract, vol)
+	if err != nil {
+		return err
+	}
+	greeks := inCurrency(value.Greeks, quoteRate)
+	pv.Price = price * quoteRate
+	pv.Value = value.MarketValue * quoteRate
+	pv.Cost = p.Quantity * p.AvgPrice * quoteRate
+	pv.UnrealizedPnL = value.UnrealizedPnL * quoteRate
+	pv.Greeks = &greeks
+
+	underlying := contract.Underlying.String()
+	book, ok := books[underlying]
+	if !ok {
+		book = &optionBook{asset: contract.Underlying.Base, rate: quoteRate}
+		books[underlying] = book
+	}
+	book.positions = append(book.positions, position)
+	return nil
+}
+
+// inCurrency converts greeks in the quote of their underlying into another
+// currency at rate: delta stays in units of the underlying while gamma is
+// per unit of its price in the currency
+func inCurrency(g options.Greeks, rate float64) options.Greeks {
+	return options.Greeks{
+		Price: g.Price * rate,
+		Delta: g.Delta,
+		Gamma: g.Gamma / rate,
+		Vega:  g.Vega * rate,
+		Theta: g.Theta * rate,
+		Rho:   g.Rho * rate,
+	}
+}
+
+func valuePosition(pv *PositionValue, p Position, currency string, rates Converter) error {
+	symbol, err := sqx.NewSymbolFromStr(p.Symbol)
+	if err != nil {
+		return err
+	}
+	if pv.Price, err = rates.Rate(symbol.Base, currency); err != nil {
+		return err
+	}
+	quoteRate, err := rates.Rate(symbol.Quote, currency)
+	if err != nil {
+		return err
+	}
+	pv.Value = p.Quantity * pv.Price
+	pv.Cost = p.Quantity * p.AvgPrice * quoteRate
+	pv.UnrealizedPnL = pv.Value - pv.Cost
+	return nil
+}
+
+// ConvertRealized expresses realizations, which are in the quote currency
+// of their symbol, in currency. Historical rates are not recorded, so
+// amounts are converted at the current rate.
+func ConvertRealized(realized []Realization, currency string, rates Converter) ([]Realization, error) {
+	converted := make([]Realization, len(realized))
+	for i, r := range realized {
+		symbol, err := sqx.NewSymbolFromStr(r.Symbol)
+		if err != nil {
+			return nil, err
+		}
+		rate, err := rates.Rate(symbol.Quote, currency)
+		if err != nil {
+			return nil, fmt.Errorf("realized PnL of %s: %w", r.Symbol, err)
+		}
+		r.Proceeds *= rate
+		r.CostBasis *= rate
+		r.PnL *= rate
+		converted[i] = r
+	}
+	return converted, nil
+}
+
+// ConvertCharges expresses charges in currency at the current rate
+func ConvertCharges(charges []Charge, currency string, rates Converter) ([]Charge, error) {
+	converted := make([]Charge, len(charges))
+	for i, c := range charges {
+		rate, err := rates.Rate(c.Asset, currency)
+		if err != nil {
+			return nil, fmt.Errorf("%s fee of %s: %w", c.Asset, c.Symbol, err)
+		}
+		c.Amount *= rate
+		c.Asset = currency
+		converted[i] = c
+	}
+	return converted, nil
+}
+
+// ConvertCashFlows expresses cash flows in currency at the current rate
+func ConvertCashFlows(flows []CashFlow, currency string, rates Converter) ([]CashFlow, error) {
+	converted := make([]CashFlow, len(flows))
+	for i, c := range flows {
+		rate, err := rates.Rate(c.Asset, currency)
+		if err != nil {
+			return nil, fmt.Errorf("%s %s payment: %w", c.Asset, c.Kind, err)
+		}
+		c.Amount *= rate
+		c.Asset = currency
+		converted[i] = c
+	}
+	return converted, nil
+}
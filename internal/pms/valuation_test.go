@@ -0,0 +1,138 @@
+package pms
+
+import (
+	"fmt"
+	"math"
+	"testing"
+	"time"
+
+	"github.com/BullionBear/sequex/internal/model/sqx"
+	"github.com/BullionBear/sequex/pkg/options"
+)
+
+type fakeRates map[string]float64
+
+func (f fakeRates) Rate(from, to string) (float64, error) {
+	if from == to {
+		return 1, nil
+	}
+	if rate, ok := f[from+"-"+to]; ok {
+		return rate, nil
+	}
+	return 0, fmt.Errorf("no rate: %s to %s", from, to)
+}
+
+func TestValueMixedCurrencies(t *testing.T) {
+	rates := fakeRates{"BTC-EUR": 50000, "USDT-EUR": 0.9, "USD-EUR": 0.9}
+	portfolio := Portfolio{ID: "main", BaseCurrency: "eur"}
+	positions := []Position{
+		{Symbol: "BTC-USDT", Quantity: 0.5, AvgPrice: 40000}, // Spot bought in USDT
+		{Symbol: "BTC-USD", Quantity: 0.1, AvgPrice: 50000},  // Inverse perp
+		{Symbol: "EUR-USDT", Quantity: 1000},                 // Cash deposit
+		{Symbol: "SOL-USDT", Quantity: 10, AvgPrice: 150},
+	}
+
+	v := Value(portfolio, positions, rates)
+	if v.Currency != "EUR" || v.Complete || v.Positions[3].Error == "" {
+		t.Fatalf("valuation = %+v", v)
+	}
+	if p := v.Positions[0]; p.Value != 25000 || p.Cost != 18000 || p.UnrealizedPnL != 7000 {
+		t.Errorf("spot = %+v", p)
+	}
+	if p := v.Positions[2]; p.Value != 1000 {
+		t.Errorf("cash = %+v", p)
+	}
+	if math.Abs(v.Value-31000) > 1e-9 {
+		t.Errorf("value = %v, want 31000", v.Value)
+	}
+}
+
+// volRates are rates with the implied volatilities of option contracts
+type volRates struct {
+	fakeRates
+	vols map[string]float64
+}
+
+func (r volRates) Vol(contract string) (float64, error) {
+	if vol, ok := r.vols[contract]; ok {
+		return vol, nil
+	}
+	return 0, fmt.Errorf("no vol of %s", contract)
+}
+
+func TestValueOptions(t *testing.T) {
+	expiry := time.Now().AddDate(0, 3, 0).UTC().Format("060102")
+	call := "BTC-USDT-" + expiry + "-60000-C"
+	put := "BTC-USDT-" + expiry + "-50000-P"
+	rates := volRates{
+		fakeRates: fakeRates{"BTC-USDT": 60000, "BTC-EUR": 54000, "USDT-EUR": 0.9},
+		vols:      map[string]float64{call: 0.5},
+	}
+	portfolio := Portfolio{ID: "main", BaseCurrency: "EUR"}
+	positions := []Position{
+		{Symbol: call, Quantity: 2, AvgPrice: 5000},
+		{Symbol: "BTC-USDT", Quantity: -0.5, AvgPrice: 60000}, // Delta hedge
+		{Symbol: put, Quantity: 1, AvgPrice: 1000},            // No vol
+	}
+
+	v := Value(portfolio, positions, rates)
+	if v.Complete || v.Positions[2].Error == "" {
+		t.Fatalf("put without vol valued: %+v", v.Positions[2])
+	}
+	p := v.Positions[0]
+	if p.Error != "" || p.Greeks == nil {
+		t.Fatalf("call = %+v", p)
+	}
+	contract, err := sqx.NewOptionContractFromStr(call)
+	if err != nil {
+		t.Fatal(err)
+	}
+	price, err := options.Price(options.Contract{Type: options.OptionTypeCall, Forward: 60000, Strike: 60000, Expiry: contract.YearsToExpiry(v.AsOf)}, 0.5)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if math.Abs(p.Value-2*price*0.9) > 1e-6 || p.Cost != 9000 || math.Abs(p.UnrealizedPnL-(p.Value-p.Cost)) > 1e-6 {
+		t.Errorf("call = %+v, want a value of %v", p, 2*price*0.9)
+	}
+	if p.Greeks.Delta < 1 || p.Greeks.Delta > 1.2 || p.Greeks.Gamma <= 0 || p.Greeks.Vega <= 0 || p.Greeks.Theta >= 0 {
+		t.Errorf("call greeks = %+v", p.Greeks)
+	}
+
+	g, ok := v.Greeks["BTC"]
+	if !ok || len(v.Greeks) != 1 {
+		t.Fatalf("greeks = %+v", v.Greeks)
+	}
+	if math.Abs(g.Delta-(p.Greeks.Delta-0.5)) > 1e-9 || math.Abs(g.Gamma-p.Greeks.Gamma) > 1e-12 || math.Abs(g.Vega-p.Greeks.Vega) > 1e-9 {
+		t.Errorf("BTC greeks = %+v, call %+v", g, p.Greeks)
+	}
+	if math.Abs(v.UnrealizedPnL-p.UnrealizedPnL-v.Positions[1].UnrealizedPnL) > 1e-6 {
+		t.Errorf("unrealized PnL = %v", v.UnrealizedPnL)
+	}
+
+	// Without implied volatilities options cannot be priced
+	if v := Value(portfolio, positions[:1], rates.fakeRates); v.Complete || v.Greeks != nil {
+		t.Errorf("valued without vols: %+v", v)
+	}
+}
+
+func TestConvertRealized(t *testing.T) {
+	realized := []Realization{{Symbol: "ETH-BTC", PnL: 0.01, Proceeds: 0.05, CostBasis: 0.04}}
+	converted, err := ConvertRealized(realized, "USDT", fakeRates{"BTC-USDT": 60000})
+	if err != nil || converted[0].PnL != 600 || realized[0].PnL != 0.01 {
+		t.Errorf("converted = %+v, %v", converted, err)
+	}
+	if _, err := ConvertRealized(realized, "EUR", fakeRates{}); err == nil {
+		t.Error("missing rate not reported")
+	}
+}
+
+func TestConvertCharges(t *testing.T) {
+	charges := []Charge{{Symbol: "BTC-USDT", Asset: "BNB", Amount: 0.01}}
+	converted, err := ConvertCharges(charges, "USDT", fakeRates{"BNB-USDT": 600})
+	if err != nil || converted[0].Amount != 6 || converted[0].Asset != "USDT" || charges[0].Amount != 0.01 {
+		t.Errorf("converted = %+v, %v", converted, err)
+	}
+	if _, err := ConvertCharges(charges, "EUR", fakeRates{}); err == nil {
+		t.Error("missing rate not reported")
+	}
+}
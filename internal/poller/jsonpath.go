@@ -0,0 +1,130 @@
+package poller
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// segment is one step of a JSON path: a key, an index, or a wildcard over
+// the elements of an array or the values of an object
+type segment struct {
+	key      string
+	index    int
+	isIndex  bool
+	wildcard bool
+}
+
+// Path is a compiled JSON path. The supported subset is enough to reach
+// into exchange responses: $.a.b, $.a[0], $.a[-1], $.a[*].b, $['a-b'].
+type Path struct {
+	expr     string
+	segments []segment
+}
+
+// CompilePath parses a JSON path
+func CompilePath(expr string) (Path, error) {
+	p := Path{expr: expr}
+	rest := strings.TrimSpace(expr)
+	if !strings.HasPrefix(rest, "$") {
+		return p, fmt.Errorf("path %q must start with $", expr)
+	}
+	rest = rest[1:]
+	for rest != "" {
+		switch {
+		case strings.HasPrefix(rest, ".*"):
+			p.segments = append(p.segments, segment{wildcard: true})
+			rest = rest[2:]
+		case rest[0] == '.':
+			end := strings.IndexAny(rest[1:], ".[")
+			if end < 0 {
+				end = len(rest) - 1
+			}
+			key := rest[1 : end+1]
+			if key == "" {
+				return p, fmt.Errorf("path %q: empty key", expr)
+			}
+			p.segments = append(p.segments, segment{key: key})
+			rest = rest[end+1:]
+		case rest[0] == '[':
+			end := strings.IndexByte(rest, ']')
+			if end < 0 {
+				return p, fmt.Errorf("path %q: unterminated [", expr)
+			}
+			inner := strings.TrimSpace(rest[1:end])
+			switch {
+			case inner == "*":
+				p.segments = append(p.segments, segment{wildcard: true})
+			case len(inner) >= 2 && (inner[0] == '\'' || inner[0] == '"') && inner[len(inner)-1] == inner[0]:
+				p.segments = append(p.segments, segment{key: inner[1 : len(inner)-1]})
+			default:
+				index, err := strconv.Atoi(inner)
+				if err != nil {
+					return p, fmt.Errorf("path %q: invalid index %q", expr, inner)
+				}
+				p.segments = append(p.segments, segment{index: index, isIndex: true})
+			}
+			rest = rest[end+1:]
+		default:
+			return p, fmt.Errorf("path %q: unexpected %q", expr, rest[0])
+		}
+	}
+	return p, nil
+}
+
+// String returns the path expression
+func (p Path) String() string {
+	return p.expr
+}
+
+// Wildcard reports whether the path can select several values
+func (p Path) Wildcard() bool {
+	for _, s := range p.segments {
+		if s.wildcard {
+			return true
+		}
+	}
+	return false
+}
+
+// Select returns the values the path selects in a decoded JSON document.
+// Missing keys and out of range indexes select nothing.
+func (p Path) Select(doc any) []any {
+	current := []any{doc}
+	for _, s := range p.segments {
+		var next []any
+		for _, v := range current {
+			switch node := v.(type) {
+			case map[string]any:
+				if s.wildcard {
+					keys := make([]string, 0, len(node))
+					for key := range node {
+						keys = append(keys, key)
+					}
+					sort.Strings(keys) // Deterministic order
+					for _, key := range keys {
+						next = append(next, node[key])
+					}
+				} else if child, ok := node[s.key]; ok && !s.isIndex {
+					next = append(next, child)
+				}
+			case []any:
+				switch {
+				case s.wildcard:
+					next = append(next, node...)
+				case s.isIndex:
+					i := s.index
+					if i < 0 {
+						i += len(node)
+					}
+					if i >= 0 && i < len(node) {
+						next = append(next, node[i])
+					}
+				}
+			}
+		}
+		current = next
+	}
+	return current
+}
@@ -0,0 +1,482 @@
+// Package poller calls REST endpoints described by configuration on a
+// schedule and turns the responses into typed events, covering the long
+// tail of data sources without an exchange client for each of them.
+package poller
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"text/template"
+	"time"
+)
+
+// FieldType is the type a field is converted to
+type FieldType string
+
+const (
+	TypeNumber FieldType = "number" // float64, decimal strings are parsed
+	TypeInt    FieldType = "int"    // int64, decimal strings are parsed
+	TypeString FieldType = "string"
+	TypeBool   FieldType = "bool"
+	TypeRaw    FieldType = "raw" // JSON value as decoded
+)
+
+// Field extracts one value of the event from the response
+type Field struct {
+	Name     string
+	Path     string // JSON path, relative to the item when Items is set
+	Type     FieldType
+	Optional bool // Missing values are left out instead of failing the poll
+}
+
+// AuthKind is how requests are authenticated
+type AuthKind string
+
+const (
+	AuthNone   AuthKind = ""
+	AuthBearer AuthKind = "bearer" // Authorization: Bearer <secret>
+	AuthHMAC   AuthKind = "hmac"   // Query signed with HMAC-SHA256, as Binance and most exchanges do
+)
+
+// Auth configures request authentication. Credentials come from the
+// environment so they never sit in configuration files.
+type Auth struct {
+	Kind           AuthKind
+	APIKey         string
+	Secret         string
+	KeyHeader      string // Header the API key is sent in, e.g. X-MBX-APIKEY
+	SignatureParam string // Query parameter of the signature, signature when empty
+	TimestampParam string // Query parameter of the timestamp in ms, timestamp when empty
+}
+
+// Endpoint describes a polled REST endpoint. Path, params, headers, body and
+// subject are text/template strings rendered with the variables of each
+// target, e.g. {{.symbol}}, and the env, lower and upper functions.
+type Endpoint struct {
+	Name      string
+	EventType string
+	BaseURL   string
+	Method    string // GET when empty
+	Path      string
+	Params    map[string]string
+	Headers   map[string]string
+	Body      string              // Request body of POST endpoints
+	Targets   []map[string]string // One request per target, a single request without variables when empty
+	Items     string              // JSON path of the items to emit one event each, the whole response when empty
+	Fields    []Field
+	Subject   string
+	Auth      Auth
+	Interval  time.Duration
+	Timeout   time.Duration // Per request, the interval when 0
+}
+
+// Event is the typed result of a poll
+type Event struct {
+	Type      string            `json:"type"`
+	Source    string            `json:"source"` // Endpoint name
+	Target    map[string]string `json:"target,omitempty"`
+	Fields    map[string]any    `json:"fields"`
+	Timestamp int64             `json:"timestamp"` // Time of the response
+	Subject   string            `json:"-"`         // Where the event is published
+}
+
+var funcs = template.FuncMap{
+	"env":   os.Getenv,
+	"lower": strings.ToLower,
+	"upper": strings.ToUpper,
+}
+
+type compiledField struct {
+	Field
+	path Path
+}
+
+// Poller polls one endpoint
+type Poller struct {
+	ep      Endpoint
+	client  *http.Client
+	path    *template.Template
+	params  map[string]*template.Template
+	headers map[string]*template.Template
+	body    *template.Template
+	subject *template.Template
+	items   *Path
+	fields  []compiledField
+	now     func() time.Time
+}
+
+// New validates an endpoint and compiles its templates and paths. A nil
+// client uses http.DefaultClient.
+func New(ep Endpoint, client *http.Client) (*Poller, error) {
+	if ep.Name == "" {
+		return nil, fmt.Errorf("name cannot be empty")
+	}
+	wrap := func(err error) error { return fmt.Errorf("endpoint %s: %w", ep.Name, err) }
+	if ep.EventType == "" {
+		ep.EventType = ep.Name
+	}
+	if ep.Method == "" {
+		ep.Method = http.MethodGet
+	}
+	ep.Method = strings.ToUpper(ep.Method)
+	if ep.Method != http.MethodGet && ep.Method != http.MethodPost {
+		return nil, wrap(fmt.Errorf("method must be GET or POST"))
+	}
+	if _, err := url.ParseRequestURI(ep.BaseURL); err != nil {
+		return nil, wrap(fmt.Errorf("invalid base_url: %w", err))
+	}
+	if ep.Interval <= 0 {
+		return nil, wrap(fmt.Errorf("interval must be positive"))
+	}
+	if ep.Timeout <= 0 {
+		ep.Timeout = ep.Interval
+	}
+	if len(ep.Fields) == 0 {
+		return nil, wrap(fmt.Errorf("fields cannot be empty"))
+	}
+	switch ep.Auth.Kind {
+	case AuthNone:
+	case AuthBearer:
+		if ep.Auth.Secret == "" {
+			return nil, wrap(fmt.Errorf("bearer auth requires a secret"))
+		}
+	case AuthHMAC:
+		if ep.Auth.Secret == "" {
+			return nil, wrap(fmt.Errorf("hmac auth requires a secret"))
+		}
+		if ep.Auth.SignatureParam == "" {
+			ep.Auth.SignatureParam = "signature"
+		}
+		if ep.Auth.TimestampParam == "" {
+			ep.Auth.TimestampParam = "timestamp"
+		}
+	default:
+		return nil, wrap(fmt.Errorf("unknown auth %q", ep.Auth.Kind))
+	}
+	if client == nil {
+		client = http.DefaultClient
+	}
+
+	p := &Poller{ep: ep, client: client, params: make(map[string]*template.Template), headers: make(map[string]*template.Template), now: time.Now}
+	var err error
+	parse := func(name, text string) *template.Template {
+		if err != nil {
+			return nil
+		}
+		var t *template.Template
+		t, err = template.New(name).Funcs(funcs).Option("missingkey=error").Parse(text)
+		if err != nil {
+			err = fmt.Errorf("invalid %s template: %w", name, err)
+		}
+		return t
+	}
+	p.path = parse("path", ep.Path)
+	p.body = parse("body", ep.Body)
+	p.subject = parse("subject", ep.Subject)
+	for k, v := range ep.Params {
+		p.params[k] = parse("params."+k, v)
+	}
+	for k, v := range ep.Headers {
+		p.headers[k] = parse("headers."+k, v)
+	}
+	if err != nil {
+		return nil, wrap(err)
+	}
+	if ep.Subject == "" {
+		return nil, wrap(fmt.Errorf("subject cannot be empty"))
+	}
+
+	if ep.Items != "" {
+		items, err := CompilePath(ep.Items)
+		if err != nil {
+			return nil, wrap(err)
+		}
+		p.items = &items
+	}
+	seen := make(map[string]bool)
+	for _, f := range ep.Fields {
+		if f.Name == "" || seen[f.Name] {
+			return nil, wrap(fmt.Errorf("field names must be unique and not empty"))
+		}
+		seen[f.Name] = true
+		switch f.Type {
+		case "":
+			f.Type = TypeRaw
+		case TypeNumber, TypeInt, TypeString, TypeBool, TypeRaw:
+		default:
+			return nil, wrap(fmt.Errorf("field %s: unknown type %q", f.Name, f.Type))
+		}
+		path, err := CompilePath(f.Path)
+		if err != nil {
+			return nil, wrap(fmt.Errorf("field %s: %w", f.Name, err))
+		}
+		p.fields = append(p.fields, compiledField{Field: f, path: path})
+	}
+	return p, nil
+}
+
+// Name returns the endpoint name
+func (p *Poller) Name() string {
+	return p.ep.Name
+}
+
+// Interval returns the polling interval
+func (p *Poller) Interval() time.Duration {
+	return p.ep.Interval
+}
+
+func render(t *template.Template, vars map[string]string) (string, error) {
+	var buf bytes.Buffer
+	if err := t.Execute(&buf, vars); err != nil {
+		return "", err
+	}
+	return buf.String(), nil
+}
+
+// Poll requests every target once and returns their events. Failing targets
+// do not prevent the others from being polled; their errors are returned
+// together.
+func (p *Poller) Poll(ctx context.Context) ([]Event, error) {
+	targets := p.ep.Targets
+	if len(targets) == 0 {
+		targets = []map[string]string{{}}
+	}
+	var events []Event
+	var errs []error
+	for _, target := range targets {
+		list, err := p.pollTarget(ctx, target)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("%s %v: %w", p.ep.Name, target, err))
+			continue
+		}
+		events = append(events, list...)
+	}
+	return events, errors.Join(errs...)
+}
+
+// Run polls on every interval until ctx is done
+func (p *Poller) Run(ctx context.Context, emit func(Event) error, onError func(error)) {
+	ticker := time.NewTicker(p.ep.Interval)
+	defer ticker.Stop()
+	for {
+		events, err := p.Poll(ctx)
+		if err != nil && ctx.Err() == nil {
+			onError(err)
+		}
+		for _, event := range events {
+			if err := emit(event); err != nil {
+				onError(fmt.Errorf("%s: %w", p.ep.Name, err))
+			}
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func (p *Poller) pollTarget(ctx context.Context, target map[string]string) ([]Event, error) {
+	ctx, cancel := context.WithTimeout(ctx, p.ep.Timeout) // Covers reading the body as well
+	defer cancel()
+	req, err := p.request(ctx, target)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("http error: %d %s", resp.StatusCode, truncate(data, 200))
+	}
+	subject, err := render(p.subject, target)
+	if err != nil {
+		return nil, err
+	}
+	return p.Extract(data, target, subject)
+}
+
+// request builds the request of a target, signing it when configured
+func (p *Poller) request(ctx context.Context, target map[string]string) (*http.Request, error) {
+	path, err := render(p.path, target)
+	if err != nil {
+		return nil, err
+	}
+	query := url.Values{}
+	for k, t := range p.params {
+		v, err := render(t, target)
+		if err != nil {
+			return nil, err
+		}
+		query.Set(k, v)
+	}
+	rawQuery := query.Encode()
+	if p.ep.Auth.Kind == AuthHMAC {
+		query.Set(p.ep.Auth.TimestampParam, strconv.FormatInt(p.now().UnixMilli(), 10))
+		rawQuery = query.Encode()
+		mac := hmac.New(sha256.New, []byte(p.ep.Auth.Secret))
+		mac.Write([]byte(rawQuery))
+		rawQuery += "&" + url.QueryEscape(p.ep.Auth.SignatureParam) + "=" + hex.EncodeToString(mac.Sum(nil))
+	}
+
+	var body io.Reader
+	if p.ep.Body != "" {
+		rendered, err := render(p.body, target)
+		if err != nil {
+			return nil, err
+		}
+		body = strings.NewReader(rendered)
+	}
+	reqURL := strings.TrimRight(p.ep.BaseURL, "/") + path
+	if rawQuery != "" {
+		reqURL += "?" + rawQuery
+	}
+	req, err := http.NewRequestWithContext(ctx, p.ep.Method, reqURL, body)
+	if err != nil {
+		return nil, err
+	}
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	for k, t := range p.headers {
+		v, err := render(t, target)
+		if err != nil {
+			return nil, err
+		}
+		req.Header.Set(k, v)
+	}
+	switch p.ep.Auth.Kind {
+	case AuthBearer:
+		req.Header.Set("Authorization", "Bearer "+p.ep.Auth.Secret)
+	case AuthHMAC:
+		if p.ep.Auth.KeyHeader != "" {
+			req.Header.Set(p.ep.Auth.KeyHeader, p.ep.Auth.APIKey)
+		}
+	}
+	return req, nil
+}
+
+// Extract converts a response into events, one per item when Items is set
+func (p *Poller) Extract(data []byte, target map[string]string, subject string) ([]Event, error) {
+	var doc any
+	if err := json.Unmarshal(data, &doc); err != nil {
+		return nil, fmt.Errorf("invalid JSON response: %w", err)
+	}
+	items := []any{doc}
+	if p.items != nil {
+		items = p.items.Select(doc)
+	}
+	timestamp := p.now().UnixMilli()
+	events := make([]Event, 0, len(items))
+	for i, item := range items {
+		event := Event{
+			Type:      p.ep.EventType,
+			Source:    p.ep.Name,
+			Target:    target,
+			Fields:    make(map[string]any, len(p.fields)),
+			Timestamp: timestamp,
+			Subject:   subject,
+		}
+		if len(target) == 0 {
+			event.Target = nil
+		}
+		for _, f := range p.fields {
+			value, err := f.extract(item)
+			if err != nil {
+				return nil, fmt.Errorf("item %d: field %s: %w", i, f.Name, err)
+			}
+			if value != nil {
+				event.Fields[f.Name] = value
+			}
+		}
+		events = append(events, event)
+	}
+	return events, nil
+}
+
+func (f compiledField) extract(item any) (any, error) {
+	values := f.path.Select(item)
+	if len(values) == 0 {
+		if f.Optional {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("%s selects nothing", f.path)
+	}
+	if !f.path.Wildcard() {
+		return convert(values[0], f.Type)
+	}
+	list := make([]any, len(values))
+	for i, v := range values {
+		converted, err := convert(v, f.Type)
+		if err != nil {
+			return nil, err
+		}
+		list[i] = converted
+	}
+	return list, nil
+}
+
+// convert coerces a decoded JSON value. Exchanges send decimals as strings
+// to preserve precision, so numbers are parsed from strings as well.
+func convert(v any, t FieldType) (any, error) {
+	switch t {
+	case TypeNumber:
+		switch x := v.(type) {
+		case float64:
+			return x, nil
+		case string:
+			return strconv.ParseFloat(x, 64)
+		}
+	case TypeInt:
+		switch x := v.(type) {
+		case float64:
+			return int64(x), nil
+		case string:
+			return strconv.ParseInt(x, 10, 64)
+		}
+	case TypeString:
+		switch x := v.(type) {
+		case string:
+			return x, nil
+		case float64:
+			return strconv.FormatFloat(x, 'f', -1, 64), nil
+		case bool:
+			return strconv.FormatBool(x), nil
+		}
+	case TypeBool:
+		switch x := v.(type) {
+		case bool:
+			return x, nil
+		case string:
+			return strconv.ParseBool(x)
+		}
+	case TypeRaw:
+		return v, nil
+	}
+	return nil, fmt.Errorf("cannot convert %T to %s", v, t)
+}
+
+func truncate(data []byte, n int) string {
+	if len(data) > n {
+		return string(data[:n]) + "..."
+	}
+	return string(data)
+}
@@ -0,0 +1,203 @@
+package poller
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestPathSelect(t *testing.T) {
+	var doc any
+	if err := json.Unmarshal([]byte(`{"data":{"list":[{"a":1},{"a":2},{"a":3}],"odd-key":"x"},"m":{"b":2,"a":1}}`), &doc); err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		expr string
+		want string
+	}{
+		{"$", `[{"data":{"list":[{"a":1},{"a":2},{"a":3}],"odd-key":"x"},"m":{"a":1,"b":2}}]`},
+		{"$.data.list[0].a", `[1]`},
+		{"$.data.list[-1].a", `[3]`},
+		{"$.data.list[*].a", `[1,2,3]`},
+		{"$.data['odd-key']", `["x"]`},
+		{"$.m.*", `[1,2]`},
+		{"$.data.list[5]", `null`},
+		{"$.missing.a", `null`},
+	}
+	for _, tt := range tests {
+		p, err := CompilePath(tt.expr)
+		if err != nil {
+			t.Fatalf("%s: %v", tt.expr, err)
+		}
+		got, _ := json.Marshal(p.Select(doc))
+		if string(got) != tt.want {
+			t.Errorf("%s: got %s, want %s", tt.expr, got, tt.want)
+		}
+	}
+
+	for _, expr := range []string{"data.a", "$.a[", "$.a[x]", "$..a"} {
+		if _, err := CompilePath(expr); err == nil {
+			t.Errorf("%s: expected an error", expr)
+		}
+	}
+}
+
+func TestPollerPoll(t *testing.T) {
+	var paths []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		paths = append(paths, r.URL.RequestURI())
+		if r.URL.Query().Get("symbol") == "ETHUSDT" {
+			http.Error(w, "unknown symbol", http.StatusBadRequest)
+			return
+		}
+		w.Write([]byte(`{"result":{"rates":[{"rate":"0.0001","time":1000,"live":true},{"rate":"0.0002","time":2000,"live":false}]}}`))
+	}))
+	defer server.Close()
+
+	p, err := New(Endpoint{
+		Name:      "funding",
+		EventType: "funding_rate",
+		BaseURL:   server.URL,
+		Path:      "/v1/{{lower .symbol}}/funding",
+		Params:    map[string]string{"symbol": "{{.symbol}}"},
+		Subject:   "poller.funding.{{lower .symbol}}",
+		Targets:   []map[string]string{{"symbol": "BTCUSDT"}, {"symbol": "ETHUSDT"}},
+		Items:     "$.result.rates[*]",
+		Fields: []Field{
+			{Name: "rate", Path: "$.rate", Type: TypeNumber},
+			{Name: "time", Path: "$.time", Type: TypeInt},
+			{Name: "live", Path: "$.live", Type: TypeBool},
+			{Name: "note", Path: "$.note", Type: TypeString, Optional: true},
+		},
+		Interval: time.Minute,
+	}, server.Client())
+	if err != nil {
+		t.Fatal(err)
+	}
+	p.now = func() time.Time { return time.UnixMilli(5000) }
+
+	events, err := p.Poll(context.Background())
+	if err == nil || !strings.Contains(err.Error(), "400") {
+		t.Fatalf("expected the ETHUSDT failure, got %v", err)
+	}
+	if len(paths) != 2 || paths[0] != "/v1/btcusdt/funding?symbol=BTCUSDT" {
+		t.Fatalf("unexpected requests %v", paths)
+	}
+	if len(events) != 2 {
+		t.Fatalf("expected 2 events, got %d", len(events))
+	}
+	e := events[1]
+	if e.Type != "funding_rate" || e.Source != "funding" || e.Subject != "poller.funding.btcusdt" || e.Target["symbol"] != "BTCUSDT" || e.Timestamp != 5000 {
+		t.Errorf("unexpected event %+v", e)
+	}
+	if e.Fields["rate"] != 0.0002 || e.Fields["time"] != int64(2000) || e.Fields["live"] != false {
+		t.Errorf("unexpected fields %v", e.Fields)
+	}
+	if _, ok := e.Fields["note"]; ok {
+		t.Errorf("optional missing field should be left out")
+	}
+}
+
+func TestPollerRequiredField(t *testing.T) {
+	p, err := New(Endpoint{
+		Name:     "price",
+		BaseURL:  "http://localhost",
+		Subject:  "poller.price",
+		Fields:   []Field{{Name: "price", Path: "$.price", Type: TypeNumber}},
+		Interval: time.Second,
+	}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := p.Extract([]byte(`{"last":"1"}`), nil, "poller.price"); err == nil {
+		t.Error("expected an error for a missing field")
+	}
+	if _, err := p.Extract([]byte(`{"price":"abc"}`), nil, "poller.price"); err == nil {
+		t.Error("expected an error for an invalid number")
+	}
+	events, err := p.Extract([]byte(`{"price":"101.5"}`), nil, "poller.price")
+	if err != nil || len(events) != 1 || events[0].Fields["price"] != 101.5 || events[0].Target != nil {
+		t.Errorf("unexpected events %+v, %v", events, err)
+	}
+}
+
+func TestPollerHMAC(t *testing.T) {
+	const secret = "secret"
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("X-MBX-APIKEY") != "key" {
+			http.Error(w, "missing key", http.StatusUnauthorized)
+			return
+		}
+		query, signature, _ := strings.Cut(r.URL.RawQuery, "&signature=")
+		mac := hmac.New(sha256.New, []byte(secret))
+		mac.Write([]byte(query))
+		if hex.EncodeToString(mac.Sum(nil)) != signature || !strings.Contains(query, "timestamp=7000") {
+			http.Error(w, "invalid signature", http.StatusUnauthorized)
+			return
+		}
+		w.Write([]byte(`{"balance":"12.5"}`))
+	}))
+	defer server.Close()
+
+	p, err := New(Endpoint{
+		Name:     "balance",
+		BaseURL:  server.URL,
+		Path:     "/api/balance",
+		Params:   map[string]string{"asset": "USDT", "recvWindow": "5000"},
+		Subject:  "poller.balance",
+		Fields:   []Field{{Name: "balance", Path: "$.balance", Type: TypeNumber}},
+		Auth:     Auth{Kind: AuthHMAC, APIKey: "key", Secret: secret, KeyHeader: "X-MBX-APIKEY"},
+		Interval: time.Minute,
+	}, server.Client())
+	if err != nil {
+		t.Fatal(err)
+	}
+	p.now = func() time.Time { return time.UnixMilli(7000) }
+	events, err := p.Poll(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(events) != 1 || events[0].Fields["balance"] != 12.5 {
+		t.Errorf("unexpected events %+v", events)
+	}
+}
+
+func TestNewValidation(t *testing.T) {
+	valid := Endpoint{
+		Name:     "x",
+		BaseURL:  "https://example.com",
+		Subject:  "poller.x",
+		Fields:   []Field{{Name: "v", Path: "$.v"}},
+		Interval: time.Second,
+	}
+	if _, err := New(valid, nil); err != nil {
+		t.Fatal(err)
+	}
+	broken := []func(*Endpoint){
+		func(e *Endpoint) { e.Name = "" },
+		func(e *Endpoint) { e.BaseURL = "example" },
+		func(e *Endpoint) { e.Interval = 0 },
+		func(e *Endpoint) { e.Method = "DELETE" },
+		func(e *Endpoint) { e.Subject = "" },
+		func(e *Endpoint) { e.Path = "/{{.symbol" },
+		func(e *Endpoint) { e.Fields = nil },
+		func(e *Endpoint) { e.Fields = []Field{{Name: "v", Path: "v"}} },
+		func(e *Endpoint) { e.Fields = []Field{{Name: "v", Path: "$.v", Type: "decimal"}} },
+		func(e *Endpoint) { e.Auth = Auth{Kind: AuthBearer} },
+		func(e *Endpoint) { e.Auth = Auth{Kind: "oauth", Secret: "s"} },
+	}
+	for i, breakIt := range broken {
+		ep := valid
+		breakIt(&ep)
+		if _, err := New(ep, nil); err == nil {
+			t.Errorf("case %d: expected an error", i)
+		}
+	}
+}
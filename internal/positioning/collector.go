@@ -0,0 +1,247 @@
+// Package positioning polls the positioning statistics of Binance USDⓈ-M
+// futures, open interest, long/short ratios and taker volume, and normalizes
+// them into sqx models so strategies can use them alongside prices.
+package positioning
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/BullionBear/sequex/internal/model/sqx"
+	"github.com/BullionBear/sequex/pkg/exchange/binanceperp"
+)
+
+// Dataset is a positioning statistic
+type Dataset string
+
+const (
+	DatasetOpenInterest  Dataset = "open_interest"
+	DatasetTopPositions  Dataset = Dataset(sqx.LongShortTopPositions)
+	DatasetTopAccounts   Dataset = Dataset(sqx.LongShortTopAccounts)
+	DatasetGlobalAccount Dataset = Dataset(sqx.LongShortGlobalAccount)
+	DatasetTakerVolume   Dataset = "taker_volume"
+)
+
+// Datasets lists every dataset a collector can poll
+var Datasets = []Dataset{DatasetOpenInterest, DatasetTopPositions, DatasetTopAccounts, DatasetGlobalAccount, DatasetTakerVolume}
+
+// ParseDataset validates a dataset name
+func ParseDataset(s string) (Dataset, error) {
+	for _, d := range Datasets {
+		if string(d) == s {
+			return d, nil
+		}
+	}
+	return "", fmt.Errorf("unknown dataset %q", s)
+}
+
+// Periods are the sampling periods Binance computes the statistics over
+var Periods = []string{"5m", "15m", "30m", "1h", "2h", "4h", "6h", "12h", "1d"}
+
+// Source is the subset of the Binance USDⓈ-M client the collector polls
+type Source interface {
+	GetOpenInterestHist(ctx context.Context, req binanceperp.GetFuturesDataRequest) (binanceperp.Response[[]binanceperp.OpenInterestHist], error)
+	GetTopLongShortPositionRatio(ctx context.Context, req binanceperp.GetFuturesDataRequest) (binanceperp.Response[[]binanceperp.LongShortRatio], error)
+	GetTopLongShortAccountRatio(ctx context.Context, req binanceperp.GetFuturesDataRequest) (binanceperp.Response[[]binanceperp.LongShortRatio], error)
+	GetGlobalLongShortAccountRatio(ctx context.Context, req binanceperp.GetFuturesDataRequest) (binanceperp.Response[[]binanceperp.LongShortRatio], error)
+	GetTakerVolumeRatio(ctx context.Context, req binanceperp.GetFuturesDataRequest) (binanceperp.Response[[]binanceperp.TakerVolumeRatio], error)
+}
+
+// Record is a normalized statistic: *sqx.OpenInterest, *sqx.LongShortRatio
+// or *sqx.TakerVolume
+type Record interface {
+	Marshal() ([]byte, error)
+	IdStr() string
+}
+
+// Event is a statistic ready to be published
+type Event struct {
+	Dataset Dataset
+	Symbol  sqx.Symbol
+	Record  Record
+}
+
+// Options configures a collector
+type Options struct {
+	Symbols  []sqx.Symbol
+	Datasets []Dataset // Every dataset when empty
+	Period   string    // Sampling period, 5m when empty
+}
+
+// Collector polls the latest period of every dataset and symbol and emits
+// each period once
+type Collector struct {
+	source   Source
+	opts     Options
+	mu       sync.Mutex
+	lastSeen map[string]int64 // Timestamp of the last emitted period keyed by dataset and symbol
+}
+
+// NewCollector validates the options and creates a collector
+func NewCollector(source Source, opts Options) (*Collector, error) {
+	if len(opts.Symbols) == 0 {
+		return nil, fmt.Errorf("symbols cannot be empty")
+	}
+	if len(opts.Datasets) == 0 {
+		opts.Datasets = Datasets
+	}
+	if opts.Period == "" {
+		opts.Period = "5m"
+	}
+	valid := false
+	for _, p := range Periods {
+		valid = valid || p == opts.Period
+	}
+	if !valid {
+		return nil, fmt.Errorf("unsupported period %q", opts.Period)
+	}
+	return &Collector{source: source, opts: opts, lastSeen: make(map[string]int64)}, nil
+}
+
+// Poll fetches every dataset once and emits the periods not emitted before.
+// Failures of one dataset do not prevent the others from being polled; they
+// are returned together.
+func (c *Collector) Poll(ctx context.Context, emit func(Event) error) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	var errs []error
+	for _, symbol := range c.opts.Symbols {
+		for _, dataset := range c.opts.Datasets {
+			record, timestamp, err := c.fetch(ctx, dataset, symbol)
+			if err != nil {
+				errs = append(errs, fmt.Errorf("%s %s: %w", dataset, symbol, err))
+				continue
+			}
+			key := string(dataset) + "/" + symbol.String()
+			if record == nil || timestamp <= c.lastSeen[key] {
+				continue
+			}
+			if err := emit(Event{Dataset: dataset, Symbol: symbol, Record: record}); err != nil {
+				errs = append(errs, fmt.Errorf("%s %s: %w", dataset, symbol, err))
+				continue
+			}
+			c.lastSeen[key] = timestamp
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// Run polls on every interval until ctx is done, reporting errors to onError
+func (c *Collector) Run(ctx context.Context, interval time.Duration, emit func(Event) error, onError func(error)) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		if err := c.Poll(ctx, emit); err != nil && ctx.Err() == nil {
+			onError(err)
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// fetch returns the latest period of a dataset, nil when none is available
+func (c *Collector) fetch(ctx context.Context, dataset Dataset, symbol sqx.Symbol) (Record, int64, error) {
+	req := binanceperp.GetFuturesDataRequest{Symbol: symbol.Base + symbol.Quote, Period: c.opts.Period, Limit: 1}
+	switch dataset {
+	case DatasetOpenInterest:
+		resp, err := c.source.GetOpenInterestHist(ctx, req)
+		if err != nil || resp.Data == nil || len(*resp.Data) == 0 {
+			return nil, 0, err
+		}
+		record, err := convertOpenInterest(symbol, c.opts.Period, (*resp.Data)[len(*resp.Data)-1])
+		return record, record.Timestamp, err
+	case DatasetTopPositions, DatasetTopAccounts, DatasetGlobalAccount:
+		get := c.source.GetGlobalLongShortAccountRatio
+		switch dataset {
+		case DatasetTopPositions:
+			get = c.source.GetTopLongShortPositionRatio
+		case DatasetTopAccounts:
+			get = c.source.GetTopLongShortAccountRatio
+		}
+		resp, err := get(ctx, req)
+		if err != nil || resp.Data == nil || len(*resp.Data) == 0 {
+			return nil, 0, err
+		}
+		record, err := convertLongShortRatio(symbol, sqx.LongShortRatioKind(dataset), c.opts.Period, (*resp.Data)[len(*resp.Data)-1])
+		return record, record.Timestamp, err
+	case DatasetTakerVolume:
+		resp, err := c.source.GetTakerVolumeRatio(ctx, req)
+		if err != nil || resp.Data == nil || len(*resp.Data) == 0 {
+			return nil, 0, err
+		}
+		record, err := convertTakerVolume(symbol, c.opts.Period, (*resp.Data)[len(*resp.Data)-1])
+		return record, record.Timestamp, err
+	}
+	return nil, 0, fmt.Errorf("unknown dataset %q", dataset)
+}
+
+// parseFloats parses the decimal strings of a Binance statistic in order
+func parseFloats(fields ...string) ([]float64, error) {
+	values := make([]float64, len(fields))
+	for i, field := range fields {
+		v, err := strconv.ParseFloat(field, 64)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse %q: %w", field, err)
+		}
+		values[i] = v
+	}
+	return values, nil
+}
+
+func convertOpenInterest(symbol sqx.Symbol, period string, hist binanceperp.OpenInterestHist) (*sqx.OpenInterest, error) {
+	v, err := parseFloats(hist.SumOpenInterest, hist.SumOpenInterestValue)
+	if err != nil {
+		return &sqx.OpenInterest{}, err
+	}
+	return &sqx.OpenInterest{
+		Exchange:       sqx.ExchangeBinancePerp,
+		InstrumentType: sqx.InstrumentTypePerp,
+		Symbol:         symbol,
+		Quantity:       v[0],
+		Notional:       v[1],
+		Period:         period,
+		Timestamp:      hist.Timestamp,
+	}, nil
+}
+
+func convertLongShortRatio(symbol sqx.Symbol, kind sqx.LongShortRatioKind, period string, ratio binanceperp.LongShortRatio) (*sqx.LongShortRatio, error) {
+	v, err := parseFloats(ratio.LongShortRatio, ratio.LongAccount, ratio.ShortAccount)
+	if err != nil {
+		return &sqx.LongShortRatio{}, err
+	}
+	return &sqx.LongShortRatio{
+		Exchange:       sqx.ExchangeBinancePerp,
+		InstrumentType: sqx.InstrumentTypePerp,
+		Symbol:         symbol,
+		Kind:           kind,
+		Ratio:          v[0],
+		Long:           v[1],
+		Short:          v[2],
+		Period:         period,
+		Timestamp:      ratio.Timestamp,
+	}, nil
+}
+
+func convertTakerVolume(symbol sqx.Symbol, period string, ratio binanceperp.TakerVolumeRatio) (*sqx.TakerVolume, error) {
+	v, err := parseFloats(ratio.BuySellRatio, ratio.BuyVol, ratio.SellVol)
+	if err != nil {
+		return &sqx.TakerVolume{}, err
+	}
+	return &sqx.TakerVolume{
+		Exchange:       sqx.ExchangeBinancePerp,
+		InstrumentType: sqx.InstrumentTypePerp,
+		Symbol:         symbol,
+		Ratio:          v[0],
+		BuyVolume:      v[1],
+		SellVolume:     v[2],
+		Period:         period,
+		Timestamp:      ratio.Timestamp,
+	}, nil
+}
@@ -0,0 +1,106 @@
+package positioning
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/BullionBear/sequex/internal/model/sqx"
+	"github.com/BullionBear/sequex/pkg/exchange/binanceperp"
+)
+
+type fakeSource struct {
+	timestamp int64
+	takerErr  error
+	requests  []binanceperp.GetFuturesDataRequest
+}
+
+func ok[T any](data T) (binanceperp.Response[T], error) {
+	return binanceperp.Response[T]{Message: "success", Data: &data}, nil
+}
+
+func (f *fakeSource) GetOpenInterestHist(_ context.Context, req binanceperp.GetFuturesDataRequest) (binanceperp.Response[[]binanceperp.OpenInterestHist], error) {
+	f.requests = append(f.requests, req)
+	return ok([]binanceperp.OpenInterestHist{{Symbol: req.Symbol, SumOpenInterest: "80000.5", SumOpenInterestValue: "8000000000", Timestamp: f.timestamp}})
+}
+
+func (f *fakeSource) ratio(req binanceperp.GetFuturesDataRequest) (binanceperp.Response[[]binanceperp.LongShortRatio], error) {
+	f.requests = append(f.requests, req)
+	return ok([]binanceperp.LongShortRatio{{Symbol: req.Symbol, LongShortRatio: "1.5", LongAccount: "0.6", ShortAccount: "0.4", Timestamp: f.timestamp}})
+}
+
+func (f *fakeSource) GetTopLongShortPositionRatio(_ context.Context, req binanceperp.GetFuturesDataRequest) (binanceperp.Response[[]binanceperp.LongShortRatio], error) {
+	return f.ratio(req)
+}
+
+func (f *fakeSource) GetTopLongShortAccountRatio(_ context.Context, req binanceperp.GetFuturesDataRequest) (binanceperp.Response[[]binanceperp.LongShortRatio], error) {
+	return f.ratio(req)
+}
+
+func (f *fakeSource) GetGlobalLongShortAccountRatio(_ context.Context, req binanceperp.GetFuturesDataRequest) (binanceperp.Response[[]binanceperp.LongShortRatio], error) {
+	return f.ratio(req)
+}
+
+func (f *fakeSource) GetTakerVolumeRatio(_ context.Context, req binanceperp.GetFuturesDataRequest) (binanceperp.Response[[]binanceperp.TakerVolumeRatio], error) {
+	f.requests = append(f.requests, req)
+	if f.takerErr != nil {
+		return binanceperp.Response[[]binanceperp.TakerVolumeRatio]{}, f.takerErr
+	}
+	return ok([]binanceperp.TakerVolumeRatio{{BuySellRatio: "0.8", BuyVol: "400", SellVol: "500", Timestamp: f.timestamp}})
+}
+
+func TestCollectorPoll(t *testing.T) {
+	source := &fakeSource{timestamp: 1000}
+	c, err := NewCollector(source, Options{Symbols: []sqx.Symbol{sqx.NewSymbol("BTC", "USDT")}, Period: "15m"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var events []Event
+	emit := func(e Event) error {
+		events = append(events, e)
+		return nil
+	}
+	if err := c.Poll(context.Background(), emit); err != nil {
+		t.Fatal(err)
+	}
+	if len(events) != len(Datasets) {
+		t.Fatalf("events = %d, want %d", len(events), len(Datasets))
+	}
+	if req := source.requests[0]; req.Symbol != "BTCUSDT" || req.Period != "15m" || req.Limit != 1 {
+		t.Errorf("request = %+v", req)
+	}
+	oi := events[0].Record.(*sqx.OpenInterest)
+	if oi.Quantity != 80000.5 || oi.Notional != 8e9 || oi.Period != "15m" || oi.Timestamp != 1000 {
+		t.Errorf("open interest = %+v", oi)
+	}
+	if r := events[1].Record.(*sqx.LongShortRatio); r.Kind != sqx.LongShortTopPositions || r.Ratio != 1.5 || r.Long != 0.6 {
+		t.Errorf("long/short ratio = %+v", r)
+	}
+	if v := events[4].Record.(*sqx.TakerVolume); v.BuyVolume != 400 || v.SellVolume != 500 || v.Ratio != 0.8 {
+		t.Errorf("taker volume = %+v", v)
+	}
+
+	// The same period is emitted once, a failing dataset does not block the others
+	events = nil
+	if err := c.Poll(context.Background(), emit); err != nil || len(events) != 0 {
+		t.Errorf("repeated poll: %v, %d events", err, len(events))
+	}
+	source.timestamp = 2000
+	source.takerErr = errors.New("http error: 429")
+	if err := c.Poll(context.Background(), emit); err == nil || len(events) != len(Datasets)-1 {
+		t.Errorf("partial poll: %v, %d events", err, len(events))
+	}
+}
+
+func TestCollectorOptions(t *testing.T) {
+	if _, err := NewCollector(&fakeSource{}, Options{}); err == nil {
+		t.Error("collector without symbols accepted")
+	}
+	if _, err := NewCollector(&fakeSource{}, Options{Symbols: []sqx.Symbol{sqx.NewSymbol("BTC", "USDT")}, Period: "1m"}); err == nil {
+		t.Error("unsupported period accepted")
+	}
+	if _, err := ParseDataset("funding"); err == nil {
+		t.Error("unknown dataset accepted")
+	}
+}
@@ -0,0 +1,119 @@
+package publisher
+
+import (
+	"sync"
+	"time"
+
+	"github.com/BullionBear/sequex/internal/model/sqx"
+)
+
+// FlushFunc publishes a batch of trades. The slice must not be retained.
+type FlushFunc func(trades []sqx.Trade) error
+
+// ErrorFunc is called with errors of flushes triggered by the timer
+type ErrorFunc func(err error, trades []sqx.Trade)
+
+// BatchPublisher accumulates trades and flushes them when the batch reaches
+// size trades or when the oldest buffered trade is older than interval
+type BatchPublisher struct {
+	mu       sync.Mutex
+	size     int
+	interval time.Duration
+	flush    FlushFunc
+	onError  ErrorFunc
+	trades   []sqx.Trade
+	first    time.Time // arrival time of the oldest buffered trade
+	done     chan struct{}
+	wg       sync.WaitGroup
+	closed   bool
+}
+
+// NewBatchPublisher creates a batching publisher. A background goroutine
+// enforces the flush interval until Close is called.
+func NewBatchPublisher(size int, interval time.Duration, flush FlushFunc, onError ErrorFunc) *BatchPublisher {
+	if size <= 0 {
+		size = 1
+	}
+	p := &BatchPublisher{
+		size:     size,
+		interval: interval,
+		flush:    flush,
+		onError:  onError,
+		trades:   make([]sqx.Trade, 0, size),
+		done:     make(chan struct{}),
+	}
+	if interval > 0 {
+		p.wg.Add(1)
+		go p.run()
+	}
+	return p
+}
+
+// Add buffers a trade, flushing synchronously when the batch is full
+func (p *BatchPublisher) Add(trade sqx.Trade) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if len(p.trades) == 0 {
+		p.first = time.Now()
+	}
+	p.trades = append(p.trades, trade)
+	if len(p.trades) >= p.size {
+		return p.flushLocked()
+	}
+	return nil
+}
+
+// Flush publishes buffered trades immediately
+func (p *BatchPublisher) Flush() error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.flushLocked()
+}
+
+// Close stops the interval timer and flushes remaining trades
+func (p *BatchPublisher) Close() error {
+	p.mu.Lock()
+	if p.closed {
+		p.mu.Unlock()
+		return nil
+	}
+	p.closed = true
+	close(p.done)
+	p.mu.Unlock()
+
+	p.wg.Wait()
+	return p.Flush()
+}
+
+func (p *BatchPublisher) flushLocked() error {
+	if len(p.trades) == 0 {
+		return nil
+	}
+	err := p.flush(p.trades)
+	// Trades are dropped from the buffer even on error; the caller decides
+	// whether to retry, as it does for unbatched publishes
+	p.trades = p.trades[:0]
+	return err
+}
+
+func (p *BatchPublisher) run() {
+	defer p.wg.Done()
+	// Tick at a fraction of the interval so a batch never waits much longer than interval
+	ticker := time.NewTicker(max(p.interval/4, time.Millisecond))
+	defer ticker.Stop()
+	for {
+		select {
+		case <-p.done:
+			return
+		case now := <-ticker.C:
+			p.mu.Lock()
+			if len(p.trades) > 0 && now.Sub(p.first) >= p.interval {
+				if err := p.flush(p.trades); err != nil && p.onError != nil {
+					p.onError(err, p.trades)
+				}
+				p.trades = p.trades[:0]
+			}
+			p.mu.Unlock()
+		}
+	}
+}
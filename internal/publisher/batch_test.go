@@ -0,0 +1,124 @@
+package publisher
+
+import (
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/BullionBear/sequex/internal/model/sqx"
+	"github.com/nats-io/nats.go"
+)
+
+type flushRecorder struct {
+	mu      sync.Mutex
+	batches [][]sqx.Trade
+}
+
+func (r *flushRecorder) flush(trades []sqx.Trade) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.batches = append(r.batches, append([]sqx.Trade(nil), trades...))
+	return nil
+}
+
+func (r *flushRecorder) count() int {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return len(r.batches)
+}
+
+func newTrade(id int64) sqx.Trade {
+	return sqx.Trade{
+		Id:             id,
+		Symbol:         sqx.NewSymbol("BTC", "USDT"),
+		Exchange:       sqx.ExchangeBinance,
+		InstrumentType: sqx.InstrumentTypeSpot,
+		TakerSide:      sqx.SideSell,
+		Price:          60000,
+		Quantity:       0.1,
+		Timestamp:      1700000000000 + id,
+	}
+}
+
+func TestBatchPublisher_FlushOnSize(t *testing.T) {
+	recorder := &flushRecorder{}
+	p := NewBatchPublisher(3, time.Hour, recorder.flush, nil)
+	defer p.Close()
+
+	for i := int64(1); i <= 7; i++ {
+		if err := p.Add(newTrade(i)); err != nil {
+			t.Fatalf("Add() returned error: %v", err)
+		}
+	}
+	if recorder.count() != 2 {
+		t.Fatalf("expected 2 full batches, got %d", recorder.count())
+	}
+	if recorder.batches[1][0].Id != 4 || len(recorder.batches[1]) != 3 {
+		t.Errorf("unexpected second batch %+v", recorder.batches[1])
+	}
+
+	if err := p.Close(); err != nil {
+		t.Fatalf("Close() returned error: %v", err)
+	}
+	if recorder.count() != 3 || recorder.batches[2][0].Id != 7 {
+		t.Errorf("expected Close() to flush the partial batch, got %d batches", recorder.count())
+	}
+}
+
+func TestBatchPublisher_FlushOnInterval(t *testing.T) {
+	recorder := &flushRecorder{}
+	p := NewBatchPublisher(100, 20*time.Millisecond, recorder.flush, nil)
+	defer p.Close()
+
+	p.Add(newTrade(1))
+	p.Add(newTrade(2))
+
+	deadline := time.Now().Add(time.Second)
+	for recorder.count() == 0 && time.Now().Before(deadline) {
+		time.Sleep(5 * time.Millisecond)
+	}
+	if recorder.count() != 1 || len(recorder.batches[0]) != 2 {
+		t.Fatalf("expected one batch of 2 trades flushed by the timer, got %v", recorder.batches)
+	}
+}
+
+func TestUnbatch(t *testing.T) {
+	trades := []sqx.Trade{newTrade(1), newTrade(2), newTrade(3)}
+	data, err := sqx.MarshalTradeBatch(trades)
+	if err != nil {
+		t.Fatalf("MarshalTradeBatch() returned error: %v", err)
+	}
+
+	msg := &nats.Msg{
+		Subject: "trade.binance.spot.btcusdt",
+		Data:    data,
+		Header:  nats.Header{sqx.HeaderTradeBatch: []string{strconv.Itoa(len(trades))}},
+	}
+	payloads, err := Unbatch(msg)
+	if err != nil {
+		t.Fatalf("Unbatch() returned error: %v", err)
+	}
+	if len(payloads) != 3 {
+		t.Fatalf("expected 3 payloads, got %d", len(payloads))
+	}
+	var trade sqx.Trade
+	if err := sqx.Unmarshal(payloads[2], &trade); err != nil || trade.Id != 3 {
+		t.Errorf("third payload = %+v, err = %v", trade, err)
+	}
+
+	nine := newTrade(9)
+	single, _ := nine.Marshal()
+	payloads, err = Unbatch(&nats.Msg{Subject: "trade.binance.spot.btcusdt", Data: single})
+	if err != nil || len(payloads) != 1 {
+		t.Fatalf("Unbatch() of single trade = %d payloads, err = %v", len(payloads), err)
+	}
+
+	msg.Header.Set(sqx.HeaderTradeBatch, "5")
+	if _, err := Unbatch(msg); err == nil {
+		t.Error("expected error when header count does not match payload")
+	}
+	if id := sqx.TradeBatchIdStr(trades); id != trades[0].IdStr()+"-3" {
+		t.Errorf("TradeBatchIdStr() = %s", id)
+	}
+}
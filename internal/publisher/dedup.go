@@ -0,0 +1,393 @@
+package publisher
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/BullionBear/sequex/internal/model/sqx"
+)
+
+// DedupStats is a snapshot of deduplication metrics
+type DedupStats struct {
+	Passed  uint64 // Trades seen for the first time
+	Dropped uint64 // Trades dropped as duplicates
+}
+
+// Dedup drops trades that were already published, keyed by exchange trade id
+// per exchange, instrument and symbol. Nats-Msg-Id only deduplicates within
+// the JetStream duplicate window; Dedup also covers reconnect overlaps,
+// replays and backfills across restarts.
+//
+// It remembers the published ids of every instrument as runs of consecutive
+// ids, so a stream without gaps costs one run however long it gets, and only
+// an id inside a run is a duplicate: a late or backfilled id filling a gap
+// is let through. When an instrument has more than window runs the lowest
+// is forgotten. Ids are appended to a file so the runs survive restarts; the
+// file is compacted when it grows to twice the window of every instrument.
+//
+// An id is claimed while it is published, so concurrent publishes of the
+// same trade go out once: the later waits for the earlier and is dropped if
+// it succeeded. It is safe for concurrent use.
+type Dedup struct {
+	mu       sync.Mutex
+	path     string
+	window   int
+	windows  map[string]*idRuns // Keyed by exchange-instrument-symbol
+	inflight map[claim]chan struct{}
+	file     *os.File
+	w        *bufio.Writer
+	lines    int // Lines in the file since it was compacted
+	stats    DedupStats
+}
+
+// claim is a trade id being published
+type claim struct {
+	key string
+	id  int64
+}
+
+// idRun is a run of consecutive ids, from lo to hi included
+type idRun struct {
+	lo, hi int64
+}
+
+// idRuns holds the runs of ids of one instrument, sorted and disjoint
+type idRuns struct {
+	runs []idRun
+	size int
+}
+
+func newIDRuns(size int) *idRuns {
+	return &idRuns{size: size}
+}
+
+func (r *idRuns) contains(id int64) bool {
+	i := sort.Search(len(r.runs), func(i int) bool { return r.runs[i].hi >= id })
+	return i < len(r.runs) && r.runs[i].lo <= id
+}
+
+// add remembers an id not contained yet, extending or joining the runs it
+// is next to
+func (r *idRuns) add(id int64) {
+	r.insert(idRun{lo: id, hi: id})
+}
+
+// insert merges a run into the runs, forgetting the lowest past size
+func (r *idRuns) insert(run idRun) {
+	i := sort.Search(len(r.runs), func(i int) bool { return r.runs[i].hi >= run.lo-1 })
+	j := i
+	for j < len(r.runs) && r.runs[j].lo <= run.hi+1 {
+		run.lo, run.hi = min(run.lo, r.runs[j].lo), max(run.hi, r.runs[j].hi)
+		j++
+	}
+	r.runs = append(r.runs[:i], append([]idRun{run}, r.runs[j:]...)...)
+	if len(r.runs) > r.size {
+		r.runs = r.runs[len(r.runs)-r.size:]
+	}
+}
+
+// NewDedup creates a filter remembering window runs of ids per instrument,
+// 100000 when 0. With a path, the ids recorded by a previous run are loaded
+// from it and new ones appended to it; without, they are kept in memory only.
+func NewDedup(path string, window int) (*Dedup, error) {
+	if window <= 0 {
+		window = 100000
+	}
+	d := &Dedup{path: path, window: window, windows: make(map[string]*idRuns), inflight: make(map[claim]chan struct{})}
+	if path == "" {
+		return d, nil
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return nil, err
+	}
+	if err := d.load(); err != nil {
+		return nil, err
+	}
+	if err := d.compact(); err != nil {
+		return nil, err
+	}
+	return d, nil
+}
+
+// dedupKey returns the instrument a trade id is unique within
+func dedupKey(trade *sqx.Trade) string {
+	return trade.Exchange.String() + "-" + trade.InstrumentType.String() + "-" + trade.Symbol.String()
+}
+
+// load replays the file. Lines are "<key> <id>" for a recorded id and
+// "<key> run <lo> <hi>" for a run of a compacted file; a line cut by a crash
+// is skipped, as are the "<key> floor <id>" lines of earlier versions.
+func (d *Dedup) load() error {
+	file, err := os.Open(d.path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to open dedup file %s: %w", d.path, err)
+	}
+	defer file.Close()
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) < 2 {
+			continue
+		}
+		switch {
+		case len(fields) == 2:
+			id, err := strconv.ParseInt(fields[1], 10, 64)
+			if err != nil {
+				continue
+			}
+			if w := d.windowOf(fields[0]); !w.contains(id) {
+				w.add(id)
+			}
+		case len(fields) == 4 && fields[1] == "run":
+			lo, err := strconv.ParseInt(fields[2], 10, 64)
+			if err != nil {
+				continue
+			}
+			hi, err := strconv.ParseInt(fields[3], 10, 64)
+			if err != nil || hi < lo {
+				continue
+			}
+			d.windowOf(fields[0]).insert(idRun{lo: lo, hi: hi})
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return fmt.Errorf("failed to read dedup file %s: %w", d.path, err)
+	}
+	return nil
+}
+
+func (d *Dedup) windowOf(key string) *idRuns {
+	w, ok := d.windows[key]
+	if !ok {
+		w = newIDRuns(d.window)
+		d.windows[key] = w
+	}
+	return w
+}
+
+// compact rewrites the file with the remembered runs only and reopens it for
+// appending. The file is replaced atomically so a crash keeps the old one.
+func (d *Dedup) compact() error {
+	if d.file != nil {
+		if err := d.closeFile(); err != nil {
+			return err
+		}
+	}
+	tmp := d.path + ".tmp"
+	file, err := os.Create(tmp)
+	if err != nil {
+		return fmt.Errorf("failed to compact dedup file %s: %w", d.path, err)
+	}
+	w := bufio.NewWriter(file)
+	lines := 0
+	for key, window := range d.windows {
+		for _, run := range window.runs {
+			fmt.Fprintf(w, "%s run %d %d\n", key, run.lo, run.hi)
+			lines++
+		}
+	}
+	err = w.Flush()
+	if err == nil {
+		err = file.Sync()
+	}
+	if err = errors.Join(err, file.Close()); err != nil {
+		os.Remove(tmp)
+		return fmt.Errorf("failed to compact dedup file %s: %w", d.path, err)
+	}
+	if err := os.Rename(tmp, d.path); err != nil {
+		return fmt.Errorf("failed to compact dedup file %s: %w", d.path, err)
+	}
+	d.file, err = os.OpenFile(d.path, os.O_APPEND|os.O_WRONLY, 0o644)
+	if err != nil {
+		return fmt.Errorf("failed to open dedup file %s: %w", d.path, err)
+	}
+	d.w = bufio.NewWriter(d.file)
+	d.lines = lines
+	return nil
+}
+
+func (d *Dedup) closeFile() error {
+	err := d.w.Flush()
+	err = errors.Join(err, d.file.Close())
+	d.file, d.w = nil, nil
+	return err
+}
+
+// Seen reports whether the trade was already recorded
+func (d *Dedup) Seen(trade sqx.Trade) bool {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	w, ok := d.windows[dedupKey(&trade)]
+	return ok && w.contains(trade.Id)
+}
+
+// Record remembers the trade, appending its id to the file
+func (d *Dedup) Record(trade sqx.Trade) error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return d.record(dedupKey(&trade), trade.Id)
+}
+
+func (d *Dedup) record(key string, id int64) error {
+	w := d.windowOf(key)
+	if w.contains(id) {
+		return nil
+	}
+	w.add(id)
+	if d.w == nil {
+		return nil
+	}
+	if _, err := fmt.Fprintf(d.w, "%s %d\n", key, id); err != nil {
+		return fmt.Errorf("failed to write dedup file %s: %w", d.path, err)
+	}
+	d.lines++
+	if d.lines >= 2*d.window*len(d.windows) {
+		return d.compact()
+	}
+	return nil
+}
+
+// claim reserves the id of a trade for publishing, waiting while another
+// caller publishes it. It reports false when the trade was published.
+func (d *Dedup) claim(c claim) bool {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	for {
+		if w, ok := d.windows[c.key]; ok && w.contains(c.id) {
+			d.stats.Dropped++
+			return false
+		}
+		wait, ok := d.inflight[c]
+		if !ok {
+			d.inflight[c] = make(chan struct{})
+			return true
+		}
+		d.mu.Unlock()
+		<-wait
+		d.mu.Lock()
+	}
+}
+
+// release ends the claim of an id, recording it when it was published
+func (d *Dedup) release(c claim, published bool) error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	var err error
+	if published {
+		d.stats.Passed++
+		err = d.record(c.key, c.id)
+	}
+	close(d.inflight[c])
+	delete(d.inflight, c)
+	return err
+}
+
+// Filter wraps publish so trades already published are dropped. It belongs
+// around the publish to NATS itself, not a queue or a batch ahead of it: a
+// trade is only recorded once published, so one that failed or was dropped
+// is let through again.
+func (d *Dedup) Filter(publish PublishFunc) PublishFunc {
+	return func(trade sqx.Trade) error {
+		c := claim{key: dedupKey(&trade), id: trade.Id}
+		if !d.claim(c) {
+			return nil
+		}
+		if err := publish(trade); err != nil {
+			d.release(c, false)
+			return err
+		}
+		return d.release(c, true)
+	}
+}
+
+// FilterBatch wraps the publish of batches as Filter does, flushing the
+// trades of a batch not yet published. Claims are taken in order so
+// overlapping batches cannot wait on each other.
+func (d *Dedup) FilterBatch(flush FlushFunc) FlushFunc {
+	return func(trades []sqx.Trade) error {
+		claims := make([]claim, len(trades))
+		for i := range trades {
+			claims[i] = claim{key: dedupKey(&trades[i]), id: trades[i].Id}
+		}
+		order := append([]claim(nil), claims...)
+		sort.Slice(order, func(i, j int) bool {
+			if order[i].key != order[j].key {
+				return order[i].key < order[j].key
+			}
+			return order[i].id < order[j].id
+		})
+		var claimed []claim
+		mine := make(map[claim]bool, len(order))
+		for i, c := range order {
+			if i > 0 && order[i-1] == c {
+				d.mu.Lock()
+				d.stats.Dropped++
+				d.mu.Unlock()
+				continue
+			}
+			if d.claim(c) {
+				claimed = append(claimed, c)
+				mine[c] = true
+			}
+		}
+		if len(claimed) == 0 {
+			return nil
+		}
+		batch := make([]sqx.Trade, 0, len(claimed))
+		for i, trade := range trades {
+			if mine[claims[i]] {
+				batch = append(batch, trade)
+				mine[claims[i]] = false
+			}
+		}
+		err := flush(batch)
+		var errs []error
+		for _, c := range claimed {
+			errs = append(errs, d.release(c, err == nil))
+		}
+		if err != nil {
+			return err
+		}
+		return errors.Join(errs...)
+	}
+}
+
+// Stats returns a snapshot of the deduplication metrics
+func (d *Dedup) Stats() DedupStats {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return d.stats
+}
+
+// Sync flushes the recorded ids to disk
+func (d *Dedup) Sync() error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if d.w == nil {
+		return nil
+	}
+	if err := d.w.Flush(); err != nil {
+		return fmt.Errorf("failed to write dedup file %s: %w", d.path, err)
+	}
+	return d.file.Sync()
+}
+
+// Close flushes the recorded ids and closes the file
+func (d *Dedup) Close() error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if d.file == nil {
+		return nil
+	}
+	return d.closeFile()
+}
@@ -0,0 +1,224 @@
+package publisher
+
+import (
+	"errors"
+	"path/filepath"
+	"reflect"
+	"sync"
+	"testing"
+
+	"github.com/BullionBear/sequex/internal/model/sqx"
+)
+
+func TestDedupDropsDuplicates(t *testing.T) {
+	d, err := NewDedup("", 4)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var published []int64
+	fail := true
+	publish := d.Filter(func(trade sqx.Trade) error {
+		if trade.Id == 3 && fail {
+			fail = false
+			return errors.New("nats down")
+		}
+		published = append(published, trade.Id)
+		return nil
+	})
+
+	// A reconnect overlap replays 2 and a failed publish of 3 is retried
+	for _, id := range []int64{1, 2, 3, 2, 3, 4, 5, 6, 7} {
+		publish(newTrade(id))
+	}
+	// 1 to 7 are one run of consecutive ids, remembered whole
+	for _, id := range []int64{1, 3, 7, 8} {
+		publish(newTrade(id))
+	}
+	other := newTrade(1)
+	other.Symbol = sqx.NewSymbol("ETH", "USDT")
+	publish(other)
+
+	want := []int64{1, 2, 3, 4, 5, 6, 7, 8, 1}
+	if len(published) != len(want) {
+		t.Fatalf("published %v, want %v", published, want)
+	}
+	for i := range want {
+		if published[i] != want[i] {
+			t.Fatalf("published %v, want %v", published, want)
+		}
+	}
+	if stats := d.Stats(); stats.Passed != 9 || stats.Dropped != 4 {
+		t.Errorf("stats = %+v", stats)
+	}
+}
+
+func TestDedupPersists(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "dedup", "trades.log")
+	d, err := NewDedup(path, 3)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Enough trades to compact the file more than once
+	for id := int64(1); id <= 20; id++ {
+		if err := d.Record(newTrade(id)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := d.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	d, err = NewDedup(path, 3)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer d.Close()
+	for _, id := range []int64{5, 18, 20} {
+		if !d.Seen(newTrade(id)) {
+			t.Errorf("trade %d not remembered after a restart", id)
+		}
+	}
+	if d.Seen(newTrade(21)) {
+		t.Error("new trade seen")
+	}
+}
+
+func TestDedupLetsLateIdsThrough(t *testing.T) {
+	d, err := NewDedup("", 3)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var published []int64
+	publish := d.Filter(func(trade sqx.Trade) error {
+		published = append(published, trade.Id)
+		return nil
+	})
+
+	// 4 to 9 are late, backfilling the gap between two runs
+	for _, id := range []int64{1, 2, 3, 10, 11, 5, 2, 10, 4, 6, 7, 8, 9, 5} {
+		publish(newTrade(id))
+	}
+	want := []int64{1, 2, 3, 10, 11, 5, 4, 6, 7, 8, 9}
+	if !reflect.DeepEqual(published, want) {
+		t.Fatalf("published %v, want %v", published, want)
+	}
+	trade := newTrade(1)
+	if runs := d.windows[dedupKey(&trade)].runs; len(runs) != 1 {
+		t.Errorf("runs = %v, want the gap filled", runs)
+	}
+
+	// Past the window the lowest run is forgotten: an id of it is let
+	// through rather than a new one dropped
+	published = nil
+	for _, id := range []int64{20, 30, 40, 5} {
+		publish(newTrade(id))
+	}
+	if want := []int64{20, 30, 40, 5}; !reflect.DeepEqual(published, want) {
+		t.Fatalf("published %v, want %v", published, want)
+	}
+}
+
+func TestDedupBehindQueue(t *testing.T) {
+	d, err := NewDedup("", 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	p := newGatedPublisher()
+	q := NewQueue(3, OverflowDropNewest, 0, d.Filter(p.publish), nil, nil)
+	defer q.Close()
+
+	// Trade 1 is in flight and 5 and 6 overflow the queue
+	fill(t, q, 6)
+	close(p.gate)
+	waitFor(t, func() bool { return len(p.published()) == 4 })
+
+	// Dropped by the queue, they were never published nor marked seen
+	for _, id := range []int64{5, 6, 1} {
+		if err := q.Push(newTrade(id)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	waitFor(t, func() bool { return d.Stats().Dropped == 1 })
+	if want := []int64{1, 2, 3, 4, 5, 6}; !reflect.DeepEqual(p.published(), want) {
+		t.Errorf("published %v, want %v", p.published(), want)
+	}
+}
+
+func TestDedupConcurrentPublishes(t *testing.T) {
+	d, err := NewDedup("", 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	p := newGatedPublisher()
+	publish := d.Filter(p.publish)
+
+	var wg sync.WaitGroup
+	for i := 0; i < 4; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if err := publish(newTrade(1)); err != nil {
+				t.Error(err)
+			}
+		}()
+	}
+	// One caller holds the claim while the others wait for it
+	waitFor(t, func() bool {
+		d.mu.Lock()
+		defer d.mu.Unlock()
+		return len(d.inflight) == 1
+	})
+	close(p.gate)
+	wg.Wait()
+
+	if want := []int64{1}; !reflect.DeepEqual(p.published(), want) {
+		t.Errorf("published %v, want %v", p.published(), want)
+	}
+	if stats := d.Stats(); stats.Passed != 1 || stats.Dropped != 3 {
+		t.Errorf("stats = %+v", stats)
+	}
+}
+
+func TestDedupFilterBatch(t *testing.T) {
+	d, err := NewDedup("", 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var flushed [][]int64
+	fail := true
+	flush := d.FilterBatch(func(trades []sqx.Trade) error {
+		if fail {
+			fail = false
+			return errors.New("nats down")
+		}
+		var ids []int64
+		for _, trade := range trades {
+			ids = append(ids, trade.Id)
+		}
+		flushed = append(flushed, ids)
+		return nil
+	})
+	batch := func(ids ...int64) []sqx.Trade {
+		var trades []sqx.Trade
+		for _, id := range ids {
+			trades = append(trades, newTrade(id))
+		}
+		return trades
+	}
+
+	// A failed batch is not recorded, so its retry goes out whole
+	if err := flush(batch(1, 2, 3)); err == nil {
+		t.Fatal("failed flush returned no error")
+	}
+	for _, trades := range [][]sqx.Trade{batch(3, 1, 2, 2), batch(3, 4), batch(1, 2)} {
+		if err := flush(trades); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if want := [][]int64{{3, 1, 2}, {4}}; !reflect.DeepEqual(flushed, want) {
+		t.Errorf("flushed %v, want %v", flushed, want)
+	}
+	if stats := d.Stats(); stats.Passed != 4 || stats.Dropped != 4 {
+		t.Errorf("stats = %+v", stats)
+	}
+}
@@ -0,0 +1,75 @@
+package publisher
+
+import (
+	"sync"
+	"unsafe"
+
+	"github.com/BullionBear/sequex/internal/model/protobuf"
+	"github.com/BullionBear/sequex/internal/model/sqx"
+	"github.com/nats-io/nats.go"
+)
+
+const headerMsgId = "Nats-Msg-Id"
+
+// TradeEncoder turns trades into NATS messages while reusing the protobuf
+// message, the payload and id buffers and the message itself, so a steady
+// stream of trades does not allocate. An encoder is not safe for concurrent
+// use; take one from the pool per publishing goroutine.
+type TradeEncoder struct {
+	pb   protobuf.Trade
+	data []byte
+	id   []byte
+	ids  []string
+	msg  nats.Msg
+}
+
+var tradeEncoders = sync.Pool{
+	New: func() any { return NewTradeEncoder() },
+}
+
+// NewTradeEncoder creates a trade encoder
+func NewTradeEncoder() *TradeEncoder {
+	e := &TradeEncoder{
+		data: make([]byte, 0, 128),
+		id:   make([]byte, 0, 64),
+		ids:  make([]string, 1),
+	}
+	e.msg.Header = nats.Header{headerMsgId: e.ids}
+	return e
+}
+
+// GetTradeEncoder takes an encoder from the shared pool
+func GetTradeEncoder() *TradeEncoder {
+	return tradeEncoders.Get().(*TradeEncoder)
+}
+
+// PutTradeEncoder returns an encoder to the shared pool. Messages it produced
+// must not be used afterwards.
+func PutTradeEncoder(e *TradeEncoder) {
+	tradeEncoders.Put(e)
+}
+
+// Encode builds the message publishing trade on subject with its IdStr as the
+// JetStream deduplication id. The message and its payload are only valid
+// until the next call to Encode, which is enough for a synchronous publish.
+func (e *TradeEncoder) Encode(subject string, trade *sqx.Trade) (*nats.Msg, error) {
+	var err error
+	e.data, err = trade.MarshalAppend(e.data[:0], &e.pb)
+	if err != nil {
+		return nil, err
+	}
+	e.id = trade.AppendIdStr(e.id[:0])
+
+	// Reset fields a publish may have touched, keeping the header map
+	header := e.msg.Header
+	for key := range header {
+		if key != headerMsgId {
+			delete(header, key)
+		}
+	}
+	e.msg = nats.Msg{Subject: subject, Data: e.data, Header: header}
+	// The id aliases the encoder buffer under the same validity rule as the payload
+	e.ids[0] = unsafe.String(unsafe.SliceData(e.id), len(e.id))
+	header[headerMsgId] = e.ids
+	return &e.msg, nil
+}
@@ -0,0 +1,100 @@
+package publisher
+
+import (
+	"testing"
+
+	"github.com/BullionBear/sequex/internal/model/sqx"
+	"github.com/nats-io/nats.go"
+)
+
+func TestTradeEncoder(t *testing.T) {
+	encoder := NewTradeEncoder()
+	for id := int64(1); id <= 3; id++ {
+		trade := newTrade(id)
+		msg, err := encoder.Encode("trade.btcusdt", &trade)
+		if err != nil {
+			t.Fatalf("Encode: %v", err)
+		}
+		if msg.Subject != "trade.btcusdt" {
+			t.Errorf("subject = %q", msg.Subject)
+		}
+		if got := msg.Header.Get(headerMsgId); got != trade.IdStr() {
+			t.Errorf("msg id = %q, want %q", got, trade.IdStr())
+		}
+		var decoded sqx.Trade
+		if err := sqx.Unmarshal(msg.Data, &decoded); err != nil {
+			t.Fatalf("Unmarshal: %v", err)
+		}
+		if decoded != trade {
+			t.Errorf("decoded %+v, want %+v", decoded, trade)
+		}
+		// Headers added by a previous publish must not leak into the next message
+		msg.Header.Set("Sqx-Encoding", "zstd")
+	}
+
+	trade := newTrade(4)
+	msg, _ := encoder.Encode("trade.btcusdt", &trade)
+	if len(msg.Header) != 1 {
+		t.Errorf("header = %v, want only %s", msg.Header, headerMsgId)
+	}
+}
+
+func TestTradeEncoderAllocations(t *testing.T) {
+	encoder := NewTradeEncoder()
+	trade := newTrade(1)
+	allocs := testing.AllocsPerRun(100, func() {
+		trade.Id++
+		if _, err := encoder.Encode("trade.btcusdt", &trade); err != nil {
+			t.Fatal(err)
+		}
+	})
+	if allocs != 0 {
+		t.Errorf("Encode allocates %.1f times per trade", allocs)
+	}
+}
+
+// BenchmarkTradeMessage measures the per-trade encoding the feed used before
+// TradeEncoder, kept as the baseline for comparison
+func BenchmarkTradeMessage(b *testing.B) {
+	trade := newTrade(1)
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		trade.Id++
+		data, err := trade.Marshal()
+		if err != nil {
+			b.Fatal(err)
+		}
+		_ = &nats.Msg{
+			Subject: "trade.btcusdt",
+			Data:    data,
+			Header:  nats.Header{headerMsgId: []string{trade.IdStr()}},
+		}
+	}
+}
+
+func BenchmarkTradeEncoder(b *testing.B) {
+	encoder := NewTradeEncoder()
+	trade := newTrade(1)
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		trade.Id++
+		if _, err := encoder.Encode("trade.btcusdt", &trade); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkTradeEncoderPool(b *testing.B) {
+	b.ReportAllocs()
+	b.RunParallel(func(pb *testing.PB) {
+		trade := newTrade(1)
+		for pb.Next() {
+			trade.Id++
+			encoder := GetTradeEncoder()
+			if _, err := encoder.Encode("trade.btcusdt", &trade); err != nil {
+				b.Error(err)
+			}
+			PutTradeEncoder(encoder)
+		}
+	})
+}
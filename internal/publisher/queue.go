@@ -0,0 +1,215 @@
+package publisher
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+
+	"github.com/BullionBear/sequex/internal/model/sqx"
+)
+
+// OverflowPolicy decides what Push does when the queue is full
+type OverflowPolicy string
+
+const (
+	OverflowBlock      OverflowPolicy = "block"       // Wait for room, pushing backpressure onto the adapter
+	OverflowDropOldest OverflowPolicy = "drop_oldest" // Evict the oldest queued trade
+	OverflowDropNewest OverflowPolicy = "drop_newest" // Discard the trade being pushed
+)
+
+// ParseOverflowPolicy parses a configured policy, defaulting to block
+func ParseOverflowPolicy(s string) (OverflowPolicy, error) {
+	switch OverflowPolicy(s) {
+	case "", OverflowBlock:
+		return OverflowBlock, nil
+	case OverflowDropOldest:
+		return OverflowDropOldest, nil
+	case OverflowDropNewest:
+		return OverflowDropNewest, nil
+	}
+	return "", fmt.Errorf("unknown overflow policy: %s", s)
+}
+
+// ErrQueueClosed is returned by Push after Close
+var ErrQueueClosed = errors.New("publisher queue closed")
+
+// PublishFunc publishes a single trade
+type PublishFunc func(trade sqx.Trade) error
+
+// WatermarkFunc is called when the queue depth crosses the high watermark
+// (high is true) and again once it has drained to half of it
+type WatermarkFunc func(depth int, high bool)
+
+// QueueStats is a snapshot of queue metrics
+type QueueStats struct {
+	Depth         int    // Trades currently queued
+	MaxDepth      int    // Highest depth observed
+	Capacity      int    // Queue capacity
+	Enqueued      uint64 // Trades accepted by Push
+	Published     uint64 // Trades handed to the publish function without error
+	PublishErrors uint64 // Trades the publish function failed on
+	Dropped       uint64 // Trades discarded by the overflow policy
+	Blocked       uint64 // Pushes that had to wait for room
+}
+
+// Queue decouples adapter callbacks from the publisher with a bounded ring
+// buffer drained by a single goroutine, so a slow NATS connection is handled
+// by an explicit overflow policy instead of stalling the websocket reader
+type Queue struct {
+	mu            sync.Mutex
+	notEmpty      *sync.Cond
+	notFull       *sync.Cond
+	items         []sqx.Trade
+	head          int
+	depth         int
+	policy        OverflowPolicy
+	highWatermark int
+	aboveHigh     bool
+	publish       PublishFunc
+	onError       ErrorFunc
+	onWatermark   WatermarkFunc
+	closed        bool
+	done          chan struct{}
+	stats         QueueStats
+}
+
+// NewQueue creates a queue of the given capacity and starts draining it into
+// publish. A highWatermark of 0 disables watermark alerts.
+func NewQueue(capacity int, policy OverflowPolicy, highWatermark int, publish PublishFunc, onError ErrorFunc, onWatermark WatermarkFunc) *Queue {
+	if capacity <= 0 {
+		capacity = 1
+	}
+	q := &Queue{
+		items:         make([]sqx.Trade, capacity),
+		policy:        policy,
+		highWatermark: highWatermark,
+		publish:       publish,
+		onError:       onError,
+		onWatermark:   onWatermark,
+		done:          make(chan struct{}),
+	}
+	q.notEmpty = sync.NewCond(&q.mu)
+	q.notFull = sync.NewCond(&q.mu)
+	q.stats.Capacity = capacity
+	go q.run()
+	return q
+}
+
+// Push queues a trade according to the overflow policy
+func (q *Queue) Push(trade sqx.Trade) error {
+	q.mu.Lock()
+	if q.closed {
+		q.mu.Unlock()
+		return ErrQueueClosed
+	}
+	if q.depth == len(q.items) {
+		switch q.policy {
+		case OverflowDropNewest:
+			q.stats.Dropped++
+			q.mu.Unlock()
+			return nil
+		case OverflowDropOldest:
+			q.head = (q.head + 1) % len(q.items)
+			q.depth--
+			q.stats.Dropped++
+		default:
+			q.stats.Blocked++
+			for q.depth == len(q.items) && !q.closed {
+				q.notFull.Wait()
+			}
+			if q.closed {
+				q.mu.Unlock()
+				return ErrQueueClosed
+			}
+		}
+	}
+
+	q.items[(q.head+q.depth)%len(q.items)] = trade
+	q.depth++
+	q.stats.Enqueued++
+	q.stats.MaxDepth = max(q.stats.MaxDepth, q.depth)
+	alert := q.checkWatermarkLocked()
+	depth := q.depth
+	q.notEmpty.Signal()
+	q.mu.Unlock()
+
+	if alert && q.onWatermark != nil {
+		q.onWatermark(depth, true)
+	}
+	return nil
+}
+
+// checkWatermarkLocked reports whether the depth just crossed the high watermark
+func (q *Queue) checkWatermarkLocked() bool {
+	if q.highWatermark <= 0 || q.aboveHigh || q.depth < q.highWatermark {
+		return false
+	}
+	q.aboveHigh = true
+	return true
+}
+
+// Close stops accepting trades and returns once the queued trades are published
+func (q *Queue) Close() {
+	q.mu.Lock()
+	if q.closed {
+		q.mu.Unlock()
+		<-q.done
+		return
+	}
+	q.closed = true
+	q.notEmpty.Broadcast()
+	q.notFull.Broadcast()
+	q.mu.Unlock()
+	<-q.done
+}
+
+// Stats returns a snapshot of the queue metrics
+func (q *Queue) Stats() QueueStats {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	stats := q.stats
+	stats.Depth = q.depth
+	return stats
+}
+
+func (q *Queue) run() {
+	defer close(q.done)
+	for {
+		q.mu.Lock()
+		for q.depth == 0 && !q.closed {
+			q.notEmpty.Wait()
+		}
+		if q.depth == 0 {
+			q.mu.Unlock()
+			return
+		}
+		trade := q.items[q.head]
+		q.head = (q.head + 1) % len(q.items)
+		q.depth--
+		// Re-arm the alert with hysteresis so a depth hovering around the
+		// watermark does not flood the alert callback
+		recovered := q.aboveHigh && q.depth <= q.highWatermark/2
+		if recovered {
+			q.aboveHigh = false
+		}
+		depth := q.depth
+		q.notFull.Signal()
+		q.mu.Unlock()
+
+		if recovered && q.onWatermark != nil {
+			q.onWatermark(depth, false)
+		}
+
+		err := q.publish(trade)
+		q.mu.Lock()
+		if err != nil {
+			q.stats.PublishErrors++
+		} else {
+			q.stats.Published++
+		}
+		q.mu.Unlock()
+		if err != nil && q.onError != nil {
+			q.onError(err, []sqx.Trade{trade})
+		}
+	}
+}
@@ -0,0 +1,152 @@
+package publisher
+
+import (
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/BullionBear/sequex/internal/model/sqx"
+)
+
+// gatedPublisher blocks every publish until released, simulating a stalled NATS connection
+type gatedPublisher struct {
+	gate chan struct{}
+	mu   sync.Mutex
+	ids  []int64
+}
+
+func newGatedPublisher() *gatedPublisher {
+	return &gatedPublisher{gate: make(chan struct{})}
+}
+
+func (p *gatedPublisher) publish(trade sqx.Trade) error {
+	<-p.gate
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.ids = append(p.ids, trade.Id)
+	return nil
+}
+
+func (p *gatedPublisher) published() []int64 {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return append([]int64(nil), p.ids...)
+}
+
+// fill pushes trades 1..n once the first one is held by the stalled publisher
+func fill(t *testing.T, q *Queue, n int) {
+	t.Helper()
+	if err := q.Push(newTrade(1)); err != nil {
+		t.Fatal(err)
+	}
+	waitFor(t, func() bool { return q.Stats().Depth == 0 })
+	for id := int64(2); id <= int64(n); id++ {
+		if err := q.Push(newTrade(id)); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func waitFor(t *testing.T, cond func() bool) {
+	t.Helper()
+	deadline := time.Now().Add(2 * time.Second)
+	for !cond() {
+		if time.Now().After(deadline) {
+			t.Fatal("condition not met in time")
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+func TestQueueDropPolicies(t *testing.T) {
+	tests := []struct {
+		policy OverflowPolicy
+		want   []int64
+	}{
+		// Trade 1 is in flight, 2..6 compete for 3 slots
+		{OverflowDropNewest, []int64{1, 2, 3, 4}},
+		{OverflowDropOldest, []int64{1, 4, 5, 6}},
+	}
+	for _, tt := range tests {
+		t.Run(string(tt.policy), func(t *testing.T) {
+			p := newGatedPublisher()
+			q := NewQueue(3, tt.policy, 0, p.publish, nil, nil)
+			fill(t, q, 6)
+
+			stats := q.Stats()
+			if stats.Dropped != 2 || stats.Depth != 3 || stats.MaxDepth != 3 {
+				t.Errorf("stats = %+v", stats)
+			}
+			close(p.gate)
+			q.Close()
+
+			got := p.published()
+			if len(got) != len(tt.want) {
+				t.Fatalf("published %v, want %v", got, tt.want)
+			}
+			for i := range got {
+				if got[i] != tt.want[i] {
+					t.Fatalf("published %v, want %v", got, tt.want)
+				}
+			}
+		})
+	}
+}
+
+func TestQueueBlock(t *testing.T) {
+	p := newGatedPublisher()
+	q := NewQueue(2, OverflowBlock, 0, p.publish, nil, nil)
+	fill(t, q, 3)
+
+	pushed := make(chan error)
+	go func() { pushed <- q.Push(newTrade(4)) }()
+	select {
+	case <-pushed:
+		t.Fatal("push returned while the queue was full")
+	case <-time.After(20 * time.Millisecond):
+	}
+
+	close(p.gate)
+	if err := <-pushed; err != nil {
+		t.Fatal(err)
+	}
+	q.Close()
+	if got := p.published(); len(got) != 4 {
+		t.Errorf("published %v", got)
+	}
+	if stats := q.Stats(); stats.Blocked != 1 || stats.Dropped != 0 || stats.Published != 4 {
+		t.Errorf("stats = %+v", stats)
+	}
+	if err := q.Push(newTrade(5)); err != ErrQueueClosed {
+		t.Errorf("Push after Close = %v", err)
+	}
+}
+
+func TestQueueWatermark(t *testing.T) {
+	var mu sync.Mutex
+	var alerts []bool
+	p := newGatedPublisher()
+	q := NewQueue(10, OverflowDropNewest, 4, p.publish, nil, func(depth int, high bool) {
+		mu.Lock()
+		defer mu.Unlock()
+		alerts = append(alerts, high)
+	})
+	fill(t, q, 8)
+	close(p.gate)
+	q.Close()
+
+	mu.Lock()
+	defer mu.Unlock()
+	if len(alerts) != 2 || !alerts[0] || alerts[1] {
+		t.Errorf("alerts = %v, want one high then one recovery", alerts)
+	}
+}
+
+func TestParseOverflowPolicy(t *testing.T) {
+	if policy, err := ParseOverflowPolicy(""); err != nil || policy != OverflowBlock {
+		t.Errorf("default policy = %q, %v", policy, err)
+	}
+	if _, err := ParseOverflowPolicy("drop_random"); err == nil {
+		t.Error("expected error for unknown policy")
+	}
+}
@@ -0,0 +1,29 @@
+package publisher
+
+import (
+	"github.com/BullionBear/sequex/internal/model/sqx"
+	"github.com/nats-io/nats.go"
+)
+
+// Unbatch splits a batched trade message into single-trade payloads so
+// consumers written for one trade per message keep working. Messages without
+// the batch header are returned unchanged.
+func Unbatch(msg *nats.Msg) ([][]byte, error) {
+	batchHeader := msg.Header.Get(sqx.HeaderTradeBatch)
+	if batchHeader == "" {
+		return [][]byte{msg.Data}, nil
+	}
+	trades, err := sqx.UnmarshalTrades(msg.Data, batchHeader)
+	if err != nil {
+		return nil, err
+	}
+	payloads := make([][]byte, 0, len(trades))
+	for i := range trades {
+		data, err := trades[i].Marshal()
+		if err != nil {
+			return nil, err
+		}
+		payloads = append(payloads, data)
+	}
+	return payloads, nil
+}
@@ -0,0 +1,142 @@
+package replay
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+
+	"github.com/BullionBear/sequex/internal/model/protobuf"
+)
+
+// ManifestSuffix is appended to the name of a recorded segment to name its
+// sidecar manifest
+const ManifestSuffix = ".manifest.json"
+
+// Manifest describes a recorded segment so that its integrity can be checked
+// after transfers and archival restores
+type Manifest struct {
+	File           string `json:"file"` // Base name of the segment
+	Size           int64  `json:"size"`
+	SHA256         string `json:"sha256"`
+	Messages       int    `json:"messages"`        // Valid trades
+	FirstTimestamp int64  `json:"first_timestamp"` // Of the first trade recorded, milliseconds
+	LastTimestamp  int64  `json:"last_timestamp"`  // Of the last trade recorded, milliseconds
+	FirstID        int64  `json:"first_id"`
+	LastID         int64  `json:"last_id"`
+}
+
+// ManifestPath returns the path of the manifest of the segment at path
+func ManifestPath(path string) string {
+	return path + ManifestSuffix
+}
+
+// BuildManifest reads a recorded segment in one pass, hashing it and
+// counting its trades
+func BuildManifest(r io.Reader, name string) (*Manifest, error) {
+	m := &Manifest{File: filepath.Base(name)}
+	hash := sha256.New()
+	counter := &countingWriter{w: hash}
+	_, _, err := ReadTrades(io.TeeReader(r, counter), func(n int, trade *protobuf.Trade) error {
+		if n == 1 {
+			m.FirstTimestamp, m.FirstID = trade.Timestamp, trade.Id
+		}
+		m.LastTimestamp, m.LastID = trade.Timestamp, trade.Id
+		m.Messages = n
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	m.Size = counter.n
+	m.SHA256 = hex.EncodeToString(hash.Sum(nil))
+	return m, nil
+}
+
+// BuildManifestFile builds the manifest of the segment at path
+func BuildManifestFile(path string) (*Manifest, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open file %s: %w", path, err)
+	}
+	defer file.Close()
+	return BuildManifest(file, path)
+}
+
+// WriteManifest builds the manifest of the segment at path and writes it
+// next to the segment
+func WriteManifest(path string) (*Manifest, error) {
+	m, err := BuildManifestFile(path)
+	if err != nil {
+		return nil, err
+	}
+	data, err := json.MarshalIndent(m, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	if err := os.WriteFile(ManifestPath(path), append(data, '\n'), 0o644); err != nil {
+		return nil, fmt.Errorf("failed to write manifest: %w", err)
+	}
+	return m, nil
+}
+
+// ReadManifest reads the manifest of the segment at path
+func ReadManifest(path string) (*Manifest, error) {
+	data, err := os.ReadFile(ManifestPath(path))
+	if err != nil {
+		return nil, err
+	}
+	var m Manifest
+	if err := json.Unmarshal(data, &m); err != nil {
+		return nil, fmt.Errorf("invalid manifest %s: %w", ManifestPath(path), err)
+	}
+	return &m, nil
+}
+
+// Compare lists the fields of got that differ from the manifest m, the
+// file name aside since segments may be renamed when archived
+func (m *Manifest) Compare(got *Manifest) []string {
+	var diffs []string
+	check := func(field string, want, have any) {
+		if want != have {
+			diffs = append(diffs, fmt.Sprintf("%s: expected %v, got %v", field, want, have))
+		}
+	}
+	check("size", m.Size, got.Size)
+	check("sha256", m.SHA256, got.SHA256)
+	check("messages", m.Messages, got.Messages)
+	check("first_timestamp", m.FirstTimestamp, got.FirstTimestamp)
+	check("last_timestamp", m.LastTimestamp, got.LastTimestamp)
+	check("first_id", m.FirstID, got.FirstID)
+	check("last_id", m.LastID, got.LastID)
+	return diffs
+}
+
+// VerifyFile checks the segment at path against its manifest, returning the
+// differences found, none when the segment is intact
+func VerifyFile(path string) ([]string, error) {
+	want, err := ReadManifest(path)
+	if err != nil {
+		return nil, err
+	}
+	got, err := BuildManifestFile(path)
+	if err != nil {
+		return nil, err
+	}
+	return want.Compare(got), nil
+}
+
+// countingWriter counts the bytes written through it
+type countingWriter struct {
+	w io.Writer
+	n int64
+}
+
+func (c *countingWriter) Write(p []byte) (int, error) {
+	n, err := c.w.Write(p)
+	c.n += int64(n)
+	return n, err
+}
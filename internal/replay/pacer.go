@@ -0,0 +1,56 @@
+// Package replay republishes recorded trades, paced like the original
+// stream and routed to subjects derived from each trade.
+package replay
+
+import (
+	"context"
+	"time"
+)
+
+// Pacer spaces replayed events like the recording, optionally accelerated
+type Pacer struct {
+	speed float64
+	now   func() time.Time
+	sleep func(context.Context, time.Duration) error
+
+	started bool
+	start   time.Time // Wall clock of the first event
+	first   int64     // Timestamp of the first event in milliseconds
+}
+
+// NewPacer creates a pacer replaying speed times faster than recorded: 1 is
+// the original pacing, 0 publishes as fast as possible
+func NewPacer(speed float64) *Pacer {
+	return &Pacer{speed: speed, now: time.Now, sleep: sleepContext}
+}
+
+// Wait blocks until the event recorded at timestamp, in milliseconds, is due.
+// Events recorded out of order are released immediately.
+func (p *Pacer) Wait(ctx context.Context, timestamp int64) error {
+	if p.speed <= 0 {
+		return ctx.Err()
+	}
+	if !p.started {
+		p.started = true
+		p.start = p.now()
+		p.first = timestamp
+		return ctx.Err()
+	}
+	offset := time.Duration(float64(timestamp-p.first) / p.speed * float64(time.Millisecond))
+	delay := p.start.Add(offset).Sub(p.now())
+	if delay <= 0 {
+		return ctx.Err()
+	}
+	return p.sleep(ctx, delay)
+}
+
+func sleepContext(ctx context.Context, d time.Duration) error {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
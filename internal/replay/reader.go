@@ -0,0 +1,254 @@
+package replay
+
+import (
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/BullionBear/sequex/internal/model/protobuf"
+	"google.golang.org/protobuf/encoding/protowire"
+	"google.golang.org/protobuf/proto"
+)
+
+// ReadTrades scans a recording of serialized trades, either bare Trade
+// messages or TradeBatch entries, and hands each valid trade to handle with
+// its position among the valid trades, starting at 1. It stops at the first
+// error handle returns. Undecodable bytes are skipped, so a truncated or
+// corrupted recording yields the trades that can still be recovered.
+func ReadTrades(r io.Reader, handle func(int, *protobuf.Trade) error) (successCount, totalProcessed int, err error) {
+	buffer := make([]byte, 1024*1024) // 1MB buffer
+	var accumulated []byte
+
+	for {
+		n, readErr := r.Read(buffer)
+
+		if n > 0 {
+			accumulated = append(accumulated, buffer[:n]...)
+		}
+
+		if readErr == io.EOF && len(accumulated) == 0 {
+			break
+		}
+
+		// Process accumulated data
+		for len(accumulated) >= 10 { // Minimum viable message size
+			messageData, consumed, found := parseBatchEntry(accumulated)
+			if !found {
+				messageData, consumed, found = parseNextMessage(accumulated)
+			}
+			if !found {
+				// Skip one byte and try again
+				accumulated = accumulated[1:]
+				continue
+			}
+
+			trade := &protobuf.Trade{}
+			if err := proto.Unmarshal(messageData, trade); err == nil {
+				totalProcessed++
+
+				// Validate trade message
+				if isValidTradeMessage(trade) {
+					successCount++
+
+					if err := handle(successCount, trade); err != nil {
+						return successCount, totalProcessed, err
+					}
+				}
+			}
+
+			accumulated = accumulated[consumed:]
+		}
+
+		if readErr == io.EOF {
+			break
+		}
+		if readErr != nil {
+			return successCount, totalProcessed, fmt.Errorf("error reading file: %w", readErr)
+		}
+	}
+
+	return successCount, totalProcessed, nil
+}
+
+// tradeBatchEntryTag is the key of TradeBatch.trades (field 1, length-delimited).
+// A recorded TradeBatch is a sequence of such entries, each wrapping a Trade.
+const tradeBatchEntryTag = 0x0a
+
+// parseBatchEntry parses one Trade wrapped in a TradeBatch entry from the data
+func parseBatchEntry(data []byte) (messageData []byte, consumed int, found bool) {
+	if len(data) < 2 || data[0] != tradeBatchEntryTag {
+		return nil, 0, false
+	}
+	candidate, n := protowire.ConsumeBytes(data[1:])
+	if n < 0 {
+		return nil, 0, false
+	}
+	trade := &protobuf.Trade{}
+	if err := proto.Unmarshal(candidate, trade); err != nil || !isValidTradeMessage(trade) {
+		return nil, 0, false
+	}
+	return candidate, 1 + n, true
+}
+
+// parseNextMessage parses the next complete protobuf message from the data
+func parseNextMessage(data []byte) (messageData []byte, consumed int, found bool) {
+	if len(data) < 10 {
+		return nil, 0, false
+	}
+
+	// Parse protobuf wire format to find message boundaries
+	offset := 0
+	fieldsSeen := make(map[int]bool)
+
+	for offset < len(data) && offset < 200 { // Reasonable upper bound
+		if offset+1 >= len(data) {
+			break
+		}
+
+		// Read field header (field number + wire type)
+		fieldHeader := data[offset]
+		fieldNum := int(fieldHeader >> 3)
+		wireType := int(fieldHeader & 0x7)
+		offset++
+
+		// Skip invalid field numbers (protobuf fields start at 1)
+		if fieldNum == 0 || fieldNum > 20 {
+			break
+		}
+
+		// Skip the field data based on wire type
+		fieldLength, ok := skipFieldData(data[offset:], wireType)
+		if !ok {
+			break
+		}
+		offset += fieldLength
+		fieldsSeen[fieldNum] = true
+
+		// Check if we have a complete Trade message
+		// Trade has fields: 1=id, 2=exchange, 3=instrument, 4=symbol, 5=side, 7=price, 8=quantity, 9=timestamp
+		if hasAllExpectedFields(fieldsSeen) {
+			// We've seen all expected fields, try to parse
+			candidate := data[:offset]
+			trade := &protobuf.Trade{}
+			if err := proto.Unmarshal(candidate, trade); err == nil && isValidTradeMessage(trade) {
+				return candidate, offset, true
+			}
+		}
+	}
+
+	return nil, 0, false
+}
+
+// skipFieldData skips over field data based on wire type
+func skipFieldData(data []byte, wireType int) (int, bool) {
+	switch wireType {
+	case 0: // Varint
+		return skipVarint(data)
+	case 1: // 64-bit fixed
+		if len(data) < 8 {
+			return 0, false
+		}
+		return 8, true
+	case 2: // Length-delimited (strings, bytes, embedded messages)
+		return skipLengthDelimited(data)
+	case 5: // 32-bit fixed
+		if len(data) < 4 {
+			return 0, false
+		}
+		return 4, true
+	default:
+		return 0, false // Unknown wire type
+	}
+}
+
+// skipVarint skips over a varint-encoded value
+func skipVarint(data []byte) (int, bool) {
+	for i := 0; i < len(data) && i < 10; i++ { // Max 10 bytes for varint
+		if data[i]&0x80 == 0 {
+			return i + 1, true
+		}
+	}
+	return 0, false
+}
+
+// skipLengthDelimited skips over a length-delimited field. Lengths past the
+// end of the data, however large, are rejected.
+func skipLengthDelimited(data []byte) (int, bool) {
+	length, lengthBytes := protowire.ConsumeVarint(data)
+	if lengthBytes < 0 || length > uint64(len(data)-lengthBytes) {
+		return 0, false
+	}
+	return lengthBytes + int(length), true
+}
+
+// hasAllExpectedFields checks if we've seen all the expected fields for a complete Trade message
+func hasAllExpectedFields(fieldsSeen map[int]bool) bool {
+	// All expected Trade fields: 1=id, 2=exchange, 3=instrument, 4=symbol, 5=side, 7=price, 8=quantity, 9=timestamp
+	expectedFields := []int{1, 2, 3, 4, 5, 7, 8, 9}
+
+	for _, field := range expectedFields {
+		if !fieldsSeen[field] {
+			return false
+		}
+	}
+
+	return true
+}
+
+// ReadTradeFile reads the recorded trades of a file, see ReadTrades
+func ReadTradeFile(filename string, handle func(int, *protobuf.Trade) error) (successCount, totalProcessed int, err error) {
+	file, err := os.Open(filename)
+	if err != nil {
+		return 0, 0, fmt.Errorf("failed to open file %s: %w", filename, err)
+	}
+	defer file.Close()
+	return ReadTrades(file, handle)
+}
+
+// isValidTradeMessage validates that a Trade message contains reasonable data
+func isValidTradeMessage(trade *protobuf.Trade) bool {
+	validFields := 0
+
+	// ID should be positive
+	if trade.Id > 0 {
+		validFields++
+	}
+
+	// Exchange should be valid (1-3 for known exchanges)
+	if trade.Exchange >= 1 && trade.Exchange <= 3 {
+		validFields++
+	}
+
+	// Instrument should be valid
+	if trade.Instrument >= 1 && trade.Instrument <= 6 {
+		validFields++
+	}
+
+	// Symbol should exist and have reasonable values
+	if trade.Symbol != nil && len(trade.Symbol.Base) >= 2 && len(trade.Symbol.Quote) >= 3 {
+		validFields++
+	}
+
+	// Side should be buy or sell
+	if trade.Side >= 1 && trade.Side <= 2 {
+		validFields++
+	}
+
+	// Price should be reasonable (between $0.01 and $1M)
+	if trade.Price >= 0.01 && trade.Price <= 1000000 {
+		validFields++
+	}
+
+	// Quantity should be positive
+	if trade.Quantity > 0 {
+		validFields++
+	}
+
+	// Timestamp should be reasonable (2020-2030)
+	if trade.Timestamp >= 1577836800000 && trade.Timestamp <= 1893456000000 {
+		validFields++
+	}
+
+	// Require at least 6 out of 8 fields to be valid
+	return validFields >= 6
+}
@@ -0,0 +1,263 @@
+package replay
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"testing/quick"
+	"time"
+
+	"github.com/BullionBear/sequex/internal/model/protobuf"
+	"github.com/BullionBear/sequex/internal/model/sqx"
+)
+
+func TestReadTrades(t *testing.T) {
+	trade := func(id int64) sqx.Trade {
+		return sqx.Trade{
+			Id:             id,
+			Symbol:         sqx.NewSymbol("BTC", "USDT"),
+			Exchange:       sqx.ExchangeBinance,
+			InstrumentType: sqx.InstrumentTypeSpot,
+			TakerSide:      sqx.SideBuy,
+			Price:          60000,
+			Quantity:       0.5,
+			Timestamp:      1757894400000 + id,
+		}
+	}
+	batch, err := sqx.MarshalTradeBatch([]sqx.Trade{trade(1), trade(2)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	single := trade(3)
+	bare, err := single.Marshal()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var recording bytes.Buffer
+	recording.Write([]byte{0xff, 0xff}) // Corrupted bytes are skipped
+	recording.Write(batch)
+	recording.Write(bare)
+
+	var ids []int64
+	valid, _, err := ReadTrades(&recording, func(n int, pb *protobuf.Trade) error {
+		if n != len(ids)+1 {
+			t.Errorf("trade %d numbered %d", len(ids)+1, n)
+		}
+		ids = append(ids, pb.Id)
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if valid != 3 || len(ids) != 3 || ids[0] != 1 || ids[2] != 3 {
+		t.Errorf("read %d trades %v", valid, ids)
+	}
+}
+
+func TestPacer(t *testing.T) {
+	clock := time.UnixMilli(0)
+	var slept []time.Duration
+	p := NewPacer(2)
+	p.now = func() time.Time { return clock }
+	p.sleep = func(_ context.Context, d time.Duration) error {
+		slept = append(slept, d)
+		clock = clock.Add(d)
+		return nil
+	}
+
+	ctx := context.Background()
+	for _, ts := range []int64{1000, 1400, 1300, 2000} {
+		if err := p.Wait(ctx, ts); err != nil {
+			t.Fatal(err)
+		}
+	}
+	// 400ms recorded wait 200ms at twice the speed, the late 1300 is released
+	// immediately and 2000 is due 500ms after the start
+	want := []time.Duration{200 * time.Millisecond, 300 * time.Millisecond}
+	if len(slept) != len(want) || slept[0] != want[0] || slept[1] != want[1] {
+		t.Errorf("slept %v, want %v", slept, want)
+	}
+
+	unpaced := NewPacer(0)
+	unpaced.sleep = func(context.Context, time.Duration) error {
+		t.Fatal("speed 0 should not wait")
+		return nil
+	}
+	unpaced.Wait(ctx, 0)
+	unpaced.Wait(ctx, 60000)
+
+	cancelled, cancel := context.WithCancel(ctx)
+	cancel()
+	if err := NewPacer(1).Wait(cancelled, 0); err == nil {
+		t.Error("expected the context error")
+	}
+}
+
+func TestSubjects(t *testing.T) {
+	trade := &sqx.Trade{
+		Symbol:         sqx.NewSymbol("btc", "usdt"),
+		Exchange:       sqx.ExchangeBinancePerp,
+		InstrumentType: sqx.InstrumentTypePerp,
+	}
+	s, err := NewSubjects("trade.{exchange}.{instrument}.{symbol}", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := s.Subject(trade); got != "trade.binance_perp.perp.btcusdt" {
+		t.Errorf("got %s", got)
+	}
+
+	remap, err := ParseRemap("trade.binance_perp=staging.trade.bp")
+	if err != nil {
+		t.Fatal(err)
+	}
+	s, _ = NewSubjects("trade.{exchange}.{base}-{quote}", []Remap{{From: "trade.binance", To: "x"}, remap})
+	if got := s.Subject(trade); got != "staging.trade.bp.btc-usdt" {
+		t.Errorf("remap should match whole tokens, got %s", got)
+	}
+
+	for _, template := range []string{"", "trade.{venue}", "trade.{symbol"} {
+		if _, err := NewSubjects(template, nil); err == nil {
+			t.Errorf("%q: expected an error", template)
+		}
+	}
+	for _, r := range []string{"trade", "=x", "x="} {
+		if _, err := ParseRemap(r); err == nil {
+			t.Errorf("%q: expected an error", r)
+		}
+	}
+}
+
+func TestManifest(t *testing.T) {
+	var recording []byte
+	for id := int64(1); id <= 3; id++ {
+		trade := sqx.Trade{
+			Id:             id,
+			Symbol:         sqx.NewSymbol("BTC", "USDT"),
+			Exchange:       sqx.ExchangeBinance,
+			InstrumentType: sqx.InstrumentTypeSpot,
+			TakerSide:      sqx.SideSell,
+			Price:          60000,
+			Quantity:       0.5,
+			Timestamp:      1757894400000 + id,
+		}
+		data, err := trade.Marshal()
+		if err != nil {
+			t.Fatal(err)
+		}
+		recording = append(recording, data...)
+	}
+	path := filepath.Join(t.TempDir(), "trades.raw")
+	if err := os.WriteFile(path, recording, 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	m, err := WriteManifest(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if m.File != "trades.raw" || m.Size != int64(len(recording)) || m.Messages != 3 ||
+		m.FirstID != 1 || m.LastID != 3 || m.FirstTimestamp != 1757894400001 || m.LastTimestamp != 1757894400003 {
+		t.Errorf("manifest = %+v", m)
+	}
+	if diffs, err := VerifyFile(path); err != nil || len(diffs) != 0 {
+		t.Errorf("verify intact = %v, %v", diffs, err)
+	}
+
+	// A segment truncated in transfer loses its last trade
+	if err := os.WriteFile(path, recording[:len(recording)-5], 0o644); err != nil {
+		t.Fatal(err)
+	}
+	diffs, err := VerifyFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(diffs) != 5 { // Size, hash, count, last timestamp and last ID
+		t.Errorf("verify truncated = %v", diffs)
+	}
+}
+
+func FuzzReadTrades(f *testing.F) {
+	trade := sqx.Trade{Id: 7, Symbol: sqx.NewSymbol("BTC", "USDT"), Exchange: sqx.ExchangeBinance, InstrumentType: sqx.InstrumentTypeSpot,
+		TakerSide: sqx.SideSell, Price: 60000, Quantity: 0.01, Timestamp: 1757894400000}
+	bare, err := trade.Marshal()
+	if err != nil {
+		f.Fatal(err)
+	}
+	batch, err := sqx.MarshalTradeBatch([]sqx.Trade{trade, trade})
+	if err != nil {
+		f.Fatal(err)
+	}
+	f.Add(bare)
+	f.Add(batch)
+	f.Add(append(append([]byte{0x0a, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0x7f}, bare...), batch...))
+	f.Add(append([]byte{0x22, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0x01}, bare...))
+	f.Fuzz(func(t *testing.T, data []byte) {
+		valid, total, err := ReadTrades(bytes.NewReader(data), func(n int, pb *protobuf.Trade) error {
+			var trade sqx.Trade
+			if trade.FromProtobuf(pb) == nil {
+				trade.IdStr()
+			}
+			return nil
+		})
+		if err != nil || valid > total {
+			t.Fatalf("read %d valid trades of %d: %v", valid, total, err)
+		}
+	})
+}
+
+// TestReadTradesRecovers checks that trades written bare or batched are all
+// read back in order, whatever the trades and however they are framed
+func TestReadTradesRecovers(t *testing.T) {
+	property := func(seeds []uint32, batched []bool) bool {
+		var recording bytes.Buffer
+		var want []int64
+		for i, seed := range seeds {
+			trade := sqx.Trade{
+				Id:             1 + int64(seed),
+				Symbol:         sqx.NewSymbol([]string{"BTC", "ETH", "SOL", "1000PEPE"}[seed%4], "USDT"),
+				Exchange:       sqx.Exchange(1 + seed%3),
+				InstrumentType: sqx.InstrumentType(1 + seed%6),
+				TakerSide:      sqx.Side(1 + seed%2),
+				Price:          0.01 + float64(seed%1000000),
+				Quantity:       0.001 * float64(1+seed%5000),
+				Timestamp:      1577836800000 + int64(seed)*1000,
+			}
+			var data []byte
+			var err error
+			if i < len(batched) && batched[i] {
+				data, err = sqx.MarshalTradeBatch([]sqx.Trade{trade})
+			} else {
+				data, err = trade.Marshal()
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			recording.Write(data)
+			want = append(want, trade.Id)
+		}
+		var got []int64
+		if _, _, err := ReadTrades(&recording, func(_ int, pb *protobuf.Trade) error {
+			got = append(got, pb.Id)
+			return nil
+		}); err != nil {
+			return false
+		}
+		if len(got) != len(want) {
+			t.Logf("read %v, wrote %v", got, want)
+			return false
+		}
+		for i := range want {
+			if got[i] != want[i] {
+				t.Logf("read %v, wrote %v", got, want)
+				return false
+			}
+		}
+		return true
+	}
+	if err := quick.Check(property, nil); err != nil {
+		t.Error(err)
+	}
+}
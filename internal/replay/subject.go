@@ -0,0 +1,77 @@
+package replay
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/BullionBear/sequex/internal/model/sqx"
+)
+
+// Remap rewrites a subject prefix, matched on token boundaries
+type Remap struct {
+	From string
+	To   string
+}
+
+// ParseRemap parses a from=to remapping
+func ParseRemap(s string) (Remap, error) {
+	from, to, ok := strings.Cut(s, "=")
+	from, to = strings.TrimSpace(from), strings.TrimSpace(to)
+	if !ok || from == "" || to == "" {
+		return Remap{}, fmt.Errorf("invalid remap %q, expected from=to", s)
+	}
+	return Remap{From: from, To: to}, nil
+}
+
+// Subjects derives the subject of each replayed trade from a template with
+// the {exchange}, {instrument}, {symbol}, {base} and {quote} placeholders,
+// lower cased, then applies the first matching remapping
+type Subjects struct {
+	template string
+	remaps   []Remap
+}
+
+// NewSubjects creates a subject mapping
+func NewSubjects(template string, remaps []Remap) (*Subjects, error) {
+	if template == "" {
+		return nil, fmt.Errorf("subject template cannot be empty")
+	}
+	rest := template
+	for {
+		open := strings.IndexByte(rest, '{')
+		if open < 0 {
+			break
+		}
+		end := strings.IndexByte(rest[open:], '}')
+		if end < 0 {
+			return nil, fmt.Errorf("subject template %q: unterminated {", template)
+		}
+		switch name := rest[open+1 : open+end]; name {
+		case "exchange", "instrument", "symbol", "base", "quote":
+		default:
+			return nil, fmt.Errorf("subject template %q: unknown placeholder {%s}", template, name)
+		}
+		rest = rest[open+end+1:]
+	}
+	return &Subjects{template: template, remaps: remaps}, nil
+}
+
+// Subject returns the subject of a trade
+func (s *Subjects) Subject(trade *sqx.Trade) string {
+	subject := strings.NewReplacer(
+		"{exchange}", strings.ToLower(trade.Exchange.String()),
+		"{instrument}", strings.ToLower(trade.InstrumentType.String()),
+		"{symbol}", strings.ToLower(trade.Symbol.Base+trade.Symbol.Quote),
+		"{base}", strings.ToLower(trade.Symbol.Base),
+		"{quote}", strings.ToLower(trade.Symbol.Quote),
+	).Replace(s.template)
+	for _, r := range s.remaps {
+		if subject == r.From {
+			return r.To
+		}
+		if strings.HasPrefix(subject, r.From+".") {
+			return r.To + subject[len(r.From):]
+		}
+	}
+	return subject
+}
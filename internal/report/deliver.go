@@ -0,0 +1,136 @@
+package report
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"mime/multipart"
+	"net/http"
+	"net/smtp"
+	"net/textproto"
+	"strings"
+	"time"
+)
+
+// Sender delivers a report
+type Sender interface {
+	Name() string
+	Send(ctx context.Context, d Daily) error
+}
+
+// Slack posts the Markdown report to an incoming webhook
+type Slack struct {
+	WebhookURL string
+	Client     *http.Client // http.DefaultClient when nil
+}
+
+// Name identifies the sender in logs
+func (s *Slack) Name() string {
+	return "slack"
+}
+
+// Send posts the report as the text of a message
+func (s *Slack) Send(ctx context.Context, d Daily) error {
+	text, err := d.Markdown()
+	if err != nil {
+		return err
+	}
+	body, err := json.Marshal(map[string]string{"text": text})
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, s.WebhookURL, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	client := s.Client
+	if client == nil {
+		client = http.DefaultClient
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to post to slack: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("slack webhook returned %s", resp.Status)
+	}
+	return nil
+}
+
+// SMTP emails the report with a Markdown and an HTML alternative
+type SMTP struct {
+	Addr     string // host:port
+	Username string // Authenticates with PLAIN when set
+	Password string
+	From     string
+	To       []string
+}
+
+// Name identifies the sender in logs
+func (s *SMTP) Name() string {
+	return "smtp"
+}
+
+// Send emails the report. The SMTP exchange does not observe ctx beyond
+// the check before it starts.
+func (s *SMTP) Send(ctx context.Context, d Daily) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	msg, err := s.message(d, time.Now())
+	if err != nil {
+		return err
+	}
+	var auth smtp.Auth
+	if s.Username != "" {
+		host, _, _ := strings.Cut(s.Addr, ":")
+		auth = smtp.PlainAuth("", s.Username, s.Password, host)
+	}
+	if err := smtp.SendMail(s.Addr, auth, s.From, s.To, msg); err != nil {
+		return fmt.Errorf("failed to send email: %w", err)
+	}
+	return nil
+}
+
+// message builds a multipart/alternative email of the report
+func (s *SMTP) message(d Daily, now time.Time) ([]byte, error) {
+	text, err := d.Markdown()
+	if err != nil {
+		return nil, err
+	}
+	html, err := d.HTML()
+	if err != nil {
+		return nil, err
+	}
+
+	var body bytes.Buffer
+	mw := multipart.NewWriter(&body)
+	for _, part := range []struct{ contentType, content string }{
+		{"text/plain; charset=utf-8", text},
+		{"text/html; charset=utf-8", html},
+	} {
+		w, err := mw.CreatePart(textproto.MIMEHeader{"Content-Type": {part.contentType}})
+		if err != nil {
+			return nil, err
+		}
+		if _, err := w.Write([]byte(part.content)); err != nil {
+			return nil, err
+		}
+	}
+	if err := mw.Close(); err != nil {
+		return nil, err
+	}
+
+	var msg bytes.Buffer
+	fmt.Fprintf(&msg, "From: %s\r\n", s.From)
+	fmt.Fprintf(&msg, "To: %s\r\n", strings.Join(s.To, ", "))
+	fmt.Fprintf(&msg, "Subject: %s\r\n", d.Subject())
+	fmt.Fprintf(&msg, "Date: %s\r\n", now.Format(time.RFC1123Z))
+	fmt.Fprintf(&msg, "MIME-Version: 1.0\r\n")
+	fmt.Fprintf(&msg, "Content-Type: multipart/alternative; boundary=%s\r\n\r\n", mw.Boundary())
+	msg.Write(body.Bytes())
+	return msg.Bytes(), nil
+}
@@ -0,0 +1,115 @@
+package report
+
+import (
+	"bytes"
+	htmltemplate "html/template"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+	"text/template"
+	"time"
+)
+
+var funcs = map[string]any{
+	"amounts": amounts,
+	"percent": func(v float64) string { return strconv.FormatFloat(v*100, 'f', 2, 64) + "%" },
+	"clock":   func(t time.Time) string { return t.UTC().Format("15:04:05Z") },
+	"join":    strings.Join,
+}
+
+const markdownTemplate = `# Daily report {{.Date}}
+
+## Strategies
+{{if .Strategies}}
+| Strategy | Fills | Symbols | Volume | Realized PnL | Fees |
+|---|---|---|---|---|---|
+{{range .Strategies}}| {{.Strategy}} | {{.Fills}} | {{join .Symbols ", "}} | {{amounts .Volume}} | {{amounts .PnL}} | {{amounts .Fees}} |
+{{end}}{{else}}
+No fills.
+{{end}}
+## Risk breaches
+{{if .Breaches}}
+| Time | Kind | Name | Venue | Deviation | Threshold |
+|---|---|---|---|---|---|
+{{range .Breaches}}| {{clock .At}} | {{.Kind}} | {{.Name}} | {{.Venue}} | {{percent .Deviation}} | {{percent .Threshold}} |
+{{end}}{{else}}
+None.
+{{end}}
+## Availability
+{{if .Availability}}
+| Node | Availability | Probes | Uptime |
+|---|---|---|---|
+{{range .Availability}}| {{.Node}} | {{percent .Ratio}} | {{.Up}}/{{.Probes}} | {{.Uptime}} |
+{{end}}{{else}}
+No nodes probed.
+{{end}}`
+
+const htmlTemplate = `<!DOCTYPE html>
+<html>
+<head><meta charset="utf-8"><title>Daily report {{.Date}}</title></head>
+<body>
+<h1>Daily report {{.Date}}</h1>
+<h2>Strategies</h2>
+{{if .Strategies}}<table border="1" cellpadding="4" cellspacing="0">
+<tr><th>Strategy</th><th>Fills</th><th>Symbols</th><th>Volume</th><th>Realized PnL</th><th>Fees</th></tr>
+{{range .Strategies}}<tr><td>{{.Strategy}}</td><td>{{.Fills}}</td><td>{{join .Symbols ", "}}</td><td>{{amounts .Volume}}</td><td>{{amounts .PnL}}</td><td>{{amounts .Fees}}</td></tr>
+{{end}}</table>{{else}}<p>No fills.</p>{{end}}
+<h2>Risk breaches</h2>
+{{if .Breaches}}<table border="1" cellpadding="4" cellspacing="0">
+<tr><th>Time</th><th>Kind</th><th>Name</th><th>Venue</th><th>Deviation</th><th>Threshold</th></tr>
+{{range .Breaches}}<tr><td>{{clock .At}}</td><td>{{.Kind}}</td><td>{{.Name}}</td><td>{{.Venue}}</td><td>{{percent .Deviation}}</td><td>{{percent .Threshold}}</td></tr>
+{{end}}</table>{{else}}<p>None.</p>{{end}}
+<h2>Availability</h2>
+{{if .Availability}}<table border="1" cellpadding="4" cellspacing="0">
+<tr><th>Node</th><th>Availability</th><th>Probes</th><th>Uptime</th></tr>
+{{range .Availability}}<tr><td>{{.Node}}</td><td>{{percent .Ratio}}</td><td>{{.Up}}/{{.Probes}}</td><td>{{.Uptime}}</td></tr>
+{{end}}</table>{{else}}<p>No nodes probed.</p>{{end}}
+</body>
+</html>
+`
+
+var (
+	markdown = template.Must(template.New("markdown").Funcs(funcs).Parse(markdownTemplate))
+	html     = htmltemplate.Must(htmltemplate.New("html").Funcs(funcs).Parse(htmlTemplate))
+)
+
+// Subject is the title of the report, used as the email subject
+func (d Daily) Subject() string {
+	return "Daily report " + d.Date
+}
+
+// Markdown renders the report as Markdown tables
+func (d Daily) Markdown() (string, error) {
+	var buf bytes.Buffer
+	if err := markdown.Execute(&buf, d); err != nil {
+		return "", err
+	}
+	return buf.String(), nil
+}
+
+// HTML renders the report as an HTML document
+func (d Daily) HTML() (string, error) {
+	var buf bytes.Buffer
+	if err := html.Execute(&buf, d); err != nil {
+		return "", err
+	}
+	return buf.String(), nil
+}
+
+// amounts formats amounts keyed by currency, e.g. "1250.5 USDT, 0.01 BNB"
+func amounts(m map[string]float64) string {
+	if len(m) == 0 {
+		return "-"
+	}
+	currencies := make([]string, 0, len(m))
+	for currency := range m {
+		currencies = append(currencies, currency)
+	}
+	sort.Strings(currencies)
+	parts := make([]string, len(currencies))
+	for i, currency := range currencies {
+		parts[i] = strconv.FormatFloat(math.Round(m[currency]*1e8)/1e8, 'f', -1, 64) + " " + currency
+	}
+	return strings.Join(parts, ", ")
+}
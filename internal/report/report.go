@@ -0,0 +1,216 @@
+// Package report compiles the daily summary of the trading activity: PnL,
+// volume and fees per strategy, risk breaches and node availability. The
+// summary is rendered as Markdown or HTML and delivered over Slack or email.
+package report
+
+import (
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/BullionBear/sequex/internal/execution"
+	"github.com/BullionBear/sequex/internal/model/sqx"
+	"github.com/BullionBear/sequex/internal/monitor"
+	"github.com/BullionBear/sequex/internal/pms"
+)
+
+// StrategySummary is the activity of one strategy over the day. Amounts are
+// keyed by currency since strategies may trade several quote assets.
+type StrategySummary struct {
+	Strategy string             `json:"strategy"`
+	Fills    int                `json:"fills"`
+	Symbols  []string           `json:"symbols"`
+	Volume   map[string]float64 `json:"volume"` // Notional traded, keyed by quote asset
+	PnL      map[string]float64 `json:"pnl"`    // Realized FIFO PnL net of quote fees, keyed by quote asset
+	Fees     map[string]float64 `json:"fees"`   // Commissions, keyed by asset
+}
+
+// Breach is a risk alert raised during the day
+type Breach struct {
+	Kind      string    `json:"kind"`
+	Name      string    `json:"name"`
+	Venue     string    `json:"venue,omitempty"`
+	Deviation float64   `json:"deviation"`
+	Threshold float64   `json:"threshold"`
+	At        time.Time `json:"at"`
+}
+
+// Availability is the share of the health probes of a node it answered
+type Availability struct {
+	Node   string        `json:"node"`
+	Probes int           `json:"probes"`
+	Up     int           `json:"up"`
+	Ratio  float64       `json:"ratio"`  // Up over Probes, 0 without probes
+	Uptime time.Duration `json:"uptime"` // Uptime reported by the last probe answered
+}
+
+// Daily is the summary of one day
+type Daily struct {
+	Date         string            `json:"date"` // YYYY-MM-DD in the time zone of the schedule
+	From         time.Time         `json:"from"`
+	To           time.Time         `json:"to"` // Exclusive
+	Strategies   []StrategySummary `json:"strategies"`
+	Breaches     []Breach          `json:"breaches"`
+	Availability []Availability    `json:"availability"`
+}
+
+type probe struct {
+	at     time.Time
+	up     bool
+	uptime time.Duration
+}
+
+// Collector keeps the alerts and probes the reports are compiled from. It is
+// safe for concurrent use.
+type Collector struct {
+	mu       sync.Mutex
+	breaches []Breach
+	probes   map[string][]probe // Keyed by node, in probe order
+}
+
+// NewCollector creates an empty collector
+func NewCollector() *Collector {
+	return &Collector{probes: make(map[string][]probe)}
+}
+
+// OnAlert records an active monitor alert as a breach; resolutions are ignored
+func (c *Collector) OnAlert(a monitor.Alert) {
+	if !a.Active {
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.breaches = append(c.breaches, Breach{
+		Kind:      string(a.Kind),
+		Name:      a.Name,
+		Venue:     a.Venue,
+		Deviation: a.Deviation,
+		Threshold: a.Threshold,
+		At:        time.UnixMilli(a.Timestamp),
+	})
+}
+
+// OnProbe records whether a node answered a health probe and the uptime it reported
+func (c *Collector) OnProbe(node string, at time.Time, up bool, uptime time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.probes[node] = append(c.probes[node], probe{at: at, up: up, uptime: uptime})
+}
+
+// Prune drops the alerts and probes before t
+func (c *Collector) Prune(t time.Time) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	kept := c.breaches[:0]
+	for _, b := range c.breaches {
+		if !b.At.Before(t) {
+			kept = append(kept, b)
+		}
+	}
+	c.breaches = kept
+	for node, probes := range c.probes {
+		i := sort.Search(len(probes), func(i int) bool { return !probes[i].at.Before(t) })
+		c.probes[node] = append(probes[:0], probes[i:]...)
+	}
+}
+
+// Compile summarizes [from, to). fills must hold the history up to to so
+// that positions opened before the day are matched by the fills closing them.
+func (c *Collector) Compile(date string, from, to time.Time, fills []execution.Fill) Daily {
+	d := Daily{
+		Date:         date,
+		From:         from,
+		To:           to,
+		Strategies:   summarize(fills, from, to),
+		Breaches:     []Breach{},
+		Availability: []Availability{},
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for _, b := range c.breaches {
+		if !b.At.Before(from) && b.At.Before(to) {
+			d.Breaches = append(d.Breaches, b)
+		}
+	}
+	sort.SliceStable(d.Breaches, func(i, j int) bool { return d.Breaches[i].At.Before(d.Breaches[j].At) })
+	for node, probes := range c.probes {
+		a := Availability{Node: node}
+		for _, p := range probes {
+			if p.at.Before(from) || !p.at.Before(to) {
+				continue
+			}
+			a.Probes++
+			if p.up {
+				a.Up++
+				a.Uptime = p.uptime
+			}
+		}
+		if a.Probes > 0 {
+			a.Ratio = float64(a.Up) / float64(a.Probes)
+		}
+		d.Availability = append(d.Availability, a)
+	}
+	sort.Slice(d.Availability, func(i, j int) bool { return d.Availability[i].Node < d.Availability[j].Node })
+	return d
+}
+
+// summarize groups the fills of the day by strategy. Realized PnL replays
+// every fill of the strategy so that lots opened earlier are closed at cost.
+func summarize(fills []execution.Fill, from, to time.Time) []StrategySummary {
+	byStrategy := make(map[string][]pms.Fill)
+	summaries := make(map[string]*StrategySummary)
+	symbols := make(map[string]map[string]bool)
+	for _, f := range fills {
+		at := time.UnixMilli(f.Timestamp)
+		if !at.Before(to) {
+			continue
+		}
+		byStrategy[f.Strategy] = append(byStrategy[f.Strategy], f.PortfolioFill())
+		if at.Before(from) {
+			continue
+		}
+		s, ok := summaries[f.Strategy]
+		if !ok {
+			s = &StrategySummary{
+				Strategy: f.Strategy,
+				Volume:   make(map[string]float64),
+				PnL:      make(map[string]float64),
+				Fees:     make(map[string]float64),
+			}
+			summaries[f.Strategy] = s
+			symbols[f.Strategy] = make(map[string]bool)
+		}
+		s.Fills++
+		s.Volume[quoteOf(f.Symbol)] += f.Price * f.Quantity
+		if f.Commission != 0 {
+			s.Fees[f.CommissionAsset] += f.Commission
+		}
+		if !symbols[f.Strategy][f.Symbol] {
+			symbols[f.Strategy][f.Symbol] = true
+			s.Symbols = append(s.Symbols, f.Symbol)
+		}
+	}
+
+	list := make([]StrategySummary, 0, len(summaries))
+	for strategy, s := range summaries {
+		for _, r := range pms.BuildLedger(pms.CostFIFO, byStrategy[strategy]).Realized() {
+			closed := time.UnixMilli(r.ClosedAt)
+			if !closed.Before(from) && closed.Before(to) {
+				s.PnL[quoteOf(r.Symbol)] += r.PnL
+			}
+		}
+		sort.Strings(s.Symbols)
+		list = append(list, *s)
+	}
+	sort.Slice(list, func(i, j int) bool { return list[i].Strategy < list[j].Strategy })
+	return list
+}
+
+func quoteOf(symbol string) string {
+	s, err := sqx.NewSymbolFromStr(symbol)
+	if err != nil {
+		return symbol
+	}
+	return s.Quote
+}
@@ -0,0 +1,144 @@
+package report
+
+import (
+	"context"
+	"encoding/json"
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/BullionBear/sequex/internal/execution"
+	"github.com/BullionBear/sequex/internal/monitor"
+)
+
+var day = time.Date(2026, 10, 15, 0, 0, 0, 0, time.UTC)
+
+func fill(strategy, side string, price, qty float64, at time.Time) execution.Fill {
+	return execution.Fill{
+		ExecID:          at.Format(time.RFC3339),
+		OrderID:         strategy,
+		Strategy:        strategy,
+		Symbol:          "BTC-USDT",
+		Side:            side,
+		Price:           price,
+		Quantity:        qty,
+		Commission:      0.1,
+		CommissionAsset: "USDT",
+		Timestamp:       at.UnixMilli(),
+	}
+}
+
+func TestCompile(t *testing.T) {
+	c := NewCollector()
+	c.OnAlert(monitor.Alert{Kind: monitor.KindDepeg, Name: "USDC-USDT", Deviation: 0.02, Threshold: 0.01, Active: true, Timestamp: day.Add(3 * time.Hour).UnixMilli()})
+	c.OnAlert(monitor.Alert{Kind: monitor.KindDepeg, Name: "USDC-USDT", Active: false, Timestamp: day.Add(4 * time.Hour).UnixMilli()})
+	c.OnAlert(monitor.Alert{Kind: monitor.KindSpread, Name: "DAI-USDT", Active: true, Timestamp: day.Add(-time.Hour).UnixMilli()})
+	for i := 0; i < 4; i++ {
+		c.OnProbe("feed", day.Add(time.Duration(i)*6*time.Hour), i != 2, time.Duration(i)*time.Hour)
+	}
+
+	fills := []execution.Fill{
+		fill("grid", "BUY", 60000, 1, day.Add(-2*time.Hour)),             // Opened the day before
+		fill("grid", "SELL", 61000, 1, day.Add(time.Hour)),               // Closes it: 1000 - 0.1 fee
+		fill("trend", "BUY", 60500, 2, day.Add(2*time.Hour)),             // Still open
+		fill("trend", "SELL", 62000, 2, day.Add(25*time.Hour)),           // After the day
+		fill("other", "BUY", 59000, 1, day.Add(-26*time.Hour)),           // Before the day only
+		fill("grid", "BUY", 60000, 1, day.Add(24*time.Hour-time.Second)), // Last second of the day
+	}
+	d := c.Compile("2026-10-15", day, day.Add(24*time.Hour), fills)
+
+	if len(d.Strategies) != 2 || d.Strategies[0].Strategy != "grid" || d.Strategies[1].Strategy != "trend" {
+		t.Fatalf("strategies = %+v", d.Strategies)
+	}
+	grid := d.Strategies[0]
+	if grid.Fills != 2 || grid.Volume["USDT"] != 121000 || math.Abs(grid.PnL["USDT"]-999.8) > 1e-9 || grid.Fees["USDT"] != 0.2 {
+		t.Errorf("grid = %+v", grid)
+	}
+	if trend := d.Strategies[1]; trend.Fills != 1 || trend.PnL["USDT"] != 0 {
+		t.Errorf("trend = %+v", trend)
+	}
+	if len(d.Breaches) != 1 || d.Breaches[0].Name != "USDC-USDT" {
+		t.Errorf("breaches = %+v", d.Breaches)
+	}
+	if len(d.Availability) != 1 || d.Availability[0].Ratio != 0.75 || d.Availability[0].Uptime != 3*time.Hour {
+		t.Errorf("availability = %+v", d.Availability)
+	}
+
+	md, err := d.Markdown()
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{"# Daily report 2026-10-15", "| grid | 2 | BTC-USDT | 121000 USDT | 999.8 USDT | 0.2 USDT |", "| 03:00:00Z | depeg | USDC-USDT |  | 2.00% | 1.00% |", "| feed | 75.00% | 3/4 | 3h0m0s |"} {
+		if !strings.Contains(md, want) {
+			t.Errorf("markdown is missing %q:\n%s", want, md)
+		}
+	}
+	html, err := d.HTML()
+	if err != nil || !strings.Contains(html, "<td>grid</td>") {
+		t.Errorf("html: %v\n%s", err, html)
+	}
+
+	c.Prune(day.Add(12 * time.Hour))
+	if d := c.Compile("2026-10-15", day, day.Add(24*time.Hour), nil); len(d.Breaches) != 0 || d.Availability[0].Probes != 2 {
+		t.Errorf("pruned = %+v", d)
+	}
+}
+
+func TestSchedule(t *testing.T) {
+	s, err := ParseSchedule("00:05", "Asia/Taipei")
+	if err != nil {
+		t.Fatal(err)
+	}
+	now := time.Date(2026, 10, 15, 16, 0, 0, 0, time.UTC) // 00:00 in Taipei
+	next := s.Next(now)
+	if want := time.Date(2026, 10, 15, 16, 5, 0, 0, time.UTC); !next.Equal(want) {
+		t.Errorf("next = %v, want %v", next, want)
+	}
+	if again := s.Next(next); !again.Equal(next.Add(24 * time.Hour)) {
+		t.Errorf("next after a run = %v", again)
+	}
+	date, from, to := s.Day(next)
+	if date != "2026-10-15" || !to.Equal(now) || !from.Equal(now.Add(-24*time.Hour)) {
+		t.Errorf("day = %s %v %v", date, from, to)
+	}
+	for _, clock := range []string{"24:00", "5pm", ""} {
+		if _, err := ParseSchedule(clock, ""); err == nil {
+			t.Errorf("%q accepted", clock)
+		}
+	}
+	if _, err := ParseSchedule("00:05", "Mars/Olympus"); err == nil {
+		t.Error("unknown time zone accepted")
+	}
+}
+
+func TestDelivery(t *testing.T) {
+	d := NewCollector().Compile("2026-10-15", day, day.Add(24*time.Hour), nil)
+
+	var text string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var body map[string]string
+		json.NewDecoder(r.Body).Decode(&body)
+		text = body["text"]
+	}))
+	defer server.Close()
+	if err := (&Slack{WebhookURL: server.URL}).Send(context.Background(), d); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(text, "No fills.") {
+		t.Errorf("slack text = %q", text)
+	}
+
+	s := &SMTP{From: "sequex@example.com", To: []string{"desk@example.com", "risk@example.com"}}
+	msg, err := s.message(d, day)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{"To: desk@example.com, risk@example.com\r\n", "Subject: Daily report 2026-10-15\r\n", "Content-Type: text/html; charset=utf-8", "<h1>Daily report 2026-10-15</h1>"} {
+		if !strings.Contains(string(msg), want) {
+			t.Errorf("email is missing %q", want)
+		}
+	}
+}
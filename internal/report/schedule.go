@@ -0,0 +1,38 @@
+package report
+
+import (
+	"time"
+
+	"github.com/BullionBear/sequex/pkg/calendar"
+)
+
+// Schedule is the time of day reports are sent at, covering the previous day
+type Schedule struct {
+	Hour     int
+	Minute   int
+	Location *time.Location
+}
+
+// ParseSchedule reads a HH:MM time of day in an IANA time zone, UTC when empty
+func ParseSchedule(clock, zone string) (Schedule, error) {
+	at, err := calendar.ParseClock(clock)
+	if err != nil {
+		return Schedule{}, err
+	}
+	loc, err := calendar.LoadLocation(zone)
+	if err != nil {
+		return Schedule{}, err
+	}
+	return Schedule{Hour: at.Hour, Minute: at.Minute, Location: loc}, nil
+}
+
+// Next returns the first run strictly after now
+func (s Schedule) Next(now time.Time) time.Time {
+	return calendar.Settlement{Clock: calendar.Clock{Hour: s.Hour, Minute: s.Minute}, Location: s.Location}.Next(now)
+}
+
+// Day returns the date and bounds of the day before run, cut at midnight in
+// the time zone of the schedule
+func (s Schedule) Day(run time.Time) (string, time.Time, time.Time) {
+	return calendar.Settlement{Location: s.Location}.Previous(run)
+}
@@ -0,0 +1,226 @@
+package retention
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/BullionBear/sequex/internal/model/sqx"
+	"github.com/BullionBear/sequex/pkg/candles"
+	"github.com/BullionBear/sequex/pkg/eventbus"
+	"github.com/BullionBear/sequex/pkg/logger"
+	"github.com/nats-io/nats.go"
+)
+
+// Result reports a run of a policy
+type Result struct {
+	Stream  string `json:"stream"`
+	Subject string `json:"subject"`
+	Cutoff  int64  `json:"cutoff"`   // Messages before were deleted, Unix milliseconds
+	PurgeTo uint64 `json:"purge_to"` // First stream sequence kept
+	Trades  int    `json:"trades"`   // Trades compacted
+	Klines  int    `json:"klines"`   // Klines published
+	Late    int    `json:"late"`     // Trades older than the previous cutoff, not compacted
+	Skipped int    `json:"skipped"`  // Messages that are not trades, deleted without compaction
+}
+
+// Manager enforces retention policies on JetStream streams
+type Manager struct {
+	js         nats.JetStreamContext
+	policies   []Policy
+	watermarks []int64 // Cutoff of the last compaction of each policy
+}
+
+// NewManager creates a manager of policies, validating them
+func NewManager(js nats.JetStreamContext, policies []Policy) (*Manager, error) {
+	for _, p := range policies {
+		if err := p.Validate(); err != nil {
+			return nil, err
+		}
+	}
+	return &Manager{js: js, policies: policies, watermarks: make([]int64, len(policies))}, nil
+}
+
+// Check verifies that the streams of the policies exist and warns about the
+// limits of streams deleting messages before their policies do
+func (m *Manager) Check() error {
+	for _, p := range m.policies {
+		info, err := m.js.StreamInfo(p.Stream)
+		if err != nil {
+			return fmt.Errorf("stream %s: %w", p.Stream, err)
+		}
+		if limit := info.Config.MaxAge; limit > 0 && (p.MaxAge == 0 || limit < p.MaxAge) {
+			logger.Log.Warn().Str("stream", p.Stream).Str("subject", p.Subject).Dur("streamMaxAge", limit).Dur("maxAge", p.MaxAge).
+				Msg("Stream max age deletes messages before the retention policy does")
+		}
+		if p.Compact != nil {
+			if _, err := m.js.StreamInfo(p.Compact.Stream); err != nil {
+				return fmt.Errorf("compaction stream %s: %w", p.Compact.Stream, err)
+			}
+		}
+	}
+	return nil
+}
+
+// Run enforces the policies every interval until ctx is done
+func (m *Manager) Run(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		m.Enforce(ctx, time.Now())
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// Enforce runs each policy once at now, logging the outcomes. A policy
+// failing does not stop the next ones.
+func (m *Manager) Enforce(ctx context.Context, now time.Time) ([]Result, error) {
+	var results []Result
+	var errs []error
+	for i, p := range m.policies {
+		if p.MaxAge == 0 {
+			continue
+		}
+		r, err := m.enforce(ctx, i, now)
+		if err != nil {
+			logger.Log.Error().Err(err).Str("stream", p.Stream).Str("subject", p.Subject).Msg("Failed to enforce retention policy")
+			errs = append(errs, fmt.Errorf("%s %s: %w", p.Stream, p.Subject, err))
+			continue
+		}
+		logger.Log.Info().Str("stream", p.Stream).Str("subject", p.Subject).Time("cutoff", time.UnixMilli(r.Cutoff)).
+			Uint64("purgeTo", r.PurgeTo).Int("trades", r.Trades).Int("klines", r.Klines).Int("late", r.Late).Int("skipped", r.Skipped).
+			Msg("Enforced retention policy")
+		results = append(results, r)
+	}
+	return results, errors.Join(errs...)
+}
+
+// enforce runs policy i, compacting the expiring trades first when set
+func (m *Manager) enforce(ctx context.Context, i int, now time.Time) (Result, error) {
+	p := m.policies[i]
+	r := Result{Stream: p.Stream, Subject: p.Subject, Cutoff: p.cutoff(now)}
+
+	// Messages stored after the stream info are never purged, whatever the
+	// consumers below see
+	info, err := m.js.StreamInfo(p.Stream, nats.Context(ctx))
+	if err != nil {
+		return r, err
+	}
+	last := info.State.LastSeq
+
+	if p.Compact == nil {
+		r.PurgeTo, err = m.firstAfter(ctx, p, r.Cutoff, last)
+	} else {
+		r.PurgeTo, err = m.compact(ctx, i, &r, last)
+	}
+	if err != nil {
+		return r, err
+	}
+	if err := m.js.PurgeStream(p.Stream, &nats.StreamPurgeRequest{Subject: p.Subject, Sequence: r.PurgeTo}, nats.Context(ctx)); err != nil {
+		return r, fmt.Errorf("purge: %w", err)
+	}
+	if p.Compact != nil {
+		m.watermarks[i] = r.Cutoff
+	}
+	return r, nil
+}
+
+// firstAfter returns the sequence of the first message of the policy stored
+// at or after cutoff, after last when there is none
+func (m *Manager) firstAfter(ctx context.Context, p Policy, cutoff int64, last uint64) (uint64, error) {
+	sub, err := m.js.SubscribeSync(p.Subject, nats.BindStream(p.Stream), nats.OrderedConsumer(), nats.StartTime(time.UnixMilli(cutoff)))
+	if err != nil {
+		return 0, err
+	}
+	defer sub.Unsubscribe()
+	ci, err := sub.ConsumerInfo()
+	if err != nil {
+		return 0, err
+	}
+	if ci.NumPending == 0 {
+		return last + 1, nil
+	}
+	msg, err := sub.NextMsgWithContext(ctx)
+	if err != nil {
+		return 0, err
+	}
+	meta, err := msg.Metadata()
+	if err != nil {
+		return 0, err
+	}
+	return min(meta.Sequence.Stream, last+1), nil
+}
+
+// compact folds the trades of policy i stored before the cutoff of r into
+// klines and publishes them, returning the sequence of the first message
+// kept. A message holding a trade at or after the cutoff is kept whole,
+// with the messages after it.
+func (m *Manager) compact(ctx context.Context, i int, r *Result, last uint64) (uint64, error) {
+	p := m.policies[i]
+	sub, err := m.js.SubscribeSync(p.Subject, nats.BindStream(p.Stream), nats.OrderedConsumer(), nats.DeliverAll())
+	if err != nil {
+		return 0, err
+	}
+	defer sub.Unsubscribe()
+	ci, err := sub.ConsumerInfo()
+	if err != nil {
+		return 0, err
+	}
+	if ci.NumPending == 0 {
+		return last + 1, nil
+	}
+
+	c := newCompactor(p.Compact.Interval, m.watermarks[i])
+	purgeTo := last + 1
+scan:
+	for {
+		msg, err := sub.NextMsgWithContext(ctx)
+		if err != nil {
+			return 0, err
+		}
+		meta, err := msg.Metadata()
+		if err != nil {
+			return 0, err
+		}
+		seq := meta.Sequence.Stream
+		if seq > last || meta.Timestamp.UnixMilli() >= r.Cutoff {
+			purgeTo = min(seq, last+1)
+			break
+		}
+		if err := eventbus.Decode(msg); err != nil {
+			r.Skipped++
+		} else if trades, err := sqx.UnmarshalTrades(msg.Data, msg.Header.Get(sqx.HeaderTradeBatch)); err != nil {
+			r.Skipped++
+		} else {
+			for _, t := range trades {
+				if t.Timestamp >= r.Cutoff {
+					purgeTo = seq
+					break scan
+				}
+			}
+			if err := c.add(trades); err != nil {
+				return 0, err
+			}
+		}
+		if meta.NumPending == 0 {
+			break
+		}
+	}
+
+	// Klines are published before the trades are purged, so a failure
+	// leaves the trades for the next run
+	for s, klines := range c.flush(r.Cutoff) {
+		cache := candles.NewJetStreamCache(m.js, p.Compact.Stream, p.Compact.prefix(s))
+		if err := cache.Record(s.symbol.String(), p.Compact.Interval, klines); err != nil {
+			return 0, err
+		}
+		r.Klines += len(klines)
+	}
+	r.Trades, r.Late = c.trades, c.late
+	return purgeTo, nil
+}
@@ -0,0 +1,132 @@
+// Package retention enforces per-subject retention policies on JetStream
+// streams, finer than the limits of the streams themselves, and compacts
+// expiring trades into klines before they are deleted.
+package retention
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/BullionBear/sequex/internal/model/sqx"
+	"github.com/BullionBear/sequex/pkg/candles"
+	"github.com/BullionBear/sequex/pkg/eventbus"
+)
+
+// Compaction aggregates the trades a policy expires into klines
+type Compaction struct {
+	Interval string // Kline interval, e.g. 1m
+	Stream   string // Stream the klines are published to
+	Prefix   string // Klines go to <prefix>.<exchange>.<instrument>.<SYMBOL>.<interval>
+}
+
+// Policy bounds the age of the messages of a stream on a subject
+type Policy struct {
+	Stream  string
+	Subject string        // Subject filter, wildcards allowed
+	MaxAge  time.Duration // Messages older are deleted, 0 keeps them forever
+	Compact *Compaction   // Nil deletes expiring messages without compacting them
+}
+
+// Validate validates the policy
+func (p Policy) Validate() error {
+	if p.Stream == "" {
+		return fmt.Errorf("stream cannot be empty")
+	}
+	if err := eventbus.ValidatePattern(p.Subject); err != nil {
+		return fmt.Errorf("%s: %w", p.Stream, err)
+	}
+	if p.MaxAge < 0 {
+		return fmt.Errorf("%s %s: max age cannot be negative", p.Stream, p.Subject)
+	}
+	if p.Compact == nil {
+		return nil
+	}
+	c := p.Compact
+	interval, err := candles.ParseInterval(c.Interval)
+	if err != nil {
+		return fmt.Errorf("%s %s: %w", p.Stream, p.Subject, err)
+	}
+	if p.MaxAge < interval {
+		return fmt.Errorf("%s %s: max age cannot be shorter than the compaction interval", p.Stream, p.Subject)
+	}
+	if c.Stream == "" || c.Prefix == "" {
+		return fmt.Errorf("%s %s: compaction stream and prefix cannot be empty", p.Stream, p.Subject)
+	}
+	if err := eventbus.ValidatePattern(c.Prefix); err != nil || strings.ContainsAny(c.Prefix, "*>") {
+		return fmt.Errorf("%s %s: invalid compaction prefix %q", p.Stream, p.Subject, c.Prefix)
+	}
+	return nil
+}
+
+// series identifies the trades of one market, compacted into one series of
+// klines
+type series struct {
+	exchange   sqx.Exchange
+	instrument sqx.InstrumentType
+	symbol     sqx.Symbol
+}
+
+// prefix returns the subject prefix of the klines of s
+func (c *Compaction) prefix(s series) string {
+	return c.Prefix + "." + strings.ToLower(s.exchange.String()) + "." + strings.ToLower(s.instrument.String())
+}
+
+// compactor folds expiring trades into klines per series
+type compactor struct {
+	interval  string
+	watermark int64 // Trades before were compacted by a previous run
+	builders  map[series]*candles.Builder
+
+	trades int // Trades folded in
+	late   int // Trades before the watermark, whose klines were published already
+}
+
+func newCompactor(interval string, watermark int64) *compactor {
+	return &compactor{interval: interval, watermark: watermark, builders: make(map[series]*candles.Builder)}
+}
+
+// add folds trades in
+func (c *compactor) add(trades []sqx.Trade) error {
+	for _, t := range trades {
+		if t.Timestamp < c.watermark {
+			c.late++
+			continue
+		}
+		s := series{t.Exchange, t.InstrumentType, t.Symbol}
+		b, ok := c.builders[s]
+		if !ok {
+			var err error
+			if b, err = candles.NewBuilder(c.interval); err != nil {
+				return err
+			}
+			c.builders[s] = b
+		}
+		b.Add(t.Timestamp, t.Price, t.Quantity)
+		c.trades++
+	}
+	return nil
+}
+
+// flush returns the klines of each series closed by end
+func (c *compactor) flush(end int64) map[series][]candles.Candle {
+	klines := make(map[series][]candles.Candle, len(c.builders))
+	for s, b := range c.builders {
+		if closed := b.Flush(end); len(closed) > 0 {
+			klines[s] = closed
+		}
+	}
+	return klines
+}
+
+// cutoff returns the time in Unix milliseconds before which messages expire
+// at now, aligned on the compaction interval so that only whole klines are
+// compacted
+func (p Policy) cutoff(now time.Time) int64 {
+	cutoff := now.Add(-p.MaxAge).UnixMilli()
+	if p.Compact != nil {
+		b, _ := candles.NewBuilder(p.Compact.Interval) // Validated with the policy
+		cutoff = b.OpenTime(cutoff)
+	}
+	return cutoff
+}
@@ -0,0 +1,81 @@
+package retention
+
+import (
+	"testing"
+	"time"
+
+	"github.com/BullionBear/sequex/internal/model/sqx"
+)
+
+func TestPolicyValidate(t *testing.T) {
+	compact := &Compaction{Interval: "1m", Stream: "KLINE", Prefix: "kline"}
+	valid := []Policy{
+		{Stream: "KLINE", Subject: "kline.>"},
+		{Stream: "TRADE", Subject: "trade.>", MaxAge: 7 * 24 * time.Hour, Compact: compact},
+	}
+	for _, p := range valid {
+		if err := p.Validate(); err != nil {
+			t.Errorf("%+v: %v", p, err)
+		}
+	}
+	invalid := []Policy{
+		{Subject: "trade.>"},
+		{Stream: "TRADE", Subject: "trade.>.x"},
+		{Stream: "TRADE", Subject: "trade.>", MaxAge: -time.Hour},
+		{Stream: "TRADE", Subject: "trade.>", MaxAge: 30 * time.Second, Compact: compact},
+		{Stream: "TRADE", Subject: "trade.>", MaxAge: time.Hour, Compact: &Compaction{Interval: "1M", Stream: "KLINE", Prefix: "kline"}},
+		{Stream: "TRADE", Subject: "trade.>", MaxAge: time.Hour, Compact: &Compaction{Interval: "1m", Stream: "KLINE", Prefix: "kline.*"}},
+		{Stream: "TRADE", Subject: "trade.>", MaxAge: time.Hour, Compact: &Compaction{Interval: "1m", Prefix: "kline"}},
+	}
+	for _, p := range invalid {
+		if err := p.Validate(); err == nil {
+			t.Errorf("%+v validated", p)
+		}
+	}
+}
+
+func TestCutoff(t *testing.T) {
+	now := time.UnixMilli(10*60_000 + 30_000)
+	p := Policy{Stream: "TRADE", Subject: "trade.>", MaxAge: 5 * time.Minute}
+	if got := p.cutoff(now); got != 5*60_000+30_000 {
+		t.Errorf("cutoff = %d", got)
+	}
+	// Aligned on the klines, so that the trades of the open one are kept
+	p.Compact = &Compaction{Interval: "1m", Stream: "KLINE", Prefix: "kline"}
+	if got := p.cutoff(now); got != 5*60_000 {
+		t.Errorf("compaction cutoff = %d", got)
+	}
+}
+
+func TestCompactor(t *testing.T) {
+	trade := func(symbol string, ts int64, price float64) sqx.Trade {
+		s, _ := sqx.NewSymbolFromStr(symbol)
+		return sqx.Trade{Symbol: s, Exchange: sqx.ExchangeBinance, InstrumentType: sqx.InstrumentTypeSpot, Price: price, Quantity: 1, Timestamp: ts}
+	}
+	c := newCompactor("1m", 60_000)
+	err := c.add([]sqx.Trade{
+		trade("BTC-USDT", 30_000, 100), // Before the watermark
+		trade("BTC-USDT", 60_000, 101),
+		trade("BTC-USDT", 90_000, 103),
+		trade("ETH-USDT", 70_000, 10),
+		trade("BTC-USDT", 125_000, 104),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if c.trades != 4 || c.late != 1 {
+		t.Errorf("trades = %d, late = %d", c.trades, c.late)
+	}
+
+	klines := c.flush(120_000)
+	btc := series{sqx.ExchangeBinance, sqx.InstrumentTypeSpot, sqx.NewSymbol("BTC", "USDT")}
+	if k := klines[btc]; len(k) != 1 || k[0].OpenTime != 60_000 || k[0].Open != 101 || k[0].Close != 103 || k[0].Trades != 2 {
+		t.Errorf("BTC klines = %+v", k)
+	}
+	if len(klines) != 2 {
+		t.Errorf("klines of %d series", len(klines))
+	}
+	if got := (&Compaction{Prefix: "kline"}).prefix(btc); got != "kline.binance.spot" {
+		t.Errorf("prefix = %s", got)
+	}
+}
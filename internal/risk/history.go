@@ -0,0 +1,175 @@
+// Package risk estimates the loss distribution of a portfolio by
+// bootstrapping the historical returns of its assets: VaR, CVaR and the
+// distribution of drawdowns over a horizon.
+package risk
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/BullionBear/sequex/internal/model/sqx"
+)
+
+var (
+	// ErrNoHistory is returned when an asset has no recorded returns
+	ErrNoHistory = errors.New("no return history")
+	// ErrInvalid is returned for invalid simulation options
+	ErrInvalid = errors.New("invalid")
+)
+
+// HistoryOptions configures a price history
+type HistoryOptions struct {
+	Quote      string        // Currency asset prices are recorded in, USDT when empty
+	Interval   time.Duration // Length of a return period, 1 hour when 0
+	MaxPeriods int           // Periods kept per asset, 720 when 0
+}
+
+// History records the closing price of each asset per period from trades.
+// Every instrument type counts, so perp trades provide the returns of their
+// base asset like spot trades do. It is safe for concurrent use.
+type History struct {
+	opts   HistoryOptions
+	mu     sync.RWMutex
+	closes map[string]*series // Keyed by base asset
+}
+
+// series holds consecutive closes, the last of them at period last
+type series struct {
+	last   int64
+	closes []float64
+}
+
+// NewHistory creates an empty price history
+func NewHistory(opts HistoryOptions) *History {
+	if opts.Quote == "" {
+		opts.Quote = "USDT"
+	}
+	opts.Quote = strings.ToUpper(opts.Quote)
+	if opts.Interval <= 0 {
+		opts.Interval = time.Hour
+	}
+	if opts.MaxPeriods <= 0 {
+		opts.MaxPeriods = 720
+	}
+	return &History{opts: opts, closes: make(map[string]*series)}
+}
+
+// Quote returns the currency of the recorded prices
+func (h *History) Quote() string {
+	return h.opts.Quote
+}
+
+// Interval returns the length of a return period
+func (h *History) Interval() time.Duration {
+	return h.opts.Interval
+}
+
+// OnTrade records the price of a trade quoted in the history currency.
+// Trades are expected roughly in time order; a trade older than the last
+// recorded period of its asset is ignored.
+func (h *History) OnTrade(trade sqx.Trade) {
+	if trade.Symbol.Quote != h.opts.Quote || trade.Price <= 0 {
+		return
+	}
+	h.Record(trade.Symbol.Base, trade.Price, time.UnixMilli(trade.Timestamp))
+}
+
+// Record sets the price of an asset at a time. Periods without a price
+// repeat the previous close.
+func (h *History) Record(asset string, price float64, at time.Time) {
+	period := at.UnixNano() / int64(h.opts.Interval)
+	asset = strings.ToUpper(asset)
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	s, ok := h.closes[asset]
+	if !ok {
+		h.closes[asset] = &series{last: period, closes: []float64{price}}
+		return
+	}
+	switch {
+	case period < s.last:
+		return
+	case period == s.last:
+		s.closes[len(s.closes)-1] = price
+		return
+	}
+	gap := min(period-s.last, int64(h.opts.MaxPeriods))
+	previous := s.closes[len(s.closes)-1]
+	for i := int64(1); i < gap; i++ {
+		s.closes = append(s.closes, previous)
+	}
+	s.closes = append(s.closes, price)
+	s.last = period
+	// The closes keep one more period than the returns they provide
+	if excess := len(s.closes) - h.opts.MaxPeriods - 1; excess > 0 {
+		s.closes = append(s.closes[:0], s.closes[excess:]...)
+	}
+}
+
+// Returns are the simple returns of assets over the same periods, oldest
+// first: Periods[t][i] is the return of Assets[i] in period t
+type Returns struct {
+	Assets   []string
+	Interval time.Duration
+	Periods  [][]float64
+}
+
+// Returns aligns the returns of the assets over the periods every one of
+// them has recorded, ending at the latest period recorded for any of them
+func (h *History) Returns(assets []string) (Returns, error) {
+	r := Returns{Assets: make([]string, len(assets)), Interval: h.opts.Interval}
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+
+	all := make([]*series, len(assets))
+	end := int64(0)
+	for i, asset := range assets {
+		r.Assets[i] = strings.ToUpper(asset)
+		s, ok := h.closes[r.Assets[i]]
+		if !ok || len(s.closes) < 2 {
+			return r, fmt.Errorf("%w for %s", ErrNoHistory, r.Assets[i])
+		}
+		all[i] = s
+		end = max(end, s.last)
+	}
+	// Assets whose last trade is older than the end hold their last close
+	periods := -1
+	for _, s := range all {
+		n := len(s.closes) - 1 + int(end-s.last)
+		if periods < 0 || n < periods {
+			periods = n
+		}
+	}
+	periods = min(periods, h.opts.MaxPeriods)
+	r.Periods = make([][]float64, periods)
+	for t := range r.Periods {
+		r.Periods[t] = make([]float64, len(all))
+		for i, s := range all {
+			// Index of the close ending period t within the series, past
+			// the last close while the asset has not traded since
+			k := len(s.closes) - 1 + int(end-s.last) - (periods - 1 - t)
+			if k < 1 || k >= len(s.closes) {
+				continue
+			}
+			r.Periods[t][i] = s.closes[k]/s.closes[k-1] - 1
+		}
+	}
+	return r, nil
+}
+
+// Assets lists the assets with recorded prices
+func (h *History) Assets() []string {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	assets := make([]string, 0, len(h.closes))
+	for asset := range h.closes {
+		assets = append(assets, asset)
+	}
+	sort.Strings(assets)
+	return assets
+}
@@ -0,0 +1,75 @@
+package risk
+
+import (
+	"errors"
+	"sort"
+
+	"github.com/BullionBear/sequex/internal/model/sqx"
+	"github.com/BullionBear/sequex/internal/pms"
+)
+
+// PortfolioReport is the simulated risk of a portfolio at its current
+// weights. Returns are measured against the quote currency of the history
+// and applied to values in the currency of the portfolio.
+type PortfolioReport struct {
+	PortfolioID string     `json:"portfolio_id"`
+	Currency    string     `json:"currency"`
+	Cash        float64    `json:"cash"` // Value held in the portfolio or history currency, taken as riskless
+	Exposures   []Exposure `json:"exposures"`
+	Excluded    []string   `json:"excluded,omitempty"` // Assets without a valuation or a return history
+	Complete    bool       `json:"complete"`           // Whether every position was simulated
+	Report
+}
+
+// SimulatePortfolio simulates the positions of a valuation, grouped by base
+// asset since a perp and a spot position of the same asset move together
+func SimulatePortfolio(v pms.Valuation, history *History, opts Options) (PortfolioReport, error) {
+	r := PortfolioReport{PortfolioID: v.PortfolioID, Currency: v.Currency, Complete: true}
+	values := make(map[string]float64)
+	excluded := make(map[string]bool)
+	for _, p := range v.Positions {
+		symbol, err := sqx.NewSymbolFromStr(p.Symbol)
+		if err != nil || p.Error != "" {
+			excluded[p.Symbol] = true
+			continue
+		}
+		if symbol.Base == v.Currency || symbol.Base == history.Quote() {
+			r.Cash += p.Value
+			continue
+		}
+		values[symbol.Base] += p.Value
+	}
+
+	assets := make([]string, 0, len(values))
+	for asset := range values {
+		if _, err := history.Returns([]string{asset}); errors.Is(err, ErrNoHistory) {
+			excluded[asset] = true
+			continue
+		}
+		assets = append(assets, asset)
+	}
+	sort.Strings(assets)
+	for _, asset := range assets {
+		r.Exposures = append(r.Exposures, Exposure{Asset: asset, Value: values[asset]})
+	}
+	for name := range excluded {
+		r.Excluded = append(r.Excluded, name)
+	}
+	sort.Strings(r.Excluded)
+	r.Complete = len(r.Excluded) == 0
+
+	if len(assets) == 0 {
+		// Nothing at risk, every outcome is flat
+		report, err := Simulate(nil, Returns{Interval: history.Interval(), Periods: [][]float64{{}}}, opts)
+		r.Report = report
+		r.Value = r.Cash
+		return r, err
+	}
+	returns, err := history.Returns(assets)
+	if err != nil {
+		return r, err
+	}
+	r.Report, err = Simulate(r.Exposures, returns, opts)
+	r.Value += r.Cash
+	return r, err
+}
@@ -0,0 +1,132 @@
+package risk
+
+import (
+	"errors"
+	"math"
+	"testing"
+	"time"
+
+	"github.com/BullionBear/sequex/internal/model/sqx"
+	"github.com/BullionBear/sequex/internal/pms"
+)
+
+func near(a, b float64) bool {
+	return math.Abs(a-b) < 1e-9
+}
+
+func TestHistoryReturns(t *testing.T) {
+	h := NewHistory(HistoryOptions{Interval: time.Minute, MaxPeriods: 3})
+	at := func(minute int) time.Time { return time.Unix(0, 0).Add(time.Duration(minute) * time.Minute) }
+	trade := func(base, quote string, price float64, minute int) sqx.Trade {
+		return sqx.Trade{Symbol: sqx.NewSymbol(base, quote), Price: price, Timestamp: at(minute).UnixMilli()}
+	}
+
+	h.OnTrade(trade("BTC", "USDT", 100, 0))
+	h.OnTrade(trade("BTC", "USDT", 105, 1))
+	h.OnTrade(trade("BTC", "USDT", 110, 1)) // Last trade of the period closes it
+	h.OnTrade(trade("BTC", "EUR", 1, 2))    // Other quote currencies are ignored
+	h.OnTrade(trade("BTC", "USDT", 99, 3))  // Minute 2 repeats the close of minute 1
+	h.OnTrade(trade("ETH", "USDT", 10, 1))
+	h.OnTrade(trade("ETH", "USDT", 12, 2))
+	h.OnTrade(trade("ETH", "USDT", 1, 0)) // Late trades are ignored
+
+	r, err := h.Returns([]string{"btc", "ETH"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Minutes 2 and 3: ETH only has one return and holds its close in minute 3
+	want := [][]float64{{0, 0.2}, {-0.1, 0}}
+	if len(r.Periods) != len(want) {
+		t.Fatalf("periods = %v, want %v", r.Periods, want)
+	}
+	for i := range want {
+		if !near(r.Periods[i][0], want[i][0]) || !near(r.Periods[i][1], want[i][1]) {
+			t.Errorf("period %d = %v, want %v", i, r.Periods[i], want[i])
+		}
+	}
+
+	// Only MaxPeriods returns are kept
+	h.Record("BTC", 100, at(10))
+	if r, _ := h.Returns([]string{"BTC"}); len(r.Periods) != 3 || !near(r.Periods[2][0], 100/99.0-1) {
+		t.Errorf("periods = %v", r.Periods)
+	}
+	if _, err := h.Returns([]string{"SOL"}); !errors.Is(err, ErrNoHistory) {
+		t.Errorf("expected ErrNoHistory, got %v", err)
+	}
+}
+
+func TestSimulate(t *testing.T) {
+	returns := Returns{
+		Assets:   []string{"BTC", "ETH"},
+		Interval: time.Hour,
+		Periods:  [][]float64{{-0.1, 0.05}, {-0.1, 0.05}},
+	}
+	// Every draw loses 10% on BTC and gains 5% on the ETH short position
+	report, err := Simulate([]Exposure{{"BTC", 1000}, {"ETH", -500}}, returns, Options{Horizon: 2, Paths: 100, Seed: 7})
+	if err != nil {
+		t.Fatal(err)
+	}
+	loss := 1000 - 810 + (-500 - -551.25)
+	if !near(report.VaR, loss) || !near(report.CVaR, loss) || !near(report.ExpectedPnL, -loss) {
+		t.Errorf("report = %+v, want a loss of %v", report, loss)
+	}
+	if !near(report.Drawdown.Max, loss) || report.Horizon != 2*time.Hour || report.Value != 500 {
+		t.Errorf("report = %+v", report)
+	}
+
+	// The seed reproduces a simulation
+	mixed := Returns{Assets: []string{"BTC"}, Interval: time.Hour, Periods: [][]float64{{0.02}, {-0.03}, {0.01}, {-0.01}}}
+	a, _ := Simulate([]Exposure{{"BTC", 1000}}, mixed, Options{Horizon: 10, Paths: 1000, Seed: 42, BlockSize: 2})
+	b, _ := Simulate([]Exposure{{"BTC", 1000}}, mixed, Options{Horizon: 10, Paths: 1000, Seed: 42, BlockSize: 2})
+	if a != b {
+		t.Errorf("same seed gave %+v and %+v", a, b)
+	}
+	if a.CVaR < a.VaR || a.Loss.P99 < a.Loss.P50 {
+		t.Errorf("inconsistent report %+v", a)
+	}
+
+	for _, opts := range []Options{{Paths: MaxPaths + 1}, {Confidence: 1}, {BlockSize: 5}, {Horizon: -1}} {
+		if _, err := Simulate([]Exposure{{"BTC", 1}}, mixed, opts); err == nil {
+			t.Errorf("%+v: expected an error", opts)
+		}
+	}
+	if _, err := Simulate([]Exposure{{"SOL", 1}}, mixed, Options{}); !errors.Is(err, ErrNoHistory) {
+		t.Errorf("expected ErrNoHistory, got %v", err)
+	}
+}
+
+func TestSimulatePortfolio(t *testing.T) {
+	h := NewHistory(HistoryOptions{Interval: time.Minute})
+	for i, price := range []float64{100, 90, 99} {
+		h.Record("BTC", price, time.Unix(int64(i)*60, 0))
+	}
+	v := pms.Valuation{
+		PortfolioID: "main",
+		Currency:    "USDT",
+		Positions: []pms.PositionValue{
+			{Symbol: "BTC-USDT", Value: 600},
+			{Symbol: "BTC-USD", Value: 400}, // Perp of the same asset
+			{Symbol: "USDT-USD", Value: 250},
+			{Symbol: "SOL-USDT", Value: 50},
+			{Symbol: "ETH-USDT", Error: "no rate"},
+		},
+	}
+	r, err := SimulatePortfolio(v, h, Options{Paths: 200, Seed: 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(r.Exposures) != 1 || r.Exposures[0] != (Exposure{"BTC", 1000}) || r.Cash != 250 || r.Value != 1250 {
+		t.Errorf("report = %+v", r)
+	}
+	if r.Complete || len(r.Excluded) != 2 || r.Excluded[0] != "ETH-USDT" || r.Excluded[1] != "SOL" {
+		t.Errorf("excluded = %v", r.Excluded)
+	}
+	if r.VaR <= 0 || r.Drawdown.Max <= 0 {
+		t.Errorf("expected a loss tail, got %+v", r.Report)
+	}
+
+	cash, err := SimulatePortfolio(pms.Valuation{Currency: "USDT", Positions: []pms.PositionValue{{Symbol: "USDT-USD", Value: 10}}}, h, Options{Seed: 1})
+	if err != nil || cash.VaR != 0 || cash.Value != 10 {
+		t.Errorf("cash only = %+v, %v", cash, err)
+	}
+}
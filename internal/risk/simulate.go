@@ -0,0 +1,189 @@
+package risk
+
+import (
+	"fmt"
+	"math"
+	"math/rand"
+	"sort"
+	"time"
+)
+
+// Limits of a simulation, bounding the work of one request
+const (
+	MaxPaths = 100000
+	maxSteps = 50_000_000 // Paths x horizon x assets
+)
+
+// Options configures a simulation
+type Options struct {
+	Horizon    int     // Periods simulated, 24 when 0
+	Paths      int     // Simulated paths, 10000 when 0
+	Confidence float64 // VaR confidence level, 0.99 when 0
+	BlockSize  int     // Consecutive periods drawn together to keep volatility clustering, 1 when 0
+	Seed       int64   // Seed of the draws, random when 0
+}
+
+func (o *Options) defaults() error {
+	if o.Horizon == 0 {
+		o.Horizon = 24
+	}
+	if o.Paths == 0 {
+		o.Paths = 10000
+	}
+	if o.Confidence == 0 {
+		o.Confidence = 0.99
+	}
+	if o.BlockSize == 0 {
+		o.BlockSize = 1
+	}
+	if o.Seed == 0 {
+		o.Seed = time.Now().UnixNano()
+	}
+	switch {
+	case o.Horizon < 0 || o.BlockSize < 0:
+		return fmt.Errorf("%w: horizon and block size cannot be negative", ErrInvalid)
+	case o.Paths < 0 || o.Paths > MaxPaths:
+		return fmt.Errorf("%w: paths must be between 1 and %d", ErrInvalid, MaxPaths)
+	case o.Confidence <= 0 || o.Confidence >= 1:
+		return fmt.Errorf("%w: confidence must be between 0 and 1", ErrInvalid)
+	}
+	return nil
+}
+
+// Exposure is the value held in an asset, negative when short
+type Exposure struct {
+	Asset string  `json:"asset"`
+	Value float64 `json:"value"`
+}
+
+// Distribution summarizes simulated outcomes
+type Distribution struct {
+	Mean float64 `json:"mean"`
+	P50  float64 `json:"p50"`
+	P95  float64 `json:"p95"`
+	P99  float64 `json:"p99"`
+	Max  float64 `json:"max"`
+}
+
+// Report is the outcome of a simulation. Amounts are in the currency of the
+// exposures; losses and drawdowns are positive.
+type Report struct {
+	Value       float64       `json:"value"`   // Sum of the exposures
+	Horizon     time.Duration `json:"horizon"` // Periods times their interval
+	Periods     int           `json:"periods"` // Historical periods bootstrapped
+	Paths       int           `json:"paths"`
+	Confidence  float64       `json:"confidence"`
+	Seed        int64         `json:"seed"` // Reproduces the simulation
+	ExpectedPnL float64       `json:"expected_pnl"`
+	VaR         float64       `json:"var"`  // Loss not exceeded at the confidence level
+	CVaR        float64       `json:"cvar"` // Mean loss beyond the VaR
+	Loss        Distribution  `json:"loss"`
+	Drawdown    Distribution  `json:"drawdown"` // Largest fall from a peak within each path
+}
+
+// Simulate bootstraps the returns over the horizon for the exposures. Each
+// path draws historical periods with replacement, in blocks of BlockSize,
+// and applies the returns of every asset of a period together so their
+// correlation is kept.
+func Simulate(exposures []Exposure, returns Returns, opts Options) (Report, error) {
+	if err := opts.defaults(); err != nil {
+		return Report{}, err
+	}
+	periods := len(returns.Periods)
+	if periods == 0 {
+		return Report{}, ErrNoHistory
+	}
+	if opts.BlockSize > periods {
+		return Report{}, fmt.Errorf("%w: block size %d exceeds the %d recorded periods", ErrInvalid, opts.BlockSize, periods)
+	}
+	if opts.Paths*opts.Horizon*len(exposures) > maxSteps {
+		return Report{}, fmt.Errorf("%w: paths x horizon x assets cannot exceed %d", ErrInvalid, maxSteps)
+	}
+	column := make(map[string]int, len(returns.Assets))
+	for i, asset := range returns.Assets {
+		column[asset] = i
+	}
+	columns := make([]int, len(exposures))
+	report := Report{
+		Horizon:    time.Duration(opts.Horizon) * returns.Interval,
+		Periods:    periods,
+		Paths:      opts.Paths,
+		Confidence: opts.Confidence,
+		Seed:       opts.Seed,
+	}
+	for i, e := range exposures {
+		c, ok := column[e.Asset]
+		if !ok {
+			return Report{}, fmt.Errorf("%w for %s", ErrNoHistory, e.Asset)
+		}
+		columns[i] = c
+		report.Value += e.Value
+	}
+
+	rng := rand.New(rand.NewSource(opts.Seed))
+	losses := make([]float64, opts.Paths)
+	drawdowns := make([]float64, opts.Paths)
+	values := make([]float64, len(exposures))
+	for path := range losses {
+		for i, e := range exposures {
+			values[i] = e.Value
+		}
+		total, peak, drawdown := report.Value, report.Value, 0.0
+		start := 0
+		for step := 0; step < opts.Horizon; step++ {
+			if step%opts.BlockSize == 0 {
+				start = rng.Intn(periods)
+			}
+			period := returns.Periods[(start+step%opts.BlockSize)%periods]
+			total = 0
+			for i, c := range columns {
+				values[i] *= 1 + period[c]
+				total += values[i]
+			}
+			peak = max(peak, total)
+			drawdown = max(drawdown, peak-total)
+		}
+		losses[path] = report.Value - total
+		drawdowns[path] = drawdown
+	}
+
+	report.Loss = distribution(losses)
+	report.Drawdown = distribution(drawdowns)
+	report.ExpectedPnL = -report.Loss.Mean
+	report.VaR = percentile(losses, opts.Confidence)
+	tail, n := 0.0, 0
+	for _, loss := range losses {
+		if loss >= report.VaR {
+			tail += loss
+			n++
+		}
+	}
+	report.CVaR = tail / float64(n)
+	return report, nil
+}
+
+// distribution sorts the outcomes and summarizes them
+func distribution(outcomes []float64) Distribution {
+	sort.Float64s(outcomes)
+	sum := 0.0
+	for _, v := range outcomes {
+		sum += v
+	}
+	return Distribution{
+		Mean: sum / float64(len(outcomes)),
+		P50:  percentile(outcomes, 0.5),
+		P95:  percentile(outcomes, 0.95),
+		P99:  percentile(outcomes, 0.99),
+		Max:  outcomes[len(outcomes)-1],
+	}
+}
+
+// percentile interpolates the p quantile of sorted outcomes
+func percentile(sorted []float64, p float64) float64 {
+	rank := p * float64(len(sorted)-1)
+	lower := int(math.Floor(rank))
+	if lower+1 >= len(sorted) {
+		return sorted[len(sorted)-1]
+	}
+	return sorted[lower] + (rank-float64(lower))*(sorted[lower+1]-sorted[lower])
+}
@@ -0,0 +1,96 @@
+package rpcgen
+
+import (
+	"bytes"
+	"fmt"
+	"go/format"
+	"strings"
+	"text/template"
+)
+
+var fileTemplate = template.Must(template.New("file").Funcs(template.FuncMap{
+	"comment": func(indent, text string) string {
+		var b strings.Builder
+		for _, line := range strings.Split(text, "\n") {
+			b.WriteString(indent + "// " + line + "\n")
+		}
+		return b.String()
+	},
+}).Parse(`// Code generated by rpcgen from {{.Name}}. DO NOT EDIT.
+
+package {{.GoPackage}}
+
+import (
+	"context"
+
+	"github.com/BullionBear/sequex/pkg/eventbus"
+	"github.com/nats-io/nats.go"
+)
+{{range $s := .Services}}
+// {{$s.Name}}Server is implemented by the servers of the {{$s.Name}} service
+{{if $s.Comment}}//
+{{comment "" $s.Comment}}{{end}}type {{$s.Name}}Server interface {
+{{- range $s.Methods}}
+{{if .Comment}}{{comment "\t" .Comment}}{{end}}	{{.Name}}(ctx context.Context, req *{{.Input}}) (*{{.Output}}, error)
+{{- end}}
+}
+{{range $s.Methods}}
+// {{$s.Name}}{{.Name}}Endpoint is the {{.Name}} endpoint of the {{$s.Name}} service
+// served under prefix, on prefix.{{.Subject}}
+func {{$s.Name}}{{.Name}}Endpoint(prefix string) eventbus.Endpoint[{{.Input}}, {{.Output}}] {
+	return eventbus.{{if .Command}}NewCommand{{else}}NewEndpoint{{end}}[{{.Input}}, {{.Output}}](prefix+".{{.Subject}}", eventbus.Proto)
+}
+{{end}}
+// Register{{$s.Name}}Server serves every method of srv under prefix
+func Register{{$s.Name}}Server(bus *eventbus.Bus, prefix string, srv {{$s.Name}}Server) ([]*nats.Subscription, error) {
+	var subs []*nats.Subscription
+	for _, serve := range []func() (*nats.Subscription, error){
+{{- range $s.Methods}}
+		func() (*nats.Subscription, error) { return {{$s.Name}}{{.Name}}Endpoint(prefix).Serve(bus, srv.{{.Name}}) },
+{{- end}}
+	} {
+		sub, err := serve()
+		if err != nil {
+			for _, sub := range subs {
+				sub.Unsubscribe()
+			}
+			return nil, err
+		}
+		subs = append(subs, sub)
+	}
+	return subs, nil
+}
+
+// {{$s.Name}}Client calls the {{$s.Name}} service served under a prefix
+type {{$s.Name}}Client struct {
+	bus    *eventbus.Bus
+	prefix string
+}
+
+// New{{$s.Name}}Client creates a client of the {{$s.Name}} service served under prefix
+func New{{$s.Name}}Client(bus *eventbus.Bus, prefix string) *{{$s.Name}}Client {
+	return &{{$s.Name}}Client{bus: bus, prefix: prefix}
+}
+{{range $s.Methods}}
+{{if .Comment}}{{comment "" .Comment}}{{else}}// {{.Name}} calls the {{.Name}} method
+{{end}}func (c *{{$s.Name}}Client) {{.Name}}(ctx context.Context, req *{{.Input}}) (*{{.Output}}, error) {
+	return {{$s.Name}}{{.Name}}Endpoint(c.prefix).Call(ctx, c.bus, req)
+}
+{{end}}{{end}}`))
+
+// Generate returns the Go source of the clients and server interfaces of
+// the services of f
+func Generate(f *File) ([]byte, error) {
+	if len(f.Services) == 0 {
+		return nil, fmt.Errorf("%s: no services", f.Name)
+	}
+	var buf bytes.Buffer
+	if err := fileTemplate.Execute(&buf, f); err != nil {
+		return nil, err
+	}
+	src, err := format.Source(buf.Bytes())
+	if err != nil {
+		return nil, fmt.Errorf("%s: invalid generated code: %w", f.Name, err)
+	}
+	return src, nil
+}
@@ -0,0 +1,408 @@
+// Package rpcgen generates typed eventbus RPC clients and server interfaces
+// from the service definitions of proto files, the messages being those
+// generated by protoc-gen-go in the same Go package. Streaming rpcs are
+// served over gRPC and skipped. A method with the option (app.command) set
+// is a command: its calls are signed and authorized.
+package rpcgen
+
+import (
+	"fmt"
+	"path"
+	"strings"
+	"unicode"
+)
+
+// File is the part of a proto file rpcgen reads
+type File struct {
+	Name      string // Path of the proto file
+	Package   string // Proto package
+	GoPackage string // Go package name
+	Services  []Service
+}
+
+// Service is a proto service
+type Service struct {
+	Name    string
+	Comment string
+	Methods []Method
+}
+
+// Method is an rpc of a service
+type Method struct {
+	Name    string
+	Input   string // Message name, without the proto package
+	Output  string
+	Comment string
+	Command bool // Served with eventbus.NewCommand
+}
+
+// Subject returns the subject token the method is served on under the
+// prefix of its service, e.g. req_metadata for ReqMetadata
+func (m Method) Subject() string {
+	var b strings.Builder
+	for i, r := range m.Name {
+		if unicode.IsUpper(r) {
+			if i > 0 {
+				b.WriteByte('_')
+			}
+			r = unicode.ToLower(r)
+		}
+		b.WriteRune(r)
+	}
+	return b.String()
+}
+
+type token struct {
+	text    string
+	line    int
+	comment string // Comment lines right above the token
+}
+
+// Parse reads the package, go_package option and services of a proto file
+func Parse(name string, src []byte) (*File, error) {
+	tokens, err := tokenize(string(src))
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", name, err)
+	}
+	p := &parser{name: name, tokens: tokens}
+	f, err := p.file()
+	if err != nil {
+		return nil, err
+	}
+	return f, nil
+}
+
+type parser struct {
+	name   string
+	tokens []token
+	pos    int
+}
+
+func (p *parser) errorf(format string, args ...any) error {
+	line := 0
+	if p.pos < len(p.tokens) {
+		line = p.tokens[p.pos].line
+	} else if len(p.tokens) > 0 {
+		line = p.tokens[len(p.tokens)-1].line
+	}
+	return fmt.Errorf("%s:%d: %s", p.name, line, fmt.Sprintf(format, args...))
+}
+
+func (p *parser) next() (token, error) {
+	if p.pos >= len(p.tokens) {
+		return token{}, p.errorf("unexpected end of file")
+	}
+	t := p.tokens[p.pos]
+	p.pos++
+	return t, nil
+}
+
+func (p *parser) expect(text string) error {
+	t, err := p.next()
+	if err != nil {
+		return err
+	}
+	if t.text != text {
+		p.pos--
+		return p.errorf("expected %q, got %q", text, t.text)
+	}
+	return nil
+}
+
+// skipStatement skips up to the end of a statement or a block
+func (p *parser) skipStatement() error {
+	depth := 0
+	for {
+		t, err := p.next()
+		if err != nil {
+			return err
+		}
+		switch t.text {
+		case "{":
+			depth++
+		case "}":
+			depth--
+			if depth == 0 {
+				return nil
+			}
+		case ";":
+			if depth == 0 {
+				return nil
+			}
+		}
+	}
+}
+
+func (p *parser) file() (*File, error) {
+	f := &File{Name: p.name}
+	for p.pos < len(p.tokens) {
+		t := p.tokens[p.pos]
+		switch t.text {
+		case "package":
+			p.pos++
+			name, err := p.next()
+			if err != nil {
+				return nil, err
+			}
+			f.Package = name.text
+			if err := p.expect(";"); err != nil {
+				return nil, err
+			}
+		case "option":
+			p.pos++
+			name, err := p.next()
+			if err != nil {
+				return nil, err
+			}
+			if name.text != "go_package" {
+				p.pos--
+				if err := p.skipStatement(); err != nil {
+					return nil, err
+				}
+				continue
+			}
+			if err := p.expect("="); err != nil {
+				return nil, err
+			}
+			value, err := p.next()
+			if err != nil {
+				return nil, err
+			}
+			f.GoPackage = goPackageName(strings.Trim(value.text, `"`))
+			if err := p.expect(";"); err != nil {
+				return nil, err
+			}
+		case "service":
+			p.pos++
+			s, err := p.service(t.comment, f.Package)
+			if err != nil {
+				return nil, err
+			}
+			if len(s.Methods) > 0 {
+				f.Services = append(f.Services, s)
+			}
+		default:
+			if err := p.skipStatement(); err != nil {
+				return nil, err
+			}
+		}
+	}
+	if len(f.Services) > 0 && f.GoPackage == "" {
+		return nil, fmt.Errorf("%s: go_package option required", p.name)
+	}
+	return f, nil
+}
+
+func (p *parser) service(comment, pkg string) (Service, error) {
+	name, err := p.next()
+	if err != nil {
+		return Service{}, err
+	}
+	s := Service{Name: name.text, Comment: comment}
+	if err := p.expect("{"); err != nil {
+		return Service{}, err
+	}
+	for {
+		t, err := p.next()
+		if err != nil {
+			return Service{}, err
+		}
+		switch t.text {
+		case "}":
+			return s, nil
+		case "rpc":
+			m, streaming, err := p.method(t.comment, pkg)
+			if err != nil {
+				return Service{}, err
+			}
+			if !streaming {
+				s.Methods = append(s.Methods, m)
+			}
+		case ";":
+		default:
+			p.pos--
+			if err := p.skipStatement(); err != nil {
+				return Service{}, err
+			}
+		}
+	}
+}
+
+// method reads an rpc, reporting whether it streams either way
+func (p *parser) method(comment, pkg string) (Method, bool, error) {
+	name, err := p.next()
+	if err != nil {
+		return Method{}, false, err
+	}
+	m := Method{Name: name.text, Comment: comment}
+	var inStream, outStream bool
+	if m.Input, inStream, err = p.messageType(pkg); err != nil {
+		return Method{}, false, err
+	}
+	if err := p.expect("returns"); err != nil {
+		return Method{}, false, err
+	}
+	if m.Output, outStream, err = p.messageType(pkg); err != nil {
+		return Method{}, false, err
+	}
+	t, err := p.next()
+	if err != nil {
+		return Method{}, false, err
+	}
+	switch t.text {
+	case ";":
+	case "{":
+		if m.Command, err = p.methodOptions(pkg); err != nil {
+			return Method{}, false, err
+		}
+	default:
+		p.pos--
+		return Method{}, false, p.errorf("expected \";\", got %q", t.text)
+	}
+	return m, inStream || outStream, nil
+}
+
+// methodOptions reads the options of a method up to its closing brace,
+// reporting whether (command) is set
+func (p *parser) methodOptions(pkg string) (bool, error) {
+	command := false
+	for {
+		t, err := p.next()
+		if err != nil {
+			return false, err
+		}
+		switch t.text {
+		case "}":
+			return command, nil
+		case ";":
+		case "option":
+			if p.pos+5 < len(p.tokens) && p.tokens[p.pos].text == "(" && p.tokens[p.pos+2].text == ")" {
+				name := strings.TrimPrefix(p.tokens[p.pos+1].text, ".")
+				if name == "command" || pkg != "" && name == pkg+".command" {
+					p.pos += 3
+					if err := p.expect("="); err != nil {
+						return false, err
+					}
+					value, err := p.next()
+					if err != nil {
+						return false, err
+					}
+					if value.text != "true" && value.text != "false" {
+						p.pos--
+						return false, p.errorf("expected a bool, got %q", value.text)
+					}
+					command = value.text == "true"
+					if err := p.expect(";"); err != nil {
+						return false, err
+					}
+					continue
+				}
+			}
+			if err := p.skipStatement(); err != nil {
+				return false, err
+			}
+		default:
+			p.pos--
+			return false, p.errorf("expected an option, got %q", t.text)
+		}
+	}
+}
+
+// messageType reads a parenthesized message type of the package and
+// whether it streams
+func (p *parser) messageType(pkg string) (string, bool, error) {
+	if err := p.expect("("); err != nil {
+		return "", false, err
+	}
+	t, err := p.next()
+	if err != nil {
+		return "", false, err
+	}
+	stream := t.text == "stream"
+	if stream {
+		if t, err = p.next(); err != nil {
+			return "", false, err
+		}
+	}
+	name := strings.TrimPrefix(t.text, ".")
+	if pkg != "" {
+		name = strings.TrimPrefix(name, pkg+".")
+	}
+	if strings.Contains(name, ".") {
+		return "", false, p.errorf("message %s is not of package %s", t.text, pkg)
+	}
+	return name, stream, p.expect(")")
+}
+
+// goPackageName returns the package name of a go_package option, e.g.
+// protobuf for github.com/x/y/protobuf or name for github.com/x/y;name
+func goPackageName(option string) string {
+	if _, name, ok := strings.Cut(option, ";"); ok {
+		return name
+	}
+	return path.Base(option)
+}
+
+// tokenize splits proto source into identifiers, strings and symbols,
+// attaching the line comments right above a token to it
+func tokenize(src string) ([]token, error) {
+	var tokens []token
+	var comment []string
+	commentLine := 0
+	line := 1
+	for i := 0; i < len(src); {
+		c := src[i]
+		switch {
+		case c == '\n':
+			line++
+			i++
+		case c == ' ' || c == '\t' || c == '\r':
+			i++
+		case strings.HasPrefix(src[i:], "//"):
+			end := strings.IndexByte(src[i:], '\n')
+			if end < 0 {
+				end = len(src) - i
+			}
+			if commentLine != line-1 {
+				comment = nil
+			}
+			comment = append(comment, strings.TrimSpace(src[i+2:i+end]))
+			commentLine = line
+			i += end
+		case strings.HasPrefix(src[i:], "/*"):
+			end := strings.Index(src[i+2:], "*/")
+			if end < 0 {
+				return nil, fmt.Errorf("%d: unterminated comment", line)
+			}
+			line += strings.Count(src[i:i+2+end], "\n")
+			i += end + 4
+		case c == '"' || c == '\'':
+			end := strings.IndexByte(src[i+1:], c)
+			if end < 0 {
+				return nil, fmt.Errorf("%d: unterminated string", line)
+			}
+			tokens = append(tokens, token{text: src[i : i+end+2], line: line})
+			i += end + 2
+		case isIdent(c):
+			j := i
+			for j < len(src) && isIdent(src[j]) {
+				j++
+			}
+			t := token{text: src[i:j], line: line}
+			if commentLine == line-1 {
+				t.comment = strings.Join(comment, "\n")
+			}
+			tokens = append(tokens, t)
+			comment = nil
+			i = j
+		default:
+			tokens = append(tokens, token{text: string(c), line: line})
+			i++
+		}
+	}
+	return tokens, nil
+}
+
+func isIdent(c byte) bool {
+	return c == '_' || c == '.' || c >= '0' && c <= '9' || c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z'
+}
@@ -0,0 +1,113 @@
+package rpcgen
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+const nodeProto = `syntax = "proto3";
+
+package app;
+
+option go_package = "github.com/BullionBear/sequex/internal/model/protobuf";
+
+import "protobuf/shared.proto";
+
+message MetadataRequest {}
+
+message MetadataResponse {
+  string name = 1;
+  map<string, string> params = 2;
+}
+
+/* Services
+   of a node */
+
+// Node is the control API of a node
+service Node {
+  option deprecated = false;
+
+  // ReqMetadata returns the metadata of the node
+  rpc ReqMetadata(MetadataRequest) returns (app.MetadataResponse);
+  rpc Kill (.app.MetadataRequest) returns (MetadataResponse) {
+    option idempotency_level = IDEMPOTENT;
+    option (app.command) = true;
+  }
+  // Watch is served over gRPC
+  rpc Watch(MetadataRequest) returns (stream MetadataResponse);
+}
+
+// Feed only streams
+service Feed {
+  rpc Subscribe(stream MetadataRequest) returns (stream MetadataResponse);
+}
+`
+
+func TestParse(t *testing.T) {
+	f, err := Parse("protobuf/node.proto", []byte(nodeProto))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if f.Package != "app" || f.GoPackage != "protobuf" || len(f.Services) != 1 {
+		t.Fatalf("file = %+v", f)
+	}
+	s := f.Services[0]
+	want := []Method{
+		{Name: "ReqMetadata", Input: "MetadataRequest", Output: "MetadataResponse", Comment: "ReqMetadata returns the metadata of the node"},
+		{Name: "Kill", Input: "MetadataRequest", Output: "MetadataResponse", Command: true},
+	}
+	if s.Name != "Node" || s.Comment != "Node is the control API of a node" || !reflect.DeepEqual(s.Methods, want) {
+		t.Errorf("service = %+v", s)
+	}
+	if got := s.Methods[0].Subject(); got != "req_metadata" {
+		t.Errorf("subject = %s", got)
+	}
+}
+
+func TestParseErrors(t *testing.T) {
+	tests := map[string]string{
+		"option":  `package app; option go_package = "x"; service S { rpc M(A) returns (B) { option (command) = yes; } }`,
+		"foreign": `package app; option go_package = "x"; service S { rpc M(google.protobuf.Empty) returns (B); }`,
+		"package": `package app; service S { rpc M(A) returns (B); }`,
+		"syntax":  `package app; option go_package = "x"; service S { rpc M(A) (B); }`,
+	}
+	for name, src := range tests {
+		if _, err := Parse(name+".proto", []byte(src)); err == nil {
+			t.Errorf("%s: no error", name)
+		}
+	}
+}
+
+func TestGenerate(t *testing.T) {
+	f, err := Parse("protobuf/node.proto", []byte(nodeProto))
+	if err != nil {
+		t.Fatal(err)
+	}
+	src, err := Generate(f)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{
+		"// Code generated by rpcgen from protobuf/node.proto. DO NOT EDIT.",
+		"package protobuf",
+		"type NodeServer interface {",
+		"\tReqMetadata(ctx context.Context, req *MetadataRequest) (*MetadataResponse, error)",
+		`eventbus.NewEndpoint[MetadataRequest, MetadataResponse](prefix+".req_metadata", eventbus.Proto)`,
+		`eventbus.NewCommand[MetadataRequest, MetadataResponse](prefix+".kill", eventbus.Proto)`,
+		"func RegisterNodeServer(bus *eventbus.Bus, prefix string, srv NodeServer) ([]*nats.Subscription, error) {",
+		"// ReqMetadata returns the metadata of the node\nfunc (c *NodeClient) ReqMetadata(",
+		"// Kill calls the Kill method\nfunc (c *NodeClient) Kill(",
+	} {
+		if !strings.Contains(string(src), want) {
+			t.Errorf("generated code lacks %q:\n%s", want, src)
+		}
+	}
+	if strings.Contains(string(src), "Watch") || strings.Contains(string(src), "Feed") {
+		t.Errorf("generated code serves streaming rpcs:\n%s", src)
+	}
+
+	if _, err := Generate(&File{Name: "empty.proto"}); err == nil {
+		t.Error("generated a file without services")
+	}
+}
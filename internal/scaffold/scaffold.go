@@ -0,0 +1,282 @@
+// Package scaffold generates the configuration of new nodes, with subjects
+// and RPC endpoints named after the conventions of the other nodes, and the
+// stub of new strategies. Configurations are validated as the node would
+// load them before they are written.
+package scaffold
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/BullionBear/sequex/internal/config"
+	"github.com/BullionBear/sequex/internal/model/sqx"
+	"github.com/BullionBear/sequex/pkg/eventbus"
+	"gopkg.in/yaml.v3"
+)
+
+// DefaultNATSURIs are the servers of the generated configurations when none
+// are given, those of the local cluster
+const DefaultNATSURIs = "nats://localhost:4222,nats://localhost:4223,nats://localhost:4224"
+
+// Params describe the node to generate
+type Params struct {
+	Type       string     // Template, see Templates
+	Name       string     // Of the strategy, required by the strategy template
+	Exchange   string     // binance when empty
+	Instrument string     // spot when empty
+	Symbol     sqx.Symbol // Traded or streamed
+	NATSURIs   string     // DefaultNATSURIs when empty
+}
+
+// Node is a generated node
+type Node struct {
+	Name       string            // Of the node, used for its files and RPC subjects
+	Type       string            // Template generated from
+	Config     []byte            // YAML configuration
+	ConfigPath string            // Conventional path of the configuration
+	Stub       []byte            // Go source of the strategy, nil for nodes without one
+	StubPath   string            // Conventional path of the stub
+	Subjects   map[string]string // Subjects the node reads and publishes, by role
+	Endpoints  []string          // RPC subjects the node answers
+}
+
+// Template generates the configuration of a kind of node
+type Template struct {
+	Type    string
+	Summary string
+	Command string // Running the node, e.g. ./bin/feed-linux-amd64 -c <config>
+	build   func(p Params, n *Node) (any, error)
+}
+
+var templates = []Template{
+	{Type: "basis", Summary: "Spot-perp basis signals of a symbol", Command: "./bin/basis-linux-amd64", build: buildBasis},
+	{Type: "feed", Summary: "Trade feed of a symbol published to the TRADE stream", Command: "./bin/feed-linux-amd64", build: buildFeed},
+	{Type: "strategy", Summary: "Strategy configuration and Go stub trading a symbol", build: buildStrategy},
+}
+
+// Templates returns the templates, sorted by type
+func Templates() []Template {
+	return append([]Template(nil), templates...)
+}
+
+// quotes are the quote assets a symbol without separator is split on, the
+// longest first so that FDUSD is not read as a USD quote
+var quotes = []string{"FDUSD", "USDT", "USDC", "TUSD", "BUSD", "BTC", "ETH", "BNB", "EUR", "TRY", "USD"}
+
+// ParseSymbol reads a symbol written BTC-USDT or BTCUSDT
+func ParseSymbol(s string) (sqx.Symbol, error) {
+	s = strings.ToUpper(strings.TrimSpace(s))
+	if strings.Contains(s, "-") {
+		return sqx.NewSymbolFromStr(s)
+	}
+	for _, quote := range quotes {
+		if base, ok := strings.CutSuffix(s, quote); ok && base != "" {
+			return sqx.Symbol{Base: base, Quote: quote}, nil
+		}
+	}
+	return sqx.Symbol{}, fmt.Errorf("cannot split symbol %q, write it BASE-QUOTE", s)
+}
+
+// Generate generates a node from its template
+func Generate(p Params) (*Node, error) {
+	var tmpl *Template
+	for i := range templates {
+		if templates[i].Type == p.Type {
+			tmpl = &templates[i]
+		}
+	}
+	if tmpl == nil {
+		types := make([]string, 0, len(templates))
+		for _, t := range templates {
+			types = append(types, t.Type)
+		}
+		return nil, fmt.Errorf("unknown node type %q, expected one of %s", p.Type, strings.Join(types, ", "))
+	}
+	if p.Exchange == "" {
+		p.Exchange = "binance"
+	}
+	if p.Instrument == "" {
+		p.Instrument = "spot"
+	}
+	if p.NATSURIs == "" {
+		p.NATSURIs = DefaultNATSURIs
+	}
+	p.Exchange, p.Instrument = strings.ToLower(p.Exchange), strings.ToLower(p.Instrument)
+	for field, token := range map[string]string{"exchange": p.Exchange, "instrument": p.Instrument} {
+		if err := eventbus.ValidateToken(token); err != nil {
+			return nil, fmt.Errorf("invalid %s: %w", field, err)
+		}
+	}
+	if p.Symbol.Base == "" || p.Symbol.Quote == "" {
+		return nil, fmt.Errorf("a symbol is required")
+	}
+
+	n := &Node{Type: p.Type, Subjects: make(map[string]string)}
+	cfg, err := tmpl.build(p, n)
+	if err != nil {
+		return nil, err
+	}
+	if v, ok := cfg.(interface{ Validate() error }); ok {
+		if err := v.Validate(); err != nil {
+			return nil, fmt.Errorf("generated configuration is invalid: %w", err)
+		}
+	}
+	comment := fmt.Sprintf("Generated by sqx new node --type %s", p.Type)
+	if tmpl.Command != "" {
+		comment += fmt.Sprintf("\nRun with %s -c %s", tmpl.Command, n.ConfigPath)
+	}
+	if n.Config, err = renderYAML(cfg, comment); err != nil {
+		return nil, err
+	}
+	sort.Strings(n.Endpoints)
+	return n, nil
+}
+
+// symbolToken is the symbol as a subject token, e.g. btcusdt
+func symbolToken(s sqx.Symbol) string {
+	return strings.ToLower(s.Base + s.Quote)
+}
+
+// endpoints lists the RPC subjects of the diagnostics of a node run as
+// service
+func endpoints(d config.DiagnosticsConfig, service string) []string {
+	var subjects []string
+	opts := d.Options(service)
+	for _, subject := range []string{opts.RPCSubject, d.DescribeSubject(service), d.SnapshotSubject(service)} {
+		if subject != "" {
+			subjects = append(subjects, subject)
+		}
+	}
+	return subjects
+}
+
+func buildFeed(p Params, n *Node) (any, error) {
+	n.Name = strings.Join([]string{"trade", p.Exchange, p.Instrument, symbolToken(p.Symbol)}, "-")
+	n.ConfigPath = "config/" + n.Name + ".yml"
+	cfg := &config.Config{
+		Exchange:   p.Exchange,
+		Instrument: p.Instrument,
+		Symbol:     p.Symbol.String(),
+		Type:       "trade",
+		Queue:      config.QueueConfig{Size: 10000, Overflow: "block", HighWatermark: 8000, StatsIntervalMs: 60000},
+		Dedup:      config.DedupConfig{Path: "./data/dedup/" + n.Name + ".log", Window: 100000},
+		Lifecycle:  &config.LifecycleConfig{IntervalMs: 60000, Subject: "feed.lifecycle." + p.Exchange + "." + p.Instrument},
+		// Every feed answers its own diagnostics, diag.feed being shared
+		Diagnostics: config.DiagnosticsConfig{RPC: true, RPCSubject: "diag." + n.Name, Logs: true},
+		NATS: config.NATSConfig{
+			URIs:    p.NATSURIs,
+			Stream:  "TRADE",
+			Subject: strings.Join([]string{"trade", p.Exchange, p.Instrument, symbolToken(p.Symbol)}, "."),
+		},
+	}
+	n.Subjects["trades"] = cfg.NATS.Subject
+	n.Subjects["lifecycle"] = cfg.Lifecycle.Subject
+	n.Subjects["logs"] = cfg.Diagnostics.Options("feed").LogSubject
+	n.Endpoints = endpoints(cfg.Diagnostics, "feed")
+	return cfg, nil
+}
+
+func buildBasis(p Params, n *Node) (any, error) {
+	if p.Exchange != "binance" || p.Instrument != "spot" {
+		return nil, fmt.Errorf("basis nodes join binance spot trades with binance perp marks")
+	}
+	symbol := symbolToken(p.Symbol)
+	n.Name = "basis-" + symbol
+	n.ConfigPath = "config/" + n.Name + ".yml"
+	cfg := &config.BasisConfig{
+		Spot:     []string{"trade.binance.spot." + symbol},
+		Poll:     config.MarkPollConfig{Symbols: []string{p.Symbol.String()}, IntervalMs: 5000},
+		MaxAgeMs: 30000,
+		Rules: []config.CarryRuleConfig{
+			{Symbol: p.Symbol.String(), EntryAPR: 0.15, ExitAPR: 0.05, MinFundingRate: 0.0001, RoundTripFee: 0.002},
+		},
+		Diagnostics: config.DiagnosticsConfig{RPC: true, RPCSubject: "diag." + n.Name, Describe: true, Snapshot: true},
+		NATS: config.NATSConfig{
+			URIs:    p.NATSURIs,
+			Stream:  "SIGNAL",
+			Subject: "signal.basis",
+		},
+	}
+	n.Subjects["spot"] = cfg.Spot[0]
+	n.Subjects["signals"] = cfg.NATS.Subject + "." + symbol
+	n.Endpoints = endpoints(cfg.Diagnostics, "basis")
+	return cfg, nil
+}
+
+// renderYAML renders a configuration as YAML with the keys in the order of
+// its fields, leaving out the zero values its loader defaults to anyway
+func renderYAML(cfg any, comment string) ([]byte, error) {
+	data, err := json.Marshal(cfg)
+	if err != nil {
+		return nil, err
+	}
+	// JSON is YAML, decoding it as a node keeps the order of the keys
+	var doc yaml.Node
+	if err := yaml.Unmarshal(data, &doc); err != nil {
+		return nil, err
+	}
+	prune(&doc)
+	blockStyle(&doc)
+	doc.HeadComment = comment
+	var b strings.Builder
+	enc := yaml.NewEncoder(&b)
+	enc.SetIndent(2)
+	if err := enc.Encode(&doc); err != nil {
+		return nil, err
+	}
+	if err := enc.Close(); err != nil {
+		return nil, err
+	}
+	return []byte(b.String()), nil
+}
+
+// prune removes the keys of zero values, reporting whether n is zero
+func prune(n *yaml.Node) bool {
+	switch n.Kind {
+	case yaml.DocumentNode:
+		for _, c := range n.Content {
+			prune(c)
+		}
+		return false
+	case yaml.MappingNode:
+		content := n.Content[:0]
+		for i := 0; i+1 < len(n.Content); i += 2 {
+			if !prune(n.Content[i+1]) {
+				content = append(content, n.Content[i], n.Content[i+1])
+			}
+		}
+		n.Content = content
+		return len(content) == 0
+	case yaml.SequenceNode:
+		for _, c := range n.Content {
+			prune(c)
+		}
+		return len(n.Content) == 0
+	case yaml.ScalarNode:
+		if n.Tag == "!!str" {
+			return n.Value == ""
+		}
+		return n.Value == "0" || n.Value == "false" || n.Value == "null"
+	}
+	return false
+}
+
+// blockStyle drops the flow style and quotes of the JSON the node was
+// decoded from, keeping the quotes of strings that would read as another type
+func blockStyle(n *yaml.Node) {
+	if n.Kind == yaml.ScalarNode && n.Tag == "!!str" {
+		var v any
+		if yaml.Unmarshal([]byte(n.Value), &v) == nil {
+			if _, ok := v.(string); ok {
+				n.Style = 0
+			}
+		}
+	} else {
+		n.Style = 0
+	}
+	for _, c := range n.Content {
+		blockStyle(c)
+	}
+}
@@ -0,0 +1,108 @@
+package scaffold
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/BullionBear/sequex/internal/config"
+	"github.com/BullionBear/sequex/internal/model/sqx"
+	"github.com/BullionBear/sequex/internal/strategy"
+)
+
+func TestParseSymbol(t *testing.T) {
+	for input, want := range map[string]sqx.Symbol{
+		"BTCUSDT":   sqx.NewSymbol("BTC", "USDT"),
+		"ethbtc":    sqx.NewSymbol("ETH", "BTC"),
+		"SOLFDUSD":  sqx.NewSymbol("SOL", "FDUSD"),
+		"BTC-USDT":  sqx.NewSymbol("BTC", "USDT"),
+		"doge-usdc": sqx.NewSymbol("DOGE", "USDC"),
+	} {
+		if got, err := ParseSymbol(input); err != nil || got != want {
+			t.Errorf("ParseSymbol(%q) = %v, %v", input, got, err)
+		}
+	}
+	if _, err := ParseSymbol("USDT"); err == nil {
+		t.Error("parsed a quote alone")
+	}
+}
+
+// load writes a generated configuration and loads it as the node would
+func load(t *testing.T, n *Node, fn func(path string) error) {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), filepath.Base(n.ConfigPath))
+	if err := os.WriteFile(path, n.Config, 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := fn(path); err != nil {
+		t.Errorf("generated configuration does not load: %v\n%s", err, n.Config)
+	}
+}
+
+func TestGenerateFeed(t *testing.T) {
+	n, err := Generate(Params{Type: "feed", Symbol: sqx.NewSymbol("BTC", "USDT")})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n.Name != "trade-binance-spot-btcusdt" || n.ConfigPath != "config/trade-binance-spot-btcusdt.yml" || n.Stub != nil {
+		t.Errorf("generated %+v", n)
+	}
+	if n.Subjects["trades"] != "trade.binance.spot.btcusdt" || strings.Join(n.Endpoints, ",") != "diag.trade-binance-spot-btcusdt" {
+		t.Errorf("subjects %v, endpoints %v", n.Subjects, n.Endpoints)
+	}
+	load(t, n, func(path string) error {
+		cfg, err := config.LoadConfig(path)
+		if err == nil && (cfg.Symbol != "BTC-USDT" || cfg.Queue.Size != 10000 || cfg.NATS.Stream != "TRADE") {
+			t.Errorf("loaded %+v", cfg)
+		}
+		return err
+	})
+	// Zero values are left to the defaults of the loader
+	if strings.Contains(string(n.Config), "chaos") || !strings.HasPrefix(string(n.Config), "# Generated by sqx new node --type feed") {
+		t.Errorf("configuration\n%s", n.Config)
+	}
+}
+
+func TestGenerateBasis(t *testing.T) {
+	n, err := Generate(Params{Type: "basis", Symbol: sqx.NewSymbol("ETH", "USDT")})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Join(n.Endpoints, ",") != "describe.basis,diag.basis-ethusdt,snapshot.basis" || n.Subjects["signals"] != "signal.basis.ethusdt" {
+		t.Errorf("subjects %v, endpoints %v", n.Subjects, n.Endpoints)
+	}
+	load(t, n, func(path string) error {
+		_, err := config.LoadBasisConfig(path)
+		return err
+	})
+	if _, err := Generate(Params{Type: "basis", Instrument: "perp", Symbol: sqx.NewSymbol("ETH", "USDT")}); err == nil {
+		t.Error("generated a basis node of perps")
+	}
+}
+
+func TestGenerateStrategy(t *testing.T) {
+	if _, err := Generate(Params{Type: "strategy", Symbol: sqx.NewSymbol("BTC", "USDT")}); err == nil {
+		t.Error("generated a strategy without a name")
+	}
+	if _, err := Generate(Params{Type: "strategy", Name: "func", Symbol: sqx.NewSymbol("BTC", "USDT")}); err == nil {
+		t.Error("generated a strategy named after a keyword")
+	}
+	n, err := Generate(Params{Type: "strategy", Name: "Grid", Symbol: sqx.NewSymbol("BTC", "USDT")})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n.Name != "grid-binance-spot-btcusdt" || n.StubPath != "internal/strategy/grid/grid.go" || !strings.Contains(string(n.Stub), "package grid") {
+		t.Errorf("generated %+v\n%s", n, n.Stub)
+	}
+	var cfg strategy.StrategyConfig
+	if err := config.UnmarshalYAML(n.Config, &cfg); err != nil {
+		t.Fatal(err)
+	}
+	if cfg.Name != "grid" || !cfg.Enabled || cfg.Parameters["trades"] != "trade.binance.spot.btcusdt" || cfg.Parameters["executions"] != "oms.execution.>" {
+		t.Errorf("configuration %+v", cfg)
+	}
+	if _, err := Generate(Params{Type: "grid", Symbol: sqx.NewSymbol("BTC", "USDT")}); err == nil || !strings.Contains(err.Error(), "basis, feed, strategy") {
+		t.Errorf("unknown type: %v", err)
+	}
+}
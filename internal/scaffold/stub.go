@@ -0,0 +1,112 @@
+package scaffold
+
+import (
+	"bytes"
+	"fmt"
+	"go/format"
+	"go/token"
+	"strings"
+	"text/template"
+
+	"github.com/BullionBear/sequex/internal/strategy"
+	"github.com/BullionBear/sequex/pkg/eventbus"
+)
+
+// strategyConfig is the configuration of a strategy, validated before it is
+// written
+type strategyConfig struct {
+	strategy.StrategyConfig
+}
+
+func (c strategyConfig) Validate() error {
+	if err := eventbus.ValidateToken(c.Name); err != nil {
+		return fmt.Errorf("invalid name: %w", err)
+	}
+	for _, key := range []string{"trades", "orders", "cancels", "executions"} {
+		subject, _ := c.Parameters[key].(string)
+		if err := eventbus.ValidatePattern(subject); err != nil {
+			return fmt.Errorf("invalid parameters.%s: %w", key, err)
+		}
+	}
+	return nil
+}
+
+func buildStrategy(p Params, n *Node) (any, error) {
+	name := strings.ToLower(p.Name)
+	if name == "" {
+		return nil, fmt.Errorf("a strategy name is required, e.g. grid")
+	}
+	if !token.IsIdentifier(name) || token.IsKeyword(name) {
+		return nil, fmt.Errorf("strategy name %q is not a Go package name", name)
+	}
+	symbol := symbolToken(p.Symbol)
+	n.Name = strings.Join([]string{name, p.Exchange, p.Instrument, symbol}, "-")
+	n.ConfigPath = "config/" + n.Name + ".yml"
+	cfg := strategyConfig{strategy.StrategyConfig{
+		Name:        name,
+		Description: fmt.Sprintf("%s strategy trading %s on %s %s", name, p.Symbol, p.Exchange, p.Instrument),
+		Parameters: map[string]interface{}{
+			"exchange":   p.Exchange,
+			"instrument": p.Instrument,
+			"symbol":     p.Symbol.String(),
+			"trades":     strings.Join([]string{"trade", p.Exchange, p.Instrument, symbol}, "."),
+			"orders":     "oms.intent.order",
+			"cancels":    "oms.intent.cancel",
+			"executions": "oms.execution.>", // Reports of the orders of the strategy carry its name
+		},
+		Enabled: true,
+	}}
+	for _, key := range []string{"trades", "orders", "cancels", "executions"} {
+		n.Subjects[key] = cfg.Parameters[key].(string)
+	}
+
+	stub, err := renderStub(name)
+	if err != nil {
+		return nil, err
+	}
+	n.Stub, n.StubPath = stub, "internal/strategy/"+name+"/"+name+".go"
+	return cfg, nil
+}
+
+var stubTemplate = template.Must(template.New("stub").Parse(`// Package {{.Package}} implements the {{.Package}} strategy
+package {{.Package}}
+
+import (
+	"context"
+
+	"github.com/BullionBear/sequex/internal/strategy"
+)
+
+// Strategy is the {{.Package}} strategy
+type Strategy struct {
+	*strategy.BaseStrategy
+}
+
+// New creates a {{.Package}} strategy
+func New() *Strategy {
+	return &Strategy{BaseStrategy: strategy.NewBaseStrategy()}
+}
+
+// Initialize registers the event handlers of the strategy
+func (s *Strategy) Initialize(ctx context.Context, config strategy.StrategyConfig) error {
+	if err := s.BaseStrategy.Initialize(ctx, config); err != nil {
+		return err
+	}
+	return s.RegisterEventHandler("trade", s.onTrade)
+}
+
+// onTrade handles the trades of the symbol
+func (s *Strategy) onTrade(ctx context.Context, event strategy.Event) error {
+	// TODO: implement the {{.Package}} strategy
+	return nil
+}
+`))
+
+// renderStub renders the Go stub of a strategy
+func renderStub(name string) ([]byte, error) {
+	var b bytes.Buffer
+	if err := stubTemplate.Execute(&b, struct{ Package string }{name}); err != nil {
+		return nil, err
+	}
+	return format.Source(b.Bytes())
+}
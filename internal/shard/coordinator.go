@@ -0,0 +1,224 @@
+package shard
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+)
+
+// SubscribeFunc starts streaming a symbol and returns the function stopping it
+type SubscribeFunc func(symbol string) (func(), error)
+
+// symbolState is what a coordinator knows of one of its symbols
+type symbolState struct {
+	unsubscribe func() // Nil while the subscription failed
+	since       int64  // Unix milliseconds of the subscription
+	trades      uint64
+	lastTrade   int64
+	err         error
+}
+
+// Coordinator keeps an instance subscribed to the symbols of its shard: it
+// heartbeats its status to the registry, reads the live members back and
+// moves symbols in and out when the assignment changes.
+type Coordinator struct {
+	member     string
+	assign     Assigner
+	registry   Registry // Nil for static shards without health reporting
+	subscribe  SubscribeFunc
+	staleAfter time.Duration
+	now        func() time.Time
+
+	mu      sync.Mutex
+	symbols map[string]*symbolState
+}
+
+// NewCoordinator creates a coordinator of member. Symbols without a trade
+// for staleAfter are reported stale; 0 disables the check.
+func NewCoordinator(member string, assign Assigner, registry Registry, subscribe SubscribeFunc, staleAfter time.Duration) *Coordinator {
+	return &Coordinator{
+		member:     member,
+		assign:     assign,
+		registry:   registry,
+		subscribe:  subscribe,
+		staleAfter: staleAfter,
+		now:        time.Now,
+		symbols:    make(map[string]*symbolState),
+	}
+}
+
+// Rebalance subscribes to the symbols assigned given the live members and
+// unsubscribes from the others. Failed subscriptions are reported in the
+// status and retried at the next rebalance. It returns the symbols added
+// and removed.
+func (c *Coordinator) Rebalance(members []string) (added, removed []string, err error) {
+	if !contains(members, c.member) {
+		members = append(members, c.member)
+	}
+	want := make(map[string]bool)
+	for _, symbol := range c.assign(members) {
+		want[symbol] = true
+	}
+
+	c.mu.Lock()
+	var stop []func()
+	var subscribe []string
+	for symbol, state := range c.symbols {
+		if !want[symbol] {
+			if state.unsubscribe != nil {
+				stop = append(stop, state.unsubscribe)
+			}
+			delete(c.symbols, symbol)
+			removed = append(removed, symbol)
+		}
+	}
+	for symbol := range want {
+		if state, ok := c.symbols[symbol]; !ok || state.unsubscribe == nil {
+			subscribe = append(subscribe, symbol)
+		}
+	}
+	c.mu.Unlock()
+
+	// Symbols are released before new ones are taken, and outside the lock
+	// so trades of the subscriptions being stopped can still be counted
+	for _, unsubscribe := range stop {
+		unsubscribe()
+	}
+	sort.Strings(subscribe)
+	var errs []error
+	for _, symbol := range subscribe {
+		unsubscribe, subErr := c.subscribe(symbol)
+		c.mu.Lock()
+		state, ok := c.symbols[symbol]
+		if !ok {
+			state = &symbolState{}
+			c.symbols[symbol] = state
+		}
+		state.since = c.now().UnixMilli()
+		state.err = subErr
+		if subErr == nil {
+			state.unsubscribe = unsubscribe
+			added = append(added, symbol)
+		} else {
+			errs = append(errs, fmt.Errorf("subscribe %s: %w", symbol, subErr))
+		}
+		c.mu.Unlock()
+	}
+	sort.Strings(removed)
+	return added, removed, errors.Join(errs...)
+}
+
+func contains(members []string, member string) bool {
+	for _, m := range members {
+		if m == member {
+			return true
+		}
+	}
+	return false
+}
+
+// OnTrade records a trade of symbol received at timestamp, Unix milliseconds
+func (c *Coordinator) OnTrade(symbol string, timestamp int64) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if state, ok := c.symbols[symbol]; ok {
+		state.trades++
+		state.lastTrade = max(state.lastTrade, timestamp)
+	}
+}
+
+// Status reports the health of the symbols of the shard
+func (c *Coordinator) Status() Status {
+	now := c.now().UnixMilli()
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	status := Status{Member: c.member, Updated: now, Symbols: make([]SymbolHealth, 0, len(c.symbols))}
+	for symbol, state := range c.symbols {
+		health := SymbolHealth{Symbol: symbol, Trades: state.trades, LastTrade: state.lastTrade}
+		if state.err != nil {
+			health.Error = state.err.Error()
+		}
+		if c.staleAfter > 0 && state.err == nil {
+			health.Stale = now-max(state.lastTrade, state.since) > c.staleAfter.Milliseconds()
+		}
+		status.Symbols = append(status.Symbols, health)
+	}
+	sort.Slice(status.Symbols, func(i, j int) bool { return status.Symbols[i].Symbol < status.Symbols[j].Symbol })
+	return status
+}
+
+// sync heartbeats the status and rebalances over the live members. Without
+// a registry the assignment is static and only failed subscriptions are
+// retried. When the registry cannot be read the current symbols are kept.
+func (c *Coordinator) sync() (added, removed []string, err error) {
+	if c.registry == nil {
+		return c.Rebalance(nil)
+	}
+	if err := c.registry.Heartbeat(c.Status()); err != nil {
+		return nil, nil, err
+	}
+	statuses, err := c.registry.Members()
+	if err != nil {
+		return nil, nil, err
+	}
+	members := make([]string, len(statuses))
+	for i, status := range statuses {
+		members[i] = status.Member
+	}
+	return c.Rebalance(members)
+}
+
+// Run joins the shard and keeps it in sync every interval until ctx is done,
+// then unsubscribes from every symbol and leaves the registry. Changes of
+// assignment go to onRebalance and errors to onError.
+func (c *Coordinator) Run(ctx context.Context, interval time.Duration, onRebalance func(added, removed []string), onError func(error)) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		added, removed, err := c.sync()
+		if err != nil {
+			onError(err)
+		}
+		if len(added) > 0 || len(removed) > 0 {
+			onRebalance(added, removed)
+		}
+		select {
+		case <-ctx.Done():
+			c.leave(onError)
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func (c *Coordinator) leave(onError func(error)) {
+	c.mu.Lock()
+	symbols := c.symbols
+	c.symbols = make(map[string]*symbolState)
+	c.mu.Unlock()
+	for _, state := range symbols {
+		if state.unsubscribe != nil {
+			state.unsubscribe()
+		}
+	}
+	if c.registry != nil {
+		if err := c.registry.Leave(c.member); err != nil {
+			onError(err)
+		}
+	}
+}
+
+// Symbols returns the symbols of the shard, sorted
+func (c *Coordinator) Symbols() []string {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	symbols := make([]string, 0, len(c.symbols))
+	for symbol := range c.symbols {
+		symbols = append(symbols, symbol)
+	}
+	sort.Strings(symbols)
+	return symbols
+}
@@ -0,0 +1,151 @@
+package shard
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// VolumeSource returns the 24h quote volume of the symbols of a venue, keyed
+// by exchange symbol, e.g. BTCUSDT
+type VolumeSource func(ctx context.Context) (map[string]float64, error)
+
+// DiscoveryOptions configures the ranking of symbols by volume
+type DiscoveryOptions struct {
+	Quote      string   // Quote asset of the symbols ranked, e.g. USDT
+	Top        int      // Rank a symbol must reach to be added
+	Hysteresis int      // Ranks beyond Top a symbol may fall before it is removed
+	MinVolume  float64  // Quote volume below which a symbol is never kept
+	Exclude    []string // Symbols never kept, BASE-QUOTE, e.g. stablecoin pairs
+}
+
+// Discovery keeps the symbols ranking in the top by 24h quote volume. A
+// symbol joins once it ranks within Top and leaves once it falls below
+// Top+Hysteresis, so symbols around the cut do not churn; up to
+// Top+Hysteresis symbols are kept.
+type Discovery struct {
+	source  VolumeSource
+	opts    DiscoveryOptions
+	exclude map[string]bool
+
+	mu      sync.Mutex
+	symbols []string
+}
+
+// NewDiscovery creates a discovery of the symbols of source, empty until
+// the first refresh
+func NewDiscovery(source VolumeSource, opts DiscoveryOptions) *Discovery {
+	exclude := make(map[string]bool, len(opts.Exclude))
+	for _, symbol := range opts.Exclude {
+		exclude[symbol] = true
+	}
+	return &Discovery{source: source, opts: opts, exclude: exclude}
+}
+
+// Symbols returns the symbols kept, BASE-QUOTE and sorted
+func (d *Discovery) Symbols() []string {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return d.symbols
+}
+
+// Refresh ranks the symbols again and returns those added and removed
+func (d *Discovery) Refresh(ctx context.Context) (added, removed []string, err error) {
+	volumes, err := d.source(ctx)
+	if err != nil {
+		return nil, nil, err
+	}
+	ranks := d.rank(volumes)
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	keep := make(map[string]bool)
+	for _, symbol := range d.symbols {
+		if rank, ok := ranks[symbol]; ok && rank <= d.opts.Top+d.opts.Hysteresis {
+			keep[symbol] = true
+		} else {
+			removed = append(removed, symbol)
+		}
+	}
+	for symbol, rank := range ranks {
+		if rank <= d.opts.Top && !keep[symbol] {
+			keep[symbol] = true
+			added = append(added, symbol)
+		}
+	}
+	symbols := make([]string, 0, len(keep))
+	for symbol := range keep {
+		symbols = append(symbols, symbol)
+	}
+	sort.Strings(symbols)
+	sort.Strings(added)
+	d.symbols = symbols
+	return added, removed, nil
+}
+
+// rank returns the rank by volume, from 1, of the eligible symbols
+func (d *Discovery) rank(volumes map[string]float64) map[string]int {
+	type candidate struct {
+		symbol string
+		volume float64
+	}
+	var candidates []candidate
+	for exchangeSymbol, volume := range volumes {
+		base, ok := strings.CutSuffix(exchangeSymbol, d.opts.Quote)
+		if !ok || base == "" || volume <= 0 || volume < d.opts.MinVolume {
+			continue
+		}
+		symbol := base + "-" + d.opts.Quote
+		if !d.exclude[symbol] {
+			candidates = append(candidates, candidate{symbol, volume})
+		}
+	}
+	sort.Slice(candidates, func(i, j int) bool {
+		if candidates[i].volume != candidates[j].volume {
+			return candidates[i].volume > candidates[j].volume
+		}
+		return candidates[i].symbol < candidates[j].symbol
+	})
+	ranks := make(map[string]int, len(candidates))
+	for i, c := range candidates {
+		ranks[c.symbol] = i + 1
+	}
+	return ranks
+}
+
+// Run refreshes the ranking every interval until ctx is done. Changes go to
+// onChange and errors to onError; the symbols are kept when a refresh fails.
+func (d *Discovery) Run(ctx context.Context, interval time.Duration, onChange func(added, removed []string), onError func(error)) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+		added, removed, err := d.Refresh(ctx)
+		if err != nil {
+			onError(fmt.Errorf("symbol discovery: %w", err))
+			continue
+		}
+		if len(added) > 0 || len(removed) > 0 {
+			onChange(added, removed)
+		}
+	}
+}
+
+// SubscriptionChange is published when an instance starts or stops
+// streaming symbols, so the streams downstream can follow
+type SubscriptionChange struct {
+	Member     string   `json:"member"`
+	Exchange   string   `json:"exchange"`
+	Instrument string   `json:"instrument"`
+	Added      []string `json:"added"`
+	Removed    []string `json:"removed"`
+	Symbols    []string `json:"symbols"` // Every symbol streamed after the change
+	Timestamp  int64    `json:"timestamp"`
+}
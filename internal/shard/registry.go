@@ -0,0 +1,174 @@
+package shard
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/nats-io/nats.go"
+)
+
+// SymbolHealth is the state of one symbol of a shard
+type SymbolHealth struct {
+	Symbol    string `json:"symbol"`
+	Trades    uint64 `json:"trades"`               // Trades received since subscribed
+	LastTrade int64  `json:"last_trade,omitempty"` // Unix milliseconds of the last trade received
+	Stale     bool   `json:"stale"`                // No trade for longer than the stale threshold
+	Error     string `json:"error,omitempty"`      // Why the subscription failed, retried at the next rebalance
+}
+
+// Status is the health report of a feed instance, published to the registry
+type Status struct {
+	Member  string         `json:"member"`
+	Updated int64          `json:"updated"` // Unix milliseconds of the report
+	Symbols []SymbolHealth `json:"symbols"`
+}
+
+// Healthy reports whether every symbol of the shard is subscribed and trading
+func (s Status) Healthy() bool {
+	for _, symbol := range s.Symbols {
+		if symbol.Stale || symbol.Error != "" {
+			return false
+		}
+	}
+	return true
+}
+
+// Registry is shared by the instances of a feed to find each other and
+// report their health
+type Registry interface {
+	// Heartbeat publishes the status of an instance, keeping it a member
+	Heartbeat(status Status) error
+	// Members returns the statuses of the live instances, sorted by member
+	Members() ([]Status, error)
+	// Leave removes an instance, so its symbols move without waiting for it
+	// to expire
+	Leave(member string) error
+}
+
+// live keeps the statuses updated within ttl of now, sorted by member
+func live(statuses []Status, now time.Time, ttl time.Duration) []Status {
+	alive := statuses[:0]
+	for _, status := range statuses {
+		if now.Sub(time.UnixMilli(status.Updated)) <= ttl {
+			alive = append(alive, status)
+		}
+	}
+	sort.Slice(alive, func(i, j int) bool { return alive[i].Member < alive[j].Member })
+	return alive
+}
+
+// MemoryRegistry is a Registry local to the process
+type MemoryRegistry struct {
+	mu       sync.Mutex
+	ttl      time.Duration
+	now      func() time.Time
+	statuses map[string]Status
+}
+
+// NewMemoryRegistry creates a process local registry dropping members not
+// heard from within ttl
+func NewMemoryRegistry(ttl time.Duration) *MemoryRegistry {
+	return &MemoryRegistry{ttl: ttl, now: time.Now, statuses: make(map[string]Status)}
+}
+
+// Heartbeat implements Registry
+func (r *MemoryRegistry) Heartbeat(status Status) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.statuses[status.Member] = status
+	return nil
+}
+
+// Members implements Registry
+func (r *MemoryRegistry) Members() ([]Status, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	statuses := make([]Status, 0, len(r.statuses))
+	for _, status := range r.statuses {
+		statuses = append(statuses, status)
+	}
+	return live(statuses, r.now(), r.ttl), nil
+}
+
+// Leave implements Registry
+func (r *MemoryRegistry) Leave(member string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	delete(r.statuses, member)
+	return nil
+}
+
+// NATSRegistry is a Registry in a NATS JetStream key-value bucket, one key
+// per member holding its JSON status
+type NATSRegistry struct {
+	kv  nats.KeyValue
+	ttl time.Duration
+}
+
+// NewNATSRegistry opens the bucket, creating it when missing. Members not
+// heard from within ttl are dropped, and their keys expire with the bucket
+// TTL.
+func NewNATSRegistry(js nats.JetStreamContext, bucket string, ttl time.Duration) (*NATSRegistry, error) {
+	if bucket == "" {
+		return nil, fmt.Errorf("bucket cannot be empty")
+	}
+	kv, err := js.KeyValue(bucket)
+	if errors.Is(err, nats.ErrBucketNotFound) {
+		kv, err = js.CreateKeyValue(&nats.KeyValueConfig{Bucket: bucket, TTL: ttl, History: 1})
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to open bucket %s: %w", bucket, err)
+	}
+	return &NATSRegistry{kv: kv, ttl: ttl}, nil
+}
+
+// Heartbeat implements Registry
+func (r *NATSRegistry) Heartbeat(status Status) error {
+	data, err := json.Marshal(status)
+	if err != nil {
+		return err
+	}
+	if _, err := r.kv.Put(status.Member, data); err != nil {
+		return fmt.Errorf("failed to heartbeat %s: %w", status.Member, err)
+	}
+	return nil
+}
+
+// Members implements Registry
+func (r *NATSRegistry) Members() ([]Status, error) {
+	keys, err := r.kv.Keys()
+	if errors.Is(err, nats.ErrNoKeysFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to list members: %w", err)
+	}
+	statuses := make([]Status, 0, len(keys))
+	for _, key := range keys {
+		entry, err := r.kv.Get(key)
+		if errors.Is(err, nats.ErrKeyNotFound) {
+			continue // Left since listed
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to read member %s: %w", key, err)
+		}
+		var status Status
+		if err := json.Unmarshal(entry.Value(), &status); err != nil {
+			continue
+		}
+		statuses = append(statuses, status)
+	}
+	return live(statuses, time.Now(), r.ttl), nil
+}
+
+// Leave implements Registry
+func (r *NATSRegistry) Leave(member string) error {
+	if err := r.kv.Delete(member); err != nil {
+		return fmt.Errorf("failed to leave %s: %w", member, err)
+	}
+	return nil
+}
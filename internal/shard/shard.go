@@ -0,0 +1,74 @@
+// Package shard splits the symbols of a feed across instances, either
+// statically by hash range or explicit list, or dynamically among the live
+// members of a registry, and tracks the health of the symbols of a shard.
+package shard
+
+import (
+	"hash/fnv"
+	"math/bits"
+	"sort"
+)
+
+// hash is FNV-1a followed by the murmur3 finalizer, which spreads the
+// short, similar symbol names over the high bits the ranges are cut on
+func hash(s string) uint64 {
+	f := fnv.New64a()
+	f.Write([]byte(s))
+	h := f.Sum64()
+	h ^= h >> 33
+	h *= 0xff51afd7ed558ccd
+	h ^= h >> 33
+	return h
+}
+
+// HashRange returns the symbols whose hash falls in range index of count
+// equal ranges of the hash space. A symbol stays in its range whatever the
+// other symbols are, so adding symbols never moves existing ones.
+func HashRange(symbols []string, index, count int) []string {
+	var shard []string
+	for _, symbol := range symbols {
+		// The high word of hash * count is the range the hash falls in
+		if hi, _ := bits.Mul64(hash(symbol), uint64(count)); int(hi) == index {
+			shard = append(shard, symbol)
+		}
+	}
+	return shard
+}
+
+// Rendezvous returns the symbols of member among members by rendezvous
+// hashing: each symbol goes to the member scoring it highest. When a member
+// joins or leaves, only the symbols it wins or held move.
+func Rendezvous(symbols []string, member string, members []string) []string {
+	var shard []string
+	for _, symbol := range symbols {
+		var owner string
+		var best uint64
+		for _, m := range members {
+			if score := hash(m + "/" + symbol); owner == "" || score > best || (score == best && m < owner) {
+				owner, best = m, score
+			}
+		}
+		if owner == member {
+			shard = append(shard, symbol)
+		}
+	}
+	return shard
+}
+
+// Assigner returns the symbols of an instance given the live members of the
+// registry, which static assignments ignore
+type Assigner func(members []string) []string
+
+// Static assigns a fixed list of symbols
+func Static(symbols []string) Assigner {
+	sorted := append([]string(nil), symbols...)
+	sort.Strings(sorted)
+	return func([]string) []string { return sorted }
+}
+
+// Dynamic assigns the symbols of member by rendezvous hashing over the live members
+func Dynamic(symbols []string, member string) Assigner {
+	return func(members []string) []string {
+		return Rendezvous(symbols, member, members)
+	}
+}
@@ -0,0 +1,199 @@
+package shard
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+)
+
+func symbols(n int) []string {
+	s := make([]string, n)
+	for i := range s {
+		s[i] = fmt.Sprintf("COIN%d-USDT", i)
+	}
+	return s
+}
+
+func TestHashRange(t *testing.T) {
+	all := symbols(200)
+	owner := make(map[string]int)
+	for index := 0; index < 4; index++ {
+		shard := HashRange(all, index, 4)
+		if len(shard) < 25 || len(shard) > 75 {
+			t.Errorf("range %d holds %d of 200 symbols", index, len(shard))
+		}
+		for _, symbol := range shard {
+			if prev, ok := owner[symbol]; ok {
+				t.Fatalf("%s in ranges %d and %d", symbol, prev, index)
+			}
+			owner[symbol] = index
+		}
+	}
+	if len(owner) != len(all) {
+		t.Errorf("%d of %d symbols assigned", len(owner), len(all))
+	}
+}
+
+func TestRendezvousMovesOnlyLostSymbols(t *testing.T) {
+	all := symbols(300)
+	before := map[string]string{}
+	members := []string{"feed-a", "feed-b", "feed-c"}
+	for _, m := range members {
+		for _, symbol := range Rendezvous(all, m, members) {
+			before[symbol] = m
+		}
+	}
+	if len(before) != len(all) {
+		t.Fatalf("%d of %d symbols assigned", len(before), len(all))
+	}
+
+	// feed-b leaves: only its symbols move
+	after := []string{"feed-a", "feed-c"}
+	for _, m := range after {
+		for _, symbol := range Rendezvous(all, m, after) {
+			if was := before[symbol]; was != m && was != "feed-b" {
+				t.Errorf("%s moved from %s to %s", symbol, was, m)
+			}
+		}
+	}
+}
+
+// fakeFeed records the subscriptions of a coordinator
+type fakeFeed struct {
+	mu     sync.Mutex
+	active map[string]bool
+	fail   map[string]bool
+}
+
+func newFakeFeed() *fakeFeed {
+	return &fakeFeed{active: map[string]bool{}, fail: map[string]bool{}}
+}
+
+func (f *fakeFeed) subscribe(symbol string) (func(), error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.fail[symbol] {
+		return nil, errors.New("connection refused")
+	}
+	f.active[symbol] = true
+	return func() {
+		f.mu.Lock()
+		defer f.mu.Unlock()
+		delete(f.active, symbol)
+	}, nil
+}
+
+func (f *fakeFeed) count() int {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return len(f.active)
+}
+
+func TestCoordinatorRebalances(t *testing.T) {
+	all := symbols(50)
+	registry := NewMemoryRegistry(time.Minute)
+	feedA, feedB := newFakeFeed(), newFakeFeed()
+	a := NewCoordinator("feed-a", Dynamic(all, "feed-a"), registry, feedA.subscribe, 0)
+	b := NewCoordinator("feed-b", Dynamic(all, "feed-b"), registry, feedB.subscribe, 0)
+
+	// Alone, feed-a takes every symbol
+	if added, _, err := a.sync(); err != nil || len(added) != 50 || feedA.count() != 50 {
+		t.Fatalf("alone: %d added, %d active, %v", len(added), feedA.count(), err)
+	}
+	// feed-b joins and takes its share, which feed-a releases at its next sync
+	if _, _, err := b.sync(); err != nil {
+		t.Fatal(err)
+	}
+	_, removed, err := a.sync()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(removed) == 0 || feedA.count()+feedB.count() != 50 {
+		t.Errorf("after join: %d removed, %d + %d active", len(removed), feedA.count(), feedB.count())
+	}
+
+	// feed-b leaves and feed-a takes everything back
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	b.Run(ctx, time.Hour, func(_, _ []string) {}, func(err error) { t.Error(err) })
+	if feedB.count() != 0 {
+		t.Errorf("%d symbols still active after leaving", feedB.count())
+	}
+	if _, _, err := a.sync(); err != nil || feedA.count() != 50 {
+		t.Errorf("after leave: %d active, %v", feedA.count(), err)
+	}
+}
+
+func TestCoordinatorHealth(t *testing.T) {
+	feed := newFakeFeed()
+	feed.fail["SOL-USDT"] = true
+	now := time.UnixMilli(1_000_000)
+	c := NewCoordinator("feed-a", Static([]string{"BTC-USDT", "ETH-USDT", "SOL-USDT"}), nil, feed.subscribe, time.Minute)
+	c.now = func() time.Time { return now }
+
+	if _, _, err := c.sync(); err == nil {
+		t.Error("failed subscription not reported")
+	}
+	now = now.Add(2 * time.Minute)
+	c.OnTrade("BTC-USDT", now.UnixMilli()-1000)
+	status := c.Status()
+	if status.Healthy() || len(status.Symbols) != 3 {
+		t.Fatalf("status = %+v", status)
+	}
+	btc, eth, sol := status.Symbols[0], status.Symbols[1], status.Symbols[2]
+	if btc.Stale || btc.Trades != 1 || !eth.Stale || sol.Error == "" || sol.Stale {
+		t.Errorf("health = %+v", status.Symbols)
+	}
+
+	// The failed subscription is retried
+	delete(feed.fail, "SOL-USDT")
+	if added, _, err := c.sync(); err != nil || len(added) != 1 || added[0] != "SOL-USDT" {
+		t.Errorf("retry: %v, %v", added, err)
+	}
+}
+
+func TestMemoryRegistryExpires(t *testing.T) {
+	registry := NewMemoryRegistry(10 * time.Second)
+	now := time.UnixMilli(1_000_000)
+	registry.now = func() time.Time { return now }
+	registry.Heartbeat(Status{Member: "feed-b", Updated: now.UnixMilli()})
+	registry.Heartbeat(Status{Member: "feed-a", Updated: now.Add(-time.Minute).UnixMilli()})
+	members, err := registry.Members()
+	if err != nil || len(members) != 1 || members[0].Member != "feed-b" {
+		t.Errorf("members = %+v, %v", members, err)
+	}
+}
+
+func TestDiscoveryHysteresis(t *testing.T) {
+	volumes := map[string]float64{}
+	d := NewDiscovery(func(context.Context) (map[string]float64, error) { return volumes, nil },
+		DiscoveryOptions{Quote: "USDT", Top: 2, Hysteresis: 1, MinVolume: 10, Exclude: []string{"USDC-USDT"}})
+	set := func(v map[string]float64) []string {
+		volumes = v
+		added, removed, err := d.Refresh(context.Background())
+		if err != nil {
+			t.Fatal(err)
+		}
+		return append(added, removed...)
+	}
+
+	set(map[string]float64{"BTCUSDT": 900, "USDCUSDT": 800, "ETHUSDT": 500, "SOLUSDT": 400, "BTCFDUSD": 1000, "DUSTUSDT": 5})
+	if got := fmt.Sprint(d.Symbols()); got != "[BTC-USDT ETH-USDT]" {
+		t.Fatalf("symbols = %s", got)
+	}
+	// SOL overtakes ETH: ETH drops to rank 3, within the hysteresis, and stays
+	set(map[string]float64{"BTCUSDT": 900, "SOLUSDT": 600, "ETHUSDT": 500})
+	if got := fmt.Sprint(d.Symbols()); got != "[BTC-USDT ETH-USDT SOL-USDT]" {
+		t.Fatalf("symbols = %s", got)
+	}
+	// ETH falls to rank 4 and leaves
+	if changed := set(map[string]float64{"BTCUSDT": 900, "SOLUSDT": 600, "XRPUSDT": 550, "ETHUSDT": 500}); fmt.Sprint(changed) != "[ETH-USDT]" {
+		t.Errorf("changed = %v", changed)
+	}
+	if got := fmt.Sprint(d.Symbols()); got != "[BTC-USDT SOL-USDT]" {
+		t.Errorf("symbols = %s", got)
+	}
+}
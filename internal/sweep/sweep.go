@@ -0,0 +1,347 @@
+// Package sweep keeps the trading wallets of a Binance account topped up
+// from its funding wallet. Each rule watches the balance of an asset in a
+// trading wallet: below its minimum the balance is raised to its target
+// from the funding wallet, above its maximum the excess is swept back to
+// the funding wallet down to the target.
+package sweep
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/BullionBear/sequex/pkg/calendar"
+	"github.com/BullionBear/sequex/pkg/exchange/binance"
+	"github.com/BullionBear/sequex/pkg/exchange/binanceperp"
+)
+
+// Wallet is a trading wallet balances are kept in
+type Wallet string
+
+const (
+	WalletSpot Wallet = "spot"
+	WalletUSDM Wallet = "usdm" // USDⓈ-M futures
+)
+
+// Directions of transfers
+const (
+	DirectionTopUp = "top_up" // From the funding wallet
+	DirectionSweep = "sweep"  // To the funding wallet
+)
+
+// Client is the subset of the Binance spot client balances are read and
+// transferred with
+type Client interface {
+	GetAccountInfo(ctx context.Context, req binance.GetAccountInfoRequest) (binance.Response[binance.GetAccountInfoResponse], error)
+	GetFundingAsset(ctx context.Context, req binance.GetFundingAssetRequest) (binance.Response[[]binance.FundingAsset], error)
+	UniversalTransfer(ctx context.Context, req binance.UniversalTransferRequest) (binance.Response[binance.UniversalTransferResponse], error)
+}
+
+// FuturesClient is the subset of the Binance USDⓈ-M client the balances of
+// the futures wallet are read with
+type FuturesClient interface {
+	GetAccountBalance(ctx context.Context, req binanceperp.GetAccountBalanceRequest) (binanceperp.Response[[]binanceperp.AccountBalance], error)
+}
+
+// Rule keeps the balance of an asset in a trading wallet within bounds
+type Rule struct {
+	Asset    string
+	Wallet   Wallet
+	Min      float64               // Topped up to Target when the available balance falls below
+	Target   float64               // Balance transfers aim at
+	Max      float64               // Excess swept back down to Target when the available balance rises above, never when 0
+	Schedule []calendar.Settlement // Times of day the rule runs at, every sweep when empty
+}
+
+// Name identifies the rule in logs and transfers
+func (r Rule) Name() string {
+	return string(r.Wallet) + "." + r.Asset
+}
+
+// Validate checks the bounds of the rule are ordered
+func (r Rule) Validate() error {
+	if r.Asset == "" {
+		return errors.New("asset is required")
+	}
+	if r.Wallet != WalletSpot && r.Wallet != WalletUSDM {
+		return fmt.Errorf("unknown wallet %q, expected %s or %s", r.Wallet, WalletSpot, WalletUSDM)
+	}
+	if r.Min < 0 || r.Target < r.Min {
+		return fmt.Errorf("%s: expected 0 <= min <= target", r.Name())
+	}
+	if r.Max != 0 && r.Max < r.Target {
+		return fmt.Errorf("%s: expected target <= max", r.Name())
+	}
+	return nil
+}
+
+// transferTypes returns the universal transfer types of the wallet, to it
+// and from it
+func (w Wallet) transferTypes() (string, string) {
+	if w == WalletUSDM {
+		return binance.TransferTypeFundingUMFuture, binance.TransferTypeUMFutureFunding
+	}
+	return binance.TransferTypeFundingMain, binance.TransferTypeMainFunding
+}
+
+// Transfer is a transfer made, or planned in dry run, by a rule
+type Transfer struct {
+	Rule      string    `json:"rule"`
+	Asset     string    `json:"asset"`
+	Wallet    Wallet    `json:"wallet"`
+	Direction string    `json:"direction"`
+	Type      string    `json:"type"`    // Universal transfer type
+	Amount    float64   `json:"amount"`  // Transferred
+	Balance   float64   `json:"balance"` // Available in the trading wallet before the transfer
+	Funding   float64   `json:"funding"` // Free in the funding wallet before the transfer
+	DryRun    bool      `json:"dry_run"`
+	TranId    int64     `json:"tran_id,omitempty"` // Exchange transaction id, 0 in dry run
+	Error     string    `json:"error,omitempty"`
+	At        time.Time `json:"at"`
+}
+
+// Options configures a sweeper
+type Options struct {
+	DryRun bool // Plans the transfers without making them
+}
+
+// Sweeper applies rules to the wallets of an account. It is safe for
+// concurrent use, sweeps being serialized.
+type Sweeper struct {
+	client  Client
+	futures FuturesClient
+	rules   []Rule
+	opts    Options
+	now     func() time.Time
+
+	mu   sync.Mutex
+	next map[int]time.Time // Next run of each scheduled rule, by index
+}
+
+// NewSweeper creates a sweeper. futures may be nil when no rule watches the
+// USDⓈ-M wallet.
+func NewSweeper(client Client, futures FuturesClient, rules []Rule, opts Options) (*Sweeper, error) {
+	if len(rules) == 0 {
+		return nil, errors.New("no rule")
+	}
+	seen := make(map[string]bool, len(rules))
+	for i, r := range rules {
+		if err := r.Validate(); err != nil {
+			return nil, fmt.Errorf("rules[%d]: %w", i, err)
+		}
+		if seen[r.Name()] {
+			return nil, fmt.Errorf("rules[%d]: duplicate rule %s", i, r.Name())
+		}
+		seen[r.Name()] = true
+		if r.Wallet == WalletUSDM && futures == nil {
+			return nil, fmt.Errorf("rules[%d]: no USDⓈ-M client for %s", i, r.Name())
+		}
+	}
+	return &Sweeper{client: client, futures: futures, rules: rules, opts: opts, now: time.Now, next: make(map[int]time.Time)}, nil
+}
+
+// DryRun reports whether transfers are only planned
+func (s *Sweeper) DryRun() bool {
+	return s.opts.DryRun
+}
+
+// due reports whether rule i runs now, scheduling its next run
+func (s *Sweeper) due(i int, now time.Time) bool {
+	r := s.rules[i]
+	if len(r.Schedule) == 0 {
+		return true
+	}
+	next, ok := s.next[i]
+	if ok && now.Before(next) {
+		return false
+	}
+	earliest := time.Time{}
+	for _, at := range r.Schedule {
+		if t := at.Next(now); earliest.IsZero() || t.Before(earliest) {
+			earliest = t
+		}
+	}
+	s.next[i] = earliest
+	// The first sweep only schedules the rule, it runs at its times of day
+	return ok
+}
+
+// Sweep reads the balances of the wallets of the rules due and transfers
+// what brings them back within bounds. Transfers are returned whether they
+// failed or not; the error joins the failures to read balances.
+func (s *Sweeper) Sweep(ctx context.Context) ([]Transfer, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	now := s.now()
+	var due []Rule
+	for i := range s.rules {
+		if s.due(i, now) {
+			due = append(due, s.rules[i])
+		}
+	}
+	if len(due) == 0 {
+		return nil, nil
+	}
+
+	funding, err := s.fundingBalances(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("funding wallet: %w", err)
+	}
+	balances := make(map[Wallet]map[string]float64)
+	var errs []error
+	var transfers []Transfer
+	for _, r := range due {
+		wallet, ok := balances[r.Wallet]
+		if !ok {
+			if wallet, err = s.walletBalances(ctx, r.Wallet); err != nil {
+				errs = append(errs, fmt.Errorf("%s wallet: %w", r.Wallet, err))
+			}
+			balances[r.Wallet] = wallet
+		}
+		if wallet == nil {
+			continue
+		}
+		t, ok := s.plan(r, wallet[r.Asset], funding[r.Asset], now)
+		if !ok {
+			continue
+		}
+		s.transfer(ctx, &t)
+		if t.Error == "" {
+			// Later rules of the asset see the balances after the transfer
+			if t.Direction == DirectionTopUp {
+				funding[r.Asset] -= t.Amount
+			} else {
+				funding[r.Asset] += t.Amount
+			}
+		}
+		transfers = append(transfers, t)
+	}
+	return transfers, errors.Join(errs...)
+}
+
+// plan returns the transfer bringing balance back within the bounds of r,
+// false when none is needed or possible
+func (s *Sweeper) plan(r Rule, balance, funding float64, now time.Time) (Transfer, bool) {
+	to, from := r.Wallet.transferTypes()
+	t := Transfer{Rule: r.Name(), Asset: r.Asset, Wallet: r.Wallet, Balance: balance, Funding: funding, DryRun: s.opts.DryRun, At: now.UTC()}
+	switch {
+	case balance < r.Min:
+		// Topped up with what the funding wallet holds when short of the target
+		t.Direction, t.Type, t.Amount = DirectionTopUp, to, math.Min(r.Target-balance, funding)
+	case r.Max > 0 && balance > r.Max:
+		t.Direction, t.Type, t.Amount = DirectionSweep, from, balance-r.Target
+	default:
+		return t, false
+	}
+	t.Amount = truncate(t.Amount)
+	return t, t.Amount > 0
+}
+
+// transfer makes a planned transfer, unless in dry run
+func (s *Sweeper) transfer(ctx context.Context, t *Transfer) {
+	if s.opts.DryRun {
+		return
+	}
+	resp, err := s.client.UniversalTransfer(ctx, binance.UniversalTransferRequest{
+		Type:   t.Type,
+		Asset:  t.Asset,
+		Amount: strconv.FormatFloat(t.Amount, 'f', -1, 64),
+	})
+	if err != nil {
+		t.Error = err.Error()
+		return
+	}
+	if resp.Data != nil {
+		t.TranId = resp.Data.TranId
+	}
+}
+
+// truncate rounds an amount down to 8 decimals, the precision of Binance
+// balances, so a transfer never exceeds the balance it is taken from
+func truncate(amount float64) float64 {
+	return math.Floor(amount*1e8) / 1e8
+}
+
+func (s *Sweeper) fundingBalances(ctx context.Context) (map[string]float64, error) {
+	resp, err := s.client.GetFundingAsset(ctx, binance.GetFundingAssetRequest{})
+	if err != nil {
+		return nil, err
+	}
+	balances := make(map[string]float64)
+	if resp.Data == nil {
+		return balances, nil
+	}
+	for _, a := range *resp.Data {
+		free, err := strconv.ParseFloat(a.Free, 64)
+		if err != nil {
+			return nil, fmt.Errorf("%s: invalid free balance %q", a.Asset, a.Free)
+		}
+		balances[a.Asset] = free
+	}
+	return balances, nil
+}
+
+// walletBalances returns the balances available in a trading wallet: free
+// in the spot wallet, transferable out of the futures wallet
+func (s *Sweeper) walletBalances(ctx context.Context, w Wallet) (map[string]float64, error) {
+	balances := make(map[string]float64)
+	if w == WalletUSDM {
+		resp, err := s.futures.GetAccountBalance(ctx, binanceperp.GetAccountBalanceRequest{})
+		if err != nil {
+			return nil, err
+		}
+		if resp.Data == nil {
+			return balances, nil
+		}
+		for _, b := range *resp.Data {
+			available, err := strconv.ParseFloat(b.MaxWithdrawAmount, 64)
+			if err != nil {
+				return nil, fmt.Errorf("%s: invalid max withdraw amount %q", b.Asset, b.MaxWithdrawAmount)
+			}
+			balances[b.Asset] = available
+		}
+		return balances, nil
+	}
+	resp, err := s.client.GetAccountInfo(ctx, binance.GetAccountInfoRequest{OmitZeroBalances: true})
+	if err != nil {
+		return nil, err
+	}
+	if resp.Data == nil {
+		return balances, nil
+	}
+	for _, b := range resp.Data.Balances {
+		free, err := strconv.ParseFloat(b.Free, 64)
+		if err != nil {
+			return nil, fmt.Errorf("%s: invalid free balance %q", b.Asset, b.Free)
+		}
+		balances[b.Asset] = free
+	}
+	return balances, nil
+}
+
+// Run sweeps every interval until ctx is done, calling onTransfer with each
+// transfer and onError with the failures to read balances
+func (s *Sweeper) Run(ctx context.Context, interval time.Duration, onTransfer func(Transfer), onError func(error)) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		transfers, err := s.Sweep(ctx)
+		if err != nil && ctx.Err() == nil && onError != nil {
+			onError(err)
+		}
+		for _, t := range transfers {
+			if onTransfer != nil {
+				onTransfer(t)
+			}
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
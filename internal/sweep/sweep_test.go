@@ -0,0 +1,148 @@
+package sweep
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/BullionBear/sequex/pkg/calendar"
+	"github.com/BullionBear/sequex/pkg/exchange/binance"
+	"github.com/BullionBear/sequex/pkg/exchange/binanceperp"
+)
+
+type fakeClient struct {
+	spot      []binance.Balance
+	funding   []binance.FundingAsset
+	transfers []binance.UniversalTransferRequest
+	fail      bool
+}
+
+func (f *fakeClient) GetAccountInfo(_ context.Context, _ binance.GetAccountInfoRequest) (binance.Response[binance.GetAccountInfoResponse], error) {
+	return binance.Response[binance.GetAccountInfoResponse]{Data: &binance.GetAccountInfoResponse{Balances: f.spot}}, nil
+}
+
+func (f *fakeClient) GetFundingAsset(_ context.Context, _ binance.GetFundingAssetRequest) (binance.Response[[]binance.FundingAsset], error) {
+	return binance.Response[[]binance.FundingAsset]{Data: &f.funding}, nil
+}
+
+func (f *fakeClient) UniversalTransfer(_ context.Context, req binance.UniversalTransferRequest) (binance.Response[binance.UniversalTransferResponse], error) {
+	if f.fail {
+		return binance.Response[binance.UniversalTransferResponse]{}, errors.New("binance error: insufficient balance")
+	}
+	f.transfers = append(f.transfers, req)
+	return binance.Response[binance.UniversalTransferResponse]{Data: &binance.UniversalTransferResponse{TranId: int64(len(f.transfers))}}, nil
+}
+
+type fakeFutures []binanceperp.AccountBalance
+
+func (f fakeFutures) GetAccountBalance(_ context.Context, _ binanceperp.GetAccountBalanceRequest) (binanceperp.Response[[]binanceperp.AccountBalance], error) {
+	balances := []binanceperp.AccountBalance(f)
+	return binanceperp.Response[[]binanceperp.AccountBalance]{Data: &balances}, nil
+}
+
+func TestSweep(t *testing.T) {
+	client := &fakeClient{
+		spot:    []binance.Balance{{Asset: "USDT", Free: "400"}, {Asset: "BTC", Free: "2.5"}},
+		funding: []binance.FundingAsset{{Asset: "USDT", Free: "10000"}},
+	}
+	futures := fakeFutures{{Asset: "USDT", MaxWithdrawAmount: "5000"}}
+	s, err := NewSweeper(client, futures, []Rule{
+		{Asset: "USDT", Wallet: WalletSpot, Min: 500, Target: 1000, Max: 2000},
+		{Asset: "BTC", Wallet: WalletSpot, Min: 0.1, Target: 1, Max: 2},
+		{Asset: "USDT", Wallet: WalletUSDM, Min: 1000, Target: 3000, Max: 4000},
+	}, Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	transfers, err := s.Sweep(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(transfers) != 3 {
+		t.Fatalf("transfers: %+v", transfers)
+	}
+	for i, want := range []struct {
+		typ, amount string
+	}{
+		{binance.TransferTypeFundingMain, "600"},
+		{binance.TransferTypeMainFunding, "1.5"},
+		{binance.TransferTypeUMFutureFunding, "2000"},
+	} {
+		if got := client.transfers[i]; got.Type != want.typ || got.Amount != want.amount {
+			t.Errorf("transfer %d: %+v, want %s of %s", i, got, want.typ, want.amount)
+		}
+	}
+	if transfers[0].TranId != 1 || transfers[0].Direction != DirectionTopUp || transfers[1].Direction != DirectionSweep {
+		t.Errorf("transfers: %+v", transfers)
+	}
+
+	// Top ups are capped by the funding wallet, failures reported with the transfer
+	client.transfers = nil
+	client.funding = []binance.FundingAsset{{Asset: "USDT", Free: "100"}}
+	client.fail = true
+	transfers, err = s.Sweep(context.Background())
+	if err != nil || len(transfers) != 3 || transfers[0].Amount != 100 || transfers[0].Error == "" {
+		t.Errorf("capped and failed top up: %+v, %v", transfers, err)
+	}
+}
+
+func TestSweepDryRun(t *testing.T) {
+	client := &fakeClient{spot: []binance.Balance{{Asset: "USDT", Free: "0"}}, funding: []binance.FundingAsset{{Asset: "USDT", Free: "10000"}}}
+	s, err := NewSweeper(client, nil, []Rule{{Asset: "USDT", Wallet: WalletSpot, Min: 500, Target: 1000}}, Options{DryRun: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	transfers, err := s.Sweep(context.Background())
+	if err != nil || len(transfers) != 1 || !transfers[0].DryRun || transfers[0].Amount != 1000 || len(client.transfers) != 0 {
+		t.Errorf("dry run: %+v, %v, made %+v", transfers, err, client.transfers)
+	}
+}
+
+func TestSweepSchedule(t *testing.T) {
+	client := &fakeClient{spot: []binance.Balance{{Asset: "USDT", Free: "5000"}}}
+	at, err := calendar.ParseSettlement("08:00", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	s, err := NewSweeper(client, nil, []Rule{{Asset: "USDT", Wallet: WalletSpot, Target: 1000, Max: 2000, Schedule: []calendar.Settlement{at}}}, Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	now := time.Date(2026, 10, 16, 7, 0, 0, 0, time.UTC)
+	s.now = func() time.Time { return now }
+	for _, step := range []struct {
+		at    time.Time
+		sweep bool
+	}{
+		{time.Date(2026, 10, 16, 7, 0, 0, 0, time.UTC), false}, // Scheduled for 08:00
+		{time.Date(2026, 10, 16, 7, 59, 0, 0, time.UTC), false},
+		{time.Date(2026, 10, 16, 8, 0, 30, 0, time.UTC), true},
+		{time.Date(2026, 10, 16, 9, 0, 0, 0, time.UTC), false}, // Next day
+		{time.Date(2026, 10, 17, 8, 1, 0, 0, time.UTC), true},
+	} {
+		now = step.at
+		client.transfers = nil
+		if _, err := s.Sweep(context.Background()); err != nil {
+			t.Fatal(err)
+		}
+		if swept := len(client.transfers) == 1; swept != step.sweep {
+			t.Errorf("at %s: swept %v, want %v", step.at, swept, step.sweep)
+		}
+	}
+}
+
+func TestNewSweeperValidates(t *testing.T) {
+	for _, rules := range [][]Rule{
+		nil,
+		{{Asset: "USDT", Wallet: "margin", Target: 1}},
+		{{Asset: "USDT", Wallet: WalletSpot, Min: 10, Target: 5}},
+		{{Asset: "USDT", Wallet: WalletSpot, Target: 10, Max: 5}},
+		{{Asset: "USDT", Wallet: WalletSpot, Target: 10}, {Asset: "USDT", Wallet: WalletSpot, Target: 20}},
+		{{Asset: "USDT", Wallet: WalletUSDM, Target: 10}}, // No futures client
+	} {
+		if _, err := NewSweeper(&fakeClient{}, nil, rules, Options{}); err == nil {
+			t.Errorf("rules %+v accepted", rules)
+		}
+	}
+}
@@ -0,0 +1,100 @@
+package sysevent
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/BullionBear/sequex/internal/model/sqx"
+	"github.com/gin-gonic/gin"
+)
+
+// Register serves the events of s:
+//
+//	GET /events  events newest first, selected by the parameters
+//	             kind=risk_breach,reconnect  severity=warning  source=<service>
+//	             code=<code>  text=<in message>  attr=<key>:<value>, repeated
+//	             since=24h or from=<ms>&to=<ms>  limit=<n>
+//	GET /kinds   the kinds of events
+func Register(routes gin.IRoutes, s *Store) {
+	routes.GET("/events", func(c *gin.Context) {
+		q, err := ParseQuery(c.Request.URL.Query(), time.Now())
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		events, err := s.Query(q)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusOK, events)
+	})
+	routes.GET("/kinds", func(c *gin.Context) {
+		c.JSON(http.StatusOK, sqx.SystemEventKinds)
+	})
+}
+
+// ParseQuery parses the parameters of a query, since being relative to now
+func ParseQuery(values url.Values, now time.Time) (Query, error) {
+	var q Query
+	var err error
+	if q.Kinds, err = ParseKinds(values.Get("kind")); err != nil {
+		return q, err
+	}
+	if severity := values.Get("severity"); severity != "" {
+		if err := q.Severity.UnmarshalText([]byte(severity)); err != nil {
+			return q, err
+		}
+	}
+	q.Source, q.Code, q.Text = values.Get("source"), values.Get("code"), values.Get("text")
+	for _, attr := range values["attr"] {
+		key, value, ok := strings.Cut(attr, ":")
+		if !ok || key == "" {
+			return q, fmt.Errorf("attr must be key:value, got %q", attr)
+		}
+		if q.Attributes == nil {
+			q.Attributes = make(map[string]string)
+		}
+		q.Attributes[key] = value
+	}
+	if since := values.Get("since"); since != "" {
+		d, err := time.ParseDuration(since)
+		if err != nil || d <= 0 {
+			return q, fmt.Errorf("since must be a positive duration, got %q", since)
+		}
+		q.From = now.Add(-d).UnixMilli()
+	}
+	for name, field := range map[string]*int64{"from": &q.From, "to": &q.To} {
+		if v := values.Get(name); v != "" {
+			if *field, err = strconv.ParseInt(v, 10, 64); err != nil {
+				return q, fmt.Errorf("%s must be Unix milliseconds, got %q", name, v)
+			}
+		}
+	}
+	if limit := values.Get("limit"); limit != "" {
+		if q.Limit, err = strconv.Atoi(limit); err != nil {
+			return q, fmt.Errorf("limit must be a number, got %q", limit)
+		}
+	}
+	return q, q.Validate()
+}
+
+// ParseKinds parses kinds separated by commas, e.g. risk_breach,reconnect
+func ParseKinds(s string) ([]sqx.SystemEventKind, error) {
+	if s == "" {
+		return nil, nil
+	}
+	var kinds []sqx.SystemEventKind
+	for _, name := range strings.Split(s, ",") {
+		kind := sqx.NewSystemEventKind(strings.TrimSpace(name))
+		if kind == sqx.SystemEventKindUnknown {
+			return nil, fmt.Errorf("unknown system event kind: %s", name)
+		}
+		kinds = append(kinds, kind)
+	}
+	return kinds, nil
+}
@@ -0,0 +1,171 @@
+package sysevent
+
+import (
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/BullionBear/sequex/internal/model/sqx"
+	"github.com/nats-io/nats.go"
+	"github.com/rs/zerolog"
+)
+
+// queueSize is the number of events a Publisher holds while publishing,
+// those past it being dropped
+const queueSize = 1024
+
+// MsgPublisher publishes messages to a stream, as nats.JetStreamContext does
+type MsgPublisher interface {
+	PublishMsg(m *nats.Msg, opts ...nats.PubOpt) (*nats.PubAck, error)
+}
+
+// Publisher publishes the system events of a service. Events are queued and
+// published in the background, so that subsystems publish from their
+// callbacks without waiting for the stream. A nil Publisher drops them, so
+// subsystems publish whether or not events are enabled.
+type Publisher struct {
+	js     MsgPublisher
+	prefix string
+	source string
+	log    zerolog.Logger
+
+	queue   chan sqx.SystemEvent
+	done    chan struct{}
+	seq     atomic.Uint64
+	dropped atomic.Uint64
+
+	mu           sync.Mutex
+	closed       bool
+	disconnected time.Time // Of the NATS connection watched, zero while connected
+	disconnect   error
+}
+
+// NewPublisher creates a publisher of the events of source under prefix
+func NewPublisher(js MsgPublisher, prefix, source string, log zerolog.Logger) *Publisher {
+	p := &Publisher{
+		js:     js,
+		prefix: prefix,
+		source: source,
+		log:    log,
+		queue:  make(chan sqx.SystemEvent, queueSize),
+		done:   make(chan struct{}),
+	}
+	go p.run()
+	return p
+}
+
+// Publish queues an event. The id, source and timestamp are set when
+// empty; an id set by the caller lets JetStream drop the event when
+// republished, e.g. by every instance of a service. Events of unknown kind
+// or severity are published as errors.
+func (p *Publisher) Publish(e sqx.SystemEvent) {
+	if p == nil {
+		return
+	}
+	if e.Source == "" {
+		e.Source = p.source
+	}
+	if e.Timestamp == 0 {
+		e.Timestamp = time.Now().UnixMilli()
+	}
+	if e.ID == "" {
+		e.ID = fmt.Sprintf("%s.%d.%d", e.Source, e.Timestamp, p.seq.Add(1))
+	}
+	if e.Kind == sqx.SystemEventKindUnknown {
+		e.Kind = sqx.SystemEventKindError
+	}
+	if e.Severity == sqx.SeverityUnknown {
+		e.Severity = sqx.SeverityError
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.closed {
+		p.dropped.Add(1)
+		return
+	}
+	select {
+	case p.queue <- e:
+	default:
+		if p.dropped.Add(1)%100 == 1 {
+			p.log.Warn().Uint64("dropped", p.dropped.Load()).Msg("System event queue full, dropping events")
+		}
+	}
+}
+
+// Dropped returns the number of events dropped, the queue being full or the
+// publisher closed
+func (p *Publisher) Dropped() uint64 {
+	if p == nil {
+		return 0
+	}
+	return p.dropped.Load()
+}
+
+// Close publishes the events queued and stops the publisher
+func (p *Publisher) Close() {
+	if p == nil {
+		return
+	}
+	p.mu.Lock()
+	if !p.closed {
+		p.closed = true
+		close(p.queue)
+	}
+	p.mu.Unlock()
+	<-p.done
+}
+
+func (p *Publisher) run() {
+	defer close(p.done)
+	for e := range p.queue {
+		if err := p.publish(e); err != nil {
+			p.log.Error().Err(err).Str("id", e.ID).Str("kind", e.Kind.String()).Msg("Failed to publish system event")
+		}
+	}
+}
+
+func (p *Publisher) publish(e sqx.SystemEvent) error {
+	data, err := e.Marshal()
+	if err != nil {
+		return fmt.Errorf("failed to marshal: %w", err)
+	}
+	msg := &nats.Msg{
+		Subject: Subject(p.prefix, e.Kind),
+		Data:    data,
+		Header:  nats.Header{"Nats-Msg-Id": []string{e.ID}},
+	}
+	if _, err := p.js.PublishMsg(msg); err != nil {
+		return fmt.Errorf("failed to publish: %w", err)
+	}
+	return nil
+}
+
+// WatchConn publishes a reconnect event each time conn reconnects to NATS,
+// with how long it was disconnected. It replaces the disconnect and
+// reconnect handlers of conn.
+func (p *Publisher) WatchConn(conn *nats.Conn) {
+	if p == nil {
+		return
+	}
+	conn.SetDisconnectErrHandler(func(_ *nats.Conn, err error) {
+		p.mu.Lock()
+		defer p.mu.Unlock()
+		p.disconnected, p.disconnect = time.Now(), err
+	})
+	conn.SetReconnectHandler(func(c *nats.Conn) {
+		p.mu.Lock()
+		since, cause := p.disconnected, p.disconnect
+		p.disconnected, p.disconnect = time.Time{}, nil
+		p.mu.Unlock()
+		reason := ""
+		if cause != nil {
+			reason = cause.Error()
+		}
+		e := ReconnectEvent("nats "+c.ConnectedUrlRedacted(), reason)
+		if !since.IsZero() {
+			e.Attributes["disconnected_ms"] = fmt.Sprint(time.Since(since).Milliseconds())
+		}
+		p.Publish(e)
+	})
+}
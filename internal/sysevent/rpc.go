@@ -0,0 +1,49 @@
+package sysevent
+
+import (
+	"context"
+
+	"github.com/BullionBear/sequex/internal/model/sqx"
+	"github.com/BullionBear/sequex/pkg/eventbus"
+	"github.com/nats-io/nats.go"
+)
+
+// DefaultSubject is the prefix the query RPCs are served under when none is
+// configured
+const DefaultSubject = "sysevents"
+
+// Events is the events selected by a query, newest first
+type Events struct {
+	Events []sqx.SystemEvent `json:"events"`
+}
+
+// QueryEndpoint is the endpoint events are queried on, prefix.query
+func QueryEndpoint(prefix string) eventbus.Endpoint[Query, Events] {
+	return eventbus.NewEndpoint[Query, Events](prefix+".query", eventbus.JSON)
+}
+
+// Serve answers the queries of the events of s under prefix
+func Serve(bus *eventbus.Bus, prefix string, s *Store) ([]*nats.Subscription, error) {
+	var subs []*nats.Subscription
+	for _, serve := range []func() (*nats.Subscription, error){
+		func() (*nats.Subscription, error) {
+			return QueryEndpoint(prefix).Serve(bus, func(_ context.Context, q *Query) (*Events, error) {
+				events, err := s.Query(*q)
+				if err != nil {
+					return nil, err
+				}
+				return &Events{Events: events}, nil
+			})
+		},
+	} {
+		sub, err := serve()
+		if err != nil {
+			for _, sub := range subs {
+				sub.Unsubscribe()
+			}
+			return nil, err
+		}
+		subs = append(subs, sub)
+	}
+	return subs, nil
+}
@@ -0,0 +1,319 @@
+package sysevent
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/BullionBear/sequex/internal/model/sqx"
+	"github.com/BullionBear/sequex/pkg/eventbus"
+	"github.com/nats-io/nats.go"
+)
+
+const (
+	// DefaultRetention is how long a Store keeps events when not configured
+	DefaultRetention = 7 * 24 * time.Hour
+	// DefaultLimit is the number of events a query returns when not given
+	DefaultLimit = 100
+	// MaxLimit bounds the events a query returns
+	MaxLimit = 10000
+)
+
+// dayLayout names the journal files, one per UTC day of the events
+const dayLayout = "2006-01-02"
+
+// Query selects events, newest first. Empty fields select every event.
+type Query struct {
+	Kinds      []sqx.SystemEventKind `json:"kinds,omitempty"`
+	Severity   sqx.Severity          `json:"severity,omitempty"` // Lowest severity
+	Source     string                `json:"source,omitempty"`
+	Code       string                `json:"code,omitempty"`
+	Text       string                `json:"text,omitempty"`       // In the message, case insensitive
+	Attributes map[string]string     `json:"attributes,omitempty"` // Every one must match
+	From       int64                 `json:"from,omitempty"`       // Unix milliseconds, inclusive
+	To         int64                 `json:"to,omitempty"`         // Unix milliseconds, exclusive
+	Limit      int                   `json:"limit,omitempty"`      // DefaultLimit when 0
+}
+
+// Validate validates the query
+func (q *Query) Validate() error {
+	for _, kind := range q.Kinds {
+		if kind == sqx.SystemEventKindUnknown {
+			return fmt.Errorf("unknown system event kind")
+		}
+	}
+	if q.From < 0 || q.To < 0 || (q.To > 0 && q.To <= q.From) {
+		return fmt.Errorf("invalid time range [%d, %d)", q.From, q.To)
+	}
+	if q.Limit < 0 || q.Limit > MaxLimit {
+		return fmt.Errorf("limit must be between 0 and %d, got %d", MaxLimit, q.Limit)
+	}
+	return nil
+}
+
+// match reports whether e is selected by the query
+func (q *Query) match(e *sqx.SystemEvent) bool {
+	if len(q.Kinds) > 0 && !containsKind(q.Kinds, e.Kind) {
+		return false
+	}
+	if e.Severity < q.Severity {
+		return false
+	}
+	if (q.Source != "" && e.Source != q.Source) || (q.Code != "" && e.Code != q.Code) {
+		return false
+	}
+	if q.Text != "" && !strings.Contains(strings.ToLower(e.Message), strings.ToLower(q.Text)) {
+		return false
+	}
+	for key, value := range q.Attributes {
+		if e.Attributes[key] != value {
+			return false
+		}
+	}
+	return true
+}
+
+func containsKind(kinds []sqx.SystemEventKind, kind sqx.SystemEventKind) bool {
+	for _, k := range kinds {
+		if k == kind {
+			return true
+		}
+	}
+	return false
+}
+
+// Store keeps the system events of the last days, in memory and journaled
+// to a JSON lines file per UTC day so that they survive restarts. Events
+// are kept once per id. It is safe for concurrent use.
+type Store struct {
+	dir       string
+	retention time.Duration
+	now       func() time.Time
+
+	mu     sync.Mutex
+	events []sqx.SystemEvent // By timestamp, oldest first
+	ids    map[string]struct{}
+	file   *os.File // Journal of day
+	day    string
+}
+
+// OpenStore opens the store journaled in dir, loading the events within
+// retention. It keeps events in memory only when dir is empty.
+func OpenStore(dir string, retention time.Duration) (*Store, error) {
+	if retention <= 0 {
+		retention = DefaultRetention
+	}
+	s := &Store{dir: dir, retention: retention, now: time.Now, ids: make(map[string]struct{})}
+	if dir == "" {
+		return s, nil
+	}
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, err
+	}
+	paths, err := filepath.Glob(filepath.Join(dir, "events-*.jsonl"))
+	if err != nil {
+		return nil, err
+	}
+	sort.Strings(paths)
+	cutoff := s.cutoff()
+	for _, path := range paths {
+		if err := s.load(path, cutoff); err != nil {
+			return nil, err
+		}
+	}
+	sort.SliceStable(s.events, func(i, j int) bool { return s.events[i].Timestamp < s.events[j].Timestamp })
+	return s, nil
+}
+
+// load reads the events of a journal file at or after cutoff. A truncated
+// last line, left by a crash during a write, is dropped so the next event
+// starts on its own line.
+func (s *Store) load(path string, cutoff int64) error {
+	file, err := os.OpenFile(path, os.O_RDWR, 0o644)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+	reader := bufio.NewReader(file)
+	var size int64
+	for line := 1; ; line++ {
+		data, err := reader.ReadBytes('\n')
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return err
+		}
+		var e sqx.SystemEvent
+		if err := json.Unmarshal(data, &e); err != nil {
+			return fmt.Errorf("%s:%d: %w", path, line, err)
+		}
+		size += int64(len(data))
+		if _, ok := s.ids[e.ID]; ok || e.Timestamp < cutoff {
+			continue
+		}
+		s.ids[e.ID] = struct{}{}
+		s.events = append(s.events, e)
+	}
+	return file.Truncate(size)
+}
+
+// cutoff returns the time before which events are no longer kept
+func (s *Store) cutoff() int64 {
+	return s.now().Add(-s.retention).UnixMilli()
+}
+
+// Add keeps an event, reporting false for those already kept or older than
+// the retention
+func (s *Store) Add(e sqx.SystemEvent) (bool, error) {
+	if e.ID == "" {
+		return false, fmt.Errorf("system event without id")
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, ok := s.ids[e.ID]; ok || e.Timestamp < s.cutoff() {
+		return false, nil
+	}
+	if err := s.journal(e); err != nil {
+		return false, err
+	}
+	s.ids[e.ID] = struct{}{}
+	i := len(s.events)
+	if i > 0 && s.events[i-1].Timestamp > e.Timestamp {
+		i = sort.Search(len(s.events), func(k int) bool { return s.events[k].Timestamp > e.Timestamp })
+	}
+	s.events = append(s.events, sqx.SystemEvent{})
+	copy(s.events[i+1:], s.events[i:])
+	s.events[i] = e
+	return true, nil
+}
+
+// journal appends e to the file of its day, called with mu held
+func (s *Store) journal(e sqx.SystemEvent) error {
+	if s.dir == "" {
+		return nil
+	}
+	day := time.UnixMilli(e.Timestamp).UTC().Format(dayLayout)
+	if s.file == nil || day != s.day {
+		if s.file != nil {
+			s.file.Close()
+			s.file = nil
+		}
+		file, err := os.OpenFile(filepath.Join(s.dir, "events-"+day+".jsonl"), os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0o644)
+		if err != nil {
+			return err
+		}
+		s.file, s.day = file, day
+	}
+	data, err := json.Marshal(e)
+	if err != nil {
+		return err
+	}
+	if _, err := s.file.Write(append(data, '\n')); err != nil {
+		return err
+	}
+	return nil
+}
+
+// HandleMsg keeps the event of a message of sys.events.*
+func (s *Store) HandleMsg(msg *nats.Msg) (bool, error) {
+	if err := eventbus.Decode(msg); err != nil {
+		return false, err
+	}
+	var e sqx.SystemEvent
+	if err := sqx.UnmarshalSystemEvent(msg.Data, &e); err != nil {
+		return false, err
+	}
+	return s.Add(e)
+}
+
+// Query returns the events selected by q, newest first
+func (s *Store) Query(q Query) ([]sqx.SystemEvent, error) {
+	if err := q.Validate(); err != nil {
+		return nil, err
+	}
+	limit := q.Limit
+	if limit == 0 {
+		limit = DefaultLimit
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	end := len(s.events)
+	if q.To > 0 {
+		end = sort.Search(len(s.events), func(i int) bool { return s.events[i].Timestamp >= q.To })
+	}
+	events := []sqx.SystemEvent{}
+	for i := end - 1; i >= 0 && len(events) < limit; i-- {
+		e := &s.events[i]
+		if e.Timestamp < q.From {
+			break
+		}
+		if q.match(e) {
+			events = append(events, *e)
+		}
+	}
+	return events, nil
+}
+
+// Len returns the number of events kept
+func (s *Store) Len() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return len(s.events)
+}
+
+// Prune drops the events older than the retention and deletes the journal
+// files holding only such events. It returns the number of events dropped.
+func (s *Store) Prune() (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	cutoff := s.cutoff()
+	n := sort.Search(len(s.events), func(i int) bool { return s.events[i].Timestamp >= cutoff })
+	for _, e := range s.events[:n] {
+		delete(s.ids, e.ID)
+	}
+	s.events = append(s.events[:0:0], s.events[n:]...)
+	if s.dir == "" {
+		return n, nil
+	}
+
+	paths, err := filepath.Glob(filepath.Join(s.dir, "events-*.jsonl"))
+	if err != nil {
+		return n, err
+	}
+	var errs []error
+	for _, path := range paths {
+		day, err := time.Parse(dayLayout, strings.TrimSuffix(strings.TrimPrefix(filepath.Base(path), "events-"), ".jsonl"))
+		if err != nil || day.Add(24*time.Hour).UnixMilli() > cutoff {
+			continue
+		}
+		if day.Format(dayLayout) == s.day && s.file != nil {
+			s.file.Close()
+			s.file = nil
+		}
+		if err := os.Remove(path); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return n, errors.Join(errs...)
+}
+
+// Close closes the journal
+func (s *Store) Close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.file == nil {
+		return nil
+	}
+	err := s.file.Close()
+	s.file = nil
+	return err
+}
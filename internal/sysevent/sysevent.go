@@ -0,0 +1,174 @@
+// Package sysevent gives operators one place to see everything that
+// happened to the system. Services publish system events, of the taxonomy
+// of sqx.SystemEvent, to <prefix>.<kind>, e.g. sys.events.risk_breach; the
+// sysevents service collects them into a store keeping the last days and
+// answers queries over them.
+package sysevent
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/BullionBear/sequex/internal/announce"
+	"github.com/BullionBear/sequex/internal/guardian"
+	"github.com/BullionBear/sequex/internal/lifecycle"
+	"github.com/BullionBear/sequex/internal/model/sqx"
+	"github.com/BullionBear/sequex/pkg/node"
+)
+
+// DefaultPrefix is the subject prefix system events are published under
+// when none is configured
+const DefaultPrefix = "sys.events"
+
+// Subject returns the subject events of kind are published on, <prefix>.<kind>
+func Subject(prefix string, kind sqx.SystemEventKind) string {
+	return prefix + "." + kind.Token()
+}
+
+// NodeEvent converts an event of a node. Messages dropped after a panic are
+// dead-letter entries, the rest lifecycle events.
+func NodeEvent(e node.Event) sqx.SystemEvent {
+	event := sqx.SystemEvent{
+		Kind:       sqx.SystemEventKindNodeLifecycle,
+		Severity:   sqx.SeverityInfo,
+		Source:     e.Node,
+		Code:       string(e.Type),
+		Timestamp:  e.At.UnixMilli(),
+		Attributes: map[string]string{"node": e.Node},
+	}
+	switch e.Type {
+	case node.EventStarted, node.EventStopped, node.EventRecovered:
+		event.Message = fmt.Sprintf("node %s %s", e.Node, e.Type)
+	case node.EventDegraded:
+		event.Severity = sqx.SeverityWarning
+		event.Message = fmt.Sprintf("node %s degraded, intake paused", e.Node)
+	case node.EventQuarantined:
+		event.Severity = sqx.SeverityError
+		event.Message = fmt.Sprintf("node %s quarantined %s", e.Node, e.Subject)
+	case node.EventPanicked:
+		event.Kind = sqx.SystemEventKindDLQEntry
+		event.Severity = sqx.SeverityError
+		event.Message = fmt.Sprintf("node %s dropped a message of %s", e.Node, e.Subject)
+	default:
+		event.Message = fmt.Sprintf("node %s: %s", e.Node, e.Type)
+	}
+	if e.Reason != "" {
+		event.Message += ": " + e.Reason
+	}
+	if e.Subject != "" {
+		event.Attributes["subject"] = e.Subject
+	}
+	return event
+}
+
+// GuardianEvent converts a pause or resume of a strategy. Pauses on a
+// breach are critical, those by hand warnings.
+func GuardianEvent(e guardian.Event) sqx.SystemEvent {
+	event := sqx.SystemEvent{
+		Kind:      sqx.SystemEventKindRiskBreach,
+		Severity:  sqx.SeverityInfo,
+		Code:      e.Action,
+		Message:   fmt.Sprintf("strategy %s %sd by %s: %s", e.Strategy, e.Action, e.By, e.Reason),
+		Timestamp: e.At.UnixMilli(),
+		Attributes: map[string]string{
+			"strategy": e.Strategy,
+			"by":       e.By,
+		},
+	}
+	if e.Action == guardian.ActionPause {
+		event.Severity = sqx.SeverityWarning
+		if e.Automatic {
+			event.Severity = sqx.SeverityCritical
+		}
+		event.Attributes["drawdown"] = strconv.FormatFloat(e.Drawdown, 'f', -1, 64)
+		event.Attributes["canceled"] = strconv.Itoa(e.Canceled)
+	}
+	return event
+}
+
+// AnnouncementEvent converts an announcement halting trading: a delisting or
+// the exchange entering or leaving system maintenance. It reports false for
+// the others. The id is that of the announcement, so every instance of the
+// collector publishes it once.
+func AnnouncementEvent(a announce.Announcement) (sqx.SystemEvent, bool) {
+	exchange := strings.ToUpper(a.Exchange)
+	event := sqx.SystemEvent{
+		ID:         "announcement." + a.Exchange + "." + a.ID,
+		Kind:       sqx.SystemEventKindExchangeHalt,
+		Timestamp:  a.PublishedAt,
+		Attributes: map[string]string{"exchange": exchange},
+	}
+	switch {
+	case a.Kind == announce.KindDelisting:
+		event.Severity = sqx.SeverityWarning
+		event.Code = "delisting"
+		event.Message = fmt.Sprintf("%s delisting: %s", exchange, a.Title)
+		if len(a.Symbols) > 0 {
+			event.Attributes["symbols"] = strings.Join(a.Symbols, ",")
+		}
+		if len(a.Assets) > 0 {
+			event.Attributes["assets"] = strings.Join(a.Assets, ",")
+		}
+		if a.EffectiveAt > 0 {
+			event.Attributes["effective_at"] = strconv.FormatInt(a.EffectiveAt, 10)
+		}
+	case a.Kind == announce.KindStatus && a.Maintenance:
+		event.Severity = sqx.SeverityCritical
+		event.Code = "maintenance"
+		event.Message = fmt.Sprintf("%s under system maintenance", exchange)
+	case a.Kind == announce.KindStatus:
+		event.Severity = sqx.SeverityInfo
+		event.Code = "resumed"
+		event.Message = fmt.Sprintf("%s out of system maintenance", exchange)
+	default:
+		return sqx.SystemEvent{}, false
+	}
+	return event, true
+}
+
+// LifecycleEvent converts a change of the trading status of a symbol. It
+// reports false for symbols listed trading, which resume nothing.
+func LifecycleEvent(c lifecycle.Change) (sqx.SystemEvent, bool) {
+	if c.From == "" && c.Trading() {
+		return sqx.SystemEvent{}, false
+	}
+	exchange := strings.ToUpper(c.Exchange)
+	event := sqx.SystemEvent{
+		ID:        strings.Join([]string{"lifecycle", c.Exchange, c.Instrument, c.Symbol, c.From, c.To}, "."),
+		Kind:      sqx.SystemEventKindExchangeHalt,
+		Severity:  sqx.SeverityWarning,
+		Code:      "halted",
+		Message:   fmt.Sprintf("%s %s %s is %s", exchange, c.Instrument, c.Symbol, c.To),
+		Timestamp: c.Timestamp,
+		Attributes: map[string]string{
+			"exchange":   exchange,
+			"instrument": c.Instrument,
+			"symbol":     c.Symbol,
+			"from":       c.From,
+			"to":         c.To,
+		},
+	}
+	if c.Trading() {
+		event.Severity = sqx.SeverityInfo
+		event.Code = "resumed"
+		event.Message = fmt.Sprintf("%s %s %s trading again", exchange, c.Instrument, c.Symbol)
+	}
+	return event, true
+}
+
+// ReconnectEvent is a connection to target reestablished, e.g. the trade
+// stream of a symbol
+func ReconnectEvent(target, reason string) sqx.SystemEvent {
+	message := "reconnected to " + target
+	if reason != "" {
+		message += ": " + reason
+	}
+	return sqx.SystemEvent{
+		Kind:       sqx.SystemEventKindReconnect,
+		Severity:   sqx.SeverityWarning,
+		Code:       "reconnected",
+		Message:    message,
+		Attributes: map[string]string{"target": target},
+	}
+}
@@ -0,0 +1,218 @@
+package sysevent
+
+import (
+	"net/url"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/BullionBear/sequex/internal/announce"
+	"github.com/BullionBear/sequex/internal/guardian"
+	"github.com/BullionBear/sequex/internal/lifecycle"
+	"github.com/BullionBear/sequex/internal/model/sqx"
+	"github.com/BullionBear/sequex/pkg/node"
+	"github.com/nats-io/nats.go"
+	"github.com/rs/zerolog"
+)
+
+// fakeStream records the messages published
+type fakeStream struct {
+	mu   sync.Mutex
+	msgs []*nats.Msg
+}
+
+func (f *fakeStream) PublishMsg(m *nats.Msg, _ ...nats.PubOpt) (*nats.PubAck, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.msgs = append(f.msgs, m)
+	return &nats.PubAck{}, nil
+}
+
+func TestPublisher(t *testing.T) {
+	var nilPublisher *Publisher
+	nilPublisher.Publish(sqx.SystemEvent{Message: "dropped"})
+	nilPublisher.Close()
+
+	stream := &fakeStream{}
+	p := NewPublisher(stream, DefaultPrefix, "fixgateway", zerolog.Nop())
+	p.Publish(GuardianEvent(guardian.Event{Strategy: "mm", Action: guardian.ActionPause, Automatic: true, By: "guardian", At: time.UnixMilli(1000)}))
+	p.Publish(sqx.SystemEvent{Message: "unclassified"})
+	p.Close()
+	p.Publish(sqx.SystemEvent{Message: "after close"})
+
+	if len(stream.msgs) != 2 || p.Dropped() != 1 {
+		t.Fatalf("published %d events, dropped %d", len(stream.msgs), p.Dropped())
+	}
+	var breach, other sqx.SystemEvent
+	for i, e := range []*sqx.SystemEvent{&breach, &other} {
+		if err := sqx.UnmarshalSystemEvent(stream.msgs[i].Data, e); err != nil {
+			t.Fatal(err)
+		}
+		if e.ID == "" || stream.msgs[i].Header.Get("Nats-Msg-Id") != e.ID || e.Source != "fixgateway" {
+			t.Errorf("event %d = %+v, header %v", i, e, stream.msgs[i].Header)
+		}
+	}
+	if stream.msgs[0].Subject != "sys.events.risk_breach" || breach.Severity != sqx.SeverityCritical || breach.Timestamp != 1000 ||
+		breach.Attributes["strategy"] != "mm" {
+		t.Errorf("breach = %+v on %s", breach, stream.msgs[0].Subject)
+	}
+	if stream.msgs[1].Subject != "sys.events.error" || other.Severity != sqx.SeverityError || other.Timestamp == 0 || other.ID == breach.ID {
+		t.Errorf("other = %+v on %s", other, stream.msgs[1].Subject)
+	}
+}
+
+func TestConversions(t *testing.T) {
+	at := time.UnixMilli(5000)
+	if e := NodeEvent(node.Event{Node: "basis", Type: node.EventPanicked, Subject: "trade.btcusdt", Reason: "boom", At: at}); e.Kind != sqx.SystemEventKindDLQEntry ||
+		e.Severity != sqx.SeverityError || e.Attributes["subject"] != "trade.btcusdt" || e.Source != "basis" || e.Timestamp != 5000 {
+		t.Errorf("panicked = %+v", e)
+	}
+	if e := NodeEvent(node.Event{Node: "basis", Type: node.EventDegraded, Reason: "heap", At: at}); e.Kind != sqx.SystemEventKindNodeLifecycle ||
+		e.Severity != sqx.SeverityWarning || e.Code != "degraded" {
+		t.Errorf("degraded = %+v", e)
+	}
+	if e := GuardianEvent(guardian.Event{Strategy: "mm", Action: guardian.ActionResume, By: "ops", Reason: "reviewed", At: at}); e.Severity != sqx.SeverityInfo ||
+		e.Code != "resume" || e.Message != "strategy mm resumed by ops: reviewed" {
+		t.Errorf("resume = %+v", e)
+	}
+	if _, ok := AnnouncementEvent(announce.Announcement{ID: "1", Exchange: "binance", Kind: announce.KindListing}); ok {
+		t.Error("listing converted")
+	}
+	e, ok := AnnouncementEvent(announce.Announcement{ID: "2", Exchange: "binance", Kind: announce.KindStatus, Maintenance: true, PublishedAt: 7000})
+	if !ok || e.Severity != sqx.SeverityCritical || e.ID != "announcement.binance.2" || e.Attributes["exchange"] != "BINANCE" {
+		t.Errorf("maintenance = %+v", e)
+	}
+	if _, ok := LifecycleEvent(lifecycle.Change{Exchange: "binance", Symbol: "NEW-USDT", To: lifecycle.StatusTrading}); ok {
+		t.Error("listing trading converted")
+	}
+	e, ok = LifecycleEvent(lifecycle.Change{Exchange: "binance", Instrument: "spot", Symbol: "BTC-USDT", From: lifecycle.StatusTrading, To: "BREAK"})
+	if !ok || e.Code != "halted" || e.Severity != sqx.SeverityWarning || e.Attributes["symbol"] != "BTC-USDT" {
+		t.Errorf("halt = %+v", e)
+	}
+}
+
+func TestStore(t *testing.T) {
+	dir := t.TempDir()
+	// Midday, so that reopening the store within the retention keeps the
+	// events whatever the time the test runs
+	now := time.Now().UTC().Truncate(24 * time.Hour).Add(12 * time.Hour)
+	store, err := OpenStore(dir, 48*time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+	store.now = func() time.Time { return now }
+	ms := func(d time.Duration) int64 { return now.Add(-d).UnixMilli() }
+	events := []sqx.SystemEvent{
+		{ID: "a", Kind: sqx.SystemEventKindNodeLifecycle, Severity: sqx.SeverityInfo, Source: "basis", Code: "started", Message: "node basis started", Timestamp: ms(30 * time.Hour)},
+		{ID: "c", Kind: sqx.SystemEventKindReconnect, Severity: sqx.SeverityWarning, Source: "feed", Code: "reconnected", Message: "Reconnected to binance", Timestamp: ms(time.Hour)},
+		{ID: "b", Kind: sqx.SystemEventKindRiskBreach, Severity: sqx.SeverityCritical, Source: "fixgateway", Code: "pause", Message: "strategy mm paused", Timestamp: ms(2 * time.Hour),
+			Attributes: map[string]string{"strategy": "mm"}},
+		{ID: "old", Kind: sqx.SystemEventKindError, Severity: sqx.SeverityError, Timestamp: ms(72 * time.Hour)},
+	}
+	for i, e := range events {
+		added, err := store.Add(e)
+		if err != nil || added != (i < 3) {
+			t.Fatalf("Add(%s) = %v, %v", e.ID, added, err)
+		}
+	}
+	if added, _ := store.Add(events[0]); added {
+		t.Error("duplicate added")
+	}
+
+	ids := func(events []sqx.SystemEvent) []string {
+		var ids []string
+		for _, e := range events {
+			ids = append(ids, e.ID)
+		}
+		return ids
+	}
+	tests := []struct {
+		query Query
+		want  []string
+	}{
+		{Query{}, []string{"c", "b", "a"}},
+		{Query{Severity: sqx.SeverityWarning}, []string{"c", "b"}},
+		{Query{Kinds: []sqx.SystemEventKind{sqx.SystemEventKindNodeLifecycle, sqx.SystemEventKindReconnect}}, []string{"c", "a"}},
+		{Query{Text: "RECONNECTED"}, []string{"c"}},
+		{Query{Attributes: map[string]string{"strategy": "mm"}}, []string{"b"}},
+		{Query{From: ms(3 * time.Hour), To: ms(90 * time.Minute)}, []string{"b"}},
+		{Query{Limit: 1}, []string{"c"}},
+		{Query{Source: "none"}, nil},
+	}
+	for _, tt := range tests {
+		got, err := store.Query(tt.query)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !reflect.DeepEqual(ids(got), tt.want) {
+			t.Errorf("Query(%+v) = %v, want %v", tt.query, ids(got), tt.want)
+		}
+	}
+	if _, err := store.Query(Query{From: 10, To: 5}); err == nil {
+		t.Error("inverted range accepted")
+	}
+	if err := store.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	// A line cut short by a crash is dropped on reopening
+	paths, _ := filepath.Glob(filepath.Join(dir, "events-*.jsonl"))
+	if len(paths) != 2 {
+		t.Fatalf("journals = %v", paths)
+	}
+	f, err := os.OpenFile(paths[len(paths)-1], os.O_APPEND|os.O_WRONLY, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	f.WriteString(`{"id":"cut`)
+	f.Close()
+	store, err = OpenStore(dir, 48*time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer store.Close()
+	if store.Len() != 3 {
+		t.Fatalf("reopened with %d events", store.Len())
+	}
+
+	// The next day the first event and its journal are past the retention
+	store.now = func() time.Time { return now.Add(36 * time.Hour) }
+	dropped, err := store.Prune()
+	if err != nil || dropped != 1 {
+		t.Fatalf("Prune() = %d, %v", dropped, err)
+	}
+	if _, err := os.Stat(paths[0]); !os.IsNotExist(err) {
+		t.Errorf("journal %s not deleted: %v", paths[0], err)
+	}
+	if got, _ := store.Query(Query{}); !reflect.DeepEqual(ids(got), []string{"c", "b"}) {
+		t.Errorf("after prune = %v", ids(got))
+	}
+}
+
+func TestParseQuery(t *testing.T) {
+	now := time.UnixMilli(10_000_000)
+	values, _ := url.ParseQuery("kind=risk_breach,RECONNECT&severity=warning&attr=strategy:mm&since=1h&limit=5")
+	q, err := ParseQuery(values, now)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := Query{
+		Kinds:      []sqx.SystemEventKind{sqx.SystemEventKindRiskBreach, sqx.SystemEventKindReconnect},
+		Severity:   sqx.SeverityWarning,
+		Attributes: map[string]string{"strategy": "mm"},
+		From:       now.Add(-time.Hour).UnixMilli(),
+		Limit:      5,
+	}
+	if !reflect.DeepEqual(q, want) {
+		t.Errorf("ParseQuery() = %+v, want %+v", q, want)
+	}
+	for _, bad := range []string{"kind=nope", "severity=loud", "attr=strategy", "since=-1h", "limit=x", "from=5&to=1"} {
+		values, _ := url.ParseQuery(bad)
+		if _, err := ParseQuery(values, now); err == nil {
+			t.Errorf("ParseQuery(%s) accepted", bad)
+		}
+	}
+}
@@ -0,0 +1,202 @@
+// Package tape consolidates the trades of a symbol on every venue listing it
+// into one tape, ordered on a common clock and numbered without gaps, so that
+// consumers subscribe once per symbol rather than once per venue.
+package tape
+
+import (
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/BullionBear/sequex/internal/model/sqx"
+)
+
+// defaultSkewWindow is the window clock offsets are estimated over when
+// none is configured
+const defaultSkewWindow = time.Minute
+
+// Options configures a consolidator
+type Options struct {
+	Symbols    []sqx.Symbol  // Consolidated symbols, the trades of others are dropped
+	Delay      time.Duration // Trades are held this long to be ordered across venues, printed on arrival when 0
+	SkewWindow time.Duration // Window the clock offset of a venue is estimated over, 1 minute when 0
+}
+
+// Validate validates the options
+func (o Options) Validate() error {
+	if len(o.Symbols) == 0 {
+		return fmt.Errorf("symbols cannot be empty")
+	}
+	seen := make(map[sqx.Symbol]bool)
+	for _, symbol := range o.Symbols {
+		if seen[symbol] {
+			return fmt.Errorf("duplicate symbol %s", symbol)
+		}
+		seen[symbol] = true
+	}
+	if o.Delay < 0 || o.SkewWindow < 0 {
+		return fmt.Errorf("delay and skew window cannot be negative")
+	}
+	return nil
+}
+
+// clock estimates the offset of the clock of a venue to the local one as the
+// smallest difference between the receipt of a trade and its venue time over
+// the last one to two windows. Latency only adds to the difference, so the
+// minimum keeps the skew and the fastest transit, and a skew drifting away
+// is forgotten after two windows.
+type clock struct {
+	start             time.Time // Of the current window
+	current, previous int64     // Smallest offsets of the windows, milliseconds
+	hasPrevious       bool
+}
+
+// observe records a trade timed venueTime and received at now, returning
+// the offset of the venue clock
+func (c *clock) observe(venueTime int64, now time.Time, window time.Duration) int64 {
+	offset := now.UnixMilli() - venueTime
+	switch {
+	case c.start.IsZero():
+		c.start, c.current = now, offset
+	case now.Sub(c.start) >= window:
+		c.previous, c.hasPrevious = c.current, true
+		c.start, c.current = now, offset
+	case offset < c.current:
+		c.current = offset
+	}
+	return c.offset()
+}
+
+func (c *clock) offset() int64 {
+	if c.hasPrevious && c.previous < c.current {
+		return c.previous
+	}
+	return c.current
+}
+
+// book is the tape of a symbol
+type book struct {
+	seq     uint64
+	last    int64            // Timestamp of the last print
+	pending []sqx.TapePrint  // Held for the delay, in arrival order
+	lastIds map[string]int64 // Id of the last trade of every venue
+}
+
+// Consolidator merges trades into the tapes of their symbols. It is safe for
+// concurrent use.
+type Consolidator struct {
+	opts   Options
+	mu     sync.Mutex
+	clocks map[string]*clock
+	books  map[sqx.Symbol]*book
+}
+
+// New creates a consolidator
+func New(opts Options) (*Consolidator, error) {
+	if err := opts.Validate(); err != nil {
+		return nil, err
+	}
+	if opts.SkewWindow == 0 {
+		opts.SkewWindow = defaultSkewWindow
+	}
+	c := &Consolidator{opts: opts, clocks: make(map[string]*clock), books: make(map[sqx.Symbol]*book)}
+	for _, symbol := range opts.Symbols {
+		c.books[symbol] = &book{lastIds: make(map[string]int64)}
+	}
+	return c, nil
+}
+
+// Resume continues the tape of a symbol after its last print, published
+// before a restart, so that the sequence and clock carry on from it
+func (c *Consolidator) Resume(last sqx.TapePrint) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	b, ok := c.books[last.Symbol]
+	if !ok {
+		return fmt.Errorf("%s is not consolidated", last.Symbol)
+	}
+	if last.Seq > b.seq {
+		b.seq, b.last = last.Seq, last.Timestamp
+	}
+	return nil
+}
+
+// Add queues a trade received at now. It reports false when the trade is
+// dropped: its symbol is not consolidated, or its venue already sent a
+// trade with a greater or equal id, as redelivered trades are.
+func (c *Consolidator) Add(trade sqx.Trade, now time.Time) bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	b, ok := c.books[trade.Symbol]
+	if !ok {
+		return false
+	}
+	p := sqx.TapePrint{
+		Symbol:         trade.Symbol,
+		Exchange:       trade.Exchange,
+		InstrumentType: trade.InstrumentType,
+		TradeId:        trade.Id,
+		TakerSide:      trade.TakerSide,
+		Price:          trade.Price,
+		Quantity:       trade.Quantity,
+		VenueTime:      trade.Timestamp,
+	}
+	venue := p.Venue()
+	if last, ok := b.lastIds[venue]; ok && trade.Id <= last {
+		return false
+	}
+	b.lastIds[venue] = trade.Id
+
+	clk, ok := c.clocks[venue]
+	if !ok {
+		clk = &clock{}
+		c.clocks[venue] = clk
+	}
+	p.Skew = clk.observe(trade.Timestamp, now, c.opts.SkewWindow)
+	p.Timestamp = trade.Timestamp + p.Skew
+	b.pending = append(b.pending, p)
+	return true
+}
+
+// Flush prints the trades held for the delay at now, ordered by corrected
+// time and then by arrival. Prints are numbered in that order and their
+// timestamps raised to the previous one where the correction would move the
+// tape backwards.
+func (c *Consolidator) Flush(now time.Time) []sqx.TapePrint {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	due := now.Add(-c.opts.Delay).UnixMilli()
+	var prints []sqx.TapePrint
+	for _, symbol := range c.opts.Symbols {
+		b := c.books[symbol]
+		if len(b.pending) == 0 {
+			continue
+		}
+		sort.SliceStable(b.pending, func(i, j int) bool { return b.pending[i].Timestamp < b.pending[j].Timestamp })
+		n := sort.Search(len(b.pending), func(i int) bool { return b.pending[i].Timestamp > due })
+		for _, p := range b.pending[:n] {
+			b.seq++
+			p.Seq = b.seq
+			if p.Timestamp < b.last {
+				p.Timestamp = b.last
+			}
+			b.last = p.Timestamp
+			prints = append(prints, p)
+		}
+		b.pending = append(b.pending[:0], b.pending[n:]...)
+	}
+	return prints
+}
+
+// Skews returns the current correction of the clock of every venue seen, in
+// milliseconds, the receipt delay of the fastest trade included
+func (c *Consolidator) Skews() map[string]int64 {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	skews := make(map[string]int64, len(c.clocks))
+	for venue, clk := range c.clocks {
+		skews[venue] = clk.offset()
+	}
+	return skews
+}
@@ -0,0 +1,101 @@
+package tape
+
+import (
+	"testing"
+	"time"
+
+	"github.com/BullionBear/sequex/internal/model/sqx"
+)
+
+var btc = sqx.NewSymbol("BTC", "USDT")
+
+func trade(exchange sqx.Exchange, id int64, timestamp int64) sqx.Trade {
+	return sqx.Trade{Id: id, Symbol: btc, Exchange: exchange, InstrumentType: sqx.InstrumentTypeSpot, Price: 100, Quantity: 1, Timestamp: timestamp}
+}
+
+func TestConsolidator_OrdersAcrossSkewedVenues(t *testing.T) {
+	c, err := New(Options{Symbols: []sqx.Symbol{btc}, Delay: 100 * time.Millisecond})
+	if err != nil {
+		t.Fatal(err)
+	}
+	start := time.UnixMilli(1_000_000)
+
+	// Bybit runs 500ms ahead of Binance, both reaching us in 10ms at best
+	c.Add(trade(sqx.ExchangeBinance, 1, 999_990), start)
+	c.Add(trade(sqx.ExchangeBybit, 1, 1_000_490), start)
+	// Stamped later than the Binance trade below by the Bybit clock, yet
+	// earlier once corrected
+	c.Add(trade(sqx.ExchangeBybit, 2, 1_000_520), start.Add(40*time.Millisecond))
+	c.Add(trade(sqx.ExchangeBinance, 2, 1_000_025), start.Add(40*time.Millisecond))
+	// Redelivered
+	if c.Add(trade(sqx.ExchangeBinance, 2, 1_000_025), start.Add(45*time.Millisecond)) {
+		t.Error("Add() of a redelivered trade succeeded")
+	}
+	if c.Add(sqx.Trade{Symbol: sqx.NewSymbol("ETH", "USDT")}, start) {
+		t.Error("Add() of another symbol succeeded")
+	}
+
+	if prints := c.Flush(start.Add(50 * time.Millisecond)); len(prints) != 0 {
+		t.Fatalf("Flush() before the delay = %+v", prints)
+	}
+	prints := c.Flush(start.Add(200 * time.Millisecond))
+	want := []struct {
+		exchange  sqx.Exchange
+		id        int64
+		timestamp int64
+	}{
+		{sqx.ExchangeBinance, 1, 1_000_000},
+		{sqx.ExchangeBybit, 1, 1_000_000},
+		{sqx.ExchangeBybit, 2, 1_000_030},
+		{sqx.ExchangeBinance, 2, 1_000_035},
+	}
+	if len(prints) != len(want) {
+		t.Fatalf("Flush() = %+v, want %d prints", prints, len(want))
+	}
+	for i, p := range prints {
+		if p.Seq != uint64(i+1) || p.Exchange != want[i].exchange || p.TradeId != want[i].id || p.Timestamp != want[i].timestamp {
+			t.Errorf("prints[%d] = %+v, want %+v", i, p, want[i])
+		}
+	}
+	if skews := c.Skews(); skews["BYBIT.SPOT"] != -490 || skews["BINANCE.SPOT"] != 10 {
+		t.Errorf("Skews() = %v", skews)
+	}
+}
+
+func TestConsolidator_Monotonic(t *testing.T) {
+	c, err := New(Options{Symbols: []sqx.Symbol{btc}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := c.Resume(sqx.TapePrint{Symbol: btc, Seq: 41, Timestamp: 2_000_000}); err != nil {
+		t.Fatal(err)
+	}
+	now := time.UnixMilli(1_000_000)
+	c.Add(trade(sqx.ExchangeBinance, 7, 999_990), now)
+	prints := c.Flush(now)
+	if len(prints) != 1 || prints[0].Seq != 42 || prints[0].Timestamp != 2_000_000 || prints[0].VenueTime != 999_990 {
+		t.Errorf("Flush() after Resume() = %+v", prints)
+	}
+	if err := c.Resume(sqx.TapePrint{Symbol: sqx.NewSymbol("ETH", "USDT")}); err == nil {
+		t.Error("Resume() of another symbol succeeded")
+	}
+}
+
+func TestClock_Window(t *testing.T) {
+	var c clock
+	start := time.UnixMilli(0)
+	c.observe(1000, start.Add(1005*time.Millisecond), time.Second)
+	c.observe(1000, start.Add(1050*time.Millisecond), time.Second)
+	if c.offset() != 5 {
+		t.Errorf("offset() = %d, want 5", c.offset())
+	}
+	// The skew moved by 20ms, the old minimum is kept a window longer
+	c.observe(3000, start.Add(3025*time.Millisecond), time.Second)
+	if c.offset() != 5 {
+		t.Errorf("offset() after one window = %d, want 5", c.offset())
+	}
+	c.observe(4000, start.Add(4030*time.Millisecond), time.Second)
+	if c.offset() != 25 {
+		t.Errorf("offset() after two windows = %d, want 25", c.offset())
+	}
+}
@@ -0,0 +1,207 @@
+// Package tenant isolates the teams sharing a deployment. A tenant has its
+// own API keys, portfolios, execution history and NATS subjects, all under
+// its subject prefix, and trades through its own exchange accounts within
+// its risk limits. Services resolve the tenant of a request or a session
+// once and scope every query and event route with it.
+package tenant
+
+import (
+	"crypto/sha256"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/BullionBear/sequex/internal/model/sqx"
+	"github.com/BullionBear/sequex/pkg/eventbus"
+)
+
+// ErrRejected is returned for orders outside the accounts or limits of a tenant
+var ErrRejected = errors.New("rejected")
+
+// Limits bounds the orders of a tenant. Zero values mean no limit.
+type Limits struct {
+	MaxOrderQuantity float64 `json:"max_order_quantity"`
+	// Price times quantity of an order. Market orders have no price, so they
+	// are rejected under a notional limit.
+	MaxOrderNotional float64 `json:"max_order_notional"`
+}
+
+// Validate validates the limits
+func (l Limits) Validate() error {
+	if l.MaxOrderQuantity < 0 || l.MaxOrderNotional < 0 {
+		return fmt.Errorf("limits cannot be negative")
+	}
+	return nil
+}
+
+// Tenant is a team sharing the deployment
+type Tenant struct {
+	ID            string   // Lower case letters and digits, e.g. acme
+	Name          string   // Displayed
+	SubjectPrefix string   // Of every subject of the tenant, tenant.<id> when empty
+	Keys          []string // API keys
+	Accounts      []string // Exchanges the tenant trades on, e.g. BINANCE; it cannot place orders when empty
+	Limits        Limits
+}
+
+// Validate validates a tenant
+func (t *Tenant) Validate() error {
+	if t.ID == "" {
+		return fmt.Errorf("tenant id cannot be empty")
+	}
+	for _, r := range t.ID {
+		if (r < 'a' || r > 'z') && (r < '0' || r > '9') {
+			return fmt.Errorf("tenant id %q must be lower case letters and digits", t.ID)
+		}
+	}
+	for _, token := range strings.Split(t.Prefix(), ".") {
+		if err := eventbus.ValidateToken(token); err != nil {
+			return fmt.Errorf("tenant %s: invalid subject prefix: %w", t.ID, err)
+		}
+	}
+	for i, key := range t.Keys {
+		if len(key) < 16 {
+			return fmt.Errorf("tenant %s: keys[%d] must be at least 16 characters", t.ID, i)
+		}
+	}
+	for i, account := range t.Accounts {
+		if sqx.NewExchange(account) == sqx.ExchangeUnknown {
+			return fmt.Errorf("tenant %s: accounts[%d]: unknown exchange %q", t.ID, i, account)
+		}
+	}
+	if err := t.Limits.Validate(); err != nil {
+		return fmt.Errorf("tenant %s: %w", t.ID, err)
+	}
+	return nil
+}
+
+// Prefix returns the subject prefix of the tenant
+func (t *Tenant) Prefix() string {
+	if t.SubjectPrefix == "" {
+		return "tenant." + t.ID
+	}
+	return t.SubjectPrefix
+}
+
+// Subject returns subject within the namespace of the tenant. A nil tenant,
+// a deployment without tenants, uses subjects as they are.
+func (t *Tenant) Subject(subject string) string {
+	if t == nil {
+		return subject
+	}
+	return t.Prefix() + "." + subject
+}
+
+// Owns reports whether subject is within the namespace of the tenant
+func (t *Tenant) Owns(subject string) bool {
+	if t == nil {
+		return true
+	}
+	return strings.HasPrefix(subject, t.Prefix()+".")
+}
+
+// CheckOrder checks an order is placed on an account of the tenant and
+// within its limits. A nil tenant accepts every order.
+func (t *Tenant) CheckOrder(intent sqx.OrderIntent) error {
+	if t == nil {
+		return nil
+	}
+	allowed := false
+	for _, account := range t.Accounts {
+		if sqx.NewExchange(account) == intent.Exchange {
+			allowed = true
+			break
+		}
+	}
+	if !allowed {
+		return fmt.Errorf("%w: tenant %s has no %s account", ErrRejected, t.ID, intent.Exchange)
+	}
+	l := t.Limits
+	if l.MaxOrderQuantity > 0 && intent.Quantity > l.MaxOrderQuantity {
+		return fmt.Errorf("%w: quantity %g above the limit of %g", ErrRejected, intent.Quantity, l.MaxOrderQuantity)
+	}
+	if l.MaxOrderNotional > 0 {
+		if intent.Price <= 0 {
+			return fmt.Errorf("%w: orders without a price are not allowed under a notional limit", ErrRejected)
+		}
+		if notional := intent.Price * intent.Quantity; notional > l.MaxOrderNotional {
+			return fmt.Errorf("%w: notional %g above the limit of %g", ErrRejected, notional, l.MaxOrderNotional)
+		}
+	}
+	return nil
+}
+
+// Registry holds the tenants of a deployment. It is read only once created,
+// so it is safe for concurrent use.
+type Registry struct {
+	tenants map[string]*Tenant
+	keys    map[[sha256.Size]byte]*Tenant // Keyed by the hash of the API keys
+}
+
+// NewRegistry validates tenants and indexes them by id and API key. Ids,
+// keys and subject prefixes must be unique, and no prefix may contain
+// another.
+func NewRegistry(tenants []Tenant) (*Registry, error) {
+	r := &Registry{
+		tenants: make(map[string]*Tenant, len(tenants)),
+		keys:    make(map[[sha256.Size]byte]*Tenant),
+	}
+	for i := range tenants {
+		t := &tenants[i]
+		if err := t.Validate(); err != nil {
+			return nil, err
+		}
+		if _, ok := r.tenants[t.ID]; ok {
+			return nil, fmt.Errorf("duplicate tenant %s", t.ID)
+		}
+		for _, other := range r.tenants {
+			a, b := t.Prefix()+".", other.Prefix()+"."
+			if strings.HasPrefix(a, b) || strings.HasPrefix(b, a) {
+				return nil, fmt.Errorf("tenants %s and %s have overlapping subject prefixes", t.ID, other.ID)
+			}
+		}
+		for _, key := range t.Keys {
+			hash := sha256.Sum256([]byte(key))
+			if _, ok := r.keys[hash]; ok {
+				return nil, fmt.Errorf("tenant %s: API key already used", t.ID)
+			}
+			r.keys[hash] = t
+		}
+		r.tenants[t.ID] = t
+	}
+	return r, nil
+}
+
+// Get returns a tenant by id. A nil registry, a deployment without tenants,
+// has none.
+func (r *Registry) Get(id string) (*Tenant, bool) {
+	if r == nil {
+		return nil, false
+	}
+	t, ok := r.tenants[id]
+	return t, ok
+}
+
+// Authenticate returns the tenant of an API key. Keys are compared by hash,
+// so the lookup takes the same time whichever part of a key is wrong.
+func (r *Registry) Authenticate(key string) (*Tenant, bool) {
+	if key == "" {
+		return nil, false
+	}
+	t, ok := r.keys[sha256.Sum256([]byte(key))]
+	return t, ok
+}
+
+// Tenants returns every tenant ordered by id
+func (r *Registry) Tenants() []*Tenant {
+	if r == nil {
+		return nil
+	}
+	list := make([]*Tenant, 0, len(r.tenants))
+	for _, t := range r.tenants {
+		list = append(list, t)
+	}
+	sort.Slice(list, func(i, j int) bool { return list[i].ID < list[j].ID })
+	return list
+}
@@ -0,0 +1,91 @@
+package tenant
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/BullionBear/sequex/internal/model/sqx"
+)
+
+func TestNewRegistry(t *testing.T) {
+	acme := Tenant{ID: "acme", Keys: []string{"acme-key-0123456789"}, Accounts: []string{"BINANCE"}}
+	for _, c := range []struct {
+		name    string
+		tenants []Tenant
+		err     string
+	}{
+		{"valid", []Tenant{acme, {ID: "globex", Keys: []string{"globex-key-0123456789"}}}, ""},
+		{"upper case id", []Tenant{{ID: "Acme"}}, "lower case"},
+		{"short key", []Tenant{{ID: "acme", Keys: []string{"short"}}}, "at least 16"},
+		{"unknown account", []Tenant{{ID: "acme", Accounts: []string{"NYSE"}}}, "unknown exchange"},
+		{"invalid prefix", []Tenant{{ID: "acme", SubjectPrefix: "tenant.*"}}, "subject prefix"},
+		{"duplicate id", []Tenant{acme, {ID: "acme"}}, "duplicate"},
+		{"shared key", []Tenant{acme, {ID: "globex", Keys: acme.Keys}}, "already used"},
+		{"nested prefix", []Tenant{{ID: "acme", SubjectPrefix: "teams"}, {ID: "globex", SubjectPrefix: "teams.globex"}}, "overlapping"},
+	} {
+		_, err := NewRegistry(c.tenants)
+		if c.err == "" && err != nil || c.err != "" && (err == nil || !strings.Contains(err.Error(), c.err)) {
+			t.Errorf("%s: got %v, want %q", c.name, err, c.err)
+		}
+	}
+}
+
+func TestAuthenticate(t *testing.T) {
+	r, err := NewRegistry([]Tenant{
+		{ID: "globex", Keys: []string{"globex-key-0123456789"}},
+		{ID: "acme", Keys: []string{"acme-key-0123456789", "acme-key-rotated-01"}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if tenant, ok := r.Authenticate("acme-key-rotated-01"); !ok || tenant.ID != "acme" {
+		t.Errorf("authenticate: %+v, %v", tenant, ok)
+	}
+	for _, key := range []string{"", "acme-key-012345678", "unknown-key-0123456789"} {
+		if _, ok := r.Authenticate(key); ok {
+			t.Errorf("key %q accepted", key)
+		}
+	}
+	if list := r.Tenants(); len(list) != 2 || list[0].ID != "acme" {
+		t.Errorf("tenants %+v", list)
+	}
+}
+
+func TestSubject(t *testing.T) {
+	acme := &Tenant{ID: "acme"}
+	if got := acme.Subject("diag.master"); got != "tenant.acme.diag.master" {
+		t.Errorf("subject %s", got)
+	}
+	if !acme.Owns("tenant.acme.fix.orders") || acme.Owns("tenant.acmex.fix.orders") || acme.Owns("fix.orders") {
+		t.Error("owns outside the prefix")
+	}
+	var none *Tenant
+	if none.Subject("fix.orders") != "fix.orders" || !none.Owns("fix.orders") {
+		t.Error("a nil tenant must use subjects as they are")
+	}
+}
+
+func TestCheckOrder(t *testing.T) {
+	acme := &Tenant{ID: "acme", Accounts: []string{"BINANCE"}, Limits: Limits{MaxOrderQuantity: 2, MaxOrderNotional: 50000}}
+	for _, c := range []struct {
+		name   string
+		intent sqx.OrderIntent
+		ok     bool
+	}{
+		{"within limits", sqx.OrderIntent{Exchange: sqx.ExchangeBinance, Price: 20000, Quantity: 1}, true},
+		{"other account", sqx.OrderIntent{Exchange: sqx.ExchangeBybit, Price: 20000, Quantity: 1}, false},
+		{"quantity", sqx.OrderIntent{Exchange: sqx.ExchangeBinance, Price: 1, Quantity: 3}, false},
+		{"notional", sqx.OrderIntent{Exchange: sqx.ExchangeBinance, Price: 40000, Quantity: 2}, false},
+		{"market", sqx.OrderIntent{Exchange: sqx.ExchangeBinance, Quantity: 1}, false},
+	} {
+		err := acme.CheckOrder(c.intent)
+		if c.ok && err != nil || !c.ok && !errors.Is(err, ErrRejected) {
+			t.Errorf("%s: %v", c.name, err)
+		}
+	}
+	var none *Tenant
+	if err := none.CheckOrder(sqx.OrderIntent{Quantity: 1e9}); err != nil {
+		t.Errorf("nil tenant: %v", err)
+	}
+}
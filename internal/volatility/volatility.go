@@ -0,0 +1,248 @@
+// Package volatility keeps the realized volatility of symbols over rolling
+// windows of kline returns and classifies their regime, so risk limits and
+// strategies can adjust their parameters to the market they trade in.
+package volatility
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"sort"
+	"sync"
+	"time"
+)
+
+// ErrNotEnoughData is returned until the shortest window is full
+var ErrNotEnoughData = errors.New("not enough data")
+
+// VolRegime classifies the current volatility against its longer run level
+type VolRegime string
+
+const (
+	VolRegimeUnknown VolRegime = "UNKNOWN" // Until the longest window is full
+	VolRegimeLow     VolRegime = "LOW"
+	VolRegimeNormal  VolRegime = "NORMAL"
+	VolRegimeHigh    VolRegime = "HIGH"
+)
+
+// TrendRegime classifies how directional the price moves
+type TrendRegime string
+
+const (
+	TrendRegimeTrending TrendRegime = "TRENDING"
+	TrendRegimeRanging  TrendRegime = "RANGING"
+)
+
+// Options configures a tracker
+type Options struct {
+	Interval       time.Duration // Kline interval, 1m when 0
+	Windows        []int         // Returns each volatility is computed over, 30, 240 and 1440 when empty
+	LowRatio       float64       // Short to long volatility ratio below which the regime is low, 0.75 when 0
+	HighRatio      float64       // Short to long volatility ratio above which the regime is high, 1.5 when 0
+	TrendThreshold float64       // Efficiency ratio from which prices trend, 0.3 when 0
+}
+
+// Validate validates the options
+func (o Options) Validate() error {
+	if o.Interval < 0 {
+		return fmt.Errorf("interval cannot be negative")
+	}
+	seen := make(map[int]bool)
+	for _, w := range o.Windows {
+		if w < 2 {
+			return fmt.Errorf("windows must span at least 2 returns, got %d", w)
+		}
+		if seen[w] {
+			return fmt.Errorf("duplicate window %d", w)
+		}
+		seen[w] = true
+	}
+	if len(o.Windows) == 1 {
+		return fmt.Errorf("at least 2 windows are required to classify the regime")
+	}
+	if o.LowRatio < 0 || o.HighRatio < 0 || o.TrendThreshold < 0 || o.TrendThreshold > 1 {
+		return fmt.Errorf("ratios must be positive and the trend threshold within [0, 1]")
+	}
+	if o.LowRatio > 0 && o.HighRatio > 0 && o.LowRatio >= o.HighRatio {
+		return fmt.Errorf("low ratio must be below high ratio")
+	}
+	return nil
+}
+
+// Estimate is the realized volatility over a window, annualized over a year
+// of continuous trading
+type Estimate struct {
+	Window     int     `json:"window"`     // Returns
+	Volatility float64 `json:"volatility"` // e.g. 0.45 for 45%
+}
+
+// Snapshot is the volatility and regime of a symbol after a kline closed
+type Snapshot struct {
+	Symbol          string      `json:"symbol"`
+	IntervalMs      int64       `json:"interval_ms"`
+	Time            time.Time   `json:"time"`      // Open time of the last kline
+	Estimates       []Estimate  `json:"estimates"` // Shortest window first, full windows only
+	VolRegime       VolRegime   `json:"vol_regime"`
+	VolRatio        float64     `json:"vol_ratio"` // Shortest to longest window volatility, 0 until both are full
+	TrendRegime     TrendRegime `json:"trend_regime"`
+	EfficiencyRatio float64     `json:"efficiency_ratio"` // Net to total move over the shortest window, from 0 ranging to 1 trending
+	Changed         bool        `json:"changed"`          // Either regime changed with this kline
+}
+
+type series struct {
+	lastOpen  int64     // Open time of the last kline, milliseconds
+	lastClose float64   // Of the last kline
+	returns   []float64 // Log return of each kline following the previous one, oldest first
+	snapshot  *Snapshot // Nil until the shortest window is full
+}
+
+// Tracker keeps the returns of the longest window per symbol. It is safe for
+// concurrent use.
+type Tracker struct {
+	opts Options
+
+	mu     sync.Mutex
+	series map[string]*series
+}
+
+// New creates a tracker
+func New(opts Options) (*Tracker, error) {
+	if err := opts.Validate(); err != nil {
+		return nil, err
+	}
+	if opts.Interval == 0 {
+		opts.Interval = time.Minute
+	}
+	if len(opts.Windows) == 0 {
+		opts.Windows = []int{30, 240, 1440}
+	}
+	opts.Windows = append([]int(nil), opts.Windows...)
+	sort.Ints(opts.Windows)
+	if opts.LowRatio == 0 {
+		opts.LowRatio = 0.75
+	}
+	if opts.HighRatio == 0 {
+		opts.HighRatio = 1.5
+	}
+	if opts.LowRatio >= opts.HighRatio {
+		return nil, fmt.Errorf("low ratio %g must be below high ratio %g", opts.LowRatio, opts.HighRatio)
+	}
+	if opts.TrendThreshold == 0 {
+		opts.TrendThreshold = 0.3
+	}
+	return &Tracker{opts: opts, series: make(map[string]*series)}, nil
+}
+
+// OnKline records the close of a closed kline and returns the snapshot of
+// the symbol once the shortest window is full. Klines older than the last
+// one of the symbol are ignored, and a gap between klines restarts the
+// windows, returns across it spanning more than one interval.
+func (t *Tracker) OnKline(symbol string, openTime int64, close float64) (Snapshot, bool) {
+	if close <= 0 || math.IsNaN(close) || math.IsInf(close, 0) {
+		return Snapshot{}, false
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	s, ok := t.series[symbol]
+	if !ok {
+		s = &series{}
+		t.series[symbol] = s
+	}
+	if s.lastOpen != 0 && openTime <= s.lastOpen {
+		return Snapshot{}, false
+	}
+	switch {
+	case s.lastOpen == 0:
+	case openTime-s.lastOpen == t.opts.Interval.Milliseconds():
+		s.returns = append(s.returns, math.Log(close/s.lastClose))
+		if longest := t.opts.Windows[len(t.opts.Windows)-1]; len(s.returns) > longest {
+			s.returns = append(s.returns[:0], s.returns[len(s.returns)-longest:]...)
+		}
+	default:
+		s.returns = s.returns[:0]
+	}
+	s.lastOpen, s.lastClose = openTime, close
+
+	snapshot, ok := t.compute(symbol, s)
+	if !ok {
+		return Snapshot{}, false
+	}
+	if s.snapshot != nil {
+		snapshot.Changed = snapshot.VolRegime != s.snapshot.VolRegime || snapshot.TrendRegime != s.snapshot.TrendRegime
+	}
+	s.snapshot = &snapshot
+	return snapshot, true
+}
+
+// compute estimates the volatility of every full window and classifies the
+// regime
+func (t *Tracker) compute(symbol string, s *series) (Snapshot, bool) {
+	if len(s.returns) < t.opts.Windows[0] {
+		return Snapshot{}, false
+	}
+	annualize := math.Sqrt(float64(365*24*time.Hour) / float64(t.opts.Interval))
+	snapshot := Snapshot{
+		Symbol:     symbol,
+		IntervalMs: t.opts.Interval.Milliseconds(),
+		Time:       time.UnixMilli(s.lastOpen).UTC(),
+		VolRegime:  VolRegimeUnknown,
+	}
+	for _, window := range t.opts.Windows {
+		if len(s.returns) < window {
+			break
+		}
+		var sumSquares float64
+		for _, r := range s.returns[len(s.returns)-window:] {
+			sumSquares += r * r
+		}
+		snapshot.Estimates = append(snapshot.Estimates, Estimate{
+			Window:     window,
+			Volatility: math.Sqrt(sumSquares/float64(window)) * annualize,
+		})
+	}
+
+	if len(snapshot.Estimates) == len(t.opts.Windows) {
+		short, long := snapshot.Estimates[0].Volatility, snapshot.Estimates[len(snapshot.Estimates)-1].Volatility
+		if long > 0 {
+			snapshot.VolRatio = short / long
+			switch {
+			case snapshot.VolRatio < t.opts.LowRatio:
+				snapshot.VolRegime = VolRegimeLow
+			case snapshot.VolRatio > t.opts.HighRatio:
+				snapshot.VolRegime = VolRegimeHigh
+			default:
+				snapshot.VolRegime = VolRegimeNormal
+			}
+		}
+	}
+
+	var net, total float64
+	for _, r := range s.returns[len(s.returns)-t.opts.Windows[0]:] {
+		net += r
+		total += math.Abs(r)
+	}
+	snapshot.TrendRegime = TrendRegimeRanging
+	if total > 0 {
+		snapshot.EfficiencyRatio = math.Abs(net) / total
+		if snapshot.EfficiencyRatio >= t.opts.TrendThreshold {
+			snapshot.TrendRegime = TrendRegimeTrending
+		}
+	}
+	return snapshot, true
+}
+
+// Snapshot returns the last snapshot of a symbol
+func (t *Tracker) Snapshot(symbol string) (Snapshot, error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	s, ok := t.series[symbol]
+	if !ok || s.snapshot == nil {
+		return Snapshot{}, fmt.Errorf("%w: %s", ErrNotEnoughData, symbol)
+	}
+	return *s.snapshot, nil
+}
+
+// Window returns the longest window, the returns kept per symbol
+func (t *Tracker) Window() int {
+	return t.opts.Windows[len(t.opts.Windows)-1]
+}
@@ -0,0 +1,79 @@
+package volatility
+
+import (
+	"math"
+	"testing"
+	"time"
+)
+
+const minute = int64(60000)
+
+// feed records the closes following the log returns, one minute apart from
+// openTime, and returns the last snapshot
+func feed(t *Tracker, symbol string, openTime int64, close float64, returns ...float64) (Snapshot, bool) {
+	snapshot, ok := t.OnKline(symbol, openTime, close)
+	for i, r := range returns {
+		close *= math.Exp(r)
+		snapshot, ok = t.OnKline(symbol, openTime+int64(i+1)*minute, close)
+	}
+	return snapshot, ok
+}
+
+func TestTracker(t *testing.T) {
+	tr, err := New(Options{Windows: []int{16, 4}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var quiet []float64
+	for i := 0; i < 12; i++ {
+		quiet = append(quiet, 0.001*float64(1-2*(i%2)))
+	}
+
+	snapshot, ok := feed(tr, "BTC-USDT", minute, 100, quiet...)
+	if !ok || snapshot.VolRegime != VolRegimeUnknown || snapshot.TrendRegime != TrendRegimeRanging || len(snapshot.Estimates) != 1 {
+		t.Fatalf("snapshot before the longest window = %+v, %v", snapshot, ok)
+	}
+	annualized := 0.001 * math.Sqrt(float64(365*24*time.Hour/time.Minute))
+	if math.Abs(snapshot.Estimates[0].Volatility-annualized) > 1e-9 {
+		t.Errorf("volatility = %g, want %g", snapshot.Estimates[0].Volatility, annualized)
+	}
+
+	// A breakout
+	last := snapshot.Time.UnixMilli()
+	close := 100.0 // The quiet returns cancel out
+	snapshot, ok = feed(tr, "BTC-USDT", last+minute, close*math.Exp(0.01), 0.01, 0.01, 0.01)
+	if !ok || snapshot.VolRegime != VolRegimeHigh || snapshot.TrendRegime != TrendRegimeTrending || !snapshot.Changed {
+		t.Errorf("snapshot after a breakout = %+v, %v", snapshot, ok)
+	}
+	if len(snapshot.Estimates) != 2 || snapshot.Estimates[0].Window != 4 || math.Abs(snapshot.EfficiencyRatio-1) > 1e-9 {
+		t.Errorf("estimates = %+v, efficiency ratio = %g", snapshot.Estimates, snapshot.EfficiencyRatio)
+	}
+	if got, err := tr.Snapshot("BTC-USDT"); err != nil || got.Time != snapshot.Time {
+		t.Errorf("Snapshot() = %+v, %v", got, err)
+	}
+
+	// A gap restarts the windows
+	if _, ok := tr.OnKline("BTC-USDT", snapshot.Time.UnixMilli()+10*minute, 120); ok {
+		t.Error("OnKline() after a gap returned a snapshot")
+	}
+	if _, err := tr.Snapshot("ETH-USDT"); err == nil {
+		t.Error("Snapshot() of an unknown symbol succeeded")
+	}
+}
+
+func TestOptions_Validate(t *testing.T) {
+	for _, opts := range []Options{
+		{Windows: []int{30}},
+		{Windows: []int{30, 30}},
+		{Windows: []int{1, 30}},
+		{LowRatio: 2, HighRatio: 1},
+		{TrendThreshold: 1.5},
+	} {
+		if _, err := New(opts); err == nil {
+			t.Errorf("New(%+v) succeeded", opts)
+		}
+	}
+	if _, err := New(Options{LowRatio: 2}); err == nil {
+		t.Error("New() with a low ratio above the default high ratio succeeded")
+	}
+}
@@ -0,0 +1,136 @@
+package withdrawal
+
+import (
+	"bufio"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"sync"
+	"time"
+)
+
+// ErrTampered is returned when the audit log does not verify
+var ErrTampered = errors.New("audit log tampered")
+
+// Entry is a record of the audit log. Each entry carries the hash of the
+// previous one, so an entry altered or removed breaks the chain.
+type Entry struct {
+	Seq        int64      `json:"seq"`
+	At         time.Time  `json:"at"`
+	Action     string     `json:"action"`
+	By         string     `json:"by,omitempty"`
+	Reason     string     `json:"reason,omitempty"`
+	Withdrawal Withdrawal `json:"withdrawal"` // State after the action
+	Prev       string     `json:"prev"`       // Hash of the previous entry, empty for the first
+	Hash       string     `json:"hash"`       // Of the entry without its hash
+}
+
+// digest returns the hash of the entry without its hash
+func (e Entry) digest() (string, error) {
+	e.Hash = ""
+	data, err := json.Marshal(e)
+	if err != nil {
+		return "", err
+	}
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:]), nil
+}
+
+// AuditLog is an append only, hash chained JSON lines file. Entries are
+// written before the state they record changes, so the log is never behind.
+type AuditLog struct {
+	mu   sync.Mutex
+	file *os.File
+	seq  int64
+	last string
+}
+
+// OpenAuditLog opens the log at path, calling replay with each entry once
+// the chain is verified
+func OpenAuditLog(path string, replay func(Entry)) (*AuditLog, error) {
+	if path == "" {
+		return nil, errors.New("audit log path is required")
+	}
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_RDWR|os.O_APPEND, 0o600)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open audit log %s: %w", path, err)
+	}
+	log := &AuditLog{file: f}
+	entries, err := log.verify(f)
+	if err != nil {
+		f.Close()
+		return nil, fmt.Errorf("audit log %s: %w", path, err)
+	}
+	for _, e := range entries {
+		replay(e)
+	}
+	return log, nil
+}
+
+// verify reads the entries of r, checking their sequence and chain. Unlike
+// journals, a truncated last line is not dropped: it may be an entry being
+// removed.
+func (l *AuditLog) verify(r io.Reader) ([]Entry, error) {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	var entries []Entry
+	for scanner.Scan() {
+		var e Entry
+		if err := json.Unmarshal(scanner.Bytes(), &e); err != nil {
+			return nil, fmt.Errorf("%w: entry %d: %v", ErrTampered, l.seq+1, err)
+		}
+		digest, err := e.digest()
+		if err != nil {
+			return nil, err
+		}
+		if e.Seq != l.seq+1 || e.Prev != l.last || e.Hash != digest {
+			return nil, fmt.Errorf("%w: entry %d does not chain", ErrTampered, l.seq+1)
+		}
+		l.seq, l.last = e.Seq, e.Hash
+		entries = append(entries, e)
+	}
+	return entries, scanner.Err()
+}
+
+// Append chains and writes an entry, returning it as written
+func (l *AuditLog) Append(e Entry) (Entry, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.file == nil {
+		return e, errors.New("audit log closed")
+	}
+	e.Seq, e.Prev = l.seq+1, l.last
+	digest, err := e.digest()
+	if err != nil {
+		return e, err
+	}
+	e.Hash = digest
+	data, err := json.Marshal(e)
+	if err != nil {
+		return e, err
+	}
+	if _, err := l.file.Write(append(data, '\n')); err != nil {
+		return e, fmt.Errorf("failed to write audit log: %w", err)
+	}
+	if err := l.file.Sync(); err != nil {
+		return e, fmt.Errorf("failed to sync audit log: %w", err)
+	}
+	l.seq, l.last = e.Seq, e.Hash
+	return e, nil
+}
+
+// Close closes the file of the log
+func (l *AuditLog) Close() error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.file == nil {
+		return nil
+	}
+	err := l.file.Close()
+	l.file = nil
+	return err
+}
@@ -0,0 +1,45 @@
+package withdrawal
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+
+	"github.com/BullionBear/sequex/pkg/exchange/binance"
+)
+
+// BinanceClient is the subset of the Binance spot client withdrawals are
+// sent with
+type BinanceClient interface {
+	Withdraw(ctx context.Context, req binance.WithdrawRequest) (binance.Response[binance.WithdrawResponse], error)
+}
+
+// BinanceSender sends withdrawals from a Binance account. The id of a
+// withdrawal is its client id, so Binance refuses to send it twice.
+type BinanceSender struct {
+	client BinanceClient
+}
+
+// NewBinanceSender creates a sender from a Binance client
+func NewBinanceSender(client BinanceClient) *BinanceSender {
+	return &BinanceSender{client: client}
+}
+
+func (s *BinanceSender) Withdraw(ctx context.Context, w Withdrawal) (string, error) {
+	resp, err := s.client.Withdraw(ctx, binance.WithdrawRequest{
+		Coin:            w.Asset,
+		Network:         w.Network,
+		Address:         w.Address,
+		AddressTag:      w.Tag,
+		Amount:          strconv.FormatFloat(w.Amount, 'f', -1, 64),
+		WithdrawOrderId: w.ID,
+		Name:            w.Label,
+	})
+	if err != nil {
+		return "", err
+	}
+	if resp.Data == nil {
+		return "", fmt.Errorf("binance error: no withdrawal id")
+	}
+	return resp.Data.Id, nil
+}
@@ -0,0 +1,135 @@
+package withdrawal
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/BullionBear/sequex/internal/model/protobuf"
+	"github.com/BullionBear/sequex/pkg/eventbus"
+	"github.com/nats-io/nats.go"
+)
+
+// DefaultSubject is the prefix the withdrawal RPCs are served under when
+// none is configured
+const DefaultSubject = "withdrawal"
+
+// server serves the WithdrawalService of protobuf/withdrawal.proto
+type server struct {
+	e *Engine
+}
+
+func (s server) Submit(_ context.Context, req *protobuf.SubmitWithdrawalRequest) (*protobuf.Withdrawal, error) {
+	var w Withdrawal
+	if err := w.FromProtobuf(req.Withdrawal); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalid, err)
+	}
+	w, err := s.e.Submit(w, req.Signature)
+	return w.ToProtobuf(), err
+}
+
+func (s server) Approve(ctx context.Context, req *protobuf.ApproveWithdrawalRequest) (*protobuf.Withdrawal, error) {
+	w, err := s.e.Approve(ctx, req.Id, req.By, req.Signature)
+	return w.ToProtobuf(), err
+}
+
+func (s server) Reject(_ context.Context, req *protobuf.RejectWithdrawalRequest) (*protobuf.Withdrawal, error) {
+	w, err := s.e.Reject(req.Id, req.By, req.Reason, req.Signature)
+	return w.ToProtobuf(), err
+}
+
+func (s server) List(context.Context, *protobuf.ListWithdrawalsRequest) (*protobuf.WithdrawalList, error) {
+	return s.e.list(), nil
+}
+
+// Serve answers the withdrawal RPCs of e under prefix
+func Serve(bus *eventbus.Bus, prefix string, e *Engine) ([]*nats.Subscription, error) {
+	return protobuf.RegisterWithdrawalServiceServer(bus, prefix, server{e: e})
+}
+
+// ToProtobuf returns the withdrawal as sent over the bus
+func (w *Withdrawal) ToProtobuf() *protobuf.Withdrawal {
+	pb := &protobuf.Withdrawal{
+		Id:          w.ID,
+		Asset:       w.Asset,
+		Network:     w.Network,
+		Address:     w.Address,
+		Tag:         w.Tag,
+		Amount:      w.Amount,
+		Label:       w.Label,
+		Status:      string(w.Status),
+		RequestedBy: w.RequestedBy,
+		Reason:      w.Reason,
+		ExchangeId:  w.ExchangeID,
+		Error:       w.Error,
+		CreatedAt:   millis(w.CreatedAt),
+		UpdatedAt:   millis(w.UpdatedAt),
+	}
+	for _, a := range w.Approvals {
+		pb.Approvals = append(pb.Approvals, &protobuf.WithdrawalApproval{By: a.By, At: millis(a.At)})
+	}
+	return pb
+}
+
+// FromProtobuf sets the withdrawal from one sent over the bus
+func (w *Withdrawal) FromProtobuf(pb *protobuf.Withdrawal) error {
+	if pb == nil {
+		return fmt.Errorf("missing withdrawal")
+	}
+	*w = Withdrawal{
+		ID:          pb.Id,
+		Asset:       pb.Asset,
+		Network:     pb.Network,
+		Address:     pb.Address,
+		Tag:         pb.Tag,
+		Amount:      pb.Amount,
+		Label:       pb.Label,
+		Status:      Status(pb.Status),
+		RequestedBy: pb.RequestedBy,
+		Reason:      pb.Reason,
+		ExchangeID:  pb.ExchangeId,
+		Error:       pb.Error,
+		CreatedAt:   fromMillis(pb.CreatedAt),
+		UpdatedAt:   fromMillis(pb.UpdatedAt),
+	}
+	for _, a := range pb.Approvals {
+		w.Approvals = append(w.Approvals, Approval{By: a.By, At: fromMillis(a.At)})
+	}
+	return nil
+}
+
+// millis returns a time in Unix milliseconds, 0 for the zero time
+func millis(t time.Time) int64 {
+	if t.IsZero() {
+		return 0
+	}
+	return t.UnixMilli()
+}
+
+func fromMillis(ms int64) time.Time {
+	if ms == 0 {
+		return time.Time{}
+	}
+	return time.UnixMilli(ms)
+}
+
+// list returns the state of the engine
+func (e *Engine) list() *protobuf.WithdrawalList {
+	policy, used := e.Policy()
+	list := &protobuf.WithdrawalList{Approvals: int32(policy.Approvals)}
+	for _, w := range e.List() {
+		list.Withdrawals = append(list.Withdrawals, w.ToProtobuf())
+	}
+	for _, a := range policy.Whitelist {
+		list.Whitelist = append(list.Whitelist, &protobuf.WithdrawalAddress{Label: a.Label, Asset: a.Asset, Network: a.Network, Address: a.Address, Tag: a.Tag})
+	}
+	for asset, limit := range policy.DailyLimits {
+		list.Limits = append(list.Limits, &protobuf.WithdrawalLimit{Asset: asset, Limit: limit, Used: used[asset]})
+	}
+	sort.Slice(list.Limits, func(i, j int) bool { return list.Limits[i].Asset < list.Limits[j].Asset })
+	for _, a := range policy.Approvers {
+		list.Approvers = append(list.Approvers, a.Name)
+	}
+	return list
+}
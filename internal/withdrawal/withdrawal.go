@@ -0,0 +1,446 @@
+// Package withdrawal gates withdrawals behind a policy: destinations must be
+// whitelisted, amounts stay within daily limits per asset, and a withdrawal
+// is only sent once enough approvers have signed it, the requester's
+// signature counting as the first. Every step is recorded in a hash chained
+// audit log before it takes effect.
+package withdrawal
+
+import (
+	"context"
+	"crypto/ed25519"
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// DefaultTTL is how long a withdrawal waits for its approvals when no TTL
+// is configured
+const DefaultTTL = 24 * time.Hour
+
+// MinApprovals is the least number of signatures a withdrawal needs: the
+// requester and another approver
+const MinApprovals = 2
+
+var (
+	ErrNotWhitelisted = errors.New("address not whitelisted")
+	ErrOverLimit      = errors.New("over daily limit")
+	ErrUnauthorized   = errors.New("unauthorized")
+	ErrNotFound       = errors.New("withdrawal not found")
+	ErrNotPending     = errors.New("withdrawal not pending")
+	ErrInvalid        = errors.New("invalid withdrawal")
+)
+
+// Status is the state of a withdrawal
+type Status string
+
+const (
+	StatusPending  Status = "pending"  // Waiting for approvals
+	StatusSending  Status = "sending"  // Approved, being sent
+	StatusSent     Status = "sent"     // Accepted by the exchange
+	StatusFailed   Status = "failed"   // Refused by the exchange
+	StatusRejected Status = "rejected" // Rejected by an approver
+	StatusExpired  Status = "expired"  // Not approved in time
+)
+
+// Actions signed by approvers and recorded in the audit log
+const (
+	ActionSubmit  = "submit"
+	ActionApprove = "approve"
+	ActionReject  = "reject"
+	ActionSend    = "send"
+	ActionFail    = "fail"
+	ActionExpire  = "expire"
+)
+
+// Address is a whitelisted destination
+type Address struct {
+	Label   string `json:"label"`
+	Asset   string `json:"asset"`
+	Network string `json:"network"`
+	Address string `json:"address"`
+	Tag     string `json:"tag,omitempty"` // Memo of the networks needing one
+}
+
+// Approver may request and approve withdrawals, signing with the private
+// key of Key
+type Approver struct {
+	Name string            `json:"name"`
+	Key  ed25519.PublicKey `json:"key"`
+}
+
+// Policy is what withdrawals must comply with
+type Policy struct {
+	Whitelist   []Address
+	DailyLimits map[string]float64 // By asset, per UTC day. Assets without a limit cannot be withdrawn.
+	Approvers   []Approver
+	Approvals   int           // Distinct signatures needed, the requester's included, at least MinApprovals
+	TTL         time.Duration // Pending withdrawals expire after, DefaultTTL when 0
+}
+
+// Validate checks the policy can approve withdrawals
+func (p *Policy) Validate() error {
+	if p.Approvals < MinApprovals {
+		return fmt.Errorf("approvals must be at least %d", MinApprovals)
+	}
+	names := make(map[string]bool, len(p.Approvers))
+	for _, a := range p.Approvers {
+		if a.Name == "" || len(a.Key) != ed25519.PublicKeySize {
+			return fmt.Errorf("approver %q: a name and an ed25519 public key are required", a.Name)
+		}
+		if names[a.Name] {
+			return fmt.Errorf("approver %q declared twice", a.Name)
+		}
+		names[a.Name] = true
+	}
+	if len(p.Approvers) < p.Approvals {
+		return fmt.Errorf("%d approvals needed from %d approvers", p.Approvals, len(p.Approvers))
+	}
+	for _, a := range p.Whitelist {
+		if a.Asset == "" || a.Network == "" || a.Address == "" {
+			return fmt.Errorf("whitelisted address %q: asset, network and address are required", a.Label)
+		}
+	}
+	for asset, limit := range p.DailyLimits {
+		if limit < 0 {
+			return fmt.Errorf("daily limit of %s cannot be negative", asset)
+		}
+	}
+	if p.TTL < 0 {
+		return errors.New("ttl cannot be negative")
+	}
+	return nil
+}
+
+// Withdrawal is a request to send an asset to an address
+type Withdrawal struct {
+	ID          string     `json:"id"` // Chosen by the requester, signed with the request
+	Asset       string     `json:"asset"`
+	Network     string     `json:"network"`
+	Address     string     `json:"address"`
+	Tag         string     `json:"tag,omitempty"`
+	Amount      float64    `json:"amount"`
+	Label       string     `json:"label,omitempty"` // Of the whitelisted address
+	Status      Status     `json:"status"`
+	RequestedBy string     `json:"requested_by"`
+	Reason      string     `json:"reason,omitempty"`
+	Approvals   []Approval `json:"approvals"` // Requester first
+	ExchangeID  string     `json:"exchange_id,omitempty"`
+	Error       string     `json:"error,omitempty"`
+	CreatedAt   time.Time  `json:"created_at"`
+	UpdatedAt   time.Time  `json:"updated_at"`
+}
+
+// Approval is the signature of an approver
+type Approval struct {
+	By string    `json:"by"`
+	At time.Time `json:"at"`
+}
+
+// Payload returns the message approvers sign to take action on a
+// withdrawal. It covers what is sent, so a signature cannot approve
+// another destination or amount.
+func Payload(action string, w Withdrawal) []byte {
+	return []byte(strings.Join([]string{
+		"sequex-withdrawal-v1", action, w.ID, w.Asset, w.Network, w.Address, w.Tag,
+		strconv.FormatFloat(w.Amount, 'f', -1, 64),
+	}, "\n"))
+}
+
+// Sign signs an action on a withdrawal
+func Sign(key ed25519.PrivateKey, action string, w Withdrawal) []byte {
+	return ed25519.Sign(key, Payload(action, w))
+}
+
+// Sender sends approved withdrawals, returning the id the exchange gave them
+type Sender interface {
+	Withdraw(ctx context.Context, w Withdrawal) (string, error)
+}
+
+// Engine enforces a policy on withdrawals. It is safe for concurrent use.
+type Engine struct {
+	policy Policy
+	sender Sender
+	log    *AuditLog
+	now    func() time.Time
+
+	mu          sync.Mutex
+	withdrawals map[string]*Withdrawal
+	onEntry     []func(Entry)
+}
+
+// Open creates an engine, restoring the withdrawals from the audit log at
+// path. The log must verify: a tampered log stops the engine from starting.
+func Open(policy Policy, sender Sender, path string) (*Engine, error) {
+	if err := policy.Validate(); err != nil {
+		return nil, err
+	}
+	if policy.TTL == 0 {
+		policy.TTL = DefaultTTL
+	}
+	e := &Engine{policy: policy, sender: sender, now: time.Now, withdrawals: make(map[string]*Withdrawal)}
+	log, err := OpenAuditLog(path, func(entry Entry) {
+		w := entry.Withdrawal
+		e.withdrawals[w.ID] = &w
+	})
+	if err != nil {
+		return nil, err
+	}
+	e.log = log
+	return e, nil
+}
+
+// Close closes the audit log
+func (e *Engine) Close() error {
+	return e.log.Close()
+}
+
+// OnEntry calls fn with every entry written to the audit log
+func (e *Engine) OnEntry(fn func(Entry)) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.onEntry = append(e.onEntry, fn)
+}
+
+// record writes an entry of w to the audit log, then applies w. Called with
+// the lock held.
+func (e *Engine) record(action, by, reason string, w Withdrawal) (Withdrawal, error) {
+	w.UpdatedAt = e.now().UTC()
+	entry, err := e.log.Append(Entry{At: w.UpdatedAt, Action: action, By: by, Reason: reason, Withdrawal: w})
+	if err != nil {
+		return w, err
+	}
+	e.withdrawals[w.ID] = &w
+	for _, fn := range e.onEntry {
+		fn(entry)
+	}
+	return w, nil
+}
+
+// verify checks name is an approver who signed action on w
+func (e *Engine) verify(name, action string, w Withdrawal, signature []byte) error {
+	for _, a := range e.policy.Approvers {
+		if a.Name == name {
+			if !ed25519.Verify(a.Key, Payload(action, w), signature) {
+				return fmt.Errorf("%w: invalid signature of %s", ErrUnauthorized, name)
+			}
+			return nil
+		}
+	}
+	return fmt.Errorf("%w: %s is not an approver", ErrUnauthorized, name)
+}
+
+// whitelisted returns the whitelisted address of w
+func (e *Engine) whitelisted(w Withdrawal) (Address, bool) {
+	for _, a := range e.policy.Whitelist {
+		if a.Asset == w.Asset && a.Network == w.Network && a.Address == w.Address && a.Tag == w.Tag {
+			return a, true
+		}
+	}
+	return Address{}, false
+}
+
+// used returns the amount of asset withdrawn on the UTC day of now: sent or
+// being sent that day, or pending when pending count
+func (e *Engine) used(asset string, now time.Time, pending bool) float64 {
+	day := now.UTC().Truncate(24 * time.Hour)
+	var total float64
+	for _, w := range e.withdrawals {
+		if w.Asset != asset {
+			continue
+		}
+		switch w.Status {
+		case StatusSending, StatusSent:
+			if !w.UpdatedAt.UTC().Truncate(24 * time.Hour).Equal(day) {
+				continue
+			}
+		case StatusPending:
+			if !pending {
+				continue
+			}
+		default:
+			continue
+		}
+		total += w.Amount
+	}
+	return total
+}
+
+// checkLimit checks amount of asset fits in the daily limit
+func (e *Engine) checkLimit(asset string, amount float64, now time.Time, pending bool) error {
+	limit, ok := e.policy.DailyLimits[asset]
+	if !ok {
+		return fmt.Errorf("%w: no daily limit for %s", ErrOverLimit, asset)
+	}
+	if used := e.used(asset, now, pending); used+amount > limit {
+		return fmt.Errorf("%w: %g %s withdrawn or pending today, limit %g", ErrOverLimit, used, asset, limit)
+	}
+	return nil
+}
+
+// Submit requests a withdrawal signed by its requester, an approver
+func (e *Engine) Submit(w Withdrawal, signature []byte) (Withdrawal, error) {
+	if w.ID == "" || w.Asset == "" || w.Network == "" || w.Address == "" || !(w.Amount > 0) {
+		return w, fmt.Errorf("%w: id, asset, network, address and a positive amount are required", ErrInvalid)
+	}
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.expire()
+	if _, exists := e.withdrawals[w.ID]; exists {
+		return w, fmt.Errorf("%w: id %s already used", ErrInvalid, w.ID)
+	}
+	if err := e.verify(w.RequestedBy, ActionSubmit, w, signature); err != nil {
+		return w, err
+	}
+	address, ok := e.whitelisted(w)
+	if !ok {
+		return w, fmt.Errorf("%w: %s %s on %s", ErrNotWhitelisted, w.Asset, w.Address, w.Network)
+	}
+	now := e.now()
+	if err := e.checkLimit(w.Asset, w.Amount, now, true); err != nil {
+		return w, err
+	}
+	w.Label = address.Label
+	w.Status = StatusPending
+	w.Approvals = []Approval{{By: w.RequestedBy, At: now.UTC()}}
+	w.ExchangeID, w.Error = "", ""
+	w.CreatedAt = now.UTC()
+	return e.record(ActionSubmit, w.RequestedBy, w.Reason, w)
+}
+
+// Approve adds the signature of an approver to a pending withdrawal, sending
+// it once it has enough. The lock is released while the exchange is called,
+// the withdrawal being marked sending so it is neither approved nor rejected
+// again meanwhile.
+func (e *Engine) Approve(ctx context.Context, id, by string, signature []byte) (Withdrawal, error) {
+	w, send, err := e.approve(id, by, signature)
+	if err != nil || !send {
+		return w, err
+	}
+	exchangeID, err := e.sender.Withdraw(ctx, w)
+
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if err != nil {
+		w.Status, w.Error = StatusFailed, err.Error()
+		return e.record(ActionFail, "", err.Error(), w)
+	}
+	w.Status, w.ExchangeID = StatusSent, exchangeID
+	return e.record(ActionSend, "", "", w)
+}
+
+// approve records the signature of an approver, reporting whether the
+// withdrawal has enough to be sent
+func (e *Engine) approve(id, by string, signature []byte) (Withdrawal, bool, error) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.expire()
+	w, err := e.pending(id)
+	if err != nil {
+		return w, false, err
+	}
+	if err := e.verify(by, ActionApprove, w, signature); err != nil {
+		return w, false, err
+	}
+	for _, a := range w.Approvals {
+		if a.By == by {
+			return w, false, fmt.Errorf("%w: %s already signed %s", ErrUnauthorized, by, id)
+		}
+	}
+	w.Approvals = append(append([]Approval(nil), w.Approvals...), Approval{By: by, At: e.now().UTC()})
+	if len(w.Approvals) < e.policy.Approvals {
+		w, err = e.record(ActionApprove, by, "", w)
+		return w, false, err
+	}
+
+	// The limit is checked again against what was sent since the request
+	if err := e.checkLimit(w.Asset, w.Amount, e.now(), false); err != nil {
+		return w, false, err
+	}
+	w.Status = StatusSending
+	w, err = e.record(ActionApprove, by, "", w)
+	return w, err == nil, err
+}
+
+// Reject rejects a pending withdrawal, on the signature of any approver
+func (e *Engine) Reject(id, by, reason string, signature []byte) (Withdrawal, error) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.expire()
+	w, err := e.pending(id)
+	if err != nil {
+		return w, err
+	}
+	if err := e.verify(by, ActionReject, w, signature); err != nil {
+		return w, err
+	}
+	w.Status = StatusRejected
+	return e.record(ActionReject, by, reason, w)
+}
+
+// pending returns a copy of a pending withdrawal
+func (e *Engine) pending(id string) (Withdrawal, error) {
+	w, ok := e.withdrawals[id]
+	if !ok {
+		return Withdrawal{ID: id}, fmt.Errorf("%w: %s", ErrNotFound, id)
+	}
+	if w.Status != StatusPending {
+		return *w, fmt.Errorf("%w: %s is %s", ErrNotPending, id, w.Status)
+	}
+	return *w, nil
+}
+
+// expire expires the pending withdrawals past their TTL. Called with the
+// lock held.
+func (e *Engine) expire() {
+	now := e.now()
+	for _, w := range e.withdrawals {
+		if w.Status == StatusPending && now.Sub(w.CreatedAt) > e.policy.TTL {
+			expired := *w
+			expired.Status = StatusExpired
+			// Left pending when the log cannot be written, expired next time
+			e.record(ActionExpire, "", "not approved in time", expired)
+		}
+	}
+}
+
+// Get returns a withdrawal
+func (e *Engine) Get(id string) (Withdrawal, error) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.expire()
+	w, ok := e.withdrawals[id]
+	if !ok {
+		return Withdrawal{ID: id}, fmt.Errorf("%w: %s", ErrNotFound, id)
+	}
+	return *w, nil
+}
+
+// List returns the withdrawals, newest first
+func (e *Engine) List() []Withdrawal {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.expire()
+	list := make([]Withdrawal, 0, len(e.withdrawals))
+	for _, w := range e.withdrawals {
+		list = append(list, *w)
+	}
+	sort.Slice(list, func(i, j int) bool { return list[i].CreatedAt.After(list[j].CreatedAt) })
+	return list
+}
+
+// Policy returns the whitelist and limits withdrawals are checked against,
+// with the daily usage of each asset
+func (e *Engine) Policy() (Policy, map[string]float64) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.expire()
+	used := make(map[string]float64, len(e.policy.DailyLimits))
+	for asset := range e.policy.DailyLimits {
+		used[asset] = e.used(asset, e.now(), true)
+	}
+	return e.policy, used
+}
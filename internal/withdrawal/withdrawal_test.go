@@ -0,0 +1,284 @@
+package withdrawal
+
+import (
+	"bytes"
+	"context"
+	"crypto/ed25519"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/BullionBear/sequex/internal/model/protobuf"
+)
+
+// fakeSender records the withdrawals sent
+type fakeSender struct {
+	sent []Withdrawal
+	err  error
+}
+
+func (s *fakeSender) Withdraw(_ context.Context, w Withdrawal) (string, error) {
+	if s.err != nil {
+		return "", s.err
+	}
+	s.sent = append(s.sent, w)
+	return "x-" + w.ID, nil
+}
+
+var (
+	treasury    = Address{Label: "cold", Asset: "USDT", Network: "ETH", Address: "0xcold"}
+	treasuryBTC = Address{Label: "cold btc", Asset: "BTC", Network: "BTC", Address: "bc1cold"}
+)
+
+// approvers returns a policy needing two of alice, bob and carol, with
+// their private keys
+func approvers(t *testing.T) (Policy, map[string]ed25519.PrivateKey) {
+	t.Helper()
+	policy := Policy{Whitelist: []Address{treasury, treasuryBTC}, DailyLimits: map[string]float64{"USDT": 1000}, Approvals: 2}
+	keys := make(map[string]ed25519.PrivateKey)
+	for _, name := range []string{"alice", "bob", "carol"} {
+		public, private, err := ed25519.GenerateKey(nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		policy.Approvers = append(policy.Approvers, Approver{Name: name, Key: public})
+		keys[name] = private
+	}
+	return policy, keys
+}
+
+func request(id string, amount float64) Withdrawal {
+	return Withdrawal{ID: id, Asset: "USDT", Network: "ETH", Address: "0xcold", Amount: amount, RequestedBy: "alice", Reason: "rebalance"}
+}
+
+func TestTwoPersonApproval(t *testing.T) {
+	policy, keys := approvers(t)
+	sender := &fakeSender{}
+	e, err := Open(policy, sender, filepath.Join(t.TempDir(), "audit.jsonl"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer e.Close()
+
+	w := request("1", 600)
+	if _, err := e.Submit(w, Sign(keys["bob"], ActionSubmit, w)); !errors.Is(err, ErrUnauthorized) {
+		t.Errorf("submitted on the signature of another approver: %v", err)
+	}
+	if w, err = e.Submit(w, Sign(keys["alice"], ActionSubmit, w)); err != nil {
+		t.Fatal(err)
+	}
+	if w.Status != StatusPending || w.Label != "cold" {
+		t.Errorf("submitted %+v", w)
+	}
+	if _, err := e.Approve(context.Background(), "1", "alice", Sign(keys["alice"], ActionApprove, w)); !errors.Is(err, ErrUnauthorized) {
+		t.Errorf("requester approved their own withdrawal: %v", err)
+	}
+	other := w
+	other.Address = "0xother"
+	if _, err := e.Approve(context.Background(), "1", "bob", Sign(keys["bob"], ActionApprove, other)); !errors.Is(err, ErrUnauthorized) {
+		t.Errorf("approved on the signature of another destination: %v", err)
+	}
+	if len(sender.sent) != 0 {
+		t.Fatalf("sent before approval: %+v", sender.sent)
+	}
+
+	if w, err = e.Approve(context.Background(), "1", "bob", Sign(keys["bob"], ActionApprove, w)); err != nil {
+		t.Fatal(err)
+	}
+	if w.Status != StatusSent || w.ExchangeID != "x-1" || len(w.Approvals) != 2 || len(sender.sent) != 1 {
+		t.Errorf("approved %+v, sent %+v", w, sender.sent)
+	}
+	if _, err := e.Approve(context.Background(), "1", "carol", Sign(keys["carol"], ActionApprove, w)); !errors.Is(err, ErrNotPending) {
+		t.Errorf("approved twice: %v", err)
+	}
+}
+
+// blockingSender holds the withdrawals sent until released
+type blockingSender struct {
+	sending chan Withdrawal
+	release chan struct{}
+}
+
+func (s *blockingSender) Withdraw(_ context.Context, w Withdrawal) (string, error) {
+	s.sending <- w
+	<-s.release
+	return "x-" + w.ID, nil
+}
+
+func TestApproveSendsWithoutLock(t *testing.T) {
+	policy, keys := approvers(t)
+	sender := &blockingSender{sending: make(chan Withdrawal), release: make(chan struct{})}
+	e, err := Open(policy, sender, filepath.Join(t.TempDir(), "audit.jsonl"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer e.Close()
+	w := request("1", 600)
+	if w, err = e.Submit(w, Sign(keys["alice"], ActionSubmit, w)); err != nil {
+		t.Fatal(err)
+	}
+
+	approved := make(chan error, 1)
+	go func() {
+		_, err := e.Approve(context.Background(), "1", "bob", Sign(keys["bob"], ActionApprove, w))
+		approved <- err
+	}()
+	<-sender.sending
+	// The engine answers while the exchange is called
+	if got, err := e.Get("1"); err != nil || got.Status != StatusSending {
+		t.Errorf("while sending: %+v, %v", got, err)
+	}
+	if _, err := e.Approve(context.Background(), "1", "carol", Sign(keys["carol"], ActionApprove, w)); !errors.Is(err, ErrNotPending) {
+		t.Errorf("approved while sending: %v", err)
+	}
+	if _, err := e.Reject("1", "carol", "late", Sign(keys["carol"], ActionReject, w)); !errors.Is(err, ErrNotPending) {
+		t.Errorf("rejected while sending: %v", err)
+	}
+	close(sender.release)
+	if err := <-approved; err != nil {
+		t.Fatal(err)
+	}
+	if got, _ := e.Get("1"); got.Status != StatusSent || got.ExchangeID != "x-1" {
+		t.Errorf("sent %+v", got)
+	}
+}
+
+func TestPolicy(t *testing.T) {
+	policy, keys := approvers(t)
+	e, err := Open(policy, &fakeSender{}, filepath.Join(t.TempDir(), "audit.jsonl"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer e.Close()
+	now := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	e.now = func() time.Time { return now }
+	submit := func(w Withdrawal) (Withdrawal, error) {
+		return e.Submit(w, Sign(keys[w.RequestedBy], ActionSubmit, w))
+	}
+
+	unknown := request("1", 10)
+	unknown.Address = "0xunknown"
+	if _, err := submit(unknown); !errors.Is(err, ErrNotWhitelisted) {
+		t.Errorf("withdrew to an address not whitelisted: %v", err)
+	}
+	btc := request("2", 1)
+	btc.Asset, btc.Network, btc.Address = "BTC", "BTC", "bc1cold"
+	if _, err := submit(btc); !errors.Is(err, ErrOverLimit) {
+		t.Errorf("withdrew an asset without a limit: %v", err)
+	}
+
+	// Pending withdrawals count against the limit
+	if _, err := submit(request("3", 700)); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := submit(request("4", 400)); !errors.Is(err, ErrOverLimit) {
+		t.Errorf("withdrew over the daily limit: %v", err)
+	}
+	w, err := e.Reject("3", "carol", "wrong amount", Sign(keys["carol"], ActionReject, request("3", 700)))
+	if err != nil || w.Status != StatusRejected {
+		t.Fatalf("rejected %+v: %v", w, err)
+	}
+	if _, err := submit(request("4", 400)); err != nil {
+		t.Errorf("rejected withdrawal counted against the limit: %v", err)
+	}
+	if _, err := submit(request("4", 400)); !errors.Is(err, ErrInvalid) {
+		t.Errorf("reused an id: %v", err)
+	}
+
+	// Not approved in time
+	now = now.Add(DefaultTTL + time.Minute)
+	if w, err := e.Get("4"); err != nil || w.Status != StatusExpired {
+		t.Errorf("expected expired, got %+v: %v", w, err)
+	}
+}
+
+func TestAuditLog(t *testing.T) {
+	policy, keys := approvers(t)
+	path := filepath.Join(t.TempDir(), "audit.jsonl")
+	e, err := Open(policy, &fakeSender{err: errors.New("insufficient balance")}, path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var entries []Entry
+	e.OnEntry(func(entry Entry) { entries = append(entries, entry) })
+	w := request("1", 100)
+	if w, err = e.Submit(w, Sign(keys["alice"], ActionSubmit, w)); err != nil {
+		t.Fatal(err)
+	}
+	if w, err = e.Approve(context.Background(), "1", "bob", Sign(keys["bob"], ActionApprove, w)); err != nil {
+		t.Fatal(err)
+	}
+	if w.Status != StatusFailed || w.Error != "insufficient balance" {
+		t.Errorf("expected failed, got %+v", w)
+	}
+	e.Close()
+	if len(entries) != 3 || entries[0].Action != ActionSubmit || entries[1].Action != ActionApprove || entries[2].Action != ActionFail {
+		t.Fatalf("unexpected entries %+v", entries)
+	}
+
+	// Restored from the log
+	e, err = Open(policy, &fakeSender{}, path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if w, err := e.Get("1"); err != nil || w.Status != StatusFailed {
+		t.Errorf("restored %+v: %v", w, err)
+	}
+	e.Close()
+
+	// An entry altered breaks the chain
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, bytes.Replace(data, []byte(`"amount":100`), []byte(`"amount":10`), 1), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := Open(policy, &fakeSender{}, path); !errors.Is(err, ErrTampered) {
+		t.Errorf("opened a tampered log: %v", err)
+	}
+}
+
+func TestServer(t *testing.T) {
+	policy, keys := approvers(t)
+	e, err := Open(policy, &fakeSender{}, filepath.Join(t.TempDir(), "audit.jsonl"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer e.Close()
+	srv := server{e: e}
+	ctx := context.Background()
+
+	w := request("1", 600)
+	if _, err := srv.Submit(ctx, &protobuf.SubmitWithdrawalRequest{Signature: Sign(keys["alice"], ActionSubmit, w)}); !errors.Is(err, ErrInvalid) {
+		t.Errorf("submitted without a withdrawal: %v", err)
+	}
+	if _, err := srv.Submit(ctx, &protobuf.SubmitWithdrawalRequest{Withdrawal: w.ToProtobuf(), Signature: Sign(keys["alice"], ActionSubmit, w)}); err != nil {
+		t.Fatal(err)
+	}
+	list, err := srv.List(ctx, &protobuf.ListWithdrawalsRequest{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(list.Withdrawals) != 1 || list.Approvals != 2 || len(list.Approvers) != 3 || len(list.Whitelist) != 2 || len(list.Limits) != 1 {
+		t.Fatalf("list = %v", list)
+	}
+
+	// Approvers sign the withdrawal as listed, read back from protobuf
+	var listed Withdrawal
+	if err := listed.FromProtobuf(list.Withdrawals[0]); err != nil {
+		t.Fatal(err)
+	}
+	if listed.CreatedAt.IsZero() || len(listed.Approvals) != 1 || listed.Approvals[0].By != "alice" {
+		t.Errorf("listed %+v", listed)
+	}
+	sent, err := srv.Approve(ctx, &protobuf.ApproveWithdrawalRequest{Id: "1", By: "bob", Signature: Sign(keys["bob"], ActionApprove, listed)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if Status(sent.Status) != StatusSent || sent.ExchangeId != "x-1" {
+		t.Errorf("approved %v", sent)
+	}
+}
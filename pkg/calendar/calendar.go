@@ -0,0 +1,115 @@
+// Package calendar answers time questions that depend on where and how a
+// venue trades: when perpetuals fund, where a trading day is cut, when a
+// venue that closes, such as CME, is shut, and which days are holidays.
+// Every helper works in the time zone of its calendar and follows its
+// daylight saving changes.
+package calendar
+
+import (
+	"fmt"
+	"time"
+	_ "time/tzdata" // Time zones resolve in images without zoneinfo
+)
+
+// Clock is a time of day
+type Clock struct {
+	Hour   int
+	Minute int
+}
+
+// ParseClock reads a HH:MM time of day
+func ParseClock(s string) (Clock, error) {
+	at, err := time.Parse("15:04", s)
+	if err != nil {
+		return Clock{}, fmt.Errorf("invalid time %q, expected HH:MM", s)
+	}
+	return Clock{Hour: at.Hour(), Minute: at.Minute()}, nil
+}
+
+// String formats the clock as HH:MM
+func (c Clock) String() string {
+	return fmt.Sprintf("%02d:%02d", c.Hour, c.Minute)
+}
+
+// on returns the clock on the day of date, offset by days, in loc
+func (c Clock) on(date time.Time, days int, loc *time.Location) time.Time {
+	return time.Date(date.Year(), date.Month(), date.Day()+days, c.Hour, c.Minute, 0, 0, loc)
+}
+
+// LoadLocation reads an IANA time zone, UTC when empty
+func LoadLocation(zone string) (*time.Location, error) {
+	if zone == "" {
+		return time.UTC, nil
+	}
+	loc, err := time.LoadLocation(zone)
+	if err != nil {
+		return nil, fmt.Errorf("invalid time zone %q: %w", zone, err)
+	}
+	return loc, nil
+}
+
+// Settlement is the daily cut-off trading days end at, such as 00:00 UTC for
+// crypto venues or 17:00 New York for FX. NAV snapshots, daily PnL and
+// reports are taken at it.
+type Settlement struct {
+	Clock
+	Location *time.Location // UTC when nil
+}
+
+// UTCMidnight cuts days at 00:00 UTC
+var UTCMidnight = Settlement{}
+
+// ParseSettlement reads a HH:MM cut-off in an IANA time zone, UTC when empty
+func ParseSettlement(clock, zone string) (Settlement, error) {
+	c, err := ParseClock(clock)
+	if err != nil {
+		return Settlement{}, err
+	}
+	loc, err := LoadLocation(zone)
+	if err != nil {
+		return Settlement{}, err
+	}
+	return Settlement{Clock: c, Location: loc}, nil
+}
+
+func (s Settlement) location() *time.Location {
+	if s.Location == nil {
+		return time.UTC
+	}
+	return s.Location
+}
+
+// Start returns the cut-off the trading day containing t started at
+func (s Settlement) Start(t time.Time) time.Time {
+	t = t.In(s.location())
+	start := s.on(t, 0, s.location())
+	if start.After(t) {
+		start = s.on(t, -1, s.location())
+	}
+	return start
+}
+
+// Next returns the first cut-off strictly after t
+func (s Settlement) Next(t time.Time) time.Time {
+	return s.on(s.Start(t), 1, s.location())
+}
+
+// Day returns the date and bounds of the trading day containing t. The date
+// is the calendar day most of the trading day falls on: the day it starts
+// for cut-offs before noon, the day it ends otherwise, so that a 17:00 New
+// York cut-off labels a day by the date it settles on.
+func (s Settlement) Day(t time.Time) (string, time.Time, time.Time) {
+	from := s.Start(t)
+	to := s.on(from, 1, s.location())
+	date := from
+	if s.Hour >= 12 {
+		date = to
+	}
+	return date.Format(time.DateOnly), from, to
+}
+
+// Previous returns the date and bounds of the last trading day completed
+// by t, the one a report sent at t covers
+func (s Settlement) Previous(t time.Time) (string, time.Time, time.Time) {
+	return s.Day(s.Start(t).Add(-time.Nanosecond))
+}
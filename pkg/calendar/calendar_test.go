@@ -0,0 +1,150 @@
+package calendar
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestSettlement(t *testing.T) {
+	utc := time.Date(2026, 10, 15, 13, 0, 0, 0, time.UTC)
+	date, from, to := UTCMidnight.Day(utc)
+	if date != "2026-10-15" || !from.Equal(time.Date(2026, 10, 15, 0, 0, 0, 0, time.UTC)) || !to.Equal(from.Add(24*time.Hour)) {
+		t.Errorf("UTC day = %s %v %v", date, from, to)
+	}
+	if date, _, _ := UTCMidnight.Previous(from); date != "2026-10-14" {
+		t.Errorf("previous day at the cut-off = %s", date)
+	}
+
+	// FX days end at 17:00 New York and are named after the day they end on
+	ny, err := ParseSettlement("17:00", "America/New_York")
+	if err != nil {
+		t.Fatal(err)
+	}
+	friday := time.Date(2026, 10, 16, 20, 0, 0, 0, time.UTC) // 16:00 in New York
+	date, from, to = ny.Day(friday)
+	if date != "2026-10-16" || !from.Equal(time.Date(2026, 10, 15, 21, 0, 0, 0, time.UTC)) || !to.Equal(time.Date(2026, 10, 16, 21, 0, 0, 0, time.UTC)) {
+		t.Errorf("New York day = %s %v %v", date, from, to)
+	}
+
+	// The day daylight saving ends lasts 25 hours
+	sunday := time.Date(2026, 11, 1, 12, 0, 0, 0, time.UTC)
+	if _, from, to := ny.Day(sunday); to.Sub(from) != 25*time.Hour {
+		t.Errorf("day of the change lasts %s", to.Sub(from))
+	}
+	if next := ny.Next(sunday); !next.Equal(time.Date(2026, 11, 1, 22, 0, 0, 0, time.UTC)) {
+		t.Errorf("next cut-off = %v", next)
+	}
+
+	for _, clock := range []string{"24:00", "5pm"} {
+		if _, err := ParseSettlement(clock, ""); err == nil {
+			t.Errorf("%q accepted", clock)
+		}
+	}
+	if _, err := ParseSettlement("00:00", "Mars/Olympus"); err == nil {
+		t.Error("unknown time zone accepted")
+	}
+}
+
+func TestFunding(t *testing.T) {
+	at := time.Date(2026, 10, 15, 9, 30, 0, 0, time.UTC)
+	if prev, next := Funding8h.Previous(at), Funding8h.Next(at); prev.Hour() != 8 || next.Hour() != 16 {
+		t.Errorf("previous %v, next %v", prev, next)
+	}
+	if next := Funding8h.Next(time.Date(2026, 10, 15, 16, 0, 0, 0, time.UTC)); next.Day() != 16 || next.Hour() != 0 {
+		t.Errorf("next after a funding = %v", next)
+	}
+	times := Funding8h.Between(time.Date(2026, 10, 15, 0, 0, 0, 0, time.UTC), time.Date(2026, 10, 16, 0, 0, 0, 0, time.UTC))
+	if len(times) != 3 || times[0].Hour() != 0 || times[2].Hour() != 16 {
+		t.Errorf("fundings of a day = %v", times)
+	}
+	if Funding8h.PerYear() != 1095 {
+		t.Errorf("fundings per year = %v", Funding8h.PerYear())
+	}
+	if _, err := NewFunding(7 * time.Hour); err == nil {
+		t.Error("interval not dividing a day accepted")
+	}
+}
+
+func TestCalendar(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "cme.json")
+	data := `{"timezone": "America/Chicago", "weekend": ["Saturday", "sun"], "holidays": {"2026-12-25": "Christmas Day"}}`
+	if err := os.WriteFile(path, []byte(data), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	c, err := LoadCalendar(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	christmas := time.Date(2026, 12, 25, 18, 0, 0, 0, time.UTC)
+	if name, ok := c.Holiday(christmas); !ok || name != "Christmas Day" || c.IsTradingDay(christmas) {
+		t.Errorf("Christmas = %q %v", name, ok)
+	}
+	next, err := c.NextTradingDay(christmas)
+	if err != nil || next.Format(time.DateOnly) != "2026-12-28" {
+		t.Errorf("next trading day = %v, %v", next, err)
+	}
+	days := c.TradingDays(time.Date(2026, 12, 21, 12, 0, 0, 0, time.UTC), time.Date(2026, 12, 28, 6, 0, 0, 0, time.UTC))
+	if len(days) != 4 || days[3] != "2026-12-24" {
+		t.Errorf("trading days = %v", days)
+	}
+
+	if _, err := NewCalendar(nil, time.Saturday, time.Sunday, time.Monday, time.Tuesday, time.Wednesday, time.Thursday, time.Friday).NextTradingDay(christmas); err == nil {
+		t.Error("calendar without trading days found one")
+	}
+	if err := os.WriteFile(path, []byte(`{"holidays": {"25/12/2026": "Christmas Day"}}`), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := LoadCalendar(path); err == nil {
+		t.Error("invalid holiday date accepted")
+	}
+}
+
+func TestCMESession(t *testing.T) {
+	cme, err := CME()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := cme.Calendar.AddHoliday("2026-12-25", "Christmas Day"); err != nil {
+		t.Fatal(err)
+	}
+	chicago := cme.Calendar.Location()
+	for _, tc := range []struct {
+		at   time.Time
+		open bool
+	}{
+		{time.Date(2026, 10, 14, 10, 0, 0, 0, chicago), true},   // Wednesday
+		{time.Date(2026, 10, 14, 16, 30, 0, 0, chicago), false}, // Daily break
+		{time.Date(2026, 10, 14, 17, 0, 0, 0, chicago), true},   // Thursday session
+		{time.Date(2026, 10, 16, 16, 0, 0, 0, chicago), false},  // Friday close
+		{time.Date(2026, 10, 17, 12, 0, 0, 0, chicago), false},  // Saturday
+		{time.Date(2026, 10, 18, 17, 30, 0, 0, chicago), true},  // Sunday evening
+		{time.Date(2026, 12, 24, 18, 0, 0, 0, chicago), false},  // Christmas session
+	} {
+		if open := cme.IsOpen(tc.at); open != tc.open {
+			t.Errorf("open at %v = %v", tc.at, open)
+		}
+	}
+
+	// A week from Monday midnight: four daily breaks, then the weekend
+	from := time.Date(2026, 10, 12, 0, 0, 0, 0, chicago)
+	gaps := cme.Gaps(from, from.AddDate(0, 0, 7))
+	if len(gaps) != 5 {
+		t.Fatalf("gaps = %+v", gaps)
+	}
+	for _, gap := range gaps[:4] {
+		if gap.Duration() != time.Hour || gap.From.In(chicago).Hour() != 16 {
+			t.Errorf("daily break = %+v", gap)
+		}
+	}
+	if weekend := gaps[4]; !weekend.From.Equal(time.Date(2026, 10, 16, 16, 0, 0, 0, chicago)) || !weekend.To.Equal(time.Date(2026, 10, 18, 17, 0, 0, 0, chicago)) {
+		t.Errorf("weekend gap = %+v", weekend)
+	}
+
+	// Christmas on a Friday joins the weekend
+	gaps = cme.Gaps(time.Date(2026, 12, 24, 0, 0, 0, 0, chicago), time.Date(2026, 12, 29, 0, 0, 0, 0, chicago))
+	if len(gaps) != 2 || !gaps[0].To.Equal(time.Date(2026, 12, 27, 17, 0, 0, 0, chicago)) || gaps[0].Duration() != 73*time.Hour {
+		t.Errorf("holiday gaps = %+v", gaps)
+	}
+}
@@ -0,0 +1,59 @@
+package calendar
+
+import (
+	"fmt"
+	"time"
+)
+
+// Funding is the schedule perpetual funding payments are made on: every
+// Interval from 00:00 UTC
+type Funding struct {
+	Interval time.Duration
+}
+
+// Funding schedules of the venues, Binance and most others funding every 8
+// hours at 00:00, 08:00 and 16:00 UTC
+var (
+	Funding8h = Funding{Interval: 8 * time.Hour}
+	Funding4h = Funding{Interval: 4 * time.Hour}
+	Funding1h = Funding{Interval: time.Hour}
+)
+
+// NewFunding creates a schedule funding every interval, which must divide a day
+func NewFunding(interval time.Duration) (Funding, error) {
+	if interval < time.Minute || (24*time.Hour)%interval != 0 {
+		return Funding{}, fmt.Errorf("funding interval %s does not divide a day", interval)
+	}
+	return Funding{Interval: interval}, nil
+}
+
+// Previous returns the last funding at or before t
+func (f Funding) Previous(t time.Time) time.Time {
+	step := f.Interval.Milliseconds()
+	ms := t.UnixMilli()
+	ms -= ((ms % step) + step) % step
+	return time.UnixMilli(ms).UTC()
+}
+
+// Next returns the first funding strictly after t
+func (f Funding) Next(t time.Time) time.Time {
+	return f.Previous(t).Add(f.Interval)
+}
+
+// Between returns the fundings within [from, to)
+func (f Funding) Between(from, to time.Time) []time.Time {
+	var times []time.Time
+	at := f.Previous(from)
+	if at.Before(from) {
+		at = at.Add(f.Interval)
+	}
+	for ; at.Before(to); at = at.Add(f.Interval) {
+		times = append(times, at)
+	}
+	return times
+}
+
+// PerYear returns the number of fundings in a year, to annualize a rate
+func (f Funding) PerYear() float64 {
+	return float64(365*24*time.Hour) / float64(f.Interval)
+}
@@ -0,0 +1,149 @@
+package calendar
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Calendar tells trading days from weekends and holidays in a time zone.
+// Crypto venues trade every day, so their calendar is NewCalendar(time.UTC).
+type Calendar struct {
+	location *time.Location
+	weekend  map[time.Weekday]bool
+	holidays map[string]string // Names keyed by YYYY-MM-DD
+}
+
+// NewCalendar creates a calendar without holidays, loc being UTC when nil
+func NewCalendar(loc *time.Location, weekend ...time.Weekday) *Calendar {
+	if loc == nil {
+		loc = time.UTC
+	}
+	c := &Calendar{location: loc, weekend: make(map[time.Weekday]bool), holidays: make(map[string]string)}
+	for _, day := range weekend {
+		c.weekend[day] = true
+	}
+	return c
+}
+
+// calendarFile is the JSON layout of a calendar file:
+//
+//	{"timezone": "America/Chicago", "weekend": ["Saturday", "Sunday"],
+//	 "holidays": {"2026-12-25": "Christmas Day"}}
+type calendarFile struct {
+	Timezone string            `json:"timezone"`
+	Weekend  []string          `json:"weekend"`
+	Holidays map[string]string `json:"holidays"`
+}
+
+// LoadCalendar reads a calendar from a JSON file
+func LoadCalendar(path string) (*Calendar, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read calendar file %s: %w", path, err)
+	}
+	var file calendarFile
+	if err := json.Unmarshal(data, &file); err != nil {
+		return nil, fmt.Errorf("failed to parse calendar file %s: %w", path, err)
+	}
+	loc, err := LoadLocation(file.Timezone)
+	if err != nil {
+		return nil, fmt.Errorf("invalid calendar in %s: %w", path, err)
+	}
+	var weekend []time.Weekday
+	for _, name := range file.Weekend {
+		day, err := parseWeekday(name)
+		if err != nil {
+			return nil, fmt.Errorf("invalid calendar in %s: %w", path, err)
+		}
+		weekend = append(weekend, day)
+	}
+	c := NewCalendar(loc, weekend...)
+	for date, name := range file.Holidays {
+		if err := c.AddHoliday(date, name); err != nil {
+			return nil, fmt.Errorf("invalid calendar in %s: %w", path, err)
+		}
+	}
+	return c, nil
+}
+
+func parseWeekday(name string) (time.Weekday, error) {
+	for day := time.Sunday; day <= time.Saturday; day++ {
+		if strings.EqualFold(name, day.String()) || strings.EqualFold(name, day.String()[:3]) {
+			return day, nil
+		}
+	}
+	return 0, fmt.Errorf("invalid weekday %q", name)
+}
+
+// Location returns the time zone days are cut in
+func (c *Calendar) Location() *time.Location {
+	return c.location
+}
+
+// AddHoliday closes the calendar on a YYYY-MM-DD date
+func (c *Calendar) AddHoliday(date, name string) error {
+	if _, err := time.Parse(time.DateOnly, date); err != nil {
+		return fmt.Errorf("invalid holiday date %q, expected YYYY-MM-DD", date)
+	}
+	c.holidays[date] = name
+	return nil
+}
+
+// Holiday returns the name of the holiday on the day of t
+func (c *Calendar) Holiday(t time.Time) (string, bool) {
+	name, ok := c.holidays[t.In(c.location).Format(time.DateOnly)]
+	return name, ok
+}
+
+// Holidays returns the holiday dates within [from, to), sorted
+func (c *Calendar) Holidays(from, to time.Time) []string {
+	first, last := from.In(c.location).Format(time.DateOnly), to.In(c.location).Format(time.DateOnly)
+	var dates []string
+	for date := range c.holidays {
+		if date >= first && date < last {
+			dates = append(dates, date)
+		}
+	}
+	sort.Strings(dates)
+	return dates
+}
+
+// IsTradingDay reports whether the day of t is neither a weekend day nor a holiday
+func (c *Calendar) IsTradingDay(t time.Time) bool {
+	t = t.In(c.location)
+	if c.weekend[t.Weekday()] {
+		return false
+	}
+	_, holiday := c.holidays[t.Format(time.DateOnly)]
+	return !holiday
+}
+
+// NextTradingDay returns the midnight of the first trading day after the day of t
+func (c *Calendar) NextTradingDay(t time.Time) (time.Time, error) {
+	t = t.In(c.location)
+	// A week of weekends, or a year of holidays, means the calendar never trades
+	for i := 1; i <= 366; i++ {
+		day := time.Date(t.Year(), t.Month(), t.Day()+i, 0, 0, 0, 0, c.location)
+		if c.IsTradingDay(day) {
+			return day, nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("no trading day within a year of %s", t.Format(time.DateOnly))
+}
+
+// TradingDays returns the dates of the trading days from the day of from to
+// the last day starting before to
+func (c *Calendar) TradingDays(from, to time.Time) []string {
+	from, to = from.In(c.location), to.In(c.location)
+	var dates []string
+	for day := time.Date(from.Year(), from.Month(), from.Day(), 0, 0, 0, 0, c.location); day.Before(to); day = time.Date(day.Year(), day.Month(), day.Day()+1, 0, 0, 0, 0, c.location) {
+		if c.IsTradingDay(day) {
+			dates = append(dates, day.Format(time.DateOnly))
+		}
+	}
+	return dates
+}
@@ -0,0 +1,106 @@
+package calendar
+
+import "time"
+
+// Session is the trading hours of a venue that closes, such as CME, whose
+// series have gaps a 24/7 crypto series must be aligned with before they are
+// compared. A trading day runs from Open to Close in the time zone of the
+// calendar; when Open is not before Close, it opens on the previous calendar
+// day, as CME Globex does from 17:00 to 16:00 Chicago time. Days the calendar
+// does not trade on are closed entirely; early closes are not modelled.
+type Session struct {
+	Calendar *Calendar
+	Open     Clock
+	Close    Clock
+}
+
+// Gap is a period a session is closed
+type Gap struct {
+	From time.Time
+	To   time.Time
+}
+
+// Duration returns the length of the gap
+func (g Gap) Duration() time.Duration {
+	return g.To.Sub(g.From)
+}
+
+// CME returns the CME Globex session for equity index and crypto futures:
+// Sunday 17:00 to Friday 16:00 Chicago time with a daily break from 16:00 to
+// 17:00. Exchange holidays are added to its calendar by the caller.
+func CME() (Session, error) {
+	loc, err := LoadLocation("America/Chicago")
+	if err != nil {
+		return Session{}, err
+	}
+	return Session{
+		Calendar: NewCalendar(loc, time.Saturday, time.Sunday),
+		Open:     Clock{Hour: 17},
+		Close:    Clock{Hour: 16},
+	}, nil
+}
+
+func (s Session) overnight() bool {
+	return s.Open.Hour*60+s.Open.Minute >= s.Close.Hour*60+s.Close.Minute
+}
+
+// hours returns the open and close of the trading day of date
+func (s Session) hours(date time.Time) (time.Time, time.Time) {
+	loc := s.Calendar.Location()
+	open := s.Open.on(date, 0, loc)
+	if s.overnight() {
+		open = s.Open.on(date, -1, loc)
+	}
+	return open, s.Close.on(date, 0, loc)
+}
+
+// IsOpen reports whether the session trades at t
+func (s Session) IsOpen(t time.Time) bool {
+	t = t.In(s.Calendar.Location())
+	for _, days := range []int{0, 1} {
+		date := time.Date(t.Year(), t.Month(), t.Day()+days, 0, 0, 0, 0, t.Location())
+		if !s.Calendar.IsTradingDay(date) {
+			continue
+		}
+		if open, close := s.hours(date); !t.Before(open) && t.Before(close) {
+			return true
+		}
+	}
+	return false
+}
+
+// Gaps returns the periods within [from, to) the session is closed, cut to
+// those bounds. The weekend gap of CME runs from Friday 16:00 to Sunday
+// 17:00, longer across holidays.
+func (s Session) Gaps(from, to time.Time) []Gap {
+	loc := s.Calendar.Location()
+	start, end := from.In(loc), to.In(loc)
+	// The trading day of the day after to opens before to when overnight
+	last := time.Date(end.Year(), end.Month(), end.Day()+1, 0, 0, 0, 0, loc)
+	var gaps []Gap
+	at := from // Start of the period not yet covered
+	for date := time.Date(start.Year(), start.Month(), start.Day(), 0, 0, 0, 0, loc); !date.After(last) && at.Before(to); date = time.Date(date.Year(), date.Month(), date.Day()+1, 0, 0, 0, 0, loc) {
+		if !s.Calendar.IsTradingDay(date) {
+			continue
+		}
+		open, close := s.hours(date)
+		if !close.After(at) {
+			continue
+		}
+		if open.After(at) {
+			gaps = append(gaps, Gap{From: at, To: minTime(open, to)})
+		}
+		at = close
+	}
+	if at.Before(to) {
+		gaps = append(gaps, Gap{From: at, To: to})
+	}
+	return gaps
+}
+
+func minTime(a, b time.Time) time.Time {
+	if a.Before(b) {
+		return a
+	}
+	return b
+}
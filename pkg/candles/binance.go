@@ -0,0 +1,134 @@
+package candles
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/BullionBear/sequex/pkg/exchange/binance"
+	"github.com/BullionBear/sequex/pkg/exchange/binanceperp"
+)
+
+// kline is the part of a Binance kline a candle is made of
+type kline struct {
+	openTime                                    int64
+	open, high, low, close, volume, quoteVolume string
+	trades                                      int
+}
+
+// BinanceSource backfills candles from the Binance Spot REST API
+type BinanceSource struct {
+	client *binance.Client
+}
+
+// NewBinanceSource creates a spot source
+func NewBinanceSource(client *binance.Client) *BinanceSource {
+	return &BinanceSource{client: client}
+}
+
+// Name identifies the source in errors
+func (b *BinanceSource) Name() string {
+	return "binance"
+}
+
+// Candles pages through the klines opened in [from, to)
+func (b *BinanceSource) Candles(ctx context.Context, symbol, interval string, from, to int64) ([]Candle, error) {
+	return page(from, to, 1000, func(from int64, limit int) ([]kline, error) {
+		resp, err := b.client.GetKlines(ctx, exchangeSymbol(symbol), interval, from, to-1, "", limit)
+		if err != nil {
+			return nil, err
+		}
+		if resp.Data == nil {
+			return nil, fmt.Errorf("no klines: %s", resp.Message)
+		}
+		klines := make([]kline, len(*resp.Data))
+		for i, k := range *resp.Data {
+			klines[i] = kline{k.OpenTime, k.Open, k.High, k.Low, k.Close, k.Volume, k.QuoteAssetVolume, k.NumberOfTrades}
+		}
+		return klines, nil
+	})
+}
+
+// BinancePerpSource backfills candles from the Binance USDⓈ-M futures REST API
+type BinancePerpSource struct {
+	client *binanceperp.Client
+}
+
+// NewBinancePerpSource creates a USDⓈ-M futures source
+func NewBinancePerpSource(client *binanceperp.Client) *BinancePerpSource {
+	return &BinancePerpSource{client: client}
+}
+
+// Name identifies the source in errors
+func (b *BinancePerpSource) Name() string {
+	return "binanceperp"
+}
+
+// Candles pages through the klines opened in [from, to)
+func (b *BinancePerpSource) Candles(ctx context.Context, symbol, interval string, from, to int64) ([]Candle, error) {
+	return page(from, to, 1500, func(from int64, limit int) ([]kline, error) {
+		resp, err := b.client.GetKlines(ctx, binanceperp.GetKlinesRequest{
+			Symbol:    exchangeSymbol(symbol),
+			Interval:  interval,
+			StartTime: from,
+			EndTime:   to - 1,
+			Limit:     limit,
+		})
+		if err != nil {
+			return nil, err
+		}
+		if resp.Data == nil {
+			return nil, fmt.Errorf("no klines: %s", resp.Message)
+		}
+		klines := make([]kline, len(*resp.Data))
+		for i, k := range *resp.Data {
+			klines[i] = kline{k.OpenTime, k.Open, k.High, k.Low, k.Close, k.Volume, k.QuoteAssetVolume, k.NumberOfTrades}
+		}
+		return klines, nil
+	})
+}
+
+// exchangeSymbol converts BTC-USDT into BTCUSDT
+func exchangeSymbol(symbol string) string {
+	return strings.ToUpper(strings.ReplaceAll(symbol, "-", ""))
+}
+
+// page fetches the klines opened in [from, to), limit at a time, and
+// converts them into candles
+func page(from, to int64, limit int, fetch func(from int64, limit int) ([]kline, error)) ([]Candle, error) {
+	var candles []Candle
+	for from < to {
+		klines, err := fetch(from, limit)
+		if err != nil {
+			return nil, err
+		}
+		for _, k := range klines {
+			c, err := k.candle()
+			if err != nil {
+				return nil, err
+			}
+			candles = append(candles, c)
+		}
+		if len(klines) < limit {
+			break
+		}
+		from = klines[len(klines)-1].openTime + 1
+	}
+	return candles, nil
+}
+
+func (k kline) candle() (Candle, error) {
+	c := Candle{OpenTime: k.openTime, Trades: int64(k.trades)}
+	for _, field := range []struct {
+		dst *float64
+		src string
+	}{{&c.Open, k.open}, {&c.High, k.high}, {&c.Low, k.low}, {&c.Close, k.close}, {&c.Volume, k.volume}, {&c.QuoteVolume, k.quoteVolume}} {
+		v, err := strconv.ParseFloat(field.src, 64)
+		if err != nil {
+			return Candle{}, fmt.Errorf("kline %d: invalid number %q", k.openTime, field.src)
+		}
+		*field.dst = v
+	}
+	return c, nil
+}
@@ -0,0 +1,204 @@
+// Package candles serves OHLCV candles from the cheapest place that has
+// them: klines recorded locally, the JetStream cache, then the REST API of
+// the exchange, and stitches them into one continuous series so strategies
+// do not have to.
+package candles
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/BullionBear/sequex/pkg/logger"
+)
+
+// Candle is a closed kline. Symbols are BASE-QUOTE, e.g. BTC-USDT.
+type Candle struct {
+	OpenTime    int64   `json:"open_time"` // Unix milliseconds
+	Open        float64 `json:"open"`
+	High        float64 `json:"high"`
+	Low         float64 `json:"low"`
+	Close       float64 `json:"close"`
+	Volume      float64 `json:"volume"`
+	QuoteVolume float64 `json:"quote_volume"`
+	Trades      int64   `json:"trades"`
+}
+
+// intervals maps the Binance kline intervals onto their duration. Months
+// have no fixed length and are not supported.
+var intervals = map[string]time.Duration{
+	"1m":  time.Minute,
+	"3m":  3 * time.Minute,
+	"5m":  5 * time.Minute,
+	"15m": 15 * time.Minute,
+	"30m": 30 * time.Minute,
+	"1h":  time.Hour,
+	"2h":  2 * time.Hour,
+	"4h":  4 * time.Hour,
+	"6h":  6 * time.Hour,
+	"8h":  8 * time.Hour,
+	"12h": 12 * time.Hour,
+	"1d":  24 * time.Hour,
+	"3d":  3 * 24 * time.Hour,
+	"1w":  7 * 24 * time.Hour,
+}
+
+// weekOrigin is the first Monday after the Unix epoch: weekly candles open
+// on Mondays, 00:00 UTC
+const weekOrigin = 4 * 24 * int64(time.Hour/time.Millisecond)
+
+// ParseInterval returns the duration of a Binance kline interval, e.g. 1m or 4h
+func ParseInterval(interval string) (time.Duration, error) {
+	d, ok := intervals[interval]
+	if !ok {
+		return 0, fmt.Errorf("unsupported interval %q", interval)
+	}
+	return d, nil
+}
+
+// openTime returns the open time of the candle of interval containing t,
+// in Unix milliseconds
+func openTime(t int64, interval time.Duration) int64 {
+	step := interval.Milliseconds()
+	origin := int64(0)
+	if interval == intervals["1w"] {
+		origin = weekOrigin
+	}
+	offset := (t - origin) % step
+	if offset < 0 {
+		offset += step
+	}
+	return t - offset
+}
+
+// Source provides the candles it has of a symbol with open times in
+// [from, to), Unix milliseconds. It may return part of the range, or
+// candles outside it; the candles it is missing are asked from the next
+// source.
+type Source interface {
+	Name() string
+	Candles(ctx context.Context, symbol, interval string, from, to int64) ([]Candle, error)
+}
+
+// Sink keeps the candles fetched from later sources, so they are served by
+// an earlier one next time
+type Sink interface {
+	Record(symbol, interval string, candles []Candle) error
+}
+
+// Service merges its sources into continuous series
+type Service struct {
+	sources []Source
+	sinks   []Sink
+	now     func() time.Time
+}
+
+// NewService creates a service asking sources in order. Candles fetched
+// from any source but the first are recorded into the sinks, which are
+// usually the sources before it, e.g. a FileStore; a sink is never handed
+// the candles it served itself.
+func NewService(sources []Source, sinks ...Sink) *Service {
+	return &Service{sources: sources, sinks: sinks, now: time.Now}
+}
+
+// GetCandles returns the closed candles of symbol opened in [from, to), one
+// per interval. Each source is only asked for the candles the sources before
+// it are missing. Holes no source could fill, when the market did not trade,
+// are filled with flat candles at the previous close; candles before the
+// first one any source has, such as before a listing, are left out.
+//
+// An error is returned with the candles found when a source failed and
+// holes remain.
+func (s *Service) GetCandles(ctx context.Context, symbol, interval string, from, to time.Time) ([]Candle, error) {
+	step, err := ParseInterval(interval)
+	if err != nil {
+		return nil, err
+	}
+	stepMs := step.Milliseconds()
+	start := openTime(from.UnixMilli(), step)
+	if start < from.UnixMilli() {
+		start += stepMs
+	}
+	// The candle of now is still open
+	end := min(openTime(to.UnixMilli()-1, step)+stepMs, openTime(s.now().UnixMilli(), step))
+	if end <= start {
+		return []Candle{}, nil
+	}
+
+	found := make(map[int64]Candle)
+	var errs []error
+	for i, source := range s.sources {
+		holes := missing(found, start, end, stepMs)
+		if len(holes) == 0 {
+			break
+		}
+		var fetched []Candle
+		for _, hole := range holes {
+			candles, err := source.Candles(ctx, symbol, interval, hole[0], hole[1])
+			if err != nil {
+				errs = append(errs, fmt.Errorf("%s: %w", source.Name(), err))
+				break
+			}
+			for _, c := range candles {
+				if c.OpenTime < start || c.OpenTime >= end || openTime(c.OpenTime, step) != c.OpenTime {
+					continue
+				}
+				if _, ok := found[c.OpenTime]; !ok {
+					found[c.OpenTime] = c
+					fetched = append(fetched, c)
+				}
+			}
+		}
+		if i > 0 && len(fetched) > 0 {
+			sort.Slice(fetched, func(a, b int) bool { return fetched[a].OpenTime < fetched[b].OpenTime })
+			// The candles are served either way, so failing to keep them
+			// does not fail the read
+			for _, sink := range s.sinks {
+				if any(sink) == any(source) {
+					continue
+				}
+				if err := sink.Record(symbol, interval, fetched); err != nil {
+					logger.Log.Warn().Err(err).Str("symbol", symbol).Str("source", source.Name()).Msg("Failed to record candles")
+				}
+			}
+		}
+	}
+
+	series := make([]Candle, 0, len(found))
+	complete := true
+	for t := start; t < end; t += stepMs {
+		c, ok := found[t]
+		switch {
+		case ok:
+			series = append(series, c)
+		case len(series) > 0:
+			complete = false
+			last := series[len(series)-1].Close
+			series = append(series, Candle{OpenTime: t, Open: last, High: last, Low: last, Close: last})
+		default:
+			complete = false
+		}
+	}
+	if !complete && len(errs) > 0 {
+		return series, errors.Join(errs...)
+	}
+	return series, nil
+}
+
+// missing returns the ranges of open times in [start, end) without a candle
+func missing(found map[int64]Candle, start, end, step int64) [][2]int64 {
+	var holes [][2]int64
+	for t := start; t < end; t += step {
+		if _, ok := found[t]; ok {
+			continue
+		}
+		if n := len(holes); n > 0 && holes[n-1][1] == t {
+			holes[n-1][1] = t + step
+		} else {
+			holes = append(holes, [2]int64{t, t + step})
+		}
+	}
+	return holes
+}
@@ -0,0 +1,312 @@
+package candles
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/BullionBear/sequex/pkg/exchange/binance"
+)
+
+var t0 = time.Date(2026, 10, 15, 0, 0, 0, 0, time.UTC)
+
+func minute(i int) int64 {
+	return t0.Add(time.Duration(i) * time.Minute).UnixMilli()
+}
+
+// fakeSource serves the candles of the given minutes and records what it was asked
+type fakeSource struct {
+	name    string
+	minutes map[int]bool
+	asked   [][2]int64
+	err     error
+}
+
+func (f *fakeSource) Name() string { return f.name }
+
+func (f *fakeSource) Candles(_ context.Context, _, _ string, from, to int64) ([]Candle, error) {
+	f.asked = append(f.asked, [2]int64{from, to})
+	if f.err != nil {
+		return nil, f.err
+	}
+	var candles []Candle
+	for i := range f.minutes {
+		if t := minute(i); t >= from && t < to {
+			candles = append(candles, Candle{OpenTime: t, Open: float64(i), Close: float64(i) + 0.5, Volume: 1})
+		}
+	}
+	return candles, nil
+}
+
+type fakeSink struct {
+	recorded []Candle
+}
+
+func (f *fakeSink) Record(_, _ string, candles []Candle) error {
+	f.recorded = append(f.recorded, candles...)
+	return nil
+}
+
+func minutes(from, to int) map[int]bool {
+	m := make(map[int]bool)
+	for i := from; i < to; i++ {
+		m[i] = true
+	}
+	return m
+}
+
+func TestGetCandlesMergesSources(t *testing.T) {
+	local := &fakeSource{name: "local", minutes: minutes(0, 4)}
+	for i := 6; i < 8; i++ {
+		local.minutes[i] = true
+	}
+	rest := &fakeSource{name: "rest", minutes: minutes(0, 10)}
+	sink := &fakeSink{}
+	s := NewService([]Source{local, rest}, sink)
+	s.now = func() time.Time { return t0.Add(8*time.Minute + 30*time.Second) }
+
+	series, err := s.GetCandles(context.Background(), "BTC-USDT", "1m", t0.Add(30*time.Second), t0.Add(time.Hour))
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Starts at the first whole minute and stops before the open candle
+	if len(series) != 7 || series[0].OpenTime != minute(1) || series[6].OpenTime != minute(7) {
+		t.Fatalf("series = %+v", series)
+	}
+	if len(rest.asked) != 1 || rest.asked[0] != [2]int64{minute(4), minute(6)} {
+		t.Errorf("rest asked for %v, want only the hole", rest.asked)
+	}
+	if len(sink.recorded) != 2 || sink.recorded[0].OpenTime != minute(4) {
+		t.Errorf("recorded = %+v", sink.recorded)
+	}
+}
+
+func TestGetCandlesFillsHoles(t *testing.T) {
+	listed := &fakeSource{name: "rest", minutes: minutes(3, 10)}
+	delete(listed.minutes, 5)
+	s := NewService([]Source{listed})
+	s.now = func() time.Time { return t0.Add(time.Hour) }
+
+	series, err := s.GetCandles(context.Background(), "NEW-USDT", "1m", t0, t0.Add(10*time.Minute))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(series) != 7 || series[0].OpenTime != minute(3) {
+		t.Fatalf("series before the listing kept: %+v", series)
+	}
+	if flat := series[2]; flat.OpenTime != minute(5) || flat.Open != 4.5 || flat.Close != 4.5 || flat.Volume != 0 {
+		t.Errorf("flat candle = %+v", flat)
+	}
+
+	failing := &fakeSource{name: "rest", err: errors.New("timeout")}
+	s = NewService([]Source{&fakeSource{name: "local", minutes: minutes(0, 2)}, failing})
+	s.now = func() time.Time { return t0.Add(time.Hour) }
+	series, err = s.GetCandles(context.Background(), "BTC-USDT", "1m", t0, t0.Add(5*time.Minute))
+	if err == nil || len(series) != 5 {
+		t.Errorf("failed backfill: %d candles, %v", len(series), err)
+	}
+	if _, err := s.GetCandles(context.Background(), "BTC-USDT", "1M", t0, t0.Add(time.Hour)); err == nil {
+		t.Error("monthly interval accepted")
+	}
+}
+
+func TestOpenTime(t *testing.T) {
+	wednesday := time.Date(2026, 10, 14, 15, 4, 5, 0, time.UTC).UnixMilli()
+	for interval, want := range map[string]time.Time{
+		"1m": time.Date(2026, 10, 14, 15, 4, 0, 0, time.UTC),
+		"4h": time.Date(2026, 10, 14, 12, 0, 0, 0, time.UTC),
+		"1d": time.Date(2026, 10, 14, 0, 0, 0, 0, time.UTC),
+		"1w": time.Date(2026, 10, 12, 0, 0, 0, 0, time.UTC), // Monday
+	} {
+		d, _ := ParseInterval(interval)
+		if got := openTime(wednesday, d); got != want.UnixMilli() {
+			t.Errorf("%s open time = %v, want %v", interval, time.UnixMilli(got).UTC(), want)
+		}
+	}
+}
+
+func TestFileStore(t *testing.T) {
+	store, err := NewFileStore(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if candles, err := store.Candles(context.Background(), "BTC-USDT", "1m", 0, minute(10)); err != nil || len(candles) != 0 {
+		t.Fatalf("empty store: %+v, %v", candles, err)
+	}
+	if err := store.Record("BTC-USDT", "1m", []Candle{{OpenTime: minute(2), Close: 1}, {OpenTime: minute(1), Close: 1}}); err != nil {
+		t.Fatal(err)
+	}
+	if err := store.Record("BTC-USDT", "1m", []Candle{{OpenTime: minute(2), Close: 2}, {OpenTime: minute(3), Close: 3}}); err != nil {
+		t.Fatal(err)
+	}
+	candles, err := store.Candles(context.Background(), "BTC-USDT", "1m", minute(1), minute(3))
+	if err != nil || len(candles) != 2 || candles[0].OpenTime != minute(1) || candles[1].Close != 2 {
+		t.Errorf("candles = %+v, %v", candles, err)
+	}
+	if err := store.Record("../BTC-USDT", "1m", nil); err == nil {
+		t.Error("path outside the store accepted")
+	}
+}
+
+func TestBinanceSourcePages(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Query().Get("symbol") != "BTCUSDT" {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		start, _ := strconv.ParseInt(r.URL.Query().Get("startTime"), 10, 64)
+		end, _ := strconv.ParseInt(r.URL.Query().Get("endTime"), 10, 64)
+		limit, _ := strconv.Atoi(r.URL.Query().Get("limit"))
+		w.Write([]byte("["))
+		n := 0
+		for t := start - start%60000; t <= end && n < limit; t += 60000 {
+			if t < start {
+				continue
+			}
+			if n > 0 {
+				w.Write([]byte(","))
+			}
+			fmt.Fprintf(w, `[%d,"1.0","2.0","0.5","1.5","10.0",%d,"15.0",3,"5.0","7.5","0"]`, t, t+59999)
+			n++
+		}
+		w.Write([]byte("]"))
+	}))
+	defer server.Close()
+	source := NewBinanceSource(binance.NewClient(binance.NewConfig("", "", server.URL+"/api")))
+
+	candles, err := source.Candles(context.Background(), "BTC-USDT", "1m", minute(0), minute(2500))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(candles) != 2500 || candles[2499].OpenTime != minute(2499) {
+		t.Fatalf("%d candles", len(candles))
+	}
+	if c := candles[0]; c.High != 2 || c.Close != 1.5 || c.QuoteVolume != 15 || c.Trades != 3 {
+		t.Errorf("candle = %+v", c)
+	}
+}
+
+// oneMinute returns n 1m candles from start, the i-th opening at i and closing at i+1
+func oneMinute(start time.Time, n int) []Candle {
+	candles := make([]Candle, n)
+	for i := range candles {
+		candles[i] = Candle{
+			OpenTime:    start.Add(time.Duration(i) * time.Minute).UnixMilli(),
+			Open:        float64(i),
+			High:        float64(i) + 2,
+			Low:         float64(i) - 1,
+			Close:       float64(i) + 1,
+			Volume:      1,
+			QuoteVolume: 10,
+			Trades:      2,
+		}
+	}
+	return candles
+}
+
+func TestResample(t *testing.T) {
+	// Starts at 00:02 so the first 5m candle misses two minutes
+	candles := oneMinute(t0.Add(2*time.Minute), 13)
+	resampled, err := Resample(candles, time.Minute, ResampleOptions{Interval: 5 * time.Minute})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(resampled) != 2 || resampled[0].OpenTime != minute(5) || resampled[1].OpenTime != minute(10) {
+		t.Fatalf("resampled = %+v", resampled)
+	}
+	if c := resampled[0]; c.Open != 3 || c.High != 9 || c.Low != 2 || c.Close != 8 || c.Volume != 5 || c.QuoteVolume != 50 || c.Trades != 10 {
+		t.Errorf("5m candle = %+v", c)
+	}
+
+	partial, err := Resample(candles, time.Minute, ResampleOptions{Interval: 5 * time.Minute, Partial: true})
+	if err != nil || len(partial) != 3 || partial[0].OpenTime != minute(0) || partial[0].Volume != 3 {
+		t.Errorf("partial = %+v, %v", partial, err)
+	}
+
+	if _, err := Resample(candles, time.Minute, ResampleOptions{Interval: 90 * time.Second}); err == nil {
+		t.Error("interval that is not a multiple accepted")
+	}
+	if _, err := Resample(candles, time.Minute, ResampleOptions{Interval: 2 * day, Align: AlignUTCMidnight}); err == nil {
+		t.Error("interval longer than a day restarted at midnight")
+	}
+}
+
+func TestResampleAlignment(t *testing.T) {
+	// Two days of 1m candles from midnight
+	candles := oneMinute(t0, 2*24*60)
+
+	// 7h candles since the epoch run across midnight
+	exchange, err := Resample(candles, time.Minute, ResampleOptions{Interval: 7 * time.Hour})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, c := range exchange {
+		if c.OpenTime%(7*time.Hour).Milliseconds() != 0 {
+			t.Fatalf("exchange candle opens at %v", time.UnixMilli(c.OpenTime).UTC())
+		}
+	}
+
+	// Restarted at midnight, the last candle of the day lasts 3h
+	midnight, err := Resample(candles, time.Minute, ResampleOptions{Interval: 7 * time.Hour, Align: AlignUTCMidnight})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(midnight) != 8 {
+		t.Fatalf("%d midnight candles", len(midnight))
+	}
+	if last := midnight[3]; last.OpenTime != t0.Add(21*time.Hour).UnixMilli() || last.Volume != 180 {
+		t.Errorf("last candle of the day = %+v", last)
+	}
+	if next := midnight[4]; next.OpenTime != t0.Add(day).UnixMilli() {
+		t.Errorf("next day opens at %v", time.UnixMilli(next.OpenTime).UTC())
+	}
+
+	// 90m candles are emitted as they close
+	r, err := NewResampler(time.Minute, ResampleOptions{Interval: 90 * time.Minute})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var closed []Candle
+	for i, c := range candles[:181] {
+		out := r.Add(c)
+		if len(out) > 0 && i != 89 && i != 179 {
+			t.Errorf("candle closed by minute %d", i)
+		}
+		closed = append(closed, out...)
+	}
+	if len(closed) != 2 || closed[1].OpenTime != t0.Add(90*time.Minute).UnixMilli() || closed[1].Close != 180 {
+		t.Errorf("closed = %+v", closed)
+	}
+}
+
+func TestBuilder(t *testing.T) {
+	b, err := NewBuilder("1m")
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Trades of the first minute out of order, one of the second minute
+	b.Add(60_000+30_000, 101, 1)
+	b.Add(60_000+10_000, 100, 2)
+	b.Add(60_000+50_000, 99, 1)
+	b.Add(120_000+5_000, 102, 1)
+
+	if got := b.Flush(119_999); len(got) != 0 {
+		t.Errorf("flushed open candles %v", got)
+	}
+	got := b.Flush(120_000)
+	want := Candle{OpenTime: 60_000, Open: 100, High: 101, Low: 99, Close: 99, Volume: 4, QuoteVolume: 200 + 101 + 99, Trades: 3}
+	if len(got) != 1 || got[0] != want {
+		t.Errorf("flushed %+v, want %+v", got, want)
+	}
+	if got := b.Flush(180_000); len(got) != 1 || got[0].OpenTime != 120_000 || got[0].Trades != 1 {
+		t.Errorf("flushed %+v", got)
+	}
+	if _, err := NewBuilder("1M"); err == nil {
+		t.Error("monthly builder created")
+	}
+}
@@ -0,0 +1,98 @@
+package candles
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/nats-io/nats.go"
+)
+
+// JetStreamCache keeps candles in a JetStream stream as JSON messages on
+// <prefix>.<SYMBOL>.<interval>. The retention of the stream bounds the
+// cache. It is both a source and a sink.
+type JetStreamCache struct {
+	js      nats.JetStreamContext
+	stream  string
+	prefix  string
+	timeout time.Duration
+}
+
+// NewJetStreamCache creates a cache over stream, whose subjects must include
+// <prefix>.>
+func NewJetStreamCache(js nats.JetStreamContext, stream, prefix string) *JetStreamCache {
+	return &JetStreamCache{js: js, stream: stream, prefix: prefix, timeout: 5 * time.Second}
+}
+
+// Name identifies the cache in errors
+func (j *JetStreamCache) Name() string {
+	return "jetstream"
+}
+
+func (j *JetStreamCache) subject(symbol, interval string) string {
+	return j.prefix + "." + symbol + "." + interval
+}
+
+// Candles reads the cached candles opened in [from, to). Candles are only
+// published once closed, so the messages published before from are skipped.
+func (j *JetStreamCache) Candles(ctx context.Context, symbol, interval string, from, to int64) ([]Candle, error) {
+	if _, ok := ctx.Deadline(); !ok {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, j.timeout)
+		defer cancel()
+	}
+	subject := j.subject(symbol, interval)
+	last, err := j.js.GetLastMsg(j.stream, subject, nats.Context(ctx))
+	if errors.Is(err, nats.ErrMsgNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if last.Time.Before(time.UnixMilli(from)) {
+		return nil, nil
+	}
+
+	sub, err := j.js.SubscribeSync(subject, nats.BindStream(j.stream), nats.OrderedConsumer(), nats.StartTime(time.UnixMilli(from)))
+	if err != nil {
+		return nil, err
+	}
+	defer sub.Unsubscribe()
+	var candles []Candle
+	for {
+		msg, err := sub.NextMsgWithContext(ctx)
+		if err != nil {
+			return nil, err
+		}
+		var c Candle
+		if json.Unmarshal(msg.Data, &c) == nil && c.OpenTime >= from && c.OpenTime < to {
+			candles = append(candles, c)
+		}
+		meta, err := msg.Metadata()
+		if err != nil {
+			return nil, err
+		}
+		if meta.Sequence.Stream >= last.Sequence {
+			return candles, nil
+		}
+	}
+}
+
+// Record publishes candles to the cache. Candles published again within the
+// duplicate window of the stream are dropped by JetStream.
+func (j *JetStreamCache) Record(symbol, interval string, candles []Candle) error {
+	subject := j.subject(symbol, interval)
+	for _, c := range candles {
+		data, err := json.Marshal(c)
+		if err != nil {
+			return err
+		}
+		if _, err := j.js.Publish(subject, data, nats.MsgId(subject+"."+strconv.FormatInt(c.OpenTime, 10))); err != nil {
+			return fmt.Errorf("publish %s: %w", subject, err)
+		}
+	}
+	return nil
+}
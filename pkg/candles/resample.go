@@ -0,0 +1,143 @@
+package candles
+
+import (
+	"fmt"
+	"time"
+)
+
+// Alignment selects where resampled candles open
+type Alignment int
+
+const (
+	// AlignExchange opens candles at multiples of the interval since the
+	// Unix epoch, as Binance does; weekly candles open on Mondays
+	AlignExchange Alignment = iota
+	// AlignUTCMidnight restarts the candles at 00:00 UTC every day, the
+	// last candle of a day being cut short when the interval does not
+	// divide 24 hours, e.g. 7h candles open at 00:00, 07:00, 14:00 and 21:00
+	AlignUTCMidnight
+)
+
+const day = 24 * time.Hour
+
+// ResampleOptions configures a resampling
+type ResampleOptions struct {
+	Interval time.Duration // Interval of the resampled candles, a multiple of the source interval
+	Align    Alignment
+	Partial  bool // Keep candles missing source candles, such as the one still open
+}
+
+// Resampler aggregates candles of a base interval into candles of a higher
+// interval as they close
+type Resampler struct {
+	base time.Duration
+	opts ResampleOptions
+
+	current    Candle
+	start, end int64 // Bounds of the current candle, Unix milliseconds
+	count      int64 // Source candles folded into the current candle
+	open       bool
+}
+
+// NewResampler creates a resampler of candles of the base interval
+func NewResampler(base time.Duration, opts ResampleOptions) (*Resampler, error) {
+	if base <= 0 || base%time.Millisecond != 0 {
+		return nil, fmt.Errorf("invalid base interval %s", base)
+	}
+	if opts.Interval < base || opts.Interval%base != 0 {
+		return nil, fmt.Errorf("interval %s is not a multiple of %s", opts.Interval, base)
+	}
+	switch opts.Align {
+	case AlignExchange:
+	case AlignUTCMidnight:
+		if opts.Interval > day {
+			return nil, fmt.Errorf("interval %s is longer than a day and cannot restart at midnight", opts.Interval)
+		}
+		if day%base != 0 {
+			return nil, fmt.Errorf("base interval %s does not divide a day", base)
+		}
+	default:
+		return nil, fmt.Errorf("unknown alignment %d", opts.Align)
+	}
+	return &Resampler{base: base, opts: opts}, nil
+}
+
+// bounds returns the open and close time of the resampled candle containing t
+func (r *Resampler) bounds(t int64) (int64, int64) {
+	step := r.opts.Interval.Milliseconds()
+	if r.opts.Align == AlignUTCMidnight {
+		midnight := openTime(t, day)
+		start := midnight + (t-midnight)/step*step
+		return start, min(start+step, midnight+day.Milliseconds())
+	}
+	start := openTime(t, r.opts.Interval)
+	return start, start + step
+}
+
+// Add folds a candle in and returns the resampled candles it closed: the
+// previous one when the candle opens a new one, and the current one when
+// the candle is its last. Candles older than the current one are ignored.
+func (r *Resampler) Add(c Candle) []Candle {
+	if r.open && c.OpenTime < r.start {
+		return nil
+	}
+	var closed []Candle
+	if r.open && c.OpenTime >= r.end {
+		if done, ok := r.Flush(); ok {
+			closed = append(closed, done)
+		}
+	}
+	if !r.open {
+		r.start, r.end = r.bounds(c.OpenTime)
+		r.current = c
+		r.current.OpenTime = r.start
+		r.count = 1
+		r.open = true
+	} else {
+		r.current.High = max(r.current.High, c.High)
+		r.current.Low = min(r.current.Low, c.Low)
+		r.current.Close = c.Close
+		r.current.Volume += c.Volume
+		r.current.QuoteVolume += c.QuoteVolume
+		r.current.Trades += c.Trades
+		r.count++
+	}
+	if c.OpenTime+r.base.Milliseconds() >= r.end {
+		if done, ok := r.Flush(); ok {
+			closed = append(closed, done)
+		}
+	}
+	return closed
+}
+
+// Flush closes the current candle and returns it, unless it is missing
+// source candles and partial candles are not kept
+func (r *Resampler) Flush() (Candle, bool) {
+	if !r.open {
+		return Candle{}, false
+	}
+	r.open = false
+	if !r.opts.Partial && r.count < (r.end-r.start)/r.base.Milliseconds() {
+		return Candle{}, false
+	}
+	return r.current, true
+}
+
+// Resample aggregates candles of the base interval, oldest first, into
+// candles of opts.Interval: the first open, highest high, lowest low, last
+// close and summed volumes and trades. Custom intervals such as 90m are
+// supported; use ParseInterval or time.ParseDuration to read them.
+func Resample(candles []Candle, base time.Duration, opts ResampleOptions) ([]Candle, error) {
+	r, err := NewResampler(base, opts)
+	if err != nil {
+		return nil, err
+	}
+	resampled := make([]Candle, 0, len(candles)/int(opts.Interval/base)+1)
+	for _, c := range candles {
+		resampled = append(resampled, r.Add(c)...)
+	}
+	if last, ok := r.Flush(); ok {
+		resampled = append(resampled, last)
+	}
+	return resampled, nil
+}
@@ -0,0 +1,76 @@
+package candles
+
+import (
+	"sort"
+	"time"
+)
+
+// building is a candle being built, with the timestamps of the trades its
+// open and close come from
+type building struct {
+	candle      Candle
+	first, last int64
+}
+
+// Builder aggregates trades into candles of an interval. Trades may arrive
+// out of order: the open and close are those of the earliest and latest
+// trades of each candle.
+type Builder struct {
+	interval time.Duration
+	open     map[int64]*building
+}
+
+// NewBuilder creates a builder of candles of a Binance kline interval
+func NewBuilder(interval string) (*Builder, error) {
+	d, err := ParseInterval(interval)
+	if err != nil {
+		return nil, err
+	}
+	return &Builder{interval: d, open: make(map[int64]*building)}, nil
+}
+
+// OpenTime returns the open time of the candle containing t, in Unix
+// milliseconds
+func (b *Builder) OpenTime(t int64) int64 {
+	return openTime(t, b.interval)
+}
+
+// Add folds a trade at timestamp, in Unix milliseconds, into its candle
+func (b *Builder) Add(timestamp int64, price, quantity float64) {
+	start := b.OpenTime(timestamp)
+	c, ok := b.open[start]
+	if !ok {
+		b.open[start] = &building{
+			candle: Candle{OpenTime: start, Open: price, High: price, Low: price, Close: price, Volume: quantity, QuoteVolume: price * quantity, Trades: 1},
+			first:  timestamp,
+			last:   timestamp,
+		}
+		return
+	}
+	k := &c.candle
+	if timestamp < c.first {
+		c.first, k.Open = timestamp, price
+	}
+	if timestamp >= c.last {
+		c.last, k.Close = timestamp, price
+	}
+	k.High = max(k.High, price)
+	k.Low = min(k.Low, price)
+	k.Volume += quantity
+	k.QuoteVolume += price * quantity
+	k.Trades++
+}
+
+// Flush removes and returns the candles closed by end, oldest first.
+// Candles still open at end are kept until a later flush.
+func (b *Builder) Flush(end int64) []Candle {
+	var closed []Candle
+	for start, c := range b.open {
+		if start+b.interval.Milliseconds() <= end {
+			closed = append(closed, c.candle)
+			delete(b.open, start)
+		}
+	}
+	sort.Slice(closed, func(i, j int) bool { return closed[i].OpenTime < closed[j].OpenTime })
+	return closed
+}
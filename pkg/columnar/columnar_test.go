@@ -0,0 +1,129 @@
+package columnar
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/BullionBear/sequex/pkg/candles"
+)
+
+func TestKlines(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "klines.col")
+	var want []candles.Candle
+	for i := 0; i < 100; i++ {
+		p := 100 + float64(i)
+		want = append(want, candles.Candle{OpenTime: int64(i) * 60_000, Open: p, High: p + 2, Low: p - 1, Close: p + 1, Volume: 3, QuoteVolume: 3 * p, Trades: int64(i)})
+	}
+	if err := WriteKlines(path, want); err != nil {
+		t.Fatal(err)
+	}
+	k, err := OpenKlines(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer k.Close()
+	if k.Len() != len(want) || len(k.Closes) != len(want) {
+		t.Fatalf("%d klines read of %d", k.Len(), len(want))
+	}
+	for i, c := range want {
+		if got := k.Candle(i); got != c {
+			t.Fatalf("kline %d = %+v, want %+v", i, got, c)
+		}
+	}
+	if i, j := k.Range(90_000, 300_000); i != 2 || j != 5 {
+		t.Errorf("range [%d, %d)", i, j)
+	}
+	if got := k.Candles(-1, 120_000); !reflect.DeepEqual(got, want[:2]) {
+		t.Errorf("candles %+v", got)
+	}
+	if got := k.Candles(1e12, 2e12); len(got) != 0 {
+		t.Errorf("candles after the table %+v", got)
+	}
+
+	if _, err := OpenTrades(path); !errors.Is(err, ErrFormat) {
+		t.Errorf("klines opened as trades: %v", err)
+	}
+	os.WriteFile(path, []byte("{\"open_time\":0}\n"), 0o644)
+	if _, err := OpenKlines(path); !errors.Is(err, ErrFormat) {
+		t.Errorf("JSON opened as a table: %v", err)
+	}
+}
+
+func TestTruncated(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "trades.col")
+	if err := WriteTrades(path, []Trade{{Time: 1, ID: 1, Price: 10, Quantity: 1, Side: SideBuy}, {Time: 2, ID: 2, Price: 11, Quantity: 2, Side: SideSell}}); err != nil {
+		t.Fatal(err)
+	}
+	data, _ := os.ReadFile(path)
+	os.WriteFile(path, data[:len(data)-1], 0o644)
+	if _, err := OpenTrades(path); !errors.Is(err, ErrFormat) {
+		t.Errorf("truncated table opened: %v", err)
+	}
+	if err := WriteTrades(path, nil); err != nil {
+		t.Fatal(err)
+	}
+	empty, err := OpenTrades(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer empty.Close()
+	if i, j := empty.Range(0, 10); empty.Len() != 0 || i != 0 || j != 0 {
+		t.Errorf("empty table of %d rows, range [%d, %d)", empty.Len(), i, j)
+	}
+}
+
+func TestStore(t *testing.T) {
+	s, err := NewStore(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx := context.Background()
+	if got, err := s.Candles(ctx, "BTC-USDT", "1m", 0, 1e12); err != nil || got != nil {
+		t.Fatalf("candles of an empty store %+v: %v", got, err)
+	}
+	if _, err := s.Candles(ctx, "../BTC", "1m", 0, 1); err == nil {
+		t.Error("read outside the store")
+	}
+	if err := s.Record("BTC-USDT", "1m", []candles.Candle{{OpenTime: 120_000, Close: 2}, {OpenTime: 0, Close: 1}}); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.Record("BTC-USDT", "1m", []candles.Candle{{OpenTime: 60_000, Close: 3}, {OpenTime: 120_000, Close: 4}}); err != nil {
+		t.Fatal(err)
+	}
+	got, err := s.Candles(ctx, "BTC-USDT", "1m", 0, 1e12)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(got) != 3 || got[0].Close != 1 || got[1].Close != 3 || got[2].Close != 4 {
+		t.Errorf("merged candles %+v", got)
+	}
+
+	// A table being read is kept whole by the reader across a rewrite
+	if err := s.AppendTrades("BTC-USDT", []Trade{{Time: 2, ID: 2, Price: 11}, {Time: 1, ID: 1, Price: 10}}); err != nil {
+		t.Fatal(err)
+	}
+	path, _ := s.TradesPath("BTC-USDT")
+	before, err := OpenTrades(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer before.Close()
+	if err := s.AppendTrades("BTC-USDT", []Trade{{Time: 2, ID: 2, Price: 11}, {Time: 2, ID: 3, Price: 12}}); err != nil {
+		t.Fatal(err)
+	}
+	after, err := OpenTrades(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer after.Close()
+	if before.Len() != 2 || !reflect.DeepEqual(before.Prices, []float64{10, 11}) {
+		t.Errorf("trades before the rewrite %v", before.Prices)
+	}
+	if after.Len() != 3 || !reflect.DeepEqual(after.IDs, []int64{1, 2, 3}) {
+		t.Errorf("trades after the rewrite %v", after.IDs)
+	}
+}
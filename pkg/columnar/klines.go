@@ -0,0 +1,110 @@
+package columnar
+
+import (
+	"encoding/binary"
+	"math"
+
+	"github.com/BullionBear/sequex/pkg/candles"
+)
+
+// Columns of a kline table
+const (
+	klineOpenTime = iota
+	klineOpen
+	klineHigh
+	klineLow
+	klineClose
+	klineVolume
+	klineQuoteVolume
+	klineTrades
+)
+
+// Klines is an open table of klines. Its columns are views of the mapped
+// file, valid until it is closed, and must not be modified.
+type Klines struct {
+	t *table
+
+	OpenTimes    []int64 // Unix milliseconds, ascending
+	Opens        []float64
+	Highs        []float64
+	Lows         []float64
+	Closes       []float64
+	Volumes      []float64
+	QuoteVolumes []float64
+	Trades       []int64 // Number of trades
+}
+
+// OpenKlines maps the kline table at path
+func OpenKlines(path string) (*Klines, error) {
+	t, err := open(path, KindKlines)
+	if err != nil {
+		return nil, err
+	}
+	return &Klines{
+		t:            t,
+		OpenTimes:    t.int64s(klineOpenTime),
+		Opens:        t.float64s(klineOpen),
+		Highs:        t.float64s(klineHigh),
+		Lows:         t.float64s(klineLow),
+		Closes:       t.float64s(klineClose),
+		Volumes:      t.float64s(klineVolume),
+		QuoteVolumes: t.float64s(klineQuoteVolume),
+		Trades:       t.int64s(klineTrades),
+	}, nil
+}
+
+// Len returns the number of klines
+func (k *Klines) Len() int {
+	return k.t.rows
+}
+
+// Range returns the rows [i, j) of the klines opened in [from, to), Unix
+// milliseconds
+func (k *Klines) Range(from, to int64) (i, j int) {
+	return search(k.OpenTimes, from), search(k.OpenTimes, to)
+}
+
+// Candle returns the kline of row i
+func (k *Klines) Candle(i int) candles.Candle {
+	return candles.Candle{
+		OpenTime:    k.OpenTimes[i],
+		Open:        k.Opens[i],
+		High:        k.Highs[i],
+		Low:         k.Lows[i],
+		Close:       k.Closes[i],
+		Volume:      k.Volumes[i],
+		QuoteVolume: k.QuoteVolumes[i],
+		Trades:      k.Trades[i],
+	}
+}
+
+// Candles copies the klines opened in [from, to), Unix milliseconds
+func (k *Klines) Candles(from, to int64) []candles.Candle {
+	i, j := k.Range(from, to)
+	out := make([]candles.Candle, 0, j-i)
+	for ; i < j; i++ {
+		out = append(out, k.Candle(i))
+	}
+	return out
+}
+
+// Close unmaps the table
+func (k *Klines) Close() error {
+	return k.t.close()
+}
+
+// WriteKlines writes klines, in open time order, as the table at path
+func WriteKlines(path string, klines []candles.Candle) error {
+	return write(path, KindKlines, len(klines), func(columns [][]byte) {
+		for i, c := range klines {
+			binary.LittleEndian.PutUint64(columns[klineOpenTime][8*i:], uint64(c.OpenTime))
+			binary.LittleEndian.PutUint64(columns[klineOpen][8*i:], math.Float64bits(c.Open))
+			binary.LittleEndian.PutUint64(columns[klineHigh][8*i:], math.Float64bits(c.High))
+			binary.LittleEndian.PutUint64(columns[klineLow][8*i:], math.Float64bits(c.Low))
+			binary.LittleEndian.PutUint64(columns[klineClose][8*i:], math.Float64bits(c.Close))
+			binary.LittleEndian.PutUint64(columns[klineVolume][8*i:], math.Float64bits(c.Volume))
+			binary.LittleEndian.PutUint64(columns[klineQuoteVolume][8*i:], math.Float64bits(c.QuoteVolume))
+			binary.LittleEndian.PutUint64(columns[klineTrades][8*i:], uint64(c.Trades))
+		}
+	})
+}
@@ -0,0 +1,151 @@
+package columnar
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/BullionBear/sequex/pkg/candles"
+)
+
+// Store keeps tables under dir, one per symbol and kline interval and one
+// of trades per symbol: <dir>/<SYMBOL>/klines-<interval>.col and
+// <dir>/<SYMBOL>/trades.col. It is both a source and a sink of candles.
+type Store struct {
+	dir string
+	mu  sync.Mutex // Serializes the rewrites of tables
+}
+
+// NewStore creates a store under dir, which is created when missing
+func NewStore(dir string) (*Store, error) {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, err
+	}
+	return &Store{dir: dir}, nil
+}
+
+// Name identifies the store in errors
+func (s *Store) Name() string {
+	return "columnar"
+}
+
+func (s *Store) symbolDir(symbol string) (string, error) {
+	if symbol == "" || strings.ContainsAny(symbol, `/\.`) {
+		return "", fmt.Errorf("invalid symbol %q", symbol)
+	}
+	return filepath.Join(s.dir, strings.ToUpper(symbol)), nil
+}
+
+// KlinesPath returns the path of the kline table of symbol and interval
+func (s *Store) KlinesPath(symbol, interval string) (string, error) {
+	if _, err := candles.ParseInterval(interval); err != nil {
+		return "", err
+	}
+	dir, err := s.symbolDir(symbol)
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, "klines-"+interval+".col"), nil
+}
+
+// TradesPath returns the path of the trade table of symbol
+func (s *Store) TradesPath(symbol string) (string, error) {
+	dir, err := s.symbolDir(symbol)
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, "trades.col"), nil
+}
+
+// Candles reads the candles opened in [from, to), oldest first, none when
+// the symbol has no table of the interval
+func (s *Store) Candles(_ context.Context, symbol, interval string, from, to int64) ([]candles.Candle, error) {
+	path, err := s.KlinesPath(symbol, interval)
+	if err != nil {
+		return nil, err
+	}
+	k, err := OpenKlines(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer k.Close()
+	return k.Candles(from, to), nil
+}
+
+// Record merges candles into the table of symbol and interval, replacing
+// those of the same open time
+func (s *Store) Record(symbol, interval string, klines []candles.Candle) error {
+	path, err := s.KlinesPath(symbol, interval)
+	if err != nil {
+		return err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	byTime := make(map[int64]candles.Candle, len(klines))
+	k, err := OpenKlines(path)
+	switch {
+	case err == nil:
+		for i := 0; i < k.Len(); i++ {
+			byTime[k.OpenTimes[i]] = k.Candle(i)
+		}
+		k.Close()
+	case !errors.Is(err, os.ErrNotExist):
+		return err
+	}
+	for _, c := range klines {
+		byTime[c.OpenTime] = c
+	}
+	merged := make([]candles.Candle, 0, len(byTime))
+	for _, c := range byTime {
+		merged = append(merged, c)
+	}
+	sort.Slice(merged, func(i, j int) bool { return merged[i].OpenTime < merged[j].OpenTime })
+	return WriteKlines(path, merged)
+}
+
+// AppendTrades merges trades into the table of symbol, dropping those of
+// the same time and id as one it holds. Each call rewrites the table, so
+// trades are best appended in large batches.
+func (s *Store) AppendTrades(symbol string, trades []Trade) error {
+	path, err := s.TradesPath(symbol)
+	if err != nil {
+		return err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var merged []Trade
+	t, err := OpenTrades(path)
+	switch {
+	case err == nil:
+		merged = make([]Trade, 0, t.Len()+len(trades))
+		for i := 0; i < t.Len(); i++ {
+			merged = append(merged, t.Trade(i))
+		}
+		t.Close()
+	case !errors.Is(err, os.ErrNotExist):
+		return err
+	}
+	merged = append(merged, trades...)
+	sort.SliceStable(merged, func(i, j int) bool {
+		if merged[i].Time != merged[j].Time {
+			return merged[i].Time < merged[j].Time
+		}
+		return merged[i].ID < merged[j].ID
+	})
+	kept := merged[:0]
+	for _, trade := range merged {
+		if n := len(kept); n > 0 && kept[n-1].Time == trade.Time && kept[n-1].ID == trade.ID {
+			continue
+		}
+		kept = append(kept, trade)
+	}
+	return WriteTrades(path, kept)
+}
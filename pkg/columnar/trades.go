@@ -0,0 +1,101 @@
+package columnar
+
+import (
+	"encoding/binary"
+	"math"
+	"unsafe"
+)
+
+// Columns of a trade table
+const (
+	tradeTime = iota
+	tradeID
+	tradePrice
+	tradeQuantity
+	tradeSide
+)
+
+// Side is the aggressor side of a trade, the values of which are those of
+// the Side enum of the trade messages and of the order book simulator
+type Side uint8
+
+const (
+	SideUnknown Side = 0
+	SideBuy     Side = 1
+	SideSell    Side = 2
+)
+
+// Trade is a row of a trade table
+type Trade struct {
+	Time     int64 // Unix milliseconds
+	ID       int64 // Venue trade id
+	Price    float64
+	Quantity float64
+	Side     Side
+}
+
+// Trades is an open table of trades. Its columns are views of the mapped
+// file, valid until it is closed, and must not be modified.
+type Trades struct {
+	t *table
+
+	Times      []int64 // Unix milliseconds, ascending
+	IDs        []int64
+	Prices     []float64
+	Quantities []float64
+	Sides      []Side
+}
+
+// OpenTrades maps the trade table at path
+func OpenTrades(path string) (*Trades, error) {
+	t, err := open(path, KindTrades)
+	if err != nil {
+		return nil, err
+	}
+	var sides []Side
+	if t.rows > 0 {
+		sides = unsafe.Slice((*Side)(unsafe.SliceData(t.columns[tradeSide])), t.rows)
+	}
+	return &Trades{
+		t:          t,
+		Times:      t.int64s(tradeTime),
+		IDs:        t.int64s(tradeID),
+		Prices:     t.float64s(tradePrice),
+		Quantities: t.float64s(tradeQuantity),
+		Sides:      sides,
+	}, nil
+}
+
+// Len returns the number of trades
+func (t *Trades) Len() int {
+	return t.t.rows
+}
+
+// Range returns the rows [i, j) of the trades in [from, to), Unix
+// milliseconds
+func (t *Trades) Range(from, to int64) (i, j int) {
+	return search(t.Times, from), search(t.Times, to)
+}
+
+// Trade returns the trade of row i
+func (t *Trades) Trade(i int) Trade {
+	return Trade{Time: t.Times[i], ID: t.IDs[i], Price: t.Prices[i], Quantity: t.Quantities[i], Side: t.Sides[i]}
+}
+
+// Close unmaps the table
+func (t *Trades) Close() error {
+	return t.t.close()
+}
+
+// WriteTrades writes trades, in time order, as the table at path
+func WriteTrades(path string, trades []Trade) error {
+	return write(path, KindTrades, len(trades), func(columns [][]byte) {
+		for i, t := range trades {
+			binary.LittleEndian.PutUint64(columns[tradeTime][8*i:], uint64(t.Time))
+			binary.LittleEndian.PutUint64(columns[tradeID][8*i:], uint64(t.ID))
+			binary.LittleEndian.PutUint64(columns[tradePrice][8*i:], math.Float64bits(t.Price))
+			binary.LittleEndian.PutUint64(columns[tradeQuantity][8*i:], math.Float64bits(t.Quantity))
+			columns[tradeSide][i] = byte(t.Side)
+		}
+	})
+}
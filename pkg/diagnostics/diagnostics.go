@@ -0,0 +1,281 @@
+// Package diagnostics exposes runtime diagnostics of a service: optional
+// pprof HTTP endpoints, a NATS request/reply endpoint returning a runtime
+// report and the shipping of its log records to NATS, all enabled by
+// configuration so production binaries can be inspected without
+// redeploying. The version of the binary is always served.
+package diagnostics
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/http/pprof"
+	"runtime"
+	"sync"
+	"time"
+
+	"github.com/BullionBear/sequex/env"
+	"github.com/BullionBear/sequex/pkg/eventbus"
+	"github.com/BullionBear/sequex/pkg/logger"
+	"github.com/nats-io/nats.go"
+	"github.com/rs/zerolog"
+)
+
+// recentPauses is the number of most recent GC pauses included in a report
+const recentPauses = 16
+
+// QueueDepth is the backlog of one named queue or subscription
+type QueueDepth struct {
+	Name  string `json:"name"`
+	Depth int    `json:"depth"`
+}
+
+// QueueSource reports queue depths when a report is built
+type QueueSource func() []QueueDepth
+
+// SubscriptionSource reports the pending messages of NATS subscriptions
+func SubscriptionSource(subs ...*nats.Subscription) QueueSource {
+	return func() []QueueDepth {
+		depths := make([]QueueDepth, 0, len(subs))
+		for _, sub := range subs {
+			pending, _, err := sub.Pending()
+			if err != nil {
+				continue
+			}
+			depths = append(depths, QueueDepth{Name: sub.Subject, Depth: pending})
+		}
+		return depths
+	}
+}
+
+// HeapStats summarizes heap usage in bytes
+type HeapStats struct {
+	Alloc   uint64 `json:"alloc"`
+	InUse   uint64 `json:"in_use"`
+	Sys     uint64 `json:"sys"`
+	Objects uint64 `json:"objects"`
+}
+
+// GCStats summarizes garbage collection
+type GCStats struct {
+	Count        uint32          `json:"count"`
+	PauseTotal   time.Duration   `json:"pause_total"`
+	RecentPauses []time.Duration `json:"recent_pauses"` // Most recent first
+	LastGC       time.Time       `json:"last_gc"`
+}
+
+// Report is the runtime state of a service
+type Report struct {
+	Service    string              `json:"service"`
+	Build      env.BuildInfo       `json:"build"`
+	Uptime     time.Duration       `json:"uptime"`
+	Goroutines int                 `json:"goroutines"`
+	CPUs       int                 `json:"cpus"`
+	Heap       HeapStats           `json:"heap"`
+	GC         GCStats             `json:"gc"`
+	Queues     []QueueDepth        `json:"queues"`
+	RPC        []eventbus.RPCStats `json:"rpc,omitempty"` // Endpoints called under a policy
+	// Subscriptions the server dropped messages for
+	SlowConsumers []eventbus.SlowConsumerStats `json:"slow_consumers,omitempty"`
+}
+
+// Diagnostics builds reports for a service
+type Diagnostics struct {
+	service string
+	start   time.Time
+	mu      sync.Mutex
+	sources []QueueSource
+	rpc     []func() []eventbus.RPCStats
+	slow    []func() []eventbus.SlowConsumerStats
+}
+
+// New creates diagnostics for a service
+func New(service string) *Diagnostics {
+	return &Diagnostics{service: service, start: time.Now()}
+}
+
+// AddQueueSource adds queue depths to the reports
+func (d *Diagnostics) AddQueueSource(source QueueSource) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.sources = append(d.sources, source)
+}
+
+// AddRPCSource adds the statistics of the RPC calls of a bus, e.g.
+// bus.RPCStats, to the reports
+func (d *Diagnostics) AddRPCSource(source func() []eventbus.RPCStats) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.rpc = append(d.rpc, source)
+}
+
+// AddSlowConsumerSource adds the slow consumers of a bus, e.g.
+// bus.SlowConsumerStats, to the reports
+func (d *Diagnostics) AddSlowConsumerSource(source func() []eventbus.SlowConsumerStats) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.slow = append(d.slow, source)
+}
+
+// Report captures the current runtime state. It stops the world briefly to
+// read memory statistics, so it is meant for on-demand use.
+func (d *Diagnostics) Report() Report {
+	var mem runtime.MemStats
+	runtime.ReadMemStats(&mem)
+
+	report := Report{
+		Service:    d.service,
+		Build:      env.Info(),
+		Uptime:     time.Since(d.start),
+		Goroutines: runtime.NumGoroutine(),
+		CPUs:       runtime.NumCPU(),
+		Heap: HeapStats{
+			Alloc:   mem.HeapAlloc,
+			InUse:   mem.HeapInuse,
+			Sys:     mem.HeapSys,
+			Objects: mem.HeapObjects,
+		},
+		GC: GCStats{
+			Count:      mem.NumGC,
+			PauseTotal: time.Duration(mem.PauseTotalNs),
+		},
+		Queues: []QueueDepth{},
+	}
+	if mem.LastGC > 0 {
+		report.GC.LastGC = time.Unix(0, int64(mem.LastGC))
+	}
+	// PauseNs is a circular buffer whose latest entry is at (NumGC+255)%256
+	for i := uint32(0); i < min(mem.NumGC, recentPauses); i++ {
+		idx := (mem.NumGC - i + 255) % uint32(len(mem.PauseNs))
+		report.GC.RecentPauses = append(report.GC.RecentPauses, time.Duration(mem.PauseNs[idx]))
+	}
+
+	d.mu.Lock()
+	sources := append([]QueueSource(nil), d.sources...)
+	rpc := append([]func() []eventbus.RPCStats(nil), d.rpc...)
+	slow := append([]func() []eventbus.SlowConsumerStats(nil), d.slow...)
+	d.mu.Unlock()
+	for _, source := range sources {
+		report.Queues = append(report.Queues, source()...)
+	}
+	for _, source := range rpc {
+		report.RPC = append(report.RPC, source()...)
+	}
+	for _, source := range slow {
+		report.SlowConsumers = append(report.SlowConsumers, source()...)
+	}
+	return report
+}
+
+// Serve answers requests on subject with a JSON report
+func (d *Diagnostics) Serve(conn *nats.Conn, subject string) (*nats.Subscription, error) {
+	return conn.Subscribe(subject, func(msg *nats.Msg) {
+		data, err := json.Marshal(d.Report())
+		if err != nil {
+			data = []byte(fmt.Sprintf(`{"error":%q}`, err.Error()))
+		}
+		msg.Respond(data)
+	})
+}
+
+// PprofHandler returns a handler serving the net/http/pprof endpoints under /debug/pprof/
+func PprofHandler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/debug/pprof/", pprof.Index)
+	mux.HandleFunc("/debug/pprof/cmdline", pprof.Cmdline)
+	mux.HandleFunc("/debug/pprof/profile", pprof.Profile)
+	mux.HandleFunc("/debug/pprof/symbol", pprof.Symbol)
+	mux.HandleFunc("/debug/pprof/trace", pprof.Trace)
+	return mux
+}
+
+// Options enables the diagnostics endpoints of a service
+type Options struct {
+	Service    string
+	PprofAddr  string // Listen address of the pprof endpoints, empty disables them
+	RPCSubject string // Subject of the report RPC, empty disables it
+	LogSubject string // Subject the log records are shipped to, empty disables it
+	LogLevel   zerolog.Level
+}
+
+// Start creates diagnostics for a service and starts the endpoints enabled
+// in opts. The returned function stops them.
+func Start(opts Options, conn *nats.Conn, log zerolog.Logger) (*Diagnostics, func(), error) {
+	d := New(opts.Service)
+	var server *http.Server
+	var sub *nats.Subscription
+	var versionSubs []*nats.Subscription
+
+	if opts.PprofAddr != "" {
+		listener, err := net.Listen("tcp", opts.PprofAddr)
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to listen on %s: %w", opts.PprofAddr, err)
+		}
+		mux := http.NewServeMux()
+		mux.Handle("/debug/pprof/", PprofHandler())
+		mux.Handle("/version", VersionHandler(opts.Service))
+		server = &http.Server{Handler: mux, ReadHeaderTimeout: 5 * time.Second}
+		go func() {
+			if err := server.Serve(listener); err != nil && !errors.Is(err, http.ErrServerClosed) {
+				log.Error().Err(err).Msg("pprof server stopped")
+			}
+		}()
+		log.Info().Str("addr", listener.Addr().String()).Msg("pprof endpoints enabled")
+	}
+
+	if opts.RPCSubject != "" {
+		var err error
+		sub, err = d.Serve(conn, opts.RPCSubject)
+		if err != nil {
+			if server != nil {
+				server.Close()
+			}
+			return nil, nil, fmt.Errorf("failed to serve diagnostics on %s: %w", opts.RPCSubject, err)
+		}
+		log.Info().Str("subject", opts.RPCSubject).Msg("Diagnostics RPC enabled")
+	}
+
+	if conn != nil {
+		var err error
+		versionSubs, err = ServeVersion(conn, opts.Service)
+		if err != nil {
+			if sub != nil {
+				sub.Unsubscribe()
+			}
+			if server != nil {
+				server.Close()
+			}
+			return nil, nil, err
+		}
+	}
+
+	var stopShipping func()
+	if opts.LogSubject != "" {
+		_, stopShipping = logger.Ship(conn, opts.LogSubject, opts.LogLevel)
+		log.Info().Str("subject", opts.LogSubject).Str("level", opts.LogLevel.String()).Msg("Log shipping enabled")
+	}
+
+	stop := func() {
+		if stopShipping != nil {
+			stopShipping()
+		}
+		if sub != nil {
+			if err := sub.Unsubscribe(); err != nil {
+				log.Error().Err(err).Msg("Failed to unsubscribe diagnostics RPC")
+			}
+		}
+		for _, sub := range versionSubs {
+			if err := sub.Unsubscribe(); err != nil {
+				log.Error().Err(err).Msg("Failed to unsubscribe version RPC")
+			}
+		}
+		if server != nil {
+			if err := server.Close(); err != nil {
+				log.Error().Err(err).Msg("Failed to close pprof server")
+			}
+		}
+	}
+	return d, stop, nil
+}
@@ -0,0 +1,60 @@
+package diagnostics
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"runtime"
+	"testing"
+)
+
+func TestReport(t *testing.T) {
+	d := New("feed")
+	d.AddQueueSource(func() []QueueDepth {
+		return []QueueDepth{{Name: "publisher", Depth: 42}}
+	})
+	runtime.GC()
+
+	report := d.Report()
+	if report.Service != "feed" || report.Goroutines == 0 || report.Heap.Alloc == 0 {
+		t.Errorf("report = %+v", report)
+	}
+	if report.GC.Count == 0 || len(report.GC.RecentPauses) == 0 || report.GC.LastGC.IsZero() {
+		t.Errorf("gc = %+v", report.GC)
+	}
+	if len(report.Queues) != 1 || report.Queues[0].Depth != 42 {
+		t.Errorf("queues = %+v", report.Queues)
+	}
+}
+
+func TestPprofHandler(t *testing.T) {
+	server := httptest.NewServer(PprofHandler())
+	defer server.Close()
+
+	resp, err := http.Get(server.URL + "/debug/pprof/goroutine?debug=1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		t.Errorf("status = %d", resp.StatusCode)
+	}
+}
+
+func TestVersionHandler(t *testing.T) {
+	server := httptest.NewServer(VersionHandler("monitor"))
+	defer server.Close()
+
+	resp, err := http.Get(server.URL + "/version")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	var v Version
+	if err := json.NewDecoder(resp.Body).Decode(&v); err != nil {
+		t.Fatal(err)
+	}
+	if v.Service != "monitor" || v.GoVersion == "" || v.Platform == "" {
+		t.Errorf("version = %+v", v)
+	}
+}
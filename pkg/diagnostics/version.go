@@ -0,0 +1,60 @@
+package diagnostics
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+
+	"github.com/BullionBear/sequex/env"
+	"github.com/nats-io/nats.go"
+)
+
+// VersionSubject is the subject every service answers version requests on,
+// a request gathering the replies of all of them; VersionSubject.<service>
+// reaches the instances of one service
+const VersionSubject = "req_version"
+
+// Version identifies the code a service instance runs
+type Version struct {
+	Service string `json:"service"`
+	Host    string `json:"host"`
+	env.BuildInfo
+}
+
+// NewVersion returns the version of the running binary for service
+func NewVersion(service string) Version {
+	host, _ := os.Hostname()
+	return Version{Service: service, Host: host, BuildInfo: env.Info()}
+}
+
+// VersionHandler serves the version of the running binary as JSON, e.g. on
+// /version
+func VersionHandler(service string) http.HandlerFunc {
+	version := NewVersion(service)
+	return func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(version)
+	}
+}
+
+// ServeVersion answers version requests on VersionSubject and
+// VersionSubject.<service>
+func ServeVersion(conn *nats.Conn, service string) ([]*nats.Subscription, error) {
+	data, err := json.Marshal(NewVersion(service))
+	if err != nil {
+		return nil, err
+	}
+	var subs []*nats.Subscription
+	for _, subject := range []string{VersionSubject, VersionSubject + "." + service} {
+		sub, err := conn.Subscribe(subject, func(msg *nats.Msg) { msg.Respond(data) })
+		if err != nil {
+			for _, sub := range subs {
+				sub.Unsubscribe()
+			}
+			return nil, fmt.Errorf("failed to serve version on %s: %w", subject, err)
+		}
+		subs = append(subs, sub)
+	}
+	return subs, nil
+}
@@ -0,0 +1,217 @@
+package eventbus
+
+import (
+	"fmt"
+	"sync"
+	"sync/atomic"
+
+	"github.com/nats-io/nats.go"
+)
+
+// Options configures an event bus
+type Options struct {
+	Compression []CompressionRule // First matching rule wins
+	RPC         []RPCPolicy       // Of the endpoints called, first matching policy wins
+	Priority    []string          // Subject patterns of the priority lane, DefaultPrioritySubjects when nil
+	Lossless    []LosslessRule    // Of the subscriptions switched to JetStream when slow, first matching rule wins
+	// Called on each slow consumer report of the server, from the
+	// goroutine of the asynchronous errors of the connection
+	OnSlowConsumer func(SlowConsumerEvent)
+	// Degrades the deliveries of every subscription, for tests
+	Network *Network
+	// Signs the calls of command endpoints, nil to send them unsigned
+	Signer *Signer
+	// Verifies the commands served, nil to serve them to anyone
+	Authorizer *Authorizer
+	// Called on each command served under the authorizer, refused or not
+	OnCommand func(CommandEvent)
+}
+
+// Bus wraps a NATS connection and applies envelope handling such as
+// compression transparently on Publish and Subscribe
+type Bus struct {
+	conn        *nats.Conn
+	rules       []CompressionRule
+	compression compressionCounters
+	lane        *nats.Conn // Priority lane, nil when not attached
+	priority    []string
+	lanes       laneCounters
+
+	rpcMu        sync.Mutex
+	rpcPolicies  []RPCPolicy
+	rpcEndpoints map[string]*rpcEndpoint // By subject, nil when no policy matches
+
+	subsMu    sync.Mutex
+	subs      map[*nats.Subscription]*tracked
+	slowStats map[slowKey]*SlowConsumerStats
+	lossless  []LosslessRule
+	onSlow    func(SlowConsumerEvent)
+	slow      atomic.Uint64
+
+	network *Network // Nil on a healthy network
+
+	signer     *Signer
+	authorizer *Authorizer
+	onCommand  func(CommandEvent)
+}
+
+// NewBus creates an event bus over an established NATS connection
+func NewBus(conn *nats.Conn, opts Options) (*Bus, error) {
+	for i, rule := range opts.Compression {
+		if err := ValidatePattern(rule.Subject); err != nil {
+			return nil, fmt.Errorf("compression[%d]: %w", i, err)
+		}
+		if _, err := ParseCodec(string(rule.Codec)); err != nil {
+			return nil, fmt.Errorf("compression[%d]: %w", i, err)
+		}
+	}
+	for i, policy := range opts.RPC {
+		if err := policy.Validate(); err != nil {
+			return nil, fmt.Errorf("rpc[%d]: %w", i, err)
+		}
+	}
+	priority := opts.Priority
+	if priority == nil {
+		priority = DefaultPrioritySubjects
+	}
+	for _, pattern := range priority {
+		if err := ValidatePattern(pattern); err != nil {
+			return nil, fmt.Errorf("priority: %w", err)
+		}
+	}
+	for i, rule := range opts.Lossless {
+		if err := rule.Validate(); err != nil {
+			return nil, fmt.Errorf("lossless[%d]: %w", i, err)
+		}
+	}
+	b := &Bus{
+		conn:        conn,
+		rules:       opts.Compression,
+		rpcPolicies: opts.RPC,
+		priority:    priority,
+		subs:        make(map[*nats.Subscription]*tracked),
+		slowStats:   make(map[slowKey]*SlowConsumerStats),
+		lossless:    opts.Lossless,
+		onSlow:      opts.OnSlowConsumer,
+		network:     opts.Network,
+		signer:      opts.Signer,
+		authorizer:  opts.Authorizer,
+		onCommand:   opts.OnCommand,
+	}
+	b.watch(conn)
+	return b, nil
+}
+
+// Conn returns the underlying NATS connection of the bulk lane
+func (b *Bus) Conn() *nats.Conn {
+	return b.conn
+}
+
+// Publish publishes data on a subject
+func (b *Bus) Publish(subject string, data []byte) error {
+	return b.PublishMsg(&nats.Msg{Subject: subject, Data: data})
+}
+
+// PublishMsg encodes and publishes a message
+func (b *Bus) PublishMsg(msg *nats.Msg) error {
+	if err := b.Encode(msg); err != nil {
+		return err
+	}
+	return b.connFor(msg.Subject, true).PublishMsg(msg)
+}
+
+// Subscribe subscribes to a subject and hands decoded messages to the
+// handler. Under a lossless rule, the subscription switches to JetStream
+// once slow, see Release.
+func (b *Bus) Subscribe(subject string, handler nats.MsgHandler) (*nats.Subscription, error) {
+	return b.subscribe(b.connFor(subject, false), subject, "", handler)
+}
+
+// QueueSubscribe is Subscribe within a queue group, never switched to
+// JetStream
+func (b *Bus) QueueSubscribe(subject, queue string, handler nats.MsgHandler) (*nats.Subscription, error) {
+	return b.subscribe(b.connFor(subject, false), subject, queue, handler)
+}
+
+func (b *Bus) wrap(handler nats.MsgHandler) nats.MsgHandler {
+	decode := func(msg *nats.Msg) {
+		if err := Decode(msg); err != nil {
+			// Undecodable payloads are dropped rather than handed over corrupted
+			b.compression.decompressError.Add(1)
+			return
+		}
+		handler(msg)
+	}
+	if b.network != nil {
+		return b.network.Wrap(decode)
+	}
+	return decode
+}
+
+// Encode applies the first matching compression rule to a message in place.
+// It is exported for callers publishing through JetStream contexts.
+func (b *Bus) Encode(msg *nats.Msg) error {
+	rule, ok := b.rule(msg.Subject)
+	if !ok || rule.Codec == CodecNone {
+		return nil
+	}
+	if len(msg.Data) < rule.MinSize {
+		b.compression.skipped.Add(1)
+		return nil
+	}
+	compressed, err := Compress(rule.Codec, msg.Data)
+	if err != nil {
+		return fmt.Errorf("failed to compress payload on %s: %w", msg.Subject, err)
+	}
+	if len(compressed) >= len(msg.Data) {
+		b.compression.skipped.Add(1)
+		return nil
+	}
+
+	b.compression.messages.Add(1)
+	b.compression.bytesIn.Add(uint64(len(msg.Data)))
+	b.compression.bytesOut.Add(uint64(len(compressed)))
+	if msg.Header == nil {
+		msg.Header = nats.Header{}
+	}
+	msg.Header.Set(HeaderEncoding, string(rule.Codec))
+	msg.Data = compressed
+	return nil
+}
+
+func (b *Bus) rule(subject string) (CompressionRule, bool) {
+	for _, rule := range b.rules {
+		if Match(rule.Subject, subject) {
+			return rule, true
+		}
+	}
+	return CompressionRule{}, false
+}
+
+// Decode reverses Encode in place based on the envelope header. It is a no-op
+// for messages published without compression.
+func Decode(msg *nats.Msg) error {
+	if msg.Header == nil {
+		return nil
+	}
+	encoding := msg.Header.Get(HeaderEncoding)
+	if encoding == "" {
+		return nil
+	}
+	codec, err := ParseCodec(encoding)
+	if err != nil {
+		return err
+	}
+	data, err := Decompress(codec, msg.Data)
+	if err != nil {
+		return fmt.Errorf("failed to decompress payload on %s: %w", msg.Subject, err)
+	}
+	msg.Data = data
+	msg.Header.Del(HeaderEncoding)
+	return nil
+}
+
+// CompressionStats returns the compression counters of the bus
+func (b *Bus) CompressionStats() CompressionStats {
+	return b.compression.snapshot()
+}
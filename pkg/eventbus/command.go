@@ -0,0 +1,293 @@
+package eventbus
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"maps"
+	"path"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/nats-io/nats.go"
+)
+
+// Headers of the envelope of a signed command
+const (
+	HeaderCommandOperator  = "Sqx-Operator"
+	HeaderCommandNonce     = "Sqx-Nonce"
+	HeaderCommandExpires   = "Sqx-Expires" // Unix milliseconds
+	HeaderCommandSignature = "Sqx-Signature"
+)
+
+const (
+	DefaultCommandTTL    = 30 * time.Second // Validity of a command signed by a signer without TTL
+	DefaultCommandMaxTTL = 5 * time.Minute  // Validity accepted by an authorizer without MaxTTL
+)
+
+// ErrUnauthorized is returned by Authorize for the commands refused
+var ErrUnauthorized = errors.New("unauthorized")
+
+// ErrReplayed is returned by Authorize, with ErrUnauthorized, for a command
+// whose nonce was accepted already
+var ErrReplayed = errors.New("command replayed")
+
+// CommandPayload returns the message an operator signs to call a command.
+// It covers the subject and the request, so a signature cannot be replayed
+// on another endpoint or with other arguments.
+func CommandPayload(subject, operator, nonce string, expires int64, data []byte) []byte {
+	digest := sha256.Sum256(data)
+	return []byte(strings.Join([]string{
+		"sequex-command-v1", subject, operator, nonce, strconv.FormatInt(expires, 10), hex.EncodeToString(digest[:]),
+	}, "\n"))
+}
+
+// Signer signs the commands called by an operator
+type Signer struct {
+	Operator string
+	Key      ed25519.PrivateKey
+	TTL      time.Duration // Validity of the commands, DefaultCommandTTL when 0
+}
+
+// Sign sets the envelope of a command on msg, whose data must not be
+// encoded yet
+func (s *Signer) Sign(msg *nats.Msg, now time.Time) error {
+	if s.Operator == "" || len(s.Key) != ed25519.PrivateKeySize {
+		return fmt.Errorf("an operator and an ed25519 private key are required to sign commands")
+	}
+	ttl := s.TTL
+	if ttl == 0 {
+		ttl = DefaultCommandTTL
+	}
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return err
+	}
+	nonce := hex.EncodeToString(b)
+	expires := now.Add(ttl).UnixMilli()
+	if msg.Header == nil {
+		msg.Header = nats.Header{}
+	}
+	msg.Header.Set(HeaderCommandOperator, s.Operator)
+	msg.Header.Set(HeaderCommandNonce, nonce)
+	msg.Header.Set(HeaderCommandExpires, strconv.FormatInt(expires, 10))
+	msg.Header.Set(HeaderCommandSignature, base64.StdEncoding.EncodeToString(
+		ed25519.Sign(s.Key, CommandPayload(msg.Subject, s.Operator, nonce, expires, msg.Data))))
+	return nil
+}
+
+// Operator is who may sign commands, with the roles granting them
+type Operator struct {
+	Name  string
+	Key   ed25519.PublicKey
+	Roles []string
+}
+
+// Grant allows calling the endpoints of subject patterns on nodes, names
+// or shell patterns, e.g. * for every node
+type Grant struct {
+	Endpoints []string
+	Nodes     []string
+}
+
+// CommandPolicy maps the operators to the commands they may call
+type CommandPolicy struct {
+	Operators []Operator
+	Roles     map[string][]Grant
+	MaxTTL    time.Duration // Of the commands accepted, DefaultCommandMaxTTL when 0
+}
+
+// Validate validates the policy
+func (p CommandPolicy) Validate() error {
+	names := make(map[string]bool)
+	for _, o := range p.Operators {
+		if o.Name == "" || strings.ContainsAny(o.Name, "\r\n") || len(o.Key) != ed25519.PublicKeySize {
+			return fmt.Errorf("operator %q: a name and an ed25519 public key are required", o.Name)
+		}
+		if names[o.Name] {
+			return fmt.Errorf("duplicate operator %q", o.Name)
+		}
+		names[o.Name] = true
+		for _, role := range o.Roles {
+			if _, ok := p.Roles[role]; !ok {
+				return fmt.Errorf("operator %q: unknown role %q", o.Name, role)
+			}
+		}
+	}
+	for role, grants := range p.Roles {
+		for i, g := range grants {
+			if len(g.Endpoints) == 0 || len(g.Nodes) == 0 {
+				return fmt.Errorf("role %q: grant %d needs endpoints and nodes", role, i)
+			}
+			for _, pattern := range g.Endpoints {
+				if err := ValidatePattern(pattern); err != nil {
+					return fmt.Errorf("role %q: %w", role, err)
+				}
+			}
+			for _, node := range g.Nodes {
+				if _, err := path.Match(node, ""); err != nil {
+					return fmt.Errorf("role %q: invalid node pattern %q", role, node)
+				}
+			}
+		}
+	}
+	if p.MaxTTL < 0 {
+		return fmt.Errorf("max ttl cannot be negative")
+	}
+	return nil
+}
+
+// Authorizer verifies the commands served by a node against a policy.
+// Nonces are remembered until their command expires, so a command is
+// accepted once, along with the reply it was served, which retries of the
+// caller under the same nonce get back.
+type Authorizer struct {
+	node      string
+	operators map[string]Operator
+	grants    map[string][]Grant // Of each operator, from their roles
+	maxTTL    time.Duration
+	now       func() time.Time
+
+	mu     sync.Mutex
+	nonces map[string]*served // By operator and nonce
+	pruned time.Time
+}
+
+// served is a command accepted under a nonce
+type served struct {
+	expires int64     // Unix milliseconds
+	reply   *nats.Msg // Sent to the caller, nil until served
+}
+
+// NewAuthorizer creates the authorizer of the commands served by node
+func NewAuthorizer(node string, policy CommandPolicy) (*Authorizer, error) {
+	if node == "" {
+		return nil, fmt.Errorf("node cannot be empty")
+	}
+	if err := policy.Validate(); err != nil {
+		return nil, err
+	}
+	a := &Authorizer{
+		node:      node,
+		operators: make(map[string]Operator),
+		grants:    make(map[string][]Grant),
+		maxTTL:    policy.MaxTTL,
+		now:       time.Now,
+		nonces:    make(map[string]*served),
+	}
+	if a.maxTTL == 0 {
+		a.maxTTL = DefaultCommandMaxTTL
+	}
+	for _, o := range policy.Operators {
+		a.operators[o.Name] = o
+		for _, role := range o.Roles {
+			a.grants[o.Name] = append(a.grants[o.Name], policy.Roles[role]...)
+		}
+	}
+	return a, nil
+}
+
+// Node returns the node the authorizer verifies the commands of
+func (a *Authorizer) Node() string {
+	return a.node
+}
+
+// Authorize verifies the envelope of a command, decoded, and returns the
+// operator who signed it
+func (a *Authorizer) Authorize(msg *nats.Msg) (string, error) {
+	operator := msg.Header.Get(HeaderCommandOperator)
+	if operator == "" {
+		return "", fmt.Errorf("%w: unsigned command", ErrUnauthorized)
+	}
+	o, ok := a.operators[operator]
+	if !ok {
+		return operator, fmt.Errorf("%w: unknown operator %q", ErrUnauthorized, operator)
+	}
+	nonce := msg.Header.Get(HeaderCommandNonce)
+	expires, err := strconv.ParseInt(msg.Header.Get(HeaderCommandExpires), 10, 64)
+	if nonce == "" || err != nil {
+		return operator, fmt.Errorf("%w: malformed envelope", ErrUnauthorized)
+	}
+	signature, err := base64.StdEncoding.DecodeString(msg.Header.Get(HeaderCommandSignature))
+	if err != nil || !ed25519.Verify(o.Key, CommandPayload(msg.Subject, operator, nonce, expires, msg.Data), signature) {
+		return operator, fmt.Errorf("%w: invalid signature of %q", ErrUnauthorized, operator)
+	}
+	now := a.now()
+	switch {
+	case expires <= now.UnixMilli():
+		return operator, fmt.Errorf("%w: command expired", ErrUnauthorized)
+	case expires > now.Add(a.maxTTL).UnixMilli():
+		return operator, fmt.Errorf("%w: command valid for more than %s", ErrUnauthorized, a.maxTTL)
+	}
+	if !a.allowed(operator, msg.Subject) {
+		return operator, fmt.Errorf("%w: %q may not call %s on %s", ErrUnauthorized, operator, msg.Subject, a.node)
+	}
+
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if now.Sub(a.pruned) >= a.maxTTL {
+		for key, s := range a.nonces {
+			if s.expires <= now.UnixMilli() {
+				delete(a.nonces, key)
+			}
+		}
+		a.pruned = now
+	}
+	key := operator + "\n" + nonce
+	if _, ok := a.nonces[key]; ok {
+		return operator, fmt.Errorf("%w: %w", ErrUnauthorized, ErrReplayed)
+	}
+	a.nonces[key] = &served{expires: expires}
+	return operator, nil
+}
+
+// record keeps the reply served to an authorized command until it expires
+func (a *Authorizer) record(operator string, msg, reply *nats.Msg) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if s, ok := a.nonces[operator+"\n"+msg.Header.Get(HeaderCommandNonce)]; ok {
+		s.reply = &nats.Msg{Header: maps.Clone(reply.Header), Data: reply.Data}
+	}
+}
+
+// replay returns a copy of the reply served to a command Authorize found
+// replayed, nil while it is being served
+func (a *Authorizer) replay(operator string, msg *nats.Msg) *nats.Msg {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	s, ok := a.nonces[operator+"\n"+msg.Header.Get(HeaderCommandNonce)]
+	if !ok || s.reply == nil {
+		return nil
+	}
+	return &nats.Msg{Header: maps.Clone(s.reply.Header), Data: s.reply.Data}
+}
+
+// allowed reports whether a grant of the operator covers subject on the node
+func (a *Authorizer) allowed(operator, subject string) bool {
+	for _, g := range a.grants[operator] {
+		if !matchAny(g.Endpoints, subject) {
+			continue
+		}
+		for _, node := range g.Nodes {
+			if ok, _ := path.Match(node, a.node); ok {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// CommandEvent reports a command served under an authorizer
+type CommandEvent struct {
+	Node     string
+	Subject  string
+	Operator string // Empty for unsigned commands
+	Err      error  // Why the command was refused, nil when authorized
+	Replayed bool   // A retry answered with the reply the command was served
+}
@@ -0,0 +1,81 @@
+package eventbus
+
+import (
+	"crypto/ed25519"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/nats-io/nats.go"
+)
+
+func TestCommandAuthorization(t *testing.T) {
+	alicePub, alice, _ := ed25519.GenerateKey(nil)
+	bobPub, bob, _ := ed25519.GenerateKey(nil)
+	_, mallory, _ := ed25519.GenerateKey(nil)
+	policy := CommandPolicy{
+		Operators: []Operator{
+			{Name: "alice", Key: alicePub, Roles: []string{"risk"}},
+			{Name: "bob", Key: bobPub, Roles: []string{"deployer"}},
+		},
+		Roles: map[string][]Grant{
+			"risk":     {{Endpoints: []string{"guardian.*.pause", "guardian.*.resume"}, Nodes: []string{"fixgateway-*"}}},
+			"deployer": {{Endpoints: []string{"apps.>"}, Nodes: []string{"*"}}},
+		},
+		MaxTTL: time.Minute,
+	}
+	a, err := NewAuthorizer("fixgateway-1", policy)
+	if err != nil {
+		t.Fatal(err)
+	}
+	now := time.Now()
+	a.now = func() time.Time { return now }
+	signed := func(subject, operator string, key ed25519.PrivateKey, ttl time.Duration) *nats.Msg {
+		msg := &nats.Msg{Subject: subject, Data: []byte(`{"strategy":"mm"}`)}
+		if err := (&Signer{Operator: operator, Key: key, TTL: ttl}).Sign(msg, now); err != nil {
+			t.Fatal(err)
+		}
+		return msg
+	}
+
+	msg := signed("guardian.fixgateway.pause", "alice", alice, 0)
+	if operator, err := a.Authorize(msg); err != nil || operator != "alice" {
+		t.Fatalf("Authorize() = %q, %v", operator, err)
+	}
+	if _, err := a.Authorize(msg); !errors.Is(err, ErrUnauthorized) || !errors.Is(err, ErrReplayed) {
+		t.Errorf("replayed command authorized: %v", err)
+	}
+
+	tampered := signed("guardian.fixgateway.pause", "alice", alice, 0)
+	tampered.Data = []byte(`{"strategy":"arb"}`)
+	moved := signed("guardian.fixgateway.pause", "alice", alice, 0)
+	moved.Subject = "guardian.fixgateway.resume"
+	for name, msg := range map[string]*nats.Msg{
+		"unsigned":      {Subject: "guardian.fixgateway.pause", Header: nats.Header{}},
+		"unknown":       signed("guardian.fixgateway.pause", "mallory", mallory, 0),
+		"impersonated":  signed("guardian.fixgateway.pause", "alice", mallory, 0),
+		"tampered":      tampered,
+		"other subject": moved,
+		"not granted":   signed("allocation.fixgateway.set", "alice", alice, 0),
+		"too long":      signed("guardian.fixgateway.pause", "alice", alice, time.Hour),
+		"expired":       signed("guardian.fixgateway.pause", "alice", alice, -time.Second),
+	} {
+		if _, err := a.Authorize(msg); !errors.Is(err, ErrUnauthorized) {
+			t.Errorf("%s command authorized: %v", name, err)
+		}
+	}
+
+	// Grants are per node
+	if _, err := a.Authorize(signed("apps.master.apply", "bob", bob, 0)); err != nil {
+		t.Errorf("bob refused: %v", err)
+	}
+	master, _ := NewAuthorizer("master", policy)
+	if _, err := master.Authorize(signed("guardian.master.pause", "alice", alice, 0)); !errors.Is(err, ErrUnauthorized) {
+		t.Errorf("alice authorized on master: %v", err)
+	}
+
+	policy.Operators[0].Roles = []string{"admin"}
+	if _, err := NewAuthorizer("master", policy); err == nil {
+		t.Error("unknown role accepted")
+	}
+}
@@ -0,0 +1,134 @@
+package eventbus
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+	"sync/atomic"
+
+	"github.com/klauspost/compress/snappy"
+	"github.com/klauspost/compress/zstd"
+)
+
+// HeaderEncoding flags the compression codec of a message payload. Messages
+// without the header are uncompressed, so compressed and plain publishers can
+// share subjects.
+const HeaderEncoding = "Sqx-Encoding"
+
+// Codec is a payload compression algorithm
+type Codec string
+
+const (
+	CodecNone   Codec = ""
+	CodecSnappy Codec = "snappy"
+	CodecZstd   Codec = "zstd"
+)
+
+// ParseCodec parses a codec name
+func ParseCodec(s string) (Codec, error) {
+	switch Codec(strings.ToLower(s)) {
+	case "", "none":
+		return CodecNone, nil
+	case CodecSnappy:
+		return CodecSnappy, nil
+	case CodecZstd:
+		return CodecZstd, nil
+	default:
+		return CodecNone, fmt.Errorf("unknown compression codec: %s", s)
+	}
+}
+
+// zstd encoders and decoders are safe for concurrent EncodeAll/DecodeAll
+var (
+	zstdOnce    sync.Once
+	zstdEncoder *zstd.Encoder
+	zstdDecoder *zstd.Decoder
+	zstdErr     error
+)
+
+func initZstd() error {
+	zstdOnce.Do(func() {
+		zstdEncoder, zstdErr = zstd.NewWriter(nil, zstd.WithEncoderLevel(zstd.SpeedFastest))
+		if zstdErr != nil {
+			return
+		}
+		zstdDecoder, zstdErr = zstd.NewReader(nil, zstd.WithDecoderConcurrency(0))
+	})
+	return zstdErr
+}
+
+// Compress compresses data with the codec
+func Compress(codec Codec, data []byte) ([]byte, error) {
+	switch codec {
+	case CodecNone:
+		return data, nil
+	case CodecSnappy:
+		return snappy.Encode(nil, data), nil
+	case CodecZstd:
+		if err := initZstd(); err != nil {
+			return nil, err
+		}
+		return zstdEncoder.EncodeAll(data, nil), nil
+	default:
+		return nil, fmt.Errorf("unknown compression codec: %s", codec)
+	}
+}
+
+// Decompress reverses Compress
+func Decompress(codec Codec, data []byte) ([]byte, error) {
+	switch codec {
+	case CodecNone:
+		return data, nil
+	case CodecSnappy:
+		return snappy.Decode(nil, data)
+	case CodecZstd:
+		if err := initZstd(); err != nil {
+			return nil, err
+		}
+		return zstdDecoder.DecodeAll(data, nil)
+	default:
+		return nil, fmt.Errorf("unknown compression codec: %s", codec)
+	}
+}
+
+// CompressionRule enables compression on subjects matching a pattern
+type CompressionRule struct {
+	Subject string // Subject pattern, wildcards allowed
+	Codec   Codec
+	MinSize int // Payloads smaller than this are sent uncompressed
+}
+
+// CompressionStats reports the effect of compression on published payloads
+type CompressionStats struct {
+	Messages        uint64 // Messages compressed
+	Skipped         uint64 // Messages matching a rule but below MinSize or not shrinking
+	BytesIn         uint64 // Payload bytes before compression
+	BytesOut        uint64 // Payload bytes after compression
+	DecompressError uint64 // Received messages that failed to decompress
+}
+
+// Ratio returns compressed over original size, 0 when nothing was compressed
+func (s CompressionStats) Ratio() float64 {
+	if s.BytesIn == 0 {
+		return 0
+	}
+	return float64(s.BytesOut) / float64(s.BytesIn)
+}
+
+type compressionCounters struct {
+	messages        atomic.Uint64
+	skipped         atomic.Uint64
+	bytesIn         atomic.Uint64
+	bytesOut        atomic.Uint64
+	decompressError atomic.Uint64
+}
+
+func (c *compressionCounters) snapshot() CompressionStats {
+	return CompressionStats{
+		Messages:        c.messages.Load(),
+		Skipped:         c.skipped.Load(),
+		BytesIn:         c.bytesIn.Load(),
+		BytesOut:        c.bytesOut.Load(),
+		DecompressError: c.decompressError.Load(),
+	}
+}
@@ -0,0 +1,82 @@
+package eventbus
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/nats-io/nats.go"
+)
+
+func TestCompress_RoundTrip(t *testing.T) {
+	payload := bytes.Repeat([]byte(`{"price":60000.1,"quantity":0.5},`), 200)
+	for _, codec := range []Codec{CodecNone, CodecSnappy, CodecZstd} {
+		compressed, err := Compress(codec, payload)
+		if err != nil {
+			t.Fatalf("Compress(%s) returned error: %v", codec, err)
+		}
+		decompressed, err := Decompress(codec, compressed)
+		if err != nil {
+			t.Fatalf("Decompress(%s) returned error: %v", codec, err)
+		}
+		if !bytes.Equal(decompressed, payload) {
+			t.Errorf("codec %s did not round trip", codec)
+		}
+	}
+}
+
+func TestBus_EncodeDecode(t *testing.T) {
+	bus, err := NewBus(nil, Options{Compression: []CompressionRule{
+		{Subject: "depth.>", Codec: CodecZstd, MinSize: 64},
+		{Subject: "kline.>", Codec: CodecSnappy},
+	}})
+	if err != nil {
+		t.Fatalf("NewBus() returned error: %v", err)
+	}
+
+	large := bytes.Repeat([]byte("level,"), 100)
+	msg := &nats.Msg{Subject: "depth.btcusdt", Data: append([]byte{}, large...)}
+	if err := bus.Encode(msg); err != nil {
+		t.Fatalf("Encode() returned error: %v", err)
+	}
+	if msg.Header.Get(HeaderEncoding) != string(CodecZstd) || len(msg.Data) >= len(large) {
+		t.Fatalf("expected zstd compressed payload, header=%q size=%d", msg.Header.Get(HeaderEncoding), len(msg.Data))
+	}
+	if err := Decode(msg); err != nil {
+		t.Fatalf("Decode() returned error: %v", err)
+	}
+	if !bytes.Equal(msg.Data, large) || msg.Header.Get(HeaderEncoding) != "" {
+		t.Error("Decode() did not restore the original payload")
+	}
+
+	// Small payloads and unmatched subjects are left untouched
+	small := &nats.Msg{Subject: "depth.btcusdt", Data: []byte("tiny")}
+	plain := &nats.Msg{Subject: "trade.btcusdt", Data: large}
+	for _, m := range []*nats.Msg{small, plain} {
+		if err := bus.Encode(m); err != nil {
+			t.Fatalf("Encode() returned error: %v", err)
+		}
+		if m.Header != nil {
+			t.Errorf("message on %s should not be compressed", m.Subject)
+		}
+		if err := Decode(m); err != nil {
+			t.Errorf("Decode() of plain message returned error: %v", err)
+		}
+	}
+
+	stats := bus.CompressionStats()
+	if stats.Messages != 1 || stats.Skipped != 1 {
+		t.Errorf("stats = %+v, expected 1 compressed and 1 skipped", stats)
+	}
+	if stats.Ratio() <= 0 || stats.Ratio() >= 1 {
+		t.Errorf("Ratio() = %v, expected between 0 and 1", stats.Ratio())
+	}
+
+	corrupt := &nats.Msg{Subject: "kline.btcusdt", Data: []byte{0xff, 0xff}, Header: nats.Header{HeaderEncoding: []string{"snappy"}}}
+	if err := Decode(corrupt); err == nil {
+		t.Error("expected error decoding corrupt payload")
+	}
+
+	if _, err := NewBus(nil, Options{Compression: []CompressionRule{{Subject: "depth.>", Codec: "lz4"}}}); err == nil {
+		t.Error("expected error for unknown codec")
+	}
+}
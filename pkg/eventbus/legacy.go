@@ -0,0 +1,42 @@
+package eventbus
+
+import (
+	"fmt"
+	"strings"
+)
+
+// LegacyMapper translates legacy subjects into the namespaced form. Two
+// legacy layouts are in use:
+//
+//	<datatype>.<symbol>                          e.g. trade.btcusdt
+//	<datatype>.<exchange>.<instrument>.<symbol>  e.g. trade.binance.spot.btcusdt
+//
+// The short form carries no venue, so DefaultExchange and DefaultInstrument
+// are used for it.
+type LegacyMapper struct {
+	Env               string
+	DefaultExchange   string
+	DefaultInstrument string
+}
+
+// Map returns the namespaced subject of a legacy subject
+func (m LegacyMapper) Map(legacy string) (Subject, error) {
+	tokens := strings.Split(legacy, ".")
+	switch len(tokens) {
+	case 2:
+		if m.DefaultExchange == "" || m.DefaultInstrument == "" {
+			return Subject{}, fmt.Errorf("legacy subject %q has no venue and no default is configured", legacy)
+		}
+		return NewSubject(m.Env, m.DefaultExchange, m.DefaultInstrument, tokens[1], tokens[0])
+	case 4:
+		return NewSubject(m.Env, tokens[1], tokens[2], tokens[3], tokens[0])
+	default:
+		return Subject{}, fmt.Errorf("unrecognized legacy subject %q", legacy)
+	}
+}
+
+// IsNamespaced reports whether a subject already follows the namespace of env
+func IsNamespaced(env, subject string) bool {
+	s, err := ParseSubject(subject)
+	return err == nil && s.Env == env
+}
@@ -0,0 +1,341 @@
+package eventbus
+
+import (
+	"container/heap"
+	"fmt"
+	"math/rand"
+	"sync"
+	"time"
+
+	"github.com/nats-io/nats.go"
+)
+
+// Distribution is the shape of the latency of degraded deliveries
+type Distribution string
+
+const (
+	DistConstant    Distribution = "constant"    // Latency
+	DistUniform     Distribution = "uniform"     // Latency ± Jitter
+	DistNormal      Distribution = "normal"      // Mean Latency, standard deviation Jitter
+	DistExponential Distribution = "exponential" // Latency plus a long tail of mean Jitter
+)
+
+// DegradeRule simulates a degraded network on the subjects matching Subject.
+// Messages of a subject stay in order, as NATS delivers those of a publisher,
+// unless reordered on purpose.
+type DegradeRule struct {
+	Subject      string
+	Latency      time.Duration
+	Jitter       time.Duration
+	Distribution Distribution  // Uniform when empty
+	DropRate     float64       // Share of the messages lost, from 0 to 1
+	ReorderRate  float64       // Share of the messages held back so the next ones overtake them
+	ReorderDelay time.Duration // Held back by, Latency+Jitter when 0, 1ms at least
+}
+
+// Validate validates the rule
+func (r DegradeRule) Validate() error {
+	if err := ValidatePattern(r.Subject); err != nil {
+		return err
+	}
+	if r.Latency < 0 || r.Jitter < 0 || r.ReorderDelay < 0 {
+		return fmt.Errorf("%s: delays cannot be negative", r.Subject)
+	}
+	switch r.Distribution {
+	case "", DistConstant, DistUniform, DistNormal, DistExponential:
+	default:
+		return fmt.Errorf("%s: unknown distribution %q", r.Subject, r.Distribution)
+	}
+	if r.DropRate < 0 || r.DropRate > 1 || r.ReorderRate < 0 || r.ReorderRate > 1 {
+		return fmt.Errorf("%s: rates must be between 0 and 1", r.Subject)
+	}
+	return nil
+}
+
+// Clock schedules the degraded deliveries, the wall clock by default
+type Clock interface {
+	Now() time.Time
+	AfterFunc(d time.Duration, f func())
+}
+
+type wallClock struct{}
+
+func (wallClock) Now() time.Time                      { return time.Now() }
+func (wallClock) AfterFunc(d time.Duration, f func()) { time.AfterFunc(d, f) }
+
+// NetworkOptions configures a simulated network
+type NetworkOptions struct {
+	Rules []DegradeRule // First matching rule wins, messages matching none are delivered at once
+	Seed  int64         // Of the random delays and losses, from the time when 0
+	Clock Clock         // ManualClock makes tests deterministic, the wall clock when nil
+}
+
+// Network simulates latency, jitter, reordering and loss on the deliveries
+// of message handlers, so strategy and order management logic can be
+// validated against degraded conditions before production meets them. Pass
+// it in Options to degrade every subscription of a bus, or wrap the handlers
+// of a test directly.
+type Network struct {
+	rules []DegradeRule
+	clock Clock
+
+	mu    sync.Mutex
+	rand  *rand.Rand
+	stats []DegradeStats
+}
+
+// DegradeStats counts the messages a rule degraded
+type DegradeStats struct {
+	Subject   string        `json:"subject"`
+	Messages  uint64        `json:"messages"`
+	Dropped   uint64        `json:"dropped"`
+	Reordered uint64        `json:"reordered"`
+	MeanDelay time.Duration `json:"mean_delay"` // Of the messages delivered
+	delay     time.Duration
+}
+
+// NewNetwork creates a simulated network
+func NewNetwork(opts NetworkOptions) (*Network, error) {
+	for i, rule := range opts.Rules {
+		if err := rule.Validate(); err != nil {
+			return nil, fmt.Errorf("rules[%d]: %w", i, err)
+		}
+	}
+	seed := opts.Seed
+	if seed == 0 {
+		seed = time.Now().UnixNano()
+	}
+	n := &Network{
+		rules: opts.Rules,
+		clock: opts.Clock,
+		rand:  rand.New(rand.NewSource(seed)),
+		stats: make([]DegradeStats, len(opts.Rules)),
+	}
+	if n.clock == nil {
+		n.clock = wallClock{}
+	}
+	for i, rule := range opts.Rules {
+		n.stats[i].Subject = rule.Subject
+	}
+	return n, nil
+}
+
+// Stats returns the counters of every rule
+func (n *Network) Stats() []DegradeStats {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	stats := append([]DegradeStats(nil), n.stats...)
+	for i := range stats {
+		if delivered := stats[i].Messages - stats[i].Dropped; delivered > 0 {
+			stats[i].MeanDelay = stats[i].delay / time.Duration(delivered)
+		}
+	}
+	return stats
+}
+
+func (n *Network) rule(subject string) int {
+	for i := range n.rules {
+		if Match(n.rules[i].Subject, subject) {
+			return i
+		}
+	}
+	return -1
+}
+
+// Wrap returns handler behind the simulated network. Messages are handed
+// over one at a time, as on a subscription, in the order of their
+// simulated arrival.
+func (n *Network) Wrap(handler nats.MsgHandler) nats.MsgHandler {
+	l := &link{network: n, handler: handler, last: make(map[string]time.Time)}
+	return l.send
+}
+
+// delay draws the fate of a message under rule i: whether it is lost, its
+// latency and whether it is held back
+func (n *Network) delay(i int) (time.Duration, bool, bool) {
+	r := n.rules[i]
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	stats := &n.stats[i]
+	stats.Messages++
+	if r.DropRate > 0 && n.rand.Float64() < r.DropRate {
+		stats.Dropped++
+		return 0, true, false
+	}
+	var d time.Duration
+	jitter := float64(r.Jitter)
+	switch r.Distribution {
+	case DistConstant:
+		d = r.Latency
+	case DistNormal:
+		d = r.Latency + time.Duration(n.rand.NormFloat64()*jitter)
+	case DistExponential:
+		d = r.Latency + time.Duration(n.rand.ExpFloat64()*jitter)
+	default:
+		d = r.Latency + time.Duration((2*n.rand.Float64()-1)*jitter)
+	}
+	d = max(d, 0)
+	reordered := r.ReorderRate > 0 && n.rand.Float64() < r.ReorderRate
+	if reordered {
+		hold := r.ReorderDelay
+		if hold == 0 {
+			hold = r.Latency + r.Jitter
+		}
+		d += max(hold, time.Millisecond)
+		stats.Reordered++
+	}
+	stats.delay += d
+	return d, false, reordered
+}
+
+// link delivers the messages of one handler
+type link struct {
+	network *Network
+	handler nats.MsgHandler
+
+	mu      sync.Mutex
+	queue   deliveries
+	seq     uint64
+	last    map[string]time.Time // Arrival of the last in-order message per subject
+	deliver sync.Mutex           // Serializes the handler
+}
+
+type delivery struct {
+	at  time.Time
+	seq uint64
+	msg *nats.Msg
+}
+
+func (l *link) send(msg *nats.Msg) {
+	i := l.network.rule(msg.Subject)
+	if i < 0 {
+		l.deliver.Lock()
+		defer l.deliver.Unlock()
+		l.handler(msg)
+		return
+	}
+	d, dropped, reordered := l.network.delay(i)
+	if dropped {
+		return
+	}
+	now := l.network.clock.Now()
+	at := now.Add(d)
+	l.mu.Lock()
+	if !reordered {
+		// In order messages never overtake the previous ones of the subject
+		if last, ok := l.last[msg.Subject]; ok && at.Before(last) {
+			at = last
+		}
+		l.last[msg.Subject] = at
+	}
+	l.seq++
+	heap.Push(&l.queue, delivery{at: at, seq: l.seq, msg: msg})
+	l.mu.Unlock()
+	l.network.clock.AfterFunc(at.Sub(now), l.flush)
+}
+
+// flush delivers the messages that arrived, in order of arrival
+func (l *link) flush() {
+	l.deliver.Lock()
+	defer l.deliver.Unlock()
+	for {
+		l.mu.Lock()
+		if l.queue.Len() == 0 || l.queue[0].at.After(l.network.clock.Now()) {
+			l.mu.Unlock()
+			return
+		}
+		next := heap.Pop(&l.queue).(delivery)
+		l.mu.Unlock()
+		l.handler(next.msg)
+	}
+}
+
+type deliveries []delivery
+
+func (d deliveries) Len() int { return len(d) }
+func (d deliveries) Less(i, j int) bool {
+	if d[i].at.Equal(d[j].at) {
+		return d[i].seq < d[j].seq
+	}
+	return d[i].at.Before(d[j].at)
+}
+func (d deliveries) Swap(i, j int) { d[i], d[j] = d[j], d[i] }
+func (d *deliveries) Push(x any)   { *d = append(*d, x.(delivery)) }
+func (d *deliveries) Pop() any {
+	old := *d
+	x := old[len(old)-1]
+	*d = old[:len(old)-1]
+	return x
+}
+
+// ManualClock is a Clock advanced by hand, so tests run degraded networks
+// without sleeping
+type ManualClock struct {
+	mu     sync.Mutex
+	now    time.Time
+	timers []manualTimer
+	seq    uint64
+}
+
+type manualTimer struct {
+	at  time.Time
+	seq uint64
+	f   func()
+}
+
+// NewManualClock creates a clock at start
+func NewManualClock(start time.Time) *ManualClock {
+	return &ManualClock{now: start}
+}
+
+// Now implements Clock
+func (c *ManualClock) Now() time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.now
+}
+
+// AfterFunc implements Clock
+func (c *ManualClock) AfterFunc(d time.Duration, f func()) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.seq++
+	c.timers = append(c.timers, manualTimer{at: c.now.Add(d), seq: c.seq, f: f})
+}
+
+// Advance moves the clock forward by d, running the timers due in order on
+// the calling goroutine
+func (c *ManualClock) Advance(d time.Duration) {
+	c.mu.Lock()
+	end := c.now.Add(d)
+	c.mu.Unlock()
+	for {
+		c.mu.Lock()
+		next := -1
+		for i, t := range c.timers {
+			if !t.at.After(end) && (next < 0 || t.at.Before(c.timers[next].at) ||
+				(t.at.Equal(c.timers[next].at) && t.seq < c.timers[next].seq)) {
+				next = i
+			}
+		}
+		if next < 0 {
+			c.now = end
+			c.mu.Unlock()
+			return
+		}
+		t := c.timers[next]
+		c.timers = append(c.timers[:next], c.timers[next+1:]...)
+		if t.at.After(c.now) {
+			c.now = t.at
+		}
+		c.mu.Unlock()
+		t.f()
+	}
+}
+
+// Pending returns the number of timers not run yet
+func (c *ManualClock) Pending() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return len(c.timers)
+}
@@ -0,0 +1,77 @@
+package eventbus
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/nats-io/nats.go"
+)
+
+func TestNetwork(t *testing.T) {
+	for _, rule := range []DegradeRule{
+		{Subject: "trade.>", Latency: -time.Millisecond},
+		{Subject: "trade.>", DropRate: 1.5},
+		{Subject: "trade.>", Distribution: "pareto"},
+	} {
+		if _, err := NewNetwork(NetworkOptions{Rules: []DegradeRule{rule}}); err == nil {
+			t.Errorf("rule %+v accepted", rule)
+		}
+	}
+
+	clock := NewManualClock(time.Unix(0, 0))
+	n, err := NewNetwork(NetworkOptions{
+		Rules: []DegradeRule{
+			{Subject: "lost.>", DropRate: 1},
+			{Subject: "reordered.>", Latency: time.Millisecond, Distribution: DistConstant, ReorderRate: 1, ReorderDelay: 50 * time.Millisecond},
+			{Subject: "trade.>", Latency: 10 * time.Millisecond, Jitter: 8 * time.Millisecond, Distribution: DistNormal},
+		},
+		Seed:  1,
+		Clock: clock,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got []string
+	handler := n.Wrap(func(msg *nats.Msg) { got = append(got, msg.Subject+":"+string(msg.Data)) })
+
+	// Jitter never reorders the messages of a subject
+	for i := 0; i < 50; i++ {
+		handler(&nats.Msg{Subject: "trade.btcusdt", Data: []byte(fmt.Sprint(i))})
+	}
+	handler(&nats.Msg{Subject: "healthy"})
+	if len(got) != 1 || got[0] != "healthy:" {
+		t.Fatalf("delivered before the latency: %v", got)
+	}
+	clock.Advance(time.Second)
+	if len(got) != 51 || clock.Pending() != 0 {
+		t.Fatalf("delivered %d messages, %d pending", len(got), clock.Pending())
+	}
+	for i, s := range got[1:] {
+		if s != fmt.Sprintf("trade.btcusdt:%d", i) {
+			t.Fatalf("message %d delivered as %s", i, s)
+		}
+	}
+
+	// Held back messages are overtaken
+	got = nil
+	handler(&nats.Msg{Subject: "reordered.a", Data: []byte("1")})
+	clock.Advance(time.Millisecond)
+	handler(&nats.Msg{Subject: "trade.btcusdt", Data: []byte("2")})
+	handler(&nats.Msg{Subject: "lost.a"})
+	clock.Advance(time.Second)
+	if len(got) != 2 || got[0] != "trade.btcusdt:2" || got[1] != "reordered.a:1" {
+		t.Errorf("delivered %v", got)
+	}
+
+	stats := n.Stats()
+	if stats[0].Messages != 1 || stats[0].Dropped != 1 {
+		t.Errorf("lost stats = %+v", stats[0])
+	}
+	if stats[1].Reordered != 1 || stats[1].MeanDelay != 51*time.Millisecond {
+		t.Errorf("reordered stats = %+v", stats[1])
+	}
+	if stats[2].Messages != 51 || stats[2].MeanDelay == 0 {
+		t.Errorf("trade stats = %+v", stats[2])
+	}
+}
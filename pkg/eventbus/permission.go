@@ -0,0 +1,97 @@
+package eventbus
+
+import "fmt"
+
+// InboxPattern is the reply subject space used by NATS request/reply
+const InboxPattern = "_INBOX.>"
+
+// Permissions lists the subject patterns a service may publish and subscribe to
+type Permissions struct {
+	Publish   []string `json:"publish"`
+	Subscribe []string `json:"subscribe"`
+}
+
+// Validate checks every pattern of the permission set
+func (p Permissions) Validate() error {
+	for _, pattern := range append(append([]string{}, p.Publish...), p.Subscribe...) {
+		if pattern == InboxPattern {
+			continue
+		}
+		if err := ValidatePattern(pattern); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// CanPublish reports whether the subject is covered by a publish pattern
+func (p Permissions) CanPublish(subject string) bool {
+	return matchAny(p.Publish, subject)
+}
+
+// CanSubscribe reports whether the subject is covered by a subscribe pattern
+func (p Permissions) CanSubscribe(subject string) bool {
+	return matchAny(p.Subscribe, subject)
+}
+
+func matchAny(patterns []string, subject string) bool {
+	for _, pattern := range patterns {
+		if Match(pattern, subject) {
+			return true
+		}
+	}
+	return false
+}
+
+// NATSPermissions is the authorization block of a nats-server user
+type NATSPermissions struct {
+	Publish   NATSPermission `json:"publish"`
+	Subscribe NATSPermission `json:"subscribe"`
+}
+
+// NATSPermission lists allowed subjects for one direction
+type NATSPermission struct {
+	Allow []string `json:"allow"`
+}
+
+// NATS converts the permission set to a nats-server authorization block.
+// Request/reply inboxes are always allowed for subscriptions.
+func (p Permissions) NATS() NATSPermissions {
+	subscribe := append([]string{}, p.Subscribe...)
+	if !contains(subscribe, InboxPattern) {
+		subscribe = append(subscribe, InboxPattern)
+	}
+	return NATSPermissions{
+		Publish:   NATSPermission{Allow: append([]string{}, p.Publish...)},
+		Subscribe: NATSPermission{Allow: subscribe},
+	}
+}
+
+func contains(items []string, item string) bool {
+	for _, v := range items {
+		if v == item {
+			return true
+		}
+	}
+	return false
+}
+
+// PublisherPermissions allows a feed to publish one data type of an
+// environment and nothing else
+func PublisherPermissions(env, dataType string) (Permissions, error) {
+	p := Permissions{Publish: []string{DataTypeFilter(env, dataType)}}
+	if err := p.Validate(); err != nil {
+		return Permissions{}, fmt.Errorf("invalid publisher permissions: %w", err)
+	}
+	return p, nil
+}
+
+// ReaderPermissions allows a consumer to subscribe to everything of an
+// environment without publishing
+func ReaderPermissions(env string) (Permissions, error) {
+	p := Permissions{Subscribe: []string{EnvFilter(env)}}
+	if err := p.Validate(); err != nil {
+		return Permissions{}, fmt.Errorf("invalid reader permissions: %w", err)
+	}
+	return p, nil
+}
@@ -0,0 +1,283 @@
+package eventbus
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/nats-io/nats.go"
+)
+
+// ErrCircuitOpen is returned without calling when the endpoint failed too
+// many times in a row recently
+var ErrCircuitOpen = errors.New("circuit open")
+
+// RPCPolicy bounds the calls made by Endpoint.Call to the endpoints of a
+// subject pattern. The zero policy makes a single attempt bounded by the
+// context only.
+type RPCPolicy struct {
+	Subject       string        // Subject pattern, wildcards allowed
+	Timeout       time.Duration // Of each attempt, 0 for the deadline of the context
+	Retries       int           // Attempts after a timeout or no responders
+	Backoff       time.Duration // Wait before the first retry, doubled before each next one
+	MaxBackoff    time.Duration // Cap of the backoff, 0 for none
+	BreakAfter    int           // Consecutive failures opening the circuit, 0 disables it
+	BreakFor      time.Duration // Calls fail fast while open, then one call probes the endpoint
+	MaxConcurrent int           // Calls in flight, further calls wait; 0 for no limit
+}
+
+// Validate validates the policy
+func (p RPCPolicy) Validate() error {
+	if err := ValidatePattern(p.Subject); err != nil {
+		return err
+	}
+	if p.Timeout < 0 || p.Backoff < 0 || p.MaxBackoff < 0 || p.BreakFor < 0 {
+		return fmt.Errorf("%s: durations cannot be negative", p.Subject)
+	}
+	if p.Retries < 0 || p.BreakAfter < 0 || p.MaxConcurrent < 0 {
+		return fmt.Errorf("%s: retries, break after and max concurrent cannot be negative", p.Subject)
+	}
+	if p.BreakAfter > 0 && p.BreakFor == 0 {
+		return fmt.Errorf("%s: break for is required with break after", p.Subject)
+	}
+	return nil
+}
+
+// backoff returns the wait before retry n, from 1
+func (p RPCPolicy) backoff(n int) time.Duration {
+	wait := p.Backoff
+	for i := 1; i < n && wait > 0; i++ {
+		wait *= 2
+		if p.MaxBackoff > 0 && wait >= p.MaxBackoff {
+			break
+		}
+	}
+	if p.MaxBackoff > 0 && wait > p.MaxBackoff {
+		wait = p.MaxBackoff
+	}
+	return wait
+}
+
+// CircuitState is the state of the circuit breaker of an endpoint
+type CircuitState string
+
+const (
+	CircuitClosed   CircuitState = "closed"
+	CircuitOpen     CircuitState = "open"
+	CircuitHalfOpen CircuitState = "half_open" // Probing the endpoint
+)
+
+// RPCStats reports the calls made to an endpoint under a policy
+type RPCStats struct {
+	Subject   string       `json:"subject"`
+	Calls     uint64       `json:"calls"`
+	Failures  uint64       `json:"failures"`  // Calls failed after their retries
+	Retries   uint64       `json:"retries"`   // Attempts after the first one
+	Timeouts  uint64       `json:"timeouts"`  // Attempts timed out or without responders
+	Rejected  uint64       `json:"rejected"`  // Calls failed fast by the open circuit
+	InFlight  int64        `json:"in_flight"` // Calls running or waiting for a slot
+	Circuit   CircuitState `json:"circuit"`
+	LastError string       `json:"last_error,omitempty"`
+}
+
+// rpcEndpoint is the state of the calls to one subject
+type rpcEndpoint struct {
+	subject string
+	policy  RPCPolicy
+	slots   chan struct{} // Nil without concurrency limit
+
+	calls    atomic.Uint64
+	failures atomic.Uint64
+	retries  atomic.Uint64
+	timeouts atomic.Uint64
+	rejected atomic.Uint64
+	inFlight atomic.Int64
+
+	mu        sync.Mutex
+	failed    int // Consecutive failures
+	openUntil time.Time
+	probing   bool
+	lastError string
+}
+
+func newRPCEndpoint(subject string, policy RPCPolicy) *rpcEndpoint {
+	e := &rpcEndpoint{subject: subject, policy: policy}
+	if policy.MaxConcurrent > 0 {
+		e.slots = make(chan struct{}, policy.MaxConcurrent)
+	}
+	return e
+}
+
+// allow reports whether a call may go through the circuit at now
+func (e *rpcEndpoint) allow(now time.Time) bool {
+	if e.policy.BreakAfter == 0 {
+		return true
+	}
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if e.failed < e.policy.BreakAfter {
+		return true
+	}
+	if now.Before(e.openUntil) || e.probing {
+		return false
+	}
+	e.probing = true
+	return true
+}
+
+// record updates the circuit with the outcome of a call, err being a
+// failure to reach the endpoint rather than an error it answered with
+func (e *rpcEndpoint) record(err error, now time.Time) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.probing = false
+	if err == nil {
+		e.failed = 0
+		return
+	}
+	e.lastError = err.Error()
+	e.failed++
+	if e.policy.BreakAfter > 0 && e.failed >= e.policy.BreakAfter {
+		e.openUntil = now.Add(e.policy.BreakFor)
+	}
+}
+
+func (e *rpcEndpoint) stats(now time.Time) RPCStats {
+	e.mu.Lock()
+	circuit := CircuitClosed
+	switch {
+	case e.policy.BreakAfter == 0 || e.failed < e.policy.BreakAfter:
+	case e.probing || !now.Before(e.openUntil):
+		circuit = CircuitHalfOpen
+	default:
+		circuit = CircuitOpen
+	}
+	lastError := e.lastError
+	e.mu.Unlock()
+	return RPCStats{
+		Subject:   e.subject,
+		Calls:     e.calls.Load(),
+		Failures:  e.failures.Load(),
+		Retries:   e.retries.Load(),
+		Timeouts:  e.timeouts.Load(),
+		Rejected:  e.rejected.Load(),
+		InFlight:  e.inFlight.Load(),
+		Circuit:   circuit,
+		LastError: lastError,
+	}
+}
+
+// rpcEndpoint returns the state of the calls to subject, nil when no policy
+// matches it
+func (b *Bus) rpcEndpoint(subject string) *rpcEndpoint {
+	b.rpcMu.Lock()
+	defer b.rpcMu.Unlock()
+	if e, ok := b.rpcEndpoints[subject]; ok {
+		return e
+	}
+	var e *rpcEndpoint
+	for _, policy := range b.rpcPolicies {
+		if Match(policy.Subject, subject) {
+			e = newRPCEndpoint(subject, policy)
+			break
+		}
+	}
+	if b.rpcEndpoints == nil {
+		b.rpcEndpoints = make(map[string]*rpcEndpoint)
+	}
+	b.rpcEndpoints[subject] = e
+	return e
+}
+
+// RPCStats returns the statistics of the endpoints called under a policy,
+// sorted by subject
+func (b *Bus) RPCStats() []RPCStats {
+	b.rpcMu.Lock()
+	endpoints := make([]*rpcEndpoint, 0, len(b.rpcEndpoints))
+	for _, e := range b.rpcEndpoints {
+		if e != nil {
+			endpoints = append(endpoints, e)
+		}
+	}
+	b.rpcMu.Unlock()
+	now := time.Now()
+	stats := make([]RPCStats, 0, len(endpoints))
+	for _, e := range endpoints {
+		stats = append(stats, e.stats(now))
+	}
+	sort.Slice(stats, func(i, j int) bool { return stats[i].Subject < stats[j].Subject })
+	return stats
+}
+
+// retryable reports whether an attempt failed to reach the endpoint in a
+// way another attempt may not
+func retryable(err error) bool {
+	return errors.Is(err, context.DeadlineExceeded) || errors.Is(err, nats.ErrTimeout) || errors.Is(err, nats.ErrNoResponders)
+}
+
+// request sends msg under the policy of its subject, if any
+func (b *Bus) request(ctx context.Context, msg *nats.Msg) (*nats.Msg, error) {
+	e := b.rpcEndpoint(msg.Subject)
+	if e == nil {
+		return b.connFor(msg.Subject, false).RequestMsgWithContext(ctx, msg)
+	}
+	e.calls.Add(1)
+	e.inFlight.Add(1)
+	defer e.inFlight.Add(-1)
+
+	if e.slots != nil {
+		select {
+		case e.slots <- struct{}{}:
+			defer func() { <-e.slots }()
+		case <-ctx.Done():
+			e.failures.Add(1)
+			return nil, ctx.Err()
+		}
+	}
+	if !e.allow(time.Now()) {
+		e.rejected.Add(1)
+		e.failures.Add(1)
+		return nil, ErrCircuitOpen
+	}
+
+	var reply *nats.Msg
+	var err error
+	for attempt := 0; ; attempt++ {
+		reply, err = b.attempt(ctx, msg, e.policy.Timeout)
+		if err == nil || !retryable(err) {
+			break
+		}
+		e.timeouts.Add(1)
+		if attempt == e.policy.Retries || ctx.Err() != nil {
+			break
+		}
+		e.retries.Add(1)
+		if wait := e.policy.backoff(attempt + 1); wait > 0 {
+			timer := time.NewTimer(wait)
+			select {
+			case <-timer.C:
+			case <-ctx.Done():
+				timer.Stop()
+			}
+		}
+	}
+	e.record(err, time.Now())
+	if err != nil {
+		e.failures.Add(1)
+	}
+	return reply, err
+}
+
+// attempt sends msg once, within timeout when set
+func (b *Bus) attempt(ctx context.Context, msg *nats.Msg, timeout time.Duration) (*nats.Msg, error) {
+	if timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, timeout)
+		defer cancel()
+	}
+	return b.connFor(msg.Subject, false).RequestMsgWithContext(ctx, msg)
+}
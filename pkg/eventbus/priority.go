@@ -0,0 +1,67 @@
+package eventbus
+
+import (
+	"sync/atomic"
+
+	"github.com/nats-io/nats.go"
+)
+
+// DefaultPrioritySubjects are the control-plane subjects of order entry and
+// risk, of the deployment and of its tenants under their default subject
+// prefix, routed through the priority lane unless Options.Priority says
+// otherwise
+var DefaultPrioritySubjects = []string{"oms.>", "risk.>", "tenant.*.oms.>", "tenant.*.risk.>"}
+
+// LaneStats reports the messages published through each lane
+type LaneStats struct {
+	Priority uint64 `json:"priority"`
+	Bulk     uint64 `json:"bulk"`
+}
+
+type laneCounters struct {
+	priority atomic.Uint64
+	bulk     atomic.Uint64
+}
+
+// AttachPriorityLane routes the priority subjects of the bus through conn,
+// a connection of its own so that their messages never wait behind bulk
+// market data in the socket buffers and flusher of the main connection.
+// It must be called before publishing or subscribing.
+func (b *Bus) AttachPriorityLane(conn *nats.Conn) {
+	b.lane = conn
+	b.watch(conn)
+}
+
+// Prioritized reports whether messages on subject, or a subscription
+// pattern within the priority subjects, go through the priority lane
+func (b *Bus) Prioritized(subject string) bool {
+	if b.lane == nil {
+		return false
+	}
+	for _, pattern := range b.priority {
+		if Match(pattern, subject) {
+			return true
+		}
+	}
+	return false
+}
+
+// connFor returns the connection of the lane of subject, counting a
+// published message when publish is set
+func (b *Bus) connFor(subject string, publish bool) *nats.Conn {
+	if b.Prioritized(subject) {
+		if publish {
+			b.lanes.priority.Add(1)
+		}
+		return b.lane
+	}
+	if publish {
+		b.lanes.bulk.Add(1)
+	}
+	return b.conn
+}
+
+// LaneStats returns the messages published through each lane
+func (b *Bus) LaneStats() LaneStats {
+	return LaneStats{Priority: b.lanes.priority.Load(), Bulk: b.lanes.bulk.Load()}
+}
@@ -0,0 +1,48 @@
+package eventbus
+
+import (
+	"testing"
+
+	"github.com/nats-io/nats.go"
+)
+
+func TestPriorityLane(t *testing.T) {
+	bulk, lane := &nats.Conn{}, &nats.Conn{}
+	b, err := NewBus(bulk, Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if b.Prioritized("risk.control") {
+		t.Error("prioritized without a lane")
+	}
+	b.AttachPriorityLane(lane)
+	for subject, want := range map[string]bool{
+		"risk.control":                 true,
+		"oms.intent.order":             true,
+		"oms.execution.>":              true,
+		"tenant.acme.oms.intent.order": true,
+		"tenant.acme.risk.control":     true,
+		"tenant.acme.trade.btcusdt":    false,
+		"trade.binance.spot":           false,
+		">":                            false,
+	} {
+		if got := b.Prioritized(subject); got != want {
+			t.Errorf("Prioritized(%s) = %v", subject, got)
+		}
+	}
+	if b.connFor("oms.intent.order", true) != lane || b.connFor("trade.btcusdt", true) != bulk {
+		t.Error("messages routed to the wrong lane")
+	}
+	if b.connFor("tenant.acme.oms.intent.order", true) != lane {
+		t.Error("order of a tenant routed to the bulk lane")
+	}
+	if stats := b.LaneStats(); stats.Priority != 2 || stats.Bulk != 1 {
+		t.Errorf("lane stats = %+v", stats)
+	}
+
+	b, _ = NewBus(bulk, Options{Priority: []string{"control.*"}})
+	b.AttachPriorityLane(lane)
+	if b.Prioritized("risk.control") || !b.Prioritized("control.halt") {
+		t.Error("configured priority subjects not applied")
+	}
+}
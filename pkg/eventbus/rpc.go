@@ -0,0 +1,217 @@
+package eventbus
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/nats-io/nats.go"
+	"google.golang.org/protobuf/proto"
+)
+
+// HeaderRPCError carries the error of a failed request, the reply having no
+// payload
+const HeaderRPCError = "Sqx-Rpc-Error"
+
+// Marshaler encodes the requests and replies of RPC endpoints
+type Marshaler interface {
+	Marshal(v any) ([]byte, error)
+	Unmarshal(data []byte, v any) error
+}
+
+// JSON encodes requests and replies with encoding/json
+var JSON Marshaler = jsonMarshaler{}
+
+// Proto encodes requests and replies of generated protobuf messages
+var Proto Marshaler = protoMarshaler{}
+
+type jsonMarshaler struct{}
+
+func (jsonMarshaler) Marshal(v any) ([]byte, error) {
+	return json.Marshal(v)
+}
+
+// Unmarshal leaves v unset on empty data, so requests without arguments can
+// be sent with no payload, e.g. from the nats CLI
+func (jsonMarshaler) Unmarshal(data []byte, v any) error {
+	if len(data) == 0 {
+		return nil
+	}
+	return json.Unmarshal(data, v)
+}
+
+type protoMarshaler struct{}
+
+func (protoMarshaler) Marshal(v any) ([]byte, error) {
+	m, ok := v.(proto.Message)
+	if !ok {
+		return nil, fmt.Errorf("%T is not a protobuf message", v)
+	}
+	return proto.Marshal(m)
+}
+
+func (protoMarshaler) Unmarshal(data []byte, v any) error {
+	m, ok := v.(proto.Message)
+	if !ok {
+		return fmt.Errorf("%T is not a protobuf message", v)
+	}
+	return proto.Unmarshal(data, m)
+}
+
+// RPCError is returned by Call when the server failed the request
+type RPCError struct {
+	Subject string
+	Message string
+}
+
+func (e *RPCError) Error() string {
+	return fmt.Sprintf("%s: %s", e.Subject, e.Message)
+}
+
+// Endpoint is a typed request/reply endpoint: requests of type Req on
+// Subject are answered with a Resp, both encoded with Marshaler
+type Endpoint[Req, Resp any] struct {
+	Subject   string
+	Marshaler Marshaler
+	// Changes the state of the node. Calls are signed by the signer of the
+	// bus and served only once authorized by the authorizer of the bus.
+	Command bool
+}
+
+// NewEndpoint creates an endpoint on subject
+func NewEndpoint[Req, Resp any](subject string, marshaler Marshaler) Endpoint[Req, Resp] {
+	return Endpoint[Req, Resp]{Subject: subject, Marshaler: marshaler}
+}
+
+// NewCommand creates a command endpoint on subject
+func NewCommand[Req, Resp any](subject string, marshaler Marshaler) Endpoint[Req, Resp] {
+	return Endpoint[Req, Resp]{Subject: subject, Marshaler: marshaler, Command: true}
+}
+
+type operatorKey struct{}
+
+// Caller returns who called the command being served: the operator who
+// signed it when authorized, claimed otherwise, e.g. the By of the request
+// when the bus has no authorizer
+func Caller(ctx context.Context, claimed string) string {
+	if operator, _ := ctx.Value(operatorKey{}).(string); operator != "" {
+		return operator
+	}
+	return claimed
+}
+
+// Call sends req and waits for the reply until ctx is done, retrying and
+// failing fast as the RPC policy of the bus for the subject says. Commands
+// are signed once: their retries carry the same nonce, and the node runs
+// a command at most once, answering retries with the reply it was served.
+func (e Endpoint[Req, Resp]) Call(ctx context.Context, bus *Bus, req *Req) (*Resp, error) {
+	data, err := e.Marshaler.Marshal(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode request on %s: %w", e.Subject, err)
+	}
+	msg := &nats.Msg{Subject: e.Subject, Data: data}
+	if e.Command && bus.signer != nil {
+		if err := bus.signer.Sign(msg, time.Now()); err != nil {
+			return nil, fmt.Errorf("failed to sign command on %s: %w", e.Subject, err)
+		}
+	}
+	if err := bus.Encode(msg); err != nil {
+		return nil, err
+	}
+	reply, err := bus.request(ctx, msg)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", e.Subject, err)
+	}
+	if err := Decode(reply); err != nil {
+		return nil, err
+	}
+	if message := reply.Header.Get(HeaderRPCError); message != "" {
+		return nil, &RPCError{Subject: e.Subject, Message: message}
+	}
+	resp := new(Resp)
+	if err := e.Marshaler.Unmarshal(reply.Data, resp); err != nil {
+		return nil, fmt.Errorf("failed to decode reply on %s: %w", e.Subject, err)
+	}
+	return resp, nil
+}
+
+// Serve answers the requests of the endpoint with handler. Errors of the
+// handler are returned to the caller as an RPCError. Commands are refused
+// unless authorized when the bus has an authorizer.
+func (e Endpoint[Req, Resp]) Serve(bus *Bus, handler func(ctx context.Context, req *Req) (*Resp, error)) (*nats.Subscription, error) {
+	return bus.Subscribe(e.Subject, func(msg *nats.Msg) {
+		if msg.Reply == "" {
+			return
+		}
+		reply := e.respond(bus, msg, handler)
+		reply.Subject = msg.Reply
+		// Replies take the lane of the request. A caller gone by now is not an
+		// error of the server.
+		if err := bus.Encode(reply); err == nil {
+			bus.connFor(e.Subject, true).PublishMsg(reply)
+		}
+	})
+}
+
+// respond returns the reply to a request, unencoded. A command is recorded
+// with its reply, so that its retries get the reply back rather than run
+// it again.
+func (e Endpoint[Req, Resp]) respond(bus *Bus, msg *nats.Msg, handler func(ctx context.Context, req *Req) (*Resp, error)) *nats.Msg {
+	ctx, replay, err := e.authorize(bus, msg)
+	if replay != nil {
+		return replay
+	}
+	reply := &nats.Msg{Header: nats.Header{}}
+	if err != nil {
+		reply.Header.Set(HeaderRPCError, err.Error())
+		return reply
+	}
+	data, err := e.handle(ctx, msg.Data, handler)
+	if err != nil {
+		reply.Header.Set(HeaderRPCError, err.Error())
+	} else {
+		reply.Data = data
+	}
+	if e.Command && bus.authorizer != nil {
+		bus.authorizer.record(Caller(ctx, ""), msg, reply)
+	}
+	return reply
+}
+
+// authorize verifies the envelope of a command under the authorizer of the
+// bus, returning the context of the handler, or the reply served to the
+// command when it is a retry
+func (e Endpoint[Req, Resp]) authorize(bus *Bus, msg *nats.Msg) (context.Context, *nats.Msg, error) {
+	ctx := context.Background()
+	if !e.Command || bus.authorizer == nil {
+		return ctx, nil, nil
+	}
+	operator, err := bus.authorizer.Authorize(msg)
+	var replay *nats.Msg
+	if errors.Is(err, ErrReplayed) {
+		if replay = bus.authorizer.replay(operator, msg); replay != nil {
+			err = nil
+		}
+	}
+	if bus.onCommand != nil {
+		bus.onCommand(CommandEvent{Node: bus.authorizer.Node(), Subject: msg.Subject, Operator: operator, Err: err, Replayed: replay != nil})
+	}
+	if err != nil {
+		return nil, nil, err
+	}
+	return context.WithValue(ctx, operatorKey{}, operator), replay, nil
+}
+
+func (e Endpoint[Req, Resp]) handle(ctx context.Context, data []byte, handler func(ctx context.Context, req *Req) (*Resp, error)) ([]byte, error) {
+	req := new(Req)
+	if err := e.Marshaler.Unmarshal(data, req); err != nil {
+		return nil, fmt.Errorf("invalid request: %w", err)
+	}
+	resp, err := handler(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+	return e.Marshaler.Marshal(resp)
+}
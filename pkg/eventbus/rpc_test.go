@@ -0,0 +1,177 @@
+package eventbus
+
+import (
+	"context"
+	"crypto/ed25519"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/nats-io/nats.go"
+	"google.golang.org/protobuf/types/known/wrapperspb"
+)
+
+func TestEndpointHandle(t *testing.T) {
+	type request struct {
+		Symbol string `json:"symbol"`
+	}
+	type reply struct {
+		Price float64 `json:"price"`
+	}
+	e := NewEndpoint[request, reply]("rpc.price", JSON)
+	data, err := e.handle(context.Background(), []byte(`{"symbol":"BTC-USDT"}`), func(ctx context.Context, req *request) (*reply, error) {
+		if req.Symbol != "BTC-USDT" {
+			return nil, errors.New("unknown symbol")
+		}
+		return &reply{Price: 60000}, nil
+	})
+	if err != nil || string(data) != `{"price":60000}` {
+		t.Errorf("handle = %s, %v", data, err)
+	}
+
+	// Requests without arguments may have no payload
+	_, err = e.handle(context.Background(), nil, func(ctx context.Context, req *request) (*reply, error) {
+		return nil, errors.New("unknown symbol")
+	})
+	if err == nil || err.Error() != "unknown symbol" {
+		t.Errorf("handler error = %v", err)
+	}
+	if _, err := e.handle(context.Background(), []byte("{"), nil); err == nil {
+		t.Error("invalid request handled")
+	}
+}
+
+func TestCommandRetries(t *testing.T) {
+	pub, key, _ := ed25519.GenerateKey(nil)
+	authorizer, err := NewAuthorizer("guardian", CommandPolicy{
+		Operators: []Operator{{Name: "alice", Key: pub, Roles: []string{"risk"}}},
+		Roles:     map[string][]Grant{"risk": {{Endpoints: []string{"guardian.>"}, Nodes: []string{"*"}}}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var events []CommandEvent
+	bus, err := NewBus(&nats.Conn{}, Options{Authorizer: authorizer, OnCommand: func(e CommandEvent) { events = append(events, e) }})
+	if err != nil {
+		t.Fatal(err)
+	}
+	type request struct {
+		Strategy string `json:"strategy"`
+	}
+	type reply struct {
+		Paused int `json:"paused"`
+	}
+	paused := 0
+	e := NewCommand[request, reply]("guardian.pause", JSON)
+	handler := func(ctx context.Context, req *request) (*reply, error) {
+		paused++
+		if req.Strategy == "" {
+			return nil, errors.New("strategy required")
+		}
+		return &reply{Paused: paused}, nil
+	}
+	signed := func(data string) *nats.Msg {
+		msg := &nats.Msg{Subject: "guardian.pause", Data: []byte(data)}
+		if err := (&Signer{Operator: "alice", Key: key}).Sign(msg, time.Now()); err != nil {
+			t.Fatal(err)
+		}
+		return msg
+	}
+
+	// A retry of the command, under its nonce, gets the reply it was served
+	msg := signed(`{"strategy":"mm"}`)
+	for attempt := 0; attempt < 3; attempt++ {
+		if r := e.respond(bus, msg, handler); string(r.Data) != `{"paused":1}` || r.Header.Get(HeaderRPCError) != "" {
+			t.Errorf("attempt %d: reply %s, %q", attempt, r.Data, r.Header.Get(HeaderRPCError))
+		}
+	}
+	if paused != 1 {
+		t.Errorf("command run %d times, want once", paused)
+	}
+	if len(events) != 3 || events[0].Replayed || !events[1].Replayed || events[1].Err != nil {
+		t.Errorf("events = %+v, want the command then its retries replayed", events)
+	}
+
+	// Failures are replayed too, and a new call runs again
+	failed := signed(`{}`)
+	for attempt := 0; attempt < 2; attempt++ {
+		if r := e.respond(bus, failed, handler); r.Header.Get(HeaderRPCError) != "strategy required" {
+			t.Errorf("attempt %d: error %q", attempt, r.Header.Get(HeaderRPCError))
+		}
+	}
+	if r := e.respond(bus, signed(`{"strategy":"mm"}`), handler); string(r.Data) != `{"paused":3}` {
+		t.Errorf("new call reply %s", r.Data)
+	}
+
+	// A retry with other arguments is not signed by the caller
+	tampered := signed(`{"strategy":"mm"}`)
+	e.respond(bus, tampered, handler)
+	tampered.Data = []byte(`{"strategy":"arb"}`)
+	if r := e.respond(bus, tampered, handler); r.Header.Get(HeaderRPCError) == "" || paused != 4 {
+		t.Errorf("tampered retry answered %s, %d runs", r.Data, paused)
+	}
+}
+
+func TestProtoMarshaler(t *testing.T) {
+	data, err := Proto.Marshal(wrapperspb.String("BTC-USDT"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got wrapperspb.StringValue
+	if err := Proto.Unmarshal(data, &got); err != nil || got.Value != "BTC-USDT" {
+		t.Errorf("unmarshal = %v, %v", got.Value, err)
+	}
+	if _, err := Proto.Marshal(struct{}{}); err == nil {
+		t.Error("marshaled a value that is not a message")
+	}
+}
+
+func TestRPCPolicyBackoff(t *testing.T) {
+	p := RPCPolicy{Backoff: 100 * time.Millisecond, MaxBackoff: 300 * time.Millisecond}
+	for n, want := range []time.Duration{100, 200, 300, 300} {
+		if got := p.backoff(n + 1); got != want*time.Millisecond {
+			t.Errorf("backoff(%d) = %v, want %v", n+1, got, want*time.Millisecond)
+		}
+	}
+	if err := (RPCPolicy{Subject: "diag.*", BreakAfter: 3}).Validate(); err == nil {
+		t.Error("break after without break for validated")
+	}
+}
+
+func TestRPCCircuitBreaker(t *testing.T) {
+	e := newRPCEndpoint("diag.feed", RPCPolicy{Subject: "diag.*", BreakAfter: 2, BreakFor: time.Minute})
+	now := time.Now()
+	failure := errors.New("timeout")
+
+	e.record(failure, now)
+	if !e.allow(now) {
+		t.Fatal("circuit opened before break after")
+	}
+	e.record(failure, now)
+	if e.allow(now.Add(time.Second)) {
+		t.Fatal("circuit not open after break after failures")
+	}
+	if s := e.stats(now.Add(time.Second)); s.Circuit != CircuitOpen || s.LastError != "timeout" {
+		t.Errorf("stats = %+v", s)
+	}
+
+	// A single probe once open for break for
+	later := now.Add(2 * time.Minute)
+	if !e.allow(later) {
+		t.Fatal("no probe after break for")
+	}
+	if e.allow(later) {
+		t.Fatal("second call allowed while probing")
+	}
+	e.record(failure, later)
+	if e.allow(later.Add(time.Second)) {
+		t.Fatal("circuit not reopened after a failed probe")
+	}
+	if !e.allow(later.Add(2 * time.Minute)) {
+		t.Fatal("no probe after break for")
+	}
+	e.record(nil, later.Add(2*time.Minute))
+	if !e.allow(later.Add(2*time.Minute)) || e.stats(later).Circuit != CircuitClosed {
+		t.Error("circuit not closed after a successful probe")
+	}
+}
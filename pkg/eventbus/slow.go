@@ -0,0 +1,231 @@
+package eventbus
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"sync/atomic"
+	"time"
+
+	"github.com/nats-io/nats.go"
+)
+
+// DefaultLossLookback is replayed before the last message handled when a
+// subscription switches to JetStream, covering the skew between the clocks
+// of the client and the server
+const DefaultLossLookback = time.Second
+
+// LosslessRule switches the subscriptions of a subject pattern to JetStream
+// once the server reports them as slow consumers, so that the messages it
+// dropped are replayed from the stream rather than lost. Messages handled
+// within the lookback before the switch are delivered twice.
+type LosslessRule struct {
+	Subject  string        // Subject pattern, wildcards allowed
+	Stream   string        // Stream storing the subjects
+	Lookback time.Duration // DefaultLossLookback when 0
+}
+
+// Validate validates the rule
+func (r LosslessRule) Validate() error {
+	if err := ValidatePattern(r.Subject); err != nil {
+		return err
+	}
+	if r.Stream == "" {
+		return fmt.Errorf("%s: stream is required", r.Subject)
+	}
+	if r.Lookback < 0 {
+		return fmt.Errorf("%s: lookback cannot be negative", r.Subject)
+	}
+	return nil
+}
+
+// SlowConsumerEvent reports a subscription the server dropped messages for
+type SlowConsumerEvent struct {
+	Subject  string
+	Queue    string
+	Dropped  int   // Messages dropped since the previous event, as counted by the client
+	Pending  int   // Messages waiting for the handler
+	Switched bool  // The subscription switched to JetStream
+	Err      error // The switch to JetStream failed
+}
+
+// SlowConsumerStats reports the slow consumer events of the subscriptions
+// of a subject
+type SlowConsumerStats struct {
+	Subject  string `json:"subject"`
+	Queue    string `json:"queue,omitempty"`
+	Events   uint64 `json:"events"`   // Slow consumer reports of the server
+	Dropped  uint64 `json:"dropped"`  // Messages dropped, as counted by the client for the subscriptions of the bus
+	Switched uint64 `json:"switched"` // Subscriptions switched to JetStream
+}
+
+type slowKey struct {
+	subject, queue string
+}
+
+// tracked is a subscription made through the bus
+type tracked struct {
+	subject string
+	queue   string
+	handler nats.MsgHandler // Decoding the messages
+	conn    *nats.Conn
+	rule    *LosslessRule
+	last    atomic.Int64 // Time the last message was handed over, Unix nanoseconds, with a rule only
+
+	seen     int                // Drops counted by the subscription at the last event
+	current  *nats.Subscription // JetStream subscription once switched
+	released bool
+}
+
+// watch reports the slow consumer errors of conn to the bus, keeping the
+// error handler already set
+func (b *Bus) watch(conn *nats.Conn) {
+	prev := conn.ErrorHandler()
+	conn.SetErrorHandler(func(nc *nats.Conn, sub *nats.Subscription, err error) {
+		if errors.Is(err, nats.ErrSlowConsumer) && sub != nil {
+			dropped, _ := sub.Dropped()
+			pending, _, _ := sub.Pending()
+			b.slowConsumer(sub, dropped, pending)
+		}
+		if prev != nil {
+			prev(nc, sub, err)
+		}
+	})
+}
+
+// subscribe subscribes through conn, tracking the subscription
+func (b *Bus) subscribe(conn *nats.Conn, subject, queue string, handler nats.MsgHandler) (*nats.Subscription, error) {
+	t := &tracked{subject: subject, queue: queue, handler: b.wrap(handler), conn: conn, rule: b.losslessRule(subject)}
+	cb := t.handler
+	if t.rule != nil {
+		cb = func(msg *nats.Msg) {
+			t.last.Store(time.Now().UnixNano())
+			t.handler(msg)
+		}
+	}
+	var sub *nats.Subscription
+	var err error
+	if queue == "" {
+		sub, err = conn.Subscribe(subject, cb)
+	} else {
+		sub, err = conn.QueueSubscribe(subject, queue, cb)
+	}
+	if err != nil {
+		return nil, err
+	}
+	b.subsMu.Lock()
+	b.subs[sub] = t
+	b.subsMu.Unlock()
+	return sub, nil
+}
+
+func (b *Bus) losslessRule(subject string) *LosslessRule {
+	for i := range b.lossless {
+		if Match(b.lossless[i].Subject, subject) {
+			return &b.lossless[i]
+		}
+	}
+	return nil
+}
+
+// slowConsumer accounts a slow consumer report on sub, switching it to
+// JetStream under a lossless rule
+func (b *Bus) slowConsumer(sub *nats.Subscription, dropped, pending int) {
+	event := SlowConsumerEvent{Subject: sub.Subject, Queue: sub.Queue, Pending: pending}
+	b.subsMu.Lock()
+	stats := b.slowStats[slowKey{sub.Subject, sub.Queue}]
+	if stats == nil {
+		stats = &SlowConsumerStats{Subject: sub.Subject, Queue: sub.Queue}
+		b.slowStats[slowKey{sub.Subject, sub.Queue}] = stats
+	}
+	stats.Events++
+	// Subscriptions made without the bus, sharing its connection, are only
+	// counted
+	if t, ok := b.subs[sub]; ok {
+		if dropped > t.seen {
+			event.Dropped = dropped - t.seen
+			t.seen = dropped
+			stats.Dropped += uint64(event.Dropped)
+		}
+		// Queue groups are left alone: a member replaying the stream would
+		// deliver the messages of the whole group
+		if t.rule != nil && t.queue == "" && t.current == nil && !t.released {
+			event.Err = b.switchToJetStream(sub, t)
+			if event.Switched = event.Err == nil; event.Switched {
+				stats.Switched++
+			}
+		}
+	}
+	b.subsMu.Unlock()
+
+	b.slow.Add(1)
+	if b.onSlow != nil {
+		b.onSlow(event)
+	}
+}
+
+// switchToJetStream replaces the core subscription of t with an ordered
+// consumer of the stream of its rule, starting before the last message
+// handled. It is called with subsMu held.
+func (b *Bus) switchToJetStream(sub *nats.Subscription, t *tracked) error {
+	js, err := t.conn.JetStream()
+	if err != nil {
+		return err
+	}
+	lookback := t.rule.Lookback
+	if lookback == 0 {
+		lookback = DefaultLossLookback
+	}
+	start := time.Now()
+	if last := t.last.Load(); last > 0 {
+		start = time.Unix(0, last)
+	}
+	// The core subscription goes first so that no message is handled by both
+	if err := sub.Unsubscribe(); err != nil && !errors.Is(err, nats.ErrBadSubscription) {
+		return fmt.Errorf("failed to unsubscribe %s: %w", t.subject, err)
+	}
+	current, err := js.Subscribe(t.subject, t.handler, nats.BindStream(t.rule.Stream), nats.OrderedConsumer(), nats.StartTime(start.Add(-lookback)))
+	if err != nil {
+		return fmt.Errorf("failed to subscribe to %s on stream %s: %w", t.subject, t.rule.Stream, err)
+	}
+	t.current = current
+	return nil
+}
+
+// Release stops the bus from switching a subscription it made and returns
+// the subscription delivering its messages, for the caller to unsubscribe
+// or drain
+func (b *Bus) Release(sub *nats.Subscription) *nats.Subscription {
+	b.subsMu.Lock()
+	defer b.subsMu.Unlock()
+	t, ok := b.subs[sub]
+	if !ok {
+		return sub
+	}
+	delete(b.subs, sub)
+	t.released = true
+	if t.current != nil {
+		return t.current
+	}
+	return sub
+}
+
+// SlowConsumers returns the number of slow consumer reports of the server
+func (b *Bus) SlowConsumers() uint64 {
+	return b.slow.Load()
+}
+
+// SlowConsumerStats returns the subscriptions reported as slow consumers,
+// sorted by subject
+func (b *Bus) SlowConsumerStats() []SlowConsumerStats {
+	b.subsMu.Lock()
+	stats := make([]SlowConsumerStats, 0, len(b.slowStats))
+	for _, s := range b.slowStats {
+		stats = append(stats, *s)
+	}
+	b.subsMu.Unlock()
+	sort.Slice(stats, func(i, j int) bool {
+		return stats[i].Subject < stats[j].Subject || (stats[i].Subject == stats[j].Subject && stats[i].Queue < stats[j].Queue)
+	})
+	return stats
+}
@@ -0,0 +1,73 @@
+package eventbus
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/nats-io/nats.go"
+)
+
+func TestSlowConsumers(t *testing.T) {
+	if _, err := NewBus(&nats.Conn{}, Options{Lossless: []LosslessRule{{Subject: "oms.>"}}}); err == nil {
+		t.Error("lossless rule without stream accepted")
+	}
+	if _, err := NewBus(&nats.Conn{}, Options{Lossless: []LosslessRule{{Subject: "oms.>", Stream: "OMS", Lookback: -time.Second}}}); err == nil {
+		t.Error("negative lookback accepted")
+	}
+
+	conn := &nats.Conn{}
+	var prev []error
+	conn.SetErrorHandler(func(_ *nats.Conn, _ *nats.Subscription, err error) { prev = append(prev, err) })
+	var events []SlowConsumerEvent
+	b, err := NewBus(conn, Options{
+		Lossless:       []LosslessRule{{Subject: "oms.>", Stream: "OMS"}},
+		OnSlowConsumer: func(e SlowConsumerEvent) { events = append(events, e) },
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Reported through the error handler of the connection, which is kept
+	foreign := &nats.Subscription{Subject: "foreign"}
+	conn.ErrorHandler()(conn, foreign, nats.ErrSlowConsumer)
+	conn.ErrorHandler()(conn, foreign, errors.New("other"))
+	if len(prev) != 2 || len(events) != 1 || events[0].Subject != "foreign" || events[0].Dropped != 0 {
+		t.Fatalf("events = %+v, previous handler = %v", events, prev)
+	}
+
+	// Drops are counted between the reports of a subscription of the bus
+	sub := &nats.Subscription{Subject: "trade.btcusdt"}
+	b.subs[sub] = &tracked{subject: sub.Subject}
+	b.slowConsumer(sub, 10, 512)
+	b.slowConsumer(sub, 25, 512)
+	if e := events[2]; e.Dropped != 15 || e.Pending != 512 || e.Switched || e.Err != nil {
+		t.Errorf("event = %+v", e)
+	}
+	// Queue groups are never switched to JetStream
+	queue := &nats.Subscription{Subject: "oms.intent.order", Queue: "workers"}
+	b.subs[queue] = &tracked{subject: queue.Subject, queue: queue.Queue, rule: b.losslessRule(queue.Subject)}
+	b.slowConsumer(queue, 3, 0)
+	if e := events[3]; e.Switched || e.Err != nil {
+		t.Errorf("queue event = %+v", e)
+	}
+
+	stats := b.SlowConsumerStats()
+	if len(stats) != 3 || b.SlowConsumers() != 4 {
+		t.Fatalf("stats = %+v", stats)
+	}
+	if s := stats[2]; s.Subject != "trade.btcusdt" || s.Events != 2 || s.Dropped != 25 || s.Switched != 0 {
+		t.Errorf("stats = %+v", s)
+	}
+
+	// Released subscriptions are no longer tracked, their stats are kept
+	if b.Release(sub) != sub || len(b.subs) != 1 {
+		t.Errorf("release kept %d subscriptions", len(b.subs))
+	}
+	if len(b.SlowConsumerStats()) != 3 {
+		t.Error("stats dropped on release")
+	}
+	if b.losslessRule("trade.btcusdt") != nil || b.losslessRule("oms.execution.report") == nil {
+		t.Error("lossless rules not matched")
+	}
+}
@@ -0,0 +1,188 @@
+package eventbus
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Wildcard tokens of NATS subjects
+const (
+	WildcardToken = "*"
+	WildcardTail  = ">"
+)
+
+// Subject is a structured event bus subject of the form
+// env.exchange.instrument.symbol.datatype, e.g. prod.binance.spot.btcusdt.trade.
+// Putting the environment first lets multiple deployments share a NATS
+// cluster and be isolated with a single permission per environment.
+type Subject struct {
+	Env        string
+	Exchange   string
+	Instrument string
+	Symbol     string
+	DataType   string
+}
+
+// subjectTokens is the number of tokens of a namespaced subject
+const subjectTokens = 5
+
+// NewSubject creates a subject, normalizing tokens to lower case and
+// stripping symbol separators (BTC-USDT becomes btcusdt)
+func NewSubject(env, exchange, instrument, symbol, dataType string) (Subject, error) {
+	s := Subject{
+		Env:        normalizeToken(env),
+		Exchange:   normalizeToken(exchange),
+		Instrument: normalizeToken(instrument),
+		Symbol:     normalizeSymbol(symbol),
+		DataType:   normalizeToken(dataType),
+	}
+	if err := s.Validate(); err != nil {
+		return Subject{}, err
+	}
+	return s, nil
+}
+
+// ParseSubject parses a namespaced subject string
+func ParseSubject(subject string) (Subject, error) {
+	tokens := strings.Split(subject, ".")
+	if len(tokens) != subjectTokens {
+		return Subject{}, fmt.Errorf("subject %q must have %d tokens (env.exchange.instrument.symbol.datatype), got %d",
+			subject, subjectTokens, len(tokens))
+	}
+	s := Subject{Env: tokens[0], Exchange: tokens[1], Instrument: tokens[2], Symbol: tokens[3], DataType: tokens[4]}
+	if err := s.Validate(); err != nil {
+		return Subject{}, err
+	}
+	return s, nil
+}
+
+// Validate checks that every token is set and only uses [a-z0-9_-]
+func (s Subject) Validate() error {
+	fields := []struct {
+		name, value string
+	}{
+		{"env", s.Env},
+		{"exchange", s.Exchange},
+		{"instrument", s.Instrument},
+		{"symbol", s.Symbol},
+		{"datatype", s.DataType},
+	}
+	for _, f := range fields {
+		if err := ValidateToken(f.value); err != nil {
+			return fmt.Errorf("invalid %s: %w", f.name, err)
+		}
+	}
+	return nil
+}
+
+// String returns the NATS subject
+func (s Subject) String() string {
+	return strings.Join([]string{s.Env, s.Exchange, s.Instrument, s.Symbol, s.DataType}, ".")
+}
+
+// ValidateToken checks a single literal subject token
+func ValidateToken(token string) error {
+	if token == "" {
+		return fmt.Errorf("token cannot be empty")
+	}
+	for _, r := range token {
+		if (r < 'a' || r > 'z') && (r < '0' || r > '9') && r != '_' && r != '-' {
+			return fmt.Errorf("token %q contains invalid character %q", token, r)
+		}
+	}
+	return nil
+}
+
+func normalizeToken(token string) string {
+	return strings.ToLower(strings.TrimSpace(token))
+}
+
+func normalizeSymbol(symbol string) string {
+	return strings.NewReplacer("-", "", "/", "", "_", "").Replace(normalizeToken(symbol))
+}
+
+// Filter builds wildcard subscriptions over the namespace. Empty fields
+// match any token, and trailing empty fields collapse into '>'.
+type Filter struct {
+	Env        string
+	Exchange   string
+	Instrument string
+	Symbol     string
+	DataType   string
+}
+
+// String returns the NATS subject pattern of the filter
+func (f Filter) String() string {
+	tokens := []string{
+		normalizeToken(f.Env),
+		normalizeToken(f.Exchange),
+		normalizeToken(f.Instrument),
+		normalizeSymbol(f.Symbol),
+		normalizeToken(f.DataType),
+	}
+	last := len(tokens) - 1
+	for last >= 0 && tokens[last] == "" {
+		last--
+	}
+	if last < 0 {
+		return WildcardTail
+	}
+	for i := 0; i <= last; i++ {
+		if tokens[i] == "" {
+			tokens[i] = WildcardToken
+		}
+	}
+	if last < len(tokens)-1 {
+		return strings.Join(tokens[:last+1], ".") + "." + WildcardTail
+	}
+	return strings.Join(tokens, ".")
+}
+
+// EnvFilter matches every subject of an environment, e.g. prod.>
+func EnvFilter(env string) string {
+	return Filter{Env: env}.String()
+}
+
+// DataTypeFilter matches one data type across all venues of an environment,
+// e.g. prod.*.*.*.trade
+func DataTypeFilter(env, dataType string) string {
+	return Filter{Env: env, DataType: dataType}.String()
+}
+
+// Match reports whether a subject matches a pattern with * and > wildcards
+func Match(pattern, subject string) bool {
+	patternTokens := strings.Split(pattern, ".")
+	subjectTokens := strings.Split(subject, ".")
+	for i, token := range patternTokens {
+		if token == WildcardTail {
+			return len(subjectTokens) > i
+		}
+		if i >= len(subjectTokens) {
+			return false
+		}
+		if token != WildcardToken && token != subjectTokens[i] {
+			return false
+		}
+	}
+	return len(patternTokens) == len(subjectTokens)
+}
+
+// ValidatePattern checks a subject pattern: tokens are literal or '*', and
+// '>' may only appear as the last token
+func ValidatePattern(pattern string) error {
+	tokens := strings.Split(pattern, ".")
+	for i, token := range tokens {
+		switch {
+		case token == WildcardTail:
+			if i != len(tokens)-1 {
+				return fmt.Errorf("pattern %q: '>' must be the last token", pattern)
+			}
+		case token == WildcardToken:
+		default:
+			if err := ValidateToken(token); err != nil {
+				return fmt.Errorf("pattern %q: %w", pattern, err)
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,140 @@
+package eventbus
+
+import (
+	"testing"
+)
+
+func TestNewSubject(t *testing.T) {
+	s, err := NewSubject("Prod", "BINANCE", "spot", "BTC-USDT", "trade")
+	if err != nil {
+		t.Fatalf("NewSubject() returned error: %v", err)
+	}
+	if s.String() != "prod.binance.spot.btcusdt.trade" {
+		t.Errorf("String() = %s", s.String())
+	}
+
+	parsed, err := ParseSubject(s.String())
+	if err != nil {
+		t.Fatalf("ParseSubject() returned error: %v", err)
+	}
+	if parsed != s {
+		t.Errorf("ParseSubject() = %+v, expected %+v", parsed, s)
+	}
+
+	invalid := []string{
+		"prod.binance.spot.btcusdt",
+		"prod.binance.spot.btc usdt.trade",
+		"prod.binance.*.btcusdt.trade",
+		"prod..spot.btcusdt.trade",
+		"Prod.binance.spot.btcusdt.trade",
+	}
+	for _, subject := range invalid {
+		if _, err := ParseSubject(subject); err == nil {
+			t.Errorf("ParseSubject(%q) expected error", subject)
+		}
+	}
+}
+
+func TestFilter(t *testing.T) {
+	tests := []struct {
+		filter   Filter
+		expected string
+	}{
+		{Filter{}, ">"},
+		{Filter{Env: "prod"}, "prod.>"},
+		{Filter{Env: "prod", DataType: "trade"}, "prod.*.*.*.trade"},
+		{Filter{Env: "prod", Exchange: "binance", Symbol: "BTC-USDT"}, "prod.binance.*.btcusdt.>"},
+		{Filter{Env: "prod", Exchange: "binance", Instrument: "spot", Symbol: "btcusdt", DataType: "depth"}, "prod.binance.spot.btcusdt.depth"},
+	}
+	for _, tt := range tests {
+		if got := tt.filter.String(); got != tt.expected {
+			t.Errorf("Filter%+v = %s, expected %s", tt.filter, got, tt.expected)
+		}
+		if err := ValidatePattern(tt.expected); err != nil {
+			t.Errorf("ValidatePattern(%s) returned error: %v", tt.expected, err)
+		}
+	}
+
+	if err := ValidatePattern("prod.>.trade"); err == nil {
+		t.Error("expected error for '>' not in last position")
+	}
+}
+
+func TestMatch(t *testing.T) {
+	tests := []struct {
+		pattern, subject string
+		expected         bool
+	}{
+		{"prod.>", "prod.binance.spot.btcusdt.trade", true},
+		{"prod.>", "prod", false},
+		{"prod.*.*.*.trade", "prod.binance.spot.btcusdt.trade", true},
+		{"prod.*.*.*.trade", "prod.binance.spot.btcusdt.depth", false},
+		{"prod.*.*.*.trade", "staging.binance.spot.btcusdt.trade", false},
+		{"prod.binance.spot.btcusdt.trade", "prod.binance.spot.btcusdt.trade", true},
+		{"prod.binance", "prod.binance.spot", false},
+	}
+	for _, tt := range tests {
+		if got := Match(tt.pattern, tt.subject); got != tt.expected {
+			t.Errorf("Match(%s, %s) = %v, expected %v", tt.pattern, tt.subject, got, tt.expected)
+		}
+	}
+}
+
+func TestPermissions(t *testing.T) {
+	feed, err := PublisherPermissions("prod", "trade")
+	if err != nil {
+		t.Fatalf("PublisherPermissions() returned error: %v", err)
+	}
+	if !feed.CanPublish("prod.binance.spot.btcusdt.trade") {
+		t.Error("feed should publish prod trades")
+	}
+	if feed.CanPublish("staging.binance.spot.btcusdt.trade") || feed.CanPublish("prod.binance.spot.btcusdt.depth") {
+		t.Error("feed should not publish other environments or data types")
+	}
+
+	reader, err := ReaderPermissions("staging")
+	if err != nil {
+		t.Fatalf("ReaderPermissions() returned error: %v", err)
+	}
+	if reader.CanSubscribe("prod.binance.spot.btcusdt.trade") || !reader.CanSubscribe("staging.binance.spot.btcusdt.trade") {
+		t.Error("reader should be confined to its environment")
+	}
+	nats := reader.NATS()
+	if len(nats.Subscribe.Allow) != 2 || nats.Subscribe.Allow[1] != InboxPattern {
+		t.Errorf("NATS() subscribe = %v, expected inbox to be allowed", nats.Subscribe.Allow)
+	}
+
+	if _, err := PublisherPermissions("prod env", "trade"); err == nil {
+		t.Error("expected error for invalid env")
+	}
+}
+
+func TestLegacyMapper(t *testing.T) {
+	mapper := LegacyMapper{Env: "prod", DefaultExchange: "binance", DefaultInstrument: "spot"}
+
+	tests := map[string]string{
+		"trade.btcusdt":                  "prod.binance.spot.btcusdt.trade",
+		"trade.binanceperp.perp.ethusdt": "prod.binanceperp.perp.ethusdt.trade",
+		"depth.BTCUSDT":                  "prod.binance.spot.btcusdt.depth",
+	}
+	for legacy, expected := range tests {
+		s, err := mapper.Map(legacy)
+		if err != nil {
+			t.Errorf("Map(%s) returned error: %v", legacy, err)
+			continue
+		}
+		if s.String() != expected {
+			t.Errorf("Map(%s) = %s, expected %s", legacy, s, expected)
+		}
+	}
+
+	if _, err := mapper.Map("trade.binance.btcusdt"); err == nil {
+		t.Error("expected error for unrecognized layout")
+	}
+	if _, err := (LegacyMapper{Env: "prod"}).Map("trade.btcusdt"); err == nil {
+		t.Error("expected error when short subject has no default venue")
+	}
+	if !IsNamespaced("prod", "prod.binance.spot.btcusdt.trade") || IsNamespaced("prod", "trade.binance.spot.btcusdt") {
+		t.Error("IsNamespaced() misclassified subjects")
+	}
+}
@@ -5,6 +5,7 @@ import (
 	"encoding/json"
 	"fmt"
 	"net/http"
+	"strings"
 )
 
 // Client is the Binance Spot API client.
@@ -308,6 +309,58 @@ func (c *Client) GetPriceTicker(ctx context.Context, symbols ...string) (Respons
 	return Response[[]PriceTicker]{Code: 0, Message: "success", Data: &tickers}, nil
 }
 
+// GetTicker24hr retrieves the 24 hour rolling window statistics of a symbol,
+// of several symbols, or of every symbol when none is given.
+func (c *Client) GetTicker24hr(ctx context.Context, symbols ...string) (Response[[]Ticker24hr], error) {
+	params := map[string]string{}
+	if len(symbols) == 1 {
+		params["symbol"] = symbols[0]
+	} else if len(symbols) > 1 {
+		b, err := json.Marshal(symbols)
+		if err != nil {
+			return Response[[]Ticker24hr]{}, err
+		}
+		params["symbols"] = string(b)
+	}
+	body, status, err := doUnsignedGet(c.cfg, PathGetTicker24hr, params)
+	if err != nil {
+		return Response[[]Ticker24hr]{}, err
+	}
+	if status < 200 || status >= 300 {
+		return Response[[]Ticker24hr]{Code: status, Message: string(body)}, fmt.Errorf("http error: %d", status)
+	}
+	// Always unmarshal as []Ticker24hr
+	var tickers []Ticker24hr
+	if len(body) > 0 && body[0] == '{' {
+		var single Ticker24hr
+		if err := json.Unmarshal(body, &single); err != nil {
+			return Response[[]Ticker24hr]{}, err
+		}
+		tickers = append(tickers, single)
+	} else {
+		if err := json.Unmarshal(body, &tickers); err != nil {
+			return Response[[]Ticker24hr]{}, err
+		}
+	}
+	return Response[[]Ticker24hr]{Code: 0, Message: "success", Data: &tickers}, nil
+}
+
+// GetSystemStatus retrieves whether the exchange is under system maintenance.
+func (c *Client) GetSystemStatus(ctx context.Context) (Response[SystemStatus], error) {
+	body, status, err := doUnsignedGet(sapiConfig(c.cfg), PathGetSystemStatus, nil)
+	if err != nil {
+		return Response[SystemStatus]{}, err
+	}
+	if status < 200 || status >= 300 {
+		return Response[SystemStatus]{Code: status, Message: string(body)}, fmt.Errorf("http error: %d", status)
+	}
+	var resp SystemStatus
+	if err := json.Unmarshal(body, &resp); err != nil {
+		return Response[SystemStatus]{}, err
+	}
+	return Response[SystemStatus]{Code: 0, Message: "success", Data: &resp}, nil
+}
+
 // QueryOrder queries the status of an order on Binance Spot.
 func (c *Client) QueryOrder(ctx context.Context, req QueryOrderRequest) (Response[QueryOrderResponse], error) {
 	params := map[string]string{
@@ -437,6 +490,249 @@ func (c *Client) GetMyTrades(ctx context.Context, req GetAccountTradesRequest) (
 	return Response[[]AccountTrade]{Code: 0, Message: "success", Data: &resp}, nil
 }
 
+// GetMarginInterestHistory retrieves the interest charged on margin loans, newest first.
+func (c *Client) GetMarginInterestHistory(ctx context.Context, req GetMarginInterestHistoryRequest) (Response[MarginInterestHistoryResponse], error) {
+	params := map[string]string{}
+	if req.Asset != "" {
+		params["asset"] = req.Asset
+	}
+	if req.IsolatedSymbol != "" {
+		params["isolatedSymbol"] = req.IsolatedSymbol
+	}
+	if req.StartTime > 0 {
+		params["startTime"] = fmt.Sprintf("%d", req.StartTime)
+	}
+	if req.EndTime > 0 {
+		params["endTime"] = fmt.Sprintf("%d", req.EndTime)
+	}
+	if req.Current > 0 {
+		params["current"] = fmt.Sprintf("%d", req.Current)
+	}
+	if req.Size > 0 {
+		params["size"] = fmt.Sprintf("%d", req.Size)
+	}
+	if req.RecvWindow > 0 {
+		params["recvWindow"] = fmt.Sprintf("%d", req.RecvWindow)
+	}
+	body, status, err := doSignedRequest(sapiConfig(c.cfg), http.MethodGet, PathGetMarginInterestHistory, params)
+	if err != nil {
+		return Response[MarginInterestHistoryResponse]{}, err
+	}
+	if status < 200 || status >= 300 {
+		var errResp Response[MarginInterestHistoryResponse]
+		_ = json.Unmarshal(body, &errResp)
+		if errResp.Message == "" {
+			errResp.Message = string(body)
+		}
+		return errResp, fmt.Errorf("binance error: %s", errResp.Message)
+	}
+	var resp MarginInterestHistoryResponse
+	if err := json.Unmarshal(body, &resp); err != nil {
+		return Response[MarginInterestHistoryResponse]{}, err
+	}
+	return Response[MarginInterestHistoryResponse]{Code: 0, Message: "success", Data: &resp}, nil
+}
+
+// GetAssetDividend retrieves the distributions credited to the account, newest first.
+func (c *Client) GetAssetDividend(ctx context.Context, req GetAssetDividendRequest) (Response[AssetDividendResponse], error) {
+	params := map[string]string{}
+	if req.Asset != "" {
+		params["asset"] = req.Asset
+	}
+	if req.StartTime > 0 {
+		params["startTime"] = fmt.Sprintf("%d", req.StartTime)
+	}
+	if req.EndTime > 0 {
+		params["endTime"] = fmt.Sprintf("%d", req.EndTime)
+	}
+	if req.Limit > 0 {
+		params["limit"] = fmt.Sprintf("%d", req.Limit)
+	}
+	if req.RecvWindow > 0 {
+		params["recvWindow"] = fmt.Sprintf("%d", req.RecvWindow)
+	}
+	body, status, err := doSignedRequest(sapiConfig(c.cfg), http.MethodGet, PathGetAssetDividend, params)
+	if err != nil {
+		return Response[AssetDividendResponse]{}, err
+	}
+	if status < 200 || status >= 300 {
+		var errResp Response[AssetDividendResponse]
+		_ = json.Unmarshal(body, &errResp)
+		if errResp.Message == "" {
+			errResp.Message = string(body)
+		}
+		return errResp, fmt.Errorf("binance error: %s", errResp.Message)
+	}
+	var resp AssetDividendResponse
+	if err := json.Unmarshal(body, &resp); err != nil {
+		return Response[AssetDividendResponse]{}, err
+	}
+	return Response[AssetDividendResponse]{Code: 0, Message: "success", Data: &resp}, nil
+}
+
+// DustTransfer converts small balances of the given assets to BNB.
+func (c *Client) DustTransfer(ctx context.Context, req DustTransferRequest) (Response[DustTransferResponse], error) {
+	if len(req.Assets) == 0 {
+		return Response[DustTransferResponse]{}, fmt.Errorf("no asset to convert")
+	}
+	params := map[string]string{"asset": strings.Join(req.Assets, ",")}
+	if req.AccountType != "" {
+		params["accountType"] = req.AccountType
+	}
+	if req.RecvWindow > 0 {
+		params["recvWindow"] = fmt.Sprintf("%d", req.RecvWindow)
+	}
+	body, status, err := doSignedRequest(sapiConfig(c.cfg), http.MethodPost, PathDustTransfer, params)
+	if err != nil {
+		return Response[DustTransferResponse]{}, err
+	}
+	if status < 200 || status >= 300 {
+		var errResp Response[DustTransferResponse]
+		_ = json.Unmarshal(body, &errResp)
+		if errResp.Message == "" {
+			errResp.Message = string(body)
+		}
+		return errResp, fmt.Errorf("binance error: %s", errResp.Message)
+	}
+	var resp DustTransferResponse
+	if err := json.Unmarshal(body, &resp); err != nil {
+		return Response[DustTransferResponse]{}, err
+	}
+	return Response[DustTransferResponse]{Code: 0, Message: "success", Data: &resp}, nil
+}
+
+// GetAssetDetail retrieves the deposit and withdrawal details of assets, keyed by asset.
+func (c *Client) GetAssetDetail(ctx context.Context, req GetAssetDetailRequest) (Response[map[string]AssetDetail], error) {
+	params := map[string]string{}
+	if req.Asset != "" {
+		params["asset"] = req.Asset
+	}
+	if req.RecvWindow > 0 {
+		params["recvWindow"] = fmt.Sprintf("%d", req.RecvWindow)
+	}
+	body, status, err := doSignedRequest(sapiConfig(c.cfg), http.MethodGet, PathGetAssetDetail, params)
+	if err != nil {
+		return Response[map[string]AssetDetail]{}, err
+	}
+	if status < 200 || status >= 300 {
+		var errResp Response[map[string]AssetDetail]
+		_ = json.Unmarshal(body, &errResp)
+		if errResp.Message == "" {
+			errResp.Message = string(body)
+		}
+		return errResp, fmt.Errorf("binance error: %s", errResp.Message)
+	}
+	var resp map[string]AssetDetail
+	if err := json.Unmarshal(body, &resp); err != nil {
+		return Response[map[string]AssetDetail]{}, err
+	}
+	return Response[map[string]AssetDetail]{Code: 0, Message: "success", Data: &resp}, nil
+}
+
+// UniversalTransfer transfers an asset between the wallets of the account,
+// e.g. from the funding wallet to the spot wallet.
+func (c *Client) UniversalTransfer(ctx context.Context, req UniversalTransferRequest) (Response[UniversalTransferResponse], error) {
+	if req.Type == "" || req.Asset == "" || req.Amount == "" {
+		return Response[UniversalTransferResponse]{}, fmt.Errorf("type, asset and amount are required")
+	}
+	params := map[string]string{"type": req.Type, "asset": req.Asset, "amount": req.Amount}
+	if req.FromSymbol != "" {
+		params["fromSymbol"] = req.FromSymbol
+	}
+	if req.ToSymbol != "" {
+		params["toSymbol"] = req.ToSymbol
+	}
+	if req.RecvWindow > 0 {
+		params["recvWindow"] = fmt.Sprintf("%d", req.RecvWindow)
+	}
+	body, status, err := doSignedRequest(sapiConfig(c.cfg), http.MethodPost, PathUniversalTransfer, params)
+	if err != nil {
+		return Response[UniversalTransferResponse]{}, err
+	}
+	if status < 200 || status >= 300 {
+		var errResp Response[UniversalTransferResponse]
+		_ = json.Unmarshal(body, &errResp)
+		if errResp.Message == "" {
+			errResp.Message = string(body)
+		}
+		return errResp, fmt.Errorf("binance error: %s", errResp.Message)
+	}
+	var resp UniversalTransferResponse
+	if err := json.Unmarshal(body, &resp); err != nil {
+		return Response[UniversalTransferResponse]{}, err
+	}
+	return Response[UniversalTransferResponse]{Code: 0, Message: "success", Data: &resp}, nil
+}
+
+// GetFundingAsset retrieves the balances of the funding wallet.
+func (c *Client) GetFundingAsset(ctx context.Context, req GetFundingAssetRequest) (Response[[]FundingAsset], error) {
+	params := map[string]string{}
+	if req.Asset != "" {
+		params["asset"] = req.Asset
+	}
+	if req.RecvWindow > 0 {
+		params["recvWindow"] = fmt.Sprintf("%d", req.RecvWindow)
+	}
+	body, status, err := doSignedRequest(sapiConfig(c.cfg), http.MethodPost, PathGetFundingAsset, params)
+	if err != nil {
+		return Response[[]FundingAsset]{}, err
+	}
+	if status < 200 || status >= 300 {
+		var errResp Response[[]FundingAsset]
+		_ = json.Unmarshal(body, &errResp)
+		if errResp.Message == "" {
+			errResp.Message = string(body)
+		}
+		return errResp, fmt.Errorf("binance error: %s", errResp.Message)
+	}
+	var resp []FundingAsset
+	if err := json.Unmarshal(body, &resp); err != nil {
+		return Response[[]FundingAsset]{}, err
+	}
+	return Response[[]FundingAsset]{Code: 0, Message: "success", Data: &resp}, nil
+}
+
+// Withdraw withdraws an asset from the account to an address. The address
+// must be in the whitelist of the API key when the account enables it.
+func (c *Client) Withdraw(ctx context.Context, req WithdrawRequest) (Response[WithdrawResponse], error) {
+	if req.Coin == "" || req.Address == "" || req.Amount == "" {
+		return Response[WithdrawResponse]{}, fmt.Errorf("coin, address and amount are required")
+	}
+	params := map[string]string{"coin": req.Coin, "address": req.Address, "amount": req.Amount}
+	if req.Network != "" {
+		params["network"] = req.Network
+	}
+	if req.AddressTag != "" {
+		params["addressTag"] = req.AddressTag
+	}
+	if req.WithdrawOrderId != "" {
+		params["withdrawOrderId"] = req.WithdrawOrderId
+	}
+	if req.Name != "" {
+		params["name"] = req.Name
+	}
+	if req.RecvWindow > 0 {
+		params["recvWindow"] = fmt.Sprintf("%d", req.RecvWindow)
+	}
+	body, status, err := doSignedRequest(sapiConfig(c.cfg), http.MethodPost, PathWithdraw, params)
+	if err != nil {
+		return Response[WithdrawResponse]{}, err
+	}
+	if status < 200 || status >= 300 {
+		var errResp Response[WithdrawResponse]
+		_ = json.Unmarshal(body, &errResp)
+		if errResp.Message == "" {
+			errResp.Message = string(body)
+		}
+		return errResp, fmt.Errorf("binance error: %s", errResp.Message)
+	}
+	var resp WithdrawResponse
+	if err := json.Unmarshal(body, &resp); err != nil {
+		return Response[WithdrawResponse]{}, err
+	}
+	return Response[WithdrawResponse]{Code: 0, Message: "success", Data: &resp}, nil
+}
+
 // GetExchangeInfo retrieves current exchange trading rules and symbol information.
 func (c *Client) GetExchangeInfo(ctx context.Context, req ExchangeInfoRequest) (Response[ExchangeInfoResponse], error) {
 	params := map[string]string{}
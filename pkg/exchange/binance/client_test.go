@@ -2,6 +2,8 @@ package binance
 
 import (
 	"context"
+	"net/http"
+	"net/http/httptest"
 	"os"
 	"testing"
 )
@@ -128,6 +130,49 @@ func TestGetPriceTicker(t *testing.T) {
 	}
 }
 
+func TestGetTicker24hr(t *testing.T) {
+	cfg := &Config{
+		BaseURL: MainnetBaseUrl,
+	}
+	client := NewClient(cfg)
+	ctx := context.Background()
+
+	// Single symbol
+	resp, err := client.GetTicker24hr(ctx, "BTCUSDT")
+	if err != nil {
+		t.Fatalf("GetTicker24hr error: %v", err)
+	}
+	if resp.Data == nil || len(*resp.Data) != 1 {
+		t.Fatal("expected one ticker")
+	}
+	if (*resp.Data)[0].Symbol != "BTCUSDT" || (*resp.Data)[0].QuoteVolume == "" {
+		t.Errorf("unexpected ticker: %+v", (*resp.Data)[0])
+	}
+
+	// Every symbol
+	resp, err = client.GetTicker24hr(ctx)
+	if err != nil {
+		t.Fatalf("GetTicker24hr error: %v", err)
+	}
+	if resp.Data == nil || len(*resp.Data) < 2 {
+		t.Fatal("expected tickers of every symbol")
+	}
+}
+
+func TestGetSystemStatus(t *testing.T) {
+	cfg := &Config{
+		BaseURL: MainnetBaseUrl,
+	}
+	client := NewClient(cfg)
+	resp, err := client.GetSystemStatus(context.Background())
+	if err != nil {
+		t.Fatalf("GetSystemStatus error: %v", err)
+	}
+	if resp.Data == nil || resp.Data.Msg == "" {
+		t.Fatalf("unexpected status: %+v", resp.Data)
+	}
+}
+
 func TestGetExchangeInfo(t *testing.T) {
 	cfg := &Config{
 		BaseURL: MainnetBaseUrl,
@@ -391,3 +436,83 @@ func TestCloseUserDataStream(t *testing.T) {
 		t.Fatal("resp.Data is nil")
 	}
 }
+
+func TestSapiEndpoints(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		r.ParseForm()
+		if r.Header.Get("X-MBX-APIKEY") != "key" || r.Form.Get("signature") == "" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		switch r.Method + " " + r.URL.Path {
+		case "POST /sapi" + PathDustTransfer:
+			if r.PostForm.Get("asset") != "ADA,TRX" {
+				w.WriteHeader(http.StatusBadRequest)
+				w.Write([]byte(`{"code":-1102,"msg":"Mandatory parameter 'asset' was not sent"}`))
+				return
+			}
+			w.Write([]byte(`{"totalServiceCharge":"0.02","totalTransfered":"1.0","transferResult":[{"amount":"0.03","fromAsset":"ADA","operateTime":1563368549307,"serviceChargeAmount":"0.01","tranId":2970932918,"transferedAmount":"0.5"}]}`))
+		case "GET /sapi" + PathGetAssetDetail:
+			w.Write([]byte(`{"CTR":{"minWithdrawAmount":"70.00000000","depositStatus":false,"withdrawFee":35,"withdrawStatus":true,"depositTip":"Delisted"}}`))
+		case "POST /sapi" + PathUniversalTransfer:
+			if r.PostForm.Get("type") != TransferTypeFundingMain || r.PostForm.Get("amount") != "150.5" {
+				w.WriteHeader(http.StatusBadRequest)
+				w.Write([]byte(`{"code":-1102,"msg":"Mandatory parameter 'type' was not sent"}`))
+				return
+			}
+			w.Write([]byte(`{"tranId":13526853623}`))
+		case "POST /sapi" + PathWithdraw:
+			if r.PostForm.Get("withdrawOrderId") != "w-1" || r.PostForm.Get("network") != "ETH" {
+				w.WriteHeader(http.StatusBadRequest)
+				return
+			}
+			w.Write([]byte(`{"id":"7213fea8e94b4a5593d507237e5a555b"}`))
+		case "POST /sapi" + PathGetFundingAsset:
+			w.Write([]byte(`[{"asset":"USDT","free":"1200.5","locked":"0","freeze":"0","withdrawing":"0","btcValuation":"0.02"}]`))
+		case "GET /sapi" + PathGetAssetDividend:
+			w.Write([]byte(`{"rows":[{"id":242006910,"amount":"10.00000000","asset":"BHFT","divTime":1563189166000,"enInfo":"BHFT distribution","tranId":2968885920}],"total":1}`))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer server.Close()
+	client := NewClient(NewConfig("key", "secret", server.URL+"/api"))
+	ctx := context.Background()
+
+	dust, err := client.DustTransfer(ctx, DustTransferRequest{Assets: []string{"ADA", "TRX"}})
+	if err != nil || len(dust.Data.TransferResult) != 1 || dust.Data.TransferResult[0].FromAsset != "ADA" {
+		t.Errorf("DustTransfer = %+v, %v", dust.Data, err)
+	}
+	if _, err := client.DustTransfer(ctx, DustTransferRequest{}); err == nil {
+		t.Error("DustTransfer without assets accepted")
+	}
+
+	transfer, err := client.UniversalTransfer(ctx, UniversalTransferRequest{Type: TransferTypeFundingMain, Asset: "USDT", Amount: "150.5"})
+	if err != nil || transfer.Data.TranId != 13526853623 {
+		t.Errorf("UniversalTransfer = %+v, %v", transfer.Data, err)
+	}
+	if _, err := client.UniversalTransfer(ctx, UniversalTransferRequest{Type: TransferTypeMainFunding, Asset: "USDT", Amount: "1"}); err == nil {
+		t.Error("UniversalTransfer error not returned")
+	}
+
+	withdrawal, err := client.Withdraw(ctx, WithdrawRequest{Coin: "USDT", Network: "ETH", Address: "0xabc", Amount: "100", WithdrawOrderId: "w-1"})
+	if err != nil || withdrawal.Data.Id != "7213fea8e94b4a5593d507237e5a555b" {
+		t.Errorf("Withdraw = %+v, %v", withdrawal.Data, err)
+	}
+
+	funding, err := client.GetFundingAsset(ctx, GetFundingAssetRequest{Asset: "USDT"})
+	if err != nil || len(*funding.Data) != 1 || (*funding.Data)[0].Free != "1200.5" {
+		t.Errorf("GetFundingAsset = %+v, %v", funding.Data, err)
+	}
+
+	dividends, err := client.GetAssetDividend(ctx, GetAssetDividendRequest{Limit: 10})
+	if err != nil || dividends.Data.Total != 1 || dividends.Data.Rows[0].TranId != 2968885920 {
+		t.Errorf("GetAssetDividend = %+v, %v", dividends.Data, err)
+	}
+
+	// withdrawFee is sometimes sent as a number
+	details, err := client.GetAssetDetail(ctx, GetAssetDetailRequest{Asset: "CTR"})
+	if err != nil || (*details.Data)["CTR"].WithdrawFee != "35" || (*details.Data)["CTR"].DepositStatus {
+		t.Errorf("GetAssetDetail = %+v, %v", details.Data, err)
+	}
+}
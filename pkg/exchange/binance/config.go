@@ -1,5 +1,12 @@
 package binance
 
+import (
+	"time"
+
+	"github.com/BullionBear/sequex/pkg/exchange/ratelimit"
+	"github.com/BullionBear/sequex/pkg/exchange/transport"
+)
+
 type Config struct {
 	// API credentials
 	APIKey    string
@@ -7,6 +14,12 @@ type Config struct {
 
 	// API endpoints
 	BaseURL string
+
+	// Proxy, dialer and DNS settings, direct connection when empty
+	Network transport.Options
+
+	// IP weight budget shared with the other processes of the IP, none when nil
+	Limiter *ratelimit.Limiter
 }
 
 func NewConfig(apiKey, apiSecret, baseURL string) *Config {
@@ -33,6 +46,17 @@ type WSConfig struct {
 	// API endpoints
 	BaseWsURL   string
 	BaseRestURL string
+
+	// Market stream reconnection, retried until it succeeds
+	ReconnectDelay    time.Duration // Delay before the first attempt, doubled after each failure, 5 seconds when 0
+	MaxReconnectDelay time.Duration // Cap of the backoff, 1 minute when 0
+	ReconnectJitter   float64       // Fraction of each delay randomized, 0.2 when 0, negative disables
+
+	// Proxy, dialer and DNS settings of both the streams and the REST client
+	Network transport.Options
+
+	// IP weight budget of the REST client, none when nil
+	Limiter *ratelimit.Limiter
 }
 
 func NewMainnetWSConfig(apiKey, apiSecret string) *WSConfig {
@@ -0,0 +1,50 @@
+package binance
+
+import (
+	"testing"
+	"time"
+
+	"github.com/BullionBear/sequex/pkg/exchange/wstest"
+)
+
+// tradeAdapter runs the websocket conformance suite over the raw trade stream
+type tradeAdapter struct {
+	client *WSClient
+}
+
+func (a *tradeAdapter) Subscribe(symbol string, h wstest.Handlers) (func(), error) {
+	return a.client.SubscribeTrade(symbol, TradeSubscriptionOptions{
+		OnConnect:    h.OnConnect,
+		OnReconnect:  h.OnReconnect,
+		OnDisconnect: h.OnDisconnect,
+		OnError:      h.OnError,
+		OnTrade:      func(trade WSTrade) { h.OnData(trade) },
+		OnGap:        func(gap Gap) { h.OnGap(gap.From, gap.To) },
+		OnStale:      func(time.Duration) { h.OnStale() },
+		StaleTimeout: h.StaleTimeout,
+	})
+}
+
+func (a *tradeAdapter) Close() {
+	a.client.Close()
+}
+
+func TestWSClient_Conformance(t *testing.T) {
+	wstest.Run(t, wstest.Case{
+		NewClient: func(url string) wstest.Client {
+			return &tradeAdapter{client: NewWSClient(&WSConfig{BaseWsURL: url + "/ws", ReconnectDelay: 100 * time.Millisecond})}
+		},
+		Symbol:  "BTCUSDT",
+		Path:    "/ws/btcusdt@trade",
+		Message: []byte(`{"e":"trade","E":1672515782136,"s":"BTCUSDT","t":12345,"p":"16500.10","q":"0.002","T":1672515782134,"m":true,"M":true}`),
+		Check: func(t *testing.T, data any) {
+			trade, ok := data.(WSTrade)
+			if !ok {
+				t.Fatalf("unexpected event %T", data)
+			}
+			if trade.Symbol != "BTCUSDT" || trade.TradeId != 12345 || trade.Price != "16500.10" || trade.Quantity != "0.002" || !trade.IsBuyerMaker {
+				t.Errorf("unexpected trade %+v", trade)
+			}
+		},
+	})
+}
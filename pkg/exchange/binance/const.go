@@ -25,6 +25,12 @@ const (
 	TestnetWSBaseUrl9443 = "wss://stream.testnet.binance.vision:9443/ws"
 )
 
+// IP request weight, counted per minute across every process of the IP
+const (
+	IPWeightLimit    = 6000
+	HeaderUsedWeight = "X-MBX-USED-WEIGHT-1M"
+)
+
 // Paths
 const (
 	PathCreateOrder      = "/v3/order"
@@ -33,6 +39,7 @@ const (
 	PathGetAggTrades     = "/v3/aggTrades"
 	PathGetKlines        = "/v3/klines"
 	PathGetPriceTicker   = "/v3/ticker/price"
+	PathGetTicker24hr    = "/v3/ticker/24hr"
 	PathGetExchangeInfo  = "/v3/exchangeInfo"
 	PathCancelOrder      = "/v3/order"
 	PathCancelAllOrders  = "/v3/openOrders"
@@ -41,4 +48,14 @@ const (
 	PathListOpenOrders   = "/v3/openOrders"
 	PathGetAccountTrades = "/v3/myTrades"
 	PathUserDataStream   = "/v3/userDataStream"
+
+	// Relative to the /sapi base, see sapiConfig
+	PathGetMarginInterestHistory = "/v1/margin/interestHistory"
+	PathGetAssetDividend         = "/v1/asset/assetDividend"
+	PathDustTransfer             = "/v1/asset/dust"
+	PathGetAssetDetail           = "/v1/asset/assetDetail"
+	PathUniversalTransfer        = "/v1/asset/transfer"
+	PathGetFundingAsset          = "/v1/asset/get-funding-asset"
+	PathWithdraw                 = "/v1/capital/withdraw/apply"
+	PathGetSystemStatus          = "/v1/system/status"
 )
@@ -42,3 +42,13 @@ const (
 	NewOrderRespTypeResult = "RESULT"
 	NewOrderRespTypeFull   = "FULL"
 )
+
+// TransferType represents the wallets of a universal transfer, from and to.
+const (
+	TransferTypeFundingMain     = "FUNDING_MAIN"     // Funding to spot
+	TransferTypeMainFunding     = "MAIN_FUNDING"     // Spot to funding
+	TransferTypeFundingUMFuture = "FUNDING_UMFUTURE" // Funding to USDⓈ-M futures
+	TransferTypeUMFutureFunding = "UMFUTURE_FUNDING" // USDⓈ-M futures to funding
+	TransferTypeMainUMFuture    = "MAIN_UMFUTURE"    // Spot to USDⓈ-M futures
+	TransferTypeUMFutureMain    = "UMFUTURE_MAIN"    // USDⓈ-M futures to spot
+)
@@ -0,0 +1,45 @@
+package binance
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/BullionBear/sequex/pkg/exchange/wstest"
+)
+
+// TestWSClient_Golden decodes recorded payloads of every stream through the
+// dispatch of the client, fast decoders included. Payloads named user_* are
+// user data stream events.
+func TestWSClient_Golden(t *testing.T) {
+	wstest.Golden(t, func(t *testing.T, name string, payload []byte) any {
+		var events []any
+		capture := func(event any) { events = append(events, event) }
+		onError := func(err error) { t.Errorf("decode: %v", err) }
+		c := &WSClient{}
+
+		if strings.HasPrefix(name, "user_") {
+			c.handleUserDataMessage(&Subscription{options: UserDataSubscriptionOptions{
+				OnError:            onError,
+				OnAccountPosition:  func(event WSOutboundAccountPositionEvent) { capture(event) },
+				OnBalanceUpdate:    func(event WSBalanceUpdateEvent) { capture(event) },
+				OnExecutionReport:  func(event WSExecutionReportEvent) { capture(event) },
+				OnListenKeyExpired: func(event WSListenKeyExpiredEvent) { capture(event) },
+			}}, payload)
+		} else {
+			// Each message only reaches the callback of its stream
+			for _, options := range []any{
+				KlineSubscriptionOptions{OnError: onError, OnKline: func(kline WSKline) { capture(kline) }},
+				AggTradeSubscriptionOptions{OnError: onError, OnAggTrade: func(trade WSAggTrade) { capture(trade) }},
+				TradeSubscriptionOptions{OnError: onError, OnTrade: func(trade WSTrade) { capture(trade) }},
+				DepthSubscriptionOptions{OnError: onError, OnDepth: func(depth WSDepth) { capture(depth) }},
+				DepthUpdateSubscriptionOptions{OnError: onError, OnDepthUpdate: func(update WSDepthUpdate) { capture(update) }},
+			} {
+				c.handleMessage(&Subscription{options: options}, payload)
+			}
+		}
+		if len(events) != 1 {
+			t.Fatalf("decoded %d events, want 1", len(events))
+		}
+		return events[0]
+	})
+}
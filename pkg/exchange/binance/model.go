@@ -1,5 +1,7 @@
 package binance
 
+import "encoding/json"
+
 // Response is the unified response wrapper for all endpoints.
 type Response[T any] struct {
 	Code    int    `json:"code"`
@@ -118,6 +120,37 @@ type PriceTicker struct {
 	Price  string `json:"price"`
 }
 
+// Ticker24hr models a single ticker in the /api/v3/ticker/24hr response.
+type Ticker24hr struct {
+	Symbol             string `json:"symbol"`
+	PriceChange        string `json:"priceChange"`
+	PriceChangePercent string `json:"priceChangePercent"`
+	WeightedAvgPrice   string `json:"weightedAvgPrice"`
+	PrevClosePrice     string `json:"prevClosePrice"`
+	LastPrice          string `json:"lastPrice"`
+	LastQty            string `json:"lastQty"`
+	BidPrice           string `json:"bidPrice"`
+	BidQty             string `json:"bidQty"`
+	AskPrice           string `json:"askPrice"`
+	AskQty             string `json:"askQty"`
+	OpenPrice          string `json:"openPrice"`
+	HighPrice          string `json:"highPrice"`
+	LowPrice           string `json:"lowPrice"`
+	Volume             string `json:"volume"`
+	QuoteVolume        string `json:"quoteVolume"`
+	OpenTime           int64  `json:"openTime"`
+	CloseTime          int64  `json:"closeTime"`
+	FirstId            int64  `json:"firstId"`
+	LastId             int64  `json:"lastId"`
+	Count              int64  `json:"count"`
+}
+
+// SystemStatus models the /sapi/v1/system/status response.
+type SystemStatus struct {
+	Status int    `json:"status"` // 0 normal, 1 system maintenance
+	Msg    string `json:"msg"`
+}
+
 // CancelOrderRequest models the request for cancelling an order.
 type CancelOrderRequest struct {
 	Symbol             string
@@ -261,6 +294,149 @@ type AccountTrade struct {
 	IsBestMatch     bool   `json:"isBestMatch"`
 }
 
+// GetMarginInterestHistoryRequest models the request for getting the margin borrow interest history.
+type GetMarginInterestHistoryRequest struct {
+	Asset          string // Every asset when empty
+	IsolatedSymbol string // Isolated margin pair, cross margin when empty
+	StartTime      int64
+	EndTime        int64
+	Current        int // Page, from 1
+	Size           int // Default 10, max 100
+	RecvWindow     int64
+}
+
+// MarginInterest models a single interest charge on a margin loan.
+type MarginInterest struct {
+	TxId                int64  `json:"txId"`
+	InterestAccuredTime int64  `json:"interestAccuredTime"`
+	Asset               string `json:"asset"`
+	RawAsset            string `json:"rawAsset"` // Asset borrowed, when the interest is paid in BNB
+	Principal           string `json:"principal"`
+	Interest            string `json:"interest"`
+	InterestRate        string `json:"interestRate"`
+	Type                string `json:"type"` // e.g. ON_BORROW, PERIODIC or PERIODIC_CONVERTED
+	IsolatedSymbol      string `json:"isolatedSymbol"`
+}
+
+// MarginInterestHistoryResponse models a page of the margin interest history.
+type MarginInterestHistoryResponse struct {
+	Rows  []MarginInterest `json:"rows"`
+	Total int64            `json:"total"`
+}
+
+// GetAssetDividendRequest models the request for getting the asset dividend record.
+type GetAssetDividendRequest struct {
+	Asset      string // Every asset when empty
+	StartTime  int64
+	EndTime    int64
+	Limit      int // Default 20, max 500
+	RecvWindow int64
+}
+
+// AssetDividend models a distribution credited to the account, such as
+// staking rewards or airdrops.
+type AssetDividend struct {
+	Id      int64  `json:"id"`
+	Amount  string `json:"amount"`
+	Asset   string `json:"asset"`
+	DivTime int64  `json:"divTime"`
+	EnInfo  string `json:"enInfo"` // Description of the distribution
+	TranId  int64  `json:"tranId"`
+}
+
+// AssetDividendResponse models the asset dividend record.
+type AssetDividendResponse struct {
+	Rows  []AssetDividend `json:"rows"`
+	Total int64           `json:"total"`
+}
+
+// DustTransferRequest models the request for converting small balances to BNB.
+type DustTransferRequest struct {
+	Assets      []string // Assets converted
+	AccountType string   // SPOT or MARGIN, SPOT when empty
+	RecvWindow  int64
+}
+
+// DustTransferResult models the conversion of one asset.
+type DustTransferResult struct {
+	Amount              string `json:"amount"` // Amount of FromAsset converted
+	FromAsset           string `json:"fromAsset"`
+	OperateTime         int64  `json:"operateTime"`
+	ServiceChargeAmount string `json:"serviceChargeAmount"` // Fee, in BNB
+	TranId              int64  `json:"tranId"`
+	TransferedAmount    string `json:"transferedAmount"` // BNB received, after the fee
+}
+
+// DustTransferResponse models the response for converting small balances to BNB.
+type DustTransferResponse struct {
+	TotalServiceCharge string               `json:"totalServiceCharge"`
+	TotalTransfered    string               `json:"totalTransfered"`
+	TransferResult     []DustTransferResult `json:"transferResult"`
+}
+
+// GetAssetDetailRequest models the request for getting the deposit and withdrawal details of assets.
+type GetAssetDetailRequest struct {
+	Asset      string // Every asset when empty
+	RecvWindow int64
+}
+
+// AssetDetail models the deposit and withdrawal details of an asset.
+type AssetDetail struct {
+	MinWithdrawAmount json.Number `json:"minWithdrawAmount"`
+	DepositStatus     bool        `json:"depositStatus"`
+	WithdrawFee       json.Number `json:"withdrawFee"` // Sent as a string or a number
+	WithdrawStatus    bool        `json:"withdrawStatus"`
+	DepositTip        string      `json:"depositTip"` // Reason deposits are suspended, if any
+}
+
+// UniversalTransferRequest models the request for transferring an asset between wallets.
+type UniversalTransferRequest struct {
+	Type       string // Wallets transferred from and to, see TransferType
+	Asset      string
+	Amount     string
+	FromSymbol string // Isolated margin symbol transferred from, if any
+	ToSymbol   string // Isolated margin symbol transferred to, if any
+	RecvWindow int64
+}
+
+// UniversalTransferResponse models the response for transferring an asset between wallets.
+type UniversalTransferResponse struct {
+	TranId int64 `json:"tranId"`
+}
+
+// GetFundingAssetRequest models the request for getting the balances of the funding wallet.
+type GetFundingAssetRequest struct {
+	Asset      string // Every asset with a balance when empty
+	RecvWindow int64
+}
+
+// FundingAsset models a balance of the funding wallet.
+type FundingAsset struct {
+	Asset        string `json:"asset"`
+	Free         string `json:"free"`
+	Locked       string `json:"locked"`
+	Freeze       string `json:"freeze"`
+	Withdrawing  string `json:"withdrawing"`
+	BtcValuation string `json:"btcValuation"`
+}
+
+// WithdrawRequest models the request for withdrawing an asset to an address.
+type WithdrawRequest struct {
+	Coin            string
+	Network         string // Default network of the coin when empty
+	Address         string
+	AddressTag      string // Memo of the networks needing one
+	Amount          string
+	WithdrawOrderId string // Client id of the withdrawal, rejected when reused
+	Name            string // Label of the address
+	RecvWindow      int64
+}
+
+// WithdrawResponse models the response for withdrawing an asset.
+type WithdrawResponse struct {
+	Id string `json:"id"`
+}
+
 // UserDataStreamResponse models the response for starting a user data stream.
 type UserDataStreamResponse struct {
 	ListenKey string `json:"listenKey"`
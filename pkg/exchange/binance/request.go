@@ -25,13 +25,11 @@ func doUnsignedGet(cfg *Config, endpoint string, params map[string]string) ([]by
 		}
 		fullURL += "?" + q.Encode()
 	}
-	resp, err := http.Get(fullURL)
+	req, err := http.NewRequest(http.MethodGet, fullURL, nil)
 	if err != nil {
 		return nil, 0, err
 	}
-	defer resp.Body.Close()
-	body, err := io.ReadAll(resp.Body)
-	return body, resp.StatusCode, err
+	return send(cfg, req)
 }
 
 // signed request (GET/POST/PUT/DELETE)
@@ -66,14 +64,15 @@ func doSignedRequest(cfg *Config, method, endpoint string, params map[string]str
 	// Set API key header
 	req.Header.Set("X-MBX-APIKEY", cfg.APIKey)
 
-	client := &http.Client{}
-	resp, err := client.Do(req)
-	if err != nil {
-		return nil, 0, err
-	}
-	defer resp.Body.Close()
-	body, err := io.ReadAll(resp.Body)
-	return body, resp.StatusCode, err
+	return send(cfg, req)
+}
+
+// sapiConfig returns cfg pointed at the /sapi endpoints, which are served
+// next to /api on the same host
+func sapiConfig(cfg *Config) *Config {
+	sapi := *cfg
+	sapi.BaseURL = strings.TrimSuffix(strings.TrimRight(cfg.BaseURL, "/"), "/api") + "/sapi"
+	return &sapi
 }
 
 // doAPIKeyOnlyRequest handles requests that only need API key header (no signing)
@@ -98,12 +97,32 @@ func doAPIKeyOnlyRequest(cfg *Config, method, endpoint string, params map[string
 	// Set API key header
 	req.Header.Set("X-MBX-APIKEY", cfg.APIKey)
 
-	client := &http.Client{}
+	return send(cfg, req)
+}
+
+// send performs a request within the weight budget of the configuration and
+// reports the weight the exchange counted back to it
+func send(cfg *Config, req *http.Request) ([]byte, int, error) {
+	ctx := req.Context()
+	if cfg.Limiter != nil {
+		if err := cfg.Limiter.Wait(ctx, 1); err != nil {
+			return nil, 0, err
+		}
+	}
+	client, err := cfg.Network.SharedHTTPClient()
+	if err != nil {
+		return nil, 0, err
+	}
 	resp, err := client.Do(req)
 	if err != nil {
 		return nil, 0, err
 	}
 	defer resp.Body.Close()
+	if cfg.Limiter != nil {
+		if used, err := strconv.Atoi(resp.Header.Get(HeaderUsedWeight)); err == nil {
+			cfg.Limiter.Observe(ctx, used)
+		}
+	}
 	body, err := io.ReadAll(resp.Body)
 	return body, resp.StatusCode, err
 }
@@ -135,3 +135,19 @@ func TestDoSignedRequest_PostTestOrderWithCommissionRates(t *testing.T) {
 		t.Fatalf("expected status 200, got %d", status)
 	}
 }
+
+func TestSapiConfig(t *testing.T) {
+	for base, want := range map[string]string{
+		MainnetBaseUrl:              "https://api.binance.com/sapi",
+		MainnetBaseUrl1 + "/":       "https://api1.binance.com/sapi",
+		"http://127.0.0.1:8080/api": "http://127.0.0.1:8080/sapi",
+	} {
+		cfg := &Config{BaseURL: base}
+		if got := sapiConfig(cfg).BaseURL; got != want {
+			t.Errorf("sapi base of %s = %s, want %s", base, got, want)
+		}
+		if cfg.BaseURL != base {
+			t.Errorf("config modified: %s", cfg.BaseURL)
+		}
+	}
+}
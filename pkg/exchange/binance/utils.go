@@ -3,31 +3,43 @@ package binance
 import (
 	"context"
 	"fmt"
-	"log"
+	"sync"
 )
 
 var (
-	symbolMap = make(map[string]Symbol)
+	symbolMap     = make(map[string]Symbol)
+	symbolMapOnce sync.Once
+	symbolMapErr  error
 )
 
-func init() {
-	binanceClient := NewClient(NewMainnetConfig("", ""))
-	resp, err := binanceClient.GetExchangeInfo(context.Background(), ExchangeInfoRequest{
-		Permissions:  []string{"SPOT"},
-		SymbolStatus: "TRADING",
+// loadSymbolMap fetches the trading symbols on first use, so importing the
+// package does not require a connection to Binance
+func loadSymbolMap() error {
+	symbolMapOnce.Do(func() {
+		binanceClient := NewClient(NewMainnetConfig("", ""))
+		resp, err := binanceClient.GetExchangeInfo(context.Background(), ExchangeInfoRequest{
+			Permissions:  []string{"SPOT"},
+			SymbolStatus: "TRADING",
+		})
+		if err != nil {
+			symbolMapErr = fmt.Errorf("failed to get exchange info: %w", err)
+			return
+		}
+		if resp.Code != 0 {
+			symbolMapErr = fmt.Errorf("failed to get exchange info: %s", resp.Message)
+			return
+		}
+		for _, s := range resp.Data.Symbols {
+			symbolMap[s.Symbol] = s
+		}
 	})
-	if err != nil {
-		log.Fatalf("Failed to get exchange info: %v", err)
-	}
-	if resp.Code != 0 {
-		log.Fatalf("Failed to get exchange info: %v", resp.Message)
-	}
-	for _, s := range resp.Data.Symbols {
-		symbolMap[s.Symbol] = s
-	}
+	return symbolMapErr
 }
 
 func GetBaseAsset(symbol string) (string, error) {
+	if err := loadSymbolMap(); err != nil {
+		return "", err
+	}
 	binanceSymbol, ok := symbolMap[symbol]
 	if !ok {
 		return "", fmt.Errorf("symbol %s not found", symbol)
@@ -36,6 +48,9 @@ func GetBaseAsset(symbol string) (string, error) {
 }
 
 func GetQuoteAsset(symbol string) (string, error) {
+	if err := loadSymbolMap(); err != nil {
+		return "", err
+	}
 	binanceSymbol, ok := symbolMap[symbol]
 	if !ok {
 		return "", fmt.Errorf("symbol %s not found", symbol)
@@ -3,6 +3,7 @@ package binance
 import (
 	"context"
 	"log"
+	"math/rand"
 	"sync"
 	"time"
 
@@ -18,19 +19,38 @@ type WSConnection interface {
 
 const (
 	pingInterval      = 20 * time.Second
-	reconnectDelay    = 5 * time.Second
+	reconnectDelay    = 5 * time.Second  // Delay before the first reconnection attempt
+	maxReconnectDelay = 1 * time.Minute  // Cap of the reconnection backoff
+	reconnectJitter   = 0.2              // Fraction of each reconnection delay randomized
 	keepaliveInterval = 30 * time.Minute // Keepalive interval for user data streams
 )
 
+// Gap is the window a stream delivered no data because of a reconnection
+type Gap struct {
+	From time.Time // Receipt of the last message before the drop
+	To   time.Time // Reconnection
+}
+
+// Duration returns the length of the gap
+func (g Gap) Duration() time.Duration {
+	return g.To.Sub(g.From)
+}
+
 type BinanceWSConn struct {
-	conn      *websocket.Conn
-	url       string
-	mu        sync.Mutex
-	connected bool
-	ctx       context.Context
-	cancel    context.CancelFunc
-	reconnect bool
-	OnMessage func([]byte) // Callback for handling messages
+	conn         *websocket.Conn
+	url          string
+	mu           sync.Mutex
+	connected    bool
+	ctx          context.Context
+	cancel       context.CancelFunc
+	reconnect    bool
+	config       *WSConfig
+	lastMessage  time.Time // Receipt of the last message, where a gap starts
+	pingOnce     sync.Once // The ping and watchdog loops outlive connections
+	staleTimeout time.Duration
+	OnMessage    func([]byte)        // Callback for handling messages
+	OnReconnect  func(Gap)           // Callback after a reconnection with the window data may have been missed
+	OnStale      func(time.Duration) // Callback when the stream went silent for the stale timeout
 }
 
 func NewBinanceWSConn(baseURL, streamPath string) *BinanceWSConn {
@@ -40,21 +60,31 @@ func NewBinanceWSConn(baseURL, streamPath string) *BinanceWSConn {
 		ctx:       ctx,
 		cancel:    cancel,
 		reconnect: true,
+		config:    &WSConfig{},
 	}
 }
 
 func (w *BinanceWSConn) Connect() error {
 	w.mu.Lock()
 	defer w.mu.Unlock()
-	dialer := websocket.DefaultDialer
-	c, _, err := dialer.Dial(w.url, nil)
+	dialer, err := w.config.Network.WSDialer()
+	if err != nil {
+		return err
+	}
+	c, _, err := dialer.DialContext(w.ctx, w.url, nil)
 	if err != nil {
 		return err
 	}
 	w.conn = c
 	w.connected = true
+	w.lastMessage = time.Now()
 	go w.readLoop()
-	go w.pingLoop()
+	w.pingOnce.Do(func() {
+		go w.pingLoop()
+		if w.staleTimeout > 0 {
+			go w.watchdogLoop(w.staleTimeout)
+		}
+	})
 	return nil
 }
 
@@ -62,6 +92,27 @@ func (w *BinanceWSConn) SetOnMessage(handler func([]byte)) {
 	w.OnMessage = handler
 }
 
+// SetOnReconnect sets the callback called after every reconnection
+func (w *BinanceWSConn) SetOnReconnect(handler func(Gap)) {
+	w.OnReconnect = handler
+}
+
+// SetStale enables the staleness watchdog: once the stream is silent for
+// timeout, the handler is called and the connection probed with a ping, and
+// if it stays silent for half a timeout more, the connection is reconnected.
+// A zero timeout disables the watchdog.
+func (w *BinanceWSConn) SetStale(timeout time.Duration, handler func(time.Duration)) {
+	w.staleTimeout = timeout
+	w.OnStale = handler
+}
+
+// SetConfig sets the reconnection backoff from a client configuration
+func (w *BinanceWSConn) SetConfig(config *WSConfig) {
+	if config != nil {
+		w.config = config
+	}
+}
+
 func (w *BinanceWSConn) readLoop() {
 	for {
 		select {
@@ -78,7 +129,7 @@ func (w *BinanceWSConn) readLoop() {
 			return
 		}
 
-		_, message, err := conn.ReadMessage()
+		_, message, err := w.config.Network.ReadMessage(conn)
 		if err != nil {
 			// Check if this is a graceful shutdown
 			if w.ctx.Err() != nil {
@@ -89,6 +140,10 @@ func (w *BinanceWSConn) readLoop() {
 			return
 		}
 
+		w.mu.Lock()
+		w.lastMessage = time.Now()
+		w.mu.Unlock()
+
 		// Call the message handler if set
 		if w.OnMessage != nil {
 			w.OnMessage(message)
@@ -115,6 +170,50 @@ func (w *BinanceWSConn) pingLoop() {
 	}
 }
 
+// watchdogLoop detects streams that went quiet while the TCP connection
+// stays open, which Binance occasionally does, and forces a reconnection
+func (w *BinanceWSConn) watchdogLoop(timeout time.Duration) {
+	ticker := time.NewTicker(timeout / 4)
+	defer ticker.Stop()
+
+	var probed time.Time // Last message when the current silence was probed
+	for {
+		select {
+		case <-w.ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		w.mu.Lock()
+		conn := w.conn
+		last := w.lastMessage
+		w.mu.Unlock()
+		if conn == nil {
+			continue // Reconnecting
+		}
+
+		silence := time.Since(last)
+		switch {
+		case silence < timeout:
+		case !probed.Equal(last):
+			probed = last
+			log.Printf("[WS] No message for %v, probing", silence.Round(time.Millisecond))
+			if w.OnStale != nil {
+				w.OnStale(silence)
+			}
+			if err := conn.WriteControl(websocket.PingMessage, nil, time.Now().Add(timeout/2)); err != nil {
+				log.Printf("[WS] Probe failed: %v", err)
+				conn.Close() // The read loop reconnects
+			}
+		case silence >= timeout+timeout/2:
+			log.Printf("[WS] No message for %v, reconnecting", silence.Round(time.Millisecond))
+			conn.Close() // The read loop reconnects
+		}
+	}
+}
+
+// handleDisconnect reconnects until it succeeds or the connection is
+// disconnected, backing off between failed attempts
 func (w *BinanceWSConn) handleDisconnect() {
 	w.mu.Lock()
 	w.connected = false
@@ -122,15 +221,33 @@ func (w *BinanceWSConn) handleDisconnect() {
 		w.conn.Close()
 		w.conn = nil
 	}
-	shouldReconnect := w.reconnect && w.ctx.Err() == nil
+	gapStart := w.lastMessage
 	w.mu.Unlock()
 
-	if shouldReconnect {
-		log.Printf("[WS] Reconnecting in %v...", reconnectDelay)
-		time.Sleep(reconnectDelay)
+	for attempt := 0; ; attempt++ {
+		w.mu.Lock()
+		shouldReconnect := w.reconnect && w.ctx.Err() == nil
+		w.mu.Unlock()
+		if !shouldReconnect {
+			return
+		}
+
+		delay := backoffDelay(w.config, attempt)
+		log.Printf("[WS] Reconnecting in %v... (attempt %d)", delay, attempt+1)
+		select {
+		case <-w.ctx.Done():
+			return
+		case <-time.After(delay):
+		}
+
 		if err := w.Connect(); err != nil {
 			log.Printf("[WS] Reconnect failed: %v", err)
+			continue
+		}
+		if w.OnReconnect != nil {
+			w.OnReconnect(Gap{From: gapStart, To: time.Now()})
 		}
+		return
 	}
 }
 
@@ -156,6 +273,39 @@ func (w *BinanceWSConn) IsConnected() bool {
 	return w.connected
 }
 
+// backoffDelay returns the delay before a reconnection attempt: the
+// reconnect delay doubled on every failed attempt up to MaxReconnectDelay,
+// randomized by the jitter fraction so streams dropped together do not
+// reconnect together
+func backoffDelay(config *WSConfig, attempt int) time.Duration {
+	delay := config.ReconnectDelay
+	if delay <= 0 {
+		delay = reconnectDelay
+	}
+	maxDelay := config.MaxReconnectDelay
+	if maxDelay <= 0 {
+		maxDelay = maxReconnectDelay
+	}
+	for i := 0; i < attempt && delay < maxDelay; i++ {
+		delay *= 2
+	}
+	if delay > maxDelay {
+		delay = maxDelay
+	}
+
+	jitter := config.ReconnectJitter
+	if jitter == 0 {
+		jitter = reconnectJitter
+	}
+	if jitter > 0 {
+		if jitter > 1 {
+			jitter = 1
+		}
+		delay = time.Duration(float64(delay) * (1 - jitter + 2*jitter*rand.Float64()))
+	}
+	return delay
+}
+
 // UserDataWSConn handles WebSocket connections for user data streams with listen key management
 type UserDataWSConn struct {
 	conn               *websocket.Conn
@@ -193,7 +343,10 @@ func (w *UserDataWSConn) Connect() error {
 	// Construct URL for user data stream
 	url := w.baseURL + "/ws/" + w.listenKey
 
-	dialer := websocket.DefaultDialer
+	dialer, err := w.client.cfg.Network.WSDialer()
+	if err != nil {
+		return err
+	}
 	c, _, err := dialer.Dial(url, nil)
 	if err != nil {
 		return err
@@ -252,7 +405,7 @@ func (w *UserDataWSConn) readLoop() {
 			return
 		}
 
-		_, message, err := conn.ReadMessage()
+		_, message, err := w.client.cfg.Network.ReadMessage(conn)
 		if err != nil {
 			// Check if this is a graceful shutdown
 			if w.ctx.Err() != nil {
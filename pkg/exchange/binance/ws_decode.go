@@ -0,0 +1,285 @@
+package binance
+
+import (
+	"errors"
+	"strconv"
+	"sync"
+	"unsafe"
+)
+
+// Hand-written decoders for the hot websocket schemas. They avoid the
+// reflection and per-field allocations of encoding/json; anything they do not
+// understand (escaped strings, nested values, unexpected layout) returns
+// errFastDecode and the caller falls back to encoding/json.
+
+var errFastDecode = errors.New("binance: payload not supported by fast decoder")
+
+// internTableLimit bounds the intern table so unexpected symbols cannot grow it forever
+const internTableLimit = 4096
+
+var (
+	internMu    sync.RWMutex
+	internTable = make(map[string]string)
+)
+
+// intern returns a shared string for small, highly repetitive values such as
+// event types and symbols, allocating only the first time a value is seen
+func intern(b []byte) string {
+	internMu.RLock()
+	s, ok := internTable[string(b)]
+	internMu.RUnlock()
+	if ok {
+		return s
+	}
+	s = string(b)
+	internMu.Lock()
+	if len(internTable) < internTableLimit {
+		internTable[s] = s
+	}
+	internMu.Unlock()
+	return s
+}
+
+// alias returns a string sharing memory with b. It is only used on websocket
+// frames, which are freshly allocated per message and never mutated.
+func alias(b []byte) string {
+	if len(b) == 0 {
+		return ""
+	}
+	return unsafe.String(unsafe.SliceData(b), len(b))
+}
+
+// jsonScanner walks a flat JSON object
+type jsonScanner struct {
+	data []byte
+	pos  int
+}
+
+func (s *jsonScanner) skipSpace() {
+	for s.pos < len(s.data) {
+		switch s.data[s.pos] {
+		case ' ', '\t', '\n', '\r':
+			s.pos++
+		default:
+			return
+		}
+	}
+}
+
+func (s *jsonScanner) consume(c byte) bool {
+	s.skipSpace()
+	if s.pos < len(s.data) && s.data[s.pos] == c {
+		s.pos++
+		return true
+	}
+	return false
+}
+
+// str reads a string without escape sequences and returns its raw bytes
+func (s *jsonScanner) str() ([]byte, error) {
+	if !s.consume('"') {
+		return nil, errFastDecode
+	}
+	start := s.pos
+	for s.pos < len(s.data) {
+		switch s.data[s.pos] {
+		case '"':
+			v := s.data[start:s.pos]
+			s.pos++
+			return v, nil
+		case '\\':
+			return nil, errFastDecode
+		}
+		s.pos++
+	}
+	return nil, errFastDecode
+}
+
+// number reads a JSON number token
+func (s *jsonScanner) number() ([]byte, error) {
+	s.skipSpace()
+	start := s.pos
+	for s.pos < len(s.data) {
+		c := s.data[s.pos]
+		if (c >= '0' && c <= '9') || c == '-' || c == '+' || c == '.' || c == 'e' || c == 'E' {
+			s.pos++
+			continue
+		}
+		break
+	}
+	if start == s.pos {
+		return nil, errFastDecode
+	}
+	return s.data[start:s.pos], nil
+}
+
+func (s *jsonScanner) int64() (int64, error) {
+	b, err := s.number()
+	if err != nil {
+		return 0, err
+	}
+	return strconv.ParseInt(alias(b), 10, 64)
+}
+
+func (s *jsonScanner) bool() (bool, error) {
+	s.skipSpace()
+	rest := s.data[s.pos:]
+	switch {
+	case len(rest) >= 4 && string(rest[:4]) == "true":
+		s.pos += 4
+		return true, nil
+	case len(rest) >= 5 && string(rest[:5]) == "false":
+		s.pos += 5
+		return false, nil
+	}
+	return false, errFastDecode
+}
+
+// skipValue skips a scalar value of a field the decoder does not need
+func (s *jsonScanner) skipValue() error {
+	s.skipSpace()
+	if s.pos >= len(s.data) {
+		return errFastDecode
+	}
+	switch c := s.data[s.pos]; {
+	case c == '"':
+		_, err := s.str()
+		return err
+	case c == 't' || c == 'f':
+		_, err := s.bool()
+		return err
+	case c == 'n':
+		if len(s.data)-s.pos >= 4 && string(s.data[s.pos:s.pos+4]) == "null" {
+			s.pos += 4
+			return nil
+		}
+		return errFastDecode
+	default:
+		_, err := s.number()
+		return err
+	}
+}
+
+// fields iterates the keys of a flat object, calling field with the scanner
+// positioned on each value
+func (s *jsonScanner) fields(field func(key []byte) error) error {
+	if !s.consume('{') {
+		return errFastDecode
+	}
+	if s.consume('}') {
+		return nil
+	}
+	for {
+		key, err := s.str()
+		if err != nil {
+			return err
+		}
+		if !s.consume(':') {
+			return errFastDecode
+		}
+		if err := field(key); err != nil {
+			return err
+		}
+		if s.consume(',') {
+			continue
+		}
+		if s.consume('}') {
+			return nil
+		}
+		return errFastDecode
+	}
+}
+
+// peekEventType returns the event type when it is the first key of the
+// object, which is how Binance lays out every event stream
+func peekEventType(data []byte) (string, bool) {
+	s := jsonScanner{data: data}
+	if !s.consume('{') {
+		return "", false
+	}
+	key, err := s.str()
+	if err != nil || string(key) != "e" || !s.consume(':') {
+		return "", false
+	}
+	value, err := s.str()
+	if err != nil {
+		return "", false
+	}
+	return intern(value), true
+}
+
+// decodeTradeEvent decodes a raw trade event
+func decodeTradeEvent(data []byte, event *WSTradeEvent) error {
+	s := jsonScanner{data: data}
+	return s.fields(func(key []byte) error {
+		var err error
+		var b []byte
+		switch string(key) {
+		case "e":
+			b, err = s.str()
+			event.EventType = intern(b)
+		case "E":
+			event.EventTime, err = s.int64()
+		case "s":
+			b, err = s.str()
+			event.Symbol = intern(b)
+		case "t":
+			event.TradeId, err = s.int64()
+		case "p":
+			b, err = s.str()
+			event.Price = alias(b)
+		case "q":
+			b, err = s.str()
+			event.Quantity = alias(b)
+		case "T":
+			event.TradeTime, err = s.int64()
+		case "m":
+			event.IsBuyerMaker, err = s.bool()
+		case "M":
+			event.Ignore, err = s.bool()
+		default:
+			err = s.skipValue()
+		}
+		return err
+	})
+}
+
+// decodeAggTradeEvent decodes an aggregate trade event
+func decodeAggTradeEvent(data []byte, event *WSAggTradeEvent) error {
+	s := jsonScanner{data: data}
+	return s.fields(func(key []byte) error {
+		var err error
+		var b []byte
+		switch string(key) {
+		case "e":
+			b, err = s.str()
+			event.EventType = intern(b)
+		case "E":
+			event.EventTime, err = s.int64()
+		case "s":
+			b, err = s.str()
+			event.Symbol = intern(b)
+		case "a":
+			event.AggTradeId, err = s.int64()
+		case "p":
+			b, err = s.str()
+			event.Price = alias(b)
+		case "q":
+			b, err = s.str()
+			event.Quantity = alias(b)
+		case "f":
+			event.FirstTradeId, err = s.int64()
+		case "l":
+			event.LastTradeId, err = s.int64()
+		case "T":
+			event.TradeTime, err = s.int64()
+		case "m":
+			event.IsBuyerMaker, err = s.bool()
+		case "M":
+			event.Ignore, err = s.bool()
+		default:
+			err = s.skipValue()
+		}
+		return err
+	})
+}
@@ -0,0 +1,107 @@
+package binance
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+var (
+	tradeEventJSON    = []byte(`{"e":"trade","E":1700000000123,"s":"BTCUSDT","t":4123456789,"p":"60123.45000000","q":"0.01230000","T":1700000000120,"m":true,"M":true}`)
+	aggTradeEventJSON = []byte(`{"e":"aggTrade","E":1700000000123,"s":"ETHUSDT","a":987654321,"p":"3012.10000000","q":"1.50000000","f":100,"l":105,"T":1700000000120,"m":false,"M":true}`)
+)
+
+func TestDecodeTradeEvent(t *testing.T) {
+	var want, got WSTradeEvent
+	if err := json.Unmarshal(tradeEventJSON, &want); err != nil {
+		t.Fatal(err)
+	}
+	if err := decodeTradeEvent(tradeEventJSON, &got); err != nil {
+		t.Fatalf("decodeTradeEvent: %v", err)
+	}
+	if got != want {
+		t.Errorf("got %+v, want %+v", got, want)
+	}
+
+	// Unknown fields and whitespace are tolerated
+	var spaced WSTradeEvent
+	data := []byte(`{ "e" : "trade", "X": null, "b": 12, "x": "MARKET", "s": "BTCUSDT", "t": 1, "p": "1.0", "q": "2.0", "T": 3, "m": false, "M": true }`)
+	if err := decodeTradeEvent(data, &spaced); err != nil {
+		t.Fatalf("decodeTradeEvent: %v", err)
+	}
+	if spaced.Symbol != "BTCUSDT" || spaced.TradeId != 1 || spaced.Quantity != "2.0" || !spaced.Ignore {
+		t.Errorf("got %+v", spaced)
+	}
+}
+
+func TestDecodeTradeEventFallback(t *testing.T) {
+	for _, data := range []string{
+		`{"e":"trade","s":"BTC\u0055SDT"}`,
+		`{"e":"trade","x":{"nested":1}}`,
+		`{"e":"trade","s":"BTCUSDT"`,
+		`["trade"]`,
+	} {
+		var event WSTradeEvent
+		if err := decodeTradeEvent([]byte(data), &event); err == nil {
+			t.Errorf("%s: expected the fast decoder to refuse", data)
+		}
+	}
+}
+
+func TestDecodeAggTradeEvent(t *testing.T) {
+	var want, got WSAggTradeEvent
+	if err := json.Unmarshal(aggTradeEventJSON, &want); err != nil {
+		t.Fatal(err)
+	}
+	if err := decodeAggTradeEvent(aggTradeEventJSON, &got); err != nil {
+		t.Fatalf("decodeAggTradeEvent: %v", err)
+	}
+	if got != want {
+		t.Errorf("got %+v, want %+v", got, want)
+	}
+}
+
+func TestPeekEventType(t *testing.T) {
+	tests := []struct {
+		data string
+		want string
+		ok   bool
+	}{
+		{`{"e":"trade","s":"BTCUSDT"}`, "trade", true},
+		{` { "e" : "depthUpdate" }`, "depthUpdate", true},
+		{`{"lastUpdateId":1,"bids":[],"asks":[]}`, "", false},
+		{`{"s":"BTCUSDT","e":"trade"}`, "", false},
+	}
+	for _, tt := range tests {
+		got, ok := peekEventType([]byte(tt.data))
+		if got != tt.want || ok != tt.ok {
+			t.Errorf("peekEventType(%s) = %q, %v, want %q, %v", tt.data, got, ok, tt.want, tt.ok)
+		}
+	}
+}
+
+func BenchmarkDecodeTradeEventJSON(b *testing.B) {
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		var raw map[string]interface{}
+		if err := json.Unmarshal(tradeEventJSON, &raw); err != nil {
+			b.Fatal(err)
+		}
+		var event WSTradeEvent
+		if err := json.Unmarshal(tradeEventJSON, &event); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkDecodeTradeEvent(b *testing.B) {
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		if _, ok := peekEventType(tradeEventJSON); !ok {
+			b.Fatal("no event type")
+		}
+		var event WSTradeEvent
+		if err := decodeTradeEvent(tradeEventJSON, &event); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
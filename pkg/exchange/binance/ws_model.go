@@ -1,5 +1,7 @@
 package binance
 
+import "time"
+
 // WSKlineEvent represents the complete kline/candlestick WebSocket event
 type WSKlineEvent struct {
 	EventType string  `json:"e"` // Event type
@@ -92,29 +94,38 @@ type WSDepthUpdate = WSDepthUpdateEvent
 
 // KlineSubscriptionOptions defines the callback functions for kline subscription
 type KlineSubscriptionOptions struct {
-	OnConnect    func()              // Called when connection is established
-	OnReconnect  func()              // Called when connection is reestablished
-	OnError      func(err error)     // Called when an error occurs
-	OnKline      func(kline WSKline) // Called when kline data is received
-	OnDisconnect func()              // Called when connection is disconnected
+	OnConnect    func()                      // Called when connection is established
+	OnReconnect  func()                      // Called when connection is reestablished
+	OnError      func(err error)             // Called when an error occurs
+	OnKline      func(kline WSKline)         // Called when kline data is received
+	OnDisconnect func()                      // Called when connection is disconnected
+	OnGap        func(gap Gap)               // Called after a reconnection with the window data may have been missed
+	OnStale      func(silence time.Duration) // Called when the stream went silent for StaleTimeout
+	StaleTimeout time.Duration               // Silence before the stream is probed and reconnected, disabled when 0
 }
 
 // AggTradeSubscriptionOptions defines the callback functions for aggregate trade subscription
 type AggTradeSubscriptionOptions struct {
-	OnConnect    func()                    // Called when connection is established
-	OnReconnect  func()                    // Called when connection is reestablished
-	OnError      func(err error)           // Called when an error occurs
-	OnAggTrade   func(aggTrade WSAggTrade) // Called when aggregate trade data is received
-	OnDisconnect func()                    // Called when connection is disconnected
+	OnConnect    func()                      // Called when connection is established
+	OnReconnect  func()                      // Called when connection is reestablished
+	OnError      func(err error)             // Called when an error occurs
+	OnAggTrade   func(aggTrade WSAggTrade)   // Called when aggregate trade data is received
+	OnDisconnect func()                      // Called when connection is disconnected
+	OnGap        func(gap Gap)               // Called after a reconnection with the window data may have been missed
+	OnStale      func(silence time.Duration) // Called when the stream went silent for StaleTimeout
+	StaleTimeout time.Duration               // Silence before the stream is probed and reconnected, disabled when 0
 }
 
 // TradeSubscriptionOptions defines the callback functions for raw trade subscription
 type TradeSubscriptionOptions struct {
-	OnConnect    func()              // Called when connection is established
-	OnReconnect  func()              // Called when connection is reestablished
-	OnError      func(err error)     // Called when an error occurs
-	OnTrade      func(trade WSTrade) // Called when trade data is received
-	OnDisconnect func()              // Called when connection is disconnected
+	OnConnect    func()                      // Called when connection is established
+	OnReconnect  func()                      // Called when connection is reestablished
+	OnError      func(err error)             // Called when an error occurs
+	OnTrade      func(trade WSTrade)         // Called when trade data is received
+	OnDisconnect func()                      // Called when connection is disconnected
+	OnGap        func(gap Gap)               // Called after a reconnection with the window data may have been missed
+	OnStale      func(silence time.Duration) // Called when the stream went silent for StaleTimeout
+	StaleTimeout time.Duration               // Silence before the stream is probed and reconnected, disabled when 0
 }
 
 func (t *TradeSubscriptionOptions) WithConnect(onConnect func()) *TradeSubscriptionOptions {
@@ -142,22 +153,39 @@ func (t *TradeSubscriptionOptions) WithDisconnect(onDisconnect func()) *TradeSub
 	return t
 }
 
+func (t *TradeSubscriptionOptions) WithGap(onGap func(Gap)) *TradeSubscriptionOptions {
+	t.OnGap = onGap
+	return t
+}
+
+func (t *TradeSubscriptionOptions) WithStale(timeout time.Duration, onStale func(silence time.Duration)) *TradeSubscriptionOptions {
+	t.StaleTimeout = timeout
+	t.OnStale = onStale
+	return t
+}
+
 // DepthSubscriptionOptions defines the callback functions for partial book depth subscription
 type DepthSubscriptionOptions struct {
-	OnConnect    func()              // Called when connection is established
-	OnReconnect  func()              // Called when connection is reestablished
-	OnError      func(err error)     // Called when an error occurs
-	OnDepth      func(depth WSDepth) // Called when depth data is received
-	OnDisconnect func()              // Called when connection is disconnected
+	OnConnect    func()                      // Called when connection is established
+	OnReconnect  func()                      // Called when connection is reestablished
+	OnError      func(err error)             // Called when an error occurs
+	OnDepth      func(depth WSDepth)         // Called when depth data is received
+	OnDisconnect func()                      // Called when connection is disconnected
+	OnGap        func(gap Gap)               // Called after a reconnection with the window data may have been missed
+	OnStale      func(silence time.Duration) // Called when the stream went silent for StaleTimeout
+	StaleTimeout time.Duration               // Silence before the stream is probed and reconnected, disabled when 0
 }
 
 // DepthUpdateSubscriptionOptions defines the callback functions for differential depth subscription
 type DepthUpdateSubscriptionOptions struct {
-	OnConnect     func()                     // Called when connection is established
-	OnReconnect   func()                     // Called when connection is reestablished
-	OnError       func(err error)            // Called when an error occurs
-	OnDepthUpdate func(update WSDepthUpdate) // Called when depth update data is received
-	OnDisconnect  func()                     // Called when connection is disconnected
+	OnConnect     func()                      // Called when connection is established
+	OnReconnect   func()                      // Called when connection is reestablished
+	OnError       func(err error)             // Called when an error occurs
+	OnDepthUpdate func(update WSDepthUpdate)  // Called when depth update data is received
+	OnDisconnect  func()                      // Called when connection is disconnected
+	OnGap         func(gap Gap)               // Called after a reconnection with the window data may have been missed
+	OnStale       func(silence time.Duration) // Called when the stream went silent for StaleTimeout
+	StaleTimeout  time.Duration               // Silence before the stream is probed and reconnected, disabled when 0
 }
 
 // ConnectionState represents the current state of a WebSocket subscription
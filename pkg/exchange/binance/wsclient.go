@@ -7,6 +7,7 @@ import (
 	"log"
 	"strings"
 	"sync"
+	"time"
 )
 
 // WSClient manages WebSocket connections for different Binance streams
@@ -14,6 +15,7 @@ type WSClient struct {
 	subscriptions map[string]*Subscription
 	mu            sync.RWMutex
 	baseWsURL     string
+	config        *WSConfig
 	restClient    *Client // REST API client for user data stream management
 }
 
@@ -27,10 +29,13 @@ func NewWSClient(config *WSConfig) *WSClient {
 		APIKey:    config.APIKey,
 		APISecret: config.APISecret,
 		BaseURL:   config.BaseRestURL,
+		Network:   config.Network,
+		Limiter:   config.Limiter,
 	})
 	return &WSClient{
 		subscriptions: make(map[string]*Subscription),
 		baseWsURL:     config.BaseWsURL,
+		config:        config,
 		restClient:    client,
 	}
 }
@@ -135,6 +140,15 @@ func (c *WSClient) subscribe(subscriptionID, streamPath string, options interfac
 	conn.SetOnMessage(func(data []byte) {
 		c.handleMessage(subscription, data)
 	})
+	conn.SetConfig(c.config)
+	conn.SetStale(c.staleOptions(options))
+	conn.SetOnReconnect(func(gap Gap) {
+		c.mu.Lock()
+		subscription.state = StateConnected
+		c.mu.Unlock()
+		c.callOnReconnect(options)
+		c.callOnGap(options, gap)
+	})
 
 	// Store subscription
 	c.subscriptions[subscriptionID] = subscription
@@ -166,6 +180,13 @@ func (c *WSClient) subscribe(subscriptionID, streamPath string, options interfac
 
 // handleMessage processes incoming WebSocket messages based on event type or structure
 func (c *WSClient) handleMessage(subscription *Subscription, data []byte) {
+	// Event streams put the event type first, which avoids a generic parse of
+	// every message on the hot path
+	if eventType, ok := peekEventType(data); ok {
+		c.handleEvent(subscription, eventType, data)
+		return
+	}
+
 	// Parse as a generic map to handle any JSON structure
 	var rawData map[string]interface{}
 	if err := json.Unmarshal(data, &rawData); err != nil {
@@ -183,18 +204,7 @@ func (c *WSClient) handleMessage(subscription *Subscription, data []byte) {
 			return
 		}
 
-		switch eventType {
-		case "kline":
-			c.handleKlineMessage(subscription, data)
-		case "aggTrade":
-			c.handleAggTradeMessage(subscription, data)
-		case "trade":
-			c.handleTradeMessage(subscription, data)
-		case "depthUpdate":
-			c.handleDepthUpdateMessage(subscription, data)
-		default:
-			log.Printf("[WSClient] Unknown event type: %s", eventType)
-		}
+		c.handleEvent(subscription, eventType, data)
 		return
 	}
 
@@ -208,6 +218,22 @@ func (c *WSClient) handleMessage(subscription *Subscription, data []byte) {
 	log.Printf("[WSClient] Unknown message format: no event type field and no lastUpdateId field")
 }
 
+// handleEvent dispatches an event-based stream message by its event type
+func (c *WSClient) handleEvent(subscription *Subscription, eventType string, data []byte) {
+	switch eventType {
+	case "kline":
+		c.handleKlineMessage(subscription, data)
+	case "aggTrade":
+		c.handleAggTradeMessage(subscription, data)
+	case "trade":
+		c.handleTradeMessage(subscription, data)
+	case "depthUpdate":
+		c.handleDepthUpdateMessage(subscription, data)
+	default:
+		log.Printf("[WSClient] Unknown event type: %s", eventType)
+	}
+}
+
 // handleKlineMessage processes incoming kline WebSocket messages
 func (c *WSClient) handleKlineMessage(subscription *Subscription, data []byte) {
 	var event WSKlineEvent
@@ -226,10 +252,14 @@ func (c *WSClient) handleKlineMessage(subscription *Subscription, data []byte) {
 // handleAggTradeMessage processes incoming aggregate trade WebSocket messages
 func (c *WSClient) handleAggTradeMessage(subscription *Subscription, data []byte) {
 	var event WSAggTradeEvent
-	if err := json.Unmarshal(data, &event); err != nil {
-		log.Printf("[WSClient] Failed to unmarshal aggregate trade data: %v", err)
-		c.callOnError(subscription.options, fmt.Errorf("failed to unmarshal aggregate trade data: %w", err))
-		return
+	if err := decodeAggTradeEvent(data, &event); err != nil {
+		// Fall back to encoding/json for layouts the fast decoder does not handle
+		event = WSAggTradeEvent{}
+		if err := json.Unmarshal(data, &event); err != nil {
+			log.Printf("[WSClient] Failed to unmarshal aggregate trade data: %v", err)
+			c.callOnError(subscription.options, fmt.Errorf("failed to unmarshal aggregate trade data: %w", err))
+			return
+		}
 	}
 
 	// Call the aggregate trade callback
@@ -241,10 +271,14 @@ func (c *WSClient) handleAggTradeMessage(subscription *Subscription, data []byte
 // handleTradeMessage processes incoming raw trade WebSocket messages
 func (c *WSClient) handleTradeMessage(subscription *Subscription, data []byte) {
 	var event WSTradeEvent
-	if err := json.Unmarshal(data, &event); err != nil {
-		log.Printf("[WSClient] Failed to unmarshal trade data: %v", err)
-		c.callOnError(subscription.options, fmt.Errorf("failed to unmarshal trade data: %w", err))
-		return
+	if err := decodeTradeEvent(data, &event); err != nil {
+		// Fall back to encoding/json for layouts the fast decoder does not handle
+		event = WSTradeEvent{}
+		if err := json.Unmarshal(data, &event); err != nil {
+			log.Printf("[WSClient] Failed to unmarshal trade data: %v", err)
+			c.callOnError(subscription.options, fmt.Errorf("failed to unmarshal trade data: %w", err))
+			return
+		}
 	}
 
 	// Call the trade callback
@@ -309,6 +343,75 @@ func (c *WSClient) callOnConnect(options interface{}) {
 	}
 }
 
+// staleOptions returns the staleness watchdog settings of any subscription type
+func (c *WSClient) staleOptions(options interface{}) (time.Duration, func(time.Duration)) {
+	switch opts := options.(type) {
+	case KlineSubscriptionOptions:
+		return opts.StaleTimeout, opts.OnStale
+	case AggTradeSubscriptionOptions:
+		return opts.StaleTimeout, opts.OnStale
+	case TradeSubscriptionOptions:
+		return opts.StaleTimeout, opts.OnStale
+	case DepthSubscriptionOptions:
+		return opts.StaleTimeout, opts.OnStale
+	case DepthUpdateSubscriptionOptions:
+		return opts.StaleTimeout, opts.OnStale
+	}
+	return 0, nil
+}
+
+// callOnReconnect calls the OnReconnect callback for any subscription type
+func (c *WSClient) callOnReconnect(options interface{}) {
+	switch opts := options.(type) {
+	case KlineSubscriptionOptions:
+		if opts.OnReconnect != nil {
+			opts.OnReconnect()
+		}
+	case AggTradeSubscriptionOptions:
+		if opts.OnReconnect != nil {
+			opts.OnReconnect()
+		}
+	case TradeSubscriptionOptions:
+		if opts.OnReconnect != nil {
+			opts.OnReconnect()
+		}
+	case DepthSubscriptionOptions:
+		if opts.OnReconnect != nil {
+			opts.OnReconnect()
+		}
+	case DepthUpdateSubscriptionOptions:
+		if opts.OnReconnect != nil {
+			opts.OnReconnect()
+		}
+	}
+}
+
+// callOnGap calls the OnGap callback for any subscription type
+func (c *WSClient) callOnGap(options interface{}, gap Gap) {
+	switch opts := options.(type) {
+	case KlineSubscriptionOptions:
+		if opts.OnGap != nil {
+			opts.OnGap(gap)
+		}
+	case AggTradeSubscriptionOptions:
+		if opts.OnGap != nil {
+			opts.OnGap(gap)
+		}
+	case TradeSubscriptionOptions:
+		if opts.OnGap != nil {
+			opts.OnGap(gap)
+		}
+	case DepthSubscriptionOptions:
+		if opts.OnGap != nil {
+			opts.OnGap(gap)
+		}
+	case DepthUpdateSubscriptionOptions:
+		if opts.OnGap != nil {
+			opts.OnGap(gap)
+		}
+	}
+}
+
 // callOnError calls the OnError callback for any subscription type
 func (c *WSClient) callOnError(options interface{}, err error) {
 	switch opts := options.(type) {
@@ -394,7 +497,7 @@ func (c *WSClient) unsubscribe(subscriptionID string) {
 func (c *WSClient) Close() {
 	c.mu.Lock()
 	subscriptions := make([]*Subscription, 0, len(c.subscriptions))
-	for _, sub := range subscriptions {
+	for _, sub := range c.subscriptions {
 		subscriptions = append(subscriptions, sub)
 	}
 	c.subscriptions = make(map[string]*Subscription)
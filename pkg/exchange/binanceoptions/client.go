@@ -0,0 +1,77 @@
+package binanceoptions
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+)
+
+// Client is the Binance Options API client.
+type Client struct {
+	cfg *Config
+}
+
+// NewClient creates a new Binance Options API client.
+func NewClient(cfg *Config) *Client {
+	return &Client{cfg: cfg}
+}
+
+// GetServerTime tests connectivity to the Rest API and gets the current server time.
+func (c *Client) GetServerTime(ctx context.Context) (Response[GetServerTimeResponse], error) {
+	return get[GetServerTimeResponse](ctx, c.cfg, PathGetServerTime, nil)
+}
+
+// GetExchangeInfo gets the current trading rules and the listed option contracts.
+func (c *Client) GetExchangeInfo(ctx context.Context) (Response[ExchangeInfo], error) {
+	return get[ExchangeInfo](ctx, c.cfg, PathGetExchangeInfo, nil)
+}
+
+// GetMark gets the mark price, implied volatilities and greeks of options.
+func (c *Client) GetMark(ctx context.Context, req GetMarkRequest) (Response[[]Mark], error) {
+	params := map[string]string{}
+	if req.Symbol != "" {
+		params["symbol"] = req.Symbol
+	}
+	return get[[]Mark](ctx, c.cfg, PathGetMark, params)
+}
+
+// GetTicker gets the 24hr ticker statistics of options.
+func (c *Client) GetTicker(ctx context.Context, req GetTickerRequest) (Response[[]Ticker], error) {
+	params := map[string]string{}
+	if req.Symbol != "" {
+		params["symbol"] = req.Symbol
+	}
+	return get[[]Ticker](ctx, c.cfg, PathGetTicker, params)
+}
+
+// GetDepth queries the order book of an option.
+func (c *Client) GetDepth(ctx context.Context, req GetDepthRequest) (Response[GetDepthResponse], error) {
+	if req.Symbol == "" {
+		return Response[GetDepthResponse]{}, fmt.Errorf("symbol is required")
+	}
+	params := map[string]string{
+		"symbol": req.Symbol,
+	}
+	if req.Limit > 0 {
+		params["limit"] = fmt.Sprintf("%d", req.Limit)
+	}
+	return get[GetDepthResponse](ctx, c.cfg, PathGetDepth, params)
+}
+
+// get performs a public GET request and decodes its response into T, the
+// body of other statuses than 200 being returned as the message
+func get[T any](ctx context.Context, cfg *Config, endpoint string, params map[string]string) (Response[T], error) {
+	body, status, err := doUnsignedGet(ctx, cfg, endpoint, params)
+	if err != nil {
+		return Response[T]{}, err
+	}
+	if status != http.StatusOK {
+		return Response[T]{Code: status, Message: string(body)}, fmt.Errorf("http error: %d", status)
+	}
+	var data T
+	if err := json.Unmarshal(body, &data); err != nil {
+		return Response[T]{}, err
+	}
+	return Response[T]{Code: 0, Message: "success", Data: &data}, nil
+}
@@ -0,0 +1,67 @@
+package binanceoptions
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestMarketDataEndpoints(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case PathGetExchangeInfo:
+			w.Write([]byte(`{"timezone":"UTC","serverTime":1743148800000,"optionContracts":[{"baseAsset":"BTC","quoteAsset":"USDT","underlying":"BTCUSDT","settleAsset":"USDT"}],"optionAssets":[{"name":"USDT"}],"optionSymbols":[{"expiryDate":1743148800000,"filters":[{"filterType":"PRICE_FILTER","minPrice":"5","maxPrice":"8000","tickSize":"5"},{"filterType":"LOT_SIZE","minQty":"0.01","maxQty":"100","stepSize":"0.01"}],"symbol":"BTC-250328-90000-C","side":"CALL","strikePrice":"90000.00000000","underlying":"BTCUSDT","unit":1,"makerFeeRate":"0.00020000","takerFeeRate":"0.00020000","minQty":"0.01","maxQty":"100","initialMargin":"0.15000000","maintenanceMargin":"0.07500000","minInitialMargin":"0.10000000","minMaintenanceMargin":"0.05000000","priceScale":0,"quantityScale":2,"quoteAsset":"USDT"}],"rateLimits":[{"rateLimitType":"REQUEST_WEIGHT","interval":"MINUTE","intervalNum":1,"limit":2400}]}`))
+		case PathGetMark:
+			if r.URL.Query().Get("symbol") != "BTC-250328-90000-C" {
+				w.WriteHeader(http.StatusBadRequest)
+				w.Write([]byte(`{"code":-1121,"msg":"Invalid symbol."}`))
+				return
+			}
+			w.Write([]byte(`[{"symbol":"BTC-250328-90000-C","markPrice":"1343.2883","bidIV":"0.52","askIV":"0.56","markIV":"0.54","delta":"0.55937056","theta":"-88.82509871","gamma":"0.00010969","vega":"78.58874732","highPriceLimit":"1618.241","lowPriceLimit":"1068.3356","riskFreeInterest":"0.1"}]`))
+		case PathGetTicker:
+			w.Write([]byte(`[{"symbol":"BTC-250328-90000-C","priceChange":"-16.2038","priceChangePercent":"-0.0162","lastPrice":"1000","lastQty":"1000","open":"1016.2038","high":"1016.2038","low":"0","volume":"5","amount":"1","bidPrice":"999.34","askPrice":"1000.23","openTime":1592317127349,"closeTime":1592380593516,"firstTradeId":1,"tradeCount":5,"strikePrice":"90000","exercisePrice":"84300.3356"}]`))
+		case PathGetDepth:
+			if r.URL.Query().Get("limit") != "10" {
+				w.WriteHeader(http.StatusBadRequest)
+				return
+			}
+			w.Write([]byte(`{"T":1589436922972,"u":37461,"bids":[["1000","0.9"]],"asks":[["1100","0.1"]]}`))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer server.Close()
+	client := NewClient(&Config{BaseURL: server.URL})
+	ctx := context.Background()
+
+	info, err := client.GetExchangeInfo(ctx)
+	if err != nil || len(info.Data.OptionSymbols) != 1 || len(info.Data.OptionContracts) != 1 {
+		t.Fatalf("GetExchangeInfo = %+v, %v", info.Data, err)
+	}
+	if s := info.Data.OptionSymbols[0]; s.Side != OptionSideCall || s.StrikePrice != "90000.00000000" || s.ExpiryDate != 1743148800000 || s.Filters[1].StepSize != "0.01" {
+		t.Errorf("option symbol = %+v", s)
+	}
+
+	marks, err := client.GetMark(ctx, GetMarkRequest{Symbol: "BTC-250328-90000-C"})
+	if err != nil || len(*marks.Data) != 1 || (*marks.Data)[0].MarkIV != "0.54" || (*marks.Data)[0].Delta != "0.55937056" {
+		t.Errorf("GetMark = %+v, %v", marks.Data, err)
+	}
+	resp, err := client.GetMark(ctx, GetMarkRequest{Symbol: "BTC-250328-1-C"})
+	if err == nil || resp.Code != http.StatusBadRequest {
+		t.Errorf("GetMark of an unknown symbol = %+v, %v", resp, err)
+	}
+
+	tickers, err := client.GetTicker(ctx, GetTickerRequest{})
+	if err != nil || len(*tickers.Data) != 1 || (*tickers.Data)[0].BidPrice != "999.34" || (*tickers.Data)[0].TradeCount != 5 {
+		t.Errorf("GetTicker = %+v, %v", tickers.Data, err)
+	}
+
+	depth, err := client.GetDepth(ctx, GetDepthRequest{Symbol: "BTC-250328-90000-C", Limit: 10})
+	if err != nil || depth.Data.UpdateId != 37461 || depth.Data.Bids[0][0] != "1000" || depth.Data.Asks[0][1] != "0.1" {
+		t.Errorf("GetDepth = %+v, %v", depth.Data, err)
+	}
+	if _, err := client.GetDepth(ctx, GetDepthRequest{}); err == nil {
+		t.Error("GetDepth without symbol accepted")
+	}
+}
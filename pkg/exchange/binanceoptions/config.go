@@ -0,0 +1,21 @@
+package binanceoptions
+
+import (
+	"github.com/BullionBear/sequex/pkg/exchange/ratelimit"
+	"github.com/BullionBear/sequex/pkg/exchange/transport"
+)
+
+type Config struct {
+	// API credentials
+	APIKey    string
+	APISecret string
+
+	// API endpoints
+	BaseURL string
+
+	// Proxy, dialer and DNS settings, direct connection when empty
+	Network transport.Options
+
+	// IP weight budget shared with the other processes of the IP, none when nil
+	Limiter *ratelimit.Limiter
+}
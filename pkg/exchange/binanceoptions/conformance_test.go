@@ -0,0 +1,53 @@
+package binanceoptions
+
+import (
+	"testing"
+	"time"
+
+	"github.com/BullionBear/sequex/pkg/exchange/wstest"
+)
+
+// tradeAdapter runs the websocket conformance suite over the trade stream
+type tradeAdapter struct {
+	client *WSClient
+}
+
+func (a *tradeAdapter) Subscribe(symbol string, h wstest.Handlers) (func(), error) {
+	return a.client.SubscribeTrade(symbol, (&TradeSubscriptionOptions{}).
+		WithConnect(h.OnConnect).
+		WithReconnect(h.OnReconnect).
+		WithDisconnect(h.OnDisconnect).
+		WithError(h.OnError).
+		WithGap(func(gap Gap) { h.OnGap(gap.From, gap.To) }).
+		WithStale(h.StaleTimeout, func(time.Duration) { h.OnStale() }).
+		WithTrade(func(trade WSTrade) { h.OnData(trade) }))
+}
+
+func (a *tradeAdapter) Close() {
+	a.client.Close()
+}
+
+func TestWSClient_Conformance(t *testing.T) {
+	wstest.Run(t, wstest.Case{
+		NewClient: func(url string) wstest.Client {
+			return &tradeAdapter{client: NewWSClient(&WSConfig{
+				BaseWSUrl:      url,
+				ReconnectDelay: 100 * time.Millisecond,
+				PingInterval:   time.Minute,
+				MaxReconnects:  -1,
+			})}
+		},
+		Symbol:  "BTC-250328-90000-C",
+		Path:    "/ws/BTC-250328-90000-C@trade",
+		Message: []byte(`{"e":"trade","E":1743062400123,"s":"BTC-250328-90000-C","t":"315","p":"1250","q":"0.5","b":4611781675939004417,"a":4611781675939004418,"T":1743062400120,"S":"-1"}`),
+		Check: func(t *testing.T, data any) {
+			trade, ok := data.(WSTrade)
+			if !ok {
+				t.Fatalf("unexpected event %T", data)
+			}
+			if trade.Symbol != "BTC-250328-90000-C" || trade.TradeId != "315" || trade.Price != "1250" || trade.Direction != TradeDirectionSell {
+				t.Errorf("unexpected trade %+v", trade)
+			}
+		},
+	})
+}
@@ -0,0 +1,22 @@
+package binanceoptions
+
+// Mainnet REST API base URL
+const MainnetBaseUrl = "https://eapi.binance.com"
+
+// Mainnet WebSocket base URL
+const MainnetWSBaseUrl = "wss://nbstream.binance.com/eoptions"
+
+// IP request weight, counted per minute across every process of the IP
+const (
+	IPWeightLimit    = 2400
+	HeaderUsedWeight = "X-MBX-USED-WEIGHT-1M"
+)
+
+// Paths
+const (
+	PathGetServerTime   = "/eapi/v1/time"
+	PathGetExchangeInfo = "/eapi/v1/exchangeInfo"
+	PathGetMark         = "/eapi/v1/mark"
+	PathGetTicker       = "/eapi/v1/ticker"
+	PathGetDepth        = "/eapi/v1/depth"
+)
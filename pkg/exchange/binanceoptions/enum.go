@@ -0,0 +1,13 @@
+package binanceoptions
+
+// OptionSide represents the kind of an option contract.
+const (
+	OptionSideCall = "CALL"
+	OptionSidePut  = "PUT"
+)
+
+// TradeDirection represents the taker side of a stream trade.
+const (
+	TradeDirectionBuy  = "1"
+	TradeDirectionSell = "-1"
+)
@@ -0,0 +1,41 @@
+package binanceoptions
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/BullionBear/sequex/pkg/exchange/wstest"
+)
+
+// TestWSClient_Golden decodes recorded payloads of every stream through the
+// dispatch of the client. A mark price message holds the prices of every
+// option of an underlying, decoded into a slice.
+func TestWSClient_Golden(t *testing.T) {
+	wstest.Golden(t, func(t *testing.T, name string, payload []byte) any {
+		var events []any
+		var marks []WSMarkPrice
+		capture := func(event any) { events = append(events, event) }
+		onError := func(err error) { t.Errorf("decode: %v", err) }
+		c := &WSClient{subscriptions: make(map[string]*WSSubscription)}
+		// Each message only reaches the callbacks of its stream
+		for _, options := range []any{
+			(&TradeSubscriptionOptions{}).WithError(onError).WithTrade(func(trade WSTrade) { capture(trade) }),
+			(&TickerSubscriptionOptions{}).WithError(onError).WithTicker(func(ticker WSTicker) { capture(ticker) }),
+			(&MarkPriceSubscriptionOptions{}).WithError(onError).WithMarkPrice(func(mark WSMarkPrice) { marks = append(marks, mark) }),
+			(&DepthSubscriptionOptions{}).WithError(onError).WithDepth(func(depth WSDepth) { capture(depth) }),
+		} {
+			c.subscriptions["golden"] = &WSSubscription{id: "golden", options: options}
+			c.handleMessage("golden", payload)
+		}
+		if len(marks) > 0 {
+			capture(marks)
+		}
+		if len(events) != 1 {
+			t.Fatalf("decoded %d events", len(events))
+		}
+		if reflect.ValueOf(events[0]).IsZero() {
+			t.Fatal("decoded an empty event")
+		}
+		return events[0]
+	})
+}
@@ -0,0 +1,139 @@
+package binanceoptions
+
+// Response is the unified response wrapper for all endpoints.
+type Response[T any] struct {
+	Code    int    `json:"code"`
+	Message string `json:"msg"`
+	Data    *T     `json:"data,omitempty"`
+}
+
+// GetServerTimeResponse represents the server time response.
+type GetServerTimeResponse struct {
+	ServerTime int64 `json:"serverTime"`
+}
+
+// ExchangeInfo represents the current exchange trading rules and option contracts.
+type ExchangeInfo struct {
+	Timezone        string           `json:"timezone"`
+	ServerTime      int64            `json:"serverTime"`
+	OptionContracts []OptionContract `json:"optionContracts"`
+	OptionAssets    []OptionAsset    `json:"optionAssets"`
+	OptionSymbols   []OptionSymbol   `json:"optionSymbols"`
+	RateLimits      []RateLimit      `json:"rateLimits"`
+}
+
+// OptionContract represents an underlying options are listed on.
+type OptionContract struct {
+	BaseAsset   string `json:"baseAsset"`   // e.g. BTC
+	QuoteAsset  string `json:"quoteAsset"`  // e.g. USDT
+	Underlying  string `json:"underlying"`  // e.g. BTCUSDT
+	SettleAsset string `json:"settleAsset"` // e.g. USDT
+}
+
+// OptionAsset represents an asset options are settled in.
+type OptionAsset struct {
+	Name string `json:"name"`
+}
+
+// OptionSymbol represents a listed option contract.
+type OptionSymbol struct {
+	Symbol               string         `json:"symbol"`               // e.g. BTC-250328-90000-C
+	Side                 string         `json:"side"`                 // CALL or PUT
+	StrikePrice          string         `json:"strikePrice"`          // Strike price
+	Underlying           string         `json:"underlying"`           // e.g. BTCUSDT
+	Unit                 int64          `json:"unit"`                 // Quantity of the underlying per contract
+	ExpiryDate           int64          `json:"expiryDate"`           // Exercise time in ms
+	MakerFeeRate         string         `json:"makerFeeRate"`         // Maker commission rate
+	TakerFeeRate         string         `json:"takerFeeRate"`         // Taker commission rate
+	MinQty               string         `json:"minQty"`               // Minimum order quantity
+	MaxQty               string         `json:"maxQty"`               // Maximum order quantity
+	InitialMargin        string         `json:"initialMargin"`        // Initial margin ratio
+	MaintenanceMargin    string         `json:"maintenanceMargin"`    // Maintenance margin ratio
+	MinInitialMargin     string         `json:"minInitialMargin"`     // Minimum initial margin ratio
+	MinMaintenanceMargin string         `json:"minMaintenanceMargin"` // Minimum maintenance margin ratio
+	PriceScale           int            `json:"priceScale"`           // Price decimals
+	QuantityScale        int            `json:"quantityScale"`        // Quantity decimals
+	QuoteAsset           string         `json:"quoteAsset"`           // Quote asset
+	Filters              []SymbolFilter `json:"filters"`
+}
+
+// SymbolFilter represents a trading rule of an option contract.
+type SymbolFilter struct {
+	FilterType string `json:"filterType"`
+	MinPrice   string `json:"minPrice,omitempty"`
+	MaxPrice   string `json:"maxPrice,omitempty"`
+	TickSize   string `json:"tickSize,omitempty"`
+	MinQty     string `json:"minQty,omitempty"`
+	MaxQty     string `json:"maxQty,omitempty"`
+	StepSize   string `json:"stepSize,omitempty"`
+}
+
+// RateLimit represents a rate limit of the API.
+type RateLimit struct {
+	RateLimitType string `json:"rateLimitType"`
+	Interval      string `json:"interval"`
+	IntervalNum   int    `json:"intervalNum"`
+	Limit         int    `json:"limit"`
+}
+
+// GetMarkRequest defines the parameters for getting option mark prices.
+type GetMarkRequest struct {
+	Symbol string // optional, if not provided returns all symbols
+}
+
+// Mark represents the mark price, implied volatilities and greeks of an option.
+type Mark struct {
+	Symbol           string `json:"symbol"`           // Symbol
+	MarkPrice        string `json:"markPrice"`        // Mark price
+	BidIV            string `json:"bidIV"`            // Implied volatility of the best bid
+	AskIV            string `json:"askIV"`            // Implied volatility of the best ask
+	MarkIV           string `json:"markIV"`           // Implied volatility of the mark price
+	Delta            string `json:"delta"`            // Delta
+	Theta            string `json:"theta"`            // Theta
+	Gamma            string `json:"gamma"`            // Gamma
+	Vega             string `json:"vega"`             // Vega
+	HighPriceLimit   string `json:"highPriceLimit"`   // Current highest buy price
+	LowPriceLimit    string `json:"lowPriceLimit"`    // Current lowest sell price
+	RiskFreeInterest string `json:"riskFreeInterest"` // Risk free rate
+}
+
+// GetTickerRequest defines the parameters for getting 24hr ticker statistics.
+type GetTickerRequest struct {
+	Symbol string // optional, if not provided returns all symbols
+}
+
+// Ticker represents the 24hr ticker statistics of an option.
+type Ticker struct {
+	Symbol             string `json:"symbol"`             // Symbol
+	PriceChange        string `json:"priceChange"`        // 24hr price change
+	PriceChangePercent string `json:"priceChangePercent"` // 24hr price change percent
+	LastPrice          string `json:"lastPrice"`          // Last trade price
+	LastQty            string `json:"lastQty"`            // Last trade quantity
+	Open               string `json:"open"`               // 24hr open price
+	High               string `json:"high"`               // 24hr high price
+	Low                string `json:"low"`                // 24hr low price
+	Volume             string `json:"volume"`             // Trading volume in contracts
+	Amount             string `json:"amount"`             // Trading amount in quote asset
+	BidPrice           string `json:"bidPrice"`           // Best bid price
+	AskPrice           string `json:"askPrice"`           // Best ask price
+	OpenTime           int64  `json:"openTime"`           // Time the first trade occurred within the last 24 hours
+	CloseTime          int64  `json:"closeTime"`          // Time the last trade occurred within the last 24 hours
+	FirstTradeId       int64  `json:"firstTradeId"`       // First trade ID
+	TradeCount         int64  `json:"tradeCount"`         // Number of trades
+	StrikePrice        string `json:"strikePrice"`        // Strike price
+	ExercisePrice      string `json:"exercisePrice"`      // Underlying index price, the settlement price at expiry
+}
+
+// GetDepthRequest defines the parameters for getting order book depth.
+type GetDepthRequest struct {
+	Symbol string // required
+	Limit  int    // optional, default 100; Valid limits:[10, 20, 50, 100, 500, 1000]
+}
+
+// GetDepthResponse represents the order book depth response.
+type GetDepthResponse struct {
+	T        int64      `json:"T"` // Transaction time
+	UpdateId int64      `json:"u"` // Update ID
+	Bids     [][]string `json:"bids"`
+	Asks     [][]string `json:"asks"`
+}
@@ -0,0 +1,55 @@
+package binanceoptions
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+)
+
+// doUnsignedGet performs unsigned GET request (public endpoints)
+func doUnsignedGet(ctx context.Context, cfg *Config, endpoint string, params map[string]string) ([]byte, int, error) {
+	baseURL := strings.TrimRight(cfg.BaseURL, "/")
+	fullURL := baseURL + endpoint
+	if len(params) > 0 {
+		q := url.Values{}
+		for k, v := range params {
+			q.Set(k, v)
+		}
+		fullURL += "?" + q.Encode()
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, fullURL, nil)
+	if err != nil {
+		return nil, 0, err
+	}
+	return send(cfg, req)
+}
+
+// send performs a request within the weight budget of the configuration and
+// reports the weight the exchange counted back to it
+func send(cfg *Config, req *http.Request) ([]byte, int, error) {
+	ctx := req.Context()
+	if cfg.Limiter != nil {
+		if err := cfg.Limiter.Wait(ctx, 1); err != nil {
+			return nil, 0, err
+		}
+	}
+	client, err := cfg.Network.SharedHTTPClient()
+	if err != nil {
+		return nil, 0, err
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer resp.Body.Close()
+	if cfg.Limiter != nil {
+		if used, err := strconv.Atoi(resp.Header.Get(HeaderUsedWeight)); err == nil {
+			cfg.Limiter.Observe(ctx, used)
+		}
+	}
+	body, err := io.ReadAll(resp.Body)
+	return body, resp.StatusCode, err
+}
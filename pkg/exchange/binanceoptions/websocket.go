@@ -0,0 +1,512 @@
+package binanceoptions
+
+import (
+	"context"
+	"errors"
+	"log"
+	"math/rand"
+	"sync"
+	"time"
+
+	"github.com/BullionBear/sequex/pkg/exchange/transport"
+	"github.com/gorilla/websocket"
+)
+
+// WSConnection interface for all WebSocket connection types
+type WSConnection interface {
+	Connect(ctx context.Context, streamName string) error
+	Disconnect() error
+	IsConnected() bool
+}
+
+// Ping interval constants for Binance options WebSocket
+const (
+	pingInterval      = 3 * time.Minute  // Binance sends ping every 3 minutes
+	pongTimeout       = 10 * time.Minute // Disconnect if no pong received within 10 minutes
+	reconnectDelay    = 5 * time.Second  // Delay before the first reconnection attempt
+	maxReconnectDelay = 1 * time.Minute  // Cap of the reconnection backoff
+	reconnectJitter   = 0.2              // Fraction of each reconnection delay randomized
+	connectionTimeout = 24 * time.Hour   // Connection valid for 24 hours
+)
+
+// ErrMaxReconnects is reported when MaxReconnects attempts in a row failed
+// and the stream was closed
+var ErrMaxReconnects = errors.New("max reconnects exceeded")
+
+// WSConfig holds WebSocket client configuration
+type WSConfig struct {
+	BaseWSUrl         string
+	ReconnectDelay    time.Duration // Delay before the first reconnection attempt, doubled after each failure
+	MaxReconnectDelay time.Duration // Cap of the reconnection backoff, 1 minute when 0
+	ReconnectJitter   float64       // Fraction of each delay randomized, 0.2 when 0, negative disables
+	PingInterval      time.Duration
+	MaxReconnects     int               // Failed attempts in a row before giving up, -1 means no max reconnects
+	Network           transport.Options // Proxy, dialer and DNS settings, direct connection when empty
+}
+
+// Gap is the window a stream delivered no data because of a reconnection
+type Gap struct {
+	From time.Time // Receipt of the last message before the drop
+	To   time.Time // Reconnection
+}
+
+// Duration returns the length of the gap
+func (g Gap) Duration() time.Duration {
+	return g.To.Sub(g.From)
+}
+
+// Subscription provides a builder pattern for configuring WebSocket stream callbacks
+type Subscription struct {
+	onConnect   func()
+	onReconnect func()
+	onError     func(error)
+	onMessage   func([]byte)
+	onClose     func()
+	onGap       func(Gap)
+
+	staleTimeout time.Duration
+	onStale      func(silence time.Duration)
+}
+
+// WithConnect sets the OnConnect callback
+func (s *Subscription) WithConnect(onConnect func()) *Subscription {
+	s.onConnect = onConnect
+	return s
+}
+
+// WithReconnect sets the OnReconnect callback
+func (s *Subscription) WithReconnect(onReconnect func()) *Subscription {
+	s.onReconnect = onReconnect
+	return s
+}
+
+// WithError sets the OnError callback
+func (s *Subscription) WithError(onError func(error)) *Subscription {
+	s.onError = onError
+	return s
+}
+
+// WithMessage sets the OnMessage callback
+func (s *Subscription) WithMessage(onMessage func([]byte)) *Subscription {
+	s.onMessage = onMessage
+	return s
+}
+
+// WithClose sets the OnClose callback
+func (s *Subscription) WithClose(onClose func()) *Subscription {
+	s.onClose = onClose
+	return s
+}
+
+// WithGap sets the OnGap callback, called after a reconnection with the
+// window data may have been missed
+func (s *Subscription) WithGap(onGap func(Gap)) *Subscription {
+	s.onGap = onGap
+	return s
+}
+
+// WithStale enables the staleness watchdog: once the stream is silent for
+// timeout, OnStale is called and the connection probed with a ping, and if it
+// stays silent for half a timeout more, the connection is reconnected. A zero
+// timeout disables the watchdog.
+func (s *Subscription) WithStale(timeout time.Duration, onStale func(silence time.Duration)) *Subscription {
+	s.staleTimeout = timeout
+	s.onStale = onStale
+	return s
+}
+
+// BinanceOptionsWSConn manages WebSocket connection to Binance options streams
+type BinanceOptionsWSConn struct {
+	conn         *websocket.Conn
+	mu           sync.RWMutex
+	done         chan struct{}
+	reconnect    chan struct{}
+	logger       *log.Logger
+	config       *WSConfig
+	subscription *Subscription
+
+	// Connection state
+	connected       bool
+	streamName      string
+	ctx             context.Context
+	cancel          context.CancelFunc
+	shouldReconnect bool
+	reconnectCount  int       // Failed attempts of the current reconnection
+	lastMessage     time.Time // Receipt of the last message, where a gap starts
+	loopsOnce       sync.Once // The ping, reconnect and watchdog loops outlive connections
+	closeOnce       sync.Once
+}
+
+// NewBinanceOptionsWSConn creates a new WebSocket client instance
+func NewBinanceOptionsWSConn(config *WSConfig, subscription *Subscription) *BinanceOptionsWSConn {
+	if config == nil {
+		config = &WSConfig{
+			BaseWSUrl:      MainnetWSBaseUrl,
+			ReconnectDelay: reconnectDelay,
+			PingInterval:   pingInterval,
+			MaxReconnects:  -1, // No max reconnects by default
+		}
+	}
+
+	// Set defaults if not provided
+	if config.BaseWSUrl == "" {
+		config.BaseWSUrl = MainnetWSBaseUrl
+	}
+	if config.ReconnectDelay == 0 {
+		config.ReconnectDelay = reconnectDelay
+	}
+	if config.PingInterval == 0 {
+		config.PingInterval = pingInterval
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+
+	return &BinanceOptionsWSConn{
+		config:          config,
+		subscription:    subscription,
+		ctx:             ctx,
+		cancel:          cancel,
+		done:            make(chan struct{}),
+		reconnect:       make(chan struct{}, 1), // Buffered so a drop before the loop runs is not lost
+		logger:          log.Default(),
+		shouldReconnect: true,
+	}
+}
+
+// Connect establishes WebSocket connection to Binance
+func (c *BinanceOptionsWSConn) Connect(ctx context.Context, streamName string) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.connected {
+		return nil // Already connected
+	}
+
+	c.streamName = streamName
+	if err := c.dial(ctx); err != nil {
+		if c.subscription != nil && c.subscription.onError != nil {
+			c.subscription.onError(err)
+		}
+		return err
+	}
+
+	c.loopsOnce.Do(func() {
+		go c.pingLoop()
+		go c.reconnectLoop()
+		if c.subscription != nil && c.subscription.staleTimeout > 0 {
+			go c.watchdogLoop(c.subscription.staleTimeout)
+		}
+	})
+
+	// Call OnConnect callback
+	if c.subscription != nil && c.subscription.onConnect != nil {
+		c.subscription.onConnect()
+	}
+	return nil
+}
+
+// dial opens a connection to the stream and starts reading it. The caller
+// holds the lock.
+func (c *BinanceOptionsWSConn) dial(ctx context.Context) error {
+	// Construct WebSocket URL for raw stream
+	url := c.config.BaseWSUrl + "/ws/" + c.streamName
+
+	dialer, err := c.config.Network.WSDialer()
+	if err != nil {
+		return err
+	}
+	conn, _, err := dialer.DialContext(ctx, url, nil)
+	if err != nil {
+		return err
+	}
+
+	c.conn = conn
+	c.connected = true
+	c.lastMessage = time.Now()
+	go c.readLoop(conn)
+
+	c.logger.Printf("[BinanceOptionsWS] Connected to %s", url)
+	return nil
+}
+
+// Disconnect closes the WebSocket connection gracefully
+func (c *BinanceOptionsWSConn) Disconnect() error {
+	c.mu.Lock()
+	c.shouldReconnect = false
+	conn := c.conn
+	c.conn = nil
+	c.connected = false
+	c.mu.Unlock()
+
+	// Cancel context first to stop all goroutines
+	c.cancel()
+
+	if conn != nil {
+		// Send close frame before closing
+		err := conn.WriteMessage(websocket.CloseMessage,
+			websocket.FormatCloseMessage(websocket.CloseNormalClosure, ""))
+		if err != nil {
+			c.logger.Printf("[BinanceOptionsWS] Error sending close message: %v", err)
+		}
+
+		conn.Close()
+	}
+
+	c.close()
+	c.logger.Printf("[BinanceOptionsWS] Disconnected")
+	return nil
+}
+
+// close ends the connection for good, calling OnClose once
+func (c *BinanceOptionsWSConn) close() {
+	c.closeOnce.Do(func() {
+		close(c.done)
+		if c.subscription != nil && c.subscription.onClose != nil {
+			c.subscription.onClose()
+		}
+	})
+}
+
+// IsConnected returns the current connection status
+func (c *BinanceOptionsWSConn) IsConnected() bool {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.connected
+}
+
+// readLoop continuously reads messages from one connection until it fails
+func (c *BinanceOptionsWSConn) readLoop(conn *websocket.Conn) {
+	for {
+		_, message, err := c.config.Network.ReadMessage(conn)
+		if err != nil {
+			// Check if this is a graceful shutdown
+			if c.ctx.Err() != nil {
+				return
+			}
+
+			c.logger.Printf("[BinanceOptionsWS] Read error: %v", err)
+			if c.subscription != nil && c.subscription.onError != nil {
+				c.subscription.onError(err)
+			}
+
+			c.handleDisconnect(conn)
+			return
+		}
+
+		c.mu.Lock()
+		c.lastMessage = time.Now()
+		c.mu.Unlock()
+
+		// Call the message handler if set
+		if c.subscription != nil && c.subscription.onMessage != nil {
+			c.subscription.onMessage(message)
+		}
+	}
+}
+
+// pingLoop handles ping/pong frames according to Binance requirements
+func (c *BinanceOptionsWSConn) pingLoop() {
+	ticker := time.NewTicker(c.config.PingInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-c.ctx.Done():
+			return
+		case <-c.done:
+			return
+		case <-ticker.C:
+			c.mu.RLock()
+			conn := c.conn
+			connected := c.connected
+			c.mu.RUnlock()
+
+			if !connected || conn == nil {
+				continue
+			}
+
+			// Send pong frame (unsolicited pong frames are allowed)
+			// Use a write timeout to prevent blocking
+			err := conn.WriteMessage(websocket.PongMessage, nil)
+			if err != nil {
+				c.logger.Printf("[BinanceOptionsWS] Pong error: %v", err)
+				// Don't call error callback for pong errors during shutdown
+				if c.ctx.Err() == nil && c.subscription != nil && c.subscription.onError != nil {
+					c.subscription.onError(err)
+				}
+			}
+		}
+	}
+}
+
+// reconnectLoop redials the stream after every drop, backing off between
+// failed attempts, and reports the window without data once reconnected.
+// When MaxReconnects attempts fail in a row the connection is closed so
+// subscribers learn the stream ended instead of waiting on a dead one.
+func (c *BinanceOptionsWSConn) reconnectLoop() {
+	for {
+		select {
+		case <-c.ctx.Done():
+			return
+		case <-c.done:
+			return
+		case <-c.reconnect:
+		}
+
+		c.mu.Lock()
+		gapStart := c.lastMessage
+		c.reconnectCount = 0
+		c.mu.Unlock()
+
+		for {
+			c.mu.Lock()
+			attempt := c.reconnectCount
+			c.mu.Unlock()
+
+			// Check if we've exceeded max reconnects
+			if c.config.MaxReconnects > 0 && attempt >= c.config.MaxReconnects {
+				c.logger.Printf("[BinanceOptionsWS] Max reconnects (%d) exceeded", c.config.MaxReconnects)
+				c.mu.Lock()
+				c.shouldReconnect = false
+				c.mu.Unlock()
+				if c.subscription != nil && c.subscription.onError != nil {
+					c.subscription.onError(ErrMaxReconnects)
+				}
+				c.close()
+				return
+			}
+
+			delay := backoffDelay(c.config, attempt)
+			c.logger.Printf("[BinanceOptionsWS] Reconnecting in %v... (attempt %d)", delay, attempt+1)
+			select {
+			case <-c.ctx.Done():
+				return
+			case <-time.After(delay):
+			}
+
+			c.mu.Lock()
+			if !c.shouldReconnect {
+				c.mu.Unlock()
+				return
+			}
+			err := c.dial(c.ctx)
+			if err != nil {
+				c.reconnectCount++
+			}
+			c.mu.Unlock()
+
+			if err == nil {
+				break
+			}
+			c.logger.Printf("[BinanceOptionsWS] Reconnect failed: %v", err)
+			if c.ctx.Err() == nil && c.subscription != nil && c.subscription.onError != nil {
+				c.subscription.onError(err)
+			}
+		}
+
+		c.logger.Printf("[BinanceOptionsWS] Reconnected successfully")
+		if c.subscription != nil && c.subscription.onReconnect != nil {
+			c.subscription.onReconnect()
+		}
+		if c.subscription != nil && c.subscription.onGap != nil {
+			c.subscription.onGap(Gap{From: gapStart, To: time.Now()})
+		}
+	}
+}
+
+// watchdogLoop detects streams that went quiet while the TCP connection
+// stays open, which Binance occasionally does, and forces a reconnection
+func (c *BinanceOptionsWSConn) watchdogLoop(timeout time.Duration) {
+	ticker := time.NewTicker(timeout / 4)
+	defer ticker.Stop()
+
+	var probed time.Time // Last message when the current silence was probed
+	for {
+		select {
+		case <-c.ctx.Done():
+			return
+		case <-c.done:
+			return
+		case <-ticker.C:
+		}
+
+		c.mu.RLock()
+		conn := c.conn
+		last := c.lastMessage
+		c.mu.RUnlock()
+		if conn == nil {
+			continue // Reconnecting
+		}
+
+		silence := time.Since(last)
+		switch {
+		case silence < timeout:
+		case !probed.Equal(last):
+			probed = last
+			c.logger.Printf("[BinanceOptionsWS] No message for %v, probing", silence.Round(time.Millisecond))
+			if c.subscription.onStale != nil {
+				c.subscription.onStale(silence)
+			}
+			if err := conn.WriteControl(websocket.PingMessage, nil, time.Now().Add(timeout/2)); err != nil {
+				c.logger.Printf("[BinanceOptionsWS] Probe failed: %v", err)
+				conn.Close() // The read loop reconnects
+			}
+		case silence >= timeout+timeout/2:
+			c.logger.Printf("[BinanceOptionsWS] No message for %v, reconnecting", silence.Round(time.Millisecond))
+			conn.Close() // The read loop reconnects
+		}
+	}
+}
+
+// handleDisconnect handles the loss of a connection and triggers reconnection
+func (c *BinanceOptionsWSConn) handleDisconnect(conn *websocket.Conn) {
+	c.mu.Lock()
+	if c.conn != conn {
+		// Already replaced or closed
+		c.mu.Unlock()
+		return
+	}
+	c.conn.Close()
+	c.conn = nil
+	c.connected = false
+	shouldReconnect := c.shouldReconnect && c.ctx.Err() == nil
+	c.mu.Unlock()
+
+	if shouldReconnect {
+		// Trigger reconnection
+		select {
+		case c.reconnect <- struct{}{}:
+		default:
+		}
+	}
+}
+
+// backoffDelay returns the delay before a reconnection attempt: the
+// reconnect delay doubled on every failed attempt up to MaxReconnectDelay,
+// randomized by the jitter fraction so clients dropped together do not
+// reconnect together
+func backoffDelay(config *WSConfig, attempt int) time.Duration {
+	maxDelay := config.MaxReconnectDelay
+	if maxDelay <= 0 {
+		maxDelay = maxReconnectDelay
+	}
+	delay := config.ReconnectDelay
+	for i := 0; i < attempt && delay < maxDelay; i++ {
+		delay *= 2
+	}
+	if delay > maxDelay {
+		delay = maxDelay
+	}
+
+	jitter := config.ReconnectJitter
+	if jitter == 0 {
+		jitter = reconnectJitter
+	}
+	if jitter > 0 {
+		if jitter > 1 {
+			jitter = 1
+		}
+		delay = time.Duration(float64(delay) * (1 - jitter + 2*jitter*rand.Float64()))
+	}
+	return delay
+}
@@ -0,0 +1,352 @@
+package binanceoptions
+
+import "time"
+
+// WSTradeEvent represents the trade WebSocket event
+type WSTradeEvent struct {
+	EventType   string `json:"e"` // Event type
+	EventTime   int64  `json:"E"` // Event time
+	Symbol      string `json:"s"` // Option symbol
+	TradeId     string `json:"t"` // Trade ID
+	Price       string `json:"p"` // Price
+	Quantity    string `json:"q"` // Quantity
+	BuyOrderId  int64  `json:"b"` // Buy order ID
+	SellOrderId int64  `json:"a"` // Sell order ID
+	TradeTime   int64  `json:"T"` // Trade time
+	Direction   string `json:"S"` // Taker direction, 1 for buy and -1 for sell
+}
+
+// WSTrade represents trade data (alias for event for consistency)
+type WSTrade = WSTradeEvent
+
+// WSTickerEvent represents the 24hr ticker WebSocket event, which carries
+// the best prices, implied volatilities and greeks of an option
+type WSTickerEvent struct {
+	EventType          string `json:"e"`   // Event type
+	EventTime          int64  `json:"E"`   // Event time
+	TransactionTime    int64  `json:"T"`   // Transaction time
+	Symbol             string `json:"s"`   // Option symbol
+	OpenPrice          string `json:"o"`   // 24hr open price
+	HighPrice          string `json:"h"`   // 24hr high price
+	LowPrice           string `json:"l"`   // 24hr low price
+	LastPrice          string `json:"c"`   // Last price
+	Volume             string `json:"V"`   // Trading volume in contracts
+	Amount             string `json:"A"`   // Trading amount in quote asset
+	PriceChangePercent string `json:"P"`   // Price change percent
+	PriceChange        string `json:"p"`   // Price change
+	LastQuantity       string `json:"Q"`   // Volume of the last trade
+	FirstTradeId       string `json:"F"`   // First trade ID
+	LastTradeId        string `json:"L"`   // Last trade ID
+	Count              int64  `json:"n"`   // Number of trades
+	BestBuyPrice       string `json:"bo"`  // Best bid price
+	BestSellPrice      string `json:"ao"`  // Best ask price
+	BestBuyQuantity    string `json:"bq"`  // Best bid quantity
+	BestSellQuantity   string `json:"aq"`  // Best ask quantity
+	BuyIV              string `json:"b"`   // Implied volatility of the best bid
+	SellIV             string `json:"a"`   // Implied volatility of the best ask
+	Delta              string `json:"d"`   // Delta
+	Theta              string `json:"t"`   // Theta
+	Gamma              string `json:"g"`   // Gamma
+	Vega               string `json:"v"`   // Vega
+	MarkIV             string `json:"vo"`  // Implied volatility of the mark price
+	MarkPrice          string `json:"mp"`  // Mark price
+	HighPriceLimit     string `json:"hl"`  // Current highest buy price
+	LowPriceLimit      string `json:"ll"`  // Current lowest sell price
+	ExercisePrice      string `json:"eep"` // Estimated exercise price
+}
+
+// WSTicker represents 24hr ticker data (alias for event for consistency)
+type WSTicker = WSTickerEvent
+
+// WSMarkPriceEvent represents the mark price of one option in the mark price
+// WebSocket stream, which sends those of every option of an underlying in
+// one message
+type WSMarkPriceEvent struct {
+	EventType string `json:"e"`  // Event type
+	EventTime int64  `json:"E"`  // Event time
+	Symbol    string `json:"s"`  // Option symbol
+	MarkPrice string `json:"mp"` // Mark price
+}
+
+// WSMarkPrice represents mark price data (alias for event for consistency)
+type WSMarkPrice = WSMarkPriceEvent
+
+// WSDepthEvent represents the partial book depth WebSocket event
+type WSDepthEvent struct {
+	EventType        string     `json:"e"`  // Event type
+	EventTime        int64      `json:"E"`  // Event time
+	TransactionTime  int64      `json:"T"`  // Transaction time
+	Symbol           string     `json:"s"`  // Option symbol
+	UpdateId         int64      `json:"u"`  // Update ID
+	PreviousUpdateId int64      `json:"pu"` // Update ID of the previous event
+	Bids             [][]string `json:"b"`  // Bids to be updated [price, quantity]
+	Asks             [][]string `json:"a"`  // Asks to be updated [price, quantity]
+}
+
+// WSDepth represents depth data (alias for event for consistency)
+type WSDepth = WSDepthEvent
+
+// DepthUpdateSpeed represents the update speed for depth streams
+type DepthUpdateSpeed string
+
+const (
+	DepthUpdate100ms  DepthUpdateSpeed = "100ms"
+	DepthUpdate500ms  DepthUpdateSpeed = "500ms" // Default
+	DepthUpdate1000ms DepthUpdateSpeed = "1000ms"
+)
+
+// DepthLevel represents valid depth levels
+type DepthLevel int
+
+const (
+	DepthLevel10  DepthLevel = 10
+	DepthLevel20  DepthLevel = 20
+	DepthLevel50  DepthLevel = 50
+	DepthLevel100 DepthLevel = 100
+)
+
+// ConnectionState represents the current state of a WebSocket subscription
+type ConnectionState int
+
+const (
+	StateDisconnected ConnectionState = iota
+	StateConnecting
+	StateConnected
+	StateReconnecting
+)
+
+// TradeSubscriptionOptions defines the callback functions for trade subscription
+type TradeSubscriptionOptions struct {
+	onConnect    func()                      // Called when connection is established
+	onReconnect  func()                      // Called when connection is reestablished
+	onError      func(err error)             // Called when an error occurs
+	onTrade      func(trade WSTrade)         // Called when trade data is received
+	onDisconnect func()                      // Called when connection is disconnected
+	onGap        func(gap Gap)               // Called after a reconnection with the window data may have been missed
+	staleTimeout time.Duration               // Silence before the stream is probed and reconnected, disabled when 0
+	onStale      func(silence time.Duration) // Called when the stream went silent for staleTimeout
+}
+
+// WithConnect sets the OnConnect callback using chain method
+func (t *TradeSubscriptionOptions) WithConnect(onConnect func()) *TradeSubscriptionOptions {
+	t.onConnect = onConnect
+	return t
+}
+
+// WithReconnect sets the OnReconnect callback using chain method
+func (t *TradeSubscriptionOptions) WithReconnect(onReconnect func()) *TradeSubscriptionOptions {
+	t.onReconnect = onReconnect
+	return t
+}
+
+// WithError sets the OnError callback using chain method
+func (t *TradeSubscriptionOptions) WithError(onError func(error)) *TradeSubscriptionOptions {
+	t.onError = onError
+	return t
+}
+
+// WithTrade sets the OnTrade callback using chain method
+func (t *TradeSubscriptionOptions) WithTrade(onTrade func(WSTrade)) *TradeSubscriptionOptions {
+	t.onTrade = onTrade
+	return t
+}
+
+// WithDisconnect sets the OnDisconnect callback using chain method
+func (t *TradeSubscriptionOptions) WithDisconnect(onDisconnect func()) *TradeSubscriptionOptions {
+	t.onDisconnect = onDisconnect
+	return t
+}
+
+// WithGap sets the OnGap callback using chain method
+func (t *TradeSubscriptionOptions) WithGap(onGap func(Gap)) *TradeSubscriptionOptions {
+	t.onGap = onGap
+	return t
+}
+
+// WithStale enables the staleness watchdog using chain method: after timeout
+// without a message OnStale is called and the stream probed, then reconnected
+// if it stays silent
+func (t *TradeSubscriptionOptions) WithStale(timeout time.Duration, onStale func(silence time.Duration)) *TradeSubscriptionOptions {
+	t.staleTimeout = timeout
+	t.onStale = onStale
+	return t
+}
+
+// TickerSubscriptionOptions defines the callback functions for ticker subscription
+type TickerSubscriptionOptions struct {
+	onConnect    func()                      // Called when connection is established
+	onReconnect  func()                      // Called when connection is reestablished
+	onError      func(err error)             // Called when an error occurs
+	onTicker     func(ticker WSTicker)       // Called when ticker data is received
+	onDisconnect func()                      // Called when connection is disconnected
+	onGap        func(gap Gap)               // Called after a reconnection with the window data may have been missed
+	staleTimeout time.Duration               // Silence before the stream is probed and reconnected, disabled when 0
+	onStale      func(silence time.Duration) // Called when the stream went silent for staleTimeout
+}
+
+// WithConnect sets the OnConnect callback using chain method
+func (t *TickerSubscriptionOptions) WithConnect(onConnect func()) *TickerSubscriptionOptions {
+	t.onConnect = onConnect
+	return t
+}
+
+// WithReconnect sets the OnReconnect callback using chain method
+func (t *TickerSubscriptionOptions) WithReconnect(onReconnect func()) *TickerSubscriptionOptions {
+	t.onReconnect = onReconnect
+	return t
+}
+
+// WithError sets the OnError callback using chain method
+func (t *TickerSubscriptionOptions) WithError(onError func(error)) *TickerSubscriptionOptions {
+	t.onError = onError
+	return t
+}
+
+// WithTicker sets the OnTicker callback using chain method
+func (t *TickerSubscriptionOptions) WithTicker(onTicker func(WSTicker)) *TickerSubscriptionOptions {
+	t.onTicker = onTicker
+	return t
+}
+
+// WithDisconnect sets the OnDisconnect callback using chain method
+func (t *TickerSubscriptionOptions) WithDisconnect(onDisconnect func()) *TickerSubscriptionOptions {
+	t.onDisconnect = onDisconnect
+	return t
+}
+
+// WithGap sets the OnGap callback using chain method
+func (t *TickerSubscriptionOptions) WithGap(onGap func(Gap)) *TickerSubscriptionOptions {
+	t.onGap = onGap
+	return t
+}
+
+// WithStale enables the staleness watchdog using chain method: after timeout
+// without a message OnStale is called and the stream probed, then reconnected
+// if it stays silent
+func (t *TickerSubscriptionOptions) WithStale(timeout time.Duration, onStale func(silence time.Duration)) *TickerSubscriptionOptions {
+	t.staleTimeout = timeout
+	t.onStale = onStale
+	return t
+}
+
+// MarkPriceSubscriptionOptions defines the callback functions for mark price subscription
+type MarkPriceSubscriptionOptions struct {
+	onConnect    func()                      // Called when connection is established
+	onReconnect  func()                      // Called when connection is reestablished
+	onError      func(err error)             // Called when an error occurs
+	onMarkPrice  func(markPrice WSMarkPrice) // Called when mark price data is received
+	onDisconnect func()                      // Called when connection is disconnected
+	onGap        func(gap Gap)               // Called after a reconnection with the window data may have been missed
+	staleTimeout time.Duration               // Silence before the stream is probed and reconnected, disabled when 0
+	onStale      func(silence time.Duration) // Called when the stream went silent for staleTimeout
+}
+
+// WithConnect sets the OnConnect callback using chain method
+func (m *MarkPriceSubscriptionOptions) WithConnect(onConnect func()) *MarkPriceSubscriptionOptions {
+	m.onConnect = onConnect
+	return m
+}
+
+// WithReconnect sets the OnReconnect callback using chain method
+func (m *MarkPriceSubscriptionOptions) WithReconnect(onReconnect func()) *MarkPriceSubscriptionOptions {
+	m.onReconnect = onReconnect
+	return m
+}
+
+// WithError sets the OnError callback using chain method
+func (m *MarkPriceSubscriptionOptions) WithError(onError func(error)) *MarkPriceSubscriptionOptions {
+	m.onError = onError
+	return m
+}
+
+// WithMarkPrice sets the OnMarkPrice callback using chain method
+func (m *MarkPriceSubscriptionOptions) WithMarkPrice(onMarkPrice func(WSMarkPrice)) *MarkPriceSubscriptionOptions {
+	m.onMarkPrice = onMarkPrice
+	return m
+}
+
+// WithDisconnect sets the OnDisconnect callback using chain method
+func (m *MarkPriceSubscriptionOptions) WithDisconnect(onDisconnect func()) *MarkPriceSubscriptionOptions {
+	m.onDisconnect = onDisconnect
+	return m
+}
+
+// WithGap sets the OnGap callback using chain method
+func (m *MarkPriceSubscriptionOptions) WithGap(onGap func(Gap)) *MarkPriceSubscriptionOptions {
+	m.onGap = onGap
+	return m
+}
+
+// WithStale enables the staleness watchdog using chain method: after timeout
+// without a message OnStale is called and the stream probed, then reconnected
+// if it stays silent
+func (m *MarkPriceSubscriptionOptions) WithStale(timeout time.Duration, onStale func(silence time.Duration)) *MarkPriceSubscriptionOptions {
+	m.staleTimeout = timeout
+	m.onStale = onStale
+	return m
+}
+
+// DepthSubscriptionOptions defines the callback functions for depth subscription
+type DepthSubscriptionOptions struct {
+	onConnect    func()                      // Called when connection is established
+	onReconnect  func()                      // Called when connection is reestablished
+	onError      func(err error)             // Called when an error occurs
+	onDepth      func(depth WSDepth)         // Called when depth data is received
+	onDisconnect func()                      // Called when connection is disconnected
+	onGap        func(gap Gap)               // Called after a reconnection with the window data may have been missed
+	staleTimeout time.Duration               // Silence before the stream is probed and reconnected, disabled when 0
+	onStale      func(silence time.Duration) // Called when the stream went silent for staleTimeout
+}
+
+// WithConnect sets the OnConnect callback using chain method
+func (d *DepthSubscriptionOptions) WithConnect(onConnect func()) *DepthSubscriptionOptions {
+	d.onConnect = onConnect
+	return d
+}
+
+// WithReconnect sets the OnReconnect callback using chain method
+func (d *DepthSubscriptionOptions) WithReconnect(onReconnect func()) *DepthSubscriptionOptions {
+	d.onReconnect = onReconnect
+	return d
+}
+
+// WithError sets the OnError callback using chain method
+func (d *DepthSubscriptionOptions) WithError(onError func(error)) *DepthSubscriptionOptions {
+	d.onError = onError
+	return d
+}
+
+// WithDepth sets the OnDepth callback using chain method
+func (d *DepthSubscriptionOptions) WithDepth(onDepth func(WSDepth)) *DepthSubscriptionOptions {
+	d.onDepth = onDepth
+	return d
+}
+
+// WithDisconnect sets the OnDisconnect callback using chain method
+func (d *DepthSubscriptionOptions) WithDisconnect(onDisconnect func()) *DepthSubscriptionOptions {
+	d.onDisconnect = onDisconnect
+	return d
+}
+
+// WithGap sets the OnGap callback using chain method
+func (d *DepthSubscriptionOptions) WithGap(onGap func(Gap)) *DepthSubscriptionOptions {
+	d.onGap = onGap
+	return d
+}
+
+// WithStale enables the staleness watchdog using chain method: after timeout
+// without a message OnStale is called and the stream probed, then reconnected
+// if it stays silent
+func (d *DepthSubscriptionOptions) WithStale(timeout time.Duration, onStale func(silence time.Duration)) *DepthSubscriptionOptions {
+	d.staleTimeout = timeout
+	d.onStale = onStale
+	return d
+}
+
+// WSSubscription represents an active WebSocket subscription
+type WSSubscription struct {
+	id      string
+	conn    *BinanceOptionsWSConn
+	options interface{} // Can be TradeSubscriptionOptions, TickerSubscriptionOptions, MarkPriceSubscriptionOptions or DepthSubscriptionOptions
+	state   ConnectionState
+}
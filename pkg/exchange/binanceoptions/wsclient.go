@@ -0,0 +1,484 @@
+package binanceoptions
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"strings"
+	"sync"
+	"time"
+)
+
+// WSClient manages WebSocket connections for different Binance options streams
+type WSClient struct {
+	subscriptions map[string]*WSSubscription
+	mu            sync.RWMutex
+	baseWsURL     string
+	config        *WSConfig
+}
+
+// NewWSClient creates a new WebSocket client
+func NewWSClient(config *WSConfig) *WSClient {
+	// Use default config if not provided
+	if config == nil {
+		config = &WSConfig{
+			BaseWSUrl:      MainnetWSBaseUrl,
+			ReconnectDelay: reconnectDelay,
+			PingInterval:   pingInterval,
+			MaxReconnects:  -1,
+		}
+	}
+
+	// Use default URL if not provided
+	if config.BaseWSUrl == "" {
+		config.BaseWSUrl = MainnetWSBaseUrl
+	}
+
+	return &WSClient{
+		subscriptions: make(map[string]*WSSubscription),
+		baseWsURL:     config.BaseWSUrl,
+		config:        config,
+	}
+}
+
+// SubscribeTrade subscribes to the trade WebSocket stream of an option
+// symbol, e.g. BTC-250328-90000-C, or of every option of an underlying
+// asset, e.g. BTC
+func (c *WSClient) SubscribeTrade(symbol string, options *TradeSubscriptionOptions) (func(), error) {
+	// Create stream name for trade subscription
+	// Format: <symbol>@trade
+	streamName := fmt.Sprintf("%s@trade", symbol)
+	subscriptionID := fmt.Sprintf("trade_%s", symbol)
+
+	return c.subscribe(subscriptionID, streamName, options)
+}
+
+// SubscribeTicker subscribes to 24hr ticker WebSocket stream of an option
+// symbol, which carries its implied volatilities and greeks
+func (c *WSClient) SubscribeTicker(symbol string, options *TickerSubscriptionOptions) (func(), error) {
+	// Create stream name for ticker subscription
+	// Format: <symbol>@ticker
+	streamName := fmt.Sprintf("%s@ticker", symbol)
+	subscriptionID := fmt.Sprintf("ticker_%s", symbol)
+
+	return c.subscribe(subscriptionID, streamName, options)
+}
+
+// SubscribeMarkPrice subscribes to the mark price WebSocket stream of every
+// option of an underlying asset, e.g. BTC
+func (c *WSClient) SubscribeMarkPrice(underlyingAsset string, options *MarkPriceSubscriptionOptions) (func(), error) {
+	// Create stream name for mark price subscription
+	// Format: <underlyingAsset>@markPrice
+	streamName := fmt.Sprintf("%s@markPrice", underlyingAsset)
+	subscriptionID := fmt.Sprintf("markPrice_%s", underlyingAsset)
+
+	return c.subscribe(subscriptionID, streamName, options)
+}
+
+// SubscribeDepth subscribes to partial book depth WebSocket stream
+func (c *WSClient) SubscribeDepth(symbol string, level DepthLevel, updateSpeed DepthUpdateSpeed, options *DepthSubscriptionOptions) (func(), error) {
+	// Validate depth level
+	switch level {
+	case DepthLevel10, DepthLevel20, DepthLevel50, DepthLevel100:
+		// Valid levels
+	default:
+		return nil, fmt.Errorf("invalid depth level: %d, must be 10, 20, 50, or 100", level)
+	}
+
+	// Validate update speed
+	switch updateSpeed {
+	case DepthUpdate100ms, DepthUpdate500ms, DepthUpdate1000ms:
+		// Valid update speeds
+	case "": // Empty string defaults to 500ms
+		updateSpeed = DepthUpdate500ms
+	default:
+		return nil, fmt.Errorf("invalid update speed: %s, must be 100ms, 500ms, or 1000ms", updateSpeed)
+	}
+
+	// Create stream name for depth subscription
+	// Format: <symbol>@depth<levels> OR <symbol>@depth<levels>@<speed>
+	var streamName string
+	if updateSpeed == DepthUpdate500ms {
+		// Default 500ms doesn't include speed in stream name
+		streamName = fmt.Sprintf("%s@depth%d", symbol, level)
+	} else {
+		// Include speed for non-default update speeds
+		streamName = fmt.Sprintf("%s@depth%d@%s", symbol, level, updateSpeed)
+	}
+
+	subscriptionID := fmt.Sprintf("depth_%s_%d_%s", symbol, level, updateSpeed)
+
+	return c.subscribe(subscriptionID, streamName, options)
+}
+
+// subscribe is the common subscription logic for all stream types
+func (c *WSClient) subscribe(subscriptionID, streamName string, options interface{}) (func(), error) {
+	c.mu.Lock()
+	// Check if already subscribed
+	if _, exists := c.subscriptions[subscriptionID]; exists {
+		c.mu.Unlock()
+		return nil, fmt.Errorf("already subscribed to %s stream", subscriptionID)
+	}
+
+	// Create subscription with message handler
+	subscription := c.createSubscription(subscriptionID, streamName, options)
+
+	// Store subscription
+	c.subscriptions[subscriptionID] = subscription
+	c.mu.Unlock()
+
+	// Connect to WebSocket
+	ctx := context.Background()
+	if err := subscription.conn.Connect(ctx, streamName); err != nil {
+		c.mu.Lock()
+		delete(c.subscriptions, subscriptionID)
+		c.mu.Unlock()
+		c.callOnError(options, err)
+		return nil, fmt.Errorf("failed to connect to WebSocket: %w", err)
+	}
+
+	// Update state - OnConnect will be called by the low-level connection callback
+	c.mu.Lock()
+	subscription.state = StateConnected
+	c.mu.Unlock()
+
+	// Return unsubscribe function
+	unsubscribeFunc := func() {
+		c.unsubscribe(subscriptionID)
+	}
+
+	return unsubscribeFunc, nil
+}
+
+// createSubscription creates a new subscription with the appropriate message handler
+func (c *WSClient) createSubscription(subscriptionID, streamName string, options interface{}) *WSSubscription {
+	subscription := &WSSubscription{
+		id:      subscriptionID,
+		options: options,
+		state:   StateConnecting,
+	}
+
+	// Create the underlying subscription for the BinancePerpWSConn
+	lowLevelSubscription := &Subscription{}
+	lowLevelSubscription.
+		WithConnect(func() {
+			c.callOnConnect(options)
+		}).
+		WithReconnect(func() {
+			c.mu.Lock()
+			subscription.state = StateConnected
+			c.mu.Unlock()
+			c.callOnReconnect(options)
+		}).
+		WithGap(func(gap Gap) {
+			c.callOnGap(options, gap)
+		}).
+		WithError(func(err error) {
+			c.callOnError(options, err)
+		}).
+		WithMessage(func(data []byte) {
+			c.handleMessage(subscriptionID, data)
+		}).
+		WithStale(c.staleOptions(options)).
+		WithClose(func() {
+			// The connection also closes itself once reconnecting failed
+			// MaxReconnects times, which ends the subscription
+			c.mu.Lock()
+			if c.subscriptions[subscriptionID] == subscription {
+				delete(c.subscriptions, subscriptionID)
+			}
+			subscription.state = StateDisconnected
+			c.mu.Unlock()
+			c.callOnDisconnect(options)
+		})
+
+	// Create WebSocket connection
+	subscription.conn = NewBinanceOptionsWSConn(c.config, lowLevelSubscription)
+
+	return subscription
+}
+
+// handleMessage processes incoming WebSocket messages based on subscription type
+func (c *WSClient) handleMessage(subscriptionID string, data []byte) {
+	c.mu.RLock()
+	subscription, exists := c.subscriptions[subscriptionID]
+	c.mu.RUnlock()
+
+	if !exists {
+		log.Printf("[WSClient] Received message for unknown subscription: %s", subscriptionID)
+		return
+	}
+
+	// The mark price stream sends the mark prices of an underlying as an array
+	if strings.HasPrefix(string(data), "[") {
+		c.handleMarkPriceMessage(subscription, data)
+		return
+	}
+
+	// Keys match fields regardless of case, so E needs a field of its own
+	var header struct {
+		EventType string `json:"e"`
+		EventTime int64  `json:"E"`
+	}
+	if err := json.Unmarshal(data, &header); err != nil {
+		log.Printf("[WSClient] Failed to parse JSON: %v", err)
+		c.callOnError(subscription.options, fmt.Errorf("failed to parse JSON: %w", err))
+		return
+	}
+
+	// Route message based on event type and subscription type
+	switch header.EventType {
+	case "trade":
+		c.handleTradeMessage(subscription, data)
+	case "24hrTicker":
+		c.handleTickerMessage(subscription, data)
+	case "depth":
+		c.handleDepthMessage(subscription, data)
+	case "":
+		log.Printf("[WSClient] Message missing event type 'e'")
+	default:
+		log.Printf("[WSClient] Unknown event type: %s for subscription: %s", header.EventType, subscriptionID)
+	}
+}
+
+// handleTradeMessage processes incoming trade WebSocket messages
+func (c *WSClient) handleTradeMessage(subscription *WSSubscription, data []byte) {
+	var event WSTradeEvent
+	if err := json.Unmarshal(data, &event); err != nil {
+		log.Printf("[WSClient] Failed to unmarshal trade data: %v", err)
+		c.callOnError(subscription.options, fmt.Errorf("failed to unmarshal trade data: %w", err))
+		return
+	}
+
+	// Call the trade callback
+	if tradeOptions, ok := subscription.options.(*TradeSubscriptionOptions); ok && tradeOptions.onTrade != nil {
+		tradeOptions.onTrade(event)
+	}
+}
+
+// handleTickerMessage processes incoming 24hr ticker WebSocket messages
+func (c *WSClient) handleTickerMessage(subscription *WSSubscription, data []byte) {
+	var event WSTickerEvent
+	if err := json.Unmarshal(data, &event); err != nil {
+		log.Printf("[WSClient] Failed to unmarshal ticker data: %v", err)
+		c.callOnError(subscription.options, fmt.Errorf("failed to unmarshal ticker data: %w", err))
+		return
+	}
+
+	// Call the ticker callback
+	if tickerOptions, ok := subscription.options.(*TickerSubscriptionOptions); ok && tickerOptions.onTicker != nil {
+		tickerOptions.onTicker(event)
+	}
+}
+
+// handleMarkPriceMessage processes incoming mark price WebSocket messages,
+// calling the callback once per option
+func (c *WSClient) handleMarkPriceMessage(subscription *WSSubscription, data []byte) {
+	var events []WSMarkPriceEvent
+	if err := json.Unmarshal(data, &events); err != nil {
+		log.Printf("[WSClient] Failed to unmarshal mark price data: %v", err)
+		c.callOnError(subscription.options, fmt.Errorf("failed to unmarshal mark price data: %w", err))
+		return
+	}
+
+	// Call the mark price callback
+	if markPriceOptions, ok := subscription.options.(*MarkPriceSubscriptionOptions); ok && markPriceOptions.onMarkPrice != nil {
+		for _, event := range events {
+			markPriceOptions.onMarkPrice(event)
+		}
+	}
+}
+
+// handleDepthMessage processes incoming partial book depth WebSocket messages
+func (c *WSClient) handleDepthMessage(subscription *WSSubscription, data []byte) {
+	var event WSDepthEvent
+	if err := json.Unmarshal(data, &event); err != nil {
+		log.Printf("[WSClient] Failed to unmarshal depth data: %v", err)
+		c.callOnError(subscription.options, fmt.Errorf("failed to unmarshal depth data: %w", err))
+		return
+	}
+
+	// Call the depth callback
+	if depthOptions, ok := subscription.options.(*DepthSubscriptionOptions); ok && depthOptions.onDepth != nil {
+		depthOptions.onDepth(event)
+	}
+}
+
+// callOnConnect calls the OnConnect callback for any subscription type
+func (c *WSClient) callOnConnect(options interface{}) {
+	switch opts := options.(type) {
+	case *TradeSubscriptionOptions:
+		if opts.onConnect != nil {
+			opts.onConnect()
+		}
+	case *TickerSubscriptionOptions:
+		if opts.onConnect != nil {
+			opts.onConnect()
+		}
+	case *MarkPriceSubscriptionOptions:
+		if opts.onConnect != nil {
+			opts.onConnect()
+		}
+	case *DepthSubscriptionOptions:
+		if opts.onConnect != nil {
+			opts.onConnect()
+		}
+	}
+}
+
+// callOnReconnect calls the OnReconnect callback for any subscription type
+func (c *WSClient) callOnReconnect(options interface{}) {
+	switch opts := options.(type) {
+	case *TradeSubscriptionOptions:
+		if opts.onReconnect != nil {
+			opts.onReconnect()
+		}
+	case *TickerSubscriptionOptions:
+		if opts.onReconnect != nil {
+			opts.onReconnect()
+		}
+	case *MarkPriceSubscriptionOptions:
+		if opts.onReconnect != nil {
+			opts.onReconnect()
+		}
+	case *DepthSubscriptionOptions:
+		if opts.onReconnect != nil {
+			opts.onReconnect()
+		}
+	}
+}
+
+// staleOptions returns the staleness watchdog settings of any subscription type
+func (c *WSClient) staleOptions(options interface{}) (time.Duration, func(time.Duration)) {
+	switch opts := options.(type) {
+	case *TradeSubscriptionOptions:
+		return opts.staleTimeout, opts.onStale
+	case *TickerSubscriptionOptions:
+		return opts.staleTimeout, opts.onStale
+	case *MarkPriceSubscriptionOptions:
+		return opts.staleTimeout, opts.onStale
+	case *DepthSubscriptionOptions:
+		return opts.staleTimeout, opts.onStale
+	}
+	return 0, nil
+}
+
+// callOnGap calls the OnGap callback for any subscription type
+func (c *WSClient) callOnGap(options interface{}, gap Gap) {
+	switch opts := options.(type) {
+	case *TradeSubscriptionOptions:
+		if opts.onGap != nil {
+			opts.onGap(gap)
+		}
+	case *TickerSubscriptionOptions:
+		if opts.onGap != nil {
+			opts.onGap(gap)
+		}
+	case *MarkPriceSubscriptionOptions:
+		if opts.onGap != nil {
+			opts.onGap(gap)
+		}
+	case *DepthSubscriptionOptions:
+		if opts.onGap != nil {
+			opts.onGap(gap)
+		}
+	}
+}
+
+// callOnError calls the OnError callback for any subscription type
+func (c *WSClient) callOnError(options interface{}, err error) {
+	switch opts := options.(type) {
+	case *TradeSubscriptionOptions:
+		if opts.onError != nil {
+			opts.onError(err)
+		}
+	case *TickerSubscriptionOptions:
+		if opts.onError != nil {
+			opts.onError(err)
+		}
+	case *MarkPriceSubscriptionOptions:
+		if opts.onError != nil {
+			opts.onError(err)
+		}
+	case *DepthSubscriptionOptions:
+		if opts.onError != nil {
+			opts.onError(err)
+		}
+	}
+}
+
+// callOnDisconnect calls the OnDisconnect callback for any subscription type
+func (c *WSClient) callOnDisconnect(options interface{}) {
+	switch opts := options.(type) {
+	case *TradeSubscriptionOptions:
+		if opts.onDisconnect != nil {
+			opts.onDisconnect()
+		}
+	case *TickerSubscriptionOptions:
+		if opts.onDisconnect != nil {
+			opts.onDisconnect()
+		}
+	case *MarkPriceSubscriptionOptions:
+		if opts.onDisconnect != nil {
+			opts.onDisconnect()
+		}
+	case *DepthSubscriptionOptions:
+		if opts.onDisconnect != nil {
+			opts.onDisconnect()
+		}
+	}
+}
+
+// unsubscribe removes and disconnects a subscription
+func (c *WSClient) unsubscribe(subscriptionID string) {
+	c.mu.Lock()
+	subscription, exists := c.subscriptions[subscriptionID]
+	if !exists {
+		c.mu.Unlock()
+		return
+	}
+
+	delete(c.subscriptions, subscriptionID)
+	c.mu.Unlock()
+
+	// Disconnect the WebSocket connection
+	if subscription.conn != nil {
+		subscription.conn.Disconnect()
+	}
+
+	// OnDisconnect callback is called by the connection's OnClose callback
+}
+
+// Close closes all active subscriptions
+func (c *WSClient) Close() {
+	c.mu.Lock()
+	subscriptions := make([]*WSSubscription, 0, len(c.subscriptions))
+	for _, sub := range c.subscriptions {
+		subscriptions = append(subscriptions, sub)
+	}
+	c.subscriptions = make(map[string]*WSSubscription)
+	c.mu.Unlock()
+
+	// Close all connections
+	for _, sub := range subscriptions {
+		if sub.conn != nil {
+			sub.conn.Disconnect()
+		}
+	}
+}
+
+// GetSubscriptionCount returns the number of active subscriptions
+func (c *WSClient) GetSubscriptionCount() int {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return len(c.subscriptions)
+}
+
+// IsSubscribed checks if a specific subscription is active
+func (c *WSClient) IsSubscribed(subscriptionID string) bool {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	_, exists := c.subscriptions[subscriptionID]
+	return exists
+}
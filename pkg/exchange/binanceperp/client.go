@@ -254,6 +254,124 @@ func (c *Client) GetBookTicker(ctx context.Context, req GetBookTickerRequest) (R
 	return Response[[]BookTicker]{Code: 0, Message: "success", Data: &bookTickers}, nil
 }
 
+// GetOpenInterest gets the present open interest of a symbol.
+func (c *Client) GetOpenInterest(ctx context.Context, req GetOpenInterestRequest) (Response[OpenInterest], error) {
+	params := map[string]string{
+		"symbol": req.Symbol,
+	}
+
+	body, status, err := doUnsignedGet(c.cfg, PathGetOpenInterest, params)
+	if err != nil {
+		return Response[OpenInterest]{}, err
+	}
+	if status != http.StatusOK {
+		return Response[OpenInterest]{Code: status, Message: string(body)}, fmt.Errorf("http error: %d", status)
+	}
+	var openInterest OpenInterest
+	if err := json.Unmarshal(body, &openInterest); err != nil {
+		return Response[OpenInterest]{}, err
+	}
+	return Response[OpenInterest]{Code: 0, Message: "success", Data: &openInterest}, nil
+}
+
+// futuresDataParams builds the query of the /futures/data statistics
+func futuresDataParams(req GetFuturesDataRequest) map[string]string {
+	params := map[string]string{
+		"symbol": req.Symbol,
+		"period": req.Period,
+	}
+	if req.Limit > 0 {
+		params["limit"] = fmt.Sprintf("%d", req.Limit)
+	}
+	if req.StartTime > 0 {
+		params["startTime"] = fmt.Sprintf("%d", req.StartTime)
+	}
+	if req.EndTime > 0 {
+		params["endTime"] = fmt.Sprintf("%d", req.EndTime)
+	}
+	return params
+}
+
+// GetOpenInterestHist gets the open interest of a symbol per period.
+func (c *Client) GetOpenInterestHist(ctx context.Context, req GetFuturesDataRequest) (Response[[]OpenInterestHist], error) {
+	body, status, err := doUnsignedGet(c.cfg, PathGetOpenInterestHist, futuresDataParams(req))
+	if err != nil {
+		return Response[[]OpenInterestHist]{}, err
+	}
+	if status != http.StatusOK {
+		return Response[[]OpenInterestHist]{Code: status, Message: string(body)}, fmt.Errorf("http error: %d", status)
+	}
+	var hist []OpenInterestHist
+	if err := json.Unmarshal(body, &hist); err != nil {
+		return Response[[]OpenInterestHist]{}, err
+	}
+	return Response[[]OpenInterestHist]{Code: 0, Message: "success", Data: &hist}, nil
+}
+
+// GetTopLongShortPositionRatio gets the long/short ratio of the positions of top traders, the 20% of users with the highest margin balance.
+func (c *Client) GetTopLongShortPositionRatio(ctx context.Context, req GetFuturesDataRequest) (Response[[]LongShortRatio], error) {
+	body, status, err := doUnsignedGet(c.cfg, PathGetTopPositionRatio, futuresDataParams(req))
+	if err != nil {
+		return Response[[]LongShortRatio]{}, err
+	}
+	if status != http.StatusOK {
+		return Response[[]LongShortRatio]{Code: status, Message: string(body)}, fmt.Errorf("http error: %d", status)
+	}
+	var ratios []LongShortRatio
+	if err := json.Unmarshal(body, &ratios); err != nil {
+		return Response[[]LongShortRatio]{}, err
+	}
+	return Response[[]LongShortRatio]{Code: 0, Message: "success", Data: &ratios}, nil
+}
+
+// GetTopLongShortAccountRatio gets the long/short ratio of the accounts of top traders, the 20% of users with the highest margin balance.
+func (c *Client) GetTopLongShortAccountRatio(ctx context.Context, req GetFuturesDataRequest) (Response[[]LongShortRatio], error) {
+	body, status, err := doUnsignedGet(c.cfg, PathGetTopAccountRatio, futuresDataParams(req))
+	if err != nil {
+		return Response[[]LongShortRatio]{}, err
+	}
+	if status != http.StatusOK {
+		return Response[[]LongShortRatio]{Code: status, Message: string(body)}, fmt.Errorf("http error: %d", status)
+	}
+	var ratios []LongShortRatio
+	if err := json.Unmarshal(body, &ratios); err != nil {
+		return Response[[]LongShortRatio]{}, err
+	}
+	return Response[[]LongShortRatio]{Code: 0, Message: "success", Data: &ratios}, nil
+}
+
+// GetGlobalLongShortAccountRatio gets the long/short ratio of all accounts holding a position.
+func (c *Client) GetGlobalLongShortAccountRatio(ctx context.Context, req GetFuturesDataRequest) (Response[[]LongShortRatio], error) {
+	body, status, err := doUnsignedGet(c.cfg, PathGetGlobalAccountRatio, futuresDataParams(req))
+	if err != nil {
+		return Response[[]LongShortRatio]{}, err
+	}
+	if status != http.StatusOK {
+		return Response[[]LongShortRatio]{Code: status, Message: string(body)}, fmt.Errorf("http error: %d", status)
+	}
+	var ratios []LongShortRatio
+	if err := json.Unmarshal(body, &ratios); err != nil {
+		return Response[[]LongShortRatio]{}, err
+	}
+	return Response[[]LongShortRatio]{Code: 0, Message: "success", Data: &ratios}, nil
+}
+
+// GetTakerVolumeRatio gets the taker buy and sell volume of a symbol per period.
+func (c *Client) GetTakerVolumeRatio(ctx context.Context, req GetFuturesDataRequest) (Response[[]TakerVolumeRatio], error) {
+	body, status, err := doUnsignedGet(c.cfg, PathGetTakerVolumeRatio, futuresDataParams(req))
+	if err != nil {
+		return Response[[]TakerVolumeRatio]{}, err
+	}
+	if status != http.StatusOK {
+		return Response[[]TakerVolumeRatio]{Code: status, Message: string(body)}, fmt.Errorf("http error: %d", status)
+	}
+	var ratios []TakerVolumeRatio
+	if err := json.Unmarshal(body, &ratios); err != nil {
+		return Response[[]TakerVolumeRatio]{}, err
+	}
+	return Response[[]TakerVolumeRatio]{Code: 0, Message: "success", Data: &ratios}, nil
+}
+
 // GetAccountBalance queries account balance info (USER_DATA - signed endpoint).
 func (c *Client) GetAccountBalance(ctx context.Context, req GetAccountBalanceRequest) (Response[[]AccountBalance], error) {
 	params := map[string]string{}
@@ -594,6 +712,55 @@ func (c *Client) GetPositions(ctx context.Context, req GetPositionsRequest) (Res
 	return Response[[]Position]{Code: 0, Message: "success", Data: &positions}, nil
 }
 
+// GetIncomeHistory gets the income history, oldest first (USER_DATA - signed endpoint).
+// Without a time range only the last 7 days are returned.
+func (c *Client) GetIncomeHistory(ctx context.Context, req GetIncomeHistoryRequest) (Response[[]Income], error) {
+	params := map[string]string{}
+
+	// Add optional parameters
+	if req.Symbol != "" {
+		params["symbol"] = req.Symbol
+	}
+	if req.IncomeType != "" {
+		params["incomeType"] = req.IncomeType
+	}
+	if req.StartTime > 0 {
+		params["startTime"] = fmt.Sprintf("%d", req.StartTime)
+	}
+	if req.EndTime > 0 {
+		params["endTime"] = fmt.Sprintf("%d", req.EndTime)
+	}
+	if req.Page > 0 {
+		params["page"] = fmt.Sprintf("%d", req.Page)
+	}
+	if req.Limit > 0 {
+		params["limit"] = fmt.Sprintf("%d", req.Limit)
+	}
+	if req.RecvWindow > 0 {
+		params["recvWindow"] = fmt.Sprintf("%d", req.RecvWindow)
+	}
+
+	body, status, err := doSignedRequest(c.cfg, "GET", PathGetIncomeHistory, params)
+	if err != nil {
+		return Response[[]Income]{}, err
+	}
+	if status != http.StatusOK {
+		// For signed requests, check if the response contains an error message
+		var errResp Response[[]Income]
+		if json.Unmarshal(body, &errResp) == nil && errResp.Code != 0 {
+			return errResp, fmt.Errorf("api error: %d - %s", errResp.Code, errResp.Message)
+		}
+		return Response[[]Income]{Code: status, Message: string(body)}, fmt.Errorf("http error: %d", status)
+	}
+
+	var incomes []Income
+	if err := json.Unmarshal(body, &incomes); err != nil {
+		return Response[[]Income]{}, err
+	}
+
+	return Response[[]Income]{Code: 0, Message: "success", Data: &incomes}, nil
+}
+
 // StartUserDataStream starts a new user data stream.
 // The stream will close after 60 minutes unless a keepalive is sent.
 // If the account has an active listenKey, that listenKey will be returned and its validity will be extended for 60 minutes.
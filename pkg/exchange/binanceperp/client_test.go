@@ -1380,3 +1380,107 @@ func TestUserDataStreamLifecycle(t *testing.T) {
 	t.Log("✓ Step 3: Successfully closed user data stream")
 	t.Log("✓ User data stream lifecycle test completed successfully")
 }
+
+func TestGetOpenInterest(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping integration test in short mode.")
+	}
+
+	cfg := &Config{
+		BaseURL: MainnetBaseUrl,
+	}
+	client := NewClient(cfg)
+
+	resp, err := client.GetOpenInterest(context.Background(), GetOpenInterestRequest{Symbol: "BTCUSDT"})
+	if err != nil {
+		t.Fatalf("GetOpenInterest error: %v", err)
+	}
+	if resp.Data == nil {
+		t.Fatal("response data is nil, expected open interest data")
+	}
+	if resp.Data.Symbol != "BTCUSDT" {
+		t.Errorf("expected symbol BTCUSDT, got %s", resp.Data.Symbol)
+	}
+	if resp.Data.OpenInterest == "" {
+		t.Error("open interest is empty, expected non-empty value")
+	}
+	if resp.Data.Time == 0 {
+		t.Error("time is zero, expected non-zero timestamp")
+	}
+}
+
+func TestGetOpenInterestHist(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping integration test in short mode.")
+	}
+
+	cfg := &Config{
+		BaseURL: MainnetBaseUrl,
+	}
+	client := NewClient(cfg)
+
+	resp, err := client.GetOpenInterestHist(context.Background(), GetFuturesDataRequest{Symbol: "BTCUSDT", Period: "5m", Limit: 3})
+	if err != nil {
+		t.Fatalf("GetOpenInterestHist error: %v", err)
+	}
+	if resp.Data == nil || len(*resp.Data) != 3 {
+		t.Fatalf("expected 3 periods, got %v", resp.Data)
+	}
+	hist := (*resp.Data)[0]
+	if hist.SumOpenInterest == "" || hist.SumOpenInterestValue == "" || hist.Timestamp == 0 {
+		t.Errorf("incomplete open interest history: %+v", hist)
+	}
+}
+
+func TestGetLongShortRatios(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping integration test in short mode.")
+	}
+
+	cfg := &Config{
+		BaseURL: MainnetBaseUrl,
+	}
+	client := NewClient(cfg)
+	req := GetFuturesDataRequest{Symbol: "BTCUSDT", Period: "5m", Limit: 1}
+
+	for name, get := range map[string]func(context.Context, GetFuturesDataRequest) (Response[[]LongShortRatio], error){
+		"top position":   client.GetTopLongShortPositionRatio,
+		"top account":    client.GetTopLongShortAccountRatio,
+		"global account": client.GetGlobalLongShortAccountRatio,
+	} {
+		resp, err := get(context.Background(), req)
+		if err != nil {
+			t.Fatalf("%s ratio error: %v", name, err)
+		}
+		if resp.Data == nil || len(*resp.Data) != 1 {
+			t.Fatalf("%s ratio: expected 1 period, got %v", name, resp.Data)
+		}
+		ratio := (*resp.Data)[0]
+		if ratio.LongShortRatio == "" || ratio.LongAccount == "" || ratio.ShortAccount == "" || ratio.Timestamp == 0 {
+			t.Errorf("%s ratio incomplete: %+v", name, ratio)
+		}
+	}
+}
+
+func TestGetTakerVolumeRatio(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping integration test in short mode.")
+	}
+
+	cfg := &Config{
+		BaseURL: MainnetBaseUrl,
+	}
+	client := NewClient(cfg)
+
+	resp, err := client.GetTakerVolumeRatio(context.Background(), GetFuturesDataRequest{Symbol: "BTCUSDT", Period: "5m", Limit: 1})
+	if err != nil {
+		t.Fatalf("GetTakerVolumeRatio error: %v", err)
+	}
+	if resp.Data == nil || len(*resp.Data) != 1 {
+		t.Fatalf("expected 1 period, got %v", resp.Data)
+	}
+	ratio := (*resp.Data)[0]
+	if ratio.BuySellRatio == "" || ratio.BuyVol == "" || ratio.SellVol == "" || ratio.Timestamp == 0 {
+		t.Errorf("incomplete taker volume: %+v", ratio)
+	}
+}
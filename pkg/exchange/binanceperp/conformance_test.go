@@ -0,0 +1,113 @@
+package binanceperp
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/BullionBear/sequex/pkg/exchange/wstest"
+)
+
+// aggTradeAdapter runs the websocket conformance suite over the aggregate
+// trade stream
+type aggTradeAdapter struct {
+	client *WSClient
+}
+
+func (a *aggTradeAdapter) Subscribe(symbol string, h wstest.Handlers) (func(), error) {
+	return a.client.SubscribeAggTrade(symbol, (&AggTradeSubscriptionOptions{}).
+		WithConnect(h.OnConnect).
+		WithReconnect(h.OnReconnect).
+		WithDisconnect(h.OnDisconnect).
+		WithError(h.OnError).
+		WithGap(func(gap Gap) { h.OnGap(gap.From, gap.To) }).
+		WithStale(h.StaleTimeout, func(time.Duration) { h.OnStale() }).
+		WithAggTrade(func(aggTrade WSAggTrade) { h.OnData(aggTrade) }))
+}
+
+func (a *aggTradeAdapter) Close() {
+	a.client.Close()
+}
+
+func TestWSClient_Conformance(t *testing.T) {
+	wstest.Run(t, wstest.Case{
+		NewClient: func(url string) wstest.Client {
+			return &aggTradeAdapter{client: NewWSClient(&WSConfig{
+				BaseWSUrl:      url,
+				ReconnectDelay: 100 * time.Millisecond,
+				PingInterval:   time.Minute,
+				MaxReconnects:  -1,
+			})}
+		},
+		Symbol:  "btcusdt",
+		Path:    "/ws/btcusdt@aggTrade",
+		Message: []byte(`{"e":"aggTrade","E":123456789,"s":"BTCUSDT","a":5933014,"p":"0.001","q":"100","f":100,"l":105,"T":123456785,"m":true}`),
+		Check: func(t *testing.T, data any) {
+			aggTrade, ok := data.(WSAggTrade)
+			if !ok {
+				t.Fatalf("unexpected event %T", data)
+			}
+			if aggTrade.Symbol != "BTCUSDT" || aggTrade.AggTradeID != 5933014 || aggTrade.Price != "0.001" || aggTrade.FirstTradeID != 100 || aggTrade.LastTradeID != 105 {
+				t.Errorf("unexpected aggregate trade %+v", aggTrade)
+			}
+		},
+	})
+}
+
+func TestWSClient_MaxReconnects(t *testing.T) {
+	server := wstest.NewServer()
+	client := NewWSClient(&WSConfig{
+		BaseWSUrl:      server.URL(),
+		ReconnectDelay: 10 * time.Millisecond,
+		PingInterval:   time.Minute,
+		MaxReconnects:  2,
+	})
+	defer client.Close()
+
+	errs := make(chan error, 16)
+	disconnected := make(chan struct{}, 1)
+	_, err := client.SubscribeAggTrade("btcusdt", (&AggTradeSubscriptionOptions{}).
+		WithError(func(err error) { errs <- err }).
+		WithDisconnect(func() { disconnected <- struct{}{} }))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := server.WaitConnected(time.Second); err != nil {
+		t.Fatal(err)
+	}
+
+	// Every reconnection fails once the server is gone
+	server.Close()
+	select {
+	case <-disconnected:
+	case <-time.After(5 * time.Second):
+		t.Fatal("subscription did not end after max reconnects")
+	}
+	var last error
+	for len(errs) > 0 {
+		last = <-errs
+	}
+	if !errors.Is(last, ErrMaxReconnects) {
+		t.Errorf("last error %v, want %v", last, ErrMaxReconnects)
+	}
+	if n := client.GetSubscriptionCount(); n != 0 {
+		t.Errorf("%d subscriptions left, want 0", n)
+	}
+}
+
+func TestBackoffDelay(t *testing.T) {
+	config := &WSConfig{ReconnectDelay: time.Second, MaxReconnectDelay: 10 * time.Second, ReconnectJitter: -1}
+	want := []time.Duration{time.Second, 2 * time.Second, 4 * time.Second, 8 * time.Second, 10 * time.Second, 10 * time.Second}
+	for attempt, w := range want {
+		if got := backoffDelay(config, attempt); got != w {
+			t.Errorf("attempt %d: got %v, want %v", attempt, got, w)
+		}
+	}
+
+	config.ReconnectJitter = 0.5
+	for i := 0; i < 100; i++ {
+		if got := backoffDelay(config, 1); got < time.Second || got > 3*time.Second {
+			t.Fatalf("jittered delay %v outside [1s, 3s]", got)
+		}
+	}
+}
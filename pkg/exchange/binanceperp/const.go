@@ -12,6 +12,12 @@ const TestnetBaseUrl = "https://testnet.binancefuture.com"
 // Testnet WebSocket base URL
 const TestnetWSBaseUrl = "wss://fstream.binancefuture.com"
 
+// IP request weight, counted per minute across every process of the IP
+const (
+	IPWeightLimit    = 2400
+	HeaderUsedWeight = "X-MBX-USED-WEIGHT-1M"
+)
+
 // Paths
 const (
 	PathGetServerTime         = "/fapi/v1/time"
@@ -22,6 +28,12 @@ const (
 	PathGetMarkPrice          = "/fapi/v1/premiumIndex"
 	PathGetPriceTicker        = "/fapi/v2/ticker/price"
 	PathGetBookTicker         = "/fapi/v1/ticker/bookTicker"
+	PathGetOpenInterest       = "/fapi/v1/openInterest"
+	PathGetOpenInterestHist   = "/futures/data/openInterestHist"
+	PathGetTopPositionRatio   = "/futures/data/topLongShortPositionRatio"
+	PathGetTopAccountRatio    = "/futures/data/topLongShortAccountRatio"
+	PathGetGlobalAccountRatio = "/futures/data/globalLongShortAccountRatio"
+	PathGetTakerVolumeRatio   = "/futures/data/takerlongshortRatio"
 	PathGetAccountBalance     = "/fapi/v3/balance"
 	PathCreateOrder           = "/fapi/v1/order"
 	PathCancelOrder           = "/fapi/v1/order"
@@ -31,4 +43,5 @@ const (
 	PathGetPositions          = "/fapi/v2/positionRisk"
 	PathCancelAllOrders       = "/fapi/v1/allOpenOrders"
 	PathListenKey             = "/fapi/v1/listenKey"
+	PathGetIncomeHistory      = "/fapi/v1/income"
 )
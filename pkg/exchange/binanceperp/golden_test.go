@@ -0,0 +1,79 @@
+package binanceperp
+
+import (
+	"encoding/json"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/BullionBear/sequex/pkg/exchange/wstest"
+)
+
+// TestWSClient_Golden decodes recorded payloads of every stream through the
+// dispatch of the client. Payloads named user_* are user data stream events,
+// which the client hands over raw, so they are decoded into the model of
+// their event type as callers do.
+func TestWSClient_Golden(t *testing.T) {
+	wstest.Golden(t, func(t *testing.T, name string, payload []byte) any {
+		if strings.HasPrefix(name, "user_") {
+			return decodeUserData(t, payload)
+		}
+
+		var events []any
+		capture := func(event any) { events = append(events, event) }
+		onError := func(err error) { t.Errorf("decode: %v", err) }
+		c := &WSClient{subscriptions: make(map[string]*WSSubscription)}
+		// Each message only reaches the callbacks of its stream, partial and
+		// differential depth sharing the depthUpdate event
+		for _, options := range []any{
+			(&KlineSubscriptionOptions{}).WithError(onError).WithKline(func(kline WSKline) { capture(kline) }),
+			(&AggTradeSubscriptionOptions{}).WithError(onError).WithAggTrade(func(trade WSAggTrade) { capture(trade) }),
+			(&TickerSubscriptionOptions{}).WithError(onError).WithTicker(func(ticker WSTicker) { capture(ticker) }),
+			(&LiquidationSubscriptionOptions{}).WithError(onError).WithLiquidation(func(liquidation WSLiquidation) { capture(liquidation) }),
+			(&DepthSubscriptionOptions{}).WithError(onError).WithDepth(func(depth WSDepth) { capture(depth) }),
+			(&DiffDepthSubscriptionOptions{}).WithError(onError).WithDiffDepth(func(depth WSDepth) { capture(depth) }),
+		} {
+			c.subscriptions["golden"] = &WSSubscription{id: "golden", options: options}
+			c.handleMessage("golden", payload)
+		}
+		if len(events) == 0 {
+			t.Fatal("decoded no event")
+		}
+		for _, event := range events[1:] {
+			if !reflect.DeepEqual(event, events[0]) {
+				t.Fatalf("decoded %+v and %+v", events[0], event)
+			}
+		}
+		return events[0]
+	})
+}
+
+func decodeUserData(t *testing.T, payload []byte) any {
+	// Keys match fields regardless of case, so E needs a field of its own
+	var header struct {
+		EventType string `json:"e"`
+		EventTime int64  `json:"E"`
+	}
+	if err := json.Unmarshal(payload, &header); err != nil {
+		t.Fatal(err)
+	}
+	var event any
+	switch header.EventType {
+	case "ACCOUNT_UPDATE":
+		event = &WSAccountUpdateEvent{}
+	case "MARGIN_CALL":
+		event = &WSMarginCallEvent{}
+	case "ORDER_TRADE_UPDATE":
+		event = &WSOrderTradeUpdateEvent{}
+	case "TRADE_LITE":
+		event = &WSTradeLiteEvent{}
+	case "listenKeyExpired":
+		event = &WSListenKeyExpiredEvent{}
+	default:
+		t.Fatalf("unknown user data event %q", header.EventType)
+	}
+	if err := json.Unmarshal(payload, event); err != nil {
+		t.Fatal(err)
+	}
+	return reflect.ValueOf(event).Elem().Interface()
+}
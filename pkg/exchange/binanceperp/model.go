@@ -132,6 +132,53 @@ type BookTicker struct {
 	Time     int64  `json:"time"`     // Transaction time
 }
 
+// GetOpenInterestRequest defines the parameters for getting the present open interest.
+type GetOpenInterestRequest struct {
+	Symbol string // required
+}
+
+// OpenInterest represents the present open interest of a symbol.
+type OpenInterest struct {
+	Symbol       string `json:"symbol"`       // Symbol
+	OpenInterest string `json:"openInterest"` // Open interest in contracts
+	Time         int64  `json:"time"`         // Timestamp
+}
+
+// GetFuturesDataRequest defines the parameters of the /futures/data statistics.
+// Only the last 30 days are available.
+type GetFuturesDataRequest struct {
+	Symbol    string // required
+	Period    string // required, "5m", "15m", "30m", "1h", "2h", "4h", "6h", "12h" or "1d"
+	Limit     int    // optional, default 30; max 500
+	StartTime int64  // optional, timestamp in ms
+	EndTime   int64  // optional, timestamp in ms
+}
+
+// OpenInterestHist represents the open interest of a symbol at the end of a period.
+type OpenInterestHist struct {
+	Symbol               string `json:"symbol"`               // Symbol
+	SumOpenInterest      string `json:"sumOpenInterest"`      // Total open interest in contracts
+	SumOpenInterestValue string `json:"sumOpenInterestValue"` // Total open interest value
+	Timestamp            int64  `json:"timestamp"`            // Timestamp
+}
+
+// LongShortRatio represents the long/short ratio of accounts or positions at the end of a period.
+type LongShortRatio struct {
+	Symbol         string `json:"symbol"`         // Symbol
+	LongShortRatio string `json:"longShortRatio"` // Long/short ratio
+	LongAccount    string `json:"longAccount"`    // Long share, of accounts or of positions
+	ShortAccount   string `json:"shortAccount"`   // Short share, of accounts or of positions
+	Timestamp      int64  `json:"timestamp"`      // Timestamp
+}
+
+// TakerVolumeRatio represents the taker buy and sell volume of a period.
+type TakerVolumeRatio struct {
+	BuySellRatio string `json:"buySellRatio"` // Taker buy/sell volume ratio
+	BuyVol       string `json:"buyVol"`       // Taker buy volume
+	SellVol      string `json:"sellVol"`      // Taker sell volume
+	Timestamp    int64  `json:"timestamp"`    // Timestamp
+}
+
 // GetAccountBalanceRequest defines the parameters for getting account balance.
 type GetAccountBalanceRequest struct {
 	RecvWindow int64 // optional, default 5000
@@ -358,6 +405,29 @@ type Position struct {
 	UpdateTime       int64  `json:"updateTime"`       // Update timestamp
 }
 
+// GetIncomeHistoryRequest defines the parameters for getting the income history.
+type GetIncomeHistoryRequest struct {
+	Symbol     string // optional
+	IncomeType string // optional, e.g. FUNDING_FEE, COMMISSION or REALIZED_PNL; every type when empty
+	StartTime  int64  // optional, timestamp in ms, inclusive
+	EndTime    int64  // optional, timestamp in ms, inclusive
+	Page       int    // optional
+	Limit      int    // optional, default 100; max 1000
+	RecvWindow int64  // optional, default 5000
+}
+
+// Income represents a change of the wallet balance, such as a funding payment.
+type Income struct {
+	Symbol     string `json:"symbol"`     // Trading symbol, empty for transfers
+	IncomeType string `json:"incomeType"` // Income type, e.g. FUNDING_FEE
+	Income     string `json:"income"`     // Amount, negative when paid
+	Asset      string `json:"asset"`      // Income asset
+	Info       string `json:"info"`       // Extra information
+	Time       int64  `json:"time"`       // Timestamp
+	TranID     int64  `json:"tranId"`     // Transaction ID
+	TradeID    string `json:"tradeId"`    // Trade ID, if any
+}
+
 // StartUserDataStreamResponse represents the response for starting a user data stream.
 type StartUserDataStreamResponse struct {
 	ListenKey string `json:"listenKey"`
@@ -25,13 +25,11 @@ func doUnsignedGet(cfg *Config, endpoint string, params map[string]string) ([]by
 		}
 		fullURL += "?" + q.Encode()
 	}
-	resp, err := http.Get(fullURL)
+	req, err := http.NewRequest(http.MethodGet, fullURL, nil)
 	if err != nil {
 		return nil, 0, err
 	}
-	defer resp.Body.Close()
-	body, err := io.ReadAll(resp.Body)
-	return body, resp.StatusCode, err
+	return send(cfg, req)
 }
 
 // doSignedRequest performs signed request (GET/POST/PUT/DELETE) for TRADE and USER_DATA endpoints
@@ -71,14 +69,7 @@ func doSignedRequest(cfg *Config, method, endpoint string, params map[string]str
 	// Set API key header
 	req.Header.Set("X-MBX-APIKEY", cfg.APIKey)
 
-	client := &http.Client{}
-	resp, err := client.Do(req)
-	if err != nil {
-		return nil, 0, err
-	}
-	defer resp.Body.Close()
-	body, err := io.ReadAll(resp.Body)
-	return body, resp.StatusCode, err
+	return send(cfg, req)
 }
 
 // doAPIKeyOnlyRequest handles requests that only need API key header (no signing)
@@ -103,12 +94,32 @@ func doAPIKeyOnlyRequest(cfg *Config, method, endpoint string, params map[string
 	// Set API key header
 	req.Header.Set("X-MBX-APIKEY", cfg.APIKey)
 
-	client := &http.Client{}
+	return send(cfg, req)
+}
+
+// send performs a request within the weight budget of the configuration and
+// reports the weight the exchange counted back to it
+func send(cfg *Config, req *http.Request) ([]byte, int, error) {
+	ctx := req.Context()
+	if cfg.Limiter != nil {
+		if err := cfg.Limiter.Wait(ctx, 1); err != nil {
+			return nil, 0, err
+		}
+	}
+	client, err := cfg.Network.SharedHTTPClient()
+	if err != nil {
+		return nil, 0, err
+	}
 	resp, err := client.Do(req)
 	if err != nil {
 		return nil, 0, err
 	}
 	defer resp.Body.Close()
+	if cfg.Limiter != nil {
+		if used, err := strconv.Atoi(resp.Header.Get(HeaderUsedWeight)); err == nil {
+			cfg.Limiter.Observe(ctx, used)
+		}
+	}
 	body, err := io.ReadAll(resp.Body)
 	return body, resp.StatusCode, err
 }
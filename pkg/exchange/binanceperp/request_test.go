@@ -1,9 +1,12 @@
 package binanceperp
 
 import (
+	"context"
 	"encoding/json"
 	"os"
 	"testing"
+
+	"github.com/BullionBear/sequex/pkg/exchange/ratelimit"
 )
 
 func TestDoUnsignedGet_ServerTime(t *testing.T) {
@@ -32,6 +35,27 @@ func TestDoUnsignedGet_ServerTime(t *testing.T) {
 	}
 }
 
+func TestDoUnsignedGet_WeightBudget(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping integration test in short mode.")
+	}
+	limiter, err := ratelimit.New(ratelimit.Config{Limit: IPWeightLimit})
+	if err != nil {
+		t.Fatal(err)
+	}
+	cfg := &Config{
+		BaseURL: MainnetBaseUrl,
+		Limiter: limiter,
+	}
+	if _, status, err := doUnsignedGet(cfg, PathGetServerTime, nil); err != nil || status != 200 {
+		t.Fatalf("doUnsignedGet error: %v, status %d", err, status)
+	}
+	// The weight reported by the exchange replaces the local estimate
+	if used, limit := limiter.Used(context.Background()); used < 1 || limit != IPWeightLimit {
+		t.Errorf("unexpected usage %d/%d", used, limit)
+	}
+}
+
 func TestDoUnsignedGet_OrderBook(t *testing.T) {
 	if testing.Short() {
 		t.Skip("skipping integration test in short mode.")
@@ -2,11 +2,14 @@ package binanceperp
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"log"
+	"math/rand"
 	"sync"
 	"time"
 
+	"github.com/BullionBear/sequex/pkg/exchange/transport"
 	"github.com/gorilla/websocket"
 )
 
@@ -21,16 +24,36 @@ type WSConnection interface {
 const (
 	pingInterval      = 3 * time.Minute  // Binance sends ping every 3 minutes
 	pongTimeout       = 10 * time.Minute // Disconnect if no pong received within 10 minutes
-	reconnectDelay    = 5 * time.Second  // Delay between reconnection attempts
+	reconnectDelay    = 5 * time.Second  // Delay before the first reconnection attempt
+	maxReconnectDelay = 1 * time.Minute  // Cap of the reconnection backoff
+	reconnectJitter   = 0.2              // Fraction of each reconnection delay randomized
 	connectionTimeout = 24 * time.Hour   // Connection valid for 24 hours
 )
 
+// ErrMaxReconnects is reported when MaxReconnects attempts in a row failed
+// and the stream was closed
+var ErrMaxReconnects = errors.New("max reconnects exceeded")
+
 // WSConfig holds WebSocket client configuration
 type WSConfig struct {
-	BaseWSUrl      string
-	ReconnectDelay time.Duration
-	PingInterval   time.Duration
-	MaxReconnects  int // -1 means no max reconnects
+	BaseWSUrl         string
+	ReconnectDelay    time.Duration // Delay before the first reconnection attempt, doubled after each failure
+	MaxReconnectDelay time.Duration // Cap of the reconnection backoff, 1 minute when 0
+	ReconnectJitter   float64       // Fraction of each delay randomized, 0.2 when 0, negative disables
+	PingInterval      time.Duration
+	MaxReconnects     int               // Failed attempts in a row before giving up, -1 means no max reconnects
+	Network           transport.Options // Proxy, dialer and DNS settings, direct connection when empty
+}
+
+// Gap is the window a stream delivered no data because of a reconnection
+type Gap struct {
+	From time.Time // Receipt of the last message before the drop
+	To   time.Time // Reconnection
+}
+
+// Duration returns the length of the gap
+func (g Gap) Duration() time.Duration {
+	return g.To.Sub(g.From)
 }
 
 // Subscription provides a builder pattern for configuring WebSocket stream callbacks
@@ -40,6 +63,10 @@ type Subscription struct {
 	onError     func(error)
 	onMessage   func([]byte)
 	onClose     func()
+	onGap       func(Gap)
+
+	staleTimeout time.Duration
+	onStale      func(silence time.Duration)
 }
 
 // WithConnect sets the OnConnect callback
@@ -72,6 +99,23 @@ func (s *Subscription) WithClose(onClose func()) *Subscription {
 	return s
 }
 
+// WithGap sets the OnGap callback, called after a reconnection with the
+// window data may have been missed
+func (s *Subscription) WithGap(onGap func(Gap)) *Subscription {
+	s.onGap = onGap
+	return s
+}
+
+// WithStale enables the staleness watchdog: once the stream is silent for
+// timeout, OnStale is called and the connection probed with a ping, and if it
+// stays silent for half a timeout more, the connection is reconnected. A zero
+// timeout disables the watchdog.
+func (s *Subscription) WithStale(timeout time.Duration, onStale func(silence time.Duration)) *Subscription {
+	s.staleTimeout = timeout
+	s.onStale = onStale
+	return s
+}
+
 // BinancePerpWSConn manages WebSocket connection to Binance perpetual futures streams
 type BinancePerpWSConn struct {
 	conn         *websocket.Conn
@@ -88,7 +132,10 @@ type BinancePerpWSConn struct {
 	ctx             context.Context
 	cancel          context.CancelFunc
 	shouldReconnect bool
-	reconnectCount  int
+	reconnectCount  int       // Failed attempts of the current reconnection
+	lastMessage     time.Time // Receipt of the last message, where a gap starts
+	loopsOnce       sync.Once // The ping, reconnect and watchdog loops outlive connections
+	closeOnce       sync.Once
 }
 
 // NewBinancePerpWSConn creates a new WebSocket client instance
@@ -121,7 +168,7 @@ func NewBinancePerpWSConn(config *WSConfig, subscription *Subscription) *Binance
 		ctx:             ctx,
 		cancel:          cancel,
 		done:            make(chan struct{}),
-		reconnect:       make(chan struct{}),
+		reconnect:       make(chan struct{}, 1), // Buffered so a drop before the loop runs is not lost
 		logger:          log.Default(),
 		shouldReconnect: true,
 	}
@@ -137,32 +184,47 @@ func (c *BinancePerpWSConn) Connect(ctx context.Context, streamName string) erro
 	}
 
 	c.streamName = streamName
-
-	// Construct WebSocket URL for raw stream
-	url := c.config.BaseWSUrl + "/ws/" + streamName
-
-	dialer := websocket.DefaultDialer
-	conn, _, err := dialer.DialContext(ctx, url, nil)
-	if err != nil {
+	if err := c.dial(ctx); err != nil {
 		if c.subscription != nil && c.subscription.onError != nil {
 			c.subscription.onError(err)
 		}
 		return err
 	}
 
-	c.conn = conn
-	c.connected = true
-	c.reconnectCount = 0
-
-	// Start goroutines for message handling and ping/pong
-	go c.readLoop()
-	go c.pingLoop()
-	go c.reconnectLoop()
+	c.loopsOnce.Do(func() {
+		go c.pingLoop()
+		go c.reconnectLoop()
+		if c.subscription != nil && c.subscription.staleTimeout > 0 {
+			go c.watchdogLoop(c.subscription.staleTimeout)
+		}
+	})
 
 	// Call OnConnect callback
 	if c.subscription != nil && c.subscription.onConnect != nil {
 		c.subscription.onConnect()
 	}
+	return nil
+}
+
+// dial opens a connection to the stream and starts reading it. The caller
+// holds the lock.
+func (c *BinancePerpWSConn) dial(ctx context.Context) error {
+	// Construct WebSocket URL for raw stream
+	url := c.config.BaseWSUrl + "/ws/" + c.streamName
+
+	dialer, err := c.config.Network.WSDialer()
+	if err != nil {
+		return err
+	}
+	conn, _, err := dialer.DialContext(ctx, url, nil)
+	if err != nil {
+		return err
+	}
+
+	c.conn = conn
+	c.connected = true
+	c.lastMessage = time.Now()
+	go c.readLoop(conn)
 
 	c.logger.Printf("[BinancePerpWS] Connected to %s", url)
 	return nil
@@ -177,13 +239,9 @@ func (c *BinancePerpWSConn) Disconnect() error {
 	c.connected = false
 	c.mu.Unlock()
 
-	// Cancel context and close channels first to stop all goroutines
+	// Cancel context first to stop all goroutines
 	c.cancel()
 
-	// Wait a moment for goroutines to stop
-	time.Sleep(10 * time.Millisecond)
-
-	// Now safely send close message and close connection
 	if conn != nil {
 		// Send close frame before closing
 		err := conn.WriteMessage(websocket.CloseMessage,
@@ -195,23 +253,21 @@ func (c *BinancePerpWSConn) Disconnect() error {
 		conn.Close()
 	}
 
-	// Close done channel after connection is closed
-	select {
-	case <-c.done:
-		// Already closed
-	default:
-		close(c.done)
-	}
-
-	// Call OnClose callback
-	if c.subscription != nil && c.subscription.onClose != nil {
-		c.subscription.onClose()
-	}
-
+	c.close()
 	c.logger.Printf("[BinancePerpWS] Disconnected")
 	return nil
 }
 
+// close ends the connection for good, calling OnClose once
+func (c *BinancePerpWSConn) close() {
+	c.closeOnce.Do(func() {
+		close(c.done)
+		if c.subscription != nil && c.subscription.onClose != nil {
+			c.subscription.onClose()
+		}
+	})
+}
+
 // IsConnected returns the current connection status
 func (c *BinancePerpWSConn) IsConnected() bool {
 	c.mu.RLock()
@@ -219,26 +275,10 @@ func (c *BinancePerpWSConn) IsConnected() bool {
 	return c.connected
 }
 
-// readLoop continuously reads messages from the WebSocket connection
-func (c *BinancePerpWSConn) readLoop() {
+// readLoop continuously reads messages from one connection until it fails
+func (c *BinancePerpWSConn) readLoop(conn *websocket.Conn) {
 	for {
-		select {
-		case <-c.ctx.Done():
-			return
-		case <-c.done:
-			return
-		default:
-		}
-
-		c.mu.RLock()
-		conn := c.conn
-		c.mu.RUnlock()
-
-		if conn == nil {
-			return
-		}
-
-		_, message, err := conn.ReadMessage()
+		_, message, err := c.config.Network.ReadMessage(conn)
 		if err != nil {
 			// Check if this is a graceful shutdown
 			if c.ctx.Err() != nil {
@@ -250,10 +290,14 @@ func (c *BinancePerpWSConn) readLoop() {
 				c.subscription.onError(err)
 			}
 
-			c.handleDisconnect()
+			c.handleDisconnect(conn)
 			return
 		}
 
+		c.mu.Lock()
+		c.lastMessage = time.Now()
+		c.mu.Unlock()
+
 		// Call the message handler if set
 		if c.subscription != nil && c.subscription.onMessage != nil {
 			c.subscription.onMessage(message)
@@ -296,7 +340,10 @@ func (c *BinancePerpWSConn) pingLoop() {
 	}
 }
 
-// reconnectLoop handles automatic reconnection
+// reconnectLoop redials the stream after every drop, backing off between
+// failed attempts, and reports the window without data once reconnected.
+// When MaxReconnects attempts fail in a row the connection is closed so
+// subscribers learn the stream ended instead of waiting on a dead one.
 func (c *BinancePerpWSConn) reconnectLoop() {
 	for {
 		select {
@@ -305,49 +352,123 @@ func (c *BinancePerpWSConn) reconnectLoop() {
 		case <-c.done:
 			return
 		case <-c.reconnect:
-			if !c.shouldReconnect {
-				continue
-			}
+		}
+
+		c.mu.Lock()
+		gapStart := c.lastMessage
+		c.reconnectCount = 0
+		c.mu.Unlock()
+
+		for {
+			c.mu.Lock()
+			attempt := c.reconnectCount
+			c.mu.Unlock()
 
 			// Check if we've exceeded max reconnects
-			if c.config.MaxReconnects > 0 && c.reconnectCount >= c.config.MaxReconnects {
+			if c.config.MaxReconnects > 0 && attempt >= c.config.MaxReconnects {
 				c.logger.Printf("[BinancePerpWS] Max reconnects (%d) exceeded", c.config.MaxReconnects)
+				c.mu.Lock()
+				c.shouldReconnect = false
+				c.mu.Unlock()
 				if c.subscription != nil && c.subscription.onError != nil {
-					c.subscription.onError(fmt.Errorf("max reconnects exceeded"))
+					c.subscription.onError(ErrMaxReconnects)
 				}
+				c.close()
 				return
 			}
 
-			c.logger.Printf("[BinancePerpWS] Reconnecting in %v... (attempt %d)",
-				c.config.ReconnectDelay, c.reconnectCount+1)
-
-			time.Sleep(c.config.ReconnectDelay)
+			delay := backoffDelay(c.config, attempt)
+			c.logger.Printf("[BinancePerpWS] Reconnecting in %v... (attempt %d)", delay, attempt+1)
+			select {
+			case <-c.ctx.Done():
+				return
+			case <-time.After(delay):
+			}
 
-			if err := c.Connect(c.ctx, c.streamName); err != nil {
-				c.logger.Printf("[BinancePerpWS] Reconnect failed: %v", err)
+			c.mu.Lock()
+			if !c.shouldReconnect {
+				c.mu.Unlock()
+				return
+			}
+			err := c.dial(c.ctx)
+			if err != nil {
 				c.reconnectCount++
-				// Trigger another reconnect attempt
-				select {
-				case c.reconnect <- struct{}{}:
-				default:
-				}
-			} else {
-				c.logger.Printf("[BinancePerpWS] Reconnected successfully")
-				if c.subscription != nil && c.subscription.onReconnect != nil {
-					c.subscription.onReconnect()
-				}
 			}
+			c.mu.Unlock()
+
+			if err == nil {
+				break
+			}
+			c.logger.Printf("[BinancePerpWS] Reconnect failed: %v", err)
+			if c.ctx.Err() == nil && c.subscription != nil && c.subscription.onError != nil {
+				c.subscription.onError(err)
+			}
+		}
+
+		c.logger.Printf("[BinancePerpWS] Reconnected successfully")
+		if c.subscription != nil && c.subscription.onReconnect != nil {
+			c.subscription.onReconnect()
+		}
+		if c.subscription != nil && c.subscription.onGap != nil {
+			c.subscription.onGap(Gap{From: gapStart, To: time.Now()})
 		}
 	}
 }
 
-// handleDisconnect handles connection loss and triggers reconnection
-func (c *BinancePerpWSConn) handleDisconnect() {
+// watchdogLoop detects streams that went quiet while the TCP connection
+// stays open, which Binance occasionally does, and forces a reconnection
+func (c *BinancePerpWSConn) watchdogLoop(timeout time.Duration) {
+	ticker := time.NewTicker(timeout / 4)
+	defer ticker.Stop()
+
+	var probed time.Time // Last message when the current silence was probed
+	for {
+		select {
+		case <-c.ctx.Done():
+			return
+		case <-c.done:
+			return
+		case <-ticker.C:
+		}
+
+		c.mu.RLock()
+		conn := c.conn
+		last := c.lastMessage
+		c.mu.RUnlock()
+		if conn == nil {
+			continue // Reconnecting
+		}
+
+		silence := time.Since(last)
+		switch {
+		case silence < timeout:
+		case !probed.Equal(last):
+			probed = last
+			c.logger.Printf("[BinancePerpWS] No message for %v, probing", silence.Round(time.Millisecond))
+			if c.subscription.onStale != nil {
+				c.subscription.onStale(silence)
+			}
+			if err := conn.WriteControl(websocket.PingMessage, nil, time.Now().Add(timeout/2)); err != nil {
+				c.logger.Printf("[BinancePerpWS] Probe failed: %v", err)
+				conn.Close() // The read loop reconnects
+			}
+		case silence >= timeout+timeout/2:
+			c.logger.Printf("[BinancePerpWS] No message for %v, reconnecting", silence.Round(time.Millisecond))
+			conn.Close() // The read loop reconnects
+		}
+	}
+}
+
+// handleDisconnect handles the loss of a connection and triggers reconnection
+func (c *BinancePerpWSConn) handleDisconnect(conn *websocket.Conn) {
 	c.mu.Lock()
-	if c.conn != nil {
-		c.conn.Close()
-		c.conn = nil
+	if c.conn != conn {
+		// Already replaced or closed
+		c.mu.Unlock()
+		return
 	}
+	c.conn.Close()
+	c.conn = nil
 	c.connected = false
 	shouldReconnect := c.shouldReconnect && c.ctx.Err() == nil
 	c.mu.Unlock()
@@ -361,6 +482,36 @@ func (c *BinancePerpWSConn) handleDisconnect() {
 	}
 }
 
+// backoffDelay returns the delay before a reconnection attempt: the
+// reconnect delay doubled on every failed attempt up to MaxReconnectDelay,
+// randomized by the jitter fraction so clients dropped together do not
+// reconnect together
+func backoffDelay(config *WSConfig, attempt int) time.Duration {
+	maxDelay := config.MaxReconnectDelay
+	if maxDelay <= 0 {
+		maxDelay = maxReconnectDelay
+	}
+	delay := config.ReconnectDelay
+	for i := 0; i < attempt && delay < maxDelay; i++ {
+		delay *= 2
+	}
+	if delay > maxDelay {
+		delay = maxDelay
+	}
+
+	jitter := config.ReconnectJitter
+	if jitter == 0 {
+		jitter = reconnectJitter
+	}
+	if jitter > 0 {
+		if jitter > 1 {
+			jitter = 1
+		}
+		delay = time.Duration(float64(delay) * (1 - jitter + 2*jitter*rand.Float64()))
+	}
+	return delay
+}
+
 // User Data Stream Constants
 const (
 	listenKeyRefreshInterval = 55 * time.Minute // Refresh every 55 minutes (5 minutes before expiry)
@@ -422,7 +573,7 @@ func NewBinancePerpUserDataStream(client *Client, config *WSConfig, subscription
 		ctx:             ctx,
 		cancel:          cancel,
 		done:            make(chan struct{}),
-		reconnect:       make(chan struct{}),
+		reconnect:       make(chan struct{}, 1), // Buffered so a drop before the loop runs is not lost
 		refreshDone:     make(chan struct{}),
 		logger:          log.Default(),
 		shouldReconnect: true,
@@ -624,7 +775,10 @@ func (u *BinancePerpUserDataStream) connectWebSocket(ctx context.Context) error
 	// Construct WebSocket URL for user data stream
 	url := u.config.BaseWSUrl + "/ws/" + u.listenKey
 
-	dialer := websocket.DefaultDialer
+	dialer, err := u.config.Network.WSDialer()
+	if err != nil {
+		return fmt.Errorf("failed to connect to user data stream: %w", err)
+	}
 	conn, _, err := dialer.DialContext(ctx, url, nil)
 	if err != nil {
 		return fmt.Errorf("failed to connect to user data stream: %w", err)
@@ -654,7 +808,7 @@ func (u *BinancePerpUserDataStream) readLoop() {
 			return
 		}
 
-		_, message, err := conn.ReadMessage()
+		_, message, err := u.config.Network.ReadMessage(conn)
 		if err != nil {
 			// Check if this is a graceful shutdown
 			if u.ctx.Err() != nil {
@@ -728,15 +882,16 @@ func (u *BinancePerpUserDataStream) reconnectLoop() {
 			if u.config.MaxReconnects > 0 && u.reconnectCount >= u.config.MaxReconnects {
 				u.logger.Printf("[BinancePerpUserData] Max reconnects (%d) exceeded", u.config.MaxReconnects)
 				if u.subscription != nil && u.subscription.onError != nil {
-					u.subscription.onError(fmt.Errorf("max reconnects exceeded"))
+					u.subscription.onError(ErrMaxReconnects)
 				}
 				return
 			}
 
+			delay := backoffDelay(u.config, u.reconnectCount)
 			u.logger.Printf("[BinancePerpUserData] Reconnecting in %v... (attempt %d)",
-				u.config.ReconnectDelay, u.reconnectCount+1)
+				delay, u.reconnectCount+1)
 
-			time.Sleep(u.config.ReconnectDelay)
+			time.Sleep(delay)
 
 			if err := u.Connect(u.ctx); err != nil {
 				u.logger.Printf("[BinancePerpUserData] Reconnect failed: %v", err)
@@ -1,5 +1,7 @@
 package binanceperp
 
+import "time"
+
 // WSKlineEvent represents the complete kline/candlestick WebSocket event
 type WSKlineEvent struct {
 	EventType string  `json:"e"` // Event type
@@ -31,11 +33,14 @@ type WSKline struct {
 
 // KlineSubscriptionOptions defines the callback functions for kline subscription
 type KlineSubscriptionOptions struct {
-	onConnect    func()              // Called when connection is established
-	onReconnect  func()              // Called when connection is reestablished
-	onError      func(err error)     // Called when an error occurs
-	onKline      func(kline WSKline) // Called when kline data is received
-	onDisconnect func()              // Called when connection is disconnected
+	onConnect    func()                      // Called when connection is established
+	onReconnect  func()                      // Called when connection is reestablished
+	onError      func(err error)             // Called when an error occurs
+	onKline      func(kline WSKline)         // Called when kline data is received
+	onDisconnect func()                      // Called when connection is disconnected
+	onGap        func(gap Gap)               // Called after a reconnection with the window data may have been missed
+	staleTimeout time.Duration               // Silence before the stream is probed and reconnected, disabled when 0
+	onStale      func(silence time.Duration) // Called when the stream went silent for staleTimeout
 }
 
 // WithConnect sets the OnConnect callback using chain method
@@ -68,6 +73,21 @@ func (k *KlineSubscriptionOptions) WithDisconnect(onDisconnect func()) *KlineSub
 	return k
 }
 
+// WithGap sets the OnGap callback using chain method
+func (k *KlineSubscriptionOptions) WithGap(onGap func(Gap)) *KlineSubscriptionOptions {
+	k.onGap = onGap
+	return k
+}
+
+// WithStale enables the staleness watchdog using chain method: after timeout
+// without a message OnStale is called and the stream probed, then reconnected
+// if it stays silent
+func (k *KlineSubscriptionOptions) WithStale(timeout time.Duration, onStale func(silence time.Duration)) *KlineSubscriptionOptions {
+	k.staleTimeout = timeout
+	k.onStale = onStale
+	return k
+}
+
 // ConnectionState represents the current state of a WebSocket subscription
 type ConnectionState int
 
@@ -97,11 +117,14 @@ type WSAggTrade = WSAggTradeEvent
 
 // AggTradeSubscriptionOptions defines the callback functions for aggregate trade subscription
 type AggTradeSubscriptionOptions struct {
-	onConnect    func()                    // Called when connection is established
-	onReconnect  func()                    // Called when connection is reestablished
-	onError      func(err error)           // Called when an error occurs
-	onAggTrade   func(aggTrade WSAggTrade) // Called when aggregate trade data is received
-	onDisconnect func()                    // Called when connection is disconnected
+	onConnect    func()                      // Called when connection is established
+	onReconnect  func()                      // Called when connection is reestablished
+	onError      func(err error)             // Called when an error occurs
+	onAggTrade   func(aggTrade WSAggTrade)   // Called when aggregate trade data is received
+	onDisconnect func()                      // Called when connection is disconnected
+	onGap        func(gap Gap)               // Called after a reconnection with the window data may have been missed
+	staleTimeout time.Duration               // Silence before the stream is probed and reconnected, disabled when 0
+	onStale      func(silence time.Duration) // Called when the stream went silent for staleTimeout
 }
 
 // WithConnect sets the OnConnect callback using chain method
@@ -134,6 +157,21 @@ func (a *AggTradeSubscriptionOptions) WithDisconnect(onDisconnect func()) *AggTr
 	return a
 }
 
+// WithGap sets the OnGap callback using chain method
+func (a *AggTradeSubscriptionOptions) WithGap(onGap func(Gap)) *AggTradeSubscriptionOptions {
+	a.onGap = onGap
+	return a
+}
+
+// WithStale enables the staleness watchdog using chain method: after timeout
+// without a message OnStale is called and the stream probed, then reconnected
+// if it stays silent
+func (a *AggTradeSubscriptionOptions) WithStale(timeout time.Duration, onStale func(silence time.Duration)) *AggTradeSubscriptionOptions {
+	a.staleTimeout = timeout
+	a.onStale = onStale
+	return a
+}
+
 // WSTickerEvent represents the complete 24hr ticker statistics WebSocket event
 type WSTickerEvent struct {
 	EventType          string `json:"e"` // Event type
@@ -161,11 +199,14 @@ type WSTicker = WSTickerEvent
 
 // TickerSubscriptionOptions defines the callback functions for ticker subscription
 type TickerSubscriptionOptions struct {
-	onConnect    func()                // Called when connection is established
-	onReconnect  func()                // Called when connection is reestablished
-	onError      func(err error)       // Called when an error occurs
-	onTicker     func(ticker WSTicker) // Called when ticker data is received
-	onDisconnect func()                // Called when connection is disconnected
+	onConnect    func()                      // Called when connection is established
+	onReconnect  func()                      // Called when connection is reestablished
+	onError      func(err error)             // Called when an error occurs
+	onTicker     func(ticker WSTicker)       // Called when ticker data is received
+	onDisconnect func()                      // Called when connection is disconnected
+	onGap        func(gap Gap)               // Called after a reconnection with the window data may have been missed
+	staleTimeout time.Duration               // Silence before the stream is probed and reconnected, disabled when 0
+	onStale      func(silence time.Duration) // Called when the stream went silent for staleTimeout
 }
 
 // WithConnect sets the OnConnect callback using chain method
@@ -198,6 +239,21 @@ func (t *TickerSubscriptionOptions) WithDisconnect(onDisconnect func()) *TickerS
 	return t
 }
 
+// WithGap sets the OnGap callback using chain method
+func (t *TickerSubscriptionOptions) WithGap(onGap func(Gap)) *TickerSubscriptionOptions {
+	t.onGap = onGap
+	return t
+}
+
+// WithStale enables the staleness watchdog using chain method: after timeout
+// without a message OnStale is called and the stream probed, then reconnected
+// if it stays silent
+func (t *TickerSubscriptionOptions) WithStale(timeout time.Duration, onStale func(silence time.Duration)) *TickerSubscriptionOptions {
+	t.staleTimeout = timeout
+	t.onStale = onStale
+	return t
+}
+
 // WSLiquidationEvent represents the complete liquidation order WebSocket event
 type WSLiquidationEvent struct {
 	EventType string             `json:"e"` // Event type
@@ -230,6 +286,9 @@ type LiquidationSubscriptionOptions struct {
 	onError       func(err error)                 // Called when an error occurs
 	onLiquidation func(liquidation WSLiquidation) // Called when liquidation data is received
 	onDisconnect  func()                          // Called when connection is disconnected
+	onGap         func(gap Gap)                   // Called after a reconnection with the window data may have been missed
+	staleTimeout  time.Duration                   // Silence before the stream is probed and reconnected, disabled when 0
+	onStale       func(silence time.Duration)     // Called when the stream went silent for staleTimeout
 }
 
 // WithConnect sets the OnConnect callback using chain method
@@ -262,6 +321,21 @@ func (l *LiquidationSubscriptionOptions) WithDisconnect(onDisconnect func()) *Li
 	return l
 }
 
+// WithGap sets the OnGap callback using chain method
+func (l *LiquidationSubscriptionOptions) WithGap(onGap func(Gap)) *LiquidationSubscriptionOptions {
+	l.onGap = onGap
+	return l
+}
+
+// WithStale enables the staleness watchdog using chain method: after timeout
+// without a message OnStale is called and the stream probed, then reconnected
+// if it stays silent
+func (l *LiquidationSubscriptionOptions) WithStale(timeout time.Duration, onStale func(silence time.Duration)) *LiquidationSubscriptionOptions {
+	l.staleTimeout = timeout
+	l.onStale = onStale
+	return l
+}
+
 // WSDepthEvent represents the partial book depth WebSocket event
 type WSDepthEvent struct {
 	EventType       string     `json:"e"`  // Event type
@@ -298,11 +372,14 @@ const (
 
 // DepthSubscriptionOptions defines the callback functions for depth subscription
 type DepthSubscriptionOptions struct {
-	onConnect    func()              // Called when connection is established
-	onReconnect  func()              // Called when connection is reestablished
-	onError      func(err error)     // Called when an error occurs
-	onDepth      func(depth WSDepth) // Called when depth data is received
-	onDisconnect func()              // Called when connection is disconnected
+	onConnect    func()                      // Called when connection is established
+	onReconnect  func()                      // Called when connection is reestablished
+	onError      func(err error)             // Called when an error occurs
+	onDepth      func(depth WSDepth)         // Called when depth data is received
+	onDisconnect func()                      // Called when connection is disconnected
+	onGap        func(gap Gap)               // Called after a reconnection with the window data may have been missed
+	staleTimeout time.Duration               // Silence before the stream is probed and reconnected, disabled when 0
+	onStale      func(silence time.Duration) // Called when the stream went silent for staleTimeout
 }
 
 // WithConnect sets the OnConnect callback using chain method
@@ -335,14 +412,32 @@ func (d *DepthSubscriptionOptions) WithDisconnect(onDisconnect func()) *DepthSub
 	return d
 }
 
+// WithGap sets the OnGap callback using chain method
+func (d *DepthSubscriptionOptions) WithGap(onGap func(Gap)) *DepthSubscriptionOptions {
+	d.onGap = onGap
+	return d
+}
+
+// WithStale enables the staleness watchdog using chain method: after timeout
+// without a message OnStale is called and the stream probed, then reconnected
+// if it stays silent
+func (d *DepthSubscriptionOptions) WithStale(timeout time.Duration, onStale func(silence time.Duration)) *DepthSubscriptionOptions {
+	d.staleTimeout = timeout
+	d.onStale = onStale
+	return d
+}
+
 // DiffDepthSubscriptionOptions defines the callback functions for differential depth subscription
 // Note: Reuses WSDepthEvent structure but represents order book changes rather than snapshots
 type DiffDepthSubscriptionOptions struct {
-	onConnect    func()                  // Called when connection is established
-	onReconnect  func()                  // Called when connection is reestablished
-	onError      func(err error)         // Called when an error occurs
-	onDiffDepth  func(diffDepth WSDepth) // Called when differential depth data is received
-	onDisconnect func()                  // Called when connection is disconnected
+	onConnect    func()                      // Called when connection is established
+	onReconnect  func()                      // Called when connection is reestablished
+	onError      func(err error)             // Called when an error occurs
+	onDiffDepth  func(diffDepth WSDepth)     // Called when differential depth data is received
+	onDisconnect func()                      // Called when connection is disconnected
+	onGap        func(gap Gap)               // Called after a reconnection with the window data may have been missed
+	staleTimeout time.Duration               // Silence before the stream is probed and reconnected, disabled when 0
+	onStale      func(silence time.Duration) // Called when the stream went silent for staleTimeout
 }
 
 // User Data Stream Events
@@ -515,6 +610,21 @@ func (dd *DiffDepthSubscriptionOptions) WithDisconnect(onDisconnect func()) *Dif
 	return dd
 }
 
+// WithGap sets the OnGap callback using chain method
+func (dd *DiffDepthSubscriptionOptions) WithGap(onGap func(Gap)) *DiffDepthSubscriptionOptions {
+	dd.onGap = onGap
+	return dd
+}
+
+// WithStale enables the staleness watchdog using chain method: after timeout
+// without a message OnStale is called and the stream probed, then reconnected
+// if it stays silent
+func (dd *DiffDepthSubscriptionOptions) WithStale(timeout time.Duration, onStale func(silence time.Duration)) *DiffDepthSubscriptionOptions {
+	dd.staleTimeout = timeout
+	dd.onStale = onStale
+	return dd
+}
+
 // WSSubscription represents an active WebSocket subscription
 type WSSubscription struct {
 	id      string
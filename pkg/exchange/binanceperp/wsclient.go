@@ -6,6 +6,7 @@ import (
 	"fmt"
 	"log"
 	"sync"
+	"time"
 )
 
 // WSClient manages WebSocket connections for different Binance perpetual futures streams
@@ -185,6 +186,12 @@ func (c *WSClient) subscribe(subscriptionID, streamName string, options interfac
 
 // createSubscription creates a new subscription with the appropriate message handler
 func (c *WSClient) createSubscription(subscriptionID, streamName string, options interface{}) *WSSubscription {
+	subscription := &WSSubscription{
+		id:      subscriptionID,
+		options: options,
+		state:   StateConnecting,
+	}
+
 	// Create the underlying subscription for the BinancePerpWSConn
 	lowLevelSubscription := &Subscription{}
 	lowLevelSubscription.
@@ -192,28 +199,35 @@ func (c *WSClient) createSubscription(subscriptionID, streamName string, options
 			c.callOnConnect(options)
 		}).
 		WithReconnect(func() {
+			c.mu.Lock()
+			subscription.state = StateConnected
+			c.mu.Unlock()
 			c.callOnReconnect(options)
 		}).
+		WithGap(func(gap Gap) {
+			c.callOnGap(options, gap)
+		}).
 		WithError(func(err error) {
 			c.callOnError(options, err)
 		}).
 		WithMessage(func(data []byte) {
 			c.handleMessage(subscriptionID, data)
 		}).
+		WithStale(c.staleOptions(options)).
 		WithClose(func() {
+			// The connection also closes itself once reconnecting failed
+			// MaxReconnects times, which ends the subscription
+			c.mu.Lock()
+			if c.subscriptions[subscriptionID] == subscription {
+				delete(c.subscriptions, subscriptionID)
+			}
+			subscription.state = StateDisconnected
+			c.mu.Unlock()
 			c.callOnDisconnect(options)
 		})
 
 	// Create WebSocket connection
-	conn := NewBinancePerpWSConn(c.config, lowLevelSubscription)
-
-	// Create subscription
-	subscription := &WSSubscription{
-		id:      subscriptionID,
-		conn:    conn,
-		options: options,
-		state:   StateConnecting,
-	}
+	subscription.conn = NewBinancePerpWSConn(c.config, lowLevelSubscription)
 
 	return subscription
 }
@@ -413,6 +427,55 @@ func (c *WSClient) callOnReconnect(options interface{}) {
 	}
 }
 
+// staleOptions returns the staleness watchdog settings of any subscription type
+func (c *WSClient) staleOptions(options interface{}) (time.Duration, func(time.Duration)) {
+	switch opts := options.(type) {
+	case *KlineSubscriptionOptions:
+		return opts.staleTimeout, opts.onStale
+	case *AggTradeSubscriptionOptions:
+		return opts.staleTimeout, opts.onStale
+	case *TickerSubscriptionOptions:
+		return opts.staleTimeout, opts.onStale
+	case *LiquidationSubscriptionOptions:
+		return opts.staleTimeout, opts.onStale
+	case *DepthSubscriptionOptions:
+		return opts.staleTimeout, opts.onStale
+	case *DiffDepthSubscriptionOptions:
+		return opts.staleTimeout, opts.onStale
+	}
+	return 0, nil
+}
+
+// callOnGap calls the OnGap callback for any subscription type
+func (c *WSClient) callOnGap(options interface{}, gap Gap) {
+	switch opts := options.(type) {
+	case *KlineSubscriptionOptions:
+		if opts.onGap != nil {
+			opts.onGap(gap)
+		}
+	case *AggTradeSubscriptionOptions:
+		if opts.onGap != nil {
+			opts.onGap(gap)
+		}
+	case *TickerSubscriptionOptions:
+		if opts.onGap != nil {
+			opts.onGap(gap)
+		}
+	case *LiquidationSubscriptionOptions:
+		if opts.onGap != nil {
+			opts.onGap(gap)
+		}
+	case *DepthSubscriptionOptions:
+		if opts.onGap != nil {
+			opts.onGap(gap)
+		}
+	case *DiffDepthSubscriptionOptions:
+		if opts.onGap != nil {
+			opts.onGap(gap)
+		}
+	}
+}
+
 // callOnError calls the OnError callback for any subscription type
 func (c *WSClient) callOnError(options interface{}, err error) {
 	switch opts := options.(type) {
@@ -0,0 +1,152 @@
+// Package ratelimit shares an exchange request weight budget between the
+// processes behind one IP. Exchanges such as Binance limit the weight per IP,
+// so processes limiting themselves independently still get the IP banned.
+package ratelimit
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+)
+
+const (
+	defaultStoreTimeout = 500 * time.Millisecond
+	defaultRetryStore   = 5 * time.Second
+)
+
+// Config configures a Limiter
+type Config struct {
+	Limit         int           // Weight allowed per window across every process of the IP
+	Window        time.Duration // 1 minute when 0
+	Store         Store         // Shared counter, process local when nil
+	FallbackLimit int           // Weight allowed to this process alone while the store is unreachable, a quarter of the limit when 0
+	StoreTimeout  time.Duration // Timeout of each store update, 500ms when 0
+	RetryStore    time.Duration // Time the store is bypassed after a failure, 5s when 0
+}
+
+// Limiter admits requests while the weight used in the current window stays
+// within the budget, and waits for the next window otherwise
+type Limiter struct {
+	cfg      Config
+	fallback *MemoryStore
+	now      func() time.Time
+
+	mu        sync.Mutex
+	downUntil time.Time // Store bypassed until then
+	onError   func(error)
+}
+
+// New creates a limiter
+func New(cfg Config) (*Limiter, error) {
+	if cfg.Limit <= 0 {
+		return nil, fmt.Errorf("limit must be positive")
+	}
+	if cfg.Window == 0 {
+		cfg.Window = time.Minute
+	}
+	if cfg.FallbackLimit == 0 {
+		cfg.FallbackLimit = max(cfg.Limit/4, 1)
+	}
+	if cfg.StoreTimeout == 0 {
+		cfg.StoreTimeout = defaultStoreTimeout
+	}
+	if cfg.RetryStore == 0 {
+		cfg.RetryStore = defaultRetryStore
+	}
+	if cfg.Window < 0 || cfg.FallbackLimit < 0 || cfg.StoreTimeout < 0 || cfg.RetryStore < 0 {
+		return nil, fmt.Errorf("window, fallback limit and timeouts cannot be negative")
+	}
+	l := &Limiter{cfg: cfg, fallback: NewMemoryStore(), now: time.Now}
+	if l.cfg.Store == nil {
+		l.cfg.Store = l.fallback
+		l.cfg.FallbackLimit = cfg.Limit
+	}
+	return l, nil
+}
+
+// OnError sets the callback of store failures, each of which switches the
+// limiter to its local budget for the retry period
+func (l *Limiter) OnError(fn func(error)) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.onError = fn
+}
+
+// Wait blocks until weight fits in the budget of a window and reserves it
+func (l *Limiter) Wait(ctx context.Context, weight int) error {
+	if weight > l.cfg.Limit {
+		return fmt.Errorf("weight %d exceeds the limit %d", weight, l.cfg.Limit)
+	}
+	for {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		now := l.now()
+		window := now.Truncate(l.cfg.Window)
+		reserved := false
+		l.update(ctx, window.UnixMilli(), func(used, limit int) int {
+			reserved = used+weight <= limit
+			if reserved {
+				return used + weight
+			}
+			return used
+		})
+		if reserved {
+			return nil
+		}
+		timer := time.NewTimer(window.Add(l.cfg.Window).Sub(now))
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return ctx.Err()
+		case <-timer.C:
+		}
+	}
+}
+
+// Observe reports the weight the exchange counted for the IP in the current
+// window, e.g. from the X-MBX-USED-WEIGHT-1M header. It accounts for the
+// requests of processes that do not share the store.
+func (l *Limiter) Observe(ctx context.Context, used int) {
+	window := l.now().Truncate(l.cfg.Window).UnixMilli()
+	l.update(ctx, window, func(current, _ int) int {
+		return max(current, used)
+	})
+}
+
+// Used returns the weight used in the current window as far as the limiter
+// knows, and the budget it is held to
+func (l *Limiter) Used(ctx context.Context) (used, limit int) {
+	window := l.now().Truncate(l.cfg.Window).UnixMilli()
+	return l.update(ctx, window, func(current, _ int) int { return current })
+}
+
+// update applies fn to the shared store, or to the local store with the
+// fallback limit while the shared one is unreachable
+func (l *Limiter) update(ctx context.Context, window int64, fn func(used, limit int) int) (int, int) {
+	l.mu.Lock()
+	down := l.now().Before(l.downUntil)
+	l.mu.Unlock()
+
+	if !down {
+		storeCtx, cancel := context.WithTimeout(ctx, l.cfg.StoreTimeout)
+		used, err := l.cfg.Store.Update(storeCtx, window, func(used int) int { return fn(used, l.cfg.Limit) })
+		cancel()
+		if err == nil {
+			return used, l.cfg.Limit
+		}
+		if ctx.Err() == nil {
+			l.mu.Lock()
+			l.downUntil = l.now().Add(l.cfg.RetryStore)
+			onError := l.onError
+			l.mu.Unlock()
+			if onError != nil {
+				onError(err)
+			}
+		}
+	}
+
+	used, _ := l.fallback.Update(ctx, window, func(used int) int { return fn(used, l.cfg.FallbackLimit) })
+	return used, l.cfg.FallbackLimit
+}
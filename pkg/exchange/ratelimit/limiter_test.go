@@ -0,0 +1,114 @@
+package ratelimit
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// failingStore is a coordinator that cannot be reached
+type failingStore struct {
+	calls atomic.Int32
+}
+
+func (s *failingStore) Update(context.Context, int64, func(int) int) (int, error) {
+	s.calls.Add(1)
+	return 0, errors.New("connection refused")
+}
+
+func waitShort(l *Limiter, weight int) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	return l.Wait(ctx, weight)
+}
+
+func TestLimiterSharedBudget(t *testing.T) {
+	store := NewMemoryStore()
+	a, err := New(Config{Limit: 10, Store: store})
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, _ := New(Config{Limit: 10, Store: store})
+
+	if err := waitShort(a, 6); err != nil {
+		t.Fatal(err)
+	}
+	if err := waitShort(b, 4); err != nil {
+		t.Fatal(err)
+	}
+	if err := waitShort(b, 1); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("expected the budget to be exhausted, got %v", err)
+	}
+	if used, limit := a.Used(context.Background()); used != 10 || limit != 10 {
+		t.Errorf("unexpected usage %d/%d", used, limit)
+	}
+	if err := a.Wait(context.Background(), 11); err == nil {
+		t.Error("expected an error for a weight above the limit")
+	}
+}
+
+func TestLimiterNextWindow(t *testing.T) {
+	l, _ := New(Config{Limit: 1, Window: 50 * time.Millisecond})
+	start := time.Now()
+	for i := 0; i < 2; i++ {
+		if err := l.Wait(context.Background(), 1); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Errorf("second request waited %v", elapsed)
+	}
+}
+
+func TestLimiterObserve(t *testing.T) {
+	l, _ := New(Config{Limit: 10})
+	l.Wait(context.Background(), 2)
+	l.Observe(context.Background(), 8)
+	l.Observe(context.Background(), 5) // Stale reports never lower the usage
+	if used, _ := l.Used(context.Background()); used != 8 {
+		t.Errorf("expected 8 used, got %d", used)
+	}
+	if err := waitShort(l, 3); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("expected the budget to be exhausted, got %v", err)
+	}
+}
+
+func TestLimiterFallback(t *testing.T) {
+	store := &failingStore{}
+	l, err := New(Config{Limit: 12, Store: store, RetryStore: time.Hour})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var failures atomic.Int32
+	l.OnError(func(error) { failures.Add(1) })
+
+	if err := waitShort(l, 3); err != nil {
+		t.Fatal(err)
+	}
+	if err := waitShort(l, 1); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("expected the fallback budget of 3 to be exhausted, got %v", err)
+	}
+	if _, limit := l.Used(context.Background()); limit != 3 {
+		t.Errorf("expected the fallback limit, got %d", limit)
+	}
+	if store.calls.Load() != 1 || failures.Load() != 1 {
+		t.Errorf("store should be bypassed after a failure, %d calls, %d failures", store.calls.Load(), failures.Load())
+	}
+
+	// The store is tried again once the retry period is over
+	l.now = func() time.Time { return time.Now().Add(2 * time.Hour) }
+	l.Used(context.Background())
+	if store.calls.Load() != 2 {
+		t.Errorf("store should be retried, %d calls", store.calls.Load())
+	}
+}
+
+func TestNewValidation(t *testing.T) {
+	for _, cfg := range []Config{{}, {Limit: -1}, {Limit: 10, Window: -time.Second}} {
+		if _, err := New(cfg); err == nil {
+			t.Errorf("%+v: expected an error", cfg)
+		}
+	}
+}
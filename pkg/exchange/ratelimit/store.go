@@ -0,0 +1,104 @@
+package ratelimit
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/nats-io/nats.go"
+)
+
+// Store holds the weight used in each window by every process sharing an IP
+type Store interface {
+	// Update replaces the weight used in the window with fn of the current
+	// value, atomically, and returns the stored value. fn may run several
+	// times when other processes update the window concurrently.
+	Update(ctx context.Context, window int64, fn func(used int) int) (int, error)
+}
+
+// MemoryStore is a Store local to the process
+type MemoryStore struct {
+	mu     sync.Mutex
+	window int64
+	used   int
+}
+
+// NewMemoryStore creates a process local store
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{}
+}
+
+// Update implements Store
+func (s *MemoryStore) Update(_ context.Context, window int64, fn func(used int) int) (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if window != s.window {
+		s.window = window
+		s.used = 0
+	}
+	s.used = fn(s.used)
+	return s.used, nil
+}
+
+// maxCASAttempts bounds the compare-and-set retries of a contended window
+const maxCASAttempts = 16
+
+// NATSStore is a Store in a NATS JetStream key-value bucket, one key per
+// window under the configured prefix
+type NATSStore struct {
+	kv     nats.KeyValue
+	prefix string
+}
+
+// NewNATSStore opens the bucket, creating it when missing. Keys expire after
+// ttl, which must outlast the window of the limiters using the store.
+func NewNATSStore(js nats.JetStreamContext, bucket, prefix string, ttl time.Duration) (*NATSStore, error) {
+	if bucket == "" || prefix == "" {
+		return nil, fmt.Errorf("bucket and prefix cannot be empty")
+	}
+	kv, err := js.KeyValue(bucket)
+	if errors.Is(err, nats.ErrBucketNotFound) {
+		kv, err = js.CreateKeyValue(&nats.KeyValueConfig{Bucket: bucket, TTL: ttl, History: 1})
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to open bucket %s: %w", bucket, err)
+	}
+	return &NATSStore{kv: kv, prefix: prefix}, nil
+}
+
+// Update implements Store with compare-and-set on the key of the window
+func (s *NATSStore) Update(ctx context.Context, window int64, fn func(used int) int) (int, error) {
+	key := s.prefix + "." + strconv.FormatInt(window, 10)
+	var err error
+	for attempt := 0; attempt < maxCASAttempts; attempt++ {
+		if err := ctx.Err(); err != nil {
+			return 0, err
+		}
+		entry, getErr := s.kv.Get(key)
+		switch {
+		case errors.Is(getErr, nats.ErrKeyNotFound):
+			used := fn(0)
+			if _, err = s.kv.Create(key, []byte(strconv.Itoa(used))); err == nil {
+				return used, nil
+			}
+		case getErr != nil:
+			return 0, getErr
+		default:
+			current, convErr := strconv.Atoi(string(entry.Value()))
+			if convErr != nil {
+				return 0, fmt.Errorf("invalid weight in %s: %w", key, convErr)
+			}
+			used := fn(current)
+			if used == current {
+				return used, nil
+			}
+			if _, err = s.kv.Update(key, []byte(strconv.Itoa(used)), entry.Revision()); err == nil {
+				return used, nil
+			}
+		}
+	}
+	return 0, fmt.Errorf("failed to update %s: %w", key, err)
+}
@@ -0,0 +1,196 @@
+package transport
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"math/rand"
+	"net"
+	"net/http"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+// Chaos injects faults into the traffic of exchange clients, for resilience
+// testing of the feed, order management and risk in staging. It is refused
+// unless the binary is built with the chaos tag, so a configuration copied
+// to production cannot enable it. Rates are shares from 0 to 1.
+type Chaos struct {
+	RateLimitRate   float64 `json:"rate_limit_rate,omitempty"`   // HTTP requests answered 429 without reaching the exchange
+	ServerErrorRate float64 `json:"server_error_rate,omitempty"` // HTTP requests answered 500, 502 or 503 without reaching the exchange
+	MalformedRate   float64 `json:"malformed_rate,omitempty"`    // HTTP bodies and websocket messages truncated
+	DisconnectRate  float64 `json:"disconnect_rate,omitempty"`   // Websocket reads failing as the connection drops
+	Seed            int64   `json:"seed,omitempty"`              // Of the faults drawn, from the time when 0
+}
+
+// Enabled reports whether the chaos injects any fault
+func (c Chaos) Enabled() bool {
+	return c.RateLimitRate > 0 || c.ServerErrorRate > 0 || c.MalformedRate > 0 || c.DisconnectRate > 0
+}
+
+// Validate checks the rates, and that the binary is built for chaos when
+// enabled
+func (c Chaos) Validate() error {
+	for _, rate := range []float64{c.RateLimitRate, c.ServerErrorRate, c.MalformedRate, c.DisconnectRate} {
+		if rate < 0 || rate > 1 {
+			return fmt.Errorf("chaos rates must be between 0 and 1")
+		}
+	}
+	if c.RateLimitRate+c.ServerErrorRate > 1 {
+		return fmt.Errorf("chaos rate_limit_rate and server_error_rate cannot exceed 1 together")
+	}
+	if c.Enabled() && !chaosBuild {
+		return fmt.Errorf("chaos needs a binary built with -tags chaos")
+	}
+	return nil
+}
+
+// injector draws the faults of a chaos configuration
+type injector struct {
+	chaos Chaos
+	mu    sync.Mutex
+	rand  *rand.Rand
+}
+
+func newInjector(c Chaos) *injector {
+	seed := c.Seed
+	if seed == 0 {
+		seed = time.Now().UnixNano()
+	}
+	return &injector{chaos: c, rand: rand.New(rand.NewSource(seed))}
+}
+
+var (
+	injectors sync.Map // Chaos -> *injector
+	process   atomic.Pointer[Chaos]
+)
+
+// EnableChaos injects the faults of c into every exchange client of the
+// process whose options set no chaos of their own, including those created
+// before, such as the trade adapters of the feed
+func EnableChaos(c Chaos) error {
+	if err := c.Validate(); err != nil {
+		return err
+	}
+	process.Store(&c)
+	return nil
+}
+
+// chaos returns the chaos of the options, that of the process when unset
+func (o Options) chaos() Chaos {
+	if o.Chaos.Enabled() {
+		return o.Chaos
+	}
+	if c := process.Load(); c != nil {
+		return *c
+	}
+	return Chaos{}
+}
+
+// injectorFor returns the injector of the options, shared by the clients with
+// the same chaos configuration, nil when disabled
+func (o Options) injectorFor() *injector {
+	c := o.chaos()
+	if !c.Enabled() {
+		return nil
+	}
+	if i, ok := injectors.Load(c); ok {
+		return i.(*injector)
+	}
+	i, _ := injectors.LoadOrStore(c, newInjector(c))
+	return i.(*injector)
+}
+
+func (i *injector) float() float64 {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+	return i.rand.Float64()
+}
+
+func (i *injector) intn(n int) int {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+	return i.rand.Intn(n)
+}
+
+func (i *injector) malformed(data []byte) []byte {
+	if i.chaos.MalformedRate == 0 || len(data) == 0 || i.float() >= i.chaos.MalformedRate {
+		return data
+	}
+	return data[:i.intn(len(data))]
+}
+
+// chaosTransport answers requests with the faults of an injector
+type chaosTransport struct {
+	next     http.RoundTripper
+	injector *injector
+}
+
+var serverErrors = []int{http.StatusInternalServerError, http.StatusBadGateway, http.StatusServiceUnavailable}
+
+func (t *chaosTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	c := t.injector.chaos
+	switch draw := t.injector.float(); {
+	case draw < c.RateLimitRate:
+		header := http.Header{"Retry-After": {"1"}}
+		return fakeResponse(req, http.StatusTooManyRequests, header, `{"code":-1003,"msg":"chaos: too many requests"}`), nil
+	case draw < c.RateLimitRate+c.ServerErrorRate:
+		status := serverErrors[t.injector.intn(len(serverErrors))]
+		return fakeResponse(req, status, nil, "chaos: "+http.StatusText(status)), nil
+	}
+	resp, err := t.next.RoundTrip(req)
+	if err != nil || c.MalformedRate == 0 {
+		return resp, err
+	}
+	body, err := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if err != nil {
+		return nil, err
+	}
+	body = t.injector.malformed(body)
+	resp.Body = io.NopCloser(bytes.NewReader(body))
+	resp.ContentLength = int64(len(body))
+	resp.Header.Set("Content-Length", strconv.Itoa(len(body)))
+	return resp, nil
+}
+
+func fakeResponse(req *http.Request, status int, header http.Header, body string) *http.Response {
+	if header == nil {
+		header = http.Header{}
+	}
+	if req.Body != nil {
+		req.Body.Close()
+	}
+	return &http.Response{
+		Status:        fmt.Sprintf("%d %s", status, http.StatusText(status)),
+		StatusCode:    status,
+		Proto:         "HTTP/1.1",
+		ProtoMajor:    1,
+		ProtoMinor:    1,
+		Header:        header,
+		Body:          io.NopCloser(bytes.NewBufferString(body)),
+		ContentLength: int64(len(body)),
+		Request:       req,
+	}
+}
+
+// ReadMessage reads the next message of a websocket connection of a client,
+// through the chaos of the options when enabled: the message may be
+// truncated, or the connection dropped, failing the read as a network
+// disconnect does.
+func (o Options) ReadMessage(conn *websocket.Conn) (int, []byte, error) {
+	messageType, data, err := conn.ReadMessage()
+	i := o.injectorFor()
+	if err != nil || i == nil {
+		return messageType, data, err
+	}
+	if rate := i.chaos.DisconnectRate; rate > 0 && i.float() < rate {
+		conn.Close()
+		return messageType, nil, fmt.Errorf("chaos: %w", net.ErrClosed)
+	}
+	return messageType, i.malformed(data), nil
+}
@@ -0,0 +1,6 @@
+//go:build !chaos
+
+package transport
+
+// chaosBuild refuses Chaos outside of binaries built with -tags chaos
+const chaosBuild = false
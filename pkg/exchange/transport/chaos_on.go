@@ -0,0 +1,6 @@
+//go:build chaos
+
+package transport
+
+// chaosBuild allows Chaos, built with -tags chaos
+const chaosBuild = true
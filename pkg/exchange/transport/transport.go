@@ -0,0 +1,178 @@
+// Package transport configures how exchange clients reach the network:
+// HTTP and SOCKS5 proxies, dial timeouts, the local address and the DNS
+// server, shared by the REST and websocket clients of every exchange.
+package transport
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"sync"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+const (
+	defaultDialTimeout = 30 * time.Second
+	defaultKeepAlive   = 30 * time.Second
+)
+
+// Options configures the network path of a client. The zero value connects
+// directly, or through the proxy of the HTTP_PROXY, HTTPS_PROXY and NO_PROXY
+// environment variables, as the standard library does.
+type Options struct {
+	Proxy       string        `json:"proxy,omitempty"`        // http://, https:// or socks5:// URL, credentials as user:password@
+	DialTimeout time.Duration `json:"dial_timeout,omitempty"` // TCP connect timeout, 30s when 0
+	KeepAlive   time.Duration `json:"keep_alive,omitempty"`   // TCP keep-alive period, 30s when 0
+	LocalAddr   string        `json:"local_addr,omitempty"`   // Local IP to dial from, for hosts with several egress addresses
+	DNSServer   string        `json:"dns_server,omitempty"`   // host:port of the DNS server used instead of the system resolver
+	Timeout     time.Duration `json:"timeout,omitempty"`      // Whole HTTP request timeout, none when 0
+	Chaos       Chaos         `json:"chaos,omitempty"`        // Faults injected for resilience testing, none by default
+}
+
+// IsZero reports whether the options keep the default network path
+func (o Options) IsZero() bool {
+	return o == Options{}
+}
+
+// Validate checks the options
+func (o Options) Validate() error {
+	if _, err := o.proxyURL(); err != nil {
+		return err
+	}
+	if o.LocalAddr != "" && net.ParseIP(o.LocalAddr) == nil {
+		return fmt.Errorf("invalid local_addr %q", o.LocalAddr)
+	}
+	if o.DNSServer != "" {
+		if _, _, err := net.SplitHostPort(o.DNSServer); err != nil {
+			return fmt.Errorf("invalid dns_server %q: %w", o.DNSServer, err)
+		}
+	}
+	if o.DialTimeout < 0 || o.KeepAlive < 0 || o.Timeout < 0 {
+		return fmt.Errorf("timeouts cannot be negative")
+	}
+	return o.Chaos.Validate()
+}
+
+func (o Options) proxyURL() (*url.URL, error) {
+	if o.Proxy == "" {
+		return nil, nil
+	}
+	u, err := url.Parse(o.Proxy)
+	if err != nil {
+		return nil, fmt.Errorf("invalid proxy: %w", err)
+	}
+	switch u.Scheme {
+	case "http", "https", "socks5":
+	default:
+		return nil, fmt.Errorf("unsupported proxy scheme %q", u.Scheme)
+	}
+	if u.Host == "" {
+		return nil, fmt.Errorf("proxy %q has no host", o.Proxy)
+	}
+	return u, nil
+}
+
+// proxy returns the proxy function of the options
+func (o Options) proxy() (func(*http.Request) (*url.URL, error), error) {
+	u, err := o.proxyURL()
+	if err != nil {
+		return nil, err
+	}
+	if u == nil {
+		return http.ProxyFromEnvironment, nil
+	}
+	return http.ProxyURL(u), nil
+}
+
+// Dialer returns the TCP dialer of the options
+func (o Options) Dialer() (*net.Dialer, error) {
+	if err := o.Validate(); err != nil {
+		return nil, err
+	}
+	d := &net.Dialer{Timeout: o.DialTimeout, KeepAlive: o.KeepAlive}
+	if d.Timeout == 0 {
+		d.Timeout = defaultDialTimeout
+	}
+	if d.KeepAlive == 0 {
+		d.KeepAlive = defaultKeepAlive
+	}
+	if o.LocalAddr != "" {
+		d.LocalAddr = &net.TCPAddr{IP: net.ParseIP(o.LocalAddr)}
+	}
+	if o.DNSServer != "" {
+		server := o.DNSServer
+		d.Resolver = &net.Resolver{
+			PreferGo: true,
+			Dial: func(ctx context.Context, network, _ string) (net.Conn, error) {
+				var dns net.Dialer
+				return dns.DialContext(ctx, network, server)
+			},
+		}
+	}
+	return d, nil
+}
+
+// HTTPClient returns an HTTP client following the options. Build it once and
+// reuse it, since every client holds its own connection pool.
+func (o Options) HTTPClient() (*http.Client, error) {
+	o.Chaos = o.chaos()
+	if o.IsZero() {
+		return http.DefaultClient, nil
+	}
+	d, err := o.Dialer()
+	if err != nil {
+		return nil, err
+	}
+	proxy, err := o.proxy()
+	if err != nil {
+		return nil, err
+	}
+	t := http.DefaultTransport.(*http.Transport).Clone()
+	t.Proxy = proxy
+	t.DialContext = d.DialContext
+	if i := o.injectorFor(); i != nil {
+		return &http.Client{Transport: &chaosTransport{next: t, injector: i}, Timeout: o.Timeout}, nil
+	}
+	return &http.Client{Transport: t, Timeout: o.Timeout}, nil
+}
+
+var shared sync.Map // Options -> *http.Client
+
+// SharedHTTPClient returns the HTTP client of the options, built on first use
+// and shared by every caller with the same options
+func (o Options) SharedHTTPClient() (*http.Client, error) {
+	o.Chaos = o.chaos()
+	if c, ok := shared.Load(o); ok {
+		return c.(*http.Client), nil
+	}
+	c, err := o.HTTPClient()
+	if err != nil {
+		return nil, err
+	}
+	actual, _ := shared.LoadOrStore(o, c)
+	return actual.(*http.Client), nil
+}
+
+// WSDialer returns a websocket dialer following the options
+func (o Options) WSDialer() (*websocket.Dialer, error) {
+	if o.IsZero() {
+		return websocket.DefaultDialer, nil
+	}
+	d, err := o.Dialer()
+	if err != nil {
+		return nil, err
+	}
+	proxy, err := o.proxy()
+	if err != nil {
+		return nil, err
+	}
+	return &websocket.Dialer{
+		Proxy:            proxy,
+		NetDialContext:   d.DialContext,
+		HandshakeTimeout: websocket.DefaultDialer.HandshakeTimeout,
+	}, nil
+}
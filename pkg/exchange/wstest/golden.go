@@ -0,0 +1,102 @@
+package wstest
+
+import (
+	"bytes"
+	"encoding/json"
+	"flag"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+var update = flag.Bool("update", false, "rewrite the golden files of the exchange message tests")
+
+// Decoder decodes the recorded payload of a name the way the client under
+// test does, returning the event it hands to the callbacks
+type Decoder func(t *testing.T, name string, payload []byte) any
+
+// Golden decodes every payload testdata/ws/<name>.json of the package and
+// compares the events to testdata/ws/<name>.golden, which holds their type
+// and lists their fields by Go name. A json tag mapped to the wrong key, a
+// renamed field or a message dispatched to the wrong handler then fails the
+// test, where a round trip through the same tags would not. Run the tests
+// with -update to rewrite the golden files after a deliberate change, and
+// review their diff.
+func Golden(t *testing.T, decode Decoder) {
+	t.Helper()
+	payloads, err := filepath.Glob(filepath.Join("testdata", "ws", "*.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(payloads) == 0 {
+		t.Fatal("no payload in testdata/ws")
+	}
+	for _, path := range payloads {
+		name := strings.TrimSuffix(filepath.Base(path), ".json")
+		t.Run(name, func(t *testing.T) {
+			payload, err := os.ReadFile(path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			event := decode(t, name, bytes.TrimSpace(payload))
+			if event == nil {
+				t.Fatalf("%s decoded to no event", path)
+			}
+			decoded := map[string]any{"type": reflect.TypeOf(event).String(), "event": fields(reflect.ValueOf(event))}
+			got, err := json.MarshalIndent(decoded, "", "  ")
+			if err != nil {
+				t.Fatal(err)
+			}
+			got = append(got, '\n')
+			golden := strings.TrimSuffix(path, ".json") + ".golden"
+			if *update {
+				if err := os.WriteFile(golden, got, 0o644); err != nil {
+					t.Fatal(err)
+				}
+				return
+			}
+			want, err := os.ReadFile(golden)
+			if err != nil {
+				t.Fatalf("%v, run with -update to create it", err)
+			}
+			if !bytes.Equal(got, want) {
+				t.Errorf("%s decoded as\n%s\nwant\n%s", path, got, want)
+			}
+		})
+	}
+}
+
+// fields returns the value with structs as maps keyed by field name, so its
+// JSON encoding ignores the json tags
+func fields(v reflect.Value) any {
+	switch v.Kind() {
+	case reflect.Invalid:
+		return nil
+	case reflect.Pointer, reflect.Interface:
+		if v.IsNil() {
+			return nil
+		}
+		return fields(v.Elem())
+	case reflect.Struct:
+		m := make(map[string]any, v.NumField())
+		for i := 0; i < v.NumField(); i++ {
+			if f := v.Type().Field(i); f.IsExported() {
+				m[f.Name] = fields(v.Field(i))
+			}
+		}
+		return m
+	case reflect.Slice, reflect.Array:
+		if v.Kind() == reflect.Slice && v.IsNil() {
+			return nil
+		}
+		items := make([]any, v.Len())
+		for i := range items {
+			items[i] = fields(v.Index(i))
+		}
+		return items
+	default:
+		return v.Interface()
+	}
+}
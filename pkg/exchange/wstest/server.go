@@ -0,0 +1,170 @@
+// Package wstest provides a mock websocket server and a conformance suite
+// that runs the same lifecycle assertions against every exchange websocket
+// client, so new clients behave like the existing ones.
+package wstest
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+// Server is a mock exchange websocket server. Connections are keyed by their
+// request path, which selects the stream on raw stream endpoints such as
+// /ws/btcusdt@trade.
+type Server struct {
+	srv      *httptest.Server
+	upgrader websocket.Upgrader
+
+	mu       sync.Mutex
+	conns    map[*serverConn]struct{}
+	accepted map[string]int
+	received []string
+
+	connected    chan string
+	disconnected chan string
+}
+
+type serverConn struct {
+	path string
+	mu   sync.Mutex // Serializes writes
+	conn *websocket.Conn
+}
+
+// NewServer starts a mock server. Close it when done.
+func NewServer() *Server {
+	s := &Server{
+		conns:        make(map[*serverConn]struct{}),
+		accepted:     make(map[string]int),
+		connected:    make(chan string, 64),
+		disconnected: make(chan string, 64),
+	}
+	s.srv = httptest.NewServer(http.HandlerFunc(s.handle))
+	return s
+}
+
+// URL returns the ws:// base URL of the server
+func (s *Server) URL() string {
+	return "ws" + strings.TrimPrefix(s.srv.URL, "http")
+}
+
+// Close drops every connection and stops the server
+func (s *Server) Close() {
+	s.Drop()
+	s.srv.Close()
+}
+
+func (s *Server) handle(w http.ResponseWriter, r *http.Request) {
+	conn, err := s.upgrader.Upgrade(w, r, nil)
+	if err != nil {
+		return
+	}
+	c := &serverConn{path: r.URL.Path, conn: conn}
+	s.mu.Lock()
+	s.conns[c] = struct{}{}
+	s.accepted[c.path]++
+	s.mu.Unlock()
+	s.connected <- c.path
+
+	// Read until the client goes away so closes are observed
+	for {
+		_, message, err := conn.ReadMessage()
+		if err != nil {
+			break
+		}
+		s.mu.Lock()
+		s.received = append(s.received, string(message))
+		s.mu.Unlock()
+	}
+	conn.Close()
+	s.mu.Lock()
+	delete(s.conns, c)
+	s.mu.Unlock()
+	s.disconnected <- c.path
+}
+
+// Send writes a text message to every open connection on path and returns
+// how many connections it reached
+func (s *Server) Send(path string, data []byte) int {
+	s.mu.Lock()
+	var targets []*serverConn
+	for c := range s.conns {
+		if c.path == path {
+			targets = append(targets, c)
+		}
+	}
+	s.mu.Unlock()
+
+	sent := 0
+	for _, c := range targets {
+		c.mu.Lock()
+		err := c.conn.WriteMessage(websocket.TextMessage, data)
+		c.mu.Unlock()
+		if err == nil {
+			sent++
+		}
+	}
+	return sent
+}
+
+// Drop closes every open connection without a close frame, as a network
+// failure would
+func (s *Server) Drop() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for c := range s.conns {
+		c.conn.Close()
+	}
+}
+
+// Open returns the number of open connections on path
+func (s *Server) Open(path string) int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	n := 0
+	for c := range s.conns {
+		if c.path == path {
+			n++
+		}
+	}
+	return n
+}
+
+// Accepted returns the number of connections ever accepted on path
+func (s *Server) Accepted(path string) int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.accepted[path]
+}
+
+// Received returns the messages clients sent, in order
+func (s *Server) Received() []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]string(nil), s.received...)
+}
+
+// WaitConnected waits for the next connection and returns its path
+func (s *Server) WaitConnected(timeout time.Duration) (string, error) {
+	select {
+	case path := <-s.connected:
+		return path, nil
+	case <-time.After(timeout):
+		return "", fmt.Errorf("no connection within %v", timeout)
+	}
+}
+
+// WaitDisconnected waits for the next connection to close and returns its path
+func (s *Server) WaitDisconnected(timeout time.Duration) (string, error) {
+	select {
+	case path := <-s.disconnected:
+		return path, nil
+	case <-time.After(timeout):
+		return "", fmt.Errorf("no disconnection within %v", timeout)
+	}
+}
@@ -0,0 +1,53 @@
+package wstest
+
+import (
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+func TestServer(t *testing.T) {
+	server := NewServer()
+	defer server.Close()
+
+	conn, _, err := websocket.DefaultDialer.Dial(server.URL()+"/ws/btcusdt@trade", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	if path, err := server.WaitConnected(time.Second); err != nil || path != "/ws/btcusdt@trade" {
+		t.Fatalf("unexpected connection %q, %v", path, err)
+	}
+
+	if err := conn.WriteMessage(websocket.TextMessage, []byte(`{"method":"SUBSCRIBE"}`)); err != nil {
+		t.Fatal(err)
+	}
+	if n := server.Send("/ws/btcusdt@trade", []byte("hello")); n != 1 {
+		t.Fatalf("sent to %d connections, want 1", n)
+	}
+	if n := server.Send("/ws/ethusdt@trade", []byte("hello")); n != 0 {
+		t.Fatalf("sent to %d connections on another path", n)
+	}
+	if _, data, err := conn.ReadMessage(); err != nil || string(data) != "hello" {
+		t.Fatalf("unexpected message %q, %v", data, err)
+	}
+	deadline := time.Now().Add(time.Second)
+	for len(server.Received()) == 0 && time.Now().Before(deadline) {
+		time.Sleep(10 * time.Millisecond)
+	}
+	if got := server.Received(); len(got) != 1 || got[0] != `{"method":"SUBSCRIBE"}` {
+		t.Errorf("unexpected received messages %v", got)
+	}
+
+	server.Drop()
+	if _, err := server.WaitDisconnected(time.Second); err != nil {
+		t.Fatal(err)
+	}
+	if _, _, err := conn.ReadMessage(); err == nil {
+		t.Error("expected the dropped connection to fail")
+	}
+	if server.Open("/ws/btcusdt@trade") != 0 || server.Accepted("/ws/btcusdt@trade") != 1 {
+		t.Error("unexpected connection counts")
+	}
+}
@@ -0,0 +1,275 @@
+package wstest
+
+import (
+	"sync"
+	"testing"
+	"time"
+)
+
+// Handlers are the lifecycle callbacks the suite observes. Adapters wire
+// them to the subscription options of their client.
+type Handlers struct {
+	OnConnect    func()
+	OnReconnect  func()
+	OnDisconnect func()
+	OnError      func(error)
+	OnData       func(any)                // Deserialized event
+	OnGap        func(from, to time.Time) // Window without data after a reconnection
+	OnStale      func()                   // Stream silent for StaleTimeout
+
+	StaleTimeout time.Duration // Silence before the stream is probed and reconnected, disabled when 0
+}
+
+// Client adapts an exchange websocket client to the suite. Adapters live in
+// the exchange package tests and subscribe to one stream type.
+type Client interface {
+	Subscribe(symbol string, h Handlers) (unsubscribe func(), err error)
+	Close()
+}
+
+// Case configures the suite for one client and stream
+type Case struct {
+	NewClient func(url string) Client      // Creates a client against the mock server base URL
+	Symbol    string                       // Symbol subscribed to
+	Path      string                       // Request path the subscription connects to, e.g. /ws/btcusdt@trade
+	Message   []byte                       // Raw message the server sends on the stream
+	Check     func(t *testing.T, data any) // Verifies the deserialized event
+	Timeout   time.Duration                // Wait for an expected callback or connection, 2s when 0
+
+	ReconnectTimeout time.Duration // Wait for a reconnection after a drop, 10s when 0
+}
+
+// recorder counts the callbacks of a subscription
+type recorder struct {
+	mu          sync.Mutex
+	connects    int
+	reconnects  int
+	disconnects int
+	errors      []error
+	data        chan any
+	gaps        chan [2]time.Time
+	stale       chan struct{}
+	reconnected chan struct{}
+	closed      chan struct{}
+}
+
+func newRecorder() *recorder {
+	return &recorder{data: make(chan any, 64), gaps: make(chan [2]time.Time, 8), stale: make(chan struct{}, 8), reconnected: make(chan struct{}, 8), closed: make(chan struct{}, 8)}
+}
+
+func (r *recorder) handlers() Handlers {
+	return Handlers{
+		OnConnect: func() {
+			r.mu.Lock()
+			r.connects++
+			r.mu.Unlock()
+		},
+		OnReconnect: func() {
+			r.mu.Lock()
+			r.reconnects++
+			r.mu.Unlock()
+			r.reconnected <- struct{}{}
+		},
+		OnDisconnect: func() {
+			r.mu.Lock()
+			r.disconnects++
+			r.mu.Unlock()
+			r.closed <- struct{}{}
+		},
+		OnError: func(err error) {
+			r.mu.Lock()
+			r.errors = append(r.errors, err)
+			r.mu.Unlock()
+		},
+		OnData: func(v any) {
+			r.data <- v
+		},
+		OnGap: func(from, to time.Time) {
+			r.gaps <- [2]time.Time{from, to}
+		},
+		OnStale: func() {
+			select {
+			case r.stale <- struct{}{}:
+			default:
+			}
+		},
+	}
+}
+
+func (r *recorder) counts() (connects, reconnects, disconnects int) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.connects, r.reconnects, r.disconnects
+}
+
+// Run runs the conformance suite against a fresh mock server per subtest
+func Run(t *testing.T, c Case) {
+	if c.Timeout == 0 {
+		c.Timeout = 2 * time.Second
+	}
+	if c.ReconnectTimeout == 0 {
+		c.ReconnectTimeout = 10 * time.Second
+	}
+
+	// setup subscribes once and waits for the server to see the connection
+	setup := func(t *testing.T, staleTimeout time.Duration) (*Server, Client, *recorder, func()) {
+		t.Helper()
+		server := NewServer()
+		client := c.NewClient(server.URL())
+		t.Cleanup(func() {
+			client.Close()
+			server.Close()
+		})
+		rec := newRecorder()
+		h := rec.handlers()
+		h.StaleTimeout = staleTimeout
+		unsubscribe, err := client.Subscribe(c.Symbol, h)
+		if err != nil {
+			t.Fatalf("subscribe: %v", err)
+		}
+		path, err := server.WaitConnected(c.Timeout)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if path != c.Path {
+			t.Fatalf("connected to %s, want %s", path, c.Path)
+		}
+		return server, client, rec, unsubscribe
+	}
+
+	// receive sends the case message and waits for its event
+	receive := func(t *testing.T, server *Server, rec *recorder) {
+		t.Helper()
+		if server.Send(c.Path, c.Message) == 0 {
+			t.Fatalf("no open connection on %s", c.Path)
+		}
+		select {
+		case v := <-rec.data:
+			c.Check(t, v)
+		case <-time.After(c.Timeout):
+			t.Fatal("message was not delivered")
+		}
+	}
+
+	t.Run("connects once", func(t *testing.T) {
+		server, _, rec, _ := setup(t, 0)
+		time.Sleep(100 * time.Millisecond) // Leave room for a stray second dial
+		if n := server.Accepted(c.Path); n != 1 {
+			t.Errorf("accepted %d connections, want 1", n)
+		}
+		if connects, _, _ := rec.counts(); connects != 1 {
+			t.Errorf("OnConnect called %d times, want 1", connects)
+		}
+	})
+
+	t.Run("data deserializes", func(t *testing.T) {
+		server, _, rec, _ := setup(t, 0)
+		receive(t, server, rec)
+		rec.mu.Lock()
+		defer rec.mu.Unlock()
+		if len(rec.errors) > 0 {
+			t.Errorf("unexpected errors %v", rec.errors)
+		}
+	})
+
+	t.Run("duplicate subscribe rejected", func(t *testing.T) {
+		server, client, _, _ := setup(t, 0)
+		if _, err := client.Subscribe(c.Symbol, newRecorder().handlers()); err == nil {
+			t.Error("duplicate subscribe succeeded")
+		}
+		time.Sleep(100 * time.Millisecond)
+		if n := server.Accepted(c.Path); n != 1 {
+			t.Errorf("accepted %d connections, want 1", n)
+		}
+	})
+
+	t.Run("unsubscribe fires disconnect", func(t *testing.T) {
+		server, client, rec, unsubscribe := setup(t, 0)
+		unsubscribe()
+		select {
+		case <-rec.closed:
+		case <-time.After(c.Timeout):
+			t.Fatal("OnDisconnect was not called")
+		}
+		if _, err := server.WaitDisconnected(c.Timeout); err != nil {
+			t.Fatal(err)
+		}
+		// The stream can be subscribed again once unsubscribed
+		if _, err := client.Subscribe(c.Symbol, newRecorder().handlers()); err != nil {
+			t.Errorf("resubscribe after unsubscribe: %v", err)
+		}
+	})
+
+	t.Run("close disconnects", func(t *testing.T) {
+		server, client, _, _ := setup(t, 0)
+		done := make(chan struct{})
+		go func() {
+			client.Close()
+			close(done)
+		}()
+		select {
+		case <-done:
+		case <-time.After(c.Timeout):
+			t.Fatal("Close did not return")
+		}
+		if _, err := server.WaitDisconnected(c.Timeout); err != nil {
+			t.Fatal(err)
+		}
+	})
+
+	t.Run("reconnects on drop", func(t *testing.T) {
+		server, _, rec, _ := setup(t, 0)
+		receive(t, server, rec)
+		dropped := time.Now()
+		server.Drop()
+		if _, err := server.WaitDisconnected(c.Timeout); err != nil {
+			t.Fatal(err)
+		}
+		path, err := server.WaitConnected(c.ReconnectTimeout)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if path != c.Path {
+			t.Fatalf("reconnected to %s, want %s", path, c.Path)
+		}
+		select {
+		case <-rec.reconnected:
+		case <-time.After(c.Timeout):
+			t.Fatal("OnReconnect was not called")
+		}
+		select {
+		case gap := <-rec.gaps:
+			if gap[0].After(dropped) || gap[1].Before(dropped) {
+				t.Errorf("gap %v - %v does not cover the drop at %v", gap[0], gap[1], dropped)
+			}
+		case <-time.After(c.Timeout):
+			t.Fatal("OnGap was not called")
+		}
+		receive(t, server, rec)
+		if _, _, disconnects := rec.counts(); disconnects != 0 {
+			t.Errorf("OnDisconnect called %d times on a drop, want 0", disconnects)
+		}
+	})
+	t.Run("reconnects when stale", func(t *testing.T) {
+		const staleTimeout = 200 * time.Millisecond
+		server, _, rec, _ := setup(t, staleTimeout)
+		// The server keeps the connection open but sends nothing
+		select {
+		case <-rec.stale:
+		case <-time.After(staleTimeout + c.Timeout):
+			t.Fatal("OnStale was not called")
+		}
+		if _, err := server.WaitDisconnected(staleTimeout + c.Timeout); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := server.WaitConnected(c.ReconnectTimeout); err != nil {
+			t.Fatal(err)
+		}
+		select {
+		case <-rec.reconnected:
+		case <-time.After(c.Timeout):
+			t.Fatal("OnReconnect was not called")
+		}
+		receive(t, server, rec)
+	})
+}
@@ -0,0 +1,225 @@
+// Package featureflag gates node behaviors being rolled out, e.g. a new
+// execution algorithm or risk check. Flags are configured per service and
+// overridden at runtime through a NATS key-value bucket, each change kept in
+// an audit trail. A flag targets nodes and symbols so a behavior can be
+// enabled for one node or a few symbols before everywhere.
+package featureflag
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// auditSize is the number of changes a Set keeps
+const auditSize = 100
+
+var namePattern = regexp.MustCompile(`^[a-z0-9_-]+(\.[a-z0-9_-]+)*$`)
+
+// Flag gates a behavior. It is on for a node and symbol when enabled and
+// both are targeted.
+type Flag struct {
+	Name        string   `json:"name"` // Lower case tokens separated by dots, e.g. execution.twap_v2
+	Description string   `json:"description,omitempty"`
+	Enabled     bool     `json:"enabled"`
+	Nodes       []string `json:"nodes,omitempty"`   // Nodes the flag is on for, all of them when empty
+	Symbols     []string `json:"symbols,omitempty"` // Symbols the flag is on for, all of them when empty
+}
+
+// Validate validates the flag
+func (f Flag) Validate() error {
+	if !namePattern.MatchString(f.Name) {
+		return fmt.Errorf("invalid flag name %q, lower case tokens separated by dots expected", f.Name)
+	}
+	for _, node := range f.Nodes {
+		if node == "" {
+			return fmt.Errorf("%s: nodes cannot be empty", f.Name)
+		}
+	}
+	for _, symbol := range f.Symbols {
+		if symbol == "" {
+			return fmt.Errorf("%s: symbols cannot be empty", f.Name)
+		}
+	}
+	return nil
+}
+
+// On reports whether the flag is on for node and symbol. A flag targeting
+// symbols is off for behaviors that are not per symbol, symbol being empty.
+// Symbols match regardless of case and separators, BTC-USDT being btcusdt.
+func (f Flag) On(node, symbol string) bool {
+	if !f.Enabled {
+		return false
+	}
+	if len(f.Nodes) > 0 && !contains(f.Nodes, node, func(s string) string { return s }) {
+		return false
+	}
+	if len(f.Symbols) > 0 && (symbol == "" || !contains(f.Symbols, symbol, normalizeSymbol)) {
+		return false
+	}
+	return true
+}
+
+func contains(items []string, item string, normalize func(string) string) bool {
+	item = normalize(item)
+	for _, i := range items {
+		if normalize(i) == item {
+			return true
+		}
+	}
+	return false
+}
+
+func normalizeSymbol(symbol string) string {
+	return strings.ToLower(strings.NewReplacer("-", "", "_", "", "/", "").Replace(symbol))
+}
+
+// Override replaces the configured value of a flag at runtime
+type Override struct {
+	Flag   *Flag     `json:"flag,omitempty"` // Nil resets the flag to its configured value
+	By     string    `json:"by"`             // Who changed the flag
+	Reason string    `json:"reason,omitempty"`
+	At     time.Time `json:"at"`
+}
+
+// Change is an entry of the audit trail
+type Change struct {
+	Flag     string    `json:"flag"`
+	Before   *Flag     `json:"before,omitempty"` // Nil when the flag was undefined
+	After    *Flag     `json:"after,omitempty"`  // Nil when the flag is undefined
+	Source   string    `json:"source"`           // config, override or reset
+	By       string    `json:"by,omitempty"`
+	Reason   string    `json:"reason,omitempty"`
+	Revision uint64    `json:"revision,omitempty"` // Of the override in the bucket
+	At       time.Time `json:"at"`
+}
+
+// Set holds the flags of a service, configured ones overridden at runtime
+type Set struct {
+	mu         sync.RWMutex
+	configured map[string]Flag
+	overrides  map[string]Flag
+	audit      []Change
+	onChange   func(Change)
+}
+
+// NewSet creates a set of the configured flags
+func NewSet(flags []Flag) (*Set, error) {
+	s := &Set{configured: make(map[string]Flag, len(flags)), overrides: make(map[string]Flag)}
+	now := time.Now()
+	for i, f := range flags {
+		if err := f.Validate(); err != nil {
+			return nil, fmt.Errorf("invalid flags[%d]: %w", i, err)
+		}
+		if _, ok := s.configured[f.Name]; ok {
+			return nil, fmt.Errorf("duplicate flag %s", f.Name)
+		}
+		s.configured[f.Name] = f
+		after := f
+		s.audit = append(s.audit, Change{Flag: f.Name, After: &after, Source: "config", At: now})
+	}
+	return s, nil
+}
+
+// OnChange calls fn on every runtime change, e.g. to log it
+func (s *Set) OnChange(fn func(Change)) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.onChange = fn
+}
+
+// Enabled reports whether the flag name is on for node and symbol, false
+// when undefined
+func (s *Set) Enabled(name, node, symbol string) bool {
+	if s == nil {
+		return false
+	}
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	f, ok := s.lookupLocked(name)
+	return ok && f.On(node, symbol)
+}
+
+// Get returns the current value of a flag
+func (s *Set) Get(name string) (Flag, bool) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.lookupLocked(name)
+}
+
+func (s *Set) lookupLocked(name string) (Flag, bool) {
+	if f, ok := s.overrides[name]; ok {
+		return f, true
+	}
+	f, ok := s.configured[name]
+	return f, ok
+}
+
+// Flags returns the current value of every flag, sorted by name
+func (s *Set) Flags() []Flag {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	flags := make([]Flag, 0, len(s.configured)+len(s.overrides))
+	for name, f := range s.configured {
+		if _, ok := s.overrides[name]; !ok {
+			flags = append(flags, f)
+		}
+	}
+	for _, f := range s.overrides {
+		flags = append(flags, f)
+	}
+	sort.Slice(flags, func(i, j int) bool { return flags[i].Name < flags[j].Name })
+	return flags
+}
+
+// Audit returns the last changes of the flags, oldest first
+func (s *Set) Audit() []Change {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return append([]Change(nil), s.audit...)
+}
+
+// Apply applies the override of flag name at revision, returning the change
+func (s *Set) Apply(name string, o Override, revision uint64) Change {
+	s.mu.Lock()
+	c := Change{Flag: name, By: o.By, Reason: o.Reason, Revision: revision, At: o.At}
+	if before, ok := s.lookupLocked(name); ok {
+		c.Before = &before
+	}
+	if o.Flag != nil {
+		f := *o.Flag
+		f.Name = name
+		s.overrides[name] = f
+		c.Source = "override"
+	} else {
+		delete(s.overrides, name)
+		c.Source = "reset"
+	}
+	if after, ok := s.lookupLocked(name); ok {
+		c.After = &after
+	}
+	s.audit = append(s.audit, c)
+	if len(s.audit) > auditSize {
+		s.audit = append([]Change(nil), s.audit[len(s.audit)-auditSize:]...)
+	}
+	onChange := s.onChange
+	s.mu.Unlock()
+	if onChange != nil {
+		onChange(c)
+	}
+	return c
+}
+
+// Snapshot is the state of a set for node snapshots
+type Snapshot struct {
+	Flags []Flag   `json:"flags"`
+	Audit []Change `json:"audit"`
+}
+
+// Snapshot returns the flags and the audit trail
+func (s *Set) Snapshot() Snapshot {
+	return Snapshot{Flags: s.Flags(), Audit: s.Audit()}
+}
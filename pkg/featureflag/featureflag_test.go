@@ -0,0 +1,83 @@
+package featureflag
+
+import (
+	"testing"
+	"time"
+)
+
+func TestFlagOn(t *testing.T) {
+	f := Flag{Name: "risk.max_notional", Enabled: true, Nodes: []string{"basis"}, Symbols: []string{"BTC-USDT"}}
+	for _, c := range []struct {
+		node, symbol string
+		want         bool
+	}{
+		{"basis", "BTC-USDT", true},
+		{"basis", "btcusdt", true},
+		{"basis", "ETH-USDT", false},
+		{"basis", "", false}, // Not per symbol
+		{"monitor", "BTC-USDT", false},
+	} {
+		if got := f.On(c.node, c.symbol); got != c.want {
+			t.Errorf("On(%q, %q) = %v, want %v", c.node, c.symbol, got, c.want)
+		}
+	}
+	if !(Flag{Name: "x", Enabled: true}).On("monitor", "") {
+		t.Error("untargeted flag off")
+	}
+	if (Flag{Name: "x"}).On("monitor", "") {
+		t.Error("disabled flag on")
+	}
+}
+
+func TestSet(t *testing.T) {
+	if _, err := NewSet([]Flag{{Name: "Execution.TWAP"}}); err == nil {
+		t.Error("invalid name accepted")
+	}
+	if _, err := NewSet([]Flag{{Name: "a"}, {Name: "a"}}); err == nil {
+		t.Error("duplicate flag accepted")
+	}
+
+	s, err := NewSet([]Flag{{Name: "execution.twap_v2", Enabled: true, Nodes: []string{"basis"}}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var changes []Change
+	s.OnChange(func(c Change) { changes = append(changes, c) })
+	if !s.Enabled("execution.twap_v2", "basis", "BTC-USDT") || s.Enabled("execution.twap_v2", "monitor", "") || s.Enabled("unknown", "basis", "") {
+		t.Fatal("configured flags not applied")
+	}
+
+	// Overrides win over the configured value until reset
+	at := time.Date(2025, 9, 15, 0, 0, 0, 0, time.UTC)
+	s.Apply("execution.twap_v2", Override{Flag: &Flag{Enabled: true}, By: "ops", Reason: "rollout", At: at}, 3)
+	if !s.Enabled("execution.twap_v2", "monitor", "") {
+		t.Error("override not applied")
+	}
+	s.Apply("risk.new_check", Override{Flag: &Flag{Enabled: true, Symbols: []string{"ETH-USDT"}}, By: "ops", At: at}, 4)
+	if len(s.Flags()) != 2 || !s.Enabled("risk.new_check", "basis", "ETH-USDT") {
+		t.Errorf("flags = %+v", s.Flags())
+	}
+	s.Apply("execution.twap_v2", Override{By: "ops", Reason: "regression", At: at}, 5)
+	if s.Enabled("execution.twap_v2", "monitor", "") || !s.Enabled("execution.twap_v2", "basis", "") {
+		t.Error("reset did not revert to the configured value")
+	}
+
+	if len(changes) != 3 {
+		t.Fatalf("changes = %+v", changes)
+	}
+	if c := changes[2]; c.Source != "reset" || c.By != "ops" || c.Revision != 5 || c.Before == nil || len(c.Before.Nodes) != 0 || c.After == nil || c.After.Nodes[0] != "basis" {
+		t.Errorf("reset change = %+v", c)
+	}
+	if c := changes[1]; c.Before != nil || c.After == nil || c.After.Name != "risk.new_check" {
+		t.Errorf("new flag change = %+v", c)
+	}
+	audit := s.Audit()
+	if len(audit) != 4 || audit[0].Source != "config" || audit[3].Reason != "regression" {
+		t.Errorf("audit = %+v", audit)
+	}
+
+	var unset *Set
+	if unset.Enabled("execution.twap_v2", "basis", "") {
+		t.Error("nil set enabled a flag")
+	}
+}
@@ -0,0 +1,177 @@
+package featureflag
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/nats-io/nats.go"
+	"github.com/rs/zerolog"
+)
+
+// DefaultBucket is the key-value bucket of the runtime overrides
+const DefaultBucket = "feature_flags"
+
+// history is the number of revisions the bucket keeps per flag, the durable
+// audit trail of its overrides
+const history = 64
+
+// Store applies the overrides of a key-value bucket, one key per flag
+// holding its JSON Override, to a Set as they are written
+type Store struct {
+	set     *Set
+	kv      nats.KeyValue
+	watcher nats.KeyWatcher
+	log     zerolog.Logger
+	done    chan struct{}
+}
+
+// OpenBucket opens the bucket of the overrides, creating it when missing
+func OpenBucket(js nats.JetStreamContext, bucket string) (nats.KeyValue, error) {
+	if bucket == "" {
+		return nil, fmt.Errorf("bucket cannot be empty")
+	}
+	kv, err := js.KeyValue(bucket)
+	if errors.Is(err, nats.ErrBucketNotFound) {
+		kv, err = js.CreateKeyValue(&nats.KeyValueConfig{Bucket: bucket, History: history, Description: "Feature flag overrides"})
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to open bucket %s: %w", bucket, err)
+	}
+	return kv, nil
+}
+
+// Watch applies the overrides of the bucket to set, returning once the
+// current ones are applied, then every new one until Close
+func Watch(js nats.JetStreamContext, bucket string, set *Set, log zerolog.Logger) (*Store, error) {
+	kv, err := OpenBucket(js, bucket)
+	if err != nil {
+		return nil, err
+	}
+	watcher, err := kv.WatchAll()
+	if err != nil {
+		return nil, fmt.Errorf("failed to watch bucket %s: %w", bucket, err)
+	}
+	s := &Store{set: set, kv: kv, watcher: watcher, log: log, done: make(chan struct{})}
+	// A nil entry marks the end of the current values
+	for entry := range watcher.Updates() {
+		if entry == nil {
+			break
+		}
+		s.apply(entry)
+	}
+	set.OnChange(s.logChange)
+	go s.run()
+	return s, nil
+}
+
+func (s *Store) run() {
+	defer close(s.done)
+	for entry := range s.watcher.Updates() {
+		if entry != nil {
+			s.apply(entry)
+		}
+	}
+}
+
+func (s *Store) apply(entry nats.KeyValueEntry) {
+	var o Override
+	if entry.Operation() == nats.KeyValuePut {
+		if err := json.Unmarshal(entry.Value(), &o); err != nil {
+			s.log.Error().Err(err).Str("flag", entry.Key()).Uint64("revision", entry.Revision()).Msg("Invalid feature flag override")
+			return
+		}
+		if o.Flag != nil {
+			o.Flag.Name = entry.Key()
+			if err := o.Flag.Validate(); err != nil {
+				s.log.Error().Err(err).Uint64("revision", entry.Revision()).Msg("Invalid feature flag override")
+				return
+			}
+		}
+	}
+	if o.At.IsZero() {
+		o.At = entry.Created()
+	}
+	s.set.Apply(entry.Key(), o, entry.Revision())
+}
+
+func (s *Store) logChange(c Change) {
+	event := s.log.Info().Str("flag", c.Flag).Str("source", c.Source).Str("by", c.By).Str("reason", c.Reason).Uint64("revision", c.Revision)
+	if c.After != nil {
+		event = event.Bool("enabled", c.After.Enabled).Strs("nodes", c.After.Nodes).Strs("symbols", c.After.Symbols)
+	}
+	event.Msg("Feature flag changed")
+}
+
+// Close stops applying the overrides
+func (s *Store) Close() {
+	if err := s.watcher.Stop(); err != nil {
+		s.log.Error().Err(err).Msg("Failed to stop feature flag watcher")
+	}
+	<-s.done
+}
+
+// Put overrides a flag in the bucket for every service watching it
+func Put(kv nats.KeyValue, flag Flag, by, reason string) (uint64, error) {
+	if err := flag.Validate(); err != nil {
+		return 0, err
+	}
+	return put(kv, flag.Name, Override{Flag: &flag, By: by, Reason: reason, At: time.Now().UTC()})
+}
+
+// Reset removes the override of a flag, which reverts to its configured
+// value. The reset is written as an override so the audit trail records
+// who reset the flag.
+func Reset(kv nats.KeyValue, name, by, reason string) (uint64, error) {
+	if !namePattern.MatchString(name) {
+		return 0, fmt.Errorf("invalid flag name %q", name)
+	}
+	return put(kv, name, Override{By: by, Reason: reason, At: time.Now().UTC()})
+}
+
+func put(kv nats.KeyValue, name string, o Override) (uint64, error) {
+	if o.By == "" {
+		return 0, fmt.Errorf("by cannot be empty")
+	}
+	data, err := json.Marshal(o)
+	if err != nil {
+		return 0, err
+	}
+	revision, err := kv.Put(name, data)
+	if err != nil {
+		return 0, fmt.Errorf("failed to write flag %s: %w", name, err)
+	}
+	return revision, nil
+}
+
+// History returns the overrides of a flag kept by the bucket, oldest first
+func History(kv nats.KeyValue, name string) ([]Change, error) {
+	entries, err := kv.History(name)
+	if errors.Is(err, nats.ErrKeyNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read the history of %s: %w", name, err)
+	}
+	changes := make([]Change, 0, len(entries))
+	for _, entry := range entries {
+		c := Change{Flag: name, Source: "reset", Revision: entry.Revision(), At: entry.Created()}
+		if entry.Operation() == nats.KeyValuePut {
+			var o Override
+			if err := json.Unmarshal(entry.Value(), &o); err != nil {
+				continue
+			}
+			if o.Flag != nil {
+				o.Flag.Name = name
+				c.After, c.Source = o.Flag, "override"
+			}
+			c.By, c.Reason = o.By, o.Reason
+			if !o.At.IsZero() {
+				c.At = o.At
+			}
+		}
+		changes = append(changes, c)
+	}
+	return changes, nil
+}
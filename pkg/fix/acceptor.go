@@ -0,0 +1,249 @@
+package fix
+
+import (
+	"bufio"
+	"crypto/subtle"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"net"
+	"net/netip"
+	"sync"
+	"time"
+
+	"github.com/BullionBear/sequex/pkg/logger"
+)
+
+// AcceptorConfig configures a FIX acceptor
+type AcceptorConfig struct {
+	Addr     string
+	Sessions []SessionID             // Sessions allowed to log on
+	Logons   map[SessionID]LogonAuth // Who may log on to each session, anyone knowing its comp ids when missing
+	TLS      *tls.Config             // Serves the sessions over TLS when set
+	StoreDir string                  // Directory for sequence number persistence, empty keeps them in memory
+}
+
+// LogonAuth restricts the Logon of a session to a counterparty
+type LogonAuth struct {
+	Username string
+	Password string         // Of the Logon, along with Username
+	Allowed  []netip.Prefix // Source addresses the session logs on from, any when empty
+}
+
+// allows reports whether the session may be logged on from addr
+func (l LogonAuth) allows(addr net.Addr) bool {
+	if len(l.Allowed) == 0 {
+		return true
+	}
+	tcp, ok := addr.(*net.TCPAddr)
+	if !ok {
+		return false
+	}
+	ip, ok := netip.AddrFromSlice(tcp.IP)
+	if !ok {
+		return false
+	}
+	for _, prefix := range l.Allowed {
+		if prefix.Contains(ip.Unmap()) {
+			return true
+		}
+	}
+	return false
+}
+
+// check verifies the credentials of a Logon
+func (l LogonAuth) check(logon *Message) error {
+	username, _ := logon.Get(TagUsername)
+	password, _ := logon.Get(TagPassword)
+	// Both compared in constant time, so a guess learns nothing of either
+	userOK := subtle.ConstantTimeCompare([]byte(username), []byte(l.Username)) == 1
+	passwordOK := subtle.ConstantTimeCompare([]byte(password), []byte(l.Password)) == 1
+	if !userOK || !passwordOK {
+		return fmt.Errorf("invalid Username or Password")
+	}
+	return nil
+}
+
+// Acceptor accepts FIX 4.4 initiator connections for a fixed set of sessions
+type Acceptor struct {
+	cfg      AcceptorConfig
+	app      Application
+	listener net.Listener
+	mu       sync.Mutex
+	stores   map[SessionID]SeqStore
+	active   map[SessionID]*Session
+	wg       sync.WaitGroup
+}
+
+// NewAcceptor creates an acceptor, preparing a sequence store for every configured session
+func NewAcceptor(cfg AcceptorConfig, app Application) (*Acceptor, error) {
+	stores := make(map[SessionID]SeqStore, len(cfg.Sessions))
+	for _, id := range cfg.Sessions {
+		if cfg.StoreDir == "" {
+			stores[id] = NewMemoryStore()
+			continue
+		}
+		store, err := NewFileStore(cfg.StoreDir, id)
+		if err != nil {
+			return nil, err
+		}
+		stores[id] = store
+	}
+	return &Acceptor{
+		cfg:    cfg,
+		app:    app,
+		stores: stores,
+		active: make(map[SessionID]*Session),
+	}, nil
+}
+
+// Start begins listening for connections
+func (a *Acceptor) Start() error {
+	listener, err := net.Listen("tcp", a.cfg.Addr)
+	if err != nil {
+		return fmt.Errorf("failed to listen on %s: %w", a.cfg.Addr, err)
+	}
+	if a.cfg.TLS != nil {
+		listener = tls.NewListener(listener, a.cfg.TLS)
+	}
+	a.listener = listener
+	logger.Log.Info().Str("addr", listener.Addr().String()).Bool("tls", a.cfg.TLS != nil).Msg("FIX acceptor listening")
+
+	a.wg.Add(1)
+	go a.acceptLoop()
+	return nil
+}
+
+// Addr returns the listening address
+func (a *Acceptor) Addr() net.Addr {
+	return a.listener.Addr()
+}
+
+func (a *Acceptor) acceptLoop() {
+	defer a.wg.Done()
+	for {
+		conn, err := a.listener.Accept()
+		if err != nil {
+			if errors.Is(err, net.ErrClosed) {
+				return
+			}
+			logger.Log.Error().Err(err).Msg("FIX accept failed")
+			continue
+		}
+		a.wg.Add(1)
+		go func() {
+			defer a.wg.Done()
+			a.serve(conn)
+		}()
+	}
+}
+
+// serve waits for the Logon message and binds the connection to its
+// session. A Logon that is not allowed is dropped with the connection.
+func (a *Acceptor) serve(conn net.Conn) {
+	remote := conn.RemoteAddr().String()
+	if !a.reachable(conn.RemoteAddr()) {
+		logger.Log.Warn().Str("remote", remote).Msg("Rejecting connection from an address no session is allowed from")
+		conn.Close()
+		return
+	}
+	reader := bufio.NewReader(conn)
+	conn.SetReadDeadline(time.Now().Add(10 * time.Second))
+	raw, err := readMessage(reader)
+	if err != nil {
+		logger.Log.Warn().Err(err).Str("remote", remote).Msg("Failed to read Logon")
+		conn.Close()
+		return
+	}
+	conn.SetReadDeadline(time.Time{})
+
+	logon, err := Parse(raw)
+	if err != nil || logon.MsgType() != MsgTypeLogon {
+		logger.Log.Warn().Str("remote", remote).Msg("First message is not a valid Logon")
+		conn.Close()
+		return
+	}
+
+	// The counterparty's sender is our target and vice versa
+	sender, _ := logon.Get(TagTargetCompID)
+	target, _ := logon.Get(TagSenderCompID)
+	id := SessionID{SenderCompID: sender, TargetCompID: target}
+
+	if auth, ok := a.cfg.Logons[id]; ok {
+		if !auth.allows(conn.RemoteAddr()) {
+			err = fmt.Errorf("address not allowed")
+		} else {
+			err = auth.check(logon)
+		}
+		if err != nil {
+			logger.Log.Warn().Err(err).Str("session", id.String()).Str("remote", remote).Msg("Rejecting Logon")
+			conn.Close()
+			return
+		}
+	}
+
+	a.mu.Lock()
+	store, known := a.stores[id]
+	_, busy := a.active[id]
+	if !known || busy {
+		a.mu.Unlock()
+		logger.Log.Warn().Str("session", id.String()).Bool("known", known).Bool("busy", busy).Msg("Rejecting Logon")
+		conn.Close()
+		return
+	}
+	session, err := newSession(id, conn, reader, store, a.app)
+	if err != nil {
+		a.mu.Unlock()
+		logger.Log.Error().Err(err).Str("session", id.String()).Msg("Failed to create session")
+		conn.Close()
+		return
+	}
+	a.active[id] = session
+	a.mu.Unlock()
+
+	session.run(logon)
+
+	a.mu.Lock()
+	delete(a.active, id)
+	a.mu.Unlock()
+	logger.Log.Info().Str("session", id.String()).Msg("Session disconnected")
+}
+
+// reachable reports whether a session may be logged on from addr, so other
+// connections are dropped before reading anything
+func (a *Acceptor) reachable(addr net.Addr) bool {
+	for _, id := range a.cfg.Sessions {
+		if auth, ok := a.cfg.Logons[id]; !ok || auth.allows(addr) {
+			return true
+		}
+	}
+	return false
+}
+
+// Send sends an application message on a logged on session
+func (a *Acceptor) Send(id SessionID, msg *Message) error {
+	a.mu.Lock()
+	session, ok := a.active[id]
+	a.mu.Unlock()
+	if !ok {
+		return fmt.Errorf("session %s is not logged on", id)
+	}
+	return session.Send(msg)
+}
+
+// Stop logs out every active session and stops accepting connections
+func (a *Acceptor) Stop() {
+	if a.listener != nil {
+		a.listener.Close()
+	}
+	a.mu.Lock()
+	sessions := make([]*Session, 0, len(a.active))
+	for _, session := range a.active {
+		sessions = append(sessions, session)
+	}
+	a.mu.Unlock()
+	for _, session := range sessions {
+		session.logout("acceptor shutting down")
+	}
+	a.wg.Wait()
+}
@@ -0,0 +1,375 @@
+package fix
+
+import (
+	"bufio"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"math/big"
+	"net"
+	"net/netip"
+	"os"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestMessage_RoundTrip(t *testing.T) {
+	msg := NewMessage(MsgTypeNewOrderSingle).
+		Set(TagSenderCompID, "CLIENT1").
+		Set(TagTargetCompID, "SEQUEX").
+		SetInt(TagMsgSeqNum, 2).
+		Set(TagClOrdID, "order-1").
+		Set(TagSymbol, "BTC-USDT").
+		Set(TagSide, "1").
+		SetFloat(TagOrderQty, 0.25).
+		SetFloat(TagPrice, 60000.5)
+
+	parsed, err := Parse(msg.Bytes())
+	if err != nil {
+		t.Fatalf("Parse() returned error: %v", err)
+	}
+	if parsed.MsgType() != MsgTypeNewOrderSingle {
+		t.Errorf("MsgType = %s, expected D", parsed.MsgType())
+	}
+	if qty, _ := parsed.GetFloat(TagOrderQty); qty != 0.25 {
+		t.Errorf("OrderQty = %v, expected 0.25", qty)
+	}
+	if price, _ := parsed.Get(TagPrice); price != "60000.5" {
+		t.Errorf("Price = %s, expected 60000.5", price)
+	}
+	if len(parsed.Fields) != len(msg.Fields) {
+		t.Errorf("expected %d fields, got %d", len(msg.Fields), len(parsed.Fields))
+	}
+}
+
+func TestParse_Invalid(t *testing.T) {
+	valid := NewMessage(MsgTypeHeartbeat).SetInt(TagMsgSeqNum, 1).Bytes()
+
+	badChecksum := append([]byte{}, valid...)
+	badChecksum[len(badChecksum)-2]++
+
+	badLength := []byte("8=FIX.4.4\x019=99\x0135=0\x0134=1\x0110=000\x01")
+	badLength = append(badLength[:len(badLength)-7], []byte("10="+pad3(checksum(badLength[:len(badLength)-7]))+"\x01")...)
+
+	tests := []struct {
+		name string
+		data []byte
+	}{
+		{"bad checksum", badChecksum},
+		{"bad body length", badLength},
+		{"wrong version", []byte("8=FIX.4.2\x019=5\x0135=0\x0110=000\x01")},
+		{"missing SOH", []byte("8=FIX.4.4")},
+		{"non numeric tag", []byte("8=FIX.4.4\x01x=1\x01")},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := Parse(tt.data); err == nil {
+				t.Error("Parse() expected error, got nil")
+			}
+		})
+	}
+}
+
+func pad3(n int) string {
+	s := strconv.Itoa(n)
+	for len(s) < 3 {
+		s = "0" + s
+	}
+	return s
+}
+
+// recordingApp records application messages received by the acceptor
+type recordingApp struct {
+	mu       sync.Mutex
+	messages []*Message
+	logons   int
+}
+
+func (a *recordingApp) OnLogon(id SessionID) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.logons++
+}
+
+func (a *recordingApp) OnLogout(id SessionID) {}
+
+func (a *recordingApp) FromApp(id SessionID, msg *Message) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.messages = append(a.messages, msg)
+}
+
+func (a *recordingApp) count() int {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	return len(a.messages)
+}
+
+// testInitiator is a minimal counterparty used to drive the acceptor
+type testInitiator struct {
+	t      *testing.T
+	conn   net.Conn
+	reader *bufio.Reader
+	seq    int
+}
+
+func dialInitiator(t *testing.T, addr string, seq int) *testInitiator {
+	conn, err := net.Dial("tcp", addr)
+	if err != nil {
+		t.Fatalf("failed to dial acceptor: %v", err)
+	}
+	return &testInitiator{t: t, conn: conn, reader: bufio.NewReader(conn), seq: seq}
+}
+
+func (i *testInitiator) send(msg *Message) {
+	msg.Set(TagSenderCompID, "CLIENT1").
+		Set(TagTargetCompID, "SEQUEX").
+		SetInt(TagMsgSeqNum, i.seq).
+		SetTime(TagSendingTime, time.Now())
+	i.seq++
+	if _, err := i.conn.Write(msg.Bytes()); err != nil {
+		i.t.Fatalf("failed to write: %v", err)
+	}
+}
+
+func (i *testInitiator) read() *Message {
+	i.conn.SetReadDeadline(time.Now().Add(2 * time.Second))
+	raw, err := readMessage(i.reader)
+	if err != nil {
+		i.t.Fatalf("failed to read: %v", err)
+	}
+	msg, err := Parse(raw)
+	if err != nil {
+		i.t.Fatalf("failed to parse: %v", err)
+	}
+	return msg
+}
+
+// closed reports whether the acceptor dropped the connection
+func (i *testInitiator) closed() bool {
+	i.conn.SetReadDeadline(time.Now().Add(2 * time.Second))
+	_, err := readMessage(i.reader)
+	return err != nil && !errors.Is(err, os.ErrDeadlineExceeded)
+}
+
+var testSession = SessionID{SenderCompID: "SEQUEX", TargetCompID: "CLIENT1"}
+
+func startAcceptor(t *testing.T, dir string, app Application) *Acceptor {
+	return startAcceptorConfig(t, AcceptorConfig{Sessions: []SessionID{testSession}, StoreDir: dir}, app)
+}
+
+func startAcceptorConfig(t *testing.T, cfg AcceptorConfig, app Application) *Acceptor {
+	cfg.Addr = "127.0.0.1:0"
+	acceptor, err := NewAcceptor(cfg, app)
+	if err != nil {
+		t.Fatalf("NewAcceptor() returned error: %v", err)
+	}
+	if err := acceptor.Start(); err != nil {
+		t.Fatalf("Start() returned error: %v", err)
+	}
+	return acceptor
+}
+
+func TestAcceptor_SessionLifecycle(t *testing.T) {
+	dir := t.TempDir()
+	app := &recordingApp{}
+	acceptor := startAcceptor(t, dir, app)
+
+	initiator := dialInitiator(t, acceptor.Addr().String(), 1)
+	initiator.send(NewMessage(MsgTypeLogon).SetInt(TagEncryptMethod, 0).SetInt(TagHeartBtInt, 30))
+	reply := initiator.read()
+	if reply.MsgType() != MsgTypeLogon {
+		t.Fatalf("expected Logon reply, got %s", reply.MsgType())
+	}
+	if seq, _ := reply.GetInt(TagMsgSeqNum); seq != 1 {
+		t.Errorf("Logon reply MsgSeqNum = %d, expected 1", seq)
+	}
+
+	initiator.send(NewMessage(MsgTypeTestRequest).Set(TagTestReqID, "ping"))
+	heartbeat := initiator.read()
+	if id, _ := heartbeat.Get(TagTestReqID); heartbeat.MsgType() != MsgTypeHeartbeat || id != "ping" {
+		t.Fatalf("expected Heartbeat answering ping, got %s", heartbeat)
+	}
+
+	initiator.send(NewMessage(MsgTypeNewOrderSingle).Set(TagClOrdID, "order-1"))
+	deadline := time.Now().Add(2 * time.Second)
+	for app.count() == 0 && time.Now().Before(deadline) {
+		time.Sleep(10 * time.Millisecond)
+	}
+	if app.count() != 1 {
+		t.Fatalf("expected 1 application message, got %d", app.count())
+	}
+
+	if err := acceptor.Send(SessionID{SenderCompID: "SEQUEX", TargetCompID: "CLIENT1"}, NewMessage(MsgTypeExecutionReport)); err != nil {
+		t.Fatalf("Send() returned error: %v", err)
+	}
+	if report := initiator.read(); report.MsgType() != MsgTypeExecutionReport {
+		t.Fatalf("expected ExecutionReport, got %s", report.MsgType())
+	}
+
+	initiator.send(NewMessage(MsgTypeLogout))
+	if logout := initiator.read(); logout.MsgType() != MsgTypeLogout {
+		t.Fatalf("expected Logout reply, got %s", logout.MsgType())
+	}
+	initiator.conn.Close()
+	acceptor.Stop()
+
+	// Sequence numbers survive an acceptor restart
+	store, err := NewFileStore(dir, SessionID{SenderCompID: "SEQUEX", TargetCompID: "CLIENT1"})
+	if err != nil {
+		t.Fatalf("NewFileStore() returned error: %v", err)
+	}
+	nextSender, nextTarget, err := store.Load()
+	if err != nil {
+		t.Fatalf("Load() returned error: %v", err)
+	}
+	if nextSender != 5 || nextTarget != 5 {
+		t.Errorf("persisted sequence numbers = (%d, %d), expected (5, 5)", nextSender, nextTarget)
+	}
+
+	restarted := startAcceptor(t, dir, app)
+	defer restarted.Stop()
+	initiator = dialInitiator(t, restarted.Addr().String(), 5)
+	defer initiator.conn.Close()
+	initiator.send(NewMessage(MsgTypeLogon).SetInt(TagEncryptMethod, 0).SetInt(TagHeartBtInt, 30))
+	reply = initiator.read()
+	if seq, _ := reply.GetInt(TagMsgSeqNum); reply.MsgType() != MsgTypeLogon || seq != 5 {
+		t.Fatalf("expected Logon reply with MsgSeqNum 5, got %s", reply)
+	}
+}
+
+func TestAcceptor_GapTriggersResendRequest(t *testing.T) {
+	app := &recordingApp{}
+	acceptor := startAcceptor(t, "", app)
+	defer acceptor.Stop()
+
+	initiator := dialInitiator(t, acceptor.Addr().String(), 1)
+	defer initiator.conn.Close()
+	initiator.send(NewMessage(MsgTypeLogon).SetInt(TagEncryptMethod, 0).SetInt(TagHeartBtInt, 30))
+	initiator.read()
+
+	initiator.seq = 5
+	initiator.send(NewMessage(MsgTypeNewOrderSingle).Set(TagClOrdID, "order-gap"))
+	resend := initiator.read()
+	if resend.MsgType() != MsgTypeResendRequest {
+		t.Fatalf("expected ResendRequest, got %s", resend.MsgType())
+	}
+	if begin, _ := resend.GetInt(TagBeginSeqNo); begin != 2 {
+		t.Errorf("BeginSeqNo = %d, expected 2", begin)
+	}
+
+	// Counterparty fills the gap, after which messages are accepted again
+	initiator.seq = 2
+	initiator.send(NewMessage(MsgTypeSequenceReset).Set(TagGapFillFlag, "Y").SetInt(TagNewSeqNo, 6).Set(TagPossDupFlag, "Y"))
+	initiator.seq = 6
+	initiator.send(NewMessage(MsgTypeNewOrderSingle).Set(TagClOrdID, "order-after-gap"))
+
+	deadline := time.Now().Add(2 * time.Second)
+	for app.count() == 0 && time.Now().Before(deadline) {
+		time.Sleep(10 * time.Millisecond)
+	}
+	if app.count() != 1 {
+		t.Fatalf("expected only the post-gap message to be delivered, got %d", app.count())
+	}
+}
+
+func TestAcceptor_RejectsLogon(t *testing.T) {
+	loopback := []netip.Prefix{netip.MustParsePrefix("127.0.0.0/8")}
+	logon := func() *Message {
+		return NewMessage(MsgTypeLogon).SetInt(TagEncryptMethod, 0).SetInt(TagHeartBtInt, 30)
+	}
+	tests := []struct {
+		name     string
+		auth     LogonAuth
+		logon    *Message
+		accepted bool
+	}{
+		{"valid", LogonAuth{Username: "client1", Password: "s3cret", Allowed: loopback}, logon().Set(TagUsername, "client1").Set(TagPassword, "s3cret"), true},
+		{"wrong password", LogonAuth{Username: "client1", Password: "s3cret"}, logon().Set(TagUsername, "client1").Set(TagPassword, "guess"), false},
+		{"wrong username", LogonAuth{Username: "client1", Password: "s3cret"}, logon().Set(TagUsername, "client2").Set(TagPassword, "s3cret"), false},
+		{"no credentials", LogonAuth{Username: "client1", Password: "s3cret"}, logon(), false},
+		{"address not allowed", LogonAuth{Username: "client1", Password: "s3cret", Allowed: []netip.Prefix{netip.MustParsePrefix("10.0.0.0/8")}}, logon().Set(TagUsername, "client1").Set(TagPassword, "s3cret"), false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			app := &recordingApp{}
+			acceptor := startAcceptorConfig(t, AcceptorConfig{
+				Sessions: []SessionID{testSession},
+				Logons:   map[SessionID]LogonAuth{testSession: tt.auth},
+			}, app)
+			defer acceptor.Stop()
+
+			initiator := dialInitiator(t, acceptor.Addr().String(), 1)
+			defer initiator.conn.Close()
+			initiator.send(tt.logon)
+			if !tt.accepted {
+				if !initiator.closed() {
+					t.Fatal("rejected Logon left the connection open")
+				}
+				app.mu.Lock()
+				defer app.mu.Unlock()
+				if app.logons != 0 {
+					t.Errorf("session logged on %d times", app.logons)
+				}
+				return
+			}
+			if reply := initiator.read(); reply.MsgType() != MsgTypeLogon {
+				t.Fatalf("expected Logon reply, got %s", reply.MsgType())
+			}
+		})
+	}
+}
+
+func TestAcceptor_TLS(t *testing.T) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		IPAddresses:  []net.IP{net.IPv4(127, 0, 0, 1)},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cert, err := x509.ParseCertificate(der)
+	if err != nil {
+		t.Fatal(err)
+	}
+	roots := x509.NewCertPool()
+	roots.AddCert(cert)
+
+	acceptor := startAcceptorConfig(t, AcceptorConfig{
+		Sessions: []SessionID{testSession},
+		TLS:      &tls.Config{Certificates: []tls.Certificate{{Certificate: [][]byte{der}, PrivateKey: key}}},
+	}, &recordingApp{})
+	defer acceptor.Stop()
+
+	// A plaintext Logon is not understood
+	plain := dialInitiator(t, acceptor.Addr().String(), 1)
+	plain.send(NewMessage(MsgTypeLogon).SetInt(TagEncryptMethod, 0).SetInt(TagHeartBtInt, 30))
+	if !plain.closed() {
+		t.Error("plaintext Logon left the connection open")
+	}
+	plain.conn.Close()
+
+	conn, err := tls.Dial("tcp", acceptor.Addr().String(), &tls.Config{RootCAs: roots})
+	if err != nil {
+		t.Fatal(err)
+	}
+	initiator := &testInitiator{t: t, conn: conn, reader: bufio.NewReader(conn), seq: 1}
+	defer conn.Close()
+	initiator.send(NewMessage(MsgTypeLogon).SetInt(TagEncryptMethod, 0).SetInt(TagHeartBtInt, 30))
+	if reply := initiator.read(); reply.MsgType() != MsgTypeLogon {
+		t.Fatalf("expected Logon reply over TLS, got %s", reply.MsgType())
+	}
+}
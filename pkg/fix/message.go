@@ -0,0 +1,274 @@
+package fix
+
+import (
+	"bytes"
+	"fmt"
+	"strconv"
+	"time"
+)
+
+// SOH is the FIX field delimiter
+const SOH = '\x01'
+
+// BeginString is the only FIX version supported
+const BeginString = "FIX.4.4"
+
+// TimestampFormat is the UTCTimestamp format with millisecond precision
+const TimestampFormat = "20060102-15:04:05.000"
+
+// Header and session tags
+const (
+	TagBeginString     = 8
+	TagBodyLength      = 9
+	TagMsgType         = 35
+	TagSenderCompID    = 49
+	TagTargetCompID    = 56
+	TagMsgSeqNum       = 34
+	TagSendingTime     = 52
+	TagPossDupFlag     = 43
+	TagCheckSum        = 10
+	TagEncryptMethod   = 98
+	TagHeartBtInt      = 108
+	TagTestReqID       = 112
+	TagBeginSeqNo      = 7
+	TagEndSeqNo        = 16
+	TagNewSeqNo        = 36
+	TagGapFillFlag     = 123
+	TagResetSeqNumFlag = 141
+	TagRefSeqNum       = 45
+	TagRefTagID        = 371
+	TagRefMsgType      = 372
+	TagRejectReason    = 373
+	TagText            = 58
+	TagUsername        = 553
+	TagPassword        = 554
+)
+
+// Application tags
+const (
+	TagAccount          = 1
+	TagAvgPx            = 6
+	TagClOrdID          = 11
+	TagCommission       = 12
+	TagCumQty           = 14
+	TagExecID           = 17
+	TagLastPx           = 31
+	TagLastQty          = 32
+	TagOrderID          = 37
+	TagOrderQty         = 38
+	TagOrdStatus        = 39
+	TagOrdType          = 40
+	TagOrigClOrdID      = 41
+	TagPrice            = 44
+	TagSide             = 54
+	TagSymbol           = 55
+	TagTimeInForce      = 59
+	TagTransactTime     = 60
+	TagStopPx           = 99
+	TagExecType         = 150
+	TagLeavesQty        = 151
+	TagSecurityType     = 167
+	TagSecurityExchange = 207
+	TagCommCurrency     = 479
+
+	TagBusinessRejectReason = 380
+	TagCxlRejResponseTo     = 434
+)
+
+// Message types
+const (
+	MsgTypeHeartbeat          = "0"
+	MsgTypeTestRequest        = "1"
+	MsgTypeResendRequest      = "2"
+	MsgTypeReject             = "3"
+	MsgTypeSequenceReset      = "4"
+	MsgTypeLogout             = "5"
+	MsgTypeExecutionReport    = "8"
+	MsgTypeOrderCancelReject  = "9"
+	MsgTypeLogon              = "A"
+	MsgTypeNewOrderSingle     = "D"
+	MsgTypeOrderCancelRequest = "F"
+
+	MsgTypeBusinessMessageReject = "j"
+)
+
+// Field is a single tag=value pair
+type Field struct {
+	Tag   int
+	Value string
+}
+
+// Message is a FIX message holding its fields in wire order.
+// BeginString, BodyLength and CheckSum are computed on Bytes and not stored.
+type Message struct {
+	Fields []Field
+}
+
+// NewMessage creates a message of the given type
+func NewMessage(msgType string) *Message {
+	return &Message{Fields: []Field{{Tag: TagMsgType, Value: msgType}}}
+}
+
+// MsgType returns the message type
+func (m *Message) MsgType() string {
+	v, _ := m.Get(TagMsgType)
+	return v
+}
+
+// Get returns the first value of a tag
+func (m *Message) Get(tag int) (string, bool) {
+	for _, f := range m.Fields {
+		if f.Tag == tag {
+			return f.Value, true
+		}
+	}
+	return "", false
+}
+
+// GetInt returns the value of a tag as an integer
+func (m *Message) GetInt(tag int) (int, error) {
+	v, ok := m.Get(tag)
+	if !ok {
+		return 0, fmt.Errorf("missing tag %d", tag)
+	}
+	n, err := strconv.Atoi(v)
+	if err != nil {
+		return 0, fmt.Errorf("tag %d is not an integer: %s", tag, v)
+	}
+	return n, nil
+}
+
+// GetFloat returns the value of a tag as a float
+func (m *Message) GetFloat(tag int) (float64, error) {
+	v, ok := m.Get(tag)
+	if !ok {
+		return 0, fmt.Errorf("missing tag %d", tag)
+	}
+	f, err := strconv.ParseFloat(v, 64)
+	if err != nil {
+		return 0, fmt.Errorf("tag %d is not a number: %s", tag, v)
+	}
+	return f, nil
+}
+
+// Set replaces the value of a tag or appends it
+func (m *Message) Set(tag int, value string) *Message {
+	for i := range m.Fields {
+		if m.Fields[i].Tag == tag {
+			m.Fields[i].Value = value
+			return m
+		}
+	}
+	m.Fields = append(m.Fields, Field{Tag: tag, Value: value})
+	return m
+}
+
+// SetInt sets an integer tag
+func (m *Message) SetInt(tag int, value int) *Message {
+	return m.Set(tag, strconv.Itoa(value))
+}
+
+// SetFloat sets a decimal tag
+func (m *Message) SetFloat(tag int, value float64) *Message {
+	return m.Set(tag, strconv.FormatFloat(value, 'f', -1, 64))
+}
+
+// SetTime sets a UTCTimestamp tag
+func (m *Message) SetTime(tag int, t time.Time) *Message {
+	return m.Set(tag, t.UTC().Format(TimestampFormat))
+}
+
+// Bytes encodes the message with BeginString, BodyLength and CheckSum
+func (m *Message) Bytes() []byte {
+	var body bytes.Buffer
+	for _, f := range m.Fields {
+		body.WriteString(strconv.Itoa(f.Tag))
+		body.WriteByte('=')
+		body.WriteString(f.Value)
+		body.WriteByte(SOH)
+	}
+
+	var out bytes.Buffer
+	fmt.Fprintf(&out, "%d=%s%c%d=%d%c", TagBeginString, BeginString, SOH, TagBodyLength, body.Len(), SOH)
+	out.Write(body.Bytes())
+	fmt.Fprintf(&out, "%d=%03d%c", TagCheckSum, checksum(out.Bytes()), SOH)
+	return out.Bytes()
+}
+
+// String returns the message with | as delimiter for logging
+func (m *Message) String() string {
+	return string(bytes.ReplaceAll(m.Bytes(), []byte{SOH}, []byte{'|'}))
+}
+
+// Parse decodes a complete FIX message, validating BodyLength and CheckSum
+func Parse(data []byte) (*Message, error) {
+	fields, err := splitFields(data)
+	if err != nil {
+		return nil, err
+	}
+	if len(fields) < 4 {
+		return nil, fmt.Errorf("message too short")
+	}
+	if fields[0].Tag != TagBeginString || fields[0].Value != BeginString {
+		return nil, fmt.Errorf("unsupported begin string: %s", fields[0].Value)
+	}
+	if fields[1].Tag != TagBodyLength {
+		return nil, fmt.Errorf("second field must be BodyLength")
+	}
+	last := fields[len(fields)-1]
+	if last.Tag != TagCheckSum {
+		return nil, fmt.Errorf("last field must be CheckSum")
+	}
+	if fields[2].Tag != TagMsgType {
+		return nil, fmt.Errorf("third field must be MsgType")
+	}
+
+	// Checksum covers everything up to the CheckSum field
+	checksumStart := bytes.LastIndex(data, []byte{SOH, '1', '0', '='}) + 1
+	expected, err := strconv.Atoi(last.Value)
+	if err != nil || expected != checksum(data[:checksumStart]) {
+		return nil, fmt.Errorf("invalid checksum: %s", last.Value)
+	}
+
+	bodyLength, err := strconv.Atoi(fields[1].Value)
+	if err != nil {
+		return nil, fmt.Errorf("invalid body length: %s", fields[1].Value)
+	}
+	bodyStart := bytes.Index(data, []byte{SOH, '3', '5', '='}) + 1
+	if bodyLength != checksumStart-bodyStart {
+		return nil, fmt.Errorf("invalid body length: expected %d, got %d", checksumStart-bodyStart, bodyLength)
+	}
+
+	return &Message{Fields: fields[2 : len(fields)-1]}, nil
+}
+
+// splitFields splits raw data into tag=value fields
+func splitFields(data []byte) ([]Field, error) {
+	var fields []Field
+	for len(data) > 0 {
+		end := bytes.IndexByte(data, SOH)
+		if end < 0 {
+			return nil, fmt.Errorf("field not terminated by SOH")
+		}
+		eq := bytes.IndexByte(data[:end], '=')
+		if eq <= 0 {
+			return nil, fmt.Errorf("malformed field: %q", data[:end])
+		}
+		tag, err := strconv.Atoi(string(data[:eq]))
+		if err != nil {
+			return nil, fmt.Errorf("invalid tag: %q", data[:eq])
+		}
+		fields = append(fields, Field{Tag: tag, Value: string(data[eq+1 : end])})
+		data = data[end+1:]
+	}
+	return fields, nil
+}
+
+// checksum returns the sum of all bytes modulo 256
+func checksum(data []byte) int {
+	sum := 0
+	for _, b := range data {
+		sum += int(b)
+	}
+	return sum % 256
+}
@@ -0,0 +1,401 @@
+package fix
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io"
+	"net"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/BullionBear/sequex/pkg/logger"
+)
+
+// SessionID identifies a session from the acceptor's perspective
+type SessionID struct {
+	SenderCompID string // Our comp id
+	TargetCompID string // Counterparty comp id
+}
+
+func (id SessionID) String() string {
+	return fmt.Sprintf("%s-%s", id.SenderCompID, id.TargetCompID)
+}
+
+// Application receives session events and application level messages
+type Application interface {
+	OnLogon(id SessionID)
+	OnLogout(id SessionID)
+	FromApp(id SessionID, msg *Message)
+}
+
+// Session is a logged on FIX session over a single connection
+type Session struct {
+	id         SessionID
+	conn       net.Conn
+	reader     *bufio.Reader
+	store      SeqStore
+	app        Application
+	heartBtInt time.Duration
+
+	mu              sync.Mutex
+	nextSender      int
+	nextTarget      int
+	lastSent        time.Time
+	lastRecv        time.Time
+	testReqSent     bool
+	resendRequested bool
+	loggedOut       bool
+	closeOnce       sync.Once
+	done            chan struct{}
+}
+
+func newSession(id SessionID, conn net.Conn, reader *bufio.Reader, store SeqStore, app Application) (*Session, error) {
+	nextSender, nextTarget, err := store.Load()
+	if err != nil {
+		return nil, err
+	}
+	return &Session{
+		id:         id,
+		conn:       conn,
+		reader:     reader,
+		store:      store,
+		app:        app,
+		nextSender: nextSender,
+		nextTarget: nextTarget,
+		lastRecv:   time.Now(),
+		done:       make(chan struct{}),
+	}, nil
+}
+
+// ID returns the session identifier
+func (s *Session) ID() SessionID {
+	return s.id
+}
+
+// Send stamps the standard header on the message and writes it to the counterparty
+func (s *Session) Send(msg *Message) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.sendLocked(msg, s.nextSender, false)
+}
+
+func (s *Session) sendLocked(msg *Message, seqNum int, possDup bool) error {
+	header := []Field{
+		{Tag: TagMsgType, Value: msg.MsgType()},
+		{Tag: TagSenderCompID, Value: s.id.SenderCompID},
+		{Tag: TagTargetCompID, Value: s.id.TargetCompID},
+		{Tag: TagMsgSeqNum, Value: strconv.Itoa(seqNum)},
+		{Tag: TagSendingTime, Value: time.Now().UTC().Format(TimestampFormat)},
+	}
+	if possDup {
+		header = append(header, Field{Tag: TagPossDupFlag, Value: "Y"})
+	}
+	for _, f := range msg.Fields {
+		switch f.Tag {
+		case TagMsgType, TagSenderCompID, TagTargetCompID, TagMsgSeqNum, TagSendingTime, TagPossDupFlag:
+			continue
+		}
+		header = append(header, f)
+	}
+	out := &Message{Fields: header}
+
+	s.conn.SetWriteDeadline(time.Now().Add(10 * time.Second))
+	if _, err := s.conn.Write(out.Bytes()); err != nil {
+		return fmt.Errorf("failed to write message: %w", err)
+	}
+	s.lastSent = time.Now()
+	if seqNum == s.nextSender {
+		s.nextSender++
+		if err := s.store.Save(s.nextSender, s.nextTarget); err != nil {
+			logger.Log.Error().Err(err).Str("session", s.id.String()).Msg("Failed to persist sequence numbers")
+		}
+	}
+	return nil
+}
+
+// run processes the accepted Logon and serves the session until disconnect
+func (s *Session) run(logon *Message) {
+	defer s.close()
+
+	if err := s.handleLogon(logon); err != nil {
+		logger.Log.Error().Err(err).Str("session", s.id.String()).Msg("Logon rejected")
+		s.logout(err.Error())
+		return
+	}
+	s.app.OnLogon(s.id)
+	defer s.app.OnLogout(s.id)
+	logger.Log.Info().Str("session", s.id.String()).Dur("heartBtInt", s.heartBtInt).Msg("Session logged on")
+
+	go s.heartbeatLoop()
+
+	for {
+		raw, err := readMessage(s.reader)
+		if err != nil {
+			if err != io.EOF {
+				logger.Log.Warn().Err(err).Str("session", s.id.String()).Msg("Session read error")
+			}
+			return
+		}
+		msg, err := Parse(raw)
+		if err != nil {
+			// Garbled messages are ignored without incrementing the expected sequence number
+			logger.Log.Warn().Err(err).Str("session", s.id.String()).Msg("Dropping garbled message")
+			continue
+		}
+		if !s.handle(msg) {
+			return
+		}
+	}
+}
+
+func (s *Session) handleLogon(msg *Message) error {
+	heartBtInt, err := msg.GetInt(TagHeartBtInt)
+	if err != nil || heartBtInt <= 0 {
+		return fmt.Errorf("invalid HeartBtInt")
+	}
+	s.heartBtInt = time.Duration(heartBtInt) * time.Second
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	reset, _ := msg.Get(TagResetSeqNumFlag)
+	if reset == "Y" {
+		s.nextSender, s.nextTarget = 1, 1
+	}
+
+	seqNum, err := msg.GetInt(TagMsgSeqNum)
+	if err != nil {
+		return err
+	}
+	if seqNum < s.nextTarget {
+		return fmt.Errorf("MsgSeqNum too low, expecting %d but received %d", s.nextTarget, seqNum)
+	}
+
+	reply := NewMessage(MsgTypeLogon).
+		SetInt(TagEncryptMethod, 0).
+		SetInt(TagHeartBtInt, heartBtInt)
+	if reset == "Y" {
+		reply.Set(TagResetSeqNumFlag, "Y")
+	}
+	if err := s.sendLocked(reply, s.nextSender, false); err != nil {
+		return err
+	}
+
+	if seqNum > s.nextTarget {
+		s.requestResendLocked()
+	} else {
+		s.nextTarget++
+	}
+	return s.store.Save(s.nextSender, s.nextTarget)
+}
+
+// handle processes one message and returns false when the session must end
+func (s *Session) handle(msg *Message) bool {
+	s.mu.Lock()
+	s.lastRecv = time.Now()
+	s.testReqSent = false
+
+	seqNum, err := msg.GetInt(TagMsgSeqNum)
+	if err != nil {
+		s.mu.Unlock()
+		s.logout("missing MsgSeqNum")
+		return false
+	}
+
+	msgType := msg.MsgType()
+
+	// SequenceReset in reset mode ignores sequence checks entirely
+	if msgType == MsgTypeSequenceReset {
+		gapFill, _ := msg.Get(TagGapFillFlag)
+		if gapFill != "Y" || seqNum >= s.nextTarget {
+			s.applySequenceResetLocked(msg)
+			s.mu.Unlock()
+			return true
+		}
+	}
+
+	if seqNum > s.nextTarget {
+		s.requestResendLocked()
+		s.mu.Unlock()
+		return true
+	}
+	if seqNum < s.nextTarget {
+		possDup, _ := msg.Get(TagPossDupFlag)
+		expected := s.nextTarget
+		s.mu.Unlock()
+		if possDup == "Y" {
+			return true
+		}
+		s.logout(fmt.Sprintf("MsgSeqNum too low, expecting %d but received %d", expected, seqNum))
+		return false
+	}
+
+	s.nextTarget++
+	s.resendRequested = false
+	if err := s.store.Save(s.nextSender, s.nextTarget); err != nil {
+		logger.Log.Error().Err(err).Str("session", s.id.String()).Msg("Failed to persist sequence numbers")
+	}
+
+	switch msgType {
+	case MsgTypeHeartbeat, MsgTypeReject:
+		s.mu.Unlock()
+	case MsgTypeTestRequest:
+		testReqID, _ := msg.Get(TagTestReqID)
+		s.sendLocked(NewMessage(MsgTypeHeartbeat).Set(TagTestReqID, testReqID), s.nextSender, false)
+		s.mu.Unlock()
+	case MsgTypeResendRequest:
+		s.gapFillLocked(msg)
+		s.mu.Unlock()
+	case MsgTypeLogout:
+		if !s.loggedOut {
+			s.loggedOut = true
+			s.sendLocked(NewMessage(MsgTypeLogout), s.nextSender, false)
+		}
+		s.mu.Unlock()
+		logger.Log.Info().Str("session", s.id.String()).Msg("Session logged out by counterparty")
+		return false
+	case MsgTypeLogon:
+		s.mu.Unlock()
+		s.logout("unexpected Logon on established session")
+		return false
+	default:
+		s.mu.Unlock()
+		s.app.FromApp(s.id, msg)
+	}
+	return true
+}
+
+// requestResendLocked asks the counterparty to resend from the expected sequence number
+func (s *Session) requestResendLocked() {
+	if s.resendRequested {
+		return
+	}
+	s.resendRequested = true
+	msg := NewMessage(MsgTypeResendRequest).
+		SetInt(TagBeginSeqNo, s.nextTarget).
+		SetInt(TagEndSeqNo, 0)
+	if err := s.sendLocked(msg, s.nextSender, false); err != nil {
+		logger.Log.Error().Err(err).Str("session", s.id.String()).Msg("Failed to send ResendRequest")
+	}
+}
+
+// gapFillLocked answers a ResendRequest with a SequenceReset-GapFill.
+// Application messages are never replayed since stale order state must not be resent.
+func (s *Session) gapFillLocked(msg *Message) {
+	begin, err := msg.GetInt(TagBeginSeqNo)
+	if err != nil || begin >= s.nextSender {
+		return
+	}
+	reset := NewMessage(MsgTypeSequenceReset).
+		Set(TagGapFillFlag, "Y").
+		SetInt(TagNewSeqNo, s.nextSender)
+	if err := s.sendLocked(reset, begin, true); err != nil {
+		logger.Log.Error().Err(err).Str("session", s.id.String()).Msg("Failed to send SequenceReset")
+	}
+}
+
+func (s *Session) applySequenceResetLocked(msg *Message) {
+	newSeqNo, err := msg.GetInt(TagNewSeqNo)
+	if err != nil || newSeqNo <= s.nextTarget {
+		return
+	}
+	s.nextTarget = newSeqNo
+	s.resendRequested = false
+	if err := s.store.Save(s.nextSender, s.nextTarget); err != nil {
+		logger.Log.Error().Err(err).Str("session", s.id.String()).Msg("Failed to persist sequence numbers")
+	}
+}
+
+// heartbeatLoop sends heartbeats when idle and test requests when the counterparty goes quiet
+func (s *Session) heartbeatLoop() {
+	ticker := time.NewTicker(time.Second)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-s.done:
+			return
+		case <-ticker.C:
+			s.mu.Lock()
+			now := time.Now()
+			sinceRecv := now.Sub(s.lastRecv)
+			switch {
+			case sinceRecv > 2*s.heartBtInt+s.heartBtInt/5:
+				s.mu.Unlock()
+				logger.Log.Warn().Str("session", s.id.String()).Msg("Counterparty timed out")
+				s.close()
+				return
+			case sinceRecv > s.heartBtInt+s.heartBtInt/5 && !s.testReqSent:
+				s.testReqSent = true
+				s.sendLocked(NewMessage(MsgTypeTestRequest).Set(TagTestReqID, strconv.FormatInt(now.UnixNano(), 10)), s.nextSender, false)
+			case now.Sub(s.lastSent) >= s.heartBtInt:
+				s.sendLocked(NewMessage(MsgTypeHeartbeat), s.nextSender, false)
+			}
+			s.mu.Unlock()
+		}
+	}
+}
+
+// logout sends a Logout with the reason and closes the session
+func (s *Session) logout(reason string) {
+	s.mu.Lock()
+	if !s.loggedOut {
+		s.loggedOut = true
+		if err := s.sendLocked(NewMessage(MsgTypeLogout).Set(TagText, reason), s.nextSender, false); err != nil {
+			logger.Log.Warn().Err(err).Str("session", s.id.String()).Msg("Failed to send Logout")
+		}
+	}
+	s.mu.Unlock()
+	s.close()
+}
+
+func (s *Session) close() {
+	s.closeOnce.Do(func() {
+		close(s.done)
+		s.conn.Close()
+	})
+}
+
+// Done is closed when the session ends
+func (s *Session) Done() <-chan struct{} {
+	return s.done
+}
+
+// readMessage reads one framed FIX message using BodyLength to find its end
+func readMessage(r *bufio.Reader) ([]byte, error) {
+	begin, err := r.ReadBytes(SOH)
+	if err != nil {
+		return nil, err
+	}
+	if !bytes.HasPrefix(begin, []byte("8=")) {
+		return nil, fmt.Errorf("expected BeginString, got %q", begin)
+	}
+	length, err := r.ReadBytes(SOH)
+	if err != nil {
+		return nil, err
+	}
+	if !bytes.HasPrefix(length, []byte("9=")) {
+		return nil, fmt.Errorf("expected BodyLength, got %q", length)
+	}
+	bodyLength, err := strconv.Atoi(string(length[2 : len(length)-1]))
+	if err != nil || bodyLength <= 0 || bodyLength > 1<<20 {
+		return nil, fmt.Errorf("invalid BodyLength %q", length)
+	}
+
+	body := make([]byte, bodyLength)
+	if _, err := io.ReadFull(r, body); err != nil {
+		return nil, err
+	}
+	trailer, err := r.ReadBytes(SOH)
+	if err != nil {
+		return nil, err
+	}
+
+	raw := make([]byte, 0, len(begin)+len(length)+len(body)+len(trailer))
+	raw = append(raw, begin...)
+	raw = append(raw, length...)
+	raw = append(raw, body...)
+	raw = append(raw, trailer...)
+	return raw, nil
+}
@@ -0,0 +1,95 @@
+package fix
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+)
+
+// SeqStore persists the sequence numbers of a session across restarts
+type SeqStore interface {
+	// Load returns the next sender and target sequence numbers
+	Load() (nextSender, nextTarget int, err error)
+	// Save persists the next sender and target sequence numbers
+	Save(nextSender, nextTarget int) error
+}
+
+// MemoryStore keeps sequence numbers in memory only
+type MemoryStore struct {
+	mu         sync.Mutex
+	nextSender int
+	nextTarget int
+}
+
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{nextSender: 1, nextTarget: 1}
+}
+
+func (s *MemoryStore) Load() (int, int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.nextSender, s.nextTarget, nil
+}
+
+func (s *MemoryStore) Save(nextSender, nextTarget int) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.nextSender = nextSender
+	s.nextTarget = nextTarget
+	return nil
+}
+
+// FileStore persists sequence numbers of one session as a JSON file
+type FileStore struct {
+	mu   sync.Mutex
+	path string
+}
+
+type seqState struct {
+	NextSender int `json:"next_sender"`
+	NextTarget int `json:"next_target"`
+}
+
+// NewFileStore creates a file store for the session under dir
+func NewFileStore(dir string, id SessionID) (*FileStore, error) {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, fmt.Errorf("failed to create store directory %s: %w", dir, err)
+	}
+	return &FileStore{path: filepath.Join(dir, id.String()+".seqnums.json")}, nil
+}
+
+func (s *FileStore) Load() (int, int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	data, err := os.ReadFile(s.path)
+	if os.IsNotExist(err) {
+		return 1, 1, nil
+	}
+	if err != nil {
+		return 0, 0, fmt.Errorf("failed to read sequence store %s: %w", s.path, err)
+	}
+	var state seqState
+	if err := json.Unmarshal(data, &state); err != nil {
+		return 0, 0, fmt.Errorf("failed to parse sequence store %s: %w", s.path, err)
+	}
+	return state.NextSender, state.NextTarget, nil
+}
+
+// Save writes the sequence numbers atomically through a temporary file
+func (s *FileStore) Save(nextSender, nextTarget int) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	data, err := json.Marshal(seqState{NextSender: nextSender, NextTarget: nextTarget})
+	if err != nil {
+		return err
+	}
+	tmp := s.path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0o644); err != nil {
+		return fmt.Errorf("failed to write sequence store %s: %w", tmp, err)
+	}
+	return os.Rename(tmp, s.path)
+}
@@ -0,0 +1,227 @@
+package kafka
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"sync"
+	"time"
+
+	kafkago "github.com/segmentio/kafka-go"
+)
+
+// Special timestamps accepted by ListOffset
+const (
+	OffsetLatest   = kafkago.LastOffset
+	OffsetEarliest = kafkago.FirstOffset
+)
+
+// Error is a non-zero error code returned by a broker
+type Error = kafkago.Error
+
+// Header is a key/value pair attached to a record
+type Header = kafkago.Header
+
+// Record is a single Kafka message
+type Record struct {
+	Offset    int64
+	Key       []byte
+	Value     []byte
+	Headers   []Header
+	Timestamp time.Time
+}
+
+// Client adapts a kafka-go client to the partition level produce, fetch and
+// offset lookup the bridges use. kafka-go routes each request to the leader
+// of its partition and keeps the cluster metadata up to date.
+type Client struct {
+	client  *kafkago.Client
+	dialer  *kafkago.Dialer
+	brokers []string
+	timeout time.Duration
+
+	mu         sync.Mutex
+	partitions map[string]int // Partition count by topic, dropped on errors
+}
+
+// NewClient creates a client and loads cluster metadata from the bootstrap brokers
+func NewClient(brokers []string, clientID string, timeout time.Duration) (*Client, error) {
+	if len(brokers) == 0 {
+		return nil, fmt.Errorf("kafka: at least one broker is required")
+	}
+	if timeout <= 0 {
+		timeout = 10 * time.Second
+	}
+	c := &Client{
+		client: &kafkago.Client{
+			Addr:    kafkago.TCP(brokers...),
+			Timeout: timeout,
+			Transport: &kafkago.Transport{
+				ClientID:    clientID,
+				DialTimeout: timeout,
+			},
+		},
+		dialer:     &kafkago.Dialer{ClientID: clientID, Timeout: timeout},
+		brokers:    brokers,
+		timeout:    timeout,
+		partitions: make(map[string]int),
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+	if _, err := c.client.Metadata(ctx, &kafkago.MetadataRequest{}); err != nil {
+		return nil, fmt.Errorf("kafka: failed to load metadata: %w", err)
+	}
+	return c, nil
+}
+
+// Close closes all broker connections
+func (c *Client) Close() error {
+	if t, ok := c.client.Transport.(*kafkago.Transport); ok {
+		t.CloseIdleConnections()
+	}
+	return nil
+}
+
+// Partitions returns the number of partitions of a topic
+func (c *Client) Partitions(topic string) (int, error) {
+	c.mu.Lock()
+	n, ok := c.partitions[topic]
+	c.mu.Unlock()
+	if ok {
+		return n, nil
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), c.timeout)
+	defer cancel()
+	resp, err := c.client.Metadata(ctx, &kafkago.MetadataRequest{Topics: []string{topic}})
+	if err != nil {
+		return 0, fmt.Errorf("kafka: failed to load metadata: %w", err)
+	}
+	for _, t := range resp.Topics {
+		if t.Name != topic {
+			continue
+		}
+		if t.Error != nil {
+			return 0, t.Error
+		}
+		c.mu.Lock()
+		c.partitions[topic] = len(t.Partitions)
+		c.mu.Unlock()
+		return len(t.Partitions), nil
+	}
+	return 0, kafkago.UnknownTopicOrPartition
+}
+
+// Produce writes records to a partition and waits for all in-sync replicas
+// to acknowledge them. It returns the offset assigned to the first record.
+func (c *Client) Produce(topic string, partition int, records []Record) (int64, error) {
+	batch := make([]kafkago.Record, len(records))
+	for i, r := range records {
+		batch[i] = kafkago.Record{
+			Time:    r.Timestamp,
+			Key:     kafkago.NewBytes(r.Key),
+			Value:   kafkago.NewBytes(r.Value),
+			Headers: r.Headers,
+		}
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 2*c.timeout)
+	defer cancel()
+	resp, err := c.client.Produce(ctx, &kafkago.ProduceRequest{
+		Topic:        topic,
+		Partition:    partition,
+		RequiredAcks: kafkago.RequireAll,
+		Records:      kafkago.NewRecordReader(batch...),
+	})
+	if err == nil {
+		err = resp.Error
+	}
+	if err != nil {
+		c.invalidate(topic)
+		return 0, err
+	}
+	return resp.BaseOffset, nil
+}
+
+// Fetch reads records of a partition starting at offset. It returns the
+// records and the partition high watermark.
+func (c *Client) Fetch(topic string, partition int, offset int64, maxWait time.Duration, maxBytes int) ([]Record, int64, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), maxWait+c.timeout)
+	defer cancel()
+	resp, err := c.client.Fetch(ctx, &kafkago.FetchRequest{
+		Topic:     topic,
+		Partition: partition,
+		Offset:    offset,
+		MinBytes:  1,
+		MaxBytes:  int64(maxBytes),
+		MaxWait:   maxWait,
+	})
+	if err != nil {
+		c.invalidate(topic)
+		return nil, 0, err
+	}
+	if resp.Error != nil {
+		c.invalidate(topic)
+		return nil, resp.HighWatermark, resp.Error
+	}
+
+	var records []Record
+	for {
+		r, err := resp.Records.ReadRecord()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, resp.HighWatermark, fmt.Errorf("kafka: failed to read records: %w", err)
+		}
+		// Batches may start before the requested offset
+		if r.Offset < offset {
+			continue
+		}
+		record := Record{Offset: r.Offset, Headers: r.Headers, Timestamp: r.Time}
+		if record.Key, err = kafkago.ReadAll(r.Key); err != nil {
+			return nil, resp.HighWatermark, fmt.Errorf("kafka: failed to read record key: %w", err)
+		}
+		if record.Value, err = kafkago.ReadAll(r.Value); err != nil {
+			return nil, resp.HighWatermark, fmt.Errorf("kafka: failed to read record value: %w", err)
+		}
+		records = append(records, record)
+	}
+	return records, resp.HighWatermark, nil
+}
+
+// ListOffset returns the offset of a partition for a timestamp, or for
+// OffsetLatest / OffsetEarliest
+func (c *Client) ListOffset(topic string, partition int, timestamp int64) (int64, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), c.timeout)
+	defer cancel()
+	var conn *kafkago.Conn
+	var err error
+	for _, broker := range c.brokers {
+		if conn, err = c.dialer.DialLeader(ctx, "tcp", broker, topic, partition); err == nil {
+			break
+		}
+	}
+	if err != nil {
+		c.invalidate(topic)
+		return 0, fmt.Errorf("kafka: failed to connect to the leader of %s/%d: %w", topic, partition, err)
+	}
+	defer conn.Close()
+	conn.SetDeadline(time.Now().Add(c.timeout))
+
+	switch timestamp {
+	case OffsetLatest:
+		return conn.ReadLastOffset()
+	case OffsetEarliest:
+		return conn.ReadFirstOffset()
+	default:
+		return conn.ReadOffset(time.UnixMilli(timestamp))
+	}
+}
+
+// invalidate drops the cached partition count so the next request reloads it
+func (c *Client) invalidate(topic string) {
+	c.mu.Lock()
+	delete(c.partitions, topic)
+	c.mu.Unlock()
+}
@@ -0,0 +1,33 @@
+package kafka
+
+import "testing"
+
+func TestPartition_MatchesJavaClient(t *testing.T) {
+	// Reference murmur2 values from the Kafka Java client utility tests
+	tests := map[string]int32{
+		"21":                         -973932308,
+		"foobar":                     -790332482,
+		"a-little-bit-long-string":   -985981536,
+		"a-little-bit-longer-string": -1486304829,
+		"lkjh234lh9fiuh90y23oiuhsafujhadof229phr9h19h89h8": -58897971,
+		"abc": 479470107,
+	}
+	for key, expected := range tests {
+		partition := int(uint32(expected)&0x7fffffff) % 6
+		if got := Partition([]byte(key), 6); got != partition {
+			t.Errorf("Partition(%q, 6) = %d, expected %d", key, got, partition)
+		}
+	}
+}
+
+func TestPartition_InRange(t *testing.T) {
+	for _, key := range []string{"btcusdt", "ethusdt", "solusdt", ""} {
+		p := Partition([]byte(key), 6)
+		if p < 0 || p >= 6 {
+			t.Errorf("Partition(%q) = %d, out of range", key, p)
+		}
+		if p != Partition([]byte(key), 6) {
+			t.Errorf("Partition(%q) is not deterministic", key)
+		}
+	}
+}
@@ -0,0 +1,20 @@
+package kafka
+
+import kafkago "github.com/segmentio/kafka-go"
+
+// keyed hashes every key, nil included, so a partition depends on the key alone
+var keyed = kafkago.Murmur2Balancer{Consistent: true}
+
+// Partition returns the partition of a key using the murmur2 hash, matching
+// the default partitioner of the Java client so that records keyed by the
+// same symbol land on the same partition regardless of the producer.
+func Partition(key []byte, partitions int) int {
+	if partitions <= 0 {
+		return 0
+	}
+	ids := make([]int, partitions)
+	for i := range ids {
+		ids[i] = i
+	}
+	return keyed.Balance(kafkago.Message{Key: key}, ids...)
+}
@@ -0,0 +1,125 @@
+// Package lob simulates resting limit orders against a recorded order book
+// for backtests of passive strategies. The book is rebuilt from depth
+// snapshots and diffs, and each simulated order keeps track of the visible
+// quantity queued ahead of it at its price, so it only fills once trades
+// have consumed that queue or traded through its price, rather than as soon
+// as the price is touched.
+package lob
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// Side is the side of an order, or the aggressor side of a trade
+type Side int
+
+const (
+	Buy Side = iota + 1
+	Sell
+)
+
+func (s Side) String() string {
+	switch s {
+	case Buy:
+		return "BUY"
+	case Sell:
+		return "SELL"
+	}
+	return "UNKNOWN"
+}
+
+// MarshalText implements encoding.TextMarshaler
+func (s Side) MarshalText() ([]byte, error) {
+	return []byte(s.String()), nil
+}
+
+// UnmarshalText implements encoding.TextUnmarshaler
+func (s *Side) UnmarshalText(text []byte) error {
+	switch strings.ToUpper(string(text)) {
+	case "BUY":
+		*s = Buy
+	case "SELL":
+		*s = Sell
+	default:
+		return fmt.Errorf("unknown side %q", text)
+	}
+	return nil
+}
+
+// Level is the total quantity at a price. In JSON it is a [price, quantity]
+// pair as in exchange depth streams.
+type Level struct {
+	Price    float64
+	Quantity float64
+}
+
+// MarshalJSON implements json.Marshaler
+func (l Level) MarshalJSON() ([]byte, error) {
+	return json.Marshal([2]float64{l.Price, l.Quantity})
+}
+
+// UnmarshalJSON implements json.Unmarshaler
+func (l *Level) UnmarshalJSON(data []byte) error {
+	var pair [2]float64
+	if err := json.Unmarshal(data, &pair); err != nil {
+		return fmt.Errorf("level must be a [price, quantity] pair: %w", err)
+	}
+	l.Price, l.Quantity = pair[0], pair[1]
+	return nil
+}
+
+// book holds the visible quantity per price of both sides
+type book struct {
+	bids map[float64]float64
+	asks map[float64]float64
+}
+
+func newBook() *book {
+	return &book{bids: make(map[float64]float64), asks: make(map[float64]float64)}
+}
+
+func (b *book) side(side Side) map[float64]float64 {
+	if side == Buy {
+		return b.bids
+	}
+	return b.asks
+}
+
+// best returns the best price of a side, false when it is empty
+func (b *book) best(side Side) (float64, bool) {
+	var best float64
+	found := false
+	for price := range b.side(side) {
+		if !found || (side == Buy && price > best) || (side == Sell && price < best) {
+			best, found = price, true
+		}
+	}
+	return best, found
+}
+
+// levels returns a side from the best price outwards
+func (b *book) levels(side Side) []Level {
+	levels := make([]Level, 0, len(b.side(side)))
+	for price, quantity := range b.side(side) {
+		levels = append(levels, Level{Price: price, Quantity: quantity})
+	}
+	sort.Slice(levels, func(i, j int) bool {
+		if side == Buy {
+			return levels[i].Price > levels[j].Price
+		}
+		return levels[i].Price < levels[j].Price
+	})
+	return levels
+}
+
+// set replaces the quantity at a price, removing the level at 0
+func (b *book) set(side Side, price, quantity float64) {
+	if quantity <= 0 {
+		delete(b.side(side), price)
+		return
+	}
+	b.side(side)[price] = quantity
+}
@@ -0,0 +1,79 @@
+package lob
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+)
+
+// EventType is the kind of a recorded market data event
+type EventType string
+
+const (
+	EventSnapshot EventType = "snapshot" // Full book
+	EventDepth    EventType = "depth"    // Diff of absolute quantities
+	EventTrade    EventType = "trade"
+)
+
+// Event is a recorded market data event. Recordings hold one JSON event per
+// line, e.g.
+//
+//	{"type":"snapshot","time":1700000000000,"bids":[[42000.1,1.5]],"asks":[[42000.2,0.8]]}
+//	{"type":"trade","time":1700000000050,"price":42000.1,"quantity":0.4,"side":"SELL"}
+//	{"type":"depth","time":1700000000100,"bids":[[42000.1,0.9]]}
+type Event struct {
+	Type     EventType `json:"type"`
+	Time     int64     `json:"time"` // Unix milliseconds
+	Bids     []Level   `json:"bids,omitempty"`
+	Asks     []Level   `json:"asks,omitempty"`
+	Price    float64   `json:"price,omitempty"`
+	Quantity float64   `json:"quantity,omitempty"`
+	Side     Side      `json:"side,omitempty"` // Aggressor side of a trade
+}
+
+// Apply applies a recorded event to the book
+func (s *Simulator) Apply(e Event) ([]Fill, error) {
+	switch e.Type {
+	case EventSnapshot:
+		return s.Snapshot(e.Time, e.Bids, e.Asks), nil
+	case EventDepth:
+		return s.Update(e.Time, e.Bids, e.Asks), nil
+	case EventTrade:
+		if e.Side != Buy && e.Side != Sell {
+			return nil, fmt.Errorf("trade without aggressor side")
+		}
+		return s.Trade(e.Time, e.Price, e.Quantity, e.Side), nil
+	}
+	return nil, fmt.Errorf("unknown event type %q", e.Type)
+}
+
+// Replay applies the events of a recording in order, handing each one to
+// handle with the fills it caused, where a strategy places and cancels its
+// orders. It returns the number of events applied.
+func Replay(r io.Reader, s *Simulator, handle func(Event, []Fill) error) (int, error) {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), 16*1024*1024) // Snapshots of deep books are long lines
+	applied, line := 0, 0
+	for scanner.Scan() {
+		line++
+		if len(scanner.Bytes()) == 0 {
+			continue
+		}
+		var e Event
+		if err := json.Unmarshal(scanner.Bytes(), &e); err != nil {
+			return applied, fmt.Errorf("line %d: %w", line, err)
+		}
+		fills, err := s.Apply(e)
+		if err != nil {
+			return applied, fmt.Errorf("line %d: %w", line, err)
+		}
+		applied++
+		if handle != nil {
+			if err := handle(e, fills); err != nil {
+				return applied, err
+			}
+		}
+	}
+	return applied, scanner.Err()
+}
@@ -0,0 +1,229 @@
+package lob
+
+import (
+	"fmt"
+	"math"
+	"strings"
+	"testing"
+	"time"
+)
+
+func near(a, b float64) bool {
+	return math.Abs(a-b) < 1e-9
+}
+
+func TestQueuePosition(t *testing.T) {
+	s := New(Options{})
+	s.Snapshot(0, []Level{{100, 5}, {99, 3}}, []Level{{101, 2}})
+	if fills, err := s.Place(1, "bid", Buy, 100, 1); err != nil || len(fills) != 0 {
+		t.Fatalf("place: %v, %v", fills, err)
+	}
+	if o, _ := s.Order("bid"); !o.Rested || o.QueueAtEntry != 5 {
+		t.Fatalf("order = %+v", o)
+	}
+
+	// Touching the price only consumes the queue ahead
+	if fills := s.Trade(2, 100, 3, Sell); len(fills) != 0 {
+		t.Errorf("filled with 2 ahead: %+v", fills)
+	}
+	fills := s.Trade(3, 100, 2.5, Sell)
+	if len(fills) != 1 || !near(fills[0].Quantity, 0.5) || !fills[0].Maker {
+		t.Fatalf("fills = %+v", fills)
+	}
+	// A trade below the bid went through it
+	fills = s.Trade(4, 99, 0.1, Sell)
+	if len(fills) != 1 || !near(fills[0].Quantity, 0.5) || fills[0].Price != 100 {
+		t.Fatalf("fills = %+v", fills)
+	}
+	if o, _ := s.Order("bid"); o.Status != StatusFilled || o.FirstFillAt != 3 || o.FilledAt != 4 {
+		t.Errorf("order = %+v", o)
+	}
+}
+
+func TestQueueOfOwnOrders(t *testing.T) {
+	s := New(Options{})
+	s.Snapshot(0, nil, []Level{{101, 2}})
+	s.Place(1, "first", Sell, 101, 1)
+	s.Place(2, "second", Sell, 101, 1)
+	// 2 ahead of both, then the first order, then the second
+	fills := s.Trade(3, 101, 3.5, Buy)
+	if len(fills) != 2 || fills[0].OrderID != "first" || !near(fills[0].Quantity, 1) || !near(fills[1].Quantity, 0.5) {
+		t.Errorf("fills = %+v", fills)
+	}
+}
+
+func TestQueueModels(t *testing.T) {
+	for _, tt := range []struct {
+		model QueueModel
+		ahead float64
+	}{
+		{QueueProportional, 2.8},
+		{QueuePessimistic, 4},
+		{QueueOptimistic, 1},
+	} {
+		s := New(Options{Queue: tt.model})
+		s.Snapshot(0, []Level{{100, 4}}, nil)
+		s.Place(1, "bid", Buy, 100, 1)
+		s.Update(2, []Level{{100, 10}}, nil) // 6 join behind
+		s.Update(3, []Level{{100, 7}}, nil)  // 3 cancelled
+		if o, _ := s.Order("bid"); !near(o.QueueAhead, tt.ahead) {
+			t.Errorf("model %d: ahead = %v, want %v", tt.model, o.QueueAhead, tt.ahead)
+		}
+	}
+
+	// Traded quantity is not mistaken for cancels
+	s := New(Options{Queue: QueueOptimistic})
+	s.Snapshot(0, []Level{{100, 4}}, nil)
+	s.Place(1, "bid", Buy, 100, 1)
+	s.Update(2, []Level{{100, 10}}, nil)
+	s.Trade(3, 100, 3, Sell)
+	s.Update(4, []Level{{100, 7}}, nil)
+	if o, _ := s.Order("bid"); !near(o.QueueAhead, 1) {
+		t.Errorf("ahead = %v after trade", o.QueueAhead)
+	}
+}
+
+func TestLatency(t *testing.T) {
+	s := New(Options{Latency: 100 * time.Millisecond})
+	s.Snapshot(0, []Level{{100, 1}}, []Level{{101, 1}, {102, 2}})
+	if fills, _ := s.Place(0, "buy", Buy, 102, 2); len(fills) != 0 {
+		t.Fatalf("filled before reaching the book: %+v", fills)
+	}
+	if o, _ := s.Order("buy"); o.Status != StatusPending {
+		t.Fatalf("status = %s", o.Status)
+	}
+	// The order crosses once it arrives and takes both ask levels
+	fills := s.Update(100, nil, nil)
+	if len(fills) != 2 || fills[0].Price != 101 || fills[1].Price != 102 || fills[0].Maker {
+		t.Fatalf("fills = %+v", fills)
+	}
+	if o, _ := s.Order("buy"); o.Rested || o.Status != StatusFilled || o.FilledAt != 100 {
+		t.Errorf("order = %+v", o)
+	}
+	if levels := s.Levels(Sell); len(levels) != 1 || levels[0] != (Level{102, 1}) {
+		t.Errorf("asks = %+v", levels)
+	}
+
+	// A cancel in flight does not prevent a fill
+	s.Place(200, "bid", Buy, 100, 1)
+	s.Update(300, nil, nil)
+	s.Cancel(300, "bid")
+	if fills := s.Trade(350, 99, 1, Sell); len(fills) != 1 {
+		t.Errorf("fills = %+v", fills)
+	}
+	s.Place(400, "late", Buy, 100, 1)
+	s.Cancel(450, "late")
+	s.Update(600, nil, nil)
+	if o, _ := s.Order("late"); o.Status != StatusCanceled || o.CanceledAt != 550 {
+		t.Errorf("order = %+v", o)
+	}
+}
+
+func TestJitterAndFillSplits(t *testing.T) {
+	run := func(seed int64) ([]int64, []Fill) {
+		s := New(Options{Latency: 50 * time.Millisecond, Jitter: 40 * time.Millisecond, FillSplits: 4, Seed: seed})
+		s.Snapshot(0, []Level{{100, 1}}, []Level{{101, 1}})
+		var arrivals []int64
+		var fills []Fill
+		for i := 0; i < 20; i++ {
+			id := string(rune('a' + i))
+			s.Place(int64(i), id, Buy, 100, 1)
+			o, _ := s.Order(id)
+			arrivals = append(arrivals, o.ActiveAt)
+		}
+		s.Update(1000, nil, nil)
+		fills = append(fills, s.Trade(1001, 99, 1, Sell)...)
+		return arrivals, fills
+	}
+	arrivals, fills := run(7)
+	for i, at := range arrivals {
+		if latency := at - int64(i); latency < 50 || latency > 90 {
+			t.Fatalf("latency %d of order %d out of range", latency, i)
+		}
+	}
+	// Every order traded through, in executions adding up to it
+	filled := make(map[string]float64)
+	for _, f := range fills {
+		if f.Quantity <= 0 || f.Price != 100 || !f.Maker {
+			t.Fatalf("fill %+v", f)
+		}
+		filled[f.OrderID] += f.Quantity
+	}
+	if len(filled) != 20 || len(fills) <= 20 {
+		t.Fatalf("%d fills of %d orders", len(fills), len(filled))
+	}
+	for id, q := range filled {
+		if !near(q, 1) {
+			t.Errorf("order %s filled %v", id, q)
+		}
+	}
+
+	again, againFills := run(7)
+	if fmt.Sprint(arrivals, fills) != fmt.Sprint(again, againFills) {
+		t.Error("runs of the same seed differ")
+	}
+	if other, _ := run(8); fmt.Sprint(arrivals) == fmt.Sprint(other) {
+		t.Error("runs of different seeds agree")
+	}
+
+	// A cancel overtaking its placement keeps it out of the book
+	s := New(Options{Latency: 100 * time.Millisecond})
+	s.Snapshot(0, []Level{{100, 1}}, nil)
+	s.Place(0, "bid", Buy, 100, 1)
+	s.send(action{at: 50, order: s.orders["bid"], cancel: true})
+	s.Update(200, nil, nil)
+	if o, _ := s.Order("bid"); o.Status != StatusCanceled || o.CanceledAt != 50 || o.Rested {
+		t.Errorf("order = %+v", o)
+	}
+}
+
+func TestEstimate(t *testing.T) {
+	orders := []Order{
+		{Rested: true, ActiveAt: 0, FirstFillAt: 1000, FilledAt: 1000, Status: StatusFilled},
+		{Rested: true, ActiveAt: 0, FirstFillAt: 3000, FilledAt: 3000, Status: StatusFilled},
+		{Rested: true, ActiveAt: 0, FirstFillAt: 500, FilledAt: 20000, Status: StatusFilled}, // Partial within 10s
+		{Rested: true, ActiveAt: 0, Status: StatusCanceled, CanceledAt: 15000},               // Observed unfilled
+		{Rested: true, ActiveAt: 0, Status: StatusCanceled, CanceledAt: 2000},                // Cancelled too early to tell
+		{Rested: true, ActiveAt: 25000, Status: StatusOpen},                                  // Open for 5s only
+		{Rested: false, ActiveAt: 0, FilledAt: 0, Status: StatusFilled},                      // Crossed on arrival
+	}
+	stats := Estimate(orders, 30000, 10*time.Second)
+	if stats.Orders != 4 || stats.Filled != 2 || stats.Partial != 1 || stats.FillProbability != 0.5 {
+		t.Errorf("stats = %+v", stats)
+	}
+	if stats.MeanTimeToFill != 2*time.Second || stats.P50TimeToFill != time.Second {
+		t.Errorf("times = %+v", stats)
+	}
+}
+
+func TestReplay(t *testing.T) {
+	recording := `{"type":"snapshot","time":0,"bids":[[100,2]],"asks":[[101,1]]}
+{"type":"depth","time":10,"bids":[[100,3]]}
+
+{"type":"trade","time":20,"price":100,"quantity":2.5,"side":"SELL"}
+{"type":"trade","time":30,"price":100,"quantity":1,"side":"SELL"}
+`
+	s := New(Options{})
+	var filled float64
+	n, err := Replay(strings.NewReader(recording), s, func(e Event, fills []Fill) error {
+		if e.Type == EventSnapshot {
+			if _, err := s.Place(e.Time, "bid", Buy, 100, 1); err != nil {
+				return err
+			}
+		}
+		for _, f := range fills {
+			filled += f.Quantity
+		}
+		return nil
+	})
+	if err != nil || n != 4 {
+		t.Fatalf("replayed %d: %v", n, err)
+	}
+	if !near(filled, 1) {
+		t.Errorf("filled = %v", filled)
+	}
+
+	if _, err := Replay(strings.NewReader(`{"type":"trade","time":1,"price":1,"quantity":1}`), New(Options{}), nil); err == nil || !strings.Contains(err.Error(), "line 1") {
+		t.Errorf("trade without side: %v", err)
+	}
+}
@@ -0,0 +1,383 @@
+package lob
+
+import (
+	"fmt"
+	"math/rand"
+	"sort"
+	"time"
+)
+
+// QueueModel decides how much of the quantity cancelled at a price was
+// queued ahead of a simulated order. Depth diffs only show the total, so
+// the share is an assumption; the pessimistic and optimistic models bound
+// the fills a strategy can expect.
+type QueueModel int
+
+const (
+	QueueProportional QueueModel = iota // Cancels spread evenly over the queue
+	QueuePessimistic                    // Cancels come from behind the order first
+	QueueOptimistic                     // Cancels come from ahead of the order first
+)
+
+// Options configures a simulator
+type Options struct {
+	Queue      QueueModel
+	Latency    time.Duration // Delay before placements and cancels reach the book
+	Jitter     time.Duration // Up to which is added at random to the latency of each placement and cancel
+	FillSplits int           // Fills of resting orders are split at random into up to as many executions, 1 when 0
+	Seed       int64         // Of the jitter and the fill splits, 1 when 0, so runs are reproducible
+}
+
+// Status is the state of a simulated order
+type Status string
+
+const (
+	StatusPending  Status = "pending" // Sent, not yet in the book
+	StatusOpen     Status = "open"
+	StatusFilled   Status = "filled"
+	StatusCanceled Status = "canceled"
+)
+
+// Order is a simulated limit order. Times are Unix milliseconds.
+type Order struct {
+	ID           string  `json:"id"`
+	Side         Side    `json:"side"`
+	Price        float64 `json:"price"`
+	Quantity     float64 `json:"quantity"`
+	Filled       float64 `json:"filled"`
+	Status       Status  `json:"status"`
+	PlacedAt     int64   `json:"placed_at"`
+	ActiveAt     int64   `json:"active_at"`      // Placement plus latency
+	Rested       bool    `json:"rested"`         // Joined the book rather than filling on arrival
+	QueueAtEntry float64 `json:"queue_at_entry"` // Visible quantity ahead when it joined the book
+	QueueAhead   float64 `json:"queue_ahead"`    // Quantity still ahead of it
+	FirstFillAt  int64   `json:"first_fill_at,omitempty"`
+	FilledAt     int64   `json:"filled_at,omitempty"`
+	CanceledAt   int64   `json:"canceled_at,omitempty"`
+}
+
+// Remaining returns the quantity left to fill
+func (o *Order) Remaining() float64 {
+	return o.Quantity - o.Filled
+}
+
+// Fill is an execution of a simulated order
+type Fill struct {
+	OrderID  string  `json:"order_id"`
+	Time     int64   `json:"time"`
+	Price    float64 `json:"price"`
+	Quantity float64 `json:"quantity"`
+	Maker    bool    `json:"maker"` // False when the order crossed the spread on arrival
+}
+
+// action is a placement or cancel on its way to the book
+type action struct {
+	at     int64
+	order  *Order
+	cancel bool
+}
+
+// Simulator replays market data and fills the simulated orders resting in
+// it. Trades are expected before the depth diff that reflects them, as
+// exchanges publish them. It is not safe for concurrent use.
+type Simulator struct {
+	opts     Options
+	book     *book
+	now      int64
+	orders   map[string]*Order
+	sequence []*Order // In placement order, which is queue order at a price
+	inflight []action // Ordered by arrival time, then by sending
+	rand     *rand.Rand
+}
+
+// New creates a simulator with an empty book
+func New(opts Options) *Simulator {
+	if opts.Seed == 0 {
+		opts.Seed = 1
+	}
+	return &Simulator{opts: opts, book: newBook(), orders: make(map[string]*Order), rand: rand.New(rand.NewSource(opts.Seed))}
+}
+
+// Now returns the time of the last event, in Unix milliseconds
+func (s *Simulator) Now() int64 {
+	return s.now
+}
+
+// Best returns the best price of a side, false when it is empty
+func (s *Simulator) Best(side Side) (float64, bool) {
+	return s.book.best(side)
+}
+
+// Levels returns a side of the book from the best price outwards
+func (s *Simulator) Levels(side Side) []Level {
+	return s.book.levels(side)
+}
+
+// Snapshot replaces the book, e.g. after a resync. The queue ahead of
+// resting orders is capped at the new quantity of their price.
+func (s *Simulator) Snapshot(at int64, bids, asks []Level) []Fill {
+	fills := s.advance(at)
+	s.book = newBook()
+	for _, l := range bids {
+		s.book.set(Buy, l.Price, l.Quantity)
+	}
+	for _, l := range asks {
+		s.book.set(Sell, l.Price, l.Quantity)
+	}
+	for _, o := range s.sequence {
+		if o.Status == StatusOpen {
+			o.QueueAhead = min(o.QueueAhead, s.book.side(o.Side)[o.Price])
+		}
+	}
+	return fills
+}
+
+// Update applies a depth diff of absolute quantities, 0 removing a level.
+// A decrease not explained by trades is a cancel, which shortens the queue
+// ahead of the orders at that price as the queue model assumes.
+func (s *Simulator) Update(at int64, bids, asks []Level) []Fill {
+	fills := s.advance(at)
+	for _, l := range bids {
+		s.updateLevel(Buy, l)
+	}
+	for _, l := range asks {
+		s.updateLevel(Sell, l)
+	}
+	return fills
+}
+
+func (s *Simulator) updateLevel(side Side, l Level) {
+	current := s.book.side(side)[l.Price]
+	quantity := max(l.Quantity, 0)
+	if canceled := current - quantity; canceled > 0 {
+		for _, o := range s.sequence {
+			if o.Status == StatusOpen && o.Side == side && o.Price == l.Price {
+				o.QueueAhead = s.afterCancel(o.QueueAhead, current, canceled)
+			}
+		}
+	}
+	for _, o := range s.sequence {
+		if o.Status == StatusOpen && o.Side == side && o.Price == l.Price {
+			o.QueueAhead = min(o.QueueAhead, quantity)
+		}
+	}
+	s.book.set(side, l.Price, quantity)
+}
+
+// afterCancel returns the queue ahead of an order once canceled of the
+// total quantity at its price was cancelled
+func (s *Simulator) afterCancel(ahead, total, canceled float64) float64 {
+	switch s.opts.Queue {
+	case QueuePessimistic:
+		behind := total - ahead
+		return max(ahead-max(canceled-behind, 0), 0)
+	case QueueOptimistic:
+		return max(ahead-canceled, 0)
+	default:
+		if total <= 0 {
+			return 0
+		}
+		return max(ahead-canceled*ahead/total, 0)
+	}
+}
+
+// Trade applies a trade. The aggressor side is the side that crossed the
+// spread: a sell trade consumes the bids. Orders at the trade price fill
+// with what is left once the queue ahead of them is consumed; orders at a
+// better price were traded through and fill entirely.
+func (s *Simulator) Trade(at int64, price, quantity float64, aggressor Side) []Fill {
+	fills := s.advance(at)
+	resting := Buy
+	if aggressor == Buy {
+		resting = Sell
+	}
+	var ownFilled float64 // Own fills at the trade price, queued ahead of later own orders
+	for _, o := range s.sequence {
+		if o.Status != StatusOpen || o.Side != resting {
+			continue
+		}
+		switch {
+		case (resting == Buy && o.Price > price) || (resting == Sell && o.Price < price):
+			fills = append(fills, s.split(s.fill(o, at, o.Price, o.Remaining(), true))...)
+		case o.Price == price:
+			volume := max(quantity-ownFilled, 0)
+			filled := min(o.Remaining(), max(volume-o.QueueAhead, 0))
+			o.QueueAhead = max(o.QueueAhead-volume, 0)
+			if filled > 0 {
+				ownFilled += filled
+				fills = append(fills, s.split(s.fill(o, at, price, filled, true))...)
+			}
+		}
+	}
+	s.book.set(resting, price, s.book.side(resting)[price]-quantity)
+	return fills
+}
+
+// Place sends a limit order, which reaches the book after the latency. An
+// order crossing the spread on arrival fills against the book up to its
+// price and rests with what is left.
+func (s *Simulator) Place(at int64, id string, side Side, price, quantity float64) ([]Fill, error) {
+	if id == "" {
+		return nil, fmt.Errorf("order id cannot be empty")
+	}
+	if _, ok := s.orders[id]; ok {
+		return nil, fmt.Errorf("order %s already exists", id)
+	}
+	if side != Buy && side != Sell {
+		return nil, fmt.Errorf("order %s: invalid side", id)
+	}
+	if price <= 0 || quantity <= 0 {
+		return nil, fmt.Errorf("order %s: price and quantity must be positive", id)
+	}
+	fills := s.advance(at)
+	o := &Order{
+		ID:       id,
+		Side:     side,
+		Price:    price,
+		Quantity: quantity,
+		Status:   StatusPending,
+		PlacedAt: at,
+		ActiveAt: at + s.latency(),
+	}
+	s.orders[id] = o
+	s.sequence = append(s.sequence, o)
+	s.send(action{at: o.ActiveAt, order: o})
+	return append(fills, s.advance(at)...), nil
+}
+
+// Cancel sends a cancel, which reaches the book after the latency; the
+// order can still fill in the meantime
+func (s *Simulator) Cancel(at int64, id string) ([]Fill, error) {
+	o, ok := s.orders[id]
+	if !ok {
+		return nil, fmt.Errorf("order %s not found", id)
+	}
+	fills := s.advance(at)
+	if o.Status == StatusFilled || o.Status == StatusCanceled {
+		return fills, fmt.Errorf("order %s is %s", id, o.Status)
+	}
+	s.send(action{at: at + s.latency(), order: o, cancel: true})
+	return append(fills, s.advance(at)...), nil
+}
+
+// latency returns the delay of a placement or cancel, in milliseconds
+func (s *Simulator) latency() int64 {
+	latency := s.opts.Latency.Milliseconds()
+	if jitter := s.opts.Jitter.Milliseconds(); jitter > 0 {
+		latency += s.rand.Int63n(jitter + 1)
+	}
+	return latency
+}
+
+// send queues an action by its arrival time. With jitter, a cancel can
+// arrive before its placement, which then never reaches the book.
+func (s *Simulator) send(a action) {
+	i := sort.Search(len(s.inflight), func(i int) bool { return s.inflight[i].at > a.at })
+	s.inflight = append(s.inflight, action{})
+	copy(s.inflight[i+1:], s.inflight[i:])
+	s.inflight[i] = a
+}
+
+// advance moves the clock to at, applying the placements and cancels that
+// reached the book by then
+func (s *Simulator) advance(at int64) []Fill {
+	s.now = max(s.now, at)
+	var fills []Fill
+	for len(s.inflight) > 0 && s.inflight[0].at <= s.now {
+		a := s.inflight[0]
+		s.inflight = s.inflight[1:]
+		switch {
+		case a.cancel:
+			if a.order.Status == StatusOpen || a.order.Status == StatusPending {
+				a.order.Status, a.order.CanceledAt = StatusCanceled, a.at
+			}
+		case a.order.Status == StatusPending:
+			fills = append(fills, s.activate(a.order)...)
+		}
+	}
+	return fills
+}
+
+// activate puts an order in the book, taking liquidity first if it crosses
+func (s *Simulator) activate(o *Order) []Fill {
+	o.Status = StatusOpen
+	opposite := Sell
+	if o.Side == Sell {
+		opposite = Buy
+	}
+	var fills []Fill
+	for _, l := range s.book.levels(opposite) {
+		if o.Status != StatusOpen || (o.Side == Buy && l.Price > o.Price) || (o.Side == Sell && l.Price < o.Price) {
+			break
+		}
+		taken := min(o.Remaining(), l.Quantity)
+		s.book.set(opposite, l.Price, l.Quantity-taken)
+		fills = append(fills, s.fill(o, o.ActiveAt, l.Price, taken, false))
+	}
+	if o.Status == StatusOpen {
+		o.Rested = true
+		o.QueueAtEntry = s.book.side(o.Side)[o.Price]
+		o.QueueAhead = o.QueueAtEntry
+	}
+	return fills
+}
+
+func (s *Simulator) fill(o *Order, at int64, price, quantity float64, maker bool) Fill {
+	o.Filled += quantity
+	if o.FirstFillAt == 0 {
+		o.FirstFillAt = at
+	}
+	if o.Remaining() <= o.Quantity*1e-9 {
+		o.Filled = o.Quantity
+		o.Status, o.FilledAt, o.QueueAhead = StatusFilled, at, 0
+	}
+	return Fill{OrderID: o.ID, Time: at, Price: price, Quantity: quantity, Maker: maker}
+}
+
+// split divides a fill of a resting order into up to FillSplits executions
+// of random sizes, as exchanges report an order filled by several takers
+func (s *Simulator) split(f Fill) []Fill {
+	if s.opts.FillSplits <= 1 {
+		return []Fill{f}
+	}
+	n := 1 + s.rand.Intn(s.opts.FillSplits)
+	if n == 1 {
+		return []Fill{f}
+	}
+	weights := make([]float64, n)
+	var total float64
+	for i := range weights {
+		weights[i] = 0.1 + s.rand.Float64() // Bounded away from empty executions
+		total += weights[i]
+	}
+	fills := make([]Fill, n)
+	left := f.Quantity
+	for i := range fills {
+		fills[i] = f
+		if i < n-1 {
+			fills[i].Quantity = f.Quantity * weights[i] / total
+			left -= fills[i].Quantity
+		} else {
+			fills[i].Quantity = left // The rest, so the executions add up to the fill
+		}
+	}
+	return fills
+}
+
+// Order returns a copy of an order
+func (s *Simulator) Order(id string) (Order, bool) {
+	o, ok := s.orders[id]
+	if !ok {
+		return Order{}, false
+	}
+	return *o, true
+}
+
+// Orders returns a copy of every order in placement order
+func (s *Simulator) Orders() []Order {
+	orders := make([]Order, len(s.sequence))
+	for i, o := range s.sequence {
+		orders[i] = *o
+	}
+	return orders
+}
@@ -0,0 +1,81 @@
+package lob
+
+import (
+	"sort"
+	"time"
+)
+
+// FillStats estimates how likely resting orders are to fill within a
+// horizon and how long they take to
+type FillStats struct {
+	Orders          int           `json:"orders"`           // Resting orders filled within the horizon or observed for all of it
+	Filled          int           `json:"filled"`           // Filled entirely within the horizon
+	Partial         int           `json:"partial"`          // Filled in part only within the horizon
+	FillProbability float64       `json:"fill_probability"` // Filled over Orders
+	MeanTimeToFill  time.Duration `json:"mean_time_to_fill"`
+	P50TimeToFill   time.Duration `json:"p50_time_to_fill"`
+	P90TimeToFill   time.Duration `json:"p90_time_to_fill"`
+}
+
+// Stats estimates the fills of the orders that rested in the book, up to
+// the last event. A horizon of 0 counts every fill.
+func (s *Simulator) Stats(horizon time.Duration) FillStats {
+	return Estimate(s.Orders(), s.now, horizon)
+}
+
+// Estimate computes the fill statistics of orders as of until, in Unix
+// milliseconds, e.g. of the orders of one queue position bucket. Orders
+// that crossed the spread on arrival are left out, as are orders cancelled
+// or still open before the horizon elapsed without filling, since whether
+// they would have filled is unknown.
+func Estimate(orders []Order, until int64, horizon time.Duration) FillStats {
+	var stats FillStats
+	var times []time.Duration
+	window := horizon.Milliseconds()
+	for _, o := range orders {
+		if !o.Rested {
+			continue
+		}
+		within := func(at int64) bool { return at != 0 && (window == 0 || at-o.ActiveAt <= window) }
+		if !within(o.FilledAt) && !observed(o, until, window) {
+			continue
+		}
+		stats.Orders++
+		switch {
+		case within(o.FilledAt):
+			stats.Filled++
+			times = append(times, time.Duration(o.FilledAt-o.ActiveAt)*time.Millisecond)
+		case within(o.FirstFillAt):
+			stats.Partial++
+		}
+	}
+	if stats.Orders == 0 {
+		return stats
+	}
+	stats.FillProbability = float64(stats.Filled) / float64(stats.Orders)
+	if len(times) > 0 {
+		sort.Slice(times, func(i, j int) bool { return times[i] < times[j] })
+		var total time.Duration
+		for _, d := range times {
+			total += d
+		}
+		stats.MeanTimeToFill = total / time.Duration(len(times))
+		stats.P50TimeToFill = times[(len(times)-1)/2]
+		stats.P90TimeToFill = times[(len(times)-1)*9/10]
+	}
+	return stats
+}
+
+// observed reports whether an order not filled within the horizon is known
+// not to have: it stayed in the book for the whole horizon, or, without a
+// horizon, it was cancelled
+func observed(o Order, until, window int64) bool {
+	if window == 0 {
+		return o.Status == StatusCanceled
+	}
+	end := until
+	if o.Status == StatusCanceled {
+		end = o.CanceledAt
+	}
+	return end-o.ActiveAt >= window
+}
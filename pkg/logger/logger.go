@@ -24,7 +24,7 @@ func init() {
 	zerolog.SetGlobalLevel(zerolog.DebugLevel) // More verbose in dev
 
 	// For development, use the console writer
-	Log = zerolog.New(outputWriter).
+	Log = zerolog.New(zerolog.MultiLevelWriter(outputWriter, &shipping)).
 		With().
 		Timestamp().
 		Caller().
@@ -0,0 +1,75 @@
+package logger
+
+import (
+	"sync/atomic"
+
+	"github.com/nats-io/nats.go"
+	"github.com/rs/zerolog"
+)
+
+// Shipper mirrors the log records at or above a level to a NATS subject as
+// the JSON lines zerolog writes, for viewing the logs of every node in one
+// place. Records are published without waiting, so a slow or unreachable
+// server never blocks logging; records failing to publish are dropped.
+type Shipper struct {
+	conn    *nats.Conn
+	subject string
+	level   zerolog.Level
+	shipped atomic.Uint64
+	dropped atomic.Uint64
+}
+
+// NewShipper creates a shipper of the records at or above level to subject
+func NewShipper(conn *nats.Conn, subject string, level zerolog.Level) *Shipper {
+	return &Shipper{conn: conn, subject: subject, level: level}
+}
+
+// Write ships nothing as records without a level are below any level
+func (s *Shipper) Write(p []byte) (int, error) {
+	return len(p), nil
+}
+
+// WriteLevel publishes a record at or above the level of the shipper
+func (s *Shipper) WriteLevel(level zerolog.Level, p []byte) (int, error) {
+	if level < s.level || level == zerolog.NoLevel {
+		return len(p), nil
+	}
+	// Never log the failure: it would be shipped and fail again
+	if err := s.conn.Publish(s.subject, p); err != nil {
+		s.dropped.Add(1)
+	} else {
+		s.shipped.Add(1)
+	}
+	return len(p), nil
+}
+
+// Stats returns the records shipped and dropped
+func (s *Shipper) Stats() (shipped, dropped uint64) {
+	return s.shipped.Load(), s.dropped.Load()
+}
+
+// shipTarget forwards the records of Log to the shipper installed by Ship
+type shipTarget struct {
+	shipper atomic.Pointer[Shipper]
+}
+
+var shipping shipTarget
+
+func (t *shipTarget) Write(p []byte) (int, error) {
+	return len(p), nil
+}
+
+func (t *shipTarget) WriteLevel(level zerolog.Level, p []byte) (int, error) {
+	if shipper := t.shipper.Load(); shipper != nil {
+		return shipper.WriteLevel(level, p)
+	}
+	return len(p), nil
+}
+
+// Ship mirrors the records of Log at or above level to subject, replacing
+// any shipper installed before, and returns the function stopping it
+func Ship(conn *nats.Conn, subject string, level zerolog.Level) (*Shipper, func()) {
+	shipper := NewShipper(conn, subject, level)
+	shipping.shipper.Store(shipper)
+	return shipper, func() { shipping.shipper.CompareAndSwap(shipper, nil) }
+}
@@ -0,0 +1,26 @@
+package logger
+
+import (
+	"testing"
+
+	"github.com/rs/zerolog"
+)
+
+func TestShipperLevel(t *testing.T) {
+	// Without a connection every record shipped fails and is dropped
+	shipper, stop := Ship(nil, "logs.test", zerolog.WarnLevel)
+	defer stop()
+
+	Log.Info().Msg("below the level")
+	Log.Warn().Msg("at the level")
+	Log.Error().Msg("above the level")
+	if shipped, dropped := shipper.Stats(); shipped != 0 || dropped != 2 {
+		t.Errorf("shipped %d, dropped %d, want 0 and 2", shipped, dropped)
+	}
+
+	stop()
+	Log.Error().Msg("after stopping")
+	if _, dropped := shipper.Stats(); dropped != 2 {
+		t.Errorf("dropped %d after stopping", dropped)
+	}
+}
@@ -0,0 +1,113 @@
+package node
+
+import (
+	"context"
+	"fmt"
+	"sort"
+
+	"github.com/BullionBear/sequex/pkg/eventbus"
+	"github.com/nats-io/nats.go"
+)
+
+// Payload documents the messages of a subject
+type Payload struct {
+	Encoding    string  `json:"encoding,omitempty"` // e.g. json or protobuf
+	Description string  `json:"description,omitempty"`
+	Schema      *Schema `json:"schema,omitempty"` // Of the decoded message
+}
+
+// SubjectInfo is a subject a node handles or emits
+type SubjectInfo struct {
+	Subject string   `json:"subject"`
+	Queue   string   `json:"queue,omitempty"`
+	Payload *Payload `json:"payload,omitempty"`
+}
+
+// Description is the self-description of a node served on its describe
+// subject, for generic tools to render its subjects and parameters
+type Description struct {
+	Name   string        `json:"name"`
+	Status Status        `json:"status"`
+	On     []SubjectInfo `json:"on"`     // Subjects handled
+	Emits  []SubjectInfo `json:"emits"`  // Subjects published
+	Params *Schema       `json:"params"` // Nil when not set
+}
+
+// DescribeRequest asks a node for its description
+type DescribeRequest struct{}
+
+// DescribeEndpoint is the endpoint a node serves its description on
+func DescribeEndpoint(subject string) eventbus.Endpoint[DescribeRequest, Description] {
+	return eventbus.NewEndpoint[DescribeRequest, Description](subject, eventbus.JSON)
+}
+
+// NewPayload documents the messages of a subject, v being a value or a nil
+// pointer of their decoded type
+func NewPayload(encoding string, v any, description string) *Payload {
+	return &Payload{Encoding: encoding, Description: description, Schema: SchemaOf(v)}
+}
+
+// Document attaches the payload of the messages of a subject pattern
+// registered with On or OnQueue
+func (n *Node) Document(subject string, payload *Payload) error {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	found := false
+	for _, reg := range n.regs {
+		if reg.subject == subject {
+			reg.payload = payload
+			found = true
+		}
+	}
+	if !found {
+		return fmt.Errorf("no handler registered on %s", subject)
+	}
+	return nil
+}
+
+// Emits declares a subject pattern the node publishes on
+func (n *Node) Emits(subject string, payload *Payload) error {
+	if err := eventbus.ValidatePattern(subject); err != nil {
+		return err
+	}
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	n.emits = append(n.emits, SubjectInfo{Subject: subject, Payload: payload})
+	return nil
+}
+
+// SetParams describes the parameters of the node with the schema of params,
+// typically its configuration
+func (n *Node) SetParams(params any) {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	n.params = SchemaOf(params)
+}
+
+// Describe returns the self-description of the node, subjects sorted
+func (n *Node) Describe() Description {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	d := Description{
+		Name:   n.name,
+		Status: n.statusLocked(),
+		On:     make([]SubjectInfo, 0, len(n.regs)),
+		Emits:  append([]SubjectInfo{}, n.emits...),
+		Params: n.params,
+	}
+	for _, reg := range n.regs {
+		d.On = append(d.On, SubjectInfo{Subject: reg.subject, Queue: reg.queue, Payload: reg.payload})
+	}
+	sort.SliceStable(d.On, func(i, j int) bool { return d.On[i].Subject < d.On[j].Subject })
+	sort.SliceStable(d.Emits, func(i, j int) bool { return d.Emits[i].Subject < d.Emits[j].Subject })
+	return d
+}
+
+// ServeDescription answers requests on subject with the description of the
+// node
+func (n *Node) ServeDescription(subject string) (*nats.Subscription, error) {
+	return DescribeEndpoint(subject).Serve(n.bus, func(context.Context, *DescribeRequest) (*Description, error) {
+		d := n.Describe()
+		return &d, nil
+	})
+}
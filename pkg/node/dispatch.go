@@ -0,0 +1,200 @@
+package node
+
+import (
+	"fmt"
+	"hash/maphash"
+	"runtime/debug"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/nats-io/nats.go"
+)
+
+// Handler processes a message delivered to a node
+type Handler func(msg *nats.Msg) error
+
+// KeyFunc extracts the ordering key of a message. Messages with the same key
+// are handled one at a time in arrival order.
+type KeyFunc func(msg *nats.Msg) string
+
+// SubjectKey keys messages by their full subject
+func SubjectKey(msg *nats.Msg) string {
+	return msg.Subject
+}
+
+// SubjectToken keys messages by the dot separated subject token at index,
+// e.g. SubjectToken(3) orders namespaced subjects per symbol
+func SubjectToken(index int) KeyFunc {
+	return func(msg *nats.Msg) string {
+		subject := msg.Subject
+		for i := 0; i < index; i++ {
+			dot := strings.IndexByte(subject, '.')
+			if dot < 0 {
+				return ""
+			}
+			subject = subject[dot+1:]
+		}
+		if dot := strings.IndexByte(subject, '.'); dot >= 0 {
+			return subject[:dot]
+		}
+		return subject
+	}
+}
+
+// DispatchOptions configures how messages of a subject reach its handler
+type DispatchOptions struct {
+	// Workers is the number of goroutines running the handler. 0 runs the
+	// handler on the subscription callback goroutine.
+	Workers int
+	// QueueSize bounds the messages waiting per worker; a full queue blocks
+	// the subscription callback. Defaults to 64.
+	QueueSize int
+	// Key, when set, routes every message with the same key to the same
+	// worker so per-key order is preserved. Without it workers share a queue
+	// and messages may complete out of order.
+	Key KeyFunc
+}
+
+const defaultQueueSize = 64
+
+func (o DispatchOptions) validate() error {
+	if o.Workers < 0 {
+		return fmt.Errorf("workers cannot be negative")
+	}
+	if o.QueueSize < 0 {
+		return fmt.Errorf("queue size cannot be negative")
+	}
+	return nil
+}
+
+// poolHooks connects a pool to the error boundary of its node
+type poolHooks struct {
+	boundary     *boundary // Nil disables quarantine
+	onError      func(subject string, msg *nats.Msg, err error)
+	onQuarantine func(subject string)
+}
+
+// pool runs a handler inline or on a set of workers
+type pool struct {
+	subject  string
+	handler  Handler
+	opts     DispatchOptions
+	queues   []chan *nats.Msg // One per worker when keyed, a single shared one otherwise
+	seed     maphash.Seed
+	wg       sync.WaitGroup
+	counters handlerCounters
+	hooks    poolHooks
+}
+
+func newPool(subject string, handler Handler, opts DispatchOptions, hooks poolHooks) *pool {
+	if opts.QueueSize == 0 {
+		opts.QueueSize = defaultQueueSize
+	}
+	p := &pool{
+		subject: subject,
+		handler: handler,
+		opts:    opts,
+		seed:    maphash.MakeSeed(),
+		hooks:   hooks,
+	}
+	if opts.Workers == 0 {
+		return p
+	}
+
+	queues := 1
+	if opts.Key != nil {
+		queues = opts.Workers
+	}
+	p.queues = make([]chan *nats.Msg, queues)
+	for i := range p.queues {
+		p.queues[i] = make(chan *nats.Msg, opts.QueueSize*opts.Workers/queues)
+	}
+	for i := 0; i < opts.Workers; i++ {
+		queue := p.queues[i%queues]
+		p.wg.Add(1)
+		go p.work(queue)
+	}
+	return p
+}
+
+// dispatch hands a message to the handler or to the worker owning its key
+func (p *pool) dispatch(msg *nats.Msg) {
+	switch {
+	case p.queues == nil:
+		p.handle(msg)
+	case len(p.queues) == 1:
+		p.queues[0] <- msg
+	default:
+		key := p.opts.Key(msg)
+		p.queues[maphash.String(p.seed, key)%uint64(len(p.queues))] <- msg
+	}
+}
+
+func (p *pool) work(queue <-chan *nats.Msg) {
+	defer p.wg.Done()
+	for msg := range queue {
+		p.handle(msg)
+	}
+}
+
+// handle runs the handler, recording latency and outcome. A panicking
+// handler is recovered so one bad message does not kill the node, and
+// subjects that keep crashing it are quarantined.
+func (p *pool) handle(msg *nats.Msg) {
+	start := time.Now()
+	if p.hooks.boundary != nil && !p.hooks.boundary.allow(msg.Subject, start) {
+		p.counters.quarantined.Add(1)
+		return
+	}
+	err := p.call(msg)
+	p.counters.latency.record(time.Since(start))
+	p.counters.processed.Add(1)
+	if err == nil {
+		return
+	}
+	p.counters.errors.Add(1)
+	if p.hooks.onError != nil {
+		p.hooks.onError(p.subject, msg, err)
+	}
+	if _, ok := err.(*PanicError); ok && p.hooks.boundary != nil && p.hooks.boundary.recordPanic(msg.Subject, time.Now()) {
+		if p.hooks.onQuarantine != nil {
+			p.hooks.onQuarantine(msg.Subject)
+		}
+	}
+}
+
+func (p *pool) call(msg *nats.Msg) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			p.counters.panics.Add(1)
+			err = &PanicError{Value: r, Stack: debug.Stack()}
+		}
+	}()
+	return p.handler(msg)
+}
+
+// stop waits for queued messages to be handled. dispatch must not be called afterwards.
+func (p *pool) stop() {
+	for _, queue := range p.queues {
+		close(queue)
+	}
+	p.wg.Wait()
+}
+
+func (p *pool) stats() HandlerStats {
+	queued := 0
+	for _, queue := range p.queues {
+		queued += len(queue)
+	}
+	return HandlerStats{
+		Subject:     p.subject,
+		Workers:     p.opts.Workers,
+		Queued:      queued,
+		Processed:   p.counters.processed.Load(),
+		Errors:      p.counters.errors.Load(),
+		Panics:      p.counters.panics.Load(),
+		Quarantined: p.counters.quarantined.Load(),
+		Latency:     p.counters.latency.snapshot(),
+	}
+}
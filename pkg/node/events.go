@@ -0,0 +1,50 @@
+package node
+
+import (
+	"fmt"
+	"time"
+)
+
+// EventType is what happened to a node
+type EventType string
+
+const (
+	EventStarted   EventType = "started"
+	EventStopped   EventType = "stopped"
+	EventDegraded  EventType = "degraded"  // Past its resource limits, intake paused
+	EventRecovered EventType = "recovered" // Back within its limits
+	// A subject quarantined after repeated panics, its messages dropped
+	EventQuarantined EventType = "quarantined"
+	// A message dropped after its handler panicked
+	EventPanicked EventType = "panicked"
+)
+
+// Event is a change in the lifecycle of a node or a message it dropped
+type Event struct {
+	Node    string
+	Type    EventType
+	Subject string // Of the message, for quarantines and panics
+	Reason  string
+	At      time.Time
+}
+
+// OnEvent sets fn to be called on every event of the node. fn is called
+// synchronously, at times with the node locked, so it must return quickly
+// and not call the node.
+func (n *Node) OnEvent(fn func(Event)) error {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	if n.status != StatusCreated {
+		return fmt.Errorf("node %s already started", n.name)
+	}
+	n.onEvent = fn
+	return nil
+}
+
+// emit hands an event to the callback, when set
+func (n *Node) emit(typ EventType, subject, reason string) {
+	if n.onEvent == nil {
+		return
+	}
+	n.onEvent(Event{Node: n.name, Type: typ, Subject: subject, Reason: reason, At: time.Now()})
+}
@@ -0,0 +1,15 @@
+package node
+
+import "github.com/BullionBear/sequex/pkg/featureflag"
+
+// SetFlags sets the feature flags the node consults, see Enabled
+func (n *Node) SetFlags(flags *featureflag.Set) {
+	n.flags.Store(flags)
+}
+
+// Enabled reports whether a feature flag is on for the node and symbol,
+// empty for behaviors that are not per symbol. Flags are off until set.
+func (n *Node) Enabled(flag, symbol string) bool {
+	// Not under mu, which Stop holds while handlers drain
+	return n.flags.Load().Enabled(flag, n.name, symbol)
+}
@@ -0,0 +1,295 @@
+package node
+
+import (
+	"fmt"
+	"runtime"
+	"runtime/metrics"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/nats-io/nats.go"
+)
+
+// resumeRatio is the share of the goroutine and memory limits usage must
+// fall back under before intake resumes, so a node hovering at a limit does
+// not flap
+const resumeRatio = 0.9
+
+const defaultCheckInterval = time.Second
+
+// Limits bounds the resources of a node. Past the goroutine or memory limit
+// the node pauses its intake and reports StatusDegraded until usage falls
+// back under 90% of the limits; past the rate it delays messages. Intake is
+// throttled by holding the subscription callbacks, so messages wait in the
+// client buffers rather than in memory the node accounts for. Zero values
+// disable a limit.
+type Limits struct {
+	MaxGoroutines int
+	MaxMemory     uint64        // Heap bytes in use
+	MaxRate       float64       // Messages per second handed to the handlers, over all subjects
+	RateBurst     int           // Messages handed over at once under MaxRate, 1 when 0
+	CheckInterval time.Duration // Of the goroutine and memory samples, 1s when 0
+}
+
+// Validate validates the limits
+func (l Limits) Validate() error {
+	if l.MaxGoroutines < 0 || l.MaxRate < 0 || l.RateBurst < 0 || l.CheckInterval < 0 {
+		return fmt.Errorf("limits cannot be negative")
+	}
+	return nil
+}
+
+func (l Limits) enabled() bool {
+	return l.MaxGoroutines > 0 || l.MaxMemory > 0 || l.MaxRate > 0
+}
+
+// Health is the resource usage of a node against its limits
+type Health struct {
+	Status     Status   `json:"status"`
+	Reasons    []string `json:"reasons,omitempty"` // Limits exceeded while degraded
+	Goroutines int      `json:"goroutines"`        // At the last sample
+	HeapBytes  uint64   `json:"heap_bytes"`        // At the last sample
+	Throttled  uint64   `json:"throttled"`         // Messages held by a pause or the rate limit
+	Pauses     uint64   `json:"pauses"`            // Times intake was paused
+	Limits     Limits   `json:"limits"`
+}
+
+// guard enforces the limits of a node on its intake
+type guard struct {
+	limits Limits
+	sample func() (goroutines int, heap uint64)
+
+	mu         sync.Mutex
+	reasons    []string
+	gate       chan struct{} // Closed while intake is open
+	goroutines int
+	heap       uint64
+	tokens     float64
+	refilled   time.Time
+	pauses     uint64
+
+	throttled atomic.Uint64
+	stop      chan struct{}
+	stopOnce  sync.Once
+}
+
+func newGuard(limits Limits) *guard {
+	g := &guard{
+		limits:   limits,
+		sample:   sampleRuntime,
+		gate:     make(chan struct{}),
+		tokens:   float64(max(limits.RateBurst, 1)),
+		refilled: time.Now(),
+		stop:     make(chan struct{}),
+	}
+	close(g.gate)
+	return g
+}
+
+// sampleRuntime reads the goroutines and heap in use without stopping the
+// world, unlike runtime.ReadMemStats
+func sampleRuntime() (int, uint64) {
+	samples := []metrics.Sample{{Name: "/memory/classes/heap/objects:bytes"}}
+	metrics.Read(samples)
+	var heap uint64
+	if samples[0].Value.Kind() == metrics.KindUint64 {
+		heap = samples[0].Value.Uint64()
+	}
+	return runtime.NumGoroutine(), heap
+}
+
+// check samples the runtime, pausing or resuming intake. It returns whether
+// the node became degraded or recovered.
+func (g *guard) check() (changed, degraded bool) {
+	goroutines, heap := g.sample()
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	g.goroutines, g.heap = goroutines, heap
+
+	ratio := 1.0
+	if len(g.reasons) > 0 {
+		ratio = resumeRatio
+	}
+	var reasons []string
+	if l := g.limits.MaxGoroutines; l > 0 && float64(goroutines) > float64(l)*ratio {
+		reasons = append(reasons, "goroutines")
+	}
+	if l := g.limits.MaxMemory; l > 0 && float64(heap) > float64(l)*ratio {
+		reasons = append(reasons, "memory")
+	}
+	was := len(g.reasons) > 0
+	g.reasons = reasons
+	switch {
+	case !was && len(reasons) > 0:
+		g.gate = make(chan struct{})
+		g.pauses++
+		return true, true
+	case was && len(reasons) == 0:
+		close(g.gate)
+		return true, false
+	}
+	return false, len(reasons) > 0
+}
+
+// run checks the runtime every interval until close, calling onChange on
+// transitions
+func (g *guard) run(onChange func(degraded bool, h Health)) {
+	if g.limits.MaxGoroutines == 0 && g.limits.MaxMemory == 0 {
+		return
+	}
+	interval := g.limits.CheckInterval
+	if interval == 0 {
+		interval = defaultCheckInterval
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		if changed, degraded := g.check(); changed {
+			onChange(degraded, g.health())
+		}
+		select {
+		case <-g.stop:
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// wait blocks while intake is paused, then until the rate limit admits a
+// message. It returns at once after close.
+func (g *guard) wait() {
+	g.mu.Lock()
+	gate := g.gate
+	g.mu.Unlock()
+	held := false
+	select {
+	case <-gate:
+	default:
+		held = true
+		select {
+		case <-gate:
+		case <-g.stop:
+			return
+		}
+	}
+
+	if rate := g.limits.MaxRate; rate > 0 {
+		// Messages reserve a token, waiting for the debt to be refilled
+		g.mu.Lock()
+		now := time.Now()
+		burst := float64(max(g.limits.RateBurst, 1))
+		g.tokens = min(burst, g.tokens+now.Sub(g.refilled).Seconds()*rate)
+		g.refilled = now
+		g.tokens--
+		delay := time.Duration(-g.tokens / rate * float64(time.Second))
+		g.mu.Unlock()
+		if delay > 0 {
+			held = true
+			timer := time.NewTimer(delay)
+			select {
+			case <-timer.C:
+			case <-g.stop:
+				timer.Stop()
+			}
+		}
+	}
+	if held {
+		g.throttled.Add(1)
+	}
+}
+
+// close releases the callbacks held and stops the checks
+func (g *guard) close() {
+	g.stopOnce.Do(func() { close(g.stop) })
+}
+
+func (g *guard) degraded() bool {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	return len(g.reasons) > 0
+}
+
+func (g *guard) health() Health {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	return Health{
+		Reasons:    append([]string(nil), g.reasons...),
+		Goroutines: g.goroutines,
+		HeapBytes:  g.heap,
+		Throttled:  g.throttled.Load(),
+		Pauses:     g.pauses,
+		Limits:     g.limits,
+	}
+}
+
+// SetLimits bounds the resources of the node, see Limits
+func (n *Node) SetLimits(limits Limits) error {
+	if err := limits.Validate(); err != nil {
+		return err
+	}
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	if n.status != StatusCreated {
+		return fmt.Errorf("node %s already started", n.name)
+	}
+	n.limits = limits
+	return nil
+}
+
+// Health returns the resource usage of the node against its limits
+func (n *Node) Health() Health {
+	n.mu.Lock()
+	g := n.guard
+	status := n.statusLocked()
+	limits := n.limits
+	n.mu.Unlock()
+	h := Health{Limits: limits}
+	if g != nil {
+		h = g.health()
+	}
+	h.Status = status
+	h.Goroutines, h.HeapBytes = sampleRuntime()
+	return h
+}
+
+// statusLocked returns the status of the node, degraded while running past
+// its limits
+func (n *Node) statusLocked() Status {
+	if n.status == StatusRunning && n.guard != nil && n.guard.degraded() {
+		return StatusDegraded
+	}
+	return n.status
+}
+
+// startGuard starts enforcing the limits, when set, called with mu held on
+// Start
+func (n *Node) startGuard() {
+	if !n.limits.enabled() {
+		return
+	}
+	n.guard = newGuard(n.limits)
+	go n.guard.run(func(degraded bool, h Health) {
+		if degraded {
+			n.log.Warn().Strs("reasons", h.Reasons).Int("goroutines", h.Goroutines).Uint64("heapBytes", h.HeapBytes).
+				Msg("Node degraded, intake paused")
+			n.emit(EventDegraded, "", strings.Join(h.Reasons, "; "))
+			return
+		}
+		n.log.Info().Int("goroutines", h.Goroutines).Uint64("heapBytes", h.HeapBytes).Msg("Node recovered, intake resumed")
+		n.emit(EventRecovered, "", "")
+	})
+}
+
+// intake holds the messages of dispatch while the limits of the node say so
+func (n *Node) intake(dispatch func(*nats.Msg)) func(*nats.Msg) {
+	g := n.guard
+	if g == nil {
+		return dispatch
+	}
+	return func(msg *nats.Msg) {
+		g.wait()
+		dispatch(msg)
+	}
+}
@@ -0,0 +1,98 @@
+package node
+
+import (
+	"math/bits"
+	"sync/atomic"
+	"time"
+)
+
+// latencyBuckets covers 1µs to ~35min in powers of two, which is precise
+// enough to tell a 100µs handler from a 10ms one
+const latencyBuckets = 32
+
+// latencyHistogram records handler latencies into exponential buckets with
+// atomic counters so recording never contends with the handler itself
+type latencyHistogram struct {
+	count   atomic.Uint64
+	total   atomic.Int64
+	max     atomic.Int64
+	buckets [latencyBuckets]atomic.Uint64
+}
+
+func (h *latencyHistogram) record(d time.Duration) {
+	h.count.Add(1)
+	h.total.Add(int64(d))
+	for {
+		cur := h.max.Load()
+		if int64(d) <= cur || h.max.CompareAndSwap(cur, int64(d)) {
+			break
+		}
+	}
+	h.buckets[bucketOf(d)].Add(1)
+}
+
+// bucketOf maps a duration to the bucket whose upper bound is 2^i µs
+func bucketOf(d time.Duration) int {
+	us := uint64(d / time.Microsecond)
+	if us == 0 {
+		return 0
+	}
+	return min(bits.Len64(us-1), latencyBuckets-1)
+}
+
+// quantile returns the upper bound of the bucket containing quantile q
+func (h *latencyHistogram) quantile(q float64, count uint64) time.Duration {
+	if count == 0 {
+		return 0
+	}
+	rank := uint64(q*float64(count-1)) + 1
+	var seen uint64
+	for i := range h.buckets {
+		seen += h.buckets[i].Load()
+		if seen >= rank {
+			return time.Duration(uint64(1)<<i) * time.Microsecond
+		}
+	}
+	return time.Duration(h.max.Load())
+}
+
+// LatencyStats summarizes handler latencies. Percentiles are bucket upper
+// bounds, so they overestimate by at most a factor of two.
+type LatencyStats struct {
+	Count uint64        `json:"count"`
+	Mean  time.Duration `json:"mean"`
+	P50   time.Duration `json:"p50"`
+	P99   time.Duration `json:"p99"`
+	Max   time.Duration `json:"max"`
+}
+
+func (h *latencyHistogram) snapshot() LatencyStats {
+	count := h.count.Load()
+	stats := LatencyStats{Count: count, Max: time.Duration(h.max.Load())}
+	if count > 0 {
+		stats.Mean = time.Duration(h.total.Load() / int64(count))
+		stats.P50 = min(h.quantile(0.5, count), stats.Max)
+		stats.P99 = min(h.quantile(0.99, count), stats.Max)
+	}
+	return stats
+}
+
+// HandlerStats is a snapshot of the metrics of one subject handler
+type HandlerStats struct {
+	Subject     string       `json:"subject"`
+	Workers     int          `json:"workers"`
+	Queued      int          `json:"queued"`      // Messages waiting for a worker
+	Processed   uint64       `json:"processed"`   // Messages handled, including failures
+	Errors      uint64       `json:"errors"`      // Handler errors
+	Panics      uint64       `json:"panics"`      // Recovered handler panics
+	Quarantined uint64       `json:"quarantined"` // Messages dropped because their subject is quarantined
+	Latency     LatencyStats `json:"latency"`
+}
+
+type handlerCounters struct {
+	processed   atomic.Uint64
+	errors      atomic.Uint64
+	panics      atomic.Uint64
+	quarantined atomic.Uint64
+	latency     latencyHistogram
+}
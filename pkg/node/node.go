@@ -0,0 +1,334 @@
+// Package node is the runtime for long-lived services that react to event
+// bus subjects. A node registers subject handlers, each with its own dispatch
+// policy, and exposes per-handler metrics.
+package node
+
+import (
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/BullionBear/sequex/pkg/diagnostics"
+	"github.com/BullionBear/sequex/pkg/eventbus"
+	"github.com/BullionBear/sequex/pkg/featureflag"
+	"github.com/nats-io/nats.go"
+	"github.com/rs/zerolog"
+)
+
+const drainTimeout = 10 * time.Second
+
+type registration struct {
+	subject string
+	queue   string
+	handler Handler
+	opts    DispatchOptions
+	payload *Payload
+	pool    *pool           // Created on Start
+	deliver func(*nats.Msg) // Intake of the pool, created on Start
+}
+
+// Node owns the subscriptions of a service
+type Node struct {
+	name         string
+	bus          *eventbus.Bus
+	log          zerolog.Logger
+	mu           sync.Mutex
+	regs         []*registration
+	middlewares  []Middleware
+	boundary     *boundary
+	subs         []*nats.Subscription
+	status       Status
+	emits        []SubjectInfo
+	params       *Schema
+	snapshots    map[string]SnapshotFunc
+	state        StateOptions
+	stateParts   map[string]statePart
+	checkpoints  chan struct{} // Closed to stop the checkpoints, nil without
+	checkpointed chan struct{} // Closed once the checkpoints stopped
+	limits       Limits
+	guard        *guard // Created on Start when limits are set
+	flags        atomic.Pointer[featureflag.Set]
+	onEvent      func(Event) // Set before Start
+}
+
+// Status is the lifecycle state of a node
+type Status string
+
+const (
+	StatusCreated Status = "created"
+	StatusRunning Status = "running"
+	// Running past its resource limits, intake paused
+	StatusDegraded Status = "degraded"
+	StatusStopped  Status = "stopped"
+)
+
+// New creates a node named name on the event bus. A node created without a
+// bus subscribes to nothing and is only fed by Inject, which benchmarks and
+// tests use to run handlers in process.
+func New(name string, bus *eventbus.Bus, log zerolog.Logger) *Node {
+	return &Node{
+		name:     name,
+		bus:      bus,
+		log:      log.With().Str("node", name).Logger(),
+		boundary: newBoundary(RecoveryOptions{}),
+		status:   StatusCreated,
+	}
+}
+
+// Name returns the node name
+func (n *Node) Name() string {
+	return n.name
+}
+
+// Status returns the lifecycle state of the node
+func (n *Node) Status() Status {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	return n.statusLocked()
+}
+
+// On registers a handler for a subject pattern. Handlers must be registered
+// before Start.
+func (n *Node) On(subject string, handler Handler, opts DispatchOptions) error {
+	return n.register(subject, "", handler, opts)
+}
+
+// OnQueue is On within a queue group, so instances of the node share the load
+func (n *Node) OnQueue(subject, queue string, handler Handler, opts DispatchOptions) error {
+	return n.register(subject, queue, handler, opts)
+}
+
+func (n *Node) register(subject, queue string, handler Handler, opts DispatchOptions) error {
+	if handler == nil {
+		return fmt.Errorf("handler cannot be nil")
+	}
+	if err := eventbus.ValidatePattern(subject); err != nil {
+		return err
+	}
+	if err := opts.validate(); err != nil {
+		return fmt.Errorf("%s: %w", subject, err)
+	}
+
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	if n.status != StatusCreated {
+		return fmt.Errorf("node %s already started", n.name)
+	}
+	n.regs = append(n.regs, &registration{subject: subject, queue: queue, handler: handler, opts: opts})
+	return nil
+}
+
+// Use appends middlewares wrapping every handler, the first one outermost.
+// Panic recovery always wraps the whole chain.
+func (n *Node) Use(middlewares ...Middleware) error {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	if n.status != StatusCreated {
+		return fmt.Errorf("node %s already started", n.name)
+	}
+	n.middlewares = append(n.middlewares, middlewares...)
+	return nil
+}
+
+// SetRecovery configures the quarantine of subjects that keep panicking
+func (n *Node) SetRecovery(opts RecoveryOptions) error {
+	if opts.QuarantineAfter < 0 || opts.Window < 0 || opts.Duration < 0 {
+		return fmt.Errorf("recovery options cannot be negative")
+	}
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	if n.status != StatusCreated {
+		return fmt.Errorf("node %s already started", n.name)
+	}
+	n.boundary = newBoundary(opts)
+	return nil
+}
+
+// Quarantined lists the subjects currently quarantined
+func (n *Node) Quarantined() []Quarantine {
+	return n.boundary.list(time.Now())
+}
+
+// Release lifts the quarantine of a subject, reporting whether it was quarantined
+func (n *Node) Release(subject string) bool {
+	released := n.boundary.release(subject)
+	if released {
+		n.log.Info().Str("subject", subject).Msg("Subject released from quarantine")
+	}
+	return released
+}
+
+func (n *Node) chain(handler Handler) Handler {
+	for i := len(n.middlewares) - 1; i >= 0; i-- {
+		handler = n.middlewares[i](handler)
+	}
+	return handler
+}
+
+func (n *Node) hooks() poolHooks {
+	return poolHooks{
+		boundary:     n.boundary,
+		onError:      n.logError,
+		onQuarantine: n.logQuarantine,
+	}
+}
+
+func (n *Node) logError(subject string, msg *nats.Msg, err error) {
+	if panicErr, ok := err.(*PanicError); ok {
+		n.log.Error().Err(err).Str("handler", subject).Str("subject", msg.Subject).Str("stack", string(panicErr.Stack)).Msg("Handler panicked")
+		n.emit(EventPanicked, msg.Subject, err.Error())
+		return
+	}
+	n.log.Error().Err(err).Str("handler", subject).Str("subject", msg.Subject).Msg("Handler failed")
+}
+
+func (n *Node) logQuarantine(subject string) {
+	n.log.Warn().Str("subject", subject).Int("panics", n.boundary.opts.QuarantineAfter).Dur("duration", n.boundary.opts.Duration).Msg("Subject quarantined after repeated panics")
+	n.emit(EventQuarantined, subject, fmt.Sprintf("%d panics within %s", n.boundary.opts.QuarantineAfter, n.boundary.opts.Window))
+}
+
+// Start subscribes every registered handler
+func (n *Node) Start() error {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	if n.status != StatusCreated {
+		return fmt.Errorf("node %s already started", n.name)
+	}
+	n.restoreStateLocked()
+	for _, reg := range n.regs {
+		reg.pool = newPool(reg.subject, n.chain(reg.handler), reg.opts, n.hooks())
+	}
+	n.startGuard()
+	for _, reg := range n.regs {
+		reg.deliver = n.intake(reg.pool.dispatch)
+		if n.bus == nil {
+			continue
+		}
+		var sub *nats.Subscription
+		var err error
+		if reg.queue == "" {
+			sub, err = n.bus.Subscribe(reg.subject, reg.deliver)
+		} else {
+			sub, err = n.bus.QueueSubscribe(reg.subject, reg.queue, reg.deliver)
+		}
+		if err != nil {
+			if n.guard != nil {
+				n.guard.close()
+			}
+			n.unsubscribeLocked()
+			n.stopPoolsLocked()
+			return fmt.Errorf("failed to subscribe to %s: %w", reg.subject, err)
+		}
+		n.subs = append(n.subs, sub)
+	}
+	if n.state.Store != nil && n.state.Interval > 0 && len(n.stateParts) > 0 {
+		n.checkpoints, n.checkpointed = make(chan struct{}), make(chan struct{})
+		go n.checkpoint(n.state.Interval, n.checkpoints, n.checkpointed)
+	}
+	n.status = StatusRunning
+	n.log.Info().Int("handlers", len(n.regs)).Msg("Node started")
+	n.emit(EventStarted, "", fmt.Sprintf("%d handlers", len(n.regs)))
+	return nil
+}
+
+// Inject hands msg to every handler whose subject matches it, as the bus
+// would deliver it: through the resource limits, the dispatch policy, the
+// middlewares and the error boundary. It must not race with Stop.
+func (n *Node) Inject(msg *nats.Msg) error {
+	n.mu.Lock()
+	if n.status != StatusRunning {
+		n.mu.Unlock()
+		return fmt.Errorf("node %s is not running", n.name)
+	}
+	regs := n.regs
+	n.mu.Unlock()
+	for _, reg := range regs {
+		if eventbus.Match(reg.subject, msg.Subject) {
+			reg.deliver(msg)
+		}
+	}
+	return nil
+}
+
+// Stop unsubscribes and waits for in-flight messages to be handled, then
+// saves the state parts
+func (n *Node) Stop() {
+	n.mu.Lock()
+	if n.status != StatusRunning {
+		n.mu.Unlock()
+		return
+	}
+	// Held callbacks are released so the subscriptions can drain
+	if n.guard != nil {
+		n.guard.close()
+	}
+	n.unsubscribeLocked()
+	n.stopPoolsLocked()
+	n.status = StatusStopped
+	checkpoints, checkpointed := n.checkpoints, n.checkpointed
+	save := n.state.Store != nil && len(n.stateParts) > 0
+	n.mu.Unlock()
+
+	// A checkpoint takes the lock, so it is waited for without holding it
+	if checkpoints != nil {
+		close(checkpoints)
+		<-checkpointed
+	}
+	if save {
+		if err := n.SaveState(); err != nil {
+			n.log.Error().Err(err).Msg("Failed to save state")
+		}
+	}
+	n.log.Info().Msg("Node stopped")
+	n.emit(EventStopped, "", "")
+}
+
+func (n *Node) unsubscribeLocked() {
+	for i, sub := range n.subs {
+		// The bus may have switched the subscription to JetStream
+		sub = n.bus.Release(sub)
+		n.subs[i] = sub
+		// Drain lets callbacks already running finish before the pools close
+		if err := sub.Drain(); err != nil {
+			n.log.Error().Err(err).Str("subject", sub.Subject).Msg("Failed to drain subscription")
+		}
+	}
+	// Drain is asynchronous; wait for it so no callback dispatches to a stopped pool
+	deadline := time.Now().Add(drainTimeout)
+	for _, sub := range n.subs {
+		for sub.IsValid() && time.Now().Before(deadline) {
+			time.Sleep(10 * time.Millisecond)
+		}
+	}
+	n.subs = nil
+}
+
+func (n *Node) stopPoolsLocked() {
+	for _, reg := range n.regs {
+		reg.pool.stop()
+	}
+}
+
+// Stats returns the metrics of every handler once the node has started
+func (n *Node) Stats() []HandlerStats {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	stats := make([]HandlerStats, 0, len(n.regs))
+	for _, reg := range n.regs {
+		if reg.pool != nil {
+			stats = append(stats, reg.pool.stats())
+		}
+	}
+	return stats
+}
+
+// QueueDepths reports the messages waiting for workers per handler
+func (n *Node) QueueDepths() []diagnostics.QueueDepth {
+	stats := n.Stats()
+	depths := make([]diagnostics.QueueDepth, 0, len(stats))
+	for _, s := range stats {
+		depths = append(depths, diagnostics.QueueDepth{Name: s.Subject, Depth: s.Queued})
+	}
+	return depths
+}
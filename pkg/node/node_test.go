@@ -0,0 +1,605 @@
+package node
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/nats-io/nats.go"
+	"github.com/rs/zerolog"
+)
+
+func TestPoolInline(t *testing.T) {
+	var calls int
+	p := newPool("trade.>", func(msg *nats.Msg) error {
+		calls++
+		return nil
+	}, DispatchOptions{}, poolHooks{})
+	p.dispatch(&nats.Msg{Subject: "trade.btcusdt"})
+	p.stop()
+
+	if calls != 1 {
+		t.Errorf("calls = %d", calls)
+	}
+	if stats := p.stats(); stats.Processed != 1 || stats.Workers != 0 {
+		t.Errorf("stats = %+v", stats)
+	}
+}
+
+func TestPoolWorkersRunConcurrently(t *testing.T) {
+	const workers = 4
+	var running, peak atomic.Int32
+	release := make(chan struct{})
+	p := newPool("trade.>", func(msg *nats.Msg) error {
+		n := running.Add(1)
+		for {
+			cur := peak.Load()
+			if n <= cur || peak.CompareAndSwap(cur, n) {
+				break
+			}
+		}
+		<-release
+		running.Add(-1)
+		return nil
+	}, DispatchOptions{Workers: workers}, poolHooks{})
+
+	for i := 0; i < workers; i++ {
+		p.dispatch(&nats.Msg{Subject: "trade.btcusdt"})
+	}
+	deadline := time.Now().Add(2 * time.Second)
+	for peak.Load() < workers && time.Now().Before(deadline) {
+		time.Sleep(time.Millisecond)
+	}
+	close(release)
+	p.stop()
+
+	if peak.Load() != workers {
+		t.Errorf("peak concurrency = %d, want %d", peak.Load(), workers)
+	}
+}
+
+func TestPoolOrderedPerKey(t *testing.T) {
+	var mu sync.Mutex
+	seen := make(map[string][]int)
+	p := newPool("trade.>", func(msg *nats.Msg) error {
+		var seq int
+		fmt.Sscanf(string(msg.Data), "%d", &seq)
+		mu.Lock()
+		defer mu.Unlock()
+		seen[msg.Subject] = append(seen[msg.Subject], seq)
+		return nil
+	}, DispatchOptions{Workers: 4, Key: SubjectToken(1)}, poolHooks{})
+
+	symbols := []string{"btcusdt", "ethusdt", "solusdt", "bnbusdt", "xrpusdt"}
+	for seq := 0; seq < 200; seq++ {
+		for _, symbol := range symbols {
+			p.dispatch(&nats.Msg{Subject: "trade." + symbol, Data: []byte(fmt.Sprint(seq))})
+		}
+	}
+	p.stop()
+
+	for _, symbol := range symbols {
+		got := seen["trade."+symbol]
+		if len(got) != 200 {
+			t.Fatalf("%s: handled %d messages", symbol, len(got))
+		}
+		for i, seq := range got {
+			if seq != i {
+				t.Fatalf("%s: message %d handled at position %d", symbol, seq, i)
+			}
+		}
+	}
+}
+
+func TestPoolRecoversPanics(t *testing.T) {
+	var failures []error
+	p := newPool("trade.>", func(msg *nats.Msg) error {
+		switch string(msg.Data) {
+		case "panic":
+			panic("boom")
+		case "error":
+			return errors.New("bad trade")
+		}
+		return nil
+	}, DispatchOptions{Workers: 1}, poolHooks{onError: func(subject string, msg *nats.Msg, err error) {
+		failures = append(failures, err)
+	}})
+	for _, data := range []string{"panic", "error", "ok"} {
+		p.dispatch(&nats.Msg{Subject: "trade.btcusdt", Data: []byte(data)})
+	}
+	p.stop()
+
+	stats := p.stats()
+	if stats.Processed != 3 || stats.Errors != 2 || stats.Panics != 1 {
+		t.Errorf("stats = %+v", stats)
+	}
+	if len(failures) != 2 {
+		t.Errorf("failures = %v", failures)
+	}
+}
+
+func TestSubjectToken(t *testing.T) {
+	key := SubjectToken(3)
+	tests := map[string]string{
+		"prod.binance.spot.btcusdt.trade": "btcusdt",
+		"prod.binance.spot.ethusdt":       "ethusdt",
+		"prod.binance":                    "",
+	}
+	for subject, want := range tests {
+		if got := key(&nats.Msg{Subject: subject}); got != want {
+			t.Errorf("SubjectToken(3)(%q) = %q, want %q", subject, got, want)
+		}
+	}
+}
+
+func TestLatencyHistogram(t *testing.T) {
+	var h latencyHistogram
+	for i := 0; i < 99; i++ {
+		h.record(100 * time.Microsecond)
+	}
+	h.record(50 * time.Millisecond)
+
+	stats := h.snapshot()
+	if stats.Count != 100 || stats.Max != 50*time.Millisecond {
+		t.Errorf("stats = %+v", stats)
+	}
+	// Percentiles report bucket upper bounds, at most twice the true value
+	if stats.P50 < 100*time.Microsecond || stats.P50 > 200*time.Microsecond {
+		t.Errorf("p50 = %v", stats.P50)
+	}
+	if stats.P99 > 200*time.Microsecond {
+		t.Errorf("p99 = %v", stats.P99)
+	}
+}
+
+func TestPoolQuarantinesPanickingSubject(t *testing.T) {
+	var quarantined []string
+	b := newBoundary(RecoveryOptions{QuarantineAfter: 2, Window: time.Minute})
+	p := newPool("trade.>", func(msg *nats.Msg) error {
+		if string(msg.Data) == "poison" {
+			var trades map[string]int
+			trades["crash"]++
+		}
+		return nil
+	}, DispatchOptions{}, poolHooks{boundary: b, onQuarantine: func(subject string) {
+		quarantined = append(quarantined, subject)
+	}})
+
+	for _, data := range []string{"poison", "ok", "poison", "ok"} {
+		p.dispatch(&nats.Msg{Subject: "trade.btcusdt", Data: []byte(data)})
+	}
+	p.dispatch(&nats.Msg{Subject: "trade.ethusdt", Data: []byte("ok")})
+	p.stop()
+
+	if len(quarantined) != 1 || quarantined[0] != "trade.btcusdt" {
+		t.Fatalf("quarantined = %v", quarantined)
+	}
+	stats := p.stats()
+	if stats.Panics != 2 || stats.Processed != 4 || stats.Quarantined != 1 {
+		t.Errorf("stats = %+v", stats)
+	}
+	if !b.release("trade.btcusdt") || !b.allow("trade.btcusdt", time.Now()) {
+		t.Error("release did not lift the quarantine")
+	}
+}
+
+func TestBoundaryWindowAndDuration(t *testing.T) {
+	now := time.Now()
+	b := newBoundary(RecoveryOptions{QuarantineAfter: 2, Window: time.Second, Duration: time.Minute})
+
+	// Panics further apart than the window never add up
+	b.recordPanic("trade.btcusdt", now)
+	if b.recordPanic("trade.btcusdt", now.Add(2*time.Second)) {
+		t.Fatal("panics outside the window quarantined the subject")
+	}
+	if !b.recordPanic("trade.btcusdt", now.Add(2500*time.Millisecond)) {
+		t.Fatal("panics within the window did not quarantine the subject")
+	}
+	if b.allow("trade.btcusdt", now.Add(30*time.Second)) {
+		t.Error("subject allowed during quarantine")
+	}
+	if len(b.list(now.Add(30*time.Second))) != 1 {
+		t.Error("quarantine not listed")
+	}
+	if !b.allow("trade.btcusdt", now.Add(2*time.Minute)) {
+		t.Error("subject still blocked after the quarantine expired")
+	}
+}
+
+func TestMiddlewareOrder(t *testing.T) {
+	n := New("test", nil, zerolog.Nop())
+	var order []string
+	trace := func(name string) Middleware {
+		return func(next Handler) Handler {
+			return func(msg *nats.Msg) error {
+				order = append(order, name)
+				return next(msg)
+			}
+		}
+	}
+	if err := n.Use(trace("outer"), trace("inner")); err != nil {
+		t.Fatal(err)
+	}
+	handler := n.chain(func(msg *nats.Msg) error {
+		order = append(order, "handler")
+		return nil
+	})
+	handler(&nats.Msg{})
+	if fmt.Sprint(order) != "[outer inner handler]" {
+		t.Errorf("order = %v", order)
+	}
+}
+
+func TestInject(t *testing.T) {
+	n := New("test", nil, zerolog.Nop())
+	var trades, marks atomic.Int32
+	if err := n.On("trade.>", func(msg *nats.Msg) error {
+		trades.Add(1)
+		return nil
+	}, DispatchOptions{Workers: 2}); err != nil {
+		t.Fatal(err)
+	}
+	if err := n.On("mark.*", func(msg *nats.Msg) error {
+		marks.Add(1)
+		return nil
+	}, DispatchOptions{}); err != nil {
+		t.Fatal(err)
+	}
+	if err := n.Inject(&nats.Msg{Subject: "trade.btcusdt"}); err == nil {
+		t.Error("Inject before Start should fail")
+	}
+	if err := n.Start(); err != nil {
+		t.Fatal(err)
+	}
+	for _, subject := range []string{"trade.btcusdt", "trade.ethusdt", "mark.btcusdt", "index.btcusdt"} {
+		if err := n.Inject(&nats.Msg{Subject: subject}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	n.Stop()
+	if trades.Load() != 2 || marks.Load() != 1 {
+		t.Errorf("handled %d trades and %d marks", trades.Load(), marks.Load())
+	}
+}
+
+func TestEvents(t *testing.T) {
+	n := New("test", nil, zerolog.Nop())
+	if err := n.SetRecovery(RecoveryOptions{QuarantineAfter: 2, Window: time.Minute}); err != nil {
+		t.Fatal(err)
+	}
+	if err := n.On("trade.>", func(msg *nats.Msg) error {
+		panic("boom")
+	}, DispatchOptions{}); err != nil {
+		t.Fatal(err)
+	}
+	var events []Event
+	if err := n.OnEvent(func(e Event) { events = append(events, e) }); err != nil {
+		t.Fatal(err)
+	}
+	if err := n.Start(); err != nil {
+		t.Fatal(err)
+	}
+	if err := n.OnEvent(func(Event) {}); err == nil {
+		t.Error("OnEvent after Start should fail")
+	}
+	for i := 0; i < 3; i++ {
+		if err := n.Inject(&nats.Msg{Subject: "trade.btcusdt"}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	n.Stop()
+
+	var types []EventType
+	for _, e := range events {
+		if e.Node != "test" || e.At.IsZero() {
+			t.Errorf("event = %+v", e)
+		}
+		types = append(types, e.Type)
+	}
+	// The third message is dropped by the quarantine, unhandled
+	want := []EventType{EventStarted, EventPanicked, EventPanicked, EventQuarantined, EventStopped}
+	if fmt.Sprint(types) != fmt.Sprint(want) {
+		t.Fatalf("events = %v, want %v", types, want)
+	}
+	if events[1].Subject != "trade.btcusdt" || !strings.Contains(events[1].Reason, "boom") {
+		t.Errorf("panicked = %+v", events[1])
+	}
+	if events[3].Subject != "trade.btcusdt" {
+		t.Errorf("quarantined = %+v", events[3])
+	}
+}
+
+func TestSchemaOf(t *testing.T) {
+	type limits struct {
+		Max float64 `json:"max" desc:"Largest notional"`
+	}
+	type params struct {
+		Name      string            `json:"name"`
+		Symbols   []string          `json:"symbols,omitempty"`
+		Limits    limits            `json:"limits"`
+		Overrides map[string]limits `json:"overrides,omitempty"`
+		Timeout   time.Duration     `json:"timeout"`
+		Next      *params           `json:"next,omitempty"`
+		Skipped   int               `json:"-"`
+		internal  int
+	}
+	s := SchemaOf((*params)(nil))
+	if s.Type != "object" || !reflect.DeepEqual(s.Required, []string{"name", "limits", "timeout"}) {
+		t.Fatalf("schema = %+v", s)
+	}
+	if len(s.Properties) != 6 {
+		t.Errorf("properties = %v", s.Properties)
+	}
+	if s.Properties["symbols"].Type != "array" || s.Properties["symbols"].Items.Type != "string" {
+		t.Errorf("symbols = %+v", s.Properties["symbols"])
+	}
+	if max := s.Properties["limits"].Properties["max"]; max.Type != "number" || max.Description != "Largest notional" {
+		t.Errorf("limits.max = %+v", max)
+	}
+	if s.Properties["overrides"].AdditionalProperties.Properties["max"] == nil {
+		t.Errorf("overrides = %+v", s.Properties["overrides"])
+	}
+	if timeout := s.Properties["timeout"]; timeout.Type != "integer" || timeout.GoType != "time.Duration" {
+		t.Errorf("timeout = %+v", timeout)
+	}
+	if next := s.Properties["next"]; next.Type != "object" || next.Properties != nil {
+		t.Errorf("recursive next = %+v", next)
+	}
+}
+
+func TestDescribe(t *testing.T) {
+	n := New("test", nil, zerolog.Nop())
+	handler := func(msg *nats.Msg) error { return nil }
+	if err := n.On("trade.>", handler, DispatchOptions{}); err != nil {
+		t.Fatal(err)
+	}
+	if err := n.OnQueue("index.>", "test", handler, DispatchOptions{}); err != nil {
+		t.Fatal(err)
+	}
+	payload := NewPayload("json", struct {
+		Price float64 `json:"price"`
+	}{}, "Trades")
+	if err := n.Document("trade.>", payload); err != nil {
+		t.Fatal(err)
+	}
+	if err := n.Document("book.>", payload); err == nil {
+		t.Error("documented a subject without handler")
+	}
+	if err := n.Emits("signal.*", payload); err != nil {
+		t.Fatal(err)
+	}
+	n.SetParams(struct {
+		Workers int `json:"workers"`
+	}{})
+
+	d := n.Describe()
+	if d.Name != "test" || d.Status != StatusCreated || len(d.On) != 2 || len(d.Emits) != 1 {
+		t.Fatalf("description = %+v", d)
+	}
+	if d.On[0].Subject != "index.>" || d.On[0].Queue != "test" || d.On[0].Payload != nil {
+		t.Errorf("on[0] = %+v", d.On[0])
+	}
+	if d.On[1].Payload != payload || d.Emits[0].Subject != "signal.*" {
+		t.Errorf("on[1] = %+v, emits = %+v", d.On[1], d.Emits)
+	}
+	if d.Params == nil || d.Params.Properties["workers"].Type != "integer" {
+		t.Errorf("params = %+v", d.Params)
+	}
+}
+
+func TestSnapshot(t *testing.T) {
+	n := New("test", nil, zerolog.Nop())
+	levels := map[string]float64{"60000": 1.5}
+	if err := n.AddSnapshot("book", func() (any, error) { return levels, nil }); err != nil {
+		t.Fatal(err)
+	}
+	if err := n.AddSnapshot("orders", func() (any, error) { return []string{"1"}, nil }); err != nil {
+		t.Fatal(err)
+	}
+	if err := n.AddSnapshot("book", func() (any, error) { return nil, nil }); err == nil {
+		t.Error("duplicate part added")
+	}
+
+	s, err := n.Snapshot()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if s.Node != "test" || len(s.Parts) != 2 {
+		t.Errorf("snapshot = %+v", s)
+	}
+	var got map[string]float64
+	if ok, err := s.Decode("book", &got); !ok || err != nil || got["60000"] != 1.5 {
+		t.Errorf("decode = %v, %v, %v", got, ok, err)
+	}
+	if ok, _ := s.Decode("indicators", &got); ok {
+		t.Error("decoded a missing part")
+	}
+
+	s, err = n.Snapshot("orders")
+	if err != nil || len(s.Parts) != 1 || string(s.Parts["orders"]) != `["1"]` {
+		t.Errorf("snapshot of orders = %+v, %v", s, err)
+	}
+	if _, err := n.Snapshot("indicators"); err == nil {
+		t.Error("snapshot of an unknown part")
+	}
+}
+
+func TestState(t *testing.T) {
+	store := NewMemoryStateStore()
+	start := func(count *atomic.Int64) *Node {
+		t.Helper()
+		n := New("test", nil, zerolog.Nop())
+		if err := n.On("trade.>", func(*nats.Msg) error {
+			count.Add(1)
+			return nil
+		}, DispatchOptions{}); err != nil {
+			t.Fatal(err)
+		}
+		if err := n.SetState(StateOptions{Store: store, Interval: 10 * time.Millisecond}); err != nil {
+			t.Fatal(err)
+		}
+		if err := n.AddState("count", func() (any, error) { return count.Load(), nil }, func(data json.RawMessage) error {
+			var v int64
+			if err := json.Unmarshal(data, &v); err != nil {
+				return err
+			}
+			count.Store(v)
+			return nil
+		}); err != nil {
+			t.Fatal(err)
+		}
+		if err := n.Start(); err != nil {
+			t.Fatal(err)
+		}
+		return n
+	}
+
+	var first atomic.Int64
+	n := start(&first)
+	if err := n.AddState("late", func() (any, error) { return nil, nil }, func(json.RawMessage) error { return nil }); err == nil {
+		t.Error("state part added to a running node")
+	}
+	for i := 0; i < 3; i++ {
+		n.Inject(&nats.Msg{Subject: "trade.btcusdt"})
+	}
+	deadline := time.Now().Add(2 * time.Second)
+	for {
+		data, _ := store.Load("test.count")
+		if string(data) == "3" {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("checkpoint saved %q", data)
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+	n.Inject(&nats.Msg{Subject: "trade.btcusdt"})
+	n.Stop()
+	if data, _ := store.Load("test.count"); string(data) != "4" {
+		t.Fatalf("saved %q on stop", data)
+	}
+
+	// A restarted node resumes from the state saved
+	var second atomic.Int64
+	n = start(&second)
+	n.Inject(&nats.Msg{Subject: "trade.btcusdt"})
+	n.Stop()
+	if second.Load() != 5 {
+		t.Errorf("count %d after a restart", second.Load())
+	}
+
+	// State that no longer decodes is dropped rather than failing the start
+	store.Save("test.count", []byte(`"four"`))
+	var third atomic.Int64
+	n = start(&third)
+	n.Stop()
+	if third.Load() != 0 {
+		t.Errorf("count %d restored from an invalid state", third.Load())
+	}
+
+	if err := New("test", nil, zerolog.Nop()).SaveState(); err == nil {
+		t.Error("state saved without a store")
+	}
+	if err := New("test", nil, zerolog.Nop()).AddState("a.b", func() (any, error) { return nil, nil }, func(json.RawMessage) error { return nil }); err == nil {
+		t.Error("state part of an invalid name added")
+	}
+}
+
+func TestGuardPausesIntake(t *testing.T) {
+	g := newGuard(Limits{MaxGoroutines: 100, MaxMemory: 1000})
+	goroutines, heap := 50, uint64(500)
+	g.sample = func() (int, uint64) { return goroutines, heap }
+	if changed, degraded := g.check(); changed || degraded {
+		t.Fatal("degraded under the limits")
+	}
+
+	heap = 1200
+	if changed, degraded := g.check(); !changed || !degraded {
+		t.Fatal("not degraded past the memory limit")
+	}
+	released := make(chan struct{})
+	go func() {
+		g.wait()
+		close(released)
+	}()
+	// Under the limit but not under the resume ratio: still paused
+	heap = 950
+	if changed, _ := g.check(); changed {
+		t.Fatal("resumed above the resume ratio")
+	}
+	select {
+	case <-released:
+		t.Fatal("intake not paused")
+	case <-time.After(20 * time.Millisecond):
+	}
+	if h := g.health(); len(h.Reasons) != 1 || h.Reasons[0] != "memory" || h.Pauses != 1 {
+		t.Errorf("health = %+v", h)
+	}
+
+	heap = 800
+	if changed, degraded := g.check(); !changed || degraded {
+		t.Fatal("not recovered under the resume ratio")
+	}
+	select {
+	case <-released:
+	case <-time.After(time.Second):
+		t.Fatal("intake not resumed")
+	}
+	if h := g.health(); h.Throttled != 1 || len(h.Reasons) != 0 {
+		t.Errorf("health = %+v", h)
+	}
+
+	// Closing releases the callbacks held
+	goroutines = 200
+	g.check()
+	done := make(chan struct{})
+	go func() {
+		g.wait()
+		close(done)
+	}()
+	g.close()
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("intake held after close")
+	}
+}
+
+func TestGuardRate(t *testing.T) {
+	g := newGuard(Limits{MaxRate: 200, RateBurst: 2})
+	start := time.Now()
+	for range 6 {
+		g.wait()
+	}
+	// Two messages pass with the burst, the next four wait 5ms each
+	if elapsed := time.Since(start); elapsed < 18*time.Millisecond {
+		t.Errorf("6 messages at 200/s with a burst of 2 took %v", elapsed)
+	}
+	if h := g.health(); h.Throttled < 4 {
+		t.Errorf("throttled = %d", h.Throttled)
+	}
+
+	n := New("test", nil, zerolog.Nop())
+	if err := n.SetLimits(Limits{MaxRate: -1}); err == nil {
+		t.Error("negative limits accepted")
+	}
+	if err := n.SetLimits(Limits{MaxGoroutines: 1 << 20, CheckInterval: time.Millisecond}); err != nil {
+		t.Fatal(err)
+	}
+	if err := n.Start(); err != nil {
+		t.Fatal(err)
+	}
+	if err := n.SetLimits(Limits{}); err == nil {
+		t.Error("limits set on a running node")
+	}
+	if h := n.Health(); h.Status != StatusRunning || h.Goroutines == 0 || h.Limits.MaxGoroutines != 1<<20 {
+		t.Errorf("health = %+v", h)
+	}
+	n.Stop()
+}
@@ -0,0 +1,123 @@
+package node
+
+import (
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+)
+
+// Middleware wraps a handler, e.g. to add tracing or validation
+type Middleware func(Handler) Handler
+
+// PanicError is the error a recovered handler panic is reported as
+type PanicError struct {
+	Value any
+	Stack []byte
+}
+
+func (e *PanicError) Error() string {
+	return fmt.Sprintf("handler panic: %v", e.Value)
+}
+
+// RecoveryOptions configures the quarantine of subjects whose messages keep
+// crashing their handler. Panics are always recovered; quarantine is
+// disabled when QuarantineAfter is 0.
+type RecoveryOptions struct {
+	QuarantineAfter int           // Panics within Window that quarantine a subject
+	Window          time.Duration // Sliding window panics are counted over
+	Duration        time.Duration // Quarantine length, 0 until Release is called
+}
+
+// Quarantine describes a quarantined subject
+type Quarantine struct {
+	Subject string    `json:"subject"`
+	Since   time.Time `json:"since"`
+	Until   time.Time `json:"until"` // Zero when released manually only
+}
+
+// boundary tracks panics per concrete subject and decides which subjects are
+// quarantined. Messages of a quarantined subject are dropped unhandled.
+type boundary struct {
+	opts        RecoveryOptions
+	mu          sync.Mutex
+	panics      map[string][]time.Time
+	quarantined map[string]Quarantine
+}
+
+func newBoundary(opts RecoveryOptions) *boundary {
+	return &boundary{
+		opts:        opts,
+		panics:      make(map[string][]time.Time),
+		quarantined: make(map[string]Quarantine),
+	}
+}
+
+// allow reports whether messages of subject may be handled
+func (b *boundary) allow(subject string, now time.Time) bool {
+	if b.opts.QuarantineAfter <= 0 {
+		return true
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	q, ok := b.quarantined[subject]
+	if !ok {
+		return true
+	}
+	if !q.Until.IsZero() && !now.Before(q.Until) {
+		delete(b.quarantined, subject)
+		return true
+	}
+	return false
+}
+
+// recordPanic counts a panic and reports whether it quarantined the subject
+func (b *boundary) recordPanic(subject string, now time.Time) bool {
+	if b.opts.QuarantineAfter <= 0 {
+		return false
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	recent := b.panics[subject][:0]
+	for _, at := range b.panics[subject] {
+		if b.opts.Window <= 0 || now.Sub(at) < b.opts.Window {
+			recent = append(recent, at)
+		}
+	}
+	recent = append(recent, now)
+	if len(recent) < b.opts.QuarantineAfter {
+		b.panics[subject] = recent
+		return false
+	}
+
+	delete(b.panics, subject)
+	q := Quarantine{Subject: subject, Since: now}
+	if b.opts.Duration > 0 {
+		q.Until = now.Add(b.opts.Duration)
+	}
+	b.quarantined[subject] = q
+	return true
+}
+
+// release lifts the quarantine of a subject
+func (b *boundary) release(subject string) bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	_, ok := b.quarantined[subject]
+	delete(b.quarantined, subject)
+	return ok
+}
+
+func (b *boundary) list(now time.Time) []Quarantine {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	list := make([]Quarantine, 0, len(b.quarantined))
+	for _, q := range b.quarantined {
+		if q.Until.IsZero() || now.Before(q.Until) {
+			list = append(list, q)
+		}
+	}
+	sort.Slice(list, func(i, j int) bool { return list[i].Subject < list[j].Subject })
+	return list
+}
@@ -0,0 +1,140 @@
+package node
+
+import (
+	"encoding"
+	"encoding/json"
+	"reflect"
+	"strings"
+	"time"
+)
+
+// Schema is the subset of JSON Schema describing the parameters of a node
+// and the payloads of its subjects, enough for generic forms and docs
+type Schema struct {
+	Type                 string             `json:"type,omitempty"`
+	GoType               string             `json:"x-go-type,omitempty"` // Named Go type, e.g. sqx.Trade
+	Description          string             `json:"description,omitempty"`
+	Properties           map[string]*Schema `json:"properties,omitempty"`
+	Required             []string           `json:"required,omitempty"`
+	Items                *Schema            `json:"items,omitempty"`
+	AdditionalProperties *Schema            `json:"additionalProperties,omitempty"`
+}
+
+// descTag is the struct tag describing a field in its schema
+const descTag = "desc"
+
+var (
+	durationType      = reflect.TypeOf(time.Duration(0))
+	timeType          = reflect.TypeOf(time.Time{})
+	jsonMarshalerType = reflect.TypeOf((*json.Marshaler)(nil)).Elem()
+	textMarshalerType = reflect.TypeOf((*encoding.TextMarshaler)(nil)).Elem()
+	rawMessageType    = reflect.TypeOf(json.RawMessage(nil))
+)
+
+// SchemaOf returns the schema of the JSON encoding of v, a value or a nil
+// pointer of the type. Struct fields are named by their json tag, fields
+// without omitempty are required, and a desc tag describes a field.
+func SchemaOf(v any) *Schema {
+	if v == nil {
+		return nil
+	}
+	return schemaOf(reflect.TypeOf(v), make(map[reflect.Type]bool))
+}
+
+func schemaOf(t reflect.Type, visiting map[reflect.Type]bool) *Schema {
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	s := &Schema{}
+	if t.Name() != "" && t.PkgPath() != "" {
+		s.GoType = t.String()
+	}
+	switch {
+	case t == durationType:
+		s.Type = "integer"
+		s.Description = "nanoseconds"
+		return s
+	case t == timeType:
+		s.Type = "string"
+		s.Description = "RFC 3339 time"
+		return s
+	case t == rawMessageType:
+		return s
+	case t.Implements(jsonMarshalerType) || reflect.PointerTo(t).Implements(jsonMarshalerType):
+		// Encoded its own way, only the type is known
+		return s
+	case t.Implements(textMarshalerType) || reflect.PointerTo(t).Implements(textMarshalerType):
+		s.Type = "string"
+		return s
+	}
+
+	switch t.Kind() {
+	case reflect.Bool:
+		s.Type = "boolean"
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		s.Type = "integer"
+	case reflect.Float32, reflect.Float64:
+		s.Type = "number"
+	case reflect.String:
+		s.Type = "string"
+	case reflect.Slice, reflect.Array:
+		if t.Elem().Kind() == reflect.Uint8 {
+			s.Type = "string" // Base64 encoded
+			break
+		}
+		s.Type = "array"
+		s.Items = schemaOf(t.Elem(), visiting)
+	case reflect.Map:
+		s.Type = "object"
+		s.AdditionalProperties = schemaOf(t.Elem(), visiting)
+	case reflect.Struct:
+		s.Type = "object"
+		if visiting[t] {
+			// Recursive types are described once
+			return s
+		}
+		visiting[t] = true
+		defer delete(visiting, t)
+		s.Properties = make(map[string]*Schema)
+		addFields(s, t, visiting)
+	}
+	return s
+}
+
+// addFields adds the fields of struct t to s, those of embedded structs
+// without a json name inlined as encoding/json does
+func addFields(s *Schema, t reflect.Type, visiting map[reflect.Type]bool) {
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		tag := field.Tag.Get("json")
+		if tag == "-" {
+			continue
+		}
+		name, opts, _ := strings.Cut(tag, ",")
+		if field.Anonymous && name == "" {
+			embedded := field.Type
+			if embedded.Kind() == reflect.Pointer {
+				embedded = embedded.Elem()
+			}
+			if embedded.Kind() == reflect.Struct {
+				addFields(s, embedded, visiting)
+				continue
+			}
+		}
+		if !field.IsExported() {
+			continue
+		}
+		if name == "" {
+			name = field.Name
+		}
+		property := schemaOf(field.Type, visiting)
+		if desc := field.Tag.Get(descTag); desc != "" {
+			property.Description = desc
+		}
+		s.Properties[name] = property
+		if !strings.Contains(opts, "omitempty") && field.Type.Kind() != reflect.Pointer {
+			s.Required = append(s.Required, name)
+		}
+	}
+}
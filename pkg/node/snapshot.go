@@ -0,0 +1,120 @@
+package node
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/BullionBear/sequex/pkg/eventbus"
+	"github.com/nats-io/nats.go"
+)
+
+// SnapshotFunc returns the state of a component of a node, encoded as JSON
+type SnapshotFunc func() (any, error)
+
+// SnapshotRequest asks a node for the snapshot of parts of its state
+type SnapshotRequest struct {
+	Parts []string `json:"parts,omitempty"` // Every part when empty
+}
+
+// Snapshot is the internal state of a node at a point in time, for
+// debugging and for warm-starting replacement instances
+type Snapshot struct {
+	Node      string                     `json:"node"`
+	Timestamp int64                      `json:"timestamp"` // Milliseconds
+	Parts     map[string]json.RawMessage `json:"parts"`
+}
+
+// Decode decodes part into v, reporting whether the snapshot has it
+func (s *Snapshot) Decode(part string, v any) (bool, error) {
+	data, ok := s.Parts[part]
+	if !ok {
+		return false, nil
+	}
+	if err := json.Unmarshal(data, v); err != nil {
+		return true, fmt.Errorf("snapshot part %s: %w", part, err)
+	}
+	return true, nil
+}
+
+// SnapshotEndpoint is the endpoint a node serves its snapshots on
+func SnapshotEndpoint(subject string) eventbus.Endpoint[SnapshotRequest, Snapshot] {
+	return eventbus.NewEndpoint[SnapshotRequest, Snapshot](subject, eventbus.JSON)
+}
+
+// FetchSnapshot requests the snapshot of parts from the node serving
+// subject, every part when none is given
+func FetchSnapshot(ctx context.Context, bus *eventbus.Bus, subject string, parts ...string) (*Snapshot, error) {
+	return SnapshotEndpoint(subject).Call(ctx, bus, &SnapshotRequest{Parts: parts})
+}
+
+// AddSnapshot adds a named part to the snapshots of the node
+func (n *Node) AddSnapshot(part string, fn SnapshotFunc) error {
+	if fn == nil {
+		return fmt.Errorf("snapshot function cannot be nil")
+	}
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	if n.snapshots == nil {
+		n.snapshots = make(map[string]SnapshotFunc)
+	}
+	if _, ok := n.snapshots[part]; ok {
+		return fmt.Errorf("snapshot part %s already added", part)
+	}
+	n.snapshots[part] = fn
+	return nil
+}
+
+// SnapshotParts lists the parts of the snapshots of the node, sorted
+func (n *Node) SnapshotParts() []string {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	parts := make([]string, 0, len(n.snapshots))
+	for part := range n.snapshots {
+		parts = append(parts, part)
+	}
+	sort.Strings(parts)
+	return parts
+}
+
+// Snapshot captures parts of the state of the node, every part when none is
+// given. Parts are captured one after another, each consistent on its own.
+func (n *Node) Snapshot(parts ...string) (*Snapshot, error) {
+	if len(parts) == 0 {
+		parts = n.SnapshotParts()
+	}
+	n.mu.Lock()
+	fns := make(map[string]SnapshotFunc, len(parts))
+	for _, part := range parts {
+		fn, ok := n.snapshots[part]
+		if !ok {
+			n.mu.Unlock()
+			return nil, fmt.Errorf("unknown snapshot part %s", part)
+		}
+		fns[part] = fn
+	}
+	n.mu.Unlock()
+
+	s := &Snapshot{Node: n.name, Timestamp: time.Now().UnixMilli(), Parts: make(map[string]json.RawMessage, len(fns))}
+	for part, fn := range fns {
+		state, err := fn()
+		if err != nil {
+			return nil, fmt.Errorf("snapshot part %s: %w", part, err)
+		}
+		data, err := json.Marshal(state)
+		if err != nil {
+			return nil, fmt.Errorf("snapshot part %s: %w", part, err)
+		}
+		s.Parts[part] = data
+	}
+	return s, nil
+}
+
+// ServeSnapshot answers snapshot requests on subject
+func (n *Node) ServeSnapshot(subject string) (*nats.Subscription, error) {
+	return SnapshotEndpoint(subject).Serve(n.bus, func(_ context.Context, req *SnapshotRequest) (*Snapshot, error) {
+		return n.Snapshot(req.Parts...)
+	})
+}
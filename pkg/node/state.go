@@ -0,0 +1,229 @@
+package node
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/nats-io/nats.go"
+)
+
+// DefaultStateBucket is the key-value bucket the state of nodes is kept in
+const DefaultStateBucket = "node_state"
+
+// StateStore keeps the state parts of nodes across restarts, by key
+type StateStore interface {
+	Load(key string) ([]byte, error) // Nil when the key was never saved
+	Save(key string, data []byte) error
+}
+
+// KVStateStore keeps state in a key-value bucket
+type KVStateStore struct {
+	kv nats.KeyValue
+}
+
+// OpenStateStore opens the state bucket, creating it when missing. Only
+// the last state of each key is kept.
+func OpenStateStore(js nats.JetStreamContext, bucket string) (*KVStateStore, error) {
+	if bucket == "" {
+		return nil, fmt.Errorf("bucket cannot be empty")
+	}
+	kv, err := js.KeyValue(bucket)
+	if errors.Is(err, nats.ErrBucketNotFound) {
+		kv, err = js.CreateKeyValue(&nats.KeyValueConfig{Bucket: bucket, History: 1, Description: "Node state"})
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to open bucket %s: %w", bucket, err)
+	}
+	return &KVStateStore{kv: kv}, nil
+}
+
+// Load returns the state saved under key
+func (s *KVStateStore) Load(key string) ([]byte, error) {
+	entry, err := s.kv.Get(key)
+	if errors.Is(err, nats.ErrKeyNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return entry.Value(), nil
+}
+
+// Save saves the state under key
+func (s *KVStateStore) Save(key string, data []byte) error {
+	_, err := s.kv.Put(key, data)
+	return err
+}
+
+// MemoryStateStore keeps state in memory, for tests and nodes run in
+// process
+type MemoryStateStore struct {
+	mu    sync.Mutex
+	state map[string][]byte
+}
+
+// NewMemoryStateStore creates an empty store
+func NewMemoryStateStore() *MemoryStateStore {
+	return &MemoryStateStore{state: make(map[string][]byte)}
+}
+
+// Load returns the state saved under key
+func (s *MemoryStateStore) Load(key string) ([]byte, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.state[key], nil
+}
+
+// Save saves the state under key
+func (s *MemoryStateStore) Save(key string, data []byte) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.state[key] = append([]byte(nil), data...)
+	return nil
+}
+
+// RestoreFunc restores a component of a node from the state it saved
+type RestoreFunc func(data json.RawMessage) error
+
+// statePart is a part of the state of a node kept across restarts
+type statePart struct {
+	save    SnapshotFunc
+	restore RestoreFunc
+}
+
+// StateOptions configures where the state parts of a node are kept
+type StateOptions struct {
+	Store    StateStore
+	Interval time.Duration // Between checkpoints while running, saved only when stopping when 0
+}
+
+// SetState configures the store of the state parts of the node
+func (n *Node) SetState(opts StateOptions) error {
+	if opts.Store == nil {
+		return fmt.Errorf("state store cannot be nil")
+	}
+	if opts.Interval < 0 {
+		return fmt.Errorf("state interval cannot be negative")
+	}
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	if n.status != StatusCreated {
+		return fmt.Errorf("node %s already started", n.name)
+	}
+	n.state = opts
+	return nil
+}
+
+// AddState adds a named part to the state kept across restarts, under the
+// key <node>.<part> of the store. restore is handed the state saved last
+// when the node starts, before any message is handled; save is called on
+// every checkpoint and once the node has stopped handling messages, so it
+// must be safe to call concurrently with the handlers.
+func (n *Node) AddState(part string, save SnapshotFunc, restore RestoreFunc) error {
+	if save == nil || restore == nil {
+		return fmt.Errorf("state functions cannot be nil")
+	}
+	if part == "" || strings.ContainsAny(part, ".*> ") {
+		return fmt.Errorf("invalid state part %q", part)
+	}
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	if n.status != StatusCreated {
+		return fmt.Errorf("node %s already started", n.name)
+	}
+	if n.stateParts == nil {
+		n.stateParts = make(map[string]statePart)
+	}
+	if _, ok := n.stateParts[part]; ok {
+		return fmt.Errorf("state part %s already added", part)
+	}
+	n.stateParts[part] = statePart{save: save, restore: restore}
+	return nil
+}
+
+// stateNames returns the state parts, sorted
+func (n *Node) stateNames() []string {
+	parts := make([]string, 0, len(n.stateParts))
+	for part := range n.stateParts {
+		parts = append(parts, part)
+	}
+	sort.Strings(parts)
+	return parts
+}
+
+// restoreStateLocked restores every state part saved. A part failing to
+// restore is logged and left as created, to warm up from scratch, rather
+// than keeping the node from starting.
+func (n *Node) restoreStateLocked() {
+	if n.state.Store == nil {
+		return
+	}
+	for _, part := range n.stateNames() {
+		key := n.name + "." + part
+		data, err := n.state.Store.Load(key)
+		if err != nil {
+			n.log.Error().Err(err).Str("part", part).Msg("Failed to load state")
+			continue
+		}
+		if data == nil {
+			n.log.Info().Str("part", part).Msg("No state saved")
+			continue
+		}
+		if err := n.stateParts[part].restore(data); err != nil {
+			n.log.Warn().Err(err).Str("part", part).Msg("Failed to restore state, starting afresh")
+			continue
+		}
+		n.log.Info().Str("part", part).Int("bytes", len(data)).Msg("State restored")
+	}
+}
+
+// SaveState saves every state part, returning the errors of those failing
+func (n *Node) SaveState() error {
+	n.mu.Lock()
+	store := n.state.Store
+	parts := make(map[string]statePart, len(n.stateParts))
+	for part, p := range n.stateParts {
+		parts[part] = p
+	}
+	n.mu.Unlock()
+	if store == nil {
+		return fmt.Errorf("node %s has no state store", n.name)
+	}
+
+	var errs []error
+	for part, p := range parts {
+		state, err := p.save()
+		if err == nil {
+			var data []byte
+			if data, err = json.Marshal(state); err == nil {
+				err = store.Save(n.name+"."+part, data)
+			}
+		}
+		if err != nil {
+			errs = append(errs, fmt.Errorf("state part %s: %w", part, err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// checkpoint saves the state every interval until stop is closed
+func (n *Node) checkpoint(interval time.Duration, stop <-chan struct{}, done chan<- struct{}) {
+	defer close(done)
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-stop:
+			return
+		case <-ticker.C:
+			if err := n.SaveState(); err != nil {
+				n.log.Error().Err(err).Msg("Failed to checkpoint state")
+			}
+		}
+	}
+}
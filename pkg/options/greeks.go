@@ -0,0 +1,83 @@
+package options
+
+import (
+	"math"
+)
+
+// Greeks holds the price sensitivities of a single option contract.
+// Vega is per 1.00 change in volatility and Theta is per year.
+type Greeks struct {
+	Price float64 `json:"price"`
+	Delta float64 `json:"delta"`
+	Gamma float64 `json:"gamma"`
+	Vega  float64 `json:"vega"`
+	Theta float64 `json:"theta"`
+	Rho   float64 `json:"rho"`
+}
+
+// Scale returns the greeks multiplied by a position size
+func (g Greeks) Scale(size float64) Greeks {
+	return Greeks{
+		Price: g.Price * size,
+		Delta: g.Delta * size,
+		Gamma: g.Gamma * size,
+		Vega:  g.Vega * size,
+		Theta: g.Theta * size,
+		Rho:   g.Rho * size,
+	}
+}
+
+// Add returns the sum of two sets of greeks
+func (g Greeks) Add(other Greeks) Greeks {
+	return Greeks{
+		Price: g.Price + other.Price,
+		Delta: g.Delta + other.Delta,
+		Gamma: g.Gamma + other.Gamma,
+		Vega:  g.Vega + other.Vega,
+		Theta: g.Theta + other.Theta,
+		Rho:   g.Rho + other.Rho,
+	}
+}
+
+// ComputeGreeks returns the Black-76 price and greeks of the contract for the given volatility
+func ComputeGreeks(c Contract, vol float64) (Greeks, error) {
+	price, err := Price(c, vol)
+	if err != nil {
+		return Greeks{}, err
+	}
+
+	df := c.discount()
+	greeks := Greeks{
+		Price: price,
+		Rho:   -c.Expiry * price,
+	}
+
+	// Expired or zero-vol contracts only carry the delta of their intrinsic value
+	if c.Expiry == 0 || vol == 0 {
+		switch {
+		case c.Type == OptionTypeCall && c.Forward > c.Strike:
+			greeks.Delta = df
+		case c.Type == OptionTypePut && c.Forward < c.Strike:
+			greeks.Delta = -df
+		}
+		return greeks, nil
+	}
+
+	sqrtT := math.Sqrt(c.Expiry)
+	d1, d2 := c.d1d2(vol)
+	pdf := normPDF(d1)
+
+	greeks.Gamma = df * pdf / (c.Forward * vol * sqrtT)
+	greeks.Vega = df * c.Forward * pdf * sqrtT
+
+	decay := -df * c.Forward * pdf * vol / (2 * sqrtT)
+	if c.Type == OptionTypeCall {
+		greeks.Delta = df * normCDF(d1)
+		greeks.Theta = decay + c.Rate*df*(c.Forward*normCDF(d1)-c.Strike*normCDF(d2))
+	} else {
+		greeks.Delta = -df * normCDF(-d1)
+		greeks.Theta = decay + c.Rate*df*(c.Strike*normCDF(-d2)-c.Forward*normCDF(-d1))
+	}
+
+	return greeks, nil
+}
@@ -0,0 +1,63 @@
+package options
+
+import (
+	"fmt"
+	"math"
+)
+
+const (
+	minVol          = 1e-6
+	maxVol          = 10.0
+	ivTolerance     = 1e-8
+	ivMaxIterations = 100
+)
+
+// ImpliedVol solves for the Black-76 volatility that reproduces the given premium.
+// Newton-Raphson is used while vega is meaningful, falling back to bisection otherwise.
+func ImpliedVol(c Contract, premium float64) (float64, error) {
+	if err := c.Validate(); err != nil {
+		return 0, err
+	}
+	if c.Expiry == 0 {
+		return 0, fmt.Errorf("cannot solve implied volatility for an expired contract")
+	}
+
+	df := c.discount()
+	lower := c.intrinsic()
+	upper := df * c.Forward
+	if c.Type == OptionTypePut {
+		upper = df * c.Strike
+	}
+	if premium < lower || premium >= upper {
+		return 0, fmt.Errorf("premium %f outside of arbitrage bounds [%f, %f)", premium, lower, upper)
+	}
+
+	lo, hi := minVol, maxVol
+	vol := 0.5
+	for i := 0; i < ivMaxIterations; i++ {
+		greeks, err := ComputeGreeks(c, vol)
+		if err != nil {
+			return 0, err
+		}
+
+		diff := greeks.Price - premium
+		if math.Abs(diff) < ivTolerance {
+			return vol, nil
+		}
+
+		// Keep a bracket around the root so bisection can take over
+		if diff > 0 {
+			hi = vol
+		} else {
+			lo = vol
+		}
+
+		next := vol - diff/greeks.Vega
+		if greeks.Vega < ivTolerance || next <= lo || next >= hi {
+			next = 0.5 * (lo + hi)
+		}
+		vol = next
+	}
+
+	return 0, fmt.Errorf("implied volatility did not converge after %d iterations", ivMaxIterations)
+}
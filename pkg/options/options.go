@@ -0,0 +1,107 @@
+package options
+
+import (
+	"fmt"
+	"math"
+	"strings"
+)
+
+// OptionType represents the right of an option contract
+type OptionType int
+
+const (
+	OptionTypeUnknown OptionType = iota
+	OptionTypeCall
+	OptionTypePut
+)
+
+func NewOptionType(optionType string) OptionType {
+	switch strings.ToUpper(optionType) {
+	case "C", "CALL":
+		return OptionTypeCall
+	case "P", "PUT":
+		return OptionTypePut
+	}
+	return OptionTypeUnknown
+}
+
+func (o OptionType) String() string {
+	return []string{"UNKNOWN", "CALL", "PUT"}[o]
+}
+
+// Contract holds the market inputs needed to price a European option on a forward
+type Contract struct {
+	Type    OptionType `json:"type"`
+	Forward float64    `json:"forward"` // Forward (or futures) price of the underlying
+	Strike  float64    `json:"strike"`
+	Expiry  float64    `json:"expiry"` // Time to expiry in years
+	Rate    float64    `json:"rate"`   // Continuously compounded risk-free rate
+}
+
+// Validate validates the contract inputs
+func (c Contract) Validate() error {
+	if c.Type != OptionTypeCall && c.Type != OptionTypePut {
+		return fmt.Errorf("invalid option type: %s", c.Type)
+	}
+	if c.Forward <= 0 {
+		return fmt.Errorf("forward must be positive, got %f", c.Forward)
+	}
+	if c.Strike <= 0 {
+		return fmt.Errorf("strike must be positive, got %f", c.Strike)
+	}
+	if c.Expiry < 0 {
+		return fmt.Errorf("expiry cannot be negative, got %f", c.Expiry)
+	}
+	return nil
+}
+
+// discount returns the discount factor e^(-rT)
+func (c Contract) discount() float64 {
+	return math.Exp(-c.Rate * c.Expiry)
+}
+
+// intrinsic returns the discounted intrinsic value of the contract
+func (c Contract) intrinsic() float64 {
+	if c.Type == OptionTypeCall {
+		return c.discount() * math.Max(c.Forward-c.Strike, 0)
+	}
+	return c.discount() * math.Max(c.Strike-c.Forward, 0)
+}
+
+// d1d2 returns the Black-76 d1 and d2 terms for the given volatility
+func (c Contract) d1d2(vol float64) (float64, float64) {
+	stdDev := vol * math.Sqrt(c.Expiry)
+	d1 := (math.Log(c.Forward/c.Strike) + 0.5*stdDev*stdDev) / stdDev
+	return d1, d1 - stdDev
+}
+
+// Price returns the Black-76 premium of the contract for the given volatility.
+// Expired contracts or a zero volatility return the discounted intrinsic value.
+func Price(c Contract, vol float64) (float64, error) {
+	if err := c.Validate(); err != nil {
+		return 0, err
+	}
+	if vol < 0 {
+		return 0, fmt.Errorf("volatility cannot be negative, got %f", vol)
+	}
+	if c.Expiry == 0 || vol == 0 {
+		return c.intrinsic(), nil
+	}
+
+	d1, d2 := c.d1d2(vol)
+	df := c.discount()
+	if c.Type == OptionTypeCall {
+		return df * (c.Forward*normCDF(d1) - c.Strike*normCDF(d2)), nil
+	}
+	return df * (c.Strike*normCDF(-d2) - c.Forward*normCDF(-d1)), nil
+}
+
+// normCDF is the standard normal cumulative distribution function
+func normCDF(x float64) float64 {
+	return 0.5 * math.Erfc(-x/math.Sqrt2)
+}
+
+// normPDF is the standard normal probability density function
+func normPDF(x float64) float64 {
+	return math.Exp(-0.5*x*x) / math.Sqrt(2*math.Pi)
+}
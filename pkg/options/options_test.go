@@ -0,0 +1,219 @@
+package options
+
+import (
+	"math"
+	"testing"
+)
+
+func TestPrice(t *testing.T) {
+	tests := []struct {
+		name     string
+		contract Contract
+		vol      float64
+		expected float64
+	}{
+		{
+			name:     "at-the-money call",
+			contract: Contract{Type: OptionTypeCall, Forward: 100, Strike: 100, Expiry: 1, Rate: 0},
+			vol:      0.2,
+			expected: 7.965567455405804,
+		},
+		{
+			name:     "at-the-money put equals call",
+			contract: Contract{Type: OptionTypePut, Forward: 100, Strike: 100, Expiry: 1, Rate: 0},
+			vol:      0.2,
+			expected: 7.965567455405804,
+		},
+		{
+			name:     "expired in-the-money call is intrinsic",
+			contract: Contract{Type: OptionTypeCall, Forward: 110, Strike: 100, Expiry: 0, Rate: 0.05},
+			vol:      0.5,
+			expected: 10,
+		},
+		{
+			name:     "zero vol out-of-the-money put is worthless",
+			contract: Contract{Type: OptionTypePut, Forward: 110, Strike: 100, Expiry: 1, Rate: 0},
+			vol:      0,
+			expected: 0,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			price, err := Price(tt.contract, tt.vol)
+			if err != nil {
+				t.Fatalf("Price() returned error: %v", err)
+			}
+			if math.Abs(price-tt.expected) > 1e-9 {
+				t.Errorf("Price() = %v, expected %v", price, tt.expected)
+			}
+		})
+	}
+}
+
+func TestPrice_PutCallParity(t *testing.T) {
+	call := Contract{Type: OptionTypeCall, Forward: 30000, Strike: 32000, Expiry: 0.25, Rate: 0.03}
+	put := call
+	put.Type = OptionTypePut
+
+	callPrice, err := Price(call, 0.65)
+	if err != nil {
+		t.Fatalf("Price(call) returned error: %v", err)
+	}
+	putPrice, err := Price(put, 0.65)
+	if err != nil {
+		t.Fatalf("Price(put) returned error: %v", err)
+	}
+
+	// C - P = e^(-rT) (F - K)
+	expected := call.discount() * (call.Forward - call.Strike)
+	if math.Abs((callPrice-putPrice)-expected) > 1e-6 {
+		t.Errorf("put-call parity violated: C-P = %v, expected %v", callPrice-putPrice, expected)
+	}
+}
+
+func TestPrice_InvalidContract(t *testing.T) {
+	tests := []struct {
+		name     string
+		contract Contract
+		vol      float64
+	}{
+		{"unknown type", Contract{Forward: 100, Strike: 100, Expiry: 1}, 0.2},
+		{"zero forward", Contract{Type: OptionTypeCall, Strike: 100, Expiry: 1}, 0.2},
+		{"negative strike", Contract{Type: OptionTypeCall, Forward: 100, Strike: -1, Expiry: 1}, 0.2},
+		{"negative expiry", Contract{Type: OptionTypeCall, Forward: 100, Strike: 100, Expiry: -1}, 0.2},
+		{"negative vol", Contract{Type: OptionTypeCall, Forward: 100, Strike: 100, Expiry: 1}, -0.2},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := Price(tt.contract, tt.vol); err == nil {
+				t.Errorf("Price() expected error, got nil")
+			}
+		})
+	}
+}
+
+func TestComputeGreeks_FiniteDifference(t *testing.T) {
+	const h = 1e-4
+	for _, optionType := range []OptionType{OptionTypeCall, OptionTypePut} {
+		t.Run(optionType.String(), func(t *testing.T) {
+			c := Contract{Type: optionType, Forward: 2500, Strike: 2400, Expiry: 0.5, Rate: 0.04}
+			vol := 0.7
+
+			greeks, err := ComputeGreeks(c, vol)
+			if err != nil {
+				t.Fatalf("ComputeGreeks() returned error: %v", err)
+			}
+
+			up, down := c, c
+			up.Forward += h
+			down.Forward -= h
+			priceUp, _ := Price(up, vol)
+			priceDown, _ := Price(down, vol)
+			price, _ := Price(c, vol)
+
+			delta := (priceUp - priceDown) / (2 * h)
+			if math.Abs(greeks.Delta-delta) > 1e-5 {
+				t.Errorf("Delta = %v, finite difference %v", greeks.Delta, delta)
+			}
+
+			gamma := (priceUp - 2*price + priceDown) / (h * h)
+			if math.Abs(greeks.Gamma-gamma) > 1e-4 {
+				t.Errorf("Gamma = %v, finite difference %v", greeks.Gamma, gamma)
+			}
+
+			volUp, _ := Price(c, vol+h)
+			volDown, _ := Price(c, vol-h)
+			vega := (volUp - volDown) / (2 * h)
+			if math.Abs(greeks.Vega-vega) > 1e-4 {
+				t.Errorf("Vega = %v, finite difference %v", greeks.Vega, vega)
+			}
+
+			// Theta is the sensitivity to calendar time, i.e. -dV/dT
+			longer, shorter := c, c
+			longer.Expiry += h
+			shorter.Expiry -= h
+			priceLonger, _ := Price(longer, vol)
+			priceShorter, _ := Price(shorter, vol)
+			theta := -(priceLonger - priceShorter) / (2 * h)
+			if math.Abs(greeks.Theta-theta) > 1e-3 {
+				t.Errorf("Theta = %v, finite difference %v", greeks.Theta, theta)
+			}
+		})
+	}
+}
+
+func TestImpliedVol_RoundTrip(t *testing.T) {
+	tests := []struct {
+		name     string
+		contract Contract
+		vol      float64
+	}{
+		{"atm call", Contract{Type: OptionTypeCall, Forward: 100, Strike: 100, Expiry: 1, Rate: 0.01}, 0.2},
+		{"otm put high vol", Contract{Type: OptionTypePut, Forward: 60000, Strike: 45000, Expiry: 0.1, Rate: 0.05}, 1.2},
+		{"deep itm call", Contract{Type: OptionTypeCall, Forward: 150, Strike: 100, Expiry: 0.5, Rate: 0}, 0.35},
+		{"short dated otm call", Contract{Type: OptionTypeCall, Forward: 3000, Strike: 3300, Expiry: 7.0 / 365, Rate: 0}, 0.8},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			premium, err := Price(tt.contract, tt.vol)
+			if err != nil {
+				t.Fatalf("Price() returned error: %v", err)
+			}
+			vol, err := ImpliedVol(tt.contract, premium)
+			if err != nil {
+				t.Fatalf("ImpliedVol() returned error: %v", err)
+			}
+			if math.Abs(vol-tt.vol) > 1e-6 {
+				t.Errorf("ImpliedVol() = %v, expected %v", vol, tt.vol)
+			}
+		})
+	}
+}
+
+func TestImpliedVol_OutOfBounds(t *testing.T) {
+	c := Contract{Type: OptionTypeCall, Forward: 110, Strike: 100, Expiry: 1, Rate: 0}
+	if _, err := ImpliedVol(c, 5); err == nil {
+		t.Error("expected error for premium below intrinsic value")
+	}
+	if _, err := ImpliedVol(c, 120); err == nil {
+		t.Error("expected error for premium above forward")
+	}
+}
+
+func TestValuePortfolio(t *testing.T) {
+	call := Contract{Type: OptionTypeCall, Forward: 100, Strike: 100, Expiry: 1, Rate: 0}
+	put := call
+	put.Type = OptionTypePut
+
+	// A long straddle: call and put deltas largely offset, leaving long gamma and vega
+	positions := []Position{
+		{Name: "call", Contract: call, Size: 2, Multiplier: 1, EntryPrice: 7, Vol: 0.2},
+		{Name: "put", Contract: put, Size: 2, Multiplier: 1, EntryPrice: 7, Vol: 0.2},
+	}
+
+	valuation, err := ValuePortfolio(positions)
+	if err != nil {
+		t.Fatalf("ValuePortfolio() returned error: %v", err)
+	}
+
+	price, _ := Price(call, 0.2)
+	if math.Abs(valuation.MarketValue-4*price) > 1e-9 {
+		t.Errorf("MarketValue = %v, expected %v", valuation.MarketValue, 4*price)
+	}
+	if math.Abs(valuation.UnrealizedPnL-4*(price-7)) > 1e-9 {
+		t.Errorf("UnrealizedPnL = %v, expected %v", valuation.UnrealizedPnL, 4*(price-7))
+	}
+
+	callGreeks, _ := ComputeGreeks(call, 0.2)
+	putGreeks, _ := ComputeGreeks(put, 0.2)
+	expectedDelta := 2*callGreeks.Delta + 2*putGreeks.Delta
+	if math.Abs(valuation.Greeks.Delta-expectedDelta) > 1e-9 {
+		t.Errorf("Delta = %v, expected %v", valuation.Greeks.Delta, expectedDelta)
+	}
+	if valuation.Greeks.Gamma <= 0 || valuation.Greeks.Vega <= 0 {
+		t.Errorf("expected long gamma and vega, got gamma %v vega %v", valuation.Greeks.Gamma, valuation.Greeks.Vega)
+	}
+}
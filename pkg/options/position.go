@@ -0,0 +1,58 @@
+package options
+
+import (
+	"fmt"
+)
+
+// Position is a holding in a single option contract.
+// Size is signed: positive for long, negative for short.
+type Position struct {
+	Name       string   `json:"name"`
+	Contract   Contract `json:"contract"`
+	Size       float64  `json:"size"`
+	Multiplier float64  `json:"multiplier"` // Underlying units per contract, defaults to 1
+	EntryPrice float64  `json:"entry_price"`
+	Vol        float64  `json:"vol"`
+}
+
+// Valuation holds the mark-to-model value and exposures of one or more positions
+type Valuation struct {
+	MarketValue   float64 `json:"market_value"`
+	UnrealizedPnL float64 `json:"unrealized_pnl"`
+	Greeks        Greeks  `json:"greeks"`
+}
+
+// Value returns the valuation of the position at its current volatility
+func (p Position) Value() (Valuation, error) {
+	greeks, err := ComputeGreeks(p.Contract, p.Vol)
+	if err != nil {
+		return Valuation{}, fmt.Errorf("failed to value position %s: %w", p.Name, err)
+	}
+
+	multiplier := p.Multiplier
+	if multiplier == 0 {
+		multiplier = 1
+	}
+	exposure := greeks.Scale(p.Size * multiplier)
+
+	return Valuation{
+		MarketValue:   exposure.Price,
+		UnrealizedPnL: (greeks.Price - p.EntryPrice) * p.Size * multiplier,
+		Greeks:        exposure,
+	}, nil
+}
+
+// ValuePortfolio aggregates the valuation and greeks exposure of a set of positions
+func ValuePortfolio(positions []Position) (Valuation, error) {
+	var total Valuation
+	for _, p := range positions {
+		v, err := p.Value()
+		if err != nil {
+			return Valuation{}, err
+		}
+		total.MarketValue += v.MarketValue
+		total.UnrealizedPnL += v.UnrealizedPnL
+		total.Greeks = total.Greeks.Add(v.Greeks)
+	}
+	return total, nil
+}
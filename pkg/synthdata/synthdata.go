@@ -0,0 +1,380 @@
+// Package synthdata generates realistic market data for backtests, test
+// harnesses and load tests that cannot rely on recordings. Prices follow a
+// geometric Brownian motion whose volatility, drift and trading activity
+// change with configurable regimes, trades arrive as a Poisson process, and
+// flash crashes drop the price, thin the book and recover. The stream is
+// made of lob events, so it replays into the order book simulator as a
+// recording does, and trades fold into klines with Klines.
+package synthdata
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math"
+	"math/rand"
+	"sort"
+	"time"
+
+	"github.com/BullionBear/sequex/pkg/candles"
+	"github.com/BullionBear/sequex/pkg/lob"
+)
+
+// year is the time unit of volatilities and drifts
+const year = 365 * 24 * time.Hour
+
+// Regime sets the behaviour of the market from Start until the next regime
+type Regime struct {
+	Start      time.Duration `json:"start"`      // Since the start of the stream
+	Volatility float64       `json:"volatility"` // Annualized, e.g. 0.6 for 60%
+	Drift      float64       `json:"drift"`      // Annualized expected return
+	TradeRate  float64       `json:"trade_rate"` // Mean trades per second
+}
+
+// FlashCrash drops the price by Drop over Duration, as liquidity is pulled
+// and sellers rush in, then recovers Recovery of the drop over
+// RecoveryDuration
+type FlashCrash struct {
+	At               time.Duration `json:"at"`                // Since the start of the stream
+	Drop             float64       `json:"drop"`              // Share of the price lost at the bottom, e.g. 0.2
+	Duration         time.Duration `json:"duration"`          // From the top to the bottom
+	Recovery         float64       `json:"recovery"`          // Share of the drop recovered, from 0 to 1
+	RecoveryDuration time.Duration `json:"recovery_duration"` // From the bottom to the recovered price
+	Activity         float64       `json:"activity"`          // Trade rate multiplier until recovered, 5 when 0
+	Liquidity        float64       `json:"liquidity"`         // Book depth multiplier until recovered, 0.2 when 0
+}
+
+// Depth shapes the order book
+type Depth struct {
+	Levels        int           `json:"levels"`         // Per side, 10 when 0
+	Interval      time.Duration `json:"interval"`       // Between depth updates, 100ms when 0
+	SpreadTicks   int           `json:"spread_ticks"`   // Between the best bid and ask, 1 when 0
+	LevelQuantity float64       `json:"level_quantity"` // Mean quantity of the best levels, growing outwards, 1 when 0
+}
+
+// Config configures a generator
+type Config struct {
+	Start        time.Time    `json:"start"`
+	Price        float64      `json:"price"`         // Initial mid price
+	TickSize     float64      `json:"tick_size"`     // Prices are multiples of it, Price/10000 when 0
+	MeanQuantity float64      `json:"mean_quantity"` // Of trades, exponentially distributed, 0.1 when 0
+	Regimes      []Regime     `json:"regimes"`       // By start, the first starting at 0
+	Crashes      []FlashCrash `json:"crashes"`
+	Depth        Depth        `json:"depth"`
+	Seed         int64        `json:"seed"` // 1 when 0, so streams are reproducible
+}
+
+// Validate validates the config and sets its defaults
+func (c *Config) Validate() error {
+	if c.Price <= 0 {
+		return fmt.Errorf("price must be positive")
+	}
+	if c.TickSize < 0 || c.MeanQuantity < 0 {
+		return fmt.Errorf("tick_size and mean_quantity cannot be negative")
+	}
+	if c.TickSize == 0 {
+		c.TickSize = c.Price / 10000
+	}
+	if c.MeanQuantity == 0 {
+		c.MeanQuantity = 0.1
+	}
+	if len(c.Regimes) == 0 {
+		return fmt.Errorf("at least one regime is required")
+	}
+	sort.SliceStable(c.Regimes, func(i, j int) bool { return c.Regimes[i].Start < c.Regimes[j].Start })
+	if c.Regimes[0].Start != 0 {
+		return fmt.Errorf("the first regime must start at 0")
+	}
+	for i, r := range c.Regimes {
+		if r.Volatility < 0 || r.TradeRate <= 0 {
+			return fmt.Errorf("regimes[%d]: volatility cannot be negative and trade_rate must be positive", i)
+		}
+	}
+	for i := range c.Crashes {
+		crash := &c.Crashes[i]
+		if crash.At < 0 || crash.Drop <= 0 || crash.Drop >= 1 || crash.Duration <= 0 {
+			return fmt.Errorf("crashes[%d]: at cannot be negative, drop must be between 0 and 1 and duration positive", i)
+		}
+		if crash.Recovery < 0 || crash.Recovery > 1 || crash.RecoveryDuration < 0 || crash.Activity < 0 || crash.Liquidity < 0 {
+			return fmt.Errorf("crashes[%d]: recovery must be between 0 and 1, durations and multipliers cannot be negative", i)
+		}
+		if crash.Activity == 0 {
+			crash.Activity = 5
+		}
+		if crash.Liquidity == 0 {
+			crash.Liquidity = 0.2
+		}
+	}
+	d := &c.Depth
+	if d.Levels < 0 || d.Interval < 0 || d.SpreadTicks < 0 || d.LevelQuantity < 0 {
+		return fmt.Errorf("depth cannot be negative")
+	}
+	if d.Levels == 0 {
+		d.Levels = 10
+	}
+	if d.Interval == 0 {
+		d.Interval = 100 * time.Millisecond
+	}
+	if d.SpreadTicks == 0 {
+		d.SpreadTicks = 1
+	}
+	if d.LevelQuantity == 0 {
+		d.LevelQuantity = 1
+	}
+	if c.Seed == 0 {
+		c.Seed = 1
+	}
+	return nil
+}
+
+// Generator produces the events of a synthetic market in time order. It is
+// not safe for concurrent use.
+type Generator struct {
+	cfg    Config
+	rand   *rand.Rand
+	start  int64 // Unix milliseconds
+	now    time.Duration
+	base   float64 // Price of the Brownian motion, before crashes
+	tick   float64
+	trade  time.Duration // Of the next trade
+	depth  time.Duration // Of the next depth update
+	bids   map[float64]float64
+	asks   map[float64]float64
+	booked bool // Whether the snapshot was sent
+}
+
+// New creates a generator
+func New(cfg Config) (*Generator, error) {
+	if err := cfg.Validate(); err != nil {
+		return nil, err
+	}
+	g := &Generator{
+		cfg:   cfg,
+		rand:  rand.New(rand.NewSource(cfg.Seed)),
+		start: cfg.Start.UnixMilli(),
+		base:  cfg.Price,
+		tick:  cfg.TickSize,
+	}
+	g.trade = g.arrival(0)
+	return g, nil
+}
+
+// regime returns the regime in force at t
+func (g *Generator) regime(t time.Duration) Regime {
+	i := sort.Search(len(g.cfg.Regimes), func(i int) bool { return g.cfg.Regimes[i].Start > t }) - 1
+	return g.cfg.Regimes[i]
+}
+
+// crash returns the price and liquidity multipliers of the crashes at t, the
+// activity multiplier and whether the price is falling
+func (g *Generator) crash(t time.Duration) (price, liquidity, activity float64, falling bool) {
+	price, liquidity, activity = 1, 1, 1
+	for _, c := range g.cfg.Crashes {
+		elapsed := t - c.At
+		if elapsed < 0 || elapsed > c.Duration+c.RecoveryDuration {
+			continue
+		}
+		// The price moves linearly in log space, down then back up
+		bottom := math.Log(1 - c.Drop)
+		recovered := math.Log(1 - c.Drop*(1-c.Recovery))
+		var level float64
+		if elapsed <= c.Duration {
+			level = bottom * float64(elapsed) / float64(c.Duration)
+			falling = true
+		} else {
+			level = bottom + (recovered-bottom)*float64(elapsed-c.Duration)/float64(c.RecoveryDuration)
+		}
+		price *= math.Exp(level)
+		liquidity *= c.Liquidity
+		activity *= c.Activity
+	}
+	// A crash recovering less than fully leaves the price where it ended
+	for _, c := range g.cfg.Crashes {
+		if t-c.At > c.Duration+c.RecoveryDuration {
+			price *= 1 - c.Drop*(1-c.Recovery)
+		}
+	}
+	return price, liquidity, activity, falling
+}
+
+// arrival returns the time of the trade after t, the arrivals being Poisson
+// with the trade rate at t
+func (g *Generator) arrival(t time.Duration) time.Duration {
+	_, _, activity, _ := g.crash(t)
+	rate := g.regime(t).TradeRate * activity
+	gap := time.Duration(g.rand.ExpFloat64() / rate * float64(time.Second))
+	return t + max(gap, 0)
+}
+
+// advance moves the Brownian motion to t
+func (g *Generator) advance(t time.Duration) {
+	if t <= g.now {
+		return
+	}
+	r := g.regime(g.now)
+	dt := float64(t-g.now) / float64(year)
+	g.base *= math.Exp((r.Drift-r.Volatility*r.Volatility/2)*dt + r.Volatility*math.Sqrt(dt)*g.rand.NormFloat64())
+	g.now = t
+}
+
+// Mid returns the mid price at the time of the last event
+func (g *Generator) Mid() float64 {
+	price, _, _, _ := g.crash(g.now)
+	return g.round(g.base * price)
+}
+
+func (g *Generator) round(price float64) float64 {
+	return math.Max(math.Round(price/g.tick), 1) * g.tick
+}
+
+// Next returns the next event: the book snapshot first, then trades and
+// depth updates in time order
+func (g *Generator) Next() lob.Event {
+	if !g.booked {
+		g.booked = true
+		g.depth = g.cfg.Depth.Interval
+		bids, asks := g.book()
+		g.bids, g.asks = index(bids), index(asks)
+		return lob.Event{Type: lob.EventSnapshot, Time: g.start, Bids: bids, Asks: asks}
+	}
+	if g.trade < g.depth {
+		t := g.trade
+		g.advance(t)
+		g.trade = g.arrival(t)
+		return g.nextTrade(t)
+	}
+	t := g.depth
+	g.advance(t)
+	g.depth += g.cfg.Depth.Interval
+	return g.nextDepth(t)
+}
+
+func (g *Generator) nextTrade(t time.Duration) lob.Event {
+	_, _, _, falling := g.crash(t)
+	sellers := 0.5
+	if falling {
+		sellers = 0.8
+	}
+	side, price := lob.Buy, g.bestAsk()
+	if g.rand.Float64() < sellers {
+		side, price = lob.Sell, g.bestBid()
+	}
+	quantity := g.cfg.MeanQuantity * g.rand.ExpFloat64()
+	return lob.Event{Type: lob.EventTrade, Time: g.start + t.Milliseconds(), Price: price, Quantity: quantity, Side: side}
+}
+
+func (g *Generator) bestBid() float64 {
+	return g.round(g.Mid() - float64(g.cfg.Depth.SpreadTicks)*g.tick/2)
+}
+
+func (g *Generator) bestAsk() float64 {
+	return g.bestBid() + float64(g.cfg.Depth.SpreadTicks)*g.tick
+}
+
+// book returns the levels of both sides around the current price
+func (g *Generator) book() (bids, asks []lob.Level) {
+	_, liquidity, _, _ := g.crash(g.now)
+	bid, ask := g.bestBid(), g.bestAsk()
+	d := g.cfg.Depth
+	for i := 0; i < d.Levels; i++ {
+		// Books are thin at the touch and thicker further out
+		mean := d.LevelQuantity * liquidity * (1 + 0.2*float64(i))
+		bids = append(bids, lob.Level{Price: g.round(bid - float64(i)*g.tick), Quantity: mean * (0.5 + g.rand.Float64())})
+		asks = append(asks, lob.Level{Price: g.round(ask + float64(i)*g.tick), Quantity: mean * (0.5 + g.rand.Float64())})
+	}
+	return bids, asks
+}
+
+// nextDepth returns the diff from the previous book to the current one,
+// levels no longer quoted at quantity 0
+func (g *Generator) nextDepth(t time.Duration) lob.Event {
+	bids, asks := g.book()
+	next := lob.Event{Type: lob.EventDepth, Time: g.start + t.Milliseconds()}
+	next.Bids, g.bids = diff(g.bids, bids), index(bids)
+	next.Asks, g.asks = diff(g.asks, asks), index(asks)
+	return next
+}
+
+func index(levels []lob.Level) map[float64]float64 {
+	m := make(map[float64]float64, len(levels))
+	for _, l := range levels {
+		m[l.Price] = l.Quantity
+	}
+	return m
+}
+
+func diff(previous map[float64]float64, levels []lob.Level) []lob.Level {
+	var changed []lob.Level
+	current := index(levels)
+	for _, l := range levels {
+		if q, ok := previous[l.Price]; !ok || q != l.Quantity {
+			changed = append(changed, l)
+		}
+	}
+	var removed []lob.Level
+	for price := range previous {
+		if _, ok := current[price]; !ok {
+			removed = append(removed, lob.Level{Price: price})
+		}
+	}
+	sort.Slice(removed, func(i, j int) bool { return removed[i].Price < removed[j].Price })
+	return append(changed, removed...)
+}
+
+// Until hands the events up to end to handle, in time order
+func (g *Generator) Until(end time.Time, handle func(lob.Event) error) error {
+	until := end.UnixMilli()
+	for {
+		e := g.Next()
+		if e.Time > until {
+			return nil
+		}
+		if err := handle(e); err != nil {
+			return err
+		}
+	}
+}
+
+// Generate returns the events of a market from cfg.Start to end
+func Generate(cfg Config, end time.Time) ([]lob.Event, error) {
+	g, err := New(cfg)
+	if err != nil {
+		return nil, err
+	}
+	var events []lob.Event
+	err = g.Until(end, func(e lob.Event) error {
+		events = append(events, e)
+		return nil
+	})
+	return events, err
+}
+
+// WriteEvents writes events as the JSON lines lob.Replay reads
+func WriteEvents(w io.Writer, events []lob.Event) error {
+	buffered := bufio.NewWriter(w)
+	encoder := json.NewEncoder(buffered)
+	for _, e := range events {
+		if err := encoder.Encode(e); err != nil {
+			return err
+		}
+	}
+	return buffered.Flush()
+}
+
+// Klines folds the trades of events into the klines of a Binance interval,
+// e.g. 1m, up to the last one closed by the last event
+func Klines(events []lob.Event, interval string) ([]candles.Candle, error) {
+	builder, err := candles.NewBuilder(interval)
+	if err != nil {
+		return nil, err
+	}
+	var last int64
+	for _, e := range events {
+		if e.Type == lob.EventTrade {
+			builder.Add(e.Time, e.Price, e.Quantity)
+		}
+		last = max(last, e.Time)
+	}
+	return builder.Flush(last + 1), nil
+}
@@ -0,0 +1,198 @@
+package synthdata
+
+import (
+	"bytes"
+	"math"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/BullionBear/sequex/pkg/lob"
+)
+
+var start = time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+
+func config() Config {
+	return Config{
+		Start:   start,
+		Price:   60000,
+		Regimes: []Regime{{Volatility: 0.5, TradeRate: 20}},
+		Seed:    42,
+	}
+}
+
+func TestGenerateIsReproducibleAndOrdered(t *testing.T) {
+	a, err := Generate(config(), start.Add(time.Minute))
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, err := Generate(config(), start.Add(time.Minute))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(a, b) {
+		t.Fatal("the same seed generated different streams")
+	}
+	if a[0].Type != lob.EventSnapshot || len(a[0].Bids) != 10 || len(a[0].Asks) != 10 {
+		t.Fatalf("first event %+v", a[0])
+	}
+	for i := 1; i < len(a); i++ {
+		if a[i].Time < a[i-1].Time {
+			t.Fatalf("event %d at %d before %d", i, a[i].Time, a[i-1].Time)
+		}
+	}
+
+	// The stream replays into the book simulator as a recording does
+	var buf bytes.Buffer
+	if err := WriteEvents(&buf, a); err != nil {
+		t.Fatal(err)
+	}
+	sim := lob.New(lob.Options{})
+	applied, err := lob.Replay(&buf, sim, func(e lob.Event, _ []lob.Fill) error {
+		bid, okBid := sim.Best(lob.Buy)
+		ask, okAsk := sim.Best(lob.Sell)
+		if !okBid || !okAsk || bid >= ask {
+			t.Fatalf("crossed or empty book at %d: %v %v", e.Time, bid, ask)
+		}
+		return nil
+	})
+	if err != nil || applied != len(a) {
+		t.Fatalf("replayed %d of %d events: %v", applied, len(a), err)
+	}
+}
+
+func TestPoissonArrivals(t *testing.T) {
+	events, err := Generate(config(), start.Add(10*time.Minute))
+	if err != nil {
+		t.Fatal(err)
+	}
+	trades := 0
+	for _, e := range events {
+		if e.Type == lob.EventTrade {
+			trades++
+			if e.Quantity <= 0 || (e.Side != lob.Buy && e.Side != lob.Sell) {
+				t.Fatalf("trade %+v", e)
+			}
+		}
+	}
+	// 20 per second over 600s, within 5 standard deviations
+	if want := 12000.0; math.Abs(float64(trades)-want) > 5*math.Sqrt(want) {
+		t.Errorf("%d trades, want about %.0f", trades, want)
+	}
+}
+
+func TestRegimeVolatility(t *testing.T) {
+	cfg := config()
+	cfg.Regimes = []Regime{
+		{Volatility: 0.2, TradeRate: 1},
+		{Start: 12 * time.Hour, Volatility: 2, TradeRate: 1},
+	}
+	cfg.Depth.Interval = time.Minute
+	g, err := New(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Realized volatility of the mid price sampled every minute
+	realized := func(from, to time.Duration) float64 {
+		var sum float64
+		var n int
+		prev := 0.0
+		for at := from; at < to; at += time.Minute {
+			for g.now < at {
+				g.Next()
+			}
+			mid := g.base
+			if prev > 0 {
+				r := math.Log(mid / prev)
+				sum += r * r
+				n++
+			}
+			prev = mid
+		}
+		return math.Sqrt(sum / float64(n) * float64(year/time.Minute))
+	}
+	calm := realized(0, 12*time.Hour)
+	wild := realized(12*time.Hour, 24*time.Hour)
+	if calm < 0.1 || calm > 0.4 || wild < 1 || wild > 4 {
+		t.Errorf("realized volatility %.2f then %.2f, want about 0.2 then 2", calm, wild)
+	}
+}
+
+func TestFlashCrash(t *testing.T) {
+	cfg := config()
+	cfg.Regimes = []Regime{{Volatility: 0.01, TradeRate: 5}}
+	cfg.Crashes = []FlashCrash{{At: time.Minute, Drop: 0.2, Duration: 10 * time.Second, Recovery: 0.5, RecoveryDuration: time.Minute}}
+	events, err := Generate(cfg, start.Add(5*time.Minute))
+	if err != nil {
+		t.Fatal(err)
+	}
+	low, last := math.Inf(1), 0.0
+	var before, during int
+	sells := 0
+	for _, e := range events {
+		if e.Type != lob.EventTrade {
+			continue
+		}
+		low = math.Min(low, e.Price)
+		last = e.Price
+		at := time.Duration(e.Time-start.UnixMilli()) * time.Millisecond
+		switch {
+		case at < time.Minute:
+			before++
+		case at < time.Minute+10*time.Second:
+			during++
+			if e.Side == lob.Sell {
+				sells++
+			}
+		}
+	}
+	if math.Abs(low/60000-0.8) > 0.01 {
+		t.Errorf("bottom at %.0f, want about 48000", low)
+	}
+	if math.Abs(last/60000-0.9) > 0.01 {
+		t.Errorf("recovered to %.0f, want about 54000", last)
+	}
+	// Activity jumps and sellers dominate while the price falls
+	if rate := float64(during) / 10; rate < 2*float64(before)/60 {
+		t.Errorf("%d trades in the crash, %d in the minute before", during, before)
+	}
+	if float64(sells) < 0.65*float64(during) {
+		t.Errorf("%d sells of %d trades in the crash", sells, during)
+	}
+}
+
+func TestKlines(t *testing.T) {
+	events, err := Generate(config(), start.Add(5*time.Minute+time.Second))
+	if err != nil {
+		t.Fatal(err)
+	}
+	klines, err := Klines(events, "1m")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(klines) != 5 {
+		t.Fatalf("%d klines, want 5", len(klines))
+	}
+	for i, k := range klines {
+		if k.OpenTime != start.Add(time.Duration(i)*time.Minute).UnixMilli() || k.Trades == 0 || k.Low > k.High || k.Volume <= 0 {
+			t.Errorf("kline %d: %+v", i, k)
+		}
+	}
+}
+
+func TestValidate(t *testing.T) {
+	for name, mutate := range map[string]func(*Config){
+		"no price":          func(c *Config) { c.Price = 0 },
+		"no regime":         func(c *Config) { c.Regimes = nil },
+		"late first regime": func(c *Config) { c.Regimes[0].Start = time.Second },
+		"no trades":         func(c *Config) { c.Regimes[0].TradeRate = 0 },
+		"total crash":       func(c *Config) { c.Crashes = []FlashCrash{{Drop: 1, Duration: time.Second}} },
+		"instant crash":     func(c *Config) { c.Crashes = []FlashCrash{{Drop: 0.1}} },
+	} {
+		cfg := config()
+		mutate(&cfg)
+		if err := cfg.Validate(); err == nil {
+			t.Errorf("%s: expected an error", name)
+		}
+	}
+}
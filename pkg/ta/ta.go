@@ -0,0 +1,294 @@
+// Package ta computes technical indicators incrementally, one value at a
+// time, and serializes their state so that a strategy restarting resumes
+// them from where it stopped rather than replaying its whole lookback, such
+// as 200 days of closes for a 200-day moving average.
+package ta
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+)
+
+// ErrMismatch is returned when restoring a state saved by an indicator of
+// other parameters
+var ErrMismatch = errors.New("state of other parameters")
+
+// Indicator is updated with a value at a time. Its state is encoded as JSON
+// and decoded into an indicator of the same parameters.
+type Indicator interface {
+	Update(v float64) float64 // Returns the value of the indicator
+	Value() float64
+	Ready() bool // Whether it has seen its whole lookback
+	json.Marshaler
+	json.Unmarshaler
+}
+
+// EMA is an exponential moving average, seeded with the simple average of
+// its first period values
+type EMA struct {
+	period int
+	alpha  float64
+	value  float64
+	count  int
+}
+
+// NewEMA creates an exponential moving average over period values
+func NewEMA(period int) (*EMA, error) {
+	if period <= 0 {
+		return nil, fmt.Errorf("period must be positive, got %d", period)
+	}
+	return &EMA{period: period, alpha: 2 / float64(period+1)}, nil
+}
+
+// Update folds v into the average
+func (e *EMA) Update(v float64) float64 {
+	e.count++
+	if e.count <= e.period {
+		e.value += (v - e.value) / float64(e.count)
+	} else {
+		e.value += e.alpha * (v - e.value)
+	}
+	return e.value
+}
+
+// Value returns the average, that of the values seen until ready
+func (e *EMA) Value() float64 {
+	return e.value
+}
+
+// Ready reports whether period values were seen
+func (e *EMA) Ready() bool {
+	return e.count >= e.period
+}
+
+type emaState struct {
+	Period int     `json:"period"`
+	Value  float64 `json:"value"`
+	Count  int     `json:"count"`
+}
+
+func (e *EMA) MarshalJSON() ([]byte, error) {
+	return json.Marshal(emaState{Period: e.period, Value: e.value, Count: e.count})
+}
+
+func (e *EMA) UnmarshalJSON(data []byte) error {
+	var s emaState
+	if err := json.Unmarshal(data, &s); err != nil {
+		return err
+	}
+	if s.Period != e.period || s.Count < 0 {
+		return fmt.Errorf("%w: ema of period %d, not %d", ErrMismatch, s.Period, e.period)
+	}
+	e.value, e.count = s.Value, min(s.Count, e.period+1)
+	return nil
+}
+
+// SMA is a simple moving average over a rolling window
+type SMA struct {
+	window []float64 // Ring of the last period values
+	next   int       // Index the next value goes to
+	count  int
+	sum    float64
+}
+
+// NewSMA creates a simple moving average over period values
+func NewSMA(period int) (*SMA, error) {
+	if period <= 0 {
+		return nil, fmt.Errorf("period must be positive, got %d", period)
+	}
+	return &SMA{window: make([]float64, period)}, nil
+}
+
+// Update adds v to the window, evicting the oldest value once full
+func (s *SMA) Update(v float64) float64 {
+	if s.count == len(s.window) {
+		s.sum -= s.window[s.next]
+	} else {
+		s.count++
+	}
+	s.window[s.next] = v
+	s.sum += v
+	s.next = (s.next + 1) % len(s.window)
+	return s.Value()
+}
+
+// Value returns the average of the window, that of the values seen until
+// ready
+func (s *SMA) Value() float64 {
+	if s.count == 0 {
+		return 0
+	}
+	return s.sum / float64(s.count)
+}
+
+// Ready reports whether the window is full
+func (s *SMA) Ready() bool {
+	return s.count == len(s.window)
+}
+
+// Values returns the values of the window, oldest first
+func (s *SMA) Values() []float64 {
+	values := make([]float64, 0, s.count)
+	start := (s.next - s.count + len(s.window)) % len(s.window)
+	for i := 0; i < s.count; i++ {
+		values = append(values, s.window[(start+i)%len(s.window)])
+	}
+	return values
+}
+
+type smaState struct {
+	Period int       `json:"period"`
+	Values []float64 `json:"values"` // Oldest first
+}
+
+func (s *SMA) MarshalJSON() ([]byte, error) {
+	return json.Marshal(smaState{Period: len(s.window), Values: s.Values()})
+}
+
+// UnmarshalJSON restores the window, the sum being recomputed rather than
+// carrying the rounding errors of the previous run
+func (s *SMA) UnmarshalJSON(data []byte) error {
+	var st smaState
+	if err := json.Unmarshal(data, &st); err != nil {
+		return err
+	}
+	if st.Period != len(s.window) || len(st.Values) > len(s.window) {
+		return fmt.Errorf("%w: sma of period %d, not %d", ErrMismatch, st.Period, len(s.window))
+	}
+	clear(s.window)
+	s.next, s.count, s.sum = 0, 0, 0
+	for _, v := range st.Values {
+		s.Update(v)
+	}
+	return nil
+}
+
+// Set is the indicators of a strategy and the time of the last value they
+// were updated with, saved and restored together. It is safe for concurrent
+// use as long as its indicators are only updated through Update.
+type Set struct {
+	mu         sync.Mutex
+	indicators map[string]Indicator
+	time       int64
+}
+
+// NewSet creates an empty set
+func NewSet() *Set {
+	return &Set{indicators: make(map[string]Indicator)}
+}
+
+// Add adds a named indicator to the set
+func (s *Set) Add(name string, ind Indicator) error {
+	if ind == nil {
+		return fmt.Errorf("indicator %s cannot be nil", name)
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, ok := s.indicators[name]; ok {
+		return fmt.Errorf("indicator %s already added", name)
+	}
+	s.indicators[name] = ind
+	return nil
+}
+
+// Update runs fn, which updates the indicators with the value of t, e.g.
+// the close of the kline opened at t in Unix milliseconds, and records that
+// the set is updated up to t. Values at or before the time of the set were
+// already applied and are skipped, so a replay overlapping the state
+// restored is harmless. It reports whether fn ran.
+func (s *Set) Update(t int64, fn func()) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if t <= s.time {
+		return false
+	}
+	fn()
+	s.time = t
+	return true
+}
+
+// Time returns the time the indicators are updated up to, 0 until updated.
+// Once restored, only the values after it are to be replayed.
+func (s *Set) Time() int64 {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.time
+}
+
+// Ready reports whether every indicator has seen its whole lookback
+func (s *Set) Ready() bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, ind := range s.indicators {
+		if !ind.Ready() {
+			return false
+		}
+	}
+	return true
+}
+
+// State is the saved state of a set
+type State struct {
+	Time       int64                      `json:"time"`
+	Indicators map[string]json.RawMessage `json:"indicators"`
+}
+
+// State returns the state of the indicators. It has the signature of a
+// node state part.
+func (s *Set) State() (any, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	st := State{Time: s.time, Indicators: make(map[string]json.RawMessage, len(s.indicators))}
+	for name, ind := range s.indicators {
+		data, err := ind.MarshalJSON()
+		if err != nil {
+			return nil, fmt.Errorf("indicator %s: %w", name, err)
+		}
+		st.Indicators[name] = data
+	}
+	return st, nil
+}
+
+// Restore restores the indicators from a state. Either every indicator of
+// the set is restored or none is: when one is missing from the state or was
+// saved with other parameters, the set is left as it was and the strategy
+// warms up from scratch. It has the signature of a node state part.
+func (s *Set) Restore(data json.RawMessage) error {
+	var st State
+	if err := json.Unmarshal(data, &st); err != nil {
+		return err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	names := make([]string, 0, len(s.indicators))
+	for name := range s.indicators {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		if _, ok := st.Indicators[name]; !ok {
+			return fmt.Errorf("%w: indicator %s not saved", ErrMismatch, name)
+		}
+	}
+	// The indicators restored are put back as they were when a later one
+	// does not match
+	previous := make(map[string][]byte, len(names))
+	for _, name := range names {
+		current, err := s.indicators[name].MarshalJSON()
+		if err != nil {
+			return err
+		}
+		if err := s.indicators[name].UnmarshalJSON(st.Indicators[name]); err != nil {
+			for restored, data := range previous {
+				s.indicators[restored].UnmarshalJSON(data)
+			}
+			return fmt.Errorf("indicator %s: %w", name, err)
+		}
+		previous[name] = current
+	}
+	s.time = st.Time
+	return nil
+}
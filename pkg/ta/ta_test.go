@@ -0,0 +1,119 @@
+package ta
+
+import (
+	"encoding/json"
+	"errors"
+	"math"
+	"testing"
+)
+
+func TestEMA(t *testing.T) {
+	e, err := NewEMA(3)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, v := range []float64{1, 2, 3} {
+		e.Update(v)
+	}
+	if !e.Ready() || e.Value() != 2 {
+		t.Fatalf("seeded ema %v, ready %v", e.Value(), e.Ready())
+	}
+	if got := e.Update(4); got != 3 {
+		t.Errorf("ema %v, want 3", got)
+	}
+	if _, err := NewEMA(0); err == nil {
+		t.Error("ema of period 0 created")
+	}
+}
+
+func TestSMA(t *testing.T) {
+	s, err := NewSMA(3)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i, want := range []float64{1, 1.5, 2, 3, 4} {
+		if got := s.Update(float64(i + 1)); got != want {
+			t.Errorf("sma after %d values %v, want %v", i+1, got, want)
+		}
+	}
+	if !s.Ready() || len(s.Values()) != 3 || s.Values()[0] != 3 {
+		t.Errorf("window %v", s.Values())
+	}
+}
+
+// TestRestore checks that indicators restored midway continue exactly as
+// those updated with every value
+func TestRestore(t *testing.T) {
+	build := func() (*Set, *EMA, *SMA) {
+		ema, _ := NewEMA(20)
+		sma, _ := NewSMA(200)
+		set := NewSet()
+		set.Add("ema", ema)
+		set.Add("sma", sma)
+		return set, ema, sma
+	}
+	price := func(i int) float64 { return 100 + 10*math.Sin(float64(i)/7) }
+
+	full, fullEMA, fullSMA := build()
+	for i := 1; i <= 500; i++ {
+		full.Update(int64(i), func() {
+			fullEMA.Update(price(i))
+			fullSMA.Update(price(i))
+		})
+	}
+
+	first, ema, sma := build()
+	for i := 1; i <= 300; i++ {
+		first.Update(int64(i), func() {
+			ema.Update(price(i))
+			sma.Update(price(i))
+		})
+	}
+	state, err := first.State()
+	if err != nil {
+		t.Fatal(err)
+	}
+	data, err := json.Marshal(state)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	restarted, ema, sma := build()
+	if err := restarted.Restore(data); err != nil {
+		t.Fatal(err)
+	}
+	if restarted.Time() != 300 || !restarted.Ready() {
+		t.Fatalf("restored at %d, ready %v", restarted.Time(), restarted.Ready())
+	}
+	// A replay overlapping the state skips the values already applied
+	for i := 250; i <= 500; i++ {
+		restarted.Update(int64(i), func() {
+			ema.Update(price(i))
+			sma.Update(price(i))
+		})
+	}
+	if math.Abs(ema.Value()-fullEMA.Value()) > 1e-9 || math.Abs(sma.Value()-fullSMA.Value()) > 1e-9 {
+		t.Errorf("restored ema %v sma %v, uninterrupted %v %v", ema.Value(), sma.Value(), fullEMA.Value(), fullSMA.Value())
+	}
+
+	// A set of other parameters is left to warm up from scratch
+	other := NewSet()
+	otherEMA, _ := NewEMA(20)
+	otherSMA, _ := NewSMA(100)
+	other.Add("ema", otherEMA)
+	other.Add("sma", otherSMA)
+	if err := other.Restore(data); !errors.Is(err, ErrMismatch) {
+		t.Errorf("restored a set of other parameters: %v", err)
+	}
+	if other.Time() != 0 || otherEMA.Ready() || len(otherSMA.Values()) != 0 {
+		t.Errorf("set partly restored: time %d, ema ready %v, sma %v", other.Time(), otherEMA.Ready(), otherSMA.Values())
+	}
+	missing := NewSet()
+	missingEMA, _ := NewEMA(20)
+	rsi, _ := NewSMA(14)
+	missing.Add("ema", missingEMA)
+	missing.Add("rsi", rsi)
+	if err := missing.Restore(data); !errors.Is(err, ErrMismatch) || missingEMA.Ready() {
+		t.Errorf("restored a set with an indicator not saved: %v", err)
+	}
+}